@@ -23,6 +23,15 @@ type Authorization struct {
 	OrgID       ID           `json:"orgID"`
 	UserID      ID           `json:"userID,omitempty"`
 	Permissions []Permission `json:"permissions"`
+	// WritePolicy optionally restricts the measurements written with the
+	// authorization.
+	WritePolicy *WritePolicy `json:"writePolicy,omitempty"`
+	// RoleIDs are the roles assigned to the authorization, whose
+	// permissions it is allowed in addition to its own.
+	RoleIDs []ID `json:"roles,omitempty"`
+	// RolePermissions are the permissions of the roles of the
+	// authorization, resolved when the authorization is found.
+	RolePermissions []Permission `json:"-"`
 	CRUDLog
 }
 
@@ -30,6 +39,12 @@ type Authorization struct {
 type AuthorizationUpdate struct {
 	Status      *Status `json:"status,omitempty"`
 	Description *string `json:"description,omitempty"`
+	// WritePolicy replaces the write policy of the authorization. An empty
+	// policy removes it.
+	WritePolicy *WritePolicy `json:"writePolicy,omitempty"`
+	// RoleIDs replaces the roles of the authorization. An empty list removes
+	// them.
+	RoleIDs *[]ID `json:"roles,omitempty"`
 }
 
 // Valid ensures that the authorization is valid.
@@ -43,17 +58,74 @@ func (a *Authorization) Valid() error {
 		}
 	}
 
+	if a.WritePolicy != nil {
+		return a.WritePolicy.Valid()
+	}
+	return nil
+}
+
+// WritePolicy restricts the measurements that may be written with an
+// authorization, so that a leaked device token can not write arbitrary new
+// measurements into a shared bucket.
+type WritePolicy struct {
+	// AllowMeasurements, if not empty, lists the only measurements that may
+	// be written.
+	AllowMeasurements []string `json:"allowMeasurements,omitempty"`
+	// DenyMeasurements lists measurements that may not be written.
+	DenyMeasurements []string `json:"denyMeasurements,omitempty"`
+}
+
+// Valid returns an error if a measurement of the policy is empty.
+func (p *WritePolicy) Valid() error {
+	for _, names := range [][]string{p.AllowMeasurements, p.DenyMeasurements} {
+		for _, name := range names {
+			if name == "" {
+				return &Error{
+					Code: EInvalid,
+					Msg:  "write policy measurements must not be empty",
+				}
+			}
+		}
+	}
 	return nil
 }
 
+// IsEmpty returns true if the policy does not restrict any measurement.
+func (p *WritePolicy) IsEmpty() bool {
+	return p == nil || (len(p.AllowMeasurements) == 0 && len(p.DenyMeasurements) == 0)
+}
+
+// AllowsMeasurement returns true if the measurement may be written. A nil
+// policy allows every measurement.
+func (p *WritePolicy) AllowsMeasurement(name string) bool {
+	if p == nil {
+		return true
+	}
+	for _, denied := range p.DenyMeasurements {
+		if name == denied {
+			return false
+		}
+	}
+	if len(p.AllowMeasurements) == 0 {
+		return true
+	}
+	for _, allowed := range p.AllowMeasurements {
+		if name == allowed {
+			return true
+		}
+	}
+	return false
+}
+
 // Allowed returns true if the authorization is active and request permission
-// exists in the authorization's list of permissions.
+// exists in the authorization's list of permissions or in the permissions of
+// its roles.
 func (a *Authorization) Allowed(p Permission) bool {
 	if !a.IsActive() {
 		return false
 	}
 
-	return PermissionAllowed(p, a.Permissions)
+	return PermissionAllowed(p, a.Permissions) || PermissionAllowed(p, a.RolePermissions)
 }
 
 // IsActive is a stub for idpe.
@@ -10,15 +10,17 @@ import (
 var _ influxdb.AuthorizationService = (*AuthorizationService)(nil)
 
 // AuthorizationService wraps a influxdb.AuthorizationService and authorizes actions
-// against it appropriately.
+// against it appropriately. The roles of authorizations are found with rs.
 type AuthorizationService struct {
-	s influxdb.AuthorizationService
+	s  influxdb.AuthorizationService
+	rs influxdb.RoleService
 }
 
 // NewAuthorizationService constructs an instance of an authorizing authorization serivce.
-func NewAuthorizationService(s influxdb.AuthorizationService) *AuthorizationService {
+func NewAuthorizationService(s influxdb.AuthorizationService, rs influxdb.RoleService) *AuthorizationService {
 	return &AuthorizationService{
-		s: s,
+		s:  s,
+		rs: rs,
 	}
 }
 
@@ -125,6 +127,10 @@ func (s *AuthorizationService) CreateAuthorization(ctx context.Context, a *influ
 		return err
 	}
 
+	if err := verifyRoles(ctx, s.rs, a.RoleIDs); err != nil {
+		return err
+	}
+
 	return s.s.CreateAuthorization(ctx, a)
 }
 
@@ -154,6 +160,12 @@ func (s *AuthorizationService) UpdateAuthorization(ctx context.Context, id influ
 		return nil, err
 	}
 
+	if upd.RoleIDs != nil {
+		if err := verifyRoles(ctx, s.rs, *upd.RoleIDs); err != nil {
+			return nil, err
+		}
+	}
+
 	return s.s.UpdateAuthorization(ctx, id, upd)
 }
 
@@ -0,0 +1,55 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+)
+
+var _ influxdb.ConstantService = (*ConstantService)(nil)
+
+// ConstantService wraps a influxdb.ConstantService and authorizes actions
+// against it appropriately. Constants are authorized as the variables of
+// their organization.
+type ConstantService struct {
+	s influxdb.ConstantService
+}
+
+// NewConstantService constructs an instance of an authorizing constant service.
+func NewConstantService(s influxdb.ConstantService) *ConstantService {
+	return &ConstantService{
+		s: s,
+	}
+}
+
+func authorizeConstants(ctx context.Context, a influxdb.Action, orgID influxdb.ID) error {
+	p, err := influxdb.NewPermission(a, influxdb.VariablesResourceType, orgID)
+	if err != nil {
+		return err
+	}
+	return IsAllowed(ctx, *p)
+}
+
+// FindConstants checks to see if the authorizer on context has read access to the variables of the organization.
+func (s *ConstantService) FindConstants(ctx context.Context, orgID influxdb.ID) (map[string]interface{}, error) {
+	if err := authorizeConstants(ctx, influxdb.ReadAction, orgID); err != nil {
+		return nil, err
+	}
+	return s.s.FindConstants(ctx, orgID)
+}
+
+// PatchConstants checks to see if the authorizer on context has write access to the variables of the organization.
+func (s *ConstantService) PatchConstants(ctx context.Context, orgID influxdb.ID, m map[string]interface{}) error {
+	if err := authorizeConstants(ctx, influxdb.WriteAction, orgID); err != nil {
+		return err
+	}
+	return s.s.PatchConstants(ctx, orgID, m)
+}
+
+// DeleteConstants checks to see if the authorizer on context has write access to the variables of the organization.
+func (s *ConstantService) DeleteConstants(ctx context.Context, orgID influxdb.ID, names ...string) error {
+	if err := authorizeConstants(ctx, influxdb.WriteAction, orgID); err != nil {
+		return err
+	}
+	return s.s.DeleteConstants(ctx, orgID, names...)
+}
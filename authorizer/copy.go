@@ -0,0 +1,48 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+)
+
+var _ influxdb.BucketCopyService = (*BucketCopyService)(nil)
+
+// BucketCopyService wraps a influxdb.BucketCopyService and authorizes actions
+// against it appropriately.
+type BucketCopyService struct {
+	s influxdb.BucketCopyService
+}
+
+// NewBucketCopyService constructs an instance of an authorizing bucket copy service.
+func NewBucketCopyService(s influxdb.BucketCopyService) *BucketCopyService {
+	return &BucketCopyService{
+		s: s,
+	}
+}
+
+// CopyBucketRange checks that the source bucket can be read and the
+// destination bucket written before copying. Copies are rejected for the
+// authorizations with a write policy, as the blocks are copied without
+// looking at the measurements of their series.
+func (c BucketCopyService) CopyBucketRange(ctx context.Context, src, dst influxdb.BucketCopyTarget, min, max int64) (*influxdb.BucketCopyStats, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := authorizeReadBucket(ctx, src.OrgID, src.BucketID); err != nil {
+		return nil, err
+	}
+	if err := authorizeWriteBucket(ctx, dst.OrgID, dst.BucketID); err != nil {
+		return nil, err
+	}
+	if p, err := writePolicy(ctx); err != nil {
+		return nil, err
+	} else if p != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EForbidden,
+			Msg:  "copying buckets is not allowed with a token restricted by a write policy",
+		}
+	}
+	return c.s.CopyBucketRange(ctx, src, dst, min, max)
+}
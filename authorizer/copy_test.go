@@ -0,0 +1,70 @@
+package authorizer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/authorizer"
+	influxdbcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/mock"
+	influxdbtesting "github.com/influxdata/influxdb/testing"
+)
+
+func TestBucketCopyService_CopyBucketRange(t *testing.T) {
+	src := influxdb.BucketCopyTarget{OrgID: 10, BucketID: 1}
+	dst := influxdb.BucketCopyTarget{OrgID: 10, BucketID: 2}
+	permissions := []influxdb.Permission{
+		{
+			Action:   influxdb.ReadAction,
+			Resource: influxdb.Resource{Type: influxdb.BucketsResourceType, OrgID: influxdbtesting.IDPtr(10)},
+		},
+		{
+			Action:   influxdb.WriteAction,
+			Resource: influxdb.Resource{Type: influxdb.BucketsResourceType, OrgID: influxdbtesting.IDPtr(10)},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		permissions []influxdb.Permission
+		policy      *influxdb.WritePolicy
+		code        string
+	}{
+		{
+			name:        "authorized to read the source and write the destination",
+			permissions: permissions,
+		},
+		{
+			name:        "unauthorized to write the destination",
+			permissions: permissions[:1],
+			code:        influxdb.EUnauthorized,
+		},
+		{
+			name:        "token restricted by a write policy",
+			permissions: permissions,
+			policy:      &influxdb.WritePolicy{AllowMeasurements: []string{"m"}},
+			code:        influxdb.EForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := authorizer.NewBucketCopyService(mock.NewBucketCopyService())
+			ctx := influxdbcontext.SetAuthorizer(context.Background(), &influxdb.Authorization{
+				Status:      influxdb.Active,
+				Permissions: tt.permissions,
+				WritePolicy: tt.policy,
+			})
+			_, err := s.CopyBucketRange(ctx, src, dst, 0, 10)
+			if tt.code == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if got := influxdb.ErrorCode(err); got != tt.code {
+				t.Fatalf("unexpected error code: got %q, want %q (%v)", got, tt.code, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+)
+
+var _ influxdb.DiagnosticsService = (*DiagnosticsService)(nil)
+
+// DiagnosticsService wraps a influxdb.DiagnosticsService and authorizes actions
+// against it appropriately. As the configuration and the queries of every
+// organization are reported, only operators are allowed.
+type DiagnosticsService struct {
+	s influxdb.DiagnosticsService
+}
+
+// NewDiagnosticsService constructs an instance of an authorizing diagnostics service.
+func NewDiagnosticsService(s influxdb.DiagnosticsService) *DiagnosticsService {
+	return &DiagnosticsService{
+		s: s,
+	}
+}
+
+func (d DiagnosticsService) Config(ctx context.Context) ([]influxdb.ConfigOption, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := IsAllowedAll(ctx, influxdb.OperPermissions()); err != nil {
+		return nil, err
+	}
+	return d.s.Config(ctx)
+}
+
+func (d DiagnosticsService) QueryLog(ctx context.Context) (*influxdb.QueryLog, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := IsAllowedAll(ctx, influxdb.OperPermissions()); err != nil {
+		return nil, err
+	}
+	return d.s.QueryLog(ctx)
+}
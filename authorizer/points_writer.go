@@ -0,0 +1,70 @@
+package authorizer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/influxdata/influxdb"
+	influxdbcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/storage"
+)
+
+var _ storage.PointsWriter = (*PointsWriter)(nil)
+
+// PointsWriter wraps a storage.PointsWriter and rejects the points whose
+// measurement is not allowed by the write policy of the authorization on
+// context.
+type PointsWriter struct {
+	w storage.PointsWriter
+}
+
+// NewPointsWriter constructs an instance of an authorizing points writer.
+func NewPointsWriter(w storage.PointsWriter) *PointsWriter {
+	return &PointsWriter{
+		w: w,
+	}
+}
+
+// WritePoints writes the points unless the write policy of the authorization
+// on context denies one of their measurements, in which case none is written.
+func (w *PointsWriter) WritePoints(ctx context.Context, points []models.Point) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	p, err := writePolicy(ctx)
+	if err != nil {
+		return err
+	}
+	if p != nil {
+		checked := make(map[string]bool)
+		for _, pt := range points {
+			name := string(pt.Tags().Get(models.MeasurementTagKeyBytes))
+			if checked[name] {
+				continue
+			}
+			if !p.AllowsMeasurement(name) {
+				return &influxdb.Error{
+					Code: influxdb.EForbidden,
+					Msg:  fmt.Sprintf("measurement %q is not allowed by the write policy of the token", name),
+				}
+			}
+			checked[name] = true
+		}
+	}
+	return w.w.WritePoints(ctx, points)
+}
+
+// writePolicy returns the write policy of the authorization on context, or
+// nil if it does not restrict any measurement.
+func writePolicy(ctx context.Context) (*influxdb.WritePolicy, error) {
+	a, err := influxdbcontext.GetAuthorizer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if auth, ok := a.(*influxdb.Authorization); ok && !auth.WritePolicy.IsEmpty() {
+		return auth.WritePolicy, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,85 @@
+package authorizer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/authorizer"
+	influxdbcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/mock"
+	"github.com/influxdata/influxdb/models"
+)
+
+func TestPointsWriter_WritePoints(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *influxdb.WritePolicy
+		data   string
+		err    string
+	}{
+		{
+			name: "without a write policy every measurement is written",
+			data: "m1 f=1\nm2 f=1",
+		},
+		{
+			name:   "measurements allowed by the write policy are written",
+			policy: &influxdb.WritePolicy{AllowMeasurements: []string{"m1", "m2"}},
+			data:   "m1 f=1\nm2 f=1",
+		},
+		{
+			name:   "measurement not in the allowlist of the write policy is forbidden",
+			policy: &influxdb.WritePolicy{AllowMeasurements: []string{"m1"}},
+			data:   "m1 f=1\nm2 f=1",
+			err:    `measurement "m2" is not allowed by the write policy of the token`,
+		},
+		{
+			name:   "measurement in the denylist of the write policy is forbidden",
+			policy: &influxdb.WritePolicy{DenyMeasurements: []string{"m1"}},
+			data:   "m2 f=1\nm1 f=1",
+			err:    `measurement "m1" is not allowed by the write policy of the token`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, err := models.ParsePointsWithOptions([]byte(tt.data), []byte("000000000000000a000000000000000b"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pw := &mock.PointsWriter{}
+			w := authorizer.NewPointsWriter(pw)
+			ctx := influxdbcontext.SetAuthorizer(context.Background(), &influxdb.Authorization{WritePolicy: tt.policy})
+			err = w.WritePoints(ctx, points)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got, want := len(pw.Points), len(points); got != want {
+					t.Fatalf("unexpected points written: got %d, want %d", got, want)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if got, want := influxdb.ErrorCode(err), influxdb.EForbidden; got != want {
+				t.Errorf("unexpected error code: got %q, want %q", got, want)
+			}
+			if got := influxdb.ErrorMessage(err); got != tt.err {
+				t.Errorf("unexpected error message: got %q, want %q", got, tt.err)
+			}
+			if got := pw.WritePointsCalled(); got != 0 {
+				t.Errorf("unexpected writes of denied points: %d", got)
+			}
+		})
+	}
+}
+
+func TestPointsWriter_WritePoints_NoAuthorizer(t *testing.T) {
+	w := authorizer.NewPointsWriter(&mock.PointsWriter{})
+	if err := w.WritePoints(context.Background(), nil); err == nil {
+		t.Fatal("expected error without an authorizer on context")
+	}
+}
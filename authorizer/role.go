@@ -0,0 +1,164 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+)
+
+var _ influxdb.RoleService = (*RoleService)(nil)
+
+// RoleService wraps a influxdb.RoleService and authorizes actions
+// against it appropriately. Roles are read with read access to their
+// organization, and managed with write access to it. Granting the
+// permissions of a role, by defining or assigning it, also requires the
+// authorizer to be allowed them.
+type RoleService struct {
+	s influxdb.RoleService
+}
+
+// NewRoleService constructs an instance of an authorizing role service.
+func NewRoleService(s influxdb.RoleService) *RoleService {
+	return &RoleService{
+		s: s,
+	}
+}
+
+// FindRoleByID checks to see if the authorizer on context has read access to the organization of the role.
+func (s *RoleService) FindRoleByID(ctx context.Context, id influxdb.ID) (*influxdb.Role, error) {
+	r, err := s.s.FindRoleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeReadOrg(ctx, r.OrgID); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// FindRoles retrieves all roles that match the provided filter and then filters the list down to only the roles of the organizations that are authorized.
+func (s *RoleService) FindRoles(ctx context.Context, filter influxdb.RoleFilter, opt ...influxdb.FindOptions) ([]*influxdb.Role, int, error) {
+	rs, _, err := s.s.FindRoles(ctx, filter, opt...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	roles := rs[:0]
+	for _, r := range rs {
+		err := authorizeReadOrg(ctx, r.OrgID)
+		if err != nil && influxdb.ErrorCode(err) != influxdb.EUnauthorized {
+			return nil, 0, err
+		}
+
+		if influxdb.ErrorCode(err) == influxdb.EUnauthorized {
+			continue
+		}
+
+		roles = append(roles, r)
+	}
+
+	return roles, len(roles), nil
+}
+
+// CreateRole checks to see if the authorizer on context has write access to the organization of the role and its permissions.
+func (s *RoleService) CreateRole(ctx context.Context, r *influxdb.Role) error {
+	if err := authorizeWriteOrg(ctx, r.OrgID); err != nil {
+		return err
+	}
+
+	if err := VerifyPermissions(ctx, r.Permissions); err != nil {
+		return err
+	}
+
+	return s.s.CreateRole(ctx, r)
+}
+
+// UpdateRole checks to see if the authorizer on context has write access to the organization of the role and its new permissions.
+func (s *RoleService) UpdateRole(ctx context.Context, id influxdb.ID, upd influxdb.RoleUpdate) (*influxdb.Role, error) {
+	r, err := s.s.FindRoleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeWriteOrg(ctx, r.OrgID); err != nil {
+		return nil, err
+	}
+
+	if err := VerifyPermissions(ctx, upd.Permissions); err != nil {
+		return nil, err
+	}
+
+	return s.s.UpdateRole(ctx, id, upd)
+}
+
+// DeleteRole checks to see if the authorizer on context has write access to the organization of the role.
+func (s *RoleService) DeleteRole(ctx context.Context, id influxdb.ID) error {
+	r, err := s.s.FindRoleByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := authorizeWriteOrg(ctx, r.OrgID); err != nil {
+		return err
+	}
+
+	return s.s.DeleteRole(ctx, id)
+}
+
+// FindRoleUsers checks to see if the authorizer on context has read access to the organization of the role.
+func (s *RoleService) FindRoleUsers(ctx context.Context, roleID influxdb.ID) ([]influxdb.ID, error) {
+	if _, err := s.FindRoleByID(ctx, roleID); err != nil {
+		return nil, err
+	}
+
+	return s.s.FindRoleUsers(ctx, roleID)
+}
+
+// AddRoleUser checks to see if the authorizer on context has write access to the organization of the role and its permissions.
+func (s *RoleService) AddRoleUser(ctx context.Context, roleID, userID influxdb.ID) error {
+	r, err := s.s.FindRoleByID(ctx, roleID)
+	if err != nil {
+		return err
+	}
+
+	if err := authorizeWriteOrg(ctx, r.OrgID); err != nil {
+		return err
+	}
+
+	if err := VerifyPermissions(ctx, r.Permissions); err != nil {
+		return err
+	}
+
+	return s.s.AddRoleUser(ctx, roleID, userID)
+}
+
+// RemoveRoleUser checks to see if the authorizer on context has write access to the organization of the role.
+func (s *RoleService) RemoveRoleUser(ctx context.Context, roleID, userID influxdb.ID) error {
+	r, err := s.s.FindRoleByID(ctx, roleID)
+	if err != nil {
+		return err
+	}
+
+	if err := authorizeWriteOrg(ctx, r.OrgID); err != nil {
+		return err
+	}
+
+	return s.s.RemoveRoleUser(ctx, roleID, userID)
+}
+
+// verifyRoles ensures that an authorization is allowed all of the permissions
+// of the roles ids.
+func verifyRoles(ctx context.Context, rs influxdb.RoleService, ids []influxdb.ID) error {
+	for _, id := range ids {
+		r, err := rs.FindRoleByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := VerifyPermissions(ctx, r.Permissions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -34,13 +34,15 @@ const InfiniteRetention = 0
 
 // Bucket is a bucket. 🎉
 type Bucket struct {
-	ID                  ID            `json:"id,omitempty"`
-	OrgID               ID            `json:"orgID,omitempty"`
-	Type                BucketType    `json:"type"`
-	Name                string        `json:"name"`
-	Description         string        `json:"description"`
-	RetentionPolicyName string        `json:"rp,omitempty"` // This to support v1 sources
-	RetentionPeriod     time.Duration `json:"retentionPeriod"`
+	ID                   ID                   `json:"id,omitempty"`
+	OrgID                ID                   `json:"orgID,omitempty"`
+	Type                 BucketType           `json:"type"`
+	Name                 string               `json:"name"`
+	Description          string               `json:"description"`
+	RetentionPolicyName  string               `json:"rp,omitempty"` // This to support v1 sources
+	RetentionPeriod      time.Duration        `json:"retentionPeriod"`
+	NonFiniteFloatPolicy NonFiniteFloatPolicy `json:"nonFiniteFloatPolicy,omitempty"`
+	TagNormalization     *TagNormalization    `json:"tagNormalization,omitempty"`
 	CRUDLog
 }
 
@@ -63,6 +65,113 @@ func ParseBucketType(s string) BucketType {
 	return BucketTypeUser
 }
 
+// NonFiniteFloatPolicy determines how writes to a bucket handle float field values of NaN, +Inf and -Inf.
+// The zero value is equivalent to NonFiniteFloatPolicyReject.
+type NonFiniteFloatPolicy string
+
+const (
+	// NonFiniteFloatPolicyReject rejects the write with an error for each offending line.
+	NonFiniteFloatPolicyReject NonFiniteFloatPolicy = "reject"
+	// NonFiniteFloatPolicyDrop discards the offending field values and writes the remainder.
+	NonFiniteFloatPolicyDrop NonFiniteFloatPolicy = "drop"
+	// NonFiniteFloatPolicyStoreInf stores +Inf and -Inf values. NaN values can not be stored
+	// by the float encoding of the storage engine, and are rejected as with NonFiniteFloatPolicyReject.
+	NonFiniteFloatPolicyStoreInf NonFiniteFloatPolicy = "store-inf"
+)
+
+// Valid returns an error if the policy is not a known policy.
+func (p NonFiniteFloatPolicy) Valid() error {
+	switch p {
+	case "", NonFiniteFloatPolicyReject, NonFiniteFloatPolicyDrop, NonFiniteFloatPolicyStoreInf:
+		return nil
+	}
+	return &Error{
+		Code: EInvalid,
+		Msg:  fmt.Sprintf("invalid non-finite float policy %q; valid policies are reject, drop and store-inf", string(p)),
+	}
+}
+
+// TagNormalization holds the rules applied to the tags of the points written to a bucket, so that
+// agents reporting the same series with different case or spelling, such as Host=WebA and
+// host=weba, do not create duplicate series.
+type TagNormalization struct {
+	// Tags restricts the rules to the tag keys, after lowercasing if LowercaseKeys is set.
+	// The rules apply to every tag if it is empty.
+	Tags []string `json:"tags,omitempty"`
+	// TrimSpace removes the leading and trailing white space of tag values.
+	TrimSpace bool `json:"trimSpace,omitempty"`
+	// Lowercase lowercases tag values.
+	Lowercase bool `json:"lowercase,omitempty"`
+	// LowercaseKeys lowercases tag keys.
+	LowercaseKeys bool `json:"lowercaseKeys,omitempty"`
+	// Synonyms maps tag values, once trimmed and lowercased, to the value stored instead.
+	Synonyms map[string]string `json:"synonyms,omitempty"`
+}
+
+// Valid returns an error if the rules contain an empty tag key or synonym.
+func (n *TagNormalization) Valid() error {
+	for _, k := range n.Tags {
+		if k == "" {
+			return &Error{
+				Code: EInvalid,
+				Msg:  "tag normalization tags must not be empty",
+			}
+		}
+	}
+	for from, to := range n.Synonyms {
+		if from == "" || to == "" {
+			return &Error{
+				Code: EInvalid,
+				Msg:  fmt.Sprintf("invalid tag normalization synonym %q to %q; synonyms must not be empty", from, to),
+			}
+		}
+	}
+	return nil
+}
+
+// IsZero returns true if the rules do not change any tag.
+func (n *TagNormalization) IsZero() bool {
+	return n == nil || (!n.TrimSpace && !n.Lowercase && !n.LowercaseKeys && len(n.Synonyms) == 0)
+}
+
+// NormalizeKey returns the tag key k normalized by the rules.
+func (n *TagNormalization) NormalizeKey(k string) string {
+	if n.LowercaseKeys {
+		return strings.ToLower(k)
+	}
+	return k
+}
+
+// NormalizeValue returns the value v of the tag key k normalized by the rules. The key must
+// already be normalized.
+func (n *TagNormalization) NormalizeValue(k, v string) string {
+	if !n.appliesTo(k) {
+		return v
+	}
+	if n.TrimSpace {
+		v = strings.TrimSpace(v)
+	}
+	if n.Lowercase {
+		v = strings.ToLower(v)
+	}
+	if s, ok := n.Synonyms[v]; ok {
+		v = s
+	}
+	return v
+}
+
+func (n *TagNormalization) appliesTo(k string) bool {
+	if len(n.Tags) == 0 {
+		return true
+	}
+	for _, t := range n.Tags {
+		if t == k {
+			return true
+		}
+	}
+	return false
+}
+
 // ops for buckets error and buckets op logs.
 var (
 	OpFindBucketByID = "FindBucketByID"
@@ -104,6 +213,11 @@ type BucketUpdate struct {
 	Name            *string        `json:"name,omitempty"`
 	Description     *string        `json:"description,omitempty"`
 	RetentionPeriod *time.Duration `json:"retentionPeriod,omitempty"`
+
+	NonFiniteFloatPolicy *NonFiniteFloatPolicy `json:"nonFiniteFloatPolicy,omitempty"`
+	// TagNormalization replaces the tag normalization rules of the bucket. Rules that
+	// change no tag remove them.
+	TagNormalization *TagNormalization `json:"tagNormalization,omitempty"`
 }
 
 // BucketFilter represents a set of filter that restrict the returned results.
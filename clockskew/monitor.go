@@ -0,0 +1,241 @@
+// Package clockskew detects jumps of the wall clock of the server and offsets
+// from an NTP server, refusing writes while the clock is behind the time it
+// already reached, since points written without a timestamp would otherwise
+// be stored out of order, or overwrite points written before the jump.
+package clockskew
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/check"
+	influxlogger "github.com/influxdata/influxdb/logger"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/storage"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// Config configures a Monitor.
+type Config struct {
+	// Interval is the interval between checks of the clock.
+	Interval time.Duration
+
+	// MaxBackwardJump is how far the wall clock may be behind the latest
+	// time it reached before writes are refused.
+	MaxBackwardJump time.Duration
+
+	// NTPServer is the optional address of an NTP server the clock is
+	// compared to, such as pool.ntp.org, and MaxNTPOffset the offset from it
+	// above which the health check fails.
+	NTPServer    string
+	MaxNTPOffset time.Duration
+}
+
+// Validate returns an error if the configuration can not be monitored.
+func (c Config) Validate() error {
+	if c.Interval <= 0 {
+		return fmt.Errorf("clock check interval must be positive, got %s", c.Interval)
+	}
+	return nil
+}
+
+// Monitor periodically compares the wall clock with the monotonic clock, and
+// optionally with an NTP server.
+type Monitor struct {
+	config Config
+
+	// Now returns the current time, with a monotonic clock reading.
+	Now func() time.Time
+
+	// QueryNTP returns the offset of the local clock from an NTP server.
+	QueryNTP func(ctx context.Context, server string) (time.Duration, error)
+
+	mu         sync.RWMutex
+	last       time.Time // time of the last check, with its monotonic reading
+	highWater  time.Time // latest wall clock time seen
+	behind     time.Duration
+	ntpQueried bool
+	ntpOffset  time.Duration
+	ntpErr     error
+
+	metrics *metrics
+	log     *zap.Logger
+}
+
+// NewMonitor returns a Monitor of the clock configured by c.
+func NewMonitor(log *zap.Logger, c Config) *Monitor {
+	return &Monitor{
+		config:   c,
+		Now:      time.Now,
+		QueryNTP: QueryNTP,
+		metrics:  newMetrics(),
+		log:      log,
+	}
+}
+
+// Run checks the clock, first when it is called and then every interval of
+// the configuration, until ctx is done. The configuration must be valid.
+func (m *Monitor) Run(ctx context.Context) {
+	log := m.log.With(
+		zap.String("service", "clock-check"),
+		influxlogger.DurationLiteral("interval", m.config.Interval),
+	)
+	log.Info("Starting")
+
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+	for {
+		m.update(ctx, log)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			log.Info("Stopping")
+			return
+		}
+	}
+}
+
+// Update compares the wall clock with the time elapsed on the monotonic clock
+// since the previous update, and with the NTP server if there is one.
+func (m *Monitor) Update(ctx context.Context) {
+	m.update(ctx, m.log)
+}
+
+func (m *Monitor) update(ctx context.Context, log *zap.Logger) {
+	now := m.Now()
+	wall := now.Round(0) // strips the monotonic clock reading
+
+	m.mu.Lock()
+	if !m.last.IsZero() {
+		// The monotonic clock is not affected by changes of the wall clock,
+		// so the difference of the clocks is the jump since the last check.
+		expected := m.last.Round(0).Add(now.Sub(m.last))
+		if jump := wall.Sub(expected); jump < -m.config.MaxBackwardJump || jump > m.config.MaxBackwardJump {
+			log.Warn("Wall clock jumped", zap.Duration("jump", jump), zap.Time("time", wall))
+		}
+	}
+	m.last = now
+	if wall.After(m.highWater) {
+		m.highWater = wall
+	}
+	m.behind = m.highWater.Sub(wall)
+	behind := m.behind
+	m.mu.Unlock()
+	m.metrics.Behind.Set(behind.Seconds())
+
+	if m.config.NTPServer == "" {
+		return
+	}
+	offset, err := m.QueryNTP(ctx, m.config.NTPServer)
+	m.mu.Lock()
+	m.ntpQueried, m.ntpOffset, m.ntpErr = true, offset, err
+	m.mu.Unlock()
+	if err != nil {
+		log.Warn("Failed to query NTP server", zap.String("server", m.config.NTPServer), zap.Error(err))
+		return
+	}
+	m.metrics.NTPOffset.Set(offset.Seconds())
+	if abs(offset) > m.config.MaxNTPOffset {
+		log.Warn("Clock offset from NTP server exceeds maximum", zap.String("server", m.config.NTPServer), zap.Duration("offset", offset))
+	}
+}
+
+// Err returns an error if writes must be refused, as the wall clock is behind
+// the latest time it reached by more than the maximum backward jump.
+func (m *Monitor) Err() error {
+	m.mu.RLock()
+	behind := m.behind
+	m.mu.RUnlock()
+	if m.config.MaxBackwardJump <= 0 || behind <= m.config.MaxBackwardJump {
+		return nil
+	}
+	return &influxdb.Error{
+		Code: influxdb.EUnavailable,
+		Msg: fmt.Sprintf("writes refused: the server clock jumped backward by %s, more than the maximum of %s; they resume once the clock reaches the time it had reached",
+			behind, m.config.MaxBackwardJump),
+	}
+}
+
+// CheckName returns the name of the health check of the clock.
+func (m *Monitor) CheckName() string { return "clock" }
+
+// Check implements check.Checker, failing if writes are refused or the offset
+// from the NTP server exceeds its maximum.
+func (m *Monitor) Check(ctx context.Context) check.Response {
+	resp := check.Response{Name: m.CheckName(), Status: check.StatusPass}
+	if err := m.Err(); err != nil {
+		resp.Status = check.StatusFail
+		resp.Message = err.Error()
+		return resp
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	switch {
+	case m.config.NTPServer == "":
+		resp.Message = fmt.Sprintf("clock behind the latest time it reached by %s", m.behind)
+	case !m.ntpQueried:
+		resp.Message = fmt.Sprintf("NTP server %s not queried yet", m.config.NTPServer)
+	case m.ntpErr != nil:
+		resp.Message = fmt.Sprintf("unable to query NTP server %s: %v", m.config.NTPServer, m.ntpErr)
+	case abs(m.ntpOffset) > m.config.MaxNTPOffset:
+		resp.Status = check.StatusFail
+		resp.Message = fmt.Sprintf("clock offset from NTP server %s is %s, more than the maximum of %s", m.config.NTPServer, m.ntpOffset, m.config.MaxNTPOffset)
+	default:
+		resp.Message = fmt.Sprintf("clock offset from NTP server %s is %s", m.config.NTPServer, m.ntpOffset)
+	}
+	return resp
+}
+
+// PointsWriter refuses writes while the Monitor returns an error.
+type PointsWriter struct {
+	Monitor *Monitor
+	storage.PointsWriter
+}
+
+// WritePoints writes the points unless the clock jumped backward.
+func (w *PointsWriter) WritePoints(ctx context.Context, points []models.Point) error {
+	if err := w.Monitor.Err(); err != nil {
+		return err
+	}
+	return w.PointsWriter.WritePoints(ctx, points)
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+const namespace = "clock"
+
+type metrics struct {
+	Behind    prometheus.Gauge
+	NTPOffset prometheus.Gauge
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		Behind: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "behind_seconds",
+			Help:      "Time the wall clock is behind the latest time it reached, after jumping backward.",
+		}),
+		NTPOffset: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "ntp_offset_seconds",
+			Help:      "Offset of the wall clock from the NTP server at the last check.",
+		}),
+	}
+}
+
+// PrometheusCollectors satisfies the prom.PrometheusCollector interface.
+func (m *Monitor) PrometheusCollectors() []prometheus.Collector {
+	return []prometheus.Collector{m.metrics.Behind, m.metrics.NTPOffset}
+}
@@ -0,0 +1,140 @@
+package clockskew_test
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/clockskew"
+	"github.com/influxdata/influxdb/kit/check"
+	"github.com/influxdata/influxdb/models"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestMonitor_BackwardJump(t *testing.T) {
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := clockskew.NewMonitor(zaptest.NewLogger(t), clockskew.Config{MaxBackwardJump: time.Minute})
+	m.Now = func() time.Time { return now }
+
+	var written int
+	w := &clockskew.PointsWriter{Monitor: m, PointsWriter: pointsWriterFunc(func(ctx context.Context, points []models.Point) error {
+		written++
+		return nil
+	})}
+
+	m.Update(context.Background())
+	now = now.Add(-30 * time.Second)
+	m.Update(context.Background())
+	if err := w.WritePoints(context.Background(), nil); err != nil || written != 1 {
+		t.Fatalf("unexpected write error within the maximum jump: %v", err)
+	}
+	if resp := m.Check(context.Background()); resp.Status != check.StatusPass {
+		t.Fatalf("unexpected health: %+v", resp)
+	}
+
+	// Writes are refused while the clock is too far behind.
+	now = now.Add(-time.Hour)
+	m.Update(context.Background())
+	if err := w.WritePoints(context.Background(), nil); influxdb.ErrorCode(err) != influxdb.EUnavailable || written != 1 {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+	if resp := m.Check(context.Background()); resp.Status != check.StatusFail {
+		t.Fatalf("unexpected health: %+v", resp)
+	}
+
+	// Writes resume once the clock catches up.
+	now = now.Add(time.Hour)
+	m.Update(context.Background())
+	if err := w.WritePoints(context.Background(), nil); err != nil || written != 2 {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+}
+
+func TestMonitor_NTP(t *testing.T) {
+	m := clockskew.NewMonitor(zaptest.NewLogger(t), clockskew.Config{NTPServer: "ntp.example.com", MaxNTPOffset: time.Second})
+	offset, err := 2*time.Second, error(nil)
+	m.QueryNTP = func(ctx context.Context, server string) (time.Duration, error) { return offset, err }
+
+	// The check passes until the server is queried.
+	if resp := m.Check(context.Background()); resp.Status != check.StatusPass {
+		t.Fatalf("unexpected health before the first query: %+v", resp)
+	}
+
+	m.Update(context.Background())
+	if resp := m.Check(context.Background()); resp.Status != check.StatusFail {
+		t.Fatalf("unexpected health: %+v", resp)
+	}
+
+	// An unreachable server does not fail the check.
+	err = errors.New("timeout")
+	m.Update(context.Background())
+	if resp := m.Check(context.Background()); resp.Status != check.StatusPass {
+		t.Fatalf("unexpected health: %+v", resp)
+	}
+
+	offset, err = 10*time.Millisecond, nil
+	m.Update(context.Background())
+	if resp := m.Check(context.Background()); resp.Status != check.StatusPass {
+		t.Fatalf("unexpected health: %+v", resp)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if err := (clockskew.Config{Interval: interval}).Validate(); err == nil {
+			t.Fatalf("expected an error for interval %s", interval)
+		}
+	}
+	if err := (clockskew.Config{Interval: time.Second}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueryNTP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// The server answers with its clock 10s ahead of the local clock.
+	go func() {
+		buf := make([]byte, 48)
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil || n != 48 {
+			return
+		}
+		resp := make([]byte, 48)
+		resp[0] = 3<<3 | 4
+		resp[1] = 2
+		copy(resp[24:32], buf[40:48])
+		ts := ntpTime(time.Now().Add(10 * time.Second))
+		binary.BigEndian.PutUint64(resp[32:], ts)
+		binary.BigEndian.PutUint64(resp[40:], ts)
+		conn.WriteTo(resp, addr)
+	}()
+
+	offset, err := clockskew.QueryNTP(context.Background(), conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset < 9*time.Second || offset > 11*time.Second {
+		t.Fatalf("unexpected offset: %s", offset)
+	}
+}
+
+func ntpTime(t time.Time) uint64 {
+	secs := uint64(t.Unix() + 2208988800)
+	frac := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return secs<<32 | frac
+}
+
+type pointsWriterFunc func(ctx context.Context, points []models.Point) error
+
+func (f pointsWriterFunc) WritePoints(ctx context.Context, points []models.Point) error {
+	return f(ctx, points)
+}
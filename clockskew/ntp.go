@@ -0,0 +1,92 @@
+package clockskew
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+)
+
+// ntpEpochOffset is the number of seconds between the NTP epoch, 1900, and
+// the Unix epoch.
+const ntpEpochOffset = 2208988800
+
+// DefaultNTPTimeout is the timeout of a query of an NTP server when the
+// context has no deadline.
+const DefaultNTPTimeout = 5 * time.Second
+
+// QueryNTP returns the offset of the local clock from the NTP server at
+// address, with the port 123 if it has none, using a single SNTP request.
+func QueryNTP(ctx context.Context, address string) (time.Duration, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "123")
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", address)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(DefaultNTPTimeout)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return 0, err
+	}
+
+	// The request is a client (mode 3) message of version 3 with the local
+	// transmit time, which the server returns as the originate time.
+	req := make([]byte, 48)
+	req[0] = 3<<3 | 3
+	sent := time.Now()
+	binary.BigEndian.PutUint64(req[40:], toNTPTime(sent))
+	if _, err := conn.Write(req); err != nil {
+		return 0, err
+	}
+
+	resp := make([]byte, 48)
+	n, err := conn.Read(resp)
+	if err != nil {
+		return 0, err
+	}
+	received := time.Now()
+	return ntpOffset(resp[:n], sent, received)
+}
+
+// ntpOffset returns the clock offset of the NTP response to a request sent
+// and received at the given local times.
+func ntpOffset(resp []byte, sent, received time.Time) (time.Duration, error) {
+	if len(resp) < 48 {
+		return 0, errors.New("short NTP response")
+	}
+	if mode := resp[0] & 0x7; mode != 4 {
+		return 0, errors.New("unexpected NTP response mode")
+	}
+	if stratum := resp[1]; stratum == 0 {
+		return 0, errors.New("NTP server is not synchronized")
+	}
+	if binary.BigEndian.Uint64(resp[24:]) != toNTPTime(sent) {
+		return 0, errors.New("NTP response does not match the request")
+	}
+	serverReceived := fromNTPTime(binary.BigEndian.Uint64(resp[32:]))
+	serverSent := fromNTPTime(binary.BigEndian.Uint64(resp[40:]))
+	return (serverReceived.Sub(sent) + serverSent.Sub(received)) / 2, nil
+}
+
+// toNTPTime returns the 64-bit NTP timestamp of t, 32 bits of seconds since
+// 1900 and 32 bits of fraction.
+func toNTPTime(t time.Time) uint64 {
+	secs := uint64(t.Unix() + ntpEpochOffset)
+	frac := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return secs<<32 | frac
+}
+
+// fromNTPTime returns the time of a 64-bit NTP timestamp.
+func fromNTPTime(ts uint64) time.Time {
+	secs := int64(ts>>32) - ntpEpochOffset
+	nanos := int64((ts & 0xffffffff) * uint64(time.Second) >> 32)
+	return time.Unix(secs, nanos)
+}
@@ -60,6 +60,11 @@ var authCreateFlags struct {
 
 	writeNotificationEndpointPermission bool
 	readNotificationEndpointPermission  bool
+
+	allowMeasurements []string
+	denyMeasurements  []string
+
+	roles []string
 }
 
 func authCreateCmd() *cobra.Command {
@@ -102,6 +107,11 @@ func authCreateCmd() *cobra.Command {
 	cmd.Flags().BoolVarP(&authCreateFlags.writeCheckPermission, "write-checks", "", false, "Grants the permission to create checks")
 	cmd.Flags().BoolVarP(&authCreateFlags.readCheckPermission, "read-checks", "", false, "Grants the permission to read checks")
 
+	cmd.Flags().StringArrayVarP(&authCreateFlags.allowMeasurements, "allow-measurement", "", []string{}, "Only allows writing to this measurement with the token")
+	cmd.Flags().StringArrayVarP(&authCreateFlags.denyMeasurements, "deny-measurement", "", []string{}, "Rejects writes to this measurement with the token")
+
+	cmd.Flags().StringArrayVarP(&authCreateFlags.roles, "role", "", []string{}, "The ID of a role whose permissions are granted")
+
 	return cmd
 }
 
@@ -215,10 +225,26 @@ func authorizationCreateF(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	var roleIDs []platform.ID
+	for _, r := range authCreateFlags.roles {
+		var id platform.ID
+		if err := id.DecodeFromString(r); err != nil {
+			return err
+		}
+		roleIDs = append(roleIDs, id)
+	}
+
 	authorization := &platform.Authorization{
 		Permissions: permissions,
+		RoleIDs:     roleIDs,
 		OrgID:       orgID,
 	}
+	if len(authCreateFlags.allowMeasurements) > 0 || len(authCreateFlags.denyMeasurements) > 0 {
+		authorization.WritePolicy = &platform.WritePolicy{
+			AllowMeasurements: authCreateFlags.allowMeasurements,
+			DenyMeasurements:  authCreateFlags.denyMeasurements,
+		}
+	}
 
 	if userName := authCreateFlags.user; userName != "" {
 		userSvc, err := newUserService()
@@ -250,6 +276,7 @@ func authorizationCreateF(cmd *cobra.Command, args []string) error {
 		"Status",
 		"UserID",
 		"Permissions",
+		"Roles",
 	)
 
 	ps := []string{}
@@ -257,12 +284,18 @@ func authorizationCreateF(cmd *cobra.Command, args []string) error {
 		ps = append(ps, p.String())
 	}
 
+	rs := []string{}
+	for _, id := range authorization.RoleIDs {
+		rs = append(rs, id.String())
+	}
+
 	w.Write(map[string]interface{}{
 		"ID":          authorization.ID.String(),
 		"Token":       authorization.Token,
 		"Status":      authorization.Status,
 		"UserID":      authorization.UserID.String(),
 		"Permissions": ps,
+		"Roles":       rs,
 	})
 
 	w.Flush()
@@ -4,11 +4,11 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
 
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/bolt"
 	"github.com/influxdata/influxdb/http"
+	"github.com/influxdata/influxdb/internal/backup"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"go.uber.org/multierr"
@@ -21,8 +21,15 @@ func cmdBackup(f *globalFlags, opt genericCLIOpts) *cobra.Command {
 		`Backs up data and meta data for the running InfluxDB instance.
 Downloaded files are written to the directory indicated by --path.
 The target directory, and any parent directories, are created automatically.
-Data file have extension .tsm; meta data is written to %s in the same directory.`,
-		bolt.DefaultFilename)
+Data file have extension .tsm; meta data is written to %s in the same directory.
+
+The files are listed, with their size and checksum, in %s, which restore
+uses to verify the backup before touching the data directory. Use --compression
+zstd to compress the files, and --encryption-key-file with the path of a file
+holding a 32 bytes key, raw or hex encoded, to encrypt them with AES-GCM. The
+same key must be given to restore, which checks it against the key ID
+recorded in the manifest.`,
+		bolt.DefaultFilename, backup.ManifestFilename)
 
 	opts := flagOpts{
 		{
@@ -33,6 +40,17 @@ Data file have extension .tsm; meta data is written to %s in the same directory.
 			Desc:     "directory path to write backup files to",
 			Required: true,
 		},
+		{
+			DestP:   &backupFlags.Compression,
+			Flag:    "compression",
+			Default: backup.CompressionNone,
+			Desc:    "compression of the backup files, none or zstd",
+		},
+		{
+			DestP: &backupFlags.EncryptionKeyFile,
+			Flag:  "encryption-key-file",
+			Desc:  "path of a file holding the 32 bytes key to encrypt the backup files with",
+		},
 	}
 	opts.mustRegister(cmd)
 
@@ -40,7 +58,9 @@ Data file have extension .tsm; meta data is written to %s in the same directory.
 }
 
 var backupFlags struct {
-	Path string
+	Path              string
+	Compression       string
+	EncryptionKeyFile string
 }
 
 func init() {
@@ -71,6 +91,18 @@ func backupF(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("must specify path")
 	}
 
+	opts := backup.Options{Compression: backupFlags.Compression}
+	if backupFlags.EncryptionKeyFile != "" {
+		key, err := backup.ReadKeyFile(backupFlags.EncryptionKeyFile)
+		if err != nil {
+			return fmt.Errorf("unable to read encryption key: %w", err)
+		}
+		opts.Key = key
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
 	err := os.MkdirAll(backupFlags.Path, 0777)
 	if err != nil && !os.IsExist(err) {
 		return err
@@ -88,19 +120,24 @@ func backupF(cmd *cobra.Command, args []string) error {
 
 	fmt.Printf("Backup ID %d contains %d files\n", id, len(backupFilenames))
 
+	files := make([]backup.File, 0, len(backupFilenames))
 	for _, backupFilename := range backupFilenames {
-		dest := filepath.Join(backupFlags.Path, backupFilename)
-		w, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+		w, err := backup.Create(backupFlags.Path, backupFilename, opts)
 		if err != nil {
 			return err
 		}
 		err = backupService.FetchBackupFile(ctx, id, backupFilename, w)
 		if err != nil {
-			return multierr.Append(fmt.Errorf("error fetching file %s: %v", backupFilename, err), w.Close())
+			return multierr.Append(fmt.Errorf("error fetching file %s: %w", backupFilename, err), w.Close())
 		}
 		if err = w.Close(); err != nil {
 			return err
 		}
+		files = append(files, w.File())
+	}
+
+	if err := backup.WriteManifest(backupFlags.Path, files); err != nil {
+		return fmt.Errorf("error writing manifest: %w", err)
 	}
 
 	fmt.Printf("Backup complete")
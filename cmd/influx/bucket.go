@@ -30,6 +30,9 @@ type cmdBucketBuilder struct {
 	description string
 	org         organization
 	retention   time.Duration
+	nonFinite   string
+
+	tagNormalization influxdb.TagNormalization
 }
 
 func newCmdBucketBuilder(svcsFn bucketSVCsFn, opts genericCLIOpts) *cmdBucketBuilder {
@@ -72,12 +75,14 @@ func (b *cmdBucketBuilder) cmdCreate() *cobra.Command {
 
 	cmd.Flags().StringVarP(&b.description, "description", "d", "", "Description of bucket that will be created")
 	cmd.Flags().DurationVarP(&b.retention, "retention", "r", 0, "Duration in nanoseconds data will live in bucket")
+	cmd.Flags().StringVar(&b.nonFinite, "non-finite-floats", "", "How writes handle NaN and ±Inf float values: reject, drop or store-inf (NaN values are always rejected)")
+	b.registerTagNormalizationFlags(cmd)
 	b.org.register(cmd, false)
 
 	return cmd
 }
 
-func (b *cmdBucketBuilder) cmdCreateRunEFn(*cobra.Command, []string) error {
+func (b *cmdBucketBuilder) cmdCreateRunEFn(cmd *cobra.Command, args []string) error {
 	if err := b.org.validOrgFlags(); err != nil {
 		return err
 	}
@@ -88,9 +93,11 @@ func (b *cmdBucketBuilder) cmdCreateRunEFn(*cobra.Command, []string) error {
 	}
 
 	bkt := &influxdb.Bucket{
-		Name:            b.name,
-		Description:     b.description,
-		RetentionPeriod: b.retention,
+		Name:                 b.name,
+		Description:          b.description,
+		RetentionPeriod:      b.retention,
+		NonFiniteFloatPolicy: influxdb.NonFiniteFloatPolicy(b.nonFinite),
+		TagNormalization:     b.tagNormalizationUpdate(cmd),
 	}
 	bkt.OrgID, err = b.org.getID(orgSVC)
 	if err != nil {
@@ -98,7 +105,7 @@ func (b *cmdBucketBuilder) cmdCreateRunEFn(*cobra.Command, []string) error {
 	}
 
 	if err := bktSVC.CreateBucket(context.Background(), bkt); err != nil {
-		return fmt.Errorf("failed to create bucket: %v", err)
+		return fmt.Errorf("failed to create bucket: %w", err)
 	}
 
 	w := b.newTabWriter()
@@ -132,17 +139,17 @@ func (b *cmdBucketBuilder) cmdDeleteRunEFn(cmd *cobra.Command, args []string) er
 
 	var id influxdb.ID
 	if err := id.DecodeFromString(b.id); err != nil {
-		return fmt.Errorf("failed to decode bucket id %q: %v", b.id, err)
+		return fmt.Errorf("failed to decode bucket id %q: %w", b.id, err)
 	}
 
 	ctx := context.Background()
 	bkt, err := bktSVC.FindBucketByID(ctx, id)
 	if err != nil {
-		return fmt.Errorf("failed to find bucket with id %q: %v", id, err)
+		return fmt.Errorf("failed to find bucket with id %q: %w", id, err)
 	}
 
 	if err := bktSVC.DeleteBucket(ctx, id); err != nil {
-		return fmt.Errorf("failed to delete bucket with id %q: %v", id, err)
+		return fmt.Errorf("failed to delete bucket with id %q: %w", id, err)
 	}
 
 	w := b.newTabWriter()
@@ -198,14 +205,14 @@ func (b *cmdBucketBuilder) cmdFindRunEFn(cmd *cobra.Command, args []string) erro
 	if b.id != "" {
 		id, err := influxdb.IDFromString(b.id)
 		if err != nil {
-			return fmt.Errorf("failed to decode bucket id %q: %v", b.id, err)
+			return fmt.Errorf("failed to decode bucket id %q: %w", b.id, err)
 		}
 		filter.ID = id
 	}
 	if b.org.id != "" {
 		orgID, err := influxdb.IDFromString(b.org.id)
 		if err != nil {
-			return fmt.Errorf("failed to decode org id %q: %v", b.org.id, err)
+			return fmt.Errorf("failed to decode org id %q: %w", b.org.id, err)
 		}
 		filter.OrganizationID = orgID
 	}
@@ -253,6 +260,8 @@ func (b *cmdBucketBuilder) cmdUpdate() *cobra.Command {
 	cmd.Flags().StringVarP(&b.description, "description", "d", "", "Description of bucket that will be created")
 	cmd.MarkFlagRequired("id")
 	cmd.Flags().DurationVarP(&b.retention, "retention", "r", 0, "New duration data will live in bucket")
+	cmd.Flags().StringVar(&b.nonFinite, "non-finite-floats", "", "How writes handle NaN and ±Inf float values: reject, drop or store-inf (NaN values are always rejected)")
+	b.registerTagNormalizationFlags(cmd)
 
 	return cmd
 }
@@ -265,7 +274,7 @@ func (b *cmdBucketBuilder) cmdUpdateRunEFn(cmd *cobra.Command, args []string) er
 
 	var id influxdb.ID
 	if err := id.DecodeFromString(b.id); err != nil {
-		return fmt.Errorf("failed to decode bucket id %q: %v", b.id, err)
+		return fmt.Errorf("failed to decode bucket id %q: %w", b.id, err)
 	}
 
 	var update influxdb.BucketUpdate
@@ -278,10 +287,15 @@ func (b *cmdBucketBuilder) cmdUpdateRunEFn(cmd *cobra.Command, args []string) er
 	if b.retention != 0 {
 		update.RetentionPeriod = &b.retention
 	}
+	if b.nonFinite != "" {
+		policy := influxdb.NonFiniteFloatPolicy(b.nonFinite)
+		update.NonFiniteFloatPolicy = &policy
+	}
+	update.TagNormalization = b.tagNormalizationUpdate(cmd)
 
 	bkt, err := bktSVC.UpdateBucket(context.Background(), id, update)
 	if err != nil {
-		return fmt.Errorf("failed to update bucket: %v", err)
+		return fmt.Errorf("failed to update bucket: %w", err)
 	}
 
 	w := b.newTabWriter()
@@ -297,6 +311,28 @@ func (b *cmdBucketBuilder) cmdUpdateRunEFn(cmd *cobra.Command, args []string) er
 	return nil
 }
 
+var tagNormalizationFlags = []string{"normalize-tags", "trim-tag-values", "lowercase-tag-values", "lowercase-tag-keys", "tag-synonyms"}
+
+func (b *cmdBucketBuilder) registerTagNormalizationFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&b.tagNormalization.Tags, "normalize-tags", nil, "Tag keys normalized on write; all tags if empty")
+	cmd.Flags().BoolVar(&b.tagNormalization.TrimSpace, "trim-tag-values", false, "Trim white space around tag values on write")
+	cmd.Flags().BoolVar(&b.tagNormalization.Lowercase, "lowercase-tag-values", false, "Lowercase tag values on write")
+	cmd.Flags().BoolVar(&b.tagNormalization.LowercaseKeys, "lowercase-tag-keys", false, "Lowercase tag keys on write")
+	cmd.Flags().StringToStringVar(&b.tagNormalization.Synonyms, "tag-synonyms", nil, "Tag values replaced on write, as from=to pairs")
+}
+
+// tagNormalizationUpdate returns the tag normalization rules of the flags if any of them is
+// set. Together, the flags replace the rules of the bucket.
+func (b *cmdBucketBuilder) tagNormalizationUpdate(cmd *cobra.Command) *influxdb.TagNormalization {
+	for _, f := range tagNormalizationFlags {
+		if cmd.Flags().Changed(f) {
+			n := b.tagNormalization
+			return &n
+		}
+	}
+	return nil
+}
+
 func newBucketSVCs() (influxdb.BucketService, influxdb.OrganizationService, error) {
 	httpClient, err := newHTTPClient()
 	if err != nil {
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/http"
+	"github.com/spf13/cobra"
+)
+
+type compactionSVCFn func() (influxdb.CompactionService, error)
+
+func cmdCompaction(f *globalFlags, opt genericCLIOpts) *cobra.Command {
+	builder := newCmdCompactionBuilder(newCompactionService, opt)
+	builder.globalFlags = f
+	return builder.cmd()
+}
+
+type cmdCompactionBuilder struct {
+	genericCLIOpts
+	*globalFlags
+
+	svcFn compactionSVCFn
+
+	timeout time.Duration
+}
+
+func newCmdCompactionBuilder(svcFn compactionSVCFn, opt genericCLIOpts) *cmdCompactionBuilder {
+	return &cmdCompactionBuilder{
+		genericCLIOpts: opt,
+		svcFn:          svcFn,
+	}
+}
+
+func (b *cmdCompactionBuilder) cmd() *cobra.Command {
+	cmd := b.newCmd("compaction", nil)
+	cmd.Short = "Storage engine compaction commands"
+	cmd.Run = seeHelp
+	cmd.AddCommand(
+		b.cmdPause(),
+		b.cmdResume(),
+		b.cmdStatus(),
+		b.cmdReport(),
+	)
+	return cmd
+}
+
+func (b *cmdCompactionBuilder) cmdPause() *cobra.Command {
+	cmd := b.newCmd("pause", b.cmdPauseRunEFn)
+	cmd.Short = "Pause storage engine compactions"
+	cmd.Long = `Pauses level compactions of the storage engine, for example while taking
+a filesystem snapshot or during a maintenance window. Cache snapshots keep
+running so that writes are still persisted. If --timeout is set, compactions
+resume automatically once it has elapsed.`
+	cmd.Flags().DurationVar(&b.timeout, "timeout", 0, "Duration after which compactions resume automatically (e.g. 30m); 0 pauses until resumed")
+
+	return cmd
+}
+
+func (b *cmdCompactionBuilder) cmdPauseRunEFn(cmd *cobra.Command, args []string) error {
+	if b.timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+
+	svc, err := b.svcFn()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if err := svc.PauseCompactions(ctx, b.timeout); err != nil {
+		return fmt.Errorf("failed to pause compactions: %w", err)
+	}
+
+	return b.printStatus(ctx, svc)
+}
+
+func (b *cmdCompactionBuilder) cmdResume() *cobra.Command {
+	cmd := b.newCmd("resume", b.cmdResumeRunEFn)
+	cmd.Short = "Resume paused storage engine compactions"
+
+	return cmd
+}
+
+func (b *cmdCompactionBuilder) cmdResumeRunEFn(cmd *cobra.Command, args []string) error {
+	svc, err := b.svcFn()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if err := svc.ResumeCompactions(ctx); err != nil {
+		return fmt.Errorf("failed to resume compactions: %w", err)
+	}
+
+	return b.printStatus(ctx, svc)
+}
+
+func (b *cmdCompactionBuilder) cmdStatus() *cobra.Command {
+	cmd := b.newCmd("status", b.cmdStatusRunEFn)
+	cmd.Short = "Show whether storage engine compactions are paused"
+
+	return cmd
+}
+
+func (b *cmdCompactionBuilder) cmdStatusRunEFn(cmd *cobra.Command, args []string) error {
+	svc, err := b.svcFn()
+	if err != nil {
+		return err
+	}
+
+	return b.printStatus(context.Background(), svc)
+}
+
+func (b *cmdCompactionBuilder) cmdReport() *cobra.Command {
+	cmd := b.newCmd("report", b.cmdReportRunEFn)
+	cmd.Short = "Show the bytes recently written by storage engine compactions"
+	cmd.Long = `Shows the number of cache snapshots and compactions of each level over the
+compaction report window of the storage engine, and the bytes they wrote. The
+write amplification is the number of bytes written by snapshots and
+compactions for each byte written by snapshots.`
+
+	return cmd
+}
+
+func (b *cmdCompactionBuilder) cmdReportRunEFn(cmd *cobra.Command, args []string) error {
+	svc, err := b.svcFn()
+	if err != nil {
+		return err
+	}
+
+	report, err := svc.CompactionReport(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to retrieve compaction report: %w", err)
+	}
+
+	w := b.newTabWriter()
+	w.WriteHeaders("Level", "Compactions", "BytesWritten")
+	for _, l := range report.Levels {
+		w.Write(map[string]interface{}{
+			"Level":        l.Level,
+			"Compactions":  l.Compactions,
+			"BytesWritten": l.BytesWritten,
+		})
+	}
+	w.Flush()
+
+	fmt.Fprintf(b.w, "Write amplification since %s: %.2f\n", report.Since.Format(time.RFC3339), report.WriteAmplification)
+	return nil
+}
+
+func (b *cmdCompactionBuilder) printStatus(ctx context.Context, svc influxdb.CompactionService) error {
+	status, err := svc.CompactionStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve compaction status: %w", err)
+	}
+
+	var resumeAt string
+	if status.ResumeAt != nil {
+		resumeAt = status.ResumeAt.Format(time.RFC3339)
+	}
+
+	w := b.newTabWriter()
+	w.WriteHeaders("Paused", "ResumeAt")
+	w.Write(map[string]interface{}{
+		"Paused":   status.Paused,
+		"ResumeAt": resumeAt,
+	})
+	w.Flush()
+
+	return nil
+}
+
+func newCompactionService() (influxdb.CompactionService, error) {
+	if flags.local {
+		return nil, fmt.Errorf("local flag not supported for compaction command")
+	}
+
+	httpClient, err := newHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	return &http.CompactionService{Client: httpClient}, nil
+}
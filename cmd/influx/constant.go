@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/http"
+	"github.com/spf13/cobra"
+)
+
+type constantSVCsFn func() (influxdb.ConstantService, influxdb.OrganizationService, error)
+
+func cmdConstant(f *globalFlags, opt genericCLIOpts) *cobra.Command {
+	builder := newCmdConstantBuilder(newConstantSVCs, opt)
+	builder.globalFlags = f
+	return builder.cmd()
+}
+
+type cmdConstantBuilder struct {
+	genericCLIOpts
+	*globalFlags
+
+	svcFn constantSVCsFn
+
+	name     string
+	value    string
+	isString bool
+	org      organization
+}
+
+func newCmdConstantBuilder(svcsFn constantSVCsFn, opt genericCLIOpts) *cmdConstantBuilder {
+	return &cmdConstantBuilder{
+		genericCLIOpts: opt,
+		svcFn:          svcsFn,
+	}
+}
+
+func (b *cmdConstantBuilder) cmd() *cobra.Command {
+	cmd := b.newCmd("constant", nil)
+	cmd.Short = "Organization constant management commands"
+	cmd.Long = `Organization constant management commands.
+
+The constants of an organization are available to all of its Flux queries and
+tasks as the properties of org.constants, such as:
+
+  from(bucket: "sla") |> filter(fn: (r) => r._value < org.constants.threshold)`
+	cmd.Run = seeHelp
+	cmd.AddCommand(
+		b.cmdDelete(),
+		b.cmdFind(),
+		b.cmdUpdate(),
+	)
+	return cmd
+}
+
+func (b *cmdConstantBuilder) cmdUpdate() *cobra.Command {
+	cmd := b.newCmd("update", b.cmdUpdateRunEFn)
+	cmd.Aliases = []string{"set"}
+	cmd.Short = "Update constant"
+	cmd.Long = `Update the constant with the given name, creating it if it does not exist.
+
+The value is a JSON string, number, boolean, or array of values of one of
+these types, such as 99.5, 42, '"paris"' or '["paris", "london"]'. Numbers
+without a decimal point are integers. Use --string to set a string value
+without quoting it.`
+	cmd.Flags().StringVarP(&b.name, "name", "n", "", "The constant name (required)")
+	cmd.Flags().StringVarP(&b.value, "value", "v", "", "The JSON value of the constant (required)")
+	cmd.Flags().BoolVar(&b.isString, "string", false, "Set the value as a string rather than parsing it as JSON")
+	cmd.MarkFlagRequired("name")
+	cmd.MarkFlagRequired("value")
+	b.org.register(cmd, false)
+
+	return cmd
+}
+
+func (b *cmdConstantBuilder) cmdUpdateRunEFn(cmd *cobra.Command, args []string) error {
+	constSVC, orgSVC, err := b.svcFn()
+	if err != nil {
+		return err
+	}
+	orgID, err := b.org.getID(orgSVC)
+	if err != nil {
+		return err
+	}
+
+	var v interface{} = b.value
+	if !b.isString {
+		if v, err = influxdb.UnmarshalConstant([]byte(b.value)); err != nil {
+			return fmt.Errorf("invalid value %q, use --string to set a string: %w", b.value, err)
+		}
+	}
+
+	if err := constSVC.PatchConstants(context.Background(), orgID, map[string]interface{}{b.name: v}); err != nil {
+		return fmt.Errorf("failed to update constant %q: %w", b.name, err)
+	}
+
+	w := b.newTabWriter()
+	w.WriteHeaders("Name", "Value", "OrgID", "Updated")
+	w.Write(map[string]interface{}{
+		"Name":    b.name,
+		"Value":   formatConstant(v),
+		"OrgID":   orgID,
+		"Updated": true,
+	})
+	w.Flush()
+
+	return nil
+}
+
+func (b *cmdConstantBuilder) cmdDelete() *cobra.Command {
+	cmd := b.newCmd("delete", b.cmdDeleteRunEFn)
+	cmd.Short = "Delete constant"
+
+	cmd.Flags().StringVarP(&b.name, "name", "n", "", "The constant name (required)")
+	cmd.MarkFlagRequired("name")
+	b.org.register(cmd, false)
+
+	return cmd
+}
+
+func (b *cmdConstantBuilder) cmdDeleteRunEFn(cmd *cobra.Command, args []string) error {
+	constSVC, orgSVC, err := b.svcFn()
+	if err != nil {
+		return err
+	}
+	orgID, err := b.org.getID(orgSVC)
+	if err != nil {
+		return err
+	}
+
+	if err := constSVC.DeleteConstants(context.Background(), orgID, b.name); err != nil {
+		return fmt.Errorf("failed to delete constant %q: %w", b.name, err)
+	}
+
+	w := b.newTabWriter()
+	w.WriteHeaders("Name", "OrgID", "Deleted")
+	w.Write(map[string]interface{}{
+		"Name":    b.name,
+		"OrgID":   orgID,
+		"Deleted": true,
+	})
+	w.Flush()
+
+	return nil
+}
+
+func (b *cmdConstantBuilder) cmdFind() *cobra.Command {
+	cmd := b.newCmd("find", b.cmdFindRunEFn)
+	cmd.Aliases = []string{"list", "ls"}
+	cmd.Short = "Find constants"
+	b.org.register(cmd, false)
+
+	return cmd
+}
+
+func (b *cmdConstantBuilder) cmdFindRunEFn(cmd *cobra.Command, args []string) error {
+	constSVC, orgSVC, err := b.svcFn()
+	if err != nil {
+		return err
+	}
+	orgID, err := b.org.getID(orgSVC)
+	if err != nil {
+		return err
+	}
+
+	constants, err := constSVC.FindConstants(context.Background(), orgID)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve constants: %w", err)
+	}
+
+	names := make([]string, 0, len(constants))
+	for name := range constants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := b.newTabWriter()
+	w.WriteHeaders("Name", "Value", "OrganizationID")
+	for _, name := range names {
+		w.Write(map[string]interface{}{
+			"Name":           name,
+			"Value":          formatConstant(constants[name]),
+			"OrganizationID": orgID,
+		})
+	}
+	w.Flush()
+
+	return nil
+}
+
+// formatConstant returns the JSON encoding of the value of a constant.
+func formatConstant(v interface{}) string {
+	b, err := influxdb.MarshalConstant(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func newConstantSVCs() (influxdb.ConstantService, influxdb.OrganizationService, error) {
+	httpClient, err := newHTTPClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	orgSvc := &http.OrganizationService{Client: httpClient}
+
+	return &http.ConstantService{Client: httpClient}, orgSvc, nil
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/ast"
+	"github.com/influxdata/flux/lang"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/http"
+	"github.com/influxdata/influxdb/query"
+	"github.com/spf13/cobra"
+)
+
+// Formats a dashboard is rendered to.
+const (
+	renderHTML = "html"
+	renderPNG  = "png"
+)
+
+// dashboardQueryFn executes a query of a dashboard of the organization.
+type dashboardQueryFn func(ctx context.Context, orgID influxdb.ID, q string, extern *ast.File) (flux.ResultIterator, error)
+
+type dashboardSVCsFn func() (influxdb.DashboardService, dashboardQueryFn, error)
+
+func cmdDashboard(f *globalFlags, opt genericCLIOpts) *cobra.Command {
+	builder := newCmdDashboardBuilder(newDashboardSVCs, opt)
+	builder.globalFlags = f
+	return builder.cmd()
+}
+
+type cmdDashboardBuilder struct {
+	genericCLIOpts
+	*globalFlags
+
+	svcFn dashboardSVCsFn
+
+	id      string
+	render  string
+	start   string
+	stop    string
+	file    string
+	browser string
+
+	now func() time.Time
+}
+
+func newCmdDashboardBuilder(svcsFn dashboardSVCsFn, opt genericCLIOpts) *cmdDashboardBuilder {
+	return &cmdDashboardBuilder{
+		genericCLIOpts: opt,
+		svcFn:          svcsFn,
+		now:            time.Now,
+	}
+}
+
+func (b *cmdDashboardBuilder) cmd() *cobra.Command {
+	cmd := b.newCmd("dashboards", nil)
+	cmd.Aliases = []string{"dashboard"}
+	cmd.Short = "Dashboard management commands"
+	cmd.Run = seeHelp
+	cmd.AddCommand(
+		b.cmdExport(),
+	)
+	return cmd
+}
+
+func (b *cmdDashboardBuilder) cmdExport() *cobra.Command {
+	cmd := b.newCmd("export", b.cmdExportRunEFn)
+	cmd.Short = "Export a dashboard"
+	cmd.Long = `Export a dashboard and the views of its cells as JSON, or render it.
+
+With --render html, the queries of every cell are executed over the time range
+[start, stop) and the dashboard is rendered to a static HTML report, with a
+chart for each graph, the last value for single stats and gauges, and the
+first rows of the results for the other cells. With --render png, the report
+is captured as a PNG image with a headless Chrome or Chromium, found in the
+PATH unless given with --browser, so that reports can be mailed on a schedule.
+
+The range is available to the queries as v.timeRangeStart and v.timeRangeStop,
+and v.windowPeriod is set to a fraction of it. Start and stop are RFC3339
+times, or durations relative to now such as -24h.`
+	cmd.Flags().StringVarP(&b.id, "id", "i", "", "The dashboard ID (required)")
+	cmd.Flags().StringVar(&b.render, "render", "", "Render the dashboard to html or png instead of exporting it as JSON")
+	cmd.Flags().StringVar(&b.start, "start", "-1h", "Start of the time range of the rendered queries")
+	cmd.Flags().StringVar(&b.stop, "stop", "", "Stop of the time range of the rendered queries (defaults to now)")
+	cmd.Flags().StringVarP(&b.file, "file", "f", "", "Path of the file to write to, required for png (defaults to stdout)")
+	cmd.Flags().StringVar(&b.browser, "browser", "", "Path of the headless Chrome or Chromium used to render png")
+	cmd.MarkFlagRequired("id")
+
+	return cmd
+}
+
+func (b *cmdDashboardBuilder) cmdExportRunEFn(cmd *cobra.Command, args []string) error {
+	var id influxdb.ID
+	if err := id.DecodeFromString(b.id); err != nil {
+		return fmt.Errorf("invalid dashboard ID: %w", err)
+	}
+	switch b.render {
+	case "", renderHTML:
+	case renderPNG:
+		if b.file == "" {
+			return errors.New("rendering png requires a file")
+		}
+	default:
+		return fmt.Errorf("unsupported render format %q, must be html or png", b.render)
+	}
+
+	dashSVC, queryFn, err := b.svcFn()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	d, err := dashSVC.FindDashboardByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to find dashboard with ID %q: %w", b.id, err)
+	}
+	for _, c := range d.Cells {
+		v, err := dashSVC.GetDashboardCellView(ctx, d.ID, c.ID)
+		if err != nil {
+			return fmt.Errorf("failed to find the view of cell %q: %w", c.ID, err)
+		}
+		c.View = v
+	}
+
+	if b.render == "" {
+		return b.writeOutput(func(w io.Writer) error {
+			return encodeDashboard(w, d)
+		})
+	}
+
+	start, stop, err := b.timeRange()
+	if err != nil {
+		return err
+	}
+	report := renderDashboard(ctx, d, queryFn, start, stop)
+
+	if b.render == renderHTML {
+		return b.writeOutput(report.writeHTML)
+	}
+	return b.capturePNG(report)
+}
+
+// timeRange returns the time range of the rendered queries.
+func (b *cmdDashboardBuilder) timeRange() (start, stop time.Time, err error) {
+	now := b.now().UTC()
+	stop = now
+	if b.stop != "" {
+		if stop, err = parseReportTime(b.stop, now); err != nil {
+			return start, stop, fmt.Errorf("invalid stop: %w", err)
+		}
+	}
+	if start, err = parseReportTime(b.start, now); err != nil {
+		return start, stop, fmt.Errorf("invalid start: %w", err)
+	}
+	if !start.Before(stop) {
+		return start, stop, errors.New("start must be before stop")
+	}
+	return start, stop, nil
+}
+
+// parseReportTime parses an RFC3339 time, or a duration relative to now.
+func parseReportTime(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(d), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// writeOutput calls fn with the file to write to, or the output of the
+// command if none is set.
+func (b *cmdDashboardBuilder) writeOutput(fn func(w io.Writer) error) error {
+	if b.file == "" {
+		return fn(b.w)
+	}
+	f, err := os.Create(b.file)
+	if err != nil {
+		return err
+	}
+	if err := fn(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// capturePNG renders the report to a temporary HTML file and captures it
+// to the output file with a headless browser.
+func (b *cmdDashboardBuilder) capturePNG(report *dashboardReport) error {
+	browser := b.browser
+	if browser == "" {
+		for _, name := range []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable"} {
+			if path, err := exec.LookPath(name); err == nil {
+				browser = path
+				break
+			}
+		}
+		if browser == "" {
+			return errors.New("rendering png requires Chrome or Chromium, none found in the PATH; use --browser to set its path")
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "influx-dashboard")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	page := filepath.Join(dir, "dashboard.html")
+	f, err := os.Create(page)
+	if err != nil {
+		return err
+	}
+	if err := report.writeHTML(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	out, err := filepath.Abs(b.file)
+	if err != nil {
+		return err
+	}
+	width, height := report.size()
+	cmd := exec.Command(browser,
+		"--headless",
+		"--disable-gpu",
+		"--hide-scrollbars",
+		fmt.Sprintf("--window-size=%d,%d", width, height),
+		"--screenshot="+out,
+		"file://"+page,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to render png with %s: %v: %s", browser, err, output)
+	}
+	return nil
+}
+
+// encodeDashboard writes the dashboard as JSON, with the name and
+// properties of the view of each cell.
+func encodeDashboard(w io.Writer, d *influxdb.Dashboard) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(d)
+}
+
+func newDashboardSVCs() (influxdb.DashboardService, dashboardQueryFn, error) {
+	httpClient, err := newHTTPClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	querySVC := &http.FluxQueryService{
+		Addr:               flags.host,
+		Token:              flags.token,
+		InsecureSkipVerify: flags.skipVerify,
+	}
+	queryFn := func(ctx context.Context, orgID influxdb.ID, q string, extern *ast.File) (flux.ResultIterator, error) {
+		return querySVC.Query(ctx, &query.Request{
+			OrganizationID: orgID,
+			Compiler: lang.FluxCompiler{
+				Query:  q,
+				Extern: extern,
+			},
+		})
+	}
+	return &http.DashboardService{Client: httpClient}, queryFn, nil
+}
@@ -0,0 +1,554 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/ast"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/influxdb"
+)
+
+const (
+	// maxReportRows is the maximum number of rows rendered for a table cell.
+	maxReportRows = 50
+
+	// reportWidth is the width of the rendered report, in pixels.
+	reportWidth = 1024
+
+	// windowPeriods is the number of windows of v.windowPeriod in the range.
+	windowPeriods = 360
+)
+
+// Dimensions of the charts of the report.
+const (
+	chartWidth  = 960
+	chartHeight = 240
+	chartLeft   = 70
+	chartBottom = 24
+)
+
+// chartColors are the colors of the series of a chart.
+var chartColors = []string{
+	"#22adf6", "#7a65f2", "#4ed8a0", "#ffb94a", "#f95f53", "#bf3d5e", "#00a3ff", "#9394ff",
+}
+
+// dashboardReport is a dashboard rendered over a time range.
+type dashboardReport struct {
+	Name        string
+	Description string
+	Start, Stop string
+	Cells       []*reportCell
+}
+
+// reportCell is the rendering of a cell, as a chart, a single value, tables
+// of results or a note.
+type reportCell struct {
+	Name   string
+	Error  string
+	Note   string
+	Stat   string
+	Chart  template.HTML
+	Legend []legendEntry
+	Tables []reportTable
+	// Truncated is true if rows of the tables were not rendered.
+	Truncated bool
+}
+
+type legendEntry struct {
+	Color string
+	Label string
+}
+
+type reportTable struct {
+	Key     string
+	Columns []string
+	Rows    [][]string
+}
+
+// resultTable holds the rows of a table of a query result.
+type resultTable struct {
+	key  string
+	cols []flux.ColMeta
+	rows [][]interface{}
+}
+
+// renderDashboard executes the queries of the cells of the dashboard over
+// [start, stop) and renders them. Errors of the queries of a cell are
+// rendered in the cell.
+func renderDashboard(ctx context.Context, d *influxdb.Dashboard, queryFn dashboardQueryFn, start, stop time.Time) *dashboardReport {
+	report := &dashboardReport{
+		Name:        d.Name,
+		Description: d.Description,
+		Start:       start.Format(time.RFC3339),
+		Stop:        stop.Format(time.RFC3339),
+	}
+
+	cells := append([]*influxdb.Cell(nil), d.Cells...)
+	sort.SliceStable(cells, func(i, j int) bool {
+		if cells[i].Y != cells[j].Y {
+			return cells[i].Y < cells[j].Y
+		}
+		return cells[i].X < cells[j].X
+	})
+
+	extern := reportExtern(start, stop)
+	for _, c := range cells {
+		rc := &reportCell{}
+		report.Cells = append(report.Cells, rc)
+		if c.View == nil {
+			rc.Error = "cell has no view"
+			continue
+		}
+		rc.Name = c.View.Name
+
+		queries := viewQueries(c.View.Properties)
+		var tables []*resultTable
+		for _, q := range queries {
+			if strings.TrimSpace(q.Text) == "" {
+				continue
+			}
+			ts, err := runReportQuery(ctx, queryFn, d.OrganizationID, q.Text, extern)
+			if err != nil {
+				rc.Error = err.Error()
+				break
+			}
+			tables = append(tables, ts...)
+		}
+		if rc.Error != "" {
+			continue
+		}
+
+		switch p := c.View.Properties.(type) {
+		case influxdb.MarkdownViewProperties:
+			rc.Note = p.Note
+		case influxdb.XYViewProperties:
+			rc.renderChart(tables, p.XColumn, p.YColumn, start, stop, false)
+		case influxdb.LinePlusSingleStatProperties:
+			rc.renderChart(tables, p.XColumn, p.YColumn, start, stop, false)
+			rc.renderStat(tables, p.Prefix, p.Suffix, p.DecimalPlaces)
+		case influxdb.CheckViewProperties:
+			rc.renderChart(tables, "", "", start, stop, false)
+		case influxdb.ScatterViewProperties:
+			rc.renderChart(tables, p.XColumn, p.YColumn, start, stop, true)
+		case influxdb.SingleStatViewProperties:
+			rc.renderStat(tables, p.Prefix, p.Suffix, p.DecimalPlaces)
+		case influxdb.GaugeViewProperties:
+			rc.renderStat(tables, p.Prefix, p.Suffix, p.DecimalPlaces)
+		default:
+			if len(queries) == 0 {
+				rc.Note = fmt.Sprintf("%s cells are not rendered", c.View.Properties.GetType())
+				continue
+			}
+			rc.renderTables(tables)
+		}
+	}
+	return report
+}
+
+// viewQueries returns the queries of the view properties.
+func viewQueries(props influxdb.ViewProperties) []influxdb.DashboardQuery {
+	switch p := props.(type) {
+	case influxdb.XYViewProperties:
+		return p.Queries
+	case influxdb.LinePlusSingleStatProperties:
+		return p.Queries
+	case influxdb.CheckViewProperties:
+		return p.Queries
+	case influxdb.SingleStatViewProperties:
+		return p.Queries
+	case influxdb.HistogramViewProperties:
+		return p.Queries
+	case influxdb.HeatmapViewProperties:
+		return p.Queries
+	case influxdb.ScatterViewProperties:
+		return p.Queries
+	case influxdb.GaugeViewProperties:
+		return p.Queries
+	case influxdb.TableViewProperties:
+		return p.Queries
+	}
+	return nil
+}
+
+// reportExtern returns the v option of the queries of the dashboard.
+func reportExtern(start, stop time.Time) *ast.File {
+	period := int64(stop.Sub(start) / windowPeriods / time.Millisecond)
+	if period < 1 {
+		period = 1
+	}
+	property := func(name string, value ast.Expression) *ast.Property {
+		return &ast.Property{Key: &ast.Identifier{Name: name}, Value: value}
+	}
+	return &ast.File{
+		Body: []ast.Statement{
+			&ast.OptionStatement{
+				Assignment: &ast.VariableAssignment{
+					ID: &ast.Identifier{Name: "v"},
+					Init: &ast.ObjectExpression{
+						Properties: []*ast.Property{
+							property("timeRangeStart", &ast.DateTimeLiteral{Value: start}),
+							property("timeRangeStop", &ast.DateTimeLiteral{Value: stop}),
+							property("windowPeriod", &ast.DurationLiteral{
+								Values: []ast.Duration{{Magnitude: period, Unit: "ms"}},
+							}),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// runReportQuery executes the query and returns the tables of its results.
+func runReportQuery(ctx context.Context, queryFn dashboardQueryFn, orgID influxdb.ID, q string, extern *ast.File) ([]*resultTable, error) {
+	results, err := queryFn(ctx, orgID, q, extern)
+	if err != nil {
+		return nil, err
+	}
+	defer results.Release()
+
+	var tables []*resultTable
+	for results.More() {
+		err := results.Next().Tables().Do(func(tbl flux.Table) error {
+			t := &resultTable{key: groupKeyLabel(tbl.Key()), cols: tbl.Cols()}
+			err := tbl.Do(func(cr flux.ColReader) error {
+				for i := 0; i < cr.Len(); i++ {
+					row := make([]interface{}, len(t.cols))
+					for j, c := range t.cols {
+						row[j] = columnValue(cr, c.Type, i, j)
+					}
+					t.rows = append(t.rows, row)
+				}
+				return nil
+			})
+			tables = append(tables, t)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := results.Err(); err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+// columnValue returns the value of the row i of the column j, nil if null.
+func columnValue(cr flux.ColReader, typ flux.ColType, i, j int) interface{} {
+	switch typ {
+	case flux.TBool:
+		if vs := cr.Bools(j); vs.IsValid(i) {
+			return vs.Value(i)
+		}
+	case flux.TInt:
+		if vs := cr.Ints(j); vs.IsValid(i) {
+			return vs.Value(i)
+		}
+	case flux.TUInt:
+		if vs := cr.UInts(j); vs.IsValid(i) {
+			return vs.Value(i)
+		}
+	case flux.TFloat:
+		if vs := cr.Floats(j); vs.IsValid(i) {
+			return vs.Value(i)
+		}
+	case flux.TString:
+		if vs := cr.Strings(j); vs.IsValid(i) {
+			return vs.ValueString(i)
+		}
+	case flux.TTime:
+		if vs := cr.Times(j); vs.IsValid(i) {
+			return time.Unix(0, vs.Value(i)).UTC()
+		}
+	}
+	return nil
+}
+
+// groupKeyLabel returns the group key as col=value pairs, without the
+// bounds of the range.
+func groupKeyLabel(key flux.GroupKey) string {
+	var pairs []string
+	for j, c := range key.Cols() {
+		if c.Label == execute.DefaultStartColLabel || c.Label == execute.DefaultStopColLabel {
+			continue
+		}
+		pairs = append(pairs, c.Label+"="+key.ValueString(j))
+	}
+	return strings.Join(pairs, " ")
+}
+
+func columnIndex(cols []flux.ColMeta, label string) int {
+	for j, c := range cols {
+		if c.Label == label {
+			return j
+		}
+	}
+	return -1
+}
+
+// numeric returns the value as a float, or false if it is not a number or
+// a time.
+func numeric(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case time.Time:
+		return float64(v.UnixNano()), true
+	}
+	return 0, false
+}
+
+// renderChart renders the tables as series of an SVG chart of their y column
+// over their x column, _value over _time by default.
+func (rc *reportCell) renderChart(tables []*resultTable, xColumn, yColumn string, start, stop time.Time, scatter bool) {
+	if xColumn == "" {
+		xColumn = execute.DefaultTimeColLabel
+	}
+	if yColumn == "" {
+		yColumn = execute.DefaultValueColLabel
+	}
+
+	type point struct{ x, y float64 }
+	var (
+		series [][]point
+		labels []string
+		minX   = math.Inf(1)
+		maxX   = math.Inf(-1)
+		minY   = math.Inf(1)
+		maxY   = math.Inf(-1)
+	)
+	for _, t := range tables {
+		xi, yi := columnIndex(t.cols, xColumn), columnIndex(t.cols, yColumn)
+		if xi < 0 || yi < 0 {
+			continue
+		}
+		var points []point
+		for _, row := range t.rows {
+			x, okX := numeric(row[xi])
+			y, okY := numeric(row[yi])
+			if !okX || !okY {
+				continue
+			}
+			points = append(points, point{x, y})
+			minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+			minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+		}
+		if len(points) > 0 {
+			series = append(series, points)
+			labels = append(labels, t.key)
+		}
+	}
+	if len(series) == 0 {
+		rc.Note = "No results"
+		return
+	}
+
+	timeAxis := xColumn == execute.DefaultTimeColLabel
+	if timeAxis {
+		minX, maxX = float64(start.UnixNano()), float64(stop.UnixNano())
+	}
+	if minX == maxX {
+		minX, maxX = minX-1, maxX+1
+	}
+	if minY == maxY {
+		minY, maxY = minY-1, maxY+1
+	}
+
+	plotWidth := float64(chartWidth - chartLeft)
+	plotHeight := float64(chartHeight - chartBottom)
+	px := func(x float64) float64 { return chartLeft + (x-minX)/(maxX-minX)*plotWidth }
+	py := func(y float64) float64 { return plotHeight - (y-minY)/(maxY-minY)*plotHeight }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, chartWidth, chartHeight, chartWidth, chartHeight)
+	fmt.Fprintf(&b, `<line x1="%d" y1="0" x2="%d" y2="%g" class="axis"/>`, chartLeft, chartLeft, plotHeight)
+	fmt.Fprintf(&b, `<line x1="%d" y1="%g" x2="%d" y2="%g" class="axis"/>`, chartLeft, plotHeight, chartWidth, plotHeight)
+	fmt.Fprintf(&b, `<text x="%d" y="12" text-anchor="end">%s</text>`, chartLeft-6, html.EscapeString(formatNumber(maxY)))
+	fmt.Fprintf(&b, `<text x="%d" y="%g" text-anchor="end">%s</text>`, chartLeft-6, plotHeight, html.EscapeString(formatNumber(minY)))
+	minLabel, maxLabel := formatNumber(minX), formatNumber(maxX)
+	if timeAxis {
+		minLabel, maxLabel = start.Format(time.RFC3339), stop.Format(time.RFC3339)
+	}
+	fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`, chartLeft, chartHeight-4, html.EscapeString(minLabel))
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end">%s</text>`, chartWidth, chartHeight-4, html.EscapeString(maxLabel))
+
+	for i, points := range series {
+		color := chartColors[i%len(chartColors)]
+		rc.Legend = append(rc.Legend, legendEntry{Color: color, Label: labels[i]})
+		if scatter {
+			for _, p := range points {
+				fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="2.5" fill="%s"/>`, px(p.x), py(p.y), color)
+			}
+			continue
+		}
+		sort.SliceStable(points, func(i, j int) bool { return points[i].x < points[j].x })
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="1.5" points="`, color)
+		for _, p := range points {
+			fmt.Fprintf(&b, "%.1f,%.1f ", px(p.x), py(p.y))
+		}
+		b.WriteString(`"/>`)
+	}
+	b.WriteString(`</svg>`)
+
+	// The SVG only holds numbers, colors and escaped labels.
+	rc.Chart = template.HTML(b.String())
+}
+
+// renderStat renders the last value of the _value column of the first
+// table, as single stats do.
+func (rc *reportCell) renderStat(tables []*resultTable, prefix, suffix string, places influxdb.DecimalPlaces) {
+	for _, t := range tables {
+		j := columnIndex(t.cols, execute.DefaultValueColLabel)
+		if j < 0 {
+			continue
+		}
+		for i := len(t.rows) - 1; i >= 0; i-- {
+			v := t.rows[i][j]
+			if v == nil {
+				continue
+			}
+			s := fmt.Sprint(v)
+			if f, ok := numeric(v); ok {
+				s = strconv.FormatFloat(f, 'f', -1, 64)
+				if places.IsEnforced {
+					s = strconv.FormatFloat(f, 'f', int(places.Digits), 64)
+				}
+			}
+			rc.Stat = prefix + s + suffix
+			return
+		}
+		break
+	}
+	if rc.Chart == "" {
+		rc.Note = "No results"
+	}
+}
+
+// renderTables renders the first rows of the tables.
+func (rc *reportCell) renderTables(tables []*resultTable) {
+	rows := 0
+	for _, t := range tables {
+		if rows >= maxReportRows {
+			rc.Truncated = true
+			break
+		}
+		rt := reportTable{Key: t.key}
+		for _, c := range t.cols {
+			rt.Columns = append(rt.Columns, c.Label)
+		}
+		for _, row := range t.rows {
+			if rows >= maxReportRows {
+				rc.Truncated = true
+				break
+			}
+			cells := make([]string, len(row))
+			for j, v := range row {
+				cells[j] = formatValue(v)
+			}
+			rt.Rows = append(rt.Rows, cells)
+			rows++
+		}
+		rc.Tables = append(rc.Tables, rt)
+	}
+	if len(tables) == 0 {
+		rc.Note = "No results"
+	}
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case float64:
+		return formatNumber(v)
+	}
+	return fmt.Sprint(v)
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'g', 6, 64)
+}
+
+// size returns the size of the window the report is captured in.
+func (r *dashboardReport) size() (width, height int) {
+	height = 120
+	for _, c := range r.Cells {
+		height += 80
+		switch {
+		case c.Chart != "":
+			height += chartHeight + 30*((len(c.Legend)+2)/3)
+		case len(c.Tables) > 0:
+			for _, t := range c.Tables {
+				height += 60 + 24*len(t.Rows)
+			}
+		default:
+			height += 80
+		}
+	}
+	return reportWidth, height
+}
+
+func (r *dashboardReport) writeHTML(w io.Writer) error {
+	return reportTemplate.Execute(w, r)
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>
+body { font-family: sans-serif; background: #ffffff; color: #202028; margin: 24px; width: 976px; }
+h1 { margin: 0 0 4px 0; }
+.range { color: #676978; margin-bottom: 16px; }
+.cell { border: 1px solid #d4d7dd; border-radius: 4px; padding: 8px 12px; margin-bottom: 16px; }
+.cell h2 { font-size: 16px; margin: 0 0 8px 0; }
+.stat { font-size: 48px; font-weight: bold; }
+.error { color: #bf3d5e; }
+.note { white-space: pre-wrap; }
+svg text { font-size: 11px; fill: #676978; }
+svg .axis { stroke: #d4d7dd; }
+.legend span { display: inline-block; width: 310px; font-size: 12px; }
+.legend i { display: inline-block; width: 10px; height: 10px; margin-right: 4px; }
+table { border-collapse: collapse; font-size: 12px; margin-bottom: 8px; }
+th, td { border: 1px solid #d4d7dd; padding: 2px 6px; text-align: left; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+{{if .Description}}<div>{{.Description}}</div>{{end}}
+<div class="range">{{.Start}} to {{.Stop}}</div>
+{{range .Cells}}<div class="cell">
+<h2>{{.Name}}</h2>
+{{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+{{if .Stat}}<div class="stat">{{.Stat}}</div>{{end}}
+{{if .Chart}}{{.Chart}}
+<div class="legend">{{range .Legend}}<span><i style="background: {{.Color}}"></i>{{.Label}}</span>{{end}}</div>{{end}}
+{{range .Tables}}{{if .Key}}<div>{{.Key}}</div>{{end}}
+<table>
+<tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}{{if .Truncated}}<div>Only the first rows are shown.</div>{{end}}
+{{if .Note}}<div class="note">{{.Note}}</div>{{end}}
+</div>
+{{end}}</body>
+</html>
+`))
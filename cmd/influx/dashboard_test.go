@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/ast"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/values"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/mock"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdDashboard(t *testing.T) {
+	dashboardID := influxdb.ID(1)
+	orgID := influxdb.ID(9000)
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	views := map[influxdb.ID]*influxdb.View{
+		10: {
+			ViewContents: influxdb.ViewContents{Name: "CPU"},
+			Properties: influxdb.XYViewProperties{
+				Type:    influxdb.ViewPropertyTypeXY,
+				Queries: []influxdb.DashboardQuery{{Text: "cpu"}},
+			},
+		},
+		11: {
+			ViewContents: influxdb.ViewContents{Name: "Load"},
+			Properties: influxdb.SingleStatViewProperties{
+				Type:          influxdb.ViewPropertyTypeSingleStat,
+				Queries:       []influxdb.DashboardQuery{{Text: "cpu"}},
+				Suffix:        "%",
+				DecimalPlaces: influxdb.DecimalPlaces{IsEnforced: true, Digits: 1},
+			},
+		},
+		12: {
+			ViewContents: influxdb.ViewContents{Name: "Notes"},
+			Properties: influxdb.MarkdownViewProperties{
+				Type: influxdb.ViewPropertyTypeMarkdown,
+				Note: "Weekly <report>",
+			},
+		},
+		13: {
+			ViewContents: influxdb.ViewContents{Name: "Hosts"},
+			Properties: influxdb.TableViewProperties{
+				Type:    influxdb.ViewPropertyTypeTable,
+				Queries: []influxdb.DashboardQuery{{Text: "cpu"}},
+			},
+		},
+		14: {
+			ViewContents: influxdb.ViewContents{Name: "Broken"},
+			Properties: influxdb.XYViewProperties{
+				Type:    influxdb.ViewPropertyTypeXY,
+				Queries: []influxdb.DashboardQuery{{Text: "broken"}},
+			},
+		},
+	}
+	dashSVC := mock.NewDashboardService()
+	dashSVC.FindDashboardByIDF = func(ctx context.Context, id influxdb.ID) (*influxdb.Dashboard, error) {
+		if id != dashboardID {
+			return nil, &influxdb.Error{Code: influxdb.ENotFound, Msg: "dashboard not found"}
+		}
+		return &influxdb.Dashboard{
+			ID:             dashboardID,
+			OrganizationID: orgID,
+			Name:           "Servers",
+			Cells: []*influxdb.Cell{
+				{ID: 14, CellProperty: influxdb.CellProperty{Y: 3}},
+				{ID: 13, CellProperty: influxdb.CellProperty{Y: 2}},
+				{ID: 12, CellProperty: influxdb.CellProperty{Y: 1}},
+				{ID: 11, CellProperty: influxdb.CellProperty{X: 6}},
+				{ID: 10},
+			},
+		}, nil
+	}
+	dashSVC.GetDashboardCellViewF = func(ctx context.Context, dashboardID, cellID influxdb.ID) (*influxdb.View, error) {
+		return views[cellID], nil
+	}
+
+	var externs []*ast.File
+	queryFn := func(ctx context.Context, id influxdb.ID, q string, extern *ast.File) (flux.ResultIterator, error) {
+		if id != orgID {
+			t.Errorf("unexpected organization: %s", id)
+		}
+		externs = append(externs, extern)
+		if q == "broken" {
+			return nil, &influxdb.Error{Code: influxdb.EInvalid, Msg: "undefined identifier broken"}
+		}
+		table := func(host string, vs ...float64) *executetest.Table {
+			tbl := &executetest.Table{
+				KeyCols: []string{"host"},
+				ColMeta: []flux.ColMeta{
+					{Label: "_time", Type: flux.TTime},
+					{Label: "_value", Type: flux.TFloat},
+					{Label: "host", Type: flux.TString},
+				},
+			}
+			for i, v := range vs {
+				tbl.Data = append(tbl.Data, []interface{}{
+					values.ConvertTime(now.Add(time.Duration(i-len(vs)) * time.Minute)), v, host,
+				})
+			}
+			return tbl
+		}
+		return flux.NewSliceResultIterator([]flux.Result{
+			executetest.NewResult([]*executetest.Table{table("a", 10, 20, 42.54), table("b", 5, 6)}),
+		}), nil
+	}
+
+	run := func(t *testing.T, args ...string) (string, error) {
+		t.Helper()
+		var buf bytes.Buffer
+		builder := newInfluxCmdBuilder(
+			in(new(bytes.Buffer)),
+			out(&buf),
+		)
+		cmd := builder.cmd(func(f *globalFlags, opt genericCLIOpts) *cobra.Command {
+			// report the errors of the command rather than those of the setup check
+			opt.runEWrapFn = nil
+			b := newCmdDashboardBuilder(func() (influxdb.DashboardService, dashboardQueryFn, error) {
+				return dashSVC, queryFn, nil
+			}, opt)
+			b.now = func() time.Time { return now }
+			return b.cmd()
+		})
+		cmd.SetArgs(append([]string{"dashboards", "export"}, args...))
+		err := cmd.Execute()
+		return buf.String(), err
+	}
+
+	t.Run("json", func(t *testing.T) {
+		output, err := run(t, "--id", dashboardID.String())
+		require.NoError(t, err)
+
+		var d struct {
+			Name  string `json:"name"`
+			Cells []struct {
+				Name       string          `json:"name"`
+				Properties json.RawMessage `json:"properties"`
+			} `json:"cells"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(output), &d))
+		assert.Equal(t, "Servers", d.Name)
+		require.Len(t, d.Cells, 5)
+		assert.Equal(t, "Broken", d.Cells[0].Name)
+		assert.Contains(t, string(d.Cells[0].Properties), `"text": "broken"`)
+	})
+
+	t.Run("html", func(t *testing.T) {
+		externs = nil
+		output, err := run(t, "--id", dashboardID.String(), "--render", "html", "--start", "-24h")
+		require.NoError(t, err)
+
+		for _, want := range []string{
+			"<h1>Servers</h1>",
+			"2019-12-31T12:00:00Z to 2020-01-01T12:00:00Z",
+			`<polyline fill="none" stroke="#22adf6"`,
+			`<polyline fill="none" stroke="#7a65f2"`,
+			`host=a</span>`,
+			`<div class="stat">42.5%</div>`,
+			`<div class="note">Weekly &lt;report&gt;</div>`,
+			`<td>42.54</td><td>a</td>`,
+			`<div class="error">undefined identifier broken</div>`,
+		} {
+			assert.Contains(t, output, want)
+		}
+		// Cells are rendered from top to bottom, then left to right.
+		var order []int
+		for _, name := range []string{"CPU", "Load", "Notes", "Hosts", "Broken"} {
+			order = append(order, strings.Index(output, "<h2>"+name+"</h2>"))
+		}
+		for i := 1; i < len(order); i++ {
+			assert.True(t, order[i-1] < order[i], "unexpected order of cells: %v", order)
+		}
+
+		require.Len(t, externs, 4)
+		obj := externs[0].Body[0].(*ast.OptionStatement).Assignment.(*ast.VariableAssignment).Init.(*ast.ObjectExpression)
+		assert.Equal(t, now.Add(-24*time.Hour), obj.Properties[0].Value.(*ast.DateTimeLiteral).Value)
+		assert.Equal(t, now, obj.Properties[1].Value.(*ast.DateTimeLiteral).Value)
+		assert.Equal(t, []ast.Duration{{Magnitude: 240000, Unit: "ms"}}, obj.Properties[2].Value.(*ast.DurationLiteral).Values)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, tt := range []struct {
+			args []string
+			err  string
+		}{
+			{args: []string{"--id", "bad"}, err: "invalid dashboard ID"},
+			{args: []string{"--id", dashboardID.String(), "--render", "pdf"}, err: "unsupported render format"},
+			{args: []string{"--id", dashboardID.String(), "--render", "png"}, err: "requires a file"},
+			{args: []string{"--id", dashboardID.String(), "--render", "html", "--start", "-1h", "--stop", "-2h"}, err: "start must be before stop"},
+			{args: []string{"--id", influxdb.ID(2).String()}, err: "dashboard not found"},
+		} {
+			_, err := run(t, tt.args...)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		}
+	})
+}
@@ -75,7 +75,7 @@ func fluxDeleteF(cmd *cobra.Command, args []string) error {
 
 	ctx := signals.WithStandardSignals(context.Background())
 	if err := s.DeleteBucketRangePredicate(ctx, deleteFlags); err != nil && err != context.Canceled {
-		return fmt.Errorf("failed to delete data: %v", err)
+		return fmt.Errorf("failed to delete data: %w", err)
 	}
 
 	return nil
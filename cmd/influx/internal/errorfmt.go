@@ -1,7 +1,6 @@
 package internal
 
 import (
-	"errors"
 	"strings"
 	"unicode"
 )
@@ -31,5 +30,16 @@ func ErrorFmt(err error) error {
 
 	s = s + "."
 
-	return errors.New(s)
+	return &formattedError{msg: s, err: err}
 }
+
+// formattedError is an error formatted for the user, which still unwraps to
+// the original error so that its code determines the exit status.
+type formattedError struct {
+	msg string
+	err error
+}
+
+func (e *formattedError) Error() string { return e.msg }
+
+func (e *formattedError) Unwrap() error { return e.err }
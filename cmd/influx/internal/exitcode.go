@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	platform "github.com/influxdata/influxdb"
+)
+
+// Exit statuses of the influx CLI.
+const (
+	// ExitOK is the exit status of a successful command.
+	ExitOK = 0
+	// ExitError is the exit status of an invalid command, and of errors
+	// not covered by the other statuses.
+	ExitError = 1
+	// ExitUnauthorized is the exit status of a command whose token is
+	// missing, invalid or not allowed to perform it.
+	ExitUnauthorized = 2
+	// ExitNotFound is the exit status of a command on a resource that does
+	// not exist.
+	ExitNotFound = 3
+	// ExitConflict is the exit status of a command conflicting with the
+	// state of a resource, such as the creation of an existing one.
+	ExitConflict = 4
+	// ExitPartialWrite is the exit status of a write of which some points
+	// were rejected.
+	ExitPartialWrite = 5
+	// ExitServerError is the exit status of a command which failed because
+	// the server is unreachable, unavailable or failing.
+	ExitServerError = 6
+)
+
+// ExitStatuses documents the exit statuses of the influx CLI.
+const ExitStatuses = `EXIT STATUS
+
+   0  success
+   1  invalid command, or any error not listed below
+   2  authentication failure: missing, invalid or insufficient token
+   3  resource not found
+   4  conflict with an existing resource
+   5  partial write: some points were rejected
+   6  server error: server unreachable, unavailable or failing`
+
+// ExitCode returns the exit status of a command failing with err.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var pwerr *PartialWriteError
+	if errors.As(err, &pwerr) {
+		return ExitPartialWrite
+	}
+
+	var perr *platform.Error
+	if !errors.As(err, &perr) {
+		var nerr net.Error
+		if errors.As(err, &nerr) {
+			return ExitServerError
+		}
+		return ExitError
+	}
+
+	switch platform.ErrorCode(perr) {
+	case platform.EUnauthorized, platform.EForbidden:
+		return ExitUnauthorized
+	case platform.ENotFound:
+		return ExitNotFound
+	case platform.EConflict:
+		return ExitConflict
+	case platform.EInternal, platform.EUnavailable, platform.ETooManyRequests:
+		return ExitServerError
+	default:
+		return ExitError
+	}
+}
+
+// PartialWriteError is the error of a write of which the server rejected some
+// points, writing the others. Other errors of the unprocessable entity code
+// are validation failures, which exit with ExitError.
+type PartialWriteError struct {
+	Err error
+}
+
+func (e *PartialWriteError) Error() string { return e.Err.Error() }
+
+func (e *PartialWriteError) Unwrap() error { return e.Err }
+
+// Error formats of the influx CLI.
+const (
+	ErrorFormatText = "text"
+	ErrorFormatJSON = "json"
+)
+
+// errorJSON is the representation of an error in the JSON error format.
+type errorJSON struct {
+	Code     string `json:"code,omitempty"`
+	Message  string `json:"message"`
+	ExitCode int    `json:"exitCode"`
+}
+
+// WriteError writes err to w in the error format, text or json.
+func WriteError(w io.Writer, format string, err error) error {
+	if format != ErrorFormatJSON {
+		_, werr := fmt.Fprintf(w, "Error: %s\n", err)
+		return werr
+	}
+
+	e := errorJSON{
+		Message:  err.Error(),
+		ExitCode: ExitCode(err),
+	}
+	var perr *platform.Error
+	if errors.As(err, &perr) {
+		e.Code = platform.ErrorCode(perr)
+	}
+	return json.NewEncoder(w).Encode(e)
+}
@@ -0,0 +1,70 @@
+package internal_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx/internal"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{name: "no error", code: internal.ExitOK},
+		{name: "plain error", err: errors.New("invalid flag"), code: internal.ExitError},
+		{name: "invalid", err: &platform.Error{Code: platform.EInvalid}, code: internal.ExitError},
+		{name: "unauthorized", err: &platform.Error{Code: platform.EUnauthorized}, code: internal.ExitUnauthorized},
+		{name: "forbidden", err: &platform.Error{Code: platform.EForbidden}, code: internal.ExitUnauthorized},
+		{name: "not found", err: &platform.Error{Code: platform.ENotFound}, code: internal.ExitNotFound},
+		{name: "conflict", err: &platform.Error{Code: platform.EConflict}, code: internal.ExitConflict},
+		{name: "partial write", err: &internal.PartialWriteError{Err: &platform.Error{Code: platform.EUnprocessableEntity}}, code: internal.ExitPartialWrite},
+		{name: "unprocessable entity", err: &platform.Error{Code: platform.EUnprocessableEntity}, code: internal.ExitError},
+		{
+			name: "wrapped partial write",
+			err:  internal.ErrorFmt(fmt.Errorf("failed to write data: %w", &internal.PartialWriteError{Err: &platform.Error{Code: platform.EUnprocessableEntity}})),
+			code: internal.ExitPartialWrite,
+		},
+		{name: "server error", err: &platform.Error{Code: platform.EInternal}, code: internal.ExitServerError},
+		{name: "unreachable server", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, code: internal.ExitServerError},
+		{
+			name: "wrapped and formatted error",
+			err:  internal.ErrorFmt(fmt.Errorf("failed to find bucket: %w", &platform.Error{Code: platform.ENotFound})),
+			code: internal.ExitNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := internal.ExitCode(tt.err); got != tt.code {
+				t.Errorf("got exit code %d, want %d", got, tt.code)
+			}
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	err := internal.ErrorFmt(fmt.Errorf("failed to find bucket: %w", &platform.Error{Code: platform.ENotFound, Msg: "bucket not found"}))
+
+	var buf bytes.Buffer
+	if err := internal.WriteError(&buf, internal.ErrorFormatText, err); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "Error: Failed to find bucket: bucket not found.\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	if err := internal.WriteError(&buf, internal.ErrorFormatJSON, err); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), `{"code":"not found","message":"Failed to find bucket: bucket not found.","exitCode":3}`+"\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
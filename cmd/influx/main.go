@@ -28,8 +28,11 @@ const maxTCPConnections = 128
 func main() {
 	influxCmd := influxCmd()
 	if err := influxCmd.Execute(); err != nil {
-		seeHelp(influxCmd, nil)
-		os.Exit(1)
+		internal.WriteError(os.Stderr, flags.errorFormat, err)
+		if flags.errorFormat != internal.ErrorFormatJSON {
+			seeHelp(influxCmd, nil)
+		}
+		os.Exit(internal.ExitCode(err))
 	}
 }
 
@@ -95,10 +98,11 @@ func out(w io.Writer) genericCLIOptFn {
 }
 
 type globalFlags struct {
-	token      string
-	host       string
-	local      bool
-	skipVerify bool
+	token       string
+	host        string
+	local       bool
+	skipVerify  bool
+	errorFormat string
 }
 
 var flags globalFlags
@@ -133,7 +137,17 @@ func (b *cmdInfluxBuilder) cmd(childCmdFns ...func(f *globalFlags, opt genericCL
 
 	cmd := b.newCmd("influx", nil)
 	cmd.Short = "Influx Client"
+	cmd.Long = "Influx Client\n\n" + internal.ExitStatuses
 	cmd.SilenceUsage = true
+	// Errors are reported by main, in the format of --error-format.
+	cmd.SilenceErrors = true
+	cmd.PersistentPreRunE = func(*cobra.Command, []string) error {
+		switch flags.errorFormat {
+		case internal.ErrorFormatText, internal.ErrorFormatJSON:
+			return nil
+		}
+		return fmt.Errorf("invalid error format %q, expected %q or %q", flags.errorFormat, internal.ErrorFormatText, internal.ErrorFormatJSON)
+	}
 
 	for _, childCmd := range childCmdFns {
 		cmd.AddCommand(childCmd(&flags, b.genericCLIOpts))
@@ -154,6 +168,13 @@ func (b *cmdInfluxBuilder) cmd(childCmdFns ...func(f *globalFlags, opt genericCL
 			Desc:       "HTTP address of Influx",
 			Persistent: true,
 		},
+		{
+			DestP:      &flags.errorFormat,
+			Flag:       "error-format",
+			Default:    internal.ErrorFormatText,
+			Desc:       "Format of the errors written to stderr, text or json",
+			Persistent: true,
+		},
 	}
 	fOpts.mustRegister(cmd)
 
@@ -181,6 +202,9 @@ func influxCmd(opts ...genericCLIOptFn) *cobra.Command {
 		cmdAuth,
 		cmdBackup,
 		cmdBucket,
+		cmdCompaction,
+		cmdConstant,
+		cmdDashboard,
 		cmdDelete,
 		cmdOrganization,
 		cmdPing,
@@ -188,9 +212,12 @@ func influxCmd(opts ...genericCLIOptFn) *cobra.Command {
 		cmdQuery,
 		cmdTranspile,
 		cmdREPL,
+		cmdRole,
 		cmdSecret,
 		cmdSetup,
+		cmdTail,
 		cmdTask,
+		cmdTop,
 		cmdUser,
 		cmdWrite,
 	)
@@ -343,7 +370,7 @@ func (o *organization) getID(orgSVC influxdb.OrganizationService) (influxdb.ID,
 			Name: &o.name,
 		})
 		if err != nil {
-			return 0, fmt.Errorf("%v", err)
+			return 0, err
 		}
 		return org.ID, nil
 	}
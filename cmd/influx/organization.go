@@ -29,6 +29,10 @@ type cmdOrgBuilder struct {
 	id          string
 	memberID    string
 	name        string
+
+	queryMaxRows  int64
+	queryMaxBytes int64
+	queryReadRate int64
 }
 
 func newCmdOrgBuilder(svcFn orgSVCFn, opts genericCLIOpts) *cmdOrgBuilder {
@@ -69,7 +73,7 @@ func (b *cmdOrgBuilder) cmdCreate() *cobra.Command {
 func (b *cmdOrgBuilder) createRunEFn(cmd *cobra.Command, args []string) error {
 	orgSvc, _, _, err := b.svcFn()
 	if err != nil {
-		return fmt.Errorf("failed to initialize org service client: %v", err)
+		return fmt.Errorf("failed to initialize org service client: %w", err)
 	}
 
 	org := &influxdb.Organization{
@@ -78,7 +82,7 @@ func (b *cmdOrgBuilder) createRunEFn(cmd *cobra.Command, args []string) error {
 	}
 
 	if err := orgSvc.CreateOrganization(context.Background(), org); err != nil {
-		return fmt.Errorf("failed to create organization: %v", err)
+		return fmt.Errorf("failed to create organization: %w", err)
 	}
 
 	w := b.newTabWriter()
@@ -113,22 +117,22 @@ func (b *cmdOrgBuilder) cmdDelete() *cobra.Command {
 func (b *cmdOrgBuilder) deleteRunEFn(cmd *cobra.Command, args []string) error {
 	orgSvc, _, _, err := b.svcFn()
 	if err != nil {
-		return fmt.Errorf("failed to initialize org service client: %v", err)
+		return fmt.Errorf("failed to initialize org service client: %w", err)
 	}
 
 	var id influxdb.ID
 	if err := id.DecodeFromString(b.id); err != nil {
-		return fmt.Errorf("failed to decode org id %s: %v", b.id, err)
+		return fmt.Errorf("failed to decode org id %s: %w", b.id, err)
 	}
 
 	ctx := context.TODO()
 	o, err := orgSvc.FindOrganizationByID(ctx, id)
 	if err != nil {
-		return fmt.Errorf("failed to find org with id %q: %v", id, err)
+		return fmt.Errorf("failed to find org with id %q: %w", id, err)
 	}
 
 	if err = orgSvc.DeleteOrganization(ctx, id); err != nil {
-		return fmt.Errorf("failed to delete org with id %q: %v", id, err)
+		return fmt.Errorf("failed to delete org with id %q: %w", id, err)
 	}
 
 	w := b.newTabWriter()
@@ -171,7 +175,7 @@ func (b *cmdOrgBuilder) cmdFind() *cobra.Command {
 func (b *cmdOrgBuilder) findRunEFn(cmd *cobra.Command, args []string) error {
 	orgSvc, _, _, err := b.svcFn()
 	if err != nil {
-		return fmt.Errorf("failed to initialize org service client: %v", err)
+		return fmt.Errorf("failed to initialize org service client: %w", err)
 	}
 
 	filter := influxdb.OrganizationFilter{}
@@ -182,14 +186,14 @@ func (b *cmdOrgBuilder) findRunEFn(cmd *cobra.Command, args []string) error {
 	if b.id != "" {
 		id, err := influxdb.IDFromString(b.id)
 		if err != nil {
-			return fmt.Errorf("failed to decode org id %s: %v", b.id, err)
+			return fmt.Errorf("failed to decode org id %s: %w", b.id, err)
 		}
 		filter.ID = id
 	}
 
 	orgs, _, err := orgSvc.FindOrganizations(context.Background(), filter)
 	if err != nil {
-		return fmt.Errorf("failed find orgs: %v", err)
+		return fmt.Errorf("failed find orgs: %w", err)
 	}
 
 	w := b.newTabWriter()
@@ -234,6 +238,9 @@ func (b *cmdOrgBuilder) cmdUpdate() *cobra.Command {
 		},
 	}
 	opts.mustRegister(cmd)
+	cmd.Flags().Int64Var(&b.queryMaxRows, "query-max-rows", 0, "The maximum number of rows of query results, 0 is unlimited")
+	cmd.Flags().Int64Var(&b.queryMaxBytes, "query-max-bytes", 0, "The maximum size in bytes of the values of query results, 0 is unlimited")
+	cmd.Flags().Int64Var(&b.queryReadRate, "query-read-rate", 0, "The maximum rate in bytes per second at which each query reads from disk, 0 is unlimited")
 
 	return cmd
 }
@@ -241,12 +248,12 @@ func (b *cmdOrgBuilder) cmdUpdate() *cobra.Command {
 func (b *cmdOrgBuilder) updateRunEFn(cmd *cobra.Command, args []string) error {
 	orgSvc, _, _, err := b.svcFn()
 	if err != nil {
-		return fmt.Errorf("failed to initialize org service client: %v", err)
+		return fmt.Errorf("failed to initialize org service client: %w", err)
 	}
 
 	var id influxdb.ID
 	if err := id.DecodeFromString(b.id); err != nil {
-		return fmt.Errorf("failed to decode org id %s: %v", b.id, err)
+		return fmt.Errorf("failed to decode org id %s: %w", b.id, err)
 	}
 
 	update := influxdb.OrganizationUpdate{}
@@ -256,10 +263,37 @@ func (b *cmdOrgBuilder) updateRunEFn(cmd *cobra.Command, args []string) error {
 	if b.description != "" {
 		update.Description = &b.description
 	}
+	if cmd.Flags().Changed("query-max-rows") || cmd.Flags().Changed("query-max-bytes") {
+		if b.queryMaxRows < 0 || b.queryMaxBytes < 0 {
+			return fmt.Errorf("query result limits must not be negative")
+		}
+		// Both limits are replaced, so keep the current value of the one not given.
+		o, err := orgSvc.FindOrganizationByID(context.Background(), id)
+		if err != nil {
+			return fmt.Errorf("failed to find org: %w", err)
+		}
+		var limits influxdb.QueryResultLimits
+		if o.QueryResultLimits != nil {
+			limits = *o.QueryResultLimits
+		}
+		if cmd.Flags().Changed("query-max-rows") {
+			limits.MaxRows = b.queryMaxRows
+		}
+		if cmd.Flags().Changed("query-max-bytes") {
+			limits.MaxBytes = b.queryMaxBytes
+		}
+		update.QueryResultLimits = &limits
+	}
+	if cmd.Flags().Changed("query-read-rate") {
+		if b.queryReadRate < 0 {
+			return fmt.Errorf("query read rate must not be negative")
+		}
+		update.QueryReadRateLimit = &b.queryReadRate
+	}
 
 	o, err := orgSvc.UpdateOrganization(context.Background(), id, update)
 	if err != nil {
-		return fmt.Errorf("failed to update org: %v", err)
+		return fmt.Errorf("failed to update org: %w", err)
 	}
 
 	w := b.newTabWriter()
@@ -314,7 +348,7 @@ func (b *cmdOrgBuilder) cmdMemberList() *cobra.Command {
 func (b *cmdOrgBuilder) memberListRunEFn(cmd *cobra.Command, args []string) error {
 	orgSvc, urmSVC, userSVC, err := b.svcFn()
 	if err != nil {
-		return fmt.Errorf("failed to initialize org service client: %v", err)
+		return fmt.Errorf("failed to initialize org service client: %w", err)
 	}
 
 	if b.id == "" && b.name == "" {
@@ -330,14 +364,14 @@ func (b *cmdOrgBuilder) memberListRunEFn(cmd *cobra.Command, args []string) erro
 		var fID influxdb.ID
 		err := fID.DecodeFromString(b.id)
 		if err != nil {
-			return fmt.Errorf("failed to decode org id %s: %v", b.id, err)
+			return fmt.Errorf("failed to decode org id %s: %w", b.id, err)
 		}
 		filter.ID = &fID
 	}
 
 	organization, err := orgSvc.FindOrganization(context.Background(), filter)
 	if err != nil {
-		return fmt.Errorf("failed to find org: %v", err)
+		return fmt.Errorf("failed to find org: %w", err)
 	}
 
 	ctx := context.Background()
@@ -386,7 +420,7 @@ func (b *cmdOrgBuilder) memberAddRunEFn(cmd *cobra.Command, args []string) error
 
 	orgSvc, urmSVC, _, err := b.svcFn()
 	if err != nil {
-		return fmt.Errorf("failed to initialize org service client: %v", err)
+		return fmt.Errorf("failed to initialize org service client: %w", err)
 	}
 
 	var filter influxdb.OrganizationFilter
@@ -398,7 +432,7 @@ func (b *cmdOrgBuilder) memberAddRunEFn(cmd *cobra.Command, args []string) error
 		var fID influxdb.ID
 		err := fID.DecodeFromString(b.id)
 		if err != nil {
-			return fmt.Errorf("failed to decode org id %s: %v", b.id, err)
+			return fmt.Errorf("failed to decode org id %s: %w", b.id, err)
 		}
 		filter.ID = &fID
 	}
@@ -406,13 +440,13 @@ func (b *cmdOrgBuilder) memberAddRunEFn(cmd *cobra.Command, args []string) error
 	ctx := context.Background()
 	organization, err := orgSvc.FindOrganization(ctx, filter)
 	if err != nil {
-		return fmt.Errorf("failed to find org: %v", err)
+		return fmt.Errorf("failed to find org: %w", err)
 	}
 
 	var memberID influxdb.ID
 	err = memberID.DecodeFromString(b.memberID)
 	if err != nil {
-		return fmt.Errorf("failed to decode member id %s: %v", b.memberID, err)
+		return fmt.Errorf("failed to decode member id %s: %w", b.memberID, err)
 	}
 
 	return addMember(ctx, b.w, urmSVC, influxdb.UserResourceMapping{
@@ -463,7 +497,7 @@ func (b *cmdOrgBuilder) membersRemoveRunEFn(cmd *cobra.Command, args []string) e
 
 	orgSvc, urmSVC, _, err := b.svcFn()
 	if err != nil {
-		return fmt.Errorf("failed to initialize org service client: %v", err)
+		return fmt.Errorf("failed to initialize org service client: %w", err)
 	}
 
 	var filter influxdb.OrganizationFilter
@@ -475,7 +509,7 @@ func (b *cmdOrgBuilder) membersRemoveRunEFn(cmd *cobra.Command, args []string) e
 		var fID influxdb.ID
 		err := fID.DecodeFromString(b.id)
 		if err != nil {
-			return fmt.Errorf("failed to decode org id %s: %v", b.id, err)
+			return fmt.Errorf("failed to decode org id %s: %w", b.id, err)
 		}
 		filter.ID = &fID
 	}
@@ -483,13 +517,13 @@ func (b *cmdOrgBuilder) membersRemoveRunEFn(cmd *cobra.Command, args []string) e
 	ctx := context.Background()
 	organization, err := orgSvc.FindOrganization(ctx, filter)
 	if err != nil {
-		return fmt.Errorf("failed to find organization: %v", err)
+		return fmt.Errorf("failed to find organization: %w", err)
 	}
 
 	var memberID influxdb.ID
 	err = memberID.DecodeFromString(b.memberID)
 	if err != nil {
-		return fmt.Errorf("failed to decode member id %s: %v", b.memberID, err)
+		return fmt.Errorf("failed to decode member id %s: %w", b.memberID, err)
 	}
 
 	return removeMember(ctx, b.w, urmSVC, organization.ID, memberID)
@@ -534,7 +568,7 @@ func newOrganizationService() (influxdb.OrganizationService, error) {
 func memberList(ctx context.Context, b *cmdOrgBuilder, urmSVC influxdb.UserResourceMappingService, userSVC influxdb.UserService, f influxdb.UserResourceMappingFilter) error {
 	mps, _, err := urmSVC.FindUserResourceMappings(ctx, f)
 	if err != nil {
-		return fmt.Errorf("failed to find members: %v", err)
+		return fmt.Errorf("failed to find members: %w", err)
 	}
 
 	urs := make([]*influxdb.User, len(mps))
@@ -550,7 +584,7 @@ func memberList(ctx context.Context, b *cmdOrgBuilder, urmSVC influxdb.UserResou
 			defer func() { <-sem }()
 			usr, err := userSVC.FindUserByID(ctx, v.UserID)
 			if err != nil {
-				errC <- fmt.Errorf("failed to retrieve user details: %v", err)
+				errC <- fmt.Errorf("failed to retrieve user details: %w", err)
 				return
 			}
 			ursC <- struct {
@@ -590,7 +624,7 @@ func memberList(ctx context.Context, b *cmdOrgBuilder, urmSVC influxdb.UserResou
 
 func addMember(ctx context.Context, w io.Writer, urmSVC influxdb.UserResourceMappingService, urm influxdb.UserResourceMapping) error {
 	if err := urmSVC.CreateUserResourceMapping(ctx, &urm); err != nil {
-		return fmt.Errorf("failed to add member: %v", err)
+		return fmt.Errorf("failed to add member: %w", err)
 	}
 	_, err := fmt.Fprintf(w, "user %s has been added as a %s of %s: %s\n", urm.UserID, urm.UserType, urm.ResourceType, urm.ResourceID)
 	return err
@@ -598,7 +632,7 @@ func addMember(ctx context.Context, w io.Writer, urmSVC influxdb.UserResourceMap
 
 func removeMember(ctx context.Context, w io.Writer, urmSVC influxdb.UserResourceMappingService, resourceID, userID influxdb.ID) error {
 	if err := urmSVC.DeleteUserResourceMapping(ctx, resourceID, userID); err != nil {
-		return fmt.Errorf("failed to remove member: %v", err)
+		return fmt.Errorf("failed to remove member: %w", err)
 	}
 	_, err := fmt.Fprintf(w, "userID %s has been removed from ResourceID %s\n", userID, resourceID)
 	return err
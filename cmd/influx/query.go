@@ -1,27 +1,84 @@
 package main
 
 import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/csv"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/lang"
 	"github.com/influxdata/flux/repl"
 	_ "github.com/influxdata/flux/stdlib"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/query"
 	_ "github.com/influxdata/influxdb/query/stdlib"
 	"github.com/spf13/cobra"
 )
 
+// Output formats of the results of influx query.
+const (
+	queryOutputTable = "table"
+	queryOutputCSV   = "csv"
+	queryOutputJSON  = "json"
+	queryOutputLP    = "lp"
+)
+
 var queryFlags struct {
-	org organization
+	org       organization
+	output    string
+	file      string
+	gzip      bool
+	timeout   time.Duration
+	profilers []string
 }
 
 func cmdQuery(f *globalFlags, opts genericCLIOpts) *cobra.Command {
 	cmd := opts.newCmd("query [query literal or @/path/to/query.flux]", fluxQueryF)
 	cmd.Short = "Execute a Flux query"
 	cmd.Long = `Execute a literal Flux query provided as a string,
-or execute a literal Flux query contained in a file by specifying the file prefixed with an @ sign.`
+or execute a literal Flux query contained in a file by specifying the file prefixed with an @ sign.
+
+The --output flag selects the format of the results:
+
+	table  tables rendered as in the REPL (default)
+	csv    annotated CSV, as returned by the query API
+	json   a JSON object per line for each table, with its columns and rows
+	lp     line protocol, for tables with _measurement, _field, _value and _time columns
+
+The --file flag streams the results to a file instead of the terminal, as annotated CSV
+unless another --output is given, reporting the progress on stderr. With --gzip the file
+is gzip compressed. The file is removed if the query fails.
+
+The --timeout flag cancels the query once it has run for the given duration. The query is
+also cancelled on the first interrupt (Ctrl-C). A cancelled query reports how many results
+and tables were received before it was cancelled.
+
+The --profilers flag reports the statistics of the query once its results are received,
+on stdout after the tables or on stderr for the other outputs:
+
+	query     the durations of the stages of the query, its concurrency and allocated bytes
+	operator  the statistics of the operators, such as the values scanned by storage`
 	cmd.Args = cobra.ExactArgs(1)
 
 	queryFlags.org.register(cmd, true)
+	cmd.Flags().StringVar(&queryFlags.output, "output", queryOutputTable, "The format of the results: table, csv, json or lp")
+	cmd.Flags().StringVar(&queryFlags.file, "file", "", "The path of the file to write the results to")
+	cmd.Flags().BoolVar(&queryFlags.gzip, "gzip", false, "Compress the file of the results with gzip")
+	cmd.Flags().DurationVar(&queryFlags.timeout, "timeout", 0, "The maximum duration of the query, 0 is unlimited")
+	cmd.Flags().StringSliceVar(&queryFlags.profilers, "profilers", nil, "The profilers of the statistics of the query to report: query, operator")
 
 	return cmd
 }
@@ -35,14 +92,36 @@ func fluxQueryF(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	switch queryFlags.output {
+	case queryOutputTable, queryOutputCSV, queryOutputJSON, queryOutputLP:
+	default:
+		return fmt.Errorf("invalid output format %q: must be one of table, csv, json or lp", queryFlags.output)
+	}
+
+	for _, p := range queryFlags.profilers {
+		if err := query.ValidProfiler(p); err != nil {
+			return err
+		}
+	}
+
+	if queryFlags.file == "" && queryFlags.gzip {
+		return fmt.Errorf("the gzip flag requires the file flag")
+	}
+	if queryFlags.file != "" && queryFlags.output == queryOutputTable {
+		if cmd.Flags().Changed("output") {
+			return fmt.Errorf("table output can not be written to a file")
+		}
+		queryFlags.output = queryOutputCSV
+	}
+
 	q, err := repl.LoadQuery(args[0])
 	if err != nil {
-		return fmt.Errorf("failed to load query: %v", err)
+		return fmt.Errorf("failed to load query: %w", err)
 	}
 
 	orgSvc, err := newOrganizationService()
 	if err != nil {
-		return fmt.Errorf("failed to initialized organization service client: %v", err)
+		return fmt.Errorf("failed to initialized organization service client: %w", err)
 	}
 
 	orgID, err := queryFlags.org.getID(orgSvc)
@@ -52,14 +131,404 @@ func fluxQueryF(cmd *cobra.Command, args []string) error {
 
 	flux.FinalizeBuiltIns()
 
-	r, err := getFluxREPL(flags.host, flags.token, flags.skipVerify, orgID)
+	ctx, cancel := queryContext(queryFlags.timeout)
+	defer cancel()
+	fqs := newFluxQueryService()
+	fqs.Profilers = queryFlags.profilers
+	qs := &queryStatusService{QueryService: fqs}
+
+	switch {
+	case queryFlags.file != "":
+		err = writeQueryFile(ctx, cmd.ErrOrStderr(), qs, orgID, q, queryFlags.output, queryFlags.file, queryFlags.gzip)
+	case queryFlags.output != queryOutputTable:
+		if err = writeQueryResults(ctx, cmd.OutOrStdout(), qs, orgID, q, queryFlags.output); err != nil {
+			err = fmt.Errorf("failed to execute query: %w", err)
+		}
+	default:
+		r, rerr := getFluxREPL(ctx, qs, orgID)
+		if rerr != nil {
+			return fmt.Errorf("failed to get the flux REPL: %w", rerr)
+		}
+		if err = r.Input(q); err != nil {
+			err = fmt.Errorf("failed to execute query: %w", err)
+		}
+	}
+
+	// A query cancelled while its results are streamed may end without an
+	// error, so the context decides whether the results are complete.
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return qs.interrupted(ctxErr, queryFlags.timeout)
+	}
+	if err != nil || len(queryFlags.profilers) == 0 {
+		return err
+	}
+
+	w := cmd.ErrOrStderr()
+	if queryFlags.file == "" && queryFlags.output == queryOutputTable {
+		w = cmd.OutOrStdout()
+	}
+	return writeQueryProfile(w, queryFlags.profilers, qs.stats)
+}
+
+// writeQueryProfile writes the statistics of a query reported by each of the
+// profilers to w.
+func writeQueryProfile(w io.Writer, profilers []string, stats flux.Statistics) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, profiler := range profilers {
+		switch profiler {
+		case query.QueryProfiler:
+			fmt.Fprintln(tw, "Query profile:")
+			fmt.Fprintf(tw, "  total duration\t%s\n", stats.TotalDuration)
+			fmt.Fprintf(tw, "  compile duration\t%s\n", stats.CompileDuration)
+			fmt.Fprintf(tw, "  queue duration\t%s\n", stats.QueueDuration)
+			fmt.Fprintf(tw, "  plan duration\t%s\n", stats.PlanDuration)
+			fmt.Fprintf(tw, "  requeue duration\t%s\n", stats.RequeueDuration)
+			fmt.Fprintf(tw, "  execute duration\t%s\n", stats.ExecuteDuration)
+			fmt.Fprintf(tw, "  concurrency\t%d\n", stats.Concurrency)
+			fmt.Fprintf(tw, "  max allocated\t%d bytes\n", stats.MaxAllocated)
+			fmt.Fprintf(tw, "  total allocated\t%d bytes\n", stats.TotalAllocated)
+		case query.OperatorProfiler:
+			fmt.Fprintln(tw, "Operator profile:")
+			keys := make([]string, 0, len(stats.Metadata))
+			for k := range stats.Metadata {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				values := make([]string, 0, len(stats.Metadata[k]))
+				for _, v := range stats.Metadata[k] {
+					values = append(values, fmt.Sprint(v))
+				}
+				fmt.Fprintf(tw, "  %s\t%s\n", k, strings.Join(values, ", "))
+			}
+		}
+	}
+	return tw.Flush()
+}
+
+// queryContext returns the context of a query, cancelled once the timeout, if
+// any, expires or on the first interrupt signal. Further interrupts are no
+// longer caught, and terminate the process.
+func queryContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+		cancelCtx := cancel
+		cancel = func() {
+			cancelTimeout()
+			cancelCtx()
+		}
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		defer signal.Stop(sig)
+		select {
+		case <-sig:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// queryStatusService counts the results and tables of a query received from
+// the query service, to report the partial results of an interrupted query,
+// and keeps its statistics once its results are released.
+type queryStatusService struct {
+	query.QueryService
+	results int
+	tables  int
+	stats   flux.Statistics
+}
+
+func (s *queryStatusService) Query(ctx context.Context, req *query.Request) (flux.ResultIterator, error) {
+	results, err := s.QueryService.Query(ctx, req)
 	if err != nil {
-		return fmt.Errorf("failed to get the flux REPL: %v", err)
+		return nil, err
 	}
+	return &queryStatusResultIterator{ResultIterator: results, s: s}, nil
+}
 
-	if err := r.Input(q); err != nil {
-		return fmt.Errorf("failed to execute query: %v", err)
+// interrupted returns the error of a query interrupted with the error of its
+// context, describing the results received.
+func (s *queryStatusService) interrupted(err error, timeout time.Duration) error {
+	msg := "query cancelled"
+	if err == context.DeadlineExceeded {
+		msg = fmt.Sprintf("query timed out after %s", timeout)
+	}
+	if s.results == 0 {
+		return fmt.Errorf("%s: no results received", msg)
 	}
+	return fmt.Errorf("%s: results are partial, %d tables of %d results received", msg, s.tables, s.results)
+}
+
+type queryStatusResultIterator struct {
+	flux.ResultIterator
+	s *queryStatusService
+}
 
+func (ri *queryStatusResultIterator) Next() flux.Result {
+	ri.s.results++
+	return queryStatusResult{Result: ri.ResultIterator.Next(), s: ri.s}
+}
+
+func (ri *queryStatusResultIterator) Release() {
+	ri.ResultIterator.Release()
+	ri.s.stats = ri.ResultIterator.Statistics()
+}
+
+type queryStatusResult struct {
+	flux.Result
+	s *queryStatusService
+}
+
+func (r queryStatusResult) Tables() flux.TableIterator {
+	return queryStatusTables{TableIterator: r.Result.Tables(), s: r.s}
+}
+
+type queryStatusTables struct {
+	flux.TableIterator
+	s *queryStatusService
+}
+
+// Do counts the tables fully processed by f.
+func (t queryStatusTables) Do(f func(flux.Table) error) error {
+	return t.TableIterator.Do(func(tbl flux.Table) error {
+		if err := f(tbl); err != nil {
+			return err
+		}
+		t.s.tables++
+		return nil
+	})
+}
+
+// writeQueryResults executes the query q and writes its results to w in the
+// output format, as they are received.
+func writeQueryResults(ctx context.Context, w io.Writer, qs query.QueryService, orgID influxdb.ID, q, output string) error {
+	results, err := qs.Query(ctx, &query.Request{
+		OrganizationID: orgID,
+		Compiler:       lang.FluxCompiler{Query: q},
+	})
+	if err != nil {
+		return err
+	}
+	defer results.Release()
+
+	return writeQueryOutput(w, results, output)
+}
+
+// writeQueryOutput writes the results to w in the output format, other than
+// table, as they are received.
+func writeQueryOutput(w io.Writer, results flux.ResultIterator, output string) error {
+	var err error
+	switch output {
+	case queryOutputCSV:
+		_, err = csv.NewMultiResultEncoder(csv.DefaultEncoderConfig()).Encode(w, results)
+	case queryOutputJSON:
+		err = writeQueryJSON(w, results)
+	case queryOutputLP:
+		err = writeQueryLP(w, results)
+	default:
+		return fmt.Errorf("unsupported output format %q", output)
+	}
+	if err != nil {
+		return err
+	}
+	return results.Err()
+}
+
+// writeQueryFile executes the query q and streams its results to the file at
+// path in the output format, gzip compressed if gz is set, reporting the
+// progress to stderr. The file is removed if the query fails.
+func writeQueryFile(ctx context.Context, stderr io.Writer, qs query.QueryService, orgID influxdb.ID, q, output, path string, gz bool) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create results file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(path)
+		}
+	}()
+
+	var w io.Writer = f
+	var zw *gzip.Writer
+	if gz {
+		zw = gzip.NewWriter(f)
+		w = zw
+	}
+	bw := bufio.NewWriter(w)
+	pw := newQueryProgressWriter(bw, stderr, time.Now)
+
+	if err := writeQueryResults(ctx, pw, qs, orgID, q, output); err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write results file: %w", err)
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return fmt.Errorf("failed to write results file: %w", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write results file: %w", err)
+	}
+
+	pw.done(path)
+	return nil
+}
+
+// queryProgressInterval is the minimum interval between two reports of the
+// progress of the results written to a file.
+const queryProgressInterval = time.Second
+
+// queryProgressWriter counts the bytes of the results written to w and
+// reports them to out at most every queryProgressInterval.
+type queryProgressWriter struct {
+	w     io.Writer
+	out   io.Writer
+	now   func() time.Time
+	n     int64
+	start time.Time
+	last  time.Time
+}
+
+func newQueryProgressWriter(w, out io.Writer, now func() time.Time) *queryProgressWriter {
+	t := now()
+	return &queryProgressWriter{w: w, out: out, now: now, start: t, last: t}
+}
+
+func (p *queryProgressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.n += int64(n)
+	if t := p.now(); t.Sub(p.last) >= queryProgressInterval {
+		p.last = t
+		fmt.Fprintf(p.out, "\rwrote %d bytes in %s", p.n, t.Sub(p.start).Round(time.Second))
+	}
+	return n, err
+}
+
+// done reports the bytes of the results written to the file at path.
+func (p *queryProgressWriter) done(path string) {
+	if p.last != p.start {
+		fmt.Fprintln(p.out)
+	}
+	fmt.Fprintf(p.out, "wrote %d bytes of results to %s in %s\n", p.n, path, p.now().Sub(p.start).Round(time.Millisecond))
+}
+
+// queryJSONTable is the JSON output of a table of a result.
+type queryJSONTable struct {
+	Result   string            `json:"result"`
+	Table    int               `json:"table"`
+	Columns  []queryJSONColumn `json:"columns"`
+	GroupKey []string          `json:"groupKey"`
+	Rows     [][]interface{}   `json:"rows"`
+}
+
+type queryJSONColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// writeQueryJSON writes each table of results to w as a JSON object on its
+// own line, so that only a single table is held in memory at a time.
+func writeQueryJSON(w io.Writer, results flux.ResultIterator) error {
+	enc := json.NewEncoder(w)
+	for results.More() {
+		res := results.Next()
+		n := 0
+		err := res.Tables().Do(func(tbl flux.Table) error {
+			t := queryJSONTable{
+				Result:   res.Name(),
+				Table:    n,
+				Columns:  make([]queryJSONColumn, 0, len(tbl.Cols())),
+				GroupKey: make([]string, 0, len(tbl.Key().Cols())),
+				Rows:     [][]interface{}{},
+			}
+			n++
+			for _, c := range tbl.Cols() {
+				t.Columns = append(t.Columns, queryJSONColumn{Name: c.Label, Type: c.Type.String()})
+			}
+			for _, c := range tbl.Key().Cols() {
+				t.GroupKey = append(t.GroupKey, c.Label)
+			}
+
+			if err := tbl.Do(func(cr flux.ColReader) error {
+				for i := 0; i < cr.Len(); i++ {
+					row := make([]interface{}, len(cr.Cols()))
+					for j := range cr.Cols() {
+						row[j] = queryJSONValue(cr, i, j)
+					}
+					t.Rows = append(t.Rows, row)
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			return enc.Encode(t)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queryJSONValue returns the value of the row i of the column j of cr, as a
+// value encoded by encoding/json. Times are RFC3339 strings, and floats not
+// representable in JSON are strings too.
+func queryJSONValue(cr flux.ColReader, i, j int) interface{} {
+	v := execute.ValueForRow(cr, i, j)
+	if v.IsNull() {
+		return nil
+	}
+	switch cr.Cols()[j].Type {
+	case flux.TString:
+		return v.Str()
+	case flux.TInt:
+		return v.Int()
+	case flux.TUInt:
+		return v.UInt()
+	case flux.TFloat:
+		if f := v.Float(); math.IsNaN(f) || math.IsInf(f, 0) {
+			return strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		return v.Float()
+	case flux.TBool:
+		return v.Bool()
+	case flux.TTime:
+		return v.Time().Time().Format(time.RFC3339Nano)
+	}
+	return nil
+}
+
+// writeQueryLP writes the rows of results to w as line protocol, a table at
+// a time.
+func writeQueryLP(w io.Writer, results flux.ResultIterator) error {
+	for results.More() {
+		err := results.Next().Tables().Do(func(tbl flux.Table) error {
+			return tbl.Do(func(cr flux.ColReader) error {
+				points, err := colReaderPoints(cr)
+				if err != nil {
+					return err
+				}
+				for _, p := range points {
+					if _, err := fmt.Fprintln(w, p.String()); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/csv"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/query/mock"
+)
+
+const queryTestResults = `#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,double,string,string,string
+#group,false,false,true,true,false,false,true,true,true
+#default,_result,,,,,,,,
+,result,table,_start,_stop,_time,_value,_field,_measurement,host
+,,0,2020-01-01T00:00:00Z,2020-01-02T00:00:00Z,2020-01-01T00:00:02Z,2.5,usage,cpu,a
+,,0,2020-01-01T00:00:00Z,2020-01-02T00:00:00Z,2020-01-01T00:00:04Z,,usage,cpu,a
+
+#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,string,string,string,string
+#group,false,false,true,true,false,false,true,true,true
+#default,_result,,,,,,,,
+,result,table,_start,_stop,_time,_value,_field,_measurement,host
+,,1,2020-01-01T00:00:00Z,2020-01-02T00:00:00Z,2020-01-01T00:00:03Z,up,state,svc,a b
+`
+
+func queryTestResultIterator(t *testing.T) flux.ResultIterator {
+	t.Helper()
+	results, err := csv.NewMultiResultDecoder(csv.ResultDecoderConfig{}).Decode(ioutil.NopCloser(strings.NewReader(queryTestResults)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+func TestWriteQueryJSON(t *testing.T) {
+	results := queryTestResultIterator(t)
+	defer results.Release()
+
+	var buf bytes.Buffer
+	if err := writeQueryJSON(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"result":"_result","table":0,"columns":[{"name":"_start","type":"time"},{"name":"_stop","type":"time"},{"name":"_time","type":"time"},{"name":"_value","type":"float"},{"name":"_field","type":"string"},{"name":"_measurement","type":"string"},{"name":"host","type":"string"}],"groupKey":["_start","_stop","_field","_measurement","host"],"rows":[["2020-01-01T00:00:00Z","2020-01-02T00:00:00Z","2020-01-01T00:00:02Z",2.5,"usage","cpu","a"],["2020-01-01T00:00:00Z","2020-01-02T00:00:00Z","2020-01-01T00:00:04Z",null,"usage","cpu","a"]]}
+{"result":"_result","table":1,"columns":[{"name":"_start","type":"time"},{"name":"_stop","type":"time"},{"name":"_time","type":"time"},{"name":"_value","type":"string"},{"name":"_field","type":"string"},{"name":"_measurement","type":"string"},{"name":"host","type":"string"}],"groupKey":["_start","_stop","_field","_measurement","host"],"rows":[["2020-01-01T00:00:00Z","2020-01-02T00:00:00Z","2020-01-01T00:00:03Z","up","state","svc","a b"]]}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected JSON:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteQueryLP(t *testing.T) {
+	results := queryTestResultIterator(t)
+	defer results.Release()
+
+	var buf bytes.Buffer
+	if err := writeQueryLP(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+
+	// Points are written in the order of the tables, without null values.
+	want := "cpu,host=a usage=2.5 1577836802000000000\n" +
+		"svc,host=a\\ b state=\"up\" 1577836803000000000\n"
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected line protocol:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteQueryFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "influx-query-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	qs := &mock.QueryService{
+		QueryF: func(ctx context.Context, req *query.Request) (flux.ResultIterator, error) {
+			return queryTestResultIterator(t), nil
+		},
+	}
+	want := "cpu,host=a usage=2.5 1577836802000000000\n" +
+		"svc,host=a\\ b state=\"up\" 1577836803000000000\n"
+
+	for _, gz := range []bool{false, true} {
+		path := filepath.Join(dir, "results.lp")
+		var stderr bytes.Buffer
+		if err := writeQueryFile(context.Background(), &stderr, qs, 1, "q", queryOutputLP, path, gz); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r io.Reader = f
+		if gz {
+			if r, err = gzip.NewReader(f); err != nil {
+				t.Fatal(err)
+			}
+		}
+		got, err := ioutil.ReadAll(r)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("unexpected results with gzip %v:\ngot:\n%s\nwant:\n%s", gz, got, want)
+		}
+		if !strings.Contains(stderr.String(), "wrote 86 bytes of results to "+path) {
+			t.Errorf("unexpected progress with gzip %v: %q", gz, stderr.String())
+		}
+	}
+
+	// The file is removed if the query fails.
+	path := filepath.Join(dir, "failed.csv")
+	qs.QueryF = func(ctx context.Context, req *query.Request) (flux.ResultIterator, error) {
+		return nil, errors.New("query failed")
+	}
+	if err := writeQueryFile(context.Background(), ioutil.Discard, qs, 1, "q", queryOutputCSV, path, false); err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected the file to be removed, got %v", err)
+	}
+}
+
+func TestQueryProgressWriter(t *testing.T) {
+	now := time.Unix(0, 0)
+	clock := func() time.Time { return now }
+
+	var out bytes.Buffer
+	pw := newQueryProgressWriter(ioutil.Discard, &out, clock)
+	pw.Write(make([]byte, 10))
+	now = now.Add(500 * time.Millisecond)
+	pw.Write(make([]byte, 10))
+	now = now.Add(time.Second)
+	pw.Write(make([]byte, 10))
+	pw.done("results.csv")
+
+	want := "\rwrote 30 bytes in 2s\nwrote 30 bytes of results to results.csv in 1.5s\n"
+	if got := out.String(); got != want {
+		t.Errorf("unexpected progress:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestQueryStatusService(t *testing.T) {
+	qs := &queryStatusService{
+		QueryService: &mock.QueryService{
+			QueryF: func(ctx context.Context, req *query.Request) (flux.ResultIterator, error) {
+				return queryTestResultIterator(t), nil
+			},
+		},
+	}
+
+	err := qs.interrupted(context.Canceled, 0)
+	if got, want := err.Error(), "query cancelled: no results received"; got != want {
+		t.Errorf("unexpected error: got %q, want %q", got, want)
+	}
+
+	if err := writeQueryResults(context.Background(), ioutil.Discard, qs, 1, "q", queryOutputCSV); err != nil {
+		t.Fatal(err)
+	}
+	err = qs.interrupted(context.DeadlineExceeded, 30*time.Second)
+	if got, want := err.Error(), "query timed out after 30s: results are partial, 2 tables of 1 results received"; got != want {
+		t.Errorf("unexpected error: got %q, want %q", got, want)
+	}
+}
+
+func TestQueryContext(t *testing.T) {
+	ctx, cancel := queryContext(time.Millisecond)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the query context to time out")
+	}
+	if err := ctx.Err(); err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestWriteQueryProfile(t *testing.T) {
+	stats := flux.Statistics{
+		TotalDuration:   10 * time.Millisecond,
+		ExecuteDuration: 8 * time.Millisecond,
+		Concurrency:     2,
+		MaxAllocated:    1024,
+		TotalAllocated:  4096,
+		Metadata: flux.Metadata{
+			"influxdb/scanned-values": []interface{}{"10", "20"},
+			"influxdb/scanned-bytes":  []interface{}{"160", "320"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeQueryProfile(&buf, []string{query.QueryProfiler, query.OperatorProfiler}, stats); err != nil {
+		t.Fatal(err)
+	}
+	want := `Query profile:
+  total duration    10ms
+  compile duration  0s
+  queue duration    0s
+  plan duration     0s
+  requeue duration  0s
+  execute duration  8ms
+  concurrency       2
+  max allocated     1024 bytes
+  total allocated   4096 bytes
+Operator profile:
+  influxdb/scanned-bytes   160, 320
+  influxdb/scanned-values  10, 20
+`
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected profile:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -3,7 +3,14 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/c-bata/go-prompt"
 	"github.com/influxdata/flux"
 	"github.com/influxdata/flux/repl"
 	_ "github.com/influxdata/flux/stdlib"
@@ -15,15 +22,49 @@ import (
 )
 
 var replFlags struct {
-	org organization
+	org         organization
+	historyFile string
+	noHistory   bool
 }
 
 func cmdREPL(f *globalFlags, opt genericCLIOpts) *cobra.Command {
 	cmd := opt.newCmd("repl", replF)
 	cmd.Short = "Interactive Flux REPL (read-eval-print-loop)"
+	cmd.Long = `Interactive Flux REPL (read-eval-print-loop)
+
+The lines entered are kept in a history across sessions, in ~/.influx_history unless
+--history-file is given, or not at all with --no-history. Consecutive duplicate lines
+are kept once. Up and Down browse the history, and Ctrl-R replaces the line with the
+latest line of the history containing it, or with older ones when pressed again.
+
+Tab completes the names of the buckets in from(bucket: "..."), the measurements
+compared to r._measurement, the members of the imported packages, and the Flux
+identifiers, with the signature of functions.
+
+Lines starting with a backslash are meta-commands controlling the session:
+
+	\buckets                list the buckets of the organization
+	\orgs                   list the organizations
+	\load FILE              execute the Flux of FILE in the session
+	\save FILE              save the Flux entered in the session to FILE
+	\set [NAME=VALUE ...]   show or change the settings:
+	                        format=table|csv|json|lp, timing=on|off
+	\timing [on|off]        toggle or set the report of the time taken by each query
+	\help                   show the meta-commands
+
+Ctrl-C cancels the running query, and Ctrl-D exits.`
 	cmd.Args = cobra.NoArgs
 
 	replFlags.org.register(cmd, false)
+	opts := flagOpts{
+		{
+			DestP: &replFlags.historyFile,
+			Flag:  "history-file",
+			Desc:  "The path of the history file, ~/.influx_history by default",
+		},
+	}
+	opts.mustRegister(cmd)
+	cmd.Flags().BoolVar(&replFlags.noHistory, "no-history", false, "Do not keep a history of the lines entered across sessions")
 
 	return cmd
 }
@@ -47,28 +88,138 @@ func replF(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	flux.FinalizeBuiltIns()
-
-	r, err := getFluxREPL(flags.host, flags.token, flags.skipVerify, orgID)
+	h, err := replHistoryFromFlags()
 	if err != nil {
 		return err
 	}
 
-	r.Run()
+	flux.FinalizeBuiltIns()
+
+	q := &interruptibleQuerier{
+		Querier: &query.REPLQuerier{
+			OrganizationID: orgID,
+			QueryService:   newFluxQueryService(),
+		},
+		out: os.Stdout,
+	}
+	// DefaultDependencies are noop deps, which is safe since we send all
+	// queries to the server side.
+	s := &replSession{
+		r:         repl.New(context.Background(), flux.NewDefaultDependencies(), q),
+		querier:   q,
+		completer: newREPLCompleter(q.Querier),
+		orgs:      orgSVC,
+		out:       os.Stdout,
+		now:       time.Now,
+	}
+	runREPL(cmd.ErrOrStderr(), s, h)
 	return nil
 }
 
-func getFluxREPL(addr, token string, skipVerify bool, orgID platform.ID) (*repl.REPL, error) {
-	qs := &http.FluxQueryService{
-		Addr:               addr,
-		Token:              token,
-		InsecureSkipVerify: skipVerify,
+// replHistoryFromFlags loads the REPL history of the flags.
+func replHistoryFromFlags() (*replHistory, error) {
+	if replFlags.noHistory {
+		return loadREPLHistory("", replHistorySize)
+	}
+	path := replFlags.historyFile
+	if path == "" {
+		var err error
+		if path, err = defaultREPLHistoryPath(); err != nil {
+			return nil, fmt.Errorf("failed to locate the history file: %w", err)
+		}
+	}
+	return loadREPLHistory(path, replHistorySize)
+}
+
+// runREPL reads the lines entered in the session s until Ctrl-D, adding them
+// to the history h. The running query is cancelled on an interrupt.
+func runREPL(stderr io.Writer, s *replSession, h *replHistory) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT)
+	defer signal.Stop(sigs)
+	go func() {
+		for range sigs {
+			s.querier.cancel()
+		}
+	}()
+
+	search := &replHistorySearch{h: h}
+	p := prompt.New(
+		func(line string) {
+			if err := h.add(line); err != nil {
+				fmt.Fprintln(stderr, "Error:", err)
+			}
+			if err := s.input(line); err != nil {
+				fmt.Fprintln(s.out, "Error:", err)
+			}
+		},
+		s.completer.complete,
+		prompt.OptionPrefix("> "),
+		prompt.OptionCompletionWordSeparator(replWordSeparators),
+		prompt.OptionTitle("flux"),
+		prompt.OptionHistory(h.lines),
+		prompt.OptionAddKeyBind(search.keyBind()),
+	)
+	p.Run()
+}
+
+// interruptibleQuerier is a repl.Querier whose running query can be cancelled.
+// Unless the format is table, the REPL displays, the querier writes the
+// results to out in the format and returns no results to the REPL.
+type interruptibleQuerier struct {
+	repl.Querier
+	format string
+	out    io.Writer
+
+	mu         sync.Mutex
+	cancelFunc context.CancelFunc
+}
+
+func (q *interruptibleQuerier) Query(ctx context.Context, deps flux.Dependencies, compiler flux.Compiler) (flux.ResultIterator, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	q.mu.Lock()
+	q.cancelFunc = cancel
+	q.mu.Unlock()
+
+	results, err := q.Querier.Query(ctx, deps, compiler)
+	if err != nil || q.format == "" || q.format == queryOutputTable {
+		return results, err
+	}
+	defer results.Release()
+	if err := writeQueryOutput(q.out, results, q.format); err != nil {
+		return nil, err
 	}
+	return flux.NewSliceResultIterator(nil), nil
+}
+
+// cancel cancels the running query, if any.
+func (q *interruptibleQuerier) cancel() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.cancelFunc != nil {
+		q.cancelFunc()
+		q.cancelFunc = nil
+	}
+}
+
+// newFluxQueryService returns a client of the query API of the host of the
+// global flags.
+func newFluxQueryService() *http.FluxQueryService {
+	return &http.FluxQueryService{
+		Addr:               flags.host,
+		Token:              flags.token,
+		InsecureSkipVerify: flags.skipVerify,
+	}
+}
+
+// getFluxREPL returns a REPL sending its queries to qs. Queries are cancelled
+// when ctx is done.
+func getFluxREPL(ctx context.Context, qs query.QueryService, orgID platform.ID) (*repl.REPL, error) {
 	q := &query.REPLQuerier{
 		OrganizationID: orgID,
 		QueryService:   qs,
 	}
-	// background context is OK here, and DefaultDependencies are noop deps.  Also safe
-	// since we send all queries to the server side.
-	return repl.New(context.Background(), flux.NewDefaultDependencies(), q), nil
+	// DefaultDependencies are noop deps, which is safe since we send all
+	// queries to the server side.
+	return repl.New(ctx, flux.NewDefaultDependencies(), q), nil
 }
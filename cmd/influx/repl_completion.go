@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/c-bata/go-prompt"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/ast"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/lang"
+	"github.com/influxdata/flux/parser"
+	"github.com/influxdata/flux/repl"
+	"github.com/influxdata/flux/semantic"
+	"github.com/influxdata/flux/values"
+)
+
+const (
+	// replCompletionTimeout bounds the metadata queries of the completion,
+	// which block the prompt.
+	replCompletionTimeout = 2 * time.Second
+	// replCompletionTTL is the time the results of the metadata queries are
+	// cached.
+	replCompletionTTL = time.Minute
+)
+
+// replWordSeparators end the word completed before the cursor.
+const replWordSeparators = " \t\n()[]{},:=+-*/<>!|\"'"
+
+var (
+	// replBucketStringRE matches a bucket name being typed.
+	replBucketStringRE = regexp.MustCompile(`bucket\s*:\s*"([^"]*)$`)
+	// replBucketRE matches the bucket names of a line.
+	replBucketRE = regexp.MustCompile(`bucket\s*:\s*"([^"]+)"`)
+	// replMeasurementStringRE matches a measurement being compared to.
+	replMeasurementStringRE = regexp.MustCompile(`(?:\._measurement|\[\s*"_measurement"\s*\])\s*(?:==|!=)\s*"([^"]*)$`)
+)
+
+// replCompleter completes the lines of the REPL: the meta-commands, the
+// names of the buckets in from(bucket: "..."), the measurements compared to
+// r._measurement, the members of the imported packages, and the identifiers
+// of the Flux prelude and of the variables defined in the session. Functions are suggested with
+// their signature.
+//
+// Buckets and measurements are read with metadata queries, whose results
+// are cached for replCompletionTTL.
+type replCompleter struct {
+	querier repl.Querier
+	now     func() time.Time
+
+	identifiers  []prompt.Suggest
+	imports      map[string]string // package name to path
+	variables    map[string]bool
+	buckets      replCachedNames
+	measurements map[string]replCachedNames
+}
+
+// replCachedNames are the names returned by a metadata query.
+type replCachedNames struct {
+	names []string
+	at    time.Time
+}
+
+// newREPLCompleter returns a completer querying metadata with q. The Flux
+// built-ins must be finalized.
+func newREPLCompleter(q repl.Querier) *replCompleter {
+	c := &replCompleter{
+		querier:      q,
+		now:          time.Now,
+		imports:      make(map[string]string),
+		variables:    make(map[string]bool),
+		measurements: make(map[string]replCachedNames),
+	}
+	flux.Prelude().Range(func(name string, v values.Value) {
+		if !strings.HasPrefix(name, "_") {
+			c.identifiers = append(c.identifiers, replSuggest(name, v))
+		}
+	})
+	sort.Slice(c.identifiers, func(i, j int) bool {
+		return c.identifiers[i].Text < c.identifiers[j].Text
+	})
+	return c
+}
+
+// replSuggest returns the suggestion of the value v named name, with the
+// signature of functions.
+func replSuggest(name string, v values.Value) prompt.Suggest {
+	s := prompt.Suggest{Text: name}
+	if _, ok := v.(values.Function); ok {
+		s.Description = replSignature(v.PolyType())
+	}
+	return s
+}
+
+// replSignature returns the signature of the parameters of the function
+// type t, such as (<-tables, fn, ?onEmpty: string): the pipe parameter comes
+// first, then the required parameters and the optional ones prefixed by ?,
+// with the type of the parameters of a basic type.
+func replSignature(t semantic.PolyType) string {
+	f, ok := t.(interface {
+		Signature() semantic.FunctionPolySignature
+	})
+	if !ok {
+		return ""
+	}
+	sig := f.Signature()
+	required := make(map[string]bool, len(sig.Required))
+	for _, name := range sig.Required {
+		required[name] = true
+	}
+
+	names := make([]string, 0, len(sig.Parameters))
+	for name := range sig.Parameters {
+		if name != sig.PipeArgument {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if required[names[i]] != required[names[j]] {
+			return required[names[i]]
+		}
+		return names[i] < names[j]
+	})
+
+	params := make([]string, 0, len(sig.Parameters))
+	if sig.PipeArgument != "" {
+		params = append(params, "<-"+sig.PipeArgument)
+	}
+	for _, name := range names {
+		param := name
+		if !required[name] {
+			param = "?" + param
+		}
+		switch n := sig.Parameters[name].Nature(); n {
+		case semantic.String, semantic.Int, semantic.UInt, semantic.Float, semantic.Bool,
+			semantic.Time, semantic.Duration, semantic.Regexp:
+			param += ": " + n.String()
+		}
+		params = append(params, param)
+	}
+	return "(" + strings.Join(params, ", ") + ")"
+}
+
+// observe records the imports and variables of a line entered in the REPL.
+func (c *replCompleter) observe(line string) {
+	pkg := parser.ParseSource(line)
+	if ast.Check(pkg) > 0 {
+		return
+	}
+	for _, f := range pkg.Files {
+		for _, imp := range f.Imports {
+			path := imp.Path.Value
+			name := path[strings.LastIndex(path, "/")+1:]
+			if imp.As != nil {
+				name = imp.As.Name
+			}
+			c.imports[name] = path
+		}
+		for _, stmt := range f.Body {
+			if v, ok := stmt.(*ast.VariableAssignment); ok {
+				c.variables[v.ID.Name] = true
+			}
+		}
+	}
+}
+
+func (c *replCompleter) complete(d prompt.Document) []prompt.Suggest {
+	return c.suggest(d.TextBeforeCursor())
+}
+
+// suggest returns the suggestions completing the text before the cursor.
+func (c *replCompleter) suggest(text string) []prompt.Suggest {
+	word := text[strings.LastIndexAny(text, replWordSeparators)+1:]
+
+	if strings.HasPrefix(text, `\`) {
+		if strings.ContainsAny(text, " \t") {
+			return nil
+		}
+		return replSuggestCommands(text)
+	}
+	if replBucketStringRE.MatchString(text) {
+		return replSuggestNames(c.bucketNames(), word)
+	}
+	if replMeasurementStringRE.MatchString(text) {
+		m := replBucketRE.FindAllStringSubmatch(text, -1)
+		if len(m) == 0 {
+			return nil
+		}
+		return replSuggestNames(c.measurementNames(m[len(m)-1][1]), word)
+	}
+	if strings.Count(text, `"`)%2 == 1 || word == "" {
+		return nil
+	}
+
+	if i := strings.Index(word, "."); i >= 0 {
+		return c.members(word[:i], word)
+	}
+	s := make([]prompt.Suggest, 0, len(c.identifiers)+len(c.variables)+len(c.imports))
+	s = append(s, c.identifiers...)
+	for name := range c.variables {
+		s = append(s, prompt.Suggest{Text: name})
+	}
+	for name, path := range c.imports {
+		s = append(s, prompt.Suggest{Text: name, Description: "package " + path})
+	}
+	sort.SliceStable(s, func(i, j int) bool { return s[i].Text < s[j].Text })
+	return prompt.FilterHasPrefix(s, word, false)
+}
+
+// members returns the members of the imported package name completing word.
+func (c *replCompleter) members(name, word string) []prompt.Suggest {
+	path, ok := c.imports[name]
+	if !ok {
+		return nil
+	}
+	pkg, ok := flux.StdLib().ImportPackageObject(path)
+	if !ok {
+		return nil
+	}
+	var s []prompt.Suggest
+	pkg.Range(func(member string, v values.Value) {
+		s = append(s, replSuggest(name+"."+member, v))
+	})
+	sort.Slice(s, func(i, j int) bool { return s[i].Text < s[j].Text })
+	return prompt.FilterHasPrefix(s, word, false)
+}
+
+func replSuggestNames(names []string, word string) []prompt.Suggest {
+	s := make([]prompt.Suggest, len(names))
+	for i, name := range names {
+		s[i] = prompt.Suggest{Text: name}
+	}
+	return prompt.FilterHasPrefix(s, word, false)
+}
+
+// bucketNames returns the names of the buckets of the organization.
+func (c *replCompleter) bucketNames() []string {
+	if c.now().Sub(c.buckets.at) < replCompletionTTL {
+		return c.buckets.names
+	}
+	c.buckets = replCachedNames{
+		names: c.queryNames(`buckets() |> keep(columns: ["name"])`, "name"),
+		at:    c.now(),
+	}
+	return c.buckets.names
+}
+
+// measurementNames returns the measurements of the bucket.
+func (c *replCompleter) measurementNames(bucket string) []string {
+	if m, ok := c.measurements[bucket]; ok && c.now().Sub(m.at) < replCompletionTTL {
+		return m.names
+	}
+	q := `import "influxdata/influxdb/v1"
+v1.measurements(bucket: ` + formatFluxString(bucket) + `)`
+	m := replCachedNames{names: c.queryNames(q, "_value"), at: c.now()}
+	c.measurements[bucket] = m
+	return m.names
+}
+
+// queryNames returns the sorted distinct values of the string column col of
+// the results of the query q. Errors are ignored, since completion is only
+// a convenience, and return no names.
+func (c *replCompleter) queryNames(q, col string) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), replCompletionTimeout)
+	defer cancel()
+
+	results, err := c.querier.Query(ctx, flux.NewDefaultDependencies(), lang.FluxCompiler{Query: q})
+	if err != nil {
+		return nil
+	}
+	defer results.Release()
+
+	seen := make(map[string]bool)
+	for results.More() {
+		err := results.Next().Tables().Do(func(tbl flux.Table) error {
+			j := execute.ColIdx(col, tbl.Cols())
+			if j < 0 || tbl.Cols()[j].Type != flux.TString {
+				return tbl.Do(func(flux.ColReader) error { return nil })
+			}
+			return tbl.Do(func(cr flux.ColReader) error {
+				vs := cr.Strings(j)
+				for i := 0; i < vs.Len(); i++ {
+					if vs.IsValid(i) {
+						seen[vs.ValueString(i)] = true
+					}
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			return nil
+		}
+	}
+	results.Release()
+	if results.Err() != nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// formatFluxString formats s as a Flux string literal.
+func formatFluxString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "${", `\${`)
+	return `"` + r.Replace(s) + `"`
+}
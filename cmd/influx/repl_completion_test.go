@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/c-bata/go-prompt"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/lang"
+)
+
+func init() {
+	flux.FinalizeBuiltIns()
+}
+
+// replNamesQuerier answers the metadata queries of the completion, and the
+// other queries of the REPL, with a column of names.
+type replNamesQuerier struct {
+	queries []string
+}
+
+func (q *replNamesQuerier) Query(ctx context.Context, deps flux.Dependencies, compiler flux.Compiler) (flux.ResultIterator, error) {
+	var text string
+	if c, ok := compiler.(lang.FluxCompiler); ok {
+		text = c.Query
+		q.queries = append(q.queries, text)
+	}
+
+	col, names := "name", []string{"telegraf", "_monitoring", "tasks"}
+	if strings.Contains(text, "v1.measurements") {
+		col, names = "_value", []string{"mem", "cpu"}
+	}
+	tbl := &executetest.Table{ColMeta: []flux.ColMeta{{Label: col, Type: flux.TString}}}
+	for _, name := range names {
+		tbl.Data = append(tbl.Data, []interface{}{name})
+	}
+	return flux.NewSliceResultIterator([]flux.Result{executetest.NewResult([]*executetest.Table{tbl})}), nil
+}
+
+func suggestTexts(s []prompt.Suggest) []string {
+	texts := make([]string, len(s))
+	for i := range s {
+		texts[i] = s[i].Text
+	}
+	return texts
+}
+
+func TestREPLCompleter(t *testing.T) {
+	q := &replNamesQuerier{}
+	c := newREPLCompleter(q)
+	now := time.Unix(0, 0)
+	c.now = func() time.Time { return now }
+
+	if got, want := suggestTexts(c.suggest(`from(bucket: "t`)), []string{"tasks", "telegraf"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected buckets: got %q, want %q", got, want)
+	}
+	if got, want := suggestTexts(c.suggest(`from(bucket:"`)), []string{"_monitoring", "tasks", "telegraf"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected buckets: got %q, want %q", got, want)
+	}
+	if len(q.queries) != 1 {
+		t.Fatalf("buckets should be cached, got queries %q", q.queries)
+	}
+	now = now.Add(replCompletionTTL)
+	c.suggest(`from(bucket: "`)
+	if len(q.queries) != 2 {
+		t.Fatalf("buckets should be queried again after the TTL, got queries %q", q.queries)
+	}
+
+	line := `from(bucket: "telegraf") |> range(start: -1h) |> filter(fn: (r) => r._measurement == "c`
+	if got, want := suggestTexts(c.suggest(line)), []string{"cpu"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected measurements: got %q, want %q", got, want)
+	}
+	if got := q.queries[len(q.queries)-1]; !strings.Contains(got, `v1.measurements(bucket: "telegraf")`) {
+		t.Fatalf("unexpected measurements query: %q", got)
+	}
+
+	s := c.suggest(`from(bucket: "telegraf") |> fil`)
+	if got, want := suggestTexts(s), []string{"fill", "filter"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected identifiers: got %q, want %q", got, want)
+	}
+	if got, want := s[1].Description, "(<-tables, fn, ?onEmpty: string)"; got != want {
+		t.Fatalf("unexpected signature: got %q, want %q", got, want)
+	}
+
+	// Imports and variables are completed once entered.
+	if got := c.suggest(`x = strings.toU`); len(got) != 0 {
+		t.Fatalf("package should not be completed before its import: %q", suggestTexts(got))
+	}
+	c.observe(`import "strings"`)
+	c.observe(`myTable = from(bucket: "telegraf")`)
+	s = c.suggest(`x = strings.toU`)
+	if got, want := suggestTexts(s), []string{"strings.toUpper"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected members: got %q, want %q", got, want)
+	}
+	if got, want := s[0].Description, "(v: string)"; got != want {
+		t.Fatalf("unexpected signature: got %q, want %q", got, want)
+	}
+	if got, want := suggestTexts(c.suggest(`myT`)), []string{"myTable"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected variables: got %q, want %q", got, want)
+	}
+
+	if got, want := suggestTexts(c.suggest(`\b`)), []string{`\buckets`}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected meta-commands: got %q, want %q", got, want)
+	}
+
+	// Strings other than bucket names and measurements are not completed.
+	if got := c.suggest(`x = "fil`); len(got) != 0 {
+		t.Fatalf("unexpected suggestions in a string: %q", suggestTexts(got))
+	}
+}
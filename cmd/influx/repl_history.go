@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/c-bata/go-prompt"
+)
+
+const (
+	// replHistoryFile is the name of the file of the REPL history in the
+	// home directory of the user.
+	replHistoryFile = ".influx_history"
+	// replHistorySize is the number of lines of the REPL history kept
+	// across sessions.
+	replHistorySize = 1000
+)
+
+// defaultREPLHistoryPath returns the path of the REPL history file in the
+// home directory of the user.
+func defaultREPLHistoryPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, replHistoryFile), nil
+}
+
+// replHistory is the history of the lines entered in the REPL. Each line is
+// appended to the history file as it is entered, so that the history is kept
+// across sessions, and consecutive duplicate lines are only kept once.
+type replHistory struct {
+	path  string // empty if the history is not persisted
+	size  int
+	lines []string
+}
+
+// loadREPLHistory loads the last size lines of the history file at path,
+// which may not exist yet. The file is rewritten with only these lines if it
+// holds more. An empty path loads an empty history that is not persisted.
+func loadREPLHistory(path string, size int) (*replHistory, error) {
+	h := &replHistory{path: path, size: size}
+	if path == "" {
+		return h, nil
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer f.Close()
+
+	n := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		n++
+		h.append(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	if n > len(h.lines) {
+		if err := h.rewrite(); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// append appends the line to the lines of the history, returning false if it
+// is empty or the same as the last line.
+func (h *replHistory) append(line string) bool {
+	if strings.TrimSpace(line) == "" {
+		return false
+	}
+	if n := len(h.lines); n > 0 && h.lines[n-1] == line {
+		return false
+	}
+	h.lines = append(h.lines, line)
+	if len(h.lines) > h.size {
+		h.lines = h.lines[len(h.lines)-h.size:]
+	}
+	return true
+}
+
+// add adds the line entered in the REPL to the history and its file.
+func (h *replHistory) add(line string) error {
+	if !h.append(line) || h.path == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(h.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	if _, err := fmt.Fprintln(f, line); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	return f.Close()
+}
+
+// rewrite replaces the history file with the lines of the history.
+func (h *replHistory) rewrite() error {
+	tmp := h.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	for _, line := range h.lines {
+		fmt.Fprintln(w, line)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	return os.Rename(tmp, h.path)
+}
+
+// search returns the index of the latest line of the history before the
+// index before containing the text, or -1 if there is none.
+func (h *replHistory) search(text string, before int) int {
+	if before > len(h.lines) {
+		before = len(h.lines)
+	}
+	for i := before - 1; i >= 0; i-- {
+		if strings.Contains(h.lines[i], text) {
+			return i
+		}
+	}
+	return -1
+}
+
+// replHistorySearch is the reverse search of the REPL history bound to
+// Ctrl-R. The text typed is replaced with the latest line of the history
+// containing it, and each following Ctrl-R replaces the line with the
+// previous match, until the line is edited.
+type replHistorySearch struct {
+	h     *replHistory
+	text  string // text searched
+	match int    // index of the line of the last match
+	line  string // last match, to tell whether the line was edited since
+}
+
+func (s *replHistorySearch) next(buf *prompt.Buffer) {
+	before := len(s.h.lines)
+	if current := buf.Text(); current != s.line || s.line == "" {
+		s.text = current
+	} else {
+		before = s.match
+	}
+
+	i := s.h.search(s.text, before)
+	if i < 0 {
+		return
+	}
+	s.match, s.line = i, s.h.lines[i]
+
+	text := buf.Text()
+	buf.CursorRight(len([]rune(text)))
+	buf.DeleteBeforeCursor(len([]rune(text)))
+	buf.InsertText(s.line, false, true)
+}
+
+// keyBind returns the key binding of the search.
+func (s *replHistorySearch) keyBind() prompt.KeyBind {
+	return prompt.KeyBind{Key: prompt.ControlR, Fn: s.next}
+}
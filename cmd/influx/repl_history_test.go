@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/c-bata/go-prompt"
+)
+
+func TestREPLHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "influx-repl-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "history")
+
+	h, err := loadREPLHistory(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"a = 1", "a = 1", "", "b = 2", "a = 1", "c = 3", "d = 4"} {
+		if err := h.add(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []string{"a = 1", "c = 3", "d = 4"}; !reflect.DeepEqual(h.lines, want) {
+		t.Fatalf("unexpected history: got %q, want %q", h.lines, want)
+	}
+
+	// The next session loads the last lines, and trims the file.
+	h, err = loadREPLHistory(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a = 1", "c = 3", "d = 4"}; !reflect.DeepEqual(h.lines, want) {
+		t.Fatalf("unexpected loaded history: got %q, want %q", h.lines, want)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "a = 1\nc = 3\nd = 4\n"; got != want {
+		t.Fatalf("unexpected history file: got %q, want %q", got, want)
+	}
+
+	// The history is not persisted without a path.
+	h, err = loadREPLHistory("", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.add("e = 5"); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.lines) != 1 {
+		t.Fatalf("unexpected history: %q", h.lines)
+	}
+}
+
+func TestREPLHistorySearch(t *testing.T) {
+	h := &replHistory{size: 10, lines: []string{
+		`from(bucket: "a") |> range(start: -1h)`,
+		`x = 1`,
+		`from(bucket: "b") |> range(start: -1h)`,
+	}}
+	s := &replHistorySearch{h: h}
+
+	buf := prompt.NewBuffer()
+	buf.InsertText("from", false, true)
+	for _, want := range []string{h.lines[2], h.lines[0], h.lines[0]} {
+		s.next(buf)
+		if got := buf.Text(); got != want {
+			t.Fatalf("unexpected search result: got %q, want %q", got, want)
+		}
+	}
+
+	// A different line starts a new search.
+	buf = prompt.NewBuffer()
+	buf.InsertText("x =", false, true)
+	s.next(buf)
+	if got := buf.Text(); got != h.lines[1] {
+		t.Fatalf("unexpected search result: got %q", got)
+	}
+}
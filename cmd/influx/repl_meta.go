@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/c-bata/go-prompt"
+	"github.com/influxdata/flux/repl"
+	platform "github.com/influxdata/influxdb"
+)
+
+// replCommands are the meta-commands of the REPL, the lines starting with a
+// backslash.
+var replCommands = []struct {
+	name, args, desc string
+}{
+	{`\buckets`, "", "list the buckets of the organization"},
+	{`\orgs`, "", "list the organizations"},
+	{`\load`, "FILE", "execute the Flux of FILE in the session"},
+	{`\save`, "FILE", "save the Flux entered in the session to FILE"},
+	{`\set`, "[NAME=VALUE ...]", "show or change the settings: format=table|csv|json|lp, timing=on|off"},
+	{`\timing`, "[on|off]", "toggle or set the report of the time taken by each query"},
+	{`\help`, "", "show the meta-commands"},
+}
+
+// replBucketsQuery is the query of \buckets.
+const replBucketsQuery = `buckets() |> keep(columns: ["name", "id", "retentionPeriod"])`
+
+// replSession is an interactive REPL session: the Flux lines entered are
+// executed by the REPL, and the meta-commands control the session.
+type replSession struct {
+	r         *repl.REPL
+	querier   *interruptibleQuerier
+	completer *replCompleter
+	orgs      platform.OrganizationService
+	out       io.Writer
+	now       func() time.Time
+
+	lines  []string // Flux executed in the session, written by \save
+	timing bool
+}
+
+// input executes a line entered in the REPL.
+func (s *replSession) input(line string) error {
+	if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, `\`) {
+		return s.meta(trimmed)
+	}
+	if err := s.exec(line); err != nil {
+		return err
+	}
+	s.record(line)
+	return nil
+}
+
+// exec executes Flux in the REPL, reporting the time taken if timing is on.
+func (s *replSession) exec(src string) error {
+	start := s.now()
+	if err := s.r.Input(src); err != nil {
+		return err
+	}
+	if s.timing {
+		fmt.Fprintf(s.out, "Time: %v\n", s.now().Sub(start).Round(time.Microsecond))
+	}
+	return nil
+}
+
+// record records Flux executed in the session.
+func (s *replSession) record(src string) {
+	if strings.TrimSpace(src) == "" {
+		return
+	}
+	s.lines = append(s.lines, src)
+	s.completer.observe(src)
+}
+
+// meta executes the meta-command line.
+func (s *replSession) meta(line string) error {
+	fields := strings.Fields(line)
+	name, args := fields[0], fields[1:]
+	switch name {
+	case `\buckets`:
+		if len(args) != 0 {
+			return fmt.Errorf(`usage: \buckets`)
+		}
+		return s.exec(replBucketsQuery)
+	case `\orgs`:
+		if len(args) != 0 {
+			return fmt.Errorf(`usage: \orgs`)
+		}
+		return s.listOrgs()
+	case `\load`:
+		if len(args) != 1 {
+			return fmt.Errorf(`usage: \load FILE`)
+		}
+		b, err := ioutil.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", args[0], err)
+		}
+		if err := s.exec(string(b)); err != nil {
+			return err
+		}
+		s.record(string(b))
+		return nil
+	case `\save`:
+		if len(args) != 1 {
+			return fmt.Errorf(`usage: \save FILE`)
+		}
+		return s.save(args[0])
+	case `\set`:
+		if len(args) == 0 {
+			fmt.Fprintf(s.out, "format=%s\ntiming=%s\n", s.format(), onOff(s.timing))
+			return nil
+		}
+		for _, arg := range args {
+			i := strings.Index(arg, "=")
+			if i < 0 {
+				return fmt.Errorf(`usage: \set NAME=VALUE`)
+			}
+			if err := s.set(arg[:i], arg[i+1:]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case `\timing`:
+		switch len(args) {
+		case 0:
+			s.timing = !s.timing
+		case 1:
+			if err := s.set("timing", args[0]); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf(`usage: \timing [on|off]`)
+		}
+		fmt.Fprintf(s.out, "Timing is %s.\n", onOff(s.timing))
+		return nil
+	case `\help`, `\?`:
+		w := tabwriter.NewWriter(s.out, 0, 8, 2, ' ', 0)
+		for _, c := range replCommands {
+			fmt.Fprintf(w, "%s %s\t%s\n", c.name, c.args, c.desc)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf(`unknown meta-command %s, see \help`, name)
+	}
+}
+
+// set changes the setting name to value.
+func (s *replSession) set(name, value string) error {
+	switch name {
+	case "format":
+		switch value {
+		case queryOutputTable, queryOutputCSV, queryOutputJSON, queryOutputLP:
+			s.querier.format = value
+			return nil
+		}
+		return fmt.Errorf("invalid format %q: must be one of table, csv, json or lp", value)
+	case "timing":
+		switch value {
+		case "on":
+			s.timing = true
+			return nil
+		case "off":
+			s.timing = false
+			return nil
+		}
+		return fmt.Errorf("invalid timing %q: must be on or off", value)
+	}
+	return fmt.Errorf("unknown setting %q", name)
+}
+
+// format returns the output format of the results.
+func (s *replSession) format() string {
+	if s.querier.format == "" {
+		return queryOutputTable
+	}
+	return s.querier.format
+}
+
+// listOrgs writes the organizations to the output.
+func (s *replSession) listOrgs() error {
+	orgs, _, err := s.orgs.FindOrganizations(context.Background(), platform.OrganizationFilter{})
+	if err != nil {
+		return fmt.Errorf("failed to list organizations: %w", err)
+	}
+	w := tabwriter.NewWriter(s.out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tName")
+	for _, o := range orgs {
+		fmt.Fprintf(w, "%s\t%s\n", o.ID, o.Name)
+	}
+	return w.Flush()
+}
+
+// save writes the Flux executed in the session to the file at path.
+func (s *replSession) save(path string) error {
+	var b strings.Builder
+	for _, line := range s.lines {
+		b.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	if err := ioutil.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to save the session: %w", err)
+	}
+	fmt.Fprintf(s.out, "Saved %d entries to %s.\n", len(s.lines), path)
+	return nil
+}
+
+// replSuggestCommands returns the meta-commands completing text.
+func replSuggestCommands(text string) []prompt.Suggest {
+	s := make([]prompt.Suggest, len(replCommands))
+	for i, c := range replCommands {
+		s[i] = prompt.Suggest{Text: c.name, Description: c.desc}
+	}
+	return prompt.FilterHasPrefix(s, text, false)
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/repl"
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/mock"
+)
+
+func TestREPLSession(t *testing.T) {
+	dir, err := ioutil.TempDir("", "influx-repl-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var out bytes.Buffer
+	names := &replNamesQuerier{}
+	q := &interruptibleQuerier{Querier: names, out: &out}
+	orgs := mock.NewOrganizationService()
+	orgs.FindOrganizationsF = func(ctx context.Context, filter platform.OrganizationFilter, opt ...platform.FindOptions) ([]*platform.Organization, int, error) {
+		return []*platform.Organization{{ID: 1, Name: "acme"}}, 1, nil
+	}
+	now := time.Unix(0, 0)
+	s := &replSession{
+		r:         repl.New(context.Background(), flux.NewDefaultDependencies(), q),
+		querier:   q,
+		completer: newREPLCompleter(names),
+		orgs:      orgs,
+		out:       &out,
+		now: func() time.Time {
+			now = now.Add(time.Millisecond)
+			return now
+		},
+	}
+
+	run := func(line, want string) {
+		t.Helper()
+		out.Reset()
+		if err := s.input(line); err != nil {
+			t.Fatalf("%s: unexpected error: %v", line, err)
+		}
+		if got := out.String(); !strings.Contains(got, want) {
+			t.Fatalf("%s: unexpected output %q, want %q", line, got, want)
+		}
+	}
+
+	run(`\set`, "format=table\ntiming=off\n")
+	run(`\set format=csv timing=on`, "")
+	run(`\set`, "format=csv\ntiming=on\n")
+	run(`x = 1`, "Time: 1ms")
+	run(`from(bucket: "telegraf") |> range(start: -1h)`, ",result,table,name\r\n,,0,telegraf\r\n")
+	run(`\timing`, "Timing is off.")
+	run(`\buckets`, ",,0,_monitoring")
+	run(`\orgs`, "0000000000000001  acme")
+
+	// \save writes the Flux of the session, which \load executes again.
+	path := filepath.Join(dir, "session.flux")
+	run(`\save `+path, "Saved 2 entries")
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "x = 1\nfrom(bucket: \"telegraf\") |> range(start: -1h)\n"; got != want {
+		t.Fatalf("unexpected saved session %q, want %q", got, want)
+	}
+	run(`\load `+path, ",,0,telegraf")
+	if got := len(s.lines); got != 3 {
+		t.Fatalf("the loaded Flux should be recorded, got %q", s.lines)
+	}
+
+	for _, line := range []string{`\set format=xml`, `\set colors=on`, `\timing maybe`, `\load`, `\nope`} {
+		if err := s.input(line); err == nil {
+			t.Fatalf("%s: expected an error", line)
+		}
+	}
+}
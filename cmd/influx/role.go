@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/http"
+	"github.com/spf13/cobra"
+)
+
+type roleSVCsFn func() (influxdb.RoleService, influxdb.OrganizationService, error)
+
+func cmdRole(f *globalFlags, opt genericCLIOpts) *cobra.Command {
+	builder := newCmdRoleBuilder(newRoleSVCs, opt)
+	builder.globalFlags = f
+	return builder.cmd()
+}
+
+type cmdRoleBuilder struct {
+	genericCLIOpts
+	*globalFlags
+
+	svcFn roleSVCsFn
+
+	id          string
+	name        string
+	description string
+	permissions []string
+	userID      string
+	org         organization
+}
+
+func newCmdRoleBuilder(svcsFn roleSVCsFn, opt genericCLIOpts) *cmdRoleBuilder {
+	return &cmdRoleBuilder{
+		genericCLIOpts: opt,
+		svcFn:          svcsFn,
+	}
+}
+
+func (b *cmdRoleBuilder) cmd() *cobra.Command {
+	cmd := b.newCmd("role", nil)
+	cmd.Short = "Role management commands"
+	cmd.Long = `Role management commands.
+
+A role is a named set of permissions of an organization, assigned to users
+with "influx role assign" and to tokens with "influx auth create --role".
+The users and tokens of a role are allowed its permissions as they are when
+they are used, so that updating a role updates all of them.
+
+Permissions are given as ACTION:TYPE or ACTION:TYPE/ID, such as read:buckets
+or write:buckets/0000000000000001, for the resources of the organization of
+the role.`
+	cmd.Run = seeHelp
+	cmd.AddCommand(
+		b.cmdAssign(),
+		b.cmdCreate(),
+		b.cmdDelete(),
+		b.cmdFind(),
+		b.cmdUnassign(),
+		b.cmdUpdate(),
+	)
+	return cmd
+}
+
+func (b *cmdRoleBuilder) cmdCreate() *cobra.Command {
+	cmd := b.newCmd("create", b.cmdCreateRunEFn)
+	cmd.Short = "Create role"
+
+	cmd.Flags().StringVarP(&b.name, "name", "n", "", "The role name (required)")
+	cmd.Flags().StringVarP(&b.description, "description", "d", "", "The role description")
+	cmd.Flags().StringArrayVarP(&b.permissions, "permission", "p", []string{}, "A permission of the role, such as read:buckets")
+	cmd.MarkFlagRequired("name")
+	b.org.register(cmd, false)
+
+	return cmd
+}
+
+func (b *cmdRoleBuilder) cmdCreateRunEFn(cmd *cobra.Command, args []string) error {
+	roleSVC, orgSVC, err := b.svcFn()
+	if err != nil {
+		return err
+	}
+	orgID, err := b.org.getID(orgSVC)
+	if err != nil {
+		return err
+	}
+
+	ps, err := parseRolePermissions(b.permissions, orgID)
+	if err != nil {
+		return err
+	}
+
+	r := &influxdb.Role{
+		OrgID:       orgID,
+		Name:        b.name,
+		Description: b.description,
+		Permissions: ps,
+	}
+	if err := roleSVC.CreateRole(context.Background(), r); err != nil {
+		return fmt.Errorf("failed to create role: %w", err)
+	}
+
+	b.printRoles(r)
+	return nil
+}
+
+func (b *cmdRoleBuilder) cmdFind() *cobra.Command {
+	cmd := b.newCmd("find", b.cmdFindRunEFn)
+	cmd.Aliases = []string{"list", "ls"}
+	cmd.Short = "Find roles"
+
+	cmd.Flags().StringVarP(&b.id, "id", "i", "", "The role ID")
+	cmd.Flags().StringVarP(&b.name, "name", "n", "", "The role name")
+	cmd.Flags().StringVarP(&b.userID, "user-id", "", "", "The ID of a user whose roles are found")
+	b.org.register(cmd, false)
+
+	return cmd
+}
+
+func (b *cmdRoleBuilder) cmdFindRunEFn(cmd *cobra.Command, args []string) error {
+	roleSVC, orgSVC, err := b.svcFn()
+	if err != nil {
+		return err
+	}
+
+	var filter influxdb.RoleFilter
+	if b.id != "" {
+		id, err := influxdb.IDFromString(b.id)
+		if err != nil {
+			return err
+		}
+		filter.ID = id
+	}
+	if b.userID != "" {
+		id, err := influxdb.IDFromString(b.userID)
+		if err != nil {
+			return err
+		}
+		filter.UserID = id
+	}
+	if b.name != "" {
+		filter.Name = &b.name
+	}
+	if b.org.id != "" || b.org.name != "" {
+		orgID, err := b.org.getID(orgSVC)
+		if err != nil {
+			return err
+		}
+		filter.OrgID = &orgID
+	}
+
+	rs, _, err := roleSVC.FindRoles(context.Background(), filter)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve roles: %w", err)
+	}
+
+	b.printRoles(rs...)
+	return nil
+}
+
+func (b *cmdRoleBuilder) cmdUpdate() *cobra.Command {
+	cmd := b.newCmd("update", b.cmdUpdateRunEFn)
+	cmd.Short = "Update role"
+	cmd.Long = `Update the name, the description or the permissions of a role.
+
+The permissions given replace all the permissions of the role, for all its
+users and tokens.`
+
+	cmd.Flags().StringVarP(&b.id, "id", "i", "", "The role ID (required)")
+	cmd.Flags().StringVarP(&b.name, "name", "n", "", "The new role name")
+	cmd.Flags().StringVarP(&b.description, "description", "d", "", "The new role description")
+	cmd.Flags().StringArrayVarP(&b.permissions, "permission", "p", []string{}, "A permission of the role, such as read:buckets")
+	cmd.MarkFlagRequired("id")
+
+	return cmd
+}
+
+func (b *cmdRoleBuilder) cmdUpdateRunEFn(cmd *cobra.Command, args []string) error {
+	roleSVC, _, err := b.svcFn()
+	if err != nil {
+		return err
+	}
+	id, err := influxdb.IDFromString(b.id)
+	if err != nil {
+		return err
+	}
+
+	var upd influxdb.RoleUpdate
+	if cmd.Flags().Changed("name") {
+		upd.Name = &b.name
+	}
+	if cmd.Flags().Changed("description") {
+		upd.Description = &b.description
+	}
+	if cmd.Flags().Changed("permission") {
+		r, err := roleSVC.FindRoleByID(context.Background(), *id)
+		if err != nil {
+			return fmt.Errorf("failed to find role: %w", err)
+		}
+		if upd.Permissions, err = parseRolePermissions(b.permissions, r.OrgID); err != nil {
+			return err
+		}
+	}
+
+	r, err := roleSVC.UpdateRole(context.Background(), *id, upd)
+	if err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+
+	b.printRoles(r)
+	return nil
+}
+
+func (b *cmdRoleBuilder) cmdDelete() *cobra.Command {
+	cmd := b.newCmd("delete", b.cmdDeleteRunEFn)
+	cmd.Short = "Delete role"
+
+	cmd.Flags().StringVarP(&b.id, "id", "i", "", "The role ID (required)")
+	cmd.MarkFlagRequired("id")
+
+	return cmd
+}
+
+func (b *cmdRoleBuilder) cmdDeleteRunEFn(cmd *cobra.Command, args []string) error {
+	roleSVC, _, err := b.svcFn()
+	if err != nil {
+		return err
+	}
+	id, err := influxdb.IDFromString(b.id)
+	if err != nil {
+		return err
+	}
+
+	r, err := roleSVC.FindRoleByID(context.Background(), *id)
+	if err != nil {
+		return fmt.Errorf("failed to find role: %w", err)
+	}
+	if err := roleSVC.DeleteRole(context.Background(), *id); err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+
+	w := b.newTabWriter()
+	w.WriteHeaders("ID", "Name", "OrgID", "Deleted")
+	w.Write(map[string]interface{}{
+		"ID":      r.ID,
+		"Name":    r.Name,
+		"OrgID":   r.OrgID,
+		"Deleted": true,
+	})
+	w.Flush()
+
+	return nil
+}
+
+func (b *cmdRoleBuilder) cmdAssign() *cobra.Command {
+	cmd := b.newCmd("assign", b.cmdAssignRunEFn)
+	cmd.Short = "Assign role to a user"
+
+	cmd.Flags().StringVarP(&b.id, "id", "i", "", "The role ID (required)")
+	cmd.Flags().StringVarP(&b.userID, "user-id", "", "", "The user ID (required)")
+	cmd.MarkFlagRequired("id")
+	cmd.MarkFlagRequired("user-id")
+
+	return cmd
+}
+
+func (b *cmdRoleBuilder) cmdAssignRunEFn(cmd *cobra.Command, args []string) error {
+	return b.runAssignment(true)
+}
+
+func (b *cmdRoleBuilder) cmdUnassign() *cobra.Command {
+	cmd := b.newCmd("unassign", b.cmdUnassignRunEFn)
+	cmd.Short = "Unassign role from a user"
+
+	cmd.Flags().StringVarP(&b.id, "id", "i", "", "The role ID (required)")
+	cmd.Flags().StringVarP(&b.userID, "user-id", "", "", "The user ID (required)")
+	cmd.MarkFlagRequired("id")
+	cmd.MarkFlagRequired("user-id")
+
+	return cmd
+}
+
+func (b *cmdRoleBuilder) cmdUnassignRunEFn(cmd *cobra.Command, args []string) error {
+	return b.runAssignment(false)
+}
+
+// runAssignment assigns the role to the user, or unassigns it.
+func (b *cmdRoleBuilder) runAssignment(assign bool) error {
+	roleSVC, _, err := b.svcFn()
+	if err != nil {
+		return err
+	}
+	id, err := influxdb.IDFromString(b.id)
+	if err != nil {
+		return err
+	}
+	userID, err := influxdb.IDFromString(b.userID)
+	if err != nil {
+		return err
+	}
+
+	if assign {
+		err = roleSVC.AddRoleUser(context.Background(), *id, *userID)
+	} else {
+		err = roleSVC.RemoveRoleUser(context.Background(), *id, *userID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update the users of role: %w", err)
+	}
+
+	w := b.newTabWriter()
+	w.WriteHeaders("RoleID", "UserID", "Assigned")
+	w.Write(map[string]interface{}{
+		"RoleID":   id,
+		"UserID":   userID,
+		"Assigned": assign,
+	})
+	w.Flush()
+
+	return nil
+}
+
+func (b *cmdRoleBuilder) printRoles(rs ...*influxdb.Role) {
+	w := b.newTabWriter()
+	w.WriteHeaders("ID", "Name", "Description", "OrgID", "Permissions")
+	for _, r := range rs {
+		ps := make([]string, 0, len(r.Permissions))
+		for _, p := range r.Permissions {
+			ps = append(ps, p.String())
+		}
+		w.Write(map[string]interface{}{
+			"ID":          r.ID,
+			"Name":        r.Name,
+			"Description": r.Description,
+			"OrgID":       r.OrgID,
+			"Permissions": ps,
+		})
+	}
+	w.Flush()
+}
+
+// parseRolePermissions parses permissions given as ACTION:TYPE or
+// ACTION:TYPE/ID for the resources of the organization orgID.
+func parseRolePermissions(ss []string, orgID influxdb.ID) ([]influxdb.Permission, error) {
+	ps := make([]influxdb.Permission, 0, len(ss))
+	for _, s := range ss {
+		i := strings.Index(s, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid permission %q: must be ACTION:TYPE or ACTION:TYPE/ID", s)
+		}
+		action, resource := influxdb.Action(s[:i]), s[i+1:]
+
+		var (
+			p   *influxdb.Permission
+			err error
+		)
+		if j := strings.Index(resource, "/"); j >= 0 {
+			var id influxdb.ID
+			if err := id.DecodeFromString(resource[j+1:]); err != nil {
+				return nil, fmt.Errorf("invalid permission %q: %w", s, err)
+			}
+			p, err = influxdb.NewPermissionAtID(id, action, influxdb.ResourceType(resource[:j]), orgID)
+		} else {
+			p, err = influxdb.NewPermission(action, influxdb.ResourceType(resource), orgID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid permission %q: %w", s, err)
+		}
+		ps = append(ps, *p)
+	}
+	return ps, nil
+}
+
+func newRoleSVCs() (influxdb.RoleService, influxdb.OrganizationService, error) {
+	httpClient, err := newHTTPClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	orgSvc := &http.OrganizationService{Client: httpClient}
+
+	return &http.RoleService{Client: httpClient}, orgSvc, nil
+}
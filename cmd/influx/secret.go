@@ -2,7 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
 
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/http"
@@ -26,6 +31,7 @@ type cmdSecretBuilder struct {
 
 	key   string
 	value string
+	file  string
 	org   organization
 }
 
@@ -50,9 +56,16 @@ func (b *cmdSecretBuilder) cmd() *cobra.Command {
 
 func (b *cmdSecretBuilder) cmdUpdate() *cobra.Command {
 	cmd := b.newCmd("update", b.cmdUpdateRunEFn)
+	cmd.Aliases = []string{"set"}
 	cmd.Short = "Update secret"
+	cmd.Long = `Update the secret with the given key, creating it if it does not exist.
+
+The value is prompted for unless it is read from a file with --file, or from
+stdin with --file -, so that it does not appear in the shell history. A single
+trailing newline is removed from the value read from a file.`
 	cmd.Flags().StringVarP(&b.key, "key", "k", "", "The secret key (required)")
 	cmd.Flags().StringVarP(&b.value, "value", "v", "", "Optional secret value for scripting convenience, using this might expose the secret to your local history")
+	cmd.Flags().StringVarP(&b.file, "file", "f", "", "Path of a file to read the secret value from, or - to read it from stdin")
 	cmd.MarkFlagRequired("key")
 	b.org.register(cmd, false)
 
@@ -87,14 +100,21 @@ func (b *cmdSecretBuilder) cmdUpdateRunEFn(cmd *cobra.Command, args []string) er
 		Reader: b.genericCLIOpts.in,
 	}
 	var secret string
-	if b.value != "" {
+	switch {
+	case b.value != "" && b.file != "":
+		return errors.New("only one of value or file may be provided")
+	case b.value != "":
 		secret = b.value
-	} else {
+	case b.file != "":
+		if secret, err = b.readSecretFile(); err != nil {
+			return fmt.Errorf("failed to read secret value: %w", err)
+		}
+	default:
 		secret = getSecretFn(ui)
 	}
 
 	if err := scrSVC.PatchSecrets(ctx, orgID, map[string]string{b.key: secret}); err != nil {
-		return fmt.Errorf("failed to update secret with key %q: %v", b.key, err)
+		return fmt.Errorf("failed to update secret with key %q: %w", b.key, err)
 	}
 
 	w := b.newTabWriter()
@@ -109,6 +129,30 @@ func (b *cmdSecretBuilder) cmdUpdateRunEFn(cmd *cobra.Command, args []string) er
 	return nil
 }
 
+// readSecretFile returns the secret value read from the file, or from stdin
+// if the file is -, without its trailing newline.
+func (b *cmdSecretBuilder) readSecretFile() (string, error) {
+	var r io.Reader = b.in
+	if b.file != "-" {
+		f, err := os.Open(b.file)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	secret := strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+	if secret == "" {
+		return "", errors.New("secret value is empty")
+	}
+	return secret, nil
+}
+
 func (b *cmdSecretBuilder) cmdDeleteRunEFn(cmd *cobra.Command, args []string) error {
 	scrSVC, orgSVC, _, err := b.svcFn()
 	if err != nil {
@@ -121,7 +165,7 @@ func (b *cmdSecretBuilder) cmdDeleteRunEFn(cmd *cobra.Command, args []string) er
 
 	ctx := context.Background()
 	if err := scrSVC.DeleteSecret(ctx, orgID, b.key); err != nil {
-		return fmt.Errorf("failed to delete secret with key %q: %v", b.key, err)
+		return fmt.Errorf("failed to delete secret with key %q: %w", b.key, err)
 	}
 
 	w := b.newTabWriter()
@@ -138,6 +182,7 @@ func (b *cmdSecretBuilder) cmdDeleteRunEFn(cmd *cobra.Command, args []string) er
 
 func (b *cmdSecretBuilder) cmdFind() *cobra.Command {
 	cmd := b.newCmd("find", b.cmdFindRunEFn)
+	cmd.Aliases = []string{"list", "ls"}
 	cmd.Short = "Find secrets"
 	b.org.register(cmd, false)
 
@@ -5,6 +5,9 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/influxdata/influxdb"
@@ -217,4 +220,73 @@ func TestCmdSecret(t *testing.T) {
 			t.Run(tt.name, fn)
 		}
 	})
+
+	t.Run("set from file", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "influx-secret-")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "secret")
+		require.NoError(t, ioutil.WriteFile(path, []byte("from file\n"), 0600))
+
+		tests := []struct {
+			name          string
+			stdin         string
+			flags         []string
+			expectedValue string
+			expectedErr   bool
+		}{
+			{
+				name:          "file",
+				flags:         []string{"--key=key1", "--file=" + path},
+				expectedValue: "from file",
+			},
+			{
+				name:          "stdin",
+				stdin:         "from stdin\r\n",
+				flags:         []string{"-k=key1", "-f=-"},
+				expectedValue: "from stdin",
+			},
+			{
+				name:        "empty stdin",
+				flags:       []string{"-k=key1", "-f=-"},
+				expectedErr: true,
+			},
+			{
+				name:        "value and file",
+				flags:       []string{"-k=key1", "-v=v1", "-f=" + path},
+				expectedErr: true,
+			},
+		}
+
+		for _, tt := range tests {
+			fn := func(t *testing.T) {
+				var value string
+				svc := mock.NewSecretService()
+				svc.PatchSecretsFn = func(ctx context.Context, orgID influxdb.ID, m map[string]string) error {
+					value = m["key1"]
+					return nil
+				}
+
+				builder := newInfluxCmdBuilder(
+					in(strings.NewReader(tt.stdin)),
+					out(ioutil.Discard),
+				)
+				cmd := builder.cmd(func(g *globalFlags, opt genericCLIOpts) *cobra.Command {
+					return newCmdSecretBuilder(fakeSVCFn(svc, nil), opt).cmd()
+				})
+				cmd.SetArgs(append([]string{"secret", "set", "--org-id=" + orgID.String()}, tt.flags...))
+
+				err := cmd.Execute()
+				if tt.expectedErr {
+					require.Error(t, err)
+					return
+				}
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedValue, value)
+			}
+
+			t.Run(tt.name, fn)
+		}
+	})
 }
@@ -52,7 +52,7 @@ func setupF(cmd *cobra.Command, args []string) error {
 
 	allowed, err := s.IsOnboarding(context.Background())
 	if err != nil {
-		return fmt.Errorf("failed to determine if instance has been configured: %v", err)
+		return fmt.Errorf("failed to determine if instance has been configured: %w", err)
 	}
 	if !allowed {
 		return fmt.Errorf("instance at %q has already been setup", flags.host)
@@ -72,17 +72,17 @@ func setupF(cmd *cobra.Command, args []string) error {
 
 	req, err := onboardingRequest()
 	if err != nil {
-		return fmt.Errorf("failed to retrieve data to setup instance: %v", err)
+		return fmt.Errorf("failed to retrieve data to setup instance: %w", err)
 	}
 
 	result, err := s.Generate(context.Background(), req)
 	if err != nil {
-		return fmt.Errorf("failed to setup instance: %v", err)
+		return fmt.Errorf("failed to setup instance: %w", err)
 	}
 
 	err = writeTokenToPath(result.Auth.Token, dPath, dir)
 	if err != nil {
-		return fmt.Errorf("failed to write token to path %q: %v", dPath, err)
+		return fmt.Errorf("failed to write token to path %q: %w", dPath, err)
 	}
 
 	fmt.Println(string(promptWithColor("Your token has been stored in "+dPath+".", colorCyan)))
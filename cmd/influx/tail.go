@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/ast"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/lang"
+	"github.com/influxdata/flux/parser"
+	"github.com/influxdata/influxdb/http"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/query"
+	"github.com/spf13/cobra"
+)
+
+var tailFlags struct {
+	org      organization
+	bucket   string
+	filter   string
+	interval time.Duration
+	lookback time.Duration
+}
+
+func cmdTail(f *globalFlags, opts genericCLIOpts) *cobra.Command {
+	cmd := opts.newCmd("tail", tailF)
+	cmd.Short = "Print points as they are written to a bucket"
+	cmd.Long = `Repeatedly queries a bucket and prints newly arrived points in line protocol,
+until interrupted. The optional --filter is a Flux predicate where bare
+identifiers refer to columns, for example:
+
+	influx tail --bucket telegraf --filter 'host == "a" and _measurement == "cpu"'
+
+Only points with a timestamp later than the last printed point are reported,
+so points written with an older timestamp after the fact are not shown.`
+	cmd.Args = cobra.NoArgs
+
+	tailFlags.org.register(cmd, false)
+	cmd.Flags().StringVarP(&tailFlags.bucket, "bucket", "b", "", "The name of the bucket to tail (required)")
+	cmd.Flags().StringVar(&tailFlags.filter, "filter", "", "Flux predicate that points must match, e.g. 'host == \"a\"'")
+	cmd.Flags().DurationVar(&tailFlags.interval, "interval", time.Second, "How often to query for new points")
+	cmd.Flags().DurationVar(&tailFlags.lookback, "lookback", time.Minute, "How far back to look for points on the first query")
+	cmd.MarkFlagRequired("bucket")
+
+	return cmd
+}
+
+func tailF(cmd *cobra.Command, args []string) error {
+	if flags.local {
+		return fmt.Errorf("local flag not supported for tail command")
+	}
+
+	if err := tailFlags.org.validOrgFlags(); err != nil {
+		return err
+	}
+
+	if tailFlags.interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+
+	flux.FinalizeBuiltIns()
+
+	predicate, err := tailPredicate(tailFlags.filter)
+	if err != nil {
+		return fmt.Errorf("invalid filter: %w", err)
+	}
+
+	orgSvc, err := newOrganizationService()
+	if err != nil {
+		return fmt.Errorf("failed to initialized organization service client: %w", err)
+	}
+
+	orgID, err := tailFlags.org.getID(orgSvc)
+	if err != nil {
+		return err
+	}
+
+	qs := &http.FluxQueryService{
+		Addr:               flags.host,
+		Token:              flags.token,
+		InsecureSkipVerify: flags.skipVerify,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	defer signal.Stop(sigCh)
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(tailFlags.interval)
+	defer ticker.Stop()
+
+	start := time.Now().Add(-tailFlags.lookback)
+	for {
+		req := &query.Request{
+			OrganizationID: orgID,
+			Compiler:       lang.FluxCompiler{Query: tailQuery(tailFlags.bucket, predicate, start)},
+		}
+
+		points, err := tailPoints(ctx, qs, req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		for _, p := range points {
+			fmt.Fprintln(cmd.OutOrStdout(), p.String())
+		}
+		if n := len(points); n > 0 {
+			// range() is inclusive of its start, so resume just after the last point seen.
+			start = points[n-1].Time().Add(time.Nanosecond)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// tailPredicate parses filter as a Flux expression and returns the body of a
+// filter() predicate, where bare identifiers are rewritten as columns of r.
+func tailPredicate(filter string) (ast.Expression, error) {
+	if filter == "" {
+		return nil, nil
+	}
+
+	pkg := parser.ParseSource(filter)
+	if ast.Check(pkg) > 0 {
+		return nil, ast.GetError(pkg)
+	}
+
+	if len(pkg.Files) != 1 || len(pkg.Files[0].Body) != 1 {
+		return nil, fmt.Errorf("expected a single expression")
+	}
+	stmt, ok := pkg.Files[0].Body[0].(*ast.ExpressionStatement)
+	if !ok {
+		return nil, fmt.Errorf("expected a single expression")
+	}
+	return tailColumns(stmt.Expression), nil
+}
+
+// tailColumns replaces identifiers in e with member expressions of the
+// predicate row, so that host == "a" becomes r.host == "a". Identifiers of
+// the Flux prelude, such as true or now, are left as they are; columns of the
+// same name can still be given as r.<column>.
+func tailColumns(e ast.Expression) ast.Expression {
+	switch e := e.(type) {
+	case *ast.Identifier:
+		if _, ok := flux.Prelude().Lookup(e.Name); ok {
+			return e
+		}
+		return &ast.MemberExpression{
+			Object:   &ast.Identifier{Name: "r"},
+			Property: &ast.Identifier{Name: e.Name},
+		}
+	case *ast.BinaryExpression:
+		e.Left = tailColumns(e.Left)
+		e.Right = tailColumns(e.Right)
+	case *ast.LogicalExpression:
+		e.Left = tailColumns(e.Left)
+		e.Right = tailColumns(e.Right)
+	case *ast.UnaryExpression:
+		e.Argument = tailColumns(e.Argument)
+	case *ast.ParenExpression:
+		e.Expression = tailColumns(e.Expression)
+	}
+	return e
+}
+
+// tailQuery returns the Flux query that reads the points of bucket written
+// at or after start and matching predicate, if any.
+func tailQuery(bucket string, predicate ast.Expression, start time.Time) string {
+	q := fmt.Sprintf("from(bucket: %s)\n\t|> range(start: %s)",
+		ast.Format(&ast.StringLiteral{Value: bucket}),
+		ast.Format(&ast.DateTimeLiteral{Value: start.UTC()}))
+	if predicate != nil {
+		q += fmt.Sprintf("\n\t|> filter(fn: (r) => %s)", ast.Format(predicate))
+	}
+	return q
+}
+
+// tailPoints runs req and converts the result rows to points, sorted by time.
+func tailPoints(ctx context.Context, qs query.QueryService, req *query.Request) ([]models.Point, error) {
+	results, err := qs.Query(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer results.Release()
+
+	points, err := resultPoints(results)
+	if err != nil {
+		return nil, err
+	}
+	return points, results.Err()
+}
+
+// resultPoints converts the rows of all tables in results to points, sorted by time.
+// Each row must include the _measurement, _field, _value and _time columns; any
+// other string column, except for _start and _stop, is reported as a tag.
+func resultPoints(results flux.ResultIterator) ([]models.Point, error) {
+	var points []models.Point
+	for results.More() {
+		err := results.Next().Tables().Do(func(tbl flux.Table) error {
+			return tbl.Do(func(cr flux.ColReader) error {
+				pts, err := colReaderPoints(cr)
+				points = append(points, pts...)
+				return err
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].Time().Before(points[j].Time())
+	})
+	return points, nil
+}
+
+func colReaderPoints(cr flux.ColReader) ([]models.Point, error) {
+	var (
+		measurementIdx, fieldIdx, valueIdx, timeIdx = -1, -1, -1, -1
+		tagIdx                                      []int
+	)
+	for j, c := range cr.Cols() {
+		switch c.Label {
+		case "_measurement":
+			measurementIdx = j
+		case "_field":
+			fieldIdx = j
+		case execute.DefaultValueColLabel:
+			valueIdx = j
+		case execute.DefaultTimeColLabel:
+			timeIdx = j
+		case execute.DefaultStartColLabel, execute.DefaultStopColLabel:
+		default:
+			if c.Type == flux.TString {
+				tagIdx = append(tagIdx, j)
+			}
+		}
+	}
+	if measurementIdx < 0 || fieldIdx < 0 || valueIdx < 0 || timeIdx < 0 {
+		return nil, fmt.Errorf("result is missing one of the _measurement, _field, _value or _time columns")
+	}
+
+	cols := cr.Cols()
+	points := make([]models.Point, 0, cr.Len())
+	for i := 0; i < cr.Len(); i++ {
+		value := execute.ValueForRow(cr, i, valueIdx)
+		if value.IsNull() {
+			continue
+		}
+
+		tags := make(map[string]string, len(tagIdx))
+		for _, j := range tagIdx {
+			if v := execute.ValueForRow(cr, i, j); !v.IsNull() && v.Str() != "" {
+				tags[cols[j].Label] = v.Str()
+			}
+		}
+
+		var fv interface{}
+		switch cols[valueIdx].Type {
+		case flux.TFloat:
+			fv = value.Float()
+		case flux.TInt:
+			fv = value.Int()
+		case flux.TUInt:
+			fv = value.UInt()
+		case flux.TString:
+			fv = value.Str()
+		case flux.TBool:
+			fv = value.Bool()
+		default:
+			return nil, fmt.Errorf("unsupported _value type %s", cols[valueIdx].Type)
+		}
+
+		name := execute.ValueForRow(cr, i, measurementIdx).Str()
+		field := execute.ValueForRow(cr, i, fieldIdx).Str()
+		t := execute.ValueForRow(cr, i, timeIdx).Time().Time()
+
+		p, err := models.NewPoint(name, models.NewTags(tags), models.Fields{field: fv}, t)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux/ast"
+	"github.com/influxdata/flux/csv"
+)
+
+func TestTailPredicate(t *testing.T) {
+	tests := []struct {
+		filter  string
+		want    string
+		wantErr bool
+	}{
+		{filter: `host=="a"`, want: `r.host == "a"`},
+		{filter: `host == "a" and (region != "us" or not _field =~ /^usage/)`, want: `r.host == "a" and (r.region != "us" or not r._field =~ /^usage/)`},
+		{filter: `r._value > 10`, want: `r._value > 10`},
+		{filter: `ok == true and not failed == false`, want: `r.ok == true and not r.failed == false`},
+		{filter: `_time < now()`, want: `r._time < now()`},
+		{filter: `host ==`, wantErr: true},
+		{filter: `a = 1`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			got, err := tailPredicate(tt.filter)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %s", ast.Format(got))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if s := ast.Format(got); s != tt.want {
+				t.Errorf("unexpected predicate: got %s, want %s", s, tt.want)
+			}
+		})
+	}
+}
+
+func TestTailQuery(t *testing.T) {
+	pred, err := tailPredicate(`host == "a"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := tailQuery(`my "bucket"`, pred, time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC))
+	want := "from(bucket: \"my \\\"bucket\\\"\")\n\t|> range(start: 2020-01-02T03:04:05.000000006Z)\n\t|> filter(fn: (r) => r.host == \"a\")"
+	if got != want {
+		t.Errorf("unexpected query:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestResultPoints(t *testing.T) {
+	in := `#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,double,string,string,string
+#group,false,false,true,true,false,false,true,true,true
+#default,_result,,,,,,,,
+,result,table,_start,_stop,_time,_value,_field,_measurement,host
+,,0,2020-01-01T00:00:00Z,2020-01-02T00:00:00Z,2020-01-01T00:00:02Z,2.5,usage,cpu,a
+,,0,2020-01-01T00:00:00Z,2020-01-02T00:00:00Z,2020-01-01T00:00:04Z,3.5,usage,cpu,a
+
+#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,string,string,string,string
+#group,false,false,true,true,false,false,true,true,true
+#default,_result,,,,,,,,
+,result,table,_start,_stop,_time,_value,_field,_measurement,host
+,,1,2020-01-01T00:00:00Z,2020-01-02T00:00:00Z,2020-01-01T00:00:03Z,up,state,svc,a b
+`
+
+	results, err := csv.NewMultiResultDecoder(csv.ResultDecoderConfig{}).Decode(ioutil.NopCloser(strings.NewReader(in)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer results.Release()
+
+	points, err := resultPoints(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, p := range points {
+		got = append(got, p.String())
+	}
+	want := []string{
+		`cpu,host=a usage=2.5 1577836802000000000`,
+		`svc,host=a\ b state="up" 1577836803000000000`,
+		`cpu,host=a usage=3.5 1577836804000000000`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected points:\ngot:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -1,11 +1,18 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/andreyvit/diff"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/csv"
+	"github.com/influxdata/flux/execute"
 	"github.com/influxdata/flux/repl"
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/cmd/influx/internal"
@@ -33,6 +40,8 @@ func cmdTask(f *globalFlags, opt genericCLIOpts) *cobra.Command {
 		taskDeleteCmd(opt),
 		taskFindCmd(opt),
 		taskUpdateCmd(opt),
+		taskPreviewCmd(opt),
+		taskHistoryCmd(opt),
 	)
 
 	return cmd
@@ -381,6 +390,77 @@ func taskLogCmd(opt genericCLIOpts) *cobra.Command {
 	return cmd
 }
 
+var taskPreviewFlags struct {
+	id    string
+	start string
+	stop  string
+}
+
+func taskPreviewCmd(opt genericCLIOpts) *cobra.Command {
+	cmd := opt.newCmd("preview", taskPreviewF)
+	cmd.Short = "Preview the output of a task"
+	cmd.Long = `Execute a task immediately over the time range [start, stop) and print
+the tables it would write, without writing them. The range is available to the
+task script as v.timeRangeStart and v.timeRangeStop, and now() returns stop.`
+
+	cmd.Flags().StringVarP(&taskPreviewFlags.id, "id", "i", "", "task ID (required)")
+	cmd.Flags().StringVarP(&taskPreviewFlags.start, "start", "", "", "start of the time range, in RFC3339 format (required)")
+	cmd.Flags().StringVarP(&taskPreviewFlags.stop, "stop", "", "", "stop of the time range, in RFC3339 format (defaults to now)")
+	cmd.MarkFlagRequired("id")
+	cmd.MarkFlagRequired("start")
+
+	return cmd
+}
+
+func taskPreviewF(cmd *cobra.Command, args []string) error {
+	client, err := newHTTPClient()
+	if err != nil {
+		return err
+	}
+
+	s := &http.TaskService{
+		Client:             client,
+		InsecureSkipVerify: flags.skipVerify,
+	}
+
+	var id influxdb.ID
+	if err := id.DecodeFromString(taskPreviewFlags.id); err != nil {
+		return err
+	}
+
+	start, err := time.Parse(time.RFC3339, taskPreviewFlags.start)
+	if err != nil {
+		return fmt.Errorf("invalid start time: %w", err)
+	}
+	stop := time.Now()
+	if taskPreviewFlags.stop != "" {
+		if stop, err = time.Parse(time.RFC3339, taskPreviewFlags.stop); err != nil {
+			return fmt.Errorf("invalid stop time: %w", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := s.PreviewTask(context.Background(), id, start, stop, &buf); err != nil {
+		return err
+	}
+
+	results, err := csv.NewMultiResultDecoder(csv.ResultDecoderConfig{}).Decode(ioutil.NopCloser(&buf))
+	if err != nil {
+		return err
+	}
+	defer results.Release()
+
+	for results.More() {
+		if err := results.Next().Tables().Do(func(tbl flux.Table) error {
+			_, err := execute.NewFormatter(tbl, nil).WriteTo(os.Stdout)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return results.Err()
+}
+
 var taskLogFindFlags struct {
 	taskID string
 	runID  string
@@ -599,3 +679,162 @@ func runRetryF(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+var taskHistoryFlags struct {
+	id    string
+	limit int
+}
+
+func taskHistoryCmd(opt genericCLIOpts) *cobra.Command {
+	cmd := opt.newCmd("history", taskHistoryF)
+	cmd.Short = "List the versions of the Flux script of a task"
+	cmd.Long = `List the versions of the Flux script of a task, from the most recent one.
+A version is recorded when the task is created and every time its script changes,
+including when a previous version is restored with the rollback command.`
+
+	cmd.PersistentFlags().StringVarP(&taskHistoryFlags.id, "id", "i", "", "task ID (required)")
+	cmd.Flags().IntVarP(&taskHistoryFlags.limit, "limit", "", 20, "the number of versions to list")
+	cmd.MarkPersistentFlagRequired("id")
+
+	cmd.AddCommand(
+		taskHistoryDiffCmd(opt),
+		taskHistoryRollbackCmd(opt),
+	)
+
+	return cmd
+}
+
+func taskHistoryF(cmd *cobra.Command, args []string) error {
+	s, id, err := taskHistoryService()
+	if err != nil {
+		return err
+	}
+
+	opts := influxdb.FindOptions{Limit: taskHistoryFlags.limit, Descending: true}
+	versions, _, err := s.FindTaskVersions(context.Background(), id, opts)
+	if err != nil {
+		return err
+	}
+
+	w := internal.NewTabWriter(os.Stdout)
+	w.WriteHeaders(
+		"Version",
+		"Time",
+		"UserID",
+		"Lines",
+	)
+	for _, v := range versions {
+		userID := ""
+		if v.UserID.Valid() {
+			userID = v.UserID.String()
+		}
+		w.Write(map[string]interface{}{
+			"Version": v.Version,
+			"Time":    v.Time.Format(time.RFC3339),
+			"UserID":  userID,
+			"Lines":   strings.Count(v.Definition, "\n") + 1,
+		})
+	}
+	w.Flush()
+
+	return nil
+}
+
+var taskHistoryDiffFlags struct {
+	from, to int
+}
+
+func taskHistoryDiffCmd(opt genericCLIOpts) *cobra.Command {
+	cmd := opt.newCmd("diff", taskHistoryDiffF)
+	cmd.Short = "Show the changes of the Flux script of a task between two versions"
+
+	cmd.Flags().IntVarP(&taskHistoryDiffFlags.from, "from", "", 0, "the version to compare from (defaults to the version before --to)")
+	cmd.Flags().IntVarP(&taskHistoryDiffFlags.to, "to", "", 0, "the version to compare to (defaults to the latest version)")
+
+	return cmd
+}
+
+func taskHistoryDiffF(cmd *cobra.Command, args []string) error {
+	s, id, err := taskHistoryService()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	to := taskHistoryDiffFlags.to
+	if to == 0 {
+		_, n, err := s.FindTaskVersions(ctx, id, influxdb.FindOptions{Limit: 1, Descending: true})
+		if err != nil {
+			return err
+		}
+		to = n
+	}
+	from := taskHistoryDiffFlags.from
+	if from == 0 {
+		from = to - 1
+	}
+	if from < 1 || to < 1 {
+		return fmt.Errorf("task %s has no version to compare", id)
+	}
+
+	a, err := s.FindTaskVersion(ctx, id, from)
+	if err != nil {
+		return err
+	}
+	b, err := s.FindTaskVersion(ctx, id, to)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("--- version %d (%s)\n+++ version %d (%s)\n", a.Version, a.Time.Format(time.RFC3339), b.Version, b.Time.Format(time.RFC3339))
+	fmt.Println(diff.LineDiff(a.Definition, b.Definition))
+
+	return nil
+}
+
+var taskHistoryRollbackFlags struct {
+	version int
+}
+
+func taskHistoryRollbackCmd(opt genericCLIOpts) *cobra.Command {
+	cmd := opt.newCmd("rollback", taskHistoryRollbackF)
+	cmd.Short = "Restore the Flux script of a task to a previous version"
+
+	cmd.Flags().IntVarP(&taskHistoryRollbackFlags.version, "version", "", 0, "the version to restore (required)")
+	cmd.MarkFlagRequired("version")
+
+	return cmd
+}
+
+func taskHistoryRollbackF(cmd *cobra.Command, args []string) error {
+	s, id, err := taskHistoryService()
+	if err != nil {
+		return err
+	}
+
+	t, err := s.RollbackTask(context.Background(), id, taskHistoryRollbackFlags.version)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Task %s (%s) rolled back to version %d.\n", t.ID, t.Name, taskHistoryRollbackFlags.version)
+
+	return nil
+}
+
+func taskHistoryService() (*http.TaskService, influxdb.ID, error) {
+	client, err := newHTTPClient()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var id influxdb.ID
+	if err := id.DecodeFromString(taskHistoryFlags.id); err != nil {
+		return nil, 0, err
+	}
+
+	return &http.TaskService{
+		Client:             client,
+		InsecureSkipVerify: flags.skipVerify,
+	}, id, nil
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx/internal"
+	"github.com/influxdata/influxdb/kit/check"
+	"github.com/influxdata/influxdb/pkg/httpc"
+	"github.com/mattn/go-isatty"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/spf13/cobra"
+)
+
+var topFlags struct {
+	interval time.Duration
+	count    int
+	buckets  int
+}
+
+func cmdTop(f *globalFlags, opts genericCLIOpts) *cobra.Command {
+	cmd := opts.newCmd("top", topF)
+	cmd.Short = "Display live server statistics"
+	cmd.Long = `Repeatedly polls the /metrics and /health endpoints of the server and displays
+the ingest rate, the active queries, the size of the cache, the compaction
+activity and the number of series of the largest buckets, until interrupted.
+
+Rates are computed between two refreshes, so they are only shown from the
+second refresh on. When the output is not a terminal, each refresh is printed
+after the previous one instead of replacing it.`
+
+	cmd.Flags().DurationVarP(&topFlags.interval, "interval", "i", 2*time.Second, "How often to refresh the statistics")
+	cmd.Flags().IntVarP(&topFlags.count, "count", "n", 0, "Number of refreshes before exiting; 0 refreshes until interrupted")
+	cmd.Flags().IntVar(&topFlags.buckets, "buckets", 10, "Number of buckets to display, by decreasing number of series")
+
+	return cmd
+}
+
+func topF(cmd *cobra.Command, args []string) error {
+	if flags.local {
+		return fmt.Errorf("local flag not supported for top command")
+	}
+
+	if topFlags.interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	if topFlags.count < 0 {
+		return fmt.Errorf("count must not be negative")
+	}
+
+	client, err := newHTTPClient()
+	if err != nil {
+		return err
+	}
+	bucketSvc, err := newBucketService()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	defer signal.Stop(sigCh)
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	w := cmd.OutOrStdout()
+	var clear bool
+	if f, ok := w.(*os.File); ok && isatty.IsTerminal(f.Fd()) {
+		clear = true
+	}
+
+	ticker := time.NewTicker(topFlags.interval)
+	defer ticker.Stop()
+
+	names := &topBucketNames{svc: bucketSvc, names: make(map[string]string)}
+	var prev *topSample
+	for n := 1; ; n++ {
+		cur, err := fetchTopSample(ctx, client)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to retrieve server statistics: %w", err)
+		}
+
+		var buf bytes.Buffer
+		if clear {
+			buf.WriteString("\033[H\033[2J")
+		} else if prev != nil {
+			buf.WriteString("\n")
+		}
+		renderTop(&buf, prev, cur, names.lookup(ctx, cur), topFlags.buckets)
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+		prev = cur
+
+		if topFlags.count > 0 && n >= topFlags.count {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// topSample holds the server statistics of a single refresh of influx top.
+type topSample struct {
+	time   time.Time
+	status string
+
+	cacheWrites       float64
+	cacheWrittenBytes float64
+	cacheBytes        float64
+	cacheAge          float64
+
+	queryRequests    float64
+	queriesCompiling float64
+	queriesQueueing  float64
+	queriesExecuting float64
+
+	compactions       float64
+	compactionsActive float64
+	compactionsQueued float64
+
+	goroutines float64
+	heapBytes  float64
+
+	// bucketSeries maps the ID of each bucket to its number of series.
+	bucketSeries map[string]float64
+}
+
+// fetchTopSample retrieves the current statistics of the server.
+func fetchTopSample(ctx context.Context, client *httpc.Client) (*topSample, error) {
+	var s *topSample
+	err := client.Get("/metrics").
+		Accept("text/plain").
+		Decode(func(resp *http.Response) error {
+			var err error
+			s, err = parseTopSample(resp.Body)
+			return err
+		}).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.time = time.Now()
+
+	// The health endpoint reports a failed check with a 503.
+	var health check.Response
+	err = client.Get("/health").
+		StatusFn(httpc.StatusIn(http.StatusOK, http.StatusServiceUnavailable)).
+		DecodeJSON(&health).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.status = string(health.Status)
+	return s, nil
+}
+
+// parseTopSample parses the metrics of the server, in the Prometheus text
+// format, into a sample. Values of metrics reported by more than one engine
+// or with more than one set of labels are summed.
+func parseTopSample(r io.Reader) (*topSample, error) {
+	var parser expfmt.TextParser
+	mfs, err := parser.TextToMetricFamilies(r)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &topSample{bucketSeries: make(map[string]float64)}
+	for name, mf := range mfs {
+		switch name {
+		case "storage_cache_writes_total":
+			s.cacheWrites = topSum(mf, "status", "ok")
+		case "storage_cache_written_bytes":
+			s.cacheWrittenBytes = topSum(mf, "status", "ok")
+		case "storage_cache_inuse_bytes":
+			s.cacheBytes = topSum(mf, "", "")
+		case "storage_cache_age_seconds":
+			for _, m := range mf.GetMetric() {
+				if v := topValue(m); v > s.cacheAge {
+					s.cacheAge = v
+				}
+			}
+		case "query_control_requests_total":
+			s.queryRequests = topSum(mf, "", "")
+		case "query_control_compiling_active":
+			s.queriesCompiling = topSum(mf, "", "")
+		case "query_control_queueing_active":
+			s.queriesQueueing = topSum(mf, "", "")
+		case "query_control_executing_active":
+			s.queriesExecuting = topSum(mf, "", "")
+		case "storage_compactions_total":
+			s.compactions = topSum(mf, "", "")
+		case "storage_compactions_active":
+			s.compactionsActive = topSum(mf, "", "")
+		case "storage_compactions_queued":
+			s.compactionsQueued = topSum(mf, "", "")
+		case "go_goroutines":
+			s.goroutines = topSum(mf, "", "")
+		case "go_memstats_heap_inuse_bytes":
+			s.heapBytes = topSum(mf, "", "")
+		case "storage_bucket_series_total":
+			for _, m := range mf.GetMetric() {
+				s.bucketSeries[topLabel(m, "bucket_id")] += topValue(m)
+			}
+		}
+	}
+	return s, nil
+}
+
+// topSum returns the sum of the values of the metrics of mf, restricted to
+// those whose label has the value value if label is not empty.
+func topSum(mf *dto.MetricFamily, label, value string) float64 {
+	var sum float64
+	for _, m := range mf.GetMetric() {
+		if label != "" && topLabel(m, label) != value {
+			continue
+		}
+		sum += topValue(m)
+	}
+	return sum
+}
+
+func topLabel(m *dto.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
+	}
+	return ""
+}
+
+func topValue(m *dto.Metric) float64 {
+	switch {
+	case m.Counter != nil:
+		return m.GetCounter().GetValue()
+	case m.Gauge != nil:
+		return m.GetGauge().GetValue()
+	case m.Untyped != nil:
+		return m.GetUntyped().GetValue()
+	}
+	return 0
+}
+
+// topBucketNames resolves the names of buckets from their IDs, caching them
+// for the following refreshes.
+type topBucketNames struct {
+	svc   influxdb.BucketService
+	names map[string]string
+}
+
+// lookup returns the names of the buckets of s, by ID. The ID of a bucket is
+// its name if it can not be found.
+func (n *topBucketNames) lookup(ctx context.Context, s *topSample) map[string]string {
+	for id := range s.bucketSeries {
+		if _, ok := n.names[id]; ok {
+			continue
+		}
+		n.names[id] = id
+		bucketID, err := influxdb.IDFromString(id)
+		if err != nil {
+			continue
+		}
+		if b, err := n.svc.FindBucketByID(ctx, *bucketID); err == nil {
+			n.names[id] = b.Name
+		}
+	}
+	return n.names
+}
+
+// renderTop writes the statistics of cur to w, with the rates since prev if
+// it is not nil, and the nBuckets buckets with the most series.
+func renderTop(w io.Writer, prev, cur *topSample, names map[string]string, nBuckets int) {
+	fmt.Fprintf(w, "influx top - %s - %s - status: %s\n\n", flags.host, cur.time.Format("15:04:05"), cur.status)
+
+	rate := func(f func(s *topSample) float64) float64 {
+		elapsed := cur.time.Sub(prev.time).Seconds()
+		if elapsed <= 0 {
+			return 0
+		}
+		// Counters are reset when the server restarts.
+		d := f(cur) - f(prev)
+		if d < 0 {
+			d = f(cur)
+		}
+		return d / elapsed
+	}
+
+	ingest, queries, compactions := "-", "-", "-"
+	if prev != nil {
+		ingest = fmt.Sprintf("%.1f writes/s, %s/s",
+			rate(func(s *topSample) float64 { return s.cacheWrites }),
+			topBytes(rate(func(s *topSample) float64 { return s.cacheWrittenBytes })))
+		queries = fmt.Sprintf("%.1f/s", rate(func(s *topSample) float64 { return s.queryRequests }))
+		compactions = fmt.Sprintf("%.2f/s", rate(func(s *topSample) float64 { return s.compactions }))
+	}
+
+	tw := internal.NewTabWriter(w)
+	tw.HideHeaders(true)
+	tw.WriteHeaders("Name", "Value")
+	for _, l := range [][2]string{
+		{"Ingest", ingest},
+		{"Queries", fmt.Sprintf("%.0f executing, %.0f queued, %.0f compiling, %s",
+			cur.queriesExecuting, cur.queriesQueueing, cur.queriesCompiling, queries)},
+		{"Cache", fmt.Sprintf("%s in use, %s old", topBytes(cur.cacheBytes), time.Duration(cur.cacheAge)*time.Second)},
+		{"Compactions", fmt.Sprintf("%.0f active, %.0f queued, %s", cur.compactionsActive, cur.compactionsQueued, compactions)},
+		{"Runtime", fmt.Sprintf("%.0f goroutines, %s heap", cur.goroutines, topBytes(cur.heapBytes))},
+	} {
+		tw.Write(map[string]interface{}{"Name": l[0], "Value": l[1]})
+	}
+	tw.Flush()
+
+	ids := make([]string, 0, len(cur.bucketSeries))
+	for id := range cur.bucketSeries {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if si, sj := cur.bucketSeries[ids[i]], cur.bucketSeries[ids[j]]; si != sj {
+			return si > sj
+		}
+		return ids[i] < ids[j]
+	})
+	if nBuckets > 0 && len(ids) > nBuckets {
+		ids = ids[:nBuckets]
+	}
+
+	fmt.Fprintln(w)
+	tw = internal.NewTabWriter(w)
+	tw.WriteHeaders("Bucket", "ID", "Series")
+	for _, id := range ids {
+		name := names[id]
+		if name == "" {
+			name = id
+		}
+		tw.Write(map[string]interface{}{
+			"Bucket": name,
+			"ID":     id,
+			"Series": strconv.FormatFloat(cur.bucketSeries[id], 'f', 0, 64),
+		})
+	}
+	tw.Flush()
+}
+
+// topBytes formats a number of bytes with a binary unit.
+func topBytes(b float64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%.0f B", b)
+	}
+	div, exp := float64(unit), 0
+	for n := b / unit; n >= unit && exp < 4; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", b/div, "KMGTP"[exp])
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTopSample(t *testing.T) {
+	in := `# TYPE storage_cache_writes_total counter
+storage_cache_writes_total{engine_id="0",node_id="0",status="ok"} 100
+storage_cache_writes_total{engine_id="0",node_id="0",status="error"} 7
+# TYPE storage_cache_written_bytes counter
+storage_cache_written_bytes{engine_id="0",node_id="0",status="ok"} 4096
+# TYPE storage_cache_inuse_bytes gauge
+storage_cache_inuse_bytes{engine_id="0",node_id="0"} 2048
+# TYPE storage_cache_age_seconds gauge
+storage_cache_age_seconds{engine_id="0",node_id="0"} 12
+storage_cache_age_seconds{engine_id="1",node_id="0"} 30
+# TYPE query_control_executing_active gauge
+query_control_executing_active{org="a"} 2
+query_control_executing_active{org="b"} 1
+# TYPE storage_compactions_active gauge
+storage_compactions_active{engine_id="0",level="1",node_id="0"} 1
+storage_compactions_active{engine_id="0",level="2",node_id="0"} 1
+# TYPE storage_bucket_series_total gauge
+storage_bucket_series_total{bucket_id="0000000000002000",engine_id="0",node_id="0",org_id="0000000000001000"} 10
+storage_bucket_series_total{bucket_id="0000000000003000",engine_id="0",node_id="0",org_id="0000000000001000"} 25
+# TYPE go_goroutines gauge
+go_goroutines 42
+`
+	s, err := parseTopSample(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.cacheWrites != 100 || s.cacheWrittenBytes != 4096 || s.cacheBytes != 2048 || s.cacheAge != 30 {
+		t.Errorf("unexpected cache statistics: %+v", s)
+	}
+	if s.queriesExecuting != 3 || s.compactionsActive != 2 || s.goroutines != 42 {
+		t.Errorf("unexpected statistics: %+v", s)
+	}
+	if len(s.bucketSeries) != 2 || s.bucketSeries["0000000000002000"] != 10 || s.bucketSeries["0000000000003000"] != 25 {
+		t.Errorf("unexpected bucket series: %v", s.bucketSeries)
+	}
+}
+
+func TestRenderTop(t *testing.T) {
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	prev := &topSample{
+		time:              now.Add(-2 * time.Second),
+		cacheWrites:       100,
+		cacheWrittenBytes: 1024,
+	}
+	cur := &topSample{
+		time:              now,
+		status:            "pass",
+		cacheWrites:       300,
+		cacheWrittenBytes: 4 * 1024 * 1024,
+		bucketSeries: map[string]float64{
+			"0000000000002000": 10,
+			"0000000000003000": 25,
+			"0000000000004000": 5,
+		},
+	}
+	names := map[string]string{"0000000000003000": "telegraf"}
+
+	var buf bytes.Buffer
+	renderTop(&buf, nil, cur, names, 2)
+	if out := topFields(buf.String()); !strings.Contains(out, "status: pass") || !strings.Contains(out, "Ingest -") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	buf.Reset()
+	renderTop(&buf, prev, cur, names, 2)
+	out := topFields(buf.String())
+	if !strings.Contains(out, "100.0 writes/s, 2.0 MiB/s") {
+		t.Errorf("unexpected ingest rate:\n%s", out)
+	}
+	telegraf, other := strings.Index(out, "telegraf 0000000000003000 25"), strings.Index(out, "0000000000002000 0000000000002000 10")
+	if telegraf < 0 || other < telegraf || strings.Contains(out, "0000000000004000") {
+		t.Errorf("unexpected buckets:\n%s", out)
+	}
+}
+
+// topFields replaces the padding of the columns of each line of s with a
+// single space.
+func topFields(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.Join(strings.Fields(l), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestTopBytes(t *testing.T) {
+	for _, tt := range []struct {
+		b    float64
+		want string
+	}{
+		{b: 0, want: "0 B"},
+		{b: 1023, want: "1023 B"},
+		{b: 1536, want: "1.5 KiB"},
+		{b: 3 * 1024 * 1024 * 1024, want: "3.0 GiB"},
+	} {
+		if got := topBytes(tt.b); got != tt.want {
+			t.Errorf("topBytes(%v): got %q, want %q", tt.b, got, tt.want)
+		}
+	}
+}
@@ -2,12 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strings"
 
 	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx/internal"
 	"github.com/influxdata/influxdb/http"
 	"github.com/influxdata/influxdb/kit/signals"
 	"github.com/influxdata/influxdb/models"
@@ -21,6 +23,7 @@ var writeFlags struct {
 	BucketID  string
 	Bucket    string
 	Precision string
+	Strict    bool
 }
 
 func cmdWrite(f *globalFlags, opt genericCLIOpts) *cobra.Command {
@@ -63,7 +66,13 @@ or add an entire file specified with an @ prefix.`
 			Flag:       "precision",
 			Short:      'p',
 			Default:    "ns",
-			Desc:       "Precision of the timestamps of the lines",
+			Desc:       "Precision of the timestamps of the lines, or auto to detect the precision of each timestamp from its magnitude",
+			Persistent: true,
+		},
+		{
+			DestP:      &writeFlags.Strict,
+			Flag:       "strict-precision",
+			Desc:       "With the auto precision, reject batches of lines whose timestamps are not all of the same precision",
 			Persistent: true,
 		},
 	}
@@ -83,9 +92,12 @@ func fluxWriteF(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("please specify one of bucket or bucket-id")
 	}
 
-	if !models.ValidPrecision(writeFlags.Precision) {
+	if !models.ValidPrecision(writeFlags.Precision) && writeFlags.Precision != models.PrecisionAuto {
 		return fmt.Errorf("invalid precision")
 	}
+	if writeFlags.Strict && writeFlags.Precision != models.PrecisionAuto {
+		return fmt.Errorf("strict precision requires the auto precision")
+	}
 
 	bs, err := newBucketService()
 	if err != nil {
@@ -96,7 +108,7 @@ func fluxWriteF(cmd *cobra.Command, args []string) error {
 	if writeFlags.BucketID != "" {
 		filter.ID, err = platform.IDFromString(writeFlags.BucketID)
 		if err != nil {
-			return fmt.Errorf("failed to decode bucket-id: %v", err)
+			return fmt.Errorf("failed to decode bucket-id: %w", err)
 		}
 	}
 	if writeFlags.Bucket != "" {
@@ -106,7 +118,7 @@ func fluxWriteF(cmd *cobra.Command, args []string) error {
 	if writeFlags.OrgID != "" {
 		filter.OrganizationID, err = platform.IDFromString(writeFlags.OrgID)
 		if err != nil {
-			return fmt.Errorf("failed to decode org-id id: %v", err)
+			return fmt.Errorf("failed to decode org-id id: %w", err)
 		}
 	}
 	if writeFlags.Org != "" {
@@ -115,16 +127,22 @@ func fluxWriteF(cmd *cobra.Command, args []string) error {
 
 	buckets, n, err := bs.FindBuckets(ctx, filter)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve buckets: %v", err)
+		return fmt.Errorf("failed to retrieve buckets: %w", err)
 	}
 
 	if n == 0 {
 		if writeFlags.Bucket != "" {
-			return fmt.Errorf("bucket %q was not found", writeFlags.Bucket)
+			return &platform.Error{
+				Code: platform.ENotFound,
+				Msg:  fmt.Sprintf("bucket %q was not found", writeFlags.Bucket),
+			}
 		}
 
 		if writeFlags.BucketID != "" {
-			return fmt.Errorf("bucket with id %q does not exist", writeFlags.BucketID)
+			return &platform.Error{
+				Code: platform.ENotFound,
+				Msg:  fmt.Sprintf("bucket with id %q does not exist", writeFlags.BucketID),
+			}
 		}
 	}
 
@@ -136,7 +154,7 @@ func fluxWriteF(cmd *cobra.Command, args []string) error {
 	} else if len(args[0]) > 0 && args[0][0] == '@' {
 		f, err := os.Open(args[0][1:])
 		if err != nil {
-			return fmt.Errorf("failed to open %q: %v", args[0][1:], err)
+			return fmt.Errorf("failed to open %q: %w", args[0][1:], err)
 		}
 		defer f.Close()
 		r = f
@@ -150,13 +168,25 @@ func fluxWriteF(cmd *cobra.Command, args []string) error {
 			Token:              flags.token,
 			Precision:          writeFlags.Precision,
 			InsecureSkipVerify: flags.skipVerify,
+			StrictPrecision:    writeFlags.Strict,
 		},
 	}
 
 	ctx = signals.WithStandardSignals(ctx)
 	if err := s.Write(ctx, orgID, bucketID, r); err != nil && err != context.Canceled {
-		return fmt.Errorf("failed to write data: %v", err)
+		return fmt.Errorf("failed to write data: %w", partialWriteError(err))
 	}
 
 	return nil
 }
+
+// partialWriteError returns err as an internal.PartialWriteError if the
+// server rejected some of the points written. The write endpoint only
+// answers with an unprocessable entity when the other points were written.
+func partialWriteError(err error) error {
+	var perr *platform.Error
+	if errors.As(err, &perr) && platform.ErrorCode(perr) == platform.EUnprocessableEntity {
+		return &internal.PartialWriteError{Err: err}
+	}
+	return err
+}
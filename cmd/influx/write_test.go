@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx/internal"
+	"github.com/influxdata/influxdb/http"
+)
+
+func TestPartialWriteError(t *testing.T) {
+	// The server rejects every request as an unprocessable entity.
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(nethttp.StatusUnprocessableEntity)
+		io.WriteString(w, `{"code":"unprocessable entity","message":"rejected"}`)
+	}))
+	defer srv.Close()
+
+	ws := &http.WriteService{Addr: srv.URL, Token: "token"}
+	err := ws.Write(context.Background(), influxdb.ID(1), influxdb.ID(2), strings.NewReader("m f=1"))
+	err = fmt.Errorf("failed to write data: %w", partialWriteError(err))
+	if got := internal.ExitCode(err); got != internal.ExitPartialWrite {
+		t.Errorf("unexpected exit code of a partial write: got %d, want %d", got, internal.ExitPartialWrite)
+	}
+
+	// Validation failures of other commands are not partial writes.
+	client, err := http.NewHTTPClient(srv.URL, "token", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs := &http.BucketService{Client: client}
+	err = bs.CreateBucket(context.Background(), &influxdb.Bucket{OrgID: influxdb.ID(1), Name: "b"})
+	if got := internal.ExitCode(err); got != internal.ExitError {
+		t.Errorf("unexpected exit code of a rejected bucket: got %d, want %d", got, internal.ExitError)
+	}
+}
@@ -0,0 +1,487 @@
+// Package anonymize rewrites TSM files with pseudonyms in place of their tag
+// values, so that shards can be shared without disclosing them.
+package anonymize
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// Command rewrites a set of TSM files, replacing the values of their tags,
+// and optionally the names of their measurements and the values of string
+// fields, with pseudonyms.
+//
+// The pseudonym of a value is derived from a hash of the value, salted if a
+// salt is given, so that a value has the same pseudonym in every series and
+// every file, and the cardinality of the series is preserved. Tag keys, field keys, the
+// organization and bucket of the series, and the timestamps of the values are
+// left untouched. Tombstoned values are dropped from the new files, and the
+// input files are left untouched.
+//
+// The new files are written under OutDir. Since the series of the new files
+// are not those of the index and series file of the engine, the index must be
+// rebuilt once the files are moved in place of the original ones.
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Paths lists the TSM files to anonymize, and the directories, such as
+	// shard directories or a whole data directory, searched recursively for
+	// TSM files. The new files of a directory keep their path relative to
+	// it.
+	Paths []string
+
+	// OutDir is the directory the new files are written to.
+	OutDir string
+
+	// Measurements also replaces the names of measurements with pseudonyms.
+	Measurements bool
+
+	// Tags lists the keys of the tags whose values are replaced. The values
+	// of every tag are replaced if empty.
+	Tags []string
+
+	// StringFields lists the keys of the string fields whose values are
+	// replaced with pseudonyms.
+	StringFields []string
+
+	// Salt salts the hashes of the pseudonyms, so that they cannot be
+	// reversed by hashing guessed values without knowing it.
+	Salt string
+
+	// MappingFile, if set, is a JSON file mapping values to their
+	// pseudonyms. The mappings it holds are used in place of hashes, and
+	// the mappings of new values are added to it, so that several runs map
+	// values consistently. The file discloses the original values, and
+	// must not be shared with the anonymized files.
+	MappingFile string
+
+	// Jitter, if positive, multiplies every numeric field value by a random
+	// factor between 1-Jitter and 1+Jitter. Boolean and string values are
+	// left untouched.
+	Jitter float64
+
+	// Seed seeds the random factors of Jitter, so that an anonymization can
+	// be reproduced. A seed is derived from the current time if zero.
+	Seed int64
+}
+
+// NewCommand returns a new instance of Command writing to the standard output and error.
+func NewCommand() *Command {
+	return &Command{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Stats summarizes the anonymization of a TSM file.
+type Stats struct {
+	Keys   int
+	Blocks int
+
+	// Rewritten is the number of blocks decoded to jitter their values,
+	// replace string values or drop tombstoned values, the other blocks
+	// being copied verbatim.
+	Rewritten int
+}
+
+// Run anonymizes the TSM files of Paths.
+func (cmd *Command) Run() error {
+	if len(cmd.Paths) == 0 {
+		return errors.New("path required")
+	}
+	if cmd.OutDir == "" {
+		return errors.New("output directory required")
+	}
+	if cmd.Jitter < 0 || cmd.Jitter >= 1 {
+		return errors.New("jitter must be between 0 and 1")
+	}
+
+	files, err := cmd.findFiles()
+	if err != nil {
+		return err
+	}
+
+	seed := cmd.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	a := &Anonymizer{
+		Measurements: cmd.Measurements,
+		Tags:         stringSet(cmd.Tags),
+		StringFields: stringSet(cmd.StringFields),
+		Salt:         []byte(cmd.Salt),
+		Jitter:       cmd.Jitter,
+		rand:         rand.New(rand.NewSource(seed)),
+	}
+	if cmd.MappingFile != "" {
+		if a.Mapping, err = readMapping(cmd.MappingFile); err != nil {
+			return err
+		}
+	}
+
+	var total Stats
+	for _, f := range files {
+		s, err := a.AnonymizeFile(f.path, filepath.Join(cmd.OutDir, f.rel))
+		if err != nil {
+			return fmt.Errorf("%s: %v", f.path, err)
+		}
+		fmt.Fprintf(cmd.Stdout, "%s: %d key(s), %d block(s), %d block(s) rewritten\n", f.path, s.Keys, s.Blocks, s.Rewritten)
+		total.Keys += s.Keys
+		total.Blocks += s.Blocks
+		total.Rewritten += s.Rewritten
+	}
+
+	fmt.Fprintf(cmd.Stdout, "anonymized %d TSM file(s) into %s: %d key(s), %d block(s), %d block(s) rewritten\n",
+		len(files), cmd.OutDir, total.Keys, total.Blocks, total.Rewritten)
+	if cmd.MappingFile != "" {
+		if err := writeMapping(cmd.MappingFile, a.Mapping); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.Stdout, "%d pseudonym(s) mapped in %s, which must not be shared\n", len(a.Mapping), cmd.MappingFile)
+	}
+	if cmd.Jitter > 0 {
+		fmt.Fprintf(cmd.Stdout, "field values jittered by up to %g with seed %d\n", cmd.Jitter, seed)
+	}
+	fmt.Fprintln(cmd.Stdout, "the index must be rebuilt for the anonymized files")
+	return nil
+}
+
+// Anonymizer replaces the tag values of TSM keys with pseudonyms.
+type Anonymizer struct {
+	// Measurements also replaces the names of measurements.
+	Measurements bool
+
+	// Tags is the set of tag keys whose values are replaced, every tag if
+	// nil.
+	Tags map[string]bool
+
+	// StringFields is the set of string fields whose values are replaced.
+	StringFields map[string]bool
+
+	// Salt salts the hashes of the pseudonyms.
+	Salt []byte
+
+	// Mapping maps values to their pseudonyms. Pseudonyms of values it does
+	// not hold are added to it, if it is not nil.
+	Mapping map[string]string
+
+	// Jitter is the maximum relative change of numeric field values.
+	Jitter float64
+
+	rand *rand.Rand
+}
+
+// Pseudonym returns the pseudonym of a tag value or measurement name.
+func Pseudonym(v []byte) []byte {
+	return SaltedPseudonym(nil, v)
+}
+
+// SaltedPseudonym returns the pseudonym of a value, hashed with the HMAC
+// of salt. It is the pseudonym of Pseudonym if salt is empty.
+func SaltedPseudonym(salt, v []byte) []byte {
+	var sum []byte
+	if len(salt) == 0 {
+		s := sha256.Sum256(v)
+		sum = s[:]
+	} else {
+		h := hmac.New(sha256.New, salt)
+		h.Write(v)
+		sum = h.Sum(nil)
+	}
+	dst := make([]byte, hex.EncodedLen(8))
+	hex.Encode(dst, sum[:8])
+	return dst
+}
+
+// pseudonym returns the pseudonym of v, from the mapping if it holds one.
+func (a *Anonymizer) pseudonym(v []byte) []byte {
+	if p, ok := a.Mapping[string(v)]; ok {
+		return []byte(p)
+	}
+	p := SaltedPseudonym(a.Salt, v)
+	if a.Mapping != nil {
+		a.Mapping[string(v)] = string(p)
+	}
+	return p
+}
+
+// AnonymizeKey returns the TSM key with pseudonyms in place of its tag values.
+func (a *Anonymizer) AnonymizeKey(key []byte) []byte {
+	seriesKey, field := tsm1.SeriesAndFieldFromCompositeKey(key)
+	name, tags := models.ParseKeyBytes(seriesKey)
+
+	anonymized := make(models.Tags, len(tags))
+	for i, t := range tags {
+		anonymized[i] = models.Tag{Key: t.Key, Value: t.Value}
+		switch {
+		case bytes.Equal(t.Key, models.FieldKeyTagKeyBytes):
+		case bytes.Equal(t.Key, models.MeasurementTagKeyBytes):
+			if a.Measurements {
+				anonymized[i].Value = a.pseudonym(t.Value)
+			}
+		case a.Tags == nil || a.Tags[string(t.Key)]:
+			anonymized[i].Value = a.pseudonym(t.Value)
+		}
+	}
+	return tsm1.SeriesFieldKeyBytes(string(models.MakeKey(name, anonymized)), string(field))
+}
+
+// AnonymizeFile writes the values of the TSM file at path to a new TSM file
+// at outPath, under anonymized keys. An existing file is not overwritten.
+func (a *Anonymizer) AnonymizeFile(path, outPath string) (stats Stats, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return stats, err
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		f.Close()
+		return stats, fmt.Errorf("unable to read: %v", err)
+	}
+	defer r.Close()
+
+	keys, err := a.anonymizeKeys(r)
+	if err != nil {
+		return stats, err
+	}
+	stats.Keys = len(keys)
+
+	if _, err := os.Stat(outPath); err == nil {
+		return stats, fmt.Errorf("%s already exists", outPath)
+	} else if !os.IsNotExist(err) {
+		return stats, err
+	}
+	if err := os.MkdirAll(filepath.Dir(outPath), 0777); err != nil {
+		return stats, err
+	}
+	out, err := os.Create(outPath)
+	if err != nil {
+		return stats, err
+	}
+	w, err := tsm1.NewTSMWriter(out)
+	if err != nil {
+		out.Close()
+		return stats, err
+	}
+	defer func() {
+		if err == nil {
+			err = w.WriteIndex()
+		}
+		if err == nil {
+			err = w.Close()
+			return
+		}
+		w.Remove()
+	}()
+
+	var (
+		entries []tsm1.IndexEntry
+		trbuf   []tsm1.TimeRange
+		values  []tsm1.Value
+		buf     []byte
+		written bool
+	)
+	for _, k := range keys {
+		if entries, err = r.ReadEntries(k.key, entries[:0]); err != nil {
+			return stats, err
+		}
+		trbuf = r.TombstoneRange(k.key, trbuf[:0])
+		_, field := tsm1.SeriesAndFieldFromCompositeKey(k.key)
+		rewrite := a.Jitter > 0 || a.StringFields[string(field)]
+		for i := range entries {
+			e := &entries[i]
+			stats.Blocks++
+			if _, buf, err = r.ReadBytes(e, buf); err != nil {
+				return stats, err
+			}
+
+			if !rewrite && !overlaps(trbuf, e.MinTime, e.MaxTime) {
+				if err := w.WriteBlock(k.anonymized, e.MinTime, e.MaxTime, buf); err != nil {
+					return stats, err
+				}
+				written = true
+				continue
+			}
+
+			stats.Rewritten++
+			if values, err = tsm1.DecodeBlock(buf, values[:0]); err != nil {
+				return stats, fmt.Errorf("unable to decode block of key %q: %v", k.key, err)
+			}
+			vs := tsm1.Values(values)
+			for _, tr := range trbuf {
+				vs = vs.Exclude(tr.Min, tr.Max)
+			}
+			if len(vs) == 0 {
+				continue
+			}
+			if a.Jitter > 0 {
+				vs = a.jitter(vs)
+			}
+			if a.StringFields[string(field)] {
+				vs = a.replaceStrings(vs)
+			}
+			if err := w.Write(k.anonymized, vs); err != nil {
+				return stats, err
+			}
+			written = true
+		}
+	}
+	if !written {
+		return stats, errors.New("no values to write")
+	}
+	return stats, nil
+}
+
+type anonymizedKey struct {
+	key, anonymized []byte
+}
+
+// anonymizeKeys returns the keys of the reader, sorted by their anonymized
+// key as required by the TSM writer.
+func (a *Anonymizer) anonymizeKeys(r *tsm1.TSMReader) ([]anonymizedKey, error) {
+	var keys []anonymizedKey
+	iter := r.Iterator(nil)
+	for iter.Next() {
+		key := append([]byte(nil), iter.Key()...)
+		keys = append(keys, anonymizedKey{key: key, anonymized: a.AnonymizeKey(key)})
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i].anonymized, keys[j].anonymized) < 0 })
+	for i := 1; i < len(keys); i++ {
+		if bytes.Equal(keys[i-1].anonymized, keys[i].anonymized) {
+			return nil, fmt.Errorf("keys %q and %q have the same pseudonym", keys[i-1].key, keys[i].key)
+		}
+	}
+	return keys, nil
+}
+
+// jitter returns the values with their numeric values multiplied by random
+// factors.
+func (a *Anonymizer) jitter(vs tsm1.Values) tsm1.Values {
+	out := make(tsm1.Values, len(vs))
+	for i, v := range vs {
+		f := 1 + a.Jitter*(2*a.rand.Float64()-1)
+		switch x := v.Value().(type) {
+		case float64:
+			out[i] = tsm1.NewValue(v.UnixNano(), x*f)
+		case int64:
+			out[i] = tsm1.NewValue(v.UnixNano(), int64(math.Round(float64(x)*f)))
+		case uint64:
+			out[i] = tsm1.NewValue(v.UnixNano(), uint64(math.Round(float64(x)*f)))
+		default:
+			out[i] = v
+		}
+	}
+	return out
+}
+
+// replaceStrings returns the values with pseudonyms in place of their string
+// values.
+func (a *Anonymizer) replaceStrings(vs tsm1.Values) tsm1.Values {
+	out := make(tsm1.Values, len(vs))
+	for i, v := range vs {
+		if x, ok := v.Value().(string); ok {
+			out[i] = tsm1.NewValue(v.UnixNano(), string(a.pseudonym([]byte(x))))
+		} else {
+			out[i] = v
+		}
+	}
+	return out
+}
+
+// stringSet returns the set of values, nil if there are none.
+func stringSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// readMapping reads the mapping of values to pseudonyms of a mapping file.
+// The mapping is empty if the file does not exist.
+func readMapping(path string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return mapping, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return nil, fmt.Errorf("invalid mapping file %s: %v", path, err)
+	}
+	return mapping, nil
+}
+
+// writeMapping writes the mapping of values to pseudonyms to a mapping file,
+// readable by its owner only.
+func writeMapping(path string, mapping map[string]string) error {
+	data, err := json.MarshalIndent(mapping, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// overlaps returns true if any of the time ranges overlaps [min, max].
+func overlaps(trs []tsm1.TimeRange, min, max int64) bool {
+	for _, tr := range trs {
+		if tr.Min <= max && tr.Max >= min {
+			return true
+		}
+	}
+	return false
+}
+
+type tsmFile struct {
+	path string
+	rel  string // path of the new file, relative to the output directory
+}
+
+// findFiles returns the TSM files of Paths, searching directories
+// recursively.
+func (cmd *Command) findFiles() ([]tsmFile, error) {
+	var files []tsmFile
+	for _, root := range cmd.Paths {
+		err := tsmfile.Walk(root, func(path string) error {
+			rel := filepath.Base(path)
+			if path != root {
+				var err error
+				if rel, err = filepath.Rel(root, path); err != nil {
+					return err
+				}
+			}
+			files = append(files, tsmFile{path: path, rel: rel})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
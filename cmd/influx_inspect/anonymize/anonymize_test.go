@@ -0,0 +1,188 @@
+package anonymize_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/anonymize"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile/tsmtest"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+func TestCommand_Run(t *testing.T) {
+	dir, err := ioutil.TempDir("", "anonymize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	shard := filepath.Join(dir, "data", "1")
+	if err := os.MkdirAll(shard, 0777); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(shard, "000000001-000000001.tsm")
+	hostA, hostB := key("cpu", "a", "usage"), key("cpu", "b", "usage")
+	tsmtest.WriteFile(t, path, map[string]tsm1.Values{
+		hostA:                   {tsm1.NewValue(10, 1.0), tsm1.NewValue(20, 2.0)},
+		hostB:                   {tsm1.NewValue(10, 3.0)},
+		key("mem", "a", "free"): {tsm1.NewValue(10, int64(100))},
+		key("mem", "a", "mode"): {tsm1.NewValue(10, "s")},
+	})
+	deleteRange(t, path, hostA, 10, 10)
+
+	var stdout bytes.Buffer
+	cmd := anonymize.NewCommand()
+	cmd.Stdout = &stdout
+	cmd.Paths = []string{filepath.Join(dir, "data")}
+	cmd.OutDir = filepath.Join(dir, "out")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "anonymized 1 TSM file(s)") {
+		t.Fatalf("unexpected output: %s", stdout.String())
+	}
+
+	a, b := string(anonymize.Pseudonym([]byte("a"))), string(anonymize.Pseudonym([]byte("b")))
+	got := tsmtest.ReadValues(t, filepath.Join(dir, "out", "1", "000000001-000000001.tsm"))
+	want := map[string][]interface{}{
+		key("cpu", a, "usage"): {2.0},
+		key("cpu", b, "usage"): {3.0},
+		key("mem", a, "free"):  {int64(100)},
+		key("mem", a, "mode"):  {"s"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected values: got %v, want %v", got, want)
+	}
+
+	// Existing files are not overwritten.
+	if err := cmd.Run(); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Measurements are anonymized, and values jittered, on demand.
+	cmd.OutDir = filepath.Join(dir, "jittered")
+	cmd.Measurements = true
+	cmd.Jitter = 0.5
+	cmd.Seed = 1
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	got = tsmtest.ReadValues(t, filepath.Join(dir, "jittered", "1", "000000001-000000001.tsm"))
+	mem := string(anonymize.Pseudonym([]byte("mem")))
+	if len(got) != 4 || !reflect.DeepEqual(got[key(mem, a, "mode")], []interface{}{"s"}) || len(got[key(mem, a, "free")]) != 1 {
+		t.Fatalf("unexpected values: %v", got)
+	} else if v := got[key(mem, a, "free")][0].(int64); v < 50 || v > 150 || v == 100 {
+		t.Fatalf("unexpected jittered value %d", v)
+	}
+}
+
+func TestCommand_Run_Salted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "anonymize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "000000001-000000001.tsm")
+	tsmtest.WriteFile(t, path, map[string]tsm1.Values{
+		key("cpu", "a", "usage"): {tsm1.NewValue(10, 1.0)},
+		key("cpu", "a", "user"):  {tsm1.NewValue(10, "alice"), tsm1.NewValue(20, "bob")},
+	})
+
+	mappingFile := filepath.Join(dir, "mapping.json")
+	cmd := anonymize.NewCommand()
+	cmd.Stdout = ioutil.Discard
+	cmd.Paths = []string{path}
+	cmd.OutDir = filepath.Join(dir, "out")
+	cmd.Tags = []string{"host"}
+	cmd.StringFields = []string{"user"}
+	cmd.Salt = "secret"
+	cmd.MappingFile = mappingFile
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	salt := []byte("secret")
+	a := string(anonymize.SaltedPseudonym(salt, []byte("a")))
+	if a == string(anonymize.Pseudonym([]byte("a"))) {
+		t.Fatalf("salted pseudonym is not salted: %s", a)
+	}
+	got := tsmtest.ReadValues(t, filepath.Join(dir, "out", "000000001-000000001.tsm"))
+	want := map[string][]interface{}{
+		key("cpu", a, "usage"): {1.0},
+		key("cpu", a, "user"): {
+			string(anonymize.SaltedPseudonym(salt, []byte("alice"))),
+			string(anonymize.SaltedPseudonym(salt, []byte("bob"))),
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected values: got %v, want %v", got, want)
+	}
+
+	// Pseudonyms of the mapping file are reused by other runs, whatever
+	// their salt.
+	cmd.OutDir = filepath.Join(dir, "remapped")
+	cmd.Salt = "other"
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got := tsmtest.ReadValues(t, filepath.Join(dir, "remapped", "000000001-000000001.tsm")); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected values: got %v, want %v", got, want)
+	}
+
+	// Tags that are not listed are left untouched.
+	cmd.OutDir = filepath.Join(dir, "untouched")
+	cmd.Tags = []string{"region"}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got := tsmtest.ReadValues(t, filepath.Join(dir, "untouched", "000000001-000000001.tsm")); len(got[key("cpu", "a", "usage")]) != 1 {
+		t.Fatalf("unexpected values: %v", got)
+	}
+}
+
+func TestPseudonym(t *testing.T) {
+	if a, b := anonymize.Pseudonym([]byte("host-1")), anonymize.Pseudonym([]byte("host-1")); !bytes.Equal(a, b) {
+		t.Fatalf("pseudonyms differ: %s, %s", a, b)
+	}
+	if a, b := anonymize.Pseudonym([]byte("host-1")), anonymize.Pseudonym([]byte("host-2")); bytes.Equal(a, b) {
+		t.Fatalf("pseudonyms are equal: %s", a)
+	}
+}
+
+// key returns the TSM key of a series of a bucket.
+func key(measurement, host, field string) string {
+	encoded := tsdb.EncodeName(influxdb.ID(1), influxdb.ID(2))
+	seriesKey := models.MakeKey(encoded[:], models.NewTags(map[string]string{
+		models.MeasurementTagKey: measurement,
+		"host":                   host,
+		models.FieldKeyTagKey:    field,
+	}))
+	return string(tsm1.SeriesFieldKeyBytes(string(seriesKey), field))
+}
+
+// deleteRange records a tombstone for the values of key in [min, max].
+func deleteRange(t *testing.T, path, key string, min, max int64) {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := r.DeleteRange([][]byte{[]byte(key)}, min, max); err != nil {
+		t.Fatal(err)
+	}
+}
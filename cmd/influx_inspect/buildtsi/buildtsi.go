@@ -4,9 +4,13 @@ package buildtsi
 import (
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/pkg/fs"
@@ -16,31 +20,140 @@ import (
 	"github.com/influxdata/influxdb/tsdb/tsi1"
 	"github.com/influxdata/influxdb/tsdb/tsm1"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 )
 
-func IndexShard(sfile *tsdb.SeriesFile, indexPath, dataDir, walDir string, maxLogFileSize int64, maxCacheSize uint64, batchSize int, log *zap.Logger, verboseLogging bool) error {
+// Options configures the build of the index of a shard.
+type Options struct {
+	// MaxLogFileSize is the size the log file of a partition of the index
+	// grows to before it is compacted into an index file.
+	MaxLogFileSize int64
+
+	// MaxCacheSize is the size of the cache the WAL is replayed into.
+	MaxCacheSize uint64
+
+	// BatchSize is the maximum number of series created in the index at once.
+	BatchSize int
+
+	// Concurrency is the number of TSM files indexed concurrently.
+	Concurrency int
+
+	// MaxMemory optionally bounds the memory used by the index being built,
+	// and by the batches of series in flight, to about this many bytes. Half
+	// of it is shared by the batches of the concurrent workers, which are
+	// flushed early if needed, and half by the log files of the partitions
+	// of the index, which are compacted to disk sooner.
+	MaxMemory int64
+
+	// Incremental updates an existing index with the series of the TSM
+	// files and WAL segments added or modified since it was built, as
+	// recorded in its manifest, rather than leaving it untouched.
+	Incremental bool
+
+	// Verify checks that the index holds exactly the series of the TSM files
+	// and WAL segments once it is built, before it is moved to its path. An
+	// index updated incrementally may hold more series, those of the files
+	// removed since it was built.
+	Verify bool
+
+	// Report, if set, receives the duration of each step of the build and
+	// the series counted by the verification.
+	Report io.Writer
+
+	Verbose bool
+}
+
+// settings returns the configuration of the index being built, and the
+// size of the batches, the number of workers and the maximum size in bytes
+// of the batches of each worker.
+func (opts Options) settings() (c tsi1.Config, batchSize, concurrency, maxBatchBytes int) {
+	batchSize = opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = 10000
+	}
+	concurrency = opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	c = tsi1.NewConfig()
+	c.MaxIndexLogFileSize = toml.Size(opts.MaxLogFileSize)
+
+	// Bound the memory of the batches in flight and of the log files.
+	if opts.MaxMemory > 0 {
+		maxBatchBytes = int(opts.MaxMemory / 2 / int64(concurrency))
+		if size := opts.MaxMemory / 2 / int64(tsi1.DefaultPartitionN); c.MaxIndexLogFileSize == 0 || toml.Size(size) < c.MaxIndexLogFileSize {
+			c.MaxIndexLogFileSize = toml.Size(size)
+		}
+	}
+	return c, batchSize, concurrency, maxBatchBytes
+}
+
+// progressFilename is the name of the file, in the temporary index
+// directory, listing the TSM files already indexed.
+const progressFilename = "buildtsi.progress"
+
+// IndexShard builds the index of the series of the TSM files of dataDir and
+// of the WAL segments of walDir at indexPath. It does nothing if the index
+// already exists, unless opts.Incremental is set.
+//
+// The index is built in a temporary directory, moved to indexPath once
+// complete. If a previous build was interrupted, the TSM files it indexed,
+// recorded in a progress file, are skipped and the build is resumed. The
+// files indexed are recorded in the manifest of the index, so that
+// incremental builds only index the files added or modified since.
+//
+// If opts.Verify is set, the series of the index are checked against those
+// of the files, including when the index is not built or updated. The
+// duration of each step is written to opts.Report, if set, even if the build
+// fails.
+func IndexShard(sfile *tsdb.SeriesFile, indexPath, dataDir, walDir string, opts Options, log *zap.Logger) error {
+	rep := newReport()
+	err := indexShard(sfile, indexPath, dataDir, walDir, opts, rep, log)
+	if werr := rep.write(opts.Report); err == nil {
+		err = werr
+	}
+	return err
+}
+
+func indexShard(sfile *tsdb.SeriesFile, indexPath, dataDir, walDir string, opts Options, rep *report, log *zap.Logger) error {
 	log.Info("Rebuilding shard")
 
 	// Check if shard already has a TSI index.
 	log.Info("Checking index path", zap.String("path", indexPath))
 	if _, err := os.Stat(indexPath); !os.IsNotExist(err) {
+		if opts.Incremental {
+			return updateIndex(sfile, indexPath, dataDir, walDir, opts, rep, log)
+		}
 		log.Info("TSI1 index already exists, skipping", zap.String("path", indexPath))
+		if opts.Verify {
+			return verifyExistingIndex(sfile, indexPath, dataDir, walDir, opts.MaxCacheSize, rep, log)
+		}
 		return nil
 	}
 
 	log.Info("Opening shard")
 
-	// Remove temporary index files if this is being re-run.
+	// Resume the build of a previous run, or remove its temporary index
+	// files if it did not record its progress.
 	tmpPath := filepath.Join(dataDir, ".index")
-	log.Info("Cleaning up partial index from previous run, if any")
-	if err := os.RemoveAll(tmpPath); err != nil {
+	progressPath := filepath.Join(tmpPath, progressFilename)
+	done, err := readProgress(progressPath)
+	if err != nil {
 		return err
 	}
+	if len(done) > 0 {
+		log.Info("Resuming partial index from previous run", zap.Int("indexed_files", len(done)))
+	} else {
+		log.Info("Cleaning up partial index from previous run, if any")
+		if err := os.RemoveAll(tmpPath); err != nil {
+			return err
+		}
+	}
 
-	// Open TSI index in temporary path.
-	c := tsi1.NewConfig()
-	c.MaxIndexLogFileSize = toml.Size(maxLogFileSize)
+	c, batchSize, concurrency, maxBatchBytes := opts.settings()
 
+	// Open TSI index in temporary path.
 	tsiIndex := tsi1.NewIndex(sfile, c,
 		tsi1.WithPath(tmpPath),
 		tsi1.DisableFsync(),
@@ -63,88 +176,311 @@ func IndexShard(sfile *tsdb.SeriesFile, indexPath, dataDir, walDir string, maxLo
 	if err != nil {
 		return err
 	}
-
-	log.Info("Iterating over tsm files")
+	var todo []string
 	for _, path := range tsmPaths {
-		log.Info("Processing tsm file", zap.String("path", path))
-		if err := IndexTSMFile(tsiIndex, path, batchSize, log, verboseLogging); err != nil {
-			return err
+		if !done[filepath.Base(path)] {
+			todo = append(todo, path)
 		}
 	}
 
+	progress, err := os.OpenFile(progressPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	defer progress.Close()
+
+	rep.skippedTSMFiles = len(tsmPaths) - len(todo)
+	err = rep.time("Index TSM files", len(todo), func() error {
+		return indexTSMFiles(tsiIndex, todo, batchSize, maxBatchBytes, concurrency, log, opts.Verbose, func(path string) error {
+			_, err := fmt.Fprintln(progress, filepath.Base(path))
+			return err
+		})
+	})
+	if err != nil {
+		return err
+	}
+
 	// Write out wal files.
 	walPaths, err := collectWALFiles(walDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	err = rep.time("Index WAL segments", len(walPaths), func() error {
+		return indexWALFiles(tsiIndex, walPaths, batchSize, opts.MaxCacheSize, log, opts.Verbose)
+	})
 	if err != nil {
-		if !os.IsNotExist(err) {
-			return err
-		}
+		return err
+	}
 
-	} else {
-		log.Info("Building cache from wal files")
-		cache := tsm1.NewCache(uint64(tsm1.DefaultCacheMaxMemorySize))
-		loader := tsm1.NewCacheLoader(walPaths)
-		loader.WithLogger(log)
-		if err := loader.Load(cache); err != nil {
+	// Record the indexed files for incremental builds.
+	manifest, err := newManifest(tsmPaths, walPaths)
+	if err != nil {
+		return err
+	} else if err := manifest.write(filepath.Join(tmpPath, manifestFilename)); err != nil {
+		return err
+	}
+
+	// Attempt to compact the index & wait for all compactions to complete.
+	err = rep.time("Compact index", 0, func() error {
+		log.Info("Compacting index")
+		tsiIndex.Compact()
+		tsiIndex.Wait()
+
+		// Close TSI index.
+		log.Info("Closing tsi index")
+		return tsiIndex.Close()
+	})
+	if err != nil {
+		return err
+	}
+
+	// An index failing verification is removed, so that it is built again
+	// from scratch by the next run.
+	if opts.Verify {
+		if err := verifyIndex(sfile, tmpPath, tsmPaths, walPaths, opts.MaxCacheSize, false, rep, log); err != nil {
+			progress.Close()
+			if rerr := os.RemoveAll(tmpPath); rerr != nil {
+				log.Warn("Unable to remove the index failing verification", zap.String("path", tmpPath), zap.Error(rerr))
+			}
 			return err
 		}
+	}
+
+	// The index is complete, so the progress of the build is discarded.
+	if err := progress.Close(); err != nil {
+		return err
+	} else if err := os.Remove(progressPath); err != nil {
+		return err
+	}
+
+	// Rename TSI to standard path.
+	log.Info("Moving tsi to permanent location")
+	return fs.RenameFile(tmpPath, indexPath)
+}
 
-		log.Info("Iterating over cache")
-		collection := &tsdb.SeriesCollection{
-			Keys:  make([][]byte, 0, batchSize),
-			Names: make([][]byte, 0, batchSize),
-			Tags:  make([]models.Tags, 0, batchSize),
-			Types: make([]models.FieldType, 0, batchSize),
+// updateIndex indexes the TSM files and WAL segments of dataDir and walDir
+// which are missing from the manifest of the index at indexPath, or were
+// modified since it was written, in the index itself. The index must not be
+// in use.
+//
+// The series of the removed files are left in the index, which must be fully
+// rebuilt to drop them.
+func updateIndex(sfile *tsdb.SeriesFile, indexPath, dataDir, walDir string, opts Options, rep *report, log *zap.Logger) error {
+	manifestPath := filepath.Join(indexPath, manifestFilename)
+	prev, err := readManifest(manifestPath)
+	if err != nil {
+		return err
+	} else if prev == nil {
+		log.Info("TSI1 index has no manifest, skipping; remove it to rebuild it", zap.String("path", indexPath))
+		return nil
+	}
+
+	tsmPaths, err := collectTSMFiles(dataDir)
+	if err != nil {
+		return err
+	}
+	walPaths, err := collectWALFiles(walDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	manifest, err := newManifest(tsmPaths, walPaths)
+	if err != nil {
+		return err
+	}
+	tsmTodo, walTodo := manifest.changedSince(prev, tsmPaths, walPaths)
+	if len(tsmTodo) == 0 && len(walTodo) == 0 {
+		log.Info("TSI1 index is up to date, skipping", zap.String("path", indexPath))
+		if opts.Verify {
+			return verifyIndex(sfile, indexPath, tsmPaths, walPaths, opts.MaxCacheSize, true, rep, log)
 		}
+		return nil
+	}
+	log.Info("Updating index", zap.String("path", indexPath),
+		zap.Int("tsm_files", len(tsmTodo)), zap.Int("wal_files", len(walTodo)))
 
-		for _, key := range cache.Keys() {
-			seriesKey, _ := tsm1.SeriesAndFieldFromCompositeKey(key)
-			name, tags := models.ParseKeyBytes(seriesKey)
-			typ, _ := cache.Type(key)
+	c, batchSize, concurrency, maxBatchBytes := opts.settings()
+	tsiIndex := tsi1.NewIndex(sfile, c,
+		tsi1.WithPath(indexPath),
+		tsi1.WithLogFileBufferSize(12*batchSize),
+		tsi1.DisableMetrics(),
+	)
+	tsiIndex.WithLogger(log)
+	if err := tsiIndex.Open(context.Background()); err != nil {
+		return err
+	}
+	defer tsiIndex.Close()
 
-			if verboseLogging {
-				log.Info("Series", zap.String("name", string(name)), zap.String("tags", tags.String()))
-			}
+	rep.skippedTSMFiles = len(tsmPaths) - len(tsmTodo)
+	err = rep.time("Index TSM files", len(tsmTodo), func() error {
+		return indexTSMFiles(tsiIndex, tsmTodo, batchSize, maxBatchBytes, concurrency, log, opts.Verbose, nil)
+	})
+	if err != nil {
+		return err
+	}
+	err = rep.time("Index WAL segments", len(walTodo), func() error {
+		return indexWALFiles(tsiIndex, walTodo, batchSize, opts.MaxCacheSize, log, opts.Verbose)
+	})
+	if err != nil {
+		return err
+	}
 
-			collection.Keys = append(collection.Keys, seriesKey)
-			collection.Names = append(collection.Names, name)
-			collection.Tags = append(collection.Tags, tags)
-			collection.Types = append(collection.Types, typ)
+	err = rep.time("Compact index", 0, func() error {
+		log.Info("Compacting index")
+		tsiIndex.Compact()
+		tsiIndex.Wait()
+		return tsiIndex.Close()
+	})
+	if err != nil {
+		return err
+	}
 
-			// Flush batch?
-			if collection.Length() == batchSize {
-				if err := tsiIndex.CreateSeriesListIfNotExists(collection); err != nil {
-					return fmt.Errorf("problem creating series: (%s)", err)
+	// The manifest is only updated once the index is complete and verified,
+	// so that an interrupted or failed update is done again.
+	if opts.Verify {
+		if err := verifyIndex(sfile, indexPath, tsmPaths, walPaths, opts.MaxCacheSize, true, rep, log); err != nil {
+			return err
+		}
+	}
+	return manifest.write(manifestPath)
+}
+
+// indexTSMFiles creates the series of the TSM files in the index with
+// concurrency workers, calling done, if set, with each file indexed once its
+// series are on disk.
+func indexTSMFiles(index *tsi1.Index, paths []string, batchSize, maxBatchBytes, concurrency int, log *zap.Logger, verbose bool, done func(path string) error) error {
+	log.Info("Iterating over tsm files", zap.Int("files", len(paths)), zap.Int("concurrency", concurrency))
+	// The files are queued up front, and the workers stop taking files once
+	// one of them fails.
+	queue := make(chan string, len(paths))
+	for _, path := range paths {
+		queue <- path
+	}
+	close(queue)
+
+	var (
+		g       errgroup.Group
+		mu      sync.Mutex
+		stopped int32
+		indexed int
+	)
+	for i := 0; i < concurrency; i++ {
+		g.Go(func() error {
+			for path := range queue {
+				if atomic.LoadInt32(&stopped) != 0 {
+					return nil
+				}
+				log.Info("Processing tsm file", zap.String("path", path))
+				err := indexTSMFile(index, path, batchSize, maxBatchBytes, log, verbose)
+				if err == nil {
+					// Record the file as indexed once its series are on disk.
+					mu.Lock()
+					if err = index.Flush(); err == nil && done != nil {
+						err = done(path)
+					}
+					if err == nil {
+						indexed++
+						log.Info("Indexed tsm file", zap.String("path", path),
+							zap.Int("indexed_files", indexed), zap.Int("files", len(paths)),
+							zap.String("progress", fmt.Sprintf("%.1f%%", 100*float64(indexed)/float64(len(paths)))))
+					}
+					mu.Unlock()
+				}
+				if err != nil {
+					atomic.StoreInt32(&stopped, 1)
+					return err
 				}
-				collection.Truncate(0)
 			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// indexWALFiles creates the series of the WAL segments in the index, by
+// replaying them into a cache of at most maxCacheSize bytes.
+func indexWALFiles(index *tsi1.Index, paths []string, batchSize int, maxCacheSize uint64, log *zap.Logger, verbose bool) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	log.Info("Building cache from wal files", zap.Int("files", len(paths)))
+	cache := tsm1.NewCache(maxCacheSize)
+	loader := tsm1.NewCacheLoader(paths)
+	loader.WithLogger(log)
+	if err := loader.Load(cache); err != nil {
+		return err
+	}
+
+	log.Info("Iterating over cache")
+	collection := &tsdb.SeriesCollection{
+		Keys:  make([][]byte, 0, batchSize),
+		Names: make([][]byte, 0, batchSize),
+		Tags:  make([]models.Tags, 0, batchSize),
+		Types: make([]models.FieldType, 0, batchSize),
+	}
+
+	for _, key := range cache.Keys() {
+		seriesKey, _ := tsm1.SeriesAndFieldFromCompositeKey(key)
+		name, tags := models.ParseKeyBytes(seriesKey)
+		typ, _ := cache.Type(key)
+
+		if verbose {
+			log.Info("Series", zap.String("name", string(name)), zap.String("tags", tags.String()))
 		}
 
-		// Flush any remaining series in the batches
-		if collection.Length() > 0 {
-			if err := tsiIndex.CreateSeriesListIfNotExists(collection); err != nil {
+		collection.Keys = append(collection.Keys, seriesKey)
+		collection.Names = append(collection.Names, name)
+		collection.Tags = append(collection.Tags, tags)
+		collection.Types = append(collection.Types, typ)
+
+		// Flush batch?
+		if collection.Length() == batchSize {
+			if err := index.CreateSeriesListIfNotExists(collection); err != nil {
 				return fmt.Errorf("problem creating series: (%s)", err)
 			}
-			collection = nil
+			collection.Truncate(0)
 		}
 	}
 
-	// Attempt to compact the index & wait for all compactions to complete.
-	log.Info("Compacting index")
-	tsiIndex.Compact()
-	tsiIndex.Wait()
+	// Flush any remaining series in the batches
+	if collection.Length() > 0 {
+		if err := index.CreateSeriesListIfNotExists(collection); err != nil {
+			return fmt.Errorf("problem creating series: (%s)", err)
+		}
+	}
+	return nil
+}
 
-	// Close TSI index.
-	log.Info("Closing tsi index")
-	if err := tsiIndex.Close(); err != nil {
-		return err
+// readProgress returns the names of the TSM files listed in the progress
+// file at path, if any.
+func readProgress(path string) (map[string]bool, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
 	}
 
-	// Rename TSI to standard path.
-	log.Info("Moving tsi to permanent location")
-	return fs.RenameFile(tmpPath, indexPath)
+	done := make(map[string]bool)
+	for _, name := range strings.Split(string(data), "\n") {
+		// An interrupted write may leave a partial last line, which is
+		// ignored as it matches no file.
+		if name != "" {
+			done[name] = true
+		}
+	}
+	return done, nil
 }
 
+// IndexTSMFile creates the series of the TSM file at path in the index, by
+// batches of batchSize series.
 func IndexTSMFile(index *tsi1.Index, path string, batchSize int, log *zap.Logger, verboseLogging bool) error {
+	return indexTSMFile(index, path, batchSize, 0, log, verboseLogging)
+}
+
+// indexTSMFile creates the series of the TSM file at path in the index, by
+// batches of at most batchSize series and, if set, maxBatchBytes bytes.
+func indexTSMFile(index *tsi1.Index, path string, batchSize, maxBatchBytes int, log *zap.Logger, verboseLogging bool) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return err
@@ -164,7 +500,7 @@ func IndexTSMFile(index *tsi1.Index, path string, batchSize int, log *zap.Logger
 		Tags:  make([]models.Tags, batchSize),
 		Types: make([]models.FieldType, 0, batchSize),
 	}
-	var ti int
+	var ti, batchBytes int
 	iter := r.Iterator(nil)
 	for iter.Next() {
 		key := iter.Key()
@@ -181,9 +517,10 @@ func IndexTSMFile(index *tsi1.Index, path string, batchSize int, log *zap.Logger
 		collection.Names = append(collection.Names, name)
 		collection.Types = append(collection.Types, modelsFieldType(typ))
 		ti++
+		batchBytes += seriesSize(seriesKey, collection.Tags[ti-1])
 
 		// Flush batch?
-		if len(collection.Keys) == batchSize {
+		if len(collection.Keys) == batchSize || (maxBatchBytes > 0 && batchBytes >= maxBatchBytes) {
 			collection.Truncate(ti)
 			if err := index.CreateSeriesListIfNotExists(collection); err != nil {
 				return fmt.Errorf("problem creating series: (%s)", err)
@@ -191,6 +528,7 @@ func IndexTSMFile(index *tsi1.Index, path string, batchSize int, log *zap.Logger
 			collection.Truncate(0)
 			collection.Tags = collection.Tags[:batchSize]
 			ti = 0 // Reset tags.
+			batchBytes = 0
 		}
 	}
 	if err := iter.Err(); err != nil {
@@ -207,6 +545,11 @@ func IndexTSMFile(index *tsi1.Index, path string, batchSize int, log *zap.Logger
 	return nil
 }
 
+// seriesSize estimates the memory used by a series in a batch.
+func seriesSize(key []byte, tags models.Tags) int {
+	return len(key) + 80 + 48*len(tags)
+}
+
 func collectTSMFiles(path string) ([]string, error) {
 	fis, err := ioutil.ReadDir(path)
 	if err != nil {
@@ -0,0 +1,295 @@
+package buildtsi_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/buildtsi"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile/tsmtest"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsi1"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+	"go.uber.org/zap"
+)
+
+func TestIndexShard_Resume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buildtsi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dataDir, indexPath := filepath.Join(dir, "data"), filepath.Join(dir, "index")
+	if err := os.Mkdir(dataDir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	sfile := tsdb.NewSeriesFile(filepath.Join(dir, "_series"))
+	sfile.Logger = zap.NewNop()
+	if err := sfile.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer sfile.Close()
+
+	// Index the first file as an interrupted build would have.
+	first := filepath.Join(dataDir, "000000001-000000001.tsm")
+	tsmtest.WriteKeys(t, first, "cpu,host=a#!~#usage", "cpu,host=b#!~#usage")
+	tmpPath := filepath.Join(dataDir, ".index")
+	idx := tsi1.NewIndex(sfile, tsi1.NewConfig(), tsi1.WithPath(tmpPath), tsi1.DisableMetrics())
+	if err := idx.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := buildtsi.IndexTSMFile(idx, first, 1, zap.NewNop(), false); err != nil {
+		t.Fatal(err)
+	} else if err := idx.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmpPath, "buildtsi.progress"), []byte("000000001-000000001.tsm\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first file is rewritten with series that are not indexed if it is
+	// skipped as expected.
+	if err := os.Remove(first); err != nil {
+		t.Fatal(err)
+	} else if err := os.Remove(tsm1.StatsFilename(first)); err != nil {
+		t.Fatal(err)
+	}
+	tsmtest.WriteKeys(t, first, "skipped,host=a#!~#usage")
+	for i := 2; i <= 5; i++ {
+		tsmtest.WriteKeys(t, filepath.Join(dataDir, fmt.Sprintf("%09d-000000001.tsm", i)),
+			fmt.Sprintf("mem%d,host=a#!~#used", i), fmt.Sprintf("mem%d,host=b#!~#used", i))
+	}
+
+	if err := buildtsi.IndexShard(sfile, indexPath, dataDir, "", buildtsi.Options{
+		MaxLogFileSize: tsi1.DefaultMaxIndexLogFileSize,
+		MaxCacheSize:   uint64(tsm1.DefaultCacheMaxMemorySize),
+		BatchSize:      1,
+		Concurrency:    3,
+		MaxMemory:      1 << 20,
+	}, zap.NewNop()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(tmpPath); !os.IsNotExist(err) {
+		t.Fatalf("unexpected temporary index: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(indexPath, "buildtsi.progress")); !os.IsNotExist(err) {
+		t.Fatalf("unexpected progress file: %v", err)
+	}
+
+	idx = tsi1.NewIndex(sfile, tsi1.NewConfig(), tsi1.WithPath(indexPath), tsi1.DisableMetrics())
+	if err := idx.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+	if n := idx.SeriesN(); n != 10 {
+		t.Fatalf("unexpected number of series: %d", n)
+	}
+	for _, name := range []string{"cpu", "mem2", "mem5"} {
+		if ok, err := idx.MeasurementExists([]byte(name)); err != nil {
+			t.Fatal(err)
+		} else if !ok {
+			t.Fatalf("measurement %q not indexed", name)
+		}
+	}
+	if ok, err := idx.MeasurementExists([]byte("skipped")); err != nil {
+		t.Fatal(err)
+	} else if ok {
+		t.Fatal("measurement of an indexed file indexed again")
+	}
+}
+
+func TestIndexShard_Incremental(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buildtsi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dataDir, indexPath := filepath.Join(dir, "data"), filepath.Join(dir, "index")
+	if err := os.Mkdir(dataDir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	sfile := tsdb.NewSeriesFile(filepath.Join(dir, "_series"))
+	sfile.Logger = zap.NewNop()
+	if err := sfile.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer sfile.Close()
+
+	opts := buildtsi.Options{
+		MaxLogFileSize: tsi1.DefaultMaxIndexLogFileSize,
+		MaxCacheSize:   uint64(tsm1.DefaultCacheMaxMemorySize),
+		BatchSize:      1,
+		Concurrency:    2,
+		Incremental:    true,
+	}
+	first := filepath.Join(dataDir, "000000001-000000001.tsm")
+	tsmtest.WriteKeys(t, first, "cpu,host=a#!~#usage")
+	if err := buildtsi.IndexShard(sfile, indexPath, dataDir, "", opts, zap.NewNop()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(indexPath, "buildtsi.manifest")); err != nil {
+		t.Fatalf("missing manifest: %v", err)
+	}
+
+	// The first file is rewritten with the same size and time, so that its
+	// series are not indexed if it is skipped as expected.
+	fi, err := os.Stat(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(first); err != nil {
+		t.Fatal(err)
+	} else if err := os.Remove(tsm1.StatsFilename(first)); err != nil {
+		t.Fatal(err)
+	}
+	tsmtest.WriteKeys(t, first, "cpu,host=z#!~#usage")
+	if err := os.Chtimes(first, fi.ModTime(), fi.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	tsmtest.WriteKeys(t, filepath.Join(dataDir, "000000002-000000001.tsm"), "mem,host=a#!~#used", "mem,host=b#!~#used")
+	if err := buildtsi.IndexShard(sfile, indexPath, dataDir, "", opts, zap.NewNop()); err != nil {
+		t.Fatal(err)
+	}
+
+	idx := tsi1.NewIndex(sfile, tsi1.NewConfig(), tsi1.WithPath(indexPath), tsi1.DisableMetrics())
+	if err := idx.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+	if n := idx.SeriesN(); n != 3 {
+		t.Fatalf("unexpected number of series: %d", n)
+	}
+	if ok, err := idx.MeasurementExists([]byte("mem")); err != nil {
+		t.Fatal(err)
+	} else if !ok {
+		t.Fatal("measurement of a new file not indexed")
+	}
+}
+
+func TestIndexShard_Verify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buildtsi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dataDir, indexPath := filepath.Join(dir, "data"), filepath.Join(dir, "index")
+	if err := os.Mkdir(dataDir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	sfile := tsdb.NewSeriesFile(filepath.Join(dir, "_series"))
+	sfile.Logger = zap.NewNop()
+	if err := sfile.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer sfile.Close()
+
+	tsmtest.WriteKeys(t, filepath.Join(dataDir, "000000001-000000001.tsm"), "cpu,host=a#!~#system", "cpu,host=a#!~#usage", "cpu,host=b#!~#usage")
+	tsmtest.WriteKeys(t, filepath.Join(dataDir, "000000002-000000001.tsm"), "cpu,host=a#!~#usage", "mem,host=a#!~#used")
+
+	var report bytes.Buffer
+	opts := buildtsi.Options{
+		MaxLogFileSize: tsi1.DefaultMaxIndexLogFileSize,
+		MaxCacheSize:   uint64(tsm1.DefaultCacheMaxMemorySize),
+		BatchSize:      1,
+		Concurrency:    2,
+		Verify:         true,
+		Report:         &report,
+	}
+	if err := buildtsi.IndexShard(sfile, indexPath, dataDir, "", opts, zap.NewNop()); err != nil {
+		t.Fatal(err)
+	}
+	out := report.String()
+	for _, step := range []string{"Index TSM files", "Index WAL segments", "Compact index", "Verify index", "Total"} {
+		if !strings.Contains(out, step) {
+			t.Errorf("missing step %q in report:\n%s", step, out)
+		}
+	}
+	if !strings.Contains(out, "Verified: 3 series in TSM files and WAL segments, 3 series in index, 0 missing.") {
+		t.Errorf("unexpected verification in report:\n%s", out)
+	}
+
+	// A file written since the index was built has series missing from the
+	// existing index, which is verified but not rebuilt.
+	tsmtest.WriteKeys(t, filepath.Join(dataDir, "000000003-000000001.tsm"), "disk,host=a#!~#free")
+	report.Reset()
+	err = buildtsi.IndexShard(sfile, indexPath, dataDir, "", opts, zap.NewNop())
+	if err == nil || !strings.Contains(err.Error(), "1 of 4 series are missing") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(report.String(), "Verified: 4 series in TSM files and WAL segments, 3 series in index, 1 missing.") {
+		t.Errorf("unexpected verification in report:\n%s", report.String())
+	}
+
+	// An incremental update indexes it.
+	opts.Incremental = true
+	if err := buildtsi.IndexShard(sfile, indexPath, dataDir, "", opts, zap.NewNop()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndexShard_VerifyResumed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buildtsi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dataDir, indexPath := filepath.Join(dir, "data"), filepath.Join(dir, "index")
+	if err := os.Mkdir(dataDir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	sfile := tsdb.NewSeriesFile(filepath.Join(dir, "_series"))
+	sfile.Logger = zap.NewNop()
+	if err := sfile.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer sfile.Close()
+
+	// The progress of a previous run lists a file which was not indexed, so
+	// that its series are missing from the resumed build.
+	tsmtest.WriteKeys(t, filepath.Join(dataDir, "000000001-000000001.tsm"), "cpu,host=a#!~#usage")
+	tsmtest.WriteKeys(t, filepath.Join(dataDir, "000000002-000000001.tsm"), "mem,host=a#!~#used")
+	tmpPath := filepath.Join(dataDir, ".index")
+	if err := os.Mkdir(tmpPath, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmpPath, "buildtsi.progress"), []byte("000000001-000000001.tsm\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	var report bytes.Buffer
+	opts := buildtsi.Options{
+		MaxLogFileSize: tsi1.DefaultMaxIndexLogFileSize,
+		MaxCacheSize:   uint64(tsm1.DefaultCacheMaxMemorySize),
+		BatchSize:      1,
+		Concurrency:    1,
+		Verify:         true,
+		Report:         &report,
+	}
+	err = buildtsi.IndexShard(sfile, indexPath, dataDir, "", opts, zap.NewNop())
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 series are missing") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(report.String(), "1 TSM file(s) indexed by a previous run were skipped.") {
+		t.Errorf("unexpected report:\n%s", report.String())
+	}
+
+	// The index failing verification is removed, so that the next run
+	// builds it from scratch.
+	for _, path := range []string{tmpPath, indexPath} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("unexpected index at %s: %v", path, err)
+		}
+	}
+	if err := buildtsi.IndexShard(sfile, indexPath, dataDir, "", opts, zap.NewNop()); err != nil {
+		t.Fatal(err)
+	}
+}
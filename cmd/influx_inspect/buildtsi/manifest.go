@@ -0,0 +1,92 @@
+package buildtsi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// manifestFilename is the name of the file, in the index directory, listing
+// the TSM files and WAL segments the index was built from.
+const manifestFilename = "buildtsi.manifest"
+
+// manifest lists the files an index was built from, by name.
+type manifest struct {
+	TSM map[string]manifestFile `json:"tsm"`
+	WAL map[string]manifestFile `json:"wal"`
+}
+
+// manifestFile identifies the version of a file indexed.
+type manifestFile struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// newManifest returns the manifest of the TSM files and WAL segments at the
+// given paths.
+func newManifest(tsmPaths, walPaths []string) (*manifest, error) {
+	m := &manifest{
+		TSM: make(map[string]manifestFile, len(tsmPaths)),
+		WAL: make(map[string]manifestFile, len(walPaths)),
+	}
+	for _, files := range []struct {
+		paths []string
+		dst   map[string]manifestFile
+	}{{tsmPaths, m.TSM}, {walPaths, m.WAL}} {
+		for _, path := range files.paths {
+			fi, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+			files.dst[filepath.Base(path)] = manifestFile{Size: fi.Size(), ModTime: fi.ModTime().UTC()}
+		}
+	}
+	return m, nil
+}
+
+// changedSince returns the TSM files and WAL segments of the paths which are
+// missing from the previous manifest, or were modified since.
+func (m *manifest) changedSince(prev *manifest, tsmPaths, walPaths []string) (tsm, wal []string) {
+	changed := func(paths []string, cur, prev map[string]manifestFile) []string {
+		var out []string
+		for _, path := range paths {
+			name := filepath.Base(path)
+			if p, ok := prev[name]; !ok || p.Size != cur[name].Size || !p.ModTime.Equal(cur[name].ModTime) {
+				out = append(out, path)
+			}
+		}
+		return out
+	}
+	return changed(tsmPaths, m.TSM, prev.TSM), changed(walPaths, m.WAL, prev.WAL)
+}
+
+// readManifest reads the manifest at path, or returns nil if there is none.
+func readManifest(path string) (*manifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// write writes the manifest to path atomically.
+func (m *manifest) write(path string) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,187 @@
+package buildtsi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsi1"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+	"go.uber.org/zap"
+)
+
+// report records the duration of the steps of a build of an index, and the
+// series counted by its verification.
+type report struct {
+	start time.Time
+	steps []reportStep
+
+	verified        bool
+	dataSeries      uint64 // series of the TSM files and WAL segments
+	indexSeries     uint64 // series of the index
+	missingSeries   uint64 // series of the data missing from the index
+	skippedTSMFiles int    // TSM files indexed by a previous run
+}
+
+type reportStep struct {
+	name    string
+	files   int
+	elapsed time.Duration
+}
+
+func newReport() *report {
+	return &report{start: time.Now()}
+}
+
+// time runs fn, recording its duration as the step name over files files.
+func (r *report) time(name string, files int, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	r.steps = append(r.steps, reportStep{name: name, files: files, elapsed: time.Since(start)})
+	return err
+}
+
+// write writes the report to w, if not nil.
+func (r *report) write(w io.Writer) error {
+	if w == nil {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "Step\tFiles\tDuration")
+	for _, s := range r.steps {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", s.name, s.files, s.elapsed.Round(time.Millisecond))
+	}
+	fmt.Fprintf(tw, "Total\t\t%s\n", time.Since(r.start).Round(time.Millisecond))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if r.skippedTSMFiles > 0 {
+		fmt.Fprintf(w, "\n%d TSM file(s) indexed by a previous run were skipped.\n", r.skippedTSMFiles)
+	}
+	if r.verified {
+		fmt.Fprintf(w, "\nVerified: %d series in TSM files and WAL segments, %d series in index, %d missing.\n",
+			r.dataSeries, r.indexSeries, r.missingSeries)
+	}
+	return nil
+}
+
+// verifyIndex checks that the index at indexPath holds every series of the
+// TSM files and WAL segments at the given paths and, unless extra is set, no
+// other series. Extra series are expected in an index updated incrementally,
+// which keeps the series of the files removed since it was built.
+func verifyIndex(sfile *tsdb.SeriesFile, indexPath string, tsmPaths, walPaths []string, maxCacheSize uint64, extra bool, rep *report, log *zap.Logger) error {
+	return rep.time("Verify index", len(tsmPaths)+len(walPaths), func() error {
+		return checkIndex(sfile, indexPath, tsmPaths, walPaths, maxCacheSize, extra, rep, log)
+	})
+}
+
+func checkIndex(sfile *tsdb.SeriesFile, indexPath string, tsmPaths, walPaths []string, maxCacheSize uint64, extra bool, rep *report, log *zap.Logger) error {
+	log.Info("Verifying index", zap.String("path", indexPath))
+
+	index := tsi1.NewIndex(sfile, tsi1.NewConfig(),
+		tsi1.WithPath(indexPath),
+		tsi1.DisableMetrics(),
+	)
+	index.WithLogger(log)
+	if err := index.Open(context.Background()); err != nil {
+		return err
+	}
+	defer index.Close()
+
+	indexed := index.SeriesIDSet()
+	want, absent := tsdb.NewSeriesIDSet(), tsdb.NewSeriesIDSet()
+	// Series missing from the series file have no ID, so they are tracked by
+	// key.
+	unknown := make(map[string]struct{})
+
+	var buf []byte
+	check := func(key []byte) {
+		seriesKey, _ := tsm1.SeriesAndFieldFromCompositeKey(key)
+		name, tags := models.ParseKeyBytes(seriesKey)
+		id := sfile.SeriesID(name, tags, buf)
+		if id.IsZero() {
+			unknown[string(seriesKey)] = struct{}{}
+			return
+		}
+		want.Add(id)
+		if !indexed.Contains(id) {
+			absent.Add(id)
+		}
+	}
+
+	for _, path := range tsmPaths {
+		if err := verifyTSMFile(path, check, log); err != nil {
+			return err
+		}
+	}
+
+	if len(walPaths) > 0 {
+		cache := tsm1.NewCache(maxCacheSize)
+		loader := tsm1.NewCacheLoader(walPaths)
+		loader.WithLogger(log)
+		if err := loader.Load(cache); err != nil {
+			return err
+		}
+		for _, key := range cache.Keys() {
+			check(key)
+		}
+	}
+
+	rep.verified = true
+	rep.dataSeries = want.Cardinality() + uint64(len(unknown))
+	rep.indexSeries = indexed.Cardinality()
+	rep.missingSeries = absent.Cardinality() + uint64(len(unknown))
+
+	if rep.missingSeries > 0 {
+		return fmt.Errorf("index verification failed: %d of %d series are missing from the index", rep.missingSeries, rep.dataSeries)
+	}
+	if !extra && rep.indexSeries != rep.dataSeries {
+		return fmt.Errorf("index verification failed: index has %d series, expected %d", rep.indexSeries, rep.dataSeries)
+	}
+	log.Info("Verified index", zap.Uint64("series", rep.dataSeries), zap.Uint64("index_series", rep.indexSeries))
+	return nil
+}
+
+// verifyTSMFile calls fn with every key of the TSM file at path. Unreadable
+// files are skipped, as they are when indexed.
+func verifyTSMFile(path string, fn func(key []byte), log *zap.Logger) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		log.Warn("Unable to read, skipping", zap.String("path", path), zap.Error(err))
+		return nil
+	}
+	defer r.Close()
+
+	iter := r.Iterator(nil)
+	for iter.Next() {
+		fn(iter.Key())
+	}
+	return iter.Err()
+}
+
+// verifyExistingIndex checks that the existing index at indexPath holds every
+// series of the TSM files of dataDir and of the WAL segments of walDir.
+func verifyExistingIndex(sfile *tsdb.SeriesFile, indexPath, dataDir, walDir string, maxCacheSize uint64, rep *report, log *zap.Logger) error {
+	tsmPaths, err := collectTSMFiles(dataDir)
+	if err != nil {
+		return err
+	}
+	walPaths, err := collectWALFiles(walDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return verifyIndex(sfile, indexPath, tsmPaths, walPaths, maxCacheSize, true, rep, log)
+}
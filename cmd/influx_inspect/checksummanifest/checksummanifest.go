@@ -0,0 +1,302 @@
+// Package checksummanifest generates manifests of the files of a data
+// directory, and verifies directories against them, such as to validate a
+// backup or to detect the silent corruption of files in cold storage.
+package checksummanifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// Version is the version of the manifests written by Command.
+const Version = 1
+
+// Manifest describes the files of a directory.
+type Manifest struct {
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	Files   []File    `json:"files"`
+}
+
+// File describes a file of a directory.
+type File struct {
+	// Path is the slash separated path of the file, relative to the directory.
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+
+	// TSM summarizes the index of TSM files.
+	TSM *TSMSummary `json:"tsm,omitempty"`
+}
+
+// TSMSummary summarizes the index of a TSM file.
+type TSMSummary struct {
+	Keys      int    `json:"keys"`
+	MinTime   int64  `json:"minTime"`
+	MaxTime   int64  `json:"maxTime"`
+	IndexSize uint32 `json:"indexSize"`
+}
+
+// Command generates the manifest of a directory, or verifies a directory
+// against a manifest.
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Dir is the directory whose files, searched recursively, are described.
+	Dir string
+
+	// Manifest is the path of the manifest. It is skipped when it is in Dir.
+	Manifest string
+
+	// Verify checks the files of Dir against Manifest instead of writing it.
+	Verify bool
+}
+
+// NewCommand returns a new instance of Command writing to the standard output and error.
+func NewCommand() *Command {
+	return &Command{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Run generates or verifies the manifest.
+func (cmd *Command) Run() error {
+	if cmd.Dir == "" {
+		return errors.New("directory required")
+	}
+	if cmd.Manifest == "" {
+		return errors.New("manifest path required")
+	}
+	if cmd.Verify {
+		return cmd.verify()
+	}
+	return cmd.generate()
+}
+
+func (cmd *Command) generate() error {
+	m, err := cmd.manifest()
+	if err != nil {
+		return err
+	}
+
+	tmp := cmd.Manifest + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, cmd.Manifest); err != nil {
+		return err
+	}
+
+	var size int64
+	for _, file := range m.Files {
+		size += file.Size
+	}
+	fmt.Fprintf(cmd.Stdout, "wrote the manifest of %d file(s), %d byte(s) to %s\n", len(m.Files), size, cmd.Manifest)
+	return nil
+}
+
+func (cmd *Command) verify() error {
+	want, err := ReadManifest(cmd.Manifest)
+	if err != nil {
+		return err
+	}
+	got, err := cmd.manifest()
+	if err != nil {
+		return err
+	}
+
+	diffs := Compare(want, got)
+	for _, d := range diffs {
+		fmt.Fprintln(cmd.Stdout, d)
+	}
+	if len(diffs) > 0 {
+		return fmt.Errorf("%d difference(s) between %s and the manifest %s", len(diffs), cmd.Dir, cmd.Manifest)
+	}
+	fmt.Fprintf(cmd.Stdout, "verified %d file(s) of %s against the manifest %s\n", len(want.Files), cmd.Dir, cmd.Manifest)
+	return nil
+}
+
+// manifest describes the files of the directory.
+func (cmd *Command) manifest() (*Manifest, error) {
+	skip, err := filepath.Abs(cmd.Manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{Version: Version, Created: time.Now().UTC()}
+	err = filepath.Walk(cmd.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		if abs, err := filepath.Abs(path); err != nil {
+			return err
+		} else if abs == skip || abs == skip+".tmp" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(cmd.Dir, path)
+		if err != nil {
+			return err
+		}
+		file, err := Describe(path)
+		if err != nil {
+			// A corrupt TSM file is reported as a difference when verifying,
+			// since its checksum was computed.
+			if !cmd.Verify || file.SHA256 == "" {
+				return fmt.Errorf("%s: %v", path, err)
+			}
+			fmt.Fprintf(cmd.Stderr, "%s: %v\n", path, err)
+		}
+		file.Path = filepath.ToSlash(rel)
+		m.Files = append(m.Files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(m.Files, func(i, j int) bool { return m.Files[i].Path < m.Files[j].Path })
+	return m, nil
+}
+
+// Describe returns the size and checksum of the file at path, and the
+// summary of its index if it is a TSM file. The path of the file is not set.
+// If the index cannot be read, the size and checksum are returned with the
+// error.
+func Describe(path string) (File, error) {
+	var file File
+
+	f, err := os.Open(path)
+	if err != nil {
+		return file, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if file.Size, err = io.Copy(h, f); err != nil {
+		return file, err
+	}
+	file.SHA256 = hex.EncodeToString(h.Sum(nil))
+
+	if filepath.Ext(path) == "."+tsm1.TSMFileExtension {
+		if file.TSM, err = summarize(path); err != nil {
+			return file, fmt.Errorf("unable to read the TSM index: %v", err)
+		}
+	}
+	return file, nil
+}
+
+// summarize returns the summary of the index of the TSM file at path.
+func summarize(path string) (*TSMSummary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	defer r.Close()
+
+	min, max := r.TimeRange()
+	return &TSMSummary{
+		Keys:      r.KeyCount(),
+		MinTime:   min,
+		MaxTime:   max,
+		IndexSize: r.IndexSize(),
+	}, nil
+}
+
+// ReadManifest reads the manifest at path.
+func ReadManifest(path string) (*Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var m Manifest
+	if err := json.NewDecoder(f).Decode(&m); err != nil {
+		return nil, fmt.Errorf("unable to decode the manifest %s: %v", path, err)
+	}
+	if m.Version != Version {
+		return nil, fmt.Errorf("unsupported manifest version %d", m.Version)
+	}
+	return &m, nil
+}
+
+// Compare returns the differences between the files of want and got,
+// ordered by path.
+func Compare(want, got *Manifest) []string {
+	files := make(map[string]File, len(got.Files))
+	for _, f := range got.Files {
+		files[f.Path] = f
+	}
+
+	var diffs []string
+	for _, w := range want.Files {
+		g, ok := files[w.Path]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("%s: missing", w.Path))
+			continue
+		}
+		delete(files, w.Path)
+
+		var problems []string
+		if g.Size != w.Size {
+			problems = append(problems, fmt.Sprintf("size %d, expected %d", g.Size, w.Size))
+		}
+		if g.SHA256 != w.SHA256 {
+			problems = append(problems, fmt.Sprintf("sha256 %s, expected %s", g.SHA256, w.SHA256))
+		}
+		switch {
+		case w.TSM != nil && g.TSM != nil && *w.TSM != *g.TSM:
+			problems = append(problems, fmt.Sprintf("TSM index %+v, expected %+v", *g.TSM, *w.TSM))
+		case (w.TSM == nil) != (g.TSM == nil):
+			problems = append(problems, "TSM index summary mismatch")
+		}
+		if len(problems) > 0 {
+			diffs = append(diffs, fmt.Sprintf("%s: %s", w.Path, strings.Join(problems, ", ")))
+		}
+	}
+	for _, g := range got.Files {
+		if _, ok := files[g.Path]; ok {
+			diffs = append(diffs, fmt.Sprintf("%s: not in the manifest", g.Path))
+		}
+	}
+	sort.Strings(diffs)
+	return diffs
+}
@@ -0,0 +1,102 @@
+package checksummanifest_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/checksummanifest"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile/tsmtest"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+func TestCommand_Run(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checksummanifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	data := filepath.Join(dir, "data", "1")
+	if err := os.MkdirAll(data, 0777); err != nil {
+		t.Fatal(err)
+	}
+	tsm := filepath.Join(data, "000000001-000000001.tsm")
+	tsmtest.WriteFile(t, tsm, map[string]tsm1.Values{
+		"cpu#!~#usage": {tsm1.NewValue(10, 1.0), tsm1.NewValue(20, 2.0)},
+		"mem#!~#free":  {tsm1.NewValue(15, int64(1))},
+	})
+	other := filepath.Join(dir, "other")
+	if err := ioutil.WriteFile(other, []byte("other"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	cmd := &checksummanifest.Command{
+		Stdout:   &stdout,
+		Stderr:   ioutil.Discard,
+		Dir:      dir,
+		Manifest: filepath.Join(dir, "manifest.json"),
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := checksummanifest.ReadManifest(cmd.Manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, f := range m.Files {
+		paths = append(paths, f.Path)
+	}
+	if got, want := strings.Join(paths, ","), "data/1/000000001-000000001.tsm,data/1/000000001-000000001.tss,other"; got != want {
+		t.Fatalf("unexpected files: got %s, want %s", got, want)
+	}
+	if s := m.Files[0].TSM; s == nil || s.Keys != 2 || s.MinTime != 10 || s.MaxTime != 20 || s.IndexSize == 0 {
+		t.Fatalf("unexpected TSM summary: %+v", s)
+	}
+	if m.Files[2].TSM != nil || m.Files[2].Size != 5 {
+		t.Fatalf("unexpected file: %+v", m.Files[2])
+	}
+
+	// The unmodified directory verifies.
+	cmd.Verify = true
+	stdout.Reset()
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, stdout.String())
+	}
+
+	// Corrupt, remove and add files.
+	b, err := ioutil.ReadFile(tsm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[12] ^= 0xff
+	if err := ioutil.WriteFile(tsm, b, 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(other); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "extra"), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout.Reset()
+	if err := cmd.Run(); err == nil || !strings.Contains(err.Error(), "3 difference(s)") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, s := range []string{
+		"data/1/000000001-000000001.tsm: sha256 ",
+		"extra: not in the manifest",
+		"other: missing",
+	} {
+		if !strings.Contains(stdout.String(), s) {
+			t.Fatalf("output missing %q: %s", s, stdout.String())
+		}
+	}
+}
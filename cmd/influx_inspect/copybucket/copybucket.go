@@ -0,0 +1,310 @@
+// Package copybucket copies or moves the data of a bucket to another bucket
+// by rewriting the keys of its TSM files, without running the storage engine.
+package copybucket
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// Command copies the data of a bucket to another bucket.
+//
+// The series of the source bucket are written to new TSM files of the data
+// directory, under keys of the target bucket. Blocks are copied verbatim,
+// unless they have tombstoned values, which are dropped. When Move is set,
+// the series of the source bucket are then tombstoned in the files they were
+// read from.
+//
+// The target bucket must exist, and the index must be updated with the new
+// series once the files are written, such as with the build-tsi command.
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// DataDir is the directory of the TSM files of the engine.
+	DataDir string
+
+	SourceOrgID, SourceBucketID influxdb.ID
+	TargetOrgID, TargetBucketID influxdb.ID
+
+	// Move tombstones the series of the source bucket once they are copied.
+	Move bool
+}
+
+// NewCommand returns a new instance of Command writing to the standard output and error.
+func NewCommand() *Command {
+	return &Command{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Stats summarizes the copy of the series of a TSM file.
+type Stats struct {
+	Keys   int
+	Blocks int
+
+	// Reencoded is the number of blocks decoded to drop their tombstoned
+	// values, the other blocks being copied verbatim.
+	Reencoded int
+}
+
+// Run copies the data of the source bucket to the target bucket.
+func (cmd *Command) Run() error {
+	if cmd.DataDir == "" {
+		return errors.New("data directory required")
+	}
+	if !cmd.SourceOrgID.Valid() || !cmd.SourceBucketID.Valid() {
+		return errors.New("source organization and bucket required")
+	}
+	if !cmd.TargetOrgID.Valid() || !cmd.TargetBucketID.Valid() {
+		return errors.New("target organization and bucket required")
+	}
+	if cmd.SourceOrgID == cmd.TargetOrgID && cmd.SourceBucketID == cmd.TargetBucketID {
+		return errors.New("source and target buckets must differ")
+	}
+
+	files, err := filepath.Glob(filepath.Join(cmd.DataDir, "*."+tsm1.TSMFileExtension))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	generation, err := nextGeneration(files)
+	if err != nil {
+		return err
+	}
+
+	src := bucketPrefix(cmd.SourceOrgID, cmd.SourceBucketID)
+	dst := bucketPrefix(cmd.TargetOrgID, cmd.TargetBucketID)
+
+	// The new files are written with a temporary extension, and only
+	// renamed once every file is copied.
+	var (
+		total    Stats
+		copied   []string // files of the source bucket
+		newFiles []string
+	)
+	defer func() {
+		if err != nil {
+			for _, path := range newFiles {
+				os.Remove(path)
+				os.Remove(tsm1.StatsFilename(path))
+			}
+		}
+	}()
+	for _, path := range files {
+		out := filepath.Join(cmd.DataDir, tsm1.DefaultFormatFileName(generation, 1)+"."+tsm1.TSMFileExtension+"."+tsm1.TmpTSMFileExtension)
+		var s Stats
+		if s, err = CopyFile(path, out, src, dst); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if s.Keys == 0 {
+			continue
+		}
+		fmt.Fprintf(cmd.Stdout, "%s: copied %d key(s), %d block(s), %d block(s) re-encoded\n", path, s.Keys, s.Blocks, s.Reencoded)
+		copied = append(copied, path)
+		newFiles = append(newFiles, out)
+		generation++
+		total.Keys += s.Keys
+		total.Blocks += s.Blocks
+		total.Reencoded += s.Reencoded
+	}
+	if len(newFiles) == 0 {
+		return fmt.Errorf("no data of bucket %s of organization %s in %s", cmd.SourceBucketID, cmd.SourceOrgID, cmd.DataDir)
+	}
+
+	for _, path := range newFiles {
+		tsm := path[:len(path)-len("."+tsm1.TmpTSMFileExtension)]
+		if err = os.Rename(path, tsm); err != nil {
+			return err
+		}
+	}
+	newFiles = nil
+
+	fmt.Fprintf(cmd.Stdout, "copied %d key(s), %d block(s) of bucket %s to bucket %s in %d new TSM file(s)\n",
+		total.Keys, total.Blocks, cmd.SourceBucketID, cmd.TargetBucketID, len(copied))
+
+	if cmd.Move {
+		for _, path := range copied {
+			if err := deletePrefix(path, src); err != nil {
+				return fmt.Errorf("%s: unable to tombstone the source bucket: %v", path, err)
+			}
+		}
+		fmt.Fprintf(cmd.Stdout, "tombstoned the series of bucket %s in %d TSM file(s)\n", cmd.SourceBucketID, len(copied))
+	}
+
+	fmt.Fprintln(cmd.Stdout, "the index must be updated with the series of the new files, such as with build-tsi")
+	return nil
+}
+
+// bucketPrefix returns the prefix of the TSM keys of a bucket.
+func bucketPrefix(orgID, bucketID influxdb.ID) []byte {
+	name := tsdb.EncodeName(orgID, bucketID)
+	return models.EscapeMeasurement(name[:])
+}
+
+// nextGeneration returns the generation following those of files.
+func nextGeneration(files []string) (int, error) {
+	var max int
+	for _, path := range files {
+		gen, _, err := tsm1.DefaultParseFileName(path)
+		if err != nil {
+			return 0, err
+		}
+		if gen > max {
+			max = gen
+		}
+	}
+	return max + 1, nil
+}
+
+// CopyFile writes the blocks of the keys of the TSM file at path starting
+// with src to a new TSM file at out, with src replaced by dst. The new file
+// is not written if no key starts with src.
+func CopyFile(path, out string, src, dst []byte) (stats Stats, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return stats, err
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		f.Close()
+		return stats, fmt.Errorf("unable to read: %v", err)
+	}
+	defer r.Close()
+
+	var w tsm1.TSMWriter
+	defer func() {
+		if w == nil {
+			return
+		}
+		if err == nil {
+			if err = w.WriteIndex(); err == nil {
+				err = w.Close()
+				return
+			}
+		}
+		w.Remove()
+	}()
+
+	var (
+		entries []tsm1.IndexEntry
+		trbuf   []tsm1.TimeRange
+		values  []tsm1.Value
+		buf     []byte
+	)
+	iter := r.Iterator(src)
+	for iter.Next() {
+		key := iter.Key()
+		if !bytes.HasPrefix(key, src) {
+			break
+		}
+		if entries, err = r.ReadEntries(key, entries[:0]); err != nil {
+			return stats, err
+		}
+		trbuf = r.TombstoneRange(key, trbuf[:0])
+		dstKey := append(append(make([]byte, 0, len(dst)+len(key)-len(src)), dst...), key[len(src):]...)
+
+		var written bool
+		for i := range entries {
+			e := &entries[i]
+			if _, buf, err = r.ReadBytes(e, buf); err != nil {
+				return stats, err
+			}
+			if w == nil {
+				if w, err = createWriter(out); err != nil {
+					return stats, err
+				}
+			}
+
+			if !overlaps(trbuf, e.MinTime, e.MaxTime) {
+				if err = w.WriteBlock(dstKey, e.MinTime, e.MaxTime, buf); err != nil {
+					return stats, err
+				}
+				stats.Blocks++
+				written = true
+				continue
+			}
+
+			if values, err = tsm1.DecodeBlock(buf, values[:0]); err != nil {
+				return stats, fmt.Errorf("unable to decode block of key %q: %v", key, err)
+			}
+			vs := tsm1.Values(values)
+			for _, tr := range trbuf {
+				vs = vs.Exclude(tr.Min, tr.Max)
+			}
+			if len(vs) == 0 {
+				continue
+			}
+			if err = w.Write(dstKey, vs); err != nil {
+				return stats, err
+			}
+			stats.Blocks++
+			stats.Reencoded++
+			written = true
+		}
+		if written {
+			stats.Keys++
+		}
+	}
+	if err = iter.Err(); err != nil {
+		return stats, err
+	}
+	if w != nil && stats.Keys == 0 {
+		// Every value was tombstoned.
+		w.Remove()
+		w = nil
+	}
+	return stats, nil
+}
+
+func createWriter(path string) (tsm1.TSMWriter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if err != nil {
+		return nil, err
+	}
+	w, err := tsm1.NewTSMWriter(f)
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	return w, nil
+}
+
+// deletePrefix tombstones the keys of the TSM file at path starting with
+// prefix.
+func deletePrefix(path string, prefix []byte) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0666)
+	if err != nil {
+		return err
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	defer r.Close()
+	return r.DeletePrefix(prefix, math.MinInt64, math.MaxInt64, nil, nil)
+}
+
+// overlaps returns true if any of the time ranges overlaps [min, max].
+func overlaps(trs []tsm1.TimeRange, min, max int64) bool {
+	for _, tr := range trs {
+		if tr.Min <= max && tr.Max >= min {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package copybucket_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/copybucket"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile/tsmtest"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+func TestCommand_Run(t *testing.T) {
+	for _, move := range []bool{false, true} {
+		dir, err := ioutil.TempDir("", "copybucket")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		src, dst, other := seriesKey(1, 2), seriesKey(1, 3), seriesKey(1, 4)
+		path := filepath.Join(dir, "000000000000002-000000003.tsm")
+		tsmtest.WriteFile(t, path, map[string]tsm1.Values{
+			src + "#!~#usage":  {tsm1.NewValue(10, 1.0), tsm1.NewValue(20, 2.0)},
+			src + "#!~#free":   {tsm1.NewValue(10, int64(1))},
+			other + "#!~#used": {tsm1.NewValue(10, int64(5))},
+		})
+		deleteRange(t, path, src+"#!~#usage", 10, 10)
+
+		var stdout bytes.Buffer
+		cmd := &copybucket.Command{
+			Stdout:         &stdout,
+			Stderr:         ioutil.Discard,
+			DataDir:        dir,
+			SourceOrgID:    1,
+			SourceBucketID: 2,
+			TargetOrgID:    1,
+			TargetBucketID: 3,
+			Move:           move,
+		}
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(stdout.String(), "copied 2 key(s), 2 block(s) of bucket 0000000000000002 to bucket 0000000000000003 in 1 new TSM file(s)") {
+			t.Fatalf("unexpected output: %s", stdout.String())
+		}
+
+		got := tsmtest.ReadValues(t, filepath.Join(dir, "000000000000003-000000001.tsm"))
+		want := map[string][]interface{}{
+			dst + "#!~#free":  {int64(1)},
+			dst + "#!~#usage": {2.0},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("unexpected values of the target bucket: got %v, want %v", got, want)
+		}
+
+		want = map[string][]interface{}{
+			src + "#!~#free":   {int64(1)},
+			src + "#!~#usage":  {2.0},
+			other + "#!~#used": {int64(5)},
+		}
+		if move {
+			want = map[string][]interface{}{other + "#!~#used": {int64(5)}}
+		}
+		if got := tsmtest.ReadValues(t, path); !reflect.DeepEqual(got, want) {
+			t.Fatalf("unexpected values of the source file: got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestCommand_Run_NoData(t *testing.T) {
+	dir, err := ioutil.TempDir("", "copybucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tsmtest.WriteFile(t, filepath.Join(dir, "000000000000001-000000001.tsm"), map[string]tsm1.Values{
+		seriesKey(1, 4) + "#!~#used": {tsm1.NewValue(10, int64(5))},
+	})
+
+	cmd := &copybucket.Command{
+		Stdout:         ioutil.Discard,
+		Stderr:         ioutil.Discard,
+		DataDir:        dir,
+		SourceOrgID:    1,
+		SourceBucketID: 2,
+		TargetOrgID:    1,
+		TargetBucketID: 3,
+	}
+	if err := cmd.Run(); err == nil || !strings.Contains(err.Error(), "no data of bucket") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.tsm*"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(files) != 1 {
+		t.Fatalf("unexpected TSM files: %v", files)
+	}
+}
+
+// seriesKey returns the key of a cpu series of the bucket.
+func seriesKey(orgID, bucketID influxdb.ID) string {
+	name := tsdb.EncodeName(orgID, bucketID)
+	return string(models.EscapeMeasurement(name[:])) + ",_m=cpu"
+}
+
+// deleteRange records a tombstone for the values of key in [min, max].
+func deleteRange(t *testing.T, path, key string, min, max int64) {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := r.DeleteRange([][]byte{[]byte(key)}, min, max); err != nil {
+		t.Fatal(err)
+	}
+}
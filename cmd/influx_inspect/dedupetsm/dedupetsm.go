@@ -0,0 +1,453 @@
+// Package dedupetsm collapses the duplicate points of the TSM files of a
+// shard without running the storage engine.
+//
+// The storage engine must not be running while files are deduplicated.
+package dedupetsm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// Policies choosing the value kept among the duplicates of a point.
+const (
+	// PolicyNewest keeps the value of the latest file, as the storage
+	// engine does when reading and compacting overlapping blocks.
+	PolicyNewest = "newest"
+	// PolicyOldest keeps the value of the earliest file, such as the value
+	// first written before a re-import.
+	PolicyOldest = "oldest"
+)
+
+// maxFileSize is the size of the TSM files after which a new file is
+// started, as done by the compactor of the engine.
+const maxFileSize = 2048 * 1024 * 1024
+
+// Command collapses the duplicate points of the TSM files of each directory.
+//
+// Points are duplicates when the blocks of a key, within a file or across
+// the files of a directory, hold several values at the same timestamp. Only
+// the keys whose blocks overlap are read to find them. The files of a
+// directory with duplicates are rewritten into new files of a later
+// generation, which replace them along with their tombstone and stats
+// files; tombstoned
+// values are dropped.
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Paths lists the directories whose TSM files are deduplicated, such as
+	// the data directory of the engine. Directories are searched
+	// recursively, the TSM files of each directory found being
+	// deduplicated together.
+	Paths []string
+
+	// Policy chooses the value kept among duplicates, PolicyNewest by
+	// default.
+	Policy string
+
+	// DryRun reports the duplicates without rewriting the files.
+	DryRun bool
+
+	// Verbose reports the number of duplicates of each key.
+	Verbose bool
+}
+
+// NewCommand returns a new instance of Command writing to the standard output and error.
+func NewCommand() *Command {
+	return &Command{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Policy: PolicyNewest,
+	}
+}
+
+// Stats summarizes the deduplication of the TSM files of a directory.
+type Stats struct {
+	Files int
+	// Keys is the number of keys with duplicates.
+	Keys int
+	// Duplicates is the number of points collapsed.
+	Duplicates int
+	// NewFiles is the number of files written, 0 if the files were not
+	// rewritten.
+	NewFiles int
+}
+
+// Run deduplicates the TSM files of each directory of Paths.
+func (cmd *Command) Run() error {
+	if len(cmd.Paths) == 0 {
+		return errors.New("path required")
+	}
+	if cmd.Policy == "" {
+		cmd.Policy = PolicyNewest
+	}
+	if cmd.Policy != PolicyNewest && cmd.Policy != PolicyOldest {
+		return fmt.Errorf("unsupported policy %q", cmd.Policy)
+	}
+
+	dirs, err := cmd.findDirs()
+	if err != nil {
+		return err
+	}
+
+	var total Stats
+	for _, dir := range dirs {
+		s, err := cmd.DedupeDir(dir)
+		if err != nil {
+			return fmt.Errorf("%s: %v", dir, err)
+		}
+		cmd.printStats(dir, s)
+		total.add(s)
+	}
+
+	if cmd.DryRun {
+		fmt.Fprintf(cmd.Stdout, "would collapse %d duplicate point(s) of %d key(s) in %d TSM file(s) of %d directory(s)\n",
+			total.Duplicates, total.Keys, total.Files, len(dirs))
+		return nil
+	}
+	fmt.Fprintf(cmd.Stdout, "collapsed %d duplicate point(s) of %d key(s) in %d TSM file(s) of %d directory(s)\n",
+		total.Duplicates, total.Keys, total.Files, len(dirs))
+	return nil
+}
+
+// DedupeDir collapses the duplicate points of the TSM files of dir.
+func (cmd *Command) DedupeDir(dir string) (Stats, error) {
+	var s Stats
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*."+tsm1.TSMFileExtension))
+	if err != nil {
+		return s, err
+	}
+	// The files are sorted by generation, from the oldest to the newest.
+	sort.Strings(paths)
+	s.Files = len(paths)
+
+	readers := make([]*tsm1.TSMReader, 0, len(paths))
+	defer func() {
+		for _, r := range readers {
+			r.Close()
+		}
+	}()
+	maxGeneration := 0
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return s, err
+		}
+		r, err := tsm1.NewTSMReader(f)
+		if err != nil {
+			f.Close()
+			return s, fmt.Errorf("unable to read %s: %v", path, err)
+		}
+		readers = append(readers, r)
+
+		gen, _, err := tsm1.DefaultParseFileName(path)
+		if err != nil {
+			return s, err
+		}
+		if gen > maxGeneration {
+			maxGeneration = gen
+		}
+	}
+
+	d := &deduper{cmd: cmd, readers: readers}
+	keys, err := d.sortedKeys()
+	if err != nil {
+		return s, err
+	}
+	for _, key := range keys {
+		if ok, err := d.overlaps(key); err != nil {
+			return s, fmt.Errorf("unable to read key %q: %v", key, err)
+		} else if !ok {
+			continue
+		}
+		_, n, err := d.read(key)
+		if err != nil {
+			return s, fmt.Errorf("unable to read key %q: %v", key, err)
+		}
+		if n == 0 {
+			continue
+		}
+		s.Keys++
+		s.Duplicates += n
+		if cmd.Verbose {
+			fmt.Fprintf(cmd.Stdout, "%s: %q: %d duplicate point(s)\n", dir, key, n)
+		}
+	}
+	if s.Duplicates == 0 || cmd.DryRun {
+		return s, nil
+	}
+
+	newPaths, err := d.rewrite(keys, dir, maxGeneration+1)
+	if err != nil {
+		return s, err
+	}
+	s.NewFiles = len(newPaths)
+
+	// The new files are renamed before the files they replace are removed:
+	// should the replacement be interrupted, the new files take precedence
+	// over the remaining ones, being of a later generation.
+	var removed []string
+	for _, r := range readers {
+		removed = append(removed, r.Path(), tsm1.StatsFilename(r.Path()))
+		for _, ts := range r.TombstoneFiles() {
+			removed = append(removed, ts.Path)
+		}
+		if err := r.Close(); err != nil {
+			return s, err
+		}
+	}
+	readers = nil
+	for _, path := range newPaths {
+		if err := os.Rename(tmpPath(path), path); err != nil {
+			return s, err
+		}
+	}
+	for _, path := range removed {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+func (cmd *Command) printStats(dir string, s Stats) {
+	switch {
+	case cmd.DryRun:
+		fmt.Fprintf(cmd.Stdout, "%s: would collapse %d duplicate point(s) of %d key(s) in %d file(s)\n",
+			dir, s.Duplicates, s.Keys, s.Files)
+	case s.NewFiles == 0:
+		fmt.Fprintf(cmd.Stdout, "%s: no duplicate point in %d file(s)\n", dir, s.Files)
+	default:
+		fmt.Fprintf(cmd.Stdout, "%s: collapsed %d duplicate point(s) of %d key(s), rewrote %d file(s) into %d file(s)\n",
+			dir, s.Duplicates, s.Keys, s.Files, s.NewFiles)
+	}
+}
+
+func (s *Stats) add(o Stats) {
+	s.Files += o.Files
+	s.Keys += o.Keys
+	s.Duplicates += o.Duplicates
+	s.NewFiles += o.NewFiles
+}
+
+// findDirs returns the directories of Paths holding TSM files, searching
+// them recursively.
+func (cmd *Command) findDirs() ([]string, error) {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, path := range cmd.Paths {
+		err := filepath.Walk(path, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !fi.IsDir() && filepath.Ext(path) == "."+tsm1.TSMFileExtension {
+				if dir := filepath.Dir(path); !seen[dir] {
+					seen[dir] = true
+					dirs = append(dirs, dir)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error processing path %q: %v", path, err)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+type deduper struct {
+	cmd     *Command
+	readers []*tsm1.TSMReader
+}
+
+// sortedKeys returns the sorted keys of the readers, without duplicates.
+func (d *deduper) sortedKeys() ([][]byte, error) {
+	var (
+		seen = make(map[string]bool)
+		keys [][]byte
+	)
+	for _, r := range d.readers {
+		iter := r.Iterator(nil)
+		for iter.Next() {
+			if key := iter.Key(); !seen[string(key)] {
+				seen[string(key)] = true
+				keys = append(keys, append([]byte(nil), key...))
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+	return keys, nil
+}
+
+// overlaps returns true if the blocks of key overlap, within a file or
+// across files, so that they may hold duplicate points.
+func (d *deduper) overlaps(key []byte) (bool, error) {
+	var entries []tsm1.IndexEntry
+	for _, r := range d.readers {
+		es, err := r.ReadEntries(key, nil)
+		if err != nil {
+			return false, err
+		}
+		entries = append(entries, es...)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].MinTime < entries[j].MinTime })
+	for i := 1; i < len(entries); i++ {
+		if entries[i].MinTime <= entries[i-1].MaxTime {
+			return true, nil
+		}
+		// A block may span the blocks following it.
+		if entries[i].MaxTime < entries[i-1].MaxTime {
+			entries[i].MaxTime = entries[i-1].MaxTime
+		}
+	}
+	return false, nil
+}
+
+// read returns the values of key merged from all the readers, without the
+// tombstoned values, and the number of duplicates collapsed according to the
+// policy.
+func (d *deduper) read(key []byte) (tsm1.Values, int, error) {
+	var values tsm1.Values
+	for _, r := range d.readers {
+		if !r.Contains(key) {
+			continue
+		}
+		vs, err := r.ReadAll(key)
+		if err != nil {
+			return nil, 0, err
+		}
+		values = append(values, vs...)
+	}
+	n := len(values)
+
+	// Deduplicate keeps the last of the values of a timestamp.
+	if d.cmd.Policy == PolicyOldest {
+		for i, j := 0, len(values)-1; i < j; i, j = i+1, j-1 {
+			values[i], values[j] = values[j], values[i]
+		}
+	}
+	values = values.Deduplicate()
+	return values, n - len(values), nil
+}
+
+// rewrite writes the deduplicated values of the keys to temporary files of
+// dir, starting at generation, and returns their paths.
+func (d *deduper) rewrite(keys [][]byte, dir string, generation int) ([]string, error) {
+	w := &tsmWriter{dir: dir, generation: generation}
+	for _, key := range keys {
+		values, _, err := d.read(key)
+		if err != nil {
+			w.abort()
+			return nil, fmt.Errorf("unable to read key %q: %v", key, err)
+		}
+		if err := w.write(key, values); err != nil {
+			w.abort()
+			return nil, err
+		}
+	}
+	if err := w.close(); err != nil {
+		w.abort()
+		return nil, err
+	}
+	return w.paths, nil
+}
+
+// tmpPath returns the temporary path of the TSM file at path.
+func tmpPath(path string) string {
+	return path + "." + tsm1.CompactionTempExtension
+}
+
+// tsmWriter writes values to temporary TSM files of a generation, with
+// increasing sequences.
+type tsmWriter struct {
+	dir        string
+	generation int
+	sequence   int
+
+	w     tsm1.TSMWriter
+	paths []string
+}
+
+func (w *tsmWriter) write(key []byte, values tsm1.Values) error {
+	for i := 0; i < len(values); i += tsm1.MaxPointsPerBlock {
+		j := i + tsm1.MaxPointsPerBlock
+		if j > len(values) {
+			j = len(values)
+		}
+		if err := w.open(); err != nil {
+			return err
+		}
+		if err := w.w.Write(key, values[i:j]); err != nil {
+			return err
+		}
+		// Start a new file once the current one is full, as a key may
+		// span several files.
+		if w.w.Size() > maxFileSize {
+			if err := w.finish(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *tsmWriter) open() error {
+	if w.w != nil {
+		return nil
+	}
+	w.sequence++
+	path := filepath.Join(w.dir, tsm1.DefaultFormatFileName(w.generation, w.sequence)+"."+tsm1.TSMFileExtension)
+	f, err := os.OpenFile(tmpPath(path), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	if w.w, err = tsm1.NewTSMWriter(f); err != nil {
+		f.Close()
+		return err
+	}
+	w.paths = append(w.paths, path)
+	return nil
+}
+
+// finish completes the current file.
+func (w *tsmWriter) finish() error {
+	tw := w.w
+	w.w = nil
+	if err := tw.WriteIndex(); err != nil {
+		tw.Remove()
+		return err
+	}
+	return tw.Close()
+}
+
+func (w *tsmWriter) close() error {
+	if w.w == nil {
+		return nil
+	}
+	return w.finish()
+}
+
+// abort removes the files written.
+func (w *tsmWriter) abort() {
+	if w.w != nil {
+		w.w.Remove()
+		w.w = nil
+	}
+	for _, path := range w.paths {
+		os.Remove(tmpPath(path))
+		os.Remove(tsm1.StatsFilename(path))
+	}
+}
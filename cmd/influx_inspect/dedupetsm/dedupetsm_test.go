@@ -0,0 +1,184 @@
+package dedupetsm_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/dedupetsm"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile/tsmtest"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+const (
+	cpuKey  = "cpu#!~#usage"
+	diskKey = "disk#!~#used"
+	memKey  = "mem#!~#free"
+)
+
+// writeShard writes two overlapping TSM files to dir: cpu and disk hold
+// duplicate points, mem does not.
+func writeShard(t *testing.T, dir string) {
+	t.Helper()
+
+	first := filepath.Join(dir, "000000001-000000002.tsm")
+	tsmtest.WriteFile(t, first, map[string]tsm1.Values{
+		cpuKey:  {tsm1.NewValue(0, 1.0), tsm1.NewValue(10, 2.0), tsm1.NewValue(20, 3.0)},
+		diskKey: {tsm1.NewValue(0, int64(1))},
+		memKey:  {tsm1.NewValue(0, int64(1))},
+	})
+	tsmtest.WriteFile(t, filepath.Join(dir, "000000002-000000001.tsm"), map[string]tsm1.Values{
+		cpuKey:  {tsm1.NewValue(10, 20.0), tsm1.NewValue(20, 30.0), tsm1.NewValue(30, 40.0)},
+		diskKey: {tsm1.NewValue(0, int64(10))},
+		memKey:  {tsm1.NewValue(10, int64(2))},
+	})
+	// Tombstoned values are not duplicates.
+	deleteRange(t, first, cpuKey, 20, 20)
+}
+
+func TestCommand_Run(t *testing.T) {
+	for _, tt := range []struct {
+		policy string
+		want   map[string][]tsm1.Value
+	}{
+		{
+			policy: dedupetsm.PolicyNewest,
+			want: map[string][]tsm1.Value{
+				cpuKey:  {tsm1.NewValue(0, 1.0), tsm1.NewValue(10, 20.0), tsm1.NewValue(20, 30.0), tsm1.NewValue(30, 40.0)},
+				diskKey: {tsm1.NewValue(0, int64(10))},
+				memKey:  {tsm1.NewValue(0, int64(1)), tsm1.NewValue(10, int64(2))},
+			},
+		},
+		{
+			policy: dedupetsm.PolicyOldest,
+			want: map[string][]tsm1.Value{
+				cpuKey:  {tsm1.NewValue(0, 1.0), tsm1.NewValue(10, 2.0), tsm1.NewValue(20, 30.0), tsm1.NewValue(30, 40.0)},
+				diskKey: {tsm1.NewValue(0, int64(1))},
+				memKey:  {tsm1.NewValue(0, int64(1)), tsm1.NewValue(10, int64(2))},
+			},
+		},
+	} {
+		t.Run(tt.policy, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "dedupetsm")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			shard := filepath.Join(dir, "data", "1")
+			writeShard(t, shard)
+
+			var stdout bytes.Buffer
+			cmd := dedupetsm.NewCommand()
+			cmd.Stdout = &stdout
+			cmd.Stderr = ioutil.Discard
+			cmd.Paths = []string{dir}
+			cmd.Policy = tt.policy
+			if err := cmd.Run(); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(stdout.String(), "collapsed 2 duplicate point(s) of 2 key(s) in 2 TSM file(s) of 1 directory(s)") {
+				t.Fatalf("unexpected output: %s", stdout.String())
+			}
+
+			// The files and their tombstones are replaced by a file of a
+			// later generation.
+			want := []string{"000000000000003-000000001.tsm", "000000000000003-000000001.tss"}
+			if got := listFiles(t, shard); !reflect.DeepEqual(got, want) {
+				t.Fatalf("unexpected files: got %v, want %v", got, want)
+			}
+			if got := tsmtest.ReadFile(t, filepath.Join(shard, want[0])); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("unexpected values:\ngot  %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommand_Run_DryRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dedupetsm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeShard(t, dir)
+	before := listFiles(t, dir)
+
+	var stdout bytes.Buffer
+	cmd := &dedupetsm.Command{
+		Stdout:  &stdout,
+		Stderr:  ioutil.Discard,
+		Paths:   []string{dir},
+		DryRun:  true,
+		Verbose: true,
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`"cpu#!~#usage": 1 duplicate point(s)`,
+		`"disk#!~#used": 1 duplicate point(s)`,
+		"would collapse 2 duplicate point(s) of 2 key(s) in 2 TSM file(s) of 1 directory(s)",
+	} {
+		if !strings.Contains(stdout.String(), s) {
+			t.Fatalf("missing %q in output: %s", s, stdout.String())
+		}
+	}
+	if strings.Contains(stdout.String(), memKey) {
+		t.Fatalf("unexpected output: %s", stdout.String())
+	}
+	if got := listFiles(t, dir); !reflect.DeepEqual(got, before) {
+		t.Fatalf("unexpected files: got %v, want %v", got, before)
+	}
+}
+
+func TestCommand_Run_Invalid(t *testing.T) {
+	for _, tt := range []struct {
+		cmd dedupetsm.Command
+		err string
+	}{
+		{cmd: dedupetsm.Command{}, err: "path required"},
+		{cmd: dedupetsm.Command{Paths: []string{"data"}, Policy: "latest"}, err: `unsupported policy "latest"`},
+	} {
+		if err := tt.cmd.Run(); err == nil || err.Error() != tt.err {
+			t.Errorf("unexpected error: got %v, want %q", err, tt.err)
+		}
+	}
+}
+
+// listFiles returns the sorted names of the files of dir.
+func listFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, fi := range fis {
+		names = append(names, fi.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// deleteRange records a tombstone for the values of key in [min, max].
+func deleteRange(t *testing.T, path, key string, min, max int64) {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := r.DeleteRange([][]byte{[]byte(key)}, min, max); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,230 @@
+package deletetsm
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// ManifestName is the name of the file of a backup directory listing the
+// original paths of the backed up TSM files.
+const ManifestName = "manifest.json"
+
+// backupEntry is a line of the manifest of a backup directory.
+type backupEntry struct {
+	// Path is the absolute path of the original TSM file.
+	Path string `json:"path"`
+	// Name is the name of the copy of the TSM file in the backup directory.
+	Name string `json:"name"`
+}
+
+// backup saves the TSM files, with their statistics and tombstone files, and
+// the WAL segments to a directory before they are rewritten.
+type backup struct {
+	dir string
+
+	mu       sync.Mutex
+	manifest *os.File
+	saved    map[string]string // name in the backup directory -> original path
+}
+
+// openBackup opens the backup directory dir, creating it if needed. The files
+// already saved to it are kept, so that the directory always holds the
+// files as they were before the first run using it.
+func openBackup(dir string) (*backup, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+
+	entries, err := readManifest(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	b := &backup{dir: dir, saved: make(map[string]string, len(entries))}
+	for _, e := range entries {
+		b.saved[e.Name] = e.Path
+	}
+
+	if b.manifest, err = os.OpenFile(filepath.Join(dir, ManifestName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// save links, or copies, the TSM file at path and its companion files to the
+// backup directory, then records it in the manifest. A file already saved
+// is left as is.
+func (b *backup) save(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(abs)
+
+	b.mu.Lock()
+	prev, ok := b.saved[name]
+	b.mu.Unlock()
+	if ok && prev == abs {
+		return nil
+	} else if ok {
+		return fmt.Errorf("backup of %s conflicts with backup of %s", abs, prev)
+	}
+
+	dst := companionPaths(filepath.Join(b.dir, name))
+	for i, src := range companionPaths(abs) {
+		// Files left by an interrupted run may be links to the originals,
+		// so they are removed rather than overwritten.
+		if err := removeIfExists(dst[i]); err != nil {
+			return err
+		}
+		if err := linkOrCopy(src, dst[i]); os.IsNotExist(err) && i > 0 {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("unable to back up: %v", err)
+		}
+	}
+
+	// The entry is synced before the original file is replaced, so that an
+	// interrupted run can be rolled back.
+	line, err := json.Marshal(backupEntry{Path: abs, Name: name})
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, err := b.manifest.Write(append(line, '\n')); err != nil {
+		return err
+	} else if err := b.manifest.Sync(); err != nil {
+		return err
+	}
+	b.saved[name] = abs
+	return nil
+}
+
+// Close closes the manifest.
+func (b *backup) Close() error {
+	return b.manifest.Close()
+}
+
+// restore puts back the files saved to the backup directory dir over the
+// rewritten ones, recreating the TSM files that were removed. It returns the
+// paths of the restored TSM files.
+func restore(dir string) ([]string, error) {
+	entries, err := readManifest(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read backup manifest: %v", err)
+	}
+
+	paths := make([]string, 0, len(entries))
+	for _, e := range entries {
+		// Remove any leftover of an interrupted rewrite.
+		tmp := tempPath(e.Path)
+		if err := removeIfExists(tmp); err != nil {
+			return paths, err
+		} else if err := removeIfExists(tsm1.StatsFilename(tmp)); err != nil {
+			return paths, err
+		}
+
+		dsts := companionPaths(e.Path)
+		for i, src := range companionPaths(filepath.Join(dir, e.Name)) {
+			dst := dsts[i]
+			if _, err := os.Stat(src); os.IsNotExist(err) && i > 0 {
+				// The companion file did not exist before the rewrite.
+				if err := removeIfExists(dst); err != nil {
+					return paths, err
+				}
+				continue
+			}
+
+			// Restore through a temporary file so that the original is
+			// replaced atomically.
+			if err := removeIfExists(dst + ".restore"); err != nil {
+				return paths, err
+			} else if err := linkOrCopy(src, dst+".restore"); err != nil {
+				return paths, fmt.Errorf("unable to restore %s: %v", dst, err)
+			} else if err := os.Rename(dst+".restore", dst); err != nil {
+				return paths, err
+			}
+		}
+		paths = append(paths, e.Path)
+	}
+	return paths, nil
+}
+
+// readManifest returns the entries of the manifest of the backup directory dir.
+func readManifest(dir string) ([]backupEntry, error) {
+	f, err := os.Open(filepath.Join(dir, ManifestName))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		entries []backupEntry
+		invalid error
+	)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if invalid != nil {
+			return nil, invalid
+		} else if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e backupEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// The last line is ignored if it was only partially written.
+			invalid = fmt.Errorf("invalid manifest entry %q: %v", scanner.Text(), err)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// companionPaths returns the path of the TSM file, of its statistics file
+// and of its tombstone file. Other files, such as WAL segments, have no
+// companion files.
+func companionPaths(path string) []string {
+	if filepath.Ext(path) != "."+tsm1.TSMFileExtension {
+		return []string{path}
+	}
+	return []string{
+		path,
+		tsm1.StatsFilename(path),
+		strings.TrimSuffix(path, filepath.Ext(path)) + ".tombstone",
+	}
+}
+
+// linkOrCopy hard links src to dst, or copies it if it can not be linked,
+// such as when dst is on another device.
+func linkOrCopy(src, dst string) error {
+	if err := os.Link(src, dst); err == nil || os.IsNotExist(err) {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	} else if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,99 @@
+package deletetsm
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DefaultCheckpointName is the name of the checkpoint file of the delete-tsm
+// command, in the working directory, when none is given.
+const DefaultCheckpointName = ".deletetsm.progress"
+
+// checkpoint records the TSM files completely processed by a run, one
+// absolute path per line, so that an interrupted run can be resumed without
+// processing them again.
+type checkpoint struct {
+	path string
+
+	mu   sync.Mutex
+	f    *os.File
+	done map[string]struct{}
+}
+
+// openCheckpoint opens the checkpoint file at path. The files it lists are
+// kept if resuming, and discarded otherwise.
+func openCheckpoint(path string, resume bool) (*checkpoint, error) {
+	c := &checkpoint{path: path, done: make(map[string]struct{})}
+
+	flag := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if resume {
+		if err := c.read(); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	} else {
+		flag |= os.O_TRUNC
+	}
+
+	f, err := os.OpenFile(path, flag, 0666)
+	if err != nil {
+		return nil, err
+	}
+	c.f = f
+	return c, nil
+}
+
+func (c *checkpoint) read() error {
+	f, err := os.Open(c.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// A line cut short by a crash matches no file.
+		if line := scanner.Text(); filepath.IsAbs(line) {
+			c.done[line] = struct{}{}
+		}
+	}
+	return scanner.Err()
+}
+
+// completed returns true if the file at path was processed by a previous run.
+func (c *checkpoint) completed(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	_, ok := c.done[abs]
+	return ok
+}
+
+// add records that the file at path was processed.
+func (c *checkpoint) add(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := fmt.Fprintln(c.f, abs); err != nil {
+		return err
+	}
+	return c.f.Sync()
+}
+
+// Close closes the checkpoint file, removing it if every file was processed.
+func (c *checkpoint) Close(finished bool) error {
+	err := c.f.Close()
+	if finished {
+		if rerr := os.Remove(c.path); err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,608 @@
+package deletetsm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// Deleter deletes the values of a set of series from TSM files and WAL
+// segments. It holds the deletion logic of the delete-tsm command, without
+// its output, so that other tools can embed it.
+//
+// The storage engine must not be running while files are rewritten.
+type Deleter struct {
+	// OrgID and BucketID optionally restrict deletion to series of an
+	// organization, or of a bucket within that organization.
+	OrgID    influxdb.ID
+	BucketID influxdb.ID
+
+	// Measurements selects all series of the named measurements.
+	Measurements []string
+
+	// Match optionally selects series by their key, in the
+	// `measurement,tag=value` form, and their field. It may be called
+	// concurrently.
+	Match func(seriesKey, field []byte) bool
+
+	// Predicate optionally restricts deletion to the series selected by
+	// Measurements or Match whose tags match it. When neither is set, every
+	// series matching it is deleted. It is cloned for each worker.
+	Predicate influxdb.Predicate
+
+	// All selects every series of OrgID and BucketID. It can not be combined
+	// with Measurements, Match or Predicate, one of which must otherwise be
+	// set, so that a Deleter missing its selection deletes nothing.
+	All bool
+
+	// Field optionally restricts deletion to the values of this field of the
+	// selected series.
+	Field string
+
+	// Rename optionally returns the new TSM key of a key that is not
+	// selected for deletion, or nil to keep it. The blocks of a renamed key
+	// are moved to the new key, which must not already exist in the file.
+	// It may be called concurrently. Keys can not be renamed by tombstones.
+	Rename func(key []byte) []byte
+
+	// Start and End optionally restrict deletion to the values within the
+	// window [Start, End]. Blocks partially within the window are decoded,
+	// trimmed and re-encoded.
+	Start time.Time
+	End   time.Time
+
+	// DryRun computes the statistics of the deletion without modifying any file.
+	DryRun bool
+
+	// Tombstone records tombstones for the deleted values instead of
+	// rewriting the TSM files.
+	Tombstone bool
+
+	// Verify re-reads each rewritten TSM file before it replaces the original.
+	Verify bool
+
+	// Concurrency is the number of files DeleteFiles processes concurrently.
+	// Files are processed one at a time if it is less than 2.
+	Concurrency int
+
+	// BeforeReplace is called, if set, with the path of each TSM file or WAL
+	// segment before it is modified, replaced or removed, such as to back it
+	// up. The file is left untouched if it returns an error.
+	BeforeReplace func(path string) error
+
+	// OnKeyDeleted is called, if set, with each TSM key some values of were
+	// deleted from the TSM file or WAL segment at path. It may be called
+	// concurrently.
+	OnKeyDeleted func(path string, key []byte)
+
+	// OnKeyRenamed is called, if set, with each TSM key renamed in the TSM
+	// file or WAL segment at path, and its new key. It may be called
+	// concurrently.
+	OnKeyRenamed func(path string, key, newKey []byte)
+
+	// OnBlock is called, if set, with each block deleted or trimmed when
+	// rewriting the TSM file at path. It may be called concurrently.
+	OnBlock func(path string, b Block)
+
+	// OnFile is called, if set, by DeleteFiles once each file is processed,
+	// in the order of the files. Processing stops if it returns an error.
+	OnFile func(path string, stats Stats, d time.Duration) error
+}
+
+// Block describes a block deleted or trimmed from a TSM file.
+type Block struct {
+	Key []byte
+	// MinTime and MaxTime are the time range of the deleted values.
+	MinTime int64
+	MaxTime int64
+	// Size is the size of a deleted block, or the size reduction of a
+	// trimmed one.
+	Size int
+	// Trimmed is true if only some of the values of the block were deleted.
+	Trimmed bool
+	// Values is the number of values deleted from a trimmed block.
+	Values int
+}
+
+// DeleteFiles processes the TSM files at paths with up to Concurrency
+// workers, calling OnFile for each of them in order, as if the files were
+// processed serially. Processing stops at the first file that fails,
+// although files after it may already have been rewritten.
+func (d *Deleter) DeleteFiles(paths []string) error {
+	if err := d.checkSelection(); err != nil {
+		return err
+	}
+
+	n := d.Concurrency
+	if n < 1 {
+		n = 1
+	} else if n > len(paths) {
+		n = len(paths)
+	}
+
+	type result struct {
+		stats    Stats
+		duration time.Duration
+		err      error
+		done     chan struct{}
+	}
+	results := make([]result, len(paths))
+	for i := range results {
+		results[i].done = make(chan struct{})
+	}
+
+	var (
+		next    int64 = -1
+		stopped int32
+		wg      sync.WaitGroup
+	)
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(paths) {
+					return
+				}
+				if atomic.LoadInt32(&stopped) == 0 {
+					start := time.Now()
+					results[i].stats, results[i].err = d.DeleteFile(paths[i])
+					results[i].duration = time.Since(start)
+				}
+				close(results[i].done)
+			}
+		}()
+	}
+	defer wg.Wait()
+
+	for i, path := range paths {
+		<-results[i].done
+		err := results[i].err
+		if err == nil && d.OnFile != nil {
+			err = d.OnFile(path, results[i].stats, results[i].duration)
+		}
+		if err != nil {
+			atomic.StoreInt32(&stopped, 1)
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// DeleteFile deletes the values of the selected series from the TSM file at
+// path, rewriting it or recording tombstones. The file is removed if every
+// block is deleted, and left untouched if nothing is.
+func (d *Deleter) DeleteFile(path string) (Stats, error) {
+	if err := d.checkSelection(); err != nil {
+		return Stats{}, err
+	}
+
+	if d.Tombstone {
+		if d.Rename != nil {
+			return Stats{}, errors.New("renaming keys requires rewriting files, not recording tombstones")
+		}
+		return d.tombstone(path)
+	}
+
+	outputPath := tempPath(path)
+	stats, err := d.rewrite(path, outputPath)
+	if err != nil || d.DryRun {
+		return stats, err
+	}
+
+	if stats.Empty() {
+		// Nothing was deleted, so the original file is left untouched.
+		return stats, nil
+	}
+
+	_, err = os.Stat(outputPath)
+	if err != nil && !os.IsNotExist(err) {
+		return stats, err
+	}
+	removed := os.IsNotExist(err)
+
+	if d.Verify && !removed {
+		if err := d.verify(path, outputPath); err != nil {
+			// Leave the original file untouched.
+			if rerr := os.Remove(outputPath); rerr != nil {
+				return stats, rerr
+			} else if rerr := removeIfExists(tsm1.StatsFilename(outputPath)); rerr != nil {
+				return stats, rerr
+			}
+			return stats, fmt.Errorf("verification failed: %v", err)
+		}
+	}
+
+	if d.BeforeReplace != nil {
+		if err := d.BeforeReplace(path); err != nil {
+			return stats, err
+		}
+	}
+
+	if removed {
+		// Every block was deleted, so remove the file altogether.
+		if err := os.Remove(path); err != nil {
+			return stats, err
+		} else if err := removeIfExists(tsm1.StatsFilename(path)); err != nil {
+			return stats, err
+		}
+		return stats, tsm1.NewTombstoner(path, nil).Delete()
+	}
+
+	// Replace original file with new file. Any tombstone file is kept, as
+	// blocks are copied verbatim and may still contain tombstoned values.
+	if err := os.Rename(outputPath, path); err != nil {
+		return stats, err
+	}
+	return stats, renameIfExists(tsm1.StatsFilename(outputPath), tsm1.StatsFilename(path))
+}
+
+// rewrite copies the blocks of the TSM file at path that are not deleted to a
+// new TSM file at outputPath. The new file is not created if no block is to
+// be deleted, if every block is deleted, or in dry-run mode.
+func (d *Deleter) rewrite(path, outputPath string) (stats Stats, err error) {
+	input, err := os.Open(path)
+	if err != nil {
+		return stats, err
+	}
+
+	r, err := tsm1.NewTSMReader(input)
+	if err != nil {
+		input.Close()
+		return stats, fmt.Errorf("unable to read: %v", err)
+	}
+	defer r.Close()
+
+	var w tsm1.TSMWriter
+	if !d.DryRun {
+		// Remove previous temporary files.
+		if err := os.RemoveAll(outputPath); err != nil {
+			return stats, err
+		} else if err := os.RemoveAll(outputPath + ".idx.tmp"); err != nil {
+			return stats, err
+		}
+
+		output, err := os.Create(outputPath)
+		if err != nil {
+			return stats, err
+		}
+
+		if w, err = tsm1.NewTSMWriter(output); err != nil {
+			output.Close()
+			return stats, err
+		}
+		defer func() {
+			// Discard the new file unless it is complete and some blocks were deleted.
+			if err != nil || stats.Empty() {
+				if rerr := w.Remove(); err == nil {
+					err = rerr
+				}
+			}
+		}()
+	}
+
+	// The blocks of the renamed keys are written in the order of their new
+	// key, among those of the other keys.
+	var renamed []renamedBlock
+	if d.Rename != nil {
+		if renamed, err = d.renamedBlocks(r); err != nil {
+			return stats, err
+		}
+		for i, b := range renamed {
+			if i == 0 || !bytes.Equal(b.key, renamed[i-1].key) {
+				stats.Renamed++
+				if d.OnKeyRenamed != nil {
+					d.OnKeyRenamed(path, b.key, b.newKey)
+				}
+			}
+		}
+	}
+	writeRenamed := func(before []byte) error {
+		for len(renamed) > 0 && (before == nil || bytes.Compare(renamed[0].newKey, before) < 0) {
+			b := renamed[0]
+			if w != nil {
+				if err := w.WriteBlock(b.newKey, b.minTime, b.maxTime, b.block); err != nil {
+					return err
+				}
+			}
+			renamed = renamed[1:]
+		}
+		return nil
+	}
+
+	keys := d.keyMatcher()
+	start, end := d.timeRange()
+	var (
+		lastKey     []byte
+		lastDeleted bool
+		values      []tsm1.Value
+	)
+	itr := r.BlockIterator()
+	for itr.Next() {
+		key, minTime, maxTime, _, _, block, err := itr.Read()
+		if err != nil {
+			return stats, err
+		}
+
+		match := keys.matches(key)
+		if lastKey == nil || !bytes.Equal(key, lastKey) {
+			lastKey = append(lastKey[:0], key...)
+			lastDeleted = false
+			if err := writeRenamed(key); err != nil {
+				return stats, err
+			}
+		}
+		if !match && keys.renames(key) != nil {
+			// Already written under its new key.
+			continue
+		}
+
+		if match && minTime >= start && maxTime <= end {
+			if !lastDeleted {
+				stats.Series, lastDeleted = stats.Series+1, true
+				d.keyDeleted(path, key)
+			}
+			stats.addBlock(minTime, maxTime, len(block))
+			if d.OnBlock != nil {
+				d.OnBlock(path, Block{Key: key, MinTime: minTime, MaxTime: maxTime, Size: len(block)})
+			}
+			continue
+		}
+
+		if match && minTime <= end && maxTime >= start {
+			// The block is partially within the window, so only keep the values outside of it.
+			if values, err = tsm1.DecodeBlock(block, values[:0]); err != nil {
+				return stats, fmt.Errorf("unable to decode block of %q: %v", key, err)
+			}
+
+			if i, j := window(values, start, end); i < j {
+				if !lastDeleted {
+					stats.Series, lastDeleted = stats.Series+1, true
+					d.keyDeleted(path, key)
+				}
+				deletedMin, deletedMax := values[i].UnixNano(), values[j-1].UnixNano()
+				kept := append(tsm1.Values(values[:i:i]), values[j:]...)
+				trimmed, err := kept.Encode(nil)
+				if err != nil {
+					return stats, err
+				}
+
+				stats.addTrimmed(deletedMin, deletedMax, len(block)-len(trimmed))
+				if d.OnBlock != nil {
+					d.OnBlock(path, Block{
+						Key:     key,
+						MinTime: deletedMin,
+						MaxTime: deletedMax,
+						Size:    len(block) - len(trimmed),
+						Trimmed: true,
+						Values:  j - i,
+					})
+				}
+
+				if w != nil {
+					if err := w.WriteBlock(key, kept.MinTime(), kept.MaxTime(), trimmed); err != nil {
+						return stats, err
+					}
+				}
+				continue
+			}
+		}
+
+		if w != nil {
+			if err := w.WriteBlock(key, minTime, maxTime, block); err != nil {
+				return stats, err
+			}
+		}
+	}
+	if err := itr.Err(); err != nil {
+		return stats, err
+	}
+	if err := writeRenamed(nil); err != nil {
+		return stats, err
+	}
+
+	if w == nil || stats.Empty() {
+		return stats, nil
+	}
+
+	if err := w.WriteIndex(); err == tsm1.ErrNoValues {
+		// Every block was deleted, so discard the empty file.
+		return stats, w.Remove()
+	} else if err != nil {
+		return stats, err
+	}
+	return stats, w.Close()
+}
+
+// renamedBlock is a block of a key renamed when rewriting a TSM file.
+type renamedBlock struct {
+	key, newKey      []byte
+	minTime, maxTime int64
+	block            []byte
+}
+
+// renamedBlocks returns the blocks of the keys of the TSM file renamed by
+// Rename, sorted by their new key.
+func (d *Deleter) renamedBlocks(r *tsm1.TSMReader) ([]renamedBlock, error) {
+	keys := d.keyMatcher()
+	var blocks []renamedBlock
+	itr := r.Iterator(nil)
+	for itr.Next() {
+		key := itr.Key()
+		if keys.matches(key) {
+			continue
+		}
+		newKey := keys.renames(key)
+		if newKey == nil {
+			continue
+		}
+		if r.Contains(newKey) {
+			return nil, fmt.Errorf("unable to rename %q: key %q already exists", key, newKey)
+		}
+
+		key = append([]byte(nil), key...)
+		for _, e := range itr.Entries() {
+			_, block, err := r.ReadBytes(&e, nil)
+			if err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, renamedBlock{
+				key:     key,
+				newKey:  newKey,
+				minTime: e.MinTime,
+				maxTime: e.MaxTime,
+				block:   block,
+			})
+		}
+	}
+	if err := itr.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(blocks, func(i, j int) bool { return bytes.Compare(blocks[i].newKey, blocks[j].newKey) < 0 })
+	for i := 1; i < len(blocks); i++ {
+		if prev := blocks[i-1]; bytes.Equal(prev.newKey, blocks[i].newKey) && !bytes.Equal(prev.key, blocks[i].key) {
+			return nil, fmt.Errorf("unable to rename both %q and %q to %q", prev.key, blocks[i].key, prev.newKey)
+		}
+	}
+	return blocks, nil
+}
+
+func (d *Deleter) keyDeleted(path string, key []byte) {
+	if d.OnKeyDeleted != nil {
+		d.OnKeyDeleted(path, key)
+	}
+}
+
+// checkSelection returns an error unless the series to delete are selected
+// either by All, or by Measurements, Match or Predicate.
+func (d *Deleter) checkSelection() error {
+	selected := len(d.Measurements) > 0 || d.Match != nil || d.Predicate != nil
+	if d.All && selected {
+		return errors.New("all series can not be selected along with measurements, a match or a predicate")
+	} else if !d.All && !selected {
+		return errors.New("no series selected: measurements, a match, a predicate or all series required")
+	}
+	return nil
+}
+
+// timeRange returns the window of the values to delete.
+func (d *Deleter) timeRange() (min, max int64) {
+	min, max = math.MinInt64, math.MaxInt64
+	if !d.Start.IsZero() {
+		min = d.Start.UnixNano()
+	}
+	if !d.End.IsZero() {
+		max = d.End.UnixNano()
+	}
+	return min, max
+}
+
+// match returns true if the series of the TSM key must be deleted. The where
+// predicate is a clone of the Predicate, as predicates can not be shared by
+// concurrent workers.
+func (d *Deleter) match(key []byte, where influxdb.Predicate) bool {
+	seriesKey, field := tsm1.SeriesAndFieldFromCompositeKey(key)
+	if d.Field != "" && string(field) != d.Field {
+		return false
+	}
+	if where != nil && !where.Matches(key) {
+		return false
+	}
+	if len(d.Measurements) == 0 && d.Match == nil {
+		// The series are only selected by the predicate.
+		return true
+	}
+
+	// Match is called first, so that it sees the series also selected by
+	// Measurements.
+	if d.Match != nil && d.Match(seriesKeyOf(key), field) {
+		return true
+	}
+	if len(d.Measurements) == 0 {
+		return false
+	}
+	_, tags := models.ParseKeyBytes(seriesKey)
+	name := tags.Get(models.MeasurementTagKeyBytes)
+	for _, m := range d.Measurements {
+		if string(name) == m {
+			return true
+		}
+	}
+	return false
+}
+
+// wherePredicate returns a clone of the Predicate for the exclusive use of a
+// worker, or nil if there is none.
+func (d *Deleter) wherePredicate() influxdb.Predicate {
+	if d.Predicate == nil {
+		return nil
+	}
+	return d.Predicate.Clone()
+}
+
+// keyMatcher returns a matcher of the keys of a TSM file for the exclusive
+// use of a worker.
+func (d *Deleter) keyMatcher() *keyMatcher {
+	return &keyMatcher{d: d, prefix: tsmfile.Prefix(d.OrgID, d.BucketID), where: d.wherePredicate()}
+}
+
+// keyMatcher matches the keys of a TSM file, which are consecutive, matching
+// each key only once.
+type keyMatcher struct {
+	d      *Deleter
+	prefix []byte
+	where  influxdb.Predicate
+
+	lastKey    []byte
+	lastMatch  bool
+	lastRename []byte
+}
+
+// matches returns true if the series of the TSM key must be deleted.
+func (m *keyMatcher) matches(key []byte) bool {
+	if m.lastKey == nil || !bytes.Equal(key, m.lastKey) {
+		m.lastKey = append(m.lastKey[:0], key...)
+		m.lastMatch = bytes.HasPrefix(key, m.prefix) && m.d.match(key, m.where)
+		m.lastRename = nil
+		if !m.lastMatch && m.d.Rename != nil && bytes.HasPrefix(key, m.prefix) {
+			m.lastRename = m.d.Rename(key)
+		}
+	}
+	return m.lastMatch
+}
+
+// renames returns the new key of the TSM key if it is renamed, or nil.
+func (m *keyMatcher) renames(key []byte) []byte {
+	m.matches(key)
+	return m.lastRename
+}
+
+// window returns the indexes [i, j) of the sorted values within [start, end].
+func window(values []tsm1.Value, start, end int64) (i, j int) {
+	i = sort.Search(len(values), func(i int) bool { return values[i].UnixNano() >= start })
+	j = sort.Search(len(values), func(i int) bool { return values[i].UnixNano() > end })
+	return i, j
+}
+
+// tempPath returns the path of the file that replaces the TSM file at path.
+// It keeps the TSM extension so that the writer stores its statistics in a
+// separate file from those of the original.
+func tempPath(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + ".deletetsm" + ext + "." + tsm1.TmpTSMFileExtension
+}
@@ -0,0 +1,603 @@
+// Package deletetsm bulk deletes series from raw TSM files.
+//
+// Command implements the delete-tsm command of influxd inspect, on top of
+// Deleter, which other tools may use to delete series from TSM files and WAL
+// segments without its output.
+//
+// The storage engine must not be running while files are rewritten.
+package deletetsm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile"
+	"github.com/influxdata/influxdb/predicate"
+	"github.com/influxdata/influxdb/storage/wal"
+)
+
+// Command deletes the blocks of the matching series from a set of TSM files.
+type Command struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Paths lists the TSM files to process, and the directories, such as
+	// the data directory of the engine, searched recursively for TSM files.
+	Paths []string
+
+	// WALDir is the optional WAL directory of the engine, whose segments are
+	// rewritten without the values of the matching series so that they do
+	// not reappear when the WAL is replayed.
+	WALDir string
+
+	// OrgID and BucketID optionally restrict deletion to series of an
+	// organization, or of a bucket within that organization.
+	OrgID    influxdb.ID
+	BucketID influxdb.ID
+
+	// Measurements deletes all series of the named measurements, without
+	// reading the series file or index.
+	Measurements []string
+
+	// Sanitize selects all series with keys containing invalid UTF-8 or
+	// non-printable characters, which are deleted or renamed depending on
+	// SanitizeMode. Every key touched is reported.
+	Sanitize bool
+
+	// SanitizeMode is either SanitizeDrop, the default, to delete the
+	// series selected by Sanitize, or SanitizeEscape to rename them with
+	// their invalid characters escaped. Escaping can not be combined with
+	// Field, Where, Start, End or Tombstone, and the series file and index
+	// should be rebuilt afterwards for the escaped series to be indexed.
+	SanitizeMode string
+
+	// SeriesFile is the path of a file listing the series to delete, one per
+	// line, as exact keys or as regex: or glob: patterns. The series are read
+	// from Stdin if it is -, and the file may be gzip compressed.
+	SeriesFile string
+
+	// UnmatchedPath is the optional path of a file the keys and patterns of
+	// SeriesFile that matched no series are written to, one per line, so
+	// that it can be corrected and given back as a series file. They are
+	// printed in the summary otherwise.
+	UnmatchedPath string
+
+	// Field optionally restricts deletion to the values of this field of the
+	// matching series, leaving their other fields untouched.
+	Field string
+
+	// Where is an optional predicate on the tags of the series, such as
+	// `host=web01 AND region=us-east`, restricting deletion to the series
+	// selected by the other options whose tags match it. When no other
+	// option selects series, every series matching it is deleted.
+	Where string
+
+	// Start and End optionally restrict deletion to the values of the
+	// matching series within the window [Start, End]. Blocks partially
+	// within the window are decoded, trimmed and re-encoded.
+	Start time.Time
+	End   time.Time
+
+	// DryRun reports the blocks that would be deleted without
+	// rewriting any file.
+	DryRun bool
+
+	// Verbose reports every deleted block.
+	Verbose bool
+
+	// Concurrency is the number of files rewritten concurrently. Files are
+	// processed one at a time if it is less than 2.
+	Concurrency int
+
+	// Format is the format of the summary written to Stdout, either
+	// TextFormat or JSONFormat. It defaults to TextFormat.
+	Format string
+
+	// ReportPath is the optional path of a file the JSON report is written to.
+	ReportPath string
+
+	// BackupDir is the optional directory the original TSM files are saved
+	// to, as hard links when possible, before being replaced or removed.
+	BackupDir string
+
+	// Restore puts back the files saved to BackupDir instead of deleting
+	// series, undoing the runs that used it.
+	Restore bool
+
+	// Tombstone records tombstones for the deleted values instead of
+	// rewriting the TSM files, leaving the compactions of the storage engine
+	// to reclaim their space. It does not require free space for a copy of
+	// the largest file.
+	Tombstone bool
+
+	// Verify re-reads each rewritten file before it replaces the original,
+	// checking the checksums of its blocks, that no value to delete remains
+	// and that the blocks of the other series are those of the original.
+	Verify bool
+
+	// SeriesFilePath and IndexPath are the optional paths of the series file
+	// and TSI index of the engine. When set, the series left without any
+	// value by the deletion are dropped from them. Series whose values were
+	// deleted by a previous, interrupted, run are not.
+	SeriesFilePath string
+	IndexPath      string
+
+	// Checkpoint is the optional path of a file recording the TSM files
+	// completely processed, removed once every file is processed.
+	Checkpoint string
+
+	// Resume skips the TSM files recorded in Checkpoint by a previous,
+	// interrupted, run instead of starting over.
+	Resume bool
+
+	files     []string
+	sanitized *sanitizedKeys
+	series    *seriesMatcher
+	where     influxdb.Predicate
+	backup    *backup
+	check     *checkpoint
+	deleted   *deletedSeries
+}
+
+// ExitNothingMatched is the exit status of the command when none of the keys
+// and patterns of the series file matched a series.
+const ExitNothingMatched = 2
+
+// ErrNothingMatched is returned by Run when none of the keys and patterns of
+// the series file matched a series of the files processed.
+var ErrNothingMatched error = nothingMatchedError{}
+
+type nothingMatchedError struct{}
+
+func (nothingMatchedError) Error() string { return "no series matched the series file" }
+
+// ExitCode returns the exit status of the command.
+func (nothingMatchedError) ExitCode() int { return ExitNothingMatched }
+
+// NewCommand returns a new instance of Command writing to the standard output and error.
+func NewCommand() *Command {
+	return &Command{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Run processes each of the TSM files found in Paths.
+func (cmd *Command) Run() error {
+	if cmd.Restore {
+		return cmd.restore()
+	}
+	if len(cmd.Measurements) == 0 && !cmd.Sanitize && cmd.SeriesFile == "" && cmd.Where == "" {
+		return errors.New("measurement, series file, where or sanitize option required")
+	}
+	if cmd.BucketID.Valid() && !cmd.OrgID.Valid() {
+		return errors.New("bucket requires an organization")
+	}
+	if (cmd.SeriesFilePath == "") != (cmd.IndexPath == "") {
+		return errors.New("series file and index paths must be given together")
+	}
+	if cmd.Resume && cmd.Checkpoint == "" {
+		return errors.New("resume requires a checkpoint file")
+	}
+	if cmd.Verify && cmd.Tombstone {
+		return errors.New("verify requires rewriting files, not recording tombstones")
+	}
+	if !cmd.Start.IsZero() && !cmd.End.IsZero() && cmd.End.Before(cmd.Start) {
+		return errors.New("end must not be before start")
+	}
+	switch cmd.SanitizeMode {
+	case "", SanitizeDrop:
+	case SanitizeEscape:
+		if !cmd.Sanitize {
+			return errors.New("escape mode requires the sanitize option")
+		}
+		if cmd.Field != "" || cmd.Where != "" || !cmd.Start.IsZero() || !cmd.End.IsZero() {
+			return errors.New("escape mode can not be combined with field, where, start or end")
+		}
+		if cmd.Tombstone {
+			return errors.New("escape mode requires rewriting files, not recording tombstones")
+		}
+	default:
+		return fmt.Errorf("unknown sanitize mode %q", cmd.SanitizeMode)
+	}
+	switch cmd.Format {
+	case "", TextFormat, JSONFormat:
+	default:
+		return fmt.Errorf("unknown format %q", cmd.Format)
+	}
+
+	files, err := cmd.findFiles()
+	if err != nil {
+		return err
+	}
+	cmd.files = files
+
+	// The series left without values are searched for in every TSM file of
+	// the directories of the files processed.
+	var dirs []string
+	if cmd.IndexPath != "" && !cmd.DryRun {
+		cmd.deleted = &deletedSeries{keys: make(map[string]struct{})}
+		defer func() { cmd.deleted = nil }()
+
+		seen := make(map[string]bool)
+		for _, path := range files {
+			if dir := filepath.Dir(path); !seen[dir] {
+				seen[dir] = true
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+
+	if cmd.SeriesFile != "" {
+		series, err := readSeriesFile(cmd.SeriesFile, cmd.Stdin)
+		if err != nil {
+			return fmt.Errorf("unable to read series file: %v", err)
+		}
+		cmd.series = series
+	}
+
+	if cmd.Where != "" {
+		node, err := predicate.Parse(cmd.Where)
+		if err != nil {
+			return fmt.Errorf("invalid where predicate: %v", err)
+		}
+		if cmd.where, err = predicate.New(node); err != nil {
+			return fmt.Errorf("invalid where predicate: %v", err)
+		}
+	}
+
+	if cmd.Sanitize {
+		cmd.sanitized = &sanitizedKeys{}
+		defer func() { cmd.sanitized = nil }()
+	}
+
+	if cmd.BackupDir != "" && !cmd.DryRun {
+		b, err := openBackup(cmd.BackupDir)
+		if err != nil {
+			return fmt.Errorf("unable to open backup directory: %v", err)
+		}
+		defer b.Close()
+		cmd.backup = b
+	}
+
+	var (
+		total  Stats
+		report = Report{DryRun: cmd.DryRun, Files: []FileReport{}}
+		start  = time.Now()
+	)
+
+	if cmd.Checkpoint != "" && !cmd.DryRun {
+		c, err := openCheckpoint(cmd.Checkpoint, cmd.Resume)
+		if err != nil {
+			return fmt.Errorf("unable to open checkpoint file: %v", err)
+		}
+		cmd.check = c
+		defer func() { cmd.check = nil }()
+
+		files := cmd.files[:0]
+		for _, path := range cmd.files {
+			if !c.completed(path) {
+				files = append(files, path)
+				continue
+			}
+			if cmd.Format != JSONFormat {
+				fmt.Fprintf(cmd.Stdout, "%s: already processed\n", path)
+			}
+			report.Skipped = append(report.Skipped, path)
+		}
+		cmd.files = files
+	}
+	d := cmd.newDeleter()
+	d.OnFile = func(path string, stats Stats, duration time.Duration) error {
+		if cmd.check != nil {
+			if err := cmd.check.add(path); err != nil {
+				return err
+			}
+		}
+		if cmd.Format != JSONFormat {
+			cmd.printStats(path, stats)
+		}
+		total.add(stats)
+		report.Files = append(report.Files, newFileReport(path, stats, duration))
+		return nil
+	}
+	if cmd.Concurrency > 1 {
+		stderr := cmd.Stderr
+		cmd.Stderr = &lockedWriter{w: stderr}
+		defer func() { cmd.Stderr = stderr }()
+	}
+	err = d.DeleteFiles(cmd.files)
+	if err == nil && len(cmd.files) > 1 && cmd.Format != JSONFormat {
+		cmd.printStats("total", total)
+	}
+
+	if err == nil && cmd.WALDir != "" {
+		err = cmd.processWAL(d, func(path string, stats WALStats) {
+			if cmd.Format != JSONFormat {
+				cmd.printWALStats(path, stats)
+			}
+			report.Segments = append(report.Segments, newSegmentReport(path, stats))
+		})
+	}
+
+	if cmd.sanitized != nil {
+		report.Sanitized = cmd.sanitized.sorted(cmd.files)
+		if cmd.Format != JSONFormat {
+			cmd.printSanitized(report.Sanitized)
+		}
+	}
+
+	if err == nil && cmd.deleted != nil {
+		report.SeriesDropped, err = cmd.dropSeries(dirs)
+		if err != nil {
+			err = fmt.Errorf("unable to update index: %v", err)
+		} else if cmd.Format != JSONFormat {
+			fmt.Fprintf(cmd.Stdout, "index: dropped %d series\n", report.SeriesDropped)
+		}
+	}
+
+	var unmatched []string
+	if err == nil && cmd.series != nil {
+		unmatched = cmd.series.unmatched()
+		report.Unmatched = unmatched
+		err = cmd.writeUnmatched(unmatched)
+	}
+
+	if cmd.check != nil {
+		if cerr := cmd.check.Close(err == nil); err == nil {
+			err = cerr
+		}
+	}
+	if err == nil && len(unmatched) > 0 && len(unmatched) == len(cmd.series.entries) {
+		err = ErrNothingMatched
+	}
+
+	if cmd.Format == JSONFormat || cmd.ReportPath != "" {
+		report.Total = newFileReport("", total, time.Since(start))
+		if err != nil {
+			report.Error = err.Error()
+		}
+		if rerr := cmd.writeReport(&report); err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
+// newDeleter returns the Deleter of the series selected by the options of
+// the command.
+func (cmd *Command) newDeleter() *Deleter {
+	d := &Deleter{
+		OrgID:        cmd.OrgID,
+		BucketID:     cmd.BucketID,
+		Measurements: cmd.Measurements,
+		Field:        cmd.Field,
+		Start:        cmd.Start,
+		End:          cmd.End,
+		DryRun:       cmd.DryRun,
+		Tombstone:    cmd.Tombstone,
+		Verify:       cmd.Verify,
+		Predicate:    cmd.where,
+		Concurrency:  cmd.Concurrency,
+	}
+	drop := cmd.Sanitize && cmd.SanitizeMode != SanitizeEscape
+	if series := cmd.series; series != nil || drop {
+		d.Match = func(seriesKey, field []byte) bool {
+			if series != nil && series.match(seriesKey, field) {
+				return true
+			}
+			return drop && invalidSeries(seriesKey, field)
+		}
+	} else if cmd.Sanitize && len(cmd.Measurements) == 0 {
+		// Only rename keys, deleting none.
+		d.Match = func(seriesKey, field []byte) bool { return false }
+	}
+	if cmd.Sanitize && !drop {
+		d.Rename = escapeKey
+		d.OnKeyRenamed = func(path string, key, newKey []byte) {
+			cmd.sanitized.add(path, key, newKey)
+			if cmd.Verbose {
+				fmt.Fprintf(cmd.Stderr, "escaping key: %q as %q\n", key, newKey)
+			}
+		}
+	}
+	if cmd.backup != nil {
+		d.BeforeReplace = cmd.backup.save
+	}
+
+	d.OnKeyDeleted = func(path string, key []byte) {
+		cmd.recordDeleted(key)
+		if drop && invalidKey(key) {
+			cmd.sanitized.add(path, key, nil)
+		}
+		if !cmd.Verbose {
+			return
+		}
+		if filepath.Ext(path) == "."+wal.WALFileExtension {
+			fmt.Fprintf(cmd.Stderr, "deleting wal values: %q\n", key)
+		} else if cmd.Tombstone {
+			fmt.Fprintf(cmd.Stderr, "tombstoning key: %q\n", key)
+		}
+	}
+	if cmd.Verbose {
+		d.OnBlock = func(path string, b Block) {
+			if b.Trimmed {
+				fmt.Fprintf(cmd.Stderr, "trimming block: %q (%s-%s) deleted %d value(s)\n",
+					b.Key, formatTime(b.MinTime), formatTime(b.MaxTime), b.Values)
+				return
+			}
+			fmt.Fprintf(cmd.Stderr, "deleting block: %q (%s-%s) sz=%d\n",
+				b.Key, formatTime(b.MinTime), formatTime(b.MaxTime), b.Size)
+		}
+	}
+	return d
+}
+
+// restore puts back the files saved to BackupDir.
+func (cmd *Command) restore() error {
+	if cmd.BackupDir == "" {
+		return errors.New("restore requires a backup directory")
+	}
+
+	paths, err := restore(cmd.BackupDir)
+	for _, path := range paths {
+		fmt.Fprintf(cmd.Stdout, "%s: restored\n", path)
+	}
+	return err
+}
+
+// Stats summarizes the blocks deleted from one or more TSM files.
+type Stats struct {
+	Blocks  int   // number of deleted blocks
+	Trimmed int   // number of blocks with some of their values deleted
+	Series  int   // number of series with at least one deleted value
+	Renamed int   // number of series renamed
+	Bytes   int64 // size of the deleted blocks, and size reduction of the trimmed ones
+	// MinTime and MaxTime are the time range covered by the deleted values.
+	MinTime int64
+	MaxTime int64
+}
+
+// Empty returns true if nothing was deleted or renamed.
+func (s *Stats) Empty() bool {
+	return !s.deleted() && s.Renamed == 0
+}
+
+// deleted returns true if some values were deleted.
+func (s *Stats) deleted() bool {
+	return s.Blocks > 0 || s.Trimmed > 0
+}
+
+func (s *Stats) addRange(minTime, maxTime int64) {
+	if !s.deleted() || minTime < s.MinTime {
+		s.MinTime = minTime
+	}
+	if !s.deleted() || maxTime > s.MaxTime {
+		s.MaxTime = maxTime
+	}
+}
+
+func (s *Stats) addBlock(minTime, maxTime int64, size int) {
+	s.addRange(minTime, maxTime)
+	s.Blocks++
+	s.Bytes += int64(size)
+}
+
+func (s *Stats) addTrimmed(minTime, maxTime int64, size int) {
+	s.addRange(minTime, maxTime)
+	s.Trimmed++
+	s.Bytes += int64(size)
+}
+
+func (s *Stats) add(o Stats) {
+	s.Renamed += o.Renamed
+	if !o.deleted() {
+		return
+	}
+	s.addRange(o.MinTime, o.MaxTime)
+	s.Blocks += o.Blocks
+	s.Trimmed += o.Trimmed
+	s.Series += o.Series
+	s.Bytes += o.Bytes
+}
+
+func (cmd *Command) printStats(name string, s Stats) {
+	verb := "deleted"
+	if cmd.DryRun {
+		verb = "would delete"
+	}
+
+	if s.Empty() {
+		fmt.Fprintf(cmd.Stdout, "%s: nothing to delete\n", name)
+		return
+	}
+	if s.Renamed > 0 {
+		verb := "renamed"
+		if cmd.DryRun {
+			verb = "would rename"
+		}
+		fmt.Fprintf(cmd.Stdout, "%s: %s %d series\n", name, verb, s.Renamed)
+		if !s.deleted() {
+			return
+		}
+	}
+
+	trimmed := ""
+	if s.Trimmed > 0 {
+		trimmed = fmt.Sprintf(" and trimmed %d block(s)", s.Trimmed)
+		if cmd.DryRun {
+			trimmed = fmt.Sprintf(" and would trim %d block(s)", s.Trimmed)
+		}
+	}
+	fmt.Fprintf(cmd.Stdout, "%s: %s %d block(s)%s of %d series, %d bytes, from %s to %s\n",
+		name, verb, s.Blocks, trimmed, s.Series, s.Bytes, formatTime(s.MinTime), formatTime(s.MaxTime))
+}
+
+// findFiles returns the TSM files of Paths, searching directories
+// recursively.
+func (cmd *Command) findFiles() ([]string, error) {
+	var backupDir string
+	if cmd.BackupDir != "" {
+		dir, err := filepath.Abs(cmd.BackupDir)
+		if err != nil {
+			return nil, err
+		}
+		backupDir = dir
+	}
+
+	paths, err := tsmfile.Find(cmd.Paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, path := range paths {
+		// Do not process the copies of the files saved by a previous run.
+		if backupDir != "" {
+			if abs, err := filepath.Abs(path); err == nil && strings.HasPrefix(abs, backupDir+string(filepath.Separator)) {
+				continue
+			}
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// lockedWriter serializes the writes of concurrent workers.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func renameIfExists(oldpath, newpath string) error {
+	if err := os.Rename(oldpath, newpath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func formatTime(t int64) string {
+	return time.Unix(0, t).UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,1117 @@
+package deletetsm_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/buildtsi"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/deletetsm"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile/tsmtest"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsi1"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+	"go.uber.org/zap"
+)
+
+var (
+	orgID    = influxdb.ID(0x1000)
+	bucketID = influxdb.ID(0x2000)
+)
+
+func TestCommand_Measurement(t *testing.T) {
+	dir, path := writeTSMFile(t, map[string][]int64{
+		seriesKey("cpu", "host", "a"):  {10, 20},
+		seriesKey("cpu", "host", "b"):  {30},
+		seriesKey("mem", "host", "a"):  {40},
+		seriesKey("disk", "host", "a"): {50},
+	})
+	defer os.RemoveAll(dir)
+
+	var stdout bytes.Buffer
+	cmd := &deletetsm.Command{Stdout: &stdout, Stderr: ioutil.Discard, Paths: []string{path}, Measurements: []string{"cpu"}}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := stdout.String(), path+": deleted 3 block(s) of 2 series"; !strings.HasPrefix(got, want) {
+		t.Fatalf("unexpected output: got %q, want prefix %q", got, want)
+	}
+	if got, want := tsmtest.ReadKeys(t, path), []string{seriesKey("disk", "host", "a"), seriesKey("mem", "host", "a")}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected keys: got %q, want %q", got, want)
+	}
+}
+
+func TestCommand_Measurements(t *testing.T) {
+	dir, path := writeTSMFile(t, map[string][]int64{
+		seriesKey("cpu", "host", "a"):  {10, 20},
+		seriesKey("mem", "host", "a"):  {40},
+		seriesKey("disk", "host", "a"): {50},
+	})
+	defer os.RemoveAll(dir)
+
+	var stdout bytes.Buffer
+	cmd := &deletetsm.Command{Stdout: &stdout, Stderr: ioutil.Discard, Paths: []string{path}, Measurements: []string{"disk", "cpu", "disk"}}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := stdout.String(), path+": deleted 3 block(s) of 2 series"; !strings.HasPrefix(got, want) {
+		t.Fatalf("unexpected output: got %q, want prefix %q", got, want)
+	}
+	if got, want := tsmtest.ReadKeys(t, path), []string{seriesKey("mem", "host", "a")}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected keys: got %q, want %q", got, want)
+	}
+}
+
+func TestCommand_DryRun(t *testing.T) {
+	dir, path := writeTSMFile(t, map[string][]int64{
+		seriesKey("cpu", "host", "a"): {10, 20},
+		seriesKey("cpu", "host", "b"): {30},
+		seriesKey("mem", "host", "a"): {40},
+	})
+	defer os.RemoveAll(dir)
+
+	before, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	cmd := &deletetsm.Command{Stdout: &stdout, Stderr: ioutil.Discard, Paths: []string{path}, Measurements: []string{"cpu"}, DryRun: true}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := stdout.String(), path+": would delete 3 block(s) of 2 series"; !strings.HasPrefix(got, want) {
+		t.Fatalf("unexpected output: got %q, want prefix %q", got, want)
+	}
+	if got, want := stdout.String(), "from 1970-01-01T00:00:00.00000001Z to 1970-01-01T00:00:00.00000003Z\n"; !strings.HasSuffix(got, want) {
+		t.Fatalf("unexpected time range: got %q, want suffix %q", got, want)
+	}
+
+	after, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Fatal("dry run modified the TSM file")
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(files) > 0 {
+		t.Fatalf("dry run left temporary files: %v", files)
+	}
+}
+
+func TestCommand_Sanitize(t *testing.T) {
+	dir, path := writeTSMFile(t, map[string][]int64{
+		seriesKey("cpu", "host", "a"):        {10},
+		seriesKey("cpu", "host", "bad\x01"):  {20},
+		seriesKey("cpu", "host", "\xffbad"):  {30},
+		seriesKey("mem", "host\x7f", "okay"): {40},
+	})
+	defer os.RemoveAll(dir)
+
+	cmd := &deletetsm.Command{Stdout: ioutil.Discard, Stderr: ioutil.Discard, Paths: []string{path}, Sanitize: true}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := tsmtest.ReadKeys(t, path), []string{seriesKey("cpu", "host", "a")}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected keys: got %q, want %q", got, want)
+	}
+}
+
+func TestCommand_Sanitize_Escape(t *testing.T) {
+	dir, path := writeTSMFile(t, map[string][]int64{
+		seriesKey("cpu", "host", "a"):        {10},
+		seriesKey("cpu", "host", "bad\x01"):  {20},
+		seriesKey("cpu", "host", "\xffbad"):  {30},
+		seriesKey("mem", "host\x7f", "okay"): {40},
+	})
+	defer os.RemoveAll(dir)
+
+	var stdout bytes.Buffer
+	cmd := &deletetsm.Command{
+		Stdout:       &stdout,
+		Stderr:       ioutil.Discard,
+		Paths:        []string{path},
+		Sanitize:     true,
+		SanitizeMode: deletetsm.SanitizeEscape,
+		Verify:       true,
+		Format:       deletetsm.JSONFormat,
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		seriesKey("cpu", "host", "a"),
+		seriesKey("cpu", "host", `bad\u0001`),
+		seriesKey("cpu", "host", `\xffbad`),
+		seriesKey("mem", `host\u007f`, "okay"),
+	}
+	sort.Strings(want)
+	if got := tsmtest.ReadKeys(t, path); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected keys: got %q, want %q", got, want)
+	}
+	if got := tsmtest.ReadTimestamps(t, path, seriesKey("cpu", "host", `bad\u0001`)); !reflect.DeepEqual(got, []int64{20}) {
+		t.Fatalf("unexpected timestamps of escaped key: %v", got)
+	}
+
+	var report deletetsm.Report
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(report.Sanitized); got != 3 {
+		t.Fatalf("unexpected number of sanitized keys: %d", got)
+	}
+	for _, k := range report.Sanitized {
+		if k.Path != path || k.NewKey == "" {
+			t.Fatalf("unexpected sanitized key: %+v", k)
+		}
+	}
+	if got := report.Total.SeriesRenamed; got != 3 {
+		t.Fatalf("unexpected number of renamed series: %d", got)
+	}
+
+	// The escaped keys are valid, so a second run has nothing to do.
+	stdout.Reset()
+	cmd.Format = deletetsm.TextFormat
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	} else if got, want := stdout.String(), path+": nothing to delete\n"; got != want {
+		t.Fatalf("unexpected output: got %q, want %q", got, want)
+	}
+}
+
+func TestCommand_Sanitize_Report(t *testing.T) {
+	bad := seriesKey("cpu", "host", "bad\x01")
+	dir, path := writeTSMFile(t, map[string][]int64{
+		seriesKey("cpu", "host", "a"): {10},
+		bad:                           {20},
+	})
+	defer os.RemoveAll(dir)
+
+	var stdout bytes.Buffer
+	cmd := &deletetsm.Command{Stdout: &stdout, Stderr: ioutil.Discard, Paths: []string{path}, Sanitize: true, DryRun: true}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("sanitize: 1 invalid key(s)\n  %s: would drop %q\n", path, bad); !strings.HasSuffix(stdout.String(), want) {
+		t.Fatalf("unexpected output: got %q, want suffix %q", stdout.String(), want)
+	}
+}
+
+func TestCommand_AllBlocksDeleted(t *testing.T) {
+	dir, path := writeTSMFile(t, map[string][]int64{
+		seriesKey("cpu", "host", "a"): {10},
+	})
+	defer os.RemoveAll(dir)
+
+	cmd := &deletetsm.Command{Stdout: ioutil.Discard, Stderr: ioutil.Discard, Paths: []string{path}, Measurements: []string{"cpu"}}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if files, _ := filepath.Glob(filepath.Join(dir, "*")); len(files) > 0 {
+		t.Fatalf("expected all files to be removed, got %v", files)
+	}
+}
+
+func TestCommand_Bucket(t *testing.T) {
+	other := tsdb.EncodeName(orgID, bucketID+1)
+	otherKey := string(models.MakeKey(other[:], models.NewTags(map[string]string{
+		models.MeasurementTagKey: "cpu",
+		models.FieldKeyTagKey:    "value",
+	}))) + "#!~#value"
+
+	dir, path := writeTSMFile(t, map[string][]int64{
+		seriesKey("cpu", "host", "a"): {10},
+		otherKey:                      {20},
+	})
+	defer os.RemoveAll(dir)
+
+	cmd := &deletetsm.Command{
+		Stdout:       ioutil.Discard,
+		Stderr:       ioutil.Discard,
+		Paths:        []string{path},
+		OrgID:        orgID,
+		BucketID:     bucketID,
+		Measurements: []string{"cpu"},
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := tsmtest.ReadKeys(t, path), []string{otherKey}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected keys: got %q, want %q", got, want)
+	}
+}
+
+func TestCommand_SeriesFile(t *testing.T) {
+	dir, path := writeTSMFile(t, map[string][]int64{
+		seriesKey("cpu", "host", "web-1"):  {10, 20},
+		seriesKey("cpu", "host", "web-22"): {30},
+		seriesKey("cpu", "host", "web-x"):  {40},
+		seriesKey("cpu", "host", "db-1"):   {50},
+		seriesKey("disk", "host", "db-1"):  {60},
+		seriesKey("mem", "host", "a"):      {70},
+		seriesKey("mem", "host", "b"):      {80},
+	})
+	defer os.RemoveAll(dir)
+
+	seriesFile := filepath.Join(dir, "series.txt")
+	if err := ioutil.WriteFile(seriesFile, []byte(`
+# delete the numbered web hosts
+regex:^cpu,host=web-\d+$
+glob:disk,host=db-*
+mem,host=b
+`), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	cmd := &deletetsm.Command{Stdout: &stdout, Stderr: ioutil.Discard, Paths: []string{path}, SeriesFile: seriesFile}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := stdout.String(), path+": deleted 5 block(s) of 4 series"; !strings.HasPrefix(got, want) {
+		t.Fatalf("unexpected output: got %q, want prefix %q", got, want)
+	}
+	want := []string{seriesKey("cpu", "host", "db-1"), seriesKey("cpu", "host", "web-x"), seriesKey("mem", "host", "a")}
+	sort.Strings(want)
+	if got := tsmtest.ReadKeys(t, path); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected keys: got %q, want %q", got, want)
+	}
+}
+
+func TestCommand_SeriesFile_StdinGzip(t *testing.T) {
+	dir, path := writeTSMFile(t, map[string][]int64{
+		seriesKey("cpu", "host", "a"): {10},
+		seriesKey("cpu", "host", "b"): {20},
+		seriesKey("cpu", "host", "c"): {30},
+	})
+	defer os.RemoveAll(dir)
+
+	// The series are read from stdin.
+	cmd := &deletetsm.Command{
+		Stdin:      strings.NewReader("cpu,host=a\n"),
+		Stdout:     ioutil.Discard,
+		Stderr:     ioutil.Discard,
+		Paths:      []string{path},
+		SeriesFile: "-",
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Gzip compressed series are decompressed, whether read from a file or stdin.
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte("cpu,host=b\n")); err != nil {
+		t.Fatal(err)
+	} else if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	seriesFile := filepath.Join(dir, "series.txt.gz")
+	if err := ioutil.WriteFile(seriesFile, buf.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+	cmd = &deletetsm.Command{Stdout: ioutil.Discard, Stderr: ioutil.Discard, Paths: []string{path}, SeriesFile: seriesFile}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := tsmtest.ReadKeys(t, path), []string{seriesKey("cpu", "host", "c")}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected keys: got %q, want %q", got, want)
+	}
+}
+
+func TestCommand_SeriesFile_InvalidPattern(t *testing.T) {
+	dir, path := writeTSMFile(t, map[string][]int64{
+		seriesKey("cpu", "host", "a"): {10},
+	})
+	defer os.RemoveAll(dir)
+
+	seriesFile := filepath.Join(dir, "series.txt")
+	if err := ioutil.WriteFile(seriesFile, []byte("regex:cpu,host=(\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &deletetsm.Command{Stdout: ioutil.Discard, Stderr: ioutil.Discard, Paths: []string{path}, SeriesFile: seriesFile}
+	if err := cmd.Run(); err == nil || !strings.Contains(err.Error(), "series.txt:1:") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCommand_SeriesFile_Unmatched(t *testing.T) {
+	dir, path := writeTSMFile(t, map[string][]int64{
+		seriesKey("cpu", "host", "a"): {10},
+		seriesKey("cpu", "host", "b"): {20},
+	})
+	defer os.RemoveAll(dir)
+
+	seriesFile := filepath.Join(dir, "series.txt")
+	if err := ioutil.WriteFile(seriesFile, []byte("cpu,host=a\ncpu,host=typo\nglob:mem,*\nglob:cpu,*\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	unmatchedPath := filepath.Join(dir, "unmatched.txt")
+	var stdout bytes.Buffer
+	cmd := &deletetsm.Command{Stdout: &stdout, Stderr: ioutil.Discard, Paths: []string{path}, SeriesFile: seriesFile, UnmatchedPath: unmatchedPath, DryRun: true}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "series file: 2 of 4 key(s) and pattern(s) matched no series\n"; !strings.HasSuffix(got, want) {
+		t.Fatalf("unexpected output: got %q, want suffix %q", got, want)
+	}
+	if got, err := ioutil.ReadFile(unmatchedPath); err != nil {
+		t.Fatal(err)
+	} else if want := "cpu,host=typo\nglob:mem,*\n"; string(got) != want {
+		t.Fatalf("unexpected unmatched series: got %q, want %q", got, want)
+	}
+
+	// Nothing matched, so nothing is deleted and a distinct error is returned.
+	if err := ioutil.WriteFile(seriesFile, []byte("cpu,host=typo\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	cmd = &deletetsm.Command{Stdout: &stdout, Stderr: ioutil.Discard, Paths: []string{path}, SeriesFile: seriesFile}
+	if err := cmd.Run(); err != deletetsm.ErrNothingMatched {
+		t.Fatalf("unexpected error: got %v, want %v", err, deletetsm.ErrNothingMatched)
+	}
+	if got, want := stdout.String(), "  cpu,host=typo\n"; !strings.HasSuffix(got, want) {
+		t.Fatalf("unexpected output: got %q, want suffix %q", got, want)
+	}
+	if got := tsmtest.ReadKeys(t, path); len(got) != 2 {
+		t.Fatalf("unexpected keys: %q", got)
+	}
+}
+
+func TestDeleter(t *testing.T) {
+	cpuA, cpuB, memA := seriesKey("cpu", "host", "a"), seriesKey("cpu", "host", "b"), seriesKey("mem", "host", "a")
+	dir, path := writeTSMFileBlocks(t, map[string][][]int64{
+		cpuA: {{10, 20}, {30}},
+		cpuB: {{10}},
+		memA: {{10}},
+	})
+	defer os.RemoveAll(dir)
+
+	var (
+		replaced []string
+		deleted  []string
+		blocks   []deletetsm.Block
+		files    []string
+	)
+	d := &deletetsm.Deleter{
+		Match: func(seriesKey, field []byte) bool {
+			return string(seriesKey) == "cpu,host=a"
+		},
+		End:           time.Unix(0, 10),
+		BeforeReplace: func(path string) error { replaced = append(replaced, path); return nil },
+		OnKeyDeleted:  func(path string, key []byte) { deleted = append(deleted, string(key)) },
+		OnBlock:       func(path string, b deletetsm.Block) { blocks = append(blocks, b) },
+		OnFile: func(path string, stats deletetsm.Stats, d time.Duration) error {
+			files = append(files, path)
+			if stats.Series != 1 || stats.Trimmed != 1 {
+				t.Errorf("unexpected stats: %+v", stats)
+			}
+			return nil
+		},
+	}
+	if err := d.DeleteFiles([]string{path}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(replaced, []string{path}) || !reflect.DeepEqual(files, []string{path}) {
+		t.Fatalf("unexpected files: replaced %q, reported %q", replaced, files)
+	}
+	if !reflect.DeepEqual(deleted, []string{cpuA}) {
+		t.Fatalf("unexpected keys deleted: %q", deleted)
+	}
+	if len(blocks) != 1 || !blocks[0].Trimmed || blocks[0].Values != 1 || blocks[0].MinTime != 10 {
+		t.Fatalf("unexpected blocks: %+v", blocks)
+	}
+	if got, want := tsmtest.ReadTimestamps(t, path, cpuA), []int64{20, 30}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected timestamps: got %v, want %v", got, want)
+	}
+
+	// An error of OnFile stops processing.
+	d = &deletetsm.Deleter{
+		Measurements: []string{"mem"},
+		OnFile: func(path string, stats deletetsm.Stats, d time.Duration) error {
+			return errors.New("stop")
+		},
+	}
+	if err := d.DeleteFiles([]string{path}); err == nil || err.Error() != path+": stop" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestDeleter_Selection(t *testing.T) {
+	cpuA, memA := seriesKey("cpu", "host", "a"), seriesKey("mem", "host", "a")
+	dir, path := writeTSMFile(t, map[string][]int64{cpuA: {10}, memA: {20}})
+	defer os.RemoveAll(dir)
+
+	// Nothing is deleted without a selection of series, nor with both all
+	// series and a selection.
+	for _, d := range []*deletetsm.Deleter{
+		{End: time.Unix(0, 20)},
+		{All: true, Measurements: []string{"cpu"}},
+	} {
+		if err := d.DeleteFiles([]string{path}); err == nil {
+			t.Fatal("expected an error of DeleteFiles")
+		}
+		if _, err := d.DeleteFile(path); err == nil {
+			t.Fatal("expected an error of DeleteFile")
+		}
+		if _, err := d.DeleteSegment(filepath.Join(dir, "_00001.wal")); err == nil {
+			t.Fatal("expected an error of DeleteSegment")
+		}
+	}
+	if got, want := tsmtest.ReadKeys(t, path), []string{cpuA, memA}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected keys: got %q, want %q", got, want)
+	}
+
+	d := &deletetsm.Deleter{All: true, End: time.Unix(0, 10)}
+	if err := d.DeleteFiles([]string{path}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tsmtest.ReadKeys(t, path), []string{memA}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected keys: got %q, want %q", got, want)
+	}
+}
+
+func TestCommand_Field(t *testing.T) {
+	usageA := seriesKeyField("cpu", "usage", map[string]string{"host": "a"})
+	valueA := seriesKeyField("cpu", "value", map[string]string{"host": "a"})
+	usageB := seriesKeyField("cpu", "usage", map[string]string{"host": "b"})
+	valueB := seriesKeyField("cpu", "value", map[string]string{"host": "b"})
+	dir, path := writeTSMFile(t, map[string][]int64{
+		usageA: {10},
+		valueA: {20},
+		usageB: {30},
+		valueB: {40},
+	})
+	defer os.RemoveAll(dir)
+
+	cmd := &deletetsm.Command{Stdout: ioutil.Discard, Stderr: ioutil.Discard, Paths: []string{path}, Measurements: []string{"cpu"}, Field: "usage"}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{valueA, valueB}
+	sort.Strings(want)
+	if got := tsmtest.ReadKeys(t, path); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected keys: got %q, want %q", got, want)
+	}
+
+	// A field of a single series may be listed in the series file.
+	seriesFile := filepath.Join(dir, "series.txt")
+	if err := ioutil.WriteFile(seriesFile, []byte("cpu,host=b#!~#value\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	cmd = &deletetsm.Command{Stdout: ioutil.Discard, Stderr: ioutil.Discard, Paths: []string{path}, SeriesFile: seriesFile}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tsmtest.ReadKeys(t, path), []string{valueA}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected keys: got %q, want %q", got, want)
+	}
+}
+
+func TestCommand_Where(t *testing.T) {
+	webEast := seriesKeyTags("cpu", map[string]string{"host": "web01", "region": "us-east"})
+	webWest := seriesKeyTags("cpu", map[string]string{"host": "web01", "region": "us-west"})
+	dbEast := seriesKeyTags("cpu", map[string]string{"host": "db01", "region": "us-east"})
+	memEast := seriesKeyTags("mem", map[string]string{"host": "web01", "region": "us-east"})
+	dir, path := writeTSMFile(t, map[string][]int64{
+		webEast: {10},
+		webWest: {20},
+		dbEast:  {30},
+		memEast: {40},
+	})
+	defer os.RemoveAll(dir)
+
+	cmd := &deletetsm.Command{
+		Stdout:       ioutil.Discard,
+		Stderr:       ioutil.Discard,
+		Paths:        []string{path},
+		Measurements: []string{"cpu"},
+		Where:        "host=web01 AND region=us-east",
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{webWest, dbEast, memEast}
+	sort.Strings(want)
+	if got := tsmtest.ReadKeys(t, path); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected keys: got %q, want %q", got, want)
+	}
+
+	// Without a measurement, the series of every measurement matching the predicate are deleted.
+	cmd = &deletetsm.Command{Stdout: ioutil.Discard, Stderr: ioutil.Discard, Paths: []string{path}, Where: "region=us-east"}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tsmtest.ReadKeys(t, path), []string{webWest}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected keys: got %q, want %q", got, want)
+	}
+}
+
+func TestCommand_Where_Invalid(t *testing.T) {
+	dir, path := writeTSMFile(t, map[string][]int64{
+		seriesKey("cpu", "host", "a"): {10},
+	})
+	defer os.RemoveAll(dir)
+
+	cmd := &deletetsm.Command{Stdout: ioutil.Discard, Stderr: ioutil.Discard, Paths: []string{path}, Where: "host=a OR host=b"}
+	if err := cmd.Run(); err == nil || !strings.Contains(err.Error(), "invalid where predicate") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCommand_TimeRange(t *testing.T) {
+	dir, path := writeTSMFileBlocks(t, map[string][][]int64{
+		seriesKey("cpu", "host", "a"): {{10, 20, 30}, {40, 50}},
+		seriesKey("cpu", "host", "b"): {{15}},
+		seriesKey("cpu", "host", "c"): {{25, 35}},
+		seriesKey("cpu", "host", "d"): {{10, 50}},
+		seriesKey("mem", "host", "a"): {{20}},
+	})
+	defer os.RemoveAll(dir)
+
+	var stdout bytes.Buffer
+	cmd := &deletetsm.Command{
+		Stdout:       &stdout,
+		Stderr:       ioutil.Discard,
+		Paths:        []string{path},
+		Measurements: []string{"cpu"},
+		Start:        time.Unix(0, 20),
+		End:          time.Unix(0, 40),
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := stdout.String(), path+": deleted 1 block(s) and trimmed 2 block(s) of 2 series"; !strings.HasPrefix(got, want) {
+		t.Fatalf("unexpected output: got %q, want prefix %q", got, want)
+	}
+	if got, want := stdout.String(), "from 1970-01-01T00:00:00.00000002Z to 1970-01-01T00:00:00.00000004Z\n"; !strings.HasSuffix(got, want) {
+		t.Fatalf("unexpected time range: got %q, want suffix %q", got, want)
+	}
+
+	for key, want := range map[string][]int64{
+		seriesKey("cpu", "host", "a"): {10, 50},
+		seriesKey("cpu", "host", "b"): {15},
+		seriesKey("cpu", "host", "c"): nil,
+		seriesKey("cpu", "host", "d"): {10, 50},
+		seriesKey("mem", "host", "a"): {20},
+	} {
+		if got := tsmtest.ReadTimestamps(t, path, key); !reflect.DeepEqual(got, want) {
+			t.Fatalf("unexpected timestamps of %q: got %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestCommand_Verify(t *testing.T) {
+	dir, path := writeTSMFileBlocks(t, map[string][][]int64{
+		seriesKey("cpu", "host", "a"): {{10, 20, 30}, {40}},
+		seriesKey("cpu", "host", "b"): {{25}},
+		seriesKey("mem", "host", "a"): {{10}, {20, 30}},
+	})
+	defer os.RemoveAll(dir)
+
+	cmd := &deletetsm.Command{
+		Stdout:       ioutil.Discard,
+		Stderr:       ioutil.Discard,
+		Paths:        []string{path},
+		Measurements: []string{"cpu"},
+		Start:        time.Unix(0, 20),
+		End:          time.Unix(0, 30),
+		Verify:       true,
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tsmtest.ReadKeys(t, path), []string{seriesKey("cpu", "host", "a"), seriesKey("mem", "host", "a")}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected keys: got %q, want %q", got, want)
+	}
+	if got, want := tsmtest.ReadTimestamps(t, path, seriesKey("cpu", "host", "a")), []int64{10, 40}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected timestamps: got %v, want %v", got, want)
+	}
+	if got, want := tsmtest.ReadTimestamps(t, path, seriesKey("mem", "host", "a")), []int64{10, 20, 30}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected timestamps: got %v, want %v", got, want)
+	}
+
+	cmd.Tombstone = true
+	if err := cmd.Run(); err == nil || !strings.HasPrefix(err.Error(), "verify requires rewriting files") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCommand_Tombstone(t *testing.T) {
+	dir, path := writeTSMFileBlocks(t, map[string][][]int64{
+		seriesKey("cpu", "host", "a"):  {{10, 20, 30}, {40, 50}},
+		seriesKey("cpu", "host", "b"):  {{15}},
+		seriesKey("cpu", "host", "c"):  {{25, 35}},
+		seriesKey("disk", "host", "a"): {{60}},
+		seriesKey("mem", "host", "a"):  {{20}},
+	})
+	defer os.RemoveAll(dir)
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	cmd := &deletetsm.Command{
+		Stdout:       &stdout,
+		Stderr:       ioutil.Discard,
+		Paths:        []string{path},
+		Measurements: []string{"cpu"},
+		Start:        time.Unix(0, 20),
+		End:          time.Unix(0, 40),
+		Tombstone:    true,
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := stdout.String(), path+": deleted 1 block(s) and trimmed 2 block(s) of 2 series"; !strings.HasPrefix(got, want) {
+		t.Fatalf("unexpected output: got %q, want prefix %q", got, want)
+	}
+
+	// The TSM file is left as is, and the values are deleted by tombstones.
+	if after, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if after.Size() != fi.Size() || after.ModTime() != fi.ModTime() {
+		t.Fatal("unexpected rewrite of TSM file")
+	}
+	for key, want := range map[string][]int64{
+		seriesKey("cpu", "host", "a"): {10, 50},
+		seriesKey("cpu", "host", "b"): {15},
+		seriesKey("cpu", "host", "c"): nil,
+		seriesKey("mem", "host", "a"): {20},
+	} {
+		if got := tsmtest.ReadTimestamps(t, path, key); !reflect.DeepEqual(got, want) {
+			t.Fatalf("unexpected timestamps of %q: got %v, want %v", key, got, want)
+		}
+	}
+
+	// Deleting measurements of a bucket only records one tombstone for each.
+	cmd = &deletetsm.Command{Stdout: ioutil.Discard, Stderr: ioutil.Discard, Paths: []string{path}, OrgID: orgID, BucketID: bucketID, Measurements: []string{"cpu", "disk"}, Tombstone: true}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tsmtest.ReadKeys(t, path), []string{seriesKey("mem", "host", "a")}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected keys: got %q, want %q", got, want)
+	}
+
+	var measurements []string
+	if err := tsm1.NewTombstoner(path, nil).Walk(func(ts tsm1.Tombstone) error {
+		if len(ts.Measurement) > 0 {
+			measurements = append(measurements, string(ts.Measurement))
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"cpu", "disk"}; !reflect.DeepEqual(measurements, want) {
+		t.Fatalf("unexpected measurement tombstones: got %q, want %q", measurements, want)
+	}
+}
+
+func TestCommand_Concurrency(t *testing.T) {
+	var paths []string
+	for i := 0; i < 8; i++ {
+		data := map[string][]int64{seriesKey("mem", "host", "a"): {1}}
+		for j := 0; j < i; j++ {
+			data[seriesKey("cpu", "host", fmt.Sprint(j))] = []int64{int64(j)}
+		}
+		dir, path := writeTSMFile(t, data)
+		defer os.RemoveAll(dir)
+		paths = append(paths, path)
+	}
+
+	var stdout bytes.Buffer
+	cmd := &deletetsm.Command{Stdout: &stdout, Stderr: ioutil.Discard, Paths: paths, Measurements: []string{"cpu"}, Concurrency: 4, Verbose: true}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Files are reported in order, as when processed serially.
+	lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+	if len(lines) != len(paths)+1 {
+		t.Fatalf("unexpected output: %q", stdout.String())
+	}
+	if got, want := lines[0], paths[0]+": nothing to delete"; got != want {
+		t.Fatalf("unexpected output: got %q, want %q", got, want)
+	}
+	for i, path := range paths[1:] {
+		if want := fmt.Sprintf("%s: deleted %d block(s) of %d series", path, i+1, i+1); !strings.HasPrefix(lines[i+1], want) {
+			t.Fatalf("unexpected output: got %q, want prefix %q", lines[i+1], want)
+		}
+	}
+	if want := "total: deleted 28 block(s) of 28 series"; !strings.HasPrefix(lines[len(paths)], want) {
+		t.Fatalf("unexpected output: got %q, want prefix %q", lines[len(paths)], want)
+	}
+
+	// Processing stops at the first file that fails.
+	if err := ioutil.WriteFile(paths[2], []byte("not a TSM file"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	if err := cmd.Run(); err == nil || !strings.HasPrefix(err.Error(), paths[2]+":") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := strings.Count(stdout.String(), "\n"), 2; got != want {
+		t.Fatalf("unexpected number of reported files: got %d, want %d", got, want)
+	}
+}
+
+func TestCommand_Resume(t *testing.T) {
+	data := map[string][]int64{
+		seriesKey("cpu", "host", "a"): {10},
+		seriesKey("mem", "host", "a"): {20},
+	}
+	dir1, path1 := writeTSMFile(t, data)
+	defer os.RemoveAll(dir1)
+	dir2, path2 := writeTSMFile(t, data)
+	defer os.RemoveAll(dir2)
+	checkpoint := filepath.Join(dir1, deletetsm.DefaultCheckpointName)
+
+	content, err := ioutil.ReadFile(path2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path2, []byte("not a TSM file"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	// The run is interrupted by the second file, after the first one is processed.
+	var stdout bytes.Buffer
+	cmd := &deletetsm.Command{Stdout: &stdout, Stderr: ioutil.Discard, Paths: []string{path1, path2}, Measurements: []string{"cpu"}, Checkpoint: checkpoint}
+	if err := cmd.Run(); err == nil || !strings.HasPrefix(err.Error(), path2+":") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(checkpoint); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path2, content, 0666); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	cmd.Resume = true
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+	if len(lines) != 2 || lines[0] != path1+": already processed" || !strings.HasPrefix(lines[1], path2+": deleted 1 block(s) of 1 series") {
+		t.Fatalf("unexpected output: %q", stdout.String())
+	}
+	for _, path := range []string{path1, path2} {
+		if got, want := tsmtest.ReadKeys(t, path), []string{seriesKey("mem", "host", "a")}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("unexpected keys: got %q, want %q", got, want)
+		}
+	}
+
+	// The checkpoint is removed once every file is processed.
+	if _, err := os.Stat(checkpoint); !os.IsNotExist(err) {
+		t.Fatalf("unexpected checkpoint file: %v", err)
+	}
+
+	cmd.Checkpoint = ""
+	if err := cmd.Run(); err == nil || err.Error() != "resume requires a checkpoint file" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCommand_Index(t *testing.T) {
+	cpuA, cpuB, memA := seriesKey("cpu", "host", "a"), seriesKey("cpu", "host", "b"), seriesKey("mem", "host", "a")
+	dir, path := writeTSMFile(t, map[string][]int64{cpuA: {10}, cpuB: {20}, memA: {30}})
+	defer os.RemoveAll(dir)
+
+	// The values of cpu,host=b in another file of the directory are not deleted.
+	otherDir, otherPath := writeTSMFile(t, map[string][]int64{cpuB: {40}})
+	defer os.RemoveAll(otherDir)
+	if err := os.Rename(otherPath, filepath.Join(dir, "000000002-000000001."+tsm1.TSMFileExtension)); err != nil {
+		t.Fatal(err)
+	}
+
+	engineDir, err := ioutil.TempDir("", "deletetsm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(engineDir)
+	sfilePath, indexPath := filepath.Join(engineDir, "_series"), filepath.Join(engineDir, "index")
+
+	sfile := mustOpenSeriesFile(t, sfilePath)
+	if err := buildtsi.IndexShard(sfile, indexPath, dir, "", buildtsi.Options{
+		MaxLogFileSize: tsi1.DefaultMaxIndexLogFileSize,
+		MaxCacheSize:   uint64(tsm1.DefaultCacheMaxMemorySize),
+		BatchSize:      1000,
+	}, zap.NewNop()); err != nil {
+		t.Fatal(err)
+	}
+	ids := make(map[string]tsdb.SeriesID)
+	for _, key := range []string{cpuA, cpuB, memA} {
+		seriesKey, _ := tsm1.SeriesAndFieldFromCompositeKey([]byte(key))
+		name, tags := models.ParseKeyBytes(seriesKey)
+		if ids[key] = sfile.SeriesID(name, tags, nil); ids[key].IsZero() {
+			t.Fatalf("series %q not found", key)
+		}
+	}
+	if err := sfile.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	cmd := &deletetsm.Command{
+		Stdout:         &stdout,
+		Stderr:         ioutil.Discard,
+		Paths:          []string{path},
+		Measurements:   []string{"cpu"},
+		SeriesFilePath: sfilePath,
+		IndexPath:      indexPath,
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "index: dropped 1 series\n"; !strings.HasSuffix(got, want) {
+		t.Fatalf("unexpected output: got %q, want suffix %q", got, want)
+	}
+
+	sfile = mustOpenSeriesFile(t, sfilePath)
+	defer sfile.Close()
+	for key, deleted := range map[string]bool{cpuA: true, cpuB: false, memA: false} {
+		if got := sfile.IsDeleted(ids[key]); got != deleted {
+			t.Errorf("unexpected deletion of %q from the series file: got %v, want %v", key, got, deleted)
+		}
+	}
+
+	index := tsi1.NewIndex(sfile, tsi1.NewConfig(), tsi1.WithPath(indexPath))
+	if err := index.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+	name := tsdb.EncodeName(orgID, bucketID)
+	itr, err := index.MeasurementSeriesIDIterator(name[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []tsdb.SeriesID
+	for {
+		elem, err := itr.Next()
+		if err != nil {
+			t.Fatal(err)
+		} else if elem.SeriesID.IsZero() {
+			break
+		}
+		got = append(got, elem.SeriesID)
+	}
+	itr.Close()
+	want := []tsdb.SeriesID{ids[cpuB], ids[memA]}
+	sort.Slice(want, func(i, j int) bool { return want[i].Less(want[j]) })
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected series in the index: got %v, want %v", got, want)
+	}
+
+	cmd.IndexPath = ""
+	if err := cmd.Run(); err == nil || err.Error() != "series file and index paths must be given together" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCommand_Report(t *testing.T) {
+	dir, path := writeTSMFile(t, map[string][]int64{
+		seriesKey("cpu", "host", "a"): {10, 20},
+		seriesKey("cpu", "host", "b"): {30},
+		seriesKey("mem", "host", "a"): {40},
+	})
+	defer os.RemoveAll(dir)
+	missing := filepath.Join(dir, "missing.tsm")
+	reportPath := filepath.Join(dir, "report.json")
+
+	var stdout bytes.Buffer
+	cmd := &deletetsm.Command{
+		Stdout:       &stdout,
+		Stderr:       ioutil.Discard,
+		Paths:        []string{path, missing},
+		Measurements: []string{"cpu"},
+		DryRun:       true,
+		Format:       deletetsm.JSONFormat,
+		ReportPath:   reportPath,
+	}
+	if err := cmd.Run(); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	data, err := ioutil.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != string(data) {
+		t.Fatalf("unexpected output, want the report:\n%s", stdout.String())
+	}
+
+	var report deletetsm.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if !report.DryRun || len(report.Files) != 1 || !strings.HasPrefix(report.Error, missing+":") {
+		t.Fatalf("unexpected report: %s", data)
+	}
+	f := report.Files[0]
+	if f.Path != path || f.SeriesMatched != 2 || f.BlocksDeleted != 3 || f.BytesReclaimed == 0 || f.MinTime.UnixNano() != 10 || f.MaxTime.UnixNano() != 30 {
+		t.Fatalf("unexpected file report: %s", data)
+	}
+	if report.Total.Path != "" || report.Total.BlocksDeleted != 3 {
+		t.Fatalf("unexpected total: %s", data)
+	}
+
+	cmd.Format = "xml"
+	if err := cmd.Run(); err == nil || err.Error() != `unknown format "xml"` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCommand_BackupRestore(t *testing.T) {
+	all := []string{seriesKey("cpu", "host", "a"), seriesKey("disk", "host", "a"), seriesKey("mem", "host", "a")}
+	dir, path := writeTSMFile(t, map[string][]int64{
+		all[0]: {10},
+		all[1]: {20},
+		all[2]: {30},
+	})
+	defer os.RemoveAll(dir)
+	backupDir := filepath.Join(dir, "backup")
+
+	// Delete every series over several runs, the last one removing the file.
+	for _, m := range []string{"cpu", "mem", "disk"} {
+		cmd := &deletetsm.Command{Stdout: ioutil.Discard, Stderr: ioutil.Discard, Paths: []string{path}, Measurements: []string{m}, BackupDir: backupDir}
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected file to be removed: %v", err)
+	}
+
+	// The backup holds the file as it was before the first run.
+	if got := tsmtest.ReadKeys(t, filepath.Join(backupDir, filepath.Base(path))); !reflect.DeepEqual(got, all) {
+		t.Fatalf("unexpected backup keys: got %q, want %q", got, all)
+	}
+
+	var stdout bytes.Buffer
+	cmd := &deletetsm.Command{Stdout: &stdout, Stderr: ioutil.Discard, BackupDir: backupDir, Restore: true}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got := tsmtest.ReadKeys(t, path); !reflect.DeepEqual(got, all) {
+		t.Fatalf("unexpected restored keys: got %q, want %q", got, all)
+	}
+	if abs, _ := filepath.Abs(path); stdout.String() != abs+": restored\n" {
+		t.Fatalf("unexpected output: %q", stdout.String())
+	}
+
+	// A dry run does not back up any file.
+	dryDir := filepath.Join(dir, "dry")
+	cmd = &deletetsm.Command{Stdout: ioutil.Discard, Stderr: ioutil.Discard, Paths: []string{path}, Measurements: []string{"cpu"}, BackupDir: dryDir, DryRun: true}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dryDir); !os.IsNotExist(err) {
+		t.Fatalf("unexpected backup directory: %v", err)
+	}
+}
+
+// seriesKey returns the TSM key of the value field of a series in the test bucket.
+func seriesKey(measurement, tagKey, tagValue string) string {
+	name := tsdb.EncodeName(orgID, bucketID)
+	tags := models.NewTags(map[string]string{
+		models.MeasurementTagKey: measurement,
+		models.FieldKeyTagKey:    "value",
+		tagKey:                   tagValue,
+	})
+	return string(models.MakeKey(name[:], tags)) + "#!~#value"
+}
+
+func seriesKeyTags(measurement string, tags map[string]string) string {
+	return seriesKeyField(measurement, "value", tags)
+}
+
+func seriesKeyField(measurement, field string, tags map[string]string) string {
+	name := tsdb.EncodeName(orgID, bucketID)
+	m := map[string]string{
+		models.MeasurementTagKey: measurement,
+		models.FieldKeyTagKey:    field,
+	}
+	for k, v := range tags {
+		m[k] = v
+	}
+	return string(models.MakeKey(name[:], models.NewTags(m))) + "#!~#" + field
+}
+
+func mustOpenSeriesFile(t *testing.T, path string) *tsdb.SeriesFile {
+	t.Helper()
+	sfile := tsdb.NewSeriesFile(path)
+	if err := sfile.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return sfile
+}
+
+// writeTSMFile writes a TSM file to a new temporary directory, with a block
+// for each of the timestamps of each key.
+func writeTSMFile(t *testing.T, data map[string][]int64) (dir, path string) {
+	t.Helper()
+
+	blocks := make(map[string][][]int64, len(data))
+	for k, timestamps := range data {
+		for _, ts := range timestamps {
+			blocks[k] = append(blocks[k], []int64{ts})
+		}
+	}
+	return writeTSMFileBlocks(t, blocks)
+}
+
+// writeTSMFileBlocks writes a TSM file to a new temporary directory, with a
+// block for each of the lists of timestamps of each key.
+func writeTSMFileBlocks(t *testing.T, data map[string][][]int64) (dir, path string) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "deletetsm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path = filepath.Join(dir, "000000001-000000001."+tsm1.TSMFileExtension)
+
+	var keys []string
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var blocks []tsmtest.Block
+	for _, k := range keys {
+		for _, timestamps := range data[k] {
+			blocks = append(blocks, tsmtest.Block{Key: k, Values: tsmtest.Floats(timestamps...)})
+		}
+	}
+	tsmtest.WriteBlocks(t, path, blocks...)
+	return dir, path
+}
@@ -0,0 +1,167 @@
+package deletetsm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/storage/wal"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsi1"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// deletedSeries collects the series of the TSM keys some values were deleted
+// from, which may have no value left.
+type deletedSeries struct {
+	mu   sync.Mutex
+	keys map[string]struct{}
+}
+
+// recordDeleted records that values of the TSM key were deleted, if the
+// index is to be updated.
+func (cmd *Command) recordDeleted(key []byte) {
+	if cmd.deleted == nil {
+		return
+	}
+	seriesKey, _ := tsm1.SeriesAndFieldFromCompositeKey(key)
+
+	cmd.deleted.mu.Lock()
+	cmd.deleted.keys[string(seriesKey)] = struct{}{}
+	cmd.deleted.mu.Unlock()
+}
+
+// dropSeries drops the series left without any value from the series file
+// and index. A series is kept if any TSM file in the directories of the
+// processed files, or any segment of WALDir, still holds a value of it.
+// It returns the number of series dropped.
+func (cmd *Command) dropSeries(dirs []string) (int, error) {
+	dead := cmd.deleted.keys
+	for _, dir := range dirs {
+		paths, err := filepath.Glob(filepath.Join(dir, "*."+tsm1.TSMFileExtension))
+		if err != nil {
+			return 0, err
+		}
+		for _, path := range paths {
+			if err := removeLiveTSMKeys(path, dead); err != nil {
+				return 0, fmt.Errorf("%s: %v", path, err)
+			}
+		}
+	}
+	if cmd.WALDir != "" {
+		paths, err := wal.SegmentFileNames(cmd.WALDir)
+		if err != nil {
+			return 0, err
+		}
+		for _, path := range paths {
+			if err := removeLiveWALKeys(path, dead); err != nil {
+				return 0, fmt.Errorf("%s: %v", path, err)
+			}
+		}
+	}
+	if len(dead) == 0 {
+		return 0, nil
+	}
+
+	sfile := tsdb.NewSeriesFile(cmd.SeriesFilePath)
+	if err := sfile.Open(context.Background()); err != nil {
+		return 0, fmt.Errorf("unable to open series file: %v", err)
+	}
+	defer sfile.Close()
+
+	index := tsi1.NewIndex(sfile, tsi1.NewConfig(), tsi1.WithPath(cmd.IndexPath), tsi1.DisableMetrics())
+	if err := index.Open(context.Background()); err != nil {
+		return 0, fmt.Errorf("unable to open index: %v", err)
+	}
+
+	keys := make([]string, 0, len(dead))
+	for key := range dead {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	n, err := cmd.dropIndexSeries(sfile, index, keys)
+	if cerr := index.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
+
+// dropIndexSeries drops the series keys from the index and series file.
+func (cmd *Command) dropIndexSeries(sfile *tsdb.SeriesFile, index *tsi1.Index, keys []string) (int, error) {
+	var (
+		n   int
+		buf []byte
+	)
+	for _, key := range keys {
+		seriesKey := []byte(key)
+		name, tags := models.ParseKeyBytes(seriesKey)
+		id := sfile.SeriesID(name, tags, buf)
+		if id.IsZero() {
+			continue
+		}
+
+		// Remove the series from the index before the series file.
+		if err := index.DropSeries(id, seriesKey, true); err != nil {
+			return n, err
+		} else if err := sfile.DeleteSeriesID(id); err != nil {
+			return n, err
+		}
+		n++
+		if cmd.Verbose {
+			fmt.Fprintf(cmd.Stderr, "dropping series: %q\n", seriesKey)
+		}
+	}
+	return n, nil
+}
+
+// removeLiveTSMKeys removes from dead the series with values in the TSM
+// file at path.
+func removeLiveTSMKeys(path string, dead map[string]struct{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("unable to read: %v", err)
+	}
+	defer r.Close()
+
+	iter := r.Iterator(nil)
+	for len(dead) > 0 && iter.Next() {
+		seriesKey, _ := tsm1.SeriesAndFieldFromCompositeKey(iter.Key())
+		delete(dead, string(seriesKey))
+	}
+	return iter.Err()
+}
+
+// removeLiveWALKeys removes from dead the series with values in the WAL
+// segment at path.
+func removeLiveWALKeys(path string, dead map[string]struct{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	r := wal.NewWALSegmentReader(f)
+	defer r.Close()
+
+	for len(dead) > 0 && r.Next() {
+		entry, err := r.Read()
+		if err != nil {
+			return fmt.Errorf("unable to read entry at offset %d: %v", r.Count(), err)
+		}
+		if w, ok := entry.(*wal.WriteWALEntry); ok {
+			for key := range w.Values {
+				seriesKey, _ := tsm1.SeriesAndFieldFromCompositeKey([]byte(key))
+				delete(dead, string(seriesKey))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package deletetsm
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Output formats.
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+)
+
+// Report is the machine-readable summary of a run of the command.
+type Report struct {
+	DryRun bool         `json:"dryRun"`
+	Files  []FileReport `json:"files"`
+	Total  FileReport   `json:"total"`
+	// SeriesDropped is the number of series dropped from the index.
+	SeriesDropped int `json:"seriesDropped,omitempty"`
+	// Skipped lists the files processed by the previous run being resumed.
+	Skipped []string `json:"skipped,omitempty"`
+	// Unmatched lists the keys and patterns of the series file that matched
+	// no series.
+	Unmatched []string `json:"unmatched,omitempty"`
+	// Sanitized lists the keys deleted or renamed by the sanitize option.
+	Sanitized []SanitizedKey `json:"sanitized,omitempty"`
+	// Segments lists the WAL segments processed, if any.
+	Segments []SegmentReport `json:"walSegments,omitempty"`
+	// Error is the error that stopped processing, if any. Files not listed
+	// were not processed.
+	Error string `json:"error,omitempty"`
+}
+
+// FileReport summarizes the deletions from one TSM file, or from all of them.
+type FileReport struct {
+	Path            string     `json:"path,omitempty"`
+	SeriesMatched   int        `json:"seriesMatched"`
+	SeriesRenamed   int        `json:"seriesRenamed,omitempty"`
+	BlocksDeleted   int        `json:"blocksDeleted"`
+	BlocksTrimmed   int        `json:"blocksTrimmed"`
+	BytesReclaimed  int64      `json:"bytesReclaimed"`
+	MinTime         *time.Time `json:"minTime,omitempty"`
+	MaxTime         *time.Time `json:"maxTime,omitempty"`
+	DurationSeconds float64    `json:"durationSeconds"`
+}
+
+func newFileReport(path string, s Stats, d time.Duration) FileReport {
+	r := FileReport{
+		Path:            path,
+		SeriesMatched:   s.Series,
+		SeriesRenamed:   s.Renamed,
+		BlocksDeleted:   s.Blocks,
+		BlocksTrimmed:   s.Trimmed,
+		BytesReclaimed:  s.Bytes,
+		DurationSeconds: d.Seconds(),
+	}
+	if s.deleted() {
+		min, max := time.Unix(0, s.MinTime).UTC(), time.Unix(0, s.MaxTime).UTC()
+		r.MinTime, r.MaxTime = &min, &max
+	}
+	return r
+}
+
+// SegmentReport summarizes the deletions from one WAL segment.
+type SegmentReport struct {
+	Path           string `json:"path"`
+	SeriesMatched  int    `json:"seriesMatched"`
+	ValuesDeleted  int    `json:"valuesDeleted"`
+	EntriesDeleted int    `json:"entriesDeleted"`
+}
+
+func newSegmentReport(path string, s WALStats) SegmentReport {
+	return SegmentReport{
+		Path:           path,
+		SeriesMatched:  s.Series,
+		ValuesDeleted:  s.Values,
+		EntriesDeleted: s.Entries,
+	}
+}
+
+// writeReport writes the report as JSON to the standard output if the JSON
+// format is selected, and to ReportPath if set.
+func (cmd *Command) writeReport(r *Report) error {
+	if cmd.Format == JSONFormat {
+		if err := encodeReport(cmd.Stdout, r); err != nil {
+			return err
+		}
+	}
+	if cmd.ReportPath == "" {
+		return nil
+	}
+
+	f, err := os.Create(cmd.ReportPath)
+	if err != nil {
+		return fmt.Errorf("unable to create report: %v", err)
+	}
+	if err := encodeReport(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to write report: %v", err)
+	}
+	return f.Close()
+}
+
+// writeUnmatched writes the entries of the series file that matched no series
+// to UnmatchedPath if set, and prints them in the text summary otherwise.
+func (cmd *Command) writeUnmatched(entries []string) error {
+	if cmd.UnmatchedPath != "" {
+		f, err := os.Create(cmd.UnmatchedPath)
+		if err != nil {
+			return fmt.Errorf("unable to create unmatched series file: %v", err)
+		}
+		w := bufio.NewWriter(f)
+		for _, entry := range entries {
+			fmt.Fprintln(w, entry)
+		}
+		if err := w.Flush(); err != nil {
+			f.Close()
+			return fmt.Errorf("unable to write unmatched series file: %v", err)
+		} else if err := f.Close(); err != nil {
+			return fmt.Errorf("unable to write unmatched series file: %v", err)
+		}
+	}
+	if len(entries) == 0 || cmd.Format == JSONFormat {
+		return nil
+	}
+
+	fmt.Fprintf(cmd.Stdout, "series file: %d of %d key(s) and pattern(s) matched no series\n", len(entries), len(cmd.series.entries))
+	if cmd.UnmatchedPath == "" {
+		for _, entry := range entries {
+			fmt.Fprintf(cmd.Stdout, "  %s\n", entry)
+		}
+	}
+	return nil
+}
+
+func encodeReport(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
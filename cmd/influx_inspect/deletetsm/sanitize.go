@@ -0,0 +1,157 @@
+package deletetsm
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// Sanitize modes.
+const (
+	// SanitizeDrop deletes the series with invalid keys.
+	SanitizeDrop = "drop"
+
+	// SanitizeEscape renames the series with invalid keys, escaping their
+	// invalid UTF-8 bytes as \xNN and their non-printable characters as
+	// \uNNNN.
+	SanitizeEscape = "escape"
+)
+
+// SanitizedKey is a TSM key deleted or renamed by the sanitize option.
+type SanitizedKey struct {
+	Path string `json:"path"`
+	Key  string `json:"key"`
+	// NewKey is the escaped key a renamed key was replaced by.
+	NewKey string `json:"newKey,omitempty"`
+}
+
+// sanitizedKeys records the keys touched by the sanitize option, possibly
+// by concurrent workers.
+type sanitizedKeys struct {
+	mu   sync.Mutex
+	keys []SanitizedKey
+}
+
+func (s *sanitizedKeys) add(path string, key, newKey []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = append(s.keys, SanitizedKey{Path: path, Key: string(key), NewKey: string(newKey)})
+}
+
+// sorted returns the keys of the TSM files in the order of paths, followed
+// by those of the WAL segments, sorted by key within each file.
+func (s *sanitizedKeys) sorted(paths []string) []SanitizedKey {
+	order := make(map[string]int, len(paths))
+	for i, path := range paths {
+		order[path] = i
+	}
+	position := func(path string) int {
+		if i, ok := order[path]; ok {
+			return i
+		}
+		return len(paths)
+	}
+	sort.Slice(s.keys, func(i, j int) bool {
+		a, b := s.keys[i], s.keys[j]
+		if pa, pb := position(a.Path), position(b.Path); pa != pb {
+			return pa < pb
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Key < b.Key
+	})
+	return s.keys
+}
+
+// printSanitized prints every key deleted or renamed by the sanitize option.
+func (cmd *Command) printSanitized(keys []SanitizedKey) {
+	if len(keys) == 0 {
+		return
+	}
+
+	dropped, escaped := "dropped", "escaped"
+	if cmd.DryRun {
+		dropped, escaped = "would drop", "would escape"
+	}
+	fmt.Fprintf(cmd.Stdout, "sanitize: %d invalid key(s)\n", len(keys))
+	for _, k := range keys {
+		if k.NewKey == "" {
+			fmt.Fprintf(cmd.Stdout, "  %s: %s %q\n", k.Path, dropped, k.Key)
+			continue
+		}
+		fmt.Fprintf(cmd.Stdout, "  %s: %s %q as %q\n", k.Path, escaped, k.Key, k.NewKey)
+	}
+}
+
+// invalidKey returns true if the tags or field of the TSM key contain
+// invalid UTF-8 or non-printable characters.
+func invalidKey(key []byte) bool {
+	seriesKey, _ := tsm1.SeriesAndFieldFromCompositeKey(key)
+	_, tags := models.ParseKeyBytes(seriesKey)
+	return !models.ValidTagTokens(tags)
+}
+
+// invalidSeries returns true if the measurement, tags or field of the series
+// key, in the `measurement,tag=value` form, contain invalid UTF-8 or
+// non-printable characters.
+func invalidSeries(seriesKey, field []byte) bool {
+	name, tags := models.ParseKeyBytes(seriesKey)
+	return !models.ValidToken(models.UnescapeMeasurement(name)) || !models.ValidTagTokens(tags) ||
+		(len(field) > 0 && !models.ValidToken(field))
+}
+
+// escapeKey returns the TSM key with the invalid characters of its tags and
+// field escaped, or nil if it is valid. The name, which encodes the
+// organization and bucket, is kept as is.
+func escapeKey(key []byte) []byte {
+	seriesKey, field := tsm1.SeriesAndFieldFromCompositeKey(key)
+	name, tags := models.ParseKeyBytes(seriesKey)
+	if models.ValidTagTokens(tags) {
+		return nil
+	}
+
+	escaped := make(models.Tags, 0, len(tags))
+	for _, t := range tags {
+		k := t.Key
+		if !bytes.Equal(k, models.MeasurementTagKeyBytes) && !bytes.Equal(k, models.FieldKeyTagKeyBytes) {
+			k = escapeToken(k)
+		}
+		escaped = append(escaped, models.NewTag(k, escapeToken(t.Value)))
+	}
+	sort.Sort(escaped)
+	return tsm1.SeriesFieldKeyBytes(string(models.MakeKey(name, escaped)), string(escapeToken(field)))
+}
+
+// escapeToken returns the token with its invalid UTF-8 bytes escaped as \xNN
+// and its non-printable characters as \uNNNN or \UNNNNNNNN.
+func escapeToken(b []byte) []byte {
+	if models.ValidToken(b) {
+		return b
+	}
+
+	var buf bytes.Buffer
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		switch {
+		case r == utf8.RuneError && size == 1:
+			fmt.Fprintf(&buf, `\x%02x`, b[0])
+		case r == unicode.ReplacementChar || !unicode.IsPrint(r):
+			if r > 0xffff {
+				fmt.Fprintf(&buf, `\U%08x`, r)
+			} else {
+				fmt.Fprintf(&buf, `\u%04x`, r)
+			}
+		default:
+			buf.Write(b[:size])
+		}
+		b = b[size:]
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,258 @@
+package deletetsm
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// fieldSeparator separates the series key from the field name in a TSM key.
+const fieldSeparator = "#!~#"
+
+// Prefixes of the lines of a series file that are patterns rather than exact series keys.
+const (
+	RegexPrefix = "regex:"
+	GlobPrefix  = "glob:"
+)
+
+// seriesMatcher matches series keys, in the `measurement,tag=value` form,
+// against the exact keys and patterns of a series file. Keys and patterns
+// followed by the field separator, such as `cpu,host=a#!~#usage`, only
+// match that field of the series.
+//
+// It records the entries of the series file that matched a series, so that
+// those that matched nothing are reported.
+type seriesMatcher struct {
+	// entries are the keys and patterns of the series file, as written.
+	entries []string
+	// hits flags the entries that matched a series, and is updated atomically.
+	hits []uint32
+
+	exact    map[string]int // index of the entry of each exact key
+	patterns []pattern
+	// fieldPatterns are matched against the series key followed by the field.
+	fieldPatterns []pattern
+}
+
+// pattern is a pattern of a series file, and the index of its entry.
+type pattern struct {
+	re    *regexp.Regexp
+	entry int
+}
+
+// readSeriesFile returns a matcher for the series listed in the file at path,
+// or read from stdin if path is -. Gzip compressed series files, detected by
+// their content, are transparently decompressed.
+//
+// Each line is either an exact series key, a regular expression prefixed with
+// "regex:", or a glob pattern prefixed with "glob:", where * matches any
+// sequence of characters and ? matches a single character. A key or pattern
+// containing the #!~# field separator is matched against the series key
+// followed by the separator and the field name. Blank lines and lines
+// starting with # are ignored.
+func readSeriesFile(path string, stdin io.Reader) (*seriesMatcher, error) {
+	name, r := path, stdin
+	if path == "-" {
+		if stdin == nil {
+			return nil, errors.New("no stdin to read series from")
+		}
+		name = "stdin"
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	br := bufio.NewReaderSize(r, 1<<16)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+
+	m := &seriesMatcher{exact: make(map[string]int)}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		if err := m.add(scanner.Text()); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", name, n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	m.hits = make([]uint32, len(m.entries))
+	return m, nil
+}
+
+// add adds a line of a series file to m.
+func (m *seriesMatcher) add(line string) error {
+	line = strings.TrimSpace(line)
+	switch {
+	case line == "" || strings.HasPrefix(line, "#"):
+		return nil
+
+	case strings.HasPrefix(line, RegexPrefix):
+		re, err := regexp.Compile(strings.TrimPrefix(line, RegexPrefix))
+		if err != nil {
+			return err
+		}
+		m.addPattern(line, re)
+
+	case strings.HasPrefix(line, GlobPrefix):
+		re, err := globToRegexp(strings.TrimPrefix(line, GlobPrefix))
+		if err != nil {
+			return err
+		}
+		m.addPattern(line, re)
+
+	default:
+		key, field := line, ""
+		if i := strings.Index(line, fieldSeparator); i >= 0 {
+			key, field = line[:i], line[i:]
+			if field == fieldSeparator {
+				return fmt.Errorf("invalid series key %q: missing field", line)
+			}
+		}
+
+		// Normalize the key so that tags may be listed in any order.
+		name, tags := models.ParseKeyBytes([]byte(key))
+		if len(name) == 0 {
+			return fmt.Errorf("invalid series key %q", line)
+		}
+		sort.Sort(tags)
+		key = string(models.MakeKey(name, tags)) + field
+		if _, ok := m.exact[key]; ok {
+			// A key listed twice is only reported once.
+			return nil
+		}
+		m.exact[key] = len(m.entries)
+		m.entries = append(m.entries, line)
+	}
+	return nil
+}
+
+func (m *seriesMatcher) addPattern(line string, re *regexp.Regexp) {
+	p := pattern{re: re, entry: len(m.entries)}
+	m.entries = append(m.entries, line)
+	if strings.Contains(re.String(), fieldSeparator) {
+		m.fieldPatterns = append(m.fieldPatterns, p)
+		return
+	}
+	m.patterns = append(m.patterns, p)
+}
+
+// match returns true if the series key, or its field, matches an exact key or
+// any pattern of m. Once the series matches, the patterns that already
+// matched another series are not evaluated again.
+func (m *seriesMatcher) match(key, field []byte) bool {
+	var matched bool
+	if i, ok := m.exact[string(key)]; ok {
+		m.hit(i)
+		matched = true
+	}
+	for _, p := range m.patterns {
+		if matched && m.matched(p.entry) {
+			continue
+		}
+		if p.re.Match(key) {
+			m.hit(p.entry)
+			matched = true
+		}
+	}
+
+	if len(m.exact) == 0 && len(m.fieldPatterns) == 0 {
+		return matched
+	}
+	keyField := make([]byte, 0, len(key)+len(fieldSeparator)+len(field))
+	keyField = append(append(append(keyField, key...), fieldSeparator...), field...)
+	if i, ok := m.exact[string(keyField)]; ok {
+		m.hit(i)
+		matched = true
+	}
+	for _, p := range m.fieldPatterns {
+		if matched && m.matched(p.entry) {
+			continue
+		}
+		if p.re.Match(keyField) {
+			m.hit(p.entry)
+			matched = true
+		}
+	}
+	return matched
+}
+
+func (m *seriesMatcher) hit(entry int) {
+	if !m.matched(entry) {
+		atomic.StoreUint32(&m.hits[entry], 1)
+	}
+}
+
+func (m *seriesMatcher) matched(entry int) bool {
+	return atomic.LoadUint32(&m.hits[entry]) != 0
+}
+
+// unmatched returns the entries of the series file that matched no series,
+// in the order of the file.
+func (m *seriesMatcher) unmatched() []string {
+	var entries []string
+	for i, entry := range m.entries {
+		if !m.matched(i) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// globToRegexp compiles a glob pattern to an anchored regular expression.
+func globToRegexp(glob string) (*regexp.Regexp, error) {
+	var buf strings.Builder
+	buf.WriteString("^")
+	for _, c := range glob {
+		switch c {
+		case '*':
+			buf.WriteString(".*")
+		case '?':
+			buf.WriteString(".")
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	buf.WriteString("$")
+	return regexp.Compile(buf.String())
+}
+
+// seriesKeyOf returns the `measurement,tag=value` key of the series of a TSM key,
+// without the organization, bucket and field.
+func seriesKeyOf(key []byte) []byte {
+	seriesKey, _ := tsm1.SeriesAndFieldFromCompositeKey(key)
+	_, tags := models.ParseKeyBytes(seriesKey)
+
+	name := tags.Get(models.MeasurementTagKeyBytes)
+	filtered := make(models.Tags, 0, len(tags))
+	for _, t := range tags {
+		if bytes.Equal(t.Key, models.MeasurementTagKeyBytes) || bytes.Equal(t.Key, models.FieldKeyTagKeyBytes) {
+			continue
+		}
+		filtered = append(filtered, t)
+	}
+	return models.MakeKey(name, filtered)
+}
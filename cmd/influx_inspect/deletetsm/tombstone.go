@@ -0,0 +1,130 @@
+package deletetsm
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// tombstone records tombstones for the values of the matching series in the
+// TSM file at path, instead of rewriting it. Blocks partially within the time
+// range are counted as trimmed, without decoding them, so the bytes of the
+// deleted values they hold are not included in the statistics.
+func (d *Deleter) tombstone(path string) (stats Stats, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return stats, err
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		f.Close()
+		return stats, fmt.Errorf("unable to read: %v", err)
+	}
+	defer r.Close()
+
+	// A single tombstone is enough to delete a whole measurement of a bucket.
+	prefix := tsmfile.Prefix(d.OrgID, d.BucketID)
+	measurements := d.measurementTombstones()
+	keyPrefixes := [][]byte{prefix}
+	if measurements != nil {
+		keyPrefixes = keyPrefixes[:0]
+		for _, m := range measurements {
+			keyPrefixes = append(keyPrefixes, tsm1.MeasurementPrefix(prefix, m))
+		}
+	}
+
+	where := d.wherePredicate()
+	start, end := d.timeRange()
+	var keys [][]byte
+	for _, keyPrefix := range keyPrefixes {
+		iter := r.Iterator(keyPrefix)
+		for iter.Next() {
+			key := iter.Key()
+			if !bytes.HasPrefix(key, keyPrefix) {
+				break
+			} else if !d.match(key, where) {
+				continue
+			}
+
+			var deleted bool
+			for _, e := range iter.Entries() {
+				if e.MinTime > end || e.MaxTime < start {
+					continue
+				}
+
+				deleted = true
+				if e.MinTime >= start && e.MaxTime <= end {
+					stats.addBlock(e.MinTime, e.MaxTime, int(e.Size))
+					continue
+				}
+				stats.addTrimmed(max64(e.MinTime, start), min64(e.MaxTime, end), 0)
+			}
+			if !deleted {
+				continue
+			}
+
+			stats.Series++
+			d.keyDeleted(path, key)
+			keys = append(keys, append([]byte(nil), key...))
+		}
+		if err := iter.Err(); err != nil {
+			return stats, err
+		}
+	}
+
+	if d.DryRun || stats.Empty() {
+		return stats, nil
+	}
+
+	if d.BeforeReplace != nil {
+		if err := d.BeforeReplace(path); err != nil {
+			return stats, err
+		}
+	}
+	if measurements != nil {
+		// The where predicate, if any, is recorded with the tombstones.
+		for _, m := range measurements {
+			if err := r.DeleteMeasurement(prefix, m, start, end, where, nil); err != nil {
+				return stats, err
+			}
+		}
+		return stats, nil
+	}
+	return stats, r.DeleteRange(keys, start, end)
+}
+
+// measurementTombstones returns the sorted, distinct measurements whose series of the bucket
+// are all deleted, if the series are only selected by their measurement.
+func (d *Deleter) measurementTombstones() [][]byte {
+	if len(d.Measurements) == 0 || d.Field != "" || d.Match != nil || !d.BucketID.Valid() {
+		return nil
+	}
+	names := append([]string(nil), d.Measurements...)
+	sort.Strings(names)
+	measurements := make([][]byte, 0, len(names))
+	for i, m := range names {
+		if i > 0 && m == names[i-1] {
+			continue
+		}
+		measurements = append(measurements, []byte(m))
+	}
+	return measurements
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,130 @@
+package deletetsm
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"os"
+
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// verify checks the TSM file at outputPath, rewritten from the file at path,
+// before it replaces the original. The checksums of its blocks must match, no
+// value to delete may remain, and the blocks of the series left untouched
+// must be those of the original file. The blocks of renamed series are only
+// checked for their checksum.
+func (d *Deleter) verify(path, outputPath string) error {
+	orig, err := openTSMReader(path)
+	if err != nil {
+		return err
+	}
+	defer orig.Close()
+
+	out, err := openTSMReader(outputPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	var (
+		start, end = d.timeRange()
+		origKeys   = d.keyMatcher()
+		outKeys    = d.keyMatcher()
+		origItr    = orig.BlockIterator()
+		values     []tsm1.Value
+		newKeys    = make(map[string]struct{})
+	)
+
+	if d.Rename != nil {
+		keys := d.keyMatcher()
+		itr := orig.Iterator(nil)
+		for itr.Next() {
+			if newKey := keys.renames(itr.Key()); newKey != nil {
+				newKeys[string(newKey)] = struct{}{}
+			}
+		}
+		if err := itr.Err(); err != nil {
+			return err
+		}
+	}
+
+	// nextUnmatched returns the next block of the original file that is
+	// not of a matching series.
+	nextUnmatched := func() (key []byte, minTime, maxTime int64, checksum uint32, ok bool, err error) {
+		for origItr.Next() {
+			key, minTime, maxTime, _, checksum, _, err := origItr.Read()
+			if err != nil {
+				return nil, 0, 0, 0, false, err
+			}
+			if !origKeys.matches(key) && origKeys.renames(key) == nil {
+				return key, minTime, maxTime, checksum, true, nil
+			}
+		}
+		return nil, 0, 0, 0, false, origItr.Err()
+	}
+
+	itr := out.BlockIterator()
+	for itr.Next() {
+		key, minTime, maxTime, _, checksum, block, err := itr.Read()
+		if err != nil {
+			return err
+		}
+		if crc32.ChecksumIEEE(block) != checksum {
+			return fmt.Errorf("invalid checksum for block of %q", key)
+		}
+
+		if outKeys.matches(key) {
+			if minTime > end || maxTime < start {
+				continue
+			}
+			if values, err = tsm1.DecodeBlock(block, values[:0]); err != nil {
+				return fmt.Errorf("unable to decode block of %q: %v", key, err)
+			}
+			if i, j := window(values, start, end); i < j {
+				return fmt.Errorf("%d deleted value(s) of %q remain", j-i, key)
+			}
+			continue
+		}
+		if _, ok := newKeys[string(key)]; ok {
+			continue
+		}
+		if outKeys.renames(key) != nil {
+			return fmt.Errorf("key %q was not renamed", key)
+		}
+
+		origKey, origMin, origMax, origChecksum, ok, err := nextUnmatched()
+		if err != nil {
+			return err
+		} else if !ok {
+			return fmt.Errorf("unexpected block of %q", key)
+		}
+		if !bytes.Equal(key, origKey) || minTime != origMin || maxTime != origMax || checksum != origChecksum {
+			return fmt.Errorf("block of %q (%s-%s) differs from the original", key, formatTime(minTime), formatTime(maxTime))
+		}
+	}
+	if err := itr.Err(); err != nil {
+		return err
+	}
+
+	if key, minTime, maxTime, _, ok, err := nextUnmatched(); err != nil {
+		return err
+	} else if ok {
+		return fmt.Errorf("block of %q (%s-%s) is missing", key, formatTime(minTime), formatTime(maxTime))
+	}
+	return nil
+}
+
+// openTSMReader opens the TSM file at path.
+func openTSMReader(path string) (*tsm1.TSMReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to read %s: %v", path, err)
+	}
+	return r, nil
+}
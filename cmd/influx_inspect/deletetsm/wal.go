@@ -0,0 +1,217 @@
+package deletetsm
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile"
+	"github.com/influxdata/influxdb/storage/wal"
+)
+
+// WALStats summarizes the values deleted from one or more WAL segments.
+type WALStats struct {
+	Values  int // number of deleted values
+	Series  int // number of series with at least one deleted value
+	Entries int // number of write entries dropped as all their values were deleted
+	Renamed int // number of series renamed
+}
+
+// Empty returns true if nothing was deleted or renamed.
+func (s *WALStats) Empty() bool {
+	return s.Values == 0 && s.Renamed == 0
+}
+
+func (s *WALStats) add(o WALStats) {
+	s.Values += o.Values
+	s.Series += o.Series
+	s.Entries += o.Entries
+	s.Renamed += o.Renamed
+}
+
+func (cmd *Command) printWALStats(name string, s WALStats) {
+	if s.Empty() {
+		fmt.Fprintf(cmd.Stdout, "%s: nothing to delete\n", name)
+		return
+	}
+
+	if s.Renamed > 0 {
+		verb := "renamed"
+		if cmd.DryRun {
+			verb = "would rename"
+		}
+		fmt.Fprintf(cmd.Stdout, "%s: %s %d series\n", name, verb, s.Renamed)
+		if s.Values == 0 {
+			return
+		}
+	}
+
+	verb := "deleted"
+	if cmd.DryRun {
+		verb = "would delete"
+	}
+	fmt.Fprintf(cmd.Stdout, "%s: %s %d value(s) of %d series\n", name, verb, s.Values, s.Series)
+}
+
+// processWAL removes the values of the matching series from the segments of
+// WALDir, so that they are not written back to TSM files when the WAL is
+// replayed. Segments are processed in order, and processing stops at the
+// first segment that fails.
+func (cmd *Command) processWAL(d *Deleter, report func(path string, stats WALStats)) error {
+	paths, err := wal.SegmentFileNames(cmd.WALDir)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		stats, err := d.DeleteSegment(path)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		report(path, stats)
+	}
+	return nil
+}
+
+// DeleteSegment deletes the values of the selected series from the WAL
+// segment at path, so that they are not written back to TSM files when the
+// WAL is replayed.
+func (d *Deleter) DeleteSegment(path string) (WALStats, error) {
+	if err := d.checkSelection(); err != nil {
+		return WALStats{}, err
+	}
+
+	outputPath := path + "." + tmpWALExtension
+	stats, err := d.rewriteSegment(path, outputPath)
+	if err != nil || d.DryRun || stats.Empty() {
+		return stats, err
+	}
+
+	if d.BeforeReplace != nil {
+		if err := d.BeforeReplace(path); err != nil {
+			os.Remove(outputPath)
+			return stats, err
+		}
+	}
+	// An empty segment is kept, as the WAL expects segment IDs to increase.
+	return stats, os.Rename(outputPath, path)
+}
+
+// tmpWALExtension is the extension of the segment replacing a WAL segment.
+const tmpWALExtension = "deletetsm"
+
+// rewriteSegment writes the entries of the WAL segment at path, without the
+// deleted values, to a new segment at outputPath. The new segment is not
+// created if no value is to be deleted, or in dry-run mode.
+func (d *Deleter) rewriteSegment(path, outputPath string) (stats WALStats, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return stats, err
+	}
+	r := wal.NewWALSegmentReader(f)
+	defer r.Close()
+
+	prefix := string(tsmfile.Prefix(d.OrgID, d.BucketID))
+	where := d.wherePredicate()
+	start, end := d.timeRange()
+	var (
+		entries []wal.WALEntry
+		series  = make(map[string]struct{})
+		renamed = make(map[string]struct{})
+	)
+	for r.Next() {
+		entry, err := r.Read()
+		if err != nil {
+			return stats, fmt.Errorf("unable to read entry at offset %d: %v", r.Count(), err)
+		}
+
+		if w, ok := entry.(*wal.WriteWALEntry); ok {
+			for key, values := range w.Values {
+				if !strings.HasPrefix(key, prefix) {
+					continue
+				}
+				if !d.match([]byte(key), where) {
+					if d.Rename == nil {
+						continue
+					}
+					if newKey := d.Rename([]byte(key)); newKey != nil {
+						// The cache sorts and deduplicates the values of a key
+						// when the WAL is replayed.
+						w.Values[string(newKey)] = append(w.Values[string(newKey)], values...)
+						delete(w.Values, key)
+						if _, ok := renamed[key]; !ok {
+							renamed[key] = struct{}{}
+							if d.OnKeyRenamed != nil {
+								d.OnKeyRenamed(path, []byte(key), newKey)
+							}
+						}
+					}
+					continue
+				}
+
+				kept := values[:0]
+				for _, v := range values {
+					if ts := v.UnixNano(); ts < start || ts > end {
+						kept = append(kept, v)
+					}
+				}
+				if len(kept) == len(values) {
+					continue
+				}
+
+				stats.Values += len(values) - len(kept)
+				series[key] = struct{}{}
+				d.keyDeleted(path, []byte(key))
+				if len(kept) == 0 {
+					delete(w.Values, key)
+				} else {
+					w.Values[key] = kept
+				}
+			}
+			if len(w.Values) == 0 {
+				stats.Entries++
+				continue
+			}
+		}
+		entries = append(entries, entry)
+	}
+	stats.Series = len(series)
+	stats.Renamed = len(renamed)
+
+	if d.DryRun || stats.Empty() {
+		return stats, nil
+	}
+	return stats, writeSegment(outputPath, entries)
+}
+
+// writeSegment writes the entries to a new WAL segment at path.
+func writeSegment(path string, entries []wal.WALEntry) (err error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(path)
+		}
+	}()
+
+	w := wal.NewWALSegmentWriter(f)
+	for _, entry := range entries {
+		b, err := entry.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if err := w.Write(entry.Type(), snappy.Encode(nil, b)); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	} else if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
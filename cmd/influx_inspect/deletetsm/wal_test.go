@@ -0,0 +1,158 @@
+package deletetsm_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/deletetsm"
+	"github.com/influxdata/influxdb/storage/wal"
+	"github.com/influxdata/influxdb/tsdb/value"
+)
+
+func TestCommand_WAL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deletetsm-wal-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	w := wal.NewWAL(dir)
+	if err := w.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	writes := []map[string][]int64{
+		{seriesKey("cpu", "host", "a"): {10, 20}, seriesKey("mem", "host", "a"): {10}},
+		{seriesKey("cpu", "host", "b"): {30}},
+		{seriesKey("cpu", "host", "a"): {40}, seriesKey("disk", "host", "a"): {50}},
+	}
+	for _, data := range writes {
+		values := make(map[string][]value.Value, len(data))
+		for k, timestamps := range data {
+			for _, ts := range timestamps {
+				values[k] = append(values[k], value.NewFloatValue(ts, float64(ts)))
+			}
+		}
+		if _, err := w.WriteMulti(context.Background(), values); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := w.DeleteBucketRange(orgID, bucketID, 0, 5, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	cmd := &deletetsm.Command{Stdout: &stdout, Stderr: ioutil.Discard, WALDir: dir, Measurements: []string{"cpu"}, End: time.Unix(0, 30)}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := wal.SegmentFileNames(dir)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(paths) != 1 {
+		t.Fatalf("unexpected segments: %q", paths)
+	}
+	if got, want := stdout.String(), paths[0]+": deleted 3 value(s) of 2 series\n"; got != want {
+		t.Fatalf("unexpected output: got %q, want %q", got, want)
+	}
+
+	// The second write only held deleted values, so it is dropped, while the
+	// values after the time range and the delete entry are kept.
+	got := readWALSegment(t, paths[0])
+	want := []string{
+		seriesKey("mem", "host", "a") + " 10",
+		seriesKey("cpu", "host", "a") + " 40\n" + seriesKey("disk", "host", "a") + " 50",
+		"delete",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected entries:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestCommand_WAL_SanitizeEscape(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deletetsm-wal-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	w := wal.NewWAL(dir)
+	if err := w.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	values := map[string][]value.Value{
+		seriesKey("cpu", "host", "a"):       {value.NewFloatValue(10, 1)},
+		seriesKey("cpu", "host", "bad\x01"): {value.NewFloatValue(20, 2)},
+	}
+	if _, err := w.WriteMulti(context.Background(), values); err != nil {
+		t.Fatal(err)
+	} else if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	cmd := &deletetsm.Command{Stdout: &stdout, Stderr: ioutil.Discard, WALDir: dir, Sanitize: true, SanitizeMode: deletetsm.SanitizeEscape}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	paths, err := wal.SegmentFileNames(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), paths[0]+": renamed 1 series\n"; !strings.HasPrefix(got, want) {
+		t.Fatalf("unexpected output: got %q, want prefix %q", got, want)
+	}
+	got := readWALSegment(t, paths[0])
+	want := []string{seriesKey("cpu", "host", "a") + " 10\n" + seriesKey("cpu", "host", `bad\u0001`) + " 20"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected entries:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+// readWALSegment returns the entries of a WAL segment, each write entry as
+// the sorted lines of its keys and timestamps.
+func readWALSegment(t *testing.T, path string) []string {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := wal.NewWALSegmentReader(f)
+	defer r.Close()
+
+	var entries []string
+	for r.Next() {
+		entry, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, ok := entry.(*wal.WriteWALEntry)
+		if !ok {
+			entries = append(entries, "delete")
+			continue
+		}
+
+		var lines []string
+		for k, values := range w.Values {
+			for _, v := range values {
+				lines = append(lines, k+" "+strconv.FormatInt(v.UnixNano(), 10))
+			}
+		}
+		sort.Strings(lines)
+		entries = append(entries, strings.Join(lines, "\n"))
+	}
+	return entries
+}
@@ -0,0 +1,567 @@
+// Package downsample aggregates the series of TSM files into lower-resolution
+// rollups without running the storage engine or the query engine.
+package downsample
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/escape"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// Aggregates computed over each window.
+const (
+	AggregateMean  = "mean"
+	AggregateMin   = "min"
+	AggregateMax   = "max"
+	AggregateCount = "count"
+)
+
+// Formats of the rollups.
+const (
+	FormatTSM          = "tsm"
+	FormatLineProtocol = "lp"
+)
+
+// maxFileSize is the size of the TSM files after which a new file is
+// started, as done by compactions.
+const maxFileSize = 2048 * 1024 * 1024
+
+// Command aggregates the series of a set of TSM files over fixed windows.
+//
+// The values of each field of each series are aggregated over windows of
+// Every aligned on the epoch, and each aggregate is written as a new field
+// named <field>_<aggregate>, at the start time of the window. The mean, min
+// and max of a field are only computed for numeric fields, its count for any
+// field. The values of a series found in several files are merged as by a
+// compaction, the values of newer files replacing those of older files at the
+// same time, and tombstoned values are dropped.
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Paths lists the TSM files to downsample, and the directories searched
+	// recursively for them. The files are merged in the order of Paths, then
+	// of their names.
+	Paths []string
+
+	// OrgID and BucketID optionally restrict the rollups to the series of an
+	// organization, or of a bucket within that organization.
+	OrgID    influxdb.ID
+	BucketID influxdb.ID
+
+	// Every is the duration of the windows.
+	Every time.Duration
+
+	// Aggregates lists the aggregates computed over each window, the mean
+	// only if not set.
+	Aggregates []string
+
+	// Format is the format of the rollups, FormatTSM by default.
+	Format string
+
+	// OutputDir is the directory the TSM files are written to, for
+	// instance the data directory of the engine the rollups are loaded in.
+	OutputDir string
+
+	// Generation is the generation of the first TSM file written, 1 if not
+	// set, so that files can be written next to the existing files of an
+	// engine.
+	Generation int
+
+	// TargetOrgID and TargetBucketID optionally name the bucket the TSM
+	// rollups are written to, the bucket of each series if not set.
+	TargetOrgID    influxdb.ID
+	TargetBucketID influxdb.ID
+
+	// Output is where the line protocol rollups are written to.
+	Output io.Writer
+}
+
+// NewCommand returns a new instance of Command writing to the standard output and error.
+func NewCommand() *Command {
+	return &Command{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Output: os.Stdout,
+	}
+}
+
+// Run downsamples the TSM files found in Paths.
+func (cmd *Command) Run() error {
+	if cmd.Every <= 0 {
+		return errors.New("window duration must be positive")
+	}
+	if cmd.BucketID.Valid() && !cmd.OrgID.Valid() {
+		return errors.New("bucket requires an organization")
+	}
+	if len(cmd.Aggregates) == 0 {
+		cmd.Aggregates = []string{AggregateMean}
+	}
+	for _, agg := range cmd.Aggregates {
+		switch agg {
+		case AggregateMean, AggregateMin, AggregateMax, AggregateCount:
+		default:
+			return fmt.Errorf("unsupported aggregate %q", agg)
+		}
+	}
+
+	var w writer
+	switch cmd.Format {
+	case "", FormatTSM:
+		if cmd.OutputDir == "" {
+			return errors.New("output directory required")
+		}
+		if cmd.TargetBucketID.Valid() != cmd.TargetOrgID.Valid() {
+			return errors.New("target bucket and organization must be set together")
+		}
+		if cmd.TargetBucketID.Valid() && !cmd.BucketID.Valid() {
+			// The keys of several buckets would not remain sorted once
+			// renamed to the same bucket.
+			return errors.New("target bucket requires a bucket")
+		}
+		if cmd.Generation == 0 {
+			cmd.Generation = 1
+		}
+		tw := &tsmWriter{dir: cmd.OutputDir, generation: cmd.Generation}
+		if cmd.TargetBucketID.Valid() {
+			name := tsdb.EncodeName(cmd.TargetOrgID, cmd.TargetBucketID)
+			tw.name = name[:]
+		}
+		w = tw
+	case FormatLineProtocol:
+		if cmd.Output == nil {
+			return errors.New("output required")
+		}
+		w = &lineProtocolWriter{w: bufio.NewWriter(cmd.Output)}
+	default:
+		return fmt.Errorf("unsupported format %q", cmd.Format)
+	}
+
+	files, err := tsmfile.Find(cmd.Paths)
+	if err != nil {
+		return err
+	}
+	readers := make([]*tsm1.TSMReader, 0, len(files))
+	defer func() {
+		for _, r := range readers {
+			r.Close()
+		}
+	}()
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		r, err := tsm1.NewTSMReader(f)
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("unable to read %s: %v", path, err)
+		}
+		readers = append(readers, r)
+	}
+
+	d := &downsampler{cmd: cmd, readers: readers, w: w}
+	if err := d.run(); err != nil {
+		w.abort()
+		return err
+	}
+	if err := w.close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.Stderr, "downsampled %d series key(s) of %d TSM file(s) into %d rollup(s) of %s\n",
+		d.keys, len(files), d.rollups, cmd.Every)
+	if tw, ok := w.(*tsmWriter); ok {
+		for _, path := range tw.paths {
+			fmt.Fprintf(cmd.Stderr, "wrote %s\n", path)
+		}
+	}
+	return nil
+}
+
+type downsampler struct {
+	cmd     *Command
+	readers []*tsm1.TSMReader
+	w       writer
+
+	keys    int
+	rollups int
+}
+
+// run aggregates the keys of the readers in order. The fields of a series
+// are contiguous in the order of the keys, so the rollups of each series are
+// buffered to be written in the order of their own keys.
+func (d *downsampler) run() error {
+	keys := d.sortedKeys()
+
+	var (
+		series  []byte
+		rollups []rollup
+	)
+	flush := func() error {
+		sort.Slice(rollups, func(i, j int) bool { return bytes.Compare(rollups[i].key, rollups[j].key) < 0 })
+		for _, r := range rollups {
+			if err := d.w.write(r); err != nil {
+				return err
+			}
+			d.rollups += len(r.values)
+		}
+		rollups = rollups[:0]
+		return nil
+	}
+
+	for _, key := range keys {
+		sk, field := tsm1.SeriesAndFieldFromCompositeKey(key)
+		name, tags := models.ParseKeyBytes(sk)
+		if !bytes.Equal(tags.Get(models.FieldKeyTagKeyBytes), field) {
+			return fmt.Errorf("invalid key %q", key)
+		}
+		tags = tags.Clone()
+		tags.Delete(models.FieldKeyTagKeyBytes)
+		seriesKey := models.MakeKey(name, tags)
+		if !bytes.Equal(seriesKey, series) {
+			if err := flush(); err != nil {
+				return err
+			}
+			series = seriesKey
+		}
+
+		values, err := d.read(key)
+		if err != nil {
+			return fmt.Errorf("unable to read key %q: %v", key, err)
+		}
+		if len(values) == 0 {
+			continue
+		}
+		d.keys++
+		for _, agg := range d.cmd.Aggregates {
+			vs := aggregate(agg, values, int64(d.cmd.Every))
+			if len(vs) == 0 {
+				continue
+			}
+			rollups = append(rollups, newRollup(name, tags, string(field)+"_"+agg, vs))
+		}
+	}
+	return flush()
+}
+
+// sortedKeys returns the sorted keys of the readers, without duplicates.
+func (d *downsampler) sortedKeys() [][]byte {
+	var (
+		prefix = tsmfile.Prefix(d.cmd.OrgID, d.cmd.BucketID)
+		seen   = make(map[string]bool)
+		keys   [][]byte
+	)
+	for _, r := range d.readers {
+		iter := r.Iterator(prefix)
+		for iter.Next() {
+			key := iter.Key()
+			if !bytes.HasPrefix(key, prefix) {
+				break
+			}
+			if !seen[string(key)] {
+				seen[string(key)] = true
+				keys = append(keys, append([]byte(nil), key...))
+			}
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+	return keys
+}
+
+// read returns the values of key merged from all the readers, without the
+// tombstoned values.
+func (d *downsampler) read(key []byte) (tsm1.Values, error) {
+	var values tsm1.Values
+	for _, r := range d.readers {
+		if !r.Contains(key) {
+			continue
+		}
+		vs, err := r.ReadAll(key)
+		if err != nil {
+			return nil, err
+		}
+		for _, tr := range r.TombstoneRange(key, nil) {
+			vs = tsm1.Values(vs).Exclude(tr.Min, tr.Max)
+		}
+		values = append(values, vs...)
+	}
+	return values.Deduplicate(), nil
+}
+
+// windowStart returns the start of the window of duration every holding t,
+// the windows being aligned on the epoch.
+func windowStart(t, every int64) int64 {
+	start := t - t%every
+	if t < 0 && t%every != 0 {
+		start -= every
+	}
+	return start
+}
+
+// aggregate returns the aggregate agg of the sorted values over windows of
+// duration every, timed by the start of the window. Only the count of
+// non-numeric values is computed.
+func aggregate(agg string, values tsm1.Values, every int64) tsm1.Values {
+	var out tsm1.Values
+	for i := 0; i < len(values); {
+		start := windowStart(values[i].UnixNano(), every)
+		j := i + 1
+		for j < len(values) && windowStart(values[j].UnixNano(), every) == start {
+			j++
+		}
+		if v := aggregateWindow(agg, values[i:j]); v != nil {
+			out = append(out, tsm1.NewValue(start, v))
+		}
+		i = j
+	}
+	return out
+}
+
+func aggregateWindow(agg string, values tsm1.Values) interface{} {
+	if agg == AggregateCount {
+		return int64(len(values))
+	}
+
+	switch values[0].Value().(type) {
+	case float64:
+		sum, min, max := 0.0, math.Inf(1), math.Inf(-1)
+		for _, v := range values {
+			f := v.Value().(float64)
+			sum += f
+			min = math.Min(min, f)
+			max = math.Max(max, f)
+		}
+		switch agg {
+		case AggregateMean:
+			return sum / float64(len(values))
+		case AggregateMin:
+			return min
+		case AggregateMax:
+			return max
+		}
+	case int64:
+		var sum float64
+		min, max := int64(math.MaxInt64), int64(math.MinInt64)
+		for _, v := range values {
+			n := v.Value().(int64)
+			sum += float64(n)
+			if n < min {
+				min = n
+			}
+			if n > max {
+				max = n
+			}
+		}
+		switch agg {
+		case AggregateMean:
+			return sum / float64(len(values))
+		case AggregateMin:
+			return min
+		case AggregateMax:
+			return max
+		}
+	case uint64:
+		var sum float64
+		min, max := uint64(math.MaxUint64), uint64(0)
+		for _, v := range values {
+			n := v.Value().(uint64)
+			sum += float64(n)
+			if n < min {
+				min = n
+			}
+			if n > max {
+				max = n
+			}
+		}
+		switch agg {
+		case AggregateMean:
+			return sum / float64(len(values))
+		case AggregateMin:
+			return min
+		case AggregateMax:
+			return max
+		}
+	}
+	return nil
+}
+
+// rollup is an aggregate of a field of a series.
+type rollup struct {
+	name   []byte
+	tags   models.Tags // without the field
+	field  string
+	key    []byte // TSM key
+	values tsm1.Values
+}
+
+func newRollup(name []byte, tags models.Tags, field string, values tsm1.Values) rollup {
+	ftags := tags.Clone()
+	ftags.Set(models.FieldKeyTagKeyBytes, []byte(field))
+	return rollup{
+		name:   name,
+		tags:   tags,
+		field:  field,
+		key:    tsm1.SeriesFieldKeyBytes(string(models.MakeKey(name, ftags)), field),
+		values: values,
+	}
+}
+
+// writer writes rollups in the order of their keys.
+type writer interface {
+	write(r rollup) error
+	close() error
+	abort()
+}
+
+// tsmWriter writes rollups to TSM files.
+type tsmWriter struct {
+	dir        string
+	generation int
+	name       []byte // name of the target bucket, if any
+
+	w     tsm1.TSMWriter
+	paths []string
+}
+
+func (w *tsmWriter) write(r rollup) error {
+	key := r.key
+	if w.name != nil {
+		ftags := r.tags.Clone()
+		ftags.Set(models.FieldKeyTagKeyBytes, []byte(r.field))
+		key = tsm1.SeriesFieldKeyBytes(string(models.MakeKey(w.name, ftags)), r.field)
+	}
+
+	for i := 0; i < len(r.values); i += tsm1.MaxPointsPerBlock {
+		j := i + tsm1.MaxPointsPerBlock
+		if j > len(r.values) {
+			j = len(r.values)
+		}
+		if err := w.open(); err != nil {
+			return err
+		}
+		if err := w.w.Write(key, r.values[i:j]); err != nil {
+			return err
+		}
+		// Start a new file once the current one is full, as a key may
+		// span several files.
+		if w.w.Size() > maxFileSize {
+			if err := w.finish(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *tsmWriter) open() error {
+	if w.w != nil {
+		return nil
+	}
+	if err := os.MkdirAll(w.dir, 0777); err != nil {
+		return err
+	}
+	path := filepath.Join(w.dir, fmt.Sprintf("%09d-%09d.%s", w.generation, 1, tsm1.TSMFileExtension))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	if w.w, err = tsm1.NewTSMWriter(f); err != nil {
+		f.Close()
+		return err
+	}
+	w.paths = append(w.paths, path)
+	w.generation++
+	return nil
+}
+
+// finish completes the current file.
+func (w *tsmWriter) finish() error {
+	tw := w.w
+	w.w = nil
+	if err := tw.WriteIndex(); err != nil {
+		tw.Remove()
+		return err
+	}
+	return tw.Close()
+}
+
+func (w *tsmWriter) close() error {
+	if w.w == nil {
+		return nil
+	}
+	return w.finish()
+}
+
+func (w *tsmWriter) abort() {
+	if w.w != nil {
+		w.w.Remove()
+		w.w = nil
+	}
+}
+
+// lineProtocolWriter writes rollups as line protocol, the measurement of each
+// series being its measurement tag.
+type lineProtocolWriter struct {
+	w   *bufio.Writer
+	buf []byte
+}
+
+func (w *lineProtocolWriter) write(r rollup) error {
+	var tags models.Tags
+	for _, t := range r.tags {
+		if !bytes.Equal(t.Key, models.MeasurementTagKeyBytes) {
+			tags = append(tags, t)
+		}
+	}
+	prefix := models.MakeKey(r.tags.Get(models.MeasurementTagKeyBytes), tags)
+	field := escape.String(r.field)
+
+	for _, v := range r.values {
+		b := append(w.buf[:0], prefix...)
+		b = append(b, ' ')
+		b = append(b, field...)
+		b = append(b, '=')
+		switch v := v.Value().(type) {
+		case float64:
+			b = strconv.AppendFloat(b, v, 'g', -1, 64)
+		case int64:
+			b = strconv.AppendInt(b, v, 10)
+			b = append(b, 'i')
+		case uint64:
+			b = strconv.AppendUint(b, v, 10)
+			b = append(b, 'u')
+		}
+		b = append(b, ' ')
+		b = strconv.AppendInt(b, v.UnixNano(), 10)
+		b = append(b, '\n')
+		if _, err := w.w.Write(b); err != nil {
+			return err
+		}
+		w.buf = b
+	}
+	return nil
+}
+
+func (w *lineProtocolWriter) close() error {
+	return w.w.Flush()
+}
+
+func (w *lineProtocolWriter) abort() {
+	w.w.Flush()
+}
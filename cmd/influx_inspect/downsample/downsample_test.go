@@ -0,0 +1,171 @@
+package downsample_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/downsample"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile/tsmtest"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+var (
+	orgID          = influxdb.ID(0x1000)
+	bucketID       = influxdb.ID(0x2000)
+	targetBucketID = influxdb.ID(0x3000)
+)
+
+func TestCommand_Run_TSM(t *testing.T) {
+	dir, err := ioutil.TempDir("", "downsample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	data := filepath.Join(dir, "data")
+	first := filepath.Join(data, "000000001-000000001.tsm")
+	tsmtest.WriteFile(t, first, map[string]tsm1.Values{
+		seriesKey(bucketID, "cpu", "usage", "host", "a"): {
+			tsm1.NewValue(0, 1.0), tsm1.NewValue(30, 2.0), tsm1.NewValue(60, 4.0), tsm1.NewValue(90, 100.0),
+		},
+		seriesKey(bucketID, "cpu", "state", "host", "a"):   {tsm1.NewValue(10, "up"), tsm1.NewValue(70, "down")},
+		seriesKey(bucketID+1, "cpu", "usage", "host", "a"): {tsm1.NewValue(10, 1.0)},
+	})
+	// The newer file overrides a value, and tombstones drop another.
+	second := filepath.Join(data, "000000002-000000001.tsm")
+	tsmtest.WriteFile(t, second, map[string]tsm1.Values{
+		seriesKey(bucketID, "cpu", "usage", "host", "a"): {tsm1.NewValue(60, 8.0), tsm1.NewValue(90, 100.0)},
+	})
+	deleteRange(t, first, seriesKey(bucketID, "cpu", "usage", "host", "a"), 90, 90)
+	deleteRange(t, second, seriesKey(bucketID, "cpu", "usage", "host", "a"), 90, 90)
+
+	var stderr bytes.Buffer
+	cmd := &downsample.Command{
+		Stdout:         ioutil.Discard,
+		Stderr:         &stderr,
+		Paths:          []string{data},
+		OrgID:          orgID,
+		BucketID:       bucketID,
+		Every:          60,
+		Aggregates:     []string{downsample.AggregateMean, downsample.AggregateMax, downsample.AggregateCount},
+		OutputDir:      filepath.Join(dir, "out"),
+		Generation:     5,
+		TargetOrgID:    orgID,
+		TargetBucketID: targetBucketID,
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stderr.String(), "downsampled 2 series key(s) of 2 TSM file(s) into 8 rollup(s) of 60ns") {
+		t.Fatalf("unexpected output: %s", stderr.String())
+	}
+
+	got := tsmtest.ReadFile(t, filepath.Join(dir, "out", "000000005-000000001.tsm"))
+	want := map[string][]tsm1.Value{
+		seriesKey(targetBucketID, "cpu", "state_count", "host", "a"): {tsm1.NewValue(0, int64(1)), tsm1.NewValue(60, int64(1))},
+		seriesKey(targetBucketID, "cpu", "usage_count", "host", "a"): {tsm1.NewValue(0, int64(2)), tsm1.NewValue(60, int64(1))},
+		seriesKey(targetBucketID, "cpu", "usage_max", "host", "a"):   {tsm1.NewValue(0, 2.0), tsm1.NewValue(60, 8.0)},
+		seriesKey(targetBucketID, "cpu", "usage_mean", "host", "a"):  {tsm1.NewValue(0, 1.5), tsm1.NewValue(60, 8.0)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected rollups:\ngot  %v\nwant %v", got, want)
+	}
+
+	// Existing files are not overwritten.
+	if err := cmd.Run(); err == nil || !os.IsExist(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCommand_Run_LineProtocol(t *testing.T) {
+	dir, err := ioutil.TempDir("", "downsample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tsmtest.WriteFile(t, filepath.Join(dir, "000000001-000000001.tsm"), map[string]tsm1.Values{
+		seriesKey(bucketID, "disk", "used", "host", "a b"): {
+			tsm1.NewValue(-10, int64(4)), tsm1.NewValue(0, int64(1)), tsm1.NewValue(5, int64(3)),
+		},
+	})
+
+	var out bytes.Buffer
+	cmd := &downsample.Command{
+		Stdout:     ioutil.Discard,
+		Stderr:     ioutil.Discard,
+		Paths:      []string{dir},
+		Every:      10,
+		Aggregates: []string{downsample.AggregateMin, downsample.AggregateMean},
+		Format:     downsample.FormatLineProtocol,
+		Output:     &out,
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := `disk,host=a\ b used_mean=4 -10
+disk,host=a\ b used_mean=2 0
+disk,host=a\ b used_min=4i -10
+disk,host=a\ b used_min=1i 0
+`
+	if out.String() != want {
+		t.Fatalf("unexpected line protocol:\ngot:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestCommand_Run_Invalid(t *testing.T) {
+	for _, tt := range []struct {
+		cmd downsample.Command
+		err string
+	}{
+		{cmd: downsample.Command{OutputDir: "out"}, err: "window duration must be positive"},
+		{cmd: downsample.Command{Every: 1, OutputDir: "out", Aggregates: []string{"median"}}, err: `unsupported aggregate "median"`},
+		{cmd: downsample.Command{Every: 1, Format: "csv"}, err: `unsupported format "csv"`},
+		{cmd: downsample.Command{Every: 1}, err: "output directory required"},
+		{cmd: downsample.Command{Every: 1, OutputDir: "out", TargetOrgID: orgID, TargetBucketID: targetBucketID}, err: "target bucket requires a bucket"},
+	} {
+		if err := tt.cmd.Run(); err == nil || err.Error() != tt.err {
+			t.Errorf("unexpected error: got %v, want %q", err, tt.err)
+		}
+	}
+}
+
+// seriesKey returns the TSM key of the field of a series in a bucket of the
+// test organization.
+func seriesKey(bucketID influxdb.ID, measurement, field string, tags ...string) string {
+	name := tsdb.EncodeName(orgID, bucketID)
+	m := map[string]string{
+		models.MeasurementTagKey: measurement,
+		models.FieldKeyTagKey:    field,
+	}
+	for i := 0; i < len(tags); i += 2 {
+		m[tags[i]] = tags[i+1]
+	}
+	return string(models.MakeKey(name[:], models.NewTags(m))) + "#!~#" + field
+}
+
+// deleteRange records a tombstone for the values of key in [min, max].
+func deleteRange(t *testing.T, path, key string, min, max int64) {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := r.DeleteRange([][]byte{[]byte(key)}, min, max); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,291 @@
+// Package dumpseriesfile dumps the entries of the segments of a series file,
+// and checks the series of a TSI index against them.
+package dumpseriesfile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"text/tabwriter"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsi1"
+)
+
+// Command dumps the entries of the segments of a series file, partition by
+// partition: the insertions of series keys with their IDs, and the tombstones
+// of deleted series.
+//
+// The segments are read directly rather than through the series file, so that
+// corrupt series files which can not be opened can be dumped.
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Path is the directory of the series file.
+	Path string
+
+	// OrgID and BucketID optionally restrict the dump to the series of an
+	// organization, or of a bucket within that organization.
+	OrgID    influxdb.ID
+	BucketID influxdb.ID
+
+	// Measurement optionally restricts the dump to the series of the
+	// measurements it matches.
+	Measurement *regexp.Regexp
+
+	// SummaryOnly reports the number of entries of each partition without
+	// dumping them.
+	SummaryOnly bool
+
+	// IndexPath is the path of a TSI index whose series IDs are checked
+	// against the series file, if set. The IDs of the index missing from the
+	// series file, or deleted from it, are reported.
+	IndexPath string
+}
+
+// NewCommand returns a new instance of Command writing to the standard output and error.
+func NewCommand() *Command {
+	return &Command{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Stats summarizes the entries of a series file.
+type Stats struct {
+	// Inserts is the number of series inserted, and Tombstones the number of
+	// series deleted.
+	Inserts    int
+	Tombstones int
+}
+
+// Run dumps the series file of Path.
+func (cmd *Command) Run() error {
+	if cmd.Path == "" {
+		return errors.New("series file path required")
+	}
+	if cmd.BucketID.Valid() && !cmd.OrgID.Valid() {
+		return errors.New("bucket requires an organization")
+	}
+
+	partitions, err := partitionDirs(cmd.Path)
+	if err != nil {
+		return err
+	}
+
+	// Track the state of every series, to check the index against them.
+	inserted := make(map[tsdb.SeriesID]bool)
+	var total Stats
+	for _, dir := range partitions {
+		stats, err := cmd.dumpPartition(dir, inserted)
+		if err != nil {
+			return fmt.Errorf("%s: %v", dir, err)
+		}
+		total.Inserts += stats.Inserts
+		total.Tombstones += stats.Tombstones
+	}
+	fmt.Fprintf(cmd.Stdout, "%d partition(s): %d series inserted, %d deleted\n", len(partitions), total.Inserts, total.Tombstones)
+
+	if cmd.IndexPath == "" {
+		return nil
+	}
+	return cmd.checkIndex(inserted)
+}
+
+// dumpPartition dumps the segments of the partition in dir, recording in
+// inserted whether each series is inserted or deleted.
+func (cmd *Command) dumpPartition(dir string, inserted map[tsdb.SeriesID]bool) (Stats, error) {
+	var stats Stats
+	segments, err := segmentPaths(dir)
+	if err != nil {
+		return stats, err
+	}
+
+	var tw *tabwriter.Writer
+	if !cmd.SummaryOnly {
+		fmt.Fprintf(cmd.Stdout, "partition %s\n", filepath.Base(dir))
+		tw = tabwriter.NewWriter(cmd.Stdout, 8, 2, 1, ' ', 0)
+		fmt.Fprintln(tw, "  Segment\tOffset\tEntry\tSeries ID\tType\tOrganization\tBucket\tSeries Key")
+	}
+
+	// Tombstones only hold the ID of the series they delete, so that the
+	// series selected by the filters are tracked to select them.
+	selected := make(map[tsdb.SeriesID]bool)
+	for _, path := range segments {
+		id, err := tsdb.ParseSeriesSegmentFilename(filepath.Base(path))
+		if err != nil {
+			return stats, err
+		}
+		segment := tsdb.NewSeriesSegment(id, path)
+		if err := segment.Open(); err != nil {
+			return stats, err
+		}
+
+		err = segment.ForEachEntry(func(flag uint8, typedID tsdb.SeriesIDTyped, offset int64, key []byte) error {
+			sid := typedID.SeriesID()
+			var org, bucket, series, entry, typ string
+			switch flag {
+			case tsdb.SeriesEntryInsertFlag:
+				stats.Inserts++
+				inserted[sid] = true
+
+				var ok bool
+				if org, bucket, series, ok = cmd.describeKey(key); !ok {
+					return nil
+				}
+				selected[sid] = true
+				entry, typ = "insert", typedID.Type().String()
+			case tsdb.SeriesEntryTombstoneFlag:
+				stats.Tombstones++
+				inserted[sid] = false
+
+				if !selected[sid] {
+					return nil
+				}
+				entry, typ, org, bucket, series = "tombstone", "-", "-", "-", "-"
+			}
+
+			if tw != nil {
+				_, pos := tsdb.SplitSeriesOffset(offset)
+				fmt.Fprintf(tw, "  %04x\t%d\t%s\t%d\t%s\t%s\t%s\t%s\n", id, pos, entry, sid.RawID(), typ, org, bucket, series)
+			}
+			return nil
+		})
+		if cerr := segment.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return stats, fmt.Errorf("segment %04x: %v", id, err)
+		}
+	}
+
+	if tw != nil {
+		if err := tw.Flush(); err != nil {
+			return stats, err
+		}
+	}
+	fmt.Fprintf(cmd.Stdout, "partition %s: %d segment(s), %d series inserted, %d deleted\n",
+		filepath.Base(dir), len(segments), stats.Inserts, stats.Tombstones)
+	return stats, nil
+}
+
+// describeKey returns the organization, bucket and series of a series key, and
+// whether it is selected by the filters.
+func (cmd *Command) describeKey(key []byte) (org, bucket, series string, ok bool) {
+	name, tags := tsdb.ParseSeriesKey(key)
+	if len(name) != 16 {
+		// Keys not named after an organization and bucket are only
+		// selected without filters.
+		return "-", "-", fmt.Sprintf("%q", key), !cmd.OrgID.Valid() && cmd.Measurement == nil
+	}
+
+	orgID, bucketID := tsdb.DecodeNameSlice(name)
+	if cmd.OrgID.Valid() && orgID != cmd.OrgID {
+		return "", "", "", false
+	}
+	if cmd.BucketID.Valid() && bucketID != cmd.BucketID {
+		return "", "", "", false
+	}
+	if cmd.Measurement != nil && !cmd.Measurement.Match(tags.Get(models.MeasurementTagKeyBytes)) {
+		return "", "", "", false
+	}
+
+	var buf bytes.Buffer
+	for i, t := range tags {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		switch {
+		case bytes.Equal(t.Key, models.MeasurementTagKeyBytes):
+			buf.WriteString("_measurement")
+		case bytes.Equal(t.Key, models.FieldKeyTagKeyBytes):
+			buf.WriteString("_field")
+		default:
+			buf.Write(t.Key)
+		}
+		buf.WriteByte('=')
+		buf.Write(t.Value)
+	}
+	return orgID.String(), bucketID.String(), buf.String(), true
+}
+
+// checkIndex reports the series of the index which are missing from the
+// series file or deleted from it.
+func (cmd *Command) checkIndex(inserted map[tsdb.SeriesID]bool) error {
+	ctx := context.Background()
+	sfile := tsdb.NewSeriesFile(cmd.Path)
+	if err := sfile.Open(ctx); err != nil {
+		return err
+	}
+	defer sfile.Close()
+
+	idx := tsi1.NewIndex(sfile, tsi1.NewConfig(), tsi1.WithPath(cmd.IndexPath), tsi1.DisableCompactions(), tsi1.DisableMetrics())
+	if err := idx.Open(ctx); err != nil {
+		return err
+	}
+	defer idx.Close()
+
+	// The IDs are iterated in order.
+	var missing, deleted []tsdb.SeriesID
+	var n int
+	idx.SeriesIDSet().ForEach(func(id tsdb.SeriesID) {
+		n++
+		if ok, found := inserted[id]; !found {
+			missing = append(missing, id)
+		} else if !ok {
+			deleted = append(deleted, id)
+		}
+	})
+
+	fmt.Fprintf(cmd.Stdout, "index %s: %d series, %d missing from the series file, %d deleted from it\n",
+		cmd.IndexPath, n, len(missing), len(deleted))
+	for _, ids := range []struct {
+		label string
+		ids   []tsdb.SeriesID
+	}{{"missing", missing}, {"deleted", deleted}} {
+		for _, id := range ids.ids {
+			fmt.Fprintf(cmd.Stdout, "  %s series ID %d\n", ids.label, id.RawID())
+		}
+	}
+	return nil
+}
+
+// partitionDirs returns the sorted partition directories of the series file
+// at path.
+func partitionDirs(path string) ([]string, error) {
+	fis, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, fi := range fis {
+		if fi.IsDir() {
+			dirs = append(dirs, filepath.Join(path, fi.Name()))
+		}
+	}
+	return dirs, nil
+}
+
+// segmentPaths returns the sorted segment files of the partition at dir.
+func segmentPaths(dir string) ([]string, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, fi := range fis {
+		if !fi.IsDir() && tsdb.IsValidSeriesSegmentFilename(fi.Name()) {
+			paths = append(paths, filepath.Join(dir, fi.Name()))
+		}
+	}
+	return paths, nil
+}
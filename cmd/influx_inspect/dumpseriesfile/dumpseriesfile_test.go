@@ -0,0 +1,109 @@
+package dumpseriesfile_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/dumpseriesfile"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsi1"
+	"go.uber.org/zap"
+)
+
+var (
+	orgID    = influxdb.ID(0x1000)
+	bucketID = influxdb.ID(0x2000)
+)
+
+func TestCommand_Run(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dumpseriesfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	indexPath, sfilePath := filepath.Join(dir, "index"), filepath.Join(dir, "_series")
+	writeSeries(t, indexPath, sfilePath, `
+cpu,host=a usage=1
+cpu,host=b usage=1
+mem,host=a free=1
+`, "cpu,host=b usage=1")
+
+	var stdout bytes.Buffer
+	cmd := dumpseriesfile.NewCommand()
+	cmd.Stdout = &stdout
+	cmd.Path = sfilePath
+	cmd.Measurement = regexp.MustCompile("^cpu$")
+	cmd.IndexPath = indexPath
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := stdout.String()
+	for _, exp := range []string{
+		"insert", "_measurement=cpu,host=a,_field=usage",
+		"_measurement=cpu,host=b,_field=usage",
+		"tombstone",
+		"3 series inserted, 1 deleted",
+		"index " + indexPath + ": 3 series, 0 missing from the series file, 1 deleted from it",
+	} {
+		if !strings.Contains(out, exp) {
+			t.Fatalf("expected %q in output:\n%s", exp, out)
+		}
+	}
+	if strings.Contains(out, "_measurement=mem") {
+		t.Fatalf("unexpected measurement in output:\n%s", out)
+	}
+	if got := strings.Count(out, "tombstone"); got != 1 {
+		t.Fatalf("got %d tombstones in output, expected 1:\n%s", got, out)
+	}
+}
+
+// writeSeries creates the series of the line protocol in the index and the
+// series file, and deletes the series of the line del from the series file
+// only.
+func writeSeries(t *testing.T, indexPath, sfilePath string, lp, del string) {
+	t.Helper()
+
+	sfile := tsdb.NewSeriesFile(sfilePath)
+	sfile.Logger = zap.NewNop()
+	if err := sfile.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer sfile.Close()
+	idx := tsi1.NewIndex(sfile, tsi1.NewConfig(), tsi1.WithPath(indexPath), tsi1.DisableMetrics())
+	if err := idx.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+
+	name := tsdb.EncodeName(orgID, bucketID)
+	mm := models.EscapeMeasurement(name[:])
+	points, err := models.ParsePoints([]byte(lp), mm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.CreateSeriesListIfNotExists(tsdb.NewSeriesCollection(points)); err != nil {
+		t.Fatal(err)
+	}
+
+	points, err = models.ParsePoints([]byte(del), mm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := sfile.SeriesID(points[0].Name(), points[0].Tags(), nil)
+	if id.IsZero() {
+		t.Fatalf("series %s not found", del)
+	}
+	if err := sfile.DeleteSeriesID(id); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,340 @@
+// Package exportlp exports the series of TSM files as line protocol,
+// optionally restricted to the series whose tags match a predicate.
+package exportlp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/escape"
+	"github.com/influxdata/influxdb/predicate"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// Command exports the series of TSM files as line protocol.
+//
+// The series are selected from the keys of the indexes of the TSM files, so
+// that the blocks of the other series are never read nor decoded. A line is
+// written for each value of each field of the selected series, in the order
+// of their keys, preceded by a comment naming the organization and bucket
+// whenever they change.
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Paths lists the TSM files to export, and the directories searched
+	// recursively for them.
+	Paths []string
+
+	// OrgID and BucketID optionally restrict the export to the series of an
+	// organization, or of a bucket within that organization.
+	OrgID    influxdb.ID
+	BucketID influxdb.ID
+
+	// Measurements optionally restricts the export to the named measurements.
+	Measurements []string
+
+	// Where is an optional predicate on the tags of the series, such as
+	// `datacenter=eu AND env=prod`, restricting the export to the series
+	// whose tags match it.
+	Where string
+
+	// Start and End optionally restrict the export to the values within the
+	// window [Start, End].
+	Start time.Time
+	End   time.Time
+
+	// Output is the optional file the line protocol is written to, instead
+	// of Stdout.
+	Output string
+
+	where influxdb.Predicate
+}
+
+// NewCommand returns a new instance of Command writing to the standard output and error.
+func NewCommand() *Command {
+	return &Command{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Stats summarizes an export.
+type Stats struct {
+	Series int
+	Values int
+	// Skipped is the number of TSM keys not matching the measurements or
+	// the predicate.
+	Skipped int
+}
+
+// Run exports the TSM files found in Paths.
+func (cmd *Command) Run() error {
+	if len(cmd.Paths) == 0 {
+		return errors.New("path required")
+	}
+	if cmd.BucketID.Valid() && !cmd.OrgID.Valid() {
+		return errors.New("bucket requires an organization")
+	}
+	if !cmd.Start.IsZero() && !cmd.End.IsZero() && cmd.End.Before(cmd.Start) {
+		return errors.New("end must not be before start")
+	}
+	if cmd.Where != "" {
+		node, err := predicate.Parse(cmd.Where)
+		if err != nil {
+			return fmt.Errorf("invalid where predicate: %v", err)
+		}
+		if cmd.where, err = predicate.New(node); err != nil {
+			return fmt.Errorf("invalid where predicate: %v", err)
+		}
+	}
+
+	files, err := tsmfile.Find(cmd.Paths)
+	if err != nil {
+		return err
+	}
+	// Later generations of a shard come last, whatever the order of Paths.
+	sort.Strings(files)
+
+	var readers []*tsm1.TSMReader
+	defer func() {
+		for _, r := range readers {
+			r.Close()
+		}
+	}()
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		r, err := tsm1.NewTSMReader(f)
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("%s: unable to read: %v", path, err)
+		}
+		readers = append(readers, r)
+	}
+
+	// The summary is written to Stderr when the line protocol is written to
+	// Stdout, so that it can be redirected to a file.
+	out, summary := cmd.Stdout, cmd.Stderr
+	var f *os.File
+	if cmd.Output != "" {
+		if f, err = os.Create(cmd.Output); err != nil {
+			return err
+		}
+		defer f.Close()
+		out, summary = f, cmd.Stdout
+	}
+
+	w := bufio.NewWriter(out)
+	s, err := cmd.export(w, readers)
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if f != nil {
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(summary, "exported %d value(s) of %d series from %d TSM file(s), skipped %d key(s)\n", s.Values, s.Series, len(files), s.Skipped)
+	return nil
+}
+
+// export writes the values of the selected keys of the TSM files to w.
+func (cmd *Command) export(w *bufio.Writer, readers []*tsm1.TSMReader) (Stats, error) {
+	var s Stats
+	keys, skipped, err := cmd.scan(readers)
+	if err != nil {
+		return s, err
+	}
+	s.Skipped = skipped
+
+	var (
+		name       []byte
+		lastSeries []byte
+		line       []byte
+	)
+	for _, key := range keys {
+		values, err := cmd.read(readers, key)
+		if err != nil {
+			return s, err
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		seriesKey, field := tsm1.SeriesAndFieldFromCompositeKey(key)
+		keyName, tags := models.ParseKeyBytes(seriesKey)
+		if !bytes.Equal(keyName, name) {
+			name = append(name[:0], keyName...)
+			orgID, bucketID := tsdb.DecodeNameSlice(name)
+			fmt.Fprintf(w, "# org %s bucket %s\n", orgID, bucketID)
+		}
+		if !bytes.Equal(seriesKey, lastSeries) {
+			lastSeries = append(lastSeries[:0], seriesKey...)
+			s.Series++
+		}
+
+		// The prefix of the lines of the values, up to the field value.
+		measurement := tags.Get(models.MeasurementTagKeyBytes)
+		filtered := make(models.Tags, 0, len(tags))
+		for _, t := range tags {
+			if bytes.Equal(t.Key, models.MeasurementTagKeyBytes) || bytes.Equal(t.Key, models.FieldKeyTagKeyBytes) {
+				continue
+			}
+			filtered = append(filtered, t)
+		}
+		prefix := models.AppendMakeKey(nil, measurement, filtered)
+		prefix = append(prefix, ' ')
+		prefix = append(prefix, escape.Bytes(field)...)
+		prefix = append(prefix, '=')
+
+		for _, v := range values {
+			line = append(line[:0], prefix...)
+			line = appendValue(line, v.Value())
+			line = append(line, ' ')
+			line = strconv.AppendInt(line, v.UnixNano(), 10)
+			line = append(line, '\n')
+			if _, err := w.Write(line); err != nil {
+				return s, err
+			}
+		}
+		s.Values += len(values)
+	}
+	return s, nil
+}
+
+// scan returns the sorted TSM keys of the selected series of the TSM files,
+// and the number of keys skipped.
+func (cmd *Command) scan(readers []*tsm1.TSMReader) ([][]byte, int, error) {
+	var (
+		prefix     = tsmfile.Prefix(cmd.OrgID, cmd.BucketID)
+		start, end = cmd.timeRange()
+		seen       = make(map[string]bool) // whether each key matched
+		keys       [][]byte
+		skipped    int
+	)
+	for _, r := range readers {
+		iter := r.Iterator(prefix)
+		for iter.Next() {
+			key := iter.Key()
+			if !bytes.HasPrefix(key, prefix) {
+				break
+			}
+			if _, ok := seen[string(key)]; ok {
+				continue
+			}
+
+			var overlaps bool
+			for _, e := range iter.Entries() {
+				if e.MinTime <= end && e.MaxTime >= start {
+					overlaps = true
+					break
+				}
+			}
+			if !overlaps {
+				// The values of the key in later files may be within the window.
+				continue
+			}
+			matched := cmd.match(key)
+			seen[string(key)] = matched
+			if !matched {
+				skipped++
+				continue
+			}
+			keys = append(keys, append([]byte(nil), key...))
+		}
+		if err := iter.Err(); err != nil {
+			return nil, 0, fmt.Errorf("%s: %v", r.Path(), err)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+	return keys, skipped, nil
+}
+
+// match returns true if the series of the TSM key is exported.
+func (cmd *Command) match(key []byte) bool {
+	if cmd.where != nil && !cmd.where.Matches(key) {
+		return false
+	}
+	if len(cmd.Measurements) == 0 {
+		return true
+	}
+	seriesKey, _ := tsm1.SeriesAndFieldFromCompositeKey(key)
+	_, tags := models.ParseKeyBytes(seriesKey)
+	name := tags.Get(models.MeasurementTagKeyBytes)
+	for _, m := range cmd.Measurements {
+		if string(name) == m {
+			return true
+		}
+	}
+	return false
+}
+
+// read returns the values of the TSM key within the time range, merged from
+// every TSM file holding it, those of later files overwriting earlier ones.
+func (cmd *Command) read(readers []*tsm1.TSMReader, key []byte) (tsm1.Values, error) {
+	var values tsm1.Values
+	for _, r := range readers {
+		if !r.Contains(key) {
+			continue
+		}
+		vs, err := r.ReadAll(key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: unable to read %q: %v", r.Path(), key, err)
+		}
+		values = values.Merge(vs)
+	}
+	start, end := cmd.timeRange()
+	return values.Include(start, end), nil
+}
+
+// timeRange returns the window of the values to export.
+func (cmd *Command) timeRange() (min, max int64) {
+	min, max = math.MinInt64, math.MaxInt64
+	if !cmd.Start.IsZero() {
+		min = cmd.Start.UnixNano()
+	}
+	if !cmd.End.IsZero() {
+		max = cmd.End.UnixNano()
+	}
+	return min, max
+}
+
+// appendValue appends the line protocol encoding of the field value v to b.
+func appendValue(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case float64:
+		return strconv.AppendFloat(b, v, 'f', -1, 64)
+	case int64:
+		return append(strconv.AppendInt(b, v, 10), 'i')
+	case uint64:
+		return append(strconv.AppendUint(b, v, 10), 'u')
+	case string:
+		b = append(b, '"')
+		b = append(b, models.EscapeStringField(v)...)
+		return append(b, '"')
+	case bool:
+		return strconv.AppendBool(b, v)
+	default:
+		panic(fmt.Sprintf("unsupported value type %T", v))
+	}
+}
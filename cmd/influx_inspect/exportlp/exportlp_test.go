@@ -0,0 +1,95 @@
+package exportlp_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/exportlp"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile/tsmtest"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+var (
+	orgID    = influxdb.ID(0x1000)
+	bucketID = influxdb.ID(0x2000)
+)
+
+func TestCommand_Run(t *testing.T) {
+	dir, err := ioutil.TempDir("", "exportlp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tsmtest.WriteFile(t, filepath.Join(dir, "000000001-000000001."+tsm1.TSMFileExtension), map[string]tsm1.Values{
+		seriesKey("cpu", "usage", "datacenter", "eu", "env", "prod"):  {tsm1.NewValue(10, 1.5), tsm1.NewValue(20, 2.5)},
+		seriesKey("cpu", "usage", "datacenter", "eu", "env", "dev"):   {tsm1.NewValue(10, 3.5)},
+		seriesKey("cpu", "usage", "datacenter", "us", "env", "prod"):  {tsm1.NewValue(10, 4.5)},
+		seriesKey("svc", "status", "datacenter", "eu", "env", "prod"): {tsm1.NewValue(10, `up "now"`)},
+	})
+	tsmtest.WriteFile(t, filepath.Join(dir, "000000002-000000001."+tsm1.TSMFileExtension), map[string]tsm1.Values{
+		seriesKey("cpu", "usage", "datacenter", "eu", "env", "prod"): {tsm1.NewValue(20, 5.5), tsm1.NewValue(30, 6.5)},
+		seriesKey("cpu", "count", "datacenter", "eu", "env", "prod"): {tsm1.NewValue(30, int64(3))},
+		seriesKey("mem", "free", "datacenter", "eu", "env", "prod"):  {tsm1.NewValue(40, uint64(9))},
+	})
+
+	var stdout, stderr bytes.Buffer
+	cmd := &exportlp.Command{
+		Stdout:       &stdout,
+		Stderr:       &stderr,
+		Paths:        []string{dir},
+		Measurements: []string{"cpu", "svc"},
+		Where:        "datacenter=eu AND env=prod",
+		End:          time.Unix(0, 30),
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "# org " + orgID.String() + " bucket " + bucketID.String() + "\n" +
+		"cpu,datacenter=eu,env=prod count=3i 30\n" +
+		"cpu,datacenter=eu,env=prod usage=1.5 10\n" +
+		"cpu,datacenter=eu,env=prod usage=5.5 20\n" +
+		"cpu,datacenter=eu,env=prod usage=6.5 30\n" +
+		"svc,datacenter=eu,env=prod status=\"up \\\"now\\\"\" 10\n"
+	if got := stdout.String(); got != want {
+		t.Fatalf("unexpected line protocol:\ngot  %q\nwant %q", got, want)
+	}
+	if got, want := stderr.String(), "exported 5 value(s) of 3 series from 2 TSM file(s), skipped 2 key(s)\n"; got != want {
+		t.Fatalf("unexpected summary: got %q, want %q", got, want)
+	}
+	if _, err := models.ParsePointsString(stdout.String(), "m"); err != nil {
+		t.Fatalf("unable to parse the exported line protocol: %v", err)
+	}
+}
+
+func TestCommand_Run_InvalidWhere(t *testing.T) {
+	cmd := &exportlp.Command{
+		Stdout: ioutil.Discard,
+		Stderr: ioutil.Discard,
+		Paths:  []string{"x"},
+		Where:  "env=",
+	}
+	if err := cmd.Run(); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func seriesKey(measurement, field string, tags ...string) string {
+	name := tsdb.EncodeName(orgID, bucketID)
+	m := map[string]string{
+		models.MeasurementTagKey: measurement,
+		models.FieldKeyTagKey:    field,
+	}
+	for i := 0; i < len(tags); i += 2 {
+		m[tags[i]] = tags[i+1]
+	}
+	return string(models.MakeKey(name[:], models.NewTags(m))) + "#!~#" + field
+}
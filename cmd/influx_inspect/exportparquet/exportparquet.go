@@ -0,0 +1,671 @@
+// Package exportparquet exports the series of TSM files to Parquet files, one
+// per measurement, to move historical data into data lakes.
+package exportparquet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/fs"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// Compressions of the Parquet files.
+const (
+	CompressionNone   = "none"
+	CompressionSnappy = "snappy"
+	CompressionGzip   = "gzip"
+)
+
+// DefaultRowGroupSize is the default number of rows of each row group.
+const DefaultRowGroupSize = 100000
+
+// timeColumn is the name of the column holding the time of each row.
+const timeColumn = "time"
+
+// Command exports the series of TSM files to Parquet files.
+//
+// A Parquet file is written for each measurement of each bucket, named after
+// the measurement in a directory named after the organization and bucket
+// IDs. Each row holds the values of the fields of a series at a time, with a
+// time column, a dictionary encoded column for each tag key and a column
+// for each field of the measurement. Rows are sorted by series, then time.
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Paths lists the TSM files to export, and the directories searched
+	// recursively for them.
+	Paths []string
+
+	// OrgID and BucketID optionally restrict the export to the series of an
+	// organization, or of a bucket within that organization.
+	OrgID    influxdb.ID
+	BucketID influxdb.ID
+
+	// Measurements optionally restricts the export to the named measurements.
+	Measurements []string
+
+	// Start and End optionally restrict the export to the values within the
+	// window [Start, End].
+	Start time.Time
+	End   time.Time
+
+	// Since optionally restricts the export to the blocks whose maximum time
+	// is after Since, so that periodic exports skip the blocks exported by
+	// earlier ones. As whole blocks are exported, the values of a block
+	// compacted with newer values are exported again.
+	Since time.Time
+
+	// StateFile optionally names the file holding the maximum time of the
+	// blocks exported so far. It is read before the export, to be used as
+	// Since if that is not set, and updated once the export has completed.
+	StateFile string
+
+	// OutputDir is the directory the Parquet files are written to.
+	OutputDir string
+
+	// Compression is the compression of the pages of the Parquet files,
+	// CompressionSnappy by default.
+	Compression string
+
+	// RowGroupSize is the maximum number of rows of each row group,
+	// DefaultRowGroupSize if not set.
+	RowGroupSize int
+
+	codec int32
+	since int64
+	// maxTime is the maximum time of the blocks exported.
+	maxTime int64
+}
+
+// exportState is the content of the state file of incremental exports.
+type exportState struct {
+	MaxTime int64 `json:"maxTime"`
+}
+
+// NewCommand returns a new instance of Command writing to the standard output and error.
+func NewCommand() *Command {
+	return &Command{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Run exports the TSM files found in Paths.
+func (cmd *Command) Run() error {
+	if cmd.OutputDir == "" {
+		return errors.New("output directory required")
+	}
+	if cmd.BucketID.Valid() && !cmd.OrgID.Valid() {
+		return errors.New("bucket requires an organization")
+	}
+	if !cmd.Start.IsZero() && !cmd.End.IsZero() && cmd.End.Before(cmd.Start) {
+		return errors.New("end must not be before start")
+	}
+	if (!cmd.Since.IsZero() || cmd.StateFile != "") && !cmd.End.IsZero() {
+		return errors.New("end can not be used with an incremental export")
+	}
+	if cmd.RowGroupSize < 0 {
+		return errors.New("row group size must not be negative")
+	} else if cmd.RowGroupSize == 0 {
+		cmd.RowGroupSize = DefaultRowGroupSize
+	}
+	codec, err := codecOf(cmd.Compression)
+	if err != nil {
+		return err
+	}
+	cmd.codec = codec
+
+	cmd.since = math.MinInt64
+	if !cmd.Since.IsZero() {
+		cmd.since = cmd.Since.UnixNano()
+	} else if cmd.StateFile != "" {
+		state, err := readState(cmd.StateFile)
+		if err != nil {
+			return err
+		}
+		if state != nil {
+			cmd.since = state.MaxTime
+		}
+	}
+	cmd.maxTime = cmd.since
+
+	files, err := tsmfile.Find(cmd.Paths)
+	if err != nil {
+		return err
+	}
+	// Later generations of a shard come last, whatever the order of Paths.
+	sort.Strings(files)
+
+	var readers []*tsm1.TSMReader
+	defer func() {
+		for _, r := range readers {
+			r.Close()
+		}
+	}()
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		r, err := tsm1.NewTSMReader(f)
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("%s: unable to read: %v", path, err)
+		}
+		readers = append(readers, r)
+	}
+
+	tables, keys, err := cmd.scan(readers)
+	if err != nil {
+		return err
+	}
+	e := &exporter{cmd: cmd, readers: readers, tables: tables}
+	if err := e.export(keys); err != nil {
+		return err
+	}
+
+	var rows int64
+	for _, t := range e.written {
+		fmt.Fprintf(cmd.Stdout, "%s: %d row(s) of %d series\n", t.path, t.rows, t.series)
+		rows += t.rows
+	}
+	if e.conflicts > 0 {
+		fmt.Fprintf(cmd.Stderr, "skipped %d value(s) of fields whose type differs between series\n", e.conflicts)
+	}
+	fmt.Fprintf(cmd.Stdout, "exported %d row(s) of %d measurement(s) from %d TSM file(s)\n", rows, len(e.written), len(files))
+
+	if cmd.StateFile != "" && cmd.maxTime != math.MinInt64 {
+		if err := writeState(cmd.StateFile, &exportState{MaxTime: cmd.maxTime}); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.Stdout, "exported blocks up to %s\n", time.Unix(0, cmd.maxTime).UTC().Format(time.RFC3339Nano))
+	}
+	return nil
+}
+
+// readState returns the state of the last export, or nil if there is none.
+func readState(path string) (*exportState, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var state exportState
+	if err := json.Unmarshal(b, &state); err != nil {
+		return nil, fmt.Errorf("%s: invalid state file: %v", path, err)
+	}
+	return &state, nil
+}
+
+// writeState replaces the state file atomically, so that an interrupted
+// export leaves the state of the last completed one.
+func writeState(path string, state *exportState) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0666); err != nil {
+		return err
+	}
+	return fs.RenameFileWithReplacement(tmp, path)
+}
+
+// includeBlock returns true if the values of the block are exported.
+func (cmd *Command) includeBlock(e tsm1.IndexEntry) bool {
+	start, end := cmd.timeRange()
+	return e.MinTime <= end && e.MaxTime >= start && e.MaxTime > cmd.since
+}
+
+// timeRange returns the window of the values to export.
+func (cmd *Command) timeRange() (min, max int64) {
+	min, max = math.MinInt64, math.MaxInt64
+	if !cmd.Start.IsZero() {
+		min = cmd.Start.UnixNano()
+	}
+	if !cmd.End.IsZero() {
+		max = cmd.End.UnixNano()
+	}
+	return min, max
+}
+
+// table is the schema of the Parquet file of a measurement of a bucket.
+type table struct {
+	orgID, bucketID influxdb.ID
+	measurement     string
+	tags            map[string]bool
+	fields          map[string]byte // block type of each field
+
+	path   string
+	rows   int64
+	series int
+}
+
+// seriesKey is a parsed series key of a TSM key.
+type seriesKey struct {
+	table string // organization, bucket and measurement
+	name  []byte
+	tags  models.Tags // without the measurement and field
+}
+
+func parseSeriesKey(key []byte) (seriesKey, []byte) {
+	sk, field := tsm1.SeriesAndFieldFromCompositeKey(key)
+	name, tags := models.ParseKeyBytes(sk)
+	measurement := tags.Get(models.MeasurementTagKeyBytes)
+
+	filtered := make(models.Tags, 0, len(tags))
+	for _, t := range tags {
+		if bytes.Equal(t.Key, models.MeasurementTagKeyBytes) || bytes.Equal(t.Key, models.FieldKeyTagKeyBytes) {
+			continue
+		}
+		filtered = append(filtered, t)
+	}
+	return seriesKey{table: string(name) + "," + string(measurement), name: name, tags: filtered}, field
+}
+
+// scan returns the schema of the table of each measurement from the index of
+// the TSM files, and the sorted TSM keys to export.
+func (cmd *Command) scan(readers []*tsm1.TSMReader) (map[string]*table, [][]byte, error) {
+	var (
+		prefix = tsmfile.Prefix(cmd.OrgID, cmd.BucketID)
+		tables = make(map[string]*table)
+		seen   = make(map[string]bool)
+		keys   [][]byte
+	)
+	for _, r := range readers {
+		iter := r.Iterator(prefix)
+		for iter.Next() {
+			key := iter.Key()
+			if !bytes.HasPrefix(key, prefix) {
+				break
+			}
+
+			var (
+				overlaps bool
+				maxTime  = int64(math.MinInt64)
+			)
+			for _, e := range iter.Entries() {
+				if cmd.includeBlock(e) {
+					overlaps = true
+					if e.MaxTime > maxTime {
+						maxTime = e.MaxTime
+					}
+				}
+			}
+			if !overlaps {
+				continue
+			}
+
+			sk, field := parseSeriesKey(key)
+			t := tables[sk.table]
+			if t == nil {
+				_, tags := models.ParseKeyBytes(key)
+				measurement := string(tags.Get(models.MeasurementTagKeyBytes))
+				if !cmd.matchMeasurement(measurement) {
+					continue
+				}
+				orgID, bucketID := tsdb.DecodeNameSlice(sk.name)
+				t = &table{
+					orgID:       orgID,
+					bucketID:    bucketID,
+					measurement: measurement,
+					tags:        make(map[string]bool),
+					fields:      make(map[string]byte),
+				}
+				tables[sk.table] = t
+			}
+
+			if maxTime > cmd.maxTime {
+				cmd.maxTime = maxTime
+			}
+			for _, tag := range sk.tags {
+				t.tags[string(tag.Key)] = true
+			}
+			// The first type found is kept, values of other types are skipped.
+			if _, ok := t.fields[string(field)]; !ok {
+				t.fields[string(field)] = iter.Type()
+			}
+			if !seen[string(key)] {
+				seen[string(key)] = true
+				keys = append(keys, append([]byte(nil), key...))
+			}
+		}
+		if err := iter.Err(); err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", r.Path(), err)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+	return tables, keys, nil
+}
+
+func (cmd *Command) matchMeasurement(measurement string) bool {
+	if len(cmd.Measurements) == 0 {
+		return true
+	}
+	for _, m := range cmd.Measurements {
+		if m == measurement {
+			return true
+		}
+	}
+	return false
+}
+
+// exporter writes the rows of the series to the Parquet file of their
+// measurement. As TSM keys are sorted, the series of a measurement of a
+// bucket are consecutive and a single file is written at a time.
+type exporter struct {
+	cmd     *Command
+	readers []*tsm1.TSMReader
+	tables  map[string]*table
+
+	table   *table
+	file    *os.File
+	writer  *parquetWriter
+	time    *column
+	tags    map[string]*column
+	fields  map[string]*column
+	written []*table
+
+	conflicts int
+}
+
+func (e *exporter) export(keys [][]byte) error {
+	var (
+		current seriesKey
+		values  = make(map[string]tsm1.Values)
+	)
+	for i, key := range keys {
+		sk, field := parseSeriesKey(key)
+		if i > 0 && !bytes.Equal(sk.seriesKey(), current.seriesKey()) {
+			if err := e.writeSeries(current, values); err != nil {
+				return err
+			}
+			values = make(map[string]tsm1.Values)
+		}
+		current = sk
+
+		vs, err := e.read(key)
+		if err != nil {
+			return err
+		}
+		if len(vs) > 0 {
+			values[string(field)] = vs
+		}
+	}
+	if len(keys) > 0 {
+		if err := e.writeSeries(current, values); err != nil {
+			return err
+		}
+	}
+	return e.close()
+}
+
+// seriesKey returns a key identifying the series, for comparisons.
+func (k seriesKey) seriesKey() []byte {
+	return models.MakeKey([]byte(k.table), k.tags)
+}
+
+// read returns the values of the TSM key within the time range, merged from
+// every TSM file holding it, those of later files overwriting earlier ones.
+func (e *exporter) read(key []byte) (tsm1.Values, error) {
+	var values tsm1.Values
+	for _, r := range e.readers {
+		if !r.Contains(key) {
+			continue
+		}
+		vs, err := r.ReadAll(key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: unable to read %q: %v", r.Path(), key, err)
+		}
+		if e.cmd.since != math.MinInt64 {
+			if vs, err = e.includeBlocks(r, key, vs); err != nil {
+				return nil, err
+			}
+		}
+		values = values.Merge(vs)
+	}
+	start, end := e.cmd.timeRange()
+	return values.Include(start, end), nil
+}
+
+// includeBlocks returns the values of vs within the blocks of the key to
+// export, as the blocks of a TSM file do not overlap.
+func (e *exporter) includeBlocks(r *tsm1.TSMReader, key []byte, vs tsm1.Values) (tsm1.Values, error) {
+	entries, err := r.ReadEntries(key, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: unable to read the index of %q: %v", r.Path(), key, err)
+	}
+	var included tsm1.Values
+	for _, entry := range entries {
+		if e.cmd.includeBlock(entry) {
+			included = append(included, vs.Include(entry.MinTime, entry.MaxTime)...)
+		}
+	}
+	return included, nil
+}
+
+// writeSeries writes a row for each time any of the fields of the series has
+// a value at.
+func (e *exporter) writeSeries(sk seriesKey, values map[string]tsm1.Values) error {
+	if len(values) == 0 {
+		return nil
+	}
+	t := e.tables[sk.table]
+	if t != e.table {
+		if err := e.open(t); err != nil {
+			return err
+		}
+	}
+	t.series++
+
+	tags := make(map[string][]byte, len(sk.tags))
+	for _, tag := range sk.tags {
+		tags[string(tag.Key)] = tag.Value
+	}
+
+	fields := make([]string, 0, len(values))
+	for field := range values {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	// Merge the values of the fields by time.
+	pos := make([]int, len(fields))
+	for {
+		ts := int64(math.MaxInt64)
+		var done = true
+		for i, field := range fields {
+			if vs := values[field]; pos[i] < len(vs) {
+				done = false
+				if v := vs[pos[i]].UnixNano(); v < ts {
+					ts = v
+				}
+			}
+		}
+		if done {
+			return nil
+		}
+
+		e.time.appendInt64(ts)
+		for key, c := range e.tags {
+			if v, ok := tags[key]; ok {
+				c.appendByteArray(v)
+			} else {
+				c.appendNull()
+			}
+		}
+		present := make(map[string]bool, len(fields))
+		for i, field := range fields {
+			vs := values[field]
+			if pos[i] >= len(vs) || vs[pos[i]].UnixNano() != ts {
+				continue
+			}
+			v := vs[pos[i]]
+			pos[i]++
+			if e.appendValue(e.fields[field], v.Value()) {
+				present[field] = true
+			}
+		}
+		for field, c := range e.fields {
+			if !present[field] {
+				c.appendNull()
+			}
+		}
+
+		t.rows++
+		if err := e.writer.endRow(); err != nil {
+			return fmt.Errorf("%s: %v", t.path, err)
+		}
+	}
+}
+
+// appendValue appends the value to the column, returning false if its type
+// differs from the type of the column.
+func (e *exporter) appendValue(c *column, v interface{}) bool {
+	switch v := v.(type) {
+	case float64:
+		if c.typ == typeDouble {
+			c.appendDouble(v)
+			return true
+		}
+	case int64:
+		if c.typ == typeInt64 && c.logical == logicalNone {
+			c.appendInt64(v)
+			return true
+		}
+	case uint64:
+		if c.logical == logicalUint64 {
+			c.appendInt64(int64(v))
+			return true
+		}
+	case bool:
+		if c.typ == typeBoolean {
+			c.appendBoolean(v)
+			return true
+		}
+	case string:
+		if c.typ == typeByteArray {
+			c.appendByteArray([]byte(v))
+			return true
+		}
+	}
+	e.conflicts++
+	return false
+}
+
+// open closes the Parquet file being written and creates the file of the
+// table.
+func (e *exporter) open(t *table) error {
+	if err := e.close(); err != nil {
+		return err
+	}
+
+	dir := filepath.Join(e.cmd.OutputDir, t.orgID.String(), t.bucketID.String())
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	name := url.PathEscape(t.measurement)
+	if e.cmd.since != math.MinInt64 {
+		// Incremental exports do not replace the files of earlier ones.
+		name += "-" + strconv.FormatInt(e.cmd.since, 10)
+	}
+	t.path = filepath.Join(dir, name+".parquet")
+	f, err := os.Create(t.path)
+	if err != nil {
+		return err
+	}
+
+	columns := []*column{{name: timeColumn, typ: typeInt64, logical: logicalTimestamp}}
+	e.tags = make(map[string]*column, len(t.tags))
+	for _, key := range sortedKeys(t.tags) {
+		c := &column{name: key, typ: typeByteArray, logical: logicalString, optional: true, dictionary: true}
+		e.tags[key] = c
+		columns = append(columns, c)
+	}
+	e.fields = make(map[string]*column, len(t.fields))
+	for _, field := range sortedFields(t.fields) {
+		name := field
+		if t.tags[name] || name == timeColumn {
+			// Tags and fields may share a name, but columns can not.
+			name += "_field"
+		}
+		c := &column{name: name, optional: true}
+		switch t.fields[field] {
+		case tsm1.BlockFloat64:
+			c.typ = typeDouble
+		case tsm1.BlockInteger:
+			c.typ = typeInt64
+		case tsm1.BlockUnsigned:
+			c.typ, c.logical = typeInt64, logicalUint64
+		case tsm1.BlockBoolean:
+			c.typ = typeBoolean
+		case tsm1.BlockString:
+			c.typ, c.logical = typeByteArray, logicalString
+		}
+		e.fields[field] = c
+		columns = append(columns, c)
+	}
+
+	w, err := newParquetWriter(f, columns, e.cmd.codec, e.cmd.RowGroupSize)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	e.table, e.file, e.writer, e.time = t, f, w, columns[0]
+	return nil
+}
+
+// close completes the Parquet file being written, if any.
+func (e *exporter) close() error {
+	if e.writer == nil {
+		return nil
+	}
+	t, f, w := e.table, e.file, e.writer
+	e.table, e.file, e.writer = nil, nil, nil
+
+	if err := w.Close(); err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %v", t.path, err)
+	} else if err := f.Close(); err != nil {
+		return err
+	}
+	e.written = append(e.written, t)
+	return nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedFields(m map[string]byte) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
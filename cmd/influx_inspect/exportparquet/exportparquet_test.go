@@ -0,0 +1,542 @@
+package exportparquet_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/exportparquet"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile/tsmtest"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+var (
+	orgID    = influxdb.ID(0x1000)
+	bucketID = influxdb.ID(0x2000)
+)
+
+func TestCommand_Run(t *testing.T) {
+	for _, compression := range []string{exportparquet.CompressionNone, exportparquet.CompressionSnappy, exportparquet.CompressionGzip} {
+		t.Run(compression, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "exportparquet")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			tsmtest.WriteFile(t, filepath.Join(dir, "000000001-000000001."+tsm1.TSMFileExtension), map[string]tsm1.Values{
+				seriesKey("cpu", "usage", "host", "a"):                {tsm1.NewValue(10, 1.5), tsm1.NewValue(20, 2.5)},
+				seriesKey("cpu", "idle", "host", "a"):                 {tsm1.NewValue(20, int64(7)), tsm1.NewValue(30, int64(8))},
+				seriesKey("cpu", "usage", "host", "b", "region", "w"): {tsm1.NewValue(10, 3.5)},
+				seriesKey("disk", "used", "host", "a"):                {tsm1.NewValue(10, uint64(1))},
+			})
+
+			var stdout bytes.Buffer
+			out := filepath.Join(dir, "out")
+			cmd := &exportparquet.Command{
+				Stdout:       &stdout,
+				Stderr:       ioutil.Discard,
+				Paths:        []string{dir},
+				OrgID:        orgID,
+				BucketID:     bucketID,
+				Measurements: []string{"cpu"},
+				OutputDir:    out,
+				Compression:  compression,
+				RowGroupSize: 3,
+			}
+			if err := cmd.Run(); err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join(out, orgID.String(), bucketID.String(), "cpu.parquet")
+			if got, want := stdout.String(), path+": 4 row(s) of 2 series\nexported 4 row(s) of 1 measurement(s) from 1 TSM file(s)\n"; got != want {
+				t.Fatalf("unexpected output: got %q, want %q", got, want)
+			}
+			if _, err := os.Stat(filepath.Join(out, orgID.String(), bucketID.String(), "disk.parquet")); !os.IsNotExist(err) {
+				t.Fatalf("unexpected file of an excluded measurement: %v", err)
+			}
+
+			meta := readFooter(t, path)
+			if got, want := meta[3], int64(4); got != want {
+				t.Fatalf("unexpected number of rows: got %v, want %v", got, want)
+			}
+			var names []string
+			for _, e := range meta[2].([]interface{}) {
+				names = append(names, string(e.(map[int16]interface{})[4].([]byte)))
+			}
+			if want := []string{"schema", "time", "host", "region", "idle", "usage"}; !reflect.DeepEqual(names, want) {
+				t.Fatalf("unexpected schema: got %q, want %q", names, want)
+			}
+
+			// The rows are split into a group of 3 rows and a group of 1 row.
+			groups := meta[4].([]interface{})
+			if len(groups) != 2 {
+				t.Fatalf("unexpected number of row groups: %d", len(groups))
+			}
+			first := groups[0].(map[int16]interface{})
+			if got, want := first[3], int64(3); got != want {
+				t.Fatalf("unexpected number of rows of the first group: got %v, want %v", got, want)
+			}
+
+			// The statistics of the first group reflect the rows of host a.
+			stats := func(i int) map[int16]interface{} {
+				chunk := first[1].([]interface{})[i].(map[int16]interface{})
+				return chunk[3].(map[int16]interface{})[12].(map[int16]interface{})
+			}
+			if min, max := stats(0)[6].([]byte), stats(0)[5].([]byte); binary.LittleEndian.Uint64(min) != 10 || binary.LittleEndian.Uint64(max) != 30 {
+				t.Fatalf("unexpected time range: %v, %v", min, max)
+			}
+			if got, want := stats(2)[3], int64(3); got != want {
+				t.Fatalf("unexpected null count of region: got %v, want %v", got, want)
+			}
+			if got, want := stats(3)[3], int64(1); got != want {
+				t.Fatalf("unexpected null count of idle: got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCommand_Run_Incremental(t *testing.T) {
+	dir, err := ioutil.TempDir("", "exportparquet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	data := filepath.Join(dir, "data")
+	if err := os.Mkdir(data, 0777); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out")
+	state := filepath.Join(dir, "state.json")
+	export := func() string {
+		t.Helper()
+		var stdout bytes.Buffer
+		cmd := &exportparquet.Command{
+			Stdout:    &stdout,
+			Stderr:    ioutil.Discard,
+			Paths:     []string{data},
+			OutputDir: out,
+			StateFile: state,
+		}
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+		return stdout.String()
+	}
+	bucketDir := filepath.Join(out, orgID.String(), bucketID.String())
+
+	tsmtest.WriteFile(t, filepath.Join(data, "000000001-000000001."+tsm1.TSMFileExtension), map[string]tsm1.Values{
+		seriesKey("cpu", "usage", "host", "a"): {tsm1.NewValue(10, 1.5), tsm1.NewValue(20, 2.5)},
+		seriesKey("cpu", "usage", "host", "b"): {tsm1.NewValue(30, 3.5)},
+	})
+	if got, want := export(), filepath.Join(bucketDir, "cpu.parquet")+": 3 row(s) of 2 series\n"+
+		"exported 3 row(s) of 1 measurement(s) from 1 TSM file(s)\n"+
+		"exported blocks up to 1970-01-01T00:00:00.00000003Z\n"; got != want {
+		t.Fatalf("unexpected output of the first export: got %q, want %q", got, want)
+	}
+
+	// Only the block newer than the last export is exported.
+	tsmtest.WriteFile(t, filepath.Join(data, "000000002-000000001."+tsm1.TSMFileExtension), map[string]tsm1.Values{
+		seriesKey("cpu", "usage", "host", "a"): {tsm1.NewValue(40, 4.5)},
+	})
+	if got, want := export(), filepath.Join(bucketDir, "cpu-30.parquet")+": 1 row(s) of 1 series\n"+
+		"exported 1 row(s) of 1 measurement(s) from 2 TSM file(s)\n"+
+		"exported blocks up to 1970-01-01T00:00:00.00000004Z\n"; got != want {
+		t.Fatalf("unexpected output of the second export: got %q, want %q", got, want)
+	}
+	if got := readFooter(t, filepath.Join(bucketDir, "cpu.parquet"))[3]; got != int64(3) {
+		t.Fatalf("unexpected number of rows of the first export: %v", got)
+	}
+
+	// Nothing is exported without new blocks.
+	if got, want := export(), "exported 0 row(s) of 0 measurement(s) from 2 TSM file(s)\n"+
+		"exported blocks up to 1970-01-01T00:00:00.00000004Z\n"; got != want {
+		t.Fatalf("unexpected output of the third export: got %q, want %q", got, want)
+	}
+	if b, err := ioutil.ReadFile(state); err != nil {
+		t.Fatal(err)
+	} else if got, want := string(b), `{"maxTime":40}`; got != want {
+		t.Fatalf("unexpected state: got %s, want %s", got, want)
+	}
+}
+
+func TestCommand_Run_Values(t *testing.T) {
+	for _, compression := range []string{exportparquet.CompressionNone, exportparquet.CompressionSnappy, exportparquet.CompressionGzip} {
+		t.Run(compression, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "exportparquet")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			tsmtest.WriteFile(t, filepath.Join(dir, "000000001-000000001."+tsm1.TSMFileExtension), map[string]tsm1.Values{
+				seriesKey("m", "f", "host", "a"): {tsm1.NewValue(10, 1.5), tsm1.NewValue(20, 2.5)},
+				seriesKey("m", "i", "host", "a"): {tsm1.NewValue(10, int64(-3))},
+				seriesKey("m", "u", "host", "a"): {tsm1.NewValue(10, uint64(4))},
+				seriesKey("m", "b", "host", "a"): {tsm1.NewValue(10, true)},
+				seriesKey("m", "s", "host", "a"): {tsm1.NewValue(10, "x"), tsm1.NewValue(20, "y")},
+				seriesKey("m", "u", "host", "b"): {tsm1.NewValue(10, uint64(math.MaxUint64))},
+				seriesKey("m", "b", "host", "b"): {tsm1.NewValue(10, false)},
+				seriesKey("m", "s", "host", "b"): {tsm1.NewValue(10, "x")},
+			})
+
+			out := filepath.Join(dir, "out")
+			cmd := &exportparquet.Command{
+				Stdout:       ioutil.Discard,
+				Stderr:       ioutil.Discard,
+				Paths:        []string{dir},
+				OutputDir:    out,
+				Compression:  compression,
+				RowGroupSize: 2,
+			}
+			if err := cmd.Run(); err != nil {
+				t.Fatal(err)
+			}
+
+			// The rows are split into two groups, so that the tags are
+			// encoded with a dictionary for each group.
+			got := readColumns(t, filepath.Join(out, orgID.String(), bucketID.String(), "m.parquet"))
+			want := map[string][]interface{}{
+				"time": {int64(10), int64(20), int64(10)},
+				"host": {"a", "a", "b"},
+				"b":    {true, nil, false},
+				"f":    {1.5, 2.5, nil},
+				"i":    {int64(-3), nil, nil},
+				"s":    {"x", "y", "x"},
+				"u":    {uint64(4), nil, uint64(math.MaxUint64)},
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("unexpected values: got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCommand_Run_Invalid(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		cmd  exportparquet.Command
+		err  string
+	}{
+		{name: "no output", cmd: exportparquet.Command{}, err: "output directory required"},
+		{name: "bucket without org", cmd: exportparquet.Command{OutputDir: "out", BucketID: bucketID}, err: "bucket requires an organization"},
+		{name: "end before start", cmd: exportparquet.Command{OutputDir: "out", Start: time.Unix(10, 0), End: time.Unix(5, 0)}, err: "end must not be before start"},
+		{name: "end of incremental export", cmd: exportparquet.Command{OutputDir: "out", Since: time.Unix(10, 0), End: time.Unix(20, 0)}, err: "incremental export"},
+		{name: "compression", cmd: exportparquet.Command{OutputDir: "out", Compression: "lz4"}, err: "compression"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := tt.cmd
+			cmd.Stdout, cmd.Stderr = ioutil.Discard, ioutil.Discard
+			if err := cmd.Run(); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("unexpected error: got %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+// seriesKey returns the TSM key of the field of a series in the test bucket.
+func seriesKey(measurement, field string, tags ...string) string {
+	name := tsdb.EncodeName(orgID, bucketID)
+	m := map[string]string{
+		models.MeasurementTagKey: measurement,
+		models.FieldKeyTagKey:    field,
+	}
+	for i := 0; i < len(tags); i += 2 {
+		m[tags[i]] = tags[i+1]
+	}
+	return string(models.MakeKey(name[:], models.NewTags(m))) + "#!~#" + field
+}
+
+// readFooter returns the fields of the metadata of the Parquet file at path.
+func readFooter(t *testing.T, path string) map[int16]interface{} {
+	t.Helper()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) < 12 || string(b[:4]) != "PAR1" || string(b[len(b)-4:]) != "PAR1" {
+		t.Fatalf("missing magic number")
+	}
+	n := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	r := &thriftReader{b: b[len(b)-8-n : len(b)-8]}
+	meta := r.readStruct()
+	if r.err != "" {
+		t.Fatalf("invalid metadata: %s", r.err)
+	}
+	return meta
+}
+
+// readColumns returns the values of each column of the Parquet file at path,
+// decoding the dictionary and data pages of every row group. Nulls are nil.
+func readColumns(t *testing.T, path string) map[string][]interface{} {
+	t.Helper()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := readFooter(t, path)
+	schema := meta[2].([]interface{})[1:]
+
+	columns := make(map[string][]interface{})
+	for _, g := range meta[4].([]interface{}) {
+		group := g.(map[int16]interface{})
+		numRows := int(group[3].(int64))
+		for i, c := range group[1].([]interface{}) {
+			elem := schema[i].(map[int16]interface{})
+			name := string(elem[4].([]byte))
+			md := c.(map[int16]interface{})[3].(map[int16]interface{})
+			codec := md[4].(int64)
+
+			var dict []interface{}
+			if offset, ok := md[11].(int64); ok {
+				header, page := readPage(t, b[offset:], codec)
+				dict = decodePlain(t, page, elem, int(header[7].(map[int16]interface{})[1].(int64)))
+			}
+
+			header, page := readPage(t, b[md[9].(int64):], codec)
+			data := header[5].(map[int16]interface{})
+			if n := int(data[1].(int64)); n != numRows {
+				t.Fatalf("%s: unexpected number of values: got %d, want %d", name, n, numRows)
+			}
+
+			defined := make([]uint32, numRows)
+			for i := range defined {
+				defined[i] = 1
+			}
+			if elem[3].(int64) == 1 {
+				n := int(binary.LittleEndian.Uint32(page))
+				defined = decodeRLE(t, page[4:4+n], 1, numRows)
+				page = page[4+n:]
+			}
+			var count int
+			for _, d := range defined {
+				count += int(d)
+			}
+
+			var values []interface{}
+			if data[2].(int64) == 2 { // dictionary encoded
+				for _, i := range decodeRLE(t, page[1:], int(page[0]), count) {
+					values = append(values, dict[i])
+				}
+			} else {
+				values = decodePlain(t, page, elem, count)
+			}
+			for _, d := range defined {
+				if d == 0 {
+					columns[name] = append(columns[name], nil)
+					continue
+				}
+				columns[name] = append(columns[name], values[0])
+				values = values[1:]
+			}
+		}
+	}
+	return columns
+}
+
+// readPage returns the header and the uncompressed body of the page at the
+// start of b.
+func readPage(t *testing.T, b []byte, codec int64) (map[int16]interface{}, []byte) {
+	t.Helper()
+
+	r := &thriftReader{b: b}
+	header := r.readStruct()
+	if r.err != "" {
+		t.Fatalf("invalid page header: %s", r.err)
+	}
+	body := r.b[:header[3].(int64)]
+
+	switch codec {
+	case 1:
+		page, err := snappy.Decode(nil, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body = page
+	case 2:
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body, err = ioutil.ReadAll(gz); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if int64(len(body)) != header[2].(int64) {
+		t.Fatalf("unexpected page size: got %d, want %d", len(body), header[2])
+	}
+	return header, body
+}
+
+// decodePlain decodes n plain encoded values of the column of the schema
+// element elem.
+func decodePlain(t *testing.T, b []byte, elem map[int16]interface{}, n int) []interface{} {
+	t.Helper()
+
+	converted, _ := elem[6].(int64)
+	values := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		switch elem[1].(int64) {
+		case 0: // boolean
+			values = append(values, b[i/8]&(1<<uint(i%8)) != 0)
+		case 2: // int64
+			v := binary.LittleEndian.Uint64(b)
+			if converted == 14 {
+				values = append(values, v)
+			} else {
+				values = append(values, int64(v))
+			}
+			b = b[8:]
+		case 5: // double
+			values = append(values, math.Float64frombits(binary.LittleEndian.Uint64(b)))
+			b = b[8:]
+		case 6: // byte array
+			size := binary.LittleEndian.Uint32(b)
+			values = append(values, string(b[4:4+size]))
+			b = b[4+size:]
+		default:
+			t.Fatalf("unsupported type %v", elem[1])
+		}
+	}
+	return values
+}
+
+// decodeRLE decodes n values of width bits with the RLE/bit-packing hybrid
+// encoding.
+func decodeRLE(t *testing.T, b []byte, width, n int) []uint32 {
+	t.Helper()
+
+	var values []uint32
+	for len(values) < n {
+		header, k := binary.Uvarint(b)
+		if k <= 0 {
+			t.Fatalf("invalid run header")
+		}
+		b = b[k:]
+		if header&1 == 1 {
+			// Bit-packed groups of 8 values.
+			count := int(header>>1) * 8
+			for i := 0; i < count; i++ {
+				var v uint32
+				for j := 0; j < width; j++ {
+					bit := i*width + j
+					v |= uint32(b[bit/8]>>uint(bit%8)&1) << uint(j)
+				}
+				values = append(values, v)
+			}
+			b = b[count*width/8:]
+			continue
+		}
+		var v uint32
+		for j := 0; j < (width+7)/8; j++ {
+			v |= uint32(b[j]) << (8 * uint(j))
+		}
+		b = b[(width+7)/8:]
+		for i := 0; i < int(header>>1); i++ {
+			values = append(values, v)
+		}
+	}
+	return values[:n]
+}
+
+// thriftReader decodes structs of the Thrift compact protocol.
+type thriftReader struct {
+	b   []byte
+	err string
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		r.err = "invalid varint"
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *thriftReader) varint() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) byte() byte {
+	if len(r.b) == 0 {
+		r.err = "unexpected end"
+		return 0
+	}
+	c := r.b[0]
+	r.b = r.b[1:]
+	return c
+}
+
+func (r *thriftReader) readStruct() map[int16]interface{} {
+	fields := make(map[int16]interface{})
+	var id int16
+	for r.err == "" {
+		c := r.byte()
+		if c == 0 {
+			break
+		}
+		if delta := int16(c >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(r.varint())
+		}
+		switch typ := c & 0x0f; typ {
+		case 1, 2:
+			fields[id] = typ == 1
+		default:
+			fields[id] = r.readValue(typ)
+		}
+	}
+	return fields
+}
+
+func (r *thriftReader) readValue(typ byte) interface{} {
+	switch typ {
+	case 1, 2, 3:
+		return r.byte()
+	case 4, 5, 6:
+		return r.varint()
+	case 8:
+		n := int(r.uvarint())
+		if n > len(r.b) {
+			r.err = "unexpected end"
+			return nil
+		}
+		v := r.b[:n]
+		r.b = r.b[n:]
+		return v
+	case 9:
+		c := r.byte()
+		n := int(c >> 4)
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		list := make([]interface{}, 0, n)
+		for i := 0; i < n && r.err == ""; i++ {
+			list = append(list, r.readValue(c&0x0f))
+		}
+		return list
+	case 12:
+		return r.readStruct()
+	}
+	r.err = "unsupported type"
+	return nil
+}
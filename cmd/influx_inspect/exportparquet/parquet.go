@@ -0,0 +1,577 @@
+package exportparquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/golang/snappy"
+)
+
+// parquetMagic starts and ends every Parquet file.
+const parquetMagic = "PAR1"
+
+// createdBy is recorded in the metadata of the Parquet files written.
+const createdBy = "influxd inspect export-parquet"
+
+// Physical types of Parquet columns.
+const (
+	typeBoolean   int32 = 0
+	typeInt64     int32 = 2
+	typeDouble    int32 = 5
+	typeByteArray int32 = 6
+)
+
+// Encodings of Parquet pages.
+const (
+	encodingPlain           int32 = 0
+	encodingPlainDictionary int32 = 2
+	encodingRLE             int32 = 3
+)
+
+// Types of Parquet pages.
+const (
+	pageData       int32 = 0
+	pageDictionary int32 = 2
+)
+
+// Compression codecs of Parquet pages.
+const (
+	codecUncompressed int32 = 0
+	codecSnappy       int32 = 1
+	codecGzip         int32 = 2
+)
+
+// Converted types of Parquet columns, read by older readers instead of their
+// logical type.
+const (
+	convertedUTF8   int32 = 0
+	convertedUint64 int32 = 14
+)
+
+// logicalType is the logical type of a Parquet column, which annotates how
+// its physical type is interpreted.
+type logicalType int
+
+const (
+	logicalNone logicalType = iota
+	logicalString
+	logicalTimestamp // in nanoseconds, adjusted to UTC
+	logicalUint64
+)
+
+// column is a flat column of a Parquet file, buffering the values of the
+// current row group.
+type column struct {
+	name     string
+	typ      int32
+	logical  logicalType
+	optional bool
+	// dictionary encodes the values, which must be byte arrays, as indices
+	// into a dictionary of the distinct values of each row group.
+	dictionary bool
+
+	defined    []bool // whether each row has a value, if optional
+	ints       []int64
+	floats     []float64
+	bools      []bool
+	bytes      [][]byte
+	dict       map[string]uint32
+	dictValues [][]byte
+	indices    []uint32
+}
+
+func (c *column) define() {
+	if c.optional {
+		c.defined = append(c.defined, true)
+	}
+}
+
+func (c *column) appendNull() {
+	c.defined = append(c.defined, false)
+}
+
+func (c *column) appendInt64(v int64) {
+	c.define()
+	c.ints = append(c.ints, v)
+}
+
+func (c *column) appendDouble(v float64) {
+	c.define()
+	c.floats = append(c.floats, v)
+}
+
+func (c *column) appendBoolean(v bool) {
+	c.define()
+	c.bools = append(c.bools, v)
+}
+
+func (c *column) appendByteArray(v []byte) {
+	c.define()
+	if !c.dictionary {
+		c.bytes = append(c.bytes, append([]byte(nil), v...))
+		return
+	}
+
+	if c.dict == nil {
+		c.dict = make(map[string]uint32)
+	}
+	i, ok := c.dict[string(v)]
+	if !ok {
+		i = uint32(len(c.dictValues))
+		c.dict[string(v)] = i
+		c.dictValues = append(c.dictValues, append([]byte(nil), v...))
+	}
+	c.indices = append(c.indices, i)
+}
+
+// reset clears the values of the row group, once written.
+func (c *column) reset() {
+	c.defined, c.ints, c.floats, c.bools, c.bytes = c.defined[:0], c.ints[:0], c.floats[:0], c.bools[:0], c.bytes[:0]
+	c.dict, c.dictValues, c.indices = nil, nil, c.indices[:0]
+}
+
+// plainValues returns the values of the row group with the plain encoding.
+func (c *column) plainValues() []byte {
+	var b []byte
+	switch c.typ {
+	case typeInt64:
+		for _, v := range c.ints {
+			b = appendUint64(b, uint64(v))
+		}
+	case typeDouble:
+		for _, v := range c.floats {
+			b = appendUint64(b, math.Float64bits(v))
+		}
+	case typeBoolean:
+		b = make([]byte, (len(c.bools)+7)/8)
+		for i, v := range c.bools {
+			if v {
+				b[i/8] |= 1 << uint(i%8)
+			}
+		}
+	case typeByteArray:
+		b = appendByteArrays(b, c.bytes)
+	}
+	return b
+}
+
+// stats returns the plain encoded minimum and maximum values of the row
+// group, in the sort order of the column, or nil if there are none.
+func (c *column) stats() (min, max []byte) {
+	switch c.typ {
+	case typeInt64:
+		if len(c.ints) == 0 {
+			return nil, nil
+		}
+		lo, hi := c.ints[0], c.ints[0]
+		for _, v := range c.ints[1:] {
+			if c.logical == logicalUint64 {
+				if uint64(v) < uint64(lo) {
+					lo = v
+				}
+				if uint64(v) > uint64(hi) {
+					hi = v
+				}
+				continue
+			}
+			if v < lo {
+				lo = v
+			}
+			if v > hi {
+				hi = v
+			}
+		}
+		return appendUint64(nil, uint64(lo)), appendUint64(nil, uint64(hi))
+	case typeDouble:
+		if len(c.floats) == 0 {
+			return nil, nil
+		}
+		lo, hi := c.floats[0], c.floats[0]
+		for _, v := range c.floats[1:] {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+		return appendUint64(nil, math.Float64bits(lo)), appendUint64(nil, math.Float64bits(hi))
+	case typeByteArray:
+		values := c.bytes
+		if c.dictionary {
+			values = c.dictValues
+		}
+		if len(values) == 0 {
+			return nil, nil
+		}
+		lo, hi := values[0], values[0]
+		for _, v := range values[1:] {
+			if bytes.Compare(v, lo) < 0 {
+				lo = v
+			}
+			if bytes.Compare(v, hi) > 0 {
+				hi = v
+			}
+		}
+		return lo, hi
+	}
+	return nil, nil
+}
+
+// columnChunk describes the pages of a column written for a row group.
+type columnChunk struct {
+	offset           int64
+	dictionaryOffset int64 // -1 if the column has no dictionary
+	dataOffset       int64
+	numValues        int64
+	nullCount        int64
+	uncompressed     int64
+	compressed       int64
+	min, max         []byte
+}
+
+type rowGroup struct {
+	columns   []columnChunk
+	numRows   int64
+	totalSize int64
+}
+
+// parquetWriter writes rows to a Parquet file, one row group of up to
+// rowGroupSize rows at a time. Each column chunk of a row group is written
+// as a single data page, preceded by a dictionary page for dictionary
+// encoded columns.
+type parquetWriter struct {
+	w            io.Writer
+	offset       int64
+	codec        int32
+	columns      []*column
+	rowGroupSize int
+
+	rows      int // rows of the current row group
+	numRows   int64
+	rowGroups []rowGroup
+}
+
+func newParquetWriter(w io.Writer, columns []*column, codec int32, rowGroupSize int) (*parquetWriter, error) {
+	pw := &parquetWriter{w: w, codec: codec, columns: columns, rowGroupSize: rowGroupSize}
+	if err := pw.write([]byte(parquetMagic)); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+func (w *parquetWriter) write(b []byte) error {
+	n, err := w.w.Write(b)
+	w.offset += int64(n)
+	return err
+}
+
+// endRow ends the current row, once a value or null has been appended to
+// each column, writing the row group if it is full.
+func (w *parquetWriter) endRow() error {
+	w.rows++
+	if w.rows >= w.rowGroupSize {
+		return w.flush()
+	}
+	return nil
+}
+
+// flush writes the rows buffered as a row group.
+func (w *parquetWriter) flush() error {
+	if w.rows == 0 {
+		return nil
+	}
+
+	rg := rowGroup{numRows: int64(w.rows)}
+	for _, c := range w.columns {
+		cc, err := w.writeColumnChunk(c)
+		if err != nil {
+			return err
+		}
+		rg.columns = append(rg.columns, cc)
+		rg.totalSize += cc.uncompressed
+		c.reset()
+	}
+	w.rowGroups = append(w.rowGroups, rg)
+	w.numRows += int64(w.rows)
+	w.rows = 0
+	return nil
+}
+
+func (w *parquetWriter) writeColumnChunk(c *column) (columnChunk, error) {
+	cc := columnChunk{offset: w.offset, dictionaryOffset: -1, numValues: int64(w.rows)}
+
+	var (
+		page     []byte
+		encoding = encodingPlain
+	)
+	if c.optional {
+		levels := make([]uint32, len(c.defined))
+		for i, defined := range c.defined {
+			if defined {
+				levels[i] = 1
+			} else {
+				cc.nullCount++
+			}
+		}
+		rle := appendRLE(nil, levels, 1)
+		page = appendUint32(page, uint32(len(rle)))
+		page = append(page, rle...)
+	}
+
+	if c.dictionary {
+		cc.dictionaryOffset = w.offset
+		if err := w.writePage(&cc, pageDictionary, len(c.dictValues), encodingPlainDictionary, appendByteArrays(nil, c.dictValues)); err != nil {
+			return cc, err
+		}
+		var max uint32
+		if len(c.dictValues) > 0 {
+			max = uint32(len(c.dictValues) - 1)
+		}
+		width := bitWidth(max)
+		page = append(page, byte(width))
+		page = appendRLE(page, c.indices, width)
+		encoding = encodingPlainDictionary
+	} else {
+		page = append(page, c.plainValues()...)
+	}
+
+	cc.dataOffset = w.offset
+	if err := w.writePage(&cc, pageData, w.rows, encoding, page); err != nil {
+		return cc, err
+	}
+	cc.min, cc.max = c.stats()
+	return cc, nil
+}
+
+// writePage writes a page of n values, compressed with the codec of the
+// file, preceded by its header.
+func (w *parquetWriter) writePage(cc *columnChunk, typ int32, n int, encoding int32, body []byte) error {
+	compressed, err := w.compress(body)
+	if err != nil {
+		return err
+	}
+
+	h := newThriftWriter()
+	h.i32(1, typ)
+	h.i32(2, int32(len(body)))
+	h.i32(3, int32(len(compressed)))
+	if typ == pageDictionary {
+		h.structField(7)
+		h.i32(1, int32(n))
+		h.i32(2, encoding)
+		h.structEnd()
+	} else {
+		h.structField(5)
+		h.i32(1, int32(n))
+		h.i32(2, encoding)
+		h.i32(3, encodingRLE) // definition levels
+		h.i32(4, encodingRLE) // repetition levels
+		h.structEnd()
+	}
+	h.structEnd()
+
+	if err := w.write(h.buf); err != nil {
+		return err
+	} else if err := w.write(compressed); err != nil {
+		return err
+	}
+	cc.uncompressed += int64(len(h.buf) + len(body))
+	cc.compressed += int64(len(h.buf) + len(compressed))
+	return nil
+}
+
+func (w *parquetWriter) compress(b []byte) ([]byte, error) {
+	switch w.codec {
+	case codecSnappy:
+		return snappy.Encode(nil, b), nil
+	case codecGzip:
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write(b); err != nil {
+			return nil, err
+		} else if err := gz.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return b, nil
+}
+
+// Close writes the remaining rows and the metadata of the file. It does not
+// close the underlying writer.
+func (w *parquetWriter) Close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+
+	t := newThriftWriter()
+	t.i32(1, 1) // version
+
+	t.list(2, thriftStruct, len(w.columns)+1)
+	t.structBegin()
+	t.binary(4, []byte("schema"))
+	t.i32(5, int32(len(w.columns)))
+	t.structEnd()
+	for _, c := range w.columns {
+		writeSchemaElement(t, c)
+	}
+
+	t.i64(3, w.numRows)
+	t.list(4, thriftStruct, len(w.rowGroups))
+	for _, rg := range w.rowGroups {
+		t.structBegin()
+		t.list(1, thriftStruct, len(rg.columns))
+		for i, cc := range rg.columns {
+			writeColumnChunk(t, w.columns[i], cc, w.codec)
+		}
+		t.i64(2, rg.totalSize)
+		t.i64(3, rg.numRows)
+		t.structEnd()
+	}
+	t.binary(6, []byte(createdBy))
+	t.structEnd()
+
+	if err := w.write(t.buf); err != nil {
+		return err
+	} else if err := w.write(appendUint32(nil, uint32(len(t.buf)))); err != nil {
+		return err
+	}
+	return w.write([]byte(parquetMagic))
+}
+
+func writeSchemaElement(t *thriftWriter, c *column) {
+	t.structBegin()
+	t.i32(1, c.typ)
+	if c.optional {
+		t.i32(3, 1)
+	} else {
+		t.i32(3, 0)
+	}
+	t.binary(4, []byte(c.name))
+
+	switch c.logical {
+	case logicalString:
+		t.i32(6, convertedUTF8)
+		t.structField(10)
+		t.structField(1)
+		t.structEnd()
+		t.structEnd()
+	case logicalTimestamp:
+		t.structField(10)
+		t.structField(8)
+		t.bool(1, true) // adjusted to UTC
+		t.structField(2)
+		t.structField(3) // nanoseconds
+		t.structEnd()
+		t.structEnd()
+		t.structEnd()
+		t.structEnd()
+	case logicalUint64:
+		t.i32(6, convertedUint64)
+		t.structField(10)
+		t.structField(10)
+		t.field(1, thriftByte)
+		t.buf = append(t.buf, 64) // bit width
+		t.bool(2, false)          // unsigned
+		t.structEnd()
+		t.structEnd()
+	}
+	t.structEnd()
+}
+
+func writeColumnChunk(t *thriftWriter, c *column, cc columnChunk, codec int32) {
+	t.structBegin()
+	t.i64(2, cc.offset)
+
+	t.structField(3)
+	t.i32(1, c.typ)
+	if c.dictionary {
+		t.list(2, thriftI32, 2)
+		t.varint(int64(encodingPlainDictionary))
+	} else {
+		t.list(2, thriftI32, 2)
+		t.varint(int64(encodingPlain))
+	}
+	t.varint(int64(encodingRLE))
+	t.list(3, thriftBinary, 1)
+	t.rawBinary([]byte(c.name))
+	t.i32(4, codec)
+	t.i64(5, cc.numValues)
+	t.i64(6, cc.uncompressed)
+	t.i64(7, cc.compressed)
+	t.i64(9, cc.dataOffset)
+	if cc.dictionaryOffset >= 0 {
+		t.i64(11, cc.dictionaryOffset)
+	}
+	t.structField(12)
+	t.i64(3, cc.nullCount)
+	if cc.max != nil {
+		t.binary(5, cc.max)
+		t.binary(6, cc.min)
+	}
+	t.structEnd()
+	t.structEnd()
+
+	t.structEnd()
+}
+
+// appendRLE appends the values with the run length encoding of the
+// RLE/bit-packing hybrid encoding, each value taking width bits.
+func appendRLE(b []byte, values []uint32, width int) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	for i := 0; i < len(values); {
+		j := i + 1
+		for j < len(values) && values[j] == values[i] {
+			j++
+		}
+		b = append(b, buf[:binary.PutUvarint(buf[:], uint64(j-i)<<1)]...)
+		for k, v := 0, values[i]; k < (width+7)/8; k++ {
+			b = append(b, byte(v>>(8*uint(k))))
+		}
+		i = j
+	}
+	return b
+}
+
+// bitWidth returns the number of bits needed to represent values up to max,
+// at least 1.
+func bitWidth(max uint32) int {
+	width := 1
+	for max > 1 {
+		max >>= 1
+		width++
+	}
+	return width
+}
+
+func appendByteArrays(b []byte, values [][]byte) []byte {
+	for _, v := range values {
+		b = appendUint32(b, uint32(len(v)))
+		b = append(b, v...)
+	}
+	return b
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func codecOf(compression string) (int32, error) {
+	switch compression {
+	case CompressionNone:
+		return codecUncompressed, nil
+	case "", CompressionSnappy:
+		return codecSnappy, nil
+	case CompressionGzip:
+		return codecGzip, nil
+	}
+	return 0, fmt.Errorf("unknown compression %q", compression)
+}
@@ -0,0 +1,315 @@
+// Package findpoints locates the raw points of a series in TSM files and WAL
+// segments, to debug where a point comes from or why it is missing.
+package findpoints
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/storage/wal"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// fieldSeparator separates the series key from the field name in a TSM key.
+const fieldSeparator = "#!~#"
+
+// Command reports the blocks of TSM files and the entries of WAL segments
+// holding points of a series, and prints their values.
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Paths lists the TSM files and WAL segments to search, and the
+	// directories searched recursively for them.
+	Paths []string
+
+	// OrgID and BucketID optionally restrict the search to the series of an
+	// organization, or of a bucket within that organization.
+	OrgID    influxdb.ID
+	BucketID influxdb.ID
+
+	// SeriesKey is the key of the series, in the `measurement,tag=value`
+	// form, optionally followed by the field separator and a field name.
+	SeriesKey string
+
+	// Field optionally restricts the search to this field of the series.
+	Field string
+
+	// Start and End optionally restrict the search to the points within the
+	// window [Start, End].
+	Start time.Time
+	End   time.Time
+
+	key    string // normalized SeriesKey, without field
+	field  string
+	prefix []byte // key prefix of OrgID and BucketID
+}
+
+// NewCommand returns a new instance of Command writing to the standard output and error.
+func NewCommand() *Command {
+	return &Command{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Run searches the files found in Paths for the points of the series.
+func (cmd *Command) Run() error {
+	if cmd.SeriesKey == "" {
+		return errors.New("series key required")
+	}
+	if cmd.BucketID.Valid() && !cmd.OrgID.Valid() {
+		return errors.New("bucket requires an organization")
+	}
+	if !cmd.Start.IsZero() && !cmd.End.IsZero() && cmd.End.Before(cmd.Start) {
+		return errors.New("end must not be before start")
+	}
+
+	key, field := cmd.SeriesKey, cmd.Field
+	if i := strings.Index(key, fieldSeparator); i >= 0 {
+		f := key[i+len(fieldSeparator):]
+		if field != "" && f != field {
+			return fmt.Errorf("series key field %q does not match field %q", f, field)
+		}
+		key, field = key[:i], f
+	}
+	// Normalize the key so that tags may be given in any order.
+	name, tags := models.ParseKeyBytes([]byte(key))
+	if len(name) == 0 {
+		return fmt.Errorf("invalid series key %q", cmd.SeriesKey)
+	}
+	sort.Sort(tags)
+	cmd.key, cmd.field = string(models.MakeKey(name, tags)), field
+	cmd.prefix = tsmfile.Prefix(cmd.OrgID, cmd.BucketID)
+
+	files, err := tsmfile.Find(cmd.Paths, tsm1.TSMFileExtension, wal.WALFileExtension)
+	if err != nil {
+		return err
+	}
+
+	var points, found int
+	for _, path := range files {
+		var n int
+		if filepath.Ext(path) == "."+wal.WALFileExtension {
+			n, err = cmd.searchSegment(path)
+		} else {
+			n, err = cmd.searchTSM(path)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if n > 0 {
+			points, found = points+n, found+1
+		}
+	}
+
+	if points == 0 {
+		fmt.Fprintf(cmd.Stdout, "no points found in %d file(s)\n", len(files))
+		return nil
+	}
+	fmt.Fprintf(cmd.Stdout, "found %d point(s) in %d of %d file(s)\n", points, found, len(files))
+	return nil
+}
+
+// timeRange returns the window of the points to search for.
+func (cmd *Command) timeRange() (min, max int64) {
+	min, max = math.MinInt64, math.MaxInt64
+	if !cmd.Start.IsZero() {
+		min = cmd.Start.UnixNano()
+	}
+	if !cmd.End.IsZero() {
+		max = cmd.End.UnixNano()
+	}
+	return min, max
+}
+
+// match returns true if the TSM key is of the series, and of its field if set.
+func (cmd *Command) match(key []byte) bool {
+	if !bytes.HasPrefix(key, cmd.prefix) {
+		return false
+	}
+	seriesKey, field := tsm1.SeriesAndFieldFromCompositeKey(key)
+	if cmd.field != "" && string(field) != cmd.field {
+		return false
+	}
+
+	// Compare the key without the organization, bucket and field.
+	_, tags := models.ParseKeyBytes(seriesKey)
+	name := tags.Get(models.MeasurementTagKeyBytes)
+	filtered := make(models.Tags, 0, len(tags))
+	for _, t := range tags {
+		if bytes.Equal(t.Key, models.MeasurementTagKeyBytes) || bytes.Equal(t.Key, models.FieldKeyTagKeyBytes) {
+			continue
+		}
+		filtered = append(filtered, t)
+	}
+	return string(models.MakeKey(name, filtered)) == cmd.key
+}
+
+// searchTSM prints the blocks of the TSM file at path holding points of the
+// series, and the tombstones deleting some of them. Points deleted by a
+// tombstone are flagged as such. It returns the number of points found.
+func (cmd *Command) searchTSM(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		f.Close()
+		return 0, fmt.Errorf("unable to read: %v", err)
+	}
+	defer r.Close()
+
+	start, end := cmd.timeRange()
+	var (
+		n      int
+		values []tsm1.Value
+	)
+	iter := r.Iterator(cmd.prefix)
+	for iter.Next() {
+		key := iter.Key()
+		if !bytes.HasPrefix(key, cmd.prefix) {
+			break
+		} else if !cmd.match(key) {
+			continue
+		}
+
+		deleted := r.TombstoneRange(key, nil)
+		for i, e := range iter.Entries() {
+			if e.MinTime > end || e.MaxTime < start {
+				continue
+			}
+			if values, err = r.ReadAt(&e, values[:0]); err != nil {
+				return n, fmt.Errorf("unable to read block %d of %q: %v", i, key, err)
+			}
+
+			var lines []string
+			for _, v := range values {
+				if ts := v.UnixNano(); ts < start || ts > end {
+					continue
+				}
+				line := formatValue(v.UnixNano(), v.Value())
+				if isDeleted(deleted, v.UnixNano()) {
+					line += " (deleted)"
+				}
+				lines = append(lines, line)
+			}
+			if len(lines) == 0 {
+				continue
+			}
+
+			fmt.Fprintf(cmd.Stdout, "%s: block %d of %q (%s-%s) at offset %d, %d byte(s): %d point(s)\n",
+				path, i, key, formatTime(e.MinTime), formatTime(e.MaxTime), e.Offset, e.Size, len(lines))
+			for _, line := range lines {
+				fmt.Fprintf(cmd.Stdout, "  %s\n", line)
+			}
+			n += len(lines)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return n, err
+	}
+
+	// Keys whose values are all deleted are not in the index anymore, so the
+	// tombstones are read from the tombstone file.
+	err = tsm1.NewTombstoner(path, nil).Walk(func(t tsm1.Tombstone) error {
+		if t.Min > end || t.Max < start {
+			return nil
+		}
+		if t.Prefix {
+			if prefix := t.KeyPrefix(); bytes.HasPrefix(prefix, cmd.prefix) || bytes.HasPrefix(cmd.prefix, prefix) {
+				fmt.Fprintf(cmd.Stdout, "%s: tombstone of prefix %q (%s-%s)\n", path, prefix, formatTime(t.Min), formatTime(t.Max))
+			}
+			return nil
+		}
+		if cmd.match(t.Key) {
+			fmt.Fprintf(cmd.Stdout, "%s: tombstone of %q (%s-%s)\n", path, t.Key, formatTime(t.Min), formatTime(t.Max))
+		}
+		return nil
+	})
+	return n, err
+}
+
+// searchSegment prints the write entries of the WAL segment at path holding
+// points of the series. It returns the number of points found.
+func (cmd *Command) searchSegment(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	r := wal.NewWALSegmentReader(f)
+	defer r.Close()
+
+	start, end := cmd.timeRange()
+	var n int
+	for r.Next() {
+		entry, err := r.Read()
+		if err != nil {
+			return n, fmt.Errorf("unable to read entry at offset %d: %v", r.Count(), err)
+		}
+		w, ok := entry.(*wal.WriteWALEntry)
+		if !ok {
+			continue
+		}
+
+		keys := make([]string, 0, len(w.Values))
+		for key := range w.Values {
+			if cmd.match([]byte(key)) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			var lines []string
+			for _, v := range w.Values[key] {
+				if ts := v.UnixNano(); ts >= start && ts <= end {
+					lines = append(lines, formatValue(ts, v.Value()))
+				}
+			}
+			if len(lines) == 0 {
+				continue
+			}
+
+			fmt.Fprintf(cmd.Stdout, "%s: entry ending at offset %d of %q: %d point(s)\n", path, r.Count(), key, len(lines))
+			for _, line := range lines {
+				fmt.Fprintf(cmd.Stdout, "  %s\n", line)
+			}
+			n += len(lines)
+		}
+	}
+	return n, nil
+}
+
+// isDeleted returns true if the time is within any of the deleted ranges.
+func isDeleted(deleted []tsm1.TimeRange, ts int64) bool {
+	for _, tr := range deleted {
+		if ts >= tr.Min && ts <= tr.Max {
+			return true
+		}
+	}
+	return false
+}
+
+func formatValue(ts int64, v interface{}) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%s %q", formatTime(ts), s)
+	}
+	return fmt.Sprintf("%s %v", formatTime(ts), v)
+}
+
+func formatTime(t int64) string {
+	return time.Unix(0, t).UTC().Format(time.RFC3339Nano)
+}
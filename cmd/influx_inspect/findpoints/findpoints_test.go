@@ -0,0 +1,126 @@
+package findpoints_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/findpoints"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile/tsmtest"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/storage/wal"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+	"github.com/influxdata/influxdb/tsdb/value"
+)
+
+var (
+	orgID    = influxdb.ID(0x1000)
+	bucketID = influxdb.ID(0x2000)
+)
+
+func TestCommand_Run(t *testing.T) {
+	dir, err := ioutil.TempDir("", "findpoints")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cpuA, cpuB := seriesKey("cpu", "a"), seriesKey("cpu", "b")
+	path := filepath.Join(dir, "000000001-000000001."+tsm1.TSMFileExtension)
+	tsmtest.WriteFile(t, path, map[string]tsm1.Values{cpuA: tsmtest.Floats(10, 20, 30), cpuB: tsmtest.Floats(10)})
+
+	// Delete one point of the series.
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.DeleteRange([][]byte{[]byte(cpuA)}, 20, 20); err != nil {
+		t.Fatal(err)
+	} else if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	walDir := filepath.Join(dir, "wal")
+	w := wal.NewWAL(walDir)
+	if err := w.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	values := map[string][]value.Value{
+		cpuA: {value.NewFloatValue(40, 4)},
+		cpuB: {value.NewFloatValue(40, 4)},
+	}
+	if _, err := w.WriteMulti(context.Background(), values); err != nil {
+		t.Fatal(err)
+	} else if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	cmd := &findpoints.Command{
+		Stdout:    &stdout,
+		Stderr:    ioutil.Discard,
+		Paths:     []string{dir},
+		SeriesKey: "cpu,host=a",
+		Start:     time.Unix(0, 15),
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := stdout.String()
+	for _, want := range []string{
+		fmt.Sprintf("%s: block 0 of %q", path, cpuA),
+		"  1970-01-01T00:00:00.00000002Z 20 (deleted)\n  1970-01-01T00:00:00.00000003Z 30\n",
+		fmt.Sprintf("%s: tombstone of %q", path, cpuA),
+		`.wal: entry ending at offset`,
+		"  1970-01-01T00:00:00.00000004Z 4\n",
+		"found 3 point(s) in 2 of 2 file(s)\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("unexpected output: got %q, want %q in it", got, want)
+		}
+	}
+	if strings.Contains(got, "  1970-01-01T00:00:00.00000001Z") {
+		t.Fatalf("unexpected point before start: %q", got)
+	}
+
+	// A point of an unknown series is not found.
+	stdout.Reset()
+	cmd = &findpoints.Command{Stdout: &stdout, Stderr: ioutil.Discard, Paths: []string{dir}, SeriesKey: "cpu,host=c"}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "no points found in 2 file(s)\n"; got != want {
+		t.Fatalf("unexpected output: got %q, want %q", got, want)
+	}
+}
+
+func TestCommand_Run_Field(t *testing.T) {
+	cmd := &findpoints.Command{Stdout: ioutil.Discard, Stderr: ioutil.Discard, SeriesKey: "cpu,host=a#!~#usage", Field: "idle"}
+	if err := cmd.Run(); err == nil || !strings.Contains(err.Error(), "does not match field") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// seriesKey returns the TSM key of the value field of a cpu series in the test bucket.
+func seriesKey(measurement, host string) string {
+	name := tsdb.EncodeName(orgID, bucketID)
+	tags := models.NewTags(map[string]string{
+		models.MeasurementTagKey: measurement,
+		models.FieldKeyTagKey:    "value",
+		"host":                   host,
+	})
+	return string(models.MakeKey(name[:], tags)) + "#!~#value"
+}
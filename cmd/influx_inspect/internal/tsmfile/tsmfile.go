@@ -0,0 +1,81 @@
+// Package tsmfile provides the helpers shared by the influx_inspect commands
+// reading TSM files.
+package tsmfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// Find returns the files of paths with one of the extensions exts, or with
+// the TSM file extension if none is given, searching directories
+// recursively. The files of a directory are returned in lexical order, so
+// that later generations of a shard come last.
+//
+// Paths that are not directories are returned as is, so that the files that
+// can not be read are reported when they are opened.
+func Find(paths []string, exts ...string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		if fi, err := os.Stat(path); err != nil || !fi.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err := Walk(path, func(path string) error {
+			files = append(files, path)
+			return nil
+		}, exts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// Walk calls fn for each file under root with one of the extensions exts, or
+// with the TSM file extension if none is given, in lexical order.
+func Walk(root string, fn func(path string) error, exts ...string) error {
+	if len(exts) == 0 {
+		exts = []string{tsm1.TSMFileExtension}
+	}
+	err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+		for _, ext := range exts {
+			if filepath.Ext(path) == "."+ext {
+				return fn(path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error processing path %q: %v", root, err)
+	}
+	return nil
+}
+
+// Prefix returns the key prefix of the series of the bucket bucketID of the
+// organization orgID, or of all the buckets of the organization if bucketID
+// is not valid. It returns nil if orgID is not valid.
+func Prefix(orgID, bucketID influxdb.ID) []byte {
+	if !orgID.Valid() {
+		return nil
+	}
+	if bucketID.Valid() {
+		name := tsdb.EncodeName(orgID, bucketID)
+		return models.EscapeMeasurement(name[:])
+	}
+	name := tsdb.EncodeOrgName(orgID)
+	return models.EscapeMeasurement(name[:])
+}
@@ -0,0 +1,84 @@
+package tsmfile_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+func TestFind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsmfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{
+		"1/000000002-000000001.tsm",
+		"1/000000001-000000001.tsm",
+		"1/000000001-000000001.tombstone",
+		"2/000000001-000000001.tsm",
+		"wal/_00001.wal",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	missing := filepath.Join(dir, "missing.tsm")
+	files, err := tsmfile.Find([]string{filepath.Join(dir, "2"), filepath.Join(dir, "1"), missing})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(dir, "2/000000001-000000001.tsm"),
+		filepath.Join(dir, "1/000000001-000000001.tsm"),
+		filepath.Join(dir, "1/000000002-000000001.tsm"),
+		missing,
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("unexpected files: got %q, want %q", files, want)
+	}
+
+	files, err = tsmfile.Find([]string{dir}, "tombstone", "wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{
+		filepath.Join(dir, "1/000000001-000000001.tombstone"),
+		filepath.Join(dir, "wal/_00001.wal"),
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("unexpected files: got %q, want %q", files, want)
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	orgID, bucketID := influxdb.ID(0x1000), influxdb.ID(0x2000)
+
+	if prefix := tsmfile.Prefix(influxdb.InvalidID(), bucketID); prefix != nil {
+		t.Fatalf("unexpected prefix without organization: %q", prefix)
+	}
+
+	org := tsdb.EncodeOrgName(orgID)
+	if got, want := tsmfile.Prefix(orgID, influxdb.InvalidID()), models.EscapeMeasurement(org[:]); !bytes.Equal(got, want) {
+		t.Fatalf("unexpected organization prefix: got %q, want %q", got, want)
+	}
+
+	name := tsdb.EncodeName(orgID, bucketID)
+	if got, want := tsmfile.Prefix(orgID, bucketID), models.EscapeMeasurement(name[:]); !bytes.Equal(got, want) {
+		t.Fatalf("unexpected bucket prefix: got %q, want %q", got, want)
+	}
+}
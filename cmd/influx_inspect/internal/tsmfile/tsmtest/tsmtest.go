@@ -0,0 +1,190 @@
+// Package tsmtest provides helpers for writing and reading the TSM files of
+// the tests of the influx_inspect commands.
+// These functions are only intended to be called from test files,
+// as there is a dependency on the standard library testing package.
+package tsmtest
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// Block is a block of values of a key of a TSM file.
+type Block struct {
+	Key    string
+	Values tsm1.Values
+}
+
+// WriteFile writes a TSM file at path with a single block for each key of
+// data, creating its directory if needed.
+func WriteFile(t testing.TB, path string, data map[string]tsm1.Values) {
+	t.Helper()
+
+	var keys []string
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	blocks := make([]Block, 0, len(keys))
+	for _, k := range keys {
+		blocks = append(blocks, Block{Key: k, Values: data[k]})
+	}
+	WriteBlocks(t, path, blocks...)
+}
+
+// WriteKeys writes a TSM file at path with a single value for each key,
+// which must be sorted.
+func WriteKeys(t testing.TB, path string, keys ...string) {
+	t.Helper()
+
+	blocks := make([]Block, 0, len(keys))
+	for _, k := range keys {
+		blocks = append(blocks, Block{Key: k, Values: tsm1.Values{tsm1.NewValue(10, 1.0)}})
+	}
+	WriteBlocks(t, path, blocks...)
+}
+
+// WriteBlocks writes a TSM file at path with the blocks in order, creating
+// its directory if needed. The blocks of a key must follow each other.
+func WriteBlocks(t testing.TB, path string, blocks ...Block) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := tsm1.NewTSMWriter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range blocks {
+		if err := w.Write([]byte(b.Key), b.Values); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.WriteIndex(); err != nil {
+		t.Fatal(err)
+	} else if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Floats returns a float value equal to each timestamp.
+func Floats(timestamps ...int64) tsm1.Values {
+	values := make(tsm1.Values, 0, len(timestamps))
+	for _, ts := range timestamps {
+		values = append(values, tsm1.NewValue(ts, float64(ts)))
+	}
+	return values
+}
+
+// ReadFile returns the values of each key of the TSM file at path, including
+// the deleted values.
+func ReadFile(t testing.TB, path string) map[string][]tsm1.Value {
+	t.Helper()
+
+	r := open(t, path)
+	defer r.Close()
+
+	values := make(map[string][]tsm1.Value)
+	iter := r.Iterator(nil)
+	for iter.Next() {
+		vs, err := r.ReadAll(iter.Key())
+		if err != nil {
+			t.Fatal(err)
+		}
+		values[string(iter.Key())] = vs
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+// ReadValues returns the values of each key of the TSM file at path,
+// excluding those deleted by its tombstones.
+func ReadValues(t testing.TB, path string) map[string][]interface{} {
+	t.Helper()
+
+	r := open(t, path)
+	defer r.Close()
+
+	values := make(map[string][]interface{})
+	iter := r.Iterator(nil)
+	for iter.Next() {
+		vs, err := r.ReadAll(iter.Key())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tr := range r.TombstoneRange(iter.Key(), nil) {
+			vs = tsm1.Values(vs).Exclude(tr.Min, tr.Max)
+		}
+		for _, v := range vs {
+			values[string(iter.Key())] = append(values[string(iter.Key())], v.Value())
+		}
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+// ReadTimestamps returns the timestamps of the values of key in the TSM file
+// at path, including the deleted values.
+func ReadTimestamps(t testing.TB, path, key string) []int64 {
+	t.Helper()
+
+	r := open(t, path)
+	defer r.Close()
+
+	values, err := r.ReadAll([]byte(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var timestamps []int64
+	for _, v := range values {
+		timestamps = append(timestamps, v.UnixNano())
+	}
+	return timestamps
+}
+
+// ReadKeys returns the keys of the TSM file at path.
+func ReadKeys(t testing.TB, path string) []string {
+	t.Helper()
+
+	r := open(t, path)
+	defer r.Close()
+
+	var keys []string
+	iter := r.Iterator(nil)
+	for iter.Next() {
+		keys = append(keys, string(iter.Key()))
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func open(t testing.TB, path string) *tsm1.TSMReader {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		f.Close()
+		t.Fatal(err)
+	}
+	return r
+}
@@ -0,0 +1,226 @@
+// Package mergetsm merges the TSM files of a shard into fewer, fully
+// compacted files without running the storage engine.
+//
+// The storage engine must not be running while files are merged.
+package mergetsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// Command merges the TSM files of each directory into fewer files.
+//
+// The files of a directory are merged with a full compaction, as done by
+// the storage engine: overlapping blocks are deduplicated, the values of
+// later files overwriting those of earlier ones, tombstoned values are
+// dropped and the values of each key are written in full blocks. The new
+// files replace the merged files, along with their tombstones, once they
+// are complete.
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Paths lists the directories whose TSM files are merged, such as the
+	// data directory of the engine. Directories are searched recursively,
+	// the TSM files of each directory found being merged together.
+	Paths []string
+
+	// MaxFiles optionally limits the number of files merged at once, so that
+	// the files of a directory with thousands of files are merged in several
+	// passes of bounded memory, merging consecutive generations by groups of
+	// MaxFiles files until they can all be merged together.
+	MaxFiles int
+
+	// DryRun reports the files that would be merged without merging them.
+	DryRun bool
+}
+
+// NewCommand returns a new instance of Command writing to the standard output and error.
+func NewCommand() *Command {
+	return &Command{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Stats summarizes the merge of the TSM files of a directory.
+type Stats struct {
+	Files      int
+	Bytes      int64
+	Tombstones int
+	NewFiles   int
+	NewBytes   int64
+}
+
+// Run merges the TSM files of each directory of Paths.
+func (cmd *Command) Run() error {
+	if len(cmd.Paths) == 0 {
+		return errors.New("path required")
+	}
+	if cmd.MaxFiles < 0 || cmd.MaxFiles == 1 {
+		return errors.New("max files must be at least 2")
+	}
+
+	dirs, err := cmd.findDirs()
+	if err != nil {
+		return err
+	}
+
+	var total Stats
+	for _, dir := range dirs {
+		s, err := cmd.MergeDir(dir)
+		if err != nil {
+			return fmt.Errorf("%s: %v", dir, err)
+		}
+		cmd.printStats(dir, s)
+		total.add(s)
+	}
+
+	if cmd.DryRun {
+		fmt.Fprintf(cmd.Stdout, "would merge %d TSM file(s) of %d directory(s), %d bytes\n",
+			total.Files, len(dirs), total.Bytes)
+		return nil
+	}
+	fmt.Fprintf(cmd.Stdout, "merged %d TSM file(s) of %d directory(s), %d bytes, into %d file(s), %d bytes\n",
+		total.Files, len(dirs), total.Bytes, total.NewFiles, total.NewBytes)
+	return nil
+}
+
+// MergeDir merges the TSM files of dir.
+func (cmd *Command) MergeDir(dir string) (Stats, error) {
+	var s Stats
+
+	fs := tsm1.NewFileStore(dir)
+	if err := fs.Open(context.Background()); err != nil {
+		return s, err
+	}
+	defer fs.Close()
+
+	var files []string
+	for _, f := range fs.Files() {
+		files = append(files, f.Path())
+		s.Files++
+		s.Bytes += int64(f.Size())
+		s.Tombstones += len(f.TombstoneFiles())
+	}
+	// A single file without tombstones is already fully compacted.
+	if len(files) < 2 && s.Tombstones == 0 {
+		s.NewFiles, s.NewBytes = s.Files, s.Bytes
+		return s, nil
+	}
+	if cmd.DryRun {
+		return s, nil
+	}
+
+	c := tsm1.NewCompactor()
+	c.Dir = dir
+	c.FileStore = fs
+	c.Open()
+	defer c.Close()
+
+	// Merge the files by groups of MaxFiles until they can be merged at once.
+	for cmd.MaxFiles > 0 && len(files) > cmd.MaxFiles {
+		for _, group := range cmd.groups(files) {
+			if len(group) < 2 {
+				continue
+			}
+			if err := merge(c, fs, group); err != nil {
+				return s, err
+			}
+		}
+		files = paths(fs)
+	}
+	if err := merge(c, fs, files); err != nil {
+		return s, err
+	}
+
+	for _, f := range fs.Files() {
+		s.NewFiles++
+		s.NewBytes += int64(f.Size())
+	}
+	return s, nil
+}
+
+// merge replaces the files with the files of their full compaction.
+func merge(c *tsm1.Compactor, fs *tsm1.FileStore, files []string) error {
+	newFiles, err := c.CompactFull(files)
+	if err != nil {
+		return err
+	}
+	return fs.Replace(files, newFiles)
+}
+
+func paths(fs *tsm1.FileStore) []string {
+	var files []string
+	for _, f := range fs.Files() {
+		files = append(files, f.Path())
+	}
+	return files
+}
+
+// groups splits the files, sorted by generation, into groups of MaxFiles
+// files.
+func (cmd *Command) groups(files []string) [][]string {
+	var groups [][]string
+	for len(files) > 0 {
+		n := cmd.MaxFiles
+		if n > len(files) {
+			n = len(files)
+		}
+		groups = append(groups, files[:n])
+		files = files[n:]
+	}
+	return groups
+}
+
+func (cmd *Command) printStats(dir string, s Stats) {
+	if cmd.DryRun {
+		fmt.Fprintf(cmd.Stdout, "%s: would merge %d file(s), %d bytes, with %d tombstone file(s)\n",
+			dir, s.Files, s.Bytes, s.Tombstones)
+		return
+	}
+	fmt.Fprintf(cmd.Stdout, "%s: merged %d file(s), %d bytes, with %d tombstone file(s) into %d file(s), %d bytes\n",
+		dir, s.Files, s.Bytes, s.Tombstones, s.NewFiles, s.NewBytes)
+}
+
+func (s *Stats) add(o Stats) {
+	s.Files += o.Files
+	s.Bytes += o.Bytes
+	s.Tombstones += o.Tombstones
+	s.NewFiles += o.NewFiles
+	s.NewBytes += o.NewBytes
+}
+
+// findDirs returns the directories of Paths holding TSM files, searching
+// them recursively.
+func (cmd *Command) findDirs() ([]string, error) {
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, path := range cmd.Paths {
+		err := filepath.Walk(path, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !fi.IsDir() && filepath.Ext(path) == "."+tsm1.TSMFileExtension {
+				if dir := filepath.Dir(path); !seen[dir] {
+					seen[dir] = true
+					dirs = append(dirs, dir)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error processing path %q: %v", path, err)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
@@ -0,0 +1,119 @@
+package mergetsm_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile/tsmtest"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/mergetsm"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+func TestCommand_Run(t *testing.T) {
+	for _, maxFiles := range []int{0, 2} {
+		dir, err := ioutil.TempDir("", "mergetsm")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		shard := filepath.Join(dir, "data")
+		if err := os.Mkdir(shard, 0777); err != nil {
+			t.Fatal(err)
+		}
+		tsmtest.WriteFile(t, filepath.Join(shard, "000000001-000000001.tsm"), map[string]tsm1.Values{
+			"cpu#!~#usage": {tsm1.NewValue(10, 1.0), tsm1.NewValue(20, 2.0)},
+			"mem#!~#used":  {tsm1.NewValue(10, int64(1))},
+		})
+		tsmtest.WriteFile(t, filepath.Join(shard, "000000002-000000001.tsm"), map[string]tsm1.Values{
+			"cpu#!~#usage": {tsm1.NewValue(20, 3.0), tsm1.NewValue(30, 4.0)},
+		})
+		tsmtest.WriteFile(t, filepath.Join(shard, "000000003-000000001.tsm"), map[string]tsm1.Values{
+			"disk#!~#free": {tsm1.NewValue(10, int64(5))},
+		})
+		deleteKey(t, filepath.Join(shard, "000000001-000000001.tsm"), "mem#!~#used")
+
+		var stdout bytes.Buffer
+		cmd := &mergetsm.Command{
+			Stdout:   &stdout,
+			Stderr:   ioutil.Discard,
+			Paths:    []string{dir},
+			MaxFiles: maxFiles,
+		}
+		if err := cmd.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(stdout.String(), shard+": merged 3 file(s)") || !strings.Contains(stdout.String(), "with 1 tombstone file(s) into 1 file(s)") {
+			t.Fatalf("unexpected output: %s", stdout.String())
+		}
+
+		files, err := ioutil.ReadDir(shard)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, fi := range files {
+			names = append(names, fi.Name())
+		}
+		if want := []string{"000000000000003-000000002.tsm", "000000000000003-000000002.tss"}; !reflect.DeepEqual(names, want) {
+			t.Fatalf("unexpected files: got %v, want %v", names, want)
+		}
+
+		got := tsmtest.ReadValues(t, filepath.Join(shard, names[0]))
+		want := map[string][]interface{}{
+			"cpu#!~#usage": {1.0, 3.0, 4.0},
+			"disk#!~#free": {int64(5)},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("unexpected values with %d max files: got %v, want %v", maxFiles, got, want)
+		}
+	}
+}
+
+func TestCommand_Run_DryRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mergetsm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tsmtest.WriteFile(t, filepath.Join(dir, "000000001-000000001.tsm"), map[string]tsm1.Values{"cpu#!~#usage": {tsm1.NewValue(10, 1.0)}})
+	tsmtest.WriteFile(t, filepath.Join(dir, "000000002-000000001.tsm"), map[string]tsm1.Values{"cpu#!~#usage": {tsm1.NewValue(20, 1.0)}})
+
+	var stdout bytes.Buffer
+	cmd := &mergetsm.Command{Stdout: &stdout, Stderr: ioutil.Discard, Paths: []string{dir}, DryRun: true}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "would merge 2 TSM file(s) of 1 directory(s)") {
+		t.Fatalf("unexpected output: %s", stdout.String())
+	}
+	if files, err := filepath.Glob(filepath.Join(dir, "*.tsm")); err != nil {
+		t.Fatal(err)
+	} else if len(files) != 2 {
+		t.Fatalf("unexpected files after a dry run: %v", files)
+	}
+}
+
+// deleteKey records a tombstone for every value of key in the TSM file.
+func deleteKey(t *testing.T, path, key string) {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := r.Delete([][]byte{[]byte(key)}); err != nil {
+		t.Fatal(err)
+	}
+}
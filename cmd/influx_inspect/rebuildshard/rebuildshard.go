@@ -0,0 +1,414 @@
+// Package rebuildshard rebuilds the TSM files of a shard from a line protocol
+// export, writing them directly without the WAL and the storage engine.
+//
+// The storage engine must not be running on the rebuilt directory.
+package rebuildshard
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+	"github.com/influxdata/influxql"
+)
+
+// DefaultMaxBufferedValues is the default number of values buffered in
+// memory before they are written to a TSM file.
+const DefaultMaxBufferedValues = 10000000
+
+// batchSize is the number of lines parsed at once.
+const batchSize = 5000
+
+// maxFileSize is the size of the TSM files after which a new file is
+// started, as done by the compactor of the engine.
+const maxFileSize = 2048 * 1024 * 1024
+
+// Command rebuilds the TSM files of a shard from line protocol.
+//
+// The points are buffered in memory, sorted by key and written to TSM files
+// of increasing generations every MaxBufferedValues values, bypassing the
+// WAL and the cache of the engine. The files are then merged with a full
+// compaction, so that the rebuilt shard is optimally compacted. The values of
+// a key at the same timestamp overwrite the earlier ones, as they would when
+// written to the engine.
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Paths lists the line protocol files to ingest, optionally gzipped if
+	// their name ends with .gz, or "-" for the standard input. Lines which
+	// are empty or start with a # are ignored, so that the DDL and DML
+	// comments of exports are skipped.
+	Paths []string
+
+	// OrgID and BucketID are the organization and bucket of the points.
+	OrgID    influxdb.ID
+	BucketID influxdb.ID
+
+	// OutputDir is the directory the TSM files are written to. It is created
+	// and must not already hold files.
+	OutputDir string
+
+	// MaxBufferedValues bounds the number of values buffered in memory,
+	// DefaultMaxBufferedValues if not positive.
+	MaxBufferedValues int
+
+	// Verbose reports the lines which could not be parsed.
+	Verbose bool
+
+	name       []byte
+	values     map[string]tsm1.Values
+	types      map[string]influxql.DataType
+	buffered   int
+	generation int
+	stats      Stats
+}
+
+// NewCommand returns a new instance of Command writing to the standard output and error.
+func NewCommand() *Command {
+	return &Command{
+		Stdout:            os.Stdout,
+		Stderr:            os.Stderr,
+		MaxBufferedValues: DefaultMaxBufferedValues,
+	}
+}
+
+// Stats summarizes the rebuild of a shard.
+type Stats struct {
+	// Lines is the number of lines read, and Rejected the number of lines
+	// which could not be parsed.
+	Lines    int
+	Rejected int
+	// Values is the number of values ingested, and Conflicts the number of
+	// values dropped for conflicting with the type of their field.
+	Values    int
+	Conflicts int
+	// Keys is the number of keys of the rebuilt shard, and Files its number
+	// of TSM files.
+	Keys  int
+	Files int
+}
+
+// Run rebuilds the TSM files of OutputDir from the line protocol of Paths.
+func (cmd *Command) Run() error {
+	if len(cmd.Paths) == 0 {
+		return errors.New("path required")
+	}
+	if !cmd.OrgID.Valid() || !cmd.BucketID.Valid() {
+		return errors.New("organization and bucket required")
+	}
+	if cmd.OutputDir == "" {
+		return errors.New("output directory required")
+	}
+	if cmd.MaxBufferedValues <= 0 {
+		cmd.MaxBufferedValues = DefaultMaxBufferedValues
+	}
+
+	if err := os.MkdirAll(cmd.OutputDir, 0777); err != nil {
+		return err
+	}
+	if fis, err := ioutil.ReadDir(cmd.OutputDir); err != nil {
+		return err
+	} else if len(fis) > 0 {
+		return fmt.Errorf("output directory %s is not empty", cmd.OutputDir)
+	}
+
+	encoded := tsdb.EncodeName(cmd.OrgID, cmd.BucketID)
+	cmd.name = models.EscapeMeasurement(encoded[:])
+	cmd.values = make(map[string]tsm1.Values)
+	cmd.types = make(map[string]influxql.DataType)
+	cmd.stats = Stats{}
+	start := time.Now()
+
+	for _, path := range cmd.Paths {
+		if err := cmd.ingestFile(path); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	if err := cmd.flush(); err != nil {
+		return err
+	}
+	if err := cmd.compact(); err != nil {
+		return err
+	}
+	cmd.stats.Keys = len(cmd.types)
+
+	s := cmd.stats
+	fmt.Fprintf(cmd.Stdout, "rebuilt %d key(s) with %d value(s) into %d TSM file(s) of %s in %s\n",
+		s.Keys, s.Values, s.Files, cmd.OutputDir, time.Since(start))
+	if s.Rejected > 0 || s.Conflicts > 0 {
+		fmt.Fprintf(cmd.Stdout, "rejected %d of %d line(s) and %d value(s) with a conflicting field type\n",
+			s.Rejected, s.Lines, s.Conflicts)
+	}
+	return nil
+}
+
+// Stats returns the statistics of the last run.
+func (cmd *Command) Stats() Stats {
+	return cmd.stats
+}
+
+// ingestFile buffers the points of the line protocol file at path.
+func (cmd *Command) ingestFile(path string) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+
+		if strings.HasSuffix(path, ".gz") {
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				return err
+			}
+			defer gz.Close()
+			r = gz
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	var (
+		batch []byte
+		lines int
+	)
+	for scanner.Scan() {
+		cmd.stats.Lines++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		batch = append(append(batch, line...), '\n')
+		if lines++; lines < batchSize {
+			continue
+		}
+		if err := cmd.ingest(batch); err != nil {
+			return err
+		}
+		batch, lines = batch[:0], 0
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return cmd.ingest(batch)
+}
+
+// ingest buffers the points of a batch of lines, flushing the buffer to a
+// TSM file once full.
+func (cmd *Command) ingest(batch []byte) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	// Points are parsed into a single field each, named after the
+	// organization and bucket, as they are by the write handler.
+	points, err := models.ParsePointsWithPrecision(batch, cmd.name, time.Now().UTC(), "n")
+	if err != nil {
+		// The points of the valid lines are parsed regardless.
+		n := strings.Count(err.Error(), "\n") + 1
+		cmd.stats.Rejected += n
+		if cmd.Verbose {
+			fmt.Fprintf(cmd.Stderr, "rejected %d line(s): %v\n", n, err)
+		}
+	}
+	collection := tsdb.NewSeriesCollection(points)
+	values, err := tsm1.CollectionToValues(collection)
+	if err != nil {
+		return err
+	}
+	// The values conflicting with the type of a field within the batch are
+	// dropped by the conversion.
+	cmd.stats.Conflicts += int(collection.Dropped)
+	for key, vs := range values {
+		// The type of a field is set by its first value, as it would be
+		// by the engine, so that the files can be compacted together.
+		typ, err := tsm1.Values(vs).InfluxQLType()
+		if err != nil {
+			return err
+		}
+		if t, ok := cmd.types[key]; !ok {
+			cmd.types[key] = typ
+		} else if t != typ {
+			cmd.stats.Conflicts += len(vs)
+			continue
+		}
+		cmd.values[key] = append(cmd.values[key], vs...)
+		cmd.buffered += len(vs)
+		cmd.stats.Values += len(vs)
+	}
+
+	if cmd.buffered >= cmd.MaxBufferedValues {
+		return cmd.flush()
+	}
+	return nil
+}
+
+// flush writes the buffered values to TSM files of a new generation.
+func (cmd *Command) flush() error {
+	if len(cmd.values) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(cmd.values))
+	for key := range cmd.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	cmd.generation++
+	w := &tsmWriter{dir: cmd.OutputDir, generation: cmd.generation}
+	for _, key := range keys {
+		if err := w.write([]byte(key), cmd.values[key].Deduplicate()); err != nil {
+			w.abort()
+			return err
+		}
+	}
+	if err := w.close(); err != nil {
+		w.abort()
+		return err
+	}
+	for _, path := range w.paths {
+		if err := os.Rename(tmpPath(path), path); err != nil {
+			return err
+		}
+	}
+
+	cmd.values = make(map[string]tsm1.Values)
+	cmd.buffered = 0
+	return nil
+}
+
+// compact merges the TSM files of OutputDir with a full compaction.
+func (cmd *Command) compact() error {
+	fs := tsm1.NewFileStore(cmd.OutputDir)
+	if err := fs.Open(context.Background()); err != nil {
+		return err
+	}
+	defer fs.Close()
+
+	var files []string
+	for _, f := range fs.Files() {
+		files = append(files, f.Path())
+	}
+	if len(files) > 1 {
+		c := tsm1.NewCompactor()
+		c.Dir = cmd.OutputDir
+		c.FileStore = fs
+		c.Open()
+		defer c.Close()
+
+		newFiles, err := c.CompactFull(files)
+		if err != nil {
+			return err
+		}
+		if err := fs.Replace(files, newFiles); err != nil {
+			return err
+		}
+	}
+	cmd.stats.Files = len(fs.Files())
+	return nil
+}
+
+// tmpPath returns the temporary path of the TSM file at path.
+func tmpPath(path string) string {
+	return path + "." + tsm1.CompactionTempExtension
+}
+
+// tsmWriter writes values to temporary TSM files of a generation, with
+// increasing sequences.
+type tsmWriter struct {
+	dir        string
+	generation int
+	sequence   int
+
+	w     tsm1.TSMWriter
+	paths []string
+}
+
+func (w *tsmWriter) write(key []byte, values tsm1.Values) error {
+	for i := 0; i < len(values); i += tsm1.MaxPointsPerBlock {
+		j := i + tsm1.MaxPointsPerBlock
+		if j > len(values) {
+			j = len(values)
+		}
+		if err := w.open(); err != nil {
+			return err
+		}
+		if err := w.w.Write(key, values[i:j]); err != nil {
+			return err
+		}
+		// Start a new file once the current one is full, as a key may
+		// span several files.
+		if w.w.Size() > maxFileSize {
+			if err := w.finish(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *tsmWriter) open() error {
+	if w.w != nil {
+		return nil
+	}
+	w.sequence++
+	path := filepath.Join(w.dir, tsm1.DefaultFormatFileName(w.generation, w.sequence)+"."+tsm1.TSMFileExtension)
+	f, err := os.OpenFile(tmpPath(path), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	if w.w, err = tsm1.NewTSMWriter(f); err != nil {
+		f.Close()
+		return err
+	}
+	w.paths = append(w.paths, path)
+	return nil
+}
+
+// finish completes the current file.
+func (w *tsmWriter) finish() error {
+	tw := w.w
+	w.w = nil
+	if err := tw.WriteIndex(); err != nil {
+		tw.Remove()
+		return err
+	}
+	return tw.Close()
+}
+
+func (w *tsmWriter) close() error {
+	if w.w == nil {
+		return nil
+	}
+	return w.finish()
+}
+
+// abort removes the files written.
+func (w *tsmWriter) abort() {
+	if w.w != nil {
+		w.w.Remove()
+		w.w = nil
+	}
+	for _, path := range w.paths {
+		os.Remove(tmpPath(path))
+		os.Remove(tsm1.StatsFilename(path))
+	}
+}
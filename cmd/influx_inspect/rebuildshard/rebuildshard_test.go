@@ -0,0 +1,132 @@
+package rebuildshard_test
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile/tsmtest"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/rebuildshard"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+var (
+	orgID    = influxdb.ID(0x1000)
+	bucketID = influxdb.ID(0x2000)
+)
+
+func TestCommand_Run(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rebuildshard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	export := filepath.Join(dir, "export.lp")
+	if err := ioutil.WriteFile(export, []byte(`# DDL
+# DML
+cpu,host=a usage=1,idle=9 0
+cpu,host=a usage=2 10
+cpu,host=a usage="busy" 20
+not line protocol
+
+cpu,host=a usage=3 10
+`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	gzipped := filepath.Join(dir, "export.lp.gz")
+	writeGzip(t, gzipped, "mem,host=a free=5i 0\ncpu,host=a usage=4 30\n")
+
+	var stdout strings.Builder
+	cmd := rebuildshard.NewCommand()
+	cmd.Stdout, cmd.Stderr = &stdout, ioutil.Discard
+	cmd.Paths = []string{export, gzipped}
+	cmd.OrgID, cmd.BucketID = orgID, bucketID
+	cmd.OutputDir = filepath.Join(dir, "data")
+	// Flush the buffer after each batch, so that the files are compacted.
+	cmd.MaxBufferedValues = 1
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := cmd.Stats()
+	if s.Lines != 10 || s.Rejected != 1 || s.Conflicts != 1 || s.Keys != 3 || s.Files != 1 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+	if !strings.Contains(stdout.String(), "rejected 1 of 10 line(s) and 1 value(s) with a conflicting field type") {
+		t.Fatalf("unexpected output: %s", stdout.String())
+	}
+
+	paths, err := filepath.Glob(filepath.Join(cmd.OutputDir, "*.tsm"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(paths) != 1 {
+		t.Fatalf("unexpected files: %v", paths)
+	}
+	got := tsmtest.ReadFile(t, paths[0])
+	want := map[string][]tsm1.Value{
+		seriesKey("cpu", "idle", "host", "a"): {tsm1.NewValue(0, 9.0)},
+		// The later value of a timestamp overwrites the earlier one.
+		seriesKey("cpu", "usage", "host", "a"): {tsm1.NewValue(0, 1.0), tsm1.NewValue(10, 3.0), tsm1.NewValue(30, 4.0)},
+		seriesKey("mem", "free", "host", "a"):  {tsm1.NewValue(0, int64(5))},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected values:\ngot  %v\nwant %v", got, want)
+	}
+
+	// The output directory must be empty.
+	if err := cmd.Run(); err == nil || !strings.Contains(err.Error(), "is not empty") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCommand_Run_Invalid(t *testing.T) {
+	for _, tt := range []struct {
+		cmd rebuildshard.Command
+		err string
+	}{
+		{cmd: rebuildshard.Command{}, err: "path required"},
+		{cmd: rebuildshard.Command{Paths: []string{"-"}, OrgID: orgID}, err: "organization and bucket required"},
+		{cmd: rebuildshard.Command{Paths: []string{"-"}, OrgID: orgID, BucketID: bucketID}, err: "output directory required"},
+	} {
+		if err := tt.cmd.Run(); err == nil || err.Error() != tt.err {
+			t.Errorf("unexpected error: got %v, want %q", err, tt.err)
+		}
+	}
+}
+
+// seriesKey returns the TSM key of the field of a series in the test bucket.
+func seriesKey(measurement, field string, tags ...string) string {
+	name := tsdb.EncodeName(orgID, bucketID)
+	m := map[string]string{
+		models.MeasurementTagKey: measurement,
+		models.FieldKeyTagKey:    field,
+	}
+	for i := 0; i < len(tags); i += 2 {
+		m[tags[i]] = tags[i+1]
+	}
+	return string(models.MakeKey(name[:], models.NewTags(m))) + "#!~#" + field
+}
+
+func writeGzip(t *testing.T, path, data string) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := gzip.NewWriter(f)
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	} else if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,454 @@
+// Package repairtsm salvages the blocks of corrupted TSM files.
+//
+// The storage engine must not be running while files are repaired.
+package repairtsm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+const (
+	headerSize     = 5  // magic number and version
+	footerSize     = 8  // offset of the index
+	checksumSize   = 4  // CRC32 of a block
+	indexEntrySize = 28 // min time, max time, offset and size of a block
+
+	// maxBlockSize bounds the size of the blocks searched for when the
+	// blocks of a file are scanned without its index.
+	maxBlockSize = 1 << 24
+)
+
+// Reasons a block is lost.
+const (
+	ReasonChecksum      = "checksum mismatch"
+	ReasonInvalid       = "invalid block"
+	ReasonNotIndexed    = "not indexed"
+	ReasonIndexMismatch = "index mismatch"
+)
+
+// Command repairs a set of TSM files whose index or tail is corrupted.
+//
+// The blocks of a file are salvaged from the entries of its index that can
+// still be read, and from a scan of its blocks when the index is missing,
+// such as when the file was truncated. Every block whose checksum validates,
+// and whose type and time range match those of its index entry, is written,
+// with a new index, to the repaired file. The key, time range, location and
+// number of points of every lost block are reported; the key of a block that
+// is not indexed is unknown, as is the number of points of a block that can
+// not be decoded.
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Paths lists the TSM files to repair.
+	Paths []string
+
+	// OutputDir is the directory the repaired files are written to, under
+	// their original name.
+	OutputDir string
+
+	// Replace replaces each file with its repaired file, the original being
+	// kept with a .corrupt extension. Files that are not corrupted are left
+	// untouched.
+	Replace bool
+
+	// DryRun reports the blocks that would be lost without writing files.
+	DryRun bool
+}
+
+// NewCommand returns a new instance of Command writing to the standard output and error.
+func NewCommand() *Command {
+	return &Command{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// LostBlock is a block that could not be salvaged.
+type LostBlock struct {
+	// Key is the key of the block, or nil if it is unknown.
+	Key []byte
+
+	// MinTime and MaxTime are the time range of the block, from the index
+	// or decoded from the block.
+	MinTime, MaxTime int64
+
+	// Offset and Size locate the block, with its checksum, in the file.
+	Offset int64
+	Size   uint32
+
+	// Points is the number of points of the block, or 0 if the block can
+	// not be decoded.
+	Points int
+
+	Reason string
+}
+
+// Result is the outcome of the repair of a TSM file.
+type Result struct {
+	// Corrupted is false if the file was read without error, in which case
+	// it is not rewritten.
+	Corrupted bool
+
+	Keys   int
+	Blocks int
+	Lost   []LostBlock
+}
+
+// Run repairs the TSM files of Paths.
+func (cmd *Command) Run() error {
+	if len(cmd.Paths) == 0 {
+		return errors.New("path required")
+	}
+	if !cmd.DryRun && (cmd.OutputDir == "") == !cmd.Replace {
+		return errors.New("exactly one of output directory or replace required")
+	}
+
+	var corrupted, lost, points int
+	for _, path := range cmd.Paths {
+		res, err := cmd.repair(path)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		cmd.printResult(path, res)
+		if res.Corrupted {
+			corrupted++
+		}
+		lost += len(res.Lost)
+		for _, b := range res.Lost {
+			points += b.Points
+		}
+	}
+
+	fmt.Fprintf(cmd.Stdout, "%d of %d TSM file(s) corrupted, %d block(s) lost with at least %d point(s)\n", corrupted, len(cmd.Paths), lost, points)
+	return nil
+}
+
+func (cmd *Command) repair(path string) (Result, error) {
+	switch {
+	case cmd.DryRun:
+		return RepairFile(path, "")
+	case cmd.Replace:
+		output := path + ".repaired"
+		res, err := RepairFile(path, output)
+		if err != nil || !res.Corrupted {
+			return res, err
+		}
+		if err := os.Rename(path, path+".corrupt"); err != nil {
+			return res, err
+		} else if err := os.Remove(tsm1.StatsFilename(path)); err != nil && !os.IsNotExist(err) {
+			return res, err
+		}
+		if res.Blocks == 0 {
+			// Nothing was salvaged, so the file is only set aside.
+			return res, nil
+		}
+		// The stats file of the repaired file is written as that of output.
+		if err := os.Rename(output, path); err != nil {
+			return res, err
+		}
+		return res, os.Rename(tsm1.StatsFilename(output), tsm1.StatsFilename(path))
+	default:
+		if err := os.MkdirAll(cmd.OutputDir, 0777); err != nil {
+			return Result{}, err
+		}
+		return RepairFile(path, filepath.Join(cmd.OutputDir, filepath.Base(path)))
+	}
+}
+
+func (cmd *Command) printResult(path string, res Result) {
+	if !res.Corrupted {
+		fmt.Fprintf(cmd.Stdout, "%s: ok, %d block(s) of %d key(s)\n", path, res.Blocks, res.Keys)
+		return
+	}
+	fmt.Fprintf(cmd.Stdout, "%s: recovered %d block(s) of %d key(s), lost %d block(s)\n",
+		path, res.Blocks, res.Keys, len(res.Lost))
+	for _, b := range res.Lost {
+		key := "<unknown key>"
+		if b.Key != nil {
+			key = string(b.Key)
+		}
+		points := "unknown number of points"
+		if b.Points > 0 {
+			points = fmt.Sprintf("%d point(s)", b.Points)
+		}
+		fmt.Fprintf(cmd.Stdout, "  lost %s [%s, %s]: %s, %d bytes at offset %d, %s\n", key,
+			time.Unix(0, b.MinTime).UTC().Format(time.RFC3339Nano),
+			time.Unix(0, b.MaxTime).UTC().Format(time.RFC3339Nano), b.Reason,
+			b.Size, b.Offset, points)
+	}
+}
+
+// RepairFile salvages the blocks of the TSM file at path and writes them to
+// a new TSM file at output, unless output is empty. The new file is not
+// written if the file is not corrupted, or if no block can be salvaged.
+func RepairFile(path, output string) (Result, error) {
+	var res Result
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return res, err
+	}
+	if len(data) < headerSize || binary.BigEndian.Uint32(data[:4]) != tsm1.MagicNumber {
+		return res, errors.New("not a TSM file")
+	} else if data[4] != tsm1.Version {
+		return res, fmt.Errorf("unsupported TSM version %d", data[4])
+	}
+
+	// Read the index at the offset of the footer. If it can not be read to
+	// its end, the blocks are scanned to find those it does not reference,
+	// and the index is read after the last block found if the footer was
+	// lost with the tail of the file.
+	var offset int
+	if len(data) >= headerSize+footerSize {
+		if n := binary.BigEndian.Uint64(data[len(data)-footerSize:]); n >= headerSize && n <= uint64(len(data)-footerSize) {
+			offset = int(n)
+		}
+	}
+	var (
+		keys       []indexKey
+		indexStart = offset
+		scanned    []scannedBlock
+		ok         bool
+	)
+	if offset > 0 {
+		keys, ok = parseIndex(data[:len(data)-footerSize], offset)
+	}
+	if !ok {
+		res.Corrupted = true
+		limit := len(data)
+		if len(keys) > 0 {
+			limit = offset
+		}
+		var dataEnd int
+		scanned, dataEnd = scanBlocks(data[:limit])
+		if len(keys) == 0 {
+			indexStart = dataEnd
+			keys, _ = parseIndex(data, indexStart)
+		}
+	}
+
+	type block struct {
+		key   []byte
+		entry tsm1.IndexEntry
+		data  []byte
+	}
+	var blocks []block
+	indexed := make(map[int64]bool)
+	for _, k := range keys {
+		var n int
+		for _, e := range k.entries {
+			indexed[e.Offset] = true
+			b, points, reason := readBlock(data[:indexStart], e, k.typ)
+			if reason != "" {
+				res.Lost = append(res.Lost, LostBlock{
+					Key:     k.key,
+					MinTime: e.MinTime,
+					MaxTime: e.MaxTime,
+					Offset:  e.Offset,
+					Size:    e.Size,
+					Points:  points,
+					Reason:  reason,
+				})
+				continue
+			}
+			blocks = append(blocks, block{key: k.key, entry: e, data: b})
+			n++
+		}
+		if n > 0 {
+			res.Keys++
+		}
+	}
+	res.Blocks = len(blocks)
+	for _, b := range scanned {
+		if !indexed[b.offset] {
+			res.Lost = append(res.Lost, LostBlock{
+				MinTime: b.minTime,
+				MaxTime: b.maxTime,
+				Offset:  b.offset,
+				Size:    b.size,
+				Points:  b.points,
+				Reason:  ReasonNotIndexed,
+			})
+		}
+	}
+	if len(res.Lost) > 0 {
+		res.Corrupted = true
+	}
+
+	if !res.Corrupted || output == "" || len(blocks) == 0 {
+		return res, nil
+	}
+
+	w, err := createWriter(output)
+	if err != nil {
+		return res, err
+	}
+	for _, b := range blocks {
+		if err := w.WriteBlock(b.key, b.entry.MinTime, b.entry.MaxTime, b.data); err != nil {
+			w.Remove()
+			return res, err
+		}
+	}
+	if err := w.WriteIndex(); err != nil {
+		w.Remove()
+		return res, err
+	}
+	return res, w.Close()
+}
+
+// indexKey is a key of the index of a TSM file and the entries of its blocks.
+type indexKey struct {
+	key     []byte
+	typ     byte
+	entries []tsm1.IndexEntry
+}
+
+func createWriter(path string) (tsm1.TSMWriter, error) {
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%s already exists", path)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := tsm1.NewTSMWriter(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// readBlock returns the data of the block of the index entry e of a key of
+// typ, without its checksum, and its number of points, or the reason it is
+// invalid. The number of points of an invalid block is also returned if it can
+// be decoded. A block is invalid if its checksum does not match, if it can not
+// be decoded, or if its type or time range do not match those of its index
+// entry.
+func readBlock(data []byte, e tsm1.IndexEntry, typ byte) ([]byte, int, string) {
+	if e.Offset < headerSize || e.Size <= checksumSize || e.Offset+int64(e.Size) > int64(len(data)) {
+		return nil, 0, ReasonInvalid
+	}
+	b := data[e.Offset : e.Offset+int64(e.Size)]
+	values, err := tsm1.DecodeBlock(b[checksumSize:], nil)
+	if err != nil {
+		values = nil
+	}
+	if crc32.ChecksumIEEE(b[checksumSize:]) != binary.BigEndian.Uint32(b[:checksumSize]) {
+		return nil, len(values), ReasonChecksum
+	}
+	if len(values) == 0 {
+		return nil, 0, ReasonInvalid
+	}
+	if t, err := tsm1.BlockType(b[checksumSize:]); err != nil || t != typ {
+		return nil, len(values), ReasonIndexMismatch
+	}
+	if values[0].UnixNano() < e.MinTime || values[len(values)-1].UnixNano() > e.MaxTime {
+		return nil, len(values), ReasonIndexMismatch
+	}
+	return b[checksumSize:], len(values), ""
+}
+
+// parseIndex parses the index entries of data from offset, up to the first
+// incomplete or invalid key. It returns false if the index was not read to
+// the end of data.
+func parseIndex(data []byte, offset int) ([]indexKey, bool) {
+	var keys []indexKey
+	b := data[offset:]
+	for len(b) > 0 {
+		if len(b) < 2 {
+			return keys, false
+		}
+		n := int(binary.BigEndian.Uint16(b))
+		if n == 0 || len(b) < 2+n+3 {
+			return keys, false
+		}
+		k := indexKey{key: b[2 : 2+n], typ: b[2+n]}
+		count := int(binary.BigEndian.Uint16(b[3+n:]))
+		b = b[5+n:]
+		if count == 0 || len(b) < count*indexEntrySize || k.typ > tsm1.BlockUnsigned {
+			return keys, false
+		}
+		if len(keys) > 0 && string(keys[len(keys)-1].key) >= string(k.key) {
+			return keys, false
+		}
+		k.entries = make([]tsm1.IndexEntry, count)
+		for i := range k.entries {
+			if err := k.entries[i].UnmarshalBinary(b[i*indexEntrySize:]); err != nil {
+				return keys, false
+			}
+			if k.entries[i].MinTime > k.entries[i].MaxTime {
+				return keys, false
+			}
+		}
+		b = b[count*indexEntrySize:]
+		keys = append(keys, k)
+	}
+	return keys, true
+}
+
+// scannedBlock is a block found by scanning the data of a TSM file.
+type scannedBlock struct {
+	offset           int64
+	size             uint32
+	minTime, maxTime int64
+	points           int
+}
+
+// scanBlocks returns the blocks found one after the other from the header
+// of data, and the offset the first block could not be found at. Since the
+// size of a block is not recorded with it, each block is found as the
+// shortest data following a checksum that matches it and decodes.
+func scanBlocks(data []byte) ([]scannedBlock, int) {
+	var blocks []scannedBlock
+	offset := headerSize
+	for offset+checksumSize < len(data) {
+		want := binary.BigEndian.Uint32(data[offset:])
+		start := offset + checksumSize
+		end := start + maxBlockSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		var (
+			crc   uint32
+			found bool
+		)
+		for i := start; i < end; i++ {
+			crc = crc32.Update(crc, crc32.IEEETable, data[i:i+1])
+			if crc != want {
+				continue
+			}
+			values, err := tsm1.DecodeBlock(data[start:i+1], nil)
+			if err != nil || len(values) == 0 {
+				continue
+			}
+			blocks = append(blocks, scannedBlock{
+				offset:  int64(offset),
+				size:    uint32(i + 1 - offset),
+				minTime: values[0].UnixNano(),
+				maxTime: values[len(values)-1].UnixNano(),
+				points:  len(values),
+			})
+			offset, found = i+1, true
+			break
+		}
+		if !found {
+			break
+		}
+	}
+	return blocks, offset
+}
@@ -0,0 +1,211 @@
+package repairtsm_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile/tsmtest"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/repairtsm"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+func TestCommand_Run_Truncated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "repairtsm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "000000001-000000001.tsm")
+	writeTSMFile(t, path)
+
+	// Truncate the file within the index entries of the last key.
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, fi.Size()-20); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	cmd := &repairtsm.Command{
+		Stdout:    &stdout,
+		Stderr:    ioutil.Discard,
+		Paths:     []string{path},
+		OutputDir: filepath.Join(dir, "repaired"),
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		path + ": recovered 3 block(s) of 2 key(s), lost 1 block(s)",
+		"lost <unknown key> [1970-01-01T00:00:00.00000005Z, 1970-01-01T00:00:00.00000006Z]: not indexed",
+		"1 of 1 TSM file(s) corrupted, 1 block(s) lost",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Fatalf("missing %q in output: %s", want, stdout.String())
+		}
+	}
+
+	got := tsmtest.ReadValues(t, filepath.Join(dir, "repaired", "000000001-000000001.tsm"))
+	want := map[string][]interface{}{
+		"cpu#!~#usage": {1.0, 2.0, 3.0},
+		"disk#!~#free": {int64(4)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected values: got %v, want %v", got, want)
+	}
+}
+
+func TestCommand_Run_Checksum(t *testing.T) {
+	dir, err := ioutil.TempDir("", "repairtsm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "000000001-000000001.tsm")
+	writeTSMFile(t, path)
+
+	// Corrupt the data of the second block of cpu.
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := r.ReadEntries([]byte("cpu#!~#usage"), nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[entries[1].Offset+int64(entries[1].Size)-1] ^= 0xff
+	if err := ioutil.WriteFile(path, data, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	// Dry runs only report.
+	var stdout bytes.Buffer
+	cmd := &repairtsm.Command{Stdout: &stdout, Stderr: ioutil.Discard, Paths: []string{path}, DryRun: true}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("lost cpu#!~#usage [1970-01-01T00:00:00.00000003Z, 1970-01-01T00:00:00.00000003Z]: checksum mismatch, %d bytes at offset %d",
+		entries[1].Size, entries[1].Offset); !strings.Contains(stdout.String(), want) {
+		t.Fatalf("missing %q in output: %s", want, stdout.String())
+	}
+	if _, err := os.Stat(path + ".corrupt"); !os.IsNotExist(err) {
+		t.Fatalf("unexpected file: %v", err)
+	}
+
+	stdout.Reset()
+	cmd = &repairtsm.Command{Stdout: &stdout, Stderr: ioutil.Discard, Paths: []string{path}, Replace: true}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if want := path + ": recovered 3 block(s) of 3 key(s), lost 1 block(s)"; !strings.Contains(stdout.String(), want) {
+		t.Fatalf("missing %q in output: %s", want, stdout.String())
+	}
+	if _, err := os.Stat(path + ".corrupt"); err != nil {
+		t.Fatal(err)
+	}
+	got := tsmtest.ReadValues(t, path)
+	want := map[string][]interface{}{
+		"cpu#!~#usage": {1.0, 2.0},
+		"disk#!~#free": {int64(4)},
+		"mem#!~#used":  {int64(5), int64(6)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected values: got %v, want %v", got, want)
+	}
+
+	// The repaired file is not corrupted.
+	stdout.Reset()
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if want := path + ": ok, 3 block(s) of 3 key(s)"; !strings.Contains(stdout.String(), want) {
+		t.Fatalf("missing %q in output: %s", want, stdout.String())
+	}
+}
+
+func TestCommand_Run_IndexMismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "repairtsm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "000000001-000000001.tsm")
+	writeTSMFile(t, path)
+
+	// Shift the time range of the index entry of disk before the time of
+	// the point of its block. The entry follows the key, its type and its
+	// number of entries.
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := bytes.LastIndex(data, []byte("disk#!~#free"))
+	if i < 0 {
+		t.Fatal("index entry not found")
+	}
+	entry := data[i+len("disk#!~#free")+3:]
+	binary.BigEndian.PutUint64(entry[0:], 30)
+	binary.BigEndian.PutUint64(entry[8:], 39)
+	if err := ioutil.WriteFile(path, data, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	cmd := &repairtsm.Command{Stdout: &stdout, Stderr: ioutil.Discard, Paths: []string{path}, OutputDir: filepath.Join(dir, "repaired")}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"lost disk#!~#free [1970-01-01T00:00:00.00000003Z, 1970-01-01T00:00:00.000000039Z]: index mismatch",
+		", 1 point(s)\n",
+		"1 of 1 TSM file(s) corrupted, 1 block(s) lost with at least 1 point(s)",
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Fatalf("missing %q in output: %s", want, stdout.String())
+		}
+	}
+
+	got := tsmtest.ReadValues(t, filepath.Join(dir, "repaired", "000000001-000000001.tsm"))
+	want := map[string][]interface{}{
+		"cpu#!~#usage": {1.0, 2.0, 3.0},
+		"mem#!~#used":  {int64(5), int64(6)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected values: got %v, want %v", got, want)
+	}
+}
+
+// writeTSMFile writes a TSM file at path with two blocks for cpu and one
+// for each of disk and mem.
+func writeTSMFile(t *testing.T, path string) {
+	t.Helper()
+
+	tsmtest.WriteBlocks(t, path,
+		tsmtest.Block{Key: "cpu#!~#usage", Values: tsm1.Values{tsm1.NewValue(10, 1.0), tsm1.NewValue(20, 2.0)}},
+		tsmtest.Block{Key: "cpu#!~#usage", Values: tsm1.Values{tsm1.NewValue(30, 3.0)}},
+		tsmtest.Block{Key: "disk#!~#free", Values: tsm1.Values{tsm1.NewValue(40, int64(4))}},
+		tsmtest.Block{Key: "mem#!~#used", Values: tsm1.Values{tsm1.NewValue(50, int64(5)), tsm1.NewValue(60, int64(6))}},
+	)
+}
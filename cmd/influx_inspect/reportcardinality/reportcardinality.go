@@ -0,0 +1,388 @@
+// Package reportcardinality reports the exact series cardinality of an index
+// by measurement and tag key, and its growth since a saved snapshot.
+package reportcardinality
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsi1"
+)
+
+// Command reports the number of series of each measurement of a TSI index,
+// counting each series of the index exactly rather than estimating them.
+//
+// The report can be saved as a snapshot, and compared with a snapshot saved
+// earlier to find the measurements whose cardinality grows the most.
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// IndexPath and SeriesFilePath are the paths of the TSI index and of the
+	// series file.
+	IndexPath      string
+	SeriesFilePath string
+
+	// OrgID and BucketID optionally restrict the report to the series of an
+	// organization, or of a bucket within that organization.
+	OrgID    influxdb.ID
+	BucketID influxdb.ID
+
+	// ByTagKey reports the number of series and of values of each tag key
+	// of the measurements.
+	ByTagKey bool
+
+	// Top limits the report to the Top measurements with the most series,
+	// or with the largest growth, if positive.
+	Top int
+
+	// SnapshotPath optionally saves the report as a JSON snapshot, to be
+	// compared with by a later report.
+	SnapshotPath string
+
+	// ComparePath optionally compares the report with the snapshot saved
+	// at this path, reporting the growth of each measurement.
+	ComparePath string
+
+	// Now returns the time of the snapshot, time.Now if nil.
+	Now func() time.Time
+}
+
+// NewCommand returns a new instance of Command writing to the standard output and error.
+func NewCommand() *Command {
+	return &Command{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Now:    time.Now,
+	}
+}
+
+// Snapshot is the series cardinality of an index at a point in time.
+type Snapshot struct {
+	Time         time.Time      `json:"time"`
+	Series       int64          `json:"series"`
+	Measurements []*Measurement `json:"measurements"`
+}
+
+// Measurement is the series cardinality of a measurement of a bucket.
+type Measurement struct {
+	OrgID    influxdb.ID `json:"orgID"`
+	BucketID influxdb.ID `json:"bucketID"`
+	Name     string      `json:"name"`
+	Series   int64       `json:"series"`
+	TagKeys  []*TagKey   `json:"tagKeys,omitempty"`
+}
+
+// TagKey is the number of series and of distinct values of a tag key of a
+// measurement.
+type TagKey struct {
+	Key    string `json:"key"`
+	Series int64  `json:"series"`
+	Values int64  `json:"values"`
+}
+
+// Growth is the change of the series cardinality of a measurement between
+// two snapshots.
+type Growth struct {
+	OrgID    influxdb.ID
+	BucketID influxdb.ID
+	Name     string
+	Previous int64
+	Current  int64
+}
+
+// Delta returns the number of series added since the previous snapshot,
+// negative if series were removed.
+func (g *Growth) Delta() int64 {
+	return g.Current - g.Previous
+}
+
+// Run reports the cardinality of the index.
+func (cmd *Command) Run() error {
+	if cmd.IndexPath == "" || cmd.SeriesFilePath == "" {
+		return errors.New("index and series file paths required")
+	}
+	if cmd.BucketID.Valid() && !cmd.OrgID.Valid() {
+		return errors.New("bucket requires an organization")
+	}
+
+	var previous *Snapshot
+	if cmd.ComparePath != "" {
+		var err error
+		if previous, err = ReadSnapshot(cmd.ComparePath); err != nil {
+			return err
+		}
+	}
+
+	snapshot, err := cmd.Snapshot()
+	if err != nil {
+		return err
+	}
+
+	if previous != nil {
+		cmd.printGrowth(previous, snapshot)
+	} else {
+		cmd.printSnapshot(snapshot)
+	}
+
+	if cmd.SnapshotPath != "" {
+		if err := WriteSnapshot(cmd.SnapshotPath, snapshot); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.Stdout, "\nsaved snapshot to %s\n", cmd.SnapshotPath)
+	}
+	return nil
+}
+
+// Snapshot returns the cardinality of the index.
+func (cmd *Command) Snapshot() (*Snapshot, error) {
+	ctx := context.Background()
+	sfile := tsdb.NewSeriesFile(cmd.SeriesFilePath)
+	if err := sfile.Open(ctx); err != nil {
+		return nil, err
+	}
+	defer sfile.Close()
+
+	idx := tsi1.NewIndex(sfile, tsi1.NewConfig(), tsi1.WithPath(cmd.IndexPath), tsi1.DisableMetrics())
+	if err := idx.Open(ctx); err != nil {
+		return nil, err
+	}
+	defer idx.Close()
+
+	now := time.Now
+	if cmd.Now != nil {
+		now = cmd.Now
+	}
+	s := &Snapshot{Time: now().UTC()}
+
+	itr, err := idx.MeasurementIterator()
+	if err != nil {
+		return nil, err
+	} else if itr == nil {
+		return s, nil
+	}
+	defer itr.Close()
+
+	measurements := make(map[string]*measurementCounter)
+	for {
+		name, err := itr.Next()
+		if err != nil {
+			return nil, err
+		} else if name == nil {
+			break
+		}
+		// The names of the index are those of the organizations and buckets,
+		// the measurements being tags of their series.
+		if len(name) != 16 {
+			continue
+		}
+		orgID, bucketID := tsdb.DecodeNameSlice(name)
+		if cmd.OrgID.Valid() && cmd.OrgID != orgID || cmd.BucketID.Valid() && cmd.BucketID != bucketID {
+			continue
+		}
+		if err := cmd.countSeries(idx, sfile, name, measurements); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, c := range measurements {
+		s.Series += c.Series
+		s.Measurements = append(s.Measurements, c.measurement())
+	}
+	sort.Slice(s.Measurements, func(i, j int) bool {
+		a, b := s.Measurements[i], s.Measurements[j]
+		if a.Series != b.Series {
+			return a.Series > b.Series
+		}
+		return a.key() < b.key()
+	})
+	return s, nil
+}
+
+// countSeries counts the series of the measurements of the bucket name.
+func (cmd *Command) countSeries(idx *tsi1.Index, sfile *tsdb.SeriesFile, name []byte, measurements map[string]*measurementCounter) error {
+	orgID, bucketID := tsdb.DecodeNameSlice(name)
+	sitr, err := idx.MeasurementSeriesIDIterator(name)
+	if err != nil {
+		return err
+	} else if sitr == nil {
+		return nil
+	}
+	defer sitr.Close()
+
+	for {
+		e, err := sitr.Next()
+		if err != nil {
+			return err
+		} else if e.SeriesID.IsZero() {
+			return nil
+		}
+
+		_, tags := sfile.Series(e.SeriesID)
+		if len(tags) == 0 {
+			return fmt.Errorf("series ID has empty key: %d", e.SeriesID.RawID())
+		}
+		m := &Measurement{
+			OrgID:    orgID,
+			BucketID: bucketID,
+			Name:     string(tags.Get(models.MeasurementTagKeyBytes)),
+		}
+		c := measurements[m.key()]
+		if c == nil {
+			c = &measurementCounter{Measurement: *m, tagKeys: make(map[string]*tagKeyCounter)}
+			measurements[m.key()] = c
+		}
+		c.Series++
+
+		if !cmd.ByTagKey {
+			continue
+		}
+		for _, t := range tags {
+			if bytes.Equal(t.Key, models.MeasurementTagKeyBytes) || bytes.Equal(t.Key, models.FieldKeyTagKeyBytes) {
+				continue
+			}
+			tc := c.tagKeys[string(t.Key)]
+			if tc == nil {
+				tc = &tagKeyCounter{values: make(map[string]struct{})}
+				c.tagKeys[string(t.Key)] = tc
+			}
+			tc.series++
+			tc.values[string(t.Value)] = struct{}{}
+		}
+	}
+}
+
+type measurementCounter struct {
+	Measurement
+	tagKeys map[string]*tagKeyCounter
+}
+
+type tagKeyCounter struct {
+	series int64
+	values map[string]struct{}
+}
+
+func (c *measurementCounter) measurement() *Measurement {
+	m := c.Measurement
+	for key, tc := range c.tagKeys {
+		m.TagKeys = append(m.TagKeys, &TagKey{Key: key, Series: tc.series, Values: int64(len(tc.values))})
+	}
+	sort.Slice(m.TagKeys, func(i, j int) bool {
+		a, b := m.TagKeys[i], m.TagKeys[j]
+		if a.Values != b.Values {
+			return a.Values > b.Values
+		}
+		return a.Key < b.Key
+	})
+	return &m
+}
+
+// key identifies a measurement across organizations and buckets.
+func (m *Measurement) key() string {
+	return m.OrgID.String() + "/" + m.BucketID.String() + "/" + m.Name
+}
+
+// Compare returns the growth of the measurements of current since previous,
+// sorted from the largest growth to the largest shrinkage. The measurements
+// of previous which no longer exist have a current cardinality of 0.
+func Compare(previous, current *Snapshot) []*Growth {
+	growths := make(map[string]*Growth)
+	for _, m := range previous.Measurements {
+		growths[m.key()] = &Growth{OrgID: m.OrgID, BucketID: m.BucketID, Name: m.Name, Previous: m.Series}
+	}
+	for _, m := range current.Measurements {
+		g := growths[m.key()]
+		if g == nil {
+			g = &Growth{OrgID: m.OrgID, BucketID: m.BucketID, Name: m.Name}
+			growths[m.key()] = g
+		}
+		g.Current = m.Series
+	}
+
+	list := make([]*Growth, 0, len(growths))
+	for _, g := range growths {
+		list = append(list, g)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Delta() != b.Delta() {
+			return a.Delta() > b.Delta()
+		}
+		if a.Current != b.Current {
+			return a.Current > b.Current
+		}
+		return a.OrgID.String()+a.BucketID.String()+a.Name < b.OrgID.String()+b.BucketID.String()+b.Name
+	})
+	return list
+}
+
+func (cmd *Command) printSnapshot(s *Snapshot) {
+	tw := tabwriter.NewWriter(cmd.Stdout, 8, 2, 1, ' ', 0)
+	fmt.Fprintln(tw, strings.Join([]string{"Organization", "Bucket", "Measurement", "Series"}, "\t"))
+	for i, m := range s.Measurements {
+		if cmd.Top > 0 && i == cmd.Top {
+			break
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", m.OrgID, m.BucketID, m.Name, m.Series)
+		for _, tk := range m.TagKeys {
+			fmt.Fprintf(tw, "\t\t  %s\t%d\t(%d value(s))\n", tk.Key, tk.Series, tk.Values)
+		}
+	}
+	tw.Flush()
+
+	fmt.Fprintf(cmd.Stdout, "\n%d series in %d measurement(s)\n", s.Series, len(s.Measurements))
+}
+
+func (cmd *Command) printGrowth(previous, current *Snapshot) {
+	tw := tabwriter.NewWriter(cmd.Stdout, 8, 2, 1, ' ', 0)
+	fmt.Fprintln(tw, strings.Join([]string{"Organization", "Bucket", "Measurement", "Previous", "Current", "Delta", "Growth"}, "\t"))
+	for i, g := range Compare(previous, current) {
+		if cmd.Top > 0 && i == cmd.Top {
+			break
+		}
+		growth := "new"
+		if g.Previous > 0 {
+			growth = fmt.Sprintf("%+.1f%%", float64(g.Delta())/float64(g.Previous)*100)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%+d\t%s\n", g.OrgID, g.BucketID, g.Name, g.Previous, g.Current, g.Delta(), growth)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(cmd.Stdout, "\n%d series, %+d since %s\n",
+		current.Series, current.Series-previous.Series, previous.Time.Format(time.RFC3339))
+}
+
+// ReadSnapshot reads the snapshot saved at path.
+func ReadSnapshot(path string) (*Snapshot, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %v", path, err)
+	}
+	return s, nil
+}
+
+// WriteSnapshot saves the snapshot at path.
+func WriteSnapshot(path string, s *Snapshot) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0666)
+}
@@ -0,0 +1,156 @@
+package reportcardinality_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/reportcardinality"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsi1"
+	"go.uber.org/zap"
+)
+
+var (
+	orgID    = influxdb.ID(0x1000)
+	bucketID = influxdb.ID(0x2000)
+)
+
+func TestCommand_Run(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reportcardinality")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	indexPath, sfilePath := filepath.Join(dir, "index"), filepath.Join(dir, "_series")
+	writeSeries(t, indexPath, sfilePath, bucketID, `
+cpu,host=a,region=east usage=1,idle=2
+cpu,host=b,region=east usage=1
+mem,host=a free=1
+`)
+	writeSeries(t, indexPath, sfilePath, bucketID+1, "disk,host=a used=1")
+
+	snapshot := filepath.Join(dir, "snapshot.json")
+	var stdout bytes.Buffer
+	cmd := reportcardinality.NewCommand()
+	cmd.Stdout = &stdout
+	cmd.IndexPath, cmd.SeriesFilePath = indexPath, sfilePath
+	cmd.OrgID, cmd.BucketID = orgID, bucketID
+	cmd.ByTagKey = true
+	cmd.SnapshotPath = snapshot
+	cmd.Now = func() time.Time { return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) }
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := reportcardinality.ReadSnapshot(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Series != 4 || len(s.Measurements) != 2 {
+		t.Fatalf("unexpected snapshot: %+v", s)
+	}
+	cpu := s.Measurements[0]
+	if cpu.Name != "cpu" || cpu.BucketID != bucketID || cpu.Series != 3 || len(cpu.TagKeys) != 2 {
+		t.Fatalf("unexpected measurement: %+v", cpu)
+	}
+	if tk := cpu.TagKeys[0]; tk.Key != "host" || tk.Series != 3 || tk.Values != 2 {
+		t.Fatalf("unexpected tag key: %+v", tk)
+	}
+	if tk := cpu.TagKeys[1]; tk.Key != "region" || tk.Series != 3 || tk.Values != 1 {
+		t.Fatalf("unexpected tag key: %+v", tk)
+	}
+	if mem := s.Measurements[1]; mem.Name != "mem" || mem.Series != 1 {
+		t.Fatalf("unexpected measurement: %+v", mem)
+	}
+	if !strings.Contains(stdout.String(), "4 series in 2 measurement(s)") {
+		t.Fatalf("unexpected output: %s", stdout.String())
+	}
+
+	// The series of mem grow, and a new measurement is created.
+	writeSeries(t, indexPath, sfilePath, bucketID, `
+mem,host=b free=1
+mem,host=c free=1
+swap,host=a used=1
+`)
+	stdout.Reset()
+	cmd.ByTagKey = false
+	cmd.SnapshotPath = ""
+	cmd.ComparePath = snapshot
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(stdout.String(), "\n")
+	for i, want := range []string{
+		"mem 1 3 +2 +200.0%",
+		"swap 0 1 +1 new",
+		"cpu 3 3 +0 +0.0%",
+	} {
+		if got := strings.Join(strings.Fields(lines[i+1])[2:], " "); got != want {
+			t.Fatalf("unexpected line %d: got %q, want %q", i+1, got, want)
+		}
+	}
+	if !strings.Contains(stdout.String(), "7 series, +3 since 2020-01-01T00:00:00Z") {
+		t.Fatalf("unexpected output: %s", stdout.String())
+	}
+}
+
+func TestCompare(t *testing.T) {
+	previous := &reportcardinality.Snapshot{Measurements: []*reportcardinality.Measurement{
+		{OrgID: orgID, BucketID: bucketID, Name: "cpu", Series: 10},
+		{OrgID: orgID, BucketID: bucketID, Name: "dropped", Series: 5},
+	}}
+	current := &reportcardinality.Snapshot{Measurements: []*reportcardinality.Measurement{
+		{OrgID: orgID, BucketID: bucketID, Name: "cpu", Series: 100},
+		{OrgID: orgID, BucketID: bucketID, Name: "new", Series: 1},
+	}}
+
+	got := reportcardinality.Compare(previous, current)
+	want := []reportcardinality.Growth{
+		{OrgID: orgID, BucketID: bucketID, Name: "cpu", Previous: 10, Current: 100},
+		{OrgID: orgID, BucketID: bucketID, Name: "new", Previous: 0, Current: 1},
+		{OrgID: orgID, BucketID: bucketID, Name: "dropped", Previous: 5, Current: 0},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected growth: %v", got)
+	}
+	for i := range want {
+		if *got[i] != want[i] {
+			t.Fatalf("unexpected growth %d: got %+v, want %+v", i, *got[i], want[i])
+		}
+	}
+}
+
+// writeSeries creates the series of the line protocol in the index.
+func writeSeries(t *testing.T, indexPath, sfilePath string, bucketID influxdb.ID, lp string) {
+	t.Helper()
+
+	sfile := tsdb.NewSeriesFile(sfilePath)
+	sfile.Logger = zap.NewNop()
+	if err := sfile.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer sfile.Close()
+	idx := tsi1.NewIndex(sfile, tsi1.NewConfig(), tsi1.WithPath(indexPath), tsi1.DisableMetrics())
+	if err := idx.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+
+	name := tsdb.EncodeName(orgID, bucketID)
+	points, err := models.ParsePoints([]byte(lp), models.EscapeMeasurement(name[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.CreateSeriesListIfNotExists(tsdb.NewSeriesCollection(points)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,250 @@
+// Package reportdisk reports the disk usage of TSM files by organization,
+// bucket and measurement, reading only the indexes of the files.
+package reportdisk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// Output formats of the report.
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+)
+
+// Command attributes the bytes of the blocks of TSM files to the
+// organization, bucket and measurement of their series, and optionally to the
+// tag keys of the series.
+//
+// The bytes are the compressed sizes of the blocks recorded by the indexes of
+// the files, so that the blocks themselves are not read. The indexes are not
+// attributed, and the blocks with values deleted by tombstones are attributed
+// until they are compacted.
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Paths lists the TSM files to report on, and the directories searched
+	// recursively for them.
+	Paths []string
+
+	// OrgID and BucketID optionally restrict the report to the series of an
+	// organization, or of a bucket within that organization.
+	OrgID    influxdb.ID
+	BucketID influxdb.ID
+
+	// ByTagKey breaks the usage of each measurement down by tag key: the
+	// blocks of a series are attributed to each of its tag keys.
+	ByTagKey bool
+
+	// Format is the output format, FormatTable by default.
+	Format string
+
+	// Top limits the report to the Top largest rows, if positive.
+	Top int
+}
+
+// NewCommand returns a new instance of Command writing to the standard output and error.
+func NewCommand() *Command {
+	return &Command{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Format: FormatTable,
+	}
+}
+
+// Usage is the disk usage of the blocks of an organization, bucket and
+// measurement, or of a tag key of a measurement.
+type Usage struct {
+	OrgID       influxdb.ID `json:"orgID"`
+	BucketID    influxdb.ID `json:"bucketID"`
+	Measurement string      `json:"measurement"`
+	TagKey      string      `json:"tagKey,omitempty"`
+	Blocks      int64       `json:"blocks"`
+	Bytes       int64       `json:"bytes"`
+}
+
+// Report is the disk usage of TSM files, with the usages sorted from the
+// largest to the smallest.
+type Report struct {
+	Files  int      `json:"files"`
+	Blocks int64    `json:"blocks"`
+	Bytes  int64    `json:"bytes"`
+	Usages []*Usage `json:"usages"`
+}
+
+// Run reports the disk usage of the TSM files found in Paths.
+func (cmd *Command) Run() error {
+	if len(cmd.Paths) == 0 {
+		return errors.New("path required")
+	}
+	if cmd.BucketID.Valid() && !cmd.OrgID.Valid() {
+		return errors.New("bucket requires an organization")
+	}
+	if cmd.Format == "" {
+		cmd.Format = FormatTable
+	}
+	if cmd.Format != FormatTable && cmd.Format != FormatJSON {
+		return fmt.Errorf("unsupported format %q", cmd.Format)
+	}
+
+	files, err := tsmfile.Find(cmd.Paths)
+	if err != nil {
+		return err
+	}
+	report, err := cmd.Report(files)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Format == FormatJSON {
+		enc := json.NewEncoder(cmd.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	return cmd.printTable(report)
+}
+
+// Report returns the disk usage of the TSM files.
+func (cmd *Command) Report(files []string) (*Report, error) {
+	report := &Report{Files: len(files)}
+	usages := make(map[Usage]*Usage)
+	add := func(u Usage, size int64) {
+		v := usages[u]
+		if v == nil {
+			v = &u
+			usages[u] = v
+		}
+		v.Blocks++
+		v.Bytes += size
+	}
+
+	var (
+		tags   models.Tags
+		prefix = tsmfile.Prefix(cmd.OrgID, cmd.BucketID)
+	)
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		r, err := tsm1.NewTSMReader(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("unable to read %s: %v", path, err)
+		}
+
+		iter := r.Iterator(prefix)
+		for iter.Next() {
+			key := iter.Key()
+			if !bytes.HasPrefix(key, prefix) {
+				break
+			}
+
+			seriesKey, _ := tsm1.SeriesAndFieldFromCompositeKey(key)
+			var name []byte
+			name, tags = models.ParseKeyBytesWithTags(seriesKey, tags)
+			// Skip the keys not named after an organization and bucket.
+			if len(name) != 16 {
+				continue
+			}
+			u := Usage{Measurement: tags.GetString(models.MeasurementTagKey)}
+			u.OrgID, u.BucketID = tsdb.DecodeNameSlice(name)
+
+			for _, e := range iter.Entries() {
+				size := int64(e.Size)
+				report.Blocks++
+				report.Bytes += size
+				add(u, size)
+				if !cmd.ByTagKey {
+					continue
+				}
+				for _, t := range tags {
+					if bytes.Equal(t.Key, models.MeasurementTagKeyBytes) || bytes.Equal(t.Key, models.FieldKeyTagKeyBytes) {
+						continue
+					}
+					tu := u
+					tu.TagKey = string(t.Key)
+					add(tu, size)
+				}
+			}
+		}
+		err = iter.Err()
+		if cerr := r.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+
+	for _, u := range usages {
+		report.Usages = append(report.Usages, u)
+	}
+	sort.Slice(report.Usages, func(i, j int) bool {
+		a, b := report.Usages[i], report.Usages[j]
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		if a.OrgID != b.OrgID {
+			return a.OrgID < b.OrgID
+		}
+		if a.BucketID != b.BucketID {
+			return a.BucketID < b.BucketID
+		}
+		if a.Measurement != b.Measurement {
+			return a.Measurement < b.Measurement
+		}
+		return a.TagKey < b.TagKey
+	})
+	if cmd.Top > 0 && len(report.Usages) > cmd.Top {
+		report.Usages = report.Usages[:cmd.Top]
+	}
+	return report, nil
+}
+
+func (cmd *Command) printTable(report *Report) error {
+	tw := tabwriter.NewWriter(cmd.Stdout, 8, 2, 1, ' ', 0)
+	header := []string{"Organization", "Bucket", "Measurement"}
+	if cmd.ByTagKey {
+		header = append(header, "Tag Key")
+	}
+	fmt.Fprintln(tw, strings.Join(append(header, "Blocks", "Bytes", "Percent"), "\t"))
+
+	for _, u := range report.Usages {
+		row := []string{u.OrgID.String(), u.BucketID.String(), u.Measurement}
+		if cmd.ByTagKey {
+			tagKey := u.TagKey
+			if tagKey == "" {
+				tagKey = "*"
+			}
+			row = append(row, tagKey)
+		}
+		var percent float64
+		if report.Bytes > 0 {
+			percent = float64(u.Bytes) / float64(report.Bytes) * 100
+		}
+		row = append(row, fmt.Sprint(u.Blocks), fmt.Sprint(u.Bytes), fmt.Sprintf("%.1f%%", percent))
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.Stdout, "\n%d block(s), %d bytes in %d TSM file(s)\n", report.Blocks, report.Bytes, report.Files)
+	return nil
+}
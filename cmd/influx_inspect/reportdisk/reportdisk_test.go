@@ -0,0 +1,186 @@
+package reportdisk_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile/tsmtest"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/reportdisk"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+var (
+	orgID    = influxdb.ID(0x1000)
+	bucketID = influxdb.ID(0x2000)
+)
+
+func TestCommand_Report(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reportdisk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tsmtest.WriteFile(t, filepath.Join(dir, "1", "000000001-000000001.tsm"), map[string]tsm1.Values{
+		seriesKey(bucketID, "cpu", "usage", "host", "a"):            {tsm1.NewValue(0, 1.0)},
+		seriesKey(bucketID, "cpu", "usage", "host", "b", "dc", "x"): {tsm1.NewValue(0, 1.0), tsm1.NewValue(10, 2.0)},
+		seriesKey(bucketID, "mem", "free", "host", "a"):             {tsm1.NewValue(0, int64(1))},
+		seriesKey(bucketID+1, "cpu", "usage", "host", "a"):          {tsm1.NewValue(0, 1.0)},
+	})
+	tsmtest.WriteFile(t, filepath.Join(dir, "2", "000000001-000000001.tsm"), map[string]tsm1.Values{
+		seriesKey(bucketID, "cpu", "usage", "host", "a"): {tsm1.NewValue(20, 3.0)},
+	})
+	sizes := blockSizes(t, dir)
+
+	cmd := reportdisk.NewCommand()
+	cmd.OrgID, cmd.BucketID = orgID, bucketID
+	cmd.ByTagKey = true
+	files := []string{
+		filepath.Join(dir, "1", "000000001-000000001.tsm"),
+		filepath.Join(dir, "2", "000000001-000000001.tsm"),
+	}
+	report, err := cmd.Report(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cpu := sizes[seriesKey(bucketID, "cpu", "usage", "host", "a")] + sizes[seriesKey(bucketID, "cpu", "usage", "host", "b", "dc", "x")]
+	mem := sizes[seriesKey(bucketID, "mem", "free", "host", "a")]
+	if report.Files != 2 || report.Blocks != 4 || report.Bytes != cpu+mem {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	type row struct {
+		measurement, tagKey string
+		blocks, bytes       int64
+	}
+	var got []row
+	for _, u := range report.Usages {
+		if u.OrgID != orgID || u.BucketID != bucketID {
+			t.Fatalf("unexpected usage: %+v", u)
+		}
+		got = append(got, row{u.Measurement, u.TagKey, u.Blocks, u.Bytes})
+	}
+	want := []row{
+		{"cpu", "", 3, cpu},
+		{"cpu", "host", 3, cpu},
+		{"cpu", "dc", 1, sizes[seriesKey(bucketID, "cpu", "usage", "host", "b", "dc", "x")]},
+		{"mem", "", 1, mem},
+		{"mem", "host", 1, mem},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected usages:\ngot  %v\nwant %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("unexpected usages:\ngot  %v\nwant %v", got, want)
+		}
+	}
+}
+
+func TestCommand_Run(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reportdisk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tsmtest.WriteFile(t, filepath.Join(dir, "1", "000000001-000000001.tsm"), map[string]tsm1.Values{
+		seriesKey(bucketID, "cpu", "usage", "host", "a"): {tsm1.NewValue(0, 1.0)},
+		seriesKey(bucketID, "mem", "free", "host", "a"):  {tsm1.NewValue(0, int64(1))},
+	})
+
+	var stdout bytes.Buffer
+	cmd := reportdisk.NewCommand()
+	cmd.Stdout = &stdout
+	cmd.Paths = []string{dir}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(stdout.String(), "\n")
+	if !strings.HasPrefix(lines[0], "Organization") || !strings.Contains(lines[1], "0000000000001000") ||
+		!strings.Contains(stdout.String(), "2 block(s)") || !strings.Contains(stdout.String(), "in 1 TSM file(s)") {
+		t.Fatalf("unexpected output:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	cmd.Format = reportdisk.FormatJSON
+	cmd.Top = 1
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	var report reportdisk.Report
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Files != 1 || report.Blocks != 2 || len(report.Usages) != 1 || report.Usages[0].BucketID != bucketID {
+		t.Fatalf("unexpected report: %s", stdout.String())
+	}
+}
+
+func TestCommand_Run_Invalid(t *testing.T) {
+	for _, tt := range []struct {
+		cmd reportdisk.Command
+		err string
+	}{
+		{cmd: reportdisk.Command{}, err: "path required"},
+		{cmd: reportdisk.Command{Paths: []string{"data"}, BucketID: bucketID}, err: "bucket requires an organization"},
+		{cmd: reportdisk.Command{Paths: []string{"data"}, Format: "csv"}, err: `unsupported format "csv"`},
+	} {
+		if err := tt.cmd.Run(); err == nil || err.Error() != tt.err {
+			t.Errorf("unexpected error: got %v, want %q", err, tt.err)
+		}
+	}
+}
+
+// blockSizes returns the total size of the blocks of each key of the TSM
+// files of dir.
+func blockSizes(t *testing.T, dir string) map[string]int64 {
+	t.Helper()
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*", "*.tsm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sizes := make(map[string]int64)
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := tsm1.NewTSMReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		iter := r.Iterator(nil)
+		for iter.Next() {
+			for _, e := range iter.Entries() {
+				sizes[string(iter.Key())] += int64(e.Size)
+			}
+		}
+		r.Close()
+	}
+	return sizes
+}
+
+// seriesKey returns the TSM key of the field of a series in a bucket of the
+// test organization.
+func seriesKey(bucketID influxdb.ID, measurement, field string, tags ...string) string {
+	name := tsdb.EncodeName(orgID, bucketID)
+	m := map[string]string{
+		models.MeasurementTagKey: measurement,
+		models.FieldKeyTagKey:    field,
+	}
+	for i := 0; i < len(tags); i += 2 {
+		m[tags[i]] = tags[i+1]
+	}
+	return string(models.MakeKey(name[:], models.NewTags(m))) + "#!~#" + field
+}
@@ -0,0 +1,391 @@
+// Package shifttimestamps shifts the timestamps of selected series of raw
+// TSM files by a fixed offset, such as to fix the data written by clients
+// with a misconfigured time zone.
+package shifttimestamps
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/predicate"
+	"github.com/influxdata/influxdb/storage/wal"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// Command shifts the timestamps of the values of the selected series of the
+// TSM files of a data directory by Offset.
+//
+// The blocks of the selected series are decoded, shifted and re-encoded, and
+// those of the other series are copied verbatim. Tombstoned values are
+// dropped from the rewritten files, whose tombstone files are removed, so
+// that the tombstones do not apply to the shifted values. Files without any
+// selected series are left untouched. The storage engine must not be running.
+//
+// Shifted values may overwrite values of the same series already at their
+// new timestamps in other files, depending on the order of the files.
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// DataDir is the directory searched recursively for TSM files.
+	DataDir string
+
+	// WALDir is the optional WAL directory of the engine, checked for values
+	// of the selected series, which could not be shifted.
+	WALDir string
+
+	// OrgID and BucketID optionally restrict the shift to series of an
+	// organization, or of a bucket within that organization.
+	OrgID    influxdb.ID
+	BucketID influxdb.ID
+
+	// Measurements optionally restricts the shift to the series of the named
+	// measurements.
+	Measurements []string
+
+	// Where is an optional predicate on the tags of the series, such as
+	// `host=web01 AND region=us-east`, restricting the shift to the series
+	// whose tags match it.
+	Where string
+
+	// Field optionally restricts the shift to the values of this field.
+	Field string
+
+	// Offset is added to the timestamps of the values of the selected series.
+	Offset time.Duration
+
+	// DryRun reports what would be shifted without modifying any file.
+	DryRun bool
+
+	where influxdb.Predicate
+}
+
+// NewCommand returns a new instance of Command writing to the standard output and error.
+func NewCommand() *Command {
+	return &Command{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Stats summarizes the shift of the values of a TSM file.
+type Stats struct {
+	Series int
+	Blocks int
+	Values int
+}
+
+// Run shifts the timestamps of the selected series of the TSM files of DataDir.
+func (cmd *Command) Run() error {
+	if cmd.DataDir == "" {
+		return errors.New("data directory required")
+	}
+	if cmd.Offset == 0 {
+		return errors.New("non-zero offset required")
+	}
+	if cmd.BucketID.Valid() && !cmd.OrgID.Valid() {
+		return errors.New("bucket requires an organization")
+	}
+	if !cmd.OrgID.Valid() && len(cmd.Measurements) == 0 && cmd.Where == "" && cmd.Field == "" {
+		return errors.New("organization, measurement, where or field option required")
+	}
+	if cmd.Where != "" {
+		node, err := predicate.Parse(cmd.Where)
+		if err != nil {
+			return fmt.Errorf("invalid where predicate: %v", err)
+		}
+		if cmd.where, err = predicate.New(node); err != nil {
+			return fmt.Errorf("invalid where predicate: %v", err)
+		}
+	}
+
+	if cmd.WALDir != "" {
+		if err := cmd.checkWAL(); err != nil {
+			return err
+		}
+	}
+
+	var files []string
+	err := tsmfile.Walk(cmd.DataDir, func(path string) error {
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	verb := "shifted"
+	if cmd.DryRun {
+		verb = "would shift"
+	}
+	var total Stats
+	for _, path := range files {
+		s, err := cmd.ShiftFile(path)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if s.Series == 0 {
+			fmt.Fprintf(cmd.Stdout, "%s: nothing to shift\n", path)
+			continue
+		}
+		fmt.Fprintf(cmd.Stdout, "%s: %s %d value(s) in %d block(s) of %d series\n", path, verb, s.Values, s.Blocks, s.Series)
+		total.Series += s.Series
+		total.Blocks += s.Blocks
+		total.Values += s.Values
+	}
+	fmt.Fprintf(cmd.Stdout, "%s %d value(s) in %d block(s) of %d TSM file(s) by %s\n", verb, total.Values, total.Blocks, len(files), cmd.Offset)
+	return nil
+}
+
+// ShiftFile shifts the timestamps of the selected series of the TSM file at
+// path, replacing it with a rewritten file.
+func (cmd *Command) ShiftFile(path string) (Stats, error) {
+	outputPath := tempPath(path)
+	stats, err := cmd.rewrite(path, outputPath)
+	if err != nil || cmd.DryRun || stats.Series == 0 {
+		return stats, err
+	}
+
+	if err := os.Rename(outputPath, path); err != nil {
+		return stats, err
+	}
+	if err := renameIfExists(tsm1.StatsFilename(outputPath), tsm1.StatsFilename(path)); err != nil {
+		return stats, err
+	}
+	// The tombstones were applied when rewriting the file.
+	return stats, tsm1.NewTombstoner(path, nil).Delete()
+}
+
+// rewrite copies the blocks of the TSM file at path to a new file at
+// outputPath, shifting those of the selected series. The new file is not
+// created if no series is selected, or in dry-run mode.
+func (cmd *Command) rewrite(path, outputPath string) (stats Stats, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return stats, err
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		f.Close()
+		return stats, fmt.Errorf("unable to read: %v", err)
+	}
+	defer r.Close()
+
+	// The file is only rewritten if it has a selected series.
+	var where influxdb.Predicate
+	if cmd.where != nil {
+		where = cmd.where.Clone()
+	}
+	prefix := tsmfile.Prefix(cmd.OrgID, cmd.BucketID)
+	var selected bool
+	iter := r.Iterator(prefix)
+	for iter.Next() {
+		key := iter.Key()
+		if !bytes.HasPrefix(key, prefix) {
+			break
+		}
+		if cmd.match(key, where) {
+			selected = true
+			break
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return stats, err
+	}
+	if !selected {
+		return stats, nil
+	}
+
+	var w tsm1.TSMWriter
+	if !cmd.DryRun {
+		if err := os.RemoveAll(outputPath); err != nil {
+			return stats, err
+		}
+		output, err := os.Create(outputPath)
+		if err != nil {
+			return stats, err
+		}
+		if w, err = tsm1.NewTSMWriter(output); err != nil {
+			output.Close()
+			return stats, err
+		}
+		defer func() {
+			if err != nil {
+				w.Remove()
+			}
+		}()
+	}
+
+	var (
+		lastKey   []byte
+		lastMatch bool
+		trbuf     []tsm1.TimeRange
+		values    []tsm1.Value
+		offset    = int64(cmd.Offset)
+	)
+	itr := r.BlockIterator()
+	for itr.Next() {
+		key, minTime, maxTime, _, _, block, err := itr.Read()
+		if err != nil {
+			return stats, err
+		}
+		if lastKey == nil || !bytes.Equal(key, lastKey) {
+			lastKey = append(lastKey[:0], key...)
+			lastMatch = bytes.HasPrefix(key, prefix) && cmd.match(key, where)
+			trbuf = r.TombstoneRange(key, trbuf[:0])
+			if lastMatch {
+				stats.Series++
+			}
+		}
+
+		if !lastMatch && !overlaps(trbuf, minTime, maxTime) {
+			if w != nil {
+				if err := w.WriteBlock(key, minTime, maxTime, block); err != nil {
+					return stats, err
+				}
+			}
+			continue
+		}
+
+		if values, err = tsm1.DecodeBlock(block, values[:0]); err != nil {
+			return stats, fmt.Errorf("unable to decode block of %q: %v", key, err)
+		}
+		vs := tsm1.Values(values)
+		for _, tr := range trbuf {
+			vs = vs.Exclude(tr.Min, tr.Max)
+		}
+		if len(vs) == 0 {
+			continue
+		}
+		if lastMatch {
+			if min, max := vs.MinTime(), vs.MaxTime(); offset > 0 && max > math.MaxInt64-offset || offset < 0 && min < math.MinInt64-offset {
+				return stats, fmt.Errorf("shifting the values of %q overflows their timestamps", key)
+			}
+			for i, v := range vs {
+				vs[i] = shift(v, offset)
+			}
+			stats.Blocks++
+			stats.Values += len(vs)
+		}
+		if w != nil {
+			if err := w.Write(key, vs); err != nil {
+				return stats, err
+			}
+		}
+	}
+	if err := itr.Err(); err != nil {
+		return stats, err
+	}
+
+	if w == nil {
+		return stats, nil
+	}
+	if err := w.WriteIndex(); err == tsm1.ErrNoValues {
+		// Every value was tombstoned, leave the original file untouched.
+		stats = Stats{}
+		return stats, w.Remove()
+	} else if err != nil {
+		return stats, err
+	}
+	return stats, w.Close()
+}
+
+// checkWAL returns an error if a segment of the WAL has values of the
+// selected series, which would be written back unshifted when the WAL is
+// replayed.
+func (cmd *Command) checkWAL() error {
+	paths, err := wal.SegmentFileNames(cmd.WALDir)
+	if err != nil {
+		return err
+	}
+
+	prefix := string(tsmfile.Prefix(cmd.OrgID, cmd.BucketID))
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		r := wal.NewWALSegmentReader(f)
+		for r.Next() {
+			entry, err := r.Read()
+			if err != nil {
+				r.Close()
+				return fmt.Errorf("%s: unable to read entry at offset %d: %v", path, r.Count(), err)
+			}
+			w, ok := entry.(*wal.WriteWALEntry)
+			if !ok {
+				continue
+			}
+			for key := range w.Values {
+				if strings.HasPrefix(key, prefix) && cmd.match([]byte(key), cmd.where) {
+					r.Close()
+					return fmt.Errorf("%s: the WAL has values of the selected series, which must be snapshotted to TSM files first", path)
+				}
+			}
+		}
+		r.Close()
+	}
+	return nil
+}
+
+// shift returns v with its timestamp shifted by offset.
+func shift(v tsm1.Value, offset int64) tsm1.Value {
+	return tsm1.NewValue(v.UnixNano()+offset, v.Value())
+}
+
+// match returns true if the values of the TSM key must be shifted.
+func (cmd *Command) match(key []byte, where influxdb.Predicate) bool {
+	seriesKey, field := tsm1.SeriesAndFieldFromCompositeKey(key)
+	if cmd.Field != "" && string(field) != cmd.Field {
+		return false
+	}
+	if where != nil && !where.Matches(key) {
+		return false
+	}
+	if len(cmd.Measurements) == 0 {
+		return true
+	}
+	_, tags := models.ParseKeyBytes(seriesKey)
+	name := tags.Get(models.MeasurementTagKeyBytes)
+	for _, m := range cmd.Measurements {
+		if string(name) == m {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps returns true if any of the time ranges overlaps [min, max].
+func overlaps(trs []tsm1.TimeRange, min, max int64) bool {
+	for _, tr := range trs {
+		if tr.Min <= max && tr.Max >= min {
+			return true
+		}
+	}
+	return false
+}
+
+// tempPath returns the path of the file that replaces the TSM file at path.
+// It keeps the TSM extension so that the writer stores its statistics in a
+// separate file from those of the original.
+func tempPath(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + ".shift" + ext + "." + tsm1.TmpTSMFileExtension
+}
+
+func renameIfExists(oldpath, newpath string) error {
+	if err := os.Rename(oldpath, newpath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package shifttimestamps_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile/tsmtest"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/shifttimestamps"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+const (
+	orgID    = 1
+	bucketID = 2
+)
+
+func TestCommand_Run(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shifttimestamps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cpuA, cpuB, mem := seriesKey("cpu", "a"), seriesKey("cpu", "b"), seriesKey("mem", "a")
+	path := filepath.Join(dir, "000000001-000000001.tsm")
+	tsmtest.WriteFile(t, path, map[string]tsm1.Values{
+		cpuA: tsmtest.Floats(10, 20, 30),
+		cpuB: tsmtest.Floats(10),
+		mem:  tsmtest.Floats(10, 20),
+	})
+	deleteRange(t, path, cpuA, 20, 20)
+	deleteRange(t, path, mem, 10, 10)
+
+	var stdout bytes.Buffer
+	cmd := &shifttimestamps.Command{
+		Stdout:       &stdout,
+		Stderr:       ioutil.Discard,
+		DataDir:      dir,
+		OrgID:        orgID,
+		BucketID:     bucketID,
+		Measurements: []string{"cpu"},
+		Where:        "host=a",
+		Offset:       time.Hour,
+	}
+
+	// Nothing is modified by a dry run.
+	cmd.DryRun = true
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "would shift 2 value(s) in 1 block(s) of 1 series") {
+		t.Fatalf("unexpected output: %s", stdout.String())
+	}
+	if got := tsmtest.ReadTimestamps(t, path, cpuA); !reflect.DeepEqual(got, []int64{10, 30}) {
+		t.Fatalf("unexpected timestamps after dry run: %v", got)
+	}
+
+	cmd.DryRun = false
+	stdout.Reset()
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "shifted 2 value(s) in 1 block(s) of 1 TSM file(s) by 1h0m0s") {
+		t.Fatalf("unexpected output: %s", stdout.String())
+	}
+
+	h := int64(time.Hour)
+	for key, want := range map[string][]int64{
+		cpuA: {10 + h, 30 + h},
+		cpuB: {10},
+		mem:  {20},
+	} {
+		if got := tsmtest.ReadTimestamps(t, path, key); !reflect.DeepEqual(got, want) {
+			t.Fatalf("unexpected timestamps of %q: got %v, want %v", key, got, want)
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.HasTombstones() {
+		t.Fatal("unexpected tombstones")
+	}
+}
+
+func TestCommand_Run_Overflow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shifttimestamps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key := seriesKey("cpu", "a")
+	path := filepath.Join(dir, "000000001-000000001.tsm")
+	tsmtest.WriteFile(t, path, map[string]tsm1.Values{key: tsmtest.Floats(math.MaxInt64 - 10)})
+
+	cmd := &shifttimestamps.Command{
+		Stdout:  ioutil.Discard,
+		Stderr:  ioutil.Discard,
+		DataDir: dir,
+		OrgID:   orgID,
+		Offset:  time.Second,
+	}
+	if err := cmd.Run(); err == nil || !strings.Contains(err.Error(), "overflows") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := tsmtest.ReadTimestamps(t, path, key); !reflect.DeepEqual(got, []int64{math.MaxInt64 - 10}) {
+		t.Fatalf("unexpected timestamps: %v", got)
+	}
+}
+
+func seriesKey(measurement, host string) string {
+	name := tsdb.EncodeName(orgID, bucketID)
+	tags := models.NewTags(map[string]string{
+		models.MeasurementTagKey: measurement,
+		models.FieldKeyTagKey:    "value",
+		"host":                   host,
+	})
+	return string(models.MakeKey(name[:], tags)) + "#!~#value"
+}
+
+// deleteRange records a tombstone for the values of key in [min, max].
+func deleteRange(t *testing.T, path, key string, min, max int64) {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := r.DeleteRange([][]byte{[]byte(key)}, min, max); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,287 @@
+// Package splittsm splits TSM files into the files of the values before and
+// after a point in time, without running the storage engine.
+package splittsm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// Command splits a set of TSM files at a point in time.
+//
+// Each TSM file is split into a file of the values before Time, written
+// under BeforeDir, and a file of the values at or after Time, written under
+// AfterDir, so that data can be moved to shards of another duration or
+// carved out for extraction. Blocks entirely on one side of Time are copied
+// verbatim; blocks straddling it are decoded and re-encoded as two blocks.
+// Tombstoned values are dropped from the new files. The input files are left
+// untouched.
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Paths lists the TSM files to split, and the directories, such as shard
+	// directories or a whole data directory, searched recursively for TSM
+	// files. The new files of a directory keep their path relative to it.
+	Paths []string
+
+	// Time is the boundary the files are split at.
+	Time time.Time
+
+	// BeforeDir and AfterDir are the directories the files of the values
+	// before and at or after Time are written to. A file is not written if
+	// it would be empty.
+	BeforeDir string
+	AfterDir  string
+}
+
+// NewCommand returns a new instance of Command writing to the standard output and error.
+func NewCommand() *Command {
+	return &Command{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Stats summarizes the split of a TSM file.
+type Stats struct {
+	BeforeBlocks int
+	AfterBlocks  int
+
+	// Split is the number of blocks straddling the boundary that were
+	// re-encoded.
+	Split int
+}
+
+// Run splits the TSM files of Paths.
+func (cmd *Command) Run() error {
+	if len(cmd.Paths) == 0 {
+		return errors.New("path required")
+	}
+	if cmd.Time.IsZero() {
+		return errors.New("time required")
+	}
+	if cmd.BeforeDir == "" || cmd.AfterDir == "" {
+		return errors.New("before and after directories required")
+	}
+	if filepath.Clean(cmd.BeforeDir) == filepath.Clean(cmd.AfterDir) {
+		return errors.New("before and after directories must differ")
+	}
+
+	files, err := cmd.findFiles()
+	if err != nil {
+		return err
+	}
+
+	var total Stats
+	for _, f := range files {
+		s, err := SplitFile(f.path, cmd.Time.UnixNano(),
+			filepath.Join(cmd.BeforeDir, f.rel), filepath.Join(cmd.AfterDir, f.rel))
+		if err != nil {
+			return fmt.Errorf("%s: %v", f.path, err)
+		}
+		fmt.Fprintf(cmd.Stdout, "%s: %d block(s) before, %d block(s) after, %d block(s) split\n",
+			f.path, s.BeforeBlocks, s.AfterBlocks, s.Split)
+		total.BeforeBlocks += s.BeforeBlocks
+		total.AfterBlocks += s.AfterBlocks
+		total.Split += s.Split
+	}
+
+	fmt.Fprintf(cmd.Stdout, "split %d TSM file(s) at %s: %d block(s) before, %d block(s) after, %d block(s) split\n",
+		len(files), cmd.Time.UTC().Format(time.RFC3339Nano), total.BeforeBlocks, total.AfterBlocks, total.Split)
+	return nil
+}
+
+// SplitFile writes the values of the TSM file at path before t to a TSM file
+// at beforePath, and those at or after t to a TSM file at afterPath. Either
+// file is not created if it would be empty, and existing files are not
+// overwritten.
+func SplitFile(path string, t int64, beforePath, afterPath string) (stats Stats, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return stats, err
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		f.Close()
+		return stats, fmt.Errorf("unable to read: %v", err)
+	}
+	defer r.Close()
+
+	before, err := newOutput(beforePath)
+	if err != nil {
+		return stats, err
+	}
+	defer before.close(&err)
+	after, err := newOutput(afterPath)
+	if err != nil {
+		return stats, err
+	}
+	defer after.close(&err)
+
+	var (
+		trbuf  []tsm1.TimeRange
+		values []tsm1.Value
+	)
+	iter := r.BlockIterator()
+	for iter.Next() {
+		key, minTime, maxTime, _, _, block, err := iter.Read()
+		if err != nil {
+			return stats, err
+		}
+
+		trbuf = r.TombstoneRange(key, trbuf[:0])
+		if !overlaps(trbuf, minTime, maxTime) {
+			if maxTime < t {
+				stats.BeforeBlocks++
+				if err := before.writeBlock(key, minTime, maxTime, block); err != nil {
+					return stats, err
+				}
+				continue
+			} else if minTime >= t {
+				stats.AfterBlocks++
+				if err := after.writeBlock(key, minTime, maxTime, block); err != nil {
+					return stats, err
+				}
+				continue
+			}
+		}
+
+		// Decode the block to split it and drop its tombstoned values.
+		if values, err = tsm1.DecodeBlock(block, values[:0]); err != nil {
+			return stats, fmt.Errorf("unable to decode block of key %q: %v", key, err)
+		}
+		vs := tsm1.Values(values)
+		for _, tr := range trbuf {
+			vs = vs.Exclude(tr.Min, tr.Max)
+		}
+		if minTime < t && maxTime >= t {
+			stats.Split++
+		}
+		i := 0
+		for i < len(vs) && vs[i].UnixNano() < t {
+			i++
+		}
+		if i > 0 {
+			stats.BeforeBlocks++
+			if err := before.write(key, vs[:i]); err != nil {
+				return stats, err
+			}
+		}
+		if i < len(vs) {
+			stats.AfterBlocks++
+			if err := after.write(key, vs[i:]); err != nil {
+				return stats, err
+			}
+		}
+	}
+	return stats, nil
+}
+
+// overlaps returns true if any of the time ranges overlaps [min, max].
+func overlaps(trs []tsm1.TimeRange, min, max int64) bool {
+	for _, tr := range trs {
+		if tr.Min <= max && tr.Max >= min {
+			return true
+		}
+	}
+	return false
+}
+
+// output is a TSM file created when its first block is written.
+type output struct {
+	path string
+	w    tsm1.TSMWriter
+}
+
+func newOutput(path string) (*output, error) {
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%s already exists", path)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return &output{path: path}, nil
+}
+
+func (o *output) open() error {
+	if o.w != nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(o.path), 0777); err != nil {
+		return err
+	}
+	f, err := os.Create(o.path)
+	if err != nil {
+		return err
+	}
+	if o.w, err = tsm1.NewTSMWriter(f); err != nil {
+		f.Close()
+		return err
+	}
+	return nil
+}
+
+func (o *output) writeBlock(key []byte, minTime, maxTime int64, block []byte) error {
+	if err := o.open(); err != nil {
+		return err
+	}
+	return o.w.WriteBlock(key, minTime, maxTime, block)
+}
+
+func (o *output) write(key []byte, values tsm1.Values) error {
+	if err := o.open(); err != nil {
+		return err
+	}
+	return o.w.Write(key, values)
+}
+
+// close completes the file, or removes it if *err is set.
+func (o *output) close(err *error) {
+	if o.w == nil {
+		return
+	}
+	if *err != nil {
+		o.w.Remove()
+		return
+	}
+	if *err = o.w.WriteIndex(); *err != nil {
+		o.w.Remove()
+		return
+	}
+	*err = o.w.Close()
+}
+
+type tsmFile struct {
+	path string
+	rel  string // path of the new files, relative to the output directories
+}
+
+// findFiles returns the TSM files of Paths, searching directories
+// recursively.
+func (cmd *Command) findFiles() ([]tsmFile, error) {
+	var files []tsmFile
+	for _, root := range cmd.Paths {
+		err := tsmfile.Walk(root, func(path string) error {
+			rel := filepath.Base(path)
+			if path != root {
+				var err error
+				if rel, err = filepath.Rel(root, path); err != nil {
+					return err
+				}
+			}
+			files = append(files, tsmFile{path: path, rel: rel})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
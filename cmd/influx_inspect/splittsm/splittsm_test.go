@@ -0,0 +1,125 @@
+package splittsm_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile/tsmtest"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/splittsm"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+func TestCommand_Run(t *testing.T) {
+	dir, err := ioutil.TempDir("", "splittsm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	shard := filepath.Join(dir, "data", "1")
+	if err := os.MkdirAll(shard, 0777); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(shard, "000000001-000000001.tsm")
+	tsmtest.WriteFile(t, path, map[string]tsm1.Values{
+		"cpu#!~#usage": {tsm1.NewValue(10, 1.0), tsm1.NewValue(20, 2.0), tsm1.NewValue(30, 3.0), tsm1.NewValue(40, 4.0)},
+		"disk#!~#free": {tsm1.NewValue(50, int64(5))},
+		"mem#!~#used":  {tsm1.NewValue(10, int64(1)), tsm1.NewValue(20, int64(2))},
+	})
+	deleteRange(t, path, "cpu#!~#usage", 10, 10)
+
+	var stdout bytes.Buffer
+	cmd := &splittsm.Command{
+		Stdout:    &stdout,
+		Stderr:    ioutil.Discard,
+		Paths:     []string{filepath.Join(dir, "data")},
+		Time:      time.Unix(0, 25),
+		BeforeDir: filepath.Join(dir, "before"),
+		AfterDir:  filepath.Join(dir, "after"),
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "split 1 TSM file(s) at 1970-01-01T00:00:00.000000025Z: 2 block(s) before, 2 block(s) after, 1 block(s) split") {
+		t.Fatalf("unexpected output: %s", stdout.String())
+	}
+
+	got := tsmtest.ReadValues(t, filepath.Join(dir, "before", "1", "000000001-000000001.tsm"))
+	want := map[string][]interface{}{
+		"cpu#!~#usage": {2.0},
+		"mem#!~#used":  {int64(1), int64(2)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected values before: got %v, want %v", got, want)
+	}
+
+	got = tsmtest.ReadValues(t, filepath.Join(dir, "after", "1", "000000001-000000001.tsm"))
+	want = map[string][]interface{}{
+		"cpu#!~#usage": {3.0, 4.0},
+		"disk#!~#free": {int64(5)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected values after: got %v, want %v", got, want)
+	}
+
+	// The input is left untouched, and existing files are not overwritten.
+	if got := tsmtest.ReadValues(t, path); len(got["cpu#!~#usage"]) != 3 {
+		t.Fatalf("unexpected values of the input: %v", got)
+	}
+	if err := cmd.Run(); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCommand_Run_Empty(t *testing.T) {
+	dir, err := ioutil.TempDir("", "splittsm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "000000001-000000001.tsm")
+	tsmtest.WriteFile(t, path, map[string]tsm1.Values{"cpu#!~#usage": {tsm1.NewValue(10, 1.0)}})
+
+	cmd := &splittsm.Command{
+		Stdout:    ioutil.Discard,
+		Stderr:    ioutil.Discard,
+		Paths:     []string{path},
+		Time:      time.Unix(0, 100),
+		BeforeDir: filepath.Join(dir, "before"),
+		AfterDir:  filepath.Join(dir, "after"),
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "before", "000000001-000000001.tsm")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "after")); !os.IsNotExist(err) {
+		t.Fatalf("unexpected file after time: %v", err)
+	}
+}
+
+// deleteRange records a tombstone for the values of key in [min, max].
+func deleteRange(t *testing.T, path, key string, min, max int64) {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := r.DeleteRange([][]byte{[]byte(key)}, min, max); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,315 @@
+// Package tombstones lists and edits the entries of the tombstone files of TSM
+// files, which record the deletions not yet applied by a compaction.
+//
+// The storage engine must not be running when the tombstones are edited.
+package tombstones
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/storage/reads"
+	"github.com/influxdata/influxdb/storage/reads/datatypes"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// extension is the extension of tombstone files.
+const extension = ".tombstone"
+
+// Command lists the entries of tombstone files in the order they were
+// created, numbered from 0, and optionally removes some of them.
+//
+// Removing an entry undoes its deletion, as long as the TSM file has not been
+// compacted since: the values it deleted are still in the file.
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Paths lists the tombstone files, the TSM files whose tombstone files
+	// to read, and the directories searched recursively for tombstone files.
+	Paths []string
+
+	// Delete lists the numbers of the entries to remove. It requires Paths
+	// to designate a single tombstone file.
+	Delete []int
+
+	// Compact removes the entries whose deletion is covered by another entry,
+	// which deletes a superset of their keys over a wider time range.
+	Compact bool
+
+	// DryRun reports the entries to remove without rewriting the files.
+	DryRun bool
+}
+
+// NewCommand returns a new instance of Command writing to the standard output and error.
+func NewCommand() *Command {
+	return &Command{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Run lists or edits the tombstone files of Paths.
+func (cmd *Command) Run() error {
+	if len(cmd.Paths) == 0 {
+		return errors.New("path required")
+	}
+
+	files, err := cmd.findFiles()
+	if err != nil {
+		return err
+	}
+	if len(cmd.Delete) > 0 && len(files) != 1 {
+		return fmt.Errorf("deleting entries requires a single tombstone file, found %d", len(files))
+	}
+
+	for _, path := range files {
+		if err := cmd.process(path); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// process lists or edits the tombstone file at path.
+func (cmd *Command) process(path string) error {
+	ts := tsm1.NewTombstoner(path, nil)
+	entries, err := ReadAll(ts)
+	if err != nil {
+		return err
+	}
+
+	if len(cmd.Delete) == 0 && !cmd.Compact {
+		fmt.Fprintf(cmd.Stdout, "%s: %d entries\n", path, len(entries))
+		return cmd.print(entries, nil)
+	}
+
+	remove := make(map[int]bool)
+	for _, i := range cmd.Delete {
+		if i < 0 || i >= len(entries) {
+			return fmt.Errorf("entry %d out of range, the file has %d entries", i, len(entries))
+		}
+		remove[i] = true
+	}
+	if cmd.Compact {
+		for _, i := range Redundant(entries) {
+			remove[i] = true
+		}
+	}
+	if len(remove) == 0 {
+		fmt.Fprintf(cmd.Stdout, "%s: no entries to remove\n", path)
+		return nil
+	}
+
+	keep := make([]tsm1.Tombstone, 0, len(entries)-len(remove))
+	for i, e := range entries {
+		if !remove[i] {
+			keep = append(keep, e)
+		}
+	}
+
+	verb := "removed"
+	if cmd.DryRun {
+		verb = "would remove"
+	}
+	fmt.Fprintf(cmd.Stdout, "%s: %s %d of %d entries\n", path, verb, len(remove), len(entries))
+	if err := cmd.print(entries, remove); err != nil {
+		return err
+	}
+	if cmd.DryRun {
+		return nil
+	}
+	return ts.Rewrite(keep)
+}
+
+// print writes a table of the entries, restricted to the selected entries if
+// selected is not nil.
+func (cmd *Command) print(entries []tsm1.Tombstone, selected map[int]bool) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(cmd.Stdout, 8, 2, 1, ' ', 0)
+	fmt.Fprintln(tw, "  #\tType\tOrganization\tBucket\tSeries\tMeasurement\tMin\tMax\tPredicate")
+	for i, e := range entries {
+		if selected != nil && !selected[i] {
+			continue
+		}
+		typ := "key"
+		if e.Prefix {
+			typ = "prefix"
+		}
+		org, bucket, series := describeKey(e)
+		fmt.Fprintf(tw, "  %d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", i, typ, org, bucket, series,
+			orNone(string(e.Measurement)), formatTime(e.Min), formatTime(e.Max), describePredicate(e.Predicate))
+	}
+	return tw.Flush()
+}
+
+// ReadAll returns the entries of the tombstone file of ts in the order they
+// were created.
+func ReadAll(ts *tsm1.Tombstoner) ([]tsm1.Tombstone, error) {
+	var entries []tsm1.Tombstone
+	err := ts.Walk(func(t tsm1.Tombstone) error {
+		// The buffers of the entry are reused by the walk.
+		entries = append(entries, tsm1.Tombstone{
+			Key:         append([]byte(nil), t.Key...),
+			Prefix:      t.Prefix,
+			Min:         t.Min,
+			Max:         t.Max,
+			Predicate:   append([]byte(nil), t.Predicate...),
+			Measurement: append([]byte(nil), t.Measurement...),
+		})
+		return nil
+	})
+	return entries, err
+}
+
+// Redundant returns the numbers of the entries whose deletion is covered by
+// another entry. Of identical entries, the first one is kept.
+func Redundant(entries []tsm1.Tombstone) []int {
+	var redundant []int
+	for i, e := range entries {
+		for j, other := range entries {
+			if i == j || !covers(other, e) {
+				continue
+			}
+			// Identical entries cover each other: keep the first one.
+			if covers(e, other) && i < j {
+				continue
+			}
+			redundant = append(redundant, i)
+			break
+		}
+	}
+	return redundant
+}
+
+// covers returns true if a deletes all the values deleted by b.
+func covers(a, b tsm1.Tombstone) bool {
+	if a.Min > b.Min || a.Max < b.Max {
+		return false
+	}
+	if len(a.Predicate) > 0 && !bytes.Equal(a.Predicate, b.Predicate) {
+		return false
+	}
+	if !a.Prefix {
+		return !b.Prefix && bytes.Equal(a.Key, b.Key)
+	}
+	if !b.Prefix {
+		return bytes.HasPrefix(b.Key, a.KeyPrefix())
+	}
+	return bytes.HasPrefix(b.KeyPrefix(), a.KeyPrefix())
+}
+
+// describeKey returns the organization, bucket and series of the key of the
+// entry, or the escaped key as the series if it can not be decoded.
+func describeKey(t tsm1.Tombstone) (org, bucket, series string) {
+	if t.Prefix {
+		name := models.UnescapeMeasurement(t.Key)
+		switch len(name) {
+		case 8:
+			var encoded [16]byte
+			copy(encoded[:], name)
+			orgID, _ := tsdb.DecodeName(encoded)
+			return orgID.String(), "*", "*"
+		case 16:
+			orgID, bucketID := tsdb.DecodeNameSlice(name)
+			return orgID.String(), bucketID.String(), "*"
+		}
+		return "-", "-", fmt.Sprintf("%q", t.Key)
+	}
+
+	name, tags := models.ParseKeyBytes(t.Key)
+	if len(name) != 16 {
+		return "-", "-", fmt.Sprintf("%q", t.Key)
+	}
+	orgID, bucketID := tsdb.DecodeNameSlice(name)
+	pairs := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		key := string(tag.Key)
+		switch {
+		case bytes.Equal(tag.Key, models.MeasurementTagKeyBytes):
+			key = "_measurement"
+		case bytes.Equal(tag.Key, models.FieldKeyTagKeyBytes):
+			key = "_field"
+		}
+		pairs = append(pairs, key+"="+string(tag.Value))
+	}
+	return orgID.String(), bucketID.String(), strings.Join(pairs, ",")
+}
+
+// describePredicate returns the expression of a marshaled predicate.
+func describePredicate(data []byte) string {
+	if len(data) == 0 {
+		return "-"
+	}
+	// The first byte is the version of the predicate.
+	var pred datatypes.Predicate
+	if data[0] != 0 || pred.Unmarshal(data[1:]) != nil {
+		return fmt.Sprintf("<%d bytes>", len(data))
+	}
+	return reads.PredicateToExprString(&pred)
+}
+
+func formatTime(ts int64) string {
+	switch ts {
+	case math.MinInt64:
+		return "-inf"
+	case math.MaxInt64:
+		return "+inf"
+	}
+	return time.Unix(0, ts).UTC().Format(time.RFC3339Nano)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// findFiles returns the tombstone files of Paths, searching directories
+// recursively.
+func (cmd *Command) findFiles() ([]string, error) {
+	var files []string
+	for _, path := range cmd.Paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			if filepath.Ext(path) != extension {
+				path = strings.TrimSuffix(path, filepath.Ext(path)) + extension
+			}
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.Walk(path, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !fi.IsDir() && filepath.Ext(path) == extension {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error processing path %q: %v", path, err)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
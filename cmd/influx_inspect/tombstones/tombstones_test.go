@@ -0,0 +1,170 @@
+package tombstones_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/tombstones"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+func TestCommand_List(t *testing.T) {
+	dir := mustTempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "000000000000001-000000001.tsm")
+	writeTombstones(t, path)
+
+	var buf bytes.Buffer
+	cmd := tombstones.NewCommand()
+	cmd.Stdout = &buf
+	cmd.Paths = []string{dir}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if got, exp := len(lines), 5; got != exp {
+		t.Fatalf("got %d lines, expected %d:\n%s", got, exp, buf.String())
+	}
+	for i, exp := range [][]string{
+		{"0", "prefix", "0000000000000001", "0000000000000002", "*", "-", "-inf", "+inf", "-"},
+		{"1", "prefix", "0000000000000001", "0000000000000002", "*", "cpu", "1970-01-01T00:00:00.00000001Z", "1970-01-01T00:00:00.00000002Z", "-"},
+		{"2", "key", "0000000000000001", "0000000000000002", "_measurement=cpu,host=a,_field=v", "-", "1970-01-01T00:00:00.00000001Z", "1970-01-01T00:00:00.00000002Z", "-"},
+	} {
+		if got := strings.Fields(lines[i+2]); !reflect.DeepEqual(got, exp) {
+			t.Fatalf("unexpected entry %d: got %q, expected %q", i, got, exp)
+		}
+	}
+}
+
+func TestCommand_Delete(t *testing.T) {
+	dir := mustTempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "000000000000001-000000001.tsm")
+	writeTombstones(t, path)
+
+	cmd := tombstones.NewCommand()
+	cmd.Stdout = ioutil.Discard
+	cmd.Paths = []string{path}
+	cmd.Delete = []int{0, 2}
+	cmd.DryRun = true
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTombstones(t, path); len(got) != 3 {
+		t.Fatalf("got %d entries after a dry run, expected 3", len(got))
+	}
+
+	cmd.DryRun = false
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	got := readTombstones(t, path)
+	if len(got) != 1 || string(got[0].Measurement) != "cpu" {
+		t.Fatalf("unexpected entries %v", got)
+	}
+
+	cmd.Delete = []int{1}
+	if err := cmd.Run(); err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Fatalf("expected an out of range error, got %v", err)
+	}
+}
+
+func TestCommand_Compact(t *testing.T) {
+	dir := mustTempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "000000000000001-000000001.tsm")
+	writeTombstones(t, path)
+
+	// The bucket-wide entry covers the other entries.
+	cmd := tombstones.NewCommand()
+	cmd.Stdout = ioutil.Discard
+	cmd.Paths = []string{path}
+	cmd.Compact = true
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	got := readTombstones(t, path)
+	if len(got) != 1 || got[0].Min != math.MinInt64 || len(got[0].Measurement) != 0 {
+		t.Fatalf("unexpected entries %v", got)
+	}
+}
+
+func TestRedundant(t *testing.T) {
+	key := []byte("cpu,host=a")
+	entries := []tsm1.Tombstone{
+		{Key: key, Min: 10, Max: 20},
+		{Key: key, Min: 0, Max: 30},
+		{Key: key, Min: 0, Max: 30},
+		{Key: []byte("cpu,"), Prefix: true, Min: 0, Max: 15},
+		{Key: []byte("cpu,"), Prefix: true, Min: 0, Max: 15, Predicate: []byte("p")},
+		{Key: []byte("mem,host=a"), Min: 0, Max: 15},
+	}
+	if got, exp := tombstones.Redundant(entries), []int{0, 2, 4}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("got redundant entries %v, expected %v", got, exp)
+	}
+}
+
+// writeTombstones writes a bucket-wide, a measurement and a series entry to
+// the tombstone file of the TSM file at path.
+func writeTombstones(t *testing.T, path string) {
+	t.Helper()
+
+	org, bucket := influxdb.ID(1), influxdb.ID(2)
+	encoded := tsdb.EncodeName(org, bucket)
+	name := models.EscapeMeasurement(encoded[:])
+	key := models.MakeKey(name, models.NewTags(map[string]string{
+		models.MeasurementTagKey: "cpu",
+		"host":                   "a",
+		models.FieldKeyTagKey:    "v",
+	}))
+
+	// Only the tombstone file is read.
+	if err := ioutil.WriteFile(path, nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := tsm1.NewTombstoner(path, nil)
+	if err := ts.AddPrefixRange(name, math.MinInt64, math.MaxInt64, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.AddMeasurementRange(name, []byte("cpu"), 10, 20, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.AddRange([][]byte{key}, 10, 20); err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTombstones(t *testing.T, path string) []tsm1.Tombstone {
+	t.Helper()
+	entries, err := tombstones.ReadAll(tsm1.NewTombstoner(path, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func mustTempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "tombstones")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
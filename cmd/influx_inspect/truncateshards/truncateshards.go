@@ -0,0 +1,149 @@
+// Package truncateshards deletes every value after, or before, a point in
+// time from the TSM files and WAL segments of an engine, such as to recover
+// from a backfill that wrote values in the future.
+package truncateshards
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/deletetsm"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile"
+	"github.com/influxdata/influxdb/storage/wal"
+)
+
+// Command truncates the TSM files of a data directory and the segments of a
+// WAL directory at a point in time.
+//
+// Every value strictly after Time, or strictly before it with Before, is
+// deleted. Blocks straddling Time are decoded, trimmed and re-encoded, the
+// other blocks are copied verbatim or dropped, and files left without any
+// block are removed. The storage engine must not be running.
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// DataDir is the directory searched recursively for TSM files.
+	DataDir string
+
+	// WALDir is the optional directory of the WAL segments to truncate, so
+	// that the deleted values are not written back when the WAL is replayed.
+	WALDir string
+
+	// OrgID and BucketID optionally restrict the truncation to series of an
+	// organization, or of a bucket within that organization.
+	OrgID    influxdb.ID
+	BucketID influxdb.ID
+
+	// Time is the point in time the files are truncated at.
+	Time time.Time
+
+	// Before deletes the values before Time instead of those after it.
+	Before bool
+
+	// DryRun reports what would be deleted without modifying any file.
+	DryRun bool
+
+	// Concurrency is the number of TSM files rewritten concurrently.
+	Concurrency int
+}
+
+// NewCommand returns a new instance of Command writing to the standard output and error.
+func NewCommand() *Command {
+	return &Command{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Run truncates the TSM files of DataDir and the segments of WALDir.
+func (cmd *Command) Run() error {
+	if cmd.DataDir == "" && cmd.WALDir == "" {
+		return errors.New("data or WAL directory required")
+	}
+	if cmd.Time.IsZero() {
+		return errors.New("time required")
+	}
+	if cmd.BucketID.Valid() && !cmd.OrgID.Valid() {
+		return errors.New("bucket requires an organization")
+	}
+
+	// Every series is selected, so that all the values within the time range
+	// are deleted.
+	d := &deletetsm.Deleter{
+		OrgID:       cmd.OrgID,
+		BucketID:    cmd.BucketID,
+		All:         true,
+		DryRun:      cmd.DryRun,
+		Concurrency: cmd.Concurrency,
+	}
+	if cmd.Before {
+		d.End = cmd.Time.Add(-time.Nanosecond)
+	} else {
+		d.Start = cmd.Time.Add(time.Nanosecond)
+	}
+
+	verb := "deleted"
+	if cmd.DryRun {
+		verb = "would delete"
+	}
+
+	var files []string
+	if cmd.DataDir != "" {
+		err := tsmfile.Walk(cmd.DataDir, func(path string) error {
+			files = append(files, path)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	var total deletetsm.Stats
+	d.OnFile = func(path string, s deletetsm.Stats, _ time.Duration) error {
+		if s.Empty() {
+			fmt.Fprintf(cmd.Stdout, "%s: nothing to truncate\n", path)
+			return nil
+		}
+		fmt.Fprintf(cmd.Stdout, "%s: %s %d block(s) and trimmed %d block(s) of %d series, %d bytes\n",
+			path, verb, s.Blocks, s.Trimmed, s.Series, s.Bytes)
+		total.Blocks += s.Blocks
+		total.Trimmed += s.Trimmed
+		total.Bytes += s.Bytes
+		return nil
+	}
+	if err := d.DeleteFiles(files); err != nil {
+		return err
+	}
+
+	var values int
+	if cmd.WALDir != "" {
+		segments, err := wal.SegmentFileNames(cmd.WALDir)
+		if err != nil {
+			return err
+		}
+		for _, path := range segments {
+			s, err := d.DeleteSegment(path)
+			if err != nil {
+				return fmt.Errorf("%s: %v", path, err)
+			}
+			if s.Empty() {
+				fmt.Fprintf(cmd.Stdout, "%s: nothing to truncate\n", path)
+				continue
+			}
+			fmt.Fprintf(cmd.Stdout, "%s: %s %d value(s) of %d series\n", path, verb, s.Values, s.Series)
+			values += s.Values
+		}
+	}
+
+	side := "after"
+	if cmd.Before {
+		side = "before"
+	}
+	fmt.Fprintf(cmd.Stdout, "truncated %d TSM file(s) %s %s: %s %d block(s), trimmed %d block(s), %d bytes, and %d WAL value(s)\n",
+		len(files), side, cmd.Time.UTC().Format(time.RFC3339Nano), verb, total.Blocks, total.Trimmed, total.Bytes, values)
+	return nil
+}
@@ -0,0 +1,126 @@
+package truncateshards_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile/tsmtest"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/truncateshards"
+	"github.com/influxdata/influxdb/storage/wal"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+	"github.com/influxdata/influxdb/tsdb/value"
+)
+
+func TestCommand_Run(t *testing.T) {
+	dir, err := ioutil.TempDir("", "truncateshards")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dataDir, walDir := filepath.Join(dir, "data"), filepath.Join(dir, "wal")
+	if err := os.MkdirAll(dataDir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	tsmtest.WriteFile(t, filepath.Join(dataDir, "000000001-000000001.tsm"), map[string]tsm1.Values{
+		"cpu#!~#usage": {tsm1.NewValue(10, 1.0), tsm1.NewValue(20, 2.0), tsm1.NewValue(30, 3.0)},
+		"mem#!~#used":  {tsm1.NewValue(10, int64(1))},
+	})
+	tsmtest.WriteFile(t, filepath.Join(dataDir, "000000002-000000001.tsm"), map[string]tsm1.Values{
+		"cpu#!~#usage": {tsm1.NewValue(40, 4.0)},
+	})
+
+	w := wal.NewWAL(walDir)
+	if err := w.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.WriteMulti(context.Background(), map[string][]value.Value{
+		"cpu#!~#usage": {value.NewFloatValue(10, 1.0), value.NewFloatValue(50, 5.0)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout bytes.Buffer
+	cmd := &truncateshards.Command{
+		Stdout:  &stdout,
+		Stderr:  ioutil.Discard,
+		DataDir: dataDir,
+		WALDir:  walDir,
+		Time:    time.Unix(0, 20),
+	}
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "truncated 2 TSM file(s) after 1970-01-01T00:00:00.00000002Z: deleted 1 block(s), trimmed 1 block(s)") ||
+		!strings.Contains(stdout.String(), "and 1 WAL value(s)") {
+		t.Fatalf("unexpected output: %s", stdout.String())
+	}
+
+	got := tsmtest.ReadValues(t, filepath.Join(dataDir, "000000001-000000001.tsm"))
+	want := map[string][]interface{}{
+		"cpu#!~#usage": {1.0, 2.0},
+		"mem#!~#used":  {int64(1)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected values: got %v, want %v", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "000000002-000000001.tsm")); !os.IsNotExist(err) {
+		t.Fatalf("file left without blocks not removed: %v", err)
+	}
+	if got, want := readWAL(t, walDir), []int64{10}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected WAL timestamps: got %v, want %v", got, want)
+	}
+
+	// Values before the time are deleted on demand.
+	cmd.Time, cmd.Before, cmd.WALDir = time.Unix(0, 15), true, ""
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	got = tsmtest.ReadValues(t, filepath.Join(dataDir, "000000001-000000001.tsm"))
+	if want := map[string][]interface{}{"cpu#!~#usage": {2.0}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected values: got %v, want %v", got, want)
+	}
+}
+
+// readWAL returns the timestamps of the values written to the segments of dir.
+func readWAL(t *testing.T, dir string) []int64 {
+	t.Helper()
+
+	paths, err := wal.SegmentFileNames(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var timestamps []int64
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := wal.NewWALSegmentReader(f)
+		for r.Next() {
+			entry, err := r.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w, ok := entry.(*wal.WriteWALEntry); ok {
+				for _, vs := range w.Values {
+					for _, v := range vs {
+						timestamps = append(timestamps, v.UnixNano())
+					}
+				}
+			}
+		}
+		r.Close()
+	}
+	return timestamps
+}
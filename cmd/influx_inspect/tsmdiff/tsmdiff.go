@@ -0,0 +1,311 @@
+// Package tsmdiff reports the differences between two TSM files, or between
+// the TSM files of two directories.
+package tsmdiff
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// ErrDifferent is returned by Run when the two sides differ.
+var ErrDifferent = errors.New("TSM data differs")
+
+// Command compares two sets of TSM files.
+//
+// Each side is a TSM file or a directory, such as a shard directory or a
+// whole data directory, searched recursively for TSM files. The blocks of
+// all the files of a side are compared by key, so that the data of a shard
+// can be compared with the data of its restored or rewritten copy even if
+// the blocks are spread across files differently.
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// PathA and PathB are the files or directories compared.
+	PathA string
+	PathB string
+
+	// Verbose reports every differing block rather than only the keys whose
+	// blocks differ.
+	Verbose bool
+}
+
+// NewCommand returns a new instance of Command writing to the standard output and error.
+func NewCommand() *Command {
+	return &Command{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Block describes a block of a key.
+type Block struct {
+	MinTime  int64
+	MaxTime  int64
+	Checksum uint32
+}
+
+// KeyDiff is the difference between the blocks of a key present on both
+// sides.
+type KeyDiff struct {
+	Key []byte
+
+	// OnlyA and OnlyB are the blocks of the key found on a single side.
+	OnlyA []Block
+	OnlyB []Block
+
+	// PointsA and PointsB are the number of points of the key on each side,
+	// tombstoned points excluded.
+	PointsA int
+	PointsB int
+}
+
+// Result is the difference between two sets of TSM files.
+type Result struct {
+	FilesA int
+	FilesB int
+
+	// OnlyA and OnlyB are the keys found on a single side.
+	OnlyA [][]byte
+	OnlyB [][]byte
+
+	// Keys are the keys found on both sides whose blocks differ.
+	Keys []KeyDiff
+
+	// PointsA and PointsB are the total number of points of each side,
+	// tombstoned points excluded.
+	PointsA int
+	PointsB int
+}
+
+// Equal returns true if both sides hold the same blocks.
+func (r *Result) Equal() bool {
+	return len(r.OnlyA) == 0 && len(r.OnlyB) == 0 && len(r.Keys) == 0
+}
+
+// Run compares the TSM files of PathA and PathB, and returns ErrDifferent
+// if they differ.
+func (cmd *Command) Run() error {
+	if cmd.PathA == "" || cmd.PathB == "" {
+		return errors.New("two paths required")
+	}
+
+	r, err := Diff(cmd.PathA, cmd.PathB)
+	if err != nil {
+		return err
+	}
+
+	for _, k := range r.OnlyA {
+		fmt.Fprintf(cmd.Stdout, "only in %s: %q\n", cmd.PathA, k)
+	}
+	for _, k := range r.OnlyB {
+		fmt.Fprintf(cmd.Stdout, "only in %s: %q\n", cmd.PathB, k)
+	}
+	for _, k := range r.Keys {
+		fmt.Fprintf(cmd.Stdout, "blocks differ: %q: %d block(s) only in %s, %d block(s) only in %s, %d point(s) delta\n",
+			k.Key, len(k.OnlyA), cmd.PathA, len(k.OnlyB), cmd.PathB, k.PointsB-k.PointsA)
+		if cmd.Verbose {
+			for _, b := range k.OnlyA {
+				fmt.Fprintf(cmd.Stdout, "  - %s\n", formatBlock(b))
+			}
+			for _, b := range k.OnlyB {
+				fmt.Fprintf(cmd.Stdout, "  + %s\n", formatBlock(b))
+			}
+		}
+	}
+
+	fmt.Fprintf(cmd.Stdout, "compared %d TSM file(s) with %d TSM file(s): %d key(s) only in %s, %d key(s) only in %s, %d key(s) with differing blocks, %d point(s) delta (%d to %d)\n",
+		r.FilesA, r.FilesB, len(r.OnlyA), cmd.PathA, len(r.OnlyB), cmd.PathB, len(r.Keys),
+		r.PointsB-r.PointsA, r.PointsA, r.PointsB)
+
+	if !r.Equal() {
+		return ErrDifferent
+	}
+	return nil
+}
+
+func formatBlock(b Block) string {
+	return fmt.Sprintf("[%s, %s] checksum %08x",
+		time.Unix(0, b.MinTime).UTC().Format(time.RFC3339Nano),
+		time.Unix(0, b.MaxTime).UTC().Format(time.RFC3339Nano),
+		b.Checksum)
+}
+
+// Diff compares the TSM files of the paths a and b, each being a TSM file
+// or a directory searched recursively for TSM files.
+func Diff(a, b string) (*Result, error) {
+	sa, err := load(a)
+	if err != nil {
+		return nil, err
+	}
+	sb, err := load(b)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Result{
+		FilesA:  sa.files,
+		FilesB:  sb.files,
+		PointsA: sa.points,
+		PointsB: sb.points,
+	}
+	for key, ka := range sa.keys {
+		kb, ok := sb.keys[key]
+		if !ok {
+			r.OnlyA = append(r.OnlyA, []byte(key))
+			continue
+		}
+		onlyA, onlyB := diffBlocks(ka.blocks, kb.blocks)
+		if len(onlyA) > 0 || len(onlyB) > 0 || ka.points != kb.points {
+			r.Keys = append(r.Keys, KeyDiff{
+				Key:     []byte(key),
+				OnlyA:   onlyA,
+				OnlyB:   onlyB,
+				PointsA: ka.points,
+				PointsB: kb.points,
+			})
+		}
+	}
+	for key := range sb.keys {
+		if _, ok := sa.keys[key]; !ok {
+			r.OnlyB = append(r.OnlyB, []byte(key))
+		}
+	}
+
+	sortKeys(r.OnlyA)
+	sortKeys(r.OnlyB)
+	sort.Slice(r.Keys, func(i, j int) bool { return string(r.Keys[i].Key) < string(r.Keys[j].Key) })
+	return r, nil
+}
+
+// diffBlocks returns the blocks of the sorted lists a and b missing from the
+// other list.
+func diffBlocks(a, b []Block) (onlyA, onlyB []Block) {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			i++
+			j++
+		case lessBlock(a[i], b[j]):
+			onlyA = append(onlyA, a[i])
+			i++
+		default:
+			onlyB = append(onlyB, b[j])
+			j++
+		}
+	}
+	onlyA = append(onlyA, a[i:]...)
+	onlyB = append(onlyB, b[j:]...)
+	return onlyA, onlyB
+}
+
+func lessBlock(a, b Block) bool {
+	if a.MinTime != b.MinTime {
+		return a.MinTime < b.MinTime
+	}
+	if a.MaxTime != b.MaxTime {
+		return a.MaxTime < b.MaxTime
+	}
+	return a.Checksum < b.Checksum
+}
+
+func sortKeys(keys [][]byte) {
+	sort.Slice(keys, func(i, j int) bool { return string(keys[i]) < string(keys[j]) })
+}
+
+// side holds the blocks of the TSM files of a path.
+type side struct {
+	files  int
+	points int
+	keys   map[string]*keyBlocks
+}
+
+type keyBlocks struct {
+	blocks []Block
+	points int
+}
+
+func load(root string) (*side, error) {
+	s := &side{keys: make(map[string]*keyBlocks)}
+	err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || filepath.Ext(path) != "."+tsm1.TSMFileExtension {
+			return nil
+		}
+		if err := s.loadFile(path); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		s.files++
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error processing path %q: %v", root, err)
+	}
+	for _, kb := range s.keys {
+		sort.Slice(kb.blocks, func(i, j int) bool { return lessBlock(kb.blocks[i], kb.blocks[j]) })
+	}
+	return s, nil
+}
+
+func (s *side) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("unable to read: %v", err)
+	}
+	defer r.Close()
+
+	var (
+		trbuf  []tsm1.TimeRange
+		values []tsm1.Value
+	)
+	iter := r.BlockIterator()
+	for iter.Next() {
+		key, minTime, maxTime, _, checksum, block, err := iter.Read()
+		if err != nil {
+			return err
+		}
+
+		kb := s.keys[string(key)]
+		if kb == nil {
+			kb = &keyBlocks{}
+			s.keys[string(key)] = kb
+		}
+		kb.blocks = append(kb.blocks, Block{MinTime: minTime, MaxTime: maxTime, Checksum: checksum})
+
+		// Count the points of the block, decoding it only if some of them
+		// are tombstoned.
+		n := tsm1.BlockCount(block)
+		trbuf = r.TombstoneRange(key, trbuf[:0])
+		for _, tr := range trbuf {
+			if tr.Min <= maxTime && tr.Max >= minTime {
+				if values, err = tsm1.DecodeBlock(block, values[:0]); err != nil {
+					return fmt.Errorf("unable to decode block of key %q: %v", key, err)
+				}
+				vs := tsm1.Values(values)
+				for _, tr := range trbuf {
+					vs = vs.Exclude(tr.Min, tr.Max)
+				}
+				n = len(vs)
+				break
+			}
+		}
+		kb.points += n
+		s.points += n
+	}
+	return nil
+}
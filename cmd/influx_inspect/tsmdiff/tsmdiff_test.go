@@ -0,0 +1,95 @@
+package tsmdiff_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile/tsmtest"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/tsmdiff"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+func TestDiff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsmdiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := filepath.Join(dir, "a", "000000001-000000001.tsm")
+	tsmtest.WriteFile(t, a, map[string]tsm1.Values{
+		"cpu#!~#usage": {tsm1.NewValue(10, 1.0), tsm1.NewValue(20, 2.0)},
+		"disk#!~#free": {tsm1.NewValue(10, int64(5))},
+		"mem#!~#used":  {tsm1.NewValue(10, int64(1)), tsm1.NewValue(20, int64(2))},
+	})
+
+	// The same data split across two files compares equal.
+	tsmtest.WriteFile(t, filepath.Join(dir, "b", "000000001-000000001.tsm"), map[string]tsm1.Values{
+		"cpu#!~#usage": {tsm1.NewValue(10, 1.0), tsm1.NewValue(20, 2.0)},
+	})
+	tsmtest.WriteFile(t, filepath.Join(dir, "b", "000000002-000000001.tsm"), map[string]tsm1.Values{
+		"disk#!~#free": {tsm1.NewValue(10, int64(5))},
+		"mem#!~#used":  {tsm1.NewValue(10, int64(1)), tsm1.NewValue(20, int64(2))},
+	})
+	r, err := tsmdiff.Diff(filepath.Join(dir, "a"), filepath.Join(dir, "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Equal() || r.FilesA != 1 || r.FilesB != 2 || r.PointsA != 5 || r.PointsB != 5 {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+
+	c := filepath.Join(dir, "c", "000000001-000000001.tsm")
+	tsmtest.WriteFile(t, c, map[string]tsm1.Values{
+		"cpu#!~#usage": {tsm1.NewValue(10, 1.0), tsm1.NewValue(20, 2.5)},
+		"mem#!~#used":  {tsm1.NewValue(10, int64(1)), tsm1.NewValue(20, int64(2))},
+		"net#!~#recv":  {tsm1.NewValue(10, int64(3))},
+	})
+	deleteRange(t, c, "mem#!~#used", 20, 20)
+
+	var stdout bytes.Buffer
+	cmd := &tsmdiff.Command{
+		Stdout:  &stdout,
+		Stderr:  ioutil.Discard,
+		PathA:   a,
+		PathB:   c,
+		Verbose: true,
+	}
+	if err := cmd.Run(); err != tsmdiff.ErrDifferent {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		`only in ` + a + `: "disk#!~#free"`,
+		`only in ` + c + `: "net#!~#recv"`,
+		`blocks differ: "cpu#!~#usage": 1 block(s) only in ` + a + `, 1 block(s) only in ` + c + `, 0 point(s) delta`,
+		`  - [1970-01-01T00:00:00.00000001Z, 1970-01-01T00:00:00.00000002Z] checksum `,
+		`blocks differ: "mem#!~#used": 0 block(s) only in ` + a + `, 0 block(s) only in ` + c + `, -1 point(s) delta`,
+		`1 key(s) only in ` + a + `, 1 key(s) only in ` + c + `, 2 key(s) with differing blocks, -1 point(s) delta (5 to 4)`,
+	} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, stdout.String())
+		}
+	}
+}
+
+// deleteRange records a tombstone for the values of key in [min, max].
+func deleteRange(t *testing.T, path, key string, min, max int64) {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := r.DeleteRange([][]byte{[]byte(key)}, min, max); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,358 @@
+// Package tsmstats reports the blocks, points, sizes and encodings of each
+// key of TSM files, to find the series responsible for a bloated shard.
+package tsmstats
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// Output formats of the report.
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+)
+
+// Orders of the keys of the report, from the largest to the smallest.
+const (
+	SortBytes    = "bytes"
+	SortRawBytes = "raw-bytes"
+	SortPoints   = "points"
+	SortBlocks   = "blocks"
+	// SortRatio sorts the keys from the least to the most compressed.
+	SortRatio = "ratio"
+)
+
+// Command reports the statistics of each key of TSM files, aggregated over
+// the files.
+//
+// Unlike report-disk, the blocks are read, to count their points and to
+// report their encodings. The raw bytes are the size of the timestamps and
+// values of the points once decoded: 16 bytes per point for numeric values,
+// 9 for booleans, and 8 plus the length of the string for strings. Points
+// deleted by tombstones are counted until they are compacted.
+type Command struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Paths lists the TSM files to report on, and the directories searched
+	// recursively for them.
+	Paths []string
+
+	// OrgID and BucketID optionally restrict the report to the series of an
+	// organization, or of a bucket within that organization.
+	OrgID    influxdb.ID
+	BucketID influxdb.ID
+
+	// Sort is the order of the keys, SortBytes by default.
+	Sort string
+
+	// Top limits the report to the first Top keys, if positive.
+	Top int
+
+	// Format is the output format, FormatTable by default.
+	Format string
+}
+
+// NewCommand returns a new instance of Command writing to the standard output and error.
+func NewCommand() *Command {
+	return &Command{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Sort:   SortBytes,
+		Format: FormatTable,
+	}
+}
+
+// KeyStats are the statistics of the blocks of a TSM key.
+type KeyStats struct {
+	OrgID    influxdb.ID `json:"orgID"`
+	BucketID influxdb.ID `json:"bucketID"`
+	// Series is the measurement and tags of the series.
+	Series string `json:"series"`
+	Field  string `json:"field"`
+	Type   string `json:"type"`
+
+	Files    int   `json:"files"`
+	Blocks   int   `json:"blocks"`
+	Points   int64 `json:"points"`
+	Bytes    int64 `json:"bytes"`
+	RawBytes int64 `json:"rawBytes"`
+
+	// TimestampEncodings and ValueEncodings count the blocks by encoding.
+	TimestampEncodings map[string]int `json:"timestampEncodings"`
+	ValueEncodings     map[string]int `json:"valueEncodings"`
+
+	key string
+}
+
+// Ratio returns the ratio of the raw bytes to the compressed bytes.
+func (s *KeyStats) Ratio() float64 {
+	if s.Bytes == 0 {
+		return 0
+	}
+	return float64(s.RawBytes) / float64(s.Bytes)
+}
+
+// Report is the statistics of the keys of TSM files.
+type Report struct {
+	Files    int         `json:"files"`
+	Keys     int         `json:"keys"`
+	Blocks   int         `json:"blocks"`
+	Points   int64       `json:"points"`
+	Bytes    int64       `json:"bytes"`
+	RawBytes int64       `json:"rawBytes"`
+	Stats    []*KeyStats `json:"stats"`
+}
+
+// Run reports the statistics of the keys of the TSM files found in Paths.
+func (cmd *Command) Run() error {
+	if len(cmd.Paths) == 0 {
+		return errors.New("path required")
+	}
+	if cmd.BucketID.Valid() && !cmd.OrgID.Valid() {
+		return errors.New("bucket requires an organization")
+	}
+	if cmd.Format == "" {
+		cmd.Format = FormatTable
+	}
+	if cmd.Format != FormatTable && cmd.Format != FormatJSON {
+		return fmt.Errorf("unsupported format %q", cmd.Format)
+	}
+	if cmd.Sort == "" {
+		cmd.Sort = SortBytes
+	}
+	switch cmd.Sort {
+	case SortBytes, SortRawBytes, SortPoints, SortBlocks, SortRatio:
+	default:
+		return fmt.Errorf("unsupported sort %q", cmd.Sort)
+	}
+
+	files, err := tsmfile.Find(cmd.Paths)
+	if err != nil {
+		return err
+	}
+	report, err := cmd.Report(files)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Format == FormatJSON {
+		enc := json.NewEncoder(cmd.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	return cmd.printTable(report)
+}
+
+// Report returns the statistics of the keys of the TSM files.
+func (cmd *Command) Report(files []string) (*Report, error) {
+	report := &Report{Files: len(files)}
+	stats := make(map[string]*KeyStats)
+	for _, path := range files {
+		if err := cmd.readFile(path, stats); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range stats {
+		report.Stats = append(report.Stats, s)
+		report.Blocks += s.Blocks
+		report.Points += s.Points
+		report.Bytes += s.Bytes
+		report.RawBytes += s.RawBytes
+	}
+	report.Keys = len(report.Stats)
+
+	sort.Slice(report.Stats, func(i, j int) bool {
+		a, b := report.Stats[i], report.Stats[j]
+		switch cmd.Sort {
+		case SortRawBytes:
+			if a.RawBytes != b.RawBytes {
+				return a.RawBytes > b.RawBytes
+			}
+		case SortPoints:
+			if a.Points != b.Points {
+				return a.Points > b.Points
+			}
+		case SortBlocks:
+			if a.Blocks != b.Blocks {
+				return a.Blocks > b.Blocks
+			}
+		case SortRatio:
+			if ra, rb := a.Ratio(), b.Ratio(); ra != rb {
+				return ra < rb
+			}
+		}
+		if a.Bytes != b.Bytes {
+			return a.Bytes > b.Bytes
+		}
+		return a.key < b.key
+	})
+	if cmd.Top > 0 && len(report.Stats) > cmd.Top {
+		report.Stats = report.Stats[:cmd.Top]
+	}
+	return report, nil
+}
+
+// readFile adds the statistics of the blocks of the TSM file at path.
+func (cmd *Command) readFile(path string, stats map[string]*KeyStats) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("unable to read %s: %v", path, err)
+	}
+	defer r.Close()
+
+	var (
+		prefix = tsmfile.Prefix(cmd.OrgID, cmd.BucketID)
+		buf    []byte
+		values []tsm1.StringValue
+	)
+	iter := r.Iterator(prefix)
+	for iter.Next() {
+		key := iter.Key()
+		if !bytes.HasPrefix(key, prefix) {
+			break
+		}
+
+		s := stats[string(key)]
+		if s == nil {
+			s = newKeyStats(key, iter.Type())
+			stats[s.key] = s
+		}
+		s.Files++
+
+		entries := iter.Entries()
+		for i := range entries {
+			_, buf, err = r.ReadBytes(&entries[i], buf)
+			if err != nil {
+				return fmt.Errorf("%s: unable to read a block of %q: %v", path, key, err)
+			}
+			ts, vs, err := tsm1.BlockEncodings(buf)
+			if err != nil {
+				return fmt.Errorf("%s: invalid block of %q: %v", path, key, err)
+			}
+
+			n := tsm1.BlockCount(buf)
+			raw := int64(n) * 8
+			switch iter.Type() {
+			case tsm1.BlockBoolean:
+				raw += int64(n)
+			case tsm1.BlockString:
+				if values, err = tsm1.DecodeStringBlock(buf, &values); err != nil {
+					return fmt.Errorf("%s: invalid block of %q: %v", path, key, err)
+				}
+				for _, v := range values {
+					raw += int64(len(v.RawValue()))
+				}
+			default:
+				raw += int64(n) * 8
+			}
+
+			s.Blocks++
+			s.Points += int64(n)
+			s.Bytes += int64(entries[i].Size)
+			s.RawBytes += raw
+			s.TimestampEncodings[ts]++
+			s.ValueEncodings[vs]++
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+func newKeyStats(key []byte, typ byte) *KeyStats {
+	s := &KeyStats{
+		Type:               tsm1.BlockTypeName(typ),
+		TimestampEncodings: make(map[string]int),
+		ValueEncodings:     make(map[string]int),
+		key:                string(key),
+	}
+
+	seriesKey, field := tsm1.SeriesAndFieldFromCompositeKey(key)
+	name, tags := models.ParseKeyBytes(seriesKey)
+	s.Field = string(field)
+	if len(name) == 16 {
+		s.OrgID, s.BucketID = tsdb.DecodeNameSlice(name)
+	}
+
+	measurement := tags.Get(models.MeasurementTagKeyBytes)
+	filtered := make(models.Tags, 0, len(tags))
+	for _, t := range tags {
+		if bytes.Equal(t.Key, models.MeasurementTagKeyBytes) || bytes.Equal(t.Key, models.FieldKeyTagKeyBytes) {
+			continue
+		}
+		filtered = append(filtered, t)
+	}
+	s.Series = string(models.MakeKey(measurement, filtered))
+	return s
+}
+
+func (cmd *Command) printTable(report *Report) error {
+	tw := tabwriter.NewWriter(cmd.Stdout, 8, 2, 1, ' ', 0)
+	fmt.Fprintln(tw, strings.Join([]string{"Organization", "Bucket", "Series", "Field", "Type", "Blocks", "Points", "Bytes", "Raw Bytes", "Ratio", "Encodings"}, "\t"))
+	for _, s := range report.Stats {
+		fmt.Fprintln(tw, strings.Join([]string{
+			s.OrgID.String(),
+			s.BucketID.String(),
+			s.Series,
+			s.Field,
+			s.Type,
+			fmt.Sprint(s.Blocks),
+			fmt.Sprint(s.Points),
+			fmt.Sprint(s.Bytes),
+			fmt.Sprint(s.RawBytes),
+			fmt.Sprintf("%.2f", s.Ratio()),
+			formatEncodings(s),
+		}, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	var ratio float64
+	if report.Bytes > 0 {
+		ratio = float64(report.RawBytes) / float64(report.Bytes)
+	}
+	fmt.Fprintf(cmd.Stdout, "\n%d key(s), %d block(s), %d point(s), %d bytes (%d raw, ratio %.2f) in %d TSM file(s)\n",
+		report.Keys, report.Blocks, report.Points, report.Bytes, report.RawBytes, ratio, report.Files)
+	return nil
+}
+
+// formatEncodings returns the encodings of the timestamps and values of the
+// blocks of a key, with the number of blocks of each, such as
+// "ts: rle=3 simple8b=1, values: gorilla=4".
+func formatEncodings(s *KeyStats) string {
+	format := func(m map[string]int) string {
+		names := make([]string, 0, len(m))
+		for name := range m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for i, name := range names {
+			names[i] = fmt.Sprintf("%s=%d", name, m[name])
+		}
+		return strings.Join(names, " ")
+	}
+	return "ts: " + format(s.TimestampEncodings) + ", values: " + format(s.ValueEncodings)
+}
@@ -0,0 +1,145 @@
+package tsmstats_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/internal/tsmfile/tsmtest"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/tsmstats"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+var (
+	orgID    = influxdb.ID(0x1000)
+	bucketID = influxdb.ID(0x2000)
+)
+
+func TestCommand_Report(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsmstats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var points tsm1.Values
+	for i := int64(0); i < 100; i++ {
+		points = append(points, tsm1.NewValue(i*10, float64(i%7)))
+	}
+	tsmtest.WriteFile(t, filepath.Join(dir, "1", "000000001-000000001.tsm"), map[string]tsm1.Values{
+		seriesKey("cpu", "usage", "host", "a"):  points,
+		seriesKey("svc", "status", "host", "a"): {tsm1.NewValue(0, "up"), tsm1.NewValue(10, "down")},
+	})
+	tsmtest.WriteFile(t, filepath.Join(dir, "2", "000000001-000000001.tsm"), map[string]tsm1.Values{
+		seriesKey("cpu", "usage", "host", "a"): {tsm1.NewValue(2000, 1.0)},
+		seriesKey("mem", "free", "host", "a"):  {tsm1.NewValue(0, int64(1)), tsm1.NewValue(10, int64(2)), tsm1.NewValue(20, int64(3))},
+	})
+
+	cmd := tsmstats.NewCommand()
+	cmd.Sort = tsmstats.SortPoints
+	report, err := cmd.Report([]string{
+		filepath.Join(dir, "1", "000000001-000000001.tsm"),
+		filepath.Join(dir, "2", "000000001-000000001.tsm"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Files != 2 || report.Keys != 3 || report.Blocks != 4 || report.Points != 106 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	cpu, mem, svc := report.Stats[0], report.Stats[1], report.Stats[2]
+	if cpu.OrgID != orgID || cpu.BucketID != bucketID || cpu.Series != "cpu,host=a" || cpu.Field != "usage" || cpu.Type != "float64" {
+		t.Fatalf("unexpected key: %+v", cpu)
+	}
+	if cpu.Files != 2 || cpu.Blocks != 2 || cpu.Points != 101 || cpu.RawBytes != 101*16 {
+		t.Fatalf("unexpected statistics: %+v", cpu)
+	}
+	if cpu.ValueEncodings["gorilla"] != 2 || cpu.TimestampEncodings["rle"] != 1 || cpu.Ratio() <= 1 {
+		t.Fatalf("unexpected encodings: %+v", cpu)
+	}
+	if mem.Series != "mem,host=a" || mem.Points != 3 || mem.RawBytes != 3*16 || mem.ValueEncodings["rle"] != 1 {
+		t.Fatalf("unexpected statistics: %+v", mem)
+	}
+	if svc.Series != "svc,host=a" || svc.Type != "string" || svc.Points != 2 || svc.RawBytes != int64(2*8+len("up")+len("down")) || svc.ValueEncodings["snappy"] != 1 {
+		t.Fatalf("unexpected statistics: %+v", svc)
+	}
+}
+
+func TestCommand_Run(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsmstats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tsmtest.WriteFile(t, filepath.Join(dir, "1", "000000001-000000001.tsm"), map[string]tsm1.Values{
+		seriesKey("cpu", "usage", "host", "a"): {tsm1.NewValue(0, 1.0)},
+		seriesKey("mem", "free", "host", "a"):  {tsm1.NewValue(0, int64(1)), tsm1.NewValue(10, int64(2))},
+	})
+
+	var stdout bytes.Buffer
+	cmd := tsmstats.NewCommand()
+	cmd.Stdout = &stdout
+	cmd.Paths = []string{dir}
+	cmd.OrgID, cmd.BucketID = orgID, bucketID
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(stdout.String(), "\n")
+	if !strings.HasPrefix(lines[0], "Organization") || !strings.Contains(lines[1], "0000000000001000") ||
+		!strings.Contains(stdout.String(), "values: ") || !strings.Contains(stdout.String(), "2 key(s), 2 block(s), 3 point(s)") {
+		t.Fatalf("unexpected output:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	cmd.Format = tsmstats.FormatJSON
+	cmd.Sort = tsmstats.SortPoints
+	cmd.Top = 1
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	var report tsmstats.Report
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Keys != 2 || len(report.Stats) != 1 || report.Stats[0].Series != "mem,host=a" {
+		t.Fatalf("unexpected report: %s", stdout.String())
+	}
+}
+
+func TestCommand_Run_Invalid(t *testing.T) {
+	for _, tt := range []struct {
+		cmd tsmstats.Command
+		err string
+	}{
+		{cmd: tsmstats.Command{}, err: "path required"},
+		{cmd: tsmstats.Command{Paths: []string{"data"}, BucketID: bucketID}, err: "bucket requires an organization"},
+		{cmd: tsmstats.Command{Paths: []string{"data"}, Format: "csv"}, err: `unsupported format "csv"`},
+		{cmd: tsmstats.Command{Paths: []string{"data"}, Sort: "name"}, err: `unsupported sort "name"`},
+	} {
+		if err := tt.cmd.Run(); err == nil || err.Error() != tt.err {
+			t.Errorf("unexpected error: got %v, want %q", err, tt.err)
+		}
+	}
+}
+
+// seriesKey returns the TSM key of the field of a series in the test bucket.
+func seriesKey(measurement, field string, tags ...string) string {
+	name := tsdb.EncodeName(orgID, bucketID)
+	m := map[string]string{
+		models.MeasurementTagKey: measurement,
+		models.FieldKeyTagKey:    field,
+	}
+	for i := 0; i < len(tags); i += 2 {
+		m[tags[i]] = tags[i+1]
+	}
+	return string(models.MakeKey(name[:], models.NewTags(m))) + "#!~#" + field
+}
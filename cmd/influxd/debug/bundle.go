@@ -0,0 +1,323 @@
+// Package debug collects diagnostics of an influxd server to attach to bug reports.
+package debug
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb"
+)
+
+// Bundler writes a support bundle, a gzipped tar archive of the diagnostics
+// of a server. Collecting is best effort: the diagnostics that can not be
+// collected are listed in the manifest of the bundle instead.
+type Bundler struct {
+	// Host is the URL of the running server to collect the configuration,
+	// query log, metrics and profiles from. They are not collected if it is
+	// empty.
+	Host string
+	// Token authenticates the requests to Host. The configuration and the
+	// query log require the token of an operator.
+	Token string
+	// Client is the HTTP client used to query Host.
+	Client *http.Client
+
+	// EnginePath is the path of the storage engine directory to list the
+	// files of. The inventory is not collected if it is empty.
+	EnginePath string
+
+	// LogPath is the path of the server log to include the end of.
+	LogPath string
+	// LogBytes is the maximum size of the end of the log included.
+	LogBytes int64
+
+	// MetricsSnapshots is the number of snapshots of the metrics, taken
+	// MetricsInterval apart, so that the rates of counters can be computed.
+	MetricsSnapshots int
+	MetricsInterval  time.Duration
+
+	// Now returns the current time.
+	Now func() time.Time
+}
+
+// NewBundler returns a Bundler with default settings.
+func NewBundler() *Bundler {
+	return &Bundler{
+		Client:           &http.Client{Timeout: time.Minute},
+		LogBytes:         10 << 20,
+		MetricsSnapshots: 2,
+		MetricsInterval:  10 * time.Second,
+		Now:              time.Now,
+	}
+}
+
+// Manifest describes the content of a bundle.
+type Manifest struct {
+	CreatedAt time.Time          `json:"createdAt"`
+	Build     influxdb.BuildInfo `json:"build"`
+	Host      string             `json:"host,omitempty"`
+	Files     []string           `json:"files"`
+	// Errors lists the diagnostics that could not be collected.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// bundleWriter adds files to the archive of a bundle.
+type bundleWriter struct {
+	tw       *tar.Writer
+	now      time.Time
+	manifest Manifest
+}
+
+func (w *bundleWriter) add(name string, data []byte) error {
+	if err := w.write(name, data); err != nil {
+		return err
+	}
+	w.manifest.Files = append(w.manifest.Files, name)
+	return nil
+}
+
+func (w *bundleWriter) write(name string, data []byte) error {
+	if err := w.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: w.now,
+	}); err != nil {
+		return err
+	}
+	_, err := w.tw.Write(data)
+	return err
+}
+
+// collect adds the file name with the content returned by fn, or records
+// the error of fn in the manifest. Only errors writing the archive are returned.
+func (w *bundleWriter) collect(name string, fn func() ([]byte, error)) error {
+	data, err := fn()
+	if err != nil {
+		w.manifest.Errors = append(w.manifest.Errors, fmt.Sprintf("%s: %v", name, err))
+		return nil
+	}
+	return w.add(name, data)
+}
+
+// Write writes the bundle to out and returns its manifest.
+func (b *Bundler) Write(ctx context.Context, out io.Writer) (*Manifest, error) {
+	gz := gzip.NewWriter(out)
+	w := &bundleWriter{tw: tar.NewWriter(gz), now: b.Now().UTC()}
+	w.manifest = Manifest{
+		CreatedAt: w.now,
+		Build:     influxdb.GetBuildInfo(),
+		Host:      b.Host,
+		Files:     []string{},
+	}
+
+	if b.LogPath != "" {
+		if err := w.collect("logs/influxd.log", b.logTail); err != nil {
+			return nil, err
+		}
+	}
+	if b.EnginePath != "" {
+		if err := w.collect("inventory.txt", b.inventory); err != nil {
+			return nil, err
+		}
+	}
+	if b.Host != "" {
+		if err := b.collectServer(ctx, w); err != nil {
+			return nil, err
+		}
+	}
+
+	w.manifest.Files = append(w.manifest.Files, "manifest.json")
+	data, err := json.MarshalIndent(&w.manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := w.write("manifest.json", append(data, '\n')); err != nil {
+		return nil, err
+	}
+	if err := w.tw.Close(); err != nil {
+		return nil, err
+	}
+	return &w.manifest, gz.Close()
+}
+
+// collectServer adds the configuration, health, query log, metrics and
+// profiles of the server at Host.
+func (b *Bundler) collectServer(ctx context.Context, w *bundleWriter) error {
+	if err := w.collect("config.txt", func() ([]byte, error) {
+		return b.config(ctx)
+	}); err != nil {
+		return err
+	}
+	if err := w.collect("health.json", func() ([]byte, error) {
+		return b.get(ctx, "/health")
+	}); err != nil {
+		return err
+	}
+	if err := w.collect("queries.json", func() ([]byte, error) {
+		data, err := b.get(ctx, "/api/v2/diagnostics/queries")
+		if err != nil {
+			return nil, err
+		}
+		return redactQueries(data), nil
+	}); err != nil {
+		return err
+	}
+
+	for i := 1; i <= b.MetricsSnapshots; i++ {
+		if i > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(b.MetricsInterval):
+			}
+		}
+		if err := w.collect(fmt.Sprintf("metrics/metrics-%d.txt", i), func() ([]byte, error) {
+			return b.get(ctx, "/metrics")
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := w.collect("profiles/goroutine.txt", func() ([]byte, error) {
+		return b.get(ctx, "/debug/pprof/goroutine?debug=2")
+	}); err != nil {
+		return err
+	}
+	return w.collect("profiles/heap.pb.gz", func() ([]byte, error) {
+		return b.get(ctx, "/debug/pprof/heap")
+	})
+}
+
+// get returns the body of the response to a GET request of path to Host.
+func (b *Bundler) get(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequest("GET", strings.TrimSuffix(b.Host, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if b.Token != "" {
+		req.Header.Set("Authorization", "Token "+b.Token)
+	}
+
+	resp, err := b.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return data, nil
+}
+
+// config returns the configuration options of the server as name=value
+// lines. The server redacts the values of secrets, which are redacted again
+// in case it does not know an option holds one.
+func (b *Bundler) config(ctx context.Context) ([]byte, error) {
+	data, err := b.get(ctx, "/api/v2/diagnostics/config")
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Options []influxdb.ConfigOption `json:"options"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, o := range resp.Options {
+		buf.WriteString(redactVar(o.Name + "=" + o.Value))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// logTail returns the last LogBytes of the log at LogPath, starting at a
+// line, with the credentials it contains redacted.
+func (b *Bundler) logTail() ([]byte, error) {
+	f, err := os.Open(b.LogPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := fi.Size() - b.LogBytes
+	if offset < 0 || b.LogBytes <= 0 {
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		// Skip the partial first line.
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+	return redactLog(data), nil
+}
+
+// inventory lists the files of the storage engine directory with their size
+// and modification time, followed by the total size of each top-level directory.
+func (b *Bundler) inventory() ([]byte, error) {
+	var (
+		buf    bytes.Buffer
+		totals = make(map[string]int64)
+	)
+	err := filepath.Walk(b.EnginePath, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(b.EnginePath, path)
+		if err != nil {
+			return err
+		}
+		totals[strings.SplitN(filepath.ToSlash(rel), "/", 2)[0]] += fi.Size()
+		fmt.Fprintf(&buf, "%s\t%d\t%s\n", filepath.ToSlash(rel), fi.Size(), fi.ModTime().UTC().Format(time.RFC3339))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make([]string, 0, len(totals))
+	for dir := range totals {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	buf.WriteString("\ntotal\n")
+	for _, dir := range dirs {
+		fmt.Fprintf(&buf, "%s\t%d\n", dir, totals[dir])
+	}
+	return buf.Bytes(), nil
+}
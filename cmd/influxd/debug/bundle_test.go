@@ -0,0 +1,150 @@
+package debug_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/cmd/influxd/debug"
+)
+
+func TestBundler_Write(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v2/diagnostics/config":
+			w.Write([]byte(`{"options":[{"name":"log-level","value":"debug"},{"name":"vault-token","value":"[REDACTED]"},{"name":"storage-object-store-secret-key","value":"abc"}]}`))
+		case "/api/v2/diagnostics/queries":
+			w.Write([]byte(`{"recent":[{"request":{"query":"from(bucket: \"b\", host: \"http://remote\", token: \"abc123\")"},"plan":"digraph {}"}]}`))
+		case "/health":
+			w.Write([]byte(`{"status":"pass"}`))
+		case "/metrics":
+			w.Write([]byte("http_api_requests_total 1\n"))
+		case "/debug/pprof/goroutine":
+			w.Write([]byte("goroutine 1 [running]:\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "debug-bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	mustWriteFile(t, filepath.Join(dir, "engine", "data", "000000001-000000001.tsm"), "tsm")
+	mustWriteFile(t, filepath.Join(dir, "engine", "wal", "_00001.wal"), "wal!")
+	mustWriteFile(t, filepath.Join(dir, "influxd.log"),
+		"partial line\nlvl=info msg=\"request\" authorization=\"Token abc123\"\nlvl=info msg=\"query\" url=/query?u=me&p=hunter2\n")
+
+	b := debug.NewBundler()
+	b.Host = ts.URL
+	b.Token = "secret"
+	b.EnginePath = filepath.Join(dir, "engine")
+	b.LogPath = filepath.Join(dir, "influxd.log")
+	b.LogBytes = 100
+	b.MetricsInterval = time.Millisecond
+	b.Now = func() time.Time { return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	var buf bytes.Buffer
+	manifest, err := b.Write(context.Background(), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := readBundle(t, &buf)
+	want := []string{"logs/influxd.log", "inventory.txt", "config.txt", "health.json", "queries.json",
+		"metrics/metrics-1.txt", "metrics/metrics-2.txt", "profiles/goroutine.txt", "manifest.json"}
+	if !reflect.DeepEqual(manifest.Files, want) {
+		t.Fatalf("unexpected files: got %v, want %v", manifest.Files, want)
+	}
+	if len(manifest.Errors) != 1 || !strings.HasPrefix(manifest.Errors[0], "profiles/heap.pb.gz: unexpected status 404") {
+		t.Fatalf("unexpected errors: %v", manifest.Errors)
+	}
+
+	if got, want := files["config.txt"], "log-level=debug\nvault-token=[REDACTED]\nstorage-object-store-secret-key=[REDACTED]\n"; got != want {
+		t.Errorf("unexpected config: got %q, want %q", got, want)
+	}
+	var queries struct {
+		Recent []struct {
+			Request struct {
+				Query string `json:"query"`
+			} `json:"request"`
+			Plan string `json:"plan"`
+		} `json:"recent"`
+	}
+	if err := json.Unmarshal([]byte(files["queries.json"]), &queries); err != nil {
+		t.Fatal(err)
+	} else if len(queries.Recent) != 1 || queries.Recent[0].Plan != "digraph {}" ||
+		queries.Recent[0].Request.Query != `from(bucket: "b", host: "http://remote", token: "[REDACTED]")` {
+		t.Errorf("unexpected queries: %+v", queries)
+	}
+	if got := files["logs/influxd.log"]; strings.Contains(got, "partial") || strings.Contains(got, "abc123") || strings.Contains(got, "hunter2") || !strings.Contains(got, "Token [REDACTED]") {
+		t.Errorf("unexpected log: %q", got)
+	}
+	if got := files["inventory.txt"]; !strings.Contains(got, "data/000000001-000000001.tsm\t3\t") || !strings.Contains(got, "\nwal\t4\n") {
+		t.Errorf("unexpected inventory: %q", got)
+	}
+	if got := files["metrics/metrics-2.txt"]; got != "http_api_requests_total 1\n" {
+		t.Errorf("unexpected metrics: %q", got)
+	}
+
+	var m debug.Manifest
+	if err := json.Unmarshal([]byte(files["manifest.json"]), &m); err != nil {
+		t.Fatal(err)
+	} else if m.Host != ts.URL || !m.CreatedAt.Equal(b.Now()) {
+		t.Errorf("unexpected manifest: %+v", m)
+	}
+}
+
+func mustWriteFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(data), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readBundle returns the content of the files of a bundle by name.
+func readBundle(t *testing.T, r io.Reader) map[string]string {
+	t.Helper()
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[hdr.Name] = string(data)
+	}
+	return files
+}
@@ -0,0 +1,116 @@
+package debug
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/influxdata/influxdb/internal/fs"
+	"github.com/spf13/cobra"
+)
+
+// NewCommand creates the debug command.
+func NewCommand() *cobra.Command {
+	base := &cobra.Command{
+		Use:   "debug",
+		Short: "Commands for collecting diagnostics of a server",
+	}
+
+	base.AddCommand(NewBundleCommand())
+
+	return base
+}
+
+// bundleFlags defines the `bundle` Command.
+var bundleFlags = struct {
+	host             string
+	token            string
+	enginePath       string
+	logPath          string
+	logBytes         int64
+	metricsSnapshots int
+	metricsInterval  time.Duration
+	output           string
+}{}
+
+// NewBundleCommand returns a new instance of the bundle command.
+func NewBundleCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Writes a support bundle to attach to bug reports",
+		Long: `
+This command writes a gzipped tar archive of the diagnostics of a server:
+
+	* The end of the server log given by --log-file, with credentials redacted;
+	* The files of the storage engine directory, with their size;
+	* The configuration of the server running at --host, with secrets redacted;
+	* Its health;
+	* Its recent and slow queries, with their request and plan, and with
+	  credentials redacted;
+	* Snapshots of its metrics, --metrics-interval apart; and
+	* Its goroutine and heap profiles.
+
+The configuration and the queries of the server require the --token of an
+operator.
+
+The diagnostics that can not be collected are listed in the manifest.json file
+of the archive.
+`,
+		Args: cobra.NoArgs,
+		RunE: bundleF,
+	}
+
+	enginePath := ""
+	if dir, err := fs.InfluxDir(); err == nil {
+		enginePath = filepath.Join(dir, "engine")
+	}
+
+	cmd.Flags().StringVar(&bundleFlags.host, "host", "http://localhost:9999", "URL of the server to collect the configuration, queries, metrics and profiles from, or empty to skip them")
+	cmd.Flags().StringVarP(&bundleFlags.token, "token", "t", "", "token authenticating the requests to the server")
+	cmd.Flags().StringVar(&bundleFlags.enginePath, "engine-path", enginePath, "path of the storage engine directory, or empty to skip the inventory")
+	cmd.Flags().StringVar(&bundleFlags.logPath, "log-file", "", "path of the server log to include")
+	cmd.Flags().Int64Var(&bundleFlags.logBytes, "log-bytes", 10<<20, "maximum number of bytes of the end of the log to include")
+	cmd.Flags().IntVar(&bundleFlags.metricsSnapshots, "metrics-snapshots", 2, "number of snapshots of the metrics to take")
+	cmd.Flags().DurationVar(&bundleFlags.metricsInterval, "metrics-interval", 10*time.Second, "interval between snapshots of the metrics")
+	cmd.Flags().StringVarP(&bundleFlags.output, "output", "o", "", "path of the bundle, defaults to influxd-bundle-<time>.tar.gz")
+
+	return cmd
+}
+
+func bundleF(cmd *cobra.Command, args []string) error {
+	b := NewBundler()
+	b.Host = bundleFlags.host
+	b.Token = bundleFlags.token
+	b.EnginePath = bundleFlags.enginePath
+	b.LogPath = bundleFlags.logPath
+	b.LogBytes = bundleFlags.logBytes
+	b.MetricsSnapshots = bundleFlags.metricsSnapshots
+	b.MetricsInterval = bundleFlags.metricsInterval
+
+	path := bundleFlags.output
+	if path == "" {
+		path = fmt.Sprintf("influxd-bundle-%s.tar.gz", b.Now().UTC().Format("20060102T150405Z"))
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := b.Write(context.Background(), f)
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Wrote %d file(s) to %s\n", len(manifest.Files), path)
+	for _, e := range manifest.Errors {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Not collected: %s\n", e)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package debug
+
+import (
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// secretNames are the substrings of the names of the variables holding secrets.
+var secretNames = []string{"TOKEN", "SECRET", "PASSWORD", "KEY", "CREDENTIAL"}
+
+// redactVar redacts the value of the NAME=value environment variable if its
+// name suggests it holds a secret.
+func redactVar(kv string) string {
+	i := strings.IndexByte(kv, '=')
+	if i < 0 || i == len(kv)-1 {
+		return kv
+	}
+	name := strings.ToUpper(kv[:i])
+	for _, s := range secretNames {
+		if strings.Contains(name, s) {
+			return kv[:i+1] + redacted
+		}
+	}
+	return kv
+}
+
+var (
+	// authPattern matches the credentials of Authorization headers.
+	authPattern = regexp.MustCompile(`(?i)\b(Token|Bearer|Basic)(\s+)[^\s"',]+`)
+	// paramPattern matches the values of credential parameters.
+	paramPattern = regexp.MustCompile(`(?i)\b((?:token|password|secret|p)=)[^\s&"',]+`)
+	// argPattern matches the string values of credential arguments of Flux
+	// functions, such as token: "...", with their quotes escaped in JSON.
+	argPattern = regexp.MustCompile(`(?i)\b((?:token|password|secret)\s*:\s*\\")(?:[^"\\]|\\[^"])*\\"`)
+)
+
+// redactLog redacts the credentials found in log lines.
+func redactLog(data []byte) []byte {
+	data = authPattern.ReplaceAll(data, []byte("${1}${2}"+redacted))
+	return paramPattern.ReplaceAll(data, []byte("${1}"+redacted))
+}
+
+// redactQueries redacts the credentials found in the requests of a query log,
+// either in headers or parameters, or given as arguments to Flux functions.
+func redactQueries(data []byte) []byte {
+	data = redactLog(data)
+	return argPattern.ReplaceAll(data, []byte("${1}"+redacted+`\"`))
+}
@@ -0,0 +1,81 @@
+package inspect
+
+import (
+	"github.com/influxdata/influxdb/cmd/influx_inspect/anonymize"
+	"github.com/spf13/cobra"
+)
+
+// anonymizeFlags defines the `anonymize` Command.
+var anonymizeFlags = struct {
+	outDir       string
+	measurements bool
+	tags         []string
+	stringFields []string
+	salt         string
+	mappingFile  string
+	jitter       float64
+	seed         int64
+}{}
+
+// NewAnonymizeCommand returns a new instance of the anonymize command.
+func NewAnonymizeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "anonymize <pathspec>...",
+		Short: "Rewrites TSM files with pseudonyms in place of tag values",
+		Long: `
+This command will rewrite each TSM file, replacing the values of its tags with
+pseudonyms, so that shards can be shared without disclosing host names or
+customer identifiers. The pseudonym of a value is a hash of the value, the same
+in every series and file, so that the cardinality of the series is preserved.
+Tag keys, field keys and timestamps are left untouched. Tombstoned values are
+dropped from the new files, and the input files are left untouched.
+
+Use --tag, which may be repeated, to only replace the values of some tags, and
+--string-field to also replace the values of string fields. A --salt keeps the
+pseudonyms from being reversed by hashing guessed values. With --mapping-file,
+the pseudonyms of the values are recorded in a JSON file, and the pseudonyms it
+already holds are reused, so that values are mapped consistently across runs.
+The mapping file discloses the original values and must be kept private.
+
+OPTIONS
+
+   <pathspec>...
+      A list of TSM files and directories, such as shard directories or the
+      data directory of the engine, searched recursively for TSM files. The
+      new files of a directory keep their path relative to it.
+
+The new files are written under --out-dir, which must not already hold files
+of the same name. The series of the new files are not those of the index of
+the engine, which must be rebuilt with build-tsi once the new files are moved
+in place of the original ones.
+`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: anonymizeF,
+	}
+
+	cmd.Flags().StringVar(&anonymizeFlags.outDir, "out-dir", "", "directory of the anonymized files (required)")
+	cmd.Flags().BoolVar(&anonymizeFlags.measurements, "measurements", false, "also replace the names of measurements with pseudonyms")
+	cmd.Flags().StringArrayVar(&anonymizeFlags.tags, "tag", nil, "key of a tag whose values are replaced, may be repeated, every tag by default")
+	cmd.Flags().StringArrayVar(&anonymizeFlags.stringFields, "string-field", nil, "key of a string field whose values are replaced, may be repeated")
+	cmd.Flags().StringVar(&anonymizeFlags.salt, "salt", "", "salt of the hashes of the pseudonyms")
+	cmd.Flags().StringVar(&anonymizeFlags.mappingFile, "mapping-file", "", "JSON file of the pseudonyms of values, read if it exists and updated")
+	cmd.Flags().Float64Var(&anonymizeFlags.jitter, "jitter", 0, "multiply numeric field values by a random factor between 1-jitter and 1+jitter")
+	cmd.Flags().Int64Var(&anonymizeFlags.seed, "seed", 0, "seed of the random factors of --jitter, derived from the current time if zero")
+	cmd.MarkFlagRequired("out-dir")
+
+	return cmd
+}
+
+func anonymizeF(cmd *cobra.Command, args []string) error {
+	anonymizer := anonymize.NewCommand()
+	anonymizer.Paths = args
+	anonymizer.OutDir = anonymizeFlags.outDir
+	anonymizer.Measurements = anonymizeFlags.measurements
+	anonymizer.Tags = anonymizeFlags.tags
+	anonymizer.StringFields = anonymizeFlags.stringFields
+	anonymizer.Salt = anonymizeFlags.salt
+	anonymizer.MappingFile = anonymizeFlags.mappingFile
+	anonymizer.Jitter = anonymizeFlags.jitter
+	anonymizer.Seed = anonymizeFlags.seed
+	return anonymizer.Run()
+}
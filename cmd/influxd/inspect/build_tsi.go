@@ -35,8 +35,11 @@ var buildTSIFlags = struct {
 	BatchSize      int    // optional. Defaults to 10000
 	MaxLogFileSize int64  // optional. Defaults to tsi1.DefaultMaxIndexLogFileSize
 	MaxCacheSize   uint64 // optional. Defaults to tsm1.DefaultCacheMaxMemorySize
+	MaxMemory      int64  // optional. Defaults to 0, unbounded
 
 	Concurrency int  // optional. Defaults to GOMAXPROCS(0)
+	Incremental bool // optional. Defaults to false.
+	Verify      bool // optional. Defaults to false.
 	Verbose     bool // optional. Defaults to false.
 }{
 	Stderr: os.Stderr,
@@ -55,7 +58,8 @@ func NewBuildTSICommand() *cobra.Command {
 		directory, and all of the WAL entries in the WAL data directory. If the 
 		Series File directory is missing, then the series file will be rebuilt.
 
-		If the TSI index directory already exists, then this tool will fail.
+		If the TSI index directory already exists, then this tool will fail,
+		unless incremental is set.
 
 		Performance of the tool can be tweaked by adjusting the max log file size,
 		max cache file size and the batch size.
@@ -74,6 +78,29 @@ func NewBuildTSICommand() *cobra.Command {
 		batch-size refers to the size of the batches written into the index. 
 			Increasing this can improve performance but can result in much more
 			memory usage.
+
+		concurrency is the number of TSM files indexed concurrently.
+
+		max-memory bounds the memory used by the batches in flight and by the
+			in-memory parts of the index, which are then flushed and compacted
+			to disk sooner. It lowers max-log-file-size if needed.
+
+		If the tool is interrupted, running it again resumes the build,
+			skipping the TSM files already indexed.
+
+		incremental updates an existing index with the series of the TSM files
+			and WAL segments added or modified since it was built, as recorded
+			in the manifest of the index, rather than rebuilding it. The series
+			of removed files are not dropped from the index, which must be
+			removed and rebuilt to drop them.
+
+		verify checks that the index holds every series of the TSM files and
+			WAL segments, and no other series unless it was updated
+			incrementally. A new index failing verification is removed rather
+			than moved to the index directory. An existing index is verified
+			even if it is not rebuilt.
+
+		Once done, the tool reports the duration of each step of the build.
 		`,
 		RunE: RunBuildTSI,
 	}
@@ -92,7 +119,10 @@ func NewBuildTSICommand() *cobra.Command {
 	cmd.Flags().IntVar(&buildTSIFlags.Concurrency, "concurrency", runtime.GOMAXPROCS(0), "Number of workers to dedicate to shard index building. Defaults to GOMAXPROCS")
 	cmd.Flags().Int64Var(&buildTSIFlags.MaxLogFileSize, "max-log-file-size", tsi1.DefaultMaxIndexLogFileSize, "optional: maximum log file size")
 	cmd.Flags().Uint64Var(&buildTSIFlags.MaxCacheSize, "max-cache-size", uint64(tsm1.DefaultCacheMaxMemorySize), "optional: maximum cache size")
+	cmd.Flags().Int64Var(&buildTSIFlags.MaxMemory, "max-memory", 0, "optional: approximate memory budget in bytes of the index build, 0 for unbounded")
 	cmd.Flags().IntVar(&buildTSIFlags.BatchSize, "batch-size", defaultBatchSize, "optional: set the size of the batches we write to the index. Setting this can have adverse affects on performance and heap requirements")
+	cmd.Flags().BoolVar(&buildTSIFlags.Incremental, "incremental", false, "optional: only index the files added or modified since the existing index was built")
+	cmd.Flags().BoolVar(&buildTSIFlags.Verify, "verify", false, "optional: check that the index holds the series of the TSM files and WAL segments")
 	cmd.Flags().BoolVar(&buildTSIFlags.Verbose, "v", false, "verbose")
 
 	cmd.SetOutput(buildTSIFlags.Stdout)
@@ -125,8 +155,17 @@ func RunBuildTSI(cmd *cobra.Command, args []string) error {
 	defer sfile.Close()
 
 	return buildtsi.IndexShard(sfile, buildTSIFlags.IndexPath, buildTSIFlags.DataPath, buildTSIFlags.WALPath,
-		buildTSIFlags.MaxLogFileSize, buildTSIFlags.MaxCacheSize, buildTSIFlags.BatchSize,
-		log, buildTSIFlags.Verbose)
+		buildtsi.Options{
+			MaxLogFileSize: buildTSIFlags.MaxLogFileSize,
+			MaxCacheSize:   buildTSIFlags.MaxCacheSize,
+			BatchSize:      buildTSIFlags.BatchSize,
+			Concurrency:    buildTSIFlags.Concurrency,
+			MaxMemory:      buildTSIFlags.MaxMemory,
+			Incremental:    buildTSIFlags.Incremental,
+			Verify:         buildTSIFlags.Verify,
+			Report:         buildTSIFlags.Stdout,
+			Verbose:        buildTSIFlags.Verbose,
+		}, log)
 }
 
 func isRoot() bool {
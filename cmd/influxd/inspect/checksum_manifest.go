@@ -0,0 +1,66 @@
+package inspect
+
+import (
+	"path/filepath"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/checksummanifest"
+	"github.com/influxdata/influxdb/internal/fs"
+	"github.com/spf13/cobra"
+)
+
+// checksumManifestFlags defines the `checksum-manifest` Command.
+var checksumManifestFlags = struct {
+	dir      string
+	manifest string
+	verify   bool
+}{}
+
+// NewChecksumManifestCommand returns a new instance of the checksum-manifest command.
+func NewChecksumManifestCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "checksum-manifest",
+		Short: "Generates or verifies a manifest of the checksums of a data directory",
+		Long: `
+This command will write a manifest of every file of a directory, with its
+path, size and SHA-256 checksum, and the number of keys, time range and index
+size of TSM files. With --verify, the files of the directory are instead
+checked against the manifest, reporting missing, extra and modified files,
+such as to validate a restored backup or to detect silent corruption on cold
+storage.
+
+OPTIONS
+
+   --dir
+      The directory described by the manifest, searched recursively.
+
+   --manifest
+      The path of the JSON manifest. It is skipped when it is in the
+      directory.
+
+   --verify
+      Verify the directory against the manifest instead of writing it. The
+      command fails if any difference is found.
+`,
+		Args: cobra.NoArgs,
+		RunE: checksumManifestF,
+	}
+
+	dir, err := fs.InfluxDir()
+	if err != nil {
+		panic(err)
+	}
+	cmd.Flags().StringVar(&checksumManifestFlags.dir, "dir", filepath.Join(dir, "engine"), "directory described by the manifest")
+	cmd.Flags().StringVar(&checksumManifestFlags.manifest, "manifest", "", "path of the manifest (required)")
+	cmd.Flags().BoolVar(&checksumManifestFlags.verify, "verify", false, "verify the directory against the manifest")
+	cmd.MarkFlagRequired("manifest")
+
+	return cmd
+}
+
+func checksumManifestF(cmd *cobra.Command, args []string) error {
+	manifest := checksummanifest.NewCommand()
+	manifest.Dir = checksumManifestFlags.dir
+	manifest.Manifest = checksumManifestFlags.manifest
+	manifest.Verify = checksumManifestFlags.verify
+	return manifest.Run()
+}
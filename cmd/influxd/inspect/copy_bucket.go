@@ -0,0 +1,84 @@
+package inspect
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/copybucket"
+	"github.com/influxdata/influxdb/internal/fs"
+	"github.com/influxdata/influxdb/kit/cli"
+	"github.com/spf13/cobra"
+)
+
+// copyBucketFlags defines the `copy-bucket` Command.
+var copyBucketFlags = struct {
+	dataDir        string
+	sourceOrgID    influxdb.ID
+	sourceBucketID influxdb.ID
+	targetOrgID    influxdb.ID
+	targetBucketID influxdb.ID
+	move           bool
+}{}
+
+// NewCopyBucketCommand returns a new instance of the copy-bucket command.
+func NewCopyBucketCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "copy-bucket",
+		Short: "Copies or moves the data of a bucket to another bucket",
+		Long: `
+This command will copy the data of a bucket to another bucket, by writing the
+series of the source bucket to new TSM files of the data directory under the
+keys of the target bucket, so that data can be reclassified without exporting
+and writing it again as line protocol. Blocks are copied as is, unless they
+have tombstoned values, which are dropped. The server must not be running.
+
+OPTIONS
+
+   --data-dir
+      The data directory of the engine, holding the TSM files.
+
+   --source-org-id, --source-bucket-id
+      The organization and bucket the data is copied from.
+
+   --target-org-id, --target-bucket-id
+      The organization and bucket the data is copied to. The bucket must be
+      created beforehand, such as with 'influx bucket create', since its
+      metadata is not stored with the TSM files.
+
+   --move
+      Tombstone the series of the source bucket once they are copied.
+
+The index does not hold the series of the new files until it is updated, such
+as with 'influxd inspect build-tsi --incremental', or rebuilt with build-tsi
+after a move.
+`,
+		Args: cobra.NoArgs,
+		RunE: copyBucketF,
+	}
+
+	dir, err := fs.InfluxDir()
+	if err != nil {
+		panic(err)
+	}
+	dir = filepath.Join(dir, "engine/data")
+	cmd.Flags().StringVar(&copyBucketFlags.dataDir, "data-dir", dir, fmt.Sprintf("data directory of the engine (defaults to %s)", dir))
+	cli.IDVar(cmd.Flags(), &copyBucketFlags.sourceOrgID, "source-org-id", influxdb.InvalidID(), "organization id of the bucket the data is copied from (required)")
+	cli.IDVar(cmd.Flags(), &copyBucketFlags.sourceBucketID, "source-bucket-id", influxdb.InvalidID(), "bucket id of the bucket the data is copied from (required)")
+	cli.IDVar(cmd.Flags(), &copyBucketFlags.targetOrgID, "target-org-id", influxdb.InvalidID(), "organization id of the bucket the data is copied to (required)")
+	cli.IDVar(cmd.Flags(), &copyBucketFlags.targetBucketID, "target-bucket-id", influxdb.InvalidID(), "bucket id of the bucket the data is copied to (required)")
+	cmd.Flags().BoolVar(&copyBucketFlags.move, "move", false, "tombstone the series of the source bucket once they are copied")
+
+	return cmd
+}
+
+func copyBucketF(cmd *cobra.Command, args []string) error {
+	copier := copybucket.NewCommand()
+	copier.DataDir = copyBucketFlags.dataDir
+	copier.SourceOrgID = copyBucketFlags.sourceOrgID
+	copier.SourceBucketID = copyBucketFlags.sourceBucketID
+	copier.TargetOrgID = copyBucketFlags.targetOrgID
+	copier.TargetBucketID = copyBucketFlags.targetBucketID
+	copier.Move = copyBucketFlags.move
+	return copier.Run()
+}
@@ -0,0 +1,58 @@
+package inspect
+
+import (
+	"github.com/influxdata/influxdb/cmd/influx_inspect/dedupetsm"
+	"github.com/spf13/cobra"
+)
+
+// dedupeTSMFlags defines the `dedupe-tsm` Command.
+var dedupeTSMFlags = struct {
+	policy  string
+	dryRun  bool
+	verbose bool
+}{}
+
+// NewDedupeTSMCommand returns a new instance of the dedupe-tsm command.
+func NewDedupeTSMCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dedupe-tsm <pathspec>...",
+		Short: "Collapses the duplicate points of the TSM files of a shard",
+		Long: `
+This command will detect the duplicate points of the TSM files of each
+directory, that is the values of a key at the same timestamp held by
+overlapping blocks within a file or across files, and collapse them into a
+single value. The files of a directory with duplicates are rewritten into new
+files of a later generation, which replace them along with their tombstone
+files; tombstoned values are dropped. The storage engine must not be running.
+
+OPTIONS
+
+   <pathspec>...
+      A list of directories searched recursively for TSM files, such as the
+      data directory of the engine. The files of each directory are
+      deduplicated together.
+
+Use --policy to choose the value kept among duplicates: "newest" keeps the
+value of the latest generation, as the storage engine does, and "oldest" keeps
+the value of the earliest one. Use --dry-run to report the duplicates without
+rewriting the files, and --verbose to report the duplicates of each key.
+`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: dedupeTSMF,
+	}
+
+	cmd.Flags().StringVar(&dedupeTSMFlags.policy, "policy", dedupetsm.PolicyNewest, "value kept among duplicates: newest or oldest")
+	cmd.Flags().BoolVar(&dedupeTSMFlags.dryRun, "dry-run", false, "report the duplicates without rewriting the files")
+	cmd.Flags().BoolVarP(&dedupeTSMFlags.verbose, "verbose", "v", false, "report the duplicates of each key")
+
+	return cmd
+}
+
+func dedupeTSMF(cmd *cobra.Command, args []string) error {
+	deduper := dedupetsm.NewCommand()
+	deduper.Paths = args
+	deduper.Policy = dedupeTSMFlags.policy
+	deduper.DryRun = dedupeTSMFlags.dryRun
+	deduper.Verbose = dedupeTSMFlags.verbose
+	return deduper.Run()
+}
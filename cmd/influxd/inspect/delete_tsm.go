@@ -0,0 +1,229 @@
+package inspect
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/deletetsm"
+	"github.com/influxdata/influxdb/kit/cli"
+	"github.com/spf13/cobra"
+)
+
+// deleteTSMFlags defines the `delete-tsm` Command.
+var deleteTSMFlags = struct {
+	cli.OrgBucket
+	measurements []string
+	seriesFile   string
+	unmatched    string
+	field        string
+	where        string
+	start        string
+	end          string
+	sanitize     string
+	dryRun       bool
+	verbose      bool
+	concurrency  int
+	format       string
+	report       string
+	backup       string
+	restore      bool
+	walDir       string
+	tombstone    bool
+	verify       bool
+	checkpoint   string
+	resume       bool
+	sfilePath    string
+	tsiPath      string
+}{}
+
+// NewDeleteTSMCommand returns a new instance of the delete-tsm command.
+func NewDeleteTSMCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "delete-tsm [<pathspec>...]",
+		Short: "Deletes series from TSM files",
+		Long: `
+This command will rewrite a set of TSM files, dropping the blocks of every
+series of a measurement, of every series listed in a series file, or of every
+series whose key contains invalid UTF-8 or non-printable characters. The
+storage engine must not be running.
+
+A file whose blocks are all deleted is removed.
+
+OPTIONS
+
+   <pathspec>...
+      A list of TSM files, or of directories searched recursively for TSM
+      files, such as the data directory of the engine.
+
+An optional organization or organization and bucket may be specified to limit
+the deletion.
+
+Use --measurement, which may be repeated, to delete every series of the named
+measurements. The blocks are selected by their key alone, without reading the
+series file or listing the series first, which is the quickest way to drop
+whole measurements.
+
+Each line of the file given by --series-file is either an exact series key,
+such as cpu,host=web-1, a regular expression prefixed with regex:, such as
+regex:^cpu,host=web-\d+, or a glob pattern prefixed with glob:, such as
+glob:cpu,host=web-*. Blank lines and lines starting with # are ignored. A key
+or pattern followed by #!~# and a field name, such as cpu,host=web-1#!~#usage,
+only matches that field of the series. Use --series-file - to read the series
+from stdin. Gzip compressed series files, and series piped in gzip compressed,
+are decompressed transparently.
+
+At the end of a run, the keys and patterns of the series file that matched no
+series of the processed files, such as a key with a typo in a tag value, are
+printed, or written one per line to the file given by --unmatched. The
+command exits with status 2 when none of them matched a series.
+
+Use --field to only delete the values of that field of the matching series,
+such as a field written with the wrong type, leaving their other fields
+untouched.
+
+Use --where with a predicate on the tags of the series, such as
+'host=web01 AND region=us-east', to only delete the series selected by the
+other options whose tags match it, or every matching series when no other
+option is given. Tag comparisons use = or != and are combined with AND.
+
+Use --start and --end, as RFC3339 timestamps, to only delete the values of the
+matching series within that time range. Blocks partially within the range are
+decoded, trimmed and re-encoded.
+
+Use --concurrency to rewrite several files at once. Files are still reported
+in order, and processing stops at the first file that fails.
+
+Use --dry-run to report the blocks and series that would be deleted, with
+their size and time range, without rewriting any file.
+
+Use --format json to print a JSON report instead of the text summary, or
+--report to write that report to a file. It lists, for each file, the series
+matched, the blocks deleted and trimmed, the bytes reclaimed, the time range
+deleted and the processing duration.
+
+Use --backup to save each TSM file, with its statistics and tombstone files,
+to a directory before it is replaced or removed. Files are hard linked when
+possible, and copied otherwise. Files already saved to the directory by a
+previous run are kept. Use --restore with the same --backup directory, and no
+pathspec, to put back the saved files and undo the runs that used it.
+
+Use --wal-dir with the WAL directory of the engine to also remove the values of
+the matching series from its WAL segments, so that they are not written back
+to TSM files when the WAL is replayed. Segments are rewritten after the TSM
+files, and saved to the --backup directory first. The pathspec may be omitted
+to only process the WAL.
+
+Use --verify to re-read each rewritten file before it replaces the original,
+checking the CRC-32 checksums of its blocks, that none of the values to delete
+remain, and that the blocks of every other series are identical to those of
+the original file. The original file is left untouched if verification fails.
+
+Use --sfile-path and --tsi-path with the series file and TSI index directories
+of the engine to also drop the series left without any value from them, so
+that cardinality reports are correct without rebuilding the index. A series
+is kept if any TSM file in the directories of the processed files, or any
+segment of the --wal-dir directory, still holds a value of it.
+
+Each file processed is recorded in the checkpoint file given by --checkpoint,
+.deletetsm.progress in the working directory by default, which is removed
+once every file is processed. If a run is interrupted, run the command again
+with the same options and --resume to skip the files already processed.
+
+Use --sanitize, or --sanitize=drop, to delete every series whose key contains
+invalid UTF-8 or non-printable characters, such as keys written by old
+clients, or --sanitize=escape to rename them instead, with their invalid bytes
+escaped as \xNN and their non-printable characters as \uNNNN, keeping their
+values. Every key dropped or escaped is reported. Escaping can not be combined
+with --field, --where, --start, --end or --tombstone, and fails if an escaped
+key already exists. Rebuild the index with build-tsi afterwards for the
+escaped series to be indexed.
+
+Use --tombstone to record tombstones for the deleted values instead of
+rewriting the TSM files, so that no free space is needed for a rewritten copy
+of each file. The space is reclaimed by the next compaction of the files once
+the storage engine is running again. When only --measurement and a bucket are
+given, a single tombstone is recorded for each measurement in each file.
+`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if deleteTSMFlags.restore {
+				return cobra.NoArgs(cmd, args)
+			}
+			if deleteTSMFlags.walDir != "" {
+				return nil
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		},
+		RunE: deleteTSMF,
+	}
+
+	deleteTSMFlags.AddFlags(cmd)
+	cmd.Flags().StringArrayVar(&deleteTSMFlags.measurements, "measurement", nil, "the name of a measurement to delete, may be repeated")
+	cmd.Flags().StringVar(&deleteTSMFlags.seriesFile, "series-file", "", "path of a file listing the series keys or patterns to delete, or - for stdin")
+	cmd.Flags().StringVar(&deleteTSMFlags.unmatched, "unmatched", "", "path of a file to write the keys and patterns of the series file that matched no series to")
+	cmd.Flags().StringVar(&deleteTSMFlags.field, "field", "", "only delete this field of the matching series")
+	cmd.Flags().StringVar(&deleteTSMFlags.where, "where", "", "predicate on the tags of the series to delete, such as 'host=web01 AND region=us-east'")
+	cmd.Flags().StringVar(&deleteTSMFlags.start, "start", "", "only delete values at or after this RFC3339 time")
+	cmd.Flags().StringVar(&deleteTSMFlags.end, "end", "", "only delete values at or before this RFC3339 time")
+	cmd.Flags().StringVar(&deleteTSMFlags.sanitize, "sanitize", "", "drop, or escape, all series with keys containing invalid UTF-8 or non-printable characters")
+	cmd.Flags().Lookup("sanitize").NoOptDefVal = deletetsm.SanitizeDrop
+	cmd.Flags().BoolVar(&deleteTSMFlags.dryRun, "dry-run", false, "report what would be deleted without rewriting any file")
+	cmd.Flags().IntVar(&deleteTSMFlags.concurrency, "concurrency", 1, "number of files to rewrite concurrently")
+	cmd.Flags().StringVar(&deleteTSMFlags.format, "format", deletetsm.TextFormat, "format of the summary, text or json")
+	cmd.Flags().StringVar(&deleteTSMFlags.report, "report", "", "path of a file to write the JSON report to")
+	cmd.Flags().StringVar(&deleteTSMFlags.backup, "backup", "", "directory to save the original TSM files to before rewriting them")
+	cmd.Flags().BoolVar(&deleteTSMFlags.restore, "restore", false, "put back the TSM files saved to the backup directory")
+	cmd.Flags().StringVar(&deleteTSMFlags.walDir, "wal-dir", "", "WAL directory of the engine to also delete the series from")
+	cmd.Flags().BoolVar(&deleteTSMFlags.tombstone, "tombstone", false, "record tombstones instead of rewriting the TSM files")
+	cmd.Flags().BoolVar(&deleteTSMFlags.verify, "verify", false, "verify each rewritten file before it replaces the original")
+	cmd.Flags().StringVar(&deleteTSMFlags.sfilePath, "sfile-path", "", "path to the series file directory to drop the deleted series from")
+	cmd.Flags().StringVar(&deleteTSMFlags.tsiPath, "tsi-path", "", "path to the TSI index directory to drop the deleted series from")
+	cmd.Flags().StringVar(&deleteTSMFlags.checkpoint, "checkpoint", deletetsm.DefaultCheckpointName, "path of the file recording the TSM files processed")
+	cmd.Flags().BoolVar(&deleteTSMFlags.resume, "resume", false, "skip the TSM files recorded in the checkpoint file by an interrupted run")
+	cmd.Flags().BoolVarP(&deleteTSMFlags.verbose, "verbose", "v", false, "report every deleted block")
+
+	return cmd
+}
+
+func deleteTSMF(cmd *cobra.Command, args []string) error {
+	deleter := deletetsm.NewCommand()
+	deleter.OrgID, deleter.BucketID = deleteTSMFlags.OrgBucketID()
+	deleter.Measurements = deleteTSMFlags.measurements
+	deleter.SeriesFile = deleteTSMFlags.seriesFile
+	deleter.UnmatchedPath = deleteTSMFlags.unmatched
+	deleter.Where = deleteTSMFlags.where
+	deleter.Field = deleteTSMFlags.field
+	deleter.Sanitize = deleteTSMFlags.sanitize != ""
+	deleter.SanitizeMode = deleteTSMFlags.sanitize
+	deleter.DryRun = deleteTSMFlags.dryRun
+	deleter.Verbose = deleteTSMFlags.verbose
+	deleter.Concurrency = deleteTSMFlags.concurrency
+	deleter.Format = deleteTSMFlags.format
+	deleter.ReportPath = deleteTSMFlags.report
+	deleter.BackupDir = deleteTSMFlags.backup
+	deleter.Restore = deleteTSMFlags.restore
+	deleter.WALDir = deleteTSMFlags.walDir
+	deleter.Tombstone = deleteTSMFlags.tombstone
+	deleter.Verify = deleteTSMFlags.verify
+	deleter.Checkpoint = deleteTSMFlags.checkpoint
+	deleter.SeriesFilePath = deleteTSMFlags.sfilePath
+	deleter.IndexPath = deleteTSMFlags.tsiPath
+	deleter.Resume = deleteTSMFlags.resume
+	deleter.Paths = args
+
+	if deleteTSMFlags.start != "" {
+		t, err := time.Parse(time.RFC3339Nano, deleteTSMFlags.start)
+		if err != nil {
+			return fmt.Errorf("invalid start time: %v", err)
+		}
+		deleter.Start = t
+	}
+	if deleteTSMFlags.end != "" {
+		t, err := time.Parse(time.RFC3339Nano, deleteTSMFlags.end)
+		if err != nil {
+			return fmt.Errorf("invalid end time: %v", err)
+		}
+		deleter.End = t
+	}
+
+	return deleter.Run()
+}
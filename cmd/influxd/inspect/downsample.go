@@ -0,0 +1,99 @@
+package inspect
+
+import (
+	"os"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx_inspect/downsample"
+	"github.com/influxdata/influxdb/kit/cli"
+	"github.com/spf13/cobra"
+)
+
+// downsampleFlags defines the `downsample` Command.
+var downsampleFlags = struct {
+	cli.OrgBucket
+	every          time.Duration
+	aggregates     []string
+	format         string
+	outputDir      string
+	output         string
+	generation     int
+	targetOrgID    influxdb.ID
+	targetBucketID influxdb.ID
+}{}
+
+// NewDownsampleCommand returns a new instance of the downsample command.
+func NewDownsampleCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "downsample <pathspec>...",
+		Short: "Aggregates TSM files into lower-resolution rollups",
+		Long: `
+This command will aggregate the series of a set of TSM files into rollups of a
+lower resolution, without loading the server: the values of each field of
+each series are aggregated over windows of --every, aligned on the epoch, and
+each aggregate is written as a new field named <field>_<aggregate>, at the
+start time of its window. The values of a series found in several files are
+merged as by a compaction, and tombstoned values are dropped.
+
+OPTIONS
+
+   <pathspec>...
+      A list of TSM files, or of directories searched recursively for TSM
+      files, such as the data directory of the engine. The files are merged
+      in the order given, then in the order of their names.
+
+An optional organization or organization and bucket may be specified to limit
+the rollups. Use --aggregate, which may be repeated, to compute the mean, the
+default, min, max or count of each window. The mean, min and max are only
+computed for numeric fields, the count for any field.
+
+With --format tsm, the default, the rollups are written as TSM files named
+<generation>-000000001.tsm to --output-dir, starting at --generation, and
+optionally renamed to the bucket of --target-org-id and --target-bucket-id,
+which requires a bucket. Once copied to the data directory of an engine, the
+index must be rebuilt with build-tsi for the series to be queried. With
+--format lp, the rollups are written as line protocol to --output, or to the
+standard output.
+`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: downsampleF,
+	}
+
+	downsampleFlags.AddFlags(cmd)
+	cmd.Flags().DurationVar(&downsampleFlags.every, "every", 0, "duration of the windows (required)")
+	cmd.Flags().StringArrayVar(&downsampleFlags.aggregates, "aggregate", nil, "aggregate of each window, mean, min, max or count, may be repeated")
+	cmd.Flags().StringVar(&downsampleFlags.format, "format", downsample.FormatTSM, "format of the rollups, tsm or lp")
+	cmd.Flags().StringVar(&downsampleFlags.outputDir, "output-dir", "", "directory to write the TSM files to")
+	cmd.Flags().StringVar(&downsampleFlags.output, "output", "", "file to write the line protocol to, the standard output by default")
+	cmd.Flags().IntVar(&downsampleFlags.generation, "generation", 1, "generation of the first TSM file written")
+	cli.IDVar(cmd.Flags(), &downsampleFlags.targetOrgID, "target-org-id", influxdb.InvalidID(), "organization id of the bucket the TSM files are written to")
+	cli.IDVar(cmd.Flags(), &downsampleFlags.targetBucketID, "target-bucket-id", influxdb.InvalidID(), "bucket id of the bucket the TSM files are written to")
+	cmd.MarkFlagRequired("every")
+
+	return cmd
+}
+
+func downsampleF(cmd *cobra.Command, args []string) error {
+	downsampler := downsample.NewCommand()
+	downsampler.Paths = args
+	downsampler.OrgID, downsampler.BucketID = downsampleFlags.OrgBucketID()
+	downsampler.Every = downsampleFlags.every
+	downsampler.Aggregates = downsampleFlags.aggregates
+	downsampler.Format = downsampleFlags.format
+	downsampler.OutputDir = downsampleFlags.outputDir
+	downsampler.Generation = downsampleFlags.generation
+	downsampler.TargetOrgID = downsampleFlags.targetOrgID
+	downsampler.TargetBucketID = downsampleFlags.targetBucketID
+
+	if downsampleFlags.output != "" {
+		f, err := os.Create(downsampleFlags.output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		downsampler.Output = f
+	}
+
+	return downsampler.Run()
+}
@@ -0,0 +1,78 @@
+package inspect
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/dumpseriesfile"
+	"github.com/influxdata/influxdb/internal/fs"
+	"github.com/influxdata/influxdb/kit/cli"
+	"github.com/spf13/cobra"
+)
+
+// dumpSeriesFileFlags defines the `dump-series-file` Command.
+var dumpSeriesFileFlags = struct {
+	cli.OrgBucket
+	seriesFilePath string
+	measurement    string
+	summary        bool
+	checkIndex     bool
+	indexPath      string
+}{}
+
+// NewDumpSeriesFileCommand returns a new instance of the dump-series-file command.
+func NewDumpSeriesFileCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dump-series-file",
+		Short: "Dumps the entries of the segments of the series file",
+		Long: `
+This command will dump the entries of the segments of the series file,
+partition by partition and in the order they were written: the insertions of
+series keys, with their series ID and type, and the tombstones of deleted
+series. The segments are read directly, so that a corrupt series file can be
+dumped.
+
+An optional organization or organization and bucket may be specified to limit
+the dump, and --measurement a regular expression matching the measurements of
+the series to dump. Use --summary to only report the number of entries of each
+partition.
+
+Use --check-index to report the series IDs of the TSI index which are missing
+from the series file or deleted from it.
+`,
+		Args: cobra.NoArgs,
+		RunE: dumpSeriesFileF,
+	}
+
+	dir, err := fs.InfluxDir()
+	if err != nil {
+		panic(err)
+	}
+	dumpSeriesFileFlags.AddFlags(cmd)
+	cmd.Flags().StringVar(&dumpSeriesFileFlags.seriesFilePath, "series-file", filepath.Join(dir, "engine", "_series"), fmt.Sprintf("path to the series file (defaults to %s)", filepath.Join(dir, "engine", "_series")))
+	cmd.Flags().StringVar(&dumpSeriesFileFlags.measurement, "measurement", "", "regular expression matching the measurements of the series to dump")
+	cmd.Flags().BoolVar(&dumpSeriesFileFlags.summary, "summary", false, "only report the number of entries of each partition")
+	cmd.Flags().BoolVar(&dumpSeriesFileFlags.checkIndex, "check-index", false, "report the series of the index missing from the series file")
+	cmd.Flags().StringVar(&dumpSeriesFileFlags.indexPath, "index-path", filepath.Join(dir, "engine", "index"), fmt.Sprintf("path to the index checked with --check-index (defaults to %s)", filepath.Join(dir, "engine", "index")))
+
+	return cmd
+}
+
+func dumpSeriesFileF(cmd *cobra.Command, args []string) error {
+	dumper := dumpseriesfile.NewCommand()
+	dumper.Path = dumpSeriesFileFlags.seriesFilePath
+	dumper.OrgID, dumper.BucketID = dumpSeriesFileFlags.OrgBucketID()
+	dumper.SummaryOnly = dumpSeriesFileFlags.summary
+	if dumpSeriesFileFlags.measurement != "" {
+		re, err := regexp.Compile(dumpSeriesFileFlags.measurement)
+		if err != nil {
+			return fmt.Errorf("invalid measurement regular expression: %v", err)
+		}
+		dumper.Measurement = re
+	}
+	if dumpSeriesFileFlags.checkIndex {
+		dumper.IndexPath = dumpSeriesFileFlags.indexPath
+	}
+	return dumper.Run()
+}
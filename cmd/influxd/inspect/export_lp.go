@@ -0,0 +1,87 @@
+package inspect
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/exportlp"
+	"github.com/influxdata/influxdb/kit/cli"
+	"github.com/spf13/cobra"
+)
+
+// exportLPFlags defines the `export-lp` Command.
+var exportLPFlags = struct {
+	cli.OrgBucket
+	measurements []string
+	where        string
+	start        string
+	end          string
+	output       string
+}{}
+
+// NewExportLPCommand returns a new instance of the export-lp command.
+func NewExportLPCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export-lp <pathspec>...",
+		Short: "Exports TSM files as line protocol",
+		Long: `
+This command will export the series of a set of TSM files as line protocol,
+with a line for each value of each field. The series to export are selected
+from the keys of the TSM indexes, so that the blocks of the other series are
+never decoded. A comment naming the organization and bucket ID precedes the
+lines of each bucket.
+
+OPTIONS
+
+   <pathspec>...
+      A list of TSM files, or of directories searched recursively for TSM
+      files, such as shard directories or the whole data directory.
+
+An optional organization or organization and bucket may be specified to limit
+the export. Use --measurement, which may be repeated, to only export the named
+measurements, --where to only export the series whose tags match a predicate,
+such as 'datacenter=eu AND env=prod', and --start and --end, as RFC3339
+timestamps, to only export the values within that time range.
+
+The line protocol is written to the standard output, and the summary of the
+export to the standard error, unless --output names a file to write it to.
+`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: exportLPF,
+	}
+
+	exportLPFlags.AddFlags(cmd)
+	cmd.Flags().StringArrayVar(&exportLPFlags.measurements, "measurement", nil, "the name of a measurement to export, may be repeated")
+	cmd.Flags().StringVar(&exportLPFlags.where, "where", "", "predicate on the tags of the series to export")
+	cmd.Flags().StringVar(&exportLPFlags.start, "start", "", "only export values at or after this RFC3339 time")
+	cmd.Flags().StringVar(&exportLPFlags.end, "end", "", "only export values at or before this RFC3339 time")
+	cmd.Flags().StringVar(&exportLPFlags.output, "output", "", "file to write the line protocol to, instead of the standard output")
+
+	return cmd
+}
+
+func exportLPF(cmd *cobra.Command, args []string) error {
+	exporter := exportlp.NewCommand()
+	exporter.OrgID, exporter.BucketID = exportLPFlags.OrgBucketID()
+	exporter.Measurements = exportLPFlags.measurements
+	exporter.Where = exportLPFlags.where
+	exporter.Output = exportLPFlags.output
+	exporter.Paths = args
+
+	if exportLPFlags.start != "" {
+		t, err := time.Parse(time.RFC3339Nano, exportLPFlags.start)
+		if err != nil {
+			return fmt.Errorf("invalid start time: %v", err)
+		}
+		exporter.Start = t
+	}
+	if exportLPFlags.end != "" {
+		t, err := time.Parse(time.RFC3339Nano, exportLPFlags.end)
+		if err != nil {
+			return fmt.Errorf("invalid end time: %v", err)
+		}
+		exporter.End = t
+	}
+
+	return exporter.Run()
+}
@@ -0,0 +1,116 @@
+package inspect
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/exportparquet"
+	"github.com/influxdata/influxdb/kit/cli"
+	"github.com/spf13/cobra"
+)
+
+// exportParquetFlags defines the `export-parquet` Command.
+var exportParquetFlags = struct {
+	cli.OrgBucket
+	measurements []string
+	start        string
+	end          string
+	since        string
+	stateFile    string
+	outputDir    string
+	compression  string
+	rowGroupSize int
+}{}
+
+// NewExportParquetCommand returns a new instance of the export-parquet command.
+func NewExportParquetCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export-parquet <pathspec>...",
+		Short: "Exports TSM files to Parquet files",
+		Long: `
+This command will export the series of a set of TSM files to Parquet files,
+one per measurement of each bucket, so that historical data can be loaded into
+data lakes and analytics tools without running queries against the server.
+
+The files are written to <output-dir>/<org ID>/<bucket ID>/<measurement>.parquet.
+Each row holds the values of the fields of a series at a time, with a time
+column of nanosecond timestamps, a dictionary encoded string column for each
+tag key of the measurement and a column for each of its fields. Tags missing
+from a series and fields without a value at a time are null. A field with the
+same name as a tag is exported as <field>_field. Values of a field whose type
+differs between series are skipped, and counted in a warning.
+
+OPTIONS
+
+   <pathspec>...
+      A list of TSM files, or of directories searched recursively for TSM
+      files, such as shard directories or the whole data directory.
+
+An optional organization or organization and bucket may be specified to limit
+the export. Use --measurement, which may be repeated, to only export the named
+measurements, and --start and --end, as RFC3339 timestamps, to only export the
+values within that time range.
+
+Use --since, as an RFC3339 timestamp, to only export the blocks of the TSM files
+whose maximum time is after it, or --state-file to export incrementally from
+cron: the maximum time of the exported blocks is written to the state file, and
+the next export only exports the blocks newer than it. Whole blocks are
+exported, so values compacted into a newer block are exported again. The files
+of incremental exports are named <measurement>-<since>.parquet, with since in
+nanoseconds since the epoch, and --end can not be used with them.
+
+Use --compression to compress the pages of the files with snappy, the
+default, gzip, or none, and --row-group-size to set the maximum number of rows
+of each row group.
+`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: exportParquetF,
+	}
+
+	exportParquetFlags.AddFlags(cmd)
+	cmd.Flags().StringArrayVar(&exportParquetFlags.measurements, "measurement", nil, "the name of a measurement to export, may be repeated")
+	cmd.Flags().StringVar(&exportParquetFlags.start, "start", "", "only export values at or after this RFC3339 time")
+	cmd.Flags().StringVar(&exportParquetFlags.end, "end", "", "only export values at or before this RFC3339 time")
+	cmd.Flags().StringVar(&exportParquetFlags.since, "since", "", "only export the blocks whose maximum time is after this RFC3339 time")
+	cmd.Flags().StringVar(&exportParquetFlags.stateFile, "state-file", "", "file holding the maximum time of the blocks exported so far, for incremental exports")
+	cmd.Flags().StringVar(&exportParquetFlags.outputDir, "output-dir", "", "directory to write the Parquet files to")
+	cmd.Flags().StringVar(&exportParquetFlags.compression, "compression", exportparquet.CompressionSnappy, "compression of the Parquet files, snappy, gzip or none")
+	cmd.Flags().IntVar(&exportParquetFlags.rowGroupSize, "row-group-size", exportparquet.DefaultRowGroupSize, "maximum number of rows of each row group")
+
+	return cmd
+}
+
+func exportParquetF(cmd *cobra.Command, args []string) error {
+	exporter := exportparquet.NewCommand()
+	exporter.OrgID, exporter.BucketID = exportParquetFlags.OrgBucketID()
+	exporter.Measurements = exportParquetFlags.measurements
+	exporter.StateFile = exportParquetFlags.stateFile
+	exporter.OutputDir = exportParquetFlags.outputDir
+	exporter.Compression = exportParquetFlags.compression
+	exporter.RowGroupSize = exportParquetFlags.rowGroupSize
+	exporter.Paths = args
+
+	if exportParquetFlags.start != "" {
+		t, err := time.Parse(time.RFC3339Nano, exportParquetFlags.start)
+		if err != nil {
+			return fmt.Errorf("invalid start time: %v", err)
+		}
+		exporter.Start = t
+	}
+	if exportParquetFlags.end != "" {
+		t, err := time.Parse(time.RFC3339Nano, exportParquetFlags.end)
+		if err != nil {
+			return fmt.Errorf("invalid end time: %v", err)
+		}
+		exporter.End = t
+	}
+	if exportParquetFlags.since != "" {
+		t, err := time.Parse(time.RFC3339Nano, exportParquetFlags.since)
+		if err != nil {
+			return fmt.Errorf("invalid since time: %v", err)
+		}
+		exporter.Since = t
+	}
+
+	return exporter.Run()
+}
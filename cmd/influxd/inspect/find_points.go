@@ -0,0 +1,98 @@
+package inspect
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/findpoints"
+	"github.com/influxdata/influxdb/kit/cli"
+	"github.com/spf13/cobra"
+)
+
+// findPointsFlags defines the `find-points` Command.
+var findPointsFlags = struct {
+	cli.OrgBucket
+	key   string
+	field string
+	time  string
+	start string
+	end   string
+}{}
+
+// NewFindPointsCommand returns a new instance of the find-points command.
+func NewFindPointsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "find-points <pathspec>...",
+		Short: "Locates the raw points of a series in TSM files and WAL segments",
+		Long: `
+This command searches TSM files and WAL segments for the points of a series,
+to debug where a point comes from or why it is missing. For each block of a
+TSM file and each write entry of a WAL segment holding points of the series,
+it prints its location and the decoded values. Values deleted by a tombstone
+are flagged as deleted, and the tombstones of the series are listed.
+
+OPTIONS
+
+   <pathspec>...
+      A list of TSM files and WAL segments, or of directories searched
+      recursively for them, such as the engine directory.
+
+The series is given by --key in the measurement,tag=value form, such as
+cpu,host=web-1, with tags in any order. A key followed by #!~# and a field
+name, such as cpu,host=web-1#!~#usage, or --field, only matches that field.
+
+An optional organization or organization and bucket may be specified to limit
+the search.
+
+Use --time with an RFC3339 timestamp to find the points at that time, or
+--start and --end to find the points within that time range.
+`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: findPointsF,
+	}
+
+	findPointsFlags.AddFlags(cmd)
+	cmd.Flags().StringVar(&findPointsFlags.key, "key", "", "key of the series, such as cpu,host=web-1")
+	cmd.Flags().StringVar(&findPointsFlags.field, "field", "", "only find the points of this field of the series")
+	cmd.Flags().StringVar(&findPointsFlags.time, "time", "", "only find the points at this RFC3339 time")
+	cmd.Flags().StringVar(&findPointsFlags.start, "start", "", "only find points at or after this RFC3339 time")
+	cmd.Flags().StringVar(&findPointsFlags.end, "end", "", "only find points at or before this RFC3339 time")
+
+	return cmd
+}
+
+func findPointsF(cmd *cobra.Command, args []string) error {
+	finder := findpoints.NewCommand()
+	finder.OrgID, finder.BucketID = findPointsFlags.OrgBucketID()
+	finder.SeriesKey = findPointsFlags.key
+	finder.Field = findPointsFlags.field
+	finder.Paths = args
+
+	if findPointsFlags.time != "" {
+		if findPointsFlags.start != "" || findPointsFlags.end != "" {
+			return errors.New("time can not be combined with start or end")
+		}
+		t, err := time.Parse(time.RFC3339Nano, findPointsFlags.time)
+		if err != nil {
+			return fmt.Errorf("invalid time: %v", err)
+		}
+		finder.Start, finder.End = t, t
+	}
+	if findPointsFlags.start != "" {
+		t, err := time.Parse(time.RFC3339Nano, findPointsFlags.start)
+		if err != nil {
+			return fmt.Errorf("invalid start time: %v", err)
+		}
+		finder.Start = t
+	}
+	if findPointsFlags.end != "" {
+		t, err := time.Parse(time.RFC3339Nano, findPointsFlags.end)
+		if err != nil {
+			return fmt.Errorf("invalid end time: %v", err)
+		}
+		finder.End = t
+	}
+
+	return finder.Run()
+}
@@ -14,9 +14,29 @@ func NewCommand() *cobra.Command {
 	// List of available sub-commands
 	// If a new sub-command is created, it must be added here
 	subCommands := []*cobra.Command{
+		NewAnonymizeCommand(),
 		NewBuildTSICommand(),
+		NewChecksumManifestCommand(),
+		NewCopyBucketCommand(),
+		NewDedupeTSMCommand(),
+		NewDeleteTSMCommand(),
+		NewDownsampleCommand(),
+		NewFindPointsCommand(),
+		NewMergeTSMCommand(),
+		NewRebuildShardCommand(),
+		NewRepairTSMCommand(),
+		NewShiftTimestampsCommand(),
+		NewSplitTSMCommand(),
+		NewTSMDiffCommand(),
+		NewTSMStatsCommand(),
+		NewTombstonesCommand(),
+		NewTruncateShardsCommand(),
 		NewExportBlocksCommand(),
 		NewExportIndexCommand(),
+		NewExportLPCommand(),
+		NewExportParquetCommand(),
+		NewReportCardinalityCommand(),
+		NewReportDiskCommand(),
 		NewReportTSMCommand(),
 		NewVerifyTSMCommand(),
 		NewVerifyWALCommand(),
@@ -24,6 +44,7 @@ func NewCommand() *cobra.Command {
 		NewVerifySeriesFileCommand(),
 		NewDumpWALCommand(),
 		NewDumpTSICommand(),
+		NewDumpSeriesFileCommand(),
 	}
 
 	base.AddCommand(subCommands...)
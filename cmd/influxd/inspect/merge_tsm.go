@@ -0,0 +1,55 @@
+package inspect
+
+import (
+	"github.com/influxdata/influxdb/cmd/influx_inspect/mergetsm"
+	"github.com/spf13/cobra"
+)
+
+// mergeTSMFlags defines the `merge-tsm` Command.
+var mergeTSMFlags = struct {
+	maxFiles int
+	dryRun   bool
+}{}
+
+// NewMergeTSMCommand returns a new instance of the merge-tsm command.
+func NewMergeTSMCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "merge-tsm <pathspec>...",
+		Short: "Merges the TSM files of a shard into fewer files",
+		Long: `
+This command will merge the TSM files of each directory into fewer, fully
+compacted files, as a full compaction of the storage engine would, so that the
+many small files left by bulk imports do not wait for the online compactor.
+Overlapping blocks are deduplicated, the values of later generations
+overwriting those of earlier ones, and tombstoned values are dropped along
+with their tombstone files. The storage engine must not be running.
+
+OPTIONS
+
+   <pathspec>...
+      A list of directories searched recursively for TSM files, such as the
+      data directory of the engine. The files of each directory are merged
+      together.
+
+Use --max-files to merge at most that many files at once, bounding memory on
+directories with thousands of files: consecutive generations are merged by
+groups until all files can be merged together. Use --dry-run to report the
+files that would be merged.
+`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: mergeTSMF,
+	}
+
+	cmd.Flags().IntVar(&mergeTSMFlags.maxFiles, "max-files", 0, "maximum number of files merged at once, 0 for all")
+	cmd.Flags().BoolVar(&mergeTSMFlags.dryRun, "dry-run", false, "report the files that would be merged without merging them")
+
+	return cmd
+}
+
+func mergeTSMF(cmd *cobra.Command, args []string) error {
+	merger := mergetsm.NewCommand()
+	merger.Paths = args
+	merger.MaxFiles = mergeTSMFlags.maxFiles
+	merger.DryRun = mergeTSMFlags.dryRun
+	return merger.Run()
+}
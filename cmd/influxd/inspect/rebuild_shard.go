@@ -0,0 +1,64 @@
+package inspect
+
+import (
+	"github.com/influxdata/influxdb/cmd/influx_inspect/rebuildshard"
+	"github.com/influxdata/influxdb/kit/cli"
+	"github.com/spf13/cobra"
+)
+
+// rebuildShardFlags defines the `rebuild-shard` Command.
+var rebuildShardFlags = struct {
+	cli.OrgBucket
+	outputDir         string
+	maxBufferedValues int
+	verbose           bool
+}{}
+
+// NewRebuildShardCommand returns a new instance of the rebuild-shard command.
+func NewRebuildShardCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rebuild-shard <path>...",
+		Short: "Rebuilds the TSM files of a shard from a line protocol export",
+		Long: `
+This command re-ingests a line protocol export into the TSM files of a freshly
+created directory, to recover from an unrecoverable corruption of a shard.
+The points are written directly to TSM files, without the WAL and the cache
+of the engine, and the files are then merged with a full compaction. The
+storage engine must not be running on the output directory.
+
+OPTIONS
+
+   <path>...
+      A list of line protocol files, gzipped if their name ends with .gz, or -
+      for the standard input. Empty lines and comments are ignored.
+
+The points are written to the organization and bucket given by --org-id and
+--bucket-id, with nanosecond timestamps, to the directory given by
+--output-dir, which must be empty. Once rebuilt, the TSM files replace the
+data directory of the engine, and the index is rebuilt with build-tsi.
+
+Use --max-buffered-values to bound the number of values buffered in memory
+before they are written to a TSM file, and --verbose to report the lines which
+could not be parsed.
+`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: rebuildShardF,
+	}
+
+	rebuildShardFlags.AddFlags(cmd)
+	cmd.Flags().StringVar(&rebuildShardFlags.outputDir, "output-dir", "", "directory the TSM files are written to, which must be empty")
+	cmd.Flags().IntVar(&rebuildShardFlags.maxBufferedValues, "max-buffered-values", rebuildshard.DefaultMaxBufferedValues, "number of values buffered in memory before they are written to a TSM file")
+	cmd.Flags().BoolVarP(&rebuildShardFlags.verbose, "verbose", "v", false, "report the lines which could not be parsed")
+
+	return cmd
+}
+
+func rebuildShardF(cmd *cobra.Command, args []string) error {
+	rebuilder := rebuildshard.NewCommand()
+	rebuilder.Paths = args
+	rebuilder.OrgID, rebuilder.BucketID = rebuildShardFlags.OrgBucketID()
+	rebuilder.OutputDir = rebuildShardFlags.outputDir
+	rebuilder.MaxBufferedValues = rebuildShardFlags.maxBufferedValues
+	rebuilder.Verbose = rebuildShardFlags.verbose
+	return rebuilder.Run()
+}
@@ -0,0 +1,61 @@
+package inspect
+
+import (
+	"github.com/influxdata/influxdb/cmd/influx_inspect/repairtsm"
+	"github.com/spf13/cobra"
+)
+
+// repairTSMFlags defines the `repair-tsm` Command.
+var repairTSMFlags = struct {
+	outputDir string
+	replace   bool
+	dryRun    bool
+}{}
+
+// NewRepairTSMCommand returns a new instance of the repair-tsm command.
+func NewRepairTSMCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "repair-tsm <path>...",
+		Short: "Salvages the blocks of corrupted TSM files",
+		Long: `
+This command will repair TSM files with a corrupted index or a truncated tail,
+such as left by a torn write, which can not be opened by the storage engine.
+The blocks of a file are salvaged from the entries of its index that can still
+be read and, when the index is damaged or missing, from a scan of the blocks of
+the file. Every block whose checksum validates, and whose type and time range
+match those of its index entry, is written, with a new index, to the repaired
+file. The key, time range, offset, size and number of points of every lost
+block are reported, the key of a block that is no longer indexed being
+unknown.
+The storage engine must not be running.
+
+OPTIONS
+
+   <path>...
+      A list of TSM files to repair. Files that are not corrupted are reported
+      and left untouched.
+
+Use --output-dir to write the repaired files to a directory under their
+original name, or --replace to replace each corrupted file with its repaired
+file, the original file being kept with a .corrupt extension. Use --dry-run to
+only report the blocks that would be lost.
+`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: repairTSMF,
+	}
+
+	cmd.Flags().StringVar(&repairTSMFlags.outputDir, "output-dir", "", "directory the repaired files are written to")
+	cmd.Flags().BoolVar(&repairTSMFlags.replace, "replace", false, "replace corrupted files with their repaired files, keeping the originals with a .corrupt extension")
+	cmd.Flags().BoolVar(&repairTSMFlags.dryRun, "dry-run", false, "report the blocks that would be lost without writing files")
+
+	return cmd
+}
+
+func repairTSMF(cmd *cobra.Command, args []string) error {
+	repairer := repairtsm.NewCommand()
+	repairer.Paths = args
+	repairer.OutputDir = repairTSMFlags.outputDir
+	repairer.Replace = repairTSMFlags.replace
+	repairer.DryRun = repairTSMFlags.dryRun
+	return repairer.Run()
+}
@@ -0,0 +1,75 @@
+package inspect
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/reportcardinality"
+	"github.com/influxdata/influxdb/internal/fs"
+	"github.com/influxdata/influxdb/kit/cli"
+	"github.com/spf13/cobra"
+)
+
+// reportCardinalityFlags defines the `report-cardinality` Command.
+var reportCardinalityFlags = struct {
+	cli.OrgBucket
+	indexPath      string
+	seriesFilePath string
+	byTagKey       bool
+	top            int
+	snapshotPath   string
+	comparePath    string
+}{}
+
+// NewReportCardinalityCommand returns a new instance of the report-cardinality command.
+func NewReportCardinalityCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report-cardinality",
+		Short: "Reports the exact series cardinality of the index and its growth",
+		Long: `
+This command reads the TSI index and the series file, and reports the exact
+number of series of each measurement, sorted from the largest to the
+smallest. Every series of the index is counted, rather than estimated.
+
+An optional organization or organization and bucket may be specified to limit
+the report.
+
+Use --by-tag-key to also report the number of series and of distinct values
+of each tag key of the measurements, and --top to only report the largest
+measurements.
+
+Use --snapshot to save the report as a JSON snapshot, and --compare with the
+path of a snapshot saved earlier to report the growth of each measurement
+since, sorted from the largest growth, to find the measurements whose
+cardinality explodes.
+`,
+		Args: cobra.NoArgs,
+		RunE: reportCardinalityF,
+	}
+
+	dir, err := fs.InfluxDir()
+	if err != nil {
+		panic(err)
+	}
+	reportCardinalityFlags.AddFlags(cmd)
+	cmd.Flags().StringVar(&reportCardinalityFlags.indexPath, "index-path", filepath.Join(dir, "engine", "index"), fmt.Sprintf("path to the index (defaults to %s)", filepath.Join(dir, "engine", "index")))
+	cmd.Flags().StringVar(&reportCardinalityFlags.seriesFilePath, "series-file", filepath.Join(dir, "engine", "_series"), fmt.Sprintf("path to the series file (defaults to %s)", filepath.Join(dir, "engine", "_series")))
+	cmd.Flags().BoolVar(&reportCardinalityFlags.byTagKey, "by-tag-key", false, "report the cardinality of each tag key of the measurements")
+	cmd.Flags().IntVar(&reportCardinalityFlags.top, "top", 0, "only report the top measurements, 0 for all")
+	cmd.Flags().StringVar(&reportCardinalityFlags.snapshotPath, "snapshot", "", "save the report as a JSON snapshot at this path")
+	cmd.Flags().StringVar(&reportCardinalityFlags.comparePath, "compare", "", "report the growth since the snapshot saved at this path")
+
+	return cmd
+}
+
+func reportCardinalityF(cmd *cobra.Command, args []string) error {
+	reporter := reportcardinality.NewCommand()
+	reporter.IndexPath = reportCardinalityFlags.indexPath
+	reporter.SeriesFilePath = reportCardinalityFlags.seriesFilePath
+	reporter.OrgID, reporter.BucketID = reportCardinalityFlags.OrgBucketID()
+	reporter.ByTagKey = reportCardinalityFlags.byTagKey
+	reporter.Top = reportCardinalityFlags.top
+	reporter.SnapshotPath = reportCardinalityFlags.snapshotPath
+	reporter.ComparePath = reportCardinalityFlags.comparePath
+	return reporter.Run()
+}
@@ -0,0 +1,62 @@
+package inspect
+
+import (
+	"github.com/influxdata/influxdb/cmd/influx_inspect/reportdisk"
+	"github.com/influxdata/influxdb/kit/cli"
+	"github.com/spf13/cobra"
+)
+
+// reportDiskFlags defines the `report-disk` Command.
+var reportDiskFlags = struct {
+	cli.OrgBucket
+	byTagKey bool
+	format   string
+	top      int
+}{}
+
+// NewReportDiskCommand returns a new instance of the report-disk command.
+func NewReportDiskCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report-disk <pathspec>...",
+		Short: "Reports the disk usage of TSM files by measurement",
+		Long: `
+This command attributes the bytes of the blocks of TSM files to the
+organization, bucket and measurement of their series, sorted from the largest
+to the smallest, for capacity planning. Only the indexes of the files are
+read: the bytes are the compressed sizes of the blocks, and the indexes
+themselves are not attributed.
+
+OPTIONS
+
+   <pathspec>...
+      A list of TSM files, or of directories searched recursively for them,
+      such as the data directory of the engine.
+
+An optional organization or organization and bucket may be specified to limit
+the report.
+
+Use --by-tag-key to break the usage of each measurement down by tag key, the
+blocks of a series being attributed to each of its tag keys. Use --top to only
+report the largest rows, and --format json to emit the report as JSON.
+`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: reportDiskF,
+	}
+
+	reportDiskFlags.AddFlags(cmd)
+	cmd.Flags().BoolVar(&reportDiskFlags.byTagKey, "by-tag-key", false, "break the usage of each measurement down by tag key")
+	cmd.Flags().StringVar(&reportDiskFlags.format, "format", reportdisk.FormatTable, "output format: table or json")
+	cmd.Flags().IntVar(&reportDiskFlags.top, "top", 0, "only report the largest rows, 0 for all")
+
+	return cmd
+}
+
+func reportDiskF(cmd *cobra.Command, args []string) error {
+	reporter := reportdisk.NewCommand()
+	reporter.Paths = args
+	reporter.OrgID, reporter.BucketID = reportDiskFlags.OrgBucketID()
+	reporter.ByTagKey = reportDiskFlags.byTagKey
+	reporter.Format = reportDiskFlags.format
+	reporter.Top = reportDiskFlags.top
+	return reporter.Run()
+}
@@ -0,0 +1,89 @@
+package inspect
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/shifttimestamps"
+	"github.com/influxdata/influxdb/internal/fs"
+	"github.com/influxdata/influxdb/kit/cli"
+	"github.com/spf13/cobra"
+)
+
+// shiftTimestampsFlags defines the `shift-timestamps` Command.
+var shiftTimestampsFlags = struct {
+	cli.OrgBucket
+	dataDir      string
+	walDir       string
+	measurements []string
+	where        string
+	field        string
+	offset       time.Duration
+	dryRun       bool
+}{}
+
+// NewShiftTimestampsCommand returns a new instance of the shift-timestamps command.
+func NewShiftTimestampsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "shift-timestamps",
+		Short: "Shifts the timestamps of selected series by a fixed offset",
+		Long: `
+This command will shift the timestamps of the values of the selected series
+by a fixed offset, such as to fix the data written by clients with a
+misconfigured time zone. The blocks of the selected series are decoded,
+shifted and re-encoded in place, and tombstoned values are dropped from the
+rewritten files. The storage engine must not be running, and the WAL must not
+have values of the selected series.
+
+OPTIONS
+
+   --data-dir
+      The data directory of the engine, searched recursively for TSM files.
+
+   --wal-dir
+      The WAL directory of the engine, checked for values of the selected
+      series.
+
+   --offset
+      The duration added to the timestamps, such as 5h or -30m.
+
+An optional organization or organization and bucket may be specified to limit
+the shift, along with one or more --measurement, a --where predicate on the
+tags of the series, such as 'host=web01 AND region=us-east', and a --field.
+Use --dry-run to report what would be shifted without modifying any file.
+Values already at the new timestamps of the shifted values in other files may
+be overwritten.
+`,
+		Args: cobra.NoArgs,
+		RunE: shiftTimestampsF,
+	}
+
+	dir, err := fs.InfluxDir()
+	if err != nil {
+		panic(err)
+	}
+	shiftTimestampsFlags.AddFlags(cmd)
+	cmd.Flags().StringVar(&shiftTimestampsFlags.dataDir, "data-dir", filepath.Join(dir, "engine/data"), "data directory of the engine")
+	cmd.Flags().StringVar(&shiftTimestampsFlags.walDir, "wal-dir", filepath.Join(dir, "engine/wal"), "WAL directory of the engine, or empty to skip checking it")
+	cmd.Flags().StringArrayVar(&shiftTimestampsFlags.measurements, "measurement", nil, "the name of a measurement to shift, may be repeated")
+	cmd.Flags().StringVar(&shiftTimestampsFlags.where, "where", "", "predicate on the tags of the series to shift")
+	cmd.Flags().StringVar(&shiftTimestampsFlags.field, "field", "", "field whose values are shifted")
+	cmd.Flags().DurationVar(&shiftTimestampsFlags.offset, "offset", 0, "duration added to the timestamps (required)")
+	cmd.Flags().BoolVar(&shiftTimestampsFlags.dryRun, "dry-run", false, "report what would be shifted without modifying any file")
+	cmd.MarkFlagRequired("offset")
+
+	return cmd
+}
+
+func shiftTimestampsF(cmd *cobra.Command, args []string) error {
+	shifter := shifttimestamps.NewCommand()
+	shifter.OrgID, shifter.BucketID = shiftTimestampsFlags.OrgBucketID()
+	shifter.DataDir = shiftTimestampsFlags.dataDir
+	shifter.WALDir = shiftTimestampsFlags.walDir
+	shifter.Measurements = shiftTimestampsFlags.measurements
+	shifter.Where = shiftTimestampsFlags.where
+	shifter.Field = shiftTimestampsFlags.field
+	shifter.Offset = shiftTimestampsFlags.offset
+	shifter.DryRun = shiftTimestampsFlags.dryRun
+	return shifter.Run()
+}
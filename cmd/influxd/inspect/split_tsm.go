@@ -0,0 +1,68 @@
+package inspect
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/splittsm"
+	"github.com/spf13/cobra"
+)
+
+// splitTSMFlags defines the `split-tsm` Command.
+var splitTSMFlags = struct {
+	time      string
+	beforeDir string
+	afterDir  string
+}{}
+
+// NewSplitTSMCommand returns a new instance of the split-tsm command.
+func NewSplitTSMCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "split-tsm <pathspec>...",
+		Short: "Splits TSM files at a point in time",
+		Long: `
+This command will split each TSM file into a file of the values before the
+given time and a file of the values at or after it, so that existing data can
+be moved to shards of another duration, or carved out for extraction. Blocks
+straddling the time are re-encoded as two blocks, the others are copied as is.
+Tombstoned values are dropped from the new files, and the input files are left
+untouched.
+
+OPTIONS
+
+   <pathspec>...
+      A list of TSM files and directories, such as shard directories or the
+      data directory of the engine, searched recursively for TSM files. The
+      new files of a directory keep their path relative to it.
+
+The new files are written under --before-dir and --after-dir, which must not
+already hold files of the same name. A new file is not written if it would be
+empty.
+`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: splitTSMF,
+	}
+
+	cmd.Flags().StringVar(&splitTSMFlags.time, "time", "", "RFC3339 time the files are split at (required)")
+	cmd.Flags().StringVar(&splitTSMFlags.beforeDir, "before-dir", "", "directory of the files of the values before time (required)")
+	cmd.Flags().StringVar(&splitTSMFlags.afterDir, "after-dir", "", "directory of the files of the values at or after time (required)")
+	cmd.MarkFlagRequired("time")
+	cmd.MarkFlagRequired("before-dir")
+	cmd.MarkFlagRequired("after-dir")
+
+	return cmd
+}
+
+func splitTSMF(cmd *cobra.Command, args []string) error {
+	t, err := time.Parse(time.RFC3339Nano, splitTSMFlags.time)
+	if err != nil {
+		return fmt.Errorf("invalid time: %v", err)
+	}
+
+	splitter := splittsm.NewCommand()
+	splitter.Paths = args
+	splitter.Time = t
+	splitter.BeforeDir = splitTSMFlags.beforeDir
+	splitter.AfterDir = splitTSMFlags.afterDir
+	return splitter.Run()
+}
@@ -0,0 +1,59 @@
+package inspect
+
+import (
+	"github.com/influxdata/influxdb/cmd/influx_inspect/tombstones"
+	"github.com/spf13/cobra"
+)
+
+// tombstonesFlags defines the `tombstones` Command.
+var tombstonesFlags = struct {
+	delete  []int
+	compact bool
+	dryRun  bool
+}{}
+
+// NewTombstonesCommand returns a new instance of the tombstones command.
+func NewTombstonesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tombstones <pathspec>...",
+		Short: "Lists and edits the tombstones of TSM files",
+		Long: `
+This command will list the entries of tombstone files, which record the
+deletions of TSM files not yet applied by a compaction, in the order they were
+created. Each entry is numbered and shows whether it deletes a series key or
+the keys with a prefix, the organization, bucket, series and measurement it
+applies to, its time range and its predicate.
+
+Removing an entry undoes its deletion, as long as the TSM file has not been
+compacted since. The storage engine must not be running when removing entries.
+
+OPTIONS
+
+   <pathspec>...
+      A list of tombstone files, TSM files whose tombstone file to read, or
+      directories searched recursively for tombstone files.
+
+Use --delete to remove the entries with the given numbers from a single
+tombstone file, and --compact to remove the entries whose deletion is covered
+by another entry. Use --dry-run to report the entries to remove without
+rewriting the files.
+`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: tombstonesF,
+	}
+
+	cmd.Flags().IntSliceVar(&tombstonesFlags.delete, "delete", nil, "numbers of the entries to remove from a single tombstone file")
+	cmd.Flags().BoolVar(&tombstonesFlags.compact, "compact", false, "remove the entries covered by another entry")
+	cmd.Flags().BoolVar(&tombstonesFlags.dryRun, "dry-run", false, "report the entries to remove without rewriting the files")
+
+	return cmd
+}
+
+func tombstonesF(cmd *cobra.Command, args []string) error {
+	editor := tombstones.NewCommand()
+	editor.Paths = args
+	editor.Delete = tombstonesFlags.delete
+	editor.Compact = tombstonesFlags.compact
+	editor.DryRun = tombstonesFlags.dryRun
+	return editor.Run()
+}
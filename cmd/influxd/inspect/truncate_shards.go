@@ -0,0 +1,91 @@
+package inspect
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/influxdata/influxdb/cmd/influx_inspect/truncateshards"
+	"github.com/influxdata/influxdb/internal/fs"
+	"github.com/influxdata/influxdb/kit/cli"
+	"github.com/spf13/cobra"
+)
+
+// truncateShardsFlags defines the `truncate-shards` Command.
+var truncateShardsFlags = struct {
+	cli.OrgBucket
+	dataDir     string
+	walDir      string
+	time        string
+	before      bool
+	dryRun      bool
+	concurrency int
+}{}
+
+// NewTruncateShardsCommand returns a new instance of the truncate-shards command.
+func NewTruncateShardsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "truncate-shards",
+		Short: "Deletes every value after, or before, a point in time",
+		Long: `
+This command will delete every value after a point in time from the TSM files
+and WAL segments of the engine, such as to recover from a backfill that wrote
+values in the future. Blocks straddling the time are decoded, trimmed and
+re-encoded, and files left without any block are removed. The storage engine
+must not be running.
+
+OPTIONS
+
+   --data-dir
+      The data directory of the engine, searched recursively for TSM files.
+
+   --wal-dir
+      The WAL directory of the engine. Its segments are truncated after the
+      TSM files, so that the deleted values are not written back when the WAL
+      is replayed.
+
+   --time
+      The RFC3339 time the data is truncated at. Values at that time are
+      kept.
+
+An optional organization or organization and bucket may be specified to limit
+the truncation. Use --before to delete the values before the time instead of
+those after it, and --dry-run to report what would be deleted without
+modifying any file.
+`,
+		Args: cobra.NoArgs,
+		RunE: truncateShardsF,
+	}
+
+	dir, err := fs.InfluxDir()
+	if err != nil {
+		panic(err)
+	}
+	truncateShardsFlags.AddFlags(cmd)
+	cmd.Flags().StringVar(&truncateShardsFlags.dataDir, "data-dir", filepath.Join(dir, "engine/data"), "data directory of the engine")
+	cmd.Flags().StringVar(&truncateShardsFlags.walDir, "wal-dir", filepath.Join(dir, "engine/wal"), "WAL directory of the engine, or empty to leave the WAL untouched")
+	cmd.Flags().StringVar(&truncateShardsFlags.time, "time", "", "RFC3339 time the data is truncated at (required)")
+	cmd.Flags().BoolVar(&truncateShardsFlags.before, "before", false, "delete the values before the time instead of those after it")
+	cmd.Flags().BoolVar(&truncateShardsFlags.dryRun, "dry-run", false, "report what would be deleted without modifying any file")
+	cmd.Flags().IntVar(&truncateShardsFlags.concurrency, "concurrency", 1, "number of files to rewrite concurrently")
+	cmd.MarkFlagRequired("time")
+
+	return cmd
+}
+
+func truncateShardsF(cmd *cobra.Command, args []string) error {
+	t, err := time.Parse(time.RFC3339Nano, truncateShardsFlags.time)
+	if err != nil {
+		return fmt.Errorf("invalid time: %v", err)
+	}
+
+	truncater := truncateshards.NewCommand()
+	truncater.OrgID, truncater.BucketID = truncateShardsFlags.OrgBucketID()
+	truncater.DataDir = truncateShardsFlags.dataDir
+	truncater.WALDir = truncateShardsFlags.walDir
+	truncater.Time = t
+	truncater.Before = truncateShardsFlags.before
+	truncater.DryRun = truncateShardsFlags.dryRun
+	truncater.Concurrency = truncateShardsFlags.concurrency
+	return truncater.Run()
+}
@@ -0,0 +1,51 @@
+package inspect
+
+import (
+	"github.com/influxdata/influxdb/cmd/influx_inspect/tsmdiff"
+	"github.com/spf13/cobra"
+)
+
+// tsmDiffFlags defines the `tsm-diff` Command.
+var tsmDiffFlags = struct {
+	verbose bool
+}{}
+
+// NewTSMDiffCommand returns a new instance of the tsm-diff command.
+func NewTSMDiffCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tsm-diff <pathspec> <pathspec>",
+		Short: "Reports the differences between two sets of TSM files",
+		Long: `
+This command will compare the blocks of two TSM files, or of the TSM files of
+two directories, and report the keys present on one side only, the keys whose
+blocks differ in time range or checksum, and the point-count deltas. It is
+meant to verify that offline rewrites, such as delete-tsm, and restores
+preserved the data they were supposed to.
+
+OPTIONS
+
+   <pathspec> <pathspec>
+      Two TSM files or directories, such as shard directories or the data
+      directory of the engine, searched recursively for TSM files. The
+      blocks of all the files of a directory are compared by key, regardless
+      of the file holding them.
+
+Tombstoned points are excluded from the point counts. The command exits with
+an error if the two sides differ.
+`,
+		Args: cobra.ExactArgs(2),
+		RunE: tsmDiffF,
+	}
+
+	cmd.Flags().BoolVarP(&tsmDiffFlags.verbose, "verbose", "v", false, "report every differing block")
+
+	return cmd
+}
+
+func tsmDiffF(cmd *cobra.Command, args []string) error {
+	differ := tsmdiff.NewCommand()
+	differ.PathA = args[0]
+	differ.PathB = args[1]
+	differ.Verbose = tsmDiffFlags.verbose
+	return differ.Run()
+}
@@ -0,0 +1,63 @@
+package inspect
+
+import (
+	"github.com/influxdata/influxdb/cmd/influx_inspect/tsmstats"
+	"github.com/influxdata/influxdb/kit/cli"
+	"github.com/spf13/cobra"
+)
+
+// tsmStatsFlags defines the `tsm-stats` Command.
+var tsmStatsFlags = struct {
+	cli.OrgBucket
+	sort   string
+	top    int
+	format string
+}{}
+
+// NewTSMStatsCommand returns a new instance of the tsm-stats command.
+func NewTSMStatsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tsm-stats <pathspec>...",
+		Short: "Reports the blocks, points, sizes and encodings of each TSM key",
+		Long: `
+This command reports, for each key of a set of TSM files, its number of
+blocks and points, the compressed bytes of its blocks, the raw bytes of its
+points once decoded, their compression ratio and the encodings of the
+timestamps and values of its blocks, to find the handful of series
+responsible for a bloated shard. The statistics of a key are aggregated over
+the files.
+
+OPTIONS
+
+   <pathspec>...
+      A list of TSM files, or of directories searched recursively for them,
+      such as a shard directory or the data directory of the engine.
+
+An optional organization or organization and bucket may be specified to limit
+the report.
+
+Use --sort to order the keys by bytes, the default, raw-bytes, points or
+blocks, from the largest, or by ratio, from the least compressed. Use --top to
+only report the first keys, and --format json to emit the report as JSON.
+`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: tsmStatsF,
+	}
+
+	tsmStatsFlags.AddFlags(cmd)
+	cmd.Flags().StringVar(&tsmStatsFlags.sort, "sort", tsmstats.SortBytes, "order of the keys: bytes, raw-bytes, points, blocks or ratio")
+	cmd.Flags().IntVar(&tsmStatsFlags.top, "top", 0, "only report the first keys, 0 for all")
+	cmd.Flags().StringVar(&tsmStatsFlags.format, "format", tsmstats.FormatTable, "output format: table or json")
+
+	return cmd
+}
+
+func tsmStatsF(cmd *cobra.Command, args []string) error {
+	reporter := tsmstats.NewCommand()
+	reporter.Paths = args
+	reporter.OrgID, reporter.BucketID = tsmStatsFlags.OrgBucketID()
+	reporter.Sort = tsmStatsFlags.sort
+	reporter.Top = tsmStatsFlags.top
+	reporter.Format = tsmStatsFlags.format
+	return reporter.Run()
+}
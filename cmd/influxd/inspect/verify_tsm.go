@@ -14,6 +14,7 @@ import (
 var verifyTSMFlags = struct {
 	cli.OrgBucket
 	path string
+	fix  bool
 }{}
 
 func NewVerifyTSMCommand() *cobra.Command {
@@ -36,11 +37,18 @@ OPTIONS
 
 An optional organization or organization and bucket may be specified to limit
 the analysis.
+
+Use --fix to rewrite the files with corrupted blocks, keeping their healthy
+blocks. Blocks whose checksum does not match or which can not be decoded are
+moved to a .quarantine file next to the TSM file, one JSON encoded block per
+line, and blocks whose index entry does not match their timestamps are kept
+with a corrected entry. The storage engine must not be running.
 `,
 		RunE: verifyTSMF,
 	}
 
 	verifyTSMFlags.AddFlags(cmd)
+	cmd.Flags().BoolVar(&verifyTSMFlags.fix, "fix", false, "rewrite files without their corrupted blocks, which are moved to a quarantine file")
 
 	return cmd
 }
@@ -50,6 +58,7 @@ func verifyTSMF(cmd *cobra.Command, args []string) error {
 		Stdout:   os.Stdout,
 		OrgID:    verifyTSMFlags.Org,
 		BucketID: verifyTSMFlags.Bucket,
+		Fix:      verifyTSMFlags.fix,
 	}
 
 	// resolve all pathspecs
@@ -0,0 +1,52 @@
+package launcher
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/cli"
+	"github.com/influxdata/influxdb/query"
+)
+
+// secretOptionSuffixes are the suffixes of the names of the options holding secrets.
+var secretOptionSuffixes = []string{"token", "password", "secret-access-key"}
+
+// diagnosticsService reports the configuration options of the launcher and
+// the queries recorded by its query log.
+type diagnosticsService struct {
+	opts []cli.Opt
+	*query.MemoryLogger
+}
+
+var _ platform.DiagnosticsService = (*diagnosticsService)(nil)
+
+// Config returns the values of the options, sorted by name, with the values
+// of those holding secrets redacted.
+func (s *diagnosticsService) Config(ctx context.Context) ([]platform.ConfigOption, error) {
+	opts := make([]platform.ConfigOption, 0, len(s.opts))
+	for _, o := range s.opts {
+		if o.DestP == nil {
+			continue
+		}
+		value := fmt.Sprint(reflect.ValueOf(o.DestP).Elem().Interface())
+		if value != "" && isSecretOption(o.Flag) {
+			value = "[REDACTED]"
+		}
+		opts = append(opts, platform.ConfigOption{Name: o.Flag, Value: value})
+	}
+	sort.Slice(opts, func(i, j int) bool { return opts[i].Name < opts[j].Name })
+	return opts, nil
+}
+
+func isSecretOption(name string) bool {
+	for _, suffix := range secretOptionSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"io"
 	"io/ioutil"
+	"math"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/http"
@@ -13,6 +15,7 @@ import (
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/storage"
 	"github.com/influxdata/influxdb/storage/readservice"
+	"github.com/influxdata/influxdb/toml"
 	"github.com/influxdata/influxdb/tsdb"
 	"github.com/influxdata/influxdb/tsdb/cursors"
 	"github.com/influxdata/influxql"
@@ -31,6 +34,8 @@ type Engine interface {
 	storage.BucketDeleter
 	prom.PrometheusCollector
 	influxdb.BackupService
+	influxdb.CompactionService
+	influxdb.BucketCopyService
 
 	SeriesCardinality() int64
 
@@ -67,6 +72,20 @@ func NewTemporaryEngine(c storage.Config, options ...storage.Option) *TemporaryE
 	}
 }
 
+// NewMemoryEngine creates a new engine that keeps all of the time series data
+// in the memory of its cache: the WAL is disabled and the cache is never
+// snapshotted to TSM files, except by a backup or an explicit snapshot. Only
+// the index and series file are written to a temporary directory.
+func NewMemoryEngine(c storage.Config, options ...storage.Option) *TemporaryEngine {
+	c.WAL.Enabled = false
+	c.Engine.Cache.MaxMemorySize = 0
+	c.Engine.Cache.SnapshotMemorySize = toml.Size(math.MaxUint64)
+	c.Engine.Cache.SnapshotAgeDuration = 0
+	c.Engine.Cache.SnapshotWriteColdDuration = toml.Duration(math.MaxInt64)
+	c.Engine.WarmUp.Enabled = false
+	return NewTemporaryEngine(c, options...)
+}
+
 // Open creates a temporary directory and opens the engine.
 func (t *TemporaryEngine) Open(ctx context.Context) error {
 	t.mu.Lock()
@@ -179,3 +198,23 @@ func (t *TemporaryEngine) FetchBackupFile(ctx context.Context, backupID int, bac
 func (t *TemporaryEngine) InternalBackupPath(backupID int) string {
 	return t.engine.InternalBackupPath(backupID)
 }
+
+func (t *TemporaryEngine) PauseCompactions(ctx context.Context, timeout time.Duration) error {
+	return t.engine.PauseCompactions(ctx, timeout)
+}
+
+func (t *TemporaryEngine) ResumeCompactions(ctx context.Context) error {
+	return t.engine.ResumeCompactions(ctx)
+}
+
+func (t *TemporaryEngine) CompactionStatus(ctx context.Context) (*influxdb.CompactionStatus, error) {
+	return t.engine.CompactionStatus(ctx)
+}
+
+func (t *TemporaryEngine) CompactionReport(ctx context.Context) (*influxdb.CompactionReport, error) {
+	return t.engine.CompactionReport(ctx)
+}
+
+func (t *TemporaryEngine) CopyBucketRange(ctx context.Context, src, dst influxdb.BucketCopyTarget, min, max int64) (*influxdb.BucketCopyStats, error) {
+	return t.engine.CopyBucketRange(ctx, src, dst, min, max)
+}
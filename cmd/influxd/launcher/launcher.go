@@ -2,10 +2,10 @@ package launcher
 
 import (
 	"context"
-	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math"
 	"net"
 	nethttp "net/http"
@@ -20,9 +20,12 @@ import (
 	"github.com/influxdata/influxdb/authorizer"
 	"github.com/influxdata/influxdb/bolt"
 	"github.com/influxdata/influxdb/chronograf/server"
+	"github.com/influxdata/influxdb/clockskew"
 	"github.com/influxdata/influxdb/cmd/influxd/inspect"
 	"github.com/influxdata/influxdb/endpoints"
+	"github.com/influxdata/influxdb/federation"
 	"github.com/influxdata/influxdb/gather"
+	"github.com/influxdata/influxdb/groupsync"
 	"github.com/influxdata/influxdb/http"
 	"github.com/influxdata/influxdb/inmem"
 	"github.com/influxdata/influxdb/internal/fs"
@@ -34,11 +37,15 @@ import (
 	"github.com/influxdata/influxdb/kv"
 	influxlogger "github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/nats"
+	"github.com/influxdata/influxdb/pkg/limiter"
+	"github.com/influxdata/influxdb/pkg/objstore"
 	"github.com/influxdata/influxdb/pkger"
 	infprom "github.com/influxdata/influxdb/prometheus"
 	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/query/async"
 	"github.com/influxdata/influxdb/query/control"
 	"github.com/influxdata/influxdb/query/stdlib/influxdata/influxdb"
+	"github.com/influxdata/influxdb/replication"
 	"github.com/influxdata/influxdb/snowflake"
 	"github.com/influxdata/influxdb/source"
 	"github.com/influxdata/influxdb/storage"
@@ -50,8 +57,10 @@ import (
 	"github.com/influxdata/influxdb/task/backend/middleware"
 	"github.com/influxdata/influxdb/task/backend/scheduler"
 	"github.com/influxdata/influxdb/telemetry"
+	"github.com/influxdata/influxdb/toml"
+	"github.com/influxdata/influxdb/tsdb"
 	_ "github.com/influxdata/influxdb/tsdb/tsi1" // needed for tsi1
-	_ "github.com/influxdata/influxdb/tsdb/tsm1" // needed for tsm1
+	"github.com/influxdata/influxdb/tsdb/tsm1"
 	"github.com/influxdata/influxdb/vault"
 	pzap "github.com/influxdata/influxdb/zap"
 	opentracing "github.com/opentracing/opentracing-go"
@@ -65,7 +74,8 @@ import (
 const (
 	// BoltStore stores all REST resources in boltdb.
 	BoltStore = "bolt"
-	// MemoryStore stores all REST resources in memory (useful for testing).
+	// MemoryStore stores all REST resources and time series data in memory,
+	// persisting nothing across restarts (useful for testing).
 	MemoryStore = "memory"
 
 	// LogTracing enables tracing via zap logs
@@ -160,7 +170,7 @@ func buildLauncherCommand(l *Launcher, cmd *cobra.Command) {
 			DestP:   &l.storeType,
 			Flag:    "store",
 			Default: "bolt",
-			Desc:    "backing store for REST resources (bolt or memory)",
+			Desc:    "backing store for REST resources and time series data (bolt or memory); memory persists nothing across restarts",
 		},
 		{
 			DestP:   &l.testing,
@@ -174,6 +184,81 @@ func buildLauncherCommand(l *Launcher, cmd *cobra.Command) {
 			Default: filepath.Join(dir, "engine"),
 			Desc:    "path to persistent engine files",
 		},
+		{
+			DestP:   &l.StorageConfig.Engine.WarmUp.Enabled,
+			Flag:    "storage-warm-up",
+			Default: tsm1.DefaultWarmUpEnabled,
+			Desc:    "on startup, load the TSM indexes of the buckets most recently queried before shutdown",
+		},
+		{
+			DestP:   &l.warmUpBlocksAge,
+			Flag:    "storage-warm-up-blocks-age",
+			Default: tsm1.DefaultWarmUpBlocksAge,
+			Desc:    "with storage-warm-up, also load the blocks holding data more recent than this duration",
+		},
+		{
+			DestP:   &l.seriesIDAllocation,
+			Flag:    "storage-series-id-allocation",
+			Default: string(tsdb.DefaultSeriesIDAllocation),
+			Desc:    "strategy used to create new series in the series file, sequential or batched; batched reduces lock contention when many new series are created concurrently",
+		},
+		{
+			DestP:   &l.compactionMaxFileSize,
+			Flag:    "storage-compaction-max-file-size",
+			Default: int(tsm1.DefaultCompactMaxFileSize),
+			Desc:    "size, in bytes, at which compactions roll over to a new TSM file (at most 2GB)",
+		},
+		{
+			DestP:   &l.StorageConfig.Engine.Compaction.MaxLevel,
+			Flag:    "storage-compaction-max-level",
+			Default: tsm1.DefaultCompactMaxLevel,
+			Desc:    "highest level (1 to 3) of level compactions; lower levels write data fewer times but leave more TSM files",
+		},
+		{
+			DestP:   &l.StorageConfig.Engine.Compaction.LevelGenerations,
+			Flag:    "storage-compaction-level-generations",
+			Default: tsm1.DefaultCompactLevelGenerations,
+			Desc:    "number of TSM generations of a level compacted together into the next level (doubled for level 1)",
+		},
+		{
+			DestP:   &l.StorageConfig.Engine.Compaction.SnapshotCompression,
+			Flag:    "storage-compaction-snapshot-compression",
+			Default: tsm1.DefaultCompactSnapshotCompression,
+			Desc:    "compression of the blocks of the TSM files written by cache snapshots: none, snappy or zstd; level compactions store the blocks uncompressed again; compressed snapshot files can not be opened by older versions of influxd",
+		},
+		{
+			DestP:   &l.readSharingTTL,
+			Flag:    "storage-read-sharing-ttl",
+			Default: time.Duration(0),
+			Desc:    "share identical storage reads of concurrent queries, such as the panels of a dashboard, and buffer their results for this duration (0 disables sharing)",
+		},
+		{
+			DestP: &l.StorageConfig.ObjectStore.URL,
+			Flag:  "storage-object-store-url",
+			Desc:  "experimental: path-style URL of an S3-compatible bucket, with an optional key prefix, keeping the TSM files of the engine, whose directory acts as a local cache of them",
+		},
+		{
+			DestP:   &l.StorageConfig.ObjectStore.Region,
+			Flag:    "storage-object-store-region",
+			Default: objstore.DefaultRegion,
+			Desc:    "region of the requests to the object store",
+		},
+		{
+			DestP: &l.StorageConfig.ObjectStore.AccessKeyID,
+			Flag:  "storage-object-store-access-key-id",
+			Desc:  "access key ID of the requests to the object store",
+		},
+		{
+			DestP: &l.StorageConfig.ObjectStore.SecretAccessKey,
+			Flag:  "storage-object-store-secret-access-key",
+			Desc:  "secret access key of the requests to the object store",
+		},
+		{
+			DestP:   &l.memoryBudget,
+			Flag:    "memory-budget",
+			Default: 0,
+			Desc:    "maximum memory, in bytes, shared by the storage cache, queries and compactions (0 for no limit)",
+		},
 		{
 			DestP:   &l.secretStore,
 			Flag:    "secret-store",
@@ -198,6 +283,85 @@ func buildLauncherCommand(l *Launcher, cmd *cobra.Command) {
 			Default: false,
 			Desc:    "disables automatically extending session ttl on request",
 		},
+		{
+			DestP:   &l.writeDecimals,
+			Flag:    "write-decimal-fields",
+			Default: false,
+			Desc:    "accept decimal field values written with a d suffix, such as price=12.30d, stored exactly as strings and returned by queries as string values",
+		},
+		{
+			DestP: &l.groupSyncConfig,
+			Flag:  "group-sync-config",
+			Desc:  "path to a JSON file mapping the groups of a SCIM directory to organizations, enabling the periodic synchronization of their members",
+		},
+		{
+			DestP:   &l.groupSyncInterval,
+			Flag:    "group-sync-interval",
+			Default: time.Hour,
+			Desc:    "interval between synchronizations of organization members with directory groups",
+		},
+		{
+			DestP: &l.replicationCheckConfig,
+			Flag:  "replication-check-config",
+			Desc:  "path to a JSON file listing buckets and the remote buckets they are replicated to, enabling the periodic comparison of their points",
+		},
+		{
+			DestP:   &l.replicationCheckInterval,
+			Flag:    "replication-check-interval",
+			Default: time.Minute,
+			Desc:    "interval between comparisons of replicated buckets with their targets",
+		},
+		{
+			DestP: &l.federationConfig,
+			Flag:  "federation-config",
+			Desc:  "path to a JSON file listing remote InfluxDB peers whose buckets queries read as from(bucket: \"<peer>/<bucket>\")",
+		},
+		{
+			DestP:   &l.clockCheck.Interval,
+			Flag:    "clock-check-interval",
+			Default: 10 * time.Second,
+			Desc:    "interval between checks of the server clock for backward jumps and NTP offsets",
+		},
+		{
+			DestP: &l.clockCheck.MaxBackwardJump,
+			Flag:  "clock-max-backward-jump",
+			Desc:  "refuse writes while the server clock is behind the latest time it reached by more than this duration, 0 to never refuse writes",
+		},
+		{
+			DestP: &l.clockCheck.NTPServer,
+			Flag:  "clock-ntp-server",
+			Desc:  "address of an NTP server the server clock is compared to, reported by /health",
+		},
+		{
+			DestP:   &l.clockCheck.MaxNTPOffset,
+			Flag:    "clock-max-ntp-offset",
+			Default: time.Second,
+			Desc:    "offset from the NTP server above which /health fails",
+		},
+		{
+			DestP:   &l.asyncQueryMaxResultBytes,
+			Flag:    "async-query-max-result-bytes",
+			Default: async.DefaultMaxResultBytes,
+			Desc:    "maximum size, in bytes, of the stored result of an async query; larger results fail the query",
+		},
+		{
+			DestP:   &l.asyncQueryTTL,
+			Flag:    "async-query-ttl",
+			Default: async.DefaultTTL,
+			Desc:    "duration for which completed async queries and their results are kept",
+		},
+		{
+			DestP:   &l.queryLogSize,
+			Flag:    "query-log-size",
+			Default: 100,
+			Desc:    "number of recent queries, and of recent slow queries, kept in memory with their request and plan for support bundles",
+		},
+		{
+			DestP:   &l.queryLogSlowThreshold,
+			Flag:    "query-log-slow-threshold",
+			Default: 10 * time.Second,
+			Desc:    "duration from which a query is kept in the slow query log",
+		},
 		{
 			DestP: &vaultConfig.Address,
 			Flag:  "vault-addr",
@@ -261,10 +425,15 @@ func buildLauncherCommand(l *Launcher, cmd *cobra.Command) {
 			Desc:    "TLS key for HTTPs",
 		},
 	}
+	for _, ln := range l.apiListeners {
+		opts = append(opts, ln.options()...)
+	}
 
 	cli.BindOptions(cmd, opts)
 	cmd.AddCommand(inspect.NewCommand())
 
+	l.opts = opts
+
 }
 
 // Launcher represents the main program execution.
@@ -283,10 +452,39 @@ type Launcher struct {
 	tracingType       string
 	reportingDisabled bool
 
+	writeDecimals bool
+
 	httpBindAddress string
 	boltPath        string
 	enginePath      string
 	secretStore     string
+	tempPath        string // removed on shutdown
+
+	warmUpBlocksAge       time.Duration
+	seriesIDAllocation    string
+	memoryBudget          int
+	compactionMaxFileSize int
+	readSharingTTL        time.Duration
+
+	groupSyncConfig   string
+	groupSyncInterval time.Duration
+
+	replicationCheckConfig   string
+	replicationCheckInterval time.Duration
+
+	federationConfig string
+
+	clockCheck clockskew.Config
+
+	asyncQueryMaxResultBytes int
+	asyncQueryTTL            time.Duration
+
+	queryLogSize          int
+	queryLogSlowThreshold time.Duration
+
+	// opts are the configuration options of the launcher, reported by
+	// the diagnostics service.
+	opts []cli.Opt
 
 	boltClient    *bolt.Client
 	kvService     *kv.Service
@@ -294,12 +492,15 @@ type Launcher struct {
 	StorageConfig storage.Config
 
 	queryController *control.Controller
+	asyncQueries    *async.Service
 
 	httpPort    int
 	httpServer  *nethttp.Server
 	httpTLSCert string
 	httpTLSKey  string
 
+	apiListeners []*apiListener
+
 	natsServer *nats.Server
 	natsPort   int
 
@@ -324,6 +525,7 @@ func NewLauncher() *Launcher {
 		Stdout:        os.Stdout,
 		Stderr:        os.Stderr,
 		StorageConfig: storage.NewConfig(),
+		apiListeners:  newAPIListeners(),
 	}
 }
 
@@ -366,11 +568,21 @@ func (m *Launcher) Engine() Engine {
 // Shutdown shuts down the HTTP server and waits for all services to clean up.
 func (m *Launcher) Shutdown(ctx context.Context) {
 	m.httpServer.Shutdown(ctx)
+	for _, ln := range m.apiListeners {
+		if ln.server != nil {
+			ln.server.Shutdown(ctx)
+		}
+	}
 
 	m.log.Info("Stopping", zap.String("service", "task"))
 
 	m.scheduler.Stop()
 
+	m.log.Info("Stopping", zap.String("service", "async-query"))
+	if err := m.asyncQueries.Close(); err != nil {
+		m.log.Info("Failed closing async query service", zap.Error(err))
+	}
+
 	m.log.Info("Stopping", zap.String("service", "nats"))
 	m.natsServer.Close()
 
@@ -391,6 +603,12 @@ func (m *Launcher) Shutdown(ctx context.Context) {
 
 	m.wg.Wait()
 
+	if m.tempPath != "" {
+		if err := os.RemoveAll(m.tempPath); err != nil {
+			m.log.Warn("Failed to remove temporary files", zap.Error(err))
+		}
+	}
+
 	if m.jaegerTracerCloser != nil {
 		if err := m.jaegerTracerCloser.Close(); err != nil {
 			m.log.Warn("Failed to closer Jaeger tracer", zap.Error(err))
@@ -431,6 +649,12 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		return fmt.Errorf("unknown log level; supported levels are debug, info, and error")
 	}
 
+	if m.clockCheck.MaxBackwardJump > 0 || m.clockCheck.NTPServer != "" {
+		if err := m.clockCheck.Validate(); err != nil {
+			return fmt.Errorf("invalid clock check configuration: %v", err)
+		}
+	}
+
 	// Create top level logger
 	logconf := &influxlogger.Config{
 		Format: "auto",
@@ -470,6 +694,18 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		m.jaegerTracerCloser = closer
 	}
 
+	if m.storeType == MemoryStore {
+		// Nothing is persisted, so the bolt database still used by chronograf
+		// is kept in a temporary directory removed on shutdown.
+		path, err := ioutil.TempDir("", "influxd")
+		if err != nil {
+			m.log.Error("Failed creating temporary directory", zap.Error(err))
+			return err
+		}
+		m.tempPath = path
+		m.boltPath = filepath.Join(path, bolt.DefaultFilename)
+	}
+
 	m.boltClient = bolt.NewClient(m.log.With(zap.String("service", "bolt")))
 	m.boltClient.Path = m.boltPath
 
@@ -483,17 +719,20 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 	}
 
 	flushers := flushers{}
+	var kvStore kv.Store
 	switch m.storeType {
 	case BoltStore:
 		store := bolt.NewKVStore(m.log.With(zap.String("service", "kvstore-bolt")), m.boltPath)
 		store.WithDB(m.boltClient.DB())
 		m.kvService = kv.NewService(m.log.With(zap.String("store", "kv")), store, serviceConfig)
+		kvStore = store
 		if m.testing {
 			flushers = append(flushers, store)
 		}
 	case MemoryStore:
 		store := inmem.NewKVStore()
 		m.kvService = kv.NewService(m.log.With(zap.String("store", "kv")), store, serviceConfig)
+		kvStore = store
 		if m.testing {
 			flushers = append(flushers, store)
 		}
@@ -529,6 +768,7 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		userLogSvc                platform.UserOperationLogService         = m.kvService
 		bucketLogSvc              platform.BucketOperationLogService       = m.kvService
 		orgLogSvc                 platform.OrganizationOperationLogService = m.kvService
+		definitionHistorySvc      platform.DefinitionHistoryService        = m.kvService
 		onboardingSvc             platform.OnboardingService               = m.kvService
 		scraperTargetSvc          platform.ScraperTargetStoreService       = m.kvService
 		telegrafSvc               platform.TelegrafConfigStore             = m.kvService
@@ -563,13 +803,44 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		return err
 	}
 
-	if m.testing {
+	m.StorageConfig.Engine.WarmUp.BlocksAge = toml.Duration(m.warmUpBlocksAge)
+	m.StorageConfig.TSDB.SeriesIDAllocation = tsdb.SeriesIDAllocation(m.seriesIDAllocation)
+	m.StorageConfig.Engine.Compaction.MaxFileSize = toml.Size(m.compactionMaxFileSize)
+	if err := m.StorageConfig.Engine.Compaction.Validate(); err != nil {
+		m.log.Error("Invalid storage compaction configuration", zap.Error(err))
+		return err
+	}
+
+	// The cache, compactions and queries share a single memory budget so that
+	// together they do not exceed it.
+	memoryBudget := limiter.NewMemory(int64(m.memoryBudget))
+	switch {
+	case m.storeType == MemoryStore:
+		// the memory engine keeps the time series data in its cache
+		engine := NewMemoryEngine(m.StorageConfig, storage.WithMemoryBudget(memoryBudget), storage.WithRetentionEnforcer(bucketSvc))
+		if m.testing {
+			flushers = append(flushers, engine)
+		}
+		m.engine = engine
+	case m.testing:
 		// the testing engine will write/read into a temporary directory
-		engine := NewTemporaryEngine(m.StorageConfig, storage.WithRetentionEnforcer(bucketSvc))
+		engine := NewTemporaryEngine(m.StorageConfig, storage.WithMemoryBudget(memoryBudget), storage.WithRetentionEnforcer(bucketSvc))
 		flushers = append(flushers, engine)
 		m.engine = engine
-	} else {
-		m.engine = storage.NewEngine(m.enginePath, m.StorageConfig, storage.WithRetentionEnforcer(bucketSvc))
+	default:
+		opts := []storage.Option{storage.WithMemoryBudget(memoryBudget)}
+		if m.StorageConfig.ObjectStore.Enabled() {
+			store, err := objstore.NewS3(m.StorageConfig.ObjectStore)
+			if err != nil {
+				m.log.Error("Invalid object store configuration", zap.Error(err))
+				return err
+			}
+			m.log.Warn("Keeping TSM files in the object store is experimental", zap.String("url", m.StorageConfig.ObjectStore.URL))
+			opts = append(opts, storage.WithObjectStore(store))
+		}
+		// The retention enforcer must be the last option.
+		opts = append(opts, storage.WithRetentionEnforcer(bucketSvc))
+		m.engine = storage.NewEngine(m.enginePath, m.StorageConfig, opts...)
 	}
 	m.engine.WithLogger(m.log)
 	if err := m.engine.Open(ctx); err != nil {
@@ -580,22 +851,52 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 	m.reg.MustRegister(m.engine.PrometheusCollectors()...)
 
 	var (
-		deleteService platform.DeleteService = m.engine
-		pointsWriter  storage.PointsWriter   = m.engine
-		backupService platform.BackupService = m.engine
+		deleteService     platform.DeleteService     = m.engine
+		pointsWriter      storage.PointsWriter       = m.engine
+		backupService     platform.BackupService     = m.engine
+		compactionService platform.CompactionService = m.engine
+		bucketCopyService platform.BucketCopyService = m.engine
 	)
 
+	var clockMonitor *clockskew.Monitor
+	if m.clockCheck.MaxBackwardJump > 0 || m.clockCheck.NTPServer != "" {
+		// The first check, and NTP query, is run in the background so that it
+		// does not delay the startup.
+		clockMonitor = clockskew.NewMonitor(m.log, m.clockCheck)
+		m.reg.MustRegister(clockMonitor.PrometheusCollectors()...)
+		pointsWriter = &clockskew.PointsWriter{Monitor: clockMonitor, PointsWriter: pointsWriter}
+
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			clockMonitor.Run(ctx)
+		}()
+	}
+
 	// TODO(cwolff): Figure out a good default per-query memory limit:
 	//   https://github.com/influxdata/influxdb/issues/13642
 	const (
 		concurrencyQuota         = 10
 		memoryBytesQuotaPerQuery = math.MaxInt64
 		QueueSize                = 10
+
+		// initialMemoryBytesQuotaPerQuery is the memory given to each query
+		// before it is reserved in the memory budget, if there is one.
+		initialMemoryBytesQuotaPerQuery = 1 << 20
 	)
+	var initialMemoryBytes int64
+	if memoryBudget != nil {
+		initialMemoryBytes = initialMemoryBytesQuotaPerQuery
+	}
 
+	reader := reads.NewReader(reads.NewRetryStore(readservice.NewStore(m.engine), reads.DefaultRetryPolicy()))
+	if m.readSharingTTL > 0 {
+		reader = reads.NewSharedReader(reader, m.readSharingTTL)
+	}
 	deps, err := influxdb.NewDependencies(
-		reads.NewReader(readservice.NewStore(m.engine)),
-		m.engine,
+		reader,
+		authorizer.NewPointsWriter(m.engine),
+		authorizer.NewBucketCopyService(bucketCopyService),
 		authorizer.NewBucketService(bucketSvc),
 		authorizer.NewOrgService(orgSvc),
 		authorizer.NewSecretService(secretSvc),
@@ -605,13 +906,31 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		m.log.Error("Failed to get query controller dependencies", zap.Error(err))
 		return err
 	}
+	if m.federationConfig != "" {
+		config, err := federation.LoadConfig(m.federationConfig)
+		if err != nil {
+			m.log.Error("Failed to load federation config", zap.Error(err))
+			return err
+		}
+		deps.StorageDeps.FromDeps.Peers = federation.NewService(config, func(p federation.Peer) query.QueryService {
+			return &http.FluxQueryService{
+				Addr:               p.URL,
+				Token:              p.Token,
+				InsecureSkipVerify: p.InsecureSkipVerify,
+			}
+		})
+	}
 
 	m.queryController, err = control.New(control.Config{
-		ConcurrencyQuota:         concurrencyQuota,
-		MemoryBytesQuotaPerQuery: int64(memoryBytesQuotaPerQuery),
-		QueueSize:                QueueSize,
-		Logger:                   m.log.With(zap.String("service", "storage-reads")),
-		ExecutorDependencies:     []flux.Dependency{deps},
+		ConcurrencyQuota:                concurrencyQuota,
+		MemoryBytesQuotaPerQuery:        int64(memoryBytesQuotaPerQuery),
+		InitialMemoryBytesQuotaPerQuery: initialMemoryBytes,
+		MemoryBudget:                    memoryBudget,
+		QueueSize:                       QueueSize,
+		Logger:                          m.log.With(zap.String("service", "storage-reads")),
+		ExecutorDependencies:            []flux.Dependency{deps},
+		PlanMetadata:                    influxdb.PlanMetadata,
+		ConstantService:                 m.kvService,
 	})
 	if err != nil {
 		m.log.Error("Failed to create query controller", zap.Error(err))
@@ -620,7 +939,18 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 
 	m.reg.MustRegister(m.queryController.PrometheusCollectors()...)
 
+	queryLog := query.NewMemoryLogger(m.queryLogSize, m.queryLogSlowThreshold)
 	var storageQueryService = readservice.NewProxyQueryService(m.queryController)
+	loggedQueryService := query.NewLoggingProxyQueryService(m.log.With(zap.String("service", "query-log")), queryLog, storageQueryService)
+
+	m.asyncQueries = async.NewService(m.log.With(zap.String("service", "async-query")), kvStore, storageQueryService)
+	m.asyncQueries.MaxResultBytes = int64(m.asyncQueryMaxResultBytes)
+	m.asyncQueries.TTL = m.asyncQueryTTL
+	if err := m.asyncQueries.Open(ctx); err != nil {
+		m.log.Error("Failed to open async query service", zap.Error(err))
+		return err
+	}
+
 	var taskSvc platform.TaskService
 	{
 		// create the task stack
@@ -761,6 +1091,43 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		log.Info("Stopping")
 	}(m.log)
 
+	if m.groupSyncConfig != "" {
+		config, err := groupsync.LoadConfig(m.groupSyncConfig)
+		if err != nil {
+			m.log.Error("Failed to load group sync config", zap.Error(err))
+			return err
+		}
+		syncer := groupsync.NewSyncer(m.log, config, userSvc, userResourceSvc)
+
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			syncer.Run(ctx, m.groupSyncInterval)
+		}()
+	}
+
+	if m.replicationCheckConfig != "" {
+		config, err := replication.LoadConfig(m.replicationCheckConfig)
+		if err != nil {
+			m.log.Error("Failed to load replication check config", zap.Error(err))
+			return err
+		}
+		checker := replication.NewChecker(m.log, config, query.QueryServiceBridge{AsyncQueryService: m.queryController}, func(t replication.Target) query.QueryService {
+			return &http.FluxQueryService{
+				Addr:               t.URL,
+				Token:              t.Token,
+				InsecureSkipVerify: t.InsecureSkipVerify,
+			}
+		})
+		m.reg.MustRegister(checker.PrometheusCollectors()...)
+
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			checker.Run(ctx, m.replicationCheckInterval)
+		}()
+	}
+
 	m.httpServer = &nethttp.Server{
 		Addr: m.httpBindAddress,
 	}
@@ -770,12 +1137,16 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		HTTPErrorHandler:     kithttp.ErrorHandler(0),
 		Logger:               m.log,
 		SessionRenewDisabled: m.sessionRenewDisabled,
+		WriteDecimals:        m.writeDecimals,
 		NewBucketService:     source.NewBucketService,
 		NewQueryService:      source.NewQueryService,
 		PointsWriter:         pointsWriter,
 		DeleteService:        deleteService,
 		BackupService:        backupService,
 		KVBackupService:      m.kvService,
+		CompactionService:    compactionService,
+		DiagnosticsService:   &diagnosticsService{opts: m.opts, MemoryLogger: queryLog},
+		BucketCopyService:    bucketCopyService,
 		AuthorizationService: authSvc,
 		// Wrap the BucketService in a storage backed one that will ensure deleted buckets are removed from the storage engine.
 		BucketService:                   storage.NewBucketService(bucketSvc, m.engine),
@@ -789,12 +1160,14 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		BucketOperationLogService:       bucketLogSvc,
 		UserOperationLogService:         userLogSvc,
 		OrganizationOperationLogService: orgLogSvc,
+		DefinitionHistoryService:        definitionHistorySvc,
 		SourceService:                   sourceSvc,
 		VariableService:                 variableSvc,
 		PasswordsService:                passwdsSvc,
 		OnboardingService:               onboardingSvc,
-		InfluxQLService:                 storageQueryService,
-		FluxService:                     storageQueryService,
+		InfluxQLService:                 loggedQueryService,
+		FluxService:                     loggedQueryService,
+		AsyncQueryService:               m.asyncQueries,
 		TaskService:                     taskSvc,
 		TelegrafService:                 telegrafSvc,
 		NotificationRuleStore:           notificationRuleSvc,
@@ -803,6 +1176,8 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		ScraperTargetStoreService:       scraperTargetSvc,
 		ChronografService:               chronografSvc,
 		SecretService:                   secretSvc,
+		ConstantService:                 m.kvService,
+		RoleService:                     m.kvService,
 		LookupService:                   lookupSvc,
 		DocumentService:                 m.kvService,
 		OrgLookupService:                m.kvService,
@@ -846,12 +1221,14 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		platformHandler := http.NewPlatformHandler(m.apibackend, http.WithResourceHandler(pkgHTTPServer))
 
 		httpLogger := m.log.With(zap.String("service", "http"))
-		m.httpServer.Handler = http.NewHandlerFromRegistry(
-			"platform",
-			m.reg,
+		opts := []http.HandlerOptFn{
 			http.WithLog(httpLogger),
 			http.WithAPIHandler(platformHandler),
-		)
+		}
+		if clockMonitor != nil {
+			opts = append(opts, http.WithHealthHandler(http.NewHealthHandler(clockMonitor)))
+		}
+		m.httpServer.Handler = http.NewHandlerFromRegistry("platform", m.reg, opts...)
 
 		if logconf.Level == zap.DebugLevel {
 			m.httpServer.Handler = http.LoggingMW(httpLogger)(m.httpServer.Handler)
@@ -862,52 +1239,10 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		}
 	}
 
-	ln, err := net.Listen("tcp", m.httpBindAddress)
-	if err != nil {
-		m.log.Error("failed http listener", zap.Error(err))
-		m.log.Info("Stopping")
+	if err := m.serve(m.log, m.httpServer, m.httpTLSCert, m.httpTLSKey, &m.httpPort); err != nil {
 		return err
 	}
-
-	var cer tls.Certificate
-	transport := "http"
-
-	if m.httpTLSCert != "" && m.httpTLSKey != "" {
-		var err error
-		cer, err = tls.LoadX509KeyPair(m.httpTLSCert, m.httpTLSKey)
-
-		if err != nil {
-			m.log.Error("failed to load x509 key pair", zap.Error(err))
-			m.log.Info("Stopping")
-			return err
-		}
-		transport = "https"
-
-		m.httpServer.TLSConfig = &tls.Config{}
-	}
-
-	if addr, ok := ln.Addr().(*net.TCPAddr); ok {
-		m.httpPort = addr.Port
-	}
-
-	m.wg.Add(1)
-	go func(log *zap.Logger) {
-		defer m.wg.Done()
-		log.Info("Listening", zap.String("transport", transport), zap.String("addr", m.httpBindAddress), zap.Int("port", m.httpPort))
-
-		if cer.Certificate != nil {
-			if err := m.httpServer.ServeTLS(ln, m.httpTLSCert, m.httpTLSKey); err != nethttp.ErrServerClosed {
-				log.Error("Failed https service", zap.Error(err))
-			}
-		} else {
-			if err := m.httpServer.Serve(ln); err != nethttp.ErrServerClosed {
-				log.Error("Failed http service", zap.Error(err))
-			}
-		}
-		log.Info("Stopping")
-	}(m.log)
-
-	return nil
+	return m.listenAPI(m.httpServer.Handler)
 }
 
 // isAddressPortAvailable checks whether the address:port is available to listen,
@@ -3,8 +3,11 @@ package launcher_test
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	nethttp "net/http"
+	"os"
+	"strings"
 	"testing"
 
 	platform "github.com/influxdata/influxdb"
@@ -139,3 +142,63 @@ func TestLauncher_SetupWithUsers(t *testing.T) {
 		t.Fatalf("unexpected 2 users: %#+v", exp)
 	}
 }
+
+func TestLauncher_APIListeners(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx,
+		"--write-bind-address", "127.0.0.1:0",
+		"--write-token-auth-only",
+		"--admin-bind-address", "127.0.0.1:0",
+	)
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	if url := l.ListenerURL("query"); url != "" {
+		t.Fatalf("unexpected query listener at %s", url)
+	}
+
+	writePath := fmt.Sprintf("/api/v2/write?org=%s&bucket=%s", l.Org.ID, l.Bucket.ID)
+	for _, tt := range []struct {
+		name     string
+		listener string
+		method   string
+		path     string
+		token    string
+		status   int
+	}{
+		{name: "write", listener: "write", method: "POST", path: writePath, token: l.Auth.Token, status: nethttp.StatusNoContent},
+		{name: "write without token", listener: "write", method: "POST", path: writePath, status: nethttp.StatusUnauthorized},
+		{name: "health of write", listener: "write", method: "GET", path: "/health", status: nethttp.StatusOK},
+		{name: "buckets of write", listener: "write", method: "GET", path: "/api/v2/buckets", token: l.Auth.Token, status: nethttp.StatusNotFound},
+		{name: "buckets of admin", listener: "admin", method: "GET", path: "/api/v2/buckets", token: l.Auth.Token, status: nethttp.StatusOK},
+		{name: "write of admin", listener: "admin", method: "POST", path: writePath, token: l.Auth.Token, status: nethttp.StatusNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := nethttp.NewRequest(tt.method, l.ListenerURL(tt.listener)+tt.path, strings.NewReader("m f=1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Token "+tt.token)
+			}
+			resp, err := nethttp.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Fatalf("unexpected status code: got %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestLauncher_InvalidClockCheckInterval(t *testing.T) {
+	// The launcher fails before it starts, so only its path is removed.
+	l := launcher.NewTestLauncher()
+	defer os.RemoveAll(l.Path)
+
+	err := l.Run(ctx, "--clock-max-backward-jump", "1m", "--clock-check-interval", "0s")
+	if err == nil || !strings.Contains(err.Error(), "interval must be positive") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package launcher
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	nethttp "net/http"
+	"strings"
+
+	"github.com/influxdata/influxdb/http"
+	"github.com/influxdata/influxdb/kit/cli"
+	"go.uber.org/zap"
+)
+
+// Names of the API listeners.
+const (
+	writeListener = "write"
+	queryListener = "query"
+	adminListener = "admin"
+)
+
+// apiListener is an additional HTTP listener serving a part of the API, so
+// that network policy can expose the write path alone to edge networks. The
+// http-bind-address listener keeps serving the whole API.
+type apiListener struct {
+	name        string
+	bindAddress string
+	tlsCert     string
+	tlsKey      string
+	// tokenOnly rejects the requests authenticated with a session, such as
+	// those of the UI, rather than a token.
+	tokenOnly bool
+	// allow returns true if the path is served by the listener.
+	allow func(path string) bool
+
+	server *nethttp.Server
+	port   int
+}
+
+func newAPIListeners() []*apiListener {
+	return []*apiListener{
+		{name: writeListener, allow: isWritePath},
+		{name: queryListener, allow: isQueryPath},
+		{name: adminListener, allow: func(path string) bool {
+			return !isWritePath(path) && !isQueryPath(path)
+		}},
+	}
+}
+
+// options returns the flags configuring the listener.
+func (ln *apiListener) options() []cli.Opt {
+	return []cli.Opt{
+		{
+			DestP: &ln.bindAddress,
+			Flag:  ln.name + "-bind-address",
+			Desc:  fmt.Sprintf("bind address of an additional listener serving the %s API only", ln.name),
+		},
+		{
+			DestP: &ln.tlsCert,
+			Flag:  ln.name + "-tls-cert",
+			Desc:  fmt.Sprintf("TLS certificate for HTTPs of the %s API listener", ln.name),
+		},
+		{
+			DestP: &ln.tlsKey,
+			Flag:  ln.name + "-tls-key",
+			Desc:  fmt.Sprintf("TLS key for HTTPs of the %s API listener", ln.name),
+		},
+		{
+			DestP: &ln.tokenOnly,
+			Flag:  ln.name + "-token-auth-only",
+			Desc:  fmt.Sprintf("only accept token authentication on the %s API listener, rejecting sessions", ln.name),
+		},
+	}
+}
+
+func isWritePath(path string) bool {
+	return hasPathPrefix(path, "/api/v2/write")
+}
+
+func isQueryPath(path string) bool {
+	return hasPathPrefix(path, "/api/v2/query")
+}
+
+// isProbePath returns true for the unauthenticated paths served by every
+// listener, so that each may be health checked.
+func isProbePath(path string) bool {
+	return path == "/ping" || path == http.HealthPath || path == http.ReadyPath
+}
+
+func hasPathPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// handler returns the handler of the listener, serving the paths it allows
+// with h.
+func (ln *apiListener) handler(h nethttp.Handler) nethttp.Handler {
+	return nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if isProbePath(r.URL.Path) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if !ln.allow(r.URL.Path) {
+			nethttp.NotFound(w, r)
+			return
+		}
+		if ln.tokenOnly {
+			if _, err := http.GetToken(r); err != nil {
+				nethttp.Error(w, "token required", nethttp.StatusUnauthorized)
+				return
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// listenAPI starts serving the configured API listeners with h.
+func (m *Launcher) listenAPI(h nethttp.Handler) error {
+	for _, ln := range m.apiListeners {
+		if ln.bindAddress == "" {
+			continue
+		}
+		if (ln.tlsCert == "") != (ln.tlsKey == "") {
+			return fmt.Errorf("%s listener requires both a TLS certificate and key", ln.name)
+		}
+		ln.server = &nethttp.Server{
+			Addr:    ln.bindAddress,
+			Handler: ln.handler(h),
+		}
+		if err := m.serve(m.log.With(zap.String("listener", ln.name)), ln.server, ln.tlsCert, ln.tlsKey, &ln.port); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// serve listens on the address of the server and serves it in the
+// background, with TLS if a certificate and key are given. The port listened
+// on is stored in port.
+func (m *Launcher) serve(log *zap.Logger, server *nethttp.Server, tlsCert, tlsKey string, port *int) error {
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		log.Error("failed http listener", zap.Error(err))
+		log.Info("Stopping")
+		return err
+	}
+
+	var cer tls.Certificate
+	transport := "http"
+
+	if tlsCert != "" && tlsKey != "" {
+		var err error
+		cer, err = tls.LoadX509KeyPair(tlsCert, tlsKey)
+
+		if err != nil {
+			ln.Close()
+			log.Error("failed to load x509 key pair", zap.Error(err))
+			log.Info("Stopping")
+			return err
+		}
+		transport = "https"
+
+		server.TLSConfig = &tls.Config{}
+	}
+
+	if addr, ok := ln.Addr().(*net.TCPAddr); ok {
+		*port = addr.Port
+	}
+
+	m.wg.Add(1)
+	go func(log *zap.Logger) {
+		defer m.wg.Done()
+		log.Info("Listening", zap.String("transport", transport), zap.String("addr", server.Addr), zap.Int("port", *port))
+
+		if cer.Certificate != nil {
+			if err := server.ServeTLS(ln, tlsCert, tlsKey); err != nethttp.ErrServerClosed {
+				log.Error("Failed https service", zap.Error(err))
+			}
+		} else {
+			if err := server.Serve(ln); err != nethttp.ErrServerClosed {
+				log.Error("Failed http service", zap.Error(err))
+			}
+		}
+		log.Info("Stopping")
+	}(log)
+
+	return nil
+}
+
+// ListenerURL returns the URL to connect to the named API listener, or the
+// empty string if it is not listening.
+func (m *Launcher) ListenerURL(name string) string {
+	for _, ln := range m.apiListeners {
+		if ln.name == name && ln.server != nil {
+			return fmt.Sprintf("http://127.0.0.1:%d", ln.port)
+		}
+	}
+	return ""
+}
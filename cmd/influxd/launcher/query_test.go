@@ -257,6 +257,35 @@ from(bucket: "%s")
 	}
 }
 
+func TestPipeline_Query_OrgConstants(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx)
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	if err := l.KeyValueService().PatchConstants(ctx, l.Org.ID, map[string]interface{}{
+		"site":  "lon",
+		"limit": int64(1),
+	}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	l.WritePointsOrFail(t, fmt.Sprintf("m,k=v1 f=0i %d\nm,k=v1 f=1i %d", time.Now().Add(-time.Minute).UnixNano(), time.Now().UnixNano()))
+
+	res := l.FluxQueryOrFail(t, l.Org, l.Auth.Token, fmt.Sprintf(`
+from(bucket: "%s")
+	|> range(start: -5m)
+	|> limit(n: org.constants.limit)
+	|> set(key: "site", value: org.constants.site)
+	|> keep(columns: ["_value", "site"])
+`, l.Bucket.Name))
+	want := "" +
+		",result,table,_value,site\r\n" +
+		",_result,0,0,lon\r\n\r\n"
+	if res != want {
+		t.Fatalf("unexpected result -want/+got:\n\t- %q\n\t+ %q", want, res)
+	}
+}
+
 // We need a separate test for dynamic queries because our Flux e2e tests cannot test them now.
 // Indeed, tableFind would fail while initializing the data in the input bucket, because the data is not
 // written, and tableFind would complain not finding the tables.
@@ -405,3 +434,106 @@ from(bucket: "%s")
 		t.Fatal(err)
 	}
 }
+
+func TestPipeline_Query_Diagnostics(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx, "--query-log-slow-threshold", "0s", "--vault-token", "hunter2")
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	l.WritePointsOrFail(t, "m,k=v f=1i 946684800000000000")
+	qs := fmt.Sprintf(`from(bucket:"%s") |> range(start:2000-01-01T00:00:00Z,stop:2000-01-02T00:00:00Z)`, l.Bucket.Name)
+	l.FluxQueryOrFail(t, l.Org, l.Auth.Token, qs)
+
+	svc := &phttp.DiagnosticsService{Client: l.HTTPClient(t)}
+	ql, err := svc.QueryLog(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ql.Recent) != 1 || len(ql.Slow) != 1 {
+		t.Fatalf("unexpected query log: %+v", ql)
+	}
+	if e := ql.Recent[0]; e.OrganizationID != l.Org.ID || !strings.Contains(string(e.Request), "range(start:2000-01-01T00:00:00Z") || !strings.Contains(e.Plan, "ReadRange") {
+		t.Errorf("unexpected query log entry: %+v", e)
+	}
+
+	opts, err := svc.Config(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]string)
+	for _, o := range opts {
+		values[o.Name] = o.Value
+	}
+	if values["query-log-size"] != "100" || values["vault-token"] != "[REDACTED]" || values["log-level"] != "debug" {
+		t.Errorf("unexpected config: %v", values)
+	}
+}
+
+func TestPipeline_Query_WritePolicy(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx)
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	dst := &influxdb.Bucket{OrgID: l.Org.ID, Name: "dst"}
+	if err := l.BucketService(t).CreateBucket(ctx, dst); err != nil {
+		t.Fatal(err)
+	}
+	l.WritePointsOrFail(t, fmt.Sprintf(`m1,k=v1 f=1i %d`, time.Now().UnixNano()))
+
+	auth := &influxdb.Authorization{
+		OrgID:  l.Org.ID,
+		UserID: l.User.ID,
+		Permissions: []influxdb.Permission{
+			{
+				Action:   influxdb.ReadAction,
+				Resource: influxdb.Resource{Type: influxdb.BucketsResourceType, OrgID: &l.Org.ID},
+			},
+			{
+				Action:   influxdb.WriteAction,
+				Resource: influxdb.Resource{Type: influxdb.BucketsResourceType, OrgID: &l.Org.ID},
+			},
+		},
+		WritePolicy: &influxdb.WritePolicy{AllowMeasurements: []string{"m1"}},
+	}
+	if err := l.AuthorizationService(t).CreateAuthorization(ctx, auth); err != nil {
+		t.Fatalf("unexpected error creating authorization: %s", err)
+	}
+
+	query := func(measurement string) error {
+		return l.QueryAndNopConsume(ctx, &query.Request{
+			Authorization:  auth,
+			OrganizationID: l.Org.ID,
+			Compiler: lang.FluxCompiler{
+				Query: fmt.Sprintf(`
+from(bucket: "%s")
+	|> range(start: -5m)
+	|> set(key: "_measurement", value: "%s")
+	|> to(bucket: "%s")
+`, l.Bucket.Name, measurement, dst.Name),
+			},
+		})
+	}
+	l.Auth = auth
+
+	// to() writes the measurements allowed by the write policy of the token.
+	if err := query("m1"); err != nil {
+		t.Fatalf("unexpected error writing an allowed measurement: %s", err)
+	}
+	if err := query("m2"); err == nil {
+		t.Error("expected error writing a denied measurement")
+	} else if !strings.Contains(err.Error(), `measurement "m2" is not allowed by the write policy of the token`) {
+		t.Errorf("unexpected error writing a denied measurement: %s", err)
+	}
+
+	// Copies are rejected as they write every measurement of the bucket.
+	client, err := phttp.NewHTTPClient(l.URL(), auth.Token, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copier := &phttp.BucketCopyService{Client: client}
+	src := influxdb.BucketCopyTarget{OrgID: l.Org.ID, BucketID: l.Bucket.ID}
+	_, err = copier.CopyBucketRange(ctx, src, influxdb.BucketCopyTarget{OrgID: l.Org.ID, BucketID: dst.ID}, 0, time.Now().UnixNano())
+	if got, want := influxdb.ErrorCode(err), influxdb.EForbidden; got != want {
+		t.Errorf("unexpected error code copying with a write policy: got %q, want %q (%v)", got, want, err)
+	}
+}
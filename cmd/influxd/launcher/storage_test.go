@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"io/ioutil"
 	nethttp "net/http"
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/bolt"
 	"github.com/influxdata/influxdb/cmd/influxd/launcher"
 	"github.com/influxdata/influxdb/http"
 	"github.com/influxdata/influxdb/toml"
@@ -53,6 +55,54 @@ func TestStorage_WriteAndQuery(t *testing.T) {
 	}
 }
 
+func TestStorage_WriteAndQuery_Unsigned(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx)
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	// The largest unsigned values can not be represented by integers or
+	// floats, so they are only returned exactly if stored as unsigned.
+	l.WritePointsOrFail(t, "m,k=v1 f=18446744073709551615u 946684800000000000\nm,k=v1 f=1u 946684800000000001")
+
+	qs := fmt.Sprintf(`from(bucket:"%s") |> range(start:2000-01-01T00:00:00Z,stop:2000-01-02T00:00:00Z) |> keep(columns: ["_value"])`, l.Bucket.Name)
+
+	exp := `,result,table,_value` + "\r\n" +
+		`,_result,0,18446744073709551615` + "\r\n" +
+		`,_result,0,1` + "\r\n\r\n"
+	if got := l.FluxQueryOrFail(t, l.Org, l.Auth.Token, qs); !cmp.Equal(got, exp) {
+		t.Errorf("unexpected query results -got/+exp\n%s", cmp.Diff(got, exp))
+	}
+}
+
+func TestStorage_WriteAndQuery_Decimal(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx, "--write-decimal-fields")
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	// Decimals are returned exactly, with their trailing zeros, as string
+	// values since Flux has no decimal type.
+	l.WritePointsOrFail(t, "m,k=v1 f=12.30d 946684800000000000\nm,k=v1 f=-0.000000000000000001d 946684800000000001")
+
+	qs := fmt.Sprintf(`from(bucket:"%s") |> range(start:2000-01-01T00:00:00Z,stop:2000-01-02T00:00:00Z) |> keep(columns: ["_value"])`, l.Bucket.Name)
+
+	exp := `,result,table,_value` + "\r\n" +
+		`,_result,0,12.30` + "\r\n" +
+		`,_result,0,-0.000000000000000001` + "\r\n\r\n"
+	if got := l.FluxQueryOrFail(t, l.Org, l.Auth.Token, qs); !cmp.Equal(got, exp) {
+		t.Errorf("unexpected query results -got/+exp\n%s", cmp.Diff(got, exp))
+	}
+}
+
+func TestStorage_Write_DecimalNotEnabled(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx)
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	if err := l.WritePoints("m,k=v1 f=12.30d 946684800000000000"); err == nil {
+		t.Fatal("expected decimal field to be rejected")
+	}
+}
+
 func TestLauncher_WriteAndQuery(t *testing.T) {
 	l := launcher.RunTestLauncherOrFail(t, ctx)
 	l.SetupOrFail(t)
@@ -91,6 +141,28 @@ func TestLauncher_WriteAndQuery(t *testing.T) {
 	}
 }
 
+func TestStorage_MemoryStore(t *testing.T) {
+	l := launcher.RunTestLauncherOrFail(t, ctx, "--store", "memory")
+	l.SetupOrFail(t)
+	defer l.ShutdownOrFail(t, ctx)
+
+	l.WritePointsOrFail(t, `m,k=v f=100i 946684800000000000`)
+
+	qs := `from(bucket:"BUCKET") |> range(start:2000-01-01T00:00:00Z,stop:2000-01-02T00:00:00Z)`
+	exp := `,result,table,_start,_stop,_time,_value,_field,_measurement,k` + "\r\n" +
+		`,_result,0,2000-01-01T00:00:00Z,2000-01-02T00:00:00Z,2000-01-01T00:00:00Z,100,f,m,v` + "\r\n\r\n"
+	if got := l.FluxQueryOrFail(t, l.Org, l.Auth.Token, qs); !cmp.Equal(got, exp) {
+		t.Errorf("unexpected query results -got/+exp\n%s", cmp.Diff(got, exp))
+	}
+
+	// Nothing is written to the configured paths.
+	for _, name := range []string{bolt.DefaultFilename, "engine"} {
+		if _, err := os.Stat(filepath.Join(l.Path, name)); !os.IsNotExist(err) {
+			t.Errorf("unexpected %s: %v", name, err)
+		}
+	}
+}
+
 func TestLauncher_BucketDelete(t *testing.T) {
 	l := launcher.RunTestLauncherOrFail(t, ctx)
 	l.SetupOrFail(t)
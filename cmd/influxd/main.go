@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	_ "net/http/pprof"
 	"os"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/influxdata/flux"
 	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influxd/debug"
 	"github.com/influxdata/influxdb/cmd/influxd/generate"
 	"github.com/influxdata/influxdb/cmd/influxd/inspect"
 	"github.com/influxdata/influxdb/cmd/influxd/launcher"
@@ -47,6 +49,7 @@ func init() {
 	rootCmd.AddCommand(launcher.NewCommand())
 	rootCmd.AddCommand(generate.Command)
 	rootCmd.AddCommand(inspect.NewCommand())
+	rootCmd.AddCommand(debug.NewCommand())
 	rootCmd.AddCommand(restore.Command)
 
 	// TODO: this should be removed in the future: https://github.com/influxdata/influxdb/issues/16220
@@ -71,6 +74,11 @@ func find(args []string) *cobra.Command {
 func main() {
 	cmd := find(os.Args[1:])
 	if err := cmd.Execute(); err != nil {
+		// Commands may exit with a status telling errors apart.
+		var e interface{ ExitCode() int }
+		if errors.As(err, &e) {
+			os.Exit(e.ExitCode())
+		}
 		os.Exit(1)
 	}
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/influxdata/influxdb/bolt"
 	"github.com/influxdata/influxdb/cmd/influxd/inspect"
 	"github.com/influxdata/influxdb/http"
+	"github.com/influxdata/influxdb/internal/backup"
 	"github.com/influxdata/influxdb/internal/fs"
 	"github.com/influxdata/influxdb/kit/cli"
 	"github.com/influxdata/influxdb/storage"
@@ -30,6 +31,11 @@ Rebuilding the index and series file uses default options as in
 For additional performance options, run restore with "-rebuild-index false"
 and build-tsi afterwards.
 
+When the backup has a manifest, every file is decrypted, decompressed and
+checked against its checksum before any existing data is moved. Encrypted
+backups require the key they were encrypted with, given by
+"-encryption-key-file".
+
 NOTES:
 
 * The influxd server should not be running when using the restore tool
@@ -44,9 +50,17 @@ var flags struct {
 	enginePath string
 	credPath   string
 	backupPath string
+	keyPath    string
 	rebuildTSI bool
 }
 
+// manifest lists the files of the backup, if it has one, which are
+// decrypted with key.
+var (
+	manifest *backup.Manifest
+	key      []byte
+)
+
 func init() {
 	dir, err := fs.InfluxDir()
 	if err != nil {
@@ -83,6 +97,12 @@ func init() {
 			Default: "",
 			Desc:    "path to backup files",
 		},
+		{
+			DestP:   &flags.keyPath,
+			Flag:    "encryption-key-file",
+			Default: "",
+			Desc:    "path of a file holding the key the backup files are encrypted with",
+		},
 		{
 			DestP:   &flags.rebuildTSI,
 			Flag:    "rebuild-index",
@@ -99,6 +119,10 @@ func restoreE(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no backup path given")
 	}
 
+	if err := verifyBackup(); err != nil {
+		return fmt.Errorf("failed to verify backup: %v", err)
+	}
+
 	if err := moveBolt(); err != nil {
 		return fmt.Errorf("failed to move existing bolt file: %v", err)
 	}
@@ -147,6 +171,70 @@ func restoreE(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// verifyBackup reads the manifest of the backup and checks all of its files
+// before anything is restored. Backups without a manifest are restored as is.
+func verifyBackup() error {
+	if flags.keyPath != "" {
+		k, err := backup.ReadKeyFile(flags.keyPath)
+		if err != nil {
+			return fmt.Errorf("unable to read encryption key: %v", err)
+		}
+		key = k
+	}
+
+	m, err := backup.ReadManifest(flags.backupPath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := m.Verify(flags.backupPath, key); err != nil {
+		return err
+	}
+	manifest = m
+	return nil
+}
+
+// openBackupFile opens the file of the backup named name.
+func openBackupFile(name string) (io.ReadCloser, error) {
+	if manifest == nil {
+		return os.Open(filepath.Join(flags.backupPath, name))
+	}
+	f, ok := manifest.File(name)
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return backup.Open(flags.backupPath, f, key)
+}
+
+// backupTSMFiles returns the names of the TSM files of the backup.
+func backupTSMFiles() ([]string, error) {
+	var names []string
+	if manifest != nil {
+		for _, f := range manifest.Files {
+			if strings.Contains(f.Name, ".tsm") {
+				names = append(names, f.Name)
+			}
+		}
+		return names, nil
+	}
+
+	err := filepath.Walk(flags.backupPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.Contains(path, ".tsm") {
+			name, err := filepath.Rel(flags.backupPath, path)
+			if err != nil {
+				return err
+			}
+			names = append(names, name)
+		}
+		return nil
+	})
+	return names, err
+}
+
 func moveBolt() error {
 	if _, err := os.Stat(flags.boltPath); os.IsNotExist(err) {
 		return nil
@@ -222,7 +310,7 @@ func removeIfExists(path string) error {
 func restoreBolt() error {
 	backupBolt := filepath.Join(flags.backupPath, bolt.DefaultFilename)
 
-	if err := restoreFile(backupBolt, flags.boltPath, "bolt"); err != nil {
+	if err := restoreFile(bolt.DefaultFilename, flags.boltPath, "bolt"); err != nil {
 		return err
 	}
 
@@ -236,37 +324,24 @@ func restoreEngine() error {
 		return err
 	}
 
-	count := 0
-	err := filepath.Walk(flags.backupPath, func(path string, info os.FileInfo, err error) error {
-		if strings.Contains(path, ".tsm") {
-			f, err := os.OpenFile(path, os.O_RDONLY, 0666)
-			if err != nil {
-				return fmt.Errorf("error opening TSM file: %v", err)
-			}
-			defer f.Close()
-
-			tsmPath := filepath.Join(dataDir, filepath.Base(path))
-			w, err := os.OpenFile(tsmPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
-			if err != nil {
-				return err
-			}
-			defer w.Close()
+	names, err := backupTSMFiles()
+	if err != nil {
+		return err
+	}
 
-			_, err = io.Copy(w, f)
-			if err != nil {
-				return err
-			}
-			count++
-			return nil
+	count := 0
+	for _, name := range names {
+		if err := restoreFile(name, filepath.Join(dataDir, filepath.Base(name)), "TSM"); err != nil {
+			return err
 		}
-		return nil
-	})
+		count++
+	}
 	fmt.Printf("Restored %d TSM files to %v\n", count, dataDir)
-	return err
+	return nil
 }
 
-func restoreFile(backup string, target string, filetype string) error {
-	f, err := os.Open(backup)
+func restoreFile(name string, target string, filetype string) error {
+	f, err := openBackupFile(name)
 	if err != nil {
 		return fmt.Errorf("no %s file in backup: %v", filetype, err)
 	}
@@ -288,15 +363,16 @@ func restoreFile(backup string, target string, filetype string) error {
 func restoreCred() error {
 	backupCred := filepath.Join(flags.backupPath, http.DefaultTokenFile)
 
-	_, err := os.Stat(backupCred)
+	f, err := openBackupFile(http.DefaultTokenFile)
 	if os.IsNotExist(err) {
 		fmt.Printf("No credentials file found in backup, skipping.\n")
 		return nil
 	} else if err != nil {
 		return err
 	}
+	f.Close()
 
-	if err := restoreFile(backupCred, flags.credPath, "credentials"); err != nil {
+	if err := restoreFile(http.DefaultTokenFile, flags.credPath, "credentials"); err != nil {
 		return err
 	}
 
@@ -0,0 +1,50 @@
+package influxdb
+
+import (
+	"context"
+	"time"
+)
+
+// CompactionService controls the background compactions of the storage engine.
+type CompactionService interface {
+	// PauseCompactions stops level compactions. If timeout is positive, compactions
+	// resume automatically once it has elapsed.
+	PauseCompactions(ctx context.Context, timeout time.Duration) error
+	// ResumeCompactions restarts compactions stopped by PauseCompactions.
+	ResumeCompactions(ctx context.Context) error
+	// CompactionStatus reports whether compactions are currently paused.
+	CompactionStatus(ctx context.Context) (*CompactionStatus, error)
+	// CompactionReport reports the bytes recently written by snapshots and compactions.
+	CompactionReport(ctx context.Context) (*CompactionReport, error)
+}
+
+// CompactionStatus describes the state of the storage engine compactions.
+type CompactionStatus struct {
+	Paused bool `json:"paused"`
+	// ResumeAt is the time at which paused compactions resume automatically.
+	// It is nil when compactions are running or paused without a timeout.
+	ResumeAt *time.Time `json:"resumeAt,omitempty"`
+}
+
+// CompactionReport summarizes the bytes written to TSM files by the cache
+// snapshots and compactions of the storage engine over a recent period.
+type CompactionReport struct {
+	// Since is the start of the reported period.
+	Since time.Time `json:"since"`
+	// Levels lists the snapshots, level compactions, optimize and full
+	// compactions, in that order.
+	Levels []CompactionLevelReport `json:"levels"`
+	// WriteAmplification is the number of bytes written by snapshots and
+	// compactions for each byte written by snapshots. It is zero if no
+	// snapshot was written.
+	WriteAmplification float64 `json:"writeAmplification"`
+}
+
+// CompactionLevelReport is the number of snapshots or compactions of a level
+// and the bytes they wrote.
+type CompactionLevelReport struct {
+	// Level is snapshot, 1, 2, 3, optimize or full.
+	Level        string `json:"level"`
+	Compactions  int    `json:"compactions"`
+	BytesWritten uint64 `json:"bytesWritten"`
+}
@@ -0,0 +1,163 @@
+package influxdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrConstantNotFound is the error msg for a missing constant.
+const ErrConstantNotFound = "constant not found"
+
+// ConstantService stores the named constants of organizations, such as SLA
+// thresholds or lists of sites, which the Flux queries of an organization
+// refer to as org.constants.
+//
+// The value of a constant is a string, an int64, a float64, a bool, or a
+// []interface{} of values of one of these types.
+type ConstantService interface {
+	// FindConstants returns the constants of the organization orgID by name.
+	FindConstants(ctx context.Context, orgID ID) (map[string]interface{}, error)
+
+	// PatchConstants creates the constants of m for the organization orgID,
+	// or replaces the values of those already defined.
+	PatchConstants(ctx context.Context, orgID ID, m map[string]interface{}) error
+
+	// DeleteConstants removes the named constants of the organization orgID.
+	DeleteConstants(ctx context.Context, orgID ID, names ...string) error
+}
+
+// ValidateConstant returns an error if name is not a valid Flux identifier,
+// or if v is not a valid constant value.
+func ValidateConstant(name string, v interface{}) error {
+	if err := validateConstantName(name); err != nil {
+		return err
+	}
+	if err := validateConstantValue(v, true); err != nil {
+		return &Error{
+			Code: EInvalid,
+			Msg:  fmt.Sprintf("invalid value of constant %q: %v", name, err),
+		}
+	}
+	return nil
+}
+
+func validateConstantName(name string) error {
+	valid := name != ""
+	for i, c := range name {
+		if !(c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && '0' <= c && c <= '9') {
+			valid = false
+			break
+		}
+	}
+	if !valid {
+		return &Error{
+			Code: EInvalid,
+			Msg:  fmt.Sprintf("invalid constant name %q: names must be identifiers of letters, digits and underscores", name),
+		}
+	}
+	return nil
+}
+
+func validateConstantValue(v interface{}, array bool) error {
+	switch v := v.(type) {
+	case string, int64, bool:
+		return nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("%v is not a finite number", v)
+		}
+		return nil
+	case []interface{}:
+		if !array {
+			return fmt.Errorf("arrays can not be nested")
+		}
+		if len(v) == 0 {
+			return fmt.Errorf("arrays can not be empty")
+		}
+		for _, e := range v {
+			if err := validateConstantValue(e, false); err != nil {
+				return err
+			}
+			if reflect.TypeOf(e) != reflect.TypeOf(v[0]) {
+				return fmt.Errorf("the elements of arrays must have the same type")
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+// MarshalConstant returns the JSON encoding of the value of a constant.
+// Floats are encoded with a decimal point, so that UnmarshalConstant does not
+// decode them as integers.
+func MarshalConstant(v interface{}) (json.RawMessage, error) {
+	switch v := v.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("%v is not a finite number", v)
+		}
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return json.RawMessage(s), nil
+	case []interface{}:
+		var buf bytes.Buffer
+		buf.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			b, err := MarshalConstant(e)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(b)
+		}
+		buf.WriteByte(']')
+		return buf.Bytes(), nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// UnmarshalConstant decodes the JSON encoding of the value of a constant.
+// Numbers without a decimal point or an exponent are decoded as integers.
+func UnmarshalConstant(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return normalizeConstant(v)
+}
+
+// normalizeConstant converts the numbers of a value decoded with UseNumber.
+func normalizeConstant(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case json.Number:
+		if !strings.ContainsAny(v.String(), ".eE") {
+			if i, err := v.Int64(); err == nil {
+				return i, nil
+			}
+		}
+		return v.Float64()
+	case []interface{}:
+		for i, e := range v {
+			n, err := normalizeConstant(e)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = n
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,32 @@
+package influxdb
+
+import "context"
+
+// BucketCopyService copies raw data between buckets.
+type BucketCopyService interface {
+	// CopyBucketRange copies the data of a bucket with timestamps within
+	// [min, max] to another bucket, possibly of another organization. Copied
+	// values overwrite values of the destination bucket with the same series
+	// and timestamp.
+	CopyBucketRange(ctx context.Context, src, dst BucketCopyTarget, min, max int64) (*BucketCopyStats, error)
+}
+
+// BucketCopyTarget identifies the source or destination bucket of a copy.
+type BucketCopyTarget struct {
+	OrgID    ID `json:"orgID"`
+	BucketID ID `json:"bucketID"`
+}
+
+// BucketCopyStats summarizes the data copied between buckets.
+type BucketCopyStats struct {
+	// Series is the number of series copied.
+	Series int `json:"series"`
+	// Blocks is the number of storage blocks copied without being decoded.
+	Blocks int `json:"blocks"`
+	// ReencodedBlocks is the number of storage blocks that were decoded and
+	// re-encoded because they were only partially within the time range, or
+	// had deleted values.
+	ReencodedBlocks int `json:"reencodedBlocks"`
+	// Bytes is the size of the copied blocks.
+	Bytes int64 `json:"bytes"`
+}
@@ -0,0 +1,47 @@
+package influxdb
+
+import (
+	"context"
+	"time"
+)
+
+// ErrDefinitionVersionNotFound is returned when a version of a definition can
+// not be found.
+var ErrDefinitionVersionNotFound = &Error{
+	Code: ENotFound,
+	Msg:  "definition version not found",
+}
+
+// DefinitionVersion is a version of the definition of a task, check or
+// notification rule, recorded when the resource is created and every time
+// its definition changes.
+type DefinitionVersion struct {
+	ResourceType ResourceType `json:"resourceType"`
+	ResourceID   ID           `json:"resourceID"`
+	// Version numbers the versions of the definition of a resource from 1.
+	Version int `json:"version"`
+	// Definition is the Flux script of a task, or the JSON of a check or
+	// notification rule.
+	Definition string    `json:"definition"`
+	UserID     ID        `json:"userID,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+// DefinitionHistoryService is an interface for retrieving the versions of the
+// definitions of tasks, checks and notification rules.
+type DefinitionHistoryService interface {
+	// FindDefinitionVersions returns the versions of the definition of the
+	// resource, by default from the most recent one, and their number.
+	FindDefinitionVersions(ctx context.Context, resourceType ResourceType, id ID, opts FindOptions) ([]*DefinitionVersion, int, error)
+
+	// FindDefinitionVersion returns a single version of the definition of the
+	// resource.
+	FindDefinitionVersion(ctx context.Context, resourceType ResourceType, id ID, version int) (*DefinitionVersion, error)
+}
+
+// DefaultDefinitionHistoryFindOptions are the default options for the
+// versions of a definition.
+var DefaultDefinitionHistoryFindOptions = FindOptions{
+	Descending: true,
+	Limit:      100,
+}
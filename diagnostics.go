@@ -0,0 +1,44 @@
+package influxdb
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// DiagnosticsService reports the configuration of the server and the queries
+// it ran recently, which support bundles collect.
+type DiagnosticsService interface {
+	// Config returns the configuration options of the server, with the
+	// values of those holding secrets redacted.
+	Config(ctx context.Context) ([]ConfigOption, error)
+	// QueryLog returns the most recent queries and the most recent slow queries.
+	QueryLog(ctx context.Context) (*QueryLog, error)
+}
+
+// ConfigOption is the value of a configuration option of the server.
+type ConfigOption struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// QueryLog lists queries run by the server, most recent first.
+type QueryLog struct {
+	// SlowThreshold is the duration from which a query is slow.
+	SlowThreshold time.Duration   `json:"slowThreshold"`
+	Recent        []QueryLogEntry `json:"recent"`
+	Slow          []QueryLogEntry `json:"slow"`
+}
+
+// QueryLogEntry describes a query run by the server. Durations are in nanoseconds.
+type QueryLogEntry struct {
+	Time           time.Time     `json:"time"`
+	OrganizationID ID            `json:"orgID"`
+	Duration       time.Duration `json:"duration"`
+	// Request is the body of the query request, without its authorization.
+	Request json.RawMessage `json:"request,omitempty"`
+	// Plan is the physical plan of the query, if it was planned.
+	Plan         string `json:"plan,omitempty"`
+	ResponseSize int64  `json:"responseSize"`
+	Error        string `json:"error,omitempty"`
+}
@@ -0,0 +1,87 @@
+// Package federation lets the queries of an InfluxDB read the buckets of
+// other InfluxDB instances, its peers, as from(bucket: "<peer>/<bucket>").
+//
+// The reads of the buckets of a peer, with their range, filter, group and
+// window aggregate, are sent to the peer as a Flux query, so that the peer
+// pushes them down to its own storage. The rest of the query, such as a
+// join or a union with local buckets, is performed locally.
+package federation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/influxdata/influxdb"
+)
+
+// Config configures the peers of a federation.
+type Config struct {
+	Peers []Peer `json:"peers"`
+}
+
+// Peer is a remote InfluxDB whose buckets are read by the local queries.
+type Peer struct {
+	// Name is the prefix of the buckets of the peer in from().
+	Name string `json:"name"`
+
+	// URL is the address of the InfluxDB, and Token a token allowed to read
+	// its buckets. The queries of the peer are performed in the
+	// organization OrgID.
+	URL                string      `json:"url"`
+	Token              string      `json:"token"`
+	InsecureSkipVerify bool        `json:"insecureSkipVerify"`
+	OrgID              influxdb.ID `json:"orgID"`
+
+	// LocalOrgIDs are the local organizations whose queries may read the
+	// buckets of the peer, all of them if empty. Any member of these
+	// organizations reads the peer with the permissions of Token.
+	LocalOrgIDs []influxdb.ID `json:"localOrgIDs"`
+}
+
+// LoadConfig reads a JSON configuration from the file at path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("unable to parse federation config %s: %v", path, err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid federation config %s: %v", path, err)
+	}
+	return &c, nil
+}
+
+// Validate returns an error if the configuration is invalid.
+func (c *Config) Validate() error {
+	if len(c.Peers) == 0 {
+		return fmt.Errorf("at least one peer is required")
+	}
+	names := make(map[string]bool)
+	for i, p := range c.Peers {
+		if p.Name == "" || p.URL == "" {
+			return fmt.Errorf("peer %d: name and url are required", i)
+		}
+		if strings.Contains(p.Name, "/") {
+			return fmt.Errorf("peer %q: name must not contain /", p.Name)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("duplicate peer %q", p.Name)
+		}
+		names[p.Name] = true
+		if !p.OrgID.Valid() {
+			return fmt.Errorf("peer %q: orgID is required", p.Name)
+		}
+		for _, id := range p.LocalOrgIDs {
+			if !id.Valid() {
+				return fmt.Errorf("peer %q: invalid local organization ID", p.Name)
+			}
+		}
+	}
+	return nil
+}
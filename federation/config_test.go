@@ -0,0 +1,55 @@
+package federation_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/federation"
+	"github.com/influxdata/influxdb/query"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	peer := federation.Peer{Name: "eu", URL: "http://eu:9999", OrgID: 1}
+	for _, tt := range []struct {
+		name  string
+		peers []federation.Peer
+		err   bool
+	}{
+		{name: "valid", peers: []federation.Peer{peer}},
+		{name: "no peers", err: true},
+		{name: "missing url", peers: []federation.Peer{{Name: "eu", OrgID: 1}}, err: true},
+		{name: "missing org", peers: []federation.Peer{{Name: "eu", URL: "http://eu:9999"}}, err: true},
+		{name: "slash in name", peers: []federation.Peer{{Name: "e/u", URL: "http://eu:9999", OrgID: 1}}, err: true},
+		{name: "duplicate", peers: []federation.Peer{peer, peer}, err: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &federation.Config{Peers: tt.peers}
+			if err := c.Validate(); (err != nil) != tt.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestService_HasPeer(t *testing.T) {
+	s := federation.NewService(&federation.Config{Peers: []federation.Peer{
+		{Name: "eu", URL: "http://eu:9999", OrgID: 1},
+		{Name: "us", URL: "http://us:9999", OrgID: 1, LocalOrgIDs: []influxdb.ID{2}},
+	}}, func(federation.Peer) query.QueryService { return nil })
+
+	for _, tt := range []struct {
+		orgID influxdb.ID
+		name  string
+		want  bool
+	}{
+		{orgID: 2, name: "eu", want: true},
+		{orgID: 3, name: "eu", want: true},
+		{orgID: 2, name: "us", want: true},
+		{orgID: 3, name: "us", want: false},
+		{orgID: 2, name: "asia", want: false},
+	} {
+		if got := s.HasPeer(tt.orgID, tt.name); got != tt.want {
+			t.Errorf("HasPeer(%v, %q) = %v, want %v", tt.orgID, tt.name, got, tt.want)
+		}
+	}
+}
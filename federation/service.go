@@ -0,0 +1,62 @@
+package federation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/lang"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/query"
+)
+
+// Service reads the buckets of the peers of a federation. It implements the
+// PeerService of from().
+type Service struct {
+	peers map[string]peer
+}
+
+type peer struct {
+	Peer
+	qs query.QueryService
+}
+
+// NewService returns the service of the peers of the configuration, querying
+// each peer with the query service returned by newQueryService.
+func NewService(c *Config, newQueryService func(p Peer) query.QueryService) *Service {
+	s := &Service{peers: make(map[string]peer, len(c.Peers))}
+	for _, p := range c.Peers {
+		s.peers[p.Name] = peer{Peer: p, qs: newQueryService(p)}
+	}
+	return s
+}
+
+// HasPeer returns true if name is a peer whose buckets the queries of the
+// organization orgID may read.
+func (s *Service) HasPeer(orgID influxdb.ID, name string) bool {
+	p, ok := s.peers[name]
+	if !ok {
+		return false
+	}
+	if len(p.LocalOrgIDs) == 0 {
+		return true
+	}
+	for _, id := range p.LocalOrgIDs {
+		if id == orgID {
+			return true
+		}
+	}
+	return false
+}
+
+// QueryPeer executes the Flux query q on the peer name.
+func (s *Service) QueryPeer(ctx context.Context, name, q string) (flux.ResultIterator, error) {
+	p, ok := s.peers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown peer %q", name)
+	}
+	return p.qs.Query(ctx, &query.Request{
+		OrganizationID: p.OrgID,
+		Compiler:       lang.FluxCompiler{Query: q},
+	})
+}
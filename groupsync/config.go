@@ -0,0 +1,117 @@
+// Package groupsync synchronizes the membership of organizations with the
+// groups of an external user directory.
+package groupsync
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/influxdata/influxdb"
+)
+
+// Source types.
+const (
+	SCIMSourceType = "scim"
+	LDAPSourceType = "ldap"
+)
+
+// Config configures the synchronization of organization membership.
+type Config struct {
+	// Source is the directory the groups are read from.
+	Source SourceConfig `json:"source"`
+
+	// Mappings maps directory groups to organizations.
+	Mappings []Mapping `json:"mappings"`
+
+	// Prune removes the members of the mapped organizations that are not
+	// in any of the groups mapped to them. Without it, synchronization only
+	// adds members and changes their role.
+	Prune bool `json:"prune"`
+}
+
+// SourceConfig configures the directory groups are read from.
+type SourceConfig struct {
+	// Type is the type of the directory. Only "scim" is supported.
+	Type string `json:"type"`
+
+	// URL is the base URL of the SCIM 2.0 API, without the /Groups path.
+	URL string `json:"url"`
+
+	// Token is the bearer token used to authenticate to the directory.
+	Token string `json:"token"`
+}
+
+// Mapping grants the members of a directory group a role in an organization.
+type Mapping struct {
+	// Group is the display name of the directory group.
+	Group string `json:"group"`
+
+	// OrgID is the organization the members of the group are added to.
+	OrgID influxdb.ID `json:"orgID"`
+
+	// Role is the permission set granted to the members in the organization,
+	// either "member" or "owner". It defaults to "member". A user in groups
+	// mapped to both roles of an organization is an owner.
+	Role influxdb.UserType `json:"role"`
+}
+
+// LoadConfig reads a JSON configuration from the file at path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("unable to parse group sync config %s: %v", path, err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid group sync config %s: %v", path, err)
+	}
+	return &c, nil
+}
+
+// Validate returns an error if the configuration is invalid.
+func (c *Config) Validate() error {
+	switch c.Source.Type {
+	case SCIMSourceType:
+		if c.Source.URL == "" {
+			return fmt.Errorf("scim source requires a url")
+		}
+	case LDAPSourceType:
+		return fmt.Errorf("ldap source is not supported, expose the directory through SCIM instead")
+	default:
+		return fmt.Errorf("unknown source type %q", c.Source.Type)
+	}
+
+	if len(c.Mappings) == 0 {
+		return fmt.Errorf("at least one mapping is required")
+	}
+	for i, m := range c.Mappings {
+		if m.Group == "" {
+			return fmt.Errorf("mapping %d: group is required", i)
+		}
+		if !m.OrgID.Valid() {
+			return fmt.Errorf("mapping %d: orgID is required", i)
+		}
+		switch m.Role {
+		case "", influxdb.Member, influxdb.Owner:
+		default:
+			return fmt.Errorf("mapping %d: unknown role %q", i, m.Role)
+		}
+	}
+	return nil
+}
+
+// NewSource returns the source of groups described by c.
+func (c *Config) NewSource() Source {
+	return &SCIMSource{
+		URL:    c.Source.URL,
+		Token:  c.Source.Token,
+		Client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
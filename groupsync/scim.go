@@ -0,0 +1,116 @@
+package groupsync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Group is a group of a directory.
+type Group struct {
+	Name string
+	// Members are the user names of the members of the group.
+	Members []string
+}
+
+// Source lists the groups of a directory.
+type Source interface {
+	Groups(ctx context.Context) ([]Group, error)
+}
+
+// scimPageSize is the number of groups requested per page.
+const scimPageSize = 100
+
+// SCIMSource lists the groups of a SCIM 2.0 directory.
+type SCIMSource struct {
+	// URL is the base URL of the SCIM API.
+	URL string
+
+	// Token is the optional bearer token of the requests.
+	Token string
+
+	Client *http.Client
+}
+
+type scimListResponse struct {
+	TotalResults int `json:"totalResults"`
+	StartIndex   int `json:"startIndex"`
+	ItemsPerPage int `json:"itemsPerPage"`
+	Resources    []struct {
+		DisplayName string `json:"displayName"`
+		Members     []struct {
+			Value   string `json:"value"`
+			Display string `json:"display"`
+		} `json:"members"`
+	} `json:"Resources"`
+}
+
+// Groups returns every group of the directory. Members are identified by
+// their display name, which must match the name of the InfluxDB user.
+func (s *SCIMSource) Groups(ctx context.Context) ([]Group, error) {
+	var groups []Group
+	for start := 1; ; {
+		page, err := s.groups(ctx, start)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range page.Resources {
+			g := Group{Name: r.DisplayName}
+			for _, m := range r.Members {
+				if m.Display != "" {
+					g.Members = append(g.Members, m.Display)
+				}
+			}
+			groups = append(groups, g)
+		}
+
+		start += len(page.Resources)
+		if len(page.Resources) == 0 || start > page.TotalResults {
+			return groups, nil
+		}
+	}
+}
+
+// groups returns the page of groups starting at the 1-based index start.
+func (s *SCIMSource) groups(ctx context.Context, start int) (*scimListResponse, error) {
+	params := url.Values{}
+	params.Set("attributes", "displayName,members")
+	params.Set("startIndex", strconv.Itoa(start))
+	params.Set("count", strconv.Itoa(scimPageSize))
+	u := strings.TrimSuffix(s.URL, "/") + "/Groups?" + params.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/scim+json")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status listing SCIM groups: %s", resp.Status)
+	}
+
+	var page scimListResponse
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("unable to decode SCIM groups: %v", err)
+	}
+	return &page, nil
+}
@@ -0,0 +1,201 @@
+package groupsync
+
+import (
+	"context"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	influxlogger "github.com/influxdata/influxdb/logger"
+	"go.uber.org/zap"
+)
+
+// Report summarizes the changes made by a synchronization.
+type Report struct {
+	UsersCreated   int // users created because they were first seen in a group
+	MembersAdded   int // users added to an organization
+	MembersUpdated int // members whose role changed
+	MembersRemoved int // members removed because they are in no mapped group
+}
+
+// Syncer synchronizes the membership of organizations with directory groups.
+type Syncer struct {
+	Source   Source
+	Mappings []Mapping
+	Prune    bool
+
+	UserService                influxdb.UserService
+	UserResourceMappingService influxdb.UserResourceMappingService
+
+	log *zap.Logger
+}
+
+// NewSyncer returns a Syncer for the configuration c.
+func NewSyncer(log *zap.Logger, c *Config, users influxdb.UserService, urms influxdb.UserResourceMappingService) *Syncer {
+	return &Syncer{
+		Source:                     c.NewSource(),
+		Mappings:                   c.Mappings,
+		Prune:                      c.Prune,
+		UserService:                users,
+		UserResourceMappingService: urms,
+		log:                        log,
+	}
+}
+
+// Run synchronizes membership every interval until ctx is canceled.
+func (s *Syncer) Run(ctx context.Context, interval time.Duration) {
+	log := s.log.With(
+		zap.String("service", "group-sync"),
+		influxlogger.DurationLiteral("interval", interval),
+	)
+	log.Info("Starting")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if r, err := s.Sync(ctx); err != nil {
+			log.Error("Failed to synchronize groups", zap.Error(err))
+		} else {
+			log.Info("Synchronized groups",
+				zap.Int("users_created", r.UsersCreated),
+				zap.Int("members_added", r.MembersAdded),
+				zap.Int("members_updated", r.MembersUpdated),
+				zap.Int("members_removed", r.MembersRemoved))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			log.Info("Stopping")
+			return
+		}
+	}
+}
+
+// Sync makes the members of each mapped organization match the members of
+// the groups mapped to it. Organizations of mappings whose group is not
+// found in the directory are left untouched.
+func (s *Syncer) Sync(ctx context.Context) (Report, error) {
+	var r Report
+
+	groups, err := s.Source.Groups(ctx)
+	if err != nil {
+		return r, err
+	}
+	members := make(map[string][]string, len(groups))
+	for _, g := range groups {
+		members[g.Name] = append(members[g.Name], g.Members...)
+	}
+
+	// Determine the role of every user of each organization.
+	roles := make(map[influxdb.ID]map[string]influxdb.UserType)
+	skipped := make(map[influxdb.ID]bool)
+	for _, m := range s.Mappings {
+		names, ok := members[m.Group]
+		if !ok {
+			s.log.Warn("Directory group not found, skipping organization",
+				zap.String("group", m.Group), zap.Stringer("org_id", m.OrgID))
+			skipped[m.OrgID] = true
+			continue
+		}
+
+		role := m.Role
+		if role == "" {
+			role = influxdb.Member
+		}
+		if roles[m.OrgID] == nil {
+			roles[m.OrgID] = make(map[string]influxdb.UserType)
+		}
+		for _, name := range names {
+			if roles[m.OrgID][name] != influxdb.Owner {
+				roles[m.OrgID][name] = role
+			}
+		}
+	}
+
+	for orgID, users := range roles {
+		if skipped[orgID] {
+			continue
+		}
+		if err := s.syncOrg(ctx, orgID, users, &r); err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+// syncOrg makes the members of the organization orgID match users, which maps user names to roles.
+func (s *Syncer) syncOrg(ctx context.Context, orgID influxdb.ID, users map[string]influxdb.UserType, r *Report) error {
+	existing, _, err := s.UserResourceMappingService.FindUserResourceMappings(ctx, influxdb.UserResourceMappingFilter{
+		ResourceID:   orgID,
+		ResourceType: influxdb.OrgsResourceType,
+	})
+	if err != nil {
+		return err
+	}
+	current := make(map[influxdb.ID]influxdb.UserType, len(existing))
+	for _, m := range existing {
+		current[m.UserID] = m.UserType
+	}
+
+	wanted := make(map[influxdb.ID]bool, len(users))
+	for name, role := range users {
+		user, err := s.findOrCreateUser(ctx, name, r)
+		if err != nil {
+			return err
+		}
+		wanted[user.ID] = true
+
+		userType, ok := current[user.ID]
+		if ok && userType == role {
+			continue
+		}
+		if ok {
+			if err := s.UserResourceMappingService.DeleteUserResourceMapping(ctx, orgID, user.ID); err != nil {
+				return err
+			}
+			r.MembersUpdated++
+		} else {
+			r.MembersAdded++
+		}
+
+		if err := s.UserResourceMappingService.CreateUserResourceMapping(ctx, &influxdb.UserResourceMapping{
+			UserID:       user.ID,
+			UserType:     role,
+			MappingType:  influxdb.UserMappingType,
+			ResourceType: influxdb.OrgsResourceType,
+			ResourceID:   orgID,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if !s.Prune {
+		return nil
+	}
+	for userID := range current {
+		if wanted[userID] {
+			continue
+		}
+		if err := s.UserResourceMappingService.DeleteUserResourceMapping(ctx, orgID, userID); err != nil {
+			return err
+		}
+		r.MembersRemoved++
+	}
+	return nil
+}
+
+func (s *Syncer) findOrCreateUser(ctx context.Context, name string, r *Report) (*influxdb.User, error) {
+	user, err := s.UserService.FindUser(ctx, influxdb.UserFilter{Name: &name})
+	if err == nil {
+		return user, nil
+	} else if influxdb.ErrorCode(err) != influxdb.ENotFound {
+		return nil, err
+	}
+
+	user = &influxdb.User{Name: name, Status: influxdb.Active}
+	if err := s.UserService.CreateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	r.UsersCreated++
+	return user, nil
+}
@@ -0,0 +1,161 @@
+package groupsync_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/groupsync"
+	"github.com/influxdata/influxdb/inmem"
+	"github.com/influxdata/influxdb/kv"
+	"go.uber.org/zap/zaptest"
+)
+
+type staticSource []groupsync.Group
+
+func (s staticSource) Groups(context.Context) ([]groupsync.Group, error) {
+	return s, nil
+}
+
+func TestSyncer_Sync(t *testing.T) {
+	ctx := context.Background()
+	svc := kv.NewService(zaptest.NewLogger(t), inmem.NewKVStore())
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	org := &influxdb.Organization{Name: "org"}
+	if err := svc.CreateOrganization(ctx, org); err != nil {
+		t.Fatal(err)
+	}
+	manual := &influxdb.User{Name: "manual", Status: influxdb.Active}
+	if err := svc.CreateUser(ctx, manual); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.CreateUserResourceMapping(ctx, &influxdb.UserResourceMapping{
+		UserID:       manual.ID,
+		UserType:     influxdb.Member,
+		MappingType:  influxdb.UserMappingType,
+		ResourceType: influxdb.OrgsResourceType,
+		ResourceID:   org.ID,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	s := groupsync.NewSyncer(zaptest.NewLogger(t), &groupsync.Config{
+		Mappings: []groupsync.Mapping{
+			{Group: "engineers", OrgID: org.ID},
+			{Group: "admins", OrgID: org.ID, Role: influxdb.Owner},
+		},
+	}, svc, svc)
+	s.Source = staticSource{
+		{Name: "engineers", Members: []string{"alice", "bob"}},
+		{Name: "admins", Members: []string{"bob"}},
+	}
+
+	r, err := s.Sync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (groupsync.Report{UsersCreated: 2, MembersAdded: 2}); r != want {
+		t.Fatalf("unexpected report: got %+v, want %+v", r, want)
+	}
+	if got, want := orgMembers(t, svc, org.ID), []string{"alice:member", "bob:owner", "manual:member"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected members: got %v, want %v", got, want)
+	}
+
+	// Bob leaves the admins and alice leaves the directory.
+	s.Source = staticSource{
+		{Name: "engineers", Members: []string{"bob"}},
+		{Name: "admins"},
+	}
+	s.Prune = true
+	if r, err = s.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if want := (groupsync.Report{MembersUpdated: 1, MembersRemoved: 2}); r != want {
+		t.Fatalf("unexpected report: got %+v, want %+v", r, want)
+	}
+	if got, want := orgMembers(t, svc, org.ID), []string{"bob:member"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected members: got %v, want %v", got, want)
+	}
+
+	// A missing group leaves the organization untouched.
+	s.Source = staticSource{{Name: "engineers"}}
+	if r, err = s.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if r != (groupsync.Report{}) {
+		t.Fatalf("unexpected report: %+v", r)
+	}
+}
+
+func TestSCIMSource_Groups(t *testing.T) {
+	groups := []map[string]interface{}{
+		{"displayName": "engineers", "members": []map[string]string{{"value": "1", "display": "alice"}, {"value": "2", "display": "bob"}}},
+		{"displayName": "admins", "members": []map[string]string{{"value": "2", "display": "bob"}}},
+		{"displayName": "empty"},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/scim/v2/Groups" || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		// Serve pages of two groups.
+		start, _ := strconv.Atoi(r.URL.Query().Get("startIndex"))
+		end := start + 1
+		if end > len(groups) {
+			end = len(groups)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"totalResults": len(groups),
+			"startIndex":   start,
+			"Resources":    groups[start-1 : end],
+		})
+	}))
+	defer ts.Close()
+
+	src := &groupsync.SCIMSource{URL: ts.URL + "/scim/v2/", Token: "secret"}
+	got, err := src.Groups(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Name != "engineers" || !reflect.DeepEqual(got[0].Members, []string{"alice", "bob"}) || got[2].Name != "empty" {
+		t.Fatalf("unexpected groups: %+v", got)
+	}
+
+	src.Token = "wrong"
+	if _, err := src.Groups(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+// orgMembers returns the sorted name:role of the members of an organization.
+func orgMembers(t *testing.T, svc *kv.Service, orgID influxdb.ID) []string {
+	t.Helper()
+
+	ms, _, err := svc.FindUserResourceMappings(context.Background(), influxdb.UserResourceMappingFilter{
+		ResourceID:   orgID,
+		ResourceType: influxdb.OrgsResourceType,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var members []string
+	for _, m := range ms {
+		u, err := svc.FindUserByID(context.Background(), m.UserID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		members = append(members, u.Name+":"+string(m.UserType))
+	}
+	sort.Strings(members)
+	return members
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/influxdata/influxdb/kit/prom"
 	kithttp "github.com/influxdata/influxdb/kit/transport/http"
 	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/query/async"
 	"github.com/influxdata/influxdb/storage"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
@@ -44,6 +45,10 @@ type APIBackend struct {
 	// write request. A value of zero specifies there is no limit.
 	WriteParserMaxValues int
 
+	// WriteDecimals specifies that decimal field values are accepted by write
+	// requests. They are rejected otherwise.
+	WriteDecimals bool
+
 	NewBucketService func(*influxdb.Source) (influxdb.BucketService, error)
 	NewQueryService  func(*influxdb.Source) (query.ProxyQueryService, error)
 
@@ -54,6 +59,9 @@ type APIBackend struct {
 	DeleteService                   influxdb.DeleteService
 	BackupService                   influxdb.BackupService
 	KVBackupService                 influxdb.KVBackupService
+	CompactionService               influxdb.CompactionService
+	DiagnosticsService              influxdb.DiagnosticsService
+	BucketCopyService               influxdb.BucketCopyService
 	AuthorizationService            influxdb.AuthorizationService
 	BucketService                   influxdb.BucketService
 	SessionService                  influxdb.SessionService
@@ -66,17 +74,21 @@ type APIBackend struct {
 	BucketOperationLogService       influxdb.BucketOperationLogService
 	UserOperationLogService         influxdb.UserOperationLogService
 	OrganizationOperationLogService influxdb.OrganizationOperationLogService
+	DefinitionHistoryService        influxdb.DefinitionHistoryService
 	SourceService                   influxdb.SourceService
 	VariableService                 influxdb.VariableService
 	PasswordsService                influxdb.PasswordsService
 	OnboardingService               influxdb.OnboardingService
 	InfluxQLService                 query.ProxyQueryService
 	FluxService                     query.ProxyQueryService
+	AsyncQueryService               async.QueryService
 	TaskService                     influxdb.TaskService
 	CheckService                    influxdb.CheckService
 	TelegrafService                 influxdb.TelegrafConfigStore
 	ScraperTargetStoreService       influxdb.ScraperTargetStoreService
 	SecretService                   influxdb.SecretService
+	ConstantService                 influxdb.ConstantService
+	RoleService                     influxdb.RoleService
 	LookupService                   influxdb.LookupService
 	ChronografService               *server.Service
 	OrgLookupService                authorizer.OrganizationService
@@ -122,13 +134,26 @@ func NewAPIHandler(b *APIBackend, opts ...APIHandlerOptFn) *APIHandler {
 	h.Mount("/api/v2", serveLinksHandler(b.HTTPErrorHandler))
 
 	authorizationBackend := NewAuthorizationBackend(b.Logger.With(zap.String("handler", "authorization")), b)
-	authorizationBackend.AuthorizationService = authorizer.NewAuthorizationService(b.AuthorizationService)
+	authorizationBackend.AuthorizationService = authorizer.NewAuthorizationService(b.AuthorizationService, b.RoleService)
 	h.Mount(prefixAuthorization, NewAuthorizationHandler(b.Logger, authorizationBackend))
 
 	bucketBackend := NewBucketBackend(b.Logger.With(zap.String("handler", "bucket")), b)
 	bucketBackend.BucketService = authorizer.NewBucketService(b.BucketService)
 	h.Mount(prefixBuckets, NewBucketHandler(b.Logger, bucketBackend))
 
+	compactionBackend := NewCompactionBackend(b.Logger.With(zap.String("handler", "compaction")), b)
+	compactionBackend.CompactionService = authorizer.NewCompactionService(b.CompactionService)
+	h.Mount(prefixCompactions, NewCompactionHandler(b.Logger, compactionBackend))
+
+	diagnosticsBackend := NewDiagnosticsBackend(b.Logger.With(zap.String("handler", "diagnostics")), b)
+	diagnosticsBackend.DiagnosticsService = authorizer.NewDiagnosticsService(b.DiagnosticsService)
+	h.Mount(prefixDiagnostics, NewDiagnosticsHandler(b.Logger, diagnosticsBackend))
+
+	bucketCopyBackend := NewBucketCopyBackend(b.Logger.With(zap.String("handler", "copy")), b)
+	bucketCopyBackend.BucketCopyService = authorizer.NewBucketCopyService(b.BucketCopyService)
+	bucketCopyBackend.BucketService = authorizer.NewBucketService(b.BucketService)
+	h.Mount(prefixCopy, NewBucketCopyHandler(b.Logger, bucketCopyBackend))
+
 	checkBackend := NewCheckBackend(b.Logger.With(zap.String("handler", "check")), b)
 	checkBackend.CheckService = authorizer.NewCheckService(b.CheckService,
 		b.UserResourceMappingService, b.OrganizationService)
@@ -151,6 +176,8 @@ func NewAPIHandler(b *APIBackend, opts ...APIHandlerOptFn) *APIHandler {
 
 	h.Mount(prefixLabels, NewLabelHandler(b.Logger, authorizer.NewLabelService(b.LabelService), b.HTTPErrorHandler))
 
+	h.Mount(prefixRoles, NewRoleHandler(b.Logger, authorizer.NewRoleService(b.RoleService), b.HTTPErrorHandler))
+
 	notificationEndpointBackend := NewNotificationEndpointBackend(b.Logger.With(zap.String("handler", "notificationEndpoint")), b)
 	notificationEndpointBackend.NotificationEndpointService = authorizer.NewNotificationEndpointService(b.NotificationEndpointService,
 		b.UserResourceMappingService, b.OrganizationService)
@@ -163,6 +190,7 @@ func NewAPIHandler(b *APIBackend, opts ...APIHandlerOptFn) *APIHandler {
 
 	orgBackend := NewOrgBackend(b.Logger.With(zap.String("handler", "org")), b)
 	orgBackend.OrganizationService = authorizer.NewOrgService(b.OrganizationService)
+	orgBackend.ConstantService = authorizer.NewConstantService(b.ConstantService)
 	h.Mount(prefixOrganizations, NewOrgHandler(b.Logger, orgBackend))
 
 	scraperBackend := NewScraperBackend(b.Logger.With(zap.String("handler", "scraper")), b)
@@ -213,11 +241,13 @@ func NewAPIHandler(b *APIBackend, opts ...APIHandlerOptFn) *APIHandler {
 	h.Mount(prefixBackup, NewBackupHandler(backupBackend))
 
 	writeBackend := NewWriteBackend(b.Logger.With(zap.String("handler", "write")), b)
+	writeBackend.PointsWriter = authorizer.NewPointsWriter(b.PointsWriter)
 	h.Mount(prefixWrite, NewWriteHandler(b.Logger, writeBackend,
 		WithMaxBatchSizeBytes(b.MaxBatchSizeBytes),
 		WithParserMaxBytes(b.WriteParserMaxBytes),
 		WithParserMaxLines(b.WriteParserMaxLines),
 		WithParserMaxValues(b.WriteParserMaxValues),
+		WithParserDecimals(b.WriteDecimals),
 	))
 
 	for _, o := range opts {
@@ -232,6 +262,7 @@ var apiLinks = map[string]interface{}{
 	"authorizations": "/api/v2/authorizations",
 	"backup":         "/api/v2/backup",
 	"buckets":        "/api/v2/buckets",
+	"compactions":    "/api/v2/compactions",
 	"dashboards":     "/api/v2/dashboards",
 	"external": map[string]string{
 		"statusFeed": "https://www.influxdata.com/feed/json",
@@ -247,7 +278,9 @@ var apiLinks = map[string]interface{}{
 		"ast":         "/api/v2/query/ast",
 		"analyze":     "/api/v2/query/analyze",
 		"suggestions": "/api/v2/query/suggestions",
+		"async":       "/api/v2/query/async",
 	},
+	"roles":    "/api/v2/roles",
 	"setup":    "/api/v2/setup",
 	"signin":   "/api/v2/signin",
 	"signout":  "/api/v2/signout",
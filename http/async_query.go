@@ -0,0 +1,236 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	pcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/query/async"
+	"go.uber.org/zap"
+)
+
+const (
+	prefixAsyncQuery = "/api/v2/query/async"
+	// asyncQueryWebhookParam is the query parameter of the URL notified of
+	// the completion of an async query.
+	asyncQueryWebhookParam = "webhookURL"
+)
+
+type asyncQueryLinks struct {
+	Self   string `json:"self"`
+	Result string `json:"result"`
+}
+
+type asyncQueryResponse struct {
+	*async.Query
+	Links asyncQueryLinks `json:"links"`
+}
+
+func newAsyncQueryResponse(q *async.Query) *asyncQueryResponse {
+	self := fmt.Sprintf("%s/%s", prefixAsyncQuery, q.ID)
+	return &asyncQueryResponse{
+		Query: q,
+		Links: asyncQueryLinks{
+			Self:   self,
+			Result: self + "/result",
+		},
+	}
+}
+
+// postAsyncQuery submits a query for asynchronous execution and returns
+// immediately with the ID of the query.
+func (h *FluxHandler) postAsyncQuery(w http.ResponseWriter, r *http.Request) {
+	const op = "http/postAsyncQuery"
+	span, r := tracing.ExtractFromHTTPRequest(r, "FluxHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	a, err := pcontext.GetAuthorizer(ctx)
+	if err != nil {
+		err := &influxdb.Error{
+			Code: influxdb.EUnauthorized,
+			Msg:  "authorization is invalid or missing in the query request",
+			Op:   op,
+			Err:  err,
+		}
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	webhookURL, err := decodeAsyncQueryWebhook(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	req, _, err := decodeProxyQueryRequest(ctx, r, a, h.OrganizationService)
+	if err != nil {
+		err := &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "failed to decode request body",
+			Op:   op,
+			Err:  err,
+		}
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	req.Request.Source = r.Header.Get("User-Agent")
+
+	priority, err := queryPriority(r, a, req.Request.OrganizationID)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	req.Request.Priority = priority
+
+	hd, ok := req.Dialect.(HTTPDialect)
+	if !ok {
+		err := &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  fmt.Sprintf("unsupported dialect over HTTP: %T", req.Dialect),
+			Op:   op,
+		}
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	headers := headerRecorder{header: make(http.Header)}
+	hd.SetHeaders(headers)
+
+	q, err := h.AsyncQueryService.Submit(ctx, req, async.SubmitOptions{
+		ContentType: headers.header.Get("Content-Type"),
+		WebhookURL:  webhookURL,
+	})
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Async query submitted", zap.String("query_id", q.ID.String()))
+
+	if err := encodeResponse(ctx, w, http.StatusAccepted, newAsyncQueryResponse(q)); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+// getAsyncQuery returns the status of an async query.
+func (h *FluxHandler) getAsyncQuery(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "FluxHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	q, err := h.findAsyncQuery(ctx)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err := encodeResponse(ctx, w, http.StatusOK, newAsyncQueryResponse(q)); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+// getAsyncQueryResult returns the result of a successful async query.
+func (h *FluxHandler) getAsyncQueryResult(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "FluxHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	q, err := h.findAsyncQuery(ctx)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	result, err := h.AsyncQueryService.FindResult(ctx, q.ID)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if q.ContentType != "" {
+		w.Header().Set("Content-Type", q.ContentType)
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(result); err != nil {
+		logEncodingError(h.log, r, err)
+	}
+}
+
+// deleteAsyncQuery cancels a running async query, and deletes an async query
+// and its result.
+func (h *FluxHandler) deleteAsyncQuery(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "FluxHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	q, err := h.findAsyncQuery(ctx)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	if err := h.AsyncQueryService.DeleteQuery(ctx, q.ID); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findAsyncQuery returns the async query of the id of the URL. An async query
+// is only visible to the user who submitted it.
+func (h *FluxHandler) findAsyncQuery(ctx context.Context) (*async.Query, error) {
+	a, err := pcontext.GetAuthorizer(ctx)
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EUnauthorized,
+			Msg:  "authorization is invalid or missing in the query request",
+			Err:  err,
+		}
+	}
+
+	params := httprouter.ParamsFromContext(ctx)
+	var id influxdb.ID
+	if err := id.DecodeFromString(params.ByName("id")); err != nil {
+		return nil, err
+	}
+
+	q, err := h.AsyncQueryService.FindQueryByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if q.UserID != a.GetUserID() {
+		return nil, async.ErrQueryNotFound
+	}
+	return q, nil
+}
+
+// decodeAsyncQueryWebhook returns the optional webhook URL of an async query.
+func decodeAsyncQueryWebhook(r *http.Request) (string, error) {
+	raw := r.URL.Query().Get(asyncQueryWebhookParam)
+	if raw == "" {
+		return "", nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  fmt.Sprintf("invalid %s %q, an absolute http or https URL is required", asyncQueryWebhookParam, raw),
+		}
+	}
+	return raw, nil
+}
+
+// headerRecorder is a http.ResponseWriter recording the headers set by a
+// dialect.
+type headerRecorder struct {
+	header http.Header
+}
+
+func (r headerRecorder) Header() http.Header       { return r.header }
+func (headerRecorder) Write(p []byte) (int, error) { return len(p), nil }
+func (headerRecorder) WriteHeader(int)             {}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/influxdb"
+	icontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/inmem"
+	kithttp "github.com/influxdata/influxdb/kit/transport/http"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/query/async"
+	"github.com/influxdata/influxdb/query/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestFluxHandler_AsyncQuery(t *testing.T) {
+	orgSVC := newInMemKVSVC(t)
+	org := influxdb.Organization{Name: t.Name()}
+	if err := orgSVC.CreateOrganization(context.Background(), &org); err != nil {
+		t.Fatal(err)
+	}
+
+	asyncSVC := async.NewService(zaptest.NewLogger(t), inmem.NewKVStore(), &mock.ProxyQueryService{
+		QueryF: func(ctx context.Context, w io.Writer, req *query.ProxyRequest) (flux.Statistics, error) {
+			_, err := io.WriteString(w, "#datatype,long\n")
+			return flux.Statistics{}, err
+		},
+	})
+	if err := asyncSVC.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer asyncSVC.Close()
+
+	b := &FluxBackend{
+		HTTPErrorHandler:    kithttp.ErrorHandler(0),
+		log:                 zaptest.NewLogger(t),
+		QueryEventRecorder:  noopEventRecorder{},
+		OrganizationService: orgSVC,
+		AsyncQueryService:   asyncSVC,
+	}
+	h := NewFluxHandler(zaptest.NewLogger(t), b)
+
+	owner := &influxdb.Authorization{ID: 1, UserID: 2, OrgID: org.ID}
+	do := func(method, path, body string, a influxdb.Authorizer) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req = req.WithContext(icontext.SetAuthorizer(req.Context(), a))
+		req.Header.Set("Content-Type", "application/vnd.flux")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/api/v2/query/async?webhookURL=ftp://example.com&orgID="+org.ID.String(), "buckets()", owner)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status: got %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+	}
+
+	w = do("POST", "/api/v2/query/async?orgID="+org.ID.String(), "buckets()", owner)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("unexpected status: got %d, want %d: %s", w.Code, http.StatusAccepted, w.Body.String())
+	}
+	var submitted asyncQueryResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &submitted); err != nil {
+		t.Fatal(err)
+	}
+	self := "/api/v2/query/async/" + submitted.ID.String()
+	if submitted.Links.Self != self || submitted.Links.Result != self+"/result" {
+		t.Fatalf("unexpected links: %+v", submitted.Links)
+	}
+
+	var status asyncQueryResponse
+	for start := time.Now(); status.Query == nil || !status.Completed(); time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("timed out waiting for the query to complete")
+		}
+		w = do("GET", self, "", owner)
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status: got %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if status.Status != async.StatusSuccess {
+		t.Fatalf("unexpected query: %+v", status.Query)
+	}
+
+	// The query is only visible to the user who submitted it.
+	other := &influxdb.Authorization{ID: 3, UserID: 4, OrgID: org.ID}
+	if w = do("GET", self+"/result", "", other); w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status: got %d, want %d: %s", w.Code, http.StatusNotFound, w.Body.String())
+	}
+
+	w = do("GET", self+"/result", "", owner)
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status: got %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Errorf("unexpected content type: %q", got)
+	}
+	if got := w.Body.String(); got != "#datatype,long\n" {
+		t.Errorf("unexpected result: %q", got)
+	}
+
+	if w = do("DELETE", self, "", owner); w.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status: got %d, want %d: %s", w.Code, http.StatusNoContent, w.Body.String())
+	}
+	if w = do("GET", self, "", owner); w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status: got %d, want %d: %s", w.Code, http.StatusNotFound, w.Body.String())
+	}
+}
@@ -76,18 +76,20 @@ func NewAuthorizationHandler(log *zap.Logger, b *AuthorizationBackend) *Authoriz
 }
 
 type authResponse struct {
-	ID          platform.ID          `json:"id"`
-	Token       string               `json:"token"`
-	Status      platform.Status      `json:"status"`
-	Description string               `json:"description"`
-	OrgID       platform.ID          `json:"orgID"`
-	Org         string               `json:"org"`
-	UserID      platform.ID          `json:"userID"`
-	User        string               `json:"user"`
-	Permissions []permissionResponse `json:"permissions"`
-	Links       map[string]string    `json:"links"`
-	CreatedAt   time.Time            `json:"createdAt"`
-	UpdatedAt   time.Time            `json:"updatedAt"`
+	ID          platform.ID           `json:"id"`
+	Token       string                `json:"token"`
+	Status      platform.Status       `json:"status"`
+	Description string                `json:"description"`
+	OrgID       platform.ID           `json:"orgID"`
+	Org         string                `json:"org"`
+	UserID      platform.ID           `json:"userID"`
+	User        string                `json:"user"`
+	Permissions []permissionResponse  `json:"permissions"`
+	WritePolicy *platform.WritePolicy `json:"writePolicy,omitempty"`
+	RoleIDs     []platform.ID         `json:"roles,omitempty"`
+	Links       map[string]string     `json:"links"`
+	CreatedAt   time.Time             `json:"createdAt"`
+	UpdatedAt   time.Time             `json:"updatedAt"`
 }
 
 func newAuthResponse(a *platform.Authorization, org *platform.Organization, user *platform.User, ps []permissionResponse) *authResponse {
@@ -101,6 +103,8 @@ func newAuthResponse(a *platform.Authorization, org *platform.Organization, user
 		User:        user.Name,
 		Org:         org.Name,
 		Permissions: ps,
+		WritePolicy: a.WritePolicy,
+		RoleIDs:     a.RoleIDs,
 		Links: map[string]string{
 			"self": fmt.Sprintf("/api/v2/authorizations/%s", a.ID),
 			"user": fmt.Sprintf("/api/v2/users/%s", a.UserID),
@@ -119,6 +123,8 @@ func (a *authResponse) toPlatform() *platform.Authorization {
 		Description: a.Description,
 		OrgID:       a.OrgID,
 		UserID:      a.UserID,
+		WritePolicy: a.WritePolicy,
+		RoleIDs:     a.RoleIDs,
 		CRUDLog: platform.CRUDLog{
 			CreatedAt: a.CreatedAt,
 			UpdatedAt: a.UpdatedAt,
@@ -245,6 +251,8 @@ type postAuthorizationRequest struct {
 	UserID      *platform.ID          `json:"userID,omitempty"`
 	Description string                `json:"description"`
 	Permissions []platform.Permission `json:"permissions"`
+	WritePolicy *platform.WritePolicy `json:"writePolicy,omitempty"`
+	RoleIDs     []platform.ID         `json:"roles,omitempty"`
 }
 
 func (p *postAuthorizationRequest) toPlatform(userID platform.ID) *platform.Authorization {
@@ -253,6 +261,8 @@ func (p *postAuthorizationRequest) toPlatform(userID platform.ID) *platform.Auth
 		Status:      p.Status,
 		Description: p.Description,
 		Permissions: p.Permissions,
+		WritePolicy: p.WritePolicy,
+		RoleIDs:     p.RoleIDs,
 		UserID:      userID,
 	}
 }
@@ -262,6 +272,8 @@ func newPostAuthorizationRequest(a *platform.Authorization) (*postAuthorizationR
 		OrgID:       a.OrgID,
 		Description: a.Description,
 		Permissions: a.Permissions,
+		WritePolicy: a.WritePolicy,
+		RoleIDs:     a.RoleIDs,
 		Status:      a.Status,
 	}
 
@@ -281,10 +293,10 @@ func (p *postAuthorizationRequest) SetDefaults() {
 }
 
 func (p *postAuthorizationRequest) Validate() error {
-	if len(p.Permissions) == 0 {
+	if len(p.Permissions) == 0 && len(p.RoleIDs) == 0 {
 		return &platform.Error{
 			Code: platform.EInvalid,
-			Msg:  "authorization must include permissions",
+			Msg:  "authorization must include permissions or roles",
 		}
 	}
 
@@ -304,6 +316,12 @@ func (p *postAuthorizationRequest) Validate() error {
 		}
 	}
 
+	if p.WritePolicy != nil {
+		if err := p.WritePolicy.Valid(); err != nil {
+			return err
+		}
+	}
+
 	if p.Status == "" {
 		p.Status = platform.Active
 	}
@@ -132,13 +132,15 @@ func NewBucketHandler(log *zap.Logger, b *BucketBackend) *BucketHandler {
 
 // bucket is used for serialization/deserialization with duration string syntax.
 type bucket struct {
-	ID                  influxdb.ID     `json:"id,omitempty"`
-	OrgID               influxdb.ID     `json:"orgID,omitempty"`
-	Type                string          `json:"type"`
-	Description         string          `json:"description,omitempty"`
-	Name                string          `json:"name"`
-	RetentionPolicyName string          `json:"rp,omitempty"` // This to support v1 sources
-	RetentionRules      []retentionRule `json:"retentionRules"`
+	ID                   influxdb.ID                   `json:"id,omitempty"`
+	OrgID                influxdb.ID                   `json:"orgID,omitempty"`
+	Type                 string                        `json:"type"`
+	Description          string                        `json:"description,omitempty"`
+	Name                 string                        `json:"name"`
+	RetentionPolicyName  string                        `json:"rp,omitempty"` // This to support v1 sources
+	RetentionRules       []retentionRule               `json:"retentionRules"`
+	NonFiniteFloatPolicy influxdb.NonFiniteFloatPolicy `json:"nonFiniteFloatPolicy,omitempty"`
+	TagNormalization     *influxdb.TagNormalization    `json:"tagNormalization,omitempty"`
 	influxdb.CRUDLog
 }
 
@@ -179,14 +181,16 @@ func (b *bucket) toInfluxDB() (*influxdb.Bucket, error) {
 	}
 
 	return &influxdb.Bucket{
-		ID:                  b.ID,
-		OrgID:               b.OrgID,
-		Type:                influxdb.ParseBucketType(b.Type),
-		Description:         b.Description,
-		Name:                b.Name,
-		RetentionPolicyName: b.RetentionPolicyName,
-		RetentionPeriod:     d,
-		CRUDLog:             b.CRUDLog,
+		ID:                   b.ID,
+		OrgID:                b.OrgID,
+		Type:                 influxdb.ParseBucketType(b.Type),
+		Description:          b.Description,
+		Name:                 b.Name,
+		RetentionPolicyName:  b.RetentionPolicyName,
+		RetentionPeriod:      d,
+		NonFiniteFloatPolicy: b.NonFiniteFloatPolicy,
+		TagNormalization:     b.TagNormalization,
+		CRUDLog:              b.CRUDLog,
 	}, nil
 }
 
@@ -205,22 +209,26 @@ func newBucket(pb *influxdb.Bucket) *bucket {
 	}
 
 	return &bucket{
-		ID:                  pb.ID,
-		OrgID:               pb.OrgID,
-		Type:                pb.Type.String(),
-		Name:                pb.Name,
-		Description:         pb.Description,
-		RetentionPolicyName: pb.RetentionPolicyName,
-		RetentionRules:      rules,
-		CRUDLog:             pb.CRUDLog,
+		ID:                   pb.ID,
+		OrgID:                pb.OrgID,
+		Type:                 pb.Type.String(),
+		Name:                 pb.Name,
+		Description:          pb.Description,
+		RetentionPolicyName:  pb.RetentionPolicyName,
+		RetentionRules:       rules,
+		NonFiniteFloatPolicy: pb.NonFiniteFloatPolicy,
+		TagNormalization:     pb.TagNormalization,
+		CRUDLog:              pb.CRUDLog,
 	}
 }
 
 // bucketUpdate is used for serialization/deserialization with retention rules.
 type bucketUpdate struct {
-	Name           *string         `json:"name,omitempty"`
-	Description    *string         `json:"description,omitempty"`
-	RetentionRules []retentionRule `json:"retentionRules,omitempty"`
+	Name                 *string                        `json:"name,omitempty"`
+	Description          *string                        `json:"description,omitempty"`
+	RetentionRules       []retentionRule                `json:"retentionRules,omitempty"`
+	NonFiniteFloatPolicy *influxdb.NonFiniteFloatPolicy `json:"nonFiniteFloatPolicy,omitempty"`
+	TagNormalization     *influxdb.TagNormalization     `json:"tagNormalization,omitempty"`
 }
 
 func (b *bucketUpdate) OK() error {
@@ -230,6 +238,16 @@ func (b *bucketUpdate) OK() error {
 			return err
 		}
 	}
+	if b.NonFiniteFloatPolicy != nil {
+		if err := b.NonFiniteFloatPolicy.Valid(); err != nil {
+			return err
+		}
+	}
+	if b.TagNormalization != nil {
+		if err := b.TagNormalization.Valid(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -245,9 +263,11 @@ func (b *bucketUpdate) toInfluxDB() *influxdb.BucketUpdate {
 	}
 
 	return &influxdb.BucketUpdate{
-		Name:            b.Name,
-		Description:     b.Description,
-		RetentionPeriod: &d,
+		Name:                 b.Name,
+		Description:          b.Description,
+		RetentionPeriod:      &d,
+		NonFiniteFloatPolicy: b.NonFiniteFloatPolicy,
+		TagNormalization:     b.TagNormalization,
 	}
 }
 
@@ -257,9 +277,11 @@ func newBucketUpdate(pb *influxdb.BucketUpdate) *bucketUpdate {
 	}
 
 	up := &bucketUpdate{
-		Name:           pb.Name,
-		Description:    pb.Description,
-		RetentionRules: []retentionRule{},
+		Name:                 pb.Name,
+		Description:          pb.Description,
+		RetentionRules:       []retentionRule{},
+		NonFiniteFloatPolicy: pb.NonFiniteFloatPolicy,
+		TagNormalization:     pb.TagNormalization,
 	}
 
 	if pb.RetentionPeriod != nil {
@@ -336,11 +358,13 @@ func (h *BucketHandler) handlePostBucket(w http.ResponseWriter, r *http.Request)
 }
 
 type postBucketRequest struct {
-	OrgID               influxdb.ID     `json:"orgID,omitempty"`
-	Name                string          `json:"name"`
-	Description         string          `json:"description"`
-	RetentionPolicyName string          `json:"rp,omitempty"` // This to support v1 sources
-	RetentionRules      []retentionRule `json:"retentionRules"`
+	OrgID                influxdb.ID                   `json:"orgID,omitempty"`
+	Name                 string                        `json:"name"`
+	Description          string                        `json:"description"`
+	RetentionPolicyName  string                        `json:"rp,omitempty"` // This to support v1 sources
+	RetentionRules       []retentionRule               `json:"retentionRules"`
+	NonFiniteFloatPolicy influxdb.NonFiniteFloatPolicy `json:"nonFiniteFloatPolicy,omitempty"`
+	TagNormalization     *influxdb.TagNormalization    `json:"tagNormalization,omitempty"`
 }
 
 func (b *postBucketRequest) OK() error {
@@ -361,6 +385,16 @@ func (b *postBucketRequest) OK() error {
 		}
 	}
 
+	if err := b.NonFiniteFloatPolicy.Valid(); err != nil {
+		return err
+	}
+
+	if b.TagNormalization != nil {
+		if err := b.TagNormalization.Valid(); err != nil {
+			return err
+		}
+	}
+
 	// names starting with an underscore are reserved for system buckets
 	if err := validBucketName(b.toInfluxDB()); err != nil {
 		return &influxdb.Error{
@@ -380,12 +414,14 @@ func (b postBucketRequest) toInfluxDB() *influxdb.Bucket {
 	}
 
 	return &influxdb.Bucket{
-		OrgID:               b.OrgID,
-		Description:         b.Description,
-		Name:                b.Name,
-		Type:                influxdb.BucketTypeUser,
-		RetentionPolicyName: b.RetentionPolicyName,
-		RetentionPeriod:     dur,
+		OrgID:                b.OrgID,
+		Description:          b.Description,
+		Name:                 b.Name,
+		Type:                 influxdb.BucketTypeUser,
+		RetentionPolicyName:  b.RetentionPolicyName,
+		RetentionPeriod:      dur,
+		NonFiniteFloatPolicy: b.NonFiniteFloatPolicy,
+		TagNormalization:     b.TagNormalization,
 	}
 }
 
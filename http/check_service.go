@@ -31,6 +31,7 @@ type CheckBackend struct {
 	LabelService               influxdb.LabelService
 	UserService                influxdb.UserService
 	OrganizationService        influxdb.OrganizationService
+	DefinitionHistoryService   influxdb.DefinitionHistoryService
 }
 
 // NewCheckBackend returns a new instance of CheckBackend.
@@ -45,6 +46,7 @@ func NewCheckBackend(log *zap.Logger, b *APIBackend) *CheckBackend {
 		LabelService:               b.LabelService,
 		UserService:                b.UserService,
 		OrganizationService:        b.OrganizationService,
+		DefinitionHistoryService:   b.DefinitionHistoryService,
 	}
 }
 
@@ -60,6 +62,7 @@ type CheckHandler struct {
 	LabelService               influxdb.LabelService
 	UserService                influxdb.UserService
 	OrganizationService        influxdb.OrganizationService
+	DefinitionHistoryService   influxdb.DefinitionHistoryService
 }
 
 const (
@@ -72,6 +75,9 @@ const (
 	checksIDOwnersIDPath  = "/api/v2/checks/:id/owners/:userID"
 	checksIDLabelsPath    = "/api/v2/checks/:id/labels"
 	checksIDLabelsIDPath  = "/api/v2/checks/:id/labels/:lid"
+	checksIDVersionsPath  = "/api/v2/checks/:id/versions"
+	checksIDVersionPath   = "/api/v2/checks/:id/versions/:version"
+	checksIDRollbackPath  = "/api/v2/checks/:id/versions/:version/rollback"
 )
 
 // NewCheckHandler returns a new instance of CheckHandler.
@@ -87,6 +93,7 @@ func NewCheckHandler(log *zap.Logger, b *CheckBackend) *CheckHandler {
 		UserService:                b.UserService,
 		TaskService:                b.TaskService,
 		OrganizationService:        b.OrganizationService,
+		DefinitionHistoryService:   b.DefinitionHistoryService,
 	}
 	h.HandlerFunc("POST", prefixChecks, h.handlePostCheck)
 	h.HandlerFunc("GET", prefixChecks, h.handleGetChecks)
@@ -130,9 +137,57 @@ func NewCheckHandler(log *zap.Logger, b *CheckBackend) *CheckHandler {
 	h.HandlerFunc("POST", checksIDLabelsPath, newPostLabelHandler(labelBackend))
 	h.HandlerFunc("DELETE", checksIDLabelsIDPath, newDeleteLabelHandler(labelBackend))
 
+	historyBackend := &DefinitionHistoryBackend{
+		HTTPErrorHandler:         b.HTTPErrorHandler,
+		log:                      b.log.With(zap.String("handler", "definition_history")),
+		DefinitionHistoryService: b.DefinitionHistoryService,
+		ResourceType:             influxdb.ChecksResourceType,
+		find: func(ctx context.Context, id influxdb.ID) error {
+			_, err := h.CheckService.FindCheckByID(ctx, id)
+			return err
+		},
+		rollback: h.rollbackCheck,
+	}
+	h.HandlerFunc("GET", checksIDVersionsPath, newGetDefinitionVersionsHandler(historyBackend, prefixChecks))
+	h.HandlerFunc("GET", checksIDVersionPath, newGetDefinitionVersionHandler(historyBackend, prefixChecks))
+	h.HandlerFunc("POST", checksIDRollbackPath, newPostDefinitionRollbackHandler(historyBackend))
+
 	return h
 }
 
+// rollbackCheck restores the check to the definition of the version, keeping
+// its current status.
+func (h *CheckHandler) rollbackCheck(ctx context.Context, id influxdb.ID, v *influxdb.DefinitionVersion) (interface{}, error) {
+	chk, err := check.UnmarshalJSON([]byte(v.Definition))
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInternal,
+			Msg:  "malformed check definition",
+			Err:  err,
+		}
+	}
+
+	current, err := h.CheckService.FindCheckByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	t, err := h.TaskService.FindTaskByID(ctx, current.GetTaskID())
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := h.CheckService.UpdateCheck(ctx, id, influxdb.CheckCreate{Check: chk, Status: influxdb.Status(t.Status)})
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := h.LabelService.FindResourceLabels(ctx, influxdb.LabelMappingFilter{ResourceID: c.GetID()})
+	if err != nil {
+		return nil, err
+	}
+	return h.newCheckResponse(ctx, c, labels)
+}
+
 type checkLinks struct {
 	Self    string `json:"self"`
 	Labels  string `json:"labels"`
@@ -0,0 +1,214 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	kithttp "github.com/influxdata/influxdb/kit/transport/http"
+	"github.com/influxdata/influxdb/pkg/httpc"
+	"go.uber.org/zap"
+)
+
+// CompactionBackend is all services and associated parameters required to construct
+// the CompactionHandler.
+type CompactionBackend struct {
+	log *zap.Logger
+	influxdb.HTTPErrorHandler
+
+	CompactionService influxdb.CompactionService
+}
+
+// NewCompactionBackend returns a new instance of CompactionBackend.
+func NewCompactionBackend(log *zap.Logger, b *APIBackend) *CompactionBackend {
+	return &CompactionBackend{
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		log:              log,
+
+		CompactionService: b.CompactionService,
+	}
+}
+
+// CompactionHandler represents an HTTP API handler for controlling storage engine compactions.
+type CompactionHandler struct {
+	*httprouter.Router
+	api *kithttp.API
+	log *zap.Logger
+
+	CompactionService influxdb.CompactionService
+}
+
+const (
+	prefixCompactions      = "/api/v2/compactions"
+	compactionsPausePath   = "/api/v2/compactions/pause"
+	compactionsResumePath  = "/api/v2/compactions/resume"
+	compactionsReportPath  = "/api/v2/compactions/report"
+	errInvalidPauseTimeout = "invalid timeout; must be a duration such as 30m or 1h"
+)
+
+// NewCompactionHandler returns a new instance of CompactionHandler.
+func NewCompactionHandler(log *zap.Logger, b *CompactionBackend) *CompactionHandler {
+	h := &CompactionHandler{
+		Router: NewRouter(b.HTTPErrorHandler),
+		api:    kithttp.NewAPI(kithttp.WithLog(log)),
+		log:    log,
+
+		CompactionService: b.CompactionService,
+	}
+
+	h.HandlerFunc("GET", prefixCompactions, h.handleGetCompactions)
+	h.HandlerFunc("POST", compactionsPausePath, h.handlePostPause)
+	h.HandlerFunc("POST", compactionsResumePath, h.handlePostResume)
+	h.HandlerFunc("GET", compactionsReportPath, h.handleGetReport)
+
+	return h
+}
+
+// pauseCompactionsRequest is the body of a POST /api/v2/compactions/pause request.
+type pauseCompactionsRequest struct {
+	// Timeout is an optional duration after which compactions resume automatically.
+	Timeout string `json:"timeout,omitempty"`
+}
+
+func (r *pauseCompactionsRequest) timeout() (time.Duration, error) {
+	if r.Timeout == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(r.Timeout)
+	if err != nil || d < 0 {
+		return 0, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  errInvalidPauseTimeout,
+			Err:  err,
+		}
+	}
+	return d, nil
+}
+
+// handleGetCompactions is the HTTP handler for the GET /api/v2/compactions route.
+func (h *CompactionHandler) handleGetCompactions(w http.ResponseWriter, r *http.Request) {
+	status, err := h.CompactionService.CompactionStatus(r.Context())
+	if err != nil {
+		h.api.Err(w, err)
+		return
+	}
+
+	h.api.Respond(w, http.StatusOK, status)
+}
+
+// handlePostPause is the HTTP handler for the POST /api/v2/compactions/pause route.
+func (h *CompactionHandler) handlePostPause(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req pauseCompactionsRequest
+	if r.ContentLength != 0 {
+		if err := h.api.DecodeJSON(r.Body, &req); err != nil {
+			h.api.Err(w, err)
+			return
+		}
+	}
+
+	timeout, err := req.timeout()
+	if err != nil {
+		h.api.Err(w, err)
+		return
+	}
+
+	if err := h.CompactionService.PauseCompactions(ctx, timeout); err != nil {
+		h.api.Err(w, err)
+		return
+	}
+	h.log.Info("Compactions paused", zap.Duration("timeout", timeout))
+
+	h.handleGetCompactions(w, r)
+}
+
+// handlePostResume is the HTTP handler for the POST /api/v2/compactions/resume route.
+func (h *CompactionHandler) handlePostResume(w http.ResponseWriter, r *http.Request) {
+	if err := h.CompactionService.ResumeCompactions(r.Context()); err != nil {
+		h.api.Err(w, err)
+		return
+	}
+	h.log.Info("Compactions resumed")
+
+	h.handleGetCompactions(w, r)
+}
+
+// handleGetReport is the HTTP handler for the GET /api/v2/compactions/report route.
+func (h *CompactionHandler) handleGetReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.CompactionService.CompactionReport(r.Context())
+	if err != nil {
+		h.api.Err(w, err)
+		return
+	}
+
+	h.api.Respond(w, http.StatusOK, report)
+}
+
+// CompactionService connects to Influx via HTTP using tokens to control compactions.
+type CompactionService struct {
+	Client *httpc.Client
+}
+
+var _ influxdb.CompactionService = (*CompactionService)(nil)
+
+// PauseCompactions stops level compactions, resuming them after timeout if it is positive.
+func (s *CompactionService) PauseCompactions(ctx context.Context, timeout time.Duration) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var req pauseCompactionsRequest
+	if timeout > 0 {
+		req.Timeout = timeout.String()
+	}
+
+	return s.Client.
+		PostJSON(req, compactionsPausePath).
+		Do(ctx)
+}
+
+// ResumeCompactions restarts compactions stopped by PauseCompactions.
+func (s *CompactionService) ResumeCompactions(ctx context.Context) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	return s.Client.
+		PostJSON(struct{}{}, compactionsResumePath).
+		Do(ctx)
+}
+
+// CompactionStatus reports whether compactions are paused.
+func (s *CompactionService) CompactionStatus(ctx context.Context) (*influxdb.CompactionStatus, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var status influxdb.CompactionStatus
+	err := s.Client.
+		Get(prefixCompactions).
+		DecodeJSON(&status).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// CompactionReport reports the bytes recently written by snapshots and compactions.
+func (s *CompactionService) CompactionReport(ctx context.Context) (*influxdb.CompactionReport, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var report influxdb.CompactionReport
+	err := s.Client.
+		Get(compactionsReportPath).
+		DecodeJSON(&report).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -0,0 +1,139 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	kithttp "github.com/influxdata/influxdb/kit/transport/http"
+	"github.com/influxdata/influxdb/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestCompactionHandler(t *testing.T) {
+	resumeAt := time.Date(2020, 1, 1, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		body        string
+		wantTimeout time.Duration
+		wantPaused  bool
+		wantStatus  int
+		wantBody    string
+	}{
+		{
+			name:       "get status",
+			method:     "GET",
+			path:       "/api/v2/compactions",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"paused":false}`,
+		},
+		{
+			name:       "pause without timeout",
+			method:     "POST",
+			path:       "/api/v2/compactions/pause",
+			wantPaused: true,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"paused":true}`,
+		},
+		{
+			name:        "pause with timeout",
+			method:      "POST",
+			path:        "/api/v2/compactions/pause",
+			body:        `{"timeout":"30m"}`,
+			wantTimeout: 30 * time.Minute,
+			wantPaused:  true,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"paused":true,"resumeAt":"2020-01-01T12:30:00Z"}`,
+		},
+		{
+			name:       "pause with invalid timeout",
+			method:     "POST",
+			path:       "/api/v2/compactions/pause",
+			body:       `{"timeout":"soon"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "resume",
+			method:     "POST",
+			path:       "/api/v2/compactions/resume",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"paused":false}`,
+		},
+		{
+			name:       "get report",
+			method:     "GET",
+			path:       "/api/v2/compactions/report",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"since":"2020-01-01T12:30:00Z","levels":[{"level":"snapshot","compactions":2,"bytesWritten":100},{"level":"1","compactions":1,"bytesWritten":150}],"writeAmplification":2.5}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var status influxdb.CompactionStatus
+			svc := mock.NewCompactionService()
+			svc.PauseCompactionsF = func(ctx context.Context, timeout time.Duration) error {
+				if timeout != tt.wantTimeout {
+					t.Errorf("unexpected timeout: got %v, want %v", timeout, tt.wantTimeout)
+				}
+				status.Paused = true
+				if timeout > 0 {
+					status.ResumeAt = &resumeAt
+				}
+				return nil
+			}
+			svc.ResumeCompactionsF = func(ctx context.Context) error {
+				status = influxdb.CompactionStatus{}
+				return nil
+			}
+			svc.CompactionStatusF = func(ctx context.Context) (*influxdb.CompactionStatus, error) {
+				s := status
+				return &s, nil
+			}
+			svc.CompactionReportF = func(ctx context.Context) (*influxdb.CompactionReport, error) {
+				return &influxdb.CompactionReport{
+					Since: resumeAt,
+					Levels: []influxdb.CompactionLevelReport{
+						{Level: "snapshot", Compactions: 2, BytesWritten: 100},
+						{Level: "1", Compactions: 1, BytesWritten: 150},
+					},
+					WriteAmplification: 2.5,
+				}, nil
+			}
+
+			h := NewCompactionHandler(zaptest.NewLogger(t), &CompactionBackend{
+				HTTPErrorHandler:  kithttp.ErrorHandler(0),
+				CompactionService: svc,
+			})
+
+			r := httptest.NewRequest(tt.method, "http://any.url"+tt.path, bytes.NewBufferString(tt.body))
+			if tt.body == "" {
+				r.ContentLength = 0
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			res := w.Result()
+			body, _ := ioutil.ReadAll(res.Body)
+			if res.StatusCode != tt.wantStatus {
+				t.Fatalf("unexpected status code: got %d, want %d: %s", res.StatusCode, tt.wantStatus, body)
+			}
+			if tt.wantBody != "" {
+				if eq, diff, _ := jsonEqual(string(body), tt.wantBody); !eq {
+					t.Errorf("unexpected body -got/+want:\n%s", diff)
+				}
+			}
+			if status.Paused != tt.wantPaused {
+				t.Errorf("unexpected paused state: got %v, want %v", status.Paused, tt.wantPaused)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/pkg/httpc"
+)
+
+type constantsResponse struct {
+	Links     map[string]string          `json:"links"`
+	Constants map[string]json.RawMessage `json:"constants"`
+}
+
+func newConstantsResponse(orgID influxdb.ID, m map[string]interface{}) (*constantsResponse, error) {
+	res := &constantsResponse{
+		Links: map[string]string{
+			"org":  fmt.Sprintf("/api/v2/orgs/%s", orgID),
+			"self": fmt.Sprintf("/api/v2/orgs/%s/constants", orgID),
+		},
+		Constants: make(map[string]json.RawMessage, len(m)),
+	}
+	for name, v := range m {
+		b, err := influxdb.MarshalConstant(v)
+		if err != nil {
+			return nil, err
+		}
+		res.Constants[name] = b
+	}
+	return res, nil
+}
+
+// decodeConstants decodes the JSON values of constants by name.
+func decodeConstants(raw map[string]json.RawMessage) (map[string]interface{}, error) {
+	m := make(map[string]interface{}, len(raw))
+	for name, b := range raw {
+		v, err := influxdb.UnmarshalConstant(b)
+		if err != nil {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  fmt.Sprintf("invalid value of constant %q", name),
+				Err:  err,
+			}
+		}
+		m[name] = v
+	}
+	return m, nil
+}
+
+// handleGetConstants is the HTTP handler for the GET /api/v2/orgs/:id/constants route.
+func (h *OrgHandler) handleGetConstants(w http.ResponseWriter, r *http.Request) {
+	orgID, err := decodeIDFromCtx(r.Context(), "id")
+	if err != nil {
+		h.API.Err(w, err)
+		return
+	}
+
+	m, err := h.ConstantService.FindConstants(r.Context(), orgID)
+	if err != nil {
+		h.API.Err(w, err)
+		return
+	}
+
+	res, err := newConstantsResponse(orgID, m)
+	if err != nil {
+		h.API.Err(w, err)
+		return
+	}
+	h.API.Respond(w, http.StatusOK, res)
+}
+
+// handlePatchConstants is the HTTP handler for the PATCH /api/v2/orgs/:id/constants route.
+func (h *OrgHandler) handlePatchConstants(w http.ResponseWriter, r *http.Request) {
+	orgID, err := decodeIDFromCtx(r.Context(), "id")
+	if err != nil {
+		h.API.Err(w, err)
+		return
+	}
+
+	var raw map[string]json.RawMessage
+	if err := h.API.DecodeJSON(r.Body, &raw); err != nil {
+		h.API.Err(w, err)
+		return
+	}
+	m, err := decodeConstants(raw)
+	if err != nil {
+		h.API.Err(w, err)
+		return
+	}
+
+	if err := h.ConstantService.PatchConstants(r.Context(), orgID, m); err != nil {
+		h.API.Err(w, err)
+		return
+	}
+
+	h.API.Respond(w, http.StatusNoContent, nil)
+}
+
+type constantsDeleteBody struct {
+	Constants []string `json:"constants"`
+}
+
+// handleDeleteConstants is the HTTP handler for the POST /api/v2/orgs/:id/constants/delete route.
+func (h *OrgHandler) handleDeleteConstants(w http.ResponseWriter, r *http.Request) {
+	orgID, err := decodeIDFromCtx(r.Context(), "id")
+	if err != nil {
+		h.API.Err(w, err)
+		return
+	}
+
+	var reqBody constantsDeleteBody
+	if err := h.API.DecodeJSON(r.Body, &reqBody); err != nil {
+		h.API.Err(w, err)
+		return
+	}
+
+	if err := h.ConstantService.DeleteConstants(r.Context(), orgID, reqBody.Constants...); err != nil {
+		h.API.Err(w, err)
+		return
+	}
+
+	h.API.Respond(w, http.StatusNoContent, nil)
+}
+
+// ConstantService connects to Influx via HTTP using tokens to manage the
+// constants of organizations.
+type ConstantService struct {
+	Client *httpc.Client
+}
+
+var _ influxdb.ConstantService = (*ConstantService)(nil)
+
+// FindConstants returns the constants of an organization via HTTP.
+func (s *ConstantService) FindConstants(ctx context.Context, orgID influxdb.ID) (map[string]interface{}, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	path := strings.Replace(organizationsIDConstantsPath, ":id", orgID.String(), 1)
+
+	var res constantsResponse
+	err := s.Client.
+		Get(path).
+		DecodeJSON(&res).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return decodeConstants(res.Constants)
+}
+
+// PatchConstants creates or replaces the constants of an organization via HTTP.
+func (s *ConstantService) PatchConstants(ctx context.Context, orgID influxdb.ID, m map[string]interface{}) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	raw := make(map[string]json.RawMessage, len(m))
+	for name, v := range m {
+		b, err := influxdb.MarshalConstant(v)
+		if err != nil {
+			return err
+		}
+		raw[name] = b
+	}
+
+	path := strings.Replace(organizationsIDConstantsPath, ":id", orgID.String(), 1)
+	return s.Client.
+		PatchJSON(raw, path).
+		Do(ctx)
+}
+
+// DeleteConstants removes constants of an organization via HTTP.
+func (s *ConstantService) DeleteConstants(ctx context.Context, orgID influxdb.ID, names ...string) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	path := strings.Replace(organizationsIDConstantsDeletePath, ":id", orgID.String(), 1)
+	return s.Client.
+		PostJSON(constantsDeleteBody{
+			Constants: names,
+		}, path).
+		Do(ctx)
+}
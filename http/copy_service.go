@@ -0,0 +1,166 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	kithttp "github.com/influxdata/influxdb/kit/transport/http"
+	"github.com/influxdata/influxdb/pkg/httpc"
+	"go.uber.org/zap"
+)
+
+// BucketCopyBackend is all services and associated parameters required to construct
+// the BucketCopyHandler.
+type BucketCopyBackend struct {
+	log *zap.Logger
+	influxdb.HTTPErrorHandler
+
+	BucketCopyService influxdb.BucketCopyService
+	BucketService     influxdb.BucketService
+}
+
+// NewBucketCopyBackend returns a new instance of BucketCopyBackend.
+func NewBucketCopyBackend(log *zap.Logger, b *APIBackend) *BucketCopyBackend {
+	return &BucketCopyBackend{
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		log:              log,
+
+		BucketCopyService: b.BucketCopyService,
+		BucketService:     b.BucketService,
+	}
+}
+
+// BucketCopyHandler represents an HTTP API handler for copying data between buckets.
+type BucketCopyHandler struct {
+	*httprouter.Router
+	api *kithttp.API
+	log *zap.Logger
+
+	BucketCopyService influxdb.BucketCopyService
+	BucketService     influxdb.BucketService
+}
+
+const (
+	prefixCopy = "/api/v2/copy"
+)
+
+// NewBucketCopyHandler returns a new instance of BucketCopyHandler.
+func NewBucketCopyHandler(log *zap.Logger, b *BucketCopyBackend) *BucketCopyHandler {
+	h := &BucketCopyHandler{
+		Router: NewRouter(b.HTTPErrorHandler),
+		api:    kithttp.NewAPI(kithttp.WithLog(log)),
+		log:    log,
+
+		BucketCopyService: b.BucketCopyService,
+		BucketService:     b.BucketService,
+	}
+
+	h.HandlerFunc("POST", prefixCopy, h.handlePostCopy)
+
+	return h
+}
+
+// copyRequest is the body of a POST /api/v2/copy request.
+type copyRequest struct {
+	Source      influxdb.BucketCopyTarget `json:"source"`
+	Destination influxdb.BucketCopyTarget `json:"destination"`
+	Start       time.Time                 `json:"start"`
+	Stop        time.Time                 `json:"stop"`
+}
+
+func (r *copyRequest) OK() error {
+	if !r.Source.BucketID.Valid() || !r.Destination.BucketID.Valid() {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "source and destination bucket ids are required",
+		}
+	}
+	if r.Source == r.Destination {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "source and destination buckets must differ",
+		}
+	}
+	if r.Start.IsZero() || r.Stop.IsZero() || !r.Start.Before(r.Stop) {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "start and stop are required and start must be before stop",
+		}
+	}
+	return nil
+}
+
+// handlePostCopy is the HTTP handler for the POST /api/v2/copy route.
+func (h *BucketCopyHandler) handlePostCopy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req copyRequest
+	if err := h.api.DecodeJSON(r.Body, &req); err != nil {
+		h.api.Err(w, err)
+		return
+	}
+
+	// The organizations of the buckets are optional, and must match when set.
+	for _, t := range []*influxdb.BucketCopyTarget{&req.Source, &req.Destination} {
+		b, err := h.BucketService.FindBucketByID(ctx, t.BucketID)
+		if err != nil {
+			h.api.Err(w, err)
+			return
+		}
+		if t.OrgID.Valid() && t.OrgID != b.OrgID {
+			h.api.Err(w, &influxdb.Error{
+				Code: influxdb.ENotFound,
+				Msg:  "bucket not found",
+			})
+			return
+		}
+		t.OrgID = b.OrgID
+	}
+
+	// The stop time is exclusive while the copied range is inclusive.
+	stats, err := h.BucketCopyService.CopyBucketRange(ctx, req.Source, req.Destination,
+		req.Start.UnixNano(), req.Stop.UnixNano()-1)
+	if err != nil {
+		h.api.Err(w, err)
+		return
+	}
+	h.log.Info("Bucket data copied",
+		zap.Stringer("source", req.Source.BucketID),
+		zap.Stringer("destination", req.Destination.BucketID),
+		zap.Int("series", stats.Series))
+
+	h.api.Respond(w, http.StatusOK, stats)
+}
+
+// BucketCopyService connects to Influx via HTTP using tokens to copy data between buckets.
+type BucketCopyService struct {
+	Client *httpc.Client
+}
+
+var _ influxdb.BucketCopyService = (*BucketCopyService)(nil)
+
+// CopyBucketRange copies the data of src with timestamps within [min, max] to dst.
+func (s *BucketCopyService) CopyBucketRange(ctx context.Context, src, dst influxdb.BucketCopyTarget, min, max int64) (*influxdb.BucketCopyStats, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	req := copyRequest{
+		Source:      src,
+		Destination: dst,
+		Start:       time.Unix(0, min).UTC(),
+		Stop:        time.Unix(0, max).Add(1).UTC(),
+	}
+	var stats influxdb.BucketCopyStats
+	err := s.Client.
+		PostJSON(req, prefixCopy).
+		DecodeJSON(&stats).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
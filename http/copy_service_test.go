@@ -0,0 +1,106 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	kithttp "github.com/influxdata/influxdb/kit/transport/http"
+	"github.com/influxdata/influxdb/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestBucketCopyHandler(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantSrc    influxdb.BucketCopyTarget
+		wantDst    influxdb.BucketCopyTarget
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "copy",
+			body:       `{"source":{"bucketID":"0000000000000011"},"destination":{"bucketID":"0000000000000021"},"start":"2020-01-01T00:00:00Z","stop":"2020-01-02T00:00:00Z"}`,
+			wantSrc:    influxdb.BucketCopyTarget{OrgID: 0x10, BucketID: 0x11},
+			wantDst:    influxdb.BucketCopyTarget{OrgID: 0x20, BucketID: 0x21},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"series":2,"blocks":3,"reencodedBlocks":1,"bytes":100}`,
+		},
+		{
+			name:       "copy with organizations",
+			body:       `{"source":{"orgID":"0000000000000010","bucketID":"0000000000000011"},"destination":{"orgID":"0000000000000020","bucketID":"0000000000000021"},"start":"2020-01-01T00:00:00Z","stop":"2020-01-02T00:00:00Z"}`,
+			wantSrc:    influxdb.BucketCopyTarget{OrgID: 0x10, BucketID: 0x11},
+			wantDst:    influxdb.BucketCopyTarget{OrgID: 0x20, BucketID: 0x21},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "organization mismatch",
+			body:       `{"source":{"orgID":"0000000000000020","bucketID":"0000000000000011"},"destination":{"bucketID":"0000000000000021"},"start":"2020-01-01T00:00:00Z","stop":"2020-01-02T00:00:00Z"}`,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "same bucket",
+			body:       `{"source":{"bucketID":"0000000000000011"},"destination":{"bucketID":"0000000000000011"},"start":"2020-01-01T00:00:00Z","stop":"2020-01-02T00:00:00Z"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "missing stop",
+			body:       `{"source":{"bucketID":"0000000000000011"},"destination":{"bucketID":"0000000000000021"},"start":"2020-01-01T00:00:00Z"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var called bool
+			svc := mock.NewBucketCopyService()
+			svc.CopyBucketRangeF = func(ctx context.Context, src, dst influxdb.BucketCopyTarget, min, max int64) (*influxdb.BucketCopyStats, error) {
+				called = true
+				if src != tt.wantSrc || dst != tt.wantDst {
+					t.Errorf("unexpected buckets: got %+v -> %+v, want %+v -> %+v", src, dst, tt.wantSrc, tt.wantDst)
+				}
+				if min != start.UnixNano() || max != start.Add(24*time.Hour).UnixNano()-1 {
+					t.Errorf("unexpected range: [%d, %d]", min, max)
+				}
+				return &influxdb.BucketCopyStats{Series: 2, Blocks: 3, ReencodedBlocks: 1, Bytes: 100}, nil
+			}
+			buckets := mock.NewBucketService()
+			buckets.FindBucketByIDFn = func(ctx context.Context, id influxdb.ID) (*influxdb.Bucket, error) {
+				// Buckets 0x11 and 0x21 belong to organizations 0x10 and 0x20.
+				return &influxdb.Bucket{ID: id, OrgID: id &^ 0xf}, nil
+			}
+
+			h := NewBucketCopyHandler(zaptest.NewLogger(t), &BucketCopyBackend{
+				HTTPErrorHandler:  kithttp.ErrorHandler(0),
+				BucketCopyService: svc,
+				BucketService:     buckets,
+			})
+
+			r := httptest.NewRequest("POST", "http://any.url/api/v2/copy", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			res := w.Result()
+			body, _ := ioutil.ReadAll(res.Body)
+			if res.StatusCode != tt.wantStatus {
+				t.Fatalf("unexpected status code: got %d, want %d: %s", res.StatusCode, tt.wantStatus, body)
+			}
+			if tt.wantBody != "" {
+				if eq, diff, _ := jsonEqual(string(body), tt.wantBody); !eq {
+					t.Errorf("unexpected body -got/+want:\n%s", diff)
+				}
+			}
+			if called != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("unexpected copy: called %v", called)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/pkg/httpc"
+	"go.uber.org/zap"
+)
+
+// DefinitionHistoryBackend is all services and associated parameters required
+// to construct the handlers of the versions of the definition of a resource.
+type DefinitionHistoryBackend struct {
+	log *zap.Logger
+	influxdb.HTTPErrorHandler
+	DefinitionHistoryService influxdb.DefinitionHistoryService
+	ResourceType             influxdb.ResourceType
+
+	// find looks up the resource with the services of the handler of the
+	// resource, checking that it exists and that it can be read.
+	find func(ctx context.Context, id influxdb.ID) error
+
+	// rollback restores the definition of the resource to the version,
+	// returning the response of the updated resource.
+	rollback func(ctx context.Context, id influxdb.ID, v *influxdb.DefinitionVersion) (interface{}, error)
+}
+
+type definitionVersionResponse struct {
+	Links map[string]string `json:"links"`
+	influxdb.DefinitionVersion
+}
+
+func newDefinitionVersionResponse(prefix string, v *influxdb.DefinitionVersion) *definitionVersionResponse {
+	return &definitionVersionResponse{
+		Links: map[string]string{
+			"self":     definitionVersionPath(prefix, v.ResourceID, v.Version),
+			"rollback": path.Join(definitionVersionPath(prefix, v.ResourceID, v.Version), "rollback"),
+		},
+		DefinitionVersion: *v,
+	}
+}
+
+type definitionVersionsResponse struct {
+	Links    map[string]string            `json:"links"`
+	Versions []*definitionVersionResponse `json:"versions"`
+	// Total is the number of versions of the definition.
+	Total int `json:"total"`
+}
+
+func newDefinitionVersionsResponse(prefix string, id influxdb.ID, vs []*influxdb.DefinitionVersion, total int) *definitionVersionsResponse {
+	res := &definitionVersionsResponse{
+		Links: map[string]string{
+			"self": path.Join(prefix, id.String(), "versions"),
+		},
+		Versions: make([]*definitionVersionResponse, 0, len(vs)),
+		Total:    total,
+	}
+	for _, v := range vs {
+		res.Versions = append(res.Versions, newDefinitionVersionResponse(prefix, v))
+	}
+	return res
+}
+
+func definitionVersionPath(prefix string, id influxdb.ID, version int) string {
+	return path.Join(prefix, id.String(), "versions", strconv.Itoa(version))
+}
+
+// newGetDefinitionVersionsHandler returns a handler func for a GET to the
+// /versions endpoints, listing the versions from the most recent one unless
+// descending is false.
+func newGetDefinitionVersionsHandler(b *DefinitionHistoryBackend, prefix string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := decodeIDFromCtx(ctx, "id")
+		if err != nil {
+			b.HandleHTTPError(ctx, err, w)
+			return
+		}
+
+		opts, err := decodeFindOptions(r)
+		if err != nil {
+			b.HandleHTTPError(ctx, err, w)
+			return
+		}
+		if r.URL.Query().Get("descending") == "" {
+			opts.Descending = true
+		}
+
+		if err := b.find(ctx, id); err != nil {
+			b.HandleHTTPError(ctx, err, w)
+			return
+		}
+
+		vs, total, err := b.DefinitionHistoryService.FindDefinitionVersions(ctx, b.ResourceType, id, *opts)
+		if err != nil {
+			b.HandleHTTPError(ctx, err, w)
+			return
+		}
+
+		if err := encodeResponse(ctx, w, http.StatusOK, newDefinitionVersionsResponse(prefix, id, vs, total)); err != nil {
+			logEncodingError(b.log, r, err)
+			return
+		}
+	}
+}
+
+// newGetDefinitionVersionHandler returns a handler func for a GET to the
+// /versions/:version endpoints.
+func newGetDefinitionVersionHandler(b *DefinitionHistoryBackend, prefix string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, version, err := decodeDefinitionVersionRequest(ctx)
+		if err != nil {
+			b.HandleHTTPError(ctx, err, w)
+			return
+		}
+
+		if err := b.find(ctx, id); err != nil {
+			b.HandleHTTPError(ctx, err, w)
+			return
+		}
+
+		v, err := b.DefinitionHistoryService.FindDefinitionVersion(ctx, b.ResourceType, id, version)
+		if err != nil {
+			b.HandleHTTPError(ctx, err, w)
+			return
+		}
+
+		if err := encodeResponse(ctx, w, http.StatusOK, newDefinitionVersionResponse(prefix, v)); err != nil {
+			logEncodingError(b.log, r, err)
+			return
+		}
+	}
+}
+
+// newPostDefinitionRollbackHandler returns a handler func for a POST to the
+// /versions/:version/rollback endpoints, which update the resource with the
+// definition of the version. The rollback is itself recorded as a new version.
+func newPostDefinitionRollbackHandler(b *DefinitionHistoryBackend) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, version, err := decodeDefinitionVersionRequest(ctx)
+		if err != nil {
+			b.HandleHTTPError(ctx, err, w)
+			return
+		}
+
+		if err := b.find(ctx, id); err != nil {
+			b.HandleHTTPError(ctx, err, w)
+			return
+		}
+
+		v, err := b.DefinitionHistoryService.FindDefinitionVersion(ctx, b.ResourceType, id, version)
+		if err != nil {
+			b.HandleHTTPError(ctx, err, w)
+			return
+		}
+
+		res, err := b.rollback(ctx, id, v)
+		if err != nil {
+			b.HandleHTTPError(ctx, err, w)
+			return
+		}
+		b.log.Debug("Definition rolled back", zap.String("resourceType", string(b.ResourceType)),
+			zap.Stringer("id", id), zap.Int("version", version))
+
+		if err := encodeResponse(ctx, w, http.StatusOK, res); err != nil {
+			logEncodingError(b.log, r, err)
+			return
+		}
+	}
+}
+
+func decodeDefinitionVersionRequest(ctx context.Context) (influxdb.ID, int, error) {
+	id, err := decodeIDFromCtx(ctx, "id")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	params := httprouter.ParamsFromContext(ctx)
+	version, err := strconv.Atoi(params.ByName("version"))
+	if err != nil || version < 1 {
+		return 0, 0, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  fmt.Sprintf("invalid version %q", params.ByName("version")),
+		}
+	}
+	return id, version, nil
+}
+
+// definitionHistoryClient retrieves and rolls back the versions of the
+// definition of resources through the /versions endpoints under prefix.
+type definitionHistoryClient struct {
+	client *httpc.Client
+	prefix string
+}
+
+func (c definitionHistoryClient) findVersions(ctx context.Context, id influxdb.ID, opts influxdb.FindOptions) ([]*influxdb.DefinitionVersion, int, error) {
+	var res definitionVersionsResponse
+	err := c.client.
+		Get(c.prefix, id.String(), "versions").
+		QueryParams(findOptionParams(opts)...).
+		DecodeJSON(&res).
+		Do(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	vs := make([]*influxdb.DefinitionVersion, 0, len(res.Versions))
+	for _, v := range res.Versions {
+		vs = append(vs, &v.DefinitionVersion)
+	}
+	return vs, res.Total, nil
+}
+
+func (c definitionHistoryClient) findVersion(ctx context.Context, id influxdb.ID, version int) (*influxdb.DefinitionVersion, error) {
+	var res definitionVersionResponse
+	err := c.client.
+		Get(definitionVersionPath(c.prefix, id, version)).
+		DecodeJSON(&res).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &res.DefinitionVersion, nil
+}
+
+func (c definitionHistoryClient) rollback(ctx context.Context, id influxdb.ID, version int, v interface{}) error {
+	return c.client.
+		Post(nil, definitionVersionPath(c.prefix, id, version), "rollback").
+		DecodeJSON(v).
+		Do(ctx)
+}
@@ -0,0 +1,132 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	kithttp "github.com/influxdata/influxdb/kit/transport/http"
+	"github.com/influxdata/influxdb/pkg/httpc"
+	"go.uber.org/zap"
+)
+
+// DiagnosticsBackend is all services and associated parameters required to construct
+// the DiagnosticsHandler.
+type DiagnosticsBackend struct {
+	log *zap.Logger
+	influxdb.HTTPErrorHandler
+
+	DiagnosticsService influxdb.DiagnosticsService
+}
+
+// NewDiagnosticsBackend returns a new instance of DiagnosticsBackend.
+func NewDiagnosticsBackend(log *zap.Logger, b *APIBackend) *DiagnosticsBackend {
+	return &DiagnosticsBackend{
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		log:              log,
+
+		DiagnosticsService: b.DiagnosticsService,
+	}
+}
+
+// DiagnosticsHandler represents an HTTP API handler reporting the configuration
+// and the recent queries of the server.
+type DiagnosticsHandler struct {
+	*httprouter.Router
+	api *kithttp.API
+	log *zap.Logger
+
+	DiagnosticsService influxdb.DiagnosticsService
+}
+
+const (
+	prefixDiagnostics      = "/api/v2/diagnostics"
+	diagnosticsConfigPath  = "/api/v2/diagnostics/config"
+	diagnosticsQueriesPath = "/api/v2/diagnostics/queries"
+)
+
+// NewDiagnosticsHandler returns a new instance of DiagnosticsHandler.
+func NewDiagnosticsHandler(log *zap.Logger, b *DiagnosticsBackend) *DiagnosticsHandler {
+	h := &DiagnosticsHandler{
+		Router: NewRouter(b.HTTPErrorHandler),
+		api:    kithttp.NewAPI(kithttp.WithLog(log)),
+		log:    log,
+
+		DiagnosticsService: b.DiagnosticsService,
+	}
+
+	h.HandlerFunc("GET", diagnosticsConfigPath, h.handleGetConfig)
+	h.HandlerFunc("GET", diagnosticsQueriesPath, h.handleGetQueries)
+
+	return h
+}
+
+type configResponse struct {
+	Options []influxdb.ConfigOption `json:"options"`
+}
+
+// handleGetConfig is the HTTP handler for the GET /api/v2/diagnostics/config route.
+func (h *DiagnosticsHandler) handleGetConfig(w http.ResponseWriter, r *http.Request) {
+	opts, err := h.DiagnosticsService.Config(r.Context())
+	if err != nil {
+		h.api.Err(w, err)
+		return
+	}
+	if opts == nil {
+		opts = []influxdb.ConfigOption{}
+	}
+
+	h.api.Respond(w, http.StatusOK, configResponse{Options: opts})
+}
+
+// handleGetQueries is the HTTP handler for the GET /api/v2/diagnostics/queries route.
+func (h *DiagnosticsHandler) handleGetQueries(w http.ResponseWriter, r *http.Request) {
+	log, err := h.DiagnosticsService.QueryLog(r.Context())
+	if err != nil {
+		h.api.Err(w, err)
+		return
+	}
+
+	h.api.Respond(w, http.StatusOK, log)
+}
+
+// DiagnosticsService connects to Influx via HTTP using tokens to report its diagnostics.
+type DiagnosticsService struct {
+	Client *httpc.Client
+}
+
+var _ influxdb.DiagnosticsService = (*DiagnosticsService)(nil)
+
+// Config returns the configuration options of the server, with secrets redacted.
+func (s *DiagnosticsService) Config(ctx context.Context) ([]influxdb.ConfigOption, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var resp configResponse
+	err := s.Client.
+		Get(diagnosticsConfigPath).
+		DecodeJSON(&resp).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Options, nil
+}
+
+// QueryLog returns the most recent queries and the most recent slow queries.
+func (s *DiagnosticsService) QueryLog(ctx context.Context) (*influxdb.QueryLog, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var log influxdb.QueryLog
+	err := s.Client.
+		Get(diagnosticsQueriesPath).
+		DecodeJSON(&log).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
@@ -0,0 +1,68 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	kithttp "github.com/influxdata/influxdb/kit/transport/http"
+	"github.com/influxdata/influxdb/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestDiagnosticsHandler(t *testing.T) {
+	svc := mock.NewDiagnosticsService()
+	svc.ConfigF = func(ctx context.Context) ([]influxdb.ConfigOption, error) {
+		return []influxdb.ConfigOption{{Name: "log-level", Value: "info"}, {Name: "vault-token", Value: "[REDACTED]"}}, nil
+	}
+	svc.QueryLogF = func(ctx context.Context) (*influxdb.QueryLog, error) {
+		e := influxdb.QueryLogEntry{
+			Time:           time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+			OrganizationID: 1,
+			Duration:       2 * time.Second,
+			Request:        json.RawMessage(`{"query":"buckets()"}`),
+			Plan:           "digraph {}",
+			ResponseSize:   10,
+		}
+		return &influxdb.QueryLog{SlowThreshold: time.Second, Recent: []influxdb.QueryLogEntry{e}, Slow: []influxdb.QueryLogEntry{e}}, nil
+	}
+
+	h := NewDiagnosticsHandler(zaptest.NewLogger(t), &DiagnosticsBackend{
+		HTTPErrorHandler:   kithttp.ErrorHandler(0),
+		DiagnosticsService: svc,
+	})
+
+	entry := `{"time":"2020-01-01T00:00:00Z","orgID":"0000000000000001","duration":2000000000,"request":{"query":"buckets()"},"plan":"digraph {}","responseSize":10}`
+	for _, tt := range []struct {
+		path     string
+		wantBody string
+	}{
+		{
+			path:     "/api/v2/diagnostics/config",
+			wantBody: `{"options":[{"name":"log-level","value":"info"},{"name":"vault-token","value":"[REDACTED]"}]}`,
+		},
+		{
+			path:     "/api/v2/diagnostics/queries",
+			wantBody: `{"slowThreshold":1000000000,"recent":[` + entry + `],"slow":[` + entry + `]}`,
+		},
+	} {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", "http://any.url"+tt.path, nil))
+
+			res := w.Result()
+			body, _ := ioutil.ReadAll(res.Body)
+			if res.StatusCode != http.StatusOK {
+				t.Fatalf("unexpected status code: got %d, want %d: %s", res.StatusCode, http.StatusOK, body)
+			}
+			if eq, diff, _ := jsonEqual(string(body), tt.wantBody); !eq {
+				t.Errorf("unexpected body -got/+want:\n%s", diff)
+			}
+		})
+	}
+}
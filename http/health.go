@@ -3,6 +3,8 @@ package http
 import (
 	"fmt"
 	"net/http"
+
+	"github.com/influxdata/influxdb/kit/check"
 )
 
 // HealthHandler returns the status of the process.
@@ -12,3 +14,35 @@ func HealthHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	fmt.Fprintln(w, msg)
 }
+
+// NewHealthHandler returns a handler of the status of the process, which
+// also reports the responses of the checkers. The status fails, with a 503
+// status code, if any of the checks fails.
+func NewHealthHandler(checkers ...check.Checker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := check.Response{
+			Name:    "influxdb",
+			Status:  check.StatusPass,
+			Message: "ready for queries and writes",
+			Checks:  make(check.Responses, 0, len(checkers)),
+		}
+		for _, c := range checkers {
+			cr := c.Check(r.Context())
+			if cr.Status != check.StatusPass {
+				resp.Status = cr.Status
+				resp.Message = "some checks are failing"
+			}
+			resp.Checks = append(resp.Checks, cr)
+		}
+
+		status := http.StatusOK
+		if resp.Status != check.StatusPass {
+			status = http.StatusServiceUnavailable
+		}
+		// The checks are always encoded, even if there are none.
+		encodeResponse(r.Context(), w, status, struct {
+			check.Response
+			Checks check.Responses `json:"checks"`
+		}{resp, resp.Checks})
+	})
+}
@@ -1,10 +1,13 @@
 package http
 
 import (
+	"context"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/influxdata/influxdb/kit/check"
 )
 
 func TestHealthHandler(t *testing.T) {
@@ -53,3 +56,37 @@ func TestHealthHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestNewHealthHandler(t *testing.T) {
+	failing := checkerFunc(func(ctx context.Context) check.Response {
+		return check.Response{Name: "clock", Status: check.StatusFail, Message: "clock jumped backward"}
+	})
+
+	w := httptest.NewRecorder()
+	NewHealthHandler(failing).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	res := w.Result()
+	body, _ := ioutil.ReadAll(res.Body)
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+	want := `{"name":"influxdb", "message":"some checks are failing", "status":"fail", "checks":[{"name":"clock", "status":"fail", "message":"clock jumped backward"}]}`
+	if eq, diff, err := jsonEqual(string(body), want); err != nil {
+		t.Fatal(err)
+	} else if !eq {
+		t.Errorf("unexpected body: %s", diff)
+	}
+
+	w = httptest.NewRecorder()
+	NewHealthHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	body, _ = ioutil.ReadAll(w.Result().Body)
+	want = `{"name":"influxdb", "message":"ready for queries and writes", "status":"pass", "checks":[]}`
+	if eq, diff, err := jsonEqual(string(body), want); err != nil {
+		t.Fatal(err)
+	} else if !eq {
+		t.Errorf("unexpected body: %s", diff)
+	}
+}
+
+type checkerFunc func(ctx context.Context) check.Response
+
+func (f checkerFunc) Check(ctx context.Context) check.Response { return f(ctx) }
@@ -17,6 +17,7 @@ type Event struct {
 	Endpoint      string
 	RequestBytes  int
 	ResponseBytes int
+	ValuesDropped int
 	Status        int
 }
 
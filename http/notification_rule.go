@@ -32,6 +32,7 @@ type NotificationRuleBackend struct {
 	UserService                 influxdb.UserService
 	OrganizationService         influxdb.OrganizationService
 	TaskService                 influxdb.TaskService
+	DefinitionHistoryService    influxdb.DefinitionHistoryService
 }
 
 // NewNotificationRuleBackend returns a new instance of NotificationRuleBackend.
@@ -47,6 +48,7 @@ func NewNotificationRuleBackend(log *zap.Logger, b *APIBackend) *NotificationRul
 		UserService:                 b.UserService,
 		OrganizationService:         b.OrganizationService,
 		TaskService:                 b.TaskService,
+		DefinitionHistoryService:    b.DefinitionHistoryService,
 	}
 }
 
@@ -63,6 +65,7 @@ type NotificationRuleHandler struct {
 	UserService                 influxdb.UserService
 	OrganizationService         influxdb.OrganizationService
 	TaskService                 influxdb.TaskService
+	DefinitionHistoryService    influxdb.DefinitionHistoryService
 }
 
 const (
@@ -75,6 +78,9 @@ const (
 	notificationRulesIDOwnersIDPath  = "/api/v2/notificationRules/:id/owners/:userID"
 	notificationRulesIDLabelsPath    = "/api/v2/notificationRules/:id/labels"
 	notificationRulesIDLabelsIDPath  = "/api/v2/notificationRules/:id/labels/:lid"
+	notificationRulesIDVersionsPath  = "/api/v2/notificationRules/:id/versions"
+	notificationRulesIDVersionPath   = "/api/v2/notificationRules/:id/versions/:version"
+	notificationRulesIDRollbackPath  = "/api/v2/notificationRules/:id/versions/:version/rollback"
 )
 
 // NewNotificationRuleHandler returns a new instance of NotificationRuleHandler.
@@ -91,6 +97,7 @@ func NewNotificationRuleHandler(log *zap.Logger, b *NotificationRuleBackend) *No
 		UserService:                 b.UserService,
 		OrganizationService:         b.OrganizationService,
 		TaskService:                 b.TaskService,
+		DefinitionHistoryService:    b.DefinitionHistoryService,
 	}
 	h.HandlerFunc("POST", prefixNotificationRules, h.handlePostNotificationRule)
 	h.HandlerFunc("GET", prefixNotificationRules, h.handleGetNotificationRules)
@@ -134,9 +141,63 @@ func NewNotificationRuleHandler(log *zap.Logger, b *NotificationRuleBackend) *No
 	h.HandlerFunc("POST", notificationRulesIDLabelsPath, newPostLabelHandler(labelBackend))
 	h.HandlerFunc("DELETE", notificationRulesIDLabelsIDPath, newDeleteLabelHandler(labelBackend))
 
+	historyBackend := &DefinitionHistoryBackend{
+		HTTPErrorHandler:         b.HTTPErrorHandler,
+		log:                      b.log.With(zap.String("handler", "definition_history")),
+		DefinitionHistoryService: b.DefinitionHistoryService,
+		ResourceType:             influxdb.NotificationRuleResourceType,
+		find: func(ctx context.Context, id influxdb.ID) error {
+			_, err := h.NotificationRuleStore.FindNotificationRuleByID(ctx, id)
+			return err
+		},
+		rollback: h.rollbackNotificationRule,
+	}
+	h.HandlerFunc("GET", notificationRulesIDVersionsPath, newGetDefinitionVersionsHandler(historyBackend, prefixNotificationRules))
+	h.HandlerFunc("GET", notificationRulesIDVersionPath, newGetDefinitionVersionHandler(historyBackend, prefixNotificationRules))
+	h.HandlerFunc("POST", notificationRulesIDRollbackPath, newPostDefinitionRollbackHandler(historyBackend))
+
 	return h
 }
 
+// rollbackNotificationRule restores the notification rule to the definition
+// of the version, keeping its current status.
+func (h *NotificationRuleHandler) rollbackNotificationRule(ctx context.Context, id influxdb.ID, v *influxdb.DefinitionVersion) (interface{}, error) {
+	nr, err := rule.UnmarshalJSON([]byte(v.Definition))
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInternal,
+			Msg:  "malformed notification rule definition",
+			Err:  err,
+		}
+	}
+
+	auth, err := pctx.GetAuthorizer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := h.NotificationRuleStore.FindNotificationRuleByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	t, err := h.TaskService.FindTaskByID(ctx, current.GetTaskID())
+	if err != nil {
+		return nil, err
+	}
+
+	nrc := influxdb.NotificationRuleCreate{NotificationRule: nr, Status: influxdb.Status(t.Status)}
+	updated, err := h.NotificationRuleStore.UpdateNotificationRule(ctx, id, nrc, auth.GetUserID())
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := h.LabelService.FindResourceLabels(ctx, influxdb.LabelMappingFilter{ResourceID: updated.GetID()})
+	if err != nil {
+		return nil, err
+	}
+	return h.newNotificationRuleResponse(ctx, updated, labels)
+}
+
 type notificationRuleLinks struct {
 	Self    string `json:"self"`
 	Labels  string `json:"labels"`
@@ -25,6 +25,7 @@ type OrgBackend struct {
 	OrganizationOperationLogService influxdb.OrganizationOperationLogService
 	UserResourceMappingService      influxdb.UserResourceMappingService
 	SecretService                   influxdb.SecretService
+	ConstantService                 influxdb.ConstantService
 	LabelService                    influxdb.LabelService
 	UserService                     influxdb.UserService
 }
@@ -39,6 +40,7 @@ func NewOrgBackend(log *zap.Logger, b *APIBackend) *OrgBackend {
 		OrganizationOperationLogService: b.OrganizationOperationLogService,
 		UserResourceMappingService:      b.UserResourceMappingService,
 		SecretService:                   b.SecretService,
+		ConstantService:                 b.ConstantService,
 		LabelService:                    b.LabelService,
 		UserService:                     b.UserService,
 	}
@@ -54,6 +56,7 @@ type OrgHandler struct {
 	OrganizationOperationLogService influxdb.OrganizationOperationLogService
 	UserResourceMappingService      influxdb.UserResourceMappingService
 	SecretService                   influxdb.SecretService
+	ConstantService                 influxdb.ConstantService
 	LabelService                    influxdb.LabelService
 	UserService                     influxdb.UserService
 }
@@ -68,9 +71,11 @@ const (
 	organizationsIDOwnersIDPath  = "/api/v2/orgs/:id/owners/:userID"
 	organizationsIDSecretsPath   = "/api/v2/orgs/:id/secrets"
 	// TODO(desa): need a way to specify which secrets to delete. this should work for now
-	organizationsIDSecretsDeletePath = "/api/v2/orgs/:id/secrets/delete"
-	organizationsIDLabelsPath        = "/api/v2/orgs/:id/labels"
-	organizationsIDLabelsIDPath      = "/api/v2/orgs/:id/labels/:lid"
+	organizationsIDSecretsDeletePath   = "/api/v2/orgs/:id/secrets/delete"
+	organizationsIDConstantsPath       = "/api/v2/orgs/:id/constants"
+	organizationsIDConstantsDeletePath = "/api/v2/orgs/:id/constants/delete"
+	organizationsIDLabelsPath          = "/api/v2/orgs/:id/labels"
+	organizationsIDLabelsIDPath        = "/api/v2/orgs/:id/labels/:lid"
 )
 
 func checkOrganizationExists(orgHandler *OrgHandler) kithttp.Middleware {
@@ -105,6 +110,7 @@ func NewOrgHandler(log *zap.Logger, b *OrgBackend) *OrgHandler {
 		OrganizationOperationLogService: b.OrganizationOperationLogService,
 		UserResourceMappingService:      b.UserResourceMappingService,
 		SecretService:                   b.SecretService,
+		ConstantService:                 b.ConstantService,
 		LabelService:                    b.LabelService,
 		UserService:                     b.UserService,
 	}
@@ -145,6 +151,10 @@ func NewOrgHandler(log *zap.Logger, b *OrgBackend) *OrgHandler {
 	// TODO(desa): need a way to specify which secrets to delete. this should work for now
 	h.HandlerFunc("POST", organizationsIDSecretsDeletePath, h.handleDeleteSecrets)
 
+	h.HandlerFunc("GET", organizationsIDConstantsPath, h.handleGetConstants)
+	h.HandlerFunc("PATCH", organizationsIDConstantsPath, h.handlePatchConstants)
+	h.HandlerFunc("POST", organizationsIDConstantsDeletePath, h.handleDeleteConstants)
+
 	labelBackend := &LabelBackend{
 		HTTPErrorHandler: b.HTTPErrorHandler,
 		log:              b.log.With(zap.String("handler", "label")),
@@ -197,6 +207,7 @@ func newOrgResponse(o influxdb.Organization) orgResponse {
 			"members":    fmt.Sprintf("/api/v2/orgs/%s/members", o.ID),
 			"owners":     fmt.Sprintf("/api/v2/orgs/%s/owners", o.ID),
 			"secrets":    fmt.Sprintf("/api/v2/orgs/%s/secrets", o.ID),
+			"constants":  fmt.Sprintf("/api/v2/orgs/%s/constants", o.ID),
 			"labels":     fmt.Sprintf("/api/v2/orgs/%s/labels", o.ID),
 			"buckets":    fmt.Sprintf("/api/v2/buckets?org=%s", o.Name),
 			"tasks":      fmt.Sprintf("/api/v2/tasks?org=%s", o.Name),
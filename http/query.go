@@ -38,6 +38,24 @@ type QueryRequest struct {
 	AST     *ast.Package `json:"ast,omitempty"`
 	Dialect QueryDialect `json:"dialect"`
 
+	// Limits optionally limits the size of the result. They may only be
+	// stricter than the query result limits of the organization.
+	Limits *influxdb.QueryResultLimits `json:"limits,omitempty"`
+
+	// Explain appends to the results the operations of the query that were
+	// not pushed down to storage, and why.
+	Explain bool `json:"explain,omitempty"`
+
+	// Profilers appends to the results the statistics of the query reported
+	// by each of the profilers, query or operator.
+	Profilers []string `json:"profilers,omitempty"`
+
+	// ReadRateLimit optionally limits the rate, in bytes per second, at which
+	// the query reads data from disk. It may only be lower than the query
+	// read rate limit of the organization. It is also set by the
+	// X-Influx-Query-Read-Rate header.
+	ReadRateLimit int64 `json:"readRateLimit,omitempty"`
+
 	// InfluxQL fields
 	Bucket string `json:"bucket,omitempty"`
 
@@ -138,6 +156,20 @@ func (r QueryRequest) Validate() error {
 		return fmt.Errorf(`unknown dialect date time format: %s`, r.Dialect.DateTimeFormat)
 	}
 
+	if r.Limits != nil && (r.Limits.MaxRows < 0 || r.Limits.MaxBytes < 0) {
+		return fmt.Errorf("invalid limits: must not be negative")
+	}
+
+	if r.ReadRateLimit < 0 {
+		return fmt.Errorf("invalid read rate limit: must not be negative")
+	}
+
+	for _, p := range r.Profilers {
+		if err := query.ValidProfiler(p); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -325,11 +357,39 @@ func (r QueryRequest) proxyRequest(now func() time.Time) (*query.ProxyRequest, e
 		Request: query.Request{
 			OrganizationID: r.Org.ID,
 			Compiler:       compiler,
+			ReadRateLimit:  r.readRateLimit(),
 		},
-		Dialect: dialect,
+		Dialect:   dialect,
+		Limits:    r.resultLimits(),
+		Explain:   r.Explain,
+		Profilers: r.Profilers,
 	}, nil
 }
 
+// resultLimits returns the stricter of the limits of the request and of its organization.
+func (r QueryRequest) resultLimits() *influxdb.QueryResultLimits {
+	var limits influxdb.QueryResultLimits
+	if r.Org != nil && r.Org.QueryResultLimits != nil {
+		limits = *r.Org.QueryResultLimits
+	}
+	if r.Limits != nil {
+		limits = limits.Merge(*r.Limits)
+	}
+	if limits.IsZero() {
+		return nil
+	}
+	return &limits
+}
+
+// readRateLimit returns the lower of the read rate limits of the request and of its organization.
+func (r QueryRequest) readRateLimit() int64 {
+	limit := r.ReadRateLimit
+	if r.Org != nil && r.Org.QueryReadRateLimit > 0 && (limit <= 0 || r.Org.QueryReadRateLimit < limit) {
+		limit = r.Org.QueryReadRateLimit
+	}
+	return limit
+}
+
 // QueryRequestFromProxyRequest converts a query.ProxyRequest into a QueryRequest.
 // The ProxyRequest must contain supported compilers and dialects otherwise an error occurs.
 func QueryRequestFromProxyRequest(req *query.ProxyRequest) (*QueryRequest, error) {
@@ -363,6 +423,10 @@ func QueryRequestFromProxyRequest(req *query.ProxyRequest) (*QueryRequest, error
 	default:
 		return nil, fmt.Errorf("unsupported dialect %T", d)
 	}
+	qr.Limits = req.Limits
+	qr.Explain = req.Explain
+	qr.Profilers = req.Profilers
+	qr.ReadRateLimit = req.Request.ReadRateLimit
 	return qr, nil
 }
 
@@ -400,6 +464,16 @@ func decodeQueryRequest(ctx context.Context, r *http.Request, svc influxdb.Organ
 		req.PreferNoContentWithError = true
 	}
 
+	if hv := r.Header.Get(query.ReadRateLimitHeaderKey); hv != "" {
+		limit, err := query.ParseReadRateLimit(hv)
+		if err != nil {
+			return nil, body.bytesRead, err
+		}
+		if req.ReadRateLimit <= 0 || (limit > 0 && limit < req.ReadRateLimit) {
+			req.ReadRateLimit = limit
+		}
+	}
+
 	req = req.WithDefaults()
 	if err := req.Validate(); err != nil {
 		return nil, body.bytesRead, err
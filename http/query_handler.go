@@ -28,6 +28,7 @@ import (
 	kithttp "github.com/influxdata/influxdb/kit/transport/http"
 	"github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/query/async"
 	"github.com/influxdata/influxdb/query/influxql"
 	"github.com/pkg/errors"
 	prom "github.com/prometheus/client_golang/prometheus"
@@ -48,6 +49,7 @@ type FluxBackend struct {
 
 	OrganizationService influxdb.OrganizationService
 	ProxyQueryService   query.ProxyQueryService
+	AsyncQueryService   async.QueryService
 }
 
 // NewFluxBackend returns a new instance of FluxBackend.
@@ -62,6 +64,7 @@ func NewFluxBackend(log *zap.Logger, b *APIBackend) *FluxBackend {
 			DefaultService:  b.FluxService,
 		},
 		OrganizationService: b.OrganizationService,
+		AsyncQueryService:   b.AsyncQueryService,
 	}
 }
 
@@ -79,6 +82,9 @@ type FluxHandler struct {
 	Now                 func() time.Time
 	OrganizationService influxdb.OrganizationService
 	ProxyQueryService   query.ProxyQueryService
+	// AsyncQueryService runs the queries submitted asynchronously. The async
+	// query routes are only registered when it is set.
+	AsyncQueryService async.QueryService
 
 	EventRecorder metric.EventRecorder
 }
@@ -98,6 +104,7 @@ func NewFluxHandler(log *zap.Logger, b *FluxBackend) *FluxHandler {
 
 		ProxyQueryService:   b.ProxyQueryService,
 		OrganizationService: b.OrganizationService,
+		AsyncQueryService:   b.AsyncQueryService,
 		EventRecorder:       b.QueryEventRecorder,
 	}
 
@@ -108,6 +115,12 @@ func NewFluxHandler(log *zap.Logger, b *FluxBackend) *FluxHandler {
 	h.HandlerFunc("POST", "/api/v2/query/analyze", h.postQueryAnalyze)
 	h.HandlerFunc("GET", "/api/v2/query/suggestions", h.getFluxSuggestions)
 	h.HandlerFunc("GET", "/api/v2/query/suggestions/:name", h.getFluxSuggestion)
+	if h.AsyncQueryService != nil {
+		h.HandlerFunc("POST", prefixAsyncQuery, h.postAsyncQuery)
+		h.HandlerFunc("GET", prefixAsyncQuery+"/:id", h.getAsyncQuery)
+		h.HandlerFunc("GET", prefixAsyncQuery+"/:id/result", h.getAsyncQueryResult)
+		h.HandlerFunc("DELETE", prefixAsyncQuery+"/:id", h.deleteAsyncQuery)
+	}
 	return h
 }
 
@@ -165,6 +178,13 @@ func (h *FluxHandler) handleQuery(w http.ResponseWriter, r *http.Request) {
 	orgID = req.Request.OrganizationID
 	requestBytes = n
 
+	priority, err := queryPriority(r, a, orgID)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	req.Request.Priority = priority
+
 	// Transform the context into one with the request's authorization.
 	ctx = pcontext.SetAuthorizer(ctx, req.Request.Authorization)
 
@@ -195,6 +215,38 @@ func (h *FluxHandler) handleQuery(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// queryPriority returns the priority of a query requested with the
+// X-Influx-Query-Priority header. The high priority is reserved to the
+// sessions of users, which run interactive queries, and to authorizations
+// allowed to write the organization.
+func queryPriority(r *http.Request, a influxdb.Authorizer, orgID influxdb.ID) (query.Priority, error) {
+	const op = "http/queryPriority"
+	p, err := query.ParsePriority(r.Header.Get(query.PriorityHeaderKey))
+	if err != nil {
+		return p, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Op:   op,
+			Err:  err,
+		}
+	}
+	if p != query.PriorityHigh || a.Kind() == influxdb.SessionAuthorizionKind {
+		return p, nil
+	}
+
+	perm, err := influxdb.NewPermissionAtID(orgID, influxdb.WriteAction, influxdb.OrgsResourceType, orgID)
+	if err != nil {
+		return p, err
+	}
+	if !a.Allowed(*perm) {
+		return p, &influxdb.Error{
+			Code: influxdb.EForbidden,
+			Op:   op,
+			Msg:  "high query priority requires write access to the organization",
+		}
+	}
+	return p, nil
+}
+
 type langRequest struct {
 	Query string `json:"query"`
 }
@@ -432,6 +484,10 @@ type FluxQueryService struct {
 	Token              string
 	Name               string
 	InsecureSkipVerify bool
+
+	// Profilers requests the statistics of the queries reported by each of
+	// the profilers, returned by the Statistics of their results.
+	Profilers []string
 }
 
 // Query runs a flux query against a influx server and decodes the result
@@ -448,8 +504,9 @@ func (s *FluxQueryService) Query(ctx context.Context, r *query.Request) (flux.Re
 	u.RawQuery = params.Encode()
 
 	preq := &query.ProxyRequest{
-		Request: *r,
-		Dialect: csv.DefaultDialect(),
+		Request:   *r,
+		Dialect:   csv.DefaultDialect(),
+		Profilers: s.Profilers,
 	}
 	qreq, err := QueryRequestFromProxyRequest(preq)
 	if err != nil {
@@ -492,13 +549,36 @@ func (s *FluxQueryService) Query(ctx context.Context, r *query.Request) (flux.Re
 		return nil, tracing.LogError(span, err)
 	}
 
+	if len(s.Profilers) == 0 {
+		decoder := csv.NewMultiResultDecoder(csv.ResultDecoderConfig{})
+		itr, err := decoder.Decode(resp.Body)
+		if err != nil {
+			return nil, tracing.LogError(span, err)
+		}
+		return itr, nil
+	}
+
+	pr := query.NewProfileReader(resp.Body)
 	decoder := csv.NewMultiResultDecoder(csv.ResultDecoderConfig{})
-	itr, err := decoder.Decode(resp.Body)
+	itr, err := decoder.Decode(struct {
+		io.Reader
+		io.Closer
+	}{pr, resp.Body})
 	if err != nil {
 		return nil, tracing.LogError(span, err)
 	}
+	return &profiledResultIterator{ResultIterator: itr, pr: pr}, nil
+}
+
+// profiledResultIterator is a ResultIterator whose statistics are those
+// decoded from the profile annotations of its results.
+type profiledResultIterator struct {
+	flux.ResultIterator
+	pr *query.ProfileReader
+}
 
-	return itr, nil
+func (i *profiledResultIterator) Statistics() flux.Statistics {
+	return i.ResultIterator.Statistics().Add(i.pr.Statistics())
 }
 
 func (s FluxQueryService) Check(ctx context.Context) check.Response {
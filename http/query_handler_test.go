@@ -449,6 +449,106 @@ func TestFluxHandler_PostQuery_Errors(t *testing.T) {
 	})
 }
 
+func TestFluxHandler_PostQuery_Priority(t *testing.T) {
+	orgSVC := newInMemKVSVC(t)
+	org := influxdb.Organization{Name: t.Name()}
+	if err := orgSVC.CreateOrganization(context.Background(), &org); err != nil {
+		t.Fatal(err)
+	}
+
+	var got query.Priority
+	b := &FluxBackend{
+		HTTPErrorHandler:    kithttp.ErrorHandler(0),
+		log:                 zaptest.NewLogger(t),
+		QueryEventRecorder:  noopEventRecorder{},
+		OrganizationService: orgSVC,
+		ProxyQueryService: &mock.ProxyQueryService{
+			QueryF: func(ctx context.Context, w io.Writer, req *query.ProxyRequest) (flux.Statistics, error) {
+				got = req.Request.Priority
+				return flux.Statistics{}, nil
+			},
+		},
+	}
+	h := NewFluxHandler(zaptest.NewLogger(t), b)
+
+	orgWrite, err := influxdb.NewPermissionAtID(org.ID, influxdb.WriteAction, influxdb.OrgsResourceType, org.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		header   string
+		authz    influxdb.Authorizer
+		wantCode int
+		want     query.Priority
+	}{
+		{
+			name:     "default",
+			authz:    &influxdb.Authorization{},
+			wantCode: http.StatusOK,
+			want:     query.PriorityNormal,
+		},
+		{
+			name:     "low",
+			header:   "low",
+			authz:    &influxdb.Authorization{},
+			wantCode: http.StatusOK,
+			want:     query.PriorityLow,
+		},
+		{
+			name:     "high without permission",
+			header:   "high",
+			authz:    &influxdb.Authorization{},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "high with write access to the organization",
+			header:   "High",
+			authz:    &influxdb.Authorization{Status: influxdb.Active, Permissions: []influxdb.Permission{*orgWrite}},
+			wantCode: http.StatusOK,
+			want:     query.PriorityHigh,
+		},
+		{
+			name:     "high with a session",
+			header:   "high",
+			authz:    &influxdb.Session{ExpiresAt: time.Now().Add(time.Hour)},
+			wantCode: http.StatusOK,
+			want:     query.PriorityHigh,
+		},
+		{
+			name:     "invalid",
+			header:   "urgent",
+			authz:    &influxdb.Authorization{},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = query.PriorityNormal
+			req, err := http.NewRequest("POST", "/api/v2/query?orgID="+org.ID.String(), strings.NewReader("buckets()"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(icontext.SetAuthorizer(req.Context(), tt.authz))
+			req.Header.Set("Content-Type", "application/vnd.flux")
+			if tt.header != "" {
+				req.Header.Set(query.PriorityHeaderKey, tt.header)
+			}
+
+			w := httptest.NewRecorder()
+			h.handleQuery(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("unexpected status: got %d, want %d: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			if got != tt.want {
+				t.Fatalf("unexpected priority: got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestFluxService_Query_gzip(t *testing.T) {
 	// orgService is just to mock out orgs by returning
 	// the same org every time.
@@ -212,6 +212,8 @@ func TestQueryRequest_proxyRequest(t *testing.T) {
 		Query   string
 		Type    string
 		Dialect QueryDialect
+		Limits  *platform.QueryResultLimits
+		Rate    int64
 		org     *platform.Organization
 	}
 	tests := []struct {
@@ -332,6 +334,68 @@ func TestQueryRequest_proxyRequest(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "stricter of request and organization limits",
+			fields: fields{
+				Query: "howdy",
+				Type:  "flux",
+				Dialect: QueryDialect{
+					Delimiter:      ",",
+					DateTimeFormat: "RFC3339",
+				},
+				Limits: &platform.QueryResultLimits{MaxRows: 100, MaxBytes: 5000},
+				org: &platform.Organization{
+					QueryResultLimits: &platform.QueryResultLimits{MaxRows: 10},
+				},
+			},
+			now: func() time.Time { return time.Unix(1, 1) },
+			want: &query.ProxyRequest{
+				Request: query.Request{
+					Compiler: lang.FluxCompiler{
+						Now:   time.Unix(1, 1),
+						Query: `howdy`,
+					},
+				},
+				Dialect: &csv.Dialect{
+					ResultEncoderConfig: csv.ResultEncoderConfig{
+						NoHeader:  false,
+						Delimiter: ',',
+					},
+				},
+				Limits: &platform.QueryResultLimits{MaxRows: 10, MaxBytes: 5000},
+			},
+		},
+		{
+			name: "lower of request and organization read rate limits",
+			fields: fields{
+				Query: "howdy",
+				Type:  "flux",
+				Dialect: QueryDialect{
+					Delimiter:      ",",
+					DateTimeFormat: "RFC3339",
+				},
+				Rate: 1 << 20,
+				org: &platform.Organization{
+					QueryReadRateLimit: 1 << 10,
+				},
+			},
+			now: func() time.Time { return time.Unix(1, 1) },
+			want: &query.ProxyRequest{
+				Request: query.Request{
+					Compiler: lang.FluxCompiler{
+						Now:   time.Unix(1, 1),
+						Query: `howdy`,
+					},
+					ReadRateLimit: 1 << 10,
+				},
+				Dialect: &csv.Dialect{
+					ResultEncoderConfig: csv.ResultEncoderConfig{
+						NoHeader:  false,
+						Delimiter: ',',
+					},
+				},
+			},
+		},
 		{
 			name: "valid spec",
 			fields: fields{
@@ -371,7 +435,10 @@ func TestQueryRequest_proxyRequest(t *testing.T) {
 				Query:   tt.fields.Query,
 				Type:    tt.fields.Type,
 				Dialect: tt.fields.Dialect,
+				Limits:  tt.fields.Limits,
 				Org:     tt.fields.org,
+
+				ReadRateLimit: tt.fields.Rate,
 			}
 			got, err := r.proxyRequest(tt.now)
 			if (err != nil) != tt.wantErr {
@@ -0,0 +1,445 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/pkg/httpc"
+	"go.uber.org/zap"
+)
+
+// RoleHandler represents an HTTP API handler for roles
+type RoleHandler struct {
+	*httprouter.Router
+	influxdb.HTTPErrorHandler
+	log *zap.Logger
+
+	RoleService influxdb.RoleService
+}
+
+const (
+	prefixRoles        = "/api/v2/roles"
+	rolesIDPath        = "/api/v2/roles/:id"
+	rolesIDUsersPath   = "/api/v2/roles/:id/users"
+	rolesIDUsersIDPath = "/api/v2/roles/:id/users/:userID"
+)
+
+// NewRoleHandler returns a new instance of RoleHandler
+func NewRoleHandler(log *zap.Logger, s influxdb.RoleService, he influxdb.HTTPErrorHandler) *RoleHandler {
+	h := &RoleHandler{
+		Router:           NewRouter(he),
+		HTTPErrorHandler: he,
+		log:              log,
+		RoleService:      s,
+	}
+
+	h.HandlerFunc("POST", prefixRoles, h.handlePostRole)
+	h.HandlerFunc("GET", prefixRoles, h.handleGetRoles)
+
+	h.HandlerFunc("GET", rolesIDPath, h.handleGetRole)
+	h.HandlerFunc("PATCH", rolesIDPath, h.handlePatchRole)
+	h.HandlerFunc("DELETE", rolesIDPath, h.handleDeleteRole)
+
+	h.HandlerFunc("GET", rolesIDUsersPath, h.handleGetRoleUsers)
+	h.HandlerFunc("POST", rolesIDUsersPath, h.handlePostRoleUser)
+	h.HandlerFunc("DELETE", rolesIDUsersIDPath, h.handleDeleteRoleUser)
+
+	return h
+}
+
+type roleResponse struct {
+	Links map[string]string `json:"links"`
+	influxdb.Role
+}
+
+func newRoleResponse(r *influxdb.Role) *roleResponse {
+	return &roleResponse{
+		Links: map[string]string{
+			"self":  fmt.Sprintf("/api/v2/roles/%s", r.ID),
+			"users": fmt.Sprintf("/api/v2/roles/%s/users", r.ID),
+			"org":   fmt.Sprintf("/api/v2/orgs/%s", r.OrgID),
+		},
+		Role: *r,
+	}
+}
+
+type rolesResponse struct {
+	Links map[string]string `json:"links"`
+	Roles []*roleResponse   `json:"roles"`
+}
+
+func newRolesResponse(rs []*influxdb.Role) *rolesResponse {
+	res := &rolesResponse{
+		Links: map[string]string{
+			"self": prefixRoles,
+		},
+		Roles: make([]*roleResponse, 0, len(rs)),
+	}
+	for _, r := range rs {
+		res.Roles = append(res.Roles, newRoleResponse(r))
+	}
+	return res
+}
+
+type roleUsersResponse struct {
+	Links   map[string]string `json:"links"`
+	UserIDs []influxdb.ID     `json:"users"`
+}
+
+type postRoleUserRequest struct {
+	UserID influxdb.ID `json:"userID"`
+}
+
+// handlePostRole is the HTTP handler for the POST /api/v2/roles route.
+func (h *RoleHandler) handlePostRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	role := &influxdb.Role{}
+	if err := json.NewDecoder(r.Body).Decode(role); err != nil {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "unable to decode role request",
+			Err:  err,
+		}, w)
+		return
+	}
+	if err := role.Valid(); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err := h.RoleService.CreateRole(ctx, role); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Role created", zap.String("role", fmt.Sprint(role)))
+	if err := encodeResponse(ctx, w, http.StatusCreated, newRoleResponse(role)); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+// handleGetRoles is the HTTP handler for the GET /api/v2/roles route.
+func (h *RoleHandler) handleGetRoles(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	filter, err := decodeRoleFilter(r.URL.Query())
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	rs, _, err := h.RoleService.FindRoles(ctx, filter)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Roles retrieved", zap.String("roles", fmt.Sprint(rs)))
+	if err := encodeResponse(ctx, w, http.StatusOK, newRolesResponse(rs)); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+func decodeRoleFilter(qp url.Values) (influxdb.RoleFilter, error) {
+	var filter influxdb.RoleFilter
+	for _, p := range []struct {
+		name string
+		id   **influxdb.ID
+	}{
+		{"id", &filter.ID},
+		{"orgID", &filter.OrgID},
+		{"userID", &filter.UserID},
+	} {
+		if v := qp.Get(p.name); v != "" {
+			id, err := influxdb.IDFromString(v)
+			if err != nil {
+				return filter, &influxdb.Error{
+					Code: influxdb.EInvalid,
+					Msg:  fmt.Sprintf("invalid %s", p.name),
+					Err:  err,
+				}
+			}
+			*p.id = id
+		}
+	}
+	if name := qp.Get("name"); name != "" {
+		filter.Name = &name
+	}
+	return filter, nil
+}
+
+// handleGetRole is the HTTP handler for the GET /api/v2/roles/:id route.
+func (h *RoleHandler) handleGetRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := decodeIDFromCtx(ctx, "id")
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	role, err := h.RoleService.FindRoleByID(ctx, id)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Role retrieved", zap.String("role", fmt.Sprint(role)))
+	if err := encodeResponse(ctx, w, http.StatusOK, newRoleResponse(role)); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+// handlePatchRole is the HTTP handler for the PATCH /api/v2/roles/:id route.
+func (h *RoleHandler) handlePatchRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := decodeIDFromCtx(ctx, "id")
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	var upd influxdb.RoleUpdate
+	if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "unable to decode role update",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	role, err := h.RoleService.UpdateRole(ctx, id, upd)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Role updated", zap.String("role", fmt.Sprint(role)))
+	if err := encodeResponse(ctx, w, http.StatusOK, newRoleResponse(role)); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+// handleDeleteRole is the HTTP handler for the DELETE /api/v2/roles/:id route.
+func (h *RoleHandler) handleDeleteRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := decodeIDFromCtx(ctx, "id")
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err := h.RoleService.DeleteRole(ctx, id); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Role deleted", zap.String("roleID", fmt.Sprint(id)))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetRoleUsers is the HTTP handler for the GET /api/v2/roles/:id/users route.
+func (h *RoleHandler) handleGetRoleUsers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := decodeIDFromCtx(ctx, "id")
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	ids, err := h.RoleService.FindRoleUsers(ctx, id)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	res := &roleUsersResponse{
+		Links: map[string]string{
+			"self": fmt.Sprintf("/api/v2/roles/%s/users", id),
+			"role": fmt.Sprintf("/api/v2/roles/%s", id),
+		},
+		UserIDs: ids,
+	}
+	if err := encodeResponse(ctx, w, http.StatusOK, res); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+// handlePostRoleUser is the HTTP handler for the POST /api/v2/roles/:id/users route.
+func (h *RoleHandler) handlePostRoleUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := decodeIDFromCtx(ctx, "id")
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	var req postRoleUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "unable to decode role user request",
+			Err:  err,
+		}, w)
+		return
+	}
+	if !req.UserID.Valid() {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "userID is required",
+		}, w)
+		return
+	}
+
+	if err := h.RoleService.AddRoleUser(ctx, id, req.UserID); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Role assigned", zap.String("roleID", fmt.Sprint(id)), zap.String("userID", fmt.Sprint(req.UserID)))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeleteRoleUser is the HTTP handler for the DELETE /api/v2/roles/:id/users/:userID route.
+func (h *RoleHandler) handleDeleteRoleUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := decodeIDFromCtx(ctx, "id")
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	userID, err := decodeIDFromCtx(ctx, "userID")
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err := h.RoleService.RemoveRoleUser(ctx, id, userID); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Role unassigned", zap.String("roleID", fmt.Sprint(id)), zap.String("userID", fmt.Sprint(userID)))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func roleIDPath(id influxdb.ID, elem ...string) string {
+	return path.Join(append([]string{prefixRoles, id.String()}, elem...)...)
+}
+
+// RoleService connects to Influx via HTTP using tokens to manage roles
+type RoleService struct {
+	Client *httpc.Client
+}
+
+var _ influxdb.RoleService = (*RoleService)(nil)
+
+// FindRoleByID returns a single role by ID.
+func (s *RoleService) FindRoleByID(ctx context.Context, id influxdb.ID) (*influxdb.Role, error) {
+	var res roleResponse
+	err := s.Client.
+		Get(roleIDPath(id)).
+		DecodeJSON(&res).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Role, nil
+}
+
+// FindRoles returns the roles that match filter and the total count of matching roles.
+func (s *RoleService) FindRoles(ctx context.Context, filter influxdb.RoleFilter, opt ...influxdb.FindOptions) ([]*influxdb.Role, int, error) {
+	params := findOptionParams(opt...)
+	if filter.ID != nil {
+		params = append(params, [2]string{"id", filter.ID.String()})
+	}
+	if filter.OrgID != nil {
+		params = append(params, [2]string{"orgID", filter.OrgID.String()})
+	}
+	if filter.UserID != nil {
+		params = append(params, [2]string{"userID", filter.UserID.String()})
+	}
+	if filter.Name != nil {
+		params = append(params, [2]string{"name", *filter.Name})
+	}
+
+	var res rolesResponse
+	err := s.Client.
+		Get(prefixRoles).
+		QueryParams(params...).
+		DecodeJSON(&res).
+		Do(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rs := make([]*influxdb.Role, 0, len(res.Roles))
+	for _, r := range res.Roles {
+		rs = append(rs, &r.Role)
+	}
+	return rs, len(rs), nil
+}
+
+// CreateRole creates a new role and sets r.ID with the new identifier.
+func (s *RoleService) CreateRole(ctx context.Context, r *influxdb.Role) error {
+	var res roleResponse
+	err := s.Client.
+		PostJSON(r, prefixRoles).
+		DecodeJSON(&res).
+		Do(ctx)
+	if err != nil {
+		return err
+	}
+	*r = res.Role
+	return nil
+}
+
+// UpdateRole updates a single role with a changeset.
+func (s *RoleService) UpdateRole(ctx context.Context, id influxdb.ID, upd influxdb.RoleUpdate) (*influxdb.Role, error) {
+	var res roleResponse
+	err := s.Client.
+		PatchJSON(upd, roleIDPath(id)).
+		DecodeJSON(&res).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &res.Role, nil
+}
+
+// DeleteRole removes a role by ID.
+func (s *RoleService) DeleteRole(ctx context.Context, id influxdb.ID) error {
+	return s.Client.
+		Delete(roleIDPath(id)).
+		Do(ctx)
+}
+
+// FindRoleUsers returns the IDs of the users the role is assigned to.
+func (s *RoleService) FindRoleUsers(ctx context.Context, roleID influxdb.ID) ([]influxdb.ID, error) {
+	var res roleUsersResponse
+	err := s.Client.
+		Get(roleIDPath(roleID, "users")).
+		DecodeJSON(&res).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return res.UserIDs, nil
+}
+
+// AddRoleUser assigns the role to the user.
+func (s *RoleService) AddRoleUser(ctx context.Context, roleID, userID influxdb.ID) error {
+	return s.Client.
+		PostJSON(postRoleUserRequest{UserID: userID}, roleIDPath(roleID, "users")).
+		Do(ctx)
+}
+
+// RemoveRoleUser unassigns the role from the user.
+func (s *RoleService) RemoveRoleUser(ctx context.Context, roleID, userID influxdb.ID) error {
+	return s.Client.
+		Delete(roleIDPath(roleID, "users", userID.String())).
+		Do(ctx)
+}
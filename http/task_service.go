@@ -5,18 +5,24 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"path"
 	"strconv"
 	"time"
 
+	"github.com/influxdata/flux/csv"
+	"github.com/influxdata/flux/iocounter"
+	"github.com/influxdata/flux/lang"
 	"github.com/influxdata/httprouter"
 	"github.com/influxdata/influxdb"
 	pcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/jsonweb"
 	"github.com/influxdata/influxdb/kit/tracing"
 	"github.com/influxdata/influxdb/kv"
 	"github.com/influxdata/influxdb/pkg/httpc"
+	"github.com/influxdata/influxdb/query"
 	"github.com/influxdata/influxdb/task/backend"
 	"go.uber.org/zap"
 )
@@ -34,6 +40,8 @@ type TaskBackend struct {
 	LabelService               influxdb.LabelService
 	UserService                influxdb.UserService
 	BucketService              influxdb.BucketService
+	QueryService               query.ProxyQueryService
+	DefinitionHistoryService   influxdb.DefinitionHistoryService
 }
 
 // NewTaskBackend returns a new instance of TaskBackend.
@@ -48,6 +56,8 @@ func NewTaskBackend(log *zap.Logger, b *APIBackend) *TaskBackend {
 		LabelService:               b.LabelService,
 		UserService:                b.UserService,
 		BucketService:              b.BucketService,
+		QueryService:               b.FluxService,
+		DefinitionHistoryService:   b.DefinitionHistoryService,
 	}
 }
 
@@ -64,12 +74,16 @@ type TaskHandler struct {
 	LabelService               influxdb.LabelService
 	UserService                influxdb.UserService
 	BucketService              influxdb.BucketService
+	QueryService               query.ProxyQueryService
+	DefinitionHistoryService   influxdb.DefinitionHistoryService
 }
 
 const (
 	prefixTasks            = "/api/v2/tasks"
 	tasksIDPath            = "/api/v2/tasks/:id"
 	tasksIDLogsPath        = "/api/v2/tasks/:id/logs"
+	tasksIDPreviewPath     = "/api/v2/tasks/:id/preview"
+	tasksIDGraphPath       = "/api/v2/tasks/:id/graph"
 	tasksIDMembersPath     = "/api/v2/tasks/:id/members"
 	tasksIDMembersIDPath   = "/api/v2/tasks/:id/members/:userID"
 	tasksIDOwnersPath      = "/api/v2/tasks/:id/owners"
@@ -80,6 +94,9 @@ const (
 	tasksIDRunsIDRetryPath = "/api/v2/tasks/:id/runs/:rid/retry"
 	tasksIDLabelsPath      = "/api/v2/tasks/:id/labels"
 	tasksIDLabelsIDPath    = "/api/v2/tasks/:id/labels/:lid"
+	tasksIDVersionsPath    = "/api/v2/tasks/:id/versions"
+	tasksIDVersionsIDPath  = "/api/v2/tasks/:id/versions/:version"
+	tasksIDRollbackPath    = "/api/v2/tasks/:id/versions/:version/rollback"
 )
 
 // NewTaskHandler returns a new instance of TaskHandler.
@@ -96,6 +113,8 @@ func NewTaskHandler(log *zap.Logger, b *TaskBackend) *TaskHandler {
 		LabelService:               b.LabelService,
 		UserService:                b.UserService,
 		BucketService:              b.BucketService,
+		QueryService:               b.QueryService,
+		DefinitionHistoryService:   b.DefinitionHistoryService,
 	}
 
 	h.HandlerFunc("GET", prefixTasks, h.handleGetTasks)
@@ -106,6 +125,7 @@ func NewTaskHandler(log *zap.Logger, b *TaskBackend) *TaskHandler {
 	h.HandlerFunc("DELETE", tasksIDPath, h.handleDeleteTask)
 
 	h.HandlerFunc("GET", tasksIDLogsPath, h.handleGetLogs)
+	h.HandlerFunc("GET", tasksIDGraphPath, h.handleGetTaskGraph)
 	h.HandlerFunc("GET", tasksIDRunsIDLogsPath, h.handleGetLogs)
 
 	memberBackend := MemberBackend{
@@ -136,6 +156,7 @@ func NewTaskHandler(log *zap.Logger, b *TaskBackend) *TaskHandler {
 	h.HandlerFunc("POST", tasksIDRunsPath, h.handleForceRun)
 	h.HandlerFunc("GET", tasksIDRunsIDPath, h.handleGetRun)
 	h.HandlerFunc("POST", tasksIDRunsIDRetryPath, h.handleRetryRun)
+	h.HandlerFunc("POST", tasksIDPreviewPath, h.handlePreviewTask)
 	h.HandlerFunc("DELETE", tasksIDRunsIDPath, h.handleCancelRun)
 
 	labelBackend := &LabelBackend{
@@ -148,9 +169,38 @@ func NewTaskHandler(log *zap.Logger, b *TaskBackend) *TaskHandler {
 	h.HandlerFunc("POST", tasksIDLabelsPath, newPostLabelHandler(labelBackend))
 	h.HandlerFunc("DELETE", tasksIDLabelsIDPath, newDeleteLabelHandler(labelBackend))
 
+	historyBackend := &DefinitionHistoryBackend{
+		HTTPErrorHandler:         b.HTTPErrorHandler,
+		log:                      b.log.With(zap.String("handler", "definition_history")),
+		DefinitionHistoryService: b.DefinitionHistoryService,
+		ResourceType:             influxdb.TasksResourceType,
+		find: func(ctx context.Context, id influxdb.ID) error {
+			_, err := h.TaskService.FindTaskByID(ctx, id)
+			return err
+		},
+		rollback: h.rollbackTask,
+	}
+	h.HandlerFunc("GET", tasksIDVersionsPath, newGetDefinitionVersionsHandler(historyBackend, prefixTasks))
+	h.HandlerFunc("GET", tasksIDVersionsIDPath, newGetDefinitionVersionHandler(historyBackend, prefixTasks))
+	h.HandlerFunc("POST", tasksIDRollbackPath, newPostDefinitionRollbackHandler(historyBackend))
+
 	return h
 }
 
+// rollbackTask restores the Flux script of the task to that of the version.
+func (h *TaskHandler) rollbackTask(ctx context.Context, id influxdb.ID, v *influxdb.DefinitionVersion) (interface{}, error) {
+	task, err := h.TaskService.UpdateTask(ctx, id, influxdb.TaskUpdate{Flux: &v.Definition})
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := h.LabelService.FindResourceLabels(ctx, influxdb.LabelMappingFilter{ResourceID: task.ID})
+	if err != nil {
+		return nil, err
+	}
+	return newTaskResponse(*task, labels), nil
+}
+
 // Task is a package-specific Task format that preserves the expected format for the API,
 // where time values are represented as strings
 type Task struct {
@@ -171,6 +221,7 @@ type Task struct {
 	CreatedAt       string                 `json:"createdAt,omitempty"`
 	UpdatedAt       string                 `json:"updatedAt,omitempty"`
 	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+	DependsOn       []influxdb.ID          `json:"dependsOn,omitempty"`
 }
 
 type taskResponse struct {
@@ -216,6 +267,7 @@ func NewFrontEndTask(t influxdb.Task) Task {
 		CreatedAt:       createdAt,
 		UpdatedAt:       updatedAt,
 		Metadata:        t.Metadata,
+		DependsOn:       t.DependsOn,
 	}
 }
 
@@ -264,6 +316,7 @@ func newTaskResponse(t influxdb.Task, labels []*influxdb.Label) taskResponse {
 			"labels":  fmt.Sprintf("/api/v2/tasks/%s/labels", t.ID),
 			"runs":    fmt.Sprintf("/api/v2/tasks/%s/runs", t.ID),
 			"logs":    fmt.Sprintf("/api/v2/tasks/%s/logs", t.ID),
+			"graph":   fmt.Sprintf("/api/v2/tasks/%s/graph", t.ID),
 		},
 		Task:   NewFrontEndTask(t),
 		Labels: []influxdb.Label{},
@@ -496,6 +549,14 @@ func decodeGetTasksRequest(ctx context.Context, r *http.Request, orgs influxdb.O
 		req.filter.User = id
 	}
 
+	if dependsOn := qp.Get("dependsOn"); dependsOn != "" {
+		id, err := influxdb.IDFromString(dependsOn)
+		if err != nil {
+			return nil, err
+		}
+		req.filter.DependsOn = id
+	}
+
 	if limit := qp.Get("limit"); limit != "" {
 		lim, err := strconv.Atoi(limit)
 		if err != nil {
@@ -657,6 +718,38 @@ func (h *TaskHandler) handleGetTask(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *TaskHandler) handleGetTaskGraph(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	req, err := decodeGetTaskRequest(ctx, r)
+	if err != nil {
+		err = &influxdb.Error{
+			Err:  err,
+			Code: influxdb.EInvalid,
+			Msg:  "failed to decode request",
+		}
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	graph, err := backend.FindTaskGraph(ctx, h.TaskService, req.TaskID)
+	if err != nil {
+		err := &influxdb.Error{
+			Err: err,
+			Msg: "failed to find task graph",
+		}
+		if err.Err == influxdb.ErrTaskNotFound {
+			err.Code = influxdb.ENotFound
+		}
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err := encodeResponse(ctx, w, http.StatusOK, graph); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
 type getTaskRequest struct {
 	TaskID influxdb.ID
 }
@@ -1109,6 +1202,137 @@ func decodeForceRunRequest(ctx context.Context, r *http.Request) (forceRunReques
 	}, nil
 }
 
+func (h *TaskHandler) handlePreviewTask(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := decodePreviewTaskRequest(ctx, r)
+	if err != nil {
+		err = &influxdb.Error{
+			Err:  err,
+			Code: influxdb.EInvalid,
+			Msg:  "failed to decode request",
+		}
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	task, err := h.TaskService.FindTaskByID(ctx, req.TaskID)
+	if err != nil {
+		err := &influxdb.Error{
+			Err: err,
+			Msg: "failed to find task",
+		}
+		if err.Err == influxdb.ErrTaskNotFound {
+			err.Code = influxdb.ENotFound
+		}
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	a, err := pcontext.GetAuthorizer(ctx)
+	if err != nil {
+		err = &influxdb.Error{
+			Err:  err,
+			Code: influxdb.EUnauthorized,
+			Msg:  "failed to get authorizer",
+		}
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	// The preview runs with the permissions of the caller rather than those of the task.
+	var auth *influxdb.Authorization
+	switch a := a.(type) {
+	case *influxdb.Authorization:
+		auth = a
+	case *influxdb.Session:
+		auth = a.EphemeralAuth(task.OrganizationID)
+	case *jsonweb.Token:
+		auth = a.EphemeralAuth(task.OrganizationID)
+	default:
+		h.HandleHTTPError(ctx, influxdb.ErrAuthorizerNotSupported, w)
+		return
+	}
+
+	pkg, err := backend.PreviewAST(task.Flux, req.Start, req.Stop)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	pr := &query.ProxyRequest{
+		Request: query.Request{
+			Authorization:  auth,
+			OrganizationID: task.OrganizationID,
+			Compiler: lang.ASTCompiler{
+				AST: pkg,
+				Now: req.Stop,
+			},
+		},
+		Dialect: csv.DefaultDialect(),
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	cw := iocounter.Writer{Writer: w}
+	if _, err := h.QueryService.Query(pcontext.SetAuthorizer(ctx, auth), &cw, pr); err != nil {
+		if cw.Count() == 0 {
+			// Only record the error headers if nothing has been written to w.
+			h.HandleHTTPError(ctx, err, w)
+			return
+		}
+		h.log.Info("Error writing task preview response", zap.Error(err))
+	}
+}
+
+type previewTaskRequest struct {
+	TaskID influxdb.ID
+	Start  time.Time
+	Stop   time.Time
+}
+
+func decodePreviewTaskRequest(ctx context.Context, r *http.Request) (*previewTaskRequest, error) {
+	params := httprouter.ParamsFromContext(ctx)
+	var req previewTaskRequest
+	if err := req.TaskID.DecodeFromString(params.ByName("id")); err != nil {
+		return nil, err
+	}
+
+	var body struct {
+		Start string `json:"start"`
+		Stop  string `json:"stop"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	if body.Start == "" {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "you must provide a start time",
+		}
+	}
+	start, err := time.Parse(time.RFC3339, body.Start)
+	if err != nil {
+		return nil, err
+	}
+	req.Start = start
+
+	req.Stop = time.Now()
+	if body.Stop != "" {
+		if req.Stop, err = time.Parse(time.RFC3339, body.Stop); err != nil {
+			return nil, err
+		}
+	}
+
+	if !req.Stop.After(req.Start) {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "stop must be later than start",
+		}
+	}
+	return &req, nil
+}
+
 func (h *TaskHandler) handleGetRun(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -1644,6 +1868,63 @@ func (t TaskService) RetryRun(ctx context.Context, taskID, runID influxdb.ID) (*
 	return convertRun(rs.httpRun), nil
 }
 
+// PreviewTask executes the task with its time range set to [start, stop) and
+// writes the tables it would have written to w, as annotated CSV.
+func (t TaskService) PreviewTask(ctx context.Context, taskID influxdb.ID, start, stop time.Time, w io.Writer) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	b := struct {
+		Start string `json:"start"`
+		Stop  string `json:"stop"`
+	}{
+		Start: start.UTC().Format(time.RFC3339Nano),
+		Stop:  stop.UTC().Format(time.RFC3339Nano),
+	}
+
+	return t.Client.
+		PostJSON(b, path.Join(taskIDPath(taskID), "preview")).
+		Accept("text/csv").
+		Decode(func(resp *http.Response) error {
+			_, err := io.Copy(w, resp.Body)
+			return err
+		}).
+		Do(ctx)
+}
+
+// FindTaskVersions returns the versions of the Flux script of a task and their
+// number.
+func (t TaskService) FindTaskVersions(ctx context.Context, id influxdb.ID, opts influxdb.FindOptions) ([]*influxdb.DefinitionVersion, int, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	return t.definitionHistory().findVersions(ctx, id, opts)
+}
+
+// FindTaskVersion returns a single version of the Flux script of a task.
+func (t TaskService) FindTaskVersion(ctx context.Context, id influxdb.ID, version int) (*influxdb.DefinitionVersion, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	return t.definitionHistory().findVersion(ctx, id, version)
+}
+
+// RollbackTask restores the Flux script of a task to that of the version.
+func (t TaskService) RollbackTask(ctx context.Context, id influxdb.ID, version int) (*Task, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var tr taskResponse
+	if err := t.definitionHistory().rollback(ctx, id, version, &tr); err != nil {
+		return nil, err
+	}
+	return &tr.Task, nil
+}
+
+func (t TaskService) definitionHistory() definitionHistoryClient {
+	return definitionHistoryClient{client: t.Client, prefix: prefixTasks}
+}
+
 // ForceRun starts a run manually right now.
 func (t TaskService) ForceRun(ctx context.Context, taskID influxdb.ID, scheduledFor int64) (*influxdb.Run, error) {
 	span, _ := tracing.StartSpanFromContext(ctx)
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -13,13 +14,18 @@ import (
 	"testing"
 	"time"
 
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/ast"
+	"github.com/influxdata/flux/lang"
 	"github.com/influxdata/httprouter"
 	"github.com/influxdata/influxdb"
 	pcontext "github.com/influxdata/influxdb/context"
 
 	kithttp "github.com/influxdata/influxdb/kit/transport/http"
 	"github.com/influxdata/influxdb/mock"
+	"github.com/influxdata/influxdb/query"
 	_ "github.com/influxdata/influxdb/query/builtin"
+	querymock "github.com/influxdata/influxdb/query/mock"
 	"github.com/influxdata/influxdb/task/backend"
 	influxdbtesting "github.com/influxdata/influxdb/testing"
 	"go.uber.org/zap"
@@ -138,6 +144,7 @@ func TestTaskHandler_handleGetTasks(t *testing.T) {
         "members": "/api/v2/tasks/0000000000000001/members",
         "labels": "/api/v2/tasks/0000000000000001/labels",
         "runs": "/api/v2/tasks/0000000000000001/runs",
+        "graph": "/api/v2/tasks/0000000000000001/graph",
         "logs": "/api/v2/tasks/0000000000000001/logs"
       },
       "id": "0000000000000001",
@@ -165,6 +172,7 @@ func TestTaskHandler_handleGetTasks(t *testing.T) {
         "members": "/api/v2/tasks/0000000000000002/members",
         "labels": "/api/v2/tasks/0000000000000002/labels",
         "runs": "/api/v2/tasks/0000000000000002/runs",
+        "graph": "/api/v2/tasks/0000000000000002/graph",
         "logs": "/api/v2/tasks/0000000000000002/logs"
       },
       "id": "0000000000000002",
@@ -239,6 +247,7 @@ func TestTaskHandler_handleGetTasks(t *testing.T) {
         "members": "/api/v2/tasks/0000000000000002/members",
         "labels": "/api/v2/tasks/0000000000000002/labels",
         "runs": "/api/v2/tasks/0000000000000002/runs",
+        "graph": "/api/v2/tasks/0000000000000002/graph",
         "logs": "/api/v2/tasks/0000000000000002/logs"
       },
       "id": "0000000000000002",
@@ -312,6 +321,7 @@ func TestTaskHandler_handleGetTasks(t *testing.T) {
         "members": "/api/v2/tasks/0000000000000002/members",
         "labels": "/api/v2/tasks/0000000000000002/labels",
         "runs": "/api/v2/tasks/0000000000000002/runs",
+        "graph": "/api/v2/tasks/0000000000000002/graph",
         "logs": "/api/v2/tasks/0000000000000002/logs"
       },
       "id": "0000000000000002",
@@ -474,6 +484,7 @@ func TestTaskHandler_handlePostTasks(t *testing.T) {
     "members": "/api/v2/tasks/0000000000000001/members",
     "labels": "/api/v2/tasks/0000000000000001/labels",
     "runs": "/api/v2/tasks/0000000000000001/runs",
+    "graph": "/api/v2/tasks/0000000000000001/graph",
     "logs": "/api/v2/tasks/0000000000000001/logs"
   },
   "id": "0000000000000001",
@@ -701,6 +712,79 @@ func TestTaskHandler_handleGetRun(t *testing.T) {
 	}
 }
 
+func TestTaskHandler_handlePreviewTask(t *testing.T) {
+	task := &influxdb.Task{
+		ID:             1,
+		OrganizationID: 2,
+		Flux:           `option task = {name: "t", every: 1h} from(bucket: "b") |> range(start: v.timeRangeStart) |> to(bucket: "out")`,
+	}
+
+	for _, tt := range []struct {
+		name       string
+		body       string
+		statusCode int
+	}{
+		{name: "preview", body: `{"start": "2020-01-01T00:00:00Z", "stop": "2020-01-01T01:00:00Z"}`, statusCode: http.StatusOK},
+		{name: "missing start", body: `{"stop": "2020-01-01T01:00:00Z"}`, statusCode: http.StatusBadRequest},
+		{name: "empty range", body: `{"start": "2020-01-01T01:00:00Z", "stop": "2020-01-01T01:00:00Z"}`, statusCode: http.StatusBadRequest},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *query.ProxyRequest
+			taskBackend := NewMockTaskBackend(t)
+			taskBackend.HTTPErrorHandler = kithttp.ErrorHandler(0)
+			taskBackend.TaskService = &mock.TaskService{
+				FindTaskByIDFn: func(ctx context.Context, id influxdb.ID) (*influxdb.Task, error) {
+					return task, nil
+				},
+			}
+			taskBackend.QueryService = &querymock.ProxyQueryService{
+				QueryF: func(ctx context.Context, w io.Writer, req *query.ProxyRequest) (flux.Statistics, error) {
+					got = req
+					_, err := io.WriteString(w, "#datatype,string\r\n")
+					return flux.Statistics{}, err
+				},
+			}
+			h := NewTaskHandler(zaptest.NewLogger(t), taskBackend)
+
+			r := httptest.NewRequest("POST", "http://any.url", strings.NewReader(tt.body))
+			r = r.WithContext(context.WithValue(
+				context.Background(),
+				httprouter.ParamsKey,
+				httprouter.Params{{Key: "id", Value: task.ID.String()}}))
+			auth := &influxdb.Authorization{ID: 3, OrgID: 2}
+			r = r.WithContext(pcontext.SetAuthorizer(r.Context(), auth))
+			w := httptest.NewRecorder()
+			h.handlePreviewTask(w, r)
+
+			res := w.Result()
+			if res.StatusCode != tt.statusCode {
+				body, _ := ioutil.ReadAll(res.Body)
+				t.Fatalf("unexpected status: got %d, want %d: %s", res.StatusCode, tt.statusCode, body)
+			}
+			if tt.statusCode != http.StatusOK {
+				return
+			}
+
+			if got.Request.Authorization != auth || got.Request.OrganizationID != task.OrganizationID {
+				t.Fatalf("unexpected request: %+v", got.Request)
+			}
+			c, ok := got.Request.Compiler.(lang.ASTCompiler)
+			if !ok {
+				t.Fatalf("unexpected compiler type %T", got.Request.Compiler)
+			}
+			if want := time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC); !c.Now.Equal(want) {
+				t.Fatalf("unexpected now: got %v, want %v", c.Now, want)
+			}
+			if script := ast.Format(c.AST); strings.Contains(script, "to(") || !strings.Contains(script, "timeRangeStart: 2020-01-01T00:00:00Z") {
+				t.Fatalf("unexpected script:\n%s", script)
+			}
+			if ct := res.Header.Get("Content-Type"); ct != "text/csv; charset=utf-8" {
+				t.Fatalf("unexpected content type %q", ct)
+			}
+		})
+	}
+}
+
 func TestTaskHandler_handleGetRuns(t *testing.T) {
 	type fields struct {
 		taskService influxdb.TaskService
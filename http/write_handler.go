@@ -1,6 +1,7 @@
 package http
 
 import (
+	"bytes"
 	"compress/gzip"
 	"context"
 	"errors"
@@ -8,6 +9,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"sort"
 
 	"github.com/influxdata/httprouter"
 	"github.com/influxdata/influxdb"
@@ -71,6 +73,7 @@ type WriteHandler struct {
 	parserMaxBytes    int
 	parserMaxLines    int
 	parserMaxValues   int
+	parserDecimals    bool
 }
 
 // WriteHandlerOption is a functional option for a *WriteHandler
@@ -108,6 +111,15 @@ func WithParserMaxValues(n int) WriteHandlerOption {
 	}
 }
 
+// WithParserDecimals specifies whether decimal field values, written with a d suffix, are accepted
+// by write requests. They are stored as strings holding their exact representation, and queries
+// return them as string values, as Flux has no decimal type.
+func WithParserDecimals(enabled bool) WriteHandlerOption {
+	return func(w *WriteHandler) {
+		w.parserDecimals = enabled
+	}
+}
+
 // Prefix provides the route prefix.
 func (*WriteHandler) Prefix() string {
 	return prefixWrite
@@ -116,7 +128,7 @@ func (*WriteHandler) Prefix() string {
 const (
 	prefixWrite          = "/api/v2/write"
 	errInvalidGzipHeader = "gzipped HTTP body contains an invalid header"
-	errInvalidPrecision  = "invalid precision; valid precision units are ns, us, ms, and s"
+	errInvalidPrecision  = "invalid precision; valid precision units are ns, us, ms, s, and auto"
 )
 
 // NewWriteHandler creates a new handler at /api/v2/write to receive line protocol.
@@ -146,6 +158,9 @@ func NewWriteHandler(log *zap.Logger, b *WriteBackend, opts ...WriteHandlerOptio
 	if h.parserMaxValues > 0 {
 		h.parserOptions = append(h.parserOptions, models.WithParserMaxValues(h.parserMaxValues))
 	}
+	if h.parserDecimals {
+		h.parserOptions = append(h.parserOptions, models.WithParserDecimals())
+	}
 
 	h.HandlerFunc("POST", prefixWrite, h.handleWrite)
 	return h
@@ -161,10 +176,11 @@ func (h *WriteHandler) handleWrite(w http.ResponseWriter, r *http.Request) {
 	// TODO(desa): I really don't like how we're recording the usage metrics here
 	// Ideally this will be moved when we solve https://github.com/influxdata/influxdb/issues/13403
 	var (
-		orgID        influxdb.ID
-		requestBytes int
-		sw           = kithttp.NewStatusResponseWriter(w)
-		handleError  = func(err error, code, message string) {
+		orgID         influxdb.ID
+		requestBytes  int
+		valuesDropped int
+		sw            = kithttp.NewStatusResponseWriter(w)
+		handleError   = func(err error, code, message string) {
 			h.HandleHTTPError(ctx, &influxdb.Error{
 				Code: code,
 				Op:   "http/handleWrite",
@@ -180,6 +196,7 @@ func (h *WriteHandler) handleWrite(w http.ResponseWriter, r *http.Request) {
 			Endpoint:      r.URL.Path, // This should be sufficient for the time being as it should only be single endpoint.
 			RequestBytes:  requestBytes,
 			ResponseBytes: sw.ResponseBytes(),
+			ValuesDropped: valuesDropped,
 			Status:        sw.Code(),
 		})
 	}()
@@ -249,6 +266,16 @@ func (h *WriteHandler) handleWrite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var trace *storage.WriteTrace
+	if r.Header.Get(DebugWriteHeaderKey) == "true" {
+		if err := allowDebugWrite(a, org.ID); err != nil {
+			h.HandleHTTPError(ctx, err, w)
+			return
+		}
+		trace = &storage.WriteTrace{}
+		ctx = storage.WithWriteTrace(ctx, trace)
+	}
+
 	data, err := readWriteRequest(ctx, r.Body, r.Header.Get("Content-Encoding"), h.maxBatchSizeBytes)
 	if err != nil {
 		log.Error("Error reading body", zap.Error(err))
@@ -274,18 +301,21 @@ func (h *WriteHandler) handleWrite(w http.ResponseWriter, r *http.Request) {
 	encoded := tsdb.EncodeName(org.ID, bucket.ID)
 	mm := models.EscapeMeasurement(encoded[:])
 
-	var options []models.ParserOption
-	if len(h.parserOptions) > 0 {
-		options = make([]models.ParserOption, 0, len(h.parserOptions)+1)
-		options = append(options, h.parserOptions...)
-	}
+	var stats models.ParserStats
+	options := make([]models.ParserOption, 0, len(h.parserOptions)+3)
+	options = append(options, h.parserOptions...)
+	options = append(options, models.WithParserStats(&stats), nonFiniteFloatPolicyOption(bucket.NonFiniteFloatPolicy))
 
 	if req.Precision != nil {
 		options = append(options, req.Precision)
 	}
+	if req.StrictPrecision {
+		options = append(options, models.WithParserStrictPrecision())
+	}
 
 	points, err := models.ParsePointsWithOptions(data, mm, options...)
-	span.LogKV("values_total", len(points))
+	valuesDropped = stats.NonFiniteFloatsDropped
+	span.LogKV("values_total", len(points), "values_dropped", valuesDropped)
 	span.Finish()
 	if err != nil {
 		log.Error("Error parsing points", zap.Error(err))
@@ -301,15 +331,124 @@ func (h *WriteHandler) handleWrite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if valuesDropped > 0 {
+		log.Debug("Dropped non-finite float values", zap.Int("values_dropped", valuesDropped))
+	}
+	if len(stats.PrecisionsDetected) > 1 {
+		log.Debug("Detected mixed timestamp precisions", zap.Any("precisions", stats.PrecisionsDetected))
+	}
+
+	if !bucket.TagNormalization.IsZero() {
+		normalizeTags(bucket.TagNormalization, points)
+	}
+
 	if err := h.PointsWriter.WritePoints(ctx, points); err != nil {
 		log.Error("Error writing points", zap.Error(err))
+		if influxdb.ErrorCode(err) == influxdb.EForbidden {
+			// The write policy of the token denies a measurement.
+			h.HandleHTTPError(ctx, err, w)
+			return
+		}
+		var pwerr tsdb.PartialWriteError
+		if errors.As(err, &pwerr) {
+			// The other points were written.
+			handleError(err, influxdb.EUnprocessableEntity, "")
+			return
+		}
 		handleError(err, influxdb.EInternal, "unexpected error writing points to database")
 		return
 	}
 
+	if trace != nil {
+		res := debugWriteResponse{OrgID: org.ID, BucketID: bucket.ID, WriteTrace: trace}
+		if err := encodeResponse(ctx, w, http.StatusOK, res); err != nil {
+			logEncodingError(log, r, err)
+		}
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// DebugWriteHeaderKey is the header of a write request asking for the trace
+// of the write by the storage engine, when set to "true".
+const DebugWriteHeaderKey = "X-Debug-Write"
+
+// debugWriteResponse is the response to a write traced with the
+// X-Debug-Write header.
+type debugWriteResponse struct {
+	OrgID    influxdb.ID `json:"orgID"`
+	BucketID influxdb.ID `json:"bucketID"`
+	*storage.WriteTrace
+}
+
+// allowDebugWrite returns an error unless the authorizer may trace writes to
+// the organization. Traces reveal the layout of the storage of the whole
+// organization, so that they are reserved to the authorizations allowed to
+// write the organization.
+func allowDebugWrite(a influxdb.Authorizer, orgID influxdb.ID) error {
+	perm, err := influxdb.NewPermissionAtID(orgID, influxdb.WriteAction, influxdb.OrgsResourceType, orgID)
+	if err != nil {
+		return err
+	}
+	if !a.Allowed(*perm) {
+		return &influxdb.Error{
+			Code: influxdb.EForbidden,
+			Op:   "http/handleWrite",
+			Msg:  "debugging writes requires write access to the organization",
+		}
+	}
+	return nil
+}
+
+// normalizeTags applies the tag normalization rules of the bucket to the tags of the points,
+// leaving the measurement and field keys untouched. When lowercasing keys makes two tags of a
+// point share the same key, the one first in the order of the original keys is kept.
+func normalizeTags(n *influxdb.TagNormalization, points []models.Point) {
+	for _, pt := range points {
+		tags := pt.Tags()
+		normalized := make(models.Tags, 0, len(tags))
+		changed := false
+		for _, t := range tags {
+			if bytes.Equal(t.Key, models.MeasurementTagKeyBytes) || bytes.Equal(t.Key, models.FieldKeyTagKeyBytes) {
+				normalized = append(normalized, t)
+				continue
+			}
+			k := n.NormalizeKey(string(t.Key))
+			v := n.NormalizeValue(k, string(t.Value))
+			if k != string(t.Key) || v != string(t.Value) {
+				changed = true
+			}
+			normalized = append(normalized, models.NewTag([]byte(k), []byte(v)))
+		}
+		if !changed {
+			continue
+		}
+
+		sort.Stable(normalized)
+		deduped := normalized[:0]
+		for _, t := range normalized {
+			if len(deduped) > 0 && bytes.Equal(t.Key, deduped[len(deduped)-1].Key) {
+				continue
+			}
+			deduped = append(deduped, t)
+		}
+		pt.SetTags(deduped)
+	}
+}
+
+// nonFiniteFloatPolicyOption returns the parser option implementing the bucket's non-finite float policy.
+func nonFiniteFloatPolicyOption(p influxdb.NonFiniteFloatPolicy) models.ParserOption {
+	switch p {
+	case influxdb.NonFiniteFloatPolicyDrop:
+		return models.WithParserNonFiniteFloatPolicy(models.NonFiniteFloatDrop)
+	case influxdb.NonFiniteFloatPolicyStoreInf:
+		return models.WithParserNonFiniteFloatPolicy(models.NonFiniteFloatStoreInf)
+	default:
+		return models.WithParserNonFiniteFloatPolicy(models.NonFiniteFloatReject)
+	}
+}
+
 func decodeWriteRequest(ctx context.Context, r *http.Request) (*postWriteRequest, error) {
 	qp := r.URL.Query()
 	p := qp.Get("precision")
@@ -317,7 +456,7 @@ func decodeWriteRequest(ctx context.Context, r *http.Request) (*postWriteRequest
 		p = "ns"
 	}
 
-	if !models.ValidPrecision(p) {
+	if !models.ValidPrecision(p) && p != models.PrecisionAuto {
 		return nil, &influxdb.Error{
 			Code: influxdb.EInvalid,
 			Op:   "http/decodeWriteRequest",
@@ -330,10 +469,20 @@ func decodeWriteRequest(ctx context.Context, r *http.Request) (*postWriteRequest
 		precision = models.WithParserPrecision(p)
 	}
 
+	strict := qp.Get("strictPrecision") == "true"
+	if strict && p != models.PrecisionAuto {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Op:   "http/decodeWriteRequest",
+			Msg:  "strictPrecision requires precision auto",
+		}
+	}
+
 	return &postWriteRequest{
-		Bucket:    qp.Get("bucket"),
-		Org:       qp.Get("org"),
-		Precision: precision,
+		Bucket:          qp.Get("bucket"),
+		Org:             qp.Get("org"),
+		Precision:       precision,
+		StrictPrecision: strict,
 	}, nil
 }
 
@@ -371,9 +520,10 @@ func readWriteRequest(ctx context.Context, rc io.ReadCloser, encoding string, ma
 }
 
 type postWriteRequest struct {
-	Org       string
-	Bucket    string
-	Precision models.ParserOption
+	Org             string
+	Bucket          string
+	Precision       models.ParserOption
+	StrictPrecision bool
 }
 
 // WriteService sends data over HTTP to influxdb via line protocol.
@@ -382,6 +532,10 @@ type WriteService struct {
 	Token              string
 	Precision          string
 	InsecureSkipVerify bool
+
+	// StrictPrecision rejects the writes mixing timestamp precisions, with
+	// the auto precision.
+	StrictPrecision bool
 }
 
 var _ influxdb.WriteService = (*WriteService)(nil)
@@ -392,7 +546,7 @@ func (s *WriteService) Write(ctx context.Context, orgID, bucketID influxdb.ID, r
 		precision = "ns"
 	}
 
-	if !models.ValidPrecision(precision) {
+	if !models.ValidPrecision(precision) && precision != models.PrecisionAuto {
 		return &influxdb.Error{
 			Code: influxdb.EInvalid,
 			Op:   "http/Write",
@@ -433,6 +587,9 @@ func (s *WriteService) Write(ctx context.Context, orgID, bucketID influxdb.ID, r
 	params.Set("org", string(org))
 	params.Set("bucket", string(bucket))
 	params.Set("precision", string(precision))
+	if s.StrictPrecision {
+		params.Set("strictPrecision", "true")
+	}
 	req.URL.RawQuery = params.Encode()
 
 	hc := NewClient(u.Scheme, s.InsecureSkipVerify)
@@ -12,11 +12,14 @@ import (
 	"testing"
 
 	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/authorizer"
 	"github.com/influxdata/influxdb/http/metric"
 	httpmock "github.com/influxdata/influxdb/http/mock"
 	kithttp "github.com/influxdata/influxdb/kit/transport/http"
 	"github.com/influxdata/influxdb/mock"
+	"github.com/influxdata/influxdb/models"
 	influxtesting "github.com/influxdata/influxdb/testing"
+	"github.com/influxdata/influxdb/tsdb"
 	"go.uber.org/zap/zaptest"
 )
 
@@ -100,6 +103,10 @@ func TestWriteHandler_handleWrite(t *testing.T) {
 		org    string
 		bucket string
 		body   string
+		debug  bool
+
+		precision       string
+		strictPrecision bool
 	}
 
 	tests := []struct {
@@ -142,6 +149,24 @@ func TestWriteHandler_handleWrite(t *testing.T) {
 				body: `{"code":"internal error","message":"unexpected error writing points to database: error"}`,
 			},
 		},
+		{
+			name: "partial write is an unprocessable entity",
+			request: request{
+				org:    "043e0780ee2b1000",
+				bucket: "04504b356e23b000",
+				body:   "m1,t1=v1 f1=1",
+				auth:   bucketWritePermission("043e0780ee2b1000", "04504b356e23b000"),
+			},
+			state: state{
+				org:      testOrg("043e0780ee2b1000"),
+				bucket:   testBucket("043e0780ee2b1000", "04504b356e23b000"),
+				writeErr: tsdb.PartialWriteError{Reason: "field type conflict", Dropped: 1},
+			},
+			wants: wants{
+				code: 422,
+				body: `{"code":"unprocessable entity","message":"partial write: field type conflict dropped=1"}`,
+			},
+		},
 		{
 			name: "empty request body returns 400 error",
 			request: request{
@@ -329,6 +354,213 @@ func TestWriteHandler_handleWrite(t *testing.T) {
 				body: `{"code":"request too large","message":"points: number of values exceeded"}`,
 			},
 		},
+		{
+			name: "non-finite float values rejected",
+			request: request{
+				org:    "043e0780ee2b1000",
+				bucket: "04504b356e23b000",
+				body:   "m1,t1=v1 f1=NaN\nm1,t1=v1 f1=1\n",
+				auth:   bucketWritePermission("043e0780ee2b1000", "04504b356e23b000"),
+			},
+			state: state{
+				org:    testOrg("043e0780ee2b1000"),
+				bucket: testBucket("043e0780ee2b1000", "04504b356e23b000"),
+			},
+			wants: wants{
+				code: 400,
+				body: `{"code":"invalid","message":"unable to parse 'm1,t1=v1 f1=NaN': non-finite float value: field \"f1\"=NaN"}`,
+			},
+		},
+		{
+			name: "non-finite float values dropped",
+			request: request{
+				org:    "043e0780ee2b1000",
+				bucket: "04504b356e23b000",
+				body:   "m1,t1=v1 f1=NaN,f2=1\nm1,t1=v1 f1=-Inf\n",
+				auth:   bucketWritePermission("043e0780ee2b1000", "04504b356e23b000"),
+			},
+			state: state{
+				org:    testOrg("043e0780ee2b1000"),
+				bucket: testBucketWithPolicy("043e0780ee2b1000", "04504b356e23b000", influxdb.NonFiniteFloatPolicyDrop),
+			},
+			wants: wants{
+				code: 204,
+			},
+		},
+		{
+			name: "infinite float values stored",
+			request: request{
+				org:    "043e0780ee2b1000",
+				bucket: "04504b356e23b000",
+				body:   "m1,t1=v1 f1=Inf\nm1,t1=v1 f1=-Inf\n",
+				auth:   bucketWritePermission("043e0780ee2b1000", "04504b356e23b000"),
+			},
+			state: state{
+				org:    testOrg("043e0780ee2b1000"),
+				bucket: testBucketWithPolicy("043e0780ee2b1000", "04504b356e23b000", influxdb.NonFiniteFloatPolicyStoreInf),
+			},
+			wants: wants{
+				code: 204,
+			},
+		},
+		{
+			name: "NaN float values rejected when storing infinite values",
+			request: request{
+				org:    "043e0780ee2b1000",
+				bucket: "04504b356e23b000",
+				body:   "m1,t1=v1 f1=Inf\nm1,t1=v1 f1=NaN\n",
+				auth:   bucketWritePermission("043e0780ee2b1000", "04504b356e23b000"),
+			},
+			state: state{
+				org:    testOrg("043e0780ee2b1000"),
+				bucket: testBucketWithPolicy("043e0780ee2b1000", "04504b356e23b000", influxdb.NonFiniteFloatPolicyStoreInf),
+			},
+			wants: wants{
+				code: 400,
+				body: `{"code":"invalid","message":"unable to parse 'm1,t1=v1 f1=NaN': non-finite float value: field \"f1\"=NaN: NaN values can not be stored"}`,
+			},
+		},
+		{
+			name: "measurements allowed by the write policy are accepted",
+			request: request{
+				org:    "043e0780ee2b1000",
+				bucket: "04504b356e23b000",
+				body:   "m1,t1=v1 f1=1\nm2,t1=v1 f1=1",
+				auth:   withWritePolicy(bucketWritePermission("043e0780ee2b1000", "04504b356e23b000"), []string{"m1", "m2"}, []string{"m3"}),
+			},
+			state: state{
+				org:    testOrg("043e0780ee2b1000"),
+				bucket: testBucket("043e0780ee2b1000", "04504b356e23b000"),
+			},
+			wants: wants{
+				code: 204,
+			},
+		},
+		{
+			name: "measurement not in the allowlist of the write policy is forbidden",
+			request: request{
+				org:    "043e0780ee2b1000",
+				bucket: "04504b356e23b000",
+				body:   "m1,t1=v1 f1=1\nnew,t1=v1 f1=1",
+				auth:   withWritePolicy(bucketWritePermission("043e0780ee2b1000", "04504b356e23b000"), []string{"m1"}, nil),
+			},
+			state: state{
+				org:    testOrg("043e0780ee2b1000"),
+				bucket: testBucket("043e0780ee2b1000", "04504b356e23b000"),
+			},
+			wants: wants{
+				code: 403,
+				body: `{"code":"forbidden","message":"measurement \"new\" is not allowed by the write policy of the token"}`,
+			},
+		},
+		{
+			name: "measurement in the denylist of the write policy is forbidden",
+			request: request{
+				org:    "043e0780ee2b1000",
+				bucket: "04504b356e23b000",
+				body:   "m1,t1=v1 f1=1",
+				auth:   withWritePolicy(bucketWritePermission("043e0780ee2b1000", "04504b356e23b000"), nil, []string{"m1"}),
+			},
+			state: state{
+				org:    testOrg("043e0780ee2b1000"),
+				bucket: testBucket("043e0780ee2b1000", "04504b356e23b000"),
+			},
+			wants: wants{
+				code: 403,
+				body: `{"code":"forbidden","message":"measurement \"m1\" is not allowed by the write policy of the token"}`,
+			},
+		},
+		{
+			name: "debugged write responds with its trace",
+			request: request{
+				org:    "043e0780ee2b1000",
+				bucket: "04504b356e23b000",
+				body:   "m1,t1=v1 f1=1",
+				auth:   orgWritePermission("043e0780ee2b1000"),
+				debug:  true,
+			},
+			state: state{
+				org:    testOrg("043e0780ee2b1000"),
+				bucket: testBucket("043e0780ee2b1000", "04504b356e23b000"),
+			},
+			wants: wants{
+				code: 200,
+				body: `{"orgID":"043e0780ee2b1000","bucketID":"04504b356e23b000","points":0,"dropped":0,"series":0,"seriesCreated":0,"walSegments":null,"indexPartitions":null}` + "\n",
+			},
+		},
+		{
+			name: "debugged write requires write access to the organization",
+			request: request{
+				org:    "043e0780ee2b1000",
+				bucket: "04504b356e23b000",
+				body:   "m1,t1=v1 f1=1",
+				auth:   bucketWritePermission("043e0780ee2b1000", "04504b356e23b000"),
+				debug:  true,
+			},
+			state: state{
+				org:    testOrg("043e0780ee2b1000"),
+				bucket: testBucket("043e0780ee2b1000", "04504b356e23b000"),
+			},
+			wants: wants{
+				code: 403,
+				body: `{"code":"forbidden","message":"debugging writes requires write access to the organization"}`,
+			},
+		},
+		{
+			name: "auto precision detects mixed precisions",
+			request: request{
+				org:       "043e0780ee2b1000",
+				bucket:    "04504b356e23b000",
+				body:      "m1,t1=v1 f1=1 1568000000\nm1,t1=v1 f1=2 1568000000000000000",
+				auth:      bucketWritePermission("043e0780ee2b1000", "04504b356e23b000"),
+				precision: "auto",
+			},
+			state: state{
+				org:    testOrg("043e0780ee2b1000"),
+				bucket: testBucket("043e0780ee2b1000", "04504b356e23b000"),
+			},
+			wants: wants{
+				code: 204,
+			},
+		},
+		{
+			name: "strict auto precision rejects mixed precisions",
+			request: request{
+				org:             "043e0780ee2b1000",
+				bucket:          "04504b356e23b000",
+				body:            "m1,t1=v1 f1=1 1568000000\nm1,t1=v1 f1=2 1568000000000000000",
+				auth:            bucketWritePermission("043e0780ee2b1000", "04504b356e23b000"),
+				precision:       "auto",
+				strictPrecision: true,
+			},
+			state: state{
+				org:    testOrg("043e0780ee2b1000"),
+				bucket: testBucket("043e0780ee2b1000", "04504b356e23b000"),
+			},
+			wants: wants{
+				code: 400,
+				body: `{"code":"invalid","message":"unable to parse 'm1,t1=v1 f1=2 1568000000000000000': timestamp precision ns differs from the precision s of the first timestamp"}`,
+			},
+		},
+		{
+			name: "strict precision requires auto precision",
+			request: request{
+				org:             "043e0780ee2b1000",
+				bucket:          "04504b356e23b000",
+				body:            "m1,t1=v1 f1=1 1568000000",
+				auth:            bucketWritePermission("043e0780ee2b1000", "04504b356e23b000"),
+				precision:       "s",
+				strictPrecision: true,
+			},
+			state: state{
+				org:    testOrg("043e0780ee2b1000"),
+				bucket: testBucket("043e0780ee2b1000", "04504b356e23b000"),
+			},
+			wants: wants{
+				code: 400,
+				body: `{"code":"invalid","message":"strictPrecision requires precision auto"}`,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -346,7 +578,7 @@ func TestWriteHandler_handleWrite(t *testing.T) {
 				Logger:              zaptest.NewLogger(t),
 				OrganizationService: orgs,
 				BucketService:       buckets,
-				PointsWriter:        &mock.PointsWriter{Err: tt.state.writeErr},
+				PointsWriter:        authorizer.NewPointsWriter(&mock.PointsWriter{Err: tt.state.writeErr}),
 				WriteEventRecorder:  &metric.NopEventRecorder{},
 			}
 			writeHandler := NewWriteHandler(zaptest.NewLogger(t), NewWriteBackend(zaptest.NewLogger(t), b), tt.state.opts...)
@@ -358,9 +590,19 @@ func TestWriteHandler_handleWrite(t *testing.T) {
 				strings.NewReader(tt.request.body),
 			)
 
+			if tt.request.debug {
+				r.Header.Set(DebugWriteHeaderKey, "true")
+			}
+
 			params := r.URL.Query()
 			params.Set("org", tt.request.org)
 			params.Set("bucket", tt.request.bucket)
+			if tt.request.precision != "" {
+				params.Set("precision", tt.request.precision)
+			}
+			if tt.request.strictPrecision {
+				params.Set("strictPrecision", "true")
+			}
 			r.URL.RawQuery = params.Encode()
 
 			w := httptest.NewRecorder()
@@ -397,6 +639,35 @@ func bucketWritePermission(org, bucket string) *influxdb.Authorization {
 	}
 }
 
+func orgWritePermission(org string) *influxdb.Authorization {
+	oid := influxtesting.MustIDBase16(org)
+	return &influxdb.Authorization{
+		OrgID:  oid,
+		Status: influxdb.Active,
+		Permissions: []influxdb.Permission{
+			{
+				Action: influxdb.WriteAction,
+				Resource: influxdb.Resource{
+					Type:  influxdb.BucketsResourceType,
+					OrgID: &oid,
+				},
+			},
+			{
+				Action: influxdb.WriteAction,
+				Resource: influxdb.Resource{
+					Type: influxdb.OrgsResourceType,
+					ID:   &oid,
+				},
+			},
+		},
+	}
+}
+
+func withWritePolicy(a *influxdb.Authorization, allow, deny []string) *influxdb.Authorization {
+	a.WritePolicy = &influxdb.WritePolicy{AllowMeasurements: allow, DenyMeasurements: deny}
+	return a
+}
+
 func testOrg(org string) *influxdb.Organization {
 	oid := influxtesting.MustIDBase16(org)
 	return &influxdb.Organization{
@@ -413,3 +684,55 @@ func testBucket(org, bucket string) *influxdb.Bucket {
 		OrgID: oid,
 	}
 }
+
+func testBucketWithPolicy(org, bucket string, policy influxdb.NonFiniteFloatPolicy) *influxdb.Bucket {
+	b := testBucket(org, bucket)
+	b.NonFiniteFloatPolicy = policy
+	return b
+}
+
+func TestNormalizeTags(t *testing.T) {
+	n := &influxdb.TagNormalization{
+		Tags:          []string{"host", "region"},
+		TrimSpace:     true,
+		Lowercase:     true,
+		LowercaseKeys: true,
+		Synonyms:      map[string]string{"us-west-1": "usw1"},
+	}
+
+	encoded := tsdb.EncodeName(influxdb.ID(1), influxdb.ID(2))
+	mm := models.EscapeMeasurement(encoded[:])
+	points, err := models.ParsePointsWithOptions([]byte(
+		"cpu,Host=WebA,host=webb,region=US-West-1,Service=API f=1 1\n"+
+			"cpu,host=\\ weba\\ ,region=eu f=1 2\n"), mm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	normalizeTags(n, points)
+
+	want := []string{
+		`cpu,host=weba,region=usw1,service=API f=1`,
+		`cpu,host=weba,region=eu f=1`,
+	}
+	for i, pt := range points {
+		tags := pt.Tags()
+		if got := string(tags.Get(models.MeasurementTagKeyBytes)); got != "cpu" {
+			t.Errorf("unexpected measurement of point %d: %q", i, got)
+		}
+		if got := string(tags.Get(models.FieldKeyTagKeyBytes)); got != "f" {
+			t.Errorf("unexpected field key of point %d: %q", i, got)
+		}
+
+		got := "cpu"
+		for _, tag := range tags {
+			if k := string(tag.Key); k != models.MeasurementTagKey && k != models.FieldKeyTagKey {
+				got += "," + k + "=" + string(tag.Value)
+			}
+		}
+		got += " f=1"
+		if got != want[i] {
+			t.Errorf("unexpected point %d: got %q, want %q", i, got, want[i])
+		}
+	}
+}
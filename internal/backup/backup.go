@@ -0,0 +1,296 @@
+// Package backup reads and writes the files of a backup, optionally
+// compressed with zstd and encrypted with AES-GCM, along with the manifest
+// describing them.
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ManifestFilename is the name of the manifest file of a backup directory.
+const ManifestFilename = "manifest.json"
+
+// Compression and encryption algorithms of backup files.
+const (
+	CompressionNone = "none"
+	CompressionZstd = "zstd"
+
+	EncryptionAESGCM = "aes-256-gcm"
+)
+
+// manifestVersion is the version of the manifest format.
+const manifestVersion = 1
+
+// Manifest lists the files of a backup.
+type Manifest struct {
+	Version int    `json:"version"`
+	Files   []File `json:"files"`
+}
+
+// File describes a file of a backup.
+type File struct {
+	// Name is the name of the backed up file, such as a TSM file.
+	Name string `json:"name"`
+	// Path is the name of the file holding its content in the backup directory.
+	Path string `json:"path"`
+	// Size and Checksum are the size and hex encoded SHA-256 checksum of the
+	// content of the backed up file, before compression and encryption.
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum"`
+
+	Compression string `json:"compression,omitempty"`
+	Encryption  string `json:"encryption,omitempty"`
+	// KeyID identifies the key the file is encrypted with.
+	KeyID string `json:"keyId,omitempty"`
+}
+
+// File returns the file of the manifest named name.
+func (m *Manifest) File(name string) (File, bool) {
+	for _, f := range m.Files {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return File{}, false
+}
+
+// Verify reads every file of the manifest from the backup directory dir,
+// checking that it can be decrypted with key and that its checksum matches.
+func (m *Manifest) Verify(dir string, key []byte) error {
+	for _, f := range m.Files {
+		r, err := Open(dir, f, key)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(ioutil.Discard, r)
+		if cerr := r.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// ReadManifest reads the manifest of the backup directory dir.
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, ManifestFilename))
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+	if m.Version != manifestVersion {
+		return nil, fmt.Errorf("unsupported manifest version %d", m.Version)
+	}
+	return &m, nil
+}
+
+// WriteManifest writes the manifest of the files of the backup directory dir.
+func WriteManifest(dir string, files []File) error {
+	data, err := json.MarshalIndent(&Manifest{Version: manifestVersion, Files: files}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, ManifestFilename), data, 0666)
+}
+
+// Options are the options of the files written to a backup.
+type Options struct {
+	// Compression is the compression algorithm, CompressionNone or CompressionZstd.
+	Compression string
+	// Key is the optional 32 bytes AES key the files are encrypted with.
+	Key []byte
+}
+
+// Validate returns an error if the options are invalid.
+func (o Options) Validate() error {
+	switch o.Compression {
+	case "", CompressionNone, CompressionZstd:
+	default:
+		return fmt.Errorf("unknown compression %q, expected %s or %s", o.Compression, CompressionNone, CompressionZstd)
+	}
+	if o.Key != nil && len(o.Key) != KeySize {
+		return fmt.Errorf("encryption key must be %d bytes", KeySize)
+	}
+	return nil
+}
+
+// Writer writes the content of a file to a backup directory.
+type Writer struct {
+	file   File
+	f      *os.File
+	w      io.Writer
+	closer []io.Closer // closed in order before f
+	hash   hash.Hash
+}
+
+// Create creates the file of the backup directory dir holding the content
+// of the file named name.
+func Create(dir, name string, opts Options) (*Writer, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	file := File{Name: name, Path: name}
+	if opts.Compression == CompressionZstd {
+		file.Compression = CompressionZstd
+		file.Path += ".zst"
+	}
+	if opts.Key != nil {
+		file.Encryption, file.KeyID = EncryptionAESGCM, KeyID(opts.Key)
+		file.Path += ".enc"
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, file.Path), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, err
+	}
+	bw := &Writer{file: file, f: f, w: f, hash: sha256.New()}
+
+	if opts.Key != nil {
+		ew, err := newEncryptWriter(bw.w, opts.Key)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		bw.w = ew
+		bw.closer = append([]io.Closer{ew}, bw.closer...)
+	}
+	if opts.Compression == CompressionZstd {
+		zw, err := zstd.NewWriter(bw.w)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		bw.w = zw
+		bw.closer = append([]io.Closer{zw}, bw.closer...)
+	}
+	return bw, nil
+}
+
+// Write writes p to the file.
+func (w *Writer) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.hash.Write(p[:n])
+	w.file.Size += int64(n)
+	return n, err
+}
+
+// Close flushes and closes the file.
+func (w *Writer) Close() error {
+	var err error
+	for _, c := range w.closer {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if cerr := w.f.Close(); err == nil {
+		err = cerr
+	}
+	w.file.Checksum = hex.EncodeToString(w.hash.Sum(nil))
+	return err
+}
+
+// File returns the manifest entry of the file, once closed.
+func (w *Writer) File() File {
+	return w.file
+}
+
+// ErrChecksum is returned when the content of a backup file does not match
+// the checksum of the manifest.
+var ErrChecksum = errors.New("checksum mismatch")
+
+// Open opens the file f of the backup directory dir, decrypting it with key
+// and decompressing it as needed. The reader returns ErrChecksum at the end
+// of the file if its content does not match its checksum.
+func Open(dir string, f File, key []byte) (io.ReadCloser, error) {
+	if f.Encryption != "" {
+		if f.Encryption != EncryptionAESGCM {
+			return nil, fmt.Errorf("%s: unknown encryption %q", f.Name, f.Encryption)
+		} else if key == nil {
+			return nil, fmt.Errorf("%s: encrypted with key %s, but no key given", f.Name, f.KeyID)
+		} else if id := KeyID(key); id != f.KeyID {
+			return nil, fmt.Errorf("%s: encrypted with key %s, but got key %s", f.Name, f.KeyID, id)
+		}
+	}
+	switch f.Compression {
+	case "", CompressionNone, CompressionZstd:
+	default:
+		return nil, fmt.Errorf("%s: unknown compression %q", f.Name, f.Compression)
+	}
+
+	file, err := os.Open(filepath.Join(dir, f.Path))
+	if err != nil {
+		return nil, err
+	}
+	r := &reader{file: f, f: file, r: file, hash: sha256.New()}
+
+	if f.Encryption != "" {
+		if r.dr, err = newDecryptReader(r.r, key); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("%s: %v", f.Name, err)
+		}
+		r.r = r.dr
+	}
+	if f.Compression == CompressionZstd {
+		zr, err := zstd.NewReader(r.r)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("%s: %v", f.Name, err)
+		}
+		r.r, r.zr = zr, zr
+	}
+	return r, nil
+}
+
+// reader reads the content of a backup file, checking its checksum.
+type reader struct {
+	file File
+	f    *os.File
+	r    io.Reader
+	dr   *decryptReader
+	zr   *zstd.Decoder
+	hash hash.Hash
+	size int64
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.hash.Write(p[:n])
+	r.size += int64(n)
+	if err != nil && r.dr != nil {
+		// Report the decryption error rather than that of the decompression.
+		if derr := r.dr.Err(); derr != nil {
+			return n, derr
+		}
+	}
+	if err == io.EOF {
+		if r.size != r.file.Size || hex.EncodeToString(r.hash.Sum(nil)) != r.file.Checksum {
+			return n, ErrChecksum
+		}
+	}
+	return n, err
+}
+
+func (r *reader) Close() error {
+	if r.zr != nil {
+		r.zr.Close()
+	}
+	return r.f.Close()
+}
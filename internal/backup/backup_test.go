@@ -0,0 +1,182 @@
+package backup_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb/internal/backup"
+)
+
+func TestBackup_RoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, backup.KeySize)
+	data := make([]byte, 200<<10)
+	rand.New(rand.NewSource(0)).Read(data[:len(data)/2])
+
+	for _, tt := range []struct {
+		name string
+		opts backup.Options
+		path string
+	}{
+		{name: "plain", opts: backup.Options{}, path: "000000001-000000001.tsm"},
+		{name: "zstd", opts: backup.Options{Compression: backup.CompressionZstd}, path: "000000001-000000001.tsm.zst"},
+		{name: "encrypted", opts: backup.Options{Key: key}, path: "000000001-000000001.tsm.enc"},
+		{name: "zstd encrypted", opts: backup.Options{Compression: backup.CompressionZstd, Key: key}, path: "000000001-000000001.tsm.zst.enc"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := mustTempDir(t)
+			defer os.RemoveAll(dir)
+
+			f := mustWrite(t, dir, "000000001-000000001.tsm", data, tt.opts)
+			if f.Path != tt.path || f.Size != int64(len(data)) {
+				t.Fatalf("unexpected file: %+v", f)
+			}
+			if err := backup.WriteManifest(dir, []backup.File{f}); err != nil {
+				t.Fatal(err)
+			}
+
+			m, err := backup.ReadManifest(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := m.Verify(dir, key); err != nil {
+				t.Fatal(err)
+			}
+			r, err := backup.Open(dir, m.Files[0], key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			got, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatal("unexpected content")
+			}
+		})
+	}
+}
+
+func TestBackup_Verify(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, backup.KeySize)
+	data := bytes.Repeat([]byte("data"), 50<<10)
+
+	for _, tt := range []struct {
+		name   string
+		opts   backup.Options
+		key    []byte
+		modify func(f *backup.File, path string)
+		err    string
+	}{
+		{
+			name: "wrong key",
+			opts: backup.Options{Key: key},
+			key:  bytes.Repeat([]byte{2}, backup.KeySize),
+			err:  "encrypted with key " + backup.KeyID(key) + ", but got key " + backup.KeyID(bytes.Repeat([]byte{2}, backup.KeySize)),
+		},
+		{
+			name: "missing key",
+			opts: backup.Options{Key: key},
+			err:  "but no key given",
+		},
+		{
+			name: "corrupted",
+			opts: backup.Options{Compression: backup.CompressionZstd, Key: key},
+			key:  key,
+			modify: func(f *backup.File, path string) {
+				b, _ := ioutil.ReadFile(path)
+				b[len(b)/2] ^= 0xff
+				ioutil.WriteFile(path, b, 0666)
+			},
+			err: "unable to decrypt",
+		},
+		{
+			name: "truncated",
+			opts: backup.Options{Key: key},
+			key:  key,
+			modify: func(f *backup.File, path string) {
+				b, _ := ioutil.ReadFile(path)
+				ioutil.WriteFile(path, b[:len(b)-100], 0666)
+			},
+			err: "unexpected EOF",
+		},
+		{
+			name: "checksum",
+			opts: backup.Options{},
+			modify: func(f *backup.File, path string) {
+				f.Checksum = strings.Repeat("0", 64)
+			},
+			err: backup.ErrChecksum.Error(),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := mustTempDir(t)
+			defer os.RemoveAll(dir)
+
+			f := mustWrite(t, dir, "influxd.bolt", data, tt.opts)
+			if tt.modify != nil {
+				tt.modify(&f, filepath.Join(dir, f.Path))
+			}
+			m := &backup.Manifest{Files: []backup.File{f}}
+			if err := m.Verify(dir, tt.key); err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("unexpected error: got %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestReadKeyFile(t *testing.T) {
+	dir := mustTempDir(t)
+	defer os.RemoveAll(dir)
+
+	key := bytes.Repeat([]byte{0xab}, backup.KeySize)
+	for _, content := range []string{string(key), strings.Repeat("ab", backup.KeySize) + "\n"} {
+		path := filepath.Join(dir, "key")
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		got, err := backup.ReadKeyFile(path)
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(got, key) {
+			t.Fatalf("unexpected key: %x", got)
+		}
+	}
+
+	path := filepath.Join(dir, "key")
+	if err := ioutil.WriteFile(path, []byte("short"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := backup.ReadKeyFile(path); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func mustTempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func mustWrite(t *testing.T, dir, name string, data []byte, opts backup.Options) backup.File {
+	t.Helper()
+	w, err := backup.Create(dir, name, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return w.File()
+}
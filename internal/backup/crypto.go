@@ -0,0 +1,222 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// KeySize is the size of the AES-256 keys backups are encrypted with.
+const KeySize = 32
+
+// chunkSize is the size of the chunks of plaintext encrypted separately, so
+// that files are encrypted and decrypted as streams.
+const chunkSize = 64 << 10
+
+// finalChunk flags the length of the last chunk of an encrypted file, so
+// that a truncated file is detected.
+const finalChunk = 1 << 31
+
+// KeyID returns the identifier of key recorded in the manifest, so that
+// restoring with the wrong key fails before any file is decrypted.
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// ReadKeyFile reads an encryption key from the file at path, holding either
+// the raw 32 bytes of the key or their hex encoding.
+func ReadKeyFile(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == KeySize {
+		return data, nil
+	}
+	if key, err := hex.DecodeString(string(bytes.TrimSpace(data))); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("encryption key must be %d bytes or %d hex digits", KeySize, 2*KeySize)
+}
+
+// encryptWriter encrypts a stream as a nonce followed by chunks, each
+// prefixed with its length and sealed with AES-GCM.
+type encryptWriter struct {
+	w     io.Writer
+	aead  cipher.AEAD
+	nonce []byte
+	seq   uint64
+	buf   []byte
+}
+
+func newEncryptWriter(w io.Writer, key []byte) (*encryptWriter, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(nonce); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, aead: aead, nonce: nonce, buf: make([]byte, 0, chunkSize)}, nil
+}
+
+func (w *encryptWriter) Write(p []byte) (int, error) {
+	var n int
+	for len(p) > 0 {
+		if len(w.buf) == chunkSize {
+			if err := w.flush(false); err != nil {
+				return n, err
+			}
+		}
+		m := copy(w.buf[len(w.buf):chunkSize], p)
+		w.buf = w.buf[:len(w.buf)+m]
+		p, n = p[m:], n+m
+	}
+	return n, nil
+}
+
+// Close writes the last chunk. It does not close the underlying writer.
+func (w *encryptWriter) Close() error {
+	return w.flush(true)
+}
+
+func (w *encryptWriter) flush(final bool) error {
+	length := uint32(len(w.buf) + w.aead.Overhead())
+	if final {
+		length |= finalChunk
+	}
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], length)
+
+	sealed := w.aead.Seal(nil, chunkNonce(w.nonce, w.seq), w.buf, header[:])
+	w.seq++
+	w.buf = w.buf[:0]
+
+	if _, err := w.w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.w.Write(sealed)
+	return err
+}
+
+// decryptReader decrypts a stream written by encryptWriter.
+type decryptReader struct {
+	r     io.Reader
+	aead  cipher.AEAD
+	nonce []byte
+	seq   uint64
+	buf   []byte
+	final bool
+
+	// err is sticky, and read by the reader of the decompressed stream as
+	// the zstd decoder, which reads from another goroutine, may not return it.
+	mu  sync.Mutex
+	err error
+}
+
+func newDecryptReader(r io.Reader, key []byte) (*decryptReader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(r, nonce); err != nil {
+		return nil, fmt.Errorf("unable to read nonce: %v", err)
+	}
+	return &decryptReader{r: r, aead: aead, nonce: nonce}, nil
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if err := r.Err(); err != nil {
+			return 0, err
+		}
+		if r.final {
+			// Nothing may follow the last chunk.
+			if n, _ := r.r.Read(make([]byte, 1)); n > 0 {
+				r.setErr(errors.New("unexpected data after the last chunk"))
+				continue
+			}
+			return 0, io.EOF
+		}
+		r.setErr(r.next())
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// Err returns the error that stopped decryption, if any.
+func (r *decryptReader) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *decryptReader) setErr(err error) {
+	r.mu.Lock()
+	r.err = err
+	r.mu.Unlock()
+}
+
+// next reads and decrypts the next chunk.
+func (r *decryptReader) next() error {
+	var header [4]byte
+	if _, err := io.ReadFull(r.r, header[:]); err == io.EOF {
+		return io.ErrUnexpectedEOF
+	} else if err != nil {
+		return err
+	}
+	length := binary.BigEndian.Uint32(header[:])
+	r.final = length&finalChunk != 0
+	length &^= finalChunk
+	if length > chunkSize+uint32(r.aead.Overhead()) {
+		return errors.New("invalid chunk length")
+	}
+
+	sealed := make([]byte, length)
+	if _, err := io.ReadFull(r.r, sealed); err != nil {
+		return err
+	}
+	plain, err := r.aead.Open(sealed[:0], chunkNonce(r.nonce, r.seq), sealed, header[:])
+	if err != nil {
+		return errors.New("unable to decrypt: wrong key or corrupted data")
+	}
+	r.seq++
+	r.buf = plain
+	return nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce returns the nonce of the chunk seq, the base nonce of the
+// stream with its last 8 bytes xored with the sequence number.
+func chunkNonce(base []byte, seq uint64) []byte {
+	nonce := append([]byte(nil), base...)
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], seq)
+	for i := range b {
+		nonce[len(nonce)-8+i] ^= b[i]
+	}
+	return nonce
+}
@@ -81,9 +81,27 @@ func (s *Service) findAuthorizationByID(ctx context.Context, tx Tx, id influxdb.
 		}
 	}
 
+	if err := s.resolveAuthorizationRoles(ctx, tx, a); err != nil {
+		return nil, err
+	}
+
 	return a, nil
 }
 
+// resolveAuthorizationRoles sets the role permissions of the authorization to
+// the current permissions of its roles.
+func (s *Service) resolveAuthorizationRoles(ctx context.Context, tx Tx, a *influxdb.Authorization) error {
+	if len(a.RoleIDs) == 0 {
+		return nil
+	}
+	ps, err := s.rolePermissions(ctx, tx, a.OrgID, a.RoleIDs)
+	if err != nil {
+		return err
+	}
+	a.RolePermissions = ps
+	return nil
+}
+
 // FindAuthorizationByToken returns a authorization by token for a particular authorization.
 func (s *Service) FindAuthorizationByToken(ctx context.Context, n string) (*influxdb.Authorization, error) {
 	var a *influxdb.Authorization
@@ -293,6 +311,12 @@ func (s *Service) findAuthorizations(ctx context.Context, tx Tx, f influxdb.Auth
 		return nil, err
 	}
 
+	for _, a := range as {
+		if err := s.resolveAuthorizationRoles(ctx, tx, a); err != nil {
+			return nil, err
+		}
+	}
+
 	return as, nil
 }
 
@@ -318,6 +342,10 @@ func (s *Service) createAuthorization(ctx context.Context, tx Tx, a *influxdb.Au
 		return influxdb.ErrUnableToCreateToken
 	}
 
+	if err := s.validAuthorizationRoles(ctx, tx, a); err != nil {
+		return err
+	}
+
 	if err := s.uniqueAuthToken(ctx, tx, a); err != nil {
 		return err
 	}
@@ -342,7 +370,7 @@ func (s *Service) createAuthorization(ctx context.Context, tx Tx, a *influxdb.Au
 		return err
 	}
 
-	return nil
+	return s.resolveAuthorizationRoles(ctx, tx, a)
 }
 
 // PutAuthorization will put a authorization without setting an ID.
@@ -524,6 +552,25 @@ func (s *Service) updateAuthorization(ctx context.Context, tx Tx, id influxdb.ID
 	if upd.Description != nil {
 		a.Description = *upd.Description
 	}
+	if upd.WritePolicy != nil {
+		if err := upd.WritePolicy.Valid(); err != nil {
+			return nil, err
+		}
+		a.WritePolicy = upd.WritePolicy
+		if a.WritePolicy.IsEmpty() {
+			a.WritePolicy = nil
+		}
+	}
+	if upd.RoleIDs != nil {
+		a.RoleIDs = *upd.RoleIDs
+		if err := s.validAuthorizationRoles(ctx, tx, a); err != nil {
+			return nil, err
+		}
+		a.RolePermissions = nil
+		if err := s.resolveAuthorizationRoles(ctx, tx, a); err != nil {
+			return nil, err
+		}
+	}
 
 	now := s.TimeGenerator.Now()
 	a.SetUpdatedAt(now)
@@ -465,6 +465,19 @@ func (s *Service) createBucket(ctx context.Context, tx Tx, b *influxdb.Bucket) (
 		return err
 	}
 
+	if err := b.NonFiniteFloatPolicy.Valid(); err != nil {
+		return err
+	}
+
+	if b.TagNormalization != nil {
+		if err := b.TagNormalization.Valid(); err != nil {
+			return err
+		}
+		if b.TagNormalization.IsZero() {
+			b.TagNormalization = nil
+		}
+	}
+
 	if b.ID, err = s.generateBucketID(ctx, tx); err != nil {
 		return err
 	}
@@ -679,6 +692,23 @@ func (s *Service) updateBucket(ctx context.Context, tx Tx, id influxdb.ID, upd i
 		b.Description = *upd.Description
 	}
 
+	if upd.NonFiniteFloatPolicy != nil {
+		if err := upd.NonFiniteFloatPolicy.Valid(); err != nil {
+			return nil, err
+		}
+		b.NonFiniteFloatPolicy = *upd.NonFiniteFloatPolicy
+	}
+
+	if upd.TagNormalization != nil {
+		if err := upd.TagNormalization.Valid(); err != nil {
+			return nil, err
+		}
+		b.TagNormalization = upd.TagNormalization
+		if b.TagNormalization.IsZero() {
+			b.TagNormalization = nil
+		}
+	}
+
 	if upd.Name != nil {
 		b0, err := s.findBucketByName(ctx, tx, b.OrgID, *upd.Name)
 		if err == nil && b0.ID != id {
@@ -287,6 +287,10 @@ func (s *Service) createCheck(ctx context.Context, tx Tx, c influxdb.CheckCreate
 		return err
 	}
 
+	if err := s.appendCheckVersion(ctx, tx, c.Check); err != nil {
+		return err
+	}
+
 	return s.createUserResourceMappingForOrg(ctx, tx, c.GetOrgID(), c.GetID(), influxdb.ChecksResourceType)
 }
 
@@ -330,6 +334,16 @@ func (s *Service) putCheck(ctx context.Context, tx Tx, c influxdb.Check, opts ..
 	}, opts...)
 }
 
+// appendCheckVersion records the definition of the check in its history, if
+// it changed.
+func (s *Service) appendCheckVersion(ctx context.Context, tx Tx, c influxdb.Check) error {
+	def, err := jsonDefinition(c)
+	if err != nil {
+		return err
+	}
+	return s.appendDefinitionVersion(ctx, tx, influxdb.ChecksResourceType, c.GetID(), def)
+}
+
 // PatchCheck updates a check according the parameters set on upd.
 func (s *Service) PatchCheck(ctx context.Context, id influxdb.ID, upd influxdb.CheckUpdate) (influxdb.Check, error) {
 	span, ctx := tracing.StartSpanFromContext(ctx)
@@ -430,6 +444,10 @@ func (s *Service) updateCheck(ctx context.Context, tx Tx, id influxdb.ID, chk in
 		return nil, err
 	}
 
+	if err := s.appendCheckVersion(ctx, tx, chk.Check); err != nil {
+		return nil, err
+	}
+
 	return chk.Check, nil
 }
 
@@ -467,6 +485,10 @@ func (s *Service) patchCheck(ctx context.Context, tx Tx, id influxdb.ID, upd inf
 		return nil, err
 	}
 
+	if err := s.appendCheckVersion(ctx, tx, c); err != nil {
+		return nil, err
+	}
+
 	if _, err := s.updateTask(ctx, tx, c.GetTaskID(), tu); err != nil {
 		return nil, err
 	}
@@ -493,6 +515,10 @@ func (s *Service) DeleteCheck(ctx context.Context, id influxdb.ID) error {
 			return err
 		}
 
+		if err := s.deleteDefinitionHistory(ctx, tx, influxdb.ChecksResourceType, id); err != nil {
+			return err
+		}
+
 		return s.deleteUserResourceMappings(ctx, tx, influxdb.UserResourceMappingFilter{
 			ResourceID:   id,
 			ResourceType: influxdb.ChecksResourceType,
@@ -0,0 +1,133 @@
+package kv
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+)
+
+var (
+	constantBucket = []byte("constantsv1")
+)
+
+var _ influxdb.ConstantService = (*Service)(nil)
+
+func (s *Service) initializeConstants(ctx context.Context, tx Tx) error {
+	if _, err := tx.Bucket(constantBucket); err != nil {
+		return err
+	}
+	return nil
+}
+
+// FindConstants returns the constants of the organization orgID by name.
+func (s *Service) FindConstants(ctx context.Context, orgID influxdb.ID) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	err := s.kv.View(ctx, func(tx Tx) error {
+		var err error
+		m, err = s.findConstants(ctx, tx, orgID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (s *Service) findConstants(ctx context.Context, tx Tx, orgID influxdb.ID) (map[string]interface{}, error) {
+	b, err := tx.Bucket(constantBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, err := orgID.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := b.ForwardCursor(prefix, WithCursorPrefix(prefix))
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]interface{})
+	for k, v := cur.Next(); k != nil; k, v = cur.Next() {
+		// Constants share the key layout of secrets.
+		id, name, err := decodeSecretKey(k)
+		if err != nil {
+			return nil, err
+		}
+		if id != orgID {
+			break
+		}
+
+		val, err := influxdb.UnmarshalConstant(v)
+		if err != nil {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInternal,
+				Msg:  "unable to decode constant " + name,
+				Err:  err,
+			}
+		}
+		m[name] = val
+	}
+	return m, nil
+}
+
+// PatchConstants creates the constants of m for the organization orgID, or
+// replaces the values of those already defined.
+func (s *Service) PatchConstants(ctx context.Context, orgID influxdb.ID, m map[string]interface{}) error {
+	for name, v := range m {
+		if err := influxdb.ValidateConstant(name, v); err != nil {
+			return err
+		}
+	}
+
+	return s.kv.Update(ctx, func(tx Tx) error {
+		b, err := tx.Bucket(constantBucket)
+		if err != nil {
+			return err
+		}
+		for name, v := range m {
+			key, err := encodeSecretKey(orgID, name)
+			if err != nil {
+				return err
+			}
+			val, err := influxdb.MarshalConstant(v)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(key, val); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteConstants removes the named constants of the organization orgID.
+func (s *Service) DeleteConstants(ctx context.Context, orgID influxdb.ID, names ...string) error {
+	return s.kv.Update(ctx, func(tx Tx) error {
+		b, err := tx.Bucket(constantBucket)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			key, err := encodeSecretKey(orgID, name)
+			if err != nil {
+				return err
+			}
+			if _, err := b.Get(key); IsNotFound(err) {
+				return &influxdb.Error{
+					Code: influxdb.ENotFound,
+					Msg:  influxdb.ErrConstantNotFound + ": " + name,
+				}
+			} else if err != nil {
+				return err
+			}
+			if err := b.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
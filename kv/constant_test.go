@@ -0,0 +1,82 @@
+package kv_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kv"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestService_Constants(t *testing.T) {
+	s, closeBolt, err := NewTestBoltStore(t)
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+	defer closeBolt()
+
+	ctx := context.Background()
+	svc := kv.NewService(zaptest.NewLogger(t), s)
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatalf("error initializing constant service: %v", err)
+	}
+
+	orgID, otherID := influxdb.ID(1), influxdb.ID(2)
+	if err := svc.PatchConstants(ctx, orgID, map[string]interface{}{
+		"sla":   99.5,
+		"limit": int64(10),
+		"sites": []interface{}{"a", "b"},
+	}); err != nil {
+		t.Fatalf("unexpected error patching constants: %v", err)
+	}
+	if err := svc.PatchConstants(ctx, orgID, map[string]interface{}{"limit": int64(20)}); err != nil {
+		t.Fatalf("unexpected error patching constants: %v", err)
+	}
+	if err := svc.PatchConstants(ctx, otherID, map[string]interface{}{"enabled": true}); err != nil {
+		t.Fatalf("unexpected error patching constants: %v", err)
+	}
+
+	got, err := svc.FindConstants(ctx, orgID)
+	if err != nil {
+		t.Fatalf("unexpected error finding constants: %v", err)
+	}
+	want := map[string]interface{}{
+		"sla":   99.5,
+		"limit": int64(20),
+		"sites": []interface{}{"a", "b"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected constants: got %#v, want %#v", got, want)
+	}
+
+	if err := svc.PatchConstants(ctx, orgID, map[string]interface{}{"mixed": []interface{}{"a", int64(1)}}); influxdb.ErrorCode(err) != influxdb.EInvalid {
+		t.Fatalf("expected invalid error for a mixed array, got %v", err)
+	}
+	if err := svc.PatchConstants(ctx, orgID, map[string]interface{}{"1st": "a"}); influxdb.ErrorCode(err) != influxdb.EInvalid {
+		t.Fatalf("expected invalid error for an invalid name, got %v", err)
+	}
+
+	if err := svc.DeleteConstants(ctx, orgID, "sla", "sites"); err != nil {
+		t.Fatalf("unexpected error deleting constants: %v", err)
+	}
+	if err := svc.DeleteConstants(ctx, orgID, "sla"); influxdb.ErrorCode(err) != influxdb.ENotFound {
+		t.Fatalf("expected not found error deleting a missing constant, got %v", err)
+	}
+
+	got, err = svc.FindConstants(ctx, orgID)
+	if err != nil {
+		t.Fatalf("unexpected error finding constants: %v", err)
+	}
+	if want := map[string]interface{}{"limit": int64(20)}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected constants: got %#v, want %#v", got, want)
+	}
+	got, err = svc.FindConstants(ctx, otherID)
+	if err != nil {
+		t.Fatalf("unexpected error finding constants: %v", err)
+	}
+	if want := map[string]interface{}{"enabled": true}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected constants: got %#v, want %#v", got, want)
+	}
+}
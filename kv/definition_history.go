@@ -0,0 +1,225 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	icontext "github.com/influxdata/influxdb/context"
+)
+
+var _ influxdb.DefinitionHistoryService = (*Service)(nil)
+
+const definitionHistoryKeyPrefix = "definition/"
+
+// encodeDefinitionHistoryKey returns the key of the key value log of the
+// versions of the definition of a resource.
+func encodeDefinitionHistoryKey(resourceType influxdb.ResourceType, id influxdb.ID) ([]byte, error) {
+	buf, err := id.Encode()
+	if err != nil {
+		return nil, err
+	}
+	key := append([]byte(definitionHistoryKeyPrefix), resourceType...)
+	key = append(key, '/')
+	return append(key, buf...), nil
+}
+
+// FindDefinitionVersions returns the versions of the definition of the
+// resource and the number of versions, which is that of the latest one.
+func (s *Service) FindDefinitionVersions(ctx context.Context, resourceType influxdb.ResourceType, id influxdb.ID, opts influxdb.FindOptions) ([]*influxdb.DefinitionVersion, int, error) {
+	versions := []*influxdb.DefinitionVersion{}
+	var n int
+
+	err := s.kv.View(ctx, func(tx Tx) error {
+		key, err := encodeDefinitionHistoryKey(resourceType, id)
+		if err != nil {
+			return err
+		}
+
+		last, err := s.lastDefinitionVersion(ctx, tx, key)
+		if err != nil || last == nil {
+			return err
+		}
+		n = last.Version
+
+		return s.forEachLogEntry(ctx, tx, key, opts, func(v []byte, t time.Time) error {
+			dv := &influxdb.DefinitionVersion{}
+			if err := json.Unmarshal(v, dv); err != nil {
+				return err
+			}
+			versions = append(versions, dv)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return versions, n, nil
+}
+
+// FindDefinitionVersion returns a single version of the definition of the
+// resource.
+func (s *Service) FindDefinitionVersion(ctx context.Context, resourceType influxdb.ResourceType, id influxdb.ID, version int) (*influxdb.DefinitionVersion, error) {
+	var dv *influxdb.DefinitionVersion
+
+	err := s.kv.View(ctx, func(tx Tx) error {
+		key, err := encodeDefinitionHistoryKey(resourceType, id)
+		if err != nil {
+			return err
+		}
+
+		err = s.forEachLogEntry(ctx, tx, key, influxdb.FindOptions{}, func(v []byte, t time.Time) error {
+			if dv != nil {
+				return nil
+			}
+			e := &influxdb.DefinitionVersion{}
+			if err := json.Unmarshal(v, e); err != nil {
+				return err
+			}
+			if e.Version == version {
+				dv = e
+			}
+			return nil
+		})
+		if err == errKeyValueLogBoundsNotFound {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if dv == nil {
+		return nil, influxdb.ErrDefinitionVersionNotFound
+	}
+	return dv, nil
+}
+
+// lastDefinitionVersion returns the latest version of the definition of the
+// key, or nil if the definition has no version.
+func (s *Service) lastDefinitionVersion(ctx context.Context, tx Tx, key []byte) (*influxdb.DefinitionVersion, error) {
+	v, _, err := s.lastLogEntry(ctx, tx, key)
+	if err == errKeyValueLogBoundsNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dv := &influxdb.DefinitionVersion{}
+	if err := json.Unmarshal(v, dv); err != nil {
+		return nil, err
+	}
+	return dv, nil
+}
+
+// appendDefinitionVersion records a new version of the definition of the
+// resource, unless it is that of the latest version.
+func (s *Service) appendDefinitionVersion(ctx context.Context, tx Tx, resourceType influxdb.ResourceType, id influxdb.ID, def string) error {
+	key, err := encodeDefinitionHistoryKey(resourceType, id)
+	if err != nil {
+		return err
+	}
+
+	last, err := s.lastDefinitionVersion(ctx, tx, key)
+	if err != nil {
+		return err
+	}
+
+	dv := &influxdb.DefinitionVersion{
+		ResourceType: resourceType,
+		ResourceID:   id,
+		Version:      1,
+		Definition:   def,
+		Time:         s.Now().UTC(),
+	}
+	if last != nil {
+		if last.Definition == def {
+			return nil
+		}
+		dv.Version = last.Version + 1
+		// Entries of the log are keyed by time, so the time of a version
+		// must follow that of the previous one.
+		if !dv.Time.After(last.Time) {
+			dv.Time = last.Time.Add(time.Nanosecond)
+		}
+	}
+	// Add the user to the version if you can, but don't error if its not there.
+	if a, err := icontext.GetAuthorizer(ctx); err == nil {
+		dv.UserID = a.GetUserID()
+	}
+
+	v, err := json.Marshal(dv)
+	if err != nil {
+		return err
+	}
+	return s.addLogEntry(ctx, tx, key, v, dv.Time)
+}
+
+// deleteDefinitionHistory removes the versions of the definition of the
+// resource.
+func (s *Service) deleteDefinitionHistory(ctx context.Context, tx Tx, resourceType influxdb.ResourceType, id influxdb.ID) error {
+	key, err := encodeDefinitionHistoryKey(resourceType, id)
+	if err != nil {
+		return err
+	}
+
+	var keys [][]byte
+	err = s.forEachLogEntry(ctx, tx, key, influxdb.FindOptions{}, func(v []byte, t time.Time) error {
+		k, err := encodeLogEntryKey(key, t.UTC().UnixNano())
+		if err != nil {
+			return err
+		}
+		keys = append(keys, k)
+		return nil
+	})
+	if err == errKeyValueLogBoundsNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	b, err := tx.Bucket(kvlogBucket)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	idx, err := tx.Bucket(kvlogIndex)
+	if err != nil {
+		return err
+	}
+	return idx.Delete(encodeKeyValueIndexKey(key))
+}
+
+// jsonDefinition returns the definition of a check or notification rule
+// recorded in its history, its indented JSON without the times of its
+// creation and last update.
+func jsonDefinition(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	var m map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return "", err
+	}
+	delete(m, "createdAt")
+	delete(m, "updatedAt")
+	b, err = json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
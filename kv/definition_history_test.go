@@ -0,0 +1,89 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/influxdata/influxdb"
+	icontext "github.com/influxdata/influxdb/context"
+)
+
+func TestService_TaskDefinitionHistory(t *testing.T) {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+
+	c := clock.NewMock()
+	c.Set(time.Unix(1000, 0))
+
+	ts := newService(t, ctx, c)
+	defer ts.Close()
+
+	ctx = icontext.SetAuthorizer(ctx, &ts.Auth)
+
+	const (
+		flux1 = `option task = {name: "a task", every: 1h} from(bucket:"test") |> range(start:-1h)`
+		flux2 = `option task = {name: "a task", every: 2h} from(bucket:"test") |> range(start:-2h)`
+	)
+
+	task, err := ts.Service.CreateTask(ctx, influxdb.TaskCreate{
+		Flux:           flux1,
+		OrganizationID: ts.Org.ID,
+		OwnerID:        ts.User.ID,
+	})
+	if err != nil {
+		t.Fatal("CreateTask", err)
+	}
+
+	update := func(flux string) {
+		t.Helper()
+		c.Add(time.Second)
+		if _, err := ts.Service.UpdateTask(ctx, task.ID, influxdb.TaskUpdate{Flux: &flux}); err != nil {
+			t.Fatal("UpdateTask", err)
+		}
+	}
+	update(flux2)
+	// an unchanged script does not record a version.
+	update(flux2)
+	update(flux1)
+
+	versions, n, err := ts.Service.FindDefinitionVersions(ctx, influxdb.TasksResourceType, task.ID, influxdb.DefaultDefinitionHistoryFindOptions)
+	if err != nil {
+		t.Fatal("FindDefinitionVersions", err)
+	}
+	if n != 3 || len(versions) != 3 {
+		t.Fatalf("expected 3 versions, got %d of %d", len(versions), n)
+	}
+	for i, exp := range []string{flux1, flux2, flux1} {
+		v := versions[i]
+		if v.Version != 3-i || v.Definition != exp {
+			t.Fatalf("unexpected version %d: %d %q", i, v.Version, v.Definition)
+		}
+		if v.UserID != ts.User.ID {
+			t.Fatalf("unexpected user of version %d: %v", v.Version, v.UserID)
+		}
+	}
+
+	v, err := ts.Service.FindDefinitionVersion(ctx, influxdb.TasksResourceType, task.ID, 2)
+	if err != nil {
+		t.Fatal("FindDefinitionVersion", err)
+	}
+	if v.Definition != flux2 {
+		t.Fatalf("unexpected definition of version 2: %q", v.Definition)
+	}
+	if _, err := ts.Service.FindDefinitionVersion(ctx, influxdb.TasksResourceType, task.ID, 4); influxdb.ErrorCode(err) != influxdb.ENotFound {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	if err := ts.Service.DeleteTask(ctx, task.ID); err != nil {
+		t.Fatal("DeleteTask", err)
+	}
+	versions, n, err = ts.Service.FindDefinitionVersions(ctx, influxdb.TasksResourceType, task.ID, influxdb.DefaultDefinitionHistoryFindOptions)
+	if err != nil {
+		t.Fatal("FindDefinitionVersions", err)
+	}
+	if n != 0 || len(versions) != 0 {
+		t.Fatalf("expected the history to be deleted, got %d versions", len(versions))
+	}
+}
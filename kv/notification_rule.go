@@ -99,6 +99,10 @@ func (s *Service) createNotificationRule(ctx context.Context, tx Tx, nr influxdb
 		return err
 	}
 
+	if err := s.appendNotificationRuleVersion(ctx, tx, nr.NotificationRule); err != nil {
+		return err
+	}
+
 	urm := &influxdb.UserResourceMapping{
 		ResourceID:   id,
 		UserID:       userID,
@@ -208,6 +212,10 @@ func (s *Service) updateNotificationRule(ctx context.Context, tx Tx, id influxdb
 		return nil, err
 	}
 
+	if err := s.appendNotificationRuleVersion(ctx, tx, nr.NotificationRule); err != nil {
+		return nil, err
+	}
+
 	return nr.NotificationRule, nil
 }
 
@@ -261,9 +269,23 @@ func (s *Service) patchNotificationRule(ctx context.Context, tx Tx, id influxdb.
 		return nil, err
 	}
 
+	if err := s.appendNotificationRuleVersion(ctx, tx, nr); err != nil {
+		return nil, err
+	}
+
 	return nr, nil
 }
 
+// appendNotificationRuleVersion records the definition of the notification
+// rule in its history, if it changed.
+func (s *Service) appendNotificationRuleVersion(ctx context.Context, tx Tx, nr influxdb.NotificationRule) error {
+	def, err := jsonDefinition(nr)
+	if err != nil {
+		return err
+	}
+	return s.appendDefinitionVersion(ctx, tx, influxdb.NotificationRuleResourceType, nr.GetID(), def)
+}
+
 // PutNotificationRule put a notification rule to storage.
 func (s *Service) PutNotificationRule(ctx context.Context, nr influxdb.NotificationRuleCreate) error {
 	return s.kv.Update(ctx, func(tx Tx) (err error) {
@@ -492,6 +514,10 @@ func (s *Service) deleteNotificationRule(ctx context.Context, tx Tx, id influxdb
 		return InternalNotificationRuleStoreError(err)
 	}
 
+	if err := s.deleteDefinitionHistory(ctx, tx, influxdb.NotificationRuleResourceType, id); err != nil {
+		return err
+	}
+
 	if err := s.deleteUserResourceMappings(ctx, tx, influxdb.UserResourceMappingFilter{
 		ResourceID:   id,
 		ResourceType: influxdb.NotificationRuleResourceType,
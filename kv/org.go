@@ -468,6 +468,25 @@ func (s *Service) updateOrganization(ctx context.Context, tx Tx, id influxdb.ID,
 		o.Description = *upd.Description
 	}
 
+	if upd.QueryResultLimits != nil {
+		if upd.QueryResultLimits.IsZero() {
+			o.QueryResultLimits = nil
+		} else {
+			limits := *upd.QueryResultLimits
+			o.QueryResultLimits = &limits
+		}
+	}
+
+	if upd.QueryReadRateLimit != nil {
+		if *upd.QueryReadRateLimit < 0 {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  "query read rate limit must not be negative",
+			}
+		}
+		o.QueryReadRateLimit = *upd.QueryReadRateLimit
+	}
+
 	o.UpdatedAt = s.Now()
 
 	if err := s.appendOrganizationEventToLog(ctx, tx, o.ID, organizationUpdatedEvent); err != nil {
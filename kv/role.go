@@ -0,0 +1,537 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/influxdata/influxdb"
+)
+
+var (
+	roleBucket     = []byte("rolesv1")
+	userRoleBucket = []byte("userrolesv1")
+)
+
+var _ influxdb.RoleService = (*Service)(nil)
+
+func (s *Service) initializeRoles(ctx context.Context, tx Tx) error {
+	if _, err := tx.Bucket(roleBucket); err != nil {
+		return err
+	}
+	if _, err := tx.Bucket(userRoleBucket); err != nil {
+		return err
+	}
+	return nil
+}
+
+// FindRoleByID returns a single role by ID.
+func (s *Service) FindRoleByID(ctx context.Context, id influxdb.ID) (*influxdb.Role, error) {
+	var r *influxdb.Role
+	err := s.kv.View(ctx, func(tx Tx) error {
+		role, err := s.findRoleByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		r = role
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *Service) findRoleByID(ctx context.Context, tx Tx, id influxdb.ID) (*influxdb.Role, error) {
+	encodedID, err := id.Encode()
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(roleBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := b.Get(encodedID)
+	if IsNotFound(err) {
+		return nil, &influxdb.Error{
+			Code: influxdb.ENotFound,
+			Msg:  influxdb.ErrRoleNotFound,
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r := &influxdb.Role{}
+	if err := json.Unmarshal(v, r); err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInternal,
+			Err:  err,
+		}
+	}
+	return r, nil
+}
+
+// FindRoles returns the roles that match filter and the total count of
+// matching roles.
+func (s *Service) FindRoles(ctx context.Context, filter influxdb.RoleFilter, opt ...influxdb.FindOptions) ([]*influxdb.Role, int, error) {
+	var rs []*influxdb.Role
+	err := s.kv.View(ctx, func(tx Tx) error {
+		roles, err := s.findRoles(ctx, tx, filter)
+		if err != nil {
+			return err
+		}
+		rs = roles
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return rs, len(rs), nil
+}
+
+func (s *Service) findRoles(ctx context.Context, tx Tx, filter influxdb.RoleFilter) ([]*influxdb.Role, error) {
+	if filter.ID != nil {
+		r, err := s.findRoleByID(ctx, tx, *filter.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !filterRole(filter, r) {
+			return []*influxdb.Role{}, nil
+		}
+		return []*influxdb.Role{r}, nil
+	}
+
+	if filter.UserID != nil {
+		ids, err := s.findUserRoleIDs(ctx, tx, *filter.UserID)
+		if err != nil {
+			return nil, err
+		}
+		rs := make([]*influxdb.Role, 0, len(ids))
+		for _, id := range ids {
+			r, err := s.findRoleByID(ctx, tx, id)
+			if err != nil {
+				return nil, err
+			}
+			if filterRole(filter, r) {
+				rs = append(rs, r)
+			}
+		}
+		return rs, nil
+	}
+
+	b, err := tx.Bucket(roleBucket)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := b.Cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	rs := []*influxdb.Role{}
+	for k, v := cur.First(); k != nil; k, v = cur.Next() {
+		r := &influxdb.Role{}
+		if err := json.Unmarshal(v, r); err != nil {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInternal,
+				Err:  err,
+			}
+		}
+		if filterRole(filter, r) {
+			rs = append(rs, r)
+		}
+	}
+	return rs, nil
+}
+
+func filterRole(filter influxdb.RoleFilter, r *influxdb.Role) bool {
+	return (filter.OrgID == nil || r.OrgID == *filter.OrgID) &&
+		(filter.Name == nil || r.Name == *filter.Name)
+}
+
+// CreateRole creates a new role and sets r.ID with the new identifier.
+func (s *Service) CreateRole(ctx context.Context, r *influxdb.Role) error {
+	if err := r.Valid(); err != nil {
+		return err
+	}
+
+	return s.kv.Update(ctx, func(tx Tx) error {
+		if _, err := s.findOrganizationByID(ctx, tx, r.OrgID); err != nil {
+			return err
+		}
+		if err := s.uniqueRoleName(ctx, tx, r); err != nil {
+			return err
+		}
+
+		r.ID = s.IDGenerator.ID()
+		now := s.TimeGenerator.Now()
+		r.SetCreatedAt(now)
+		r.SetUpdatedAt(now)
+		return s.putRole(ctx, tx, r)
+	})
+}
+
+func (s *Service) uniqueRoleName(ctx context.Context, tx Tx, r *influxdb.Role) error {
+	rs, err := s.findRoles(ctx, tx, influxdb.RoleFilter{OrgID: &r.OrgID, Name: &r.Name})
+	if err != nil {
+		return err
+	}
+	for _, other := range rs {
+		if other.ID != r.ID {
+			return &influxdb.Error{
+				Code: influxdb.EConflict,
+				Msg:  fmt.Sprintf("role with name %s already exists", r.Name),
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Service) putRole(ctx context.Context, tx Tx, r *influxdb.Role) error {
+	v, err := json.Marshal(r)
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInternal,
+			Err:  err,
+		}
+	}
+
+	encodedID, err := r.ID.Encode()
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(roleBucket)
+	if err != nil {
+		return err
+	}
+	if err := b.Put(encodedID, v); err != nil {
+		return &influxdb.Error{
+			Err: err,
+		}
+	}
+	return nil
+}
+
+// UpdateRole updates a single role with a changeset. The authorizations and
+// sessions of the assignees of the role are allowed its new permissions from
+// then on.
+func (s *Service) UpdateRole(ctx context.Context, id influxdb.ID, upd influxdb.RoleUpdate) (*influxdb.Role, error) {
+	var r *influxdb.Role
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		role, err := s.findRoleByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err := upd.Apply(role); err != nil {
+			return err
+		}
+		if err := s.uniqueRoleName(ctx, tx, role); err != nil {
+			return err
+		}
+
+		role.SetUpdatedAt(s.TimeGenerator.Now())
+		if err := s.putRole(ctx, tx, role); err != nil {
+			return err
+		}
+		r = role
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// DeleteRole removes a role by ID, and unassigns it from its users and
+// authorizations.
+func (s *Service) DeleteRole(ctx context.Context, id influxdb.ID) error {
+	return s.kv.Update(ctx, func(tx Tx) error {
+		if _, err := s.findRoleByID(ctx, tx, id); err != nil {
+			return err
+		}
+
+		userIDs, err := s.findRoleUsers(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		for _, userID := range userIDs {
+			if err := s.removeRoleUser(ctx, tx, id, userID); err != nil {
+				return err
+			}
+		}
+
+		var as []*influxdb.Authorization
+		err = s.forEachAuthorization(ctx, tx, nil, func(a *influxdb.Authorization) bool {
+			for _, roleID := range a.RoleIDs {
+				if roleID == id {
+					as = append(as, a)
+					break
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		for _, a := range as {
+			roleIDs := a.RoleIDs[:0]
+			for _, roleID := range a.RoleIDs {
+				if roleID != id {
+					roleIDs = append(roleIDs, roleID)
+				}
+			}
+			a.RoleIDs = roleIDs
+			if err := s.putAuthorization(ctx, tx, a); err != nil {
+				return err
+			}
+		}
+
+		encodedID, err := id.Encode()
+		if err != nil {
+			return &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Err:  err,
+			}
+		}
+		b, err := tx.Bucket(roleBucket)
+		if err != nil {
+			return err
+		}
+		if err := b.Delete(encodedID); err != nil {
+			return &influxdb.Error{
+				Err: err,
+			}
+		}
+		return nil
+	})
+}
+
+// userRoleKey is the key of the assignment of a role to a user, prefixed by
+// the user so that the roles of a user are found with a prefix scan.
+func userRoleKey(userID, roleID influxdb.ID) ([]byte, error) {
+	u, err := userID.Encode()
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+	r, err := roleID.Encode()
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+	return append(u, r...), nil
+}
+
+// FindRoleUsers returns the IDs of the users the role is assigned to.
+func (s *Service) FindRoleUsers(ctx context.Context, roleID influxdb.ID) ([]influxdb.ID, error) {
+	var ids []influxdb.ID
+	err := s.kv.View(ctx, func(tx Tx) error {
+		if _, err := s.findRoleByID(ctx, tx, roleID); err != nil {
+			return err
+		}
+		userIDs, err := s.findRoleUsers(ctx, tx, roleID)
+		if err != nil {
+			return err
+		}
+		ids = userIDs
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (s *Service) findRoleUsers(ctx context.Context, tx Tx, roleID influxdb.ID) ([]influxdb.ID, error) {
+	encodedID, err := roleID.Encode()
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(userRoleBucket)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := b.Cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []influxdb.ID{}
+	for k, _ := cur.First(); k != nil; k, _ = cur.Next() {
+		if len(k) != 2*influxdb.IDLength || !bytes.Equal(k[influxdb.IDLength:], encodedID) {
+			continue
+		}
+		var id influxdb.ID
+		if err := id.Decode(k[:influxdb.IDLength]); err != nil {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInternal,
+				Err:  err,
+			}
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// findUserRoleIDs returns the IDs of the roles assigned to the user.
+func (s *Service) findUserRoleIDs(ctx context.Context, tx Tx, userID influxdb.ID) ([]influxdb.ID, error) {
+	prefix, err := userID.Encode()
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(userRoleBucket)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := b.ForwardCursor(prefix, WithCursorPrefix(prefix))
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []influxdb.ID
+	for k, _ := cur.Next(); k != nil; k, _ = cur.Next() {
+		var id influxdb.ID
+		if err := id.Decode(k[influxdb.IDLength:]); err != nil {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInternal,
+				Err:  err,
+			}
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// AddRoleUser assigns the role to the user.
+func (s *Service) AddRoleUser(ctx context.Context, roleID, userID influxdb.ID) error {
+	return s.kv.Update(ctx, func(tx Tx) error {
+		if _, err := s.findRoleByID(ctx, tx, roleID); err != nil {
+			return err
+		}
+		if _, err := s.findUserByID(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		key, err := userRoleKey(userID, roleID)
+		if err != nil {
+			return err
+		}
+		b, err := tx.Bucket(userRoleBucket)
+		if err != nil {
+			return err
+		}
+		if err := b.Put(key, key[influxdb.IDLength:]); err != nil {
+			return &influxdb.Error{
+				Err: err,
+			}
+		}
+		return nil
+	})
+}
+
+// RemoveRoleUser unassigns the role from the user.
+func (s *Service) RemoveRoleUser(ctx context.Context, roleID, userID influxdb.ID) error {
+	return s.kv.Update(ctx, func(tx Tx) error {
+		return s.removeRoleUser(ctx, tx, roleID, userID)
+	})
+}
+
+func (s *Service) removeRoleUser(ctx context.Context, tx Tx, roleID, userID influxdb.ID) error {
+	key, err := userRoleKey(userID, roleID)
+	if err != nil {
+		return err
+	}
+	b, err := tx.Bucket(userRoleBucket)
+	if err != nil {
+		return err
+	}
+	if _, err := b.Get(key); IsNotFound(err) {
+		return &influxdb.Error{
+			Code: influxdb.ENotFound,
+			Msg:  "role is not assigned to user",
+		}
+	} else if err != nil {
+		return err
+	}
+	if err := b.Delete(key); err != nil {
+		return &influxdb.Error{
+			Err: err,
+		}
+	}
+	return nil
+}
+
+// deleteUserRoles unassigns all the roles of the user.
+func (s *Service) deleteUserRoles(ctx context.Context, tx Tx, userID influxdb.ID) error {
+	ids, err := s.findUserRoleIDs(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := s.removeRoleUser(ctx, tx, id, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rolePermissions returns the permissions of the roles ids of the
+// organization orgID. Roles deleted since they were assigned are skipped.
+func (s *Service) rolePermissions(ctx context.Context, tx Tx, orgID influxdb.ID, ids []influxdb.ID) ([]influxdb.Permission, error) {
+	var ps []influxdb.Permission
+	for _, id := range ids {
+		r, err := s.findRoleByID(ctx, tx, id)
+		if influxdb.ErrorCode(err) == influxdb.ENotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if r.OrgID != orgID {
+			continue
+		}
+		ps = append(ps, r.Permissions...)
+	}
+	return ps, nil
+}
+
+// validAuthorizationRoles returns an error if a role of the authorization
+// does not exist or is not of its organization.
+func (s *Service) validAuthorizationRoles(ctx context.Context, tx Tx, a *influxdb.Authorization) error {
+	for _, id := range a.RoleIDs {
+		r, err := s.findRoleByID(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if r.OrgID != a.OrgID {
+			return &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  fmt.Sprintf("role %s is not for org id %s", id, a.OrgID),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kv"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestService_Roles(t *testing.T) {
+	s, closeBolt, err := NewTestBoltStore(t)
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+	defer closeBolt()
+
+	ctx := context.Background()
+	svc := kv.NewService(zaptest.NewLogger(t), s)
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatalf("error initializing role service: %v", err)
+	}
+
+	org := &influxdb.Organization{Name: "org"}
+	if err := svc.CreateOrganization(ctx, org); err != nil {
+		t.Fatalf("unexpected error creating organization: %v", err)
+	}
+	user := &influxdb.User{Name: "user"}
+	if err := svc.CreateUser(ctx, user); err != nil {
+		t.Fatalf("unexpected error creating user: %v", err)
+	}
+
+	readBuckets, err := influxdb.NewPermission(influxdb.ReadAction, influxdb.BucketsResourceType, org.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeBuckets, err := influxdb.NewPermission(influxdb.WriteAction, influxdb.BucketsResourceType, org.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	role := &influxdb.Role{
+		OrgID:       org.ID,
+		Name:        "reader",
+		Permissions: []influxdb.Permission{*readBuckets},
+	}
+	if err := svc.CreateRole(ctx, role); err != nil {
+		t.Fatalf("unexpected error creating role: %v", err)
+	}
+	if err := svc.CreateRole(ctx, &influxdb.Role{OrgID: org.ID, Name: "reader"}); influxdb.ErrorCode(err) != influxdb.EConflict {
+		t.Fatalf("expected conflict error creating a role with the same name, got %v", err)
+	}
+	other := influxdb.ID(1)
+	if err := svc.CreateRole(ctx, &influxdb.Role{
+		OrgID:       org.ID,
+		Name:        "other",
+		Permissions: []influxdb.Permission{{Action: influxdb.ReadAction, Resource: influxdb.Resource{Type: influxdb.BucketsResourceType, OrgID: &other}}},
+	}); influxdb.ErrorCode(err) != influxdb.EInvalid {
+		t.Fatalf("expected invalid error creating a role with a permission of another org, got %v", err)
+	}
+
+	auth := &influxdb.Authorization{
+		OrgID:   org.ID,
+		UserID:  user.ID,
+		RoleIDs: []influxdb.ID{role.ID},
+	}
+	if err := svc.CreateAuthorization(ctx, auth); err != nil {
+		t.Fatalf("unexpected error creating authorization: %v", err)
+	}
+	if err := svc.AddRoleUser(ctx, role.ID, user.ID); err != nil {
+		t.Fatalf("unexpected error assigning role: %v", err)
+	}
+	sn, err := svc.CreateSession(ctx, user.Name)
+	if err != nil {
+		t.Fatalf("unexpected error creating session: %v", err)
+	}
+
+	// allowed returns whether the authorization and the session of the user
+	// are allowed p, as they are found when used.
+	allowed := func(p *influxdb.Permission) (bool, bool) {
+		t.Helper()
+		a, err := svc.FindAuthorizationByToken(ctx, auth.Token)
+		if err != nil {
+			t.Fatalf("unexpected error finding authorization: %v", err)
+		}
+		sn, err := svc.FindSession(ctx, sn.Key)
+		if err != nil {
+			t.Fatalf("unexpected error finding session: %v", err)
+		}
+		return a.Allowed(*p), sn.Allowed(*p)
+	}
+
+	if a, s := allowed(readBuckets); !a || !s {
+		t.Fatalf("expected the role to allow reading buckets, got authorization %v and session %v", a, s)
+	}
+	if a, s := allowed(writeBuckets); a || s {
+		t.Fatalf("expected the role not to allow writing buckets, got authorization %v and session %v", a, s)
+	}
+
+	if _, err := svc.UpdateRole(ctx, role.ID, influxdb.RoleUpdate{
+		Permissions: []influxdb.Permission{*readBuckets, *writeBuckets},
+	}); err != nil {
+		t.Fatalf("unexpected error updating role: %v", err)
+	}
+	if a, s := allowed(writeBuckets); !a || !s {
+		t.Fatalf("expected the updated role to allow writing buckets, got authorization %v and session %v", a, s)
+	}
+
+	rs, _, err := svc.FindRoles(ctx, influxdb.RoleFilter{UserID: &user.ID})
+	if err != nil {
+		t.Fatalf("unexpected error finding roles: %v", err)
+	}
+	if len(rs) != 1 || rs[0].ID != role.ID {
+		t.Fatalf("unexpected roles of user: %v", rs)
+	}
+	userIDs, err := svc.FindRoleUsers(ctx, role.ID)
+	if err != nil {
+		t.Fatalf("unexpected error finding role users: %v", err)
+	}
+	if len(userIDs) != 1 || userIDs[0] != user.ID {
+		t.Fatalf("unexpected users of role: %v", userIDs)
+	}
+
+	if err := svc.DeleteRole(ctx, role.ID); err != nil {
+		t.Fatalf("unexpected error deleting role: %v", err)
+	}
+	if a, s := allowed(readBuckets); a || s {
+		t.Fatalf("expected the deleted role not to allow reading buckets, got authorization %v and session %v", a, s)
+	}
+	a, err := svc.FindAuthorizationByID(ctx, auth.ID)
+	if err != nil {
+		t.Fatalf("unexpected error finding authorization: %v", err)
+	}
+	if len(a.RoleIDs) != 0 {
+		t.Fatalf("expected the deleted role to be unassigned from the authorization, got %v", a.RoleIDs)
+	}
+	if err := svc.RemoveRoleUser(ctx, role.ID, user.ID); influxdb.ErrorCode(err) != influxdb.ENotFound {
+		t.Fatalf("expected not found error unassigning a deleted role, got %v", err)
+	}
+}
@@ -141,6 +141,14 @@ func (s *Service) Initialize(ctx context.Context) error {
 			return err
 		}
 
+		if err := s.initializeConstants(ctx, tx); err != nil {
+			return err
+		}
+
+		if err := s.initializeRoles(ctx, tx); err != nil {
+			return err
+		}
+
 		if err := s.initializeSessions(ctx, tx); err != nil {
 			return err
 		}
@@ -133,6 +133,15 @@ func (s *Service) maxPermissions(ctx context.Context, tx Tx, userID influxdb.ID)
 	}
 	for _, a := range as {
 		ps = append(ps, a.Permissions...)
+		ps = append(ps, a.RolePermissions...)
+	}
+
+	rs, err := s.findRoles(ctx, tx, influxdb.RoleFilter{UserID: &userID})
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rs {
+		ps = append(ps, r.Permissions...)
 	}
 
 	return ps, nil
@@ -56,6 +56,7 @@ type kvTask struct {
 	CreatedAt       time.Time              `json:"createdAt,omitempty"`
 	UpdatedAt       time.Time              `json:"updatedAt,omitempty"`
 	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+	DependsOn       []influxdb.ID          `json:"dependsOn,omitempty"`
 }
 
 func kvToInfluxTask(k *kvTask) *influxdb.Task {
@@ -79,6 +80,7 @@ func kvToInfluxTask(k *kvTask) *influxdb.Task {
 		CreatedAt:       k.CreatedAt,
 		UpdatedAt:       k.UpdatedAt,
 		Metadata:        k.Metadata,
+		DependsOn:       k.DependsOn,
 	}
 }
 
@@ -489,6 +491,23 @@ func newTaskMatchFn(f influxdb.TaskFilter, org *influxdb.Organization) func(t *i
 		}
 	}
 
+	if f.DependsOn != nil {
+		expected := *f.DependsOn
+		prevFn := fn
+		fn = func(t *influxdb.Task) bool {
+			res := prevFn == nil || prevFn(t)
+			if !res {
+				return false
+			}
+			for _, id := range t.DependsOn {
+				if id == expected {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
 	return fn
 }
 
@@ -622,6 +641,13 @@ func (s *Service) createTask(ctx context.Context, tx Tx, tc influxdb.TaskCreate)
 		LatestScheduled: createdAt,
 	}
 
+	if len(tc.DependsOn) > 0 {
+		if err := s.validateTaskDependencies(ctx, tx, task, tc.DependsOn); err != nil {
+			return nil, err
+		}
+		task.DependsOn = tc.DependsOn
+	}
+
 	if opt.Offset != nil {
 		off, err := time.ParseDuration(opt.Offset.String())
 		if err != nil {
@@ -672,6 +698,10 @@ func (s *Service) createTask(ctx context.Context, tx Tx, tc influxdb.TaskCreate)
 		s.log.Info("Error creating user resource mapping for task", zap.Stringer("taskID", task.ID), zap.Error(err))
 	}
 
+	if err := s.appendDefinitionVersion(ctx, tx, influxdb.TasksResourceType, task.ID, task.Flux); err != nil {
+		return nil, err
+	}
+
 	// populate permissions so the task can be used immediately
 	// if we cant populate here we shouldn't error.
 	ps, _ := s.maxPermissions(ctx, tx, task.OwnerID)
@@ -789,6 +819,17 @@ func (s *Service) updateTask(ctx context.Context, tx Tx, id influxdb.ID, upd inf
 		task.UpdatedAt = updatedAt
 	}
 
+	if upd.DependsOn != nil {
+		if err := s.validateTaskDependencies(ctx, tx, task, *upd.DependsOn); err != nil {
+			return nil, err
+		}
+		task.DependsOn = *upd.DependsOn
+		if len(task.DependsOn) == 0 {
+			task.DependsOn = nil
+		}
+		task.UpdatedAt = updatedAt
+	}
+
 	if upd.LatestCompleted != nil {
 		// make sure we only update latest completed one way
 		tlc := task.LatestCompleted
@@ -835,6 +876,12 @@ func (s *Service) updateTask(ctx context.Context, tx Tx, id influxdb.ID, upd inf
 		return nil, influxdb.ErrUnexpectedTaskBucketErr(err)
 	}
 
+	if upd.Flux != nil {
+		if err := s.appendDefinitionVersion(ctx, tx, influxdb.TasksResourceType, task.ID, task.Flux); err != nil {
+			return nil, err
+		}
+	}
+
 	uid, _ := icontext.GetUserID(ctx)
 	if err := s.audit.Log(resource.Change{
 		Type:           resource.Update,
@@ -851,6 +898,50 @@ func (s *Service) updateTask(ctx context.Context, tx Tx, id influxdb.ID, upd inf
 	return task, nil
 }
 
+// validateTaskDependencies checks that the tasks the task depends on exist in
+// its organization, and that none of them depends on the task, directly or
+// through other tasks.
+func (s *Service) validateTaskDependencies(ctx context.Context, tx Tx, task *influxdb.Task, deps []influxdb.ID) error {
+	for _, id := range deps {
+		if id == task.ID {
+			return influxdb.ErrTaskDependencyCycle
+		}
+		dep, err := s.findTaskByID(ctx, tx, id)
+		if err == influxdb.ErrTaskNotFound {
+			return influxdb.ErrInvalidTaskDependency(id)
+		} else if err != nil {
+			return err
+		}
+		if dep.OrganizationID != task.OrganizationID {
+			return influxdb.ErrInvalidTaskDependency(id)
+		}
+	}
+
+	// walk the dependencies upstream, looking for the task.
+	visited := make(map[influxdb.ID]bool)
+	next := append([]influxdb.ID(nil), deps...)
+	for len(next) > 0 {
+		id := next[len(next)-1]
+		next = next[:len(next)-1]
+		if id == task.ID {
+			return influxdb.ErrTaskDependencyCycle
+		}
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+
+		t, err := s.findTaskByID(ctx, tx, id)
+		if err == influxdb.ErrTaskNotFound {
+			continue
+		} else if err != nil {
+			return err
+		}
+		next = append(next, t.DependsOn...)
+	}
+	return nil
+}
+
 // DeleteTask removes a task by ID and purges all associated data and scheduled runs.
 func (s *Service) DeleteTask(ctx context.Context, id influxdb.ID) error {
 	err := s.kv.Update(ctx, func(tx Tx) error {
@@ -889,6 +980,19 @@ func (s *Service) deleteTask(ctx context.Context, tx Tx, id influxdb.ID) error {
 		return err
 	}
 
+	// the tasks depending on the task would never run again.
+	dependents, _, err := s.findTasksByOrg(ctx, tx, influxdb.TaskFilter{
+		OrganizationID: &task.OrganizationID,
+		DependsOn:      &task.ID,
+		Limit:          1,
+	})
+	if err != nil && influxdb.ErrorCode(err) != influxdb.ENotFound {
+		return err
+	}
+	if len(dependents) > 0 {
+		return influxdb.ErrTaskHasDependents
+	}
+
 	// remove the orgs index
 	orgKey, err := taskOrgKey(task.OrganizationID, task.ID)
 	if err != nil {
@@ -941,6 +1045,10 @@ func (s *Service) deleteTask(ctx context.Context, tx Tx, id influxdb.ID) error {
 		s.log.Info("Error deleting user resource mapping for task", zap.Stringer("taskID", task.ID), zap.Error(err))
 	}
 
+	if err := s.deleteDefinitionHistory(ctx, tx, influxdb.TasksResourceType, task.ID); err != nil {
+		return err
+	}
+
 	uid, _ := icontext.GetUserID(ctx)
 	return s.audit.Log(resource.Change{
 		Type:           resource.Delete,
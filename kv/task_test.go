@@ -239,6 +239,74 @@ func TestService_UpdateTask_InactiveToActive(t *testing.T) {
 	}
 }
 
+func TestService_TaskDependencies(t *testing.T) {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+
+	ts := newService(t, ctx, nil)
+	defer ts.Close()
+
+	ctx = icontext.SetAuthorizer(ctx, &ts.Auth)
+
+	create := func(name string, deps ...influxdb.ID) (*influxdb.Task, error) {
+		return ts.Service.CreateTask(ctx, influxdb.TaskCreate{
+			Flux:           `option task = {name: "` + name + `", every: 1h} from(bucket:"test") |> range(start:-1h)`,
+			OrganizationID: ts.Org.ID,
+			OwnerID:        ts.User.ID,
+			DependsOn:      deps,
+		})
+	}
+
+	a, err := create("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := create("b", a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := create("c", b.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := c.DependsOn, []influxdb.ID{b.ID}; !cmp.Equal(got, exp) {
+		t.Fatalf("unexpected dependencies -got/+exp\n%s", cmp.Diff(got, exp))
+	}
+
+	// a task can not depend on an unknown task.
+	if _, err := create("d", influxdb.ID(0xbad)); influxdb.ErrorCode(err) != influxdb.EInvalid {
+		t.Fatalf("expected invalid dependency error, got %v", err)
+	}
+
+	// a depending on c would make a cycle.
+	deps := []influxdb.ID{c.ID}
+	if _, err := ts.Service.UpdateTask(ctx, a.ID, influxdb.TaskUpdate{DependsOn: &deps}); err != influxdb.ErrTaskDependencyCycle {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+
+	dependents, _, err := ts.Service.FindTasks(ctx, influxdb.TaskFilter{OrganizationID: &ts.Org.ID, DependsOn: &a.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dependents) != 1 || dependents[0].ID != b.ID {
+		t.Fatalf("unexpected dependents of a: %v", dependents)
+	}
+
+	// b can not be deleted while c depends on it.
+	if err := ts.Service.DeleteTask(ctx, b.ID); err != influxdb.ErrTaskHasDependents {
+		t.Fatalf("expected dependents error, got %v", err)
+	}
+	deps = []influxdb.ID{}
+	if c, err = ts.Service.UpdateTask(ctx, c.ID, influxdb.TaskUpdate{DependsOn: &deps}); err != nil {
+		t.Fatal(err)
+	} else if c.DependsOn != nil {
+		t.Fatalf("expected dependencies to be removed, got %v", c.DependsOn)
+	}
+	if err := ts.Service.DeleteTask(ctx, b.ID); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestTaskRunCancellation(t *testing.T) {
 	store, close, err := NewTestBoltStore(t)
 	if err != nil {
@@ -0,0 +1,57 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	"github.com/influxdata/influxdb"
+)
+
+var _ influxdb.CompactionService = &CompactionService{}
+
+// CompactionService is a mock compaction service.
+type CompactionService struct {
+	PauseCompactionsF  func(ctx context.Context, timeout time.Duration) error
+	ResumeCompactionsF func(ctx context.Context) error
+	CompactionStatusF  func(ctx context.Context) (*influxdb.CompactionStatus, error)
+	CompactionReportF  func(ctx context.Context) (*influxdb.CompactionReport, error)
+}
+
+// NewCompactionService returns a mock CompactionService where its methods will return
+// zero values.
+func NewCompactionService() *CompactionService {
+	return &CompactionService{
+		PauseCompactionsF: func(ctx context.Context, timeout time.Duration) error {
+			return nil
+		},
+		ResumeCompactionsF: func(ctx context.Context) error {
+			return nil
+		},
+		CompactionStatusF: func(ctx context.Context) (*influxdb.CompactionStatus, error) {
+			return &influxdb.CompactionStatus{}, nil
+		},
+		CompactionReportF: func(ctx context.Context) (*influxdb.CompactionReport, error) {
+			return &influxdb.CompactionReport{}, nil
+		},
+	}
+}
+
+// PauseCompactions calls PauseCompactionsF.
+func (s *CompactionService) PauseCompactions(ctx context.Context, timeout time.Duration) error {
+	return s.PauseCompactionsF(ctx, timeout)
+}
+
+// ResumeCompactions calls ResumeCompactionsF.
+func (s *CompactionService) ResumeCompactions(ctx context.Context) error {
+	return s.ResumeCompactionsF(ctx)
+}
+
+// CompactionStatus calls CompactionStatusF.
+func (s *CompactionService) CompactionStatus(ctx context.Context) (*influxdb.CompactionStatus, error) {
+	return s.CompactionStatusF(ctx)
+}
+
+// CompactionReport calls CompactionReportF.
+func (s *CompactionService) CompactionReport(ctx context.Context) (*influxdb.CompactionReport, error) {
+	return s.CompactionReportF(ctx)
+}
@@ -0,0 +1,29 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+)
+
+var _ influxdb.BucketCopyService = &BucketCopyService{}
+
+// BucketCopyService is a mock bucket copy service.
+type BucketCopyService struct {
+	CopyBucketRangeF func(ctx context.Context, src, dst influxdb.BucketCopyTarget, min, max int64) (*influxdb.BucketCopyStats, error)
+}
+
+// NewBucketCopyService returns a mock BucketCopyService where its methods will return
+// zero values.
+func NewBucketCopyService() *BucketCopyService {
+	return &BucketCopyService{
+		CopyBucketRangeF: func(ctx context.Context, src, dst influxdb.BucketCopyTarget, min, max int64) (*influxdb.BucketCopyStats, error) {
+			return &influxdb.BucketCopyStats{}, nil
+		},
+	}
+}
+
+// CopyBucketRange calls CopyBucketRangeF.
+func (s *BucketCopyService) CopyBucketRange(ctx context.Context, src, dst influxdb.BucketCopyTarget, min, max int64) (*influxdb.BucketCopyStats, error) {
+	return s.CopyBucketRangeF(ctx, src, dst, min, max)
+}
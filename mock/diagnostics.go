@@ -0,0 +1,38 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+)
+
+var _ influxdb.DiagnosticsService = &DiagnosticsService{}
+
+// DiagnosticsService is a mock diagnostics service.
+type DiagnosticsService struct {
+	ConfigF   func(ctx context.Context) ([]influxdb.ConfigOption, error)
+	QueryLogF func(ctx context.Context) (*influxdb.QueryLog, error)
+}
+
+// NewDiagnosticsService returns a mock DiagnosticsService where its methods will return
+// zero values.
+func NewDiagnosticsService() *DiagnosticsService {
+	return &DiagnosticsService{
+		ConfigF: func(ctx context.Context) ([]influxdb.ConfigOption, error) {
+			return nil, nil
+		},
+		QueryLogF: func(ctx context.Context) (*influxdb.QueryLog, error) {
+			return &influxdb.QueryLog{}, nil
+		},
+	}
+}
+
+// Config calls ConfigF.
+func (s *DiagnosticsService) Config(ctx context.Context) ([]influxdb.ConfigOption, error) {
+	return s.ConfigF(ctx)
+}
+
+// QueryLog calls QueryLogF.
+func (s *DiagnosticsService) QueryLog(ctx context.Context) (*influxdb.QueryLog, error) {
+	return s.QueryLogF(ctx)
+}
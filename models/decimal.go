@@ -0,0 +1,122 @@
+package models
+
+import (
+	"errors"
+	"strconv"
+)
+
+// MaxDecimalScale is the maximum number of fractional digits of a decimal.
+const MaxDecimalScale = 18
+
+// ErrInvalidDecimal is returned when parsing a malformed decimal value, or
+// one that does not fit a FixedDecimal.
+var ErrInvalidDecimal = errors.New("invalid decimal")
+
+// FixedDecimal is a fixed-precision decimal number, equal to Value×10^-Scale.
+// It represents values such as prices exactly, which floats can not. The
+// scale of a parsed decimal is its number of fractional digits, so that
+// trailing zeros are preserved.
+//
+// Decimal fields are written in line protocol with a d suffix, such as
+// price=12.50d, if the parser accepts them with WithParserDecimals. As neither
+// the storage engine nor Flux have a decimal type, they are stored as strings
+// holding their exact representation. Queries return them as string values,
+// in columns of type string, and the decimal type of the field is not kept.
+type FixedDecimal struct {
+	Value int64
+	Scale uint8
+}
+
+// ParseDecimal parses a decimal written as an optional minus sign followed by
+// digits, with an optional decimal point, without the d suffix of line
+// protocol. It returns ErrInvalidDecimal if the value has more than
+// MaxDecimalScale fractional digits or more significant digits than fit in
+// an int64.
+func ParseDecimal(b []byte) (FixedDecimal, error) {
+	var (
+		d        FixedDecimal
+		neg      bool
+		point    bool
+		digits   int
+		overflow bool
+		v        uint64
+	)
+	if len(b) > 0 && b[0] == '-' {
+		neg, b = true, b[1:]
+	}
+	for _, c := range b {
+		if c == '.' {
+			if point {
+				return FixedDecimal{}, ErrInvalidDecimal
+			}
+			point = true
+			continue
+		}
+		if c < '0' || c > '9' {
+			return FixedDecimal{}, ErrInvalidDecimal
+		}
+		digits++
+		if point {
+			if d.Scale == MaxDecimalScale {
+				return FixedDecimal{}, ErrInvalidDecimal
+			}
+			d.Scale++
+		}
+		if v > (1<<63)/10 {
+			overflow = true
+		}
+		v = v*10 + uint64(c-'0')
+		if v > 1<<63 {
+			overflow = true
+		}
+	}
+	if digits == 0 || overflow || (!neg && v == 1<<63) {
+		return FixedDecimal{}, ErrInvalidDecimal
+	}
+
+	d.Value = int64(v)
+	if neg {
+		d.Value = -d.Value
+	}
+	return d, nil
+}
+
+// String returns the decimal with exactly Scale fractional digits.
+func (d FixedDecimal) String() string {
+	return string(d.AppendTo(nil))
+}
+
+// AppendTo appends the decimal, as returned by String, to b.
+func (d FixedDecimal) AppendTo(b []byte) []byte {
+	if d.Value < 0 {
+		b = append(b, '-')
+	}
+	v := uint64(d.Value)
+	if d.Value < 0 {
+		v = -v
+	}
+
+	var buf [21]byte
+	digits := strconv.AppendUint(buf[:0], v, 10)
+	if d.Scale == 0 {
+		return append(b, digits...)
+	}
+
+	scale := int(d.Scale)
+	if len(digits) <= scale {
+		b = append(b, '0', '.')
+		for i := len(digits); i < scale; i++ {
+			b = append(b, '0')
+		}
+		return append(b, digits...)
+	}
+	b = append(b, digits[:len(digits)-scale]...)
+	b = append(b, '.')
+	return append(b, digits[len(digits)-scale:]...)
+}
+
+// Float returns the nearest float to the decimal.
+func (d FixedDecimal) Float() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
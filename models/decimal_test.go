@@ -0,0 +1,51 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		in  string
+		exp models.FixedDecimal
+		str string
+		err bool
+	}{
+		{in: "12.50", exp: models.FixedDecimal{Value: 1250, Scale: 2}, str: "12.50"},
+		{in: "-0.001", exp: models.FixedDecimal{Value: -1, Scale: 3}, str: "-0.001"},
+		{in: "42", exp: models.FixedDecimal{Value: 42}, str: "42"},
+		{in: ".5", exp: models.FixedDecimal{Value: 5, Scale: 1}, str: "0.5"},
+		{in: "007.", exp: models.FixedDecimal{Value: 7}, str: "7"},
+		{in: "9223372036854775807", exp: models.FixedDecimal{Value: 9223372036854775807}, str: "9223372036854775807"},
+		{in: "-9.223372036854775808", exp: models.FixedDecimal{Value: -9223372036854775808, Scale: 18}, str: "-9.223372036854775808"},
+		{in: "0.000000000000000001", exp: models.FixedDecimal{Value: 1, Scale: 18}, str: "0.000000000000000001"},
+		{in: "9223372036854775808", err: true},
+		{in: "0.0000000000000000001", err: true},
+		{in: "1.2.3", err: true},
+		{in: "1e5", err: true},
+		{in: "--1", err: true},
+		{in: "-", err: true},
+		{in: ".", err: true},
+		{in: "", err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			d, err := models.ParseDecimal([]byte(test.in))
+			if got, exp := err != nil, test.err; got != exp {
+				t.Fatalf("unexpected error state; got %v, exp %v: %v", got, exp, err)
+			}
+			if test.err {
+				return
+			}
+			if d != test.exp {
+				t.Errorf("unexpected decimal; got %#v, exp %#v", d, test.exp)
+			}
+			if got := d.String(); got != test.str {
+				t.Errorf("unexpected string; got %q, exp %q", got, test.str)
+			}
+		})
+	}
+}
@@ -181,6 +181,9 @@ const (
 
 	// Unsigned indicates the field's type is an unsigned integer.
 	Unsigned
+
+	// Decimal indicates the field's type is a fixed-precision decimal.
+	Decimal
 )
 
 func (t FieldType) String() string {
@@ -197,6 +200,8 @@ func (t FieldType) String() string {
 		return "Empty"
 	case Unsigned:
 		return "Unsigned"
+	case Decimal:
+		return "Decimal"
 	default:
 		return "<unknown>"
 	}
@@ -222,6 +227,9 @@ type FieldIterator interface {
 	// UnsignedValue returns the unsigned value of the current field.
 	UnsignedValue() (uint64, error)
 
+	// DecimalValue returns the decimal value of the current field.
+	DecimalValue() (FixedDecimal, error)
+
 	// BooleanValue returns the boolean value of the current field.
 	BooleanValue() (bool, error)
 
@@ -360,6 +368,30 @@ func ParseName(buf []byte) []byte {
 	return UnescapeMeasurement(name)
 }
 
+// PrecisionAuto is the parser precision detecting the precision of each
+// timestamp from its magnitude, with DetectPrecision.
+const PrecisionAuto = "auto"
+
+// DetectPrecision returns the precision of a Unix timestamp from its
+// magnitude, assuming that it is a time between March 1973 and the year
+// 5138: timestamps below 1e11 are seconds, below 1e14 milliseconds, below
+// 1e17 microseconds, and nanoseconds otherwise.
+func DetectPrecision(ts int64) string {
+	if ts < 0 {
+		ts = -ts
+	}
+	switch {
+	case ts < 1e11:
+		return "s"
+	case ts < 1e14:
+		return "ms"
+	case ts < 1e17:
+		return "us"
+	default:
+		return "ns"
+	}
+}
+
 // ValidPrecision checks if the precision is known.
 func ValidPrecision(precision string) bool {
 	switch precision {
@@ -731,6 +763,13 @@ func scanFields(buf []byte, i int) (int, []byte, error) {
 				return i, buf[start:i], fmt.Errorf("missing field value")
 			}
 
+			// NaN and ±Inf are scanned as floats, whether they are accepted
+			// is determined by the parser's NonFiniteFloatPolicy.
+			if n := scanNonFiniteFloat(buf, i+1); n > 0 {
+				i += 1 + n
+				continue
+			}
+
 			if isNumeric(buf[i+1]) || buf[i+1] == '-' || buf[i+1] == 'N' || buf[i+1] == 'n' {
 				var err error
 				i, err = scanNumber(buf, i+1)
@@ -816,7 +855,7 @@ func isNumeric(b byte) bool {
 // error if a invalid number is scanned.
 func scanNumber(buf []byte, i int) (int, error) {
 	start := i
-	var isInt, isUnsigned bool
+	var isInt, isUnsigned, isDecimal bool
 
 	// Is negative number?
 	if i < len(buf) && buf[i] == '-' {
@@ -842,14 +881,18 @@ func scanNumber(buf []byte, i int) (int, error) {
 			break
 		}
 
-		if buf[i] == 'i' && i > start && !(isInt || isUnsigned) {
+		if buf[i] == 'i' && i > start && !(isInt || isUnsigned || isDecimal) {
 			isInt = true
 			i++
 			continue
-		} else if buf[i] == 'u' && i > start && !(isInt || isUnsigned) {
+		} else if buf[i] == 'u' && i > start && !(isInt || isUnsigned || isDecimal) {
 			isUnsigned = true
 			i++
 			continue
+		} else if buf[i] == 'd' && i > start && !(isInt || isUnsigned || isDecimal) {
+			isDecimal = true
+			i++
+			continue
 		}
 
 		if buf[i] == '.' {
@@ -887,9 +930,12 @@ func scanNumber(buf []byte, i int) (int, error) {
 	if (isInt || isUnsigned) && (decimal || scientific) {
 		return i, ErrInvalidNumber
 	}
+	if isDecimal && scientific {
+		return i, ErrInvalidNumber
+	}
 
 	numericDigits := i - start
-	if isInt {
+	if isInt || isDecimal {
 		numericDigits--
 	}
 	if decimal {
@@ -935,6 +981,16 @@ func scanNumber(buf []byte, i int) (int, error) {
 				return i, fmt.Errorf("unable to parse unsigned %s: %s", buf[start:i-1], err)
 			}
 		}
+	} else if isDecimal {
+		// Make sure the last char is a 'd' for decimals
+		if buf[i-1] != 'd' {
+			return i, ErrInvalidNumber
+		}
+		// The digits and scale are always checked, as a decimal is only
+		// valid if it fits a Decimal.
+		if _, err := ParseDecimal(buf[start : i-1]); err != nil {
+			return i, fmt.Errorf("unable to parse decimal %s: %s", buf[start:i-1], err)
+		}
 	} else {
 		// Parse the float to check bounds if it's scientific or the number of digits could be larger than the max range
 		if scientific || len(buf[start:i]) >= maxFloat64Digits || len(buf[start:i]) >= minFloat64Digits {
@@ -947,6 +1003,34 @@ func scanNumber(buf []byte, i int) (int, error) {
 	return i, nil
 }
 
+// nonFiniteFloats are the float literals representing NaN and ±Inf.
+var nonFiniteFloats = [][]byte{[]byte("nan"), []byte("inf"), []byte("-inf")}
+
+// scanNonFiniteFloat returns the length of the non-finite float literal
+// starting at i in buf, or 0 if there is none.
+func scanNonFiniteFloat(buf []byte, i int) int {
+	for _, lit := range nonFiniteFloats {
+		end := i + len(lit)
+		if end > len(buf) || !bytes.EqualFold(buf[i:end], lit) {
+			continue
+		}
+		if end == len(buf) || buf[end] == ',' || buf[end] == ' ' {
+			return len(lit)
+		}
+	}
+	return 0
+}
+
+// isDecimal returns true if v is a decimal field value, with its d suffix.
+func isDecimal(v []byte) bool {
+	return len(v) > 1 && v[len(v)-1] == 'd' && (isNumeric(v[0]) || v[0] == '-')
+}
+
+// isNonFiniteFloat returns true if v is a float literal representing NaN or ±Inf.
+func isNonFiniteFloat(v []byte) bool {
+	return len(v) > 0 && scanNonFiniteFloat(v, 0) == len(v)
+}
+
 // scanBoolean returns the end position within buf, start at i after
 // scanning over buf for boolean. Valid values for a boolean are
 // t, T, true, TRUE, f, F, false, FALSE.  It returns an error if a invalid boolean
@@ -1370,6 +1454,11 @@ func NewPointFromBytes(b []byte) (Point, error) {
 			if err != nil {
 				return nil, fmt.Errorf("unable to unmarshal field %s: %s", string(iter.FieldKey()), err)
 			}
+		case Decimal:
+			_, err := iter.DecimalValue()
+			if err != nil {
+				return nil, fmt.Errorf("unable to unmarshal field %s: %s", string(iter.FieldKey()), err)
+			}
 		case String:
 			// Skip since this won't return an error
 		case Boolean:
@@ -1766,6 +1855,12 @@ func (p *point) unmarshalBinary() (Fields, error) {
 				return nil, fmt.Errorf("unable to unmarshal field %s: %s", string(iter.FieldKey()), err)
 			}
 			fields[string(iter.FieldKey())] = v
+		case Decimal:
+			v, err := iter.DecimalValue()
+			if err != nil {
+				return nil, fmt.Errorf("unable to unmarshal field %s: %s", string(iter.FieldKey()), err)
+			}
+			fields[string(iter.FieldKey())] = v
 		case String:
 			fields[string(iter.FieldKey())] = iter.StringValue()
 		case Boolean:
@@ -2311,6 +2406,9 @@ func (p *point) Next() bool {
 		} else if p.it.valueBuf[len(p.it.valueBuf)-1] == 'u' {
 			p.it.fieldType = Unsigned
 			p.it.valueBuf = p.it.valueBuf[:len(p.it.valueBuf)-1]
+		} else if p.it.valueBuf[len(p.it.valueBuf)-1] == 'd' {
+			p.it.fieldType = Decimal
+			p.it.valueBuf = p.it.valueBuf[:len(p.it.valueBuf)-1]
 		} else {
 			p.it.fieldType = Float
 		}
@@ -2355,6 +2453,15 @@ func (p *point) UnsignedValue() (uint64, error) {
 	return n, nil
 }
 
+// DecimalValue returns the decimal value of the current field.
+func (p *point) DecimalValue() (FixedDecimal, error) {
+	d, err := ParseDecimal(p.it.valueBuf)
+	if err != nil {
+		return FixedDecimal{}, fmt.Errorf("unable to parse decimal value %q: %v", p.it.valueBuf, err)
+	}
+	return d, nil
+}
+
 // BooleanValue returns the boolean value of the current field.
 func (p *point) BooleanValue() (bool, error) {
 	b, err := parseBoolBytes(p.it.valueBuf)
@@ -2440,6 +2547,9 @@ func appendField(b []byte, k string, v interface{}) []byte {
 	case uint64:
 		b = strconv.AppendUint(b, v, 10)
 		b = append(b, 'u')
+	case FixedDecimal:
+		b = v.AppendTo(b)
+		b = append(b, 'd')
 	case uint32:
 		b = strconv.AppendInt(b, int64(v), 10)
 		b = append(b, 'i')
@@ -26,9 +26,42 @@ var (
 	errLimit = errors.New("points: limit exceeded")
 )
 
+// ErrNonFiniteFloat is the error returned by ParsePointsWithOptions when a
+// field value is NaN or ±Inf and the NonFiniteFloatPolicy does not permit it.
+var ErrNonFiniteFloat = errors.New("non-finite float value")
+
+// ErrDecimalNotEnabled is the error returned by ParsePointsWithOptions when a
+// field value is a decimal and decimals are not enabled with WithParserDecimals.
+var ErrDecimalNotEnabled = errors.New("decimal field values are not enabled")
+
+// NonFiniteFloatPolicy determines how the parser handles float field values
+// of NaN, +Inf and -Inf.
+type NonFiniteFloatPolicy int
+
+const (
+	// NonFiniteFloatReject fails any line containing a non-finite float value.
+	NonFiniteFloatReject NonFiniteFloatPolicy = iota
+
+	// NonFiniteFloatDrop discards non-finite float values and keeps
+	// the remaining fields of the line.
+	NonFiniteFloatDrop
+
+	// NonFiniteFloatStoreInf accepts +Inf and -Inf values. NaN values are
+	// still rejected, as the TSM float encoding reserves NaN as a sentinel.
+	NonFiniteFloatStoreInf
+)
+
 type ParserStats struct {
 	// BytesN reports the number of bytes allocated to parse the request.
 	BytesN int
+
+	// NonFiniteFloatsDropped reports the number of field values discarded
+	// by the NonFiniteFloatDrop policy.
+	NonFiniteFloatsDropped int
+
+	// PrecisionsDetected reports the number of timestamps of each precision
+	// detected with PrecisionAuto.
+	PrecisionsDetected map[string]int
 }
 
 type ParserOption func(*pointsParser)
@@ -40,6 +73,15 @@ func WithParserPrecision(precision string) ParserOption {
 	}
 }
 
+// WithParserStrictPrecision fails the lines whose timestamp precision, as
+// detected with PrecisionAuto, differs from the precision of the first
+// timestamp, so that a batch mixing precisions is rejected.
+func WithParserStrictPrecision() ParserOption {
+	return func(pp *pointsParser) {
+		pp.strictPrecision = true
+	}
+}
+
 // WithParserDefaultTime specifies the default time to assign to values when no timestamp is provided.
 func WithParserDefaultTime(t time.Time) ParserOption {
 	return func(pp *pointsParser) {
@@ -68,6 +110,21 @@ func WithParserMaxValues(n int) ParserOption {
 	}
 }
 
+// WithParserNonFiniteFloatPolicy specifies how float field values of NaN, +Inf and -Inf are handled.
+func WithParserNonFiniteFloatPolicy(policy NonFiniteFloatPolicy) ParserOption {
+	return func(pp *pointsParser) {
+		pp.nonFiniteFloats = policy
+	}
+}
+
+// WithParserDecimals accepts decimal field values, written with a d suffix
+// such as price=12.30d, which are otherwise rejected.
+func WithParserDecimals() ParserOption {
+	return func(pp *pointsParser) {
+		pp.decimals = true
+	}
+}
+
 // WithParserStats specifies that s will contain statistics about the parsed request.
 func WithParserStats(s *ParserStats) ParserOption {
 	return func(pp *pointsParser) {
@@ -94,6 +151,15 @@ type pointsParser struct {
 	points      []Point
 	state       parserState
 	stats       *ParserStats
+
+	nonFiniteFloats        NonFiniteFloatPolicy
+	nonFiniteFloatsDropped int
+
+	decimals bool
+
+	strictPrecision    bool
+	precisionsDetected map[string]int
+	firstPrecision     string // precision of the first timestamp, with PrecisionAuto
 }
 
 func newPointsParser(orgBucket []byte, opts ...ParserOption) *pointsParser {
@@ -173,6 +239,8 @@ func (pp *pointsParser) parsePoints(buf []byte) (err error) {
 
 	if pp.stats != nil {
 		pp.stats.BytesN = pp.bytesN
+		pp.stats.NonFiniteFloatsDropped = pp.nonFiniteFloatsDropped
+		pp.stats.PrecisionsDetected = pp.precisionsDetected
 	}
 
 	if pp.state != parserStateOK {
@@ -251,7 +319,13 @@ func (pp *pointsParser) parsePointsAppend(buf []byte) error {
 		if err != nil {
 			return err
 		}
-		pt.time, err = SafeCalcTime(ts, pp.precision)
+		precision := pp.precision
+		if precision == PrecisionAuto {
+			if precision, err = pp.detectPrecision(ts); err != nil {
+				return err
+			}
+		}
+		pt.time, err = SafeCalcTime(ts, precision)
 		if err != nil {
 			return err
 		}
@@ -269,6 +343,18 @@ func (pp *pointsParser) parsePointsAppend(buf []byte) error {
 	// Loop over fields and split points while validating field.
 	var walkFieldsErr error
 	if err := walkFields(fields, func(k, v, fieldBuf []byte) bool {
+		if isNonFiniteFloat(v) {
+			var keep bool
+			if keep, walkFieldsErr = pp.checkNonFiniteFloat(k, v); !keep {
+				return walkFieldsErr == nil
+			}
+		}
+
+		if !pp.decimals && isDecimal(v) {
+			walkFieldsErr = fmt.Errorf("%w: field %q=%s", ErrDecimalNotEnabled, k, v)
+			return false
+		}
+
 		var newKey []byte
 		newKey, walkFieldsErr = pp.newV2Key(key, k)
 		if walkFieldsErr != nil {
@@ -286,6 +372,22 @@ func (pp *pointsParser) parsePointsAppend(buf []byte) error {
 	return nil
 }
 
+// checkNonFiniteFloat applies the non-finite float policy to the field k with value v.
+// It returns true if the field should be kept, or an error if the line must be rejected.
+func (pp *pointsParser) checkNonFiniteFloat(k, v []byte) (bool, error) {
+	switch pp.nonFiniteFloats {
+	case NonFiniteFloatDrop:
+		pp.nonFiniteFloatsDropped++
+		return false, nil
+	case NonFiniteFloatStoreInf:
+		if !bytes.EqualFold(v, nonFiniteFloats[0]) {
+			return true, nil
+		}
+		return false, fmt.Errorf("%w: field %q=%s: NaN values can not be stored", ErrNonFiniteFloat, k, v)
+	}
+	return false, fmt.Errorf("%w: field %q=%s", ErrNonFiniteFloat, k, v)
+}
+
 func (pp *pointsParser) append(p point) error {
 	if pp.maxValues > 0 && len(pp.points) > pp.maxValues {
 		pp.state = parserStateValueLimit
@@ -335,6 +437,22 @@ func (pp *pointsParser) newV2Key(oldKey, field []byte) ([]byte, error) {
 	return newKey, nil
 }
 
+// detectPrecision returns the precision of the timestamp, failing in strict
+// mode if it differs from the precision of the first timestamp.
+func (pp *pointsParser) detectPrecision(ts int64) (string, error) {
+	precision := DetectPrecision(ts)
+	if pp.firstPrecision == "" {
+		pp.firstPrecision = precision
+	} else if pp.strictPrecision && precision != pp.firstPrecision {
+		return "", fmt.Errorf("timestamp precision %s differs from the precision %s of the first timestamp", precision, pp.firstPrecision)
+	}
+	if pp.precisionsDetected == nil {
+		pp.precisionsDetected = make(map[string]int)
+	}
+	pp.precisionsDetected[precision]++
+	return precision, nil
+}
+
 func truncateTimeWithPrecision(t time.Time, precision string) time.Time {
 	switch precision {
 	case "us":
@@ -804,6 +804,81 @@ func TestParsePointMinUint64(t *testing.T) {
 	}
 }
 
+func TestParsePointDecimal(t *testing.T) {
+	// Decimal fields are rejected unless enabled.
+	for _, line := range []string{`cpu price=12.50d`, `cpu price=-1d`} {
+		if _, err := models.ParsePointsString(line, "mm"); err == nil || !strings.Contains(err.Error(), models.ErrDecimalNotEnabled.Error()) {
+			t.Errorf(`ParsePoints("%s") mismatch. got %v, exp %v`, line, err, models.ErrDecimalNotEnabled)
+		}
+	}
+
+	points, err := models.ParsePointsWithOptions([]byte(`cpu price=12.50d,delta=-0.001d,count=42d 1000000000`), []byte("mm"), models.WithParserDecimals())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := len(points), 3; got != exp {
+		t.Fatalf("unexpected number of points; got %d, exp %d", got, exp)
+	}
+	for i, exp := range []models.FixedDecimal{{Value: 1250, Scale: 2}, {Value: -1, Scale: 3}, {Value: 42}} {
+		itr := points[i].FieldIterator()
+		if !itr.Next() {
+			t.Fatalf("expected field for point %d", i)
+		}
+		if got := itr.Type(); got != models.Decimal {
+			t.Fatalf("unexpected field type; got %v, exp %v", got, models.Decimal)
+		}
+		if got, err := itr.DecimalValue(); err != nil || got != exp {
+			t.Errorf("unexpected value; got %v (%v), exp %v", got, err, exp)
+		}
+	}
+
+	for _, line := range []string{
+		`cpu value=1e5d`,
+		`cpu value=1.2.3d`,
+		`cpu value=--1d`,
+		`cpu value=1di`,
+		`cpu value=1.5dd`,
+		`cpu value=92233720368547758080d`,
+		`cpu value=0.0000000000000000001d`,
+	} {
+		if _, err := models.ParsePointsWithOptions([]byte(line), []byte("mm"), models.WithParserDecimals()); err == nil {
+			t.Errorf(`ParsePoints("%s") mismatch. got nil, exp error`, line)
+		}
+	}
+}
+
+func TestNewPointDecimal(t *testing.T) {
+	d := models.FixedDecimal{Value: -1250, Scale: 2}
+	pt, err := models.NewPoint("cpu", nil, models.Fields{"price": d}, time.Unix(1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := pt.String(), "cpu price=-12.50d 1000000000"; got != exp {
+		t.Fatalf("unexpected point; got %q, exp %q", got, exp)
+	}
+
+	fields, err := pt.Fields()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fields["price"]; got != d {
+		t.Errorf("unexpected field; got %#v, exp %#v", got, d)
+	}
+
+	// The encoded point is parsed back to the same decimal.
+	points, err := models.ParsePointsWithOptions([]byte(pt.String()), nil, models.WithParserDecimals())
+	if err != nil {
+		t.Fatal(err)
+	}
+	itr := points[0].FieldIterator()
+	if !itr.Next() {
+		t.Fatal("expected field")
+	}
+	if got, err := itr.DecimalValue(); err != nil || got != d {
+		t.Errorf("unexpected value; got %v (%v), exp %v", got, err, d)
+	}
+}
+
 func TestParsePointNumberNonNumeric(t *testing.T) {
 	_, err := models.ParsePointsString(`cpu,host=serverA,region=us-west value=.1a`, "mm")
 	if err == nil {
@@ -1883,6 +1958,131 @@ func TestParsePointNaN(t *testing.T) {
 	}
 }
 
+func TestParsePointsWithOptions_NonFiniteFloatPolicy(t *testing.T) {
+	const buf = "cpu value=NaN,other=1 1000000000\n" +
+		"cpu value=1,other=-inf 1000000000\n" +
+		"cpu value=Inf 1000000000\n"
+
+	tests := []struct {
+		name    string
+		policy  models.NonFiniteFloatPolicy
+		points  int
+		dropped int
+		err     bool
+	}{
+		{name: "reject", policy: models.NonFiniteFloatReject, err: true},
+		{name: "drop", policy: models.NonFiniteFloatDrop, points: 2, dropped: 3},
+		{name: "store-inf", policy: models.NonFiniteFloatStoreInf, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stats models.ParserStats
+			points, err := models.ParsePointsWithOptions([]byte(buf), []byte("mm"),
+				models.WithParserNonFiniteFloatPolicy(test.policy),
+				models.WithParserStats(&stats))
+			if got, exp := err != nil, test.err; got != exp {
+				t.Fatalf("unexpected error state; got %v, exp %v: %v", got, exp, err)
+			}
+			if test.err {
+				if !strings.Contains(err.Error(), models.ErrNonFiniteFloat.Error()) {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if got, exp := len(points), test.points; got != exp {
+				t.Errorf("unexpected number of points; got %d, exp %d", got, exp)
+			}
+			if got, exp := stats.NonFiniteFloatsDropped, test.dropped; got != exp {
+				t.Errorf("unexpected number of dropped values; got %d, exp %d", got, exp)
+			}
+		})
+	}
+}
+
+func TestParsePointsWithOptions_NonFiniteFloatStoreInf(t *testing.T) {
+	points, err := models.ParsePointsWithOptions([]byte("cpu value=inf,other=-Inf 1000000000"), []byte("mm"),
+		models.WithParserNonFiniteFloatPolicy(models.NonFiniteFloatStoreInf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := len(points), 2; got != exp {
+		t.Fatalf("unexpected number of points; got %d, exp %d", got, exp)
+	}
+
+	for i, exp := range []float64{math.Inf(1), math.Inf(-1)} {
+		itr := points[i].FieldIterator()
+		if !itr.Next() {
+			t.Fatalf("expected field for point %d", i)
+		}
+		if got := itr.Type(); got != models.Float {
+			t.Fatalf("unexpected field type; got %v, exp %v", got, models.Float)
+		}
+		if got, err := itr.FloatValue(); err != nil || got != exp {
+			t.Errorf("unexpected value; got %v (%v), exp %v", got, err, exp)
+		}
+	}
+
+	// NaN values can not be stored by the TSM float encoding.
+	_, err = models.ParsePointsWithOptions([]byte("cpu value=1,other=NaN 1000000000"), []byte("mm"),
+		models.WithParserNonFiniteFloatPolicy(models.NonFiniteFloatStoreInf))
+	if err == nil || !strings.Contains(err.Error(), "NaN values can not be stored") {
+		t.Fatalf("unexpected error message storing NaN: %v", err)
+	}
+}
+
+func TestParsePointsWithOptions_PrecisionAuto(t *testing.T) {
+	const buf = "cpu value=1 1568000000\n" +
+		"cpu value=2 1568000000001\n" +
+		"cpu value=3 1568000000000002\n" +
+		"cpu value=4 1568000000000000003\n"
+
+	var stats models.ParserStats
+	points, err := models.ParsePointsWithOptions([]byte(buf), []byte("mm"),
+		models.WithParserPrecision(models.PrecisionAuto),
+		models.WithParserStats(&stats))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []int64{1568000000000000000, 1568000000001000000, 1568000000000002000, 1568000000000000003}
+	if got := len(points); got != len(exp) {
+		t.Fatalf("unexpected number of points; got %d, exp %d", got, len(exp))
+	}
+	for i, p := range points {
+		if got := p.UnixNano(); got != exp[i] {
+			t.Errorf("unexpected time of point %d; got %d, exp %d", i, got, exp[i])
+		}
+	}
+	if got, exp := stats.PrecisionsDetected, map[string]int{"s": 1, "ms": 1, "us": 1, "ns": 1}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unexpected precisions detected; got %v, exp %v", got, exp)
+	}
+
+	// Strict mode rejects the lines of another precision than the first.
+	_, err = models.ParsePointsWithOptions([]byte(buf), []byte("mm"),
+		models.WithParserPrecision(models.PrecisionAuto),
+		models.WithParserStrictPrecision())
+	if err == nil || strings.Count(err.Error(), "differs from the precision s of the first timestamp") != 3 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestDetectPrecision(t *testing.T) {
+	for ts, exp := range map[int64]string{
+		0:                    "s",
+		1568000000:           "s",
+		-1568000000:          "s",
+		1568000000000:        "ms",
+		1568000000000000:     "us",
+		1568000000000000000:  "ns",
+		-1568000000000000000: "ns",
+	} {
+		if got := models.DetectPrecision(ts); got != exp {
+			t.Errorf("unexpected precision of %d; got %s, exp %s", ts, got, exp)
+		}
+	}
+}
+
 func TestNewPointLargeNumberOfTags(t *testing.T) {
 	tags := ""
 	for i := 0; i < 255; i++ {
@@ -57,10 +57,23 @@ type Base struct {
 	RunbookLink string                    `json:"runbookLink"`
 	TagRules    []notification.TagRule    `json:"tagRules,omitempty"`
 	StatusRules []notification.StatusRule `json:"statusRules,omitempty"`
+	// DedupEvery is an optional window during which the repeated statuses
+	// of a check at the same level are notified only once.
+	DedupEvery *notification.Duration `json:"dedupEvery,omitempty"`
+	// Flap optionally suppresses the notifications of bouncing checks.
+	Flap *FlapSuppression `json:"flap,omitempty"`
 	*influxdb.Limit
 	influxdb.CRUDLog
 }
 
+// FlapSuppression suppresses the notifications of a check whose level changed
+// at least Changes times within the last Within, and sends a single summary
+// of the flapping check instead.
+type FlapSuppression struct {
+	Changes int                   `json:"changes"`
+	Within  notification.Duration `json:"within"`
+}
+
 func (b Base) valid() error {
 	if !b.ID.Valid() {
 		return &influxdb.Error{
@@ -111,6 +124,26 @@ func (b Base) valid() error {
 			}
 		}
 	}
+	if b.DedupEvery != nil && b.DedupEvery.TimeDuration() <= 0 {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "if dedupEvery is set, it must be larger than 0",
+		}
+	}
+	if b.Flap != nil {
+		if b.Flap.Changes < 2 {
+			return &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  "if flap is set, flap changes must be at least 2",
+			}
+		}
+		if b.Flap.Within.TimeDuration() <= 0 {
+			return &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  "if flap is set, flap within must be larger than 0",
+			}
+		}
+	}
 
 	return nil
 }
@@ -132,60 +165,244 @@ func (b *Base) generateLevelChecks() []ast.Statement {
 		stmts = append(stmts, stmt)
 	}
 
-	now := flux.Call(flux.Identifier("now"), flux.Object())
-	timeFilter := flux.Function(
-		flux.FunctionParams("r"),
-		flux.GreaterThan(
-			flux.Member("r", "_time"),
-			flux.Call(
-				flux.Member("experimental", "subDuration"),
-				flux.Object(
-					flux.Property("from", now),
-					flux.Property("d", (*ast.DurationLiteral)(b.Every)),
-				),
-			),
-		),
-	)
-
-	var pipe *ast.PipeExpression
+	var base ast.Expression
+	calls := []*ast.CallExpression{}
 	if len(tables) == 1 {
-		pipe = flux.Pipe(
-			tables[0],
-			flux.Call(
-				flux.Identifier("filter"),
-				flux.Object(
-					flux.Property("fn", timeFilter),
-				),
+		base = tables[0]
+	} else {
+		base = flux.Call(
+			flux.Identifier("union"),
+			flux.Object(
+				flux.Property("tables", flux.Array(tables...)),
 			),
 		)
-	} else {
-		pipe = flux.Pipe(
+		calls = append(calls, flux.Call(
+			flux.Identifier("sort"),
+			flux.Object(
+				flux.Property("columns", flux.Array(flux.String("_time"))),
+			),
+		))
+	}
+
+	if b.DedupEvery != nil {
+		// Only the first status of each window is kept; the statuses are
+		// queried from the start of the window of the oldest status
+		// notified, so that the statuses of a window notified by a previous
+		// run are not notified again.
+		calls = append(calls,
 			flux.Call(
-				flux.Identifier("union"),
+				flux.Identifier("window"),
 				flux.Object(
-					flux.Property("tables", flux.Array(tables...)),
+					flux.Property("every", (*ast.DurationLiteral)(b.DedupEvery)),
 				),
 			),
 			flux.Call(
-				flux.Identifier("sort"),
+				flux.Identifier("first"),
 				flux.Object(
-					flux.Property("columns", flux.Array(flux.String("_time"))),
+					flux.Property("column", flux.String("_time")),
 				),
 			),
 			flux.Call(
-				flux.Identifier("filter"),
+				flux.Identifier("window"),
 				flux.Object(
-					flux.Property("fn", timeFilter),
+					flux.Property("every", flux.Identifier("inf")),
 				),
 			),
 		)
 	}
 
-	stmts = append(stmts, flux.DefineVariable("all_statuses", pipe))
+	calls = append(calls, flux.Call(
+		flux.Identifier("filter"),
+		flux.Object(
+			flux.Property("fn", flux.Function(flux.FunctionParams("r"), generateSince(b.Every))),
+		),
+	))
+
+	pipe := flux.Pipe(base, calls...)
+	if b.Flap == nil {
+		return append(stmts, flux.DefineVariable("all_statuses", pipe))
+	}
+
+	stmts = append(stmts, flux.DefineVariable("level_statuses", pipe))
+	stmts = append(stmts, b.generateFlapChanges())
+	stmts = append(stmts, flux.DefineVariable("flap_statuses", flux.Call(
+		flux.Identifier("join"),
+		flux.Object(
+			flux.Property("tables", flux.Object(
+				flux.Property("level", flux.Identifier("level_statuses")),
+				flux.Property("flap", flux.Identifier("flap_changes")),
+			)),
+			flux.Property("on", flux.Array(flux.String("_check_id"))),
+		),
+	)))
+
+	changes := flux.Member("r", "_flap_changes")
+	threshold := flux.Integer(int64(b.Flap.Changes))
+	stmts = append(stmts, flux.DefineVariable("not_flapping", flux.Pipe(
+		flux.Identifier("flap_statuses"),
+		flux.Call(
+			flux.Identifier("filter"),
+			flux.Object(
+				flux.Property("fn", flux.Function(
+					flux.FunctionParams("r"),
+					flux.LessThan(changes, threshold),
+				)),
+			),
+		),
+	)))
+	// A flapping check is notified once with a summary of its last status.
+	summary := flux.Add(
+		flux.Add(
+			flux.Add(
+				flux.Member("r", "_check_name"),
+				flux.String(" changed state "),
+			),
+			flux.Call(flux.Identifier("string"), flux.Object(flux.Property("v", changes))),
+		),
+		flux.String(fmt.Sprintf(" times in %s, its notifications are suppressed", ast.Format((*ast.DurationLiteral)(&b.Flap.Within)))),
+	)
+	stmts = append(stmts, flux.DefineVariable("flapping", flux.Pipe(
+		flux.Identifier("flap_statuses"),
+		flux.Call(
+			flux.Identifier("filter"),
+			flux.Object(
+				flux.Property("fn", flux.Function(
+					flux.FunctionParams("r"),
+					flux.GreaterThanEqual(changes, threshold),
+				)),
+			),
+		),
+		flux.Call(
+			flux.Identifier("group"),
+			flux.Object(
+				flux.Property("columns", flux.Array(flux.String("_check_id"))),
+			),
+		),
+		flux.Call(
+			flux.Identifier("last"),
+			flux.Object(
+				flux.Property("column", flux.String("_time")),
+			),
+		),
+		flux.Call(
+			flux.Identifier("map"),
+			flux.Object(
+				flux.Property("fn", flux.Function(
+					flux.FunctionParams("r"),
+					flux.ObjectWith("r", flux.Property("_message", summary)),
+				)),
+			),
+		),
+	)))
+	stmts = append(stmts, flux.DefineVariable("all_statuses", flux.Call(
+		flux.Identifier("union"),
+		flux.Object(
+			flux.Property("tables", flux.Array(flux.Identifier("not_flapping"), flux.Identifier("flapping"))),
+		),
+	)))
 
 	return stmts
 }
 
+// generateSince returns an expression testing whether the record r is from
+// the last d.
+func generateSince(d *notification.Duration) ast.Expression {
+	now := flux.Call(flux.Identifier("now"), flux.Object())
+	return flux.GreaterThan(
+		flux.Member("r", "_time"),
+		flux.Call(
+			flux.Member("experimental", "subDuration"),
+			flux.Object(
+				flux.Property("from", now),
+				flux.Property("d", (*ast.DurationLiteral)(d)),
+			),
+		),
+	)
+}
+
+// generateFlapChanges defines flap_changes, the number of times the level of
+// each check changed within the flap window.
+func (b *Base) generateFlapChanges() ast.Statement {
+	level := flux.Member("r", "_level")
+	levelValue := flux.If(
+		flux.Equal(level, flux.String("crit")),
+		flux.Integer(3),
+		flux.If(
+			flux.Equal(level, flux.String("warn")),
+			flux.Integer(2),
+			flux.If(
+				flux.Equal(level, flux.String("info")),
+				flux.Integer(1),
+				flux.If(
+					flux.Equal(level, flux.String("ok")),
+					flux.Integer(0),
+					flux.Integer(-1),
+				),
+			),
+		),
+	)
+
+	changes := flux.Member("r", "_flap_changes")
+	changed := flux.If(
+		flux.And(
+			generateSince(&b.Flap.Within),
+			flux.And(
+				flux.Exists(changes),
+				flux.NotEqual(changes, flux.Integer(0)),
+			),
+		),
+		flux.Integer(1),
+		flux.Integer(0),
+	)
+
+	return flux.DefineVariable("flap_changes", flux.Pipe(
+		flux.Identifier("statuses"),
+		flux.Call(
+			flux.Identifier("group"),
+			flux.Object(
+				flux.Property("columns", flux.Array(flux.String("_check_id"))),
+			),
+		),
+		flux.Call(
+			flux.Identifier("sort"),
+			flux.Object(
+				flux.Property("columns", flux.Array(flux.String("_time"))),
+			),
+		),
+		flux.Call(
+			flux.Identifier("map"),
+			flux.Object(
+				flux.Property("fn", flux.Function(
+					flux.FunctionParams("r"),
+					flux.ObjectWith("r", flux.Property("_flap_changes", levelValue)),
+				)),
+			),
+		),
+		flux.Call(
+			flux.Identifier("difference"),
+			flux.Object(
+				flux.Property("columns", flux.Array(flux.String("_flap_changes"))),
+				flux.Property("keepFirst", flux.Bool(true)),
+			),
+		),
+		flux.Call(
+			flux.Identifier("map"),
+			flux.Object(
+				flux.Property("fn", flux.Function(
+					flux.FunctionParams("r"),
+					flux.ObjectWith("r", flux.Property("_flap_changes", changed)),
+				)),
+			),
+		),
+		flux.Call(
+			flux.Identifier("sum"),
+			flux.Object(
+				flux.Property("column", flux.String("_flap_changes")),
+			),
+		),
+	))
+}
+
 func (b *Base) generateLevelCheck(r notification.StatusRule) (ast.Statement, *ast.Identifier) {
 	var name string
 	var pipe *ast.PipeExpression
@@ -285,11 +502,32 @@ func (b *Base) generateTaskOption() ast.Statement {
 	return flux.DefineTaskOption(flux.Object(props...))
 }
 
+// statusesLookback returns how far back the statuses are queried: the
+// overlapping interval of the rule, extended to the start of the
+// deduplication window of its oldest status and to the flap window.
+func (b *Base) statusesLookback() *ast.DurationLiteral {
+	dur := increaseDur((*ast.DurationLiteral)(b.Every))
+	longest := (*notification.Duration)(dur).TimeDuration()
+	if b.DedupEvery != nil {
+		d := &ast.DurationLiteral{}
+		d.Values = append(d.Values, b.Every.Values...)
+		d.Values = append(d.Values, b.DedupEvery.Values...)
+		if dd := (*notification.Duration)(d).TimeDuration(); dd > longest {
+			dur, longest = d, dd
+		}
+	}
+	if b.Flap != nil {
+		if dd := b.Flap.Within.TimeDuration(); dd > longest {
+			dur = (*ast.DurationLiteral)(&b.Flap.Within)
+		}
+	}
+	return dur
+}
+
 func (b *Base) generateFluxASTStatuses() ast.Statement {
 	props := []*ast.Property{}
 
-	dur := (*ast.DurationLiteral)(b.Every)
-	props = append(props, flux.Property("start", flux.Negative(increaseDur(dur))))
+	props = append(props, flux.Property("start", flux.Negative(b.statusesLookback())))
 
 	if len(b.TagRules) > 0 {
 		r := b.TagRules[0]
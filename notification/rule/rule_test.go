@@ -197,6 +197,65 @@ func TestValidRule(t *testing.T) {
 				Msg:  `if limit is set, limit and limitEvery must be larger than 0`,
 			},
 		},
+		{
+			name: "bad dedup every",
+			src: &rule.PagerDuty{
+				Base: rule.Base{
+					ID:         influxTesting.MustIDBase16(id1),
+					OwnerID:    influxTesting.MustIDBase16(id2),
+					OrgID:      influxTesting.MustIDBase16(id3),
+					EndpointID: 1,
+					Name:       "name1",
+					DedupEvery: mustDuration("0s"),
+				},
+				MessageTemplate: "body {var2}",
+			},
+			err: &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  `if dedupEvery is set, it must be larger than 0`,
+			},
+		},
+		{
+			name: "bad flap changes",
+			src: &rule.PagerDuty{
+				Base: rule.Base{
+					ID:         influxTesting.MustIDBase16(id1),
+					OwnerID:    influxTesting.MustIDBase16(id2),
+					OrgID:      influxTesting.MustIDBase16(id3),
+					EndpointID: 1,
+					Name:       "name1",
+					Flap: &rule.FlapSuppression{
+						Changes: 1,
+						Within:  *mustDuration("30m"),
+					},
+				},
+				MessageTemplate: "body {var2}",
+			},
+			err: &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  `if flap is set, flap changes must be at least 2`,
+			},
+		},
+		{
+			name: "bad flap within",
+			src: &rule.PagerDuty{
+				Base: rule.Base{
+					ID:         influxTesting.MustIDBase16(id1),
+					OwnerID:    influxTesting.MustIDBase16(id2),
+					OrgID:      influxTesting.MustIDBase16(id3),
+					EndpointID: 1,
+					Name:       "name1",
+					Flap: &rule.FlapSuppression{
+						Changes: 3,
+					},
+				},
+				MessageTemplate: "body {var2}",
+			},
+			err: &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  `if flap is set, flap within must be larger than 0`,
+			},
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -226,6 +285,11 @@ func TestJSON(t *testing.T) {
 					RunbookLink: "runbooklink1",
 					SleepUntil:  &time3,
 					Every:       mustDuration("1h"),
+					DedupEvery:  mustDuration("10m"),
+					Flap: &rule.FlapSuppression{
+						Changes: 4,
+						Within:  *mustDuration("30m"),
+					},
 					TagRules: []notification.TagRule{
 						{
 							Tag: influxdb.Tag{
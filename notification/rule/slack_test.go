@@ -339,6 +339,94 @@ all_statuses
 				},
 			},
 		},
+		{
+			name: "with dedup and flap suppression",
+			want: `package main
+// foo
+import "influxdata/influxdb/monitor"
+import "slack"
+import "influxdata/influxdb/secrets"
+import "experimental"
+
+option task = {name: "foo", every: 1h}
+
+slack_endpoint = slack.endpoint(url: "http://localhost:7777")
+notification = {
+	_notification_rule_id: "0000000000000001",
+	_notification_rule_name: "foo",
+	_notification_endpoint_id: "0000000000000002",
+	_notification_endpoint_name: "foo",
+}
+statuses = monitor.from(start: -3h)
+crit = statuses
+	|> filter(fn: (r) =>
+		(r._level == "crit"))
+warn = statuses
+	|> filter(fn: (r) =>
+		(r._level == "warn"))
+level_statuses = union(tables: [crit, warn])
+	|> sort(columns: ["_time"])
+	|> window(every: 10m)
+	|> first(column: "_time")
+	|> window(every: inf)
+	|> filter(fn: (r) =>
+		(r._time > experimental.subDuration(from: now(), d: 1h)))
+flap_changes = statuses
+	|> group(columns: ["_check_id"])
+	|> sort(columns: ["_time"])
+	|> map(fn: (r) =>
+		({r with _flap_changes: if r._level == "crit" then 3 else if r._level == "warn" then 2 else if r._level == "info" then 1 else if r._level == "ok" then 0 else -1}))
+	|> difference(columns: ["_flap_changes"], keepFirst: true)
+	|> map(fn: (r) =>
+		({r with _flap_changes: if r._time > experimental.subDuration(from: now(), d: 3h) and (exists r._flap_changes and r._flap_changes != 0) then 1 else 0}))
+	|> sum(column: "_flap_changes")
+flap_statuses = join(tables: {level: level_statuses, flap: flap_changes}, on: ["_check_id"])
+not_flapping = flap_statuses
+	|> filter(fn: (r) =>
+		(r._flap_changes < 4))
+flapping = flap_statuses
+	|> filter(fn: (r) =>
+		(r._flap_changes >= 4))
+	|> group(columns: ["_check_id"])
+	|> last(column: "_time")
+	|> map(fn: (r) =>
+		({r with _message: r._check_name + " changed state " + string(v: r._flap_changes) + " times in 3h, its notifications are suppressed"}))
+all_statuses = union(tables: [not_flapping, flapping])
+
+all_statuses
+	|> monitor.notify(data: notification, endpoint: slack_endpoint(mapFn: (r) =>
+		({channel: "bar", text: "blah", color: if r._level == "crit" then "danger" else if r._level == "warn" then "warning" else "good"})))`,
+			rule: &rule.Slack{
+				Channel:         "bar",
+				MessageTemplate: "blah",
+				Base: rule.Base{
+					ID:         1,
+					EndpointID: 2,
+					Name:       "foo",
+					Every:      mustDuration("1h"),
+					DedupEvery: mustDuration("10m"),
+					Flap: &rule.FlapSuppression{
+						Changes: 4,
+						Within:  *mustDuration("3h"),
+					},
+					StatusRules: []notification.StatusRule{
+						{
+							CurrentLevel: notification.Critical,
+						},
+						{
+							CurrentLevel: notification.Warn,
+						},
+					},
+				},
+			},
+			endpoint: &endpoint.Slack{
+				Base: endpoint.Base{
+					ID:   idPtr(2),
+					Name: "foo",
+				},
+				URL: "http://localhost:7777",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -10,9 +10,49 @@ type Organization struct {
 	ID          ID     `json:"id,omitempty"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	// QueryResultLimits limits the size of the results of the queries of the
+	// organization. Queries may request stricter limits, but not looser ones.
+	QueryResultLimits *QueryResultLimits `json:"queryResultLimits,omitempty"`
+	// QueryReadRateLimit limits the rate, in bytes per second, at which each
+	// query of the organization reads data from disk, so that large export
+	// queries do not slow down interactive ones. Zero is unlimited. Queries may
+	// request a lower rate, but not a higher one.
+	QueryReadRateLimit int64 `json:"queryReadRateLimit,omitempty"`
 	CRUDLog
 }
 
+// QueryResultLimits limits the size of a query result. Once a limit is reached,
+// the rest of the result is dropped and the result is marked as truncated.
+// A zero limit is unlimited.
+type QueryResultLimits struct {
+	// MaxRows is the maximum number of rows of the result.
+	MaxRows int64 `json:"maxRows,omitempty"`
+	// MaxBytes is the maximum size of the values of the result, independent
+	// of the output format.
+	MaxBytes int64 `json:"maxBytes,omitempty"`
+}
+
+// IsZero returns true if l has no limit.
+func (l QueryResultLimits) IsZero() bool {
+	return l.MaxRows <= 0 && l.MaxBytes <= 0
+}
+
+// Merge returns the stricter of each limit of l and o.
+func (l QueryResultLimits) Merge(o QueryResultLimits) QueryResultLimits {
+	return QueryResultLimits{
+		MaxRows:  minLimit(l.MaxRows, o.MaxRows),
+		MaxBytes: minLimit(l.MaxBytes, o.MaxBytes),
+	}
+}
+
+// minLimit returns the smallest of two limits, where zero is unlimited.
+func minLimit(a, b int64) int64 {
+	if a <= 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
+
 // errors of org
 var (
 	// ErrOrgNameisEmpty is error when org name is empty
@@ -61,6 +101,12 @@ type OrganizationService interface {
 type OrganizationUpdate struct {
 	Name        *string
 	Description *string `json:"description,omitempty"`
+	// QueryResultLimits replaces the query result limits of the organization.
+	// Zero limits remove them.
+	QueryResultLimits *QueryResultLimits `json:"queryResultLimits,omitempty"`
+	// QueryReadRateLimit replaces the query read rate limit of the
+	// organization. Zero removes it.
+	QueryReadRateLimit *int64 `json:"queryReadRateLimit,omitempty"`
 }
 
 // ErrInvalidOrgFilter is the error indicate org filter is empty
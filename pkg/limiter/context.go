@@ -0,0 +1,23 @@
+package limiter
+
+import "context"
+
+type key int
+
+const (
+	rateKey key = iota
+)
+
+// NewContextWithRate returns a new context with the given Rate added, limiting
+// the rate of the operations performed with the context, such as the reads of
+// a query.
+func NewContextWithRate(ctx context.Context, r Rate) context.Context {
+	return context.WithValue(ctx, rateKey, r)
+}
+
+// RateFromContext returns the Rate associated with ctx or nil if no Rate has
+// been assigned.
+func RateFromContext(ctx context.Context) Rate {
+	r, _ := ctx.Value(rateKey).(Rate)
+	return r
+}
@@ -0,0 +1,200 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Memory is a budget of bytes shared by several consumers, such as the cache,
+// the queries and the compactions of a storage engine, so that together they
+// do not use more memory than the limit.
+//
+// A nil *Memory is an unlimited budget.
+type Memory struct {
+	mu    sync.Mutex
+	limit int64
+	used  int64
+	// freed is closed, and replaced, when memory is released.
+	freed chan struct{}
+
+	consumers []*MemoryConsumer
+}
+
+// NewMemory returns a budget of limit bytes. It returns nil, an unlimited
+// budget, if limit is not positive.
+func NewMemory(limit int64) *Memory {
+	if limit <= 0 {
+		return nil
+	}
+	return &Memory{limit: limit, freed: make(chan struct{})}
+}
+
+// Limit returns the number of bytes of the budget, or 0 if it is unlimited.
+func (m *Memory) Limit() int64 {
+	if m == nil {
+		return 0
+	}
+	return m.limit
+}
+
+// Used returns the number of bytes used by all consumers.
+func (m *Memory) Used() int64 {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.used
+}
+
+// Consumer returns a new consumer of the budget named name.
+func (m *Memory) Consumer(name string) *MemoryConsumer {
+	c := &MemoryConsumer{m: m, name: name}
+	if m != nil {
+		m.mu.Lock()
+		m.consumers = append(m.consumers, c)
+		m.mu.Unlock()
+	}
+	return c
+}
+
+// Consumers returns the number of bytes used by each consumer, by name.
+func (m *Memory) Consumers() map[string]int64 {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	used := make(map[string]int64, len(m.consumers))
+	for _, c := range m.consumers {
+		used[c.name] += c.used
+	}
+	return used
+}
+
+// grow adds delta bytes to the usage of c, or sets its usage to n if set is
+// true. If force is false, it fails when growing the usage would exceed the
+// limit. Consumers waiting for memory are woken up when the usage shrinks.
+func (m *Memory) grow(c *MemoryConsumer, delta, n int64, set, force bool) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if set {
+		delta = n - c.used
+	} else if c.used+delta < 0 {
+		delta = -c.used
+	}
+	if delta > 0 && !force && m.used+delta > m.limit {
+		return false
+	}
+	m.used += delta
+	c.used += delta
+	if delta < 0 {
+		close(m.freed)
+		m.freed = make(chan struct{})
+	}
+	return true
+}
+
+// MemoryConsumer accounts for the memory used by one consumer of a Memory
+// budget. A consumer of a nil budget is never limited.
+type MemoryConsumer struct {
+	m    *Memory
+	name string
+	used int64 // protected by m.mu
+}
+
+// Name returns the name of the consumer.
+func (c *MemoryConsumer) Name() string {
+	return c.name
+}
+
+// Limit returns the number of bytes of the budget of the consumer, or 0 if it
+// is unlimited.
+func (c *MemoryConsumer) Limit() int64 {
+	if c == nil {
+		return 0
+	}
+	return c.m.Limit()
+}
+
+// Used returns the number of bytes used by the consumer.
+func (c *MemoryConsumer) Used() int64 {
+	if c == nil || c.m == nil {
+		return 0
+	}
+	c.m.mu.Lock()
+	defer c.m.mu.Unlock()
+	return c.used
+}
+
+// TryReserve reserves n more bytes and returns true, or returns false if the
+// budget does not have n bytes available.
+func (c *MemoryConsumer) TryReserve(n int64) bool {
+	if c == nil || c.m == nil {
+		return true
+	}
+	return c.m.grow(c, n, 0, false, false)
+}
+
+// Reserve reserves n more bytes, blocking until other consumers release
+// enough memory or ctx is done. Requests larger than the whole budget are
+// rejected with ErrMemoryLimitExceeded rather than blocking forever.
+func (c *MemoryConsumer) Reserve(ctx context.Context, n int64) error {
+	if c == nil || c.m == nil {
+		return nil
+	}
+	if n > c.m.limit {
+		return ErrMemoryLimitExceeded
+	}
+
+	for {
+		c.m.mu.Lock()
+		freed := c.m.freed
+		if c.m.used+n <= c.m.limit {
+			c.m.used += n
+			c.used += n
+			c.m.mu.Unlock()
+			return nil
+		}
+		c.m.mu.Unlock()
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Release releases n bytes previously reserved.
+func (c *MemoryConsumer) Release(n int64) {
+	if c == nil || c.m == nil || n <= 0 {
+		return
+	}
+	c.m.grow(c, -n, 0, false, true)
+}
+
+// TryResize sets the usage of the consumer to n bytes, such as the current
+// size of a cache, and returns true. It returns false, and leaves the usage
+// unchanged, if growing the usage would exceed the budget.
+func (c *MemoryConsumer) TryResize(n int64) bool {
+	if c == nil || c.m == nil {
+		return true
+	}
+	return c.m.grow(c, 0, n, true, false)
+}
+
+// Resize sets the usage of the consumer to n bytes, even if it exceeds the
+// budget. It is used to account for memory that was already allocated.
+func (c *MemoryConsumer) Resize(n int64) {
+	if c == nil || c.m == nil {
+		return
+	}
+	c.m.grow(c, 0, n, true, true)
+}
+
+// ErrMemoryLimitExceeded is returned when a reservation can never be
+// satisfied by a Memory budget.
+var ErrMemoryLimitExceeded = errors.New("memory budget exceeded")
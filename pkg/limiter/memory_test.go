@@ -0,0 +1,93 @@
+package limiter_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/pkg/limiter"
+)
+
+func TestMemory_TryReserve(t *testing.T) {
+	m := limiter.NewMemory(100)
+	cache, query := m.Consumer("cache"), m.Consumer("query")
+
+	if !cache.TryResize(60) {
+		t.Fatal("expected cache to be resized")
+	}
+	if query.TryReserve(50) {
+		t.Fatal("expected reservation over the budget to fail")
+	}
+	if !query.TryReserve(40) {
+		t.Fatal("expected reservation within the budget to succeed")
+	}
+	if exp, got := int64(100), m.Used(); exp != got {
+		t.Fatalf("used mismatch: exp %v, got %v", exp, got)
+	}
+
+	// Shrinking the cache makes room for the query.
+	cache.Resize(10)
+	if !query.TryReserve(50) {
+		t.Fatal("expected reservation to succeed after the cache shrank")
+	}
+	if exp, got := map[string]int64{"cache": 10, "query": 90}, m.Consumers(); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("consumers mismatch: exp %v, got %v", exp, got)
+	}
+
+	query.Release(1000)
+	if exp, got := int64(0), query.Used(); exp != got {
+		t.Fatalf("used mismatch: exp %v, got %v", exp, got)
+	}
+}
+
+func TestMemory_Reserve(t *testing.T) {
+	m := limiter.NewMemory(100)
+	cache, compaction := m.Consumer("cache"), m.Consumer("compaction")
+	cache.Resize(80)
+
+	if err := compaction.Reserve(context.Background(), 200); err != limiter.ErrMemoryLimitExceeded {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	errC := make(chan error, 1)
+	go func() { errC <- compaction.Reserve(context.Background(), 50) }()
+
+	select {
+	case err := <-errC:
+		t.Fatalf("expected reservation to block, got %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	cache.Resize(0)
+	select {
+	case err := <-errC:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected reservation to succeed after the cache shrank")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := compaction.Reserve(ctx, 60); err != context.Canceled {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestMemory_Unlimited(t *testing.T) {
+	m := limiter.NewMemory(0)
+	if m != nil {
+		t.Fatal("expected an unlimited budget")
+	}
+
+	c := m.Consumer("query")
+	if !c.TryReserve(1 << 40) {
+		t.Fatal("expected reservation to succeed")
+	}
+	if err := c.Reserve(context.Background(), 1<<40); err != nil {
+		t.Fatal(err)
+	}
+	c.Release(1 << 40)
+}
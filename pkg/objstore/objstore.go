@@ -0,0 +1,36 @@
+// Package objstore stores files as the objects of an S3-compatible object
+// store.
+package objstore
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when an object does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// Object describes an object of a store.
+type Object struct {
+	Key  string
+	Size int64
+}
+
+// Store is an object store.
+type Store interface {
+	// Put stores the content of r as the object key, replacing any existing
+	// object. size is the length of the content of r, which may be read more
+	// than once.
+	Put(ctx context.Context, key string, r io.ReadSeeker, size int64) error
+
+	// Get returns the content of the object key, or ErrNotFound.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the object key. Deleting a missing object is not an
+	// error.
+	Delete(ctx context.Context, key string) error
+
+	// List returns the objects whose keys start with prefix, ordered by key.
+	List(ctx context.Context, prefix string) ([]Object, error)
+}
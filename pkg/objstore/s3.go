@@ -0,0 +1,243 @@
+package objstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultRegion is the region requests are signed for by default.
+const DefaultRegion = "us-east-1"
+
+// Config configures the S3-compatible object store of a bucket.
+type Config struct {
+	// URL is the path-style URL of the bucket, with an optional prefix for
+	// the keys of the objects, such as https://s3.us-east-1.amazonaws.com/bucket/prefix
+	// or http://minio:9000/bucket. The store is disabled if it is empty.
+	URL string `toml:"url"`
+
+	// Region is the region requests are signed for, DefaultRegion if empty.
+	Region string `toml:"region"`
+
+	// AccessKeyID and SecretAccessKey are the credentials requests are signed
+	// with.
+	AccessKeyID     string `toml:"access-key-id"`
+	SecretAccessKey string `toml:"secret-access-key"`
+}
+
+// Enabled returns true if the object store is configured.
+func (c Config) Enabled() bool {
+	return c.URL != ""
+}
+
+// S3 is a Store of the objects of a bucket of an S3-compatible object store,
+// such as Amazon S3, MinIO or Ceph, accessed with path-style requests.
+type S3 struct {
+	// Client is the HTTP client of the requests, http.DefaultClient if nil.
+	Client *http.Client
+
+	base   *url.URL // URL of the bucket
+	prefix string   // prefix of the keys of the objects
+	signer signer
+	now    func() time.Time
+}
+
+var _ Store = (*S3)(nil)
+
+// NewS3 returns a new S3 store of the bucket configured by c.
+func NewS3(c Config) (*S3, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid object store URL: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid object store URL %q: scheme must be http or https", c.URL)
+	}
+	parts := strings.SplitN(strings.Trim(u.Path, "/"), "/", 2)
+	if parts[0] == "" {
+		return nil, fmt.Errorf("invalid object store URL %q: bucket required", c.URL)
+	}
+
+	var prefix string
+	if len(parts) == 2 && parts[1] != "" {
+		prefix = strings.TrimSuffix(parts[1], "/") + "/"
+	}
+	region := c.Region
+	if region == "" {
+		region = DefaultRegion
+	}
+	return &S3{
+		base:   &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/" + parts[0]},
+		prefix: prefix,
+		signer: signer{
+			accessKeyID:     c.AccessKeyID,
+			secretAccessKey: c.SecretAccessKey,
+			region:          region,
+		},
+		now: time.Now,
+	}, nil
+}
+
+// Put stores the content of r as the object key.
+func (s *S3) Put(ctx context.Context, key string, r io.ReadSeeker, size int64) error {
+	// The payload is signed, so it is read twice.
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	req, err := s.newRequest(ctx, http.MethodPut, key, nil, ioutil.NopCloser(r))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	resp, err := s.do(req, hex.EncodeToString(h.Sum(nil)))
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Get returns the content of the object key.
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete removes the object key.
+func (s *S3) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err == ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// listBucketResult is the response of a ListObjectsV2 request.
+type listBucketResult struct {
+	Contents []struct {
+		Key  string `xml:"Key"`
+		Size int64  `xml:"Size"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List returns the objects whose keys start with prefix.
+func (s *S3) List(ctx context.Context, prefix string) ([]Object, error) {
+	var (
+		objects []Object
+		token   string
+	)
+	for {
+		q := url.Values{
+			"list-type": {"2"},
+			"prefix":    {s.prefix + prefix},
+		}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		req, err := s.newRequest(ctx, http.MethodGet, "", q, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.do(req, emptyPayloadHash)
+		if err != nil {
+			return nil, err
+		}
+
+		var res listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&res)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode the objects of the bucket: %v", err)
+		}
+		for _, c := range res.Contents {
+			objects = append(objects, Object{
+				Key:  strings.TrimPrefix(c.Key, s.prefix),
+				Size: c.Size,
+			})
+		}
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = res.NextContinuationToken
+	}
+}
+
+// newRequest returns a request of the object key, or of the bucket if key is
+// empty.
+func (s *S3) newRequest(ctx context.Context, method, key string, q url.Values, body io.ReadCloser) (*http.Request, error) {
+	u := *s.base
+	if key != "" {
+		u.Path += "/" + s.prefix + key
+	} else {
+		u.Path += "/"
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Body = body
+	}
+	return req.WithContext(ctx), nil
+}
+
+// do signs and sends req, and returns its response if it succeeded.
+func (s *S3) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.signer.sign(req, payloadHash, s.now())
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound && req.Method != http.MethodPut {
+		return nil, ErrNotFound
+	}
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if xml.Unmarshal(b, &e) == nil && e.Code != "" {
+		return nil, fmt.Errorf("object store: %s %s: %s: %s", req.Method, req.URL.Path, e.Code, e.Message)
+	}
+	return nil, errors.New("object store: " + req.Method + " " + req.URL.Path + ": " + resp.Status)
+}
@@ -0,0 +1,158 @@
+package objstore_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/influxdata/influxdb/pkg/objstore"
+)
+
+func TestS3(t *testing.T) {
+	srv := newFakeS3(t, "bucket")
+	defer srv.Close()
+
+	s, err := objstore.NewS3(objstore.Config{
+		URL:             srv.URL + "/bucket/engine/",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for _, key := range []string{"a.tsm", "b.tsm", "c.tss"} {
+		if err := s.Put(ctx, key, strings.NewReader(key+" data"), int64(len(key+" data"))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := srv.objects["engine/a.tsm"]; !ok {
+		t.Fatalf("object not stored under the prefix: %v", srv.objects)
+	}
+
+	r, err := s.Get(ctx, "b.tsm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "b.tsm data"; got != want {
+		t.Fatalf("unexpected content: got %q, want %q", got, want)
+	}
+	if _, err := s.Get(ctx, "missing.tsm"); err != objstore.ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := s.Delete(ctx, "b.tsm"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Listings are paginated by the fake server.
+	objects, err := s.List(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []objstore.Object{{Key: "a.tsm", Size: 10}, {Key: "c.tss", Size: 10}}
+	if !reflect.DeepEqual(objects, want) {
+		t.Fatalf("unexpected objects: got %v, want %v", objects, want)
+	}
+}
+
+func TestNewS3_InvalidURL(t *testing.T) {
+	for _, u := range []string{"s3://bucket", "http://host", "http://host/"} {
+		if _, err := objstore.NewS3(objstore.Config{URL: u}); err == nil {
+			t.Errorf("expected an error for %q", u)
+		}
+	}
+}
+
+// fakeS3 serves the objects of a bucket, listing a single object per page.
+type fakeS3 struct {
+	*httptest.Server
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newFakeS3(t *testing.T, bucket string) *fakeS3 {
+	s := &fakeS3{objects: make(map[string][]byte)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=key/") {
+			t.Errorf("unexpected authorization %q", auth)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if !strings.HasPrefix(r.URL.Path, "/"+bucket+"/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		key := strings.TrimPrefix(r.URL.Path, "/"+bucket+"/")
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		switch {
+		case r.Method == http.MethodPut:
+			b, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			s.objects[key] = b
+		case r.Method == http.MethodGet && key == "":
+			s.list(w, r)
+		case r.Method == http.MethodGet:
+			b, ok := s.objects[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(b)
+		case r.Method == http.MethodDelete:
+			delete(s.objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	return s
+}
+
+func (s *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	var keys []string
+	for key := range s.objects {
+		if strings.HasPrefix(key, r.URL.Query().Get("prefix")) && key > r.URL.Query().Get("continuation-token") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	type content struct {
+		Key  string
+		Size int
+	}
+	var res struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Contents              []content
+		IsTruncated           bool
+		NextContinuationToken string `xml:",omitempty"`
+	}
+	if len(keys) > 0 {
+		res.Contents = []content{{Key: keys[0], Size: len(s.objects[keys[0]])}}
+		res.IsTruncated = len(keys) > 1
+		res.NextContinuationToken = keys[0]
+	}
+	var buf bytes.Buffer
+	xml.NewEncoder(&buf).Encode(res)
+	w.Write(buf.Bytes())
+}
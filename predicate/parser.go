@@ -195,7 +195,7 @@ scanRegularTagValue:
 	tok, pos, lit = p.scanIgnoreWhitespace()
 	switch tok {
 	case influxql.SUB:
-		n.Value = "-"
+		n.Value += "-"
 		goto scanRegularTagValue
 	case influxql.IDENT:
 		fallthrough
@@ -205,6 +205,12 @@ scanRegularTagValue:
 		fallthrough
 	case influxql.INTEGER:
 		n.Value += lit
+		// Unquoted values may contain hyphens, such as us-east-1.
+		if tok, _, _ = p.scan(); tok == influxql.SUB {
+			n.Value += "-"
+			goto scanRegularTagValue
+		}
+		p.unscan()
 		return *n, nil
 	case influxql.TRUE:
 		n.Value = "true"
@@ -21,6 +21,13 @@ func TestParseNode(t *testing.T) {
 			str:  `abc=opq`,
 			node: TagRuleNode{Tag: influxdb.Tag{Key: "abc", Value: "opq"}},
 		},
+		{
+			str: `host=web01 AND region=us-east-1`,
+			node: LogicalNode{Operator: LogicalAnd, Children: [2]Node{
+				TagRuleNode{Tag: influxdb.Tag{Key: "host", Value: "web01"}},
+				TagRuleNode{Tag: influxdb.Tag{Key: "region", Value: "us-east-1"}},
+			}},
+		},
 		{
 			str: `abc=opq and gender="male"`,
 			node: LogicalNode{Operator: LogicalAnd, Children: [2]Node{
@@ -14,6 +14,7 @@ type EventRecorder struct {
 	count         *prometheus.CounterVec
 	requestBytes  *prometheus.CounterVec
 	responseBytes *prometheus.CounterVec
+	valuesDropped *prometheus.CounterVec
 }
 
 // NewEventRecorder returns an instance of a metric event recorder. Subsystem is expected to be
@@ -25,6 +26,7 @@ type EventRecorder struct {
 // http_<subsystem>_request_count{org_id=<org_id>, status=<status>, endpoint=<endpoint>} ...
 // http_<subsystem>_request_bytes{org_id=<org_id>, status=<status>, endpoint=<endpoint>} ...
 // http_<subsystem>_response_bytes{org_id=<org_id>, status=<status>, endpoint=<endpoint>} ...
+// http_<subsystem>_values_dropped{org_id=<org_id>, status=<status>, endpoint=<endpoint>} ...
 func NewEventRecorder(subsystem string) *EventRecorder {
 	const namespace = "http"
 
@@ -51,10 +53,18 @@ func NewEventRecorder(subsystem string) *EventRecorder {
 		Help:      "Count of bytes returned",
 	}, labels)
 
+	valuesDropped := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "values_dropped",
+		Help:      "Count of values dropped by the request",
+	}, labels)
+
 	return &EventRecorder{
 		count:         count,
 		requestBytes:  requestBytes,
 		responseBytes: responseBytes,
+		valuesDropped: valuesDropped,
 	}
 }
 
@@ -69,6 +79,7 @@ func (r *EventRecorder) Record(ctx context.Context, e metric.Event) {
 	r.count.With(labels).Inc()
 	r.requestBytes.With(labels).Add(float64(e.RequestBytes))
 	r.responseBytes.With(labels).Add(float64(e.ResponseBytes))
+	r.valuesDropped.With(labels).Add(float64(e.ValuesDropped))
 }
 
 // PrometheusCollectors exposes the prometheus collectors associated with a metric recorder.
@@ -77,5 +88,6 @@ func (r *EventRecorder) PrometheusCollectors() []prometheus.Collector {
 		r.count,
 		r.requestBytes,
 		r.responseBytes,
+		r.valuesDropped,
 	}
 }
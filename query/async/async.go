@@ -0,0 +1,73 @@
+// Package async runs queries in the background and stores their results, so
+// that the clients of long queries poll for the result, or are notified of
+// its completion, instead of holding a request open until it is complete.
+package async
+
+import (
+	"context"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/query"
+)
+
+// Status is the state of an asynchronous query.
+type Status string
+
+const (
+	// StatusRunning is the status of a query being executed.
+	StatusRunning Status = "running"
+	// StatusSuccess is the status of a query whose result is stored.
+	StatusSuccess Status = "success"
+	// StatusFailed is the status of a query that failed, its error is stored.
+	StatusFailed Status = "failed"
+)
+
+// Query is a query submitted for asynchronous execution.
+type Query struct {
+	ID     influxdb.ID `json:"id"`
+	OrgID  influxdb.ID `json:"orgID"`
+	UserID influxdb.ID `json:"userID"`
+	Status Status      `json:"status"`
+	// Error is the error of a failed query.
+	Error string `json:"error,omitempty"`
+	// ContentType is the content type of the result.
+	ContentType string `json:"contentType,omitempty"`
+	// WebhookURL is the optional URL notified of the completion of the query.
+	WebhookURL string `json:"webhookURL,omitempty"`
+	// ResultBytes is the size of the stored result.
+	ResultBytes int64      `json:"resultBytes"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	// ExpiresAt is the time after which a completed query and its result are
+	// deleted.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Completed returns true if the query is no longer running.
+func (q *Query) Completed() bool {
+	return q.Status != StatusRunning
+}
+
+// SubmitOptions are the options of a submitted query.
+type SubmitOptions struct {
+	// ContentType is the content type of the result, as set by the dialect
+	// of the query.
+	ContentType string
+	// WebhookURL is the optional URL to which the query is posted once
+	// completed.
+	WebhookURL string
+}
+
+// QueryService runs queries asynchronously.
+type QueryService interface {
+	// Submit starts the execution of a query and returns immediately.
+	Submit(ctx context.Context, req *query.ProxyRequest, opts SubmitOptions) (*Query, error)
+	// FindQueryByID returns a query which has not expired.
+	FindQueryByID(ctx context.Context, id influxdb.ID) (*Query, error)
+	// FindResult returns the result of a successful query.
+	FindResult(ctx context.Context, id influxdb.ID) ([]byte, error)
+	// DeleteQuery cancels a running query, and deletes a query and its
+	// result.
+	DeleteQuery(ctx context.Context, id influxdb.ID) error
+}
@@ -0,0 +1,445 @@
+package async
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	icontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/kv"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/snowflake"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultMaxResultBytes is the default maximum size of a stored result.
+	DefaultMaxResultBytes = 16 * 1024 * 1024
+	// DefaultTTL is the default duration for which completed queries and
+	// their results are kept.
+	DefaultTTL = 24 * time.Hour
+	// DefaultWebhookTimeout is the default timeout of the webhook requests.
+	DefaultWebhookTimeout = 10 * time.Second
+)
+
+var (
+	queriesBucket = []byte("asyncqueriesv1")
+	resultsBucket = []byte("asyncqueryresultsv1")
+)
+
+var (
+	// ErrQueryNotFound is returned for a query that does not exist or has
+	// expired.
+	ErrQueryNotFound = &influxdb.Error{
+		Code: influxdb.ENotFound,
+		Msg:  "async query not found",
+	}
+
+	// ErrResultNotFound is returned for the result of a query that is
+	// running or failed.
+	ErrResultNotFound = &influxdb.Error{
+		Code: influxdb.ENotFound,
+		Msg:  "async query has no result",
+	}
+
+	errResultTooLarge = errors.New("result too large")
+)
+
+var _ QueryService = (*Service)(nil)
+
+// Service runs queries in the background with a ProxyQueryService, and
+// stores their state and results in a kv.Store.
+type Service struct {
+	store   kv.Store
+	queries query.ProxyQueryService
+	log     *zap.Logger
+
+	IDGenerator influxdb.IDGenerator
+	Now         func() time.Time
+	// MaxResultBytes is the maximum size of a result; the queries whose
+	// result is larger fail.
+	MaxResultBytes int64
+	// TTL is the duration for which completed queries are kept.
+	TTL time.Duration
+	// Client posts the completed queries to their webhook.
+	Client *http.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	running map[influxdb.ID]context.CancelFunc
+}
+
+// NewService returns a new instance of Service.
+func NewService(log *zap.Logger, store kv.Store, queries query.ProxyQueryService) *Service {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Service{
+		store:          store,
+		queries:        queries,
+		log:            log,
+		IDGenerator:    snowflake.NewIDGenerator(),
+		Now:            time.Now,
+		MaxResultBytes: DefaultMaxResultBytes,
+		TTL:            DefaultTTL,
+		Client:         &http.Client{Timeout: DefaultWebhookTimeout},
+		ctx:            ctx,
+		cancel:         cancel,
+		running:        make(map[influxdb.ID]context.CancelFunc),
+	}
+}
+
+// Open creates the buckets of the service, fails the queries which were
+// interrupted by the shutdown of the server and deletes the expired queries.
+func (s *Service) Open(ctx context.Context) error {
+	now := s.Now()
+	return s.store.Update(ctx, func(tx kv.Tx) error {
+		b, err := tx.Bucket(queriesBucket)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Bucket(resultsBucket); err != nil {
+			return err
+		}
+
+		qs, err := findQueries(b)
+		if err != nil {
+			return err
+		}
+		for _, q := range qs {
+			if q.Status == StatusRunning {
+				q.Status = StatusFailed
+				q.Error = "query interrupted by the shutdown of the server"
+				s.complete(q, now)
+				if err := putQuery(tx, q); err != nil {
+					return err
+				}
+			}
+		}
+		return deleteExpired(tx, qs, now)
+	})
+}
+
+// Close cancels the running queries and waits for them to complete.
+func (s *Service) Close() error {
+	s.cancel()
+	s.wg.Wait()
+	return nil
+}
+
+// Submit starts the execution of a query and returns immediately. The query
+// runs with the authorization of the request, and its result is stored
+// until it expires.
+func (s *Service) Submit(ctx context.Context, req *query.ProxyRequest, opts SubmitOptions) (*Query, error) {
+	if req.Request.Authorization == nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EUnauthorized,
+			Msg:  "async queries require an authorization",
+		}
+	}
+
+	now := s.Now()
+	q := &Query{
+		ID:          s.IDGenerator.ID(),
+		OrgID:       req.Request.OrganizationID,
+		UserID:      req.Request.Authorization.GetUserID(),
+		Status:      StatusRunning,
+		ContentType: opts.ContentType,
+		WebhookURL:  opts.WebhookURL,
+		CreatedAt:   now,
+	}
+	err := s.store.Update(ctx, func(tx kv.Tx) error {
+		b, err := tx.Bucket(queriesBucket)
+		if err != nil {
+			return err
+		}
+		qs, err := findQueries(b)
+		if err != nil {
+			return err
+		}
+		if err := deleteExpired(tx, qs, now); err != nil {
+			return err
+		}
+		return putQuery(tx, q)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The query outlives the request that submitted it.
+	qctx, cancel := context.WithCancel(icontext.SetAuthorizer(s.ctx, req.Request.Authorization))
+	s.mu.Lock()
+	s.running[q.ID] = cancel
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func(q Query) {
+		defer s.wg.Done()
+		defer cancel()
+		s.run(qctx, &q, req)
+	}(*q)
+
+	return q, nil
+}
+
+// run executes a query, stores its result and notifies its webhook.
+func (s *Service) run(ctx context.Context, q *Query, req *query.ProxyRequest) {
+	log := s.log.With(zap.String("query_id", q.ID.String()))
+
+	w := &limitedBuffer{max: s.MaxResultBytes}
+	if _, err := s.queries.Query(ctx, w, req); err != nil {
+		q.Status = StatusFailed
+		q.Error = err.Error()
+		if w.exceeded {
+			q.Error = fmt.Sprintf("result exceeds the maximum size of %d bytes", s.MaxResultBytes)
+		}
+	} else {
+		q.Status = StatusSuccess
+		q.ResultBytes = int64(w.buf.Len())
+	}
+	s.complete(q, s.Now())
+
+	s.mu.Lock()
+	delete(s.running, q.ID)
+	s.mu.Unlock()
+
+	// The context of the query may be canceled, the result is stored
+	// regardless.
+	var deleted bool
+	err := s.store.Update(context.Background(), func(tx kv.Tx) error {
+		if _, err := findQueryByID(tx, q.ID); err == ErrQueryNotFound {
+			deleted = true
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := putQuery(tx, q); err != nil {
+			return err
+		}
+		if q.Status != StatusSuccess {
+			return nil
+		}
+		b, err := tx.Bucket(resultsBucket)
+		if err != nil {
+			return err
+		}
+		key, err := q.ID.Encode()
+		if err != nil {
+			return err
+		}
+		return b.Put(key, w.buf.Bytes())
+	})
+	if err != nil {
+		log.Error("Failed to store async query result", zap.Error(err))
+		return
+	}
+	if deleted || q.WebhookURL == "" {
+		return
+	}
+
+	if err := s.notify(q); err != nil {
+		log.Info("Failed to notify async query webhook", zap.String("url", q.WebhookURL), zap.Error(err))
+	}
+}
+
+// complete sets the completion and expiration times of a query.
+func (s *Service) complete(q *Query, now time.Time) {
+	expires := now.Add(s.TTL)
+	q.CompletedAt, q.ExpiresAt = &now, &expires
+}
+
+// notify posts a completed query to its webhook.
+func (s *Service) notify(q *Query) error {
+	body, err := json.Marshal(q)
+	if err != nil {
+		return err
+	}
+	resp, err := s.Client.Post(q.WebhookURL, "application/json; charset=utf-8", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// FindQueryByID returns a query which has not expired.
+func (s *Service) FindQueryByID(ctx context.Context, id influxdb.ID) (*Query, error) {
+	var q *Query
+	err := s.store.View(ctx, func(tx kv.Tx) error {
+		var err error
+		q, err = findQueryByID(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if q.ExpiresAt != nil && !s.Now().Before(*q.ExpiresAt) {
+		return nil, ErrQueryNotFound
+	}
+	return q, nil
+}
+
+// FindResult returns the result of a successful query.
+func (s *Service) FindResult(ctx context.Context, id influxdb.ID) ([]byte, error) {
+	q, err := s.FindQueryByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if q.Status != StatusSuccess {
+		return nil, ErrResultNotFound
+	}
+
+	var result []byte
+	err = s.store.View(ctx, func(tx kv.Tx) error {
+		b, err := tx.Bucket(resultsBucket)
+		if err != nil {
+			return err
+		}
+		key, err := id.Encode()
+		if err != nil {
+			return err
+		}
+		v, err := b.Get(key)
+		if kv.IsNotFound(err) {
+			return ErrResultNotFound
+		} else if err != nil {
+			return err
+		}
+		// The value is only valid for the life of the transaction.
+		result = append([]byte(nil), v...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteQuery cancels a running query, and deletes a query and its result.
+func (s *Service) DeleteQuery(ctx context.Context, id influxdb.ID) error {
+	err := s.store.Update(ctx, func(tx kv.Tx) error {
+		if _, err := findQueryByID(tx, id); err != nil {
+			return err
+		}
+		return deleteQuery(tx, id)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if cancel, ok := s.running[id]; ok {
+		cancel()
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+func findQueryByID(tx kv.Tx, id influxdb.ID) (*Query, error) {
+	b, err := tx.Bucket(queriesBucket)
+	if err != nil {
+		return nil, err
+	}
+	key, err := id.Encode()
+	if err != nil {
+		return nil, ErrQueryNotFound
+	}
+	v, err := b.Get(key)
+	if kv.IsNotFound(err) {
+		return nil, ErrQueryNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	q := &Query{}
+	if err := json.Unmarshal(v, q); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func findQueries(b kv.Bucket) ([]*Query, error) {
+	cur, err := b.Cursor()
+	if err != nil {
+		return nil, err
+	}
+	var qs []*Query
+	for k, v := cur.First(); k != nil; k, v = cur.Next() {
+		q := &Query{}
+		if err := json.Unmarshal(v, q); err != nil {
+			return nil, err
+		}
+		qs = append(qs, q)
+	}
+	return qs, nil
+}
+
+func putQuery(tx kv.Tx, q *Query) error {
+	b, err := tx.Bucket(queriesBucket)
+	if err != nil {
+		return err
+	}
+	key, err := q.ID.Encode()
+	if err != nil {
+		return err
+	}
+	v, err := json.Marshal(q)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, v)
+}
+
+func deleteQuery(tx kv.Tx, id influxdb.ID) error {
+	key, err := id.Encode()
+	if err != nil {
+		return err
+	}
+	for _, name := range [][]byte{queriesBucket, resultsBucket} {
+		b, err := tx.Bucket(name)
+		if err != nil {
+			return err
+		}
+		if err := b.Delete(key); err != nil && !kv.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteExpired deletes the queries of qs which expired at now.
+func deleteExpired(tx kv.Tx, qs []*Query, now time.Time) error {
+	for _, q := range qs {
+		if q.ExpiresAt == nil || now.Before(*q.ExpiresAt) {
+			continue
+		}
+		if err := deleteQuery(tx, q.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// limitedBuffer is a buffer failing the writes past max bytes.
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	max      int64
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.max > 0 && int64(b.buf.Len()+len(p)) > b.max {
+		b.exceeded = true
+		return 0, errResultTooLarge
+	}
+	return b.buf.Write(p)
+}
@@ -0,0 +1,195 @@
+package async_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/influxdb"
+	icontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/inmem"
+	"github.com/influxdata/influxdb/mock"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/query/async"
+	qmock "github.com/influxdata/influxdb/query/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+func newService(t *testing.T, queryF func(ctx context.Context, w io.Writer, req *query.ProxyRequest) (flux.Statistics, error)) *async.Service {
+	t.Helper()
+	s := async.NewService(zaptest.NewLogger(t), inmem.NewKVStore(), &qmock.ProxyQueryService{QueryF: queryF})
+	s.IDGenerator = mock.NewIDGenerator("0000000000000001", t)
+	if err := s.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func newRequest() *query.ProxyRequest {
+	return &query.ProxyRequest{
+		Request: query.Request{
+			OrganizationID: 2,
+			Authorization:  &influxdb.Authorization{ID: 3, UserID: 4, OrgID: 2},
+		},
+	}
+}
+
+// waitCompleted polls the query until it is completed.
+func waitCompleted(t *testing.T, s *async.Service, id influxdb.ID) *async.Query {
+	t.Helper()
+	for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(10 * time.Millisecond) {
+		q, err := s.FindQueryByID(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if q.Completed() {
+			return q
+		}
+	}
+	t.Fatal("timed out waiting for the query to complete")
+	return nil
+}
+
+func TestService_Submit(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t, func(ctx context.Context, w io.Writer, req *query.ProxyRequest) (flux.Statistics, error) {
+		// The query runs with the authorization of the request.
+		if a, err := icontext.GetAuthorizer(ctx); err != nil || a.Identifier() != 3 {
+			return flux.Statistics{}, errors.New("missing authorization")
+		}
+		_, err := io.WriteString(w, "result")
+		return flux.Statistics{}, err
+	})
+	defer s.Close()
+
+	q, err := s.Submit(ctx, newRequest(), async.SubmitOptions{ContentType: "text/csv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.ID != 1 || q.OrgID != 2 || q.UserID != 4 || q.Status != async.StatusRunning {
+		t.Fatalf("unexpected query: %+v", q)
+	}
+
+	q = waitCompleted(t, s, q.ID)
+	if q.Status != async.StatusSuccess || q.ResultBytes != 6 || q.ContentType != "text/csv" || q.ExpiresAt == nil {
+		t.Fatalf("unexpected query: %+v", q)
+	}
+	result, err := s.FindResult(ctx, q.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != "result" {
+		t.Fatalf("unexpected result: %q", result)
+	}
+
+	if err := s.DeleteQuery(ctx, q.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.FindQueryByID(ctx, q.ID); err != async.ErrQueryNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.FindResult(ctx, q.ID); err != async.ErrQueryNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestService_Submit_Failed(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t, func(ctx context.Context, w io.Writer, req *query.ProxyRequest) (flux.Statistics, error) {
+		_, err := io.WriteString(w, "a large result")
+		return flux.Statistics{}, err
+	})
+	s.MaxResultBytes = 4
+	defer s.Close()
+
+	q, err := s.Submit(ctx, newRequest(), async.SubmitOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q = waitCompleted(t, s, q.ID)
+	if q.Status != async.StatusFailed || q.Error != "result exceeds the maximum size of 4 bytes" {
+		t.Fatalf("unexpected query: %+v", q)
+	}
+	if _, err := s.FindResult(ctx, q.ID); err != async.ErrResultNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestService_Submit_Webhook(t *testing.T) {
+	notified := make(chan async.Query, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var q async.Query
+		if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+			t.Error(err)
+		}
+		notified <- q
+	}))
+	defer srv.Close()
+
+	s := newService(t, func(ctx context.Context, w io.Writer, req *query.ProxyRequest) (flux.Statistics, error) {
+		return flux.Statistics{}, nil
+	})
+	defer s.Close()
+
+	q, err := s.Submit(context.Background(), newRequest(), async.SubmitOptions{WebhookURL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-notified:
+		if got.ID != q.ID || got.Status != async.StatusSuccess {
+			t.Fatalf("unexpected notification: %+v", got)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the webhook")
+	}
+}
+
+func TestService_Expiration(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newService(t, func(ctx context.Context, w io.Writer, req *query.ProxyRequest) (flux.Statistics, error) {
+		return flux.Statistics{}, nil
+	})
+	s.Now = func() time.Time { return now }
+	s.TTL = time.Hour
+	defer s.Close()
+
+	q, err := s.Submit(ctx, newRequest(), async.SubmitOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitCompleted(t, s, q.ID)
+
+	now = now.Add(time.Hour)
+	if _, err := s.FindQueryByID(ctx, q.ID); err != async.ErrQueryNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestService_Close(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t, func(ctx context.Context, w io.Writer, req *query.ProxyRequest) (flux.Statistics, error) {
+		<-ctx.Done()
+		return flux.Statistics{}, ctx.Err()
+	})
+
+	q, err := s.Submit(ctx, newRequest(), async.SubmitOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if q, err = s.FindQueryByID(ctx, q.ID); err != nil {
+		t.Fatal(err)
+	} else if q.Status != async.StatusFailed || q.Error != context.Canceled.Error() {
+		t.Fatalf("unexpected query: %+v", q)
+	}
+}
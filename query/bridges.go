@@ -138,7 +138,12 @@ func (b ProxyQueryServiceAsyncBridge) Query(ctx context.Context, w io.Writer, re
 		return flux.Statistics{}, tracing.LogError(span, err)
 	}
 
-	results := flux.NewResultIteratorFromQuery(q)
+	var results flux.ResultIterator = flux.NewResultIteratorFromQuery(q)
+	var limited *LimitedResultIterator
+	if req.Limits != nil && !req.Limits.IsZero() {
+		limited = LimitResults(results, *req.Limits)
+		results = limited
+	}
 	defer results.Release()
 
 	encoder := req.Dialect.Encoder()
@@ -146,6 +151,15 @@ func (b ProxyQueryServiceAsyncBridge) Query(ctx context.Context, w io.Writer, re
 	// Release the results and collect the statistics regardless of the error.
 	results.Release()
 	stats := results.Statistics()
+	if err == nil && limited != nil && limited.Truncated() != "" {
+		err = EncodeTruncation(w, req.Dialect, limited.Truncated())
+	}
+	if err == nil && req.Explain {
+		err = EncodeNotPushedDown(w, req.Dialect, stats)
+	}
+	if err == nil && len(req.Profilers) > 0 {
+		err = EncodeProfile(w, req.Dialect, req.Profilers, stats)
+	}
 	if err != nil {
 		return stats, tracing.LogError(span, err)
 	}
@@ -1,6 +1,7 @@
 package query_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -65,3 +66,35 @@ func TestProxyQueryServiceAsyncBridge_StatsOnClientDisconnect(t *testing.T) {
 		t.Fatalf("stats were missing or had wrong metadata: exp metadata[foo]=[bar], got %v", md)
 	}
 }
+
+func TestProxyQueryServiceAsyncBridge_Explain(t *testing.T) {
+	for _, explain := range []bool{false, true} {
+		q := mock.NewQuery()
+		q.Metadata = flux.Metadata{
+			query.NotPushedDownMetadataKey: []interface{}{"group2: storage can only group by tags not by _value"},
+		}
+		r := executetest.NewResult([]*executetest.Table{{}})
+		r.Nm = "a"
+		q.SetResults(r)
+
+		bridge := query.ProxyQueryServiceAsyncBridge{
+			AsyncQueryService: &mock.AsyncQueryService{
+				QueryF: func(ctx context.Context, req *query.Request) (flux.Query, error) {
+					return q, nil
+				},
+			},
+		}
+		var buf bytes.Buffer
+		if _, err := bridge.Query(context.Background(), &buf, &query.ProxyRequest{
+			Dialect: csv.DefaultDialect(),
+			Explain: explain,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		annotation := query.NotPushedDownAnnotation + ",group2: storage can only group by tags not by _value\r\n"
+		if got := strings.HasSuffix(buf.String(), annotation); got != explain {
+			t.Fatalf("unexpected annotation with explain %v:\n%s", explain, buf.String())
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/ast"
+	"github.com/influxdata/flux/lang"
+	"github.com/influxdata/influxdb"
+)
+
+// ConstantsIdentifier is the identifier the constants of an organization are
+// declared under in its Flux queries, as the constants property of a record.
+const ConstantsIdentifier = "org"
+
+// WithOrgConstants returns compiler with the constants of the organization
+// declared as org.constants, if its Flux query refers to them and does not
+// declare org itself. Other compilers and queries are returned unchanged.
+func WithOrgConstants(ctx context.Context, svc influxdb.ConstantService, orgID influxdb.ID, compiler flux.Compiler) (flux.Compiler, error) {
+	switch c := compiler.(type) {
+	case lang.FluxCompiler:
+		pkg, err := flux.Parse(c.Query)
+		if err != nil || !usesConstants(pkg) {
+			// Parse errors are reported when the query is compiled.
+			return compiler, nil
+		}
+		if c.Extern != nil && declaresConstants(c.Extern) {
+			return compiler, nil
+		}
+		file, err := constantsFile(ctx, svc, orgID)
+		if err != nil {
+			return nil, err
+		}
+		if c.Extern != nil {
+			extern := c.Extern.Copy().(*ast.File)
+			extern.Body = append(extern.Body, file.Body...)
+			file = extern
+		}
+		c.Extern = file
+		return c, nil
+	case lang.ASTCompiler:
+		if c.AST == nil || !usesConstants(c.AST) {
+			return compiler, nil
+		}
+		file, err := constantsFile(ctx, svc, orgID)
+		if err != nil {
+			return nil, err
+		}
+		c.AST = c.AST.Copy().(*ast.Package)
+		c.PrependFile(file)
+		return c, nil
+	}
+	return compiler, nil
+}
+
+// usesConstants returns true if the files of pkg refer to org.constants
+// without declaring org.
+func usesConstants(pkg *ast.Package) bool {
+	var uses bool
+	for _, f := range pkg.Files {
+		if declaresConstants(f) {
+			return false
+		}
+		ast.Visit(f, func(n ast.Node) {
+			m, ok := n.(*ast.MemberExpression)
+			if !ok || m.Property == nil || m.Property.Key() != "constants" {
+				return
+			}
+			if id, ok := m.Object.(*ast.Identifier); ok && id.Name == ConstantsIdentifier {
+				uses = true
+			}
+		})
+	}
+	return uses
+}
+
+// declaresConstants returns true if f declares or imports org.
+func declaresConstants(f *ast.File) bool {
+	for _, imp := range f.Imports {
+		name := path.Base(imp.Path.Value)
+		if imp.As != nil {
+			name = imp.As.Name
+		}
+		if name == ConstantsIdentifier {
+			return true
+		}
+	}
+	for _, st := range f.Body {
+		var va *ast.VariableAssignment
+		switch st := st.(type) {
+		case *ast.VariableAssignment:
+			va = st
+		case *ast.OptionStatement:
+			va, _ = st.Assignment.(*ast.VariableAssignment)
+		}
+		if va != nil && va.ID.Name == ConstantsIdentifier {
+			return true
+		}
+	}
+	return false
+}
+
+// constantsFile returns the file declaring the constants of the organization
+// as the option org = {constants: {...}}.
+func constantsFile(ctx context.Context, svc influxdb.ConstantService, orgID influxdb.ID) (*ast.File, error) {
+	m, err := svc.FindConstants(ctx, orgID)
+	if err != nil {
+		return nil, &flux.Error{
+			Msg: "unable to find the constants of the organization",
+			Err: err,
+		}
+	}
+
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	constants := &ast.ObjectExpression{}
+	for _, name := range names {
+		v, err := constantExpression(m[name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid constant %q: %v", name, err)
+		}
+		constants.Properties = append(constants.Properties, &ast.Property{
+			Key:   &ast.Identifier{Name: name},
+			Value: v,
+		})
+	}
+
+	return &ast.File{
+		Body: []ast.Statement{
+			&ast.OptionStatement{
+				Assignment: &ast.VariableAssignment{
+					ID: &ast.Identifier{Name: ConstantsIdentifier},
+					Init: &ast.ObjectExpression{
+						Properties: []*ast.Property{{
+							Key:   &ast.Identifier{Name: "constants"},
+							Value: constants,
+						}},
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+// constantExpression returns the literal of the value of a constant.
+func constantExpression(v interface{}) (ast.Expression, error) {
+	switch v := v.(type) {
+	case string:
+		return &ast.StringLiteral{Value: v}, nil
+	case int64:
+		return &ast.IntegerLiteral{Value: v}, nil
+	case float64:
+		return &ast.FloatLiteral{Value: v}, nil
+	case bool:
+		return &ast.BooleanLiteral{Value: v}, nil
+	case []interface{}:
+		array := &ast.ArrayExpression{}
+		for _, e := range v {
+			expr, err := constantExpression(e)
+			if err != nil {
+				return nil, err
+			}
+			array.Elements = append(array.Elements, expr)
+		}
+		return array, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", v)
+	}
+}
@@ -0,0 +1,107 @@
+package query_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/ast"
+	"github.com/influxdata/flux/lang"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/query"
+)
+
+type constantService struct {
+	influxdb.ConstantService
+	m map[string]interface{}
+}
+
+func (s constantService) FindConstants(ctx context.Context, orgID influxdb.ID) (map[string]interface{}, error) {
+	return s.m, nil
+}
+
+func TestWithOrgConstants(t *testing.T) {
+	svc := constantService{m: map[string]interface{}{
+		"sla":   99.5,
+		"limit": int64(10),
+		"site":  "lon",
+		"sites": []interface{}{"lon", "nyc"},
+		"on":    true,
+	}}
+	const extern = `option org = {constants: {
+	limit: 10,
+	on: true,
+	site: "lon",
+	sites: ["lon", "nyc"],
+	sla: 99.5,
+}}`
+
+	for _, tt := range []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "uses constants",
+			query: `from(bucket: "b") |> range(start: -1h) |> limit(n: org.constants.limit)`,
+			want:  extern,
+		},
+		{
+			name:  "does not use constants",
+			query: `from(bucket: "b") |> range(start: -1h)`,
+		},
+		{
+			name: "declares org",
+			query: `org = {constants: {limit: 1}}
+from(bucket: "b") |> range(start: -1h) |> limit(n: org.constants.limit)`,
+		},
+		{
+			name: "imports org",
+			query: `import org "strings"
+from(bucket: "b") |> range(start: -1h) |> limit(n: org.constants.limit)`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := query.WithOrgConstants(context.Background(), svc, 1, lang.FluxCompiler{Query: tt.query})
+			if err != nil {
+				t.Fatal(err)
+			}
+			fc := c.(lang.FluxCompiler)
+			if tt.want == "" {
+				if fc.Extern != nil {
+					t.Fatalf("unexpected extern: %s", ast.Format(fc.Extern))
+				}
+				return
+			}
+			if fc.Extern == nil {
+				t.Fatal("expected an extern")
+			}
+			if got := ast.Format(fc.Extern); got != tt.want {
+				t.Fatalf("unexpected extern:\ngot  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithOrgConstants_ASTCompiler(t *testing.T) {
+	svc := constantService{m: map[string]interface{}{"limit": int64(10)}}
+	pkg, err := flux.Parse(`from(bucket: "b") |> range(start: -1h) |> limit(n: org.constants.limit)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := query.WithOrgConstants(context.Background(), svc, 1, lang.ASTCompiler{AST: pkg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ac := c.(lang.ASTCompiler)
+	if len(pkg.Files) != 1 {
+		t.Fatalf("the AST of the query was modified")
+	}
+	if len(ac.AST.Files) != 2 {
+		t.Fatalf("expected the constants to be prepended, got %d file(s)", len(ac.AST.Files))
+	}
+	if got, want := ast.Format(ac.AST.Files[0]), `option org = {constants: {limit: 10}}`; got != want {
+		t.Fatalf("unexpected file:\ngot  %s\nwant %s", got, want)
+	}
+}
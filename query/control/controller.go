@@ -29,11 +29,13 @@ import (
 	"github.com/influxdata/flux/codes"
 	"github.com/influxdata/flux/lang"
 	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/kit/errors"
 	"github.com/influxdata/influxdb/kit/prom"
 	"github.com/influxdata/influxdb/kit/tracing"
 	influxlogger "github.com/influxdata/influxdb/logger"
+	"github.com/influxdata/influxdb/pkg/limiter"
 	"github.com/influxdata/influxdb/query"
 	"github.com/opentracing/opentracing-go"
 	"github.com/prometheus/client_golang/prometheus"
@@ -47,16 +49,17 @@ const orgLabel = "org"
 // Controller provides a central location to manage all incoming queries.
 // The controller is responsible for compiling, queueing, and executing queries.
 type Controller struct {
-	lastID     uint64
-	queriesMu  sync.RWMutex
-	queries    map[QueryID]*Query
-	queryQueue chan *Query
-	wg         sync.WaitGroup
-	shutdown   bool
-	done       chan struct{}
-	abortOnce  sync.Once
-	abort      chan struct{}
-	memory     *memoryManager
+	lastID    uint64
+	queriesMu sync.RWMutex
+	queries   map[QueryID]*Query
+	// queryQueues are the queues of queries awaiting execution, by priority.
+	queryQueues map[query.Priority]chan *Query
+	wg          sync.WaitGroup
+	shutdown    bool
+	done        chan struct{}
+	abortOnce   sync.Once
+	abort       chan struct{}
+	memory      *memoryManager
 
 	metrics   *controllerMetrics
 	labelKeys []string
@@ -64,6 +67,8 @@ type Controller struct {
 	log *zap.Logger
 
 	dependencies []flux.Dependency
+	planMetadata func(*plan.Spec) flux.Metadata
+	constants    influxdb.ConstantService
 }
 
 type Config struct {
@@ -89,8 +94,13 @@ type Config struct {
 	// This number may be less than the ConcurrencyQuota * MemoryBytesQuotaPerQuery.
 	MaxMemoryBytes int64
 
+	// MemoryBudget is an optional memory budget shared with the storage engine.
+	// The memory requested by queries beyond their initial quota is reserved
+	// in it, and requests fail when it is exhausted.
+	MemoryBudget *limiter.Memory
+
 	// QueueSize is the number of queries that are allowed to be awaiting execution before new queries are
-	// rejected.
+	// rejected. Each query priority has its own queue of this size.
 	QueueSize int
 	Logger    *zap.Logger
 	// MetricLabelKeys is a list of labels to add to the metrics produced by the controller.
@@ -99,6 +109,15 @@ type Config struct {
 	MetricLabelKeys []string
 
 	ExecutorDependencies []flux.Dependency
+
+	// PlanMetadata optionally returns metadata describing the physical plan
+	// of a query, such as the operations that were not pushed down to
+	// storage. It is added to the statistics of the query.
+	PlanMetadata func(*plan.Spec) flux.Metadata
+
+	// ConstantService optionally provides the constants of organizations,
+	// declared as org.constants in the Flux queries referring to them.
+	ConstantService influxdb.ConstantService
 }
 
 // complete will fill in the defaults, validate the configuration, and
@@ -161,11 +180,13 @@ func New(config Config) (*Controller, error) {
 		zap.Int64("initial_memory_bytes_quota_per_query", c.InitialMemoryBytesQuotaPerQuery),
 		zap.Int64("memory_bytes_quota_per_query", c.MemoryBytesQuotaPerQuery),
 		zap.Int64("max_memory_bytes", c.MaxMemoryBytes),
+		zap.Int64("memory_budget_bytes", c.MemoryBudget.Limit()),
 		zap.Int("queue_size", c.QueueSize))
 
 	mm := &memoryManager{
 		initialBytesQuotaPerQuery: c.InitialMemoryBytesQuotaPerQuery,
 		memoryBytesQuotaPerQuery:  c.MemoryBytesQuotaPerQuery,
+		budget:                    c.MemoryBudget.Consumer("query"),
 	}
 	if c.MaxMemoryBytes > 0 {
 		mm.unusedMemoryBytes = c.MaxMemoryBytes - (int64(c.ConcurrencyQuota) * c.InitialMemoryBytesQuotaPerQuery)
@@ -174,7 +195,7 @@ func New(config Config) (*Controller, error) {
 	}
 	ctrl := &Controller{
 		queries:      make(map[QueryID]*Query),
-		queryQueue:   make(chan *Query, c.QueueSize),
+		queryQueues:  make(map[query.Priority]chan *Query, len(priorities)),
 		done:         make(chan struct{}),
 		abort:        make(chan struct{}),
 		memory:       mm,
@@ -182,6 +203,11 @@ func New(config Config) (*Controller, error) {
 		metrics:      newControllerMetrics(c.MetricLabelKeys),
 		labelKeys:    c.MetricLabelKeys,
 		dependencies: c.ExecutorDependencies,
+		planMetadata: c.PlanMetadata,
+		constants:    c.ConstantService,
+	}
+	for _, p := range priorities {
+		ctrl.queryQueues[p] = make(chan *Query, c.QueueSize)
 	}
 	ctrl.wg.Add(c.ConcurrencyQuota)
 	for i := 0; i < c.ConcurrencyQuota; i++ {
@@ -202,11 +228,23 @@ func (c *Controller) Query(ctx context.Context, req *query.Request) (flux.Query,
 	ctx = query.ContextWithRequest(ctx, req)
 	// Set the org label value for controller metrics
 	ctx = context.WithValue(ctx, orgLabel, req.OrganizationID.String()) //lint:ignore SA1029 this is a temporary ignore until we have time to create an appropriate type
+	// Limit the rate at which the query reads from disk, allowing bursts of
+	// a second of reads.
+	if req.ReadRateLimit > 0 {
+		ctx = limiter.NewContextWithRate(ctx, limiter.NewRate(int(req.ReadRateLimit), int(req.ReadRateLimit)))
+	}
 	// The controller injects the dependencies for each incoming request.
 	for _, dep := range c.dependencies {
 		ctx = dep.Inject(ctx)
 	}
-	q, err := c.query(ctx, req.Compiler)
+	compiler := req.Compiler
+	if c.constants != nil {
+		var err error
+		if compiler, err = query.WithOrgConstants(ctx, c.constants, req.OrganizationID, compiler); err != nil {
+			return nil, err
+		}
+	}
+	q, err := c.query(ctx, compiler, req.Priority)
 	if err != nil {
 		return q, err
 	}
@@ -216,11 +254,12 @@ func (c *Controller) Query(ctx context.Context, req *query.Request) (flux.Query,
 
 // query submits a query for execution returning immediately.
 // Done must be called on any returned Query objects.
-func (c *Controller) query(ctx context.Context, compiler flux.Compiler) (flux.Query, error) {
+func (c *Controller) query(ctx context.Context, compiler flux.Compiler, priority query.Priority) (flux.Query, error) {
 	q, err := c.createQuery(ctx, compiler.CompilerType())
 	if err != nil {
 		return nil, handleFluxError(err)
 	}
+	q.priority = priority
 
 	if err := c.compileQuery(q, compiler); err != nil {
 		q.setErr(err)
@@ -362,8 +401,12 @@ func (c *Controller) enqueueQuery(q *Query) error {
 		}
 	}
 
+	queue, ok := c.queryQueues[q.priority]
+	if !ok {
+		queue = c.queryQueues[query.PriorityNormal]
+	}
 	select {
-	case c.queryQueue <- q:
+	case queue <- q:
 	default:
 		return &flux.Error{
 			Code: codes.ResourceExhausted,
@@ -374,14 +417,40 @@ func (c *Controller) enqueueQuery(q *Query) error {
 	return nil
 }
 
+// priorities are the query priorities, from the highest to the lowest.
+var priorities = []query.Priority{query.PriorityHigh, query.PriorityNormal, query.PriorityLow}
+
 func (c *Controller) processQueryQueue() {
 	for {
-		select {
-		case <-c.done:
+		q, ok := c.nextQuery()
+		if !ok {
 			return
-		case q := <-c.queryQueue:
-			c.executeQuery(q)
 		}
+		c.executeQuery(q)
+	}
+}
+
+// nextQuery waits for the next query to execute, the oldest of the queries
+// of the highest priority awaiting execution. It returns false once the
+// controller is done.
+func (c *Controller) nextQuery() (*Query, bool) {
+	for _, p := range priorities {
+		select {
+		case q := <-c.queryQueues[p]:
+			return q, true
+		default:
+		}
+	}
+
+	select {
+	case <-c.done:
+		return nil, false
+	case q := <-c.queryQueues[query.PriorityHigh]:
+		return q, true
+	case q := <-c.queryQueues[query.PriorityNormal]:
+		return q, true
+	case q := <-c.queryQueues[query.PriorityLow]:
+		return q, true
 	}
 }
 
@@ -425,9 +494,26 @@ func (c *Controller) executeQuery(q *Query) {
 		return
 	}
 	q.exec = exec
+	if c.planMetadata != nil {
+		if ps := planSpec(q.program); ps != nil {
+			q.planMetadata = c.planMetadata(ps)
+		}
+	}
 	q.pump(exec, ctx.Done())
 }
 
+// planSpec returns the physical plan of a started program, or nil if the
+// program does not expose it.
+func planSpec(p flux.Program) *plan.Spec {
+	switch p := p.(type) {
+	case *lang.AstProgram:
+		return p.PlanSpec
+	case *lang.Program:
+		return p.PlanSpec
+	}
+	return nil
+}
+
 // waitForQuery will wait until the query is done.
 func (c *Controller) waitForQuery(q *Query) {
 	select {
@@ -507,7 +593,8 @@ func (c *Controller) PrometheusCollectors() []prometheus.Collector {
 
 // Query represents a single request.
 type Query struct {
-	id QueryID
+	id       QueryID
+	priority query.Priority
 
 	labelValues        []string
 	compileLabelValues []string
@@ -528,9 +615,10 @@ type Query struct {
 	done   sync.Once
 	doneCh chan struct{}
 
-	program flux.Program
-	exec    flux.Query
-	results chan flux.Result
+	program      flux.Program
+	exec         flux.Query
+	results      chan flux.Result
+	planMetadata flux.Metadata
 
 	memoryManager *queryMemoryManager
 	alloc         *memory.Allocator
@@ -541,6 +629,11 @@ func (q *Query) ID() QueryID {
 	return q.id
 }
 
+// Priority reports the scheduling priority of the query.
+func (q *Query) Priority() query.Priority {
+	return q.priority
+}
+
 // Cancel will stop the query execution.
 func (q *Query) Cancel() {
 	// Call the cancel function to signal that execution should
@@ -598,6 +691,12 @@ func (q *Query) Done() {
 			// Merge the metadata from the program into the controller stats.
 			stats := q.exec.Statistics()
 			q.stats.Metadata = stats.Metadata
+			if len(q.planMetadata) > 0 {
+				if q.stats.Metadata == nil {
+					q.stats.Metadata = make(flux.Metadata)
+				}
+				q.stats.Metadata.AddAll(q.planMetadata)
+			}
 		}
 
 		// Retrieve the runtime errors that have been accumulated.
@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/influxdata/flux"
 	"github.com/influxdata/flux/arrow"
 	"github.com/influxdata/flux/codes"
@@ -797,6 +798,77 @@ func TestController_QueueSize(t *testing.T) {
 	}
 }
 
+func TestController_Priority(t *testing.T) {
+	config := config
+	config.ConcurrencyQuota = 1
+	config.QueueSize = 2
+	ctrl, err := control.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(t, ctrl)
+
+	started := make(chan query.Priority, 5)
+	block := make(chan struct{})
+	compiler := func(p query.Priority) flux.Compiler {
+		return &mock.Compiler{
+			CompileFn: func(ctx context.Context) (flux.Program, error) {
+				return &mock.Program{
+					ExecuteFn: func(ctx context.Context, q *mock.Query, alloc *memory.Allocator) {
+						started <- p
+						if p == query.PriorityNormal {
+							<-block
+						}
+					},
+				}, nil
+			},
+		}
+	}
+
+	var queries []flux.Query
+	run := func(p query.Priority) {
+		req := makeRequest(compiler(p))
+		req.Priority = p
+		q, err := ctrl.Query(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		queries = append(queries, q)
+	}
+
+	// Occupy the only execution slot, then queue queries of each priority.
+	run(query.PriorityNormal)
+	if p := <-started; p != query.PriorityNormal {
+		t.Fatalf("unexpected first query: %v", p)
+	}
+	run(query.PriorityLow)
+	run(query.PriorityHigh)
+	run(query.PriorityLow)
+	run(query.PriorityHigh)
+	close(block)
+
+	// A query holds its execution slot until it is done.
+	var wg sync.WaitGroup
+	for _, q := range queries {
+		wg.Add(1)
+		go func(q flux.Query) {
+			defer wg.Done()
+			consumeResults(t, q)
+		}(q)
+	}
+	wg.Wait()
+	close(started)
+
+	var got []query.Priority
+	for p := range started {
+		got = append(got, p)
+	}
+	want := []query.Priority{query.PriorityHigh, query.PriorityHigh, query.PriorityLow, query.PriorityLow}
+	if !cmp.Equal(want, got) {
+		t.Fatalf("unexpected execution order -want/+got\n%s", cmp.Diff(want, got))
+	}
+}
+
 // Test that rapidly starting and canceling the query and then calling done will correctly
 // cancel the query and not result in a race condition.
 func TestController_CancelDone(t *testing.T) {
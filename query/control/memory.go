@@ -6,6 +6,7 @@ import (
 	"sync/atomic"
 
 	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/influxdb/pkg/limiter"
 )
 
 type memoryManager struct {
@@ -26,6 +27,10 @@ type memoryManager struct {
 	// unlimited indicates that the memory manager should indicate
 	// there is an unlimited amount of free memory available.
 	unlimited bool
+
+	// budget accounts for the memory given to queries in a memory
+	// budget shared with the storage engine.
+	budget *limiter.MemoryConsumer
 }
 
 // createAllocator will construct an allocator and memory manager
@@ -82,11 +87,21 @@ func (q *queryMemoryManager) RequestMemory(want int64) (got int64, err error) {
 		// this method.
 		given := q.giveMemory(want, unused)
 
+		// Reserve this memory in the budget shared with the storage engine,
+		// falling back to the bare amount wanted.
+		if !q.m.budget.TryReserve(given) {
+			if given == want || !q.m.budget.TryReserve(want) {
+				return 0, errors.New("memory budget exhausted")
+			}
+			given = want
+		}
+
 		// Reserve this memory for our own use.
 		if !q.m.unlimited {
 			if !atomic.CompareAndSwapInt64(&q.m.unusedMemoryBytes, unused, unused-given) {
 				// The unused value has changed so someone may have taken
 				// the memory that we wanted. Retry.
+				q.m.budget.Release(given)
 				continue
 			}
 		}
@@ -140,6 +155,7 @@ func (q *queryMemoryManager) Release() {
 	if !q.m.unlimited {
 		atomic.AddInt64(&q.m.unusedMemoryBytes, q.given)
 	}
+	q.m.budget.Release(q.given)
 	q.limit = q.m.initialBytesQuotaPerQuery
 	q.given = 0
 }
@@ -1,8 +1,16 @@
 package query
 
 import (
+	"bufio"
+	"bytes"
+	stdcsv "encoding/csv"
+	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/influxdata/flux"
 	"github.com/influxdata/flux/csv"
@@ -25,6 +33,247 @@ func AddDialectMappings(mappings flux.DialectMappings) error {
 	})
 }
 
+// TruncatedAnnotation is the CSV annotation that ends a result truncated by a
+// limit. Its only value is the name of the limit, such as "rows" or "bytes".
+// Decoders ignore it as an unknown annotation.
+const TruncatedAnnotation = "#truncated"
+
+// EncodeTruncation marks the results encoded to w with dialect d as truncated by limit.
+// Only CSV results are marked here, other dialects mark results truncated by a
+// LimitedResultIterator themselves or have no result to mark.
+func EncodeTruncation(w io.Writer, d flux.Dialect, limit string) error {
+	cd, ok := d.(*csv.Dialect)
+	if !ok {
+		return nil
+	}
+
+	writer := stdcsv.NewWriter(w)
+	writer.UseCRLF = true
+	if cd.ResultEncoderConfig.Delimiter != 0 {
+		writer.Comma = cd.ResultEncoderConfig.Delimiter
+	}
+	if err := writer.Write([]string{TruncatedAnnotation, limit}); err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// NotPushedDownMetadataKey is the key of the query metadata listing the
+// operations that process data read from storage but were not pushed down to
+// storage, each with the reason it was not.
+const NotPushedDownMetadataKey = "influxdb/not-pushed-down"
+
+// QueryPlanMetadataKey is the key of the query metadata holding the physical
+// plan of the query, formatted with its details.
+const QueryPlanMetadataKey = "influxdb/query-plan"
+
+// NotPushedDownAnnotation is the CSV annotation that ends the results of a
+// query requested with Explain, once for each operation that was not pushed
+// down to storage. Its only value is the operation and the reason it was not
+// pushed down. Decoders ignore it as an unknown annotation.
+const NotPushedDownAnnotation = "#not-pushed-down"
+
+// EncodeNotPushedDown appends the NotPushedDownMetadataKey metadata of stats
+// to the results encoded to w with dialect d. Only CSV results are annotated.
+func EncodeNotPushedDown(w io.Writer, d flux.Dialect, stats flux.Statistics) error {
+	cd, ok := d.(*csv.Dialect)
+	if !ok {
+		return nil
+	}
+	diags := stats.Metadata[NotPushedDownMetadataKey]
+	if len(diags) == 0 {
+		return nil
+	}
+
+	writer := stdcsv.NewWriter(w)
+	writer.UseCRLF = true
+	if cd.ResultEncoderConfig.Delimiter != 0 {
+		writer.Comma = cd.ResultEncoderConfig.Delimiter
+	}
+	for _, diag := range diags {
+		if err := writer.Write([]string{NotPushedDownAnnotation, fmt.Sprint(diag)}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// The profilers of the statistics of a query.
+const (
+	// QueryProfiler reports the durations of the stages of the query, its
+	// concurrency and the memory it allocated.
+	QueryProfiler = "query"
+	// OperatorProfiler reports the metadata of the operators of the query,
+	// such as the values and bytes scanned by each read from storage.
+	OperatorProfiler = "operator"
+)
+
+// ValidProfiler returns an error if name is not a known profiler.
+func ValidProfiler(name string) error {
+	switch name {
+	case QueryProfiler, OperatorProfiler:
+		return nil
+	}
+	return fmt.Errorf("unknown profiler %q: must be %s or %s", name, QueryProfiler, OperatorProfiler)
+}
+
+// ProfileAnnotation is the CSV annotation that ends the results of a query
+// requested with profilers, once for each value of a statistic reported by a
+// profiler. Its only value is the profiler, the name of the statistic and the
+// value, as profiler:name=value, so that it has as many fields as the other
+// annotations ending the results. Decoders ignore it as an unknown annotation.
+const ProfileAnnotation = "#profile"
+
+// EncodeProfile appends the statistics reported by the profilers to the
+// results encoded to w with dialect d. Only CSV results are annotated.
+// Durations are encoded in nanoseconds.
+func EncodeProfile(w io.Writer, d flux.Dialect, profilers []string, stats flux.Statistics) error {
+	cd, ok := d.(*csv.Dialect)
+	if !ok {
+		return nil
+	}
+
+	writer := stdcsv.NewWriter(w)
+	writer.UseCRLF = true
+	if cd.ResultEncoderConfig.Delimiter != 0 {
+		writer.Comma = cd.ResultEncoderConfig.Delimiter
+	}
+	for _, profiler := range profilers {
+		var records [][]string
+		switch profiler {
+		case QueryProfiler:
+			for _, s := range []struct {
+				name  string
+				value int64
+			}{
+				{"total_duration", int64(stats.TotalDuration)},
+				{"compile_duration", int64(stats.CompileDuration)},
+				{"queue_duration", int64(stats.QueueDuration)},
+				{"plan_duration", int64(stats.PlanDuration)},
+				{"requeue_duration", int64(stats.RequeueDuration)},
+				{"execute_duration", int64(stats.ExecuteDuration)},
+				{"concurrency", int64(stats.Concurrency)},
+				{"max_allocated", stats.MaxAllocated},
+				{"total_allocated", stats.TotalAllocated},
+			} {
+				records = append(records, profileRecord(profiler, s.name, strconv.FormatInt(s.value, 10)))
+			}
+		case OperatorProfiler:
+			keys := make([]string, 0, len(stats.Metadata))
+			for k := range stats.Metadata {
+				if k == QueryPlanMetadataKey {
+					// The plan is not a statistic of the operators.
+					continue
+				}
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				for _, v := range stats.Metadata[k] {
+					records = append(records, profileRecord(profiler, k, fmt.Sprint(v)))
+				}
+			}
+		default:
+			return ValidProfiler(profiler)
+		}
+		if err := writer.WriteAll(records); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func profileRecord(profiler, name, value string) []string {
+	return []string{ProfileAnnotation, profiler + ":" + name + "=" + value}
+}
+
+// ProfileReader reads CSV results, decoding the statistics of the
+// ProfileAnnotation annotations that end them. The statistics of the query
+// profiler are decoded into their fields, and those of the operator profiler
+// into the metadata, as strings.
+type ProfileReader struct {
+	r     *bufio.Reader
+	line  []byte
+	err   error
+	stats flux.Statistics
+}
+
+// NewProfileReader returns a ProfileReader reading the results from r.
+func NewProfileReader(r io.Reader) *ProfileReader {
+	return &ProfileReader{r: bufio.NewReader(r)}
+}
+
+// Read reads the results unchanged, a line at a time.
+func (r *ProfileReader) Read(p []byte) (int, error) {
+	for len(r.line) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.line, r.err = r.r.ReadBytes('\n')
+		if bytes.HasPrefix(r.line, []byte(ProfileAnnotation+",")) {
+			r.decode(r.line)
+		}
+	}
+	n := copy(p, r.line)
+	r.line = r.line[n:]
+	return n, nil
+}
+
+// decode decodes the statistic of the annotation line, ignoring malformed ones.
+func (r *ProfileReader) decode(line []byte) {
+	cr := stdcsv.NewReader(bytes.NewReader(line))
+	cr.FieldsPerRecord = -1
+	record, err := cr.Read()
+	if err != nil || len(record) != 2 {
+		return
+	}
+	i, j := strings.Index(record[1], ":"), strings.Index(record[1], "=")
+	if i < 0 || j < i {
+		return
+	}
+	profiler, name, value := record[1][:i], record[1][i+1:j], record[1][j+1:]
+	switch profiler {
+	case QueryProfiler:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return
+		}
+		switch name {
+		case "total_duration":
+			r.stats.TotalDuration = time.Duration(v)
+		case "compile_duration":
+			r.stats.CompileDuration = time.Duration(v)
+		case "queue_duration":
+			r.stats.QueueDuration = time.Duration(v)
+		case "plan_duration":
+			r.stats.PlanDuration = time.Duration(v)
+		case "requeue_duration":
+			r.stats.RequeueDuration = time.Duration(v)
+		case "execute_duration":
+			r.stats.ExecuteDuration = time.Duration(v)
+		case "concurrency":
+			r.stats.Concurrency = int(v)
+		case "max_allocated":
+			r.stats.MaxAllocated = v
+		case "total_allocated":
+			r.stats.TotalAllocated = v
+		}
+	case OperatorProfiler:
+		if r.stats.Metadata == nil {
+			r.stats.Metadata = make(flux.Metadata)
+		}
+		r.stats.Metadata.Add(name, value)
+	}
+}
+
+// Statistics returns the statistics decoded from the results read so far.
+func (r *ProfileReader) Statistics() flux.Statistics {
+	return r.stats
+}
+
 // NoContentDialect is a dialect that provides an Encoder that discards query results.
 // When invoking `dialect.Encoder().Encode(writer, results)`, `results` get consumed,
 // while the `writer` is left intact.
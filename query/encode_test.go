@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/influxdata/flux"
@@ -127,3 +129,70 @@ func TestReturnNoContent(t *testing.T) {
 		})
 	}
 }
+
+func TestEncodeProfile(t *testing.T) {
+	r := executetest.NewResult([]*executetest.Table{{
+		ColMeta: []flux.ColMeta{
+			{Label: "_time", Type: flux.TTime},
+			{Label: "_value", Type: flux.TFloat},
+		},
+		Data: [][]interface{}{
+			{execute.Time(0), 1.0},
+		},
+	}})
+	r.Nm = "a"
+	stats := flux.Statistics{
+		TotalDuration:   10 * time.Millisecond,
+		CompileDuration: time.Millisecond,
+		ExecuteDuration: 8 * time.Millisecond,
+		Concurrency:     2,
+		MaxAllocated:    1024,
+		TotalAllocated:  4096,
+		Metadata: flux.Metadata{
+			"influxdb/scanned-values": []interface{}{int64(10), int64(20)},
+		},
+	}
+
+	d := csv.DefaultDialect()
+	var buf bytes.Buffer
+	if _, err := d.Encoder().Encode(&buf, flux.NewSliceResultIterator([]flux.Result{r})); err != nil {
+		t.Fatal(err)
+	}
+	if err := query.EncodeProfile(&buf, d, []string{query.QueryProfiler, query.OperatorProfiler}, stats); err != nil {
+		t.Fatal(err)
+	}
+
+	pr := query.NewProfileReader(&buf)
+	results, err := csv.NewMultiResultDecoder(csv.ResultDecoderConfig{}).Decode(ioutil.NopCloser(pr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for results.More() {
+		if err := results.Next().Tables().Do(func(tbl flux.Table) error {
+			n++
+			return tbl.Do(func(flux.ColReader) error { return nil })
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	results.Release()
+	if err := results.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("unexpected number of tables: %d", n)
+	}
+
+	want := stats
+	want.Metadata = flux.Metadata{
+		"influxdb/scanned-values": []interface{}{"10", "20"},
+	}
+	if got := pr.Statistics(); !cmp.Equal(got, want) {
+		t.Fatalf("unexpected statistics: -got/+want\n%s", cmp.Diff(got, want))
+	}
+
+	if err := query.EncodeProfile(&buf, d, []string{"memory"}, stats); err == nil {
+		t.Fatal("expected error for an unknown profiler")
+	}
+}
@@ -30,6 +30,7 @@ type MultiResultEncoder struct{}
 func (e *MultiResultEncoder) Encode(w io.Writer, results flux.ResultIterator) (int64, error) {
 	resp := Response{}
 	wc := &iocounter.Writer{Writer: w}
+	partial := false
 
 	for results.More() {
 		res := results.Next()
@@ -175,6 +176,17 @@ func (e *MultiResultEncoder) Encode(w io.Writer, results flux.ResultIterator) (i
 			results.Release()
 			break
 		}
+
+		// Mark the result during which a limit was reached, the following
+		// results have no series.
+		if limit := truncated(results); limit != "" && !partial {
+			partial = true
+			result.Partial = true
+			result.Messages = append(result.Messages, &Message{
+				Level: "warning",
+				Text:  fmt.Sprintf("results truncated by the %s limit", limit),
+			})
+		}
 		resp.Results = append(resp.Results, result)
 	}
 
@@ -185,6 +197,14 @@ func (e *MultiResultEncoder) Encode(w io.Writer, results flux.ResultIterator) (i
 	err := json.NewEncoder(wc).Encode(resp)
 	return wc.Count(), err
 }
+// truncated returns the name of the limit that truncated results, if any.
+func truncated(results flux.ResultIterator) string {
+	if tr, ok := results.(interface{ Truncated() string }); ok {
+		return tr.Truncated()
+	}
+	return ""
+}
+
 func NewMultiResultEncoder() *MultiResultEncoder {
 	return new(MultiResultEncoder)
 }
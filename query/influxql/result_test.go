@@ -10,6 +10,8 @@ import (
 	"github.com/influxdata/flux"
 	"github.com/influxdata/flux/execute"
 	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/query"
 	"github.com/influxdata/influxdb/query/influxql"
 )
 
@@ -78,6 +80,27 @@ func TestMultiResultEncoder_Encode(t *testing.T) {
 			),
 			out: `{"results":[{"statement_id":0,"series":[{"columns":["name"],"values":[["telegraf"]]}]}]}`,
 		},
+		{
+			name: "Truncated",
+			in: query.LimitResults(flux.NewSliceResultIterator(
+				[]flux.Result{&executetest.Result{
+					Nm: "0",
+					Tbls: []*executetest.Table{{
+						KeyCols: []string{"_measurement"},
+						ColMeta: []flux.ColMeta{
+							{Label: "_time", Type: flux.TTime},
+							{Label: "_measurement", Type: flux.TString},
+							{Label: "value", Type: flux.TFloat},
+						},
+						Data: [][]interface{}{
+							{ts("2018-05-24T09:00:00Z"), "m0", float64(2)},
+							{ts("2018-05-24T09:00:10Z"), "m0", float64(3)},
+						},
+					}},
+				}},
+			), influxdb.QueryResultLimits{MaxRows: 1}),
+			out: `{"results":[{"statement_id":0,"series":[{"name":"m0","columns":["time","value"],"values":[["2018-05-24T09:00:00Z",2]]}],"messages":[{"level":"warning","text":"results truncated by the rows limit"}],"partial":true}]}`,
+		},
 		{
 			name: "Error",
 			in:   &resultErrorIterator{Error: "expected"},
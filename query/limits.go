@@ -0,0 +1,183 @@
+package query
+
+import (
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/arrow"
+	platform "github.com/influxdata/influxdb"
+)
+
+// Names of the limits that truncate a result.
+const (
+	TruncatedByRows  = "rows"
+	TruncatedByBytes = "bytes"
+)
+
+// LimitedResultIterator is a flux.ResultIterator that stops producing rows
+// once a limit is reached, rather than failing the query.
+//
+// The row that reaches a limit is always produced, so that a table is never
+// left without rows. Tables produced after a limit was reached are discarded.
+type LimitedResultIterator struct {
+	flux.ResultIterator
+
+	limits    platform.QueryResultLimits
+	rows      int64
+	bytes     int64
+	truncated string
+}
+
+// LimitResults returns results limited by limits.
+func LimitResults(results flux.ResultIterator, limits platform.QueryResultLimits) *LimitedResultIterator {
+	return &LimitedResultIterator{
+		ResultIterator: results,
+		limits:         limits,
+	}
+}
+
+// Truncated returns the name of the limit that truncated the results,
+// or an empty string if the results are complete.
+func (it *LimitedResultIterator) Truncated() string {
+	return it.truncated
+}
+
+func (it *LimitedResultIterator) Next() flux.Result {
+	return &limitedResult{Result: it.ResultIterator.Next(), it: it}
+}
+
+// reached returns the name of the limit that was reached, if any.
+func (it *LimitedResultIterator) reached() string {
+	if it.limits.MaxRows > 0 && it.rows >= it.limits.MaxRows {
+		return TruncatedByRows
+	} else if it.limits.MaxBytes > 0 && it.bytes >= it.limits.MaxBytes {
+		return TruncatedByBytes
+	}
+	return ""
+}
+
+// take accounts for the rows of cr that fit within the limits, and returns their number.
+func (it *LimitedResultIterator) take(cr flux.ColReader) int {
+	n := cr.Len()
+	for i := 0; i < n; i++ {
+		if limit := it.reached(); limit != "" {
+			it.truncated = limit
+			return i
+		}
+		it.rows++
+		if it.limits.MaxBytes > 0 {
+			it.bytes += rowSize(cr, i)
+		}
+	}
+	return n
+}
+
+// rowSize returns the size of the values of row i of cr.
+func rowSize(cr flux.ColReader, i int) int64 {
+	var n int64
+	for j, c := range cr.Cols() {
+		switch c.Type {
+		case flux.TBool:
+			n++
+		case flux.TInt, flux.TUInt, flux.TFloat, flux.TTime:
+			n += 8
+		case flux.TString:
+			n += int64(cr.Strings(j).ValueLen(i))
+		}
+	}
+	return n
+}
+
+type limitedResult struct {
+	flux.Result
+	it *LimitedResultIterator
+}
+
+func (r *limitedResult) Tables() flux.TableIterator {
+	return &limitedTableIterator{TableIterator: r.Result.Tables(), it: r.it}
+}
+
+type limitedTableIterator struct {
+	flux.TableIterator
+	it *LimitedResultIterator
+}
+
+func (ti *limitedTableIterator) Do(f func(flux.Table) error) error {
+	return ti.TableIterator.Do(func(tbl flux.Table) error {
+		if limit := ti.it.reached(); limit != "" {
+			if !tbl.Empty() {
+				ti.it.truncated = limit
+			}
+			tbl.Done()
+			return nil
+		}
+		return f(&limitedTable{Table: tbl, it: ti.it})
+	})
+}
+
+type limitedTable struct {
+	flux.Table
+	it *LimitedResultIterator
+}
+
+func (t *limitedTable) Do(f func(flux.ColReader) error) error {
+	return t.Table.Do(func(cr flux.ColReader) error {
+		n := t.it.take(cr)
+		if n == 0 {
+			return nil
+		} else if n == cr.Len() {
+			return f(cr)
+		}
+
+		lcr := &limitedColReader{ColReader: cr, n: n}
+		defer lcr.release()
+		return f(lcr)
+	})
+}
+
+// limitedColReader is a flux.ColReader limited to its first n rows.
+type limitedColReader struct {
+	flux.ColReader
+	n      int
+	slices []array.Interface
+}
+
+func (cr *limitedColReader) Len() int {
+	return cr.n
+}
+
+func (cr *limitedColReader) Bools(j int) *array.Boolean {
+	return cr.slice(arrow.BoolSlice(cr.ColReader.Bools(j), 0, cr.n)).(*array.Boolean)
+}
+
+func (cr *limitedColReader) Ints(j int) *array.Int64 {
+	return cr.slice(arrow.IntSlice(cr.ColReader.Ints(j), 0, cr.n)).(*array.Int64)
+}
+
+func (cr *limitedColReader) UInts(j int) *array.Uint64 {
+	return cr.slice(arrow.UintSlice(cr.ColReader.UInts(j), 0, cr.n)).(*array.Uint64)
+}
+
+func (cr *limitedColReader) Floats(j int) *array.Float64 {
+	return cr.slice(arrow.FloatSlice(cr.ColReader.Floats(j), 0, cr.n)).(*array.Float64)
+}
+
+func (cr *limitedColReader) Strings(j int) *array.Binary {
+	return cr.slice(arrow.StringSlice(cr.ColReader.Strings(j), 0, cr.n)).(*array.Binary)
+}
+
+func (cr *limitedColReader) Times(j int) *array.Int64 {
+	return cr.slice(arrow.IntSlice(cr.ColReader.Times(j), 0, cr.n)).(*array.Int64)
+}
+
+// slice records a slice of a column so that it is released with cr.
+func (cr *limitedColReader) slice(arr array.Interface) array.Interface {
+	cr.slices = append(cr.slices, arr)
+	return arr
+}
+
+func (cr *limitedColReader) release() {
+	for _, arr := range cr.slices {
+		arr.Release()
+	}
+	cr.slices = nil
+}
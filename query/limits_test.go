@@ -0,0 +1,112 @@
+package query_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/csv"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/query/mock"
+)
+
+func limitsTestResult() *executetest.Result {
+	cols := []flux.ColMeta{
+		{Label: "_time", Type: flux.TTime},
+		{Label: "host", Type: flux.TString},
+		{Label: "_value", Type: flux.TFloat},
+	}
+	r := executetest.NewResult([]*executetest.Table{
+		{
+			KeyCols: []string{"host"},
+			ColMeta: cols,
+			Data: [][]interface{}{
+				{execute.Time(1), "a", 1.0},
+				{execute.Time(2), "a", 2.0},
+				{execute.Time(3), "a", 3.0},
+			},
+		},
+		{
+			KeyCols: []string{"host"},
+			ColMeta: cols,
+			Data: [][]interface{}{
+				{execute.Time(1), "b", 4.0},
+				{execute.Time(2), "b", 5.0},
+			},
+		},
+	})
+	r.Nm = "_result"
+	return r
+}
+
+func TestProxyQueryServiceAsyncBridge_Limits(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		limits    *influxdb.QueryResultLimits
+		rows      int
+		truncated string
+	}{
+		{name: "no limits", rows: 5},
+		{name: "rows", limits: &influxdb.QueryResultLimits{MaxRows: 4}, rows: 4, truncated: query.TruncatedByRows},
+		{name: "rows at table end", limits: &influxdb.QueryResultLimits{MaxRows: 3}, rows: 3, truncated: query.TruncatedByRows},
+		{name: "rows not reached", limits: &influxdb.QueryResultLimits{MaxRows: 5}, rows: 5},
+		// Each row is 8 bytes of time, 1 byte of host and 8 bytes of value.
+		{name: "bytes", limits: &influxdb.QueryResultLimits{MaxBytes: 20}, rows: 2, truncated: query.TruncatedByBytes},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			q := mock.NewQuery()
+			q.SetResults(limitsTestResult())
+			bridge := query.ProxyQueryServiceAsyncBridge{
+				AsyncQueryService: &mock.AsyncQueryService{
+					QueryF: func(ctx context.Context, req *query.Request) (flux.Query, error) {
+						return q, nil
+					},
+				},
+			}
+
+			var buf bytes.Buffer
+			if _, err := bridge.Query(context.Background(), &buf, &query.ProxyRequest{
+				Dialect: csv.DefaultDialect(),
+				Limits:  tt.limits,
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			annotation := query.TruncatedAnnotation + "," + tt.truncated + "\r\n"
+			if got := strings.HasSuffix(buf.String(), annotation); got != (tt.truncated != "") {
+				t.Fatalf("unexpected truncation annotation, want %q in:\n%s", tt.truncated, buf.String())
+			}
+
+			// The annotation must not prevent decoding the partial result.
+			results, err := csv.NewMultiResultDecoder(csv.ResultDecoderConfig{}).Decode(ioutil.NopCloser(&buf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer results.Release()
+
+			rows := 0
+			for results.More() {
+				if err := results.Next().Tables().Do(func(tbl flux.Table) error {
+					return tbl.Do(func(cr flux.ColReader) error {
+						rows += cr.Len()
+						return nil
+					})
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := results.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if rows != tt.rows {
+				t.Fatalf("unexpected number of rows: got %d, want %d", rows, tt.rows)
+			}
+		})
+	}
+}
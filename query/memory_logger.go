@@ -0,0 +1,94 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	platform "github.com/influxdata/influxdb"
+)
+
+// MemoryLogger is a Logger keeping in memory the most recent queries, and the
+// most recent queries lasting at least a threshold, which support bundles
+// collect with their request and plan.
+type MemoryLogger struct {
+	size          int
+	slowThreshold time.Duration
+
+	mu     sync.Mutex
+	recent []platform.QueryLogEntry
+	slow   []platform.QueryLogEntry
+}
+
+// NewMemoryLogger returns a MemoryLogger keeping the size most recent queries,
+// and the size most recent queries lasting at least slowThreshold.
+func NewMemoryLogger(size int, slowThreshold time.Duration) *MemoryLogger {
+	return &MemoryLogger{
+		size:          size,
+		slowThreshold: slowThreshold,
+	}
+}
+
+// Log records the query of log.
+func (l *MemoryLogger) Log(log Log) error {
+	if l.size <= 0 {
+		return nil
+	}
+
+	e := platform.QueryLogEntry{
+		Time:           log.Time,
+		OrganizationID: log.OrganizationID,
+		Duration:       log.Statistics.TotalDuration,
+		ResponseSize:   log.ResponseSize,
+	}
+	if log.ProxyRequest != nil && log.ProxyRequest.Request.Compiler != nil && log.ProxyRequest.Dialect != nil {
+		req := *log.ProxyRequest
+		req.Request.Authorization = nil
+		if body, err := json.Marshal(req); err == nil {
+			e.Request = body
+		}
+	}
+	if plans := log.Statistics.Metadata[QueryPlanMetadataKey]; len(plans) > 0 {
+		e.Plan, _ = plans[0].(string)
+	}
+	if log.Error != nil {
+		e.Error = log.Error.Error()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.recent = l.append(l.recent, e)
+	if e.Duration >= l.slowThreshold {
+		l.slow = l.append(l.slow, e)
+	}
+	return nil
+}
+
+// append appends e to entries, dropping the oldest entry beyond size.
+func (l *MemoryLogger) append(entries []platform.QueryLogEntry, e platform.QueryLogEntry) []platform.QueryLogEntry {
+	if len(entries) >= l.size {
+		copy(entries, entries[1:])
+		entries = entries[:len(entries)-1]
+	}
+	return append(entries, e)
+}
+
+// QueryLog returns the queries recorded, most recent first.
+func (l *MemoryLogger) QueryLog(ctx context.Context) (*platform.QueryLog, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return &platform.QueryLog{
+		SlowThreshold: l.slowThreshold,
+		Recent:        reversed(l.recent),
+		Slow:          reversed(l.slow),
+	}, nil
+}
+
+func reversed(entries []platform.QueryLogEntry) []platform.QueryLogEntry {
+	r := make([]platform.QueryLogEntry, len(entries))
+	for i, e := range entries {
+		r[len(entries)-1-i] = e
+	}
+	return r
+}
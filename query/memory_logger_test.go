@@ -0,0 +1,70 @@
+package query_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/csv"
+	"github.com/influxdata/flux/lang"
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/query"
+)
+
+func TestMemoryLogger(t *testing.T) {
+	l := query.NewMemoryLogger(2, time.Second)
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, d := range []time.Duration{2 * time.Second, time.Millisecond, 3 * time.Second, time.Millisecond} {
+		log := query.Log{
+			Time:           now.Add(time.Duration(i) * time.Minute),
+			OrganizationID: orgID,
+			ProxyRequest: &query.ProxyRequest{
+				Request: query.Request{
+					Authorization:  &platform.Authorization{Token: "secret"},
+					OrganizationID: orgID,
+					Compiler:       lang.FluxCompiler{Query: `from(bucket: "b") |> range(start: -1h)`},
+				},
+				Dialect: csv.DefaultDialect(),
+			},
+			ResponseSize: int64(i),
+			Statistics: flux.Statistics{
+				TotalDuration: d,
+				Metadata:      flux.Metadata{query.QueryPlanMetadataKey: []interface{}{"digraph {}"}},
+			},
+		}
+		if i == 3 {
+			log.Error = errors.New("expected")
+		}
+		if err := l.Log(log); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ql, err := l.QueryLog(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ql.SlowThreshold != time.Second {
+		t.Errorf("unexpected slow threshold: %v", ql.SlowThreshold)
+	}
+
+	// Only the most recent queries are kept, most recent first.
+	if len(ql.Recent) != 2 || ql.Recent[0].ResponseSize != 3 || ql.Recent[1].ResponseSize != 2 {
+		t.Fatalf("unexpected recent queries: %+v", ql.Recent)
+	}
+	if len(ql.Slow) != 2 || ql.Slow[0].Duration != 3*time.Second || ql.Slow[1].Duration != 2*time.Second {
+		t.Fatalf("unexpected slow queries: %+v", ql.Slow)
+	}
+
+	e := ql.Recent[0]
+	if e.Error != "expected" || e.Plan != "digraph {}" || e.OrganizationID != orgID || !e.Time.Equal(now.Add(3*time.Minute)) {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+	if body := string(e.Request); !strings.Contains(body, `range(start: -1h)`) || strings.Contains(body, "secret") || strings.Contains(body, "authorization") {
+		t.Errorf("unexpected request: %s", body)
+	}
+}
@@ -0,0 +1,60 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PriorityHeaderKey is the header of a query request setting its priority.
+const PriorityHeaderKey = "X-Influx-Query-Priority"
+
+// Priority is the scheduling priority of a query. Queued queries of higher
+// priority are executed before those of lower priority, so that batch jobs
+// can yield to interactive traffic.
+type Priority int
+
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 1
+)
+
+// ParsePriority parses the priority named s. An empty name is the normal priority.
+func ParsePriority(s string) (Priority, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "low":
+		return PriorityLow, nil
+	case "", "normal":
+		return PriorityNormal, nil
+	case "high":
+		return PriorityHigh, nil
+	}
+	return PriorityNormal, fmt.Errorf("invalid query priority %q, must be low, normal or high", s)
+}
+
+// String returns the name of the priority.
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityHigh:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+// MarshalText encodes the priority as its name.
+func (p Priority) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText decodes the priority from its name.
+func (p *Priority) UnmarshalText(text []byte) error {
+	v, err := ParsePriority(string(text))
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
@@ -0,0 +1,25 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ReadRateLimitHeaderKey is the header of a query request limiting the rate,
+// in bytes per second, at which the query reads data from disk.
+const ReadRateLimitHeaderKey = "X-Influx-Query-Read-Rate"
+
+// ParseReadRateLimit parses a read rate limit in bytes per second. An empty
+// limit is unlimited.
+func ParseReadRateLimit(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid query read rate %q, must be a positive number of bytes per second", s)
+	}
+	return n, nil
+}
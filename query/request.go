@@ -33,6 +33,13 @@ type Request struct {
 	// Source represents the ultimate source of the request.
 	Source string `json:"source"`
 
+	// Priority is the scheduling priority of the query.
+	Priority Priority `json:"priority,omitempty"`
+
+	// ReadRateLimit limits the rate, in bytes per second, at which the query
+	// reads data from disk. Zero is unlimited.
+	ReadRateLimit int64 `json:"read_rate_limit,omitempty"`
+
 	// compilerMappings maps compiler types to creation methods
 	compilerMappings flux.CompilerMappings
 
@@ -147,6 +154,17 @@ type ProxyRequest struct {
 	// Dialect is the result encoder
 	Dialect flux.Dialect `json:"dialect"`
 
+	// Limits optionally limits the size of the result.
+	Limits *platform.QueryResultLimits `json:"limits,omitempty"`
+
+	// Explain appends to the results the operations of the query that were
+	// not pushed down to storage, and why.
+	Explain bool `json:"explain,omitempty"`
+
+	// Profilers appends to the results the statistics of the query reported
+	// by each of the profilers, QueryProfiler or OperatorProfiler.
+	Profilers []string `json:"profilers,omitempty"`
+
 	// dialectMappings maps dialect types to creation methods
 	dialectMappings flux.DialectMappings
 }
@@ -0,0 +1,50 @@
+package influxdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/codes"
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/query"
+)
+
+// CopyDependencies contains the dependencies for executing the `copy` function.
+type CopyDependencies struct {
+	BucketLookup       BucketLookup
+	OrganizationLookup OrganizationLookup
+	BucketCopyService  platform.BucketCopyService
+}
+
+// Target looks up the named bucket of the named organization, or of the
+// organization of the query if org is empty.
+func (d CopyDependencies) Target(ctx context.Context, org, bucket string) (platform.BucketCopyTarget, error) {
+	var orgID platform.ID
+	if org != "" {
+		id, ok := d.OrganizationLookup.Lookup(ctx, org)
+		if !ok {
+			return platform.BucketCopyTarget{}, &flux.Error{
+				Code: codes.NotFound,
+				Msg:  fmt.Sprintf("failed to look up organization %q", org),
+			}
+		}
+		orgID = id
+	} else {
+		req := query.RequestFromContext(ctx)
+		if req == nil {
+			return platform.BucketCopyTarget{}, errors.New("missing request on context")
+		}
+		orgID = req.OrganizationID
+	}
+
+	bucketID, ok := d.BucketLookup.Lookup(ctx, orgID, bucket)
+	if !ok {
+		return platform.BucketCopyTarget{}, &flux.Error{
+			Code: codes.NotFound,
+			Msg:  fmt.Sprintf("failed to look up bucket %q in org %s", bucket, orgID),
+		}
+	}
+	return platform.BucketCopyTarget{OrgID: orgID, BucketID: bucketID}, nil
+}
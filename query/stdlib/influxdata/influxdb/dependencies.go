@@ -19,6 +19,7 @@ type StorageDependencies struct {
 	FromDeps   FromDependencies
 	BucketDeps BucketDependencies
 	ToDeps     ToDependencies
+	CopyDeps   CopyDependencies
 }
 
 func (d StorageDependencies) Inject(ctx context.Context) context.Context {
@@ -35,6 +36,7 @@ func (d StorageDependencies) PrometheusCollectors() []prometheus.Collector {
 		d.FromDeps,
 		d.BucketDeps,
 		d.ToDeps,
+		d.CopyDeps,
 	}
 	collectors := make([]prometheus.Collector, 0, len(depS))
 	for _, v := range depS {
@@ -67,6 +69,7 @@ func (d Dependencies) PrometheusCollectors() []prometheus.Collector {
 func NewDependencies(
 	reader Reader,
 	writer storage.PointsWriter,
+	copier influxdb.BucketCopyService,
 	bucketSvc influxdb.BucketService,
 	orgSvc influxdb.OrganizationService,
 	ss influxdb.SecretService,
@@ -96,5 +99,10 @@ func NewDependencies(
 	if err := deps.StorageDeps.ToDeps.Validate(); err != nil {
 		return Dependencies{}, err
 	}
+	deps.StorageDeps.CopyDeps = CopyDependencies{
+		BucketLookup:       bucketLookupSvc,
+		OrganizationLookup: orgLookupSvc,
+		BucketCopyService:  copier,
+	}
 	return deps, nil
 }
@@ -0,0 +1,333 @@
+package influxdb
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/ast"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/semantic"
+	"github.com/influxdata/flux/stdlib/universe"
+	"github.com/influxdata/influxdb/query"
+)
+
+// PushDownDiagnostic explains why an operation of a physical plan that
+// processes data read from storage was not pushed down to storage.
+type PushDownDiagnostic struct {
+	// ID is the ID of the operation in the plan, such as "filter2".
+	ID   plan.NodeID
+	Kind plan.ProcedureKind
+
+	Reason string
+}
+
+func (d PushDownDiagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.ID, d.Reason)
+}
+
+// ExplainPushDown returns the filters, groups, windows and aggregates of the
+// physical plan ps that process the data read from storage but were left to
+// the query engine, with the reason they were not pushed down to storage.
+func ExplainPushDown(ps *plan.Spec) []PushDownDiagnostic {
+	var roots []plan.Node
+	for root := range ps.Roots {
+		roots = append(roots, root)
+	}
+
+	var diags []PushDownDiagnostic
+	visited := make(map[plan.Node]bool)
+	_ = plan.WalkPredecessors(roots, func(node plan.Node) error {
+		if visited[node] {
+			return nil
+		}
+		visited[node] = true
+
+		if reason := explainNode(node); reason != "" {
+			diags = append(diags, PushDownDiagnostic{
+				ID:     node.ID(),
+				Kind:   node.Kind(),
+				Reason: reason,
+			})
+		}
+		return nil
+	})
+	sort.Slice(diags, func(i, j int) bool { return diags[i].ID < diags[j].ID })
+	return diags
+}
+
+// PushDownMetadata returns the diagnostics of ExplainPushDown as the
+// query.NotPushedDownMetadataKey metadata of a query.
+func PushDownMetadata(ps *plan.Spec) flux.Metadata {
+	md := make(flux.Metadata)
+	for _, d := range ExplainPushDown(ps) {
+		md.Add(query.NotPushedDownMetadataKey, d.String())
+	}
+	return md
+}
+
+// PlanMetadata returns the PushDownMetadata of the physical plan of a query,
+// along with the plan itself as its query.QueryPlanMetadataKey metadata.
+func PlanMetadata(ps *plan.Spec) flux.Metadata {
+	md := PushDownMetadata(ps)
+	md.Add(query.QueryPlanMetadataKey, fmt.Sprint(plan.Formatted(ps, plan.WithDetails())))
+	return md
+}
+
+// isStorageRead returns true if the node reads data from storage.
+func isStorageRead(node plan.Node) bool {
+	switch node.Kind() {
+	case ReadRangePhysKind, ReadGroupPhysKind, ReadWindowAggregatePhysKind,
+		ReadTagKeysPhysKind, ReadTagValuesPhysKind:
+		return true
+	}
+	return false
+}
+
+// readsStorage returns true if the node processes data read from storage.
+func readsStorage(node plan.Node) bool {
+	for _, pred := range node.Predecessors() {
+		if isStorageRead(pred) || readsStorage(pred) {
+			return true
+		}
+	}
+	return false
+}
+
+// explainNode returns the reason the node was not pushed down to storage, or
+// an empty string if it could not be pushed down in any case.
+func explainNode(node plan.Node) string {
+	switch node.Kind() {
+	case universe.FilterKind, universe.GroupKind, universe.WindowKind,
+		universe.CountKind, universe.SumKind, universe.MeanKind,
+		universe.MinKind, universe.MaxKind, universe.FirstKind, universe.LastKind:
+	default:
+		return ""
+	}
+	if len(node.Predecessors()) != 1 || !readsStorage(node) {
+		return ""
+	}
+
+	pred := node.Predecessors()[0]
+	if !isStorageRead(pred) {
+		// Only the operations that directly follow a read may be pushed
+		// down, which matters most to filters and groups.
+		if node.Kind() == universe.FilterKind || node.Kind() == universe.GroupKind {
+			return fmt.Sprintf("it follows %s, which is not performed by storage; move it before %s", pred.ID(), pred.ID())
+		}
+		return ""
+	}
+	if len(pred.Successors()) != 1 {
+		var others []string
+		for _, succ := range pred.Successors() {
+			if succ != node {
+				others = append(others, string(succ.ID()))
+			}
+		}
+		return fmt.Sprintf("the data read by %s is also processed by %s", pred.ID(), strings.Join(others, ", "))
+	}
+	if pred.Kind() != ReadRangePhysKind {
+		return fmt.Sprintf("storage cannot %s the data of %s", verb(node.Kind()), pred.ID())
+	}
+
+	switch spec := node.ProcedureSpec().(type) {
+	case *universe.FilterProcedureSpec:
+		return explainFilter(spec)
+	case *universe.GroupProcedureSpec:
+		return explainGroup(spec)
+	case *universe.WindowProcedureSpec:
+		return explainWindow(node, spec)
+	default:
+		return fmt.Sprintf("storage only computes the %s of windows, as with aggregateWindow()", node.Kind())
+	}
+}
+
+func verb(kind plan.ProcedureKind) string {
+	switch kind {
+	case universe.FilterKind:
+		return "filter"
+	case universe.GroupKind:
+		return "group"
+	case universe.WindowKind:
+		return "window"
+	}
+	return "aggregate"
+}
+
+func explainFilter(spec *universe.FilterProcedureSpec) string {
+	if spec.KeepEmptyTables {
+		return `storage cannot keep the empty tables of onEmpty: "keep"`
+	}
+	body, ok := spec.Fn.Fn.Block.Body.(semantic.Expression)
+	if !ok || len(spec.Fn.Fn.Block.Parameters.List) != 1 {
+		return "storage can only evaluate a predicate expression of a single record"
+	}
+	paramName := spec.Fn.Fn.Block.Parameters.List[0].Key.Name
+
+	var reasons []string
+	_, notPushable, _ := semantic.PartitionPredicates(body, func(e semantic.Expression) (bool, error) {
+		return isPushableExpr(paramName, e)
+	})
+	for _, e := range conjuncts(notPushable) {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", formatExpr(e), explainPredicate(paramName, e)))
+	}
+	if len(reasons) == 0 {
+		return ""
+	}
+	return "storage cannot evaluate " + strings.Join(reasons, "; ")
+}
+
+// conjuncts returns the expressions of the conjunction e.
+func conjuncts(e semantic.Expression) []semantic.Expression {
+	if e == nil {
+		return nil
+	}
+	if le, ok := e.(*semantic.LogicalExpression); ok && le.Operator == ast.AndOperator {
+		return append(conjuncts(le.Left), conjuncts(le.Right)...)
+	}
+	return []semantic.Expression{e}
+}
+
+// explainPredicate returns the reason the predicate e, as checked by
+// isPushableExpr, cannot be evaluated by storage.
+func explainPredicate(paramName string, e semantic.Expression) string {
+	switch e := e.(type) {
+	case *semantic.LogicalExpression:
+		if ok, _ := isPushableExpr(paramName, e.Left); !ok {
+			return explainPredicate(paramName, e.Left)
+		}
+		return explainPredicate(paramName, e.Right)
+	case *semantic.UnaryExpression:
+		switch e.Operator {
+		case ast.ExistsOperator:
+			return "storage can only test the existence of tags"
+		case ast.NotOperator:
+			return "storage only supports not before exists; negate the comparison operator instead"
+		}
+	case *semantic.BinaryExpression:
+		if !isLiteral(e.Right) {
+			if validateMemberExpr(paramName, e.Right) != nil && isLiteral(e.Left) {
+				return "the column must be on the left-hand side of the comparison"
+			}
+			if _, ok := e.Right.(*semantic.DateTimeLiteral); ok {
+				return "storage cannot compare times; use range() to select times"
+			}
+			return "storage can only compare columns with string, numeric, boolean or regular expression literals"
+		}
+		if validateMemberExpr(paramName, e.Left) == nil {
+			return fmt.Sprintf("the left-hand side of the comparison must be a column of %s", paramName)
+		}
+		if lit, ok := e.Right.(*semantic.StringLiteral); ok && lit.Value == "" && e.Operator == ast.EqualOperator {
+			return fmt.Sprintf("storage cannot match empty tag values; use not exists %s", formatExpr(e.Left))
+		}
+		if isField(paramName, e.Left) {
+			return fmt.Sprintf("storage does not support the %s operator on %s", e.Operator, fieldValueProperty)
+		}
+		return fmt.Sprintf("storage only compares tags with ==, !=, =~ and !~, not %s", e.Operator)
+	}
+	return "storage can only evaluate comparisons of columns with literals"
+}
+
+func explainGroup(spec *universe.GroupProcedureSpec) string {
+	if spec.GroupMode != flux.GroupModeBy {
+		return `storage only groups by columns, not with mode: "except"`
+	}
+	for _, col := range spec.GroupKeys {
+		if col == execute.DefaultTimeColLabel || col == execute.DefaultValueColLabel {
+			return fmt.Sprintf("storage can only group by tags, not by %s", col)
+		}
+	}
+	return ""
+}
+
+// explainWindow returns the reason the window and the operations following
+// it were not pushed down as a window aggregate, with the same conditions
+// as PushDownWindowAggregateRule.
+func explainWindow(node plan.Node, spec *universe.WindowProcedureSpec) string {
+	if len(node.Successors()) != 1 {
+		return "storage only computes aggregates of windows, and the windows are processed by several operations"
+	}
+	aggNode := node.Successors()[0]
+	var aggConfig execute.AggregateConfig
+	switch spec := aggNode.ProcedureSpec().(type) {
+	case *universe.CountProcedureSpec:
+		aggConfig = spec.AggregateConfig
+	case *universe.SumProcedureSpec:
+		aggConfig = spec.AggregateConfig
+	case *universe.MeanProcedureSpec:
+		aggConfig = spec.AggregateConfig
+	default:
+		return fmt.Sprintf("storage only computes the count, sum and mean of windows, not %s", aggNode.Kind())
+	}
+	if len(aggConfig.Columns) != 1 || aggConfig.Columns[0] != execute.DefaultValueColLabel {
+		return fmt.Sprintf("storage only aggregates the %s column of windows", execute.DefaultValueColLabel)
+	}
+
+	if !isDefaultWindowColumns(spec) {
+		return "storage only computes windows of the default time, start and stop columns"
+	}
+	every := spec.Window.Every
+	if every.Months() != 0 && every.Nanoseconds() != 0 {
+		return "storage cannot compute windows mixing calendar months and fixed durations"
+	} else if !isPushableWindowEvery(every) {
+		return "storage only computes windows of a positive, finite duration"
+	} else if !spec.Window.Period.Equal(every) || !spec.Window.Offset.IsZero() {
+		return "storage only computes contiguous windows without offset"
+	}
+
+	// The aggregates must then be merged back into the tables of their
+	// series, as by aggregateWindow().
+	if len(aggNode.Successors()) != 1 {
+		return "storage only computes aggregates of windows merged back as by aggregateWindow()"
+	}
+	duplicateNode := aggNode.Successors()[0]
+	duplicateSpec, ok := duplicateNode.ProcedureSpec().(*universe.SchemaMutationProcedureSpec)
+	if !ok || len(duplicateSpec.Mutations) != 1 || len(duplicateNode.Successors()) != 1 {
+		return "storage only computes aggregates of windows merged back as by aggregateWindow()"
+	} else if m, ok := duplicateSpec.Mutations[0].(*universe.DuplicateOpSpec); !ok ||
+		m.Column != execute.DefaultStopColLabel || m.As != execute.DefaultTimeColLabel {
+		return "storage only computes aggregates of windows timed by their stop time, as with aggregateWindow(timeSrc: \"_stop\")"
+	}
+	windowInfSpec, ok := duplicateNode.Successors()[0].ProcedureSpec().(*universe.WindowProcedureSpec)
+	if !ok || !isDefaultWindowColumns(windowInfSpec) ||
+		windowInfSpec.Window.Every.Months() != 0 || windowInfSpec.Window.Every.Nanoseconds() != math.MaxInt64 {
+		return "storage only computes aggregates of windows merged back as by aggregateWindow()"
+	}
+	return ""
+}
+
+// formatExpr formats a predicate expression as Flux source.
+func formatExpr(e semantic.Expression) string {
+	switch e := e.(type) {
+	case *semantic.LogicalExpression:
+		return fmt.Sprintf("%s %s %s", formatExpr(e.Left), e.Operator, formatExpr(e.Right))
+	case *semantic.BinaryExpression:
+		return fmt.Sprintf("%s %s %s", formatExpr(e.Left), e.Operator, formatExpr(e.Right))
+	case *semantic.UnaryExpression:
+		return fmt.Sprintf("%s %s", e.Operator, formatExpr(e.Argument))
+	case *semantic.MemberExpression:
+		return fmt.Sprintf("%s.%s", formatExpr(e.Object), e.Property)
+	case *semantic.IdentifierExpression:
+		return e.Name
+	case *semantic.StringLiteral:
+		return fmt.Sprintf("%q", e.Value)
+	case *semantic.IntegerLiteral:
+		return fmt.Sprint(e.Value)
+	case *semantic.UnsignedIntegerLiteral:
+		return fmt.Sprint(e.Value)
+	case *semantic.FloatLiteral:
+		return fmt.Sprint(e.Value)
+	case *semantic.BooleanLiteral:
+		return fmt.Sprint(e.Value)
+	case *semantic.RegexpLiteral:
+		return "/" + e.Value.String() + "/"
+	case *semantic.DateTimeLiteral:
+		return e.Value.Format("2006-01-02T15:04:05.999999999Z07:00")
+	case *semantic.CallExpression:
+		return formatExpr(e.Callee) + "(...)"
+	}
+	return "<expression>"
+}
@@ -0,0 +1,115 @@
+package influxdb_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/lang"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/query/stdlib/influxdata/influxdb"
+)
+
+func TestExplainPushDown(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name: "pushed down",
+			script: `from(bucket: "b") |> range(start: -1h)
+	|> filter(fn: (r) => r._measurement == "cpu" and r._value > 0.5)
+	|> aggregateWindow(every: 1m, fn: mean)`,
+		},
+		{
+			name: "filter",
+			script: `from(bucket: "b") |> range(start: -1h)
+	|> filter(fn: (r) => r._measurement == "cpu" and r.host == "" and r._value > r.limit and "a" == r.host)`,
+			want: []string{
+				`filter2: storage cannot evaluate r.host == "": storage cannot match empty tag values; use not exists r.host; ` +
+					`r._value > r.limit: storage can only compare columns with string, numeric, boolean or regular expression literals; ` +
+					`"a" == r.host: the column must be on the left-hand side of the comparison`,
+			},
+		},
+		{
+			name: "filter after map",
+			script: `from(bucket: "b") |> range(start: -1h)
+	|> map(fn: (r) => ({r with _value: r._value * 2.0}))
+	|> filter(fn: (r) => r.host == "a")`,
+			want: []string{"filter3: it follows map2, which is not performed by storage; move it before map2"},
+		},
+		{
+			name: "group by value",
+			script: `from(bucket: "b") |> range(start: -1h)
+	|> group(columns: ["_value"])`,
+			want: []string{"group2: storage can only group by tags, not by _value"},
+		},
+		{
+			name: "window aggregate",
+			script: `from(bucket: "b") |> range(start: -1h)
+	|> aggregateWindow(every: 1m, fn: max)`,
+			want: []string{"window2: storage only computes the count, sum and mean of windows, not max"},
+		},
+		{
+			name: "calendar window",
+			script: `from(bucket: "b") |> range(start: -1h)
+	|> aggregateWindow(every: 1mo, fn: sum)`,
+		},
+		{
+			name: "mixed calendar window",
+			script: `from(bucket: "b") |> range(start: -1h)
+	|> aggregateWindow(every: 1mo1d, fn: sum)`,
+			want: []string{"window2: storage cannot compute windows mixing calendar months and fixed durations"},
+		},
+		{
+			name: "aggregate",
+			script: `from(bucket: "b") |> range(start: -1h)
+	|> max()`,
+			want: []string{"max2: storage only computes the max of windows, as with aggregateWindow()"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Now()
+			ses, _, err := flux.Eval(ctx, `import "influxdata/influxdb"`+"\n"+tt.script, flux.SetNowOption(now))
+			if err != nil {
+				t.Fatal(err)
+			}
+			to := ses[len(ses)-1].Value.(*flux.TableObject)
+			prog, err := lang.CompileTableObject(ctx, to, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, d := range influxdb.ExplainPushDown(prog.PlanSpec) {
+				got = append(got, d.String())
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("unexpected diagnostics: got %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("unexpected diagnostic:\ngot  %q\nwant %q", got[i], tt.want[i])
+				}
+			}
+
+			md := influxdb.PushDownMetadata(prog.PlanSpec)
+			if len(md[query.NotPushedDownMetadataKey]) != len(tt.want) {
+				t.Errorf("unexpected metadata: %v", md)
+			}
+
+			md = influxdb.PlanMetadata(prog.PlanSpec)
+			if len(md[query.NotPushedDownMetadataKey]) != len(tt.want) {
+				t.Errorf("unexpected metadata: %v", md)
+			}
+			if plans := md[query.QueryPlanMetadataKey]; len(plans) != 1 || !strings.HasPrefix(plans[0].(string), "digraph {") {
+				t.Errorf("unexpected plan metadata: %v", plans)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package influxdb
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/semantic"
+	"github.com/influxdata/flux/stdlib/universe"
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/tsdb/cursors"
+)
+
+// PeerService reads the buckets of the peers of a federation, the remote
+// instances whose buckets are read by from(bucket: "<peer>/<bucket>").
+type PeerService interface {
+	// HasPeer returns true if name is a peer whose buckets the queries of
+	// the organization orgID may read.
+	HasPeer(orgID platform.ID, name string) bool
+
+	// QueryPeer executes the Flux query q on the peer name.
+	QueryPeer(ctx context.Context, name, q string) (flux.ResultIterator, error)
+}
+
+// peerBucket returns the peer and the name of its bucket read by the bucket
+// of a from() of the organization orgID, if it names the bucket of a peer.
+func (d FromDependencies) peerBucket(orgID platform.ID, bucket string) (peer, name string, ok bool) {
+	if d.Peers == nil {
+		return "", "", false
+	}
+	i := strings.Index(bucket, "/")
+	if i <= 0 || i == len(bucket)-1 || !d.Peers.HasPeer(orgID, bucket[:i]) {
+		return "", "", false
+	}
+	return bucket[:i], bucket[i+1:], true
+}
+
+// peerSource reads the tables of a bucket of a peer, with the query of the
+// read that storage would otherwise perform locally. The peer pushes the
+// query down to its own storage, and the tables are merged with the tables
+// of the other sources of the query locally.
+type peerSource struct {
+	Source
+	peers PeerService
+	peer  string
+	query string
+	stop  execute.Time
+}
+
+func newPeerSource(id execute.DatasetID, peers PeerService, peer, query string, stop execute.Time, orgID platform.ID, a execute.Administration) execute.Source {
+	src := &peerSource{
+		peers: peers,
+		peer:  peer,
+		query: query,
+		stop:  stop,
+	}
+	src.id = id
+	src.alloc = a.Allocator()
+
+	src.m = GetStorageDependencies(a.Context()).FromDeps.Metrics
+	src.orgID = orgID
+	src.op = "readPeer"
+
+	src.runner = src
+	return src
+}
+
+func (s *peerSource) run(ctx context.Context) error {
+	results, err := s.peers.QueryPeer(ctx, s.peer, s.query)
+	if err != nil {
+		return fmt.Errorf("failed to read peer %s: %w", s.peer, err)
+	}
+	defer results.Release()
+
+	if err := s.processTables(ctx, peerTables{results}, s.stop); err != nil {
+		return err
+	}
+	results.Release()
+	if err := results.Err(); err != nil {
+		return fmt.Errorf("failed to read peer %s: %w", s.peer, err)
+	}
+	return nil
+}
+
+// peerTables is the TableIterator of the tables of all the results of the
+// query of a peer.
+type peerTables struct {
+	results flux.ResultIterator
+}
+
+func (t peerTables) Do(f func(flux.Table) error) error {
+	for t.results.More() {
+		if err := t.results.Next().Tables().Do(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t peerTables) Statistics() cursors.CursorStats { return cursors.CursorStats{} }
+
+// peerReadQuery returns the query of a peer reading its bucket over the
+// bounds, filtered by the predicate if it is not nil.
+func peerReadQuery(bucket string, bounds execute.Bounds, predicate *semantic.FunctionExpression) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "from(bucket: %s)\n\t|> range(start: %s, stop: %s)",
+		formatPeerString(bucket), formatPeerTime(bounds.Start.Time()), formatPeerTime(bounds.Stop.Time()))
+	if predicate != nil {
+		if predicate.Block.Parameters == nil || len(predicate.Block.Parameters.List) != 1 {
+			return "", fmt.Errorf("predicate functions must have exactly one parameter")
+		}
+		var body semantic.Expression
+		switch b := predicate.Block.Body.(type) {
+		case semantic.Expression:
+			body = b
+		case *semantic.ReturnStatement:
+			body = b.Argument
+		default:
+			return "", fmt.Errorf("unsupported predicate body %T", predicate.Block.Body)
+		}
+		param := predicate.Block.Parameters.List[0].Key.Name
+		expr, err := formatPeerExpr(body)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "\n\t|> filter(fn: (%s) => %s)", param, expr)
+	}
+	return b.String(), nil
+}
+
+// peerGroupQuery returns the query of a peer performing the read of spec.
+func peerGroupQuery(bucket string, spec ReadGroupSpec) (string, error) {
+	q, err := peerReadQuery(bucket, spec.Bounds, spec.Predicate)
+	if err != nil {
+		return "", err
+	}
+	if spec.GroupMode != GroupModeBy {
+		return "", fmt.Errorf("unsupported group mode %v", spec.GroupMode)
+	}
+	cols := make([]string, len(spec.GroupKeys))
+	for i, k := range spec.GroupKeys {
+		cols[i] = formatPeerString(k)
+	}
+	q += fmt.Sprintf("\n\t|> group(columns: [%s], mode: \"by\")", strings.Join(cols, ", "))
+	if spec.AggregateMethod != "" {
+		q += fmt.Sprintf("\n\t|> %s()", spec.AggregateMethod)
+	}
+	return q, nil
+}
+
+// peerWindowAggregateQuery returns the query of a peer performing the read
+// of spec, as aggregateWindow() does.
+func peerWindowAggregateQuery(bucket string, spec ReadWindowAggregateSpec) (string, error) {
+	q, err := peerReadQuery(bucket, spec.Bounds, spec.Predicate)
+	if err != nil {
+		return "", err
+	}
+	switch spec.Aggregate {
+	case universe.CountKind, universe.SumKind, universe.MeanKind:
+	default:
+		return "", fmt.Errorf("unsupported window aggregate %q", spec.Aggregate)
+	}
+	every := fmt.Sprintf("%dns", spec.WindowEvery)
+	if spec.WindowEveryMonths != 0 {
+		every = fmt.Sprintf("%dmo", spec.WindowEveryMonths)
+	}
+	q += fmt.Sprintf("\n\t|> aggregateWindow(every: %s, fn: %s, createEmpty: %t)", every, spec.Aggregate, spec.Fill != FillNone)
+	switch spec.Fill {
+	case FillNone, FillNull:
+	case FillPrevious:
+		q += "\n\t|> fill(usePrevious: true)"
+	default:
+		return "", fmt.Errorf("unsupported fill policy %v", spec.Fill)
+	}
+	return q, nil
+}
+
+// peerTagKeysQuery returns the query of a peer performing the read of spec.
+func peerTagKeysQuery(bucket string, spec ReadTagKeysSpec) (string, error) {
+	q, err := peerReadQuery(bucket, spec.Bounds, spec.Predicate)
+	if err != nil {
+		return "", err
+	}
+	return q + "\n\t|> keys()\n\t|> keep(columns: [\"_value\"])\n\t|> group()\n\t|> distinct()", nil
+}
+
+// peerTagValuesQuery returns the query of a peer performing the read of spec.
+func peerTagValuesQuery(bucket string, spec ReadTagValuesSpec) (string, error) {
+	q, err := peerReadQuery(bucket, spec.Bounds, spec.Predicate)
+	if err != nil {
+		return "", err
+	}
+	tag := formatPeerString(spec.TagKey)
+	return q + fmt.Sprintf("\n\t|> keep(columns: [%s])\n\t|> group()\n\t|> distinct(column: %s)", tag, tag), nil
+}
+
+// formatPeerExpr formats a predicate expression as Flux source, for the
+// expressions that storage evaluates.
+func formatPeerExpr(e semantic.Expression) (string, error) {
+	switch e := e.(type) {
+	case *semantic.LogicalExpression:
+		left, err := formatPeerExpr(e.Left)
+		if err != nil {
+			return "", err
+		}
+		right, err := formatPeerExpr(e.Right)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(%s %s %s)", left, e.Operator, right), nil
+	case *semantic.BinaryExpression:
+		left, err := formatPeerExpr(e.Left)
+		if err != nil {
+			return "", err
+		}
+		right, err := formatPeerExpr(e.Right)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(%s %s %s)", left, e.Operator, right), nil
+	case *semantic.UnaryExpression:
+		arg, err := formatPeerExpr(e.Argument)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(%s %s)", e.Operator, arg), nil
+	case *semantic.MemberExpression:
+		obj, err := formatPeerExpr(e.Object)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s[%s]", obj, formatPeerString(e.Property)), nil
+	case *semantic.IdentifierExpression:
+		return e.Name, nil
+	case *semantic.StringLiteral:
+		return formatPeerString(e.Value), nil
+	case *semantic.IntegerLiteral:
+		return strconv.FormatInt(e.Value, 10), nil
+	case *semantic.FloatLiteral:
+		s := strconv.FormatFloat(e.Value, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return s, nil
+	case *semantic.BooleanLiteral:
+		return strconv.FormatBool(e.Value), nil
+	case *semantic.RegexpLiteral:
+		return "/" + strings.Replace(e.Value.String(), "/", `\/`, -1) + "/", nil
+	case *semantic.DateTimeLiteral:
+		return formatPeerTime(e.Value), nil
+	}
+	return "", fmt.Errorf("unsupported expression %T", e)
+}
+
+// formatPeerString formats s as a Flux string literal.
+func formatPeerString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "${", `\${`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// formatPeerTime formats t as a Flux time literal.
+func formatPeerTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
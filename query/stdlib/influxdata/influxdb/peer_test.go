@@ -0,0 +1,149 @@
+package influxdb
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux/ast"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/parser"
+	"github.com/influxdata/flux/semantic"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func TestPeerQueries(t *testing.T) {
+	bounds := execute.Bounds{
+		Start: execute.Time(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano()),
+		Stop:  execute.Time(time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC).UnixNano()),
+	}
+	member := func(p string) *semantic.MemberExpression {
+		return &semantic.MemberExpression{
+			Object:   &semantic.IdentifierExpression{Name: "r"},
+			Property: p,
+		}
+	}
+	predicate := &semantic.FunctionExpression{
+		Block: &semantic.FunctionBlock{
+			Parameters: &semantic.FunctionParameters{
+				List: []*semantic.FunctionParameter{{Key: &semantic.Identifier{Name: "r"}}},
+			},
+			Body: &semantic.ReturnStatement{
+				Argument: &semantic.LogicalExpression{
+					Operator: ast.AndOperator,
+					Left: &semantic.BinaryExpression{
+						Operator: ast.EqualOperator,
+						Left:     member("_measurement"),
+						Right:    &semantic.StringLiteral{Value: `c"pu ${x}`},
+					},
+					Right: &semantic.LogicalExpression{
+						Operator: ast.OrOperator,
+						Left: &semantic.BinaryExpression{
+							Operator: ast.RegexpMatchOperator,
+							Left:     member("host"),
+							Right:    &semantic.RegexpLiteral{Value: regexp.MustCompile("a/b")},
+						},
+						Right: &semantic.BinaryExpression{
+							Operator: ast.GreaterThanOperator,
+							Left:     member("_value"),
+							Right:    &semantic.FloatLiteral{Value: 2},
+						},
+					},
+				},
+			},
+		},
+	}
+	read := `from(bucket: "b")
+	|> range(start: 2020-01-01T00:00:00Z, stop: 2020-01-02T00:00:00Z)
+	|> filter(fn: (r) => ((r["_measurement"] == "c\"pu \${x}") and ((r["host"] =~ /a\/b/) or (r["_value"] > 2.0))))`
+
+	for _, tt := range []struct {
+		name  string
+		query func() (string, error)
+		want  string
+	}{
+		{
+			name: "range",
+			query: func() (string, error) {
+				return peerReadQuery("b", bounds, nil)
+			},
+			want: `from(bucket: "b")
+	|> range(start: 2020-01-01T00:00:00Z, stop: 2020-01-02T00:00:00Z)`,
+		},
+		{
+			name: "filter",
+			query: func() (string, error) {
+				return peerReadQuery("b", bounds, predicate)
+			},
+			want: read,
+		},
+		{
+			name: "group",
+			query: func() (string, error) {
+				return peerGroupQuery("b", ReadGroupSpec{
+					ReadFilterSpec:  ReadFilterSpec{Bounds: bounds, Predicate: predicate},
+					GroupMode:       GroupModeBy,
+					GroupKeys:       []string{"host", "region"},
+					AggregateMethod: "max",
+				})
+			},
+			want: read + `
+	|> group(columns: ["host", "region"], mode: "by")
+	|> max()`,
+		},
+		{
+			name: "window aggregate",
+			query: func() (string, error) {
+				return peerWindowAggregateQuery("b", ReadWindowAggregateSpec{
+					ReadFilterSpec: ReadFilterSpec{Bounds: bounds, Predicate: predicate},
+					WindowEvery:    int64(time.Minute),
+					Aggregate:      universe.MeanKind,
+					Fill:           FillPrevious,
+				})
+			},
+			want: read + `
+	|> aggregateWindow(every: 60000000000ns, fn: mean, createEmpty: true)
+	|> fill(usePrevious: true)`,
+		},
+		{
+			name: "window aggregate months",
+			query: func() (string, error) {
+				return peerWindowAggregateQuery("b", ReadWindowAggregateSpec{
+					ReadFilterSpec:    ReadFilterSpec{Bounds: bounds},
+					WindowEveryMonths: 3,
+					Aggregate:         universe.CountKind,
+				})
+			},
+			want: `from(bucket: "b")
+	|> range(start: 2020-01-01T00:00:00Z, stop: 2020-01-02T00:00:00Z)
+	|> aggregateWindow(every: 3mo, fn: count, createEmpty: false)`,
+		},
+		{
+			name: "tag values",
+			query: func() (string, error) {
+				return peerTagValuesQuery("b", ReadTagValuesSpec{
+					ReadFilterSpec: ReadFilterSpec{Bounds: bounds},
+					TagKey:         "host",
+				})
+			},
+			want: `from(bucket: "b")
+	|> range(start: 2020-01-01T00:00:00Z, stop: 2020-01-02T00:00:00Z)
+	|> keep(columns: ["host"])
+	|> group()
+	|> distinct(column: "host")`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.query()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("unexpected query:\ngot:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if pkg := parser.ParseSource(got); ast.Check(pkg) > 0 {
+				t.Fatalf("invalid query: %v", ast.GetError(pkg))
+			}
+		})
+	}
+}
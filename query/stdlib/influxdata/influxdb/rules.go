@@ -1,6 +1,8 @@
 package influxdb
 
 import (
+	"math"
+
 	"github.com/influxdata/flux"
 	"github.com/influxdata/flux/ast"
 	"github.com/influxdata/flux/execute"
@@ -16,6 +18,8 @@ func init() {
 		PushDownGroupRule{},
 		PushDownReadTagKeysRule{},
 		PushDownReadTagValuesRule{},
+		PushDownWindowAggregateRule{},
+		PushDownWindowAggregateFillRule{},
 		SortedPivotRule{},
 	)
 }
@@ -299,6 +303,157 @@ func (rule PushDownReadTagValuesRule) Rewrite(pn plan.Node) (plan.Node, bool, er
 	}), true, nil
 }
 
+// PushDownWindowAggregateRule matches
+// 'ReadRange |> window(every) |> agg() |> duplicate(column: "_stop", as: "_time") |> window(every: inf)',
+// the expansion of 'aggregateWindow(every, fn: agg)', for the count, sum and
+// mean aggregates over fixed or calendar windows of the _value column, such as
+// 'every: 1mo' or 'every: 1y'. The windows are
+// then aggregated by storage while the series are read, rather than by
+// materializing their values in window tables first.
+type PushDownWindowAggregateRule struct{}
+
+func (rule PushDownWindowAggregateRule) Name() string {
+	return "PushDownWindowAggregateRule"
+}
+
+func (rule PushDownWindowAggregateRule) Pattern() plan.Pattern {
+	// The aggregate may be any of the supported aggregates,
+	// so the nodes below it are matched by Rewrite.
+	return plan.Pat(universe.WindowKind,
+		plan.Pat(universe.SchemaMutationKind, plan.Any()))
+}
+
+func (rule PushDownWindowAggregateRule) Rewrite(pn plan.Node) (plan.Node, bool, error) {
+	// Retrieve the nodes and specs for all of the predecessors.
+	windowInfSpec := pn.ProcedureSpec().(*universe.WindowProcedureSpec)
+	duplicateNode := pn.Predecessors()[0]
+	duplicateSpec := duplicateNode.ProcedureSpec().(*universe.SchemaMutationProcedureSpec)
+	aggNode := duplicateNode.Predecessors()[0]
+	if len(aggNode.Predecessors()) != 1 || len(aggNode.Successors()) != 1 {
+		return pn, false, nil
+	}
+	windowNode := aggNode.Predecessors()[0]
+	if windowNode.Kind() != universe.WindowKind || len(windowNode.Predecessors()) != 1 || len(windowNode.Successors()) != 1 {
+		return pn, false, nil
+	}
+	windowSpec := windowNode.ProcedureSpec().(*universe.WindowProcedureSpec)
+	fromNode := windowNode.Predecessors()[0]
+	if fromNode.Kind() != ReadRangePhysKind || len(fromNode.Successors()) != 1 {
+		return pn, false, nil
+	}
+	fromSpec := fromNode.ProcedureSpec().(*ReadRangePhysSpec)
+
+	// Only the aggregates of the _value column that produce a value for
+	// every window, including empty ones, can be pushed down.
+	var aggConfig execute.AggregateConfig
+	switch spec := aggNode.ProcedureSpec().(type) {
+	case *universe.CountProcedureSpec:
+		aggConfig = spec.AggregateConfig
+	case *universe.SumProcedureSpec:
+		aggConfig = spec.AggregateConfig
+	case *universe.MeanProcedureSpec:
+		aggConfig = spec.AggregateConfig
+	default:
+		return pn, false, nil
+	}
+	if len(aggConfig.Columns) != 1 || aggConfig.Columns[0] != execute.DefaultValueColLabel {
+		return pn, false, nil
+	}
+
+	// The windows must be contiguous, aligned on the epoch and either of a
+	// fixed duration or of a number of calendar months.
+	if !isDefaultWindowColumns(windowSpec) {
+		return pn, false, nil
+	}
+	every := windowSpec.Window.Every
+	if !isPushableWindowEvery(every) {
+		return pn, false, nil
+	} else if !windowSpec.Window.Period.Equal(every) || !windowSpec.Window.Offset.IsZero() {
+		return pn, false, nil
+	}
+
+	// The time of each window is its stop time.
+	if len(duplicateSpec.Mutations) != 1 {
+		return pn, false, nil
+	} else if m, ok := duplicateSpec.Mutations[0].(*universe.DuplicateOpSpec); !ok {
+		return pn, false, nil
+	} else if m.Column != execute.DefaultStopColLabel || m.As != execute.DefaultTimeColLabel {
+		return pn, false, nil
+	}
+
+	// The windows are merged back into the table of their series.
+	if !isDefaultWindowColumns(windowInfSpec) {
+		return pn, false, nil
+	} else if w := windowInfSpec.Window.Every; w.Months() != 0 || w.Nanoseconds() != math.MaxInt64 {
+		return pn, false, nil
+	}
+
+	fill := FillNone
+	if windowSpec.CreateEmpty {
+		fill = FillNull
+	}
+	return plan.CreatePhysicalNode("ReadWindowAggregate", &ReadWindowAggregatePhysSpec{
+		ReadRangePhysSpec: *fromSpec.Copy().(*ReadRangePhysSpec),
+		WindowEvery:       every.Nanoseconds(),
+		WindowEveryMonths: every.Months(),
+		Aggregate:         aggNode.Kind(),
+		Fill:              fill,
+	}), true, nil
+}
+
+// isPushableWindowEvery returns true if storage can compute windows of the
+// duration, either a positive and finite number of nanoseconds or a positive
+// number of calendar months, but not both.
+func isPushableWindowEvery(every flux.Duration) bool {
+	months, nsecs := every.Months(), every.Nanoseconds()
+	if months != 0 {
+		return months > 0 && nsecs == 0
+	}
+	return nsecs > 0 && nsecs != math.MaxInt64
+}
+
+// isDefaultWindowColumns returns true if the window uses the default time,
+// start and stop columns.
+func isDefaultWindowColumns(spec *universe.WindowProcedureSpec) bool {
+	return spec.TimeColumn == execute.DefaultTimeColLabel &&
+		spec.StartColumn == execute.DefaultStartColLabel &&
+		spec.StopColumn == execute.DefaultStopColLabel
+}
+
+// PushDownWindowAggregateFillRule matches 'ReadWindowAggregate |> fill(usePrevious: true)'
+// and fills the empty windows with the value of the previous window in
+// storage, instead of in a separate pass over the aggregated tables.
+type PushDownWindowAggregateFillRule struct{}
+
+func (rule PushDownWindowAggregateFillRule) Name() string {
+	return "PushDownWindowAggregateFillRule"
+}
+
+func (rule PushDownWindowAggregateFillRule) Pattern() plan.Pattern {
+	return plan.Pat(universe.FillKind, plan.Pat(ReadWindowAggregatePhysKind))
+}
+
+func (rule PushDownWindowAggregateFillRule) Rewrite(pn plan.Node) (plan.Node, bool, error) {
+	fillSpec := pn.ProcedureSpec().(*universe.FillProcedureSpec)
+	fromNode := pn.Predecessors()[0]
+	fromSpec := fromNode.ProcedureSpec().(*ReadWindowAggregatePhysSpec)
+
+	// Only the nulls of empty windows can be filled by storage.
+	if fromSpec.Fill != FillNull {
+		return pn, false, nil
+	} else if !fillSpec.UsePrevious || fillSpec.Column != execute.DefaultValueColLabel {
+		return pn, false, nil
+	}
+
+	newFromSpec := fromSpec.Copy().(*ReadWindowAggregatePhysSpec)
+	newFromSpec.Fill = FillPrevious
+	mergedNode, err := plan.MergeToPhysicalNode(pn, fromNode, newFromSpec)
+	if err != nil {
+		return nil, false, err
+	}
+	return mergedNode, true, nil
+}
+
 var invalidTagKeysForTagValues = []string{
 	execute.DefaultTimeColLabel,
 	execute.DefaultValueColLabel,
@@ -1,6 +1,7 @@
 package influxdb_test
 
 import (
+	"math"
 	"testing"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/influxdata/flux/plan/plantest"
 	"github.com/influxdata/flux/semantic"
 	"github.com/influxdata/flux/stdlib/universe"
+	"github.com/influxdata/flux/values"
 	"github.com/influxdata/influxdb/query/stdlib/influxdata/influxdb"
 )
 
@@ -1284,3 +1286,209 @@ func TestReadTagValuesRule(t *testing.T) {
 		})
 	}
 }
+
+func TestPushDownWindowAggregateRule(t *testing.T) {
+	fromSpec := influxdb.FromProcedureSpec{
+		Bucket: "my-bucket",
+	}
+	rangeSpec := universe.RangeProcedureSpec{
+		Bounds: flux.Bounds{
+			Start: fluxTime(5),
+			Stop:  fluxTime(10),
+		},
+	}
+	windowSpec := func(every, offset flux.Duration, createEmpty bool) *universe.WindowProcedureSpec {
+		return &universe.WindowProcedureSpec{
+			Window: plan.WindowSpec{
+				Every:  every,
+				Period: every,
+				Offset: offset,
+			},
+			TimeColumn:  execute.DefaultTimeColLabel,
+			StartColumn: execute.DefaultStartColLabel,
+			StopColumn:  execute.DefaultStopColLabel,
+			CreateEmpty: createEmpty,
+		}
+	}
+	aggConfig := execute.AggregateConfig{Columns: []string{execute.DefaultValueColLabel}}
+	duplicateSpec := universe.SchemaMutationProcedureSpec{
+		Mutations: []universe.SchemaMutation{
+			&universe.DuplicateOpSpec{
+				Column: execute.DefaultStopColLabel,
+				As:     execute.DefaultTimeColLabel,
+			},
+		},
+	}
+	inf := flux.ConvertDuration(math.MaxInt64)
+	minute := flux.ConvertDuration(time.Minute)
+	quarter, _ := values.ParseDuration("3mo")
+	quarterMinute, _ := values.ParseDuration("3mo1m")
+	fillPreviousSpec := universe.FillProcedureSpec{
+		Column:      execute.DefaultValueColLabel,
+		UsePrevious: true,
+	}
+	readWindowAggregateSpec := func(agg plan.ProcedureKind, fill influxdb.FillPolicy) plan.PhysicalProcedureSpec {
+		return &influxdb.ReadWindowAggregatePhysSpec{
+			ReadRangePhysSpec: influxdb.ReadRangePhysSpec{
+				Bucket: "my-bucket",
+				Bounds: flux.Bounds{
+					Start: fluxTime(5),
+					Stop:  fluxTime(10),
+				},
+			},
+			WindowEvery: int64(time.Minute),
+			Aggregate:   agg,
+			Fill:        fill,
+		}
+	}
+	readRangeSpec := influxdb.ReadRangePhysSpec{
+		Bucket: "my-bucket",
+		Bounds: flux.Bounds{
+			Start: fluxTime(5),
+			Stop:  fluxTime(10),
+		},
+	}
+	// aggregateWindow returns the plan of 'from |> range |> aggregateWindow(every, fn: agg)',
+	// or of 'ReadRange |> aggregateWindow(every, fn: agg)' once the range is
+	// pushed down, followed by the given nodes.
+	aggregateWindow := func(pushed bool, window *universe.WindowProcedureSpec, agg plan.ProcedureSpec, successors ...plan.Node) *plantest.PlanSpec {
+		spec := &plantest.PlanSpec{
+			Nodes: []plan.Node{
+				plan.CreateLogicalNode("from", &fromSpec),
+				plan.CreateLogicalNode("range", &rangeSpec),
+			},
+		}
+		if pushed {
+			spec.Nodes = []plan.Node{plan.CreatePhysicalNode("ReadRange", &readRangeSpec)}
+		}
+		spec.Nodes = append(spec.Nodes,
+			plan.CreateLogicalNode("window", window),
+			plan.CreateLogicalNode("agg", agg),
+			plan.CreateLogicalNode("duplicate", &duplicateSpec),
+			plan.CreateLogicalNode("window2", windowSpec(inf, flux.ConvertDuration(0), false)),
+		)
+		spec.Nodes = append(spec.Nodes, successors...)
+		for i := 1; i < len(spec.Nodes); i++ {
+			spec.Edges = append(spec.Edges, [2]int{i - 1, i})
+		}
+		return spec
+	}
+
+	tests := []plantest.RuleTestCase{
+		{
+			Name: "mean",
+			// from -> range -> window -> mean -> duplicate -> window  =>  ReadWindowAggregate
+			Rules: []plan.Rule{
+				influxdb.PushDownRangeRule{},
+				influxdb.PushDownWindowAggregateRule{},
+			},
+			Before: aggregateWindow(false, windowSpec(minute, flux.ConvertDuration(0), true), &universe.MeanProcedureSpec{AggregateConfig: aggConfig}),
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("ReadWindowAggregate", readWindowAggregateSpec(universe.MeanKind, influxdb.FillNull)),
+				},
+			},
+		},
+		{
+			Name: "count without empty windows",
+			Rules: []plan.Rule{
+				influxdb.PushDownRangeRule{},
+				influxdb.PushDownWindowAggregateRule{},
+			},
+			Before: aggregateWindow(false, windowSpec(minute, flux.ConvertDuration(0), false), &universe.CountProcedureSpec{AggregateConfig: aggConfig}),
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("ReadWindowAggregate", readWindowAggregateSpec(universe.CountKind, influxdb.FillNone)),
+				},
+			},
+		},
+		{
+			Name: "sum with fill previous",
+			// from -> range -> window -> sum -> duplicate -> window -> fill  =>  ReadWindowAggregate
+			Rules: []plan.Rule{
+				influxdb.PushDownRangeRule{},
+				influxdb.PushDownWindowAggregateRule{},
+				influxdb.PushDownWindowAggregateFillRule{},
+			},
+			Before: aggregateWindow(false, windowSpec(minute, flux.ConvertDuration(0), true), &universe.SumProcedureSpec{AggregateConfig: aggConfig},
+				plan.CreateLogicalNode("fill", &fillPreviousSpec)),
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("merged_ReadWindowAggregate_fill", readWindowAggregateSpec(universe.SumKind, influxdb.FillPrevious)),
+				},
+			},
+		},
+		{
+			Name: "sum with fill value",
+			// from -> range -> window -> sum -> duplicate -> window -> fill  =>  ReadWindowAggregate -> fill
+			Rules: []plan.Rule{
+				influxdb.PushDownRangeRule{},
+				influxdb.PushDownWindowAggregateRule{},
+				influxdb.PushDownWindowAggregateFillRule{},
+			},
+			Before: aggregateWindow(false, windowSpec(minute, flux.ConvertDuration(0), true), &universe.SumProcedureSpec{AggregateConfig: aggConfig},
+				plan.CreateLogicalNode("fill", &universe.FillProcedureSpec{Column: execute.DefaultValueColLabel, Value: values.NewFloat(0)})),
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("ReadWindowAggregate", readWindowAggregateSpec(universe.SumKind, influxdb.FillNull)),
+					plan.CreatePhysicalNode("fill", &universe.FillProcedureSpec{Column: execute.DefaultValueColLabel, Value: values.NewFloat(0)}),
+				},
+				Edges: [][2]int{{0, 1}},
+			},
+		},
+		{
+			Name: "selector",
+			// Selectors produce no row for empty windows.
+			Rules: []plan.Rule{
+				influxdb.PushDownRangeRule{},
+				influxdb.PushDownWindowAggregateRule{},
+			},
+			Before: aggregateWindow(false, windowSpec(minute, flux.ConvertDuration(0), true), &universe.MaxProcedureSpec{}),
+			After:  aggregateWindow(true, windowSpec(minute, flux.ConvertDuration(0), true), &universe.MaxProcedureSpec{}),
+		},
+		{
+			Name: "calendar months",
+			Rules: []plan.Rule{
+				influxdb.PushDownRangeRule{},
+				influxdb.PushDownWindowAggregateRule{},
+			},
+			Before: aggregateWindow(false, windowSpec(quarter, flux.ConvertDuration(0), true), &universe.SumProcedureSpec{AggregateConfig: aggConfig}),
+			After: &plantest.PlanSpec{
+				Nodes: []plan.Node{
+					plan.CreatePhysicalNode("ReadWindowAggregate", &influxdb.ReadWindowAggregatePhysSpec{
+						ReadRangePhysSpec: readRangeSpec,
+						WindowEveryMonths: 3,
+						Aggregate:         universe.SumKind,
+						Fill:              influxdb.FillNull,
+					}),
+				},
+			},
+		},
+		{
+			Name: "mixed calendar months",
+			Rules: []plan.Rule{
+				influxdb.PushDownRangeRule{},
+				influxdb.PushDownWindowAggregateRule{},
+			},
+			Before: aggregateWindow(false, windowSpec(quarterMinute, flux.ConvertDuration(0), true), &universe.SumProcedureSpec{AggregateConfig: aggConfig}),
+			After:  aggregateWindow(true, windowSpec(quarterMinute, flux.ConvertDuration(0), true), &universe.SumProcedureSpec{AggregateConfig: aggConfig}),
+		},
+		{
+			Name: "offset",
+			Rules: []plan.Rule{
+				influxdb.PushDownRangeRule{},
+				influxdb.PushDownWindowAggregateRule{},
+			},
+			Before: aggregateWindow(false, windowSpec(minute, flux.ConvertDuration(time.Second), true), &universe.MeanProcedureSpec{AggregateConfig: aggConfig}),
+			After:  aggregateWindow(true, windowSpec(minute, flux.ConvertDuration(time.Second), true), &universe.MeanProcedureSpec{AggregateConfig: aggConfig}),
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			plantest.PhysicalRuleTestHelper(t, &tc)
+		})
+	}
+}
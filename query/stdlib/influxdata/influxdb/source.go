@@ -20,6 +20,7 @@ import (
 func init() {
 	execute.RegisterSource(ReadRangePhysKind, createReadFilterSource)
 	execute.RegisterSource(ReadGroupPhysKind, createReadGroupSource)
+	execute.RegisterSource(ReadWindowAggregatePhysKind, createReadWindowAggregateSource)
 	execute.RegisterSource(ReadTagKeysPhysKind, createReadTagKeysSource)
 	execute.RegisterSource(ReadTagValuesPhysKind, createReadTagValuesSource)
 }
@@ -176,15 +177,22 @@ func createReadFilterSource(s plan.ProcedureSpec, id execute.DatasetID, a execut
 	}
 
 	orgID := req.OrganizationID
-	bucketID, err := spec.LookupBucketID(ctx, orgID, deps.BucketLookup)
-	if err != nil {
-		return nil, err
-	}
-
 	var filter *semantic.FunctionExpression
 	if spec.FilterSet {
 		filter = spec.Filter
 	}
+	if peer, bucket, ok := deps.peerBucket(orgID, spec.Bucket); ok {
+		q, err := peerReadQuery(bucket, *bounds, filter)
+		if err != nil {
+			return nil, err
+		}
+		return newPeerSource(id, deps.Peers, peer, q, bounds.Stop, orgID, a), nil
+	}
+
+	bucketID, err := spec.LookupBucketID(ctx, orgID, deps.BucketLookup)
+	if err != nil {
+		return nil, err
+	}
 	return ReadFilterSource(
 		id,
 		deps.Reader,
@@ -253,15 +261,30 @@ func createReadGroupSource(s plan.ProcedureSpec, id execute.DatasetID, a execute
 	}
 
 	orgID := req.OrganizationID
-	bucketID, err := spec.LookupBucketID(ctx, orgID, deps.BucketLookup)
-	if err != nil {
-		return nil, err
-	}
-
 	var filter *semantic.FunctionExpression
 	if spec.FilterSet {
 		filter = spec.Filter
 	}
+	if peer, bucket, ok := deps.peerBucket(orgID, spec.Bucket); ok {
+		q, err := peerGroupQuery(bucket, ReadGroupSpec{
+			ReadFilterSpec: ReadFilterSpec{
+				Bounds:    *bounds,
+				Predicate: filter,
+			},
+			GroupMode:       ToGroupMode(spec.GroupMode),
+			GroupKeys:       spec.GroupKeys,
+			AggregateMethod: spec.AggregateMethod,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return newPeerSource(id, deps.Peers, peer, q, bounds.Stop, orgID, a), nil
+	}
+
+	bucketID, err := spec.LookupBucketID(ctx, orgID, deps.BucketLookup)
+	if err != nil {
+		return nil, err
+	}
 	return ReadGroupSource(
 		id,
 		deps.Reader,
@@ -280,22 +303,116 @@ func createReadGroupSource(s plan.ProcedureSpec, id execute.DatasetID, a execute
 	), nil
 }
 
-func createReadTagKeysSource(prSpec plan.ProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
+type readWindowAggregateSource struct {
+	Source
+	reader   Reader
+	readSpec ReadWindowAggregateSpec
+}
+
+func ReadWindowAggregateSource(id execute.DatasetID, r Reader, readSpec ReadWindowAggregateSpec, a execute.Administration) execute.Source {
+	src := new(readWindowAggregateSource)
+
+	src.id = id
+	src.alloc = a.Allocator()
+
+	src.reader = r
+	src.readSpec = readSpec
+
+	src.m = GetStorageDependencies(a.Context()).FromDeps.Metrics
+	src.orgID = readSpec.OrganizationID
+	src.op = "readWindowAggregate"
+
+	src.runner = src
+	return src
+}
+
+func (s *readWindowAggregateSource) run(ctx context.Context) error {
+	stop := s.readSpec.Bounds.Stop
+	tables, err := s.reader.ReadWindowAggregate(
+		ctx,
+		s.readSpec,
+		s.alloc,
+	)
+	if err != nil {
+		return err
+	}
+	return s.processTables(ctx, tables, stop)
+}
+
+func createReadWindowAggregateSource(s plan.ProcedureSpec, id execute.DatasetID, a execute.Administration) (execute.Source, error) {
 	span, ctx := tracing.StartSpanFromContext(a.Context())
 	defer span.Finish()
 
-	spec := prSpec.(*ReadTagKeysPhysSpec)
+	spec := s.(*ReadWindowAggregatePhysSpec)
+
+	bounds := a.StreamContext().Bounds()
+	if bounds == nil {
+		return nil, errors.New("nil bounds passed to from")
+	}
+
 	deps := GetStorageDependencies(a.Context()).FromDeps
+
 	req := query.RequestFromContext(a.Context())
 	if req == nil {
 		return nil, errors.New("missing request on context")
 	}
+
 	orgID := req.OrganizationID
+	var filter *semantic.FunctionExpression
+	if spec.FilterSet {
+		filter = spec.Filter
+	}
+	if peer, bucket, ok := deps.peerBucket(orgID, spec.Bucket); ok {
+		q, err := peerWindowAggregateQuery(bucket, ReadWindowAggregateSpec{
+			ReadFilterSpec: ReadFilterSpec{
+				Bounds:    *bounds,
+				Predicate: filter,
+			},
+			WindowEvery:       spec.WindowEvery,
+			WindowEveryMonths: spec.WindowEveryMonths,
+			Aggregate:         string(spec.Aggregate),
+			Fill:              spec.Fill,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return newPeerSource(id, deps.Peers, peer, q, bounds.Stop, orgID, a), nil
+	}
 
 	bucketID, err := spec.LookupBucketID(ctx, orgID, deps.BucketLookup)
 	if err != nil {
 		return nil, err
 	}
+	return ReadWindowAggregateSource(
+		id,
+		deps.Reader,
+		ReadWindowAggregateSpec{
+			ReadFilterSpec: ReadFilterSpec{
+				OrganizationID: orgID,
+				BucketID:       bucketID,
+				Bounds:         *bounds,
+				Predicate:      filter,
+			},
+			WindowEvery:       spec.WindowEvery,
+			WindowEveryMonths: spec.WindowEveryMonths,
+			Aggregate:         string(spec.Aggregate),
+			Fill:              spec.Fill,
+		},
+		a,
+	), nil
+}
+
+func createReadTagKeysSource(prSpec plan.ProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	span, ctx := tracing.StartSpanFromContext(a.Context())
+	defer span.Finish()
+
+	spec := prSpec.(*ReadTagKeysPhysSpec)
+	deps := GetStorageDependencies(a.Context()).FromDeps
+	req := query.RequestFromContext(a.Context())
+	if req == nil {
+		return nil, errors.New("missing request on context")
+	}
+	orgID := req.OrganizationID
 
 	var filter *semantic.FunctionExpression
 	if spec.FilterSet {
@@ -303,6 +420,23 @@ func createReadTagKeysSource(prSpec plan.ProcedureSpec, dsid execute.DatasetID,
 	}
 
 	bounds := a.StreamContext().Bounds()
+	if peer, bucket, ok := deps.peerBucket(orgID, spec.Bucket); ok {
+		q, err := peerTagKeysQuery(bucket, ReadTagKeysSpec{
+			ReadFilterSpec: ReadFilterSpec{
+				Bounds:    *bounds,
+				Predicate: filter,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		return newPeerSource(dsid, deps.Peers, peer, q, execute.Now(), orgID, a), nil
+	}
+
+	bucketID, err := spec.LookupBucketID(ctx, orgID, deps.BucketLookup)
+	if err != nil {
+		return nil, err
+	}
 	return ReadTagKeysSource(
 		dsid,
 		deps.Reader,
@@ -361,17 +495,30 @@ func createReadTagValuesSource(prSpec plan.ProcedureSpec, dsid execute.DatasetID
 	}
 	orgID := req.OrganizationID
 
-	bucketID, err := spec.LookupBucketID(ctx, orgID, deps.BucketLookup)
-	if err != nil {
-		return nil, err
-	}
-
 	var filter *semantic.FunctionExpression
 	if spec.FilterSet {
 		filter = spec.Filter
 	}
 
 	bounds := a.StreamContext().Bounds()
+	if peer, bucket, ok := deps.peerBucket(orgID, spec.Bucket); ok {
+		q, err := peerTagValuesQuery(bucket, ReadTagValuesSpec{
+			ReadFilterSpec: ReadFilterSpec{
+				Bounds:    *bounds,
+				Predicate: filter,
+			},
+			TagKey: spec.TagKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return newPeerSource(dsid, deps.Peers, peer, q, execute.Now(), orgID, a), nil
+	}
+
+	bucketID, err := spec.LookupBucketID(ctx, orgID, deps.BucketLookup)
+	if err != nil {
+		return nil, err
+	}
 	return ReadTagValuesSource(
 		dsid,
 		deps.Reader,
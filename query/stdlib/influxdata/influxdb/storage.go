@@ -35,6 +35,10 @@ type FromDependencies struct {
 	BucketLookup       BucketLookup
 	OrganizationLookup OrganizationLookup
 	Metrics            *metrics
+
+	// Peers reads the buckets of the peers of a federation. It is optional,
+	// and without it from() only reads the local buckets.
+	Peers PeerService
 }
 
 func (d FromDependencies) Validate() error {
@@ -119,6 +123,43 @@ type ReadGroupSpec struct {
 	AggregateMethod string
 }
 
+// FillPolicy is the value given to the windows of a windowed aggregate
+// without any value.
+type FillPolicy int
+
+const (
+	// FillNone omits empty windows.
+	FillNone FillPolicy = iota
+	// FillNull produces a null value for empty windows.
+	FillNull
+	// FillPrevious produces the value of the previous non-empty window,
+	// or null for the windows before the first non-empty one.
+	FillPrevious
+	// FillLinear interpolates the value of empty windows linearly between the
+	// values of the surrounding non-empty windows, or produces null for the
+	// windows before the first and after the last non-empty one.
+	FillLinear
+)
+
+// ReadWindowAggregateSpec describes the aggregation of the values of each
+// series over fixed windows of time, in the way of aggregateWindow().
+type ReadWindowAggregateSpec struct {
+	ReadFilterSpec
+
+	// WindowEvery is the duration of the windows, in nanoseconds. Windows are
+	// aligned on the epoch and clipped to the bounds.
+	WindowEvery int64
+	// WindowEveryMonths is the duration of calendar windows, in months, used
+	// instead of WindowEvery. As with window(), calendar windows are aligned
+	// on the first month of year 0 in UTC, so that windows of 3 and 12 months
+	// are quarters and years.
+	WindowEveryMonths int64
+	// Aggregate is the aggregate computed over each window, count, sum or mean.
+	Aggregate string
+	// Fill is the value given to windows without any value.
+	Fill FillPolicy
+}
+
 type ReadTagKeysSpec struct {
 	ReadFilterSpec
 }
@@ -131,6 +172,7 @@ type ReadTagValuesSpec struct {
 type Reader interface {
 	ReadFilter(ctx context.Context, spec ReadFilterSpec, alloc *memory.Allocator) (TableIterator, error)
 	ReadGroup(ctx context.Context, spec ReadGroupSpec, alloc *memory.Allocator) (TableIterator, error)
+	ReadWindowAggregate(ctx context.Context, spec ReadWindowAggregateSpec, alloc *memory.Allocator) (TableIterator, error)
 
 	ReadTagKeys(ctx context.Context, spec ReadTagKeysSpec, alloc *memory.Allocator) (TableIterator, error)
 	ReadTagValues(ctx context.Context, spec ReadTagValuesSpec, alloc *memory.Allocator) (TableIterator, error)
@@ -0,0 +1,132 @@
+// Package storage implements Flux functions operating on the storage
+// engine directly, such as copying data between buckets.
+package storage
+
+import (
+	"context"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/ast"
+	"github.com/influxdata/flux/codes"
+	"github.com/influxdata/flux/interpreter"
+	"github.com/influxdata/flux/parser"
+	"github.com/influxdata/flux/semantic"
+	"github.com/influxdata/flux/values"
+	"github.com/influxdata/influxdb/query/stdlib/influxdata/influxdb"
+)
+
+// CopyKind is the name of the function copying data between buckets.
+const CopyKind = "copy"
+
+// source declares the builtins of the package, implemented in Go.
+const source = `package storage
+
+builtin copy
+`
+
+var pkgAST = func() *ast.Package {
+	pkg := parser.ParseSource(source)
+	pkg.Path = "influxdata/influxdb/storage"
+	pkg.Files[0].Name = "storage.flux"
+	return pkg
+}()
+
+func init() {
+	flux.RegisterPackage(pkgAST)
+	flux.RegisterPackageValue(pkgAST.Path, CopyKind, values.NewFunction(
+		CopyKind,
+		semantic.NewFunctionPolyType(semantic.FunctionPolySignature{
+			Parameters: map[string]semantic.PolyType{
+				"bucket": semantic.String,
+				"org":    semantic.String,
+				"to":     semantic.String,
+				"toOrg":  semantic.String,
+				"start":  semantic.Time,
+				"stop":   semantic.Time,
+			},
+			Required: semantic.LabelSet{"bucket", "to", "start", "stop"},
+			Return: semantic.NewObjectPolyType(map[string]semantic.PolyType{
+				"series":          semantic.Int,
+				"blocks":          semantic.Int,
+				"reencodedBlocks": semantic.Int,
+				"bytes":           semantic.Int,
+			}, semantic.LabelSet{"series", "blocks", "reencodedBlocks", "bytes"}, nil),
+		}),
+		copyBucket,
+		true,
+	))
+}
+
+// copyBucket copies the data of a bucket within [start, stop) to another
+// bucket in the storage engine, without going through the query engine.
+func copyBucket(ctx context.Context, args values.Object) (values.Value, error) {
+	deps := influxdb.GetStorageDependencies(ctx).CopyDeps
+	if deps.BucketCopyService == nil {
+		return nil, &flux.Error{
+			Code: codes.Unimplemented,
+			Msg:  "copy is not supported by this storage",
+		}
+	}
+
+	a := interpreter.NewArguments(args)
+	start, err := a.GetRequired("start")
+	if err != nil {
+		return nil, err
+	}
+	stop, err := a.GetRequired("stop")
+	if err != nil {
+		return nil, err
+	}
+	min, max := start.Time().Time().UnixNano(), stop.Time().Time().UnixNano()
+	if min >= max {
+		return nil, &flux.Error{
+			Code: codes.Invalid,
+			Msg:  "start must be before stop",
+		}
+	}
+
+	bucket, err := a.GetRequiredString("bucket")
+	if err != nil {
+		return nil, err
+	}
+	org, _, err := a.GetString("org")
+	if err != nil {
+		return nil, err
+	}
+	src, err := deps.Target(ctx, org, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	// The destination bucket is in the same organization unless toOrg is set.
+	bucket, err = a.GetRequiredString("to")
+	if err != nil {
+		return nil, err
+	}
+	if toOrg, ok, err := a.GetString("toOrg"); err != nil {
+		return nil, err
+	} else if ok {
+		org = toOrg
+	}
+	dst, err := deps.Target(ctx, org, bucket)
+	if err != nil {
+		return nil, err
+	}
+	if src == dst {
+		return nil, &flux.Error{
+			Code: codes.Invalid,
+			Msg:  "cannot copy a bucket to itself",
+		}
+	}
+
+	stats, err := deps.BucketCopyService.CopyBucketRange(ctx, src, dst, min, max-1)
+	if err != nil {
+		return nil, err
+	}
+	return values.NewObjectWithValues(map[string]values.Value{
+		"series":          values.NewInt(int64(stats.Series)),
+		"blocks":          values.NewInt(int64(stats.Blocks)),
+		"reencodedBlocks": values.NewInt(int64(stats.ReencodedBlocks)),
+		"bytes":           values.NewInt(stats.Bytes),
+	}), nil
+}
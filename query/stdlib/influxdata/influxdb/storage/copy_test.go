@@ -0,0 +1,102 @@
+package storage_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/dependencies/dependenciestest"
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/mock"
+	_ "github.com/influxdata/influxdb/query/builtin"
+	"github.com/influxdata/influxdb/query/stdlib/influxdata/influxdb"
+)
+
+// bucketLookup looks up buckets by name in any organization.
+type bucketLookup map[string]platform.ID
+
+func (l bucketLookup) Lookup(_ context.Context, orgID platform.ID, name string) (platform.ID, bool) {
+	id, ok := l[name]
+	return id, ok
+}
+
+func (l bucketLookup) LookupName(_ context.Context, orgID platform.ID, id platform.ID) string {
+	return ""
+}
+
+func TestCopy(t *testing.T) {
+	var (
+		gotSrc, gotDst platform.BucketCopyTarget
+		gotMin, gotMax int64
+	)
+	copier := mock.NewBucketCopyService()
+	copier.CopyBucketRangeF = func(ctx context.Context, src, dst platform.BucketCopyTarget, min, max int64) (*platform.BucketCopyStats, error) {
+		gotSrc, gotDst, gotMin, gotMax = src, dst, min, max
+		return &platform.BucketCopyStats{Series: 3, Blocks: 4, ReencodedBlocks: 1, Bytes: 100}, nil
+	}
+	deps := influxdb.Dependencies{
+		FluxDeps: dependenciestest.Default(),
+		StorageDeps: influxdb.StorageDependencies{
+			CopyDeps: influxdb.CopyDependencies{
+				BucketLookup:       bucketLookup{"src": 10, "dst": 11},
+				OrganizationLookup: mock.OrganizationLookup{},
+				BucketCopyService:  copier,
+			},
+		},
+	}
+	ctx := deps.Inject(context.Background())
+
+	_, scope, err := flux.Eval(ctx, `
+import "influxdata/influxdb/storage"
+stats = storage.copy(bucket: "src", org: "my-org", to: "dst", start: 2020-01-01T00:00:00Z, stop: 2020-01-02T00:00:00Z)
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, ok := scope.Lookup("stats")
+	if !ok {
+		t.Fatal("missing stats")
+	}
+	if got, _ := stats.Object().Get("series"); got.Int() != 3 {
+		t.Errorf("unexpected series: %v", got)
+	}
+	if got, _ := stats.Object().Get("bytes"); got.Int() != 100 {
+		t.Errorf("unexpected bytes: %v", got)
+	}
+
+	if want := (platform.BucketCopyTarget{OrgID: 2, BucketID: 10}); gotSrc != want {
+		t.Errorf("unexpected source: got %+v, want %+v", gotSrc, want)
+	}
+	if want := (platform.BucketCopyTarget{OrgID: 2, BucketID: 11}); gotDst != want {
+		t.Errorf("unexpected destination: got %+v, want %+v", gotDst, want)
+	}
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	if gotMin != start.UnixNano() || gotMax != start.Add(24*time.Hour).UnixNano()-1 {
+		t.Errorf("unexpected range: [%d, %d]", gotMin, gotMax)
+	}
+
+	for _, tt := range []struct {
+		script string
+		want   string
+	}{
+		{
+			script: `storage.copy(bucket: "src", org: "my-org", to: "missing", start: 2020-01-01T00:00:00Z, stop: 2020-01-02T00:00:00Z)`,
+			want:   `failed to look up bucket "missing"`,
+		},
+		{
+			script: `storage.copy(bucket: "src", org: "my-org", to: "src", start: 2020-01-01T00:00:00Z, stop: 2020-01-02T00:00:00Z)`,
+			want:   "cannot copy a bucket to itself",
+		},
+		{
+			script: `storage.copy(bucket: "src", org: "my-org", to: "dst", start: 2020-01-02T00:00:00Z, stop: 2020-01-01T00:00:00Z)`,
+			want:   "start must be before stop",
+		},
+	} {
+		_, _, err := flux.Eval(ctx, "import \"influxdata/influxdb/storage\"\n"+tt.script)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("unexpected error for %s: got %v, want %q", tt.script, err, tt.want)
+		}
+	}
+}
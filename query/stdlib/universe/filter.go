@@ -0,0 +1,138 @@
+package universe
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/stdlib/universe"
+)
+
+func init() {
+	execute.ReplaceTransformation(universe.FilterKind, createFilterTransformation)
+}
+
+func createFilterTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*universe.FilterProcedureSpec)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid spec type %T", spec)
+	}
+	return NewFilterTransformation(a.Context(), s, id, a.Allocator())
+}
+
+// filterTransformation filters the rows of tables by evaluating the predicate
+// over whole columns. The tables whose rows can not be filtered this way are
+// filtered row by row by the transformation of the universe package.
+type filterTransformation struct {
+	execute.Transformation
+
+	d               *execute.PassthroughDataset
+	spec            *universe.FilterProcedureSpec
+	keepEmptyTables bool
+	alloc           *memory.Allocator
+
+	// evaluators are the predicates compiled for each schema of the tables
+	// processed, nil if the tables are filtered row by row.
+	evaluators map[string]evaluator
+}
+
+// NewFilterTransformation returns a filter transformation that evaluates the
+// predicate of spec over whole columns whenever it can.
+func NewFilterTransformation(ctx context.Context, spec *universe.FilterProcedureSpec, id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset, error) {
+	t, d, err := universe.NewFilterTransformation(ctx, spec, id, alloc)
+	if err != nil {
+		return nil, nil, err
+	}
+	pd, ok := d.(*execute.PassthroughDataset)
+	if !ok {
+		return t, d, nil
+	}
+	return &filterTransformation{
+		Transformation:  t,
+		d:               pd,
+		spec:            spec,
+		keepEmptyTables: spec.KeepEmptyTables,
+		alloc:           alloc,
+		evaluators:      make(map[string]evaluator),
+	}, d, nil
+}
+
+// evaluator returns the predicate compiled for the schema of tbl, or nil if
+// tbl must be filtered row by row.
+func (t *filterTransformation) evaluator(tbl flux.Table) evaluator {
+	key := schemaKey(tbl)
+	if e, ok := t.evaluators[key]; ok {
+		return e
+	}
+	t.evaluators[key] = t.compile(tbl)
+	return t.evaluators[key]
+}
+
+func (t *filterTransformation) compile(tbl flux.Table) evaluator {
+	c, body, err := newVectorCompiler(t.spec.Fn.Fn, t.spec.Fn.Scope, tbl.Cols())
+	if err != nil {
+		return nil
+	}
+	e, err := c.compileBool(body)
+	if err != nil {
+		return nil
+	}
+	// A predicate of the group key only is evaluated once per table by
+	// the row by row transformation.
+	for _, j := range c.refs {
+		if !tbl.Key().HasCol(tbl.Cols()[j].Label) {
+			return e
+		}
+	}
+	return nil
+}
+
+func (t *filterTransformation) Process(id execute.DatasetID, tbl flux.Table) error {
+	e := t.evaluator(tbl)
+	if e == nil {
+		return t.Transformation.Process(id, tbl)
+	}
+
+	b := execute.NewColListTableBuilder(tbl.Key(), t.alloc)
+	if err := execute.AddTableCols(tbl, b); err != nil {
+		return err
+	}
+	if err := tbl.Do(func(cr flux.ColReader) error {
+		v, err := e.Eval(cr, nil)
+		if err != nil {
+			return fmt.Errorf("failed to evaluate filter function: %v", err)
+		}
+
+		keep, n := make([]bool, v.n), 0
+		for i := range keep {
+			if keep[i] = !v.null(i) && v.bools[i]; keep[i] {
+				n++
+			}
+		}
+		if n == 0 {
+			return nil
+		}
+		for j, col := range cr.Cols() {
+			if err := appendVector(b, j, readColumn(cr, j, col.Type).filter(keep, n), t.alloc); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		b.Release()
+		return err
+	}
+
+	if b.NRows() == 0 && !t.keepEmptyTables {
+		b.Release()
+		return nil
+	}
+	out, err := b.Table()
+	if err != nil {
+		return err
+	}
+	return t.d.Process(out)
+}
@@ -0,0 +1,206 @@
+package universe
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/semantic"
+	"github.com/influxdata/flux/stdlib/universe"
+	"github.com/influxdata/flux/values"
+)
+
+func init() {
+	execute.ReplaceTransformation(universe.MapKind, createMapTransformation)
+}
+
+func createMapTransformation(id execute.DatasetID, mode execute.AccumulationMode, spec plan.ProcedureSpec, a execute.Administration) (execute.Transformation, execute.Dataset, error) {
+	s, ok := spec.(*universe.MapProcedureSpec)
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid spec type %T", spec)
+	}
+	cache := execute.NewTableBuilderCache(a.Allocator())
+	d := execute.NewDataset(id, mode, cache)
+	t, err := NewMapTransformation(a.Context(), s, d, cache, a.Allocator())
+	if err != nil {
+		return nil, nil, err
+	}
+	return t, d, nil
+}
+
+// mapColumn is a column of the tables produced by a vectorized map function,
+// either computed by an evaluator or copied from the column j of the input.
+type mapColumn struct {
+	flux.ColMeta
+	e evaluator
+	j int
+}
+
+// mapProgram is a map function compiled for a schema of input tables.
+type mapProgram struct {
+	cols []mapColumn
+	// keyCols are the columns of the group key of the input kept in the output.
+	keyCols []int
+}
+
+// mapTransformation maps the rows of tables by evaluating the function over
+// whole columns. The tables whose rows can not be mapped this way are mapped
+// row by row by the transformation of the universe package.
+type mapTransformation struct {
+	execute.Transformation
+
+	cache execute.TableBuilderCache
+	spec  *universe.MapProcedureSpec
+	alloc *memory.Allocator
+
+	// programs are the functions compiled for each schema of the tables
+	// processed, nil if the tables are mapped row by row.
+	programs map[string]*mapProgram
+}
+
+// NewMapTransformation returns a map transformation that evaluates the
+// function of spec over whole columns whenever it can.
+func NewMapTransformation(ctx context.Context, spec *universe.MapProcedureSpec, d execute.Dataset, cache execute.TableBuilderCache, alloc *memory.Allocator) (execute.Transformation, error) {
+	t, err := universe.NewMapTransformation(ctx, spec, d, cache)
+	if err != nil {
+		return nil, err
+	}
+	if spec.MergeKey {
+		return t, nil
+	}
+	return &mapTransformation{
+		Transformation: t,
+		cache:          cache,
+		spec:           spec,
+		alloc:          alloc,
+		programs:       make(map[string]*mapProgram),
+	}, nil
+}
+
+// program returns the function compiled for the schema of tbl, or nil if tbl
+// must be mapped row by row.
+func (t *mapTransformation) program(tbl flux.Table) *mapProgram {
+	key := schemaKey(tbl)
+	if p, ok := t.programs[key]; ok {
+		return p
+	}
+	p, err := t.compile(tbl)
+	if err != nil {
+		p = nil
+	}
+	t.programs[key] = p
+	return p
+}
+
+// compile compiles functions returning a record of expressions, optionally
+// extending the input record. The columns of the group key can only be
+// copied, so that the group key of a table does not change.
+func (t *mapTransformation) compile(tbl flux.Table) (*mapProgram, error) {
+	cols := tbl.Cols()
+	c, body, err := newVectorCompiler(t.spec.Fn.Fn, t.spec.Fn.Scope, cols)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := body.(*semantic.ObjectExpression)
+	if !ok {
+		return nil, errNotVectorizable
+	}
+
+	out := make(map[string]mapColumn)
+	if obj.With != nil {
+		if obj.With.Name != c.param {
+			return nil, errNotVectorizable
+		}
+		for j, col := range cols {
+			out[col.Label] = mapColumn{ColMeta: col, j: j}
+		}
+	}
+	assigned := make(map[string]bool, len(obj.Properties))
+	for _, p := range obj.Properties {
+		label := p.Key.Key()
+		if assigned[label] {
+			return nil, errNotVectorizable
+		}
+		assigned[label] = true
+
+		if tbl.Key().HasCol(label) {
+			if src, ok := c.column(p.Value); !ok || src != label {
+				return nil, errNotVectorizable
+			}
+			j := execute.ColIdx(label, cols)
+			out[label] = mapColumn{ColMeta: cols[j], j: j}
+			continue
+		}
+		e, err := c.compile(p.Value)
+		if err != nil {
+			return nil, err
+		}
+		out[label] = mapColumn{ColMeta: flux.ColMeta{Label: label, Type: e.Type()}, e: e}
+	}
+
+	// The columns are sorted by label, like those of the tables mapped row by row.
+	prog := &mapProgram{cols: make([]mapColumn, 0, len(out))}
+	for _, col := range out {
+		prog.cols = append(prog.cols, col)
+	}
+	sort.Slice(prog.cols, func(i, j int) bool {
+		return prog.cols[i].Label < prog.cols[j].Label
+	})
+	for j, col := range tbl.Key().Cols() {
+		if _, ok := out[col.Label]; ok {
+			prog.keyCols = append(prog.keyCols, j)
+		}
+	}
+	return prog, nil
+}
+
+func (t *mapTransformation) Process(id execute.DatasetID, tbl flux.Table) error {
+	p := t.program(tbl)
+	if p == nil {
+		return t.Transformation.Process(id, tbl)
+	}
+
+	key := tbl.Key()
+	if len(p.keyCols) != len(key.Cols()) {
+		cols := make([]flux.ColMeta, len(p.keyCols))
+		vs := make([]values.Value, len(p.keyCols))
+		for i, j := range p.keyCols {
+			cols[i], vs[i] = key.Cols()[j], key.Value(j)
+		}
+		key = execute.NewGroupKey(cols, vs)
+	}
+
+	return tbl.Do(func(cr flux.ColReader) error {
+		if cr.Len() == 0 {
+			return nil
+		}
+		builder, created := t.cache.TableBuilder(key)
+		if created {
+			for _, col := range p.cols {
+				if _, err := builder.AddCol(col.ColMeta); err != nil {
+					return err
+				}
+			}
+		}
+
+		for j, col := range p.cols {
+			var v *vector
+			if col.e == nil {
+				v = readColumn(cr, col.j, col.Type)
+			} else {
+				var err error
+				if v, err = col.e.Eval(cr, nil); err != nil {
+					return fmt.Errorf("failed to evaluate map function: %v", err)
+				}
+			}
+			if err := appendVector(builder, j, v, t.alloc); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,990 @@
+// Package universe replaces transformations of the flux universe package
+// with implementations better suited to the storage engine.
+package universe
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/arrow"
+	"github.com/influxdata/flux/ast"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/semantic"
+	"github.com/influxdata/flux/values"
+)
+
+// errNotVectorizable is returned when compiling an expression that can not be
+// evaluated over whole columns. Such expressions are evaluated row by row.
+var errNotVectorizable = errors.New("expression is not vectorizable")
+
+var (
+	errDivideByZero = errors.New("cannot divide by zero")
+	errModZero      = errors.New("cannot mod zero")
+)
+
+// vector holds the values of an expression for the rows of a column reader.
+type vector struct {
+	typ    flux.ColType
+	n      int
+	floats []float64
+	ints   []int64 // integers and times
+	uints  []uint64
+	strs   []string
+	bools  []bool
+	// valid is false for the rows whose value is null. It is nil if no
+	// value is null.
+	valid []bool
+}
+
+func newVector(typ flux.ColType, n int) *vector {
+	v := &vector{typ: typ, n: n}
+	switch typ {
+	case flux.TFloat:
+		v.floats = make([]float64, n)
+	case flux.TInt, flux.TTime:
+		v.ints = make([]int64, n)
+	case flux.TUInt:
+		v.uints = make([]uint64, n)
+	case flux.TString:
+		v.strs = make([]string, n)
+	case flux.TBool:
+		v.bools = make([]bool, n)
+	}
+	return v
+}
+
+// readColumn returns the values of the column j of cr. The values share the
+// memory of the column.
+func readColumn(cr flux.ColReader, j int, typ flux.ColType) *vector {
+	v := &vector{typ: typ, n: cr.Len()}
+	var nulls interface {
+		NullN() int
+		IsValid(i int) bool
+	}
+	switch typ {
+	case flux.TFloat:
+		arr := cr.Floats(j)
+		v.floats, nulls = arr.Float64Values(), arr
+	case flux.TInt:
+		arr := cr.Ints(j)
+		v.ints, nulls = arr.Int64Values(), arr
+	case flux.TTime:
+		arr := cr.Times(j)
+		v.ints, nulls = arr.Int64Values(), arr
+	case flux.TUInt:
+		arr := cr.UInts(j)
+		v.uints, nulls = arr.Uint64Values(), arr
+	case flux.TString:
+		arr := cr.Strings(j)
+		v.strs = make([]string, v.n)
+		for i := range v.strs {
+			v.strs[i] = arr.ValueString(i)
+		}
+		nulls = arr
+	case flux.TBool:
+		arr := cr.Bools(j)
+		v.bools = make([]bool, v.n)
+		for i := range v.bools {
+			v.bools[i] = arr.Value(i)
+		}
+		nulls = arr
+	}
+	if nulls.NullN() > 0 {
+		v.valid = make([]bool, v.n)
+		for i := range v.valid {
+			v.valid[i] = nulls.IsValid(i)
+		}
+	}
+	return v
+}
+
+func (v *vector) null(i int) bool {
+	return v.valid != nil && !v.valid[i]
+}
+
+func (v *vector) setNull(i int) {
+	if v.valid == nil {
+		v.valid = make([]bool, v.n)
+		for k := range v.valid {
+			v.valid[k] = true
+		}
+	}
+	v.valid[i] = false
+}
+
+// mergeNulls makes null the rows of v that are null in any of vs.
+func (v *vector) mergeNulls(vs ...*vector) {
+	for _, o := range vs {
+		for i, ok := range o.valid {
+			if !ok {
+				v.setNull(i)
+			}
+		}
+	}
+}
+
+// evaluated reports whether the row i of v is not null and selected by sel.
+func (v *vector) evaluated(sel []bool, i int) bool {
+	return !v.null(i) && (sel == nil || sel[i])
+}
+
+// filter returns the rows of v for which keep is true.
+func (v *vector) filter(keep []bool, n int) *vector {
+	out := newVector(v.typ, n)
+	k := 0
+	for i, ok := range keep {
+		if !ok {
+			continue
+		}
+		switch v.typ {
+		case flux.TFloat:
+			out.floats[k] = v.floats[i]
+		case flux.TInt, flux.TTime:
+			out.ints[k] = v.ints[i]
+		case flux.TUInt:
+			out.uints[k] = v.uints[i]
+		case flux.TString:
+			out.strs[k] = v.strs[i]
+		case flux.TBool:
+			out.bools[k] = v.bools[i]
+		}
+		if v.null(i) {
+			out.setNull(k)
+		}
+		k++
+	}
+	return out
+}
+
+// appendVector appends the values of v to the column j of b.
+func appendVector(b execute.TableBuilder, j int, v *vector, alloc *memory.Allocator) error {
+	switch v.typ {
+	case flux.TFloat:
+		ab := arrow.NewFloatBuilder(alloc)
+		ab.AppendValues(v.floats, v.valid)
+		arr := ab.NewFloat64Array()
+		defer arr.Release()
+		return b.AppendFloats(j, arr)
+	case flux.TInt, flux.TTime:
+		ab := arrow.NewIntBuilder(alloc)
+		ab.AppendValues(v.ints, v.valid)
+		arr := ab.NewInt64Array()
+		defer arr.Release()
+		if v.typ == flux.TTime {
+			return b.AppendTimes(j, arr)
+		}
+		return b.AppendInts(j, arr)
+	case flux.TUInt:
+		ab := arrow.NewUintBuilder(alloc)
+		ab.AppendValues(v.uints, v.valid)
+		arr := ab.NewUint64Array()
+		defer arr.Release()
+		return b.AppendUInts(j, arr)
+	case flux.TString:
+		ab := arrow.NewStringBuilder(alloc)
+		ab.AppendStringValues(v.strs, v.valid)
+		arr := ab.NewBinaryArray()
+		defer arr.Release()
+		return b.AppendStrings(j, arr)
+	case flux.TBool:
+		// The booleans are appended one by one as AppendBools misplaces
+		// the null values.
+		for i, x := range v.bools {
+			var err error
+			if v.null(i) {
+				err = b.AppendNil(j)
+			} else {
+				err = b.AppendBool(j, x)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return errNotVectorizable
+}
+
+// evaluator evaluates an expression for all the rows of a column reader at once.
+type evaluator interface {
+	Type() flux.ColType
+	// Eval returns the values of the expression for the rows of cr. Only
+	// the rows selected by sel, or all rows if sel is nil, need a value:
+	// errors, such as a division by zero, are only reported for them.
+	Eval(cr flux.ColReader, sel []bool) (*vector, error)
+}
+
+type columnEvaluator struct {
+	j   int
+	typ flux.ColType
+}
+
+func (e *columnEvaluator) Type() flux.ColType { return e.typ }
+
+func (e *columnEvaluator) Eval(cr flux.ColReader, sel []bool) (*vector, error) {
+	return readColumn(cr, e.j, e.typ), nil
+}
+
+type constantEvaluator struct {
+	typ flux.ColType
+	v   values.Value
+}
+
+func (e *constantEvaluator) Type() flux.ColType { return e.typ }
+
+func (e *constantEvaluator) Eval(cr flux.ColReader, sel []bool) (*vector, error) {
+	v := newVector(e.typ, cr.Len())
+	switch e.typ {
+	case flux.TFloat:
+		for i, x := 0, e.v.Float(); i < v.n; i++ {
+			v.floats[i] = x
+		}
+	case flux.TInt:
+		for i, x := 0, e.v.Int(); i < v.n; i++ {
+			v.ints[i] = x
+		}
+	case flux.TTime:
+		for i, x := 0, int64(e.v.Time()); i < v.n; i++ {
+			v.ints[i] = x
+		}
+	case flux.TUInt:
+		for i, x := 0, e.v.UInt(); i < v.n; i++ {
+			v.uints[i] = x
+		}
+	case flux.TString:
+		for i, x := 0, e.v.Str(); i < v.n; i++ {
+			v.strs[i] = x
+		}
+	case flux.TBool:
+		for i, x := 0, e.v.Bool(); i < v.n; i++ {
+			v.bools[i] = x
+		}
+	}
+	return v, nil
+}
+
+type arithmeticEvaluator struct {
+	op   ast.OperatorKind
+	typ  flux.ColType
+	l, r evaluator
+}
+
+func (e *arithmeticEvaluator) Type() flux.ColType { return e.typ }
+
+func (e *arithmeticEvaluator) Eval(cr flux.ColReader, sel []bool) (*vector, error) {
+	l, err := e.l.Eval(cr, sel)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.r.Eval(cr, sel)
+	if err != nil {
+		return nil, err
+	}
+	v := newVector(e.typ, l.n)
+	v.mergeNulls(l, r)
+
+	switch e.typ {
+	case flux.TFloat:
+		err = arithmeticFloats(e.op, v, l.floats, r.floats, sel)
+	case flux.TInt:
+		err = arithmeticInts(e.op, v, l.ints, r.ints, sel)
+	case flux.TUInt:
+		err = arithmeticUints(e.op, v, l.uints, r.uints, sel)
+	case flux.TString:
+		for i := range v.strs {
+			v.strs[i] = l.strs[i] + r.strs[i]
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func arithmeticFloats(op ast.OperatorKind, v *vector, a, b []float64, sel []bool) error {
+	out := v.floats
+	switch op {
+	case ast.AdditionOperator:
+		for i := range out {
+			out[i] = a[i] + b[i]
+		}
+	case ast.SubtractionOperator:
+		for i := range out {
+			out[i] = a[i] - b[i]
+		}
+	case ast.MultiplicationOperator:
+		for i := range out {
+			out[i] = a[i] * b[i]
+		}
+	case ast.DivisionOperator:
+		for i := range out {
+			if b[i] == 0 {
+				if v.evaluated(sel, i) {
+					return errDivideByZero
+				}
+				continue
+			}
+			out[i] = a[i] / b[i]
+		}
+	case ast.ModuloOperator:
+		for i := range out {
+			if b[i] == 0 {
+				if v.evaluated(sel, i) {
+					return errModZero
+				}
+				continue
+			}
+			out[i] = math.Mod(a[i], b[i])
+		}
+	}
+	return nil
+}
+
+func arithmeticInts(op ast.OperatorKind, v *vector, a, b []int64, sel []bool) error {
+	out := v.ints
+	switch op {
+	case ast.AdditionOperator:
+		for i := range out {
+			out[i] = a[i] + b[i]
+		}
+	case ast.SubtractionOperator:
+		for i := range out {
+			out[i] = a[i] - b[i]
+		}
+	case ast.MultiplicationOperator:
+		for i := range out {
+			out[i] = a[i] * b[i]
+		}
+	case ast.DivisionOperator:
+		for i := range out {
+			if b[i] == 0 {
+				if v.evaluated(sel, i) {
+					return errDivideByZero
+				}
+				continue
+			}
+			out[i] = a[i] / b[i]
+		}
+	case ast.ModuloOperator:
+		for i := range out {
+			if b[i] == 0 {
+				if v.evaluated(sel, i) {
+					return errModZero
+				}
+				continue
+			}
+			out[i] = a[i] % b[i]
+		}
+	}
+	return nil
+}
+
+func arithmeticUints(op ast.OperatorKind, v *vector, a, b []uint64, sel []bool) error {
+	out := v.uints
+	switch op {
+	case ast.AdditionOperator:
+		for i := range out {
+			out[i] = a[i] + b[i]
+		}
+	case ast.SubtractionOperator:
+		for i := range out {
+			out[i] = a[i] - b[i]
+		}
+	case ast.MultiplicationOperator:
+		for i := range out {
+			out[i] = a[i] * b[i]
+		}
+	case ast.DivisionOperator:
+		for i := range out {
+			if b[i] == 0 {
+				if v.evaluated(sel, i) {
+					return errDivideByZero
+				}
+				continue
+			}
+			out[i] = a[i] / b[i]
+		}
+	case ast.ModuloOperator:
+		for i := range out {
+			if b[i] == 0 {
+				if v.evaluated(sel, i) {
+					return errModZero
+				}
+				continue
+			}
+			out[i] = a[i] % b[i]
+		}
+	}
+	return nil
+}
+
+// comparisonEvaluator compares two values of the same type.
+type comparisonEvaluator struct {
+	op   ast.OperatorKind
+	typ  flux.ColType
+	l, r evaluator
+}
+
+func (e *comparisonEvaluator) Type() flux.ColType { return flux.TBool }
+
+func (e *comparisonEvaluator) Eval(cr flux.ColReader, sel []bool) (*vector, error) {
+	l, err := e.l.Eval(cr, sel)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.r.Eval(cr, sel)
+	if err != nil {
+		return nil, err
+	}
+	v := newVector(flux.TBool, l.n)
+	v.mergeNulls(l, r)
+
+	out := v.bools
+	switch e.typ {
+	case flux.TFloat:
+		a, b := l.floats, r.floats
+		switch e.op {
+		case ast.EqualOperator:
+			for i := range out {
+				out[i] = a[i] == b[i]
+			}
+		case ast.NotEqualOperator:
+			for i := range out {
+				out[i] = a[i] != b[i]
+			}
+		case ast.LessThanOperator:
+			for i := range out {
+				out[i] = a[i] < b[i]
+			}
+		case ast.LessThanEqualOperator:
+			for i := range out {
+				out[i] = a[i] <= b[i]
+			}
+		case ast.GreaterThanOperator:
+			for i := range out {
+				out[i] = a[i] > b[i]
+			}
+		case ast.GreaterThanEqualOperator:
+			for i := range out {
+				out[i] = a[i] >= b[i]
+			}
+		}
+	case flux.TInt, flux.TTime:
+		a, b := l.ints, r.ints
+		switch e.op {
+		case ast.EqualOperator:
+			for i := range out {
+				out[i] = a[i] == b[i]
+			}
+		case ast.NotEqualOperator:
+			for i := range out {
+				out[i] = a[i] != b[i]
+			}
+		case ast.LessThanOperator:
+			for i := range out {
+				out[i] = a[i] < b[i]
+			}
+		case ast.LessThanEqualOperator:
+			for i := range out {
+				out[i] = a[i] <= b[i]
+			}
+		case ast.GreaterThanOperator:
+			for i := range out {
+				out[i] = a[i] > b[i]
+			}
+		case ast.GreaterThanEqualOperator:
+			for i := range out {
+				out[i] = a[i] >= b[i]
+			}
+		}
+	case flux.TUInt:
+		a, b := l.uints, r.uints
+		switch e.op {
+		case ast.EqualOperator:
+			for i := range out {
+				out[i] = a[i] == b[i]
+			}
+		case ast.NotEqualOperator:
+			for i := range out {
+				out[i] = a[i] != b[i]
+			}
+		case ast.LessThanOperator:
+			for i := range out {
+				out[i] = a[i] < b[i]
+			}
+		case ast.LessThanEqualOperator:
+			for i := range out {
+				out[i] = a[i] <= b[i]
+			}
+		case ast.GreaterThanOperator:
+			for i := range out {
+				out[i] = a[i] > b[i]
+			}
+		case ast.GreaterThanEqualOperator:
+			for i := range out {
+				out[i] = a[i] >= b[i]
+			}
+		}
+	case flux.TString:
+		a, b := l.strs, r.strs
+		switch e.op {
+		case ast.EqualOperator:
+			for i := range out {
+				out[i] = a[i] == b[i]
+			}
+		case ast.NotEqualOperator:
+			for i := range out {
+				out[i] = a[i] != b[i]
+			}
+		case ast.LessThanOperator:
+			for i := range out {
+				out[i] = a[i] < b[i]
+			}
+		case ast.LessThanEqualOperator:
+			for i := range out {
+				out[i] = a[i] <= b[i]
+			}
+		case ast.GreaterThanOperator:
+			for i := range out {
+				out[i] = a[i] > b[i]
+			}
+		case ast.GreaterThanEqualOperator:
+			for i := range out {
+				out[i] = a[i] >= b[i]
+			}
+		}
+	case flux.TBool:
+		a, b := l.bools, r.bools
+		for i := range out {
+			out[i] = (a[i] == b[i]) == (e.op == ast.EqualOperator)
+		}
+	}
+	return v, nil
+}
+
+type regexpEvaluator struct {
+	x     evaluator
+	re    *regexp.Regexp
+	match bool
+}
+
+func (e *regexpEvaluator) Type() flux.ColType { return flux.TBool }
+
+func (e *regexpEvaluator) Eval(cr flux.ColReader, sel []bool) (*vector, error) {
+	x, err := e.x.Eval(cr, sel)
+	if err != nil {
+		return nil, err
+	}
+	v := newVector(flux.TBool, x.n)
+	v.mergeNulls(x)
+	for i := range v.bools {
+		if v.evaluated(sel, i) {
+			v.bools[i] = e.re.MatchString(x.strs[i]) == e.match
+		}
+	}
+	return v, nil
+}
+
+// logicalEvaluator evaluates and and or expressions. As when evaluating row
+// by row, the right operand is not evaluated for the rows whose result is
+// decided by the left operand.
+type logicalEvaluator struct {
+	op   ast.LogicalOperatorKind
+	l, r evaluator
+}
+
+func (e *logicalEvaluator) Type() flux.ColType { return flux.TBool }
+
+func (e *logicalEvaluator) Eval(cr flux.ColReader, sel []bool) (*vector, error) {
+	l, err := e.l.Eval(cr, sel)
+	if err != nil {
+		return nil, err
+	}
+	rsel := make([]bool, l.n)
+	for i := range rsel {
+		if sel == nil || sel[i] {
+			rsel[i] = (!l.null(i) && l.bools[i]) == (e.op == ast.AndOperator)
+		}
+	}
+	r, err := e.r.Eval(cr, rsel)
+	if err != nil {
+		return nil, err
+	}
+
+	v := newVector(flux.TBool, l.n)
+	for i := range v.bools {
+		switch lv := !l.null(i) && l.bools[i]; {
+		case e.op == ast.AndOperator && !lv:
+			v.bools[i] = false
+		case e.op == ast.OrOperator && lv:
+			v.bools[i] = true
+		case r.null(i):
+			v.setNull(i)
+		default:
+			v.bools[i] = r.bools[i]
+		}
+	}
+	return v, nil
+}
+
+type unaryEvaluator struct {
+	op ast.OperatorKind
+	x  evaluator
+}
+
+func (e *unaryEvaluator) Type() flux.ColType {
+	if e.op == ast.ExistsOperator {
+		return flux.TBool
+	}
+	return e.x.Type()
+}
+
+func (e *unaryEvaluator) Eval(cr flux.ColReader, sel []bool) (*vector, error) {
+	x, err := e.x.Eval(cr, sel)
+	if err != nil {
+		return nil, err
+	}
+	v := newVector(e.Type(), x.n)
+	if e.op == ast.ExistsOperator {
+		for i := range v.bools {
+			v.bools[i] = !x.null(i)
+		}
+		return v, nil
+	}
+
+	v.mergeNulls(x)
+	switch {
+	case e.op == ast.NotOperator:
+		for i := range v.bools {
+			v.bools[i] = !x.bools[i]
+		}
+	case v.typ == flux.TFloat:
+		for i := range v.floats {
+			v.floats[i] = -x.floats[i]
+		}
+	case v.typ == flux.TInt:
+		for i := range v.ints {
+			v.ints[i] = -x.ints[i]
+		}
+	}
+	return v, nil
+}
+
+// conversionEvaluator converts numbers, booleans and times like the float,
+// int and uint functions.
+type conversionEvaluator struct {
+	typ flux.ColType
+	x   evaluator
+}
+
+func (e *conversionEvaluator) Type() flux.ColType { return e.typ }
+
+func (e *conversionEvaluator) Eval(cr flux.ColReader, sel []bool) (*vector, error) {
+	x, err := e.x.Eval(cr, sel)
+	if err != nil {
+		return nil, err
+	}
+	if x.typ == e.typ {
+		return x, nil
+	}
+	v := newVector(e.typ, x.n)
+	v.mergeNulls(x)
+	for i := 0; i < v.n; i++ {
+		var f float64
+		var n int64
+		var u uint64
+		switch x.typ {
+		case flux.TFloat:
+			f, n, u = x.floats[i], int64(x.floats[i]), uint64(x.floats[i])
+		case flux.TInt, flux.TTime:
+			f, n, u = float64(x.ints[i]), x.ints[i], uint64(x.ints[i])
+		case flux.TUInt:
+			f, n, u = float64(x.uints[i]), int64(x.uints[i]), x.uints[i]
+		case flux.TBool:
+			if x.bools[i] {
+				f, n, u = 1, 1, 1
+			}
+		}
+		switch e.typ {
+		case flux.TFloat:
+			v.floats[i] = f
+		case flux.TInt:
+			v.ints[i] = n
+		case flux.TUInt:
+			v.uints[i] = u
+		}
+	}
+	return v, nil
+}
+
+// vectorCompiler compiles the expressions of a function taking a single
+// record into evaluators for tables with the columns cols.
+type vectorCompiler struct {
+	param string
+	cols  []flux.ColMeta
+	scope values.Scope
+	// refs are the indexes of the columns referenced by the compiled expressions.
+	refs []int
+}
+
+// newVectorCompiler returns a compiler for fn and its body, or
+// errNotVectorizable if fn is not a function of a single record
+// returning an expression.
+func newVectorCompiler(fn *semantic.FunctionExpression, scope values.Scope, cols []flux.ColMeta) (*vectorCompiler, semantic.Expression, error) {
+	if fn == nil || fn.Block == nil || fn.Block.Parameters == nil ||
+		len(fn.Block.Parameters.List) != 1 || fn.Block.Parameters.Pipe != nil {
+		return nil, nil, errNotVectorizable
+	}
+	body, ok := fn.Block.Body.(semantic.Expression)
+	if !ok {
+		return nil, nil, errNotVectorizable
+	}
+	return &vectorCompiler{
+		param: fn.Block.Parameters.List[0].Key.Name,
+		cols:  cols,
+		scope: scope,
+	}, body, nil
+}
+
+// column returns the name of the column e refers to, if e is a member of the record.
+func (c *vectorCompiler) column(e semantic.Expression) (string, bool) {
+	m, ok := e.(*semantic.MemberExpression)
+	if !ok {
+		return "", false
+	}
+	id, ok := m.Object.(*semantic.IdentifierExpression)
+	return m.Property, ok && id.Name == c.param
+}
+
+func (c *vectorCompiler) compile(e semantic.Expression) (evaluator, error) {
+	switch e := e.(type) {
+	case *semantic.MemberExpression:
+		label, ok := c.column(e)
+		if !ok {
+			return nil, errNotVectorizable
+		}
+		j := execute.ColIdx(label, c.cols)
+		if j < 0 {
+			return nil, errNotVectorizable
+		}
+		c.refs = append(c.refs, j)
+		return &columnEvaluator{j: j, typ: c.cols[j].Type}, nil
+	case *semantic.IdentifierExpression:
+		if e.Name == c.param {
+			return nil, errNotVectorizable
+		}
+		v, ok := c.scope.Lookup(e.Name)
+		if !ok {
+			return nil, errNotVectorizable
+		}
+		return constant(v)
+	case *semantic.FloatLiteral:
+		return constant(values.NewFloat(e.Value))
+	case *semantic.IntegerLiteral:
+		return constant(values.NewInt(e.Value))
+	case *semantic.UnsignedIntegerLiteral:
+		return constant(values.NewUInt(e.Value))
+	case *semantic.StringLiteral:
+		return constant(values.NewString(e.Value))
+	case *semantic.BooleanLiteral:
+		return constant(values.NewBool(e.Value))
+	case *semantic.DateTimeLiteral:
+		return constant(values.NewTime(values.ConvertTime(e.Value)))
+	case *semantic.UnaryExpression:
+		return c.compileUnary(e)
+	case *semantic.LogicalExpression:
+		l, err := c.compileBool(e.Left)
+		if err != nil {
+			return nil, err
+		}
+		r, err := c.compileBool(e.Right)
+		if err != nil {
+			return nil, err
+		}
+		return &logicalEvaluator{op: e.Operator, l: l, r: r}, nil
+	case *semantic.BinaryExpression:
+		return c.compileBinary(e)
+	case *semantic.CallExpression:
+		return c.compileConversion(e)
+	}
+	return nil, errNotVectorizable
+}
+
+func (c *vectorCompiler) compileBool(e semantic.Expression) (evaluator, error) {
+	x, err := c.compile(e)
+	if err != nil {
+		return nil, err
+	} else if x.Type() != flux.TBool {
+		return nil, errNotVectorizable
+	}
+	return x, nil
+}
+
+func (c *vectorCompiler) compileUnary(e *semantic.UnaryExpression) (evaluator, error) {
+	x, err := c.compile(e.Argument)
+	if err != nil {
+		return nil, err
+	}
+	switch typ := x.Type(); {
+	case e.Operator == ast.ExistsOperator,
+		e.Operator == ast.NotOperator && typ == flux.TBool,
+		e.Operator == ast.SubtractionOperator && (typ == flux.TFloat || typ == flux.TInt):
+		return &unaryEvaluator{op: e.Operator, x: x}, nil
+	}
+	return nil, errNotVectorizable
+}
+
+func (c *vectorCompiler) compileBinary(e *semantic.BinaryExpression) (evaluator, error) {
+	l, err := c.compile(e.Left)
+	if err != nil {
+		return nil, err
+	}
+	if e.Operator == ast.RegexpMatchOperator || e.Operator == ast.NotRegexpMatchOperator {
+		re, ok := e.Right.(*semantic.RegexpLiteral)
+		if !ok || l.Type() != flux.TString {
+			return nil, errNotVectorizable
+		}
+		return &regexpEvaluator{x: l, re: re.Value, match: e.Operator == ast.RegexpMatchOperator}, nil
+	}
+	r, err := c.compile(e.Right)
+	if err != nil {
+		return nil, err
+	}
+	lt, rt := l.Type(), r.Type()
+
+	switch e.Operator {
+	case ast.AdditionOperator, ast.SubtractionOperator, ast.MultiplicationOperator,
+		ast.DivisionOperator, ast.ModuloOperator:
+		if lt != rt {
+			return nil, errNotVectorizable
+		}
+		switch lt {
+		case flux.TFloat, flux.TInt, flux.TUInt:
+		case flux.TString:
+			if e.Operator != ast.AdditionOperator {
+				return nil, errNotVectorizable
+			}
+		default:
+			return nil, errNotVectorizable
+		}
+		return &arithmeticEvaluator{op: e.Operator, typ: lt, l: l, r: r}, nil
+	case ast.EqualOperator, ast.NotEqualOperator:
+	case ast.LessThanOperator, ast.LessThanEqualOperator,
+		ast.GreaterThanOperator, ast.GreaterThanEqualOperator:
+		if lt == flux.TBool || rt == flux.TBool {
+			return nil, errNotVectorizable
+		}
+	default:
+		return nil, errNotVectorizable
+	}
+
+	// Integers are compared to floats as floats. Other comparisons of
+	// different types, such as of integers to unsigned integers, are
+	// evaluated row by row.
+	if lt != rt {
+		switch {
+		case lt == flux.TFloat && (rt == flux.TInt || rt == flux.TUInt):
+			r = &conversionEvaluator{typ: flux.TFloat, x: r}
+		case rt == flux.TFloat && (lt == flux.TInt || lt == flux.TUInt):
+			l = &conversionEvaluator{typ: flux.TFloat, x: l}
+		default:
+			return nil, errNotVectorizable
+		}
+		lt = flux.TFloat
+	}
+	return &comparisonEvaluator{op: e.Operator, typ: lt, l: l, r: r}, nil
+}
+
+var conversions = map[string]flux.ColType{
+	"float": flux.TFloat,
+	"int":   flux.TInt,
+	"uint":  flux.TUInt,
+}
+
+var (
+	preludeOnce sync.Once
+	prelude     values.Scope
+)
+
+// compileConversion compiles calls to the float, int and uint functions of
+// the prelude with a number, a boolean or a time.
+func (c *vectorCompiler) compileConversion(e *semantic.CallExpression) (evaluator, error) {
+	id, ok := e.Callee.(*semantic.IdentifierExpression)
+	if !ok || e.Pipe != nil || e.Arguments == nil || len(e.Arguments.Properties) != 1 {
+		return nil, errNotVectorizable
+	}
+	typ, ok := conversions[id.Name]
+	if !ok || e.Arguments.Properties[0].Key.Key() != "v" {
+		return nil, errNotVectorizable
+	}
+
+	// The function must not have been redefined.
+	preludeOnce.Do(func() { prelude = flux.Prelude() })
+	fn, ok := c.scope.Lookup(id.Name)
+	if !ok {
+		return nil, errNotVectorizable
+	}
+	if builtin, ok := prelude.Lookup(id.Name); !ok || fn != builtin {
+		return nil, errNotVectorizable
+	}
+
+	x, err := c.compile(e.Arguments.Properties[0].Value)
+	if err != nil {
+		return nil, err
+	}
+	switch xt := x.Type(); xt {
+	case flux.TFloat, flux.TInt, flux.TUInt, flux.TBool:
+	case flux.TTime:
+		if typ == flux.TFloat {
+			return nil, errNotVectorizable
+		}
+	default:
+		return nil, errNotVectorizable
+	}
+	return &conversionEvaluator{typ: typ, x: x}, nil
+}
+
+// constant returns an evaluator of the value v of a basic type.
+func constant(v values.Value) (evaluator, error) {
+	if v.IsNull() {
+		return nil, errNotVectorizable
+	}
+	var typ flux.ColType
+	switch v.Type().Nature() {
+	case semantic.Float:
+		typ = flux.TFloat
+	case semantic.Int:
+		typ = flux.TInt
+	case semantic.UInt:
+		typ = flux.TUInt
+	case semantic.String:
+		typ = flux.TString
+	case semantic.Bool:
+		typ = flux.TBool
+	case semantic.Time:
+		typ = flux.TTime
+	default:
+		return nil, errNotVectorizable
+	}
+	return &constantEvaluator{typ: typ, v: v}, nil
+}
+
+// schemaKey identifies the columns of a table and those of its group key.
+func schemaKey(tbl flux.Table) string {
+	var b strings.Builder
+	for _, c := range tbl.Cols() {
+		b.WriteString(c.Label)
+		b.WriteByte(0)
+		b.WriteString(c.Type.String())
+		if tbl.Key().HasCol(c.Label) {
+			b.WriteByte('*')
+		}
+		b.WriteByte(0)
+	}
+	return b.String()
+}
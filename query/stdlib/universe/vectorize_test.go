@@ -0,0 +1,220 @@
+package universe
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/dependencies/dependenciestest"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/interpreter"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/parser"
+	"github.com/influxdata/flux/plan"
+	"github.com/influxdata/flux/semantic"
+	_ "github.com/influxdata/flux/stdlib"
+	"github.com/influxdata/flux/stdlib/universe"
+	"github.com/influxdata/flux/values/valuestest"
+)
+
+func init() {
+	flux.FinalizeBuiltIns()
+}
+
+func TestFilter_Vectorized(t *testing.T) {
+	for _, tc := range []struct {
+		fn         string
+		vectorized bool
+	}{
+		{fn: `(r) => r._value > 2.0`, vectorized: true},
+		{fn: `(r) => r._value > 2`, vectorized: true},
+		{fn: `(r) => r.i * 2 >= 4 and r.s != "c"`, vectorized: true},
+		{fn: `(r) => not r.b or r.u < uint(v: 3)`, vectorized: true},
+		{fn: `(r) => exists r._value`, vectorized: true},
+		{fn: `(r) => r.s =~ /^[ab]/`, vectorized: true},
+		{fn: `(r) => r.i != 0 and 10 / r.i > 3`, vectorized: true},
+		{fn: `(r) => r.i == 0 or 10 % r.i == 0`, vectorized: true},
+		{fn: `(r) => float(v: r.i) + r._value < 5.0 and -r.i < 0`, vectorized: true},
+		{fn: `(r) => r._time >= 1970-01-01T00:00:00.000000003Z`, vectorized: true},
+		{fn: `(r) => r.t == "a"`},
+		{fn: `(r) => r.i > r.u`},
+		{fn: `(r) => r.missing > 1`},
+	} {
+		t.Run(tc.fn, func(t *testing.T) {
+			spec := &universe.FilterProcedureSpec{Fn: resolvedFunction(t, tc.fn)}
+			ctx := dependenciestest.Default().Inject(context.Background())
+
+			want, wantErr := process(t, func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+				tx, d, err := universe.NewFilterTransformation(ctx, spec, id, alloc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return tx, d
+			})
+
+			var vectorized bool
+			got, gotErr := process(t, func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+				tx, d, err := NewFilterTransformation(ctx, spec, id, alloc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				vectorized = tx.(*filterTransformation).compile(testTables()[0]) != nil
+				return tx, d
+			})
+
+			if vectorized != tc.vectorized {
+				t.Errorf("unexpected vectorization: got %v, exp %v", vectorized, tc.vectorized)
+			}
+			compareResults(t, want, wantErr, got, gotErr)
+		})
+	}
+}
+
+func TestFilter_Vectorized_DivideByZero(t *testing.T) {
+	spec := &universe.FilterProcedureSpec{Fn: resolvedFunction(t, `(r) => 10 / r.i > 3`)}
+	ctx := dependenciestest.Default().Inject(context.Background())
+
+	_, err := process(t, func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+		tx, d, err := NewFilterTransformation(ctx, spec, id, alloc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx, d
+	})
+	if err == nil || !strings.Contains(err.Error(), "cannot divide by zero") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestMap_Vectorized(t *testing.T) {
+	for _, tc := range []struct {
+		fn         string
+		vectorized bool
+	}{
+		{fn: `(r) => ({r with _value: r._value * 2.0})`, vectorized: true},
+		{fn: `(r) => ({r with x: float(v: r.i) / 2.0, y: r.s + "!", z: r.u > uint(v: 1)})`, vectorized: true},
+		{fn: `(r) => ({_time: r._time, v: r.i % 3, t: r.t})`, vectorized: true},
+		{fn: `(r) => ({_time: r._time, v: r.u - uint(v: 1), ok: exists r._value})`, vectorized: true},
+		{fn: `(r) => ({r with t: "x"})`},
+		{fn: `(r) => ({r with v: string(v: r.i)})`},
+	} {
+		t.Run(tc.fn, func(t *testing.T) {
+			spec := &universe.MapProcedureSpec{Fn: resolvedFunction(t, tc.fn)}
+			ctx := dependenciestest.Default().Inject(context.Background())
+
+			want, wantErr := process(t, func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+				cache := execute.NewTableBuilderCache(alloc)
+				d := execute.NewDataset(id, execute.DiscardingMode, cache)
+				tx, err := universe.NewMapTransformation(ctx, spec, d, cache)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return tx, d
+			})
+
+			var vectorized bool
+			got, gotErr := process(t, func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset) {
+				cache := execute.NewTableBuilderCache(alloc)
+				d := execute.NewDataset(id, execute.DiscardingMode, cache)
+				tx, err := NewMapTransformation(ctx, spec, d, cache, alloc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				p, _ := tx.(*mapTransformation).compile(testTables()[0])
+				vectorized = p != nil
+				return tx, d
+			})
+
+			if vectorized != tc.vectorized {
+				t.Errorf("unexpected vectorization: got %v, exp %v", vectorized, tc.vectorized)
+			}
+			compareResults(t, want, wantErr, got, gotErr)
+		})
+	}
+}
+
+// testTables returns two tables, grouped by t, with a column of each type
+// and null values.
+func testTables() []flux.Table {
+	cols := []flux.ColMeta{
+		{Label: "_time", Type: flux.TTime},
+		{Label: "_value", Type: flux.TFloat},
+		{Label: "b", Type: flux.TBool},
+		{Label: "i", Type: flux.TInt},
+		{Label: "s", Type: flux.TString},
+		{Label: "t", Type: flux.TString},
+		{Label: "u", Type: flux.TUInt},
+	}
+	return []flux.Table{
+		&executetest.Table{
+			KeyCols: []string{"t"},
+			ColMeta: cols,
+			Data: [][]interface{}{
+				{execute.Time(1), 1.5, true, int64(1), "a", "a", uint64(1)},
+				{execute.Time(2), nil, false, int64(2), "b", "a", uint64(2)},
+				{execute.Time(3), 3.5, nil, int64(0), "c", "a", nil},
+				{execute.Time(4), -2.0, true, nil, nil, "a", uint64(4)},
+			},
+		},
+		&executetest.Table{
+			KeyCols: []string{"t"},
+			ColMeta: cols,
+			Data: [][]interface{}{
+				{execute.Time(1), 4.0, false, int64(5), "ab", "b", uint64(7)},
+				{execute.Time(5), 0.5, true, int64(-3), "ba", "b", uint64(2)},
+			},
+		},
+	}
+}
+
+func resolvedFunction(t *testing.T, src string) interpreter.ResolvedFunction {
+	t.Helper()
+	pkg, err := semantic.New(parser.ParseSource(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := pkg.Files[0].Body[0].(*semantic.ExpressionStatement).Expression.(*semantic.FunctionExpression)
+	return interpreter.ResolvedFunction{Fn: fn, Scope: valuestest.NowScope()}
+}
+
+// process returns the tables produced by the transformation for testTables.
+func process(t *testing.T, create func(id execute.DatasetID, alloc *memory.Allocator) (execute.Transformation, execute.Dataset)) ([]*executetest.Table, error) {
+	t.Helper()
+	store := executetest.NewDataStore()
+	tx, d := create(executetest.RandomDatasetID(), &memory.Allocator{})
+	d.SetTriggerSpec(plan.DefaultTriggerSpec)
+	d.AddTransformation(store)
+
+	parentID := executetest.RandomDatasetID()
+	var err error
+	for _, tbl := range testTables() {
+		if err = tx.Process(parentID, tbl); err != nil {
+			break
+		}
+	}
+	tx.Finish(parentID, err)
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := executetest.TablesFromCache(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	executetest.NormalizeTables(tables)
+	sort.Sort(executetest.SortedTables(tables))
+	return tables, nil
+}
+
+func compareResults(t *testing.T, want []*executetest.Table, wantErr error, got []*executetest.Table, gotErr error) {
+	t.Helper()
+	if (wantErr == nil) != (gotErr == nil) {
+		t.Fatalf("unexpected error: got %v, exp %v", gotErr, wantErr)
+	} else if !cmp.Equal(want, got) {
+		t.Errorf("unexpected tables -want/+got\n%s", cmp.Diff(want, got))
+	}
+}
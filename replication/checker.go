@@ -0,0 +1,383 @@
+package replication
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/lang"
+	"github.com/influxdata/influxdb"
+	influxlogger "github.com/influxdata/influxdb/logger"
+	"github.com/influxdata/influxdb/query"
+	"go.uber.org/zap"
+)
+
+// Window summarizes the points of a window of a bucket.
+type Window struct {
+	Start time.Time
+
+	// Count is the number of points of the window, and Checksum a checksum
+	// of the number of points of each series, independent of their order.
+	Count    int64
+	Checksum uint64
+}
+
+// Result is the result of the comparison of a source bucket with one of its
+// targets.
+type Result struct {
+	Target string
+
+	// Start and Stop bound the compared windows.
+	Start, Stop time.Time
+
+	// Lag is the time between the most recent points of the source and of
+	// the target.
+	Lag time.Duration
+
+	// DivergentWindows are the starts of the windows whose points differ,
+	// and MissingPoints the number of points of the source missing from
+	// these windows of the target.
+	DivergentWindows []time.Time
+	MissingPoints    int64
+
+	// Err is the error which prevented the comparison, if any.
+	Err error
+}
+
+// Divergent returns true if the target differs from the source.
+func (r *Result) Divergent() bool {
+	return len(r.DivergentWindows) > 0
+}
+
+// Checker periodically compares the points of source buckets with the points
+// of the buckets they are replicated to.
+type Checker struct {
+	Checks []Check
+
+	// Source queries the local buckets, and NewTarget returns the service
+	// querying a target.
+	Source    query.QueryService
+	NewTarget func(Target) query.QueryService
+
+	// Now returns the current time.
+	Now func() time.Time
+
+	metrics *metrics
+	log     *zap.Logger
+}
+
+// NewChecker returns a Checker for the configuration c, querying the local
+// buckets with source.
+func NewChecker(log *zap.Logger, c *Config, source query.QueryService, newTarget func(Target) query.QueryService) *Checker {
+	return &Checker{
+		Checks:    c.Checks,
+		Source:    source,
+		NewTarget: newTarget,
+		Now:       time.Now,
+		metrics:   newMetrics(),
+		log:       log,
+	}
+}
+
+// Run checks the replication of the buckets every interval until ctx is
+// canceled.
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	log := c.log.With(
+		zap.String("service", "replication-check"),
+		influxlogger.DurationLiteral("interval", interval),
+	)
+	log.Info("Starting")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, check := range c.Checks {
+			c.run(ctx, log, check)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			log.Info("Stopping")
+			return
+		}
+	}
+}
+
+// run checks the replication of a bucket, recording the results in the
+// metrics and logging the divergences.
+func (c *Checker) run(ctx context.Context, log *zap.Logger, check Check) {
+	bucket := check.BucketID.String()
+	results, err := c.Check(ctx, check)
+	if err != nil {
+		log.Error("Failed to check replication", zap.String("bucket_id", bucket), zap.Error(err))
+		for _, t := range check.Targets {
+			c.metrics.Runs.WithLabelValues(bucket, t.Name, "error").Inc()
+		}
+		return
+	}
+
+	for _, r := range results {
+		tlog := log.With(zap.String("bucket_id", bucket), zap.String("target", r.Target))
+		if r.Err != nil {
+			tlog.Error("Failed to check replication", zap.Error(r.Err))
+			c.metrics.Runs.WithLabelValues(bucket, r.Target, "error").Inc()
+			continue
+		}
+
+		c.metrics.Lag.WithLabelValues(bucket, r.Target).Set(r.Lag.Seconds())
+		c.metrics.DivergentWindows.WithLabelValues(bucket, r.Target).Set(float64(len(r.DivergentWindows)))
+		c.metrics.MissingPoints.WithLabelValues(bucket, r.Target).Set(float64(r.MissingPoints))
+
+		if !r.Divergent() {
+			c.metrics.Runs.WithLabelValues(bucket, r.Target, "ok").Inc()
+			continue
+		}
+		c.metrics.Runs.WithLabelValues(bucket, r.Target, "divergent").Inc()
+		tlog.Warn("Replication target diverges from source",
+			zap.Time("start", r.Start),
+			zap.Time("stop", r.Stop),
+			zap.Int("divergent_windows", len(r.DivergentWindows)),
+			zap.Time("first_divergent_window", r.DivergentWindows[0]),
+			zap.Int64("missing_points", r.MissingPoints),
+			zap.Duration("lag", r.Lag))
+	}
+}
+
+// Check compares the windows of the source bucket of check, older than its
+// lag, with the windows of each of its targets. An error is returned if the
+// source can not be queried, while the errors of the targets are reported in
+// their result.
+func (c *Checker) Check(ctx context.Context, check Check) ([]Result, error) {
+	window := check.Window.Duration
+	stop := c.Now().Add(-check.Lag.Duration).Truncate(window)
+	start := stop.Add(-time.Duration(check.Windows) * window)
+
+	source := bucketQuerier{
+		qs:       c.Source,
+		orgID:    check.OrgID,
+		bucketID: check.BucketID,
+		auth:     readAuthorization(check.OrgID, check.BucketID),
+	}
+	srcWindows, err := source.windows(ctx, start, stop, window)
+	if err != nil {
+		return nil, err
+	}
+	srcLatest, err := source.latest(ctx, start)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(check.Targets))
+	for _, t := range check.Targets {
+		r := Result{Target: t.Name, Start: start, Stop: stop}
+		target := bucketQuerier{
+			qs:       c.NewTarget(t),
+			orgID:    t.OrgID,
+			bucketID: t.BucketID,
+		}
+
+		var windows map[time.Time]Window
+		var latest time.Time
+		if windows, r.Err = target.windows(ctx, start, stop, window); r.Err == nil {
+			latest, r.Err = target.latest(ctx, start)
+		}
+		if r.Err != nil {
+			results = append(results, r)
+			continue
+		}
+
+		if !srcLatest.IsZero() && srcLatest.After(latest) {
+			if latest.IsZero() {
+				latest = start
+			}
+			r.Lag = srcLatest.Sub(latest)
+		}
+		r.DivergentWindows, r.MissingPoints = compareWindows(start, stop, window, srcWindows, windows)
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// compareWindows returns the starts of the windows between start and stop
+// which differ between the source and the target, and the number of points
+// of the source missing from the target.
+func compareWindows(start, stop time.Time, window time.Duration, source, target map[time.Time]Window) ([]time.Time, int64) {
+	var divergent []time.Time
+	var missing int64
+	for t := start; t.Before(stop); t = t.Add(window) {
+		s, d := source[t], target[t]
+		if s.Count == d.Count && s.Checksum == d.Checksum {
+			continue
+		}
+		divergent = append(divergent, t)
+		if s.Count > d.Count {
+			missing += s.Count - d.Count
+		}
+	}
+	return divergent, missing
+}
+
+// bucketQuerier queries the windows of a bucket.
+type bucketQuerier struct {
+	qs       query.QueryService
+	orgID    influxdb.ID
+	bucketID influxdb.ID
+
+	// auth authorizes the queries of the local buckets, the remote
+	// buckets being authorized by the token of their target.
+	auth *influxdb.Authorization
+}
+
+// windows returns the windows of the bucket between start and stop, by the
+// time they start.
+func (q bucketQuerier) windows(ctx context.Context, start, stop time.Time, window time.Duration) (map[time.Time]Window, error) {
+	script := fmt.Sprintf(`from(bucketID: %q)
+	|> range(start: %s, stop: %s)
+	|> window(every: %dns)
+	|> count()`, q.bucketID, start.Format(time.RFC3339Nano), stop.Format(time.RFC3339Nano), window.Nanoseconds())
+
+	windows := make(map[time.Time]Window)
+	err := q.query(ctx, script, func(tbl flux.Table) error {
+		key := tbl.Key()
+		startIdx := indexOfKey(key, "_start")
+		if startIdx < 0 {
+			return fmt.Errorf("table %s has no _start column", key)
+		}
+		wstart := key.ValueTime(startIdx).Time().UTC()
+		series := seriesString(key)
+
+		return tbl.Do(func(cr flux.ColReader) error {
+			j := indexOfCol(cr.Cols(), "_value")
+			if j < 0 || cr.Cols()[j].Type != flux.TInt {
+				return fmt.Errorf("table %s has no integer _value column", key)
+			}
+			vs := cr.Ints(j)
+			for i := 0; i < cr.Len(); i++ {
+				if vs.IsNull(i) {
+					continue
+				}
+				n := vs.Value(i)
+				w := windows[wstart]
+				w.Start = wstart
+				w.Count += n
+				w.Checksum += checksum(series, n)
+				windows[wstart] = w
+			}
+			return nil
+		})
+	})
+	return windows, err
+}
+
+// latest returns the time of the most recent point of the bucket since
+// start, or the zero time if it has none.
+func (q bucketQuerier) latest(ctx context.Context, start time.Time) (time.Time, error) {
+	script := fmt.Sprintf(`from(bucketID: %q)
+	|> range(start: %s)
+	|> last()
+	|> keep(columns: ["_time"])
+	|> group()
+	|> sort(columns: ["_time"], desc: true)
+	|> limit(n: 1)`, q.bucketID, start.Format(time.RFC3339Nano))
+
+	var latest time.Time
+	err := q.query(ctx, script, func(tbl flux.Table) error {
+		return tbl.Do(func(cr flux.ColReader) error {
+			j := indexOfCol(cr.Cols(), "_time")
+			if j < 0 {
+				return fmt.Errorf("table %s has no _time column", tbl.Key())
+			}
+			vs := cr.Times(j)
+			for i := 0; i < cr.Len(); i++ {
+				if t := time.Unix(0, vs.Value(i)).UTC(); !vs.IsNull(i) && t.After(latest) {
+					latest = t
+				}
+			}
+			return nil
+		})
+	})
+	return latest, err
+}
+
+func (q bucketQuerier) query(ctx context.Context, script string, fn func(flux.Table) error) error {
+	req := &query.Request{
+		Authorization:  q.auth,
+		OrganizationID: q.orgID,
+		Compiler:       lang.FluxCompiler{Query: script},
+	}
+	it, err := q.qs.Query(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer it.Release()
+
+	for it.More() {
+		if err := it.Next().Tables().Do(fn); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// readAuthorization returns an authorization to read a local bucket.
+// At this point we are behind authorization, so we are faking a read only
+// permission to the bucket.
+func readAuthorization(orgID, bucketID influxdb.ID) *influxdb.Authorization {
+	return &influxdb.Authorization{
+		Status: influxdb.Active,
+		ID:     bucketID,
+		OrgID:  orgID,
+		Permissions: []influxdb.Permission{
+			{
+				Action: influxdb.ReadAction,
+				Resource: influxdb.Resource{
+					Type:  influxdb.BucketsResourceType,
+					OrgID: &orgID,
+					ID:    &bucketID,
+				},
+			},
+		},
+	}
+}
+
+// seriesString returns the string columns of a group key, but the bounds
+// of its window, which identify the series of a table.
+func seriesString(key flux.GroupKey) string {
+	var sb strings.Builder
+	for j, c := range key.Cols() {
+		if c.Type != flux.TString || c.Label == "_start" || c.Label == "_stop" {
+			continue
+		}
+		sb.WriteString(c.Label)
+		sb.WriteByte('=')
+		sb.WriteString(key.ValueString(j))
+		sb.WriteByte(',')
+	}
+	return sb.String()
+}
+
+// checksum hashes the number of points n of a series. Checksums are summed,
+// so that the checksum of a window does not depend on the order of its
+// series.
+func checksum(series string, n int64) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s%d", series, n)
+	return h.Sum64()
+}
+
+func indexOfKey(key flux.GroupKey, label string) int {
+	return indexOfCol(key.Cols(), label)
+}
+
+func indexOfCol(cols []flux.ColMeta, label string) int {
+	for j, c := range cols {
+		if c.Label == label {
+			return j
+		}
+	}
+	return -1
+}
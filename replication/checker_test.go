@@ -0,0 +1,189 @@
+package replication_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/lang"
+	"github.com/influxdata/flux/values"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/check"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/replication"
+	"go.uber.org/zap/zaptest"
+)
+
+var (
+	orgID    = influxdb.ID(0x1000)
+	bucketID = influxdb.ID(0x2000)
+	now      = time.Date(2020, 1, 1, 0, 10, 30, 0, time.UTC)
+)
+
+// fakeBucket answers the queries of the checker with the number of points of
+// each series by window.
+type fakeBucket struct {
+	// counts maps the series to their number of points by window index.
+	counts map[string][]int64
+	latest time.Time
+	err    error
+}
+
+func (b *fakeBucket) Check(ctx context.Context) check.Response {
+	return check.Response{Name: "fake", Status: check.StatusPass}
+}
+
+func (b *fakeBucket) Query(ctx context.Context, req *query.Request) (flux.ResultIterator, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	script := req.Compiler.(lang.FluxCompiler).Query
+
+	var tables []*executetest.Table
+	if strings.Contains(script, "count()") {
+		start := now.Add(-time.Minute).Truncate(time.Minute).Add(-10 * time.Minute)
+		for series, counts := range b.counts {
+			for i, n := range counts {
+				wstart := start.Add(time.Duration(i) * time.Minute)
+				tables = append(tables, &executetest.Table{
+					KeyCols: []string{"_start", "_stop", "_measurement", "host"},
+					ColMeta: []flux.ColMeta{
+						{Label: "_start", Type: flux.TTime},
+						{Label: "_stop", Type: flux.TTime},
+						{Label: "_measurement", Type: flux.TString},
+						{Label: "host", Type: flux.TString},
+						{Label: "_value", Type: flux.TInt},
+					},
+					Data: [][]interface{}{
+						{values.ConvertTime(wstart), values.ConvertTime(wstart.Add(time.Minute)), "cpu", series, n},
+					},
+				})
+			}
+		}
+	} else if !b.latest.IsZero() {
+		tables = append(tables, &executetest.Table{
+			ColMeta: []flux.ColMeta{{Label: "_time", Type: flux.TTime}},
+			Data:    [][]interface{}{{values.ConvertTime(b.latest)}},
+		})
+	}
+	return flux.NewSliceResultIterator([]flux.Result{executetest.NewResult(tables)}), nil
+}
+
+func TestChecker_Check(t *testing.T) {
+	ones := []int64{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}
+	source := &fakeBucket{
+		counts: map[string][]int64{"a": ones, "b": ones},
+		latest: now,
+	}
+	targets := map[string]*fakeBucket{
+		"same": {
+			counts: map[string][]int64{"a": ones, "b": ones},
+			latest: now,
+		},
+		"missing": {
+			counts: map[string][]int64{"a": ones, "b": {1, 1, 1, 0, 1, 1, 1, 1, 1, 0}},
+			latest: now.Add(-30 * time.Second),
+		},
+		// The same number of points, but in another series.
+		"swapped": {
+			counts: map[string][]int64{"a": {2, 1, 1, 1, 1, 1, 1, 1, 1, 1}, "b": {0, 1, 1, 1, 1, 1, 1, 1, 1, 1}},
+			latest: now,
+		},
+		"down": {err: errors.New("connection refused")},
+	}
+
+	check := replication.Check{OrgID: orgID, BucketID: bucketID}
+	for _, name := range []string{"same", "missing", "swapped", "down"} {
+		check.Targets = append(check.Targets, replication.Target{
+			Name: name, URL: "http://" + name, OrgID: orgID, BucketID: bucketID,
+		})
+	}
+	config := &replication.Config{Checks: []replication.Check{check}}
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	c := replication.NewChecker(zaptest.NewLogger(t), config, source, func(t replication.Target) query.QueryService {
+		return targets[t.Name]
+	})
+	c.Now = func() time.Time { return now }
+
+	results, err := c.Check(context.Background(), config.Checks[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("got %d results, expected 4", len(results))
+	}
+
+	start := time.Date(2019, 12, 31, 23, 59, 0, 0, time.UTC)
+	if r := results[0]; r.Err != nil || r.Divergent() || r.Lag != 0 || !r.Start.Equal(start) {
+		t.Fatalf("unexpected result for same target: %+v", r)
+	}
+	if r := results[1]; r.Err != nil || len(r.DivergentWindows) != 2 || r.MissingPoints != 2 || r.Lag != 30*time.Second ||
+		!r.DivergentWindows[0].Equal(start.Add(3*time.Minute)) || !r.DivergentWindows[1].Equal(start.Add(9*time.Minute)) {
+		t.Fatalf("unexpected result for missing target: %+v", r)
+	}
+	if r := results[2]; r.Err != nil || len(r.DivergentWindows) != 1 || r.MissingPoints != 0 || !r.DivergentWindows[0].Equal(start) {
+		t.Fatalf("unexpected result for swapped target: %+v", r)
+	}
+	if r := results[3]; r.Err == nil {
+		t.Fatalf("expected an error for down target: %+v", r)
+	}
+
+	// The source is required.
+	source.err = errors.New("query failed")
+	if _, err := c.Check(context.Background(), config.Checks[0]); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	target := replication.Target{Name: "a", URL: "http://a", OrgID: orgID, BucketID: bucketID}
+	for _, tt := range []struct {
+		name   string
+		config replication.Config
+		err    string
+	}{
+		{name: "no checks", err: "at least one check"},
+		{
+			name:   "no targets",
+			config: replication.Config{Checks: []replication.Check{{OrgID: orgID, BucketID: bucketID}}},
+			err:    "at least one target",
+		},
+		{
+			name: "duplicate targets",
+			config: replication.Config{Checks: []replication.Check{{
+				OrgID: orgID, BucketID: bucketID, Targets: []replication.Target{target, target},
+			}}},
+			err: "duplicate target",
+		},
+		{
+			name: "defaults",
+			config: replication.Config{Checks: []replication.Check{{
+				OrgID: orgID, BucketID: bucketID, Targets: []replication.Target{target},
+			}}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, expected %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			ch := tt.config.Checks[0]
+			if ch.Window.Duration != replication.DefaultWindow || ch.Windows != replication.DefaultWindows || ch.Lag.Duration != replication.DefaultLag {
+				t.Fatalf("unexpected defaults %+v", ch)
+			}
+		})
+	}
+}
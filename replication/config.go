@@ -0,0 +1,124 @@
+// Package replication verifies that the points of buckets are replicated to
+// other InfluxDB instances, by comparing their points window by window.
+//
+// The replication itself is done outside of InfluxDB, such as by Telegraf or
+// by tasks writing to a remote instance.
+package replication
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/influxdata/influxdb"
+)
+
+// Defaults of the checks.
+const (
+	DefaultWindow  = time.Minute
+	DefaultWindows = 10
+	DefaultLag     = time.Minute
+)
+
+// Config configures the verification of the replication of buckets.
+type Config struct {
+	Checks []Check `json:"checks"`
+}
+
+// Check compares the points of a source bucket with the points of the
+// buckets it is replicated to.
+type Check struct {
+	// OrgID and BucketID identify the local source bucket.
+	OrgID    influxdb.ID `json:"orgID"`
+	BucketID influxdb.ID `json:"bucketID"`
+
+	// Targets are the buckets the source bucket is replicated to.
+	Targets []Target `json:"targets"`
+
+	// Window is the duration of the compared windows, DefaultWindow if
+	// zero, and Windows the number of windows compared by each run,
+	// DefaultWindows if zero.
+	Window  influxdb.Duration `json:"window"`
+	Windows int               `json:"windows"`
+
+	// Lag is the time points are given to be replicated, DefaultLag if zero:
+	// the windows more recent than the lag are not compared.
+	Lag influxdb.Duration `json:"lag"`
+}
+
+// Target is a bucket of a remote InfluxDB the source bucket is replicated to.
+type Target struct {
+	// Name identifies the target in logs and metrics.
+	Name string `json:"name"`
+
+	// URL is the address of the InfluxDB, and Token a token allowed to read
+	// the bucket.
+	URL                string `json:"url"`
+	Token              string `json:"token"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify"`
+
+	OrgID    influxdb.ID `json:"orgID"`
+	BucketID influxdb.ID `json:"bucketID"`
+}
+
+// LoadConfig reads a JSON configuration from the file at path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("unable to parse replication check config %s: %v", path, err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid replication check config %s: %v", path, err)
+	}
+	return &c, nil
+}
+
+// Validate returns an error if the configuration is invalid, and sets the
+// defaults of the checks.
+func (c *Config) Validate() error {
+	if len(c.Checks) == 0 {
+		return fmt.Errorf("at least one check is required")
+	}
+	for i := range c.Checks {
+		ch := &c.Checks[i]
+		if !ch.OrgID.Valid() || !ch.BucketID.Valid() {
+			return fmt.Errorf("check %d: orgID and bucketID are required", i)
+		}
+		if len(ch.Targets) == 0 {
+			return fmt.Errorf("check %d: at least one target is required", i)
+		}
+		if ch.Window.Duration < 0 || ch.Windows < 0 || ch.Lag.Duration < 0 {
+			return fmt.Errorf("check %d: window, windows and lag must not be negative", i)
+		}
+		if ch.Window.Duration == 0 {
+			ch.Window.Duration = DefaultWindow
+		}
+		if ch.Windows == 0 {
+			ch.Windows = DefaultWindows
+		}
+		if ch.Lag.Duration == 0 {
+			ch.Lag.Duration = DefaultLag
+		}
+
+		names := make(map[string]bool)
+		for j, t := range ch.Targets {
+			if t.Name == "" || t.URL == "" {
+				return fmt.Errorf("check %d: target %d: name and url are required", i, j)
+			}
+			if names[t.Name] {
+				return fmt.Errorf("check %d: duplicate target %q", i, t.Name)
+			}
+			names[t.Name] = true
+			if !t.OrgID.Valid() || !t.BucketID.Valid() {
+				return fmt.Errorf("check %d: target %q: orgID and bucketID are required", i, t.Name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package replication
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "replication"
+	subsystem = "check"
+)
+
+type metrics struct {
+	Lag              *prometheus.GaugeVec
+	DivergentWindows *prometheus.GaugeVec
+	MissingPoints    *prometheus.GaugeVec
+	Runs             *prometheus.CounterVec
+}
+
+func newMetrics() *metrics {
+	labels := []string{"bucket", "target"}
+	return &metrics{
+		Lag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "lag_seconds",
+			Help:      "Time between the most recent points of the source bucket and of the target.",
+		}, labels),
+		DivergentWindows: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "divergent_windows",
+			Help:      "Number of windows whose points differ between the source bucket and the target in the last check.",
+		}, labels),
+		MissingPoints: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "missing_points",
+			Help:      "Number of points of the source bucket missing from the target in the last check.",
+		}, labels),
+		Runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "runs_total",
+			Help:      "Number of checks of a target by status.",
+		}, append(labels, "status")),
+	}
+}
+
+// PrometheusCollectors satisfies the prom.PrometheusCollector interface.
+func (c *Checker) PrometheusCollectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.metrics.Lag,
+		c.metrics.DivergentWindows,
+		c.metrics.MissingPoints,
+		c.metrics.Runs,
+	}
+}
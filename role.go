@@ -0,0 +1,121 @@
+package influxdb
+
+import (
+	"context"
+	"fmt"
+)
+
+// ErrRoleNotFound is the error msg for a missing role.
+const ErrRoleNotFound = "role not found"
+
+// RoleService manages roles, the named bundles of permissions of an
+// organization that are assigned to users and authorizations.
+//
+// The permissions of a role are resolved each time its assignees are
+// authorized, so that updating a role updates the permissions of all of
+// them.
+type RoleService interface {
+	// FindRoleByID returns a single role by ID.
+	FindRoleByID(ctx context.Context, id ID) (*Role, error)
+
+	// FindRoles returns the roles that match filter and the total count of
+	// matching roles.
+	FindRoles(ctx context.Context, filter RoleFilter, opt ...FindOptions) ([]*Role, int, error)
+
+	// CreateRole creates a new role and sets r.ID with the new identifier.
+	CreateRole(ctx context.Context, r *Role) error
+
+	// UpdateRole updates a single role with a changeset.
+	UpdateRole(ctx context.Context, id ID, upd RoleUpdate) (*Role, error)
+
+	// DeleteRole removes a role by ID, and unassigns it from its users and
+	// authorizations.
+	DeleteRole(ctx context.Context, id ID) error
+
+	// FindRoleUsers returns the IDs of the users the role is assigned to.
+	FindRoleUsers(ctx context.Context, roleID ID) ([]ID, error)
+
+	// AddRoleUser assigns the role to the user.
+	AddRoleUser(ctx context.Context, roleID, userID ID) error
+
+	// RemoveRoleUser unassigns the role from the user.
+	RemoveRoleUser(ctx context.Context, roleID, userID ID) error
+}
+
+// Role is a named bundle of permissions of an organization.
+type Role struct {
+	ID          ID           `json:"id,omitempty"`
+	OrgID       ID           `json:"orgID"`
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Permissions []Permission `json:"permissions"`
+	CRUDLog
+}
+
+// Valid returns an error if the role has no name or organization, or if one
+// of its permissions is invalid or for another organization.
+func (r *Role) Valid() error {
+	if r.Name == "" {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "role name is required",
+		}
+	}
+	if !r.OrgID.Valid() {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "role orgID is required",
+		}
+	}
+	return validRolePermissions(r.OrgID, r.Permissions)
+}
+
+func validRolePermissions(orgID ID, ps []Permission) error {
+	for _, p := range ps {
+		if err := p.Valid(); err != nil {
+			return &Error{
+				Code: EInvalid,
+				Err:  err,
+			}
+		}
+		if p.Resource.OrgID != nil && *p.Resource.OrgID != orgID {
+			return &Error{
+				Code: EInvalid,
+				Msg:  fmt.Sprintf("permission %s is not for org id %s", p, orgID),
+			}
+		}
+	}
+	return nil
+}
+
+// RoleUpdate is the changeset of a role. Only the fields specified are
+// updated, and Permissions, if not nil, replaces all the permissions of the
+// role.
+type RoleUpdate struct {
+	Name        *string      `json:"name,omitempty"`
+	Description *string      `json:"description,omitempty"`
+	Permissions []Permission `json:"permissions,omitempty"`
+}
+
+// Apply applies the changeset to the role r.
+func (u RoleUpdate) Apply(r *Role) error {
+	if u.Name != nil {
+		r.Name = *u.Name
+	}
+	if u.Description != nil {
+		r.Description = *u.Description
+	}
+	if u.Permissions != nil {
+		r.Permissions = u.Permissions
+	}
+	return r.Valid()
+}
+
+// RoleFilter selects the roles of FindRoles.
+type RoleFilter struct {
+	ID    *ID
+	OrgID *ID
+	Name  *string
+	// UserID selects the roles assigned to the user.
+	UserID *ID
+}
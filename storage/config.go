@@ -4,6 +4,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/influxdata/influxdb/pkg/objstore"
 	"github.com/influxdata/influxdb/toml"
 	"github.com/influxdata/influxdb/tsdb"
 	"github.com/influxdata/influxdb/tsdb/tsi1"
@@ -41,6 +42,10 @@ type Config struct {
 	// Index config.
 	Index     tsi1.Config `toml:"index"`
 	IndexPath string      `toml:"index-path"` // Overrides the default path.
+
+	// Object store config. Experimental: the TSM files of the engine are
+	// kept in the object store if it is enabled.
+	ObjectStore objstore.Config `toml:"object-store"`
 }
 
 // NewConfig initialises a new config for an Engine.
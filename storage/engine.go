@@ -18,6 +18,7 @@ import (
 	"github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/pkg/limiter"
+	"github.com/influxdata/influxdb/pkg/objstore"
 	"github.com/influxdata/influxdb/storage/wal"
 	"github.com/influxdata/influxdb/tsdb"
 	"github.com/influxdata/influxdb/tsdb/tsi1"
@@ -58,6 +59,9 @@ type Engine struct {
 	engine  *tsm1.Engine
 	wal     *wal.WAL
 
+	// objectStore keeps the TSM files of the engine in an object store.
+	objectStore *objectStoreFiles
+
 	retentionEnforcer        runner
 	retentionEnforcerLimiter runnable
 
@@ -131,6 +135,17 @@ func WithFileStoreObserver(obs tsm1.FileStoreObserver) Option {
 	}
 }
 
+// WithObjectStore makes the engine keep its TSM files in the object store,
+// its local directory acting as a cache of them. Every file is stored before
+// it is made live, and the files missing from the directory are fetched from
+// the store when the engine is opened. It replaces any file store observer.
+func WithObjectStore(store objstore.Store) Option {
+	return func(e *Engine) {
+		e.objectStore = newObjectStoreFiles(store, e.config.GetEnginePath(e.path))
+		e.engine.WithFileStoreObserver(e.objectStore)
+	}
+}
+
 // WithCompactionPlanner makes the engine have the provided compaction planner.
 func WithCompactionPlanner(planner tsm1.CompactionPlanner) Option {
 	return func(e *Engine) {
@@ -147,6 +162,14 @@ func WithCompactionLimiter(limiter limiter.Fixed) Option {
 	}
 }
 
+// WithMemoryBudget accounts for the memory used by the cache and compactions
+// of the engine in a budget that may be shared with queries and other engines.
+func WithMemoryBudget(budget *limiter.Memory) Option {
+	return func(e *Engine) {
+		e.engine.WithMemoryBudget(budget)
+	}
+}
+
 // WithCompactionSemaphore sets the semaphore used to coordinate full compactions
 // across multiple storage engines.
 func WithCompactionSemaphore(s influxdb.Semaphore) Option {
@@ -168,6 +191,7 @@ func NewEngine(path string, c Config, options ...Option) *Engine {
 	// Initialize series file.
 	e.sfile = tsdb.NewSeriesFile(c.GetSeriesFilePath(path))
 	e.sfile.LargeWriteThreshold = c.TSDB.LargeSeriesWriteThreshold
+	e.sfile.IDAllocation = c.TSDB.SeriesIDAllocation
 
 	// Initialise index.
 	e.index = tsi1.NewIndex(e.sfile, c.Index,
@@ -215,6 +239,9 @@ func (e *Engine) WithLogger(log *zap.Logger) {
 	e.index.WithLogger(e.logger)
 	e.engine.WithLogger(e.logger)
 	e.wal.WithLogger(e.logger)
+	if e.objectStore != nil {
+		e.objectStore.logger = e.logger
+	}
 	if r, ok := e.retentionEnforcer.(*retentionEnforcer); ok {
 		r.WithLogger(e.logger)
 	}
@@ -229,6 +256,7 @@ func (e *Engine) PrometheusCollectors() []prometheus.Collector {
 	metrics = append(metrics, tsm1.PrometheusCollectors()...)
 	metrics = append(metrics, wal.PrometheusCollectors()...)
 	metrics = append(metrics, RetentionPrometheusCollectors()...)
+	metrics = append(metrics, newBucketSeriesCollector(e, e.defaultMetricLabels))
 	return metrics
 }
 
@@ -245,6 +273,18 @@ func (e *Engine) Open(ctx context.Context) (err error) {
 	span, ctx := tracing.StartSpanFromContext(ctx)
 	defer span.Finish()
 
+	if e.objectStore != nil {
+		downloaded, err := e.objectStore.sync(ctx)
+		if err != nil {
+			return err
+		}
+		if downloaded > 0 {
+			if _, err := os.Stat(e.config.GetIndexPath(e.path)); os.IsNotExist(err) {
+				e.logger.Warn("TSM files were fetched from the object store without an index; rebuild it with influxd inspect build-tsi")
+			}
+		}
+	}
+
 	// Open the services in order and clean up if any fail.
 	var oh openHelper
 	oh.Open(ctx, e.sfile)
@@ -526,14 +566,52 @@ func (e *Engine) WritePoints(ctx context.Context, points []models.Point) error {
 		return err
 	}
 
+	// Trace the write before its series are created.
+	trace := WriteTraceFromContext(ctx)
+	if trace != nil {
+		e.traceWrite(trace, collection)
+	}
+
 	// Add the write to the WAL to be replayed if there is a crash or shutdown.
-	if _, err := e.wal.WriteMulti(ctx, values); err != nil {
+	segment, err := e.wal.WriteMulti(ctx, values)
+	if err != nil {
 		return err
 	}
+	if trace != nil && segment >= 0 {
+		trace.addWALSegment(segment)
+	}
 
 	return e.writePointsLocked(ctx, collection, values)
 }
 
+// traceWrite records the series of the collection and their index partitions
+// to the trace, and whether they are new.
+func (e *Engine) traceWrite(trace *WriteTrace, collection *tsdb.SeriesCollection) {
+	trace.Points += collection.Length()
+	trace.Dropped += int(collection.Dropped)
+	if trace.DroppedReason == "" {
+		trace.DroppedReason = collection.Reason
+	}
+	if trace.IndexPartitions == nil {
+		trace.IndexPartitions = make(map[int]int)
+	}
+
+	var buf []byte
+	seen := make(map[string]struct{})
+	for iter := collection.Iterator(); iter.Next(); {
+		if _, ok := seen[string(iter.Key())]; ok {
+			continue
+		}
+		seen[string(iter.Key())] = struct{}{}
+
+		trace.Series++
+		if !e.sfile.HasSeries(iter.Name(), iter.Tags(), buf) {
+			trace.SeriesCreated++
+		}
+		trace.IndexPartitions[e.index.PartitionIndex(iter.Key())]++
+	}
+}
+
 // writePointsLocked does the work of writing points and must be called under some sort of lock.
 func (e *Engine) writePointsLocked(ctx context.Context, collection *tsdb.SeriesCollection, values map[string][]value.Value) error {
 	span, _ := tracing.StartSpanFromContext(ctx)
@@ -664,9 +742,174 @@ func (e *Engine) deleteBucketRangeLocked(ctx context.Context, orgID, bucketID pl
 	encoded := tsdb.EncodeName(orgID, bucketID)
 	name := models.EscapeMeasurement(encoded[:])
 
+	// Deletes of a single measurement only visit, and tombstone, its keys.
+	if measurement, ok := tsm1.PredicateMeasurement(pred); ok {
+		return e.engine.DeleteMeasurementRange(ctx, name, measurement, min, max, pred)
+	}
 	return e.engine.DeletePrefixRange(ctx, name, min, max, pred)
 }
 
+// CopyBucketRange copies the data of the src bucket within [min, max] to the
+// dst bucket. The cache is snapshotted first, so that every value written
+// before the copy started is copied. Blocks of the TSM files are copied
+// without being decoded whenever possible.
+func (e *Engine) CopyBucketRange(ctx context.Context, src, dst platform.BucketCopyTarget, min, max int64) (*platform.BucketCopyStats, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := e.engine.WriteSnapshot(ctx, tsm1.CacheStatusCopy); err != nil {
+		return nil, err
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return nil, ErrEngineClosed
+	}
+
+	srcName := tsdb.EncodeName(src.OrgID, src.BucketID)
+	dstName := tsdb.EncodeName(dst.OrgID, dst.BucketID)
+	stats, err := e.engine.CopyPrefixRange(ctx,
+		models.EscapeMeasurement(srcName[:]),
+		models.EscapeMeasurement(dstName[:]),
+		min, max,
+		e.createCopiedSeries,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &platform.BucketCopyStats{
+		Series:          stats.Keys,
+		Blocks:          stats.Blocks,
+		ReencodedBlocks: stats.Reencoded,
+		Bytes:           stats.Bytes,
+	}, nil
+}
+
+// createCopiedSeries adds the series of the TSM keys copied by a
+// CopyBucketRange to the index and series file.
+func (e *Engine) createCopiedSeries(keys [][]byte, types []byte) error {
+	collection := &tsdb.SeriesCollection{
+		Keys:  make([][]byte, 0, len(keys)),
+		Names: make([][]byte, 0, len(keys)),
+		Tags:  make([]models.Tags, 0, len(keys)),
+		Types: make([]models.FieldType, 0, len(keys)),
+	}
+	for i, key := range keys {
+		seriesKey, _ := tsm1.SeriesAndFieldFromCompositeKey(key)
+		name, tags := models.ParseKeyBytes(seriesKey)
+		collection.Keys = append(collection.Keys, seriesKey)
+		collection.Names = append(collection.Names, name)
+		collection.Tags = append(collection.Tags, tags)
+		collection.Types = append(collection.Types, blockFieldType(types[i]))
+	}
+
+	if err := e.index.CreateSeriesListIfNotExists(collection); err != nil {
+		return err
+	}
+	// A series of the destination bucket with a different type conflicts with the copy.
+	return collection.PartialWriteError()
+}
+
+// blockFieldType returns the field type of the values of a TSM block type.
+func blockFieldType(typ byte) models.FieldType {
+	switch typ {
+	case tsm1.BlockFloat64:
+		return models.Float
+	case tsm1.BlockInteger:
+		return models.Integer
+	case tsm1.BlockUnsigned:
+		return models.Unsigned
+	case tsm1.BlockBoolean:
+		return models.Boolean
+	case tsm1.BlockString:
+		return models.String
+	}
+	return models.Empty
+}
+
+// PauseCompactions stops level compactions of the engine, resuming them
+// automatically after timeout if it is positive.
+func (e *Engine) PauseCompactions(ctx context.Context, timeout time.Duration) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return ErrEngineClosed
+	}
+
+	e.engine.PauseCompactions(timeout)
+	return nil
+}
+
+// ResumeCompactions restarts level compactions stopped by PauseCompactions.
+func (e *Engine) ResumeCompactions(ctx context.Context) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return ErrEngineClosed
+	}
+
+	e.engine.ResumeCompactions()
+	return nil
+}
+
+// CompactionStatus reports whether level compactions of the engine are paused.
+func (e *Engine) CompactionStatus(ctx context.Context) (*platform.CompactionStatus, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return nil, ErrEngineClosed
+	}
+
+	paused, expiry := e.engine.CompactionsPaused()
+	status := &platform.CompactionStatus{Paused: paused}
+	if !expiry.IsZero() {
+		status.ResumeAt = &expiry
+	}
+	return status, nil
+}
+
+// CompactionReport reports the bytes written by the snapshots and compactions
+// of the engine over its compaction report window.
+func (e *Engine) CompactionReport(ctx context.Context) (*platform.CompactionReport, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return nil, ErrEngineClosed
+	}
+
+	w := e.engine.WriteAmplification()
+	report := &platform.CompactionReport{
+		Since:              w.Since,
+		Levels:             make([]platform.CompactionLevelReport, len(w.Levels)),
+		WriteAmplification: w.Ratio(),
+	}
+	for i, l := range w.Levels {
+		report.Levels[i] = platform.CompactionLevelReport{
+			Level:        compactionLevelNames[i],
+			Compactions:  l.Compactions,
+			BytesWritten: l.Bytes,
+		}
+	}
+	return report, nil
+}
+
+// compactionLevelNames are the names of the levels of tsm1.WriteAmplification.
+var compactionLevelNames = [...]string{"snapshot", "1", "2", "3", "optimize", "full"}
+
 // CreateBackup creates a "snapshot" of all TSM data in the Engine.
 //   1) Snapshot the cache to ensure the backup includes all data written before now.
 //   2) Create hard links to all TSM files, in a new directory within the engine root directory.
@@ -806,6 +1049,19 @@ func (e *Engine) MeasurementCardinalityStats() (tsi1.MeasurementCardinalityStats
 	return e.index.MeasurementCardinalityStats()
 }
 
+// TimeRange returns the minimum and maximum time of the data of the bucket,
+// or false if it has no data. The range may be larger than that of the data,
+// as it spans the TSM files holding data of the bucket.
+func (e *Engine) TimeRange(orgID, bucketID influxdb.ID) (min, max int64, ok bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return 0, 0, false
+	}
+	name := tsdb.EncodeName(orgID, bucketID)
+	return e.engine.TimeRange(models.EscapeMeasurement(name[:]))
+}
+
 // MeasurementStats returns the current measurement stats for the engine.
 func (e *Engine) MeasurementStats() (tsm1.MeasurementStats, error) {
 	e.mu.RLock()
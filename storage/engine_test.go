@@ -7,6 +7,7 @@ import (
 	"math"
 	"math/rand"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
@@ -203,6 +204,48 @@ func TestEngine_WriteAddNewField(t *testing.T) {
 	}
 }
 
+func TestEngine_WriteDecimal(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	name := tsdb.EncodeName(engine.org, engine.bucket)
+	tags := models.NewTags(map[string]string{models.FieldKeyTagKey: "price", models.MeasurementTagKey: "stock"})
+	if err := engine.Engine.WritePoints(context.Background(), []models.Point{
+		models.MustNewPoint(string(name[:]), tags, map[string]interface{}{"price": models.FixedDecimal{Value: 1230, Scale: 2}}, time.Unix(1, 0)),
+		models.MustNewPoint(string(name[:]), tags, map[string]interface{}{"price": models.FixedDecimal{Value: -1, Scale: 18}}, time.Unix(2, 0)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Decimals are stored as strings holding their exact representation.
+	ctx := context.Background()
+	itr, err := engine.CreateCursorIterator(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cur, err := itr.Next(ctx, &tsdb.CursorRequest{
+		Name:      name[:],
+		Tags:      tags,
+		Field:     "price",
+		EndTime:   math.MaxInt64,
+		Ascending: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if cur == nil {
+		t.Fatal("expected cursor to be present")
+	}
+	defer cur.Close()
+	sc, ok := cur.(tsdb.StringArrayCursor)
+	if !ok {
+		t.Fatalf("unexpected cursor type %T", cur)
+	}
+	if got, exp := sc.Next().Values, []string{"12.30", "-0.000000000000000001"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("got values %q, exp %q", got, exp)
+	}
+}
+
 func TestEngine_DeleteBucket(t *testing.T) {
 	engine := NewDefaultEngine()
 	defer engine.Close()
@@ -352,6 +395,163 @@ func TestEngine_DeleteBucket_Predicate(t *testing.T) {
 
 }
 
+func TestEngine_DeleteBucket_MeasurementPredicate(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	p := func(m, kv string) models.Point {
+		return models.MustNewPoint(
+			tsdb.EncodeNameString(engine.org, engine.bucket),
+			models.NewTags(map[string]string{models.FieldKeyTagKey: "value", models.MeasurementTagKey: m, "host": kv}),
+			map[string]interface{}{"value": 1.0},
+			time.Unix(1, 2),
+		)
+	}
+
+	err := engine.Engine.WritePoints(context.TODO(), []models.Point{
+		p("cpu", "a"), p("cpu", "b"), p("mem", "a"), p("mem", "b"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comparison := func(tag, value string) *datatypes.Node {
+		return &datatypes.Node{
+			NodeType: datatypes.NodeTypeComparisonExpression,
+			Value:    &datatypes.Node_Comparison_{Comparison: datatypes.ComparisonEqual},
+			Children: []*datatypes.Node{
+				{NodeType: datatypes.NodeTypeTagRef,
+					Value: &datatypes.Node_TagRefValue{TagRefValue: tag},
+				},
+				{NodeType: datatypes.NodeTypeLiteral,
+					Value: &datatypes.Node_StringValue{StringValue: value},
+				},
+			},
+		}
+	}
+
+	// Remove a series of the cpu measurement.
+	pred, err := tsm1.NewProtobufPredicate(&datatypes.Predicate{
+		Root: &datatypes.Node{
+			NodeType: datatypes.NodeTypeLogicalExpression,
+			Value:    &datatypes.Node_Logical_{Logical: datatypes.LogicalAnd},
+			Children: []*datatypes.Node{
+				comparison(models.MeasurementTagKey, "cpu"),
+				comparison("host", "a"),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.DeleteBucketRangePredicate(context.Background(), engine.org, engine.bucket,
+		math.MinInt64, math.MaxInt64, pred); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := engine.SeriesCardinality(), int64(3); got != exp {
+		t.Fatalf("got %d series, exp %d series in index", got, exp)
+	}
+
+	// Remove the mem measurement.
+	pred, err = tsm1.NewProtobufPredicate(&datatypes.Predicate{Root: comparison(models.MeasurementTagKey, "mem")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.DeleteBucketRangePredicate(context.Background(), engine.org, engine.bucket,
+		math.MinInt64, math.MaxInt64, pred); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := engine.SeriesCardinality(), int64(1); got != exp {
+		t.Fatalf("got %d series, exp %d series in index", got, exp)
+	}
+}
+
+func TestEngine_CopyBucketRange(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	dst := influxdb.BucketCopyTarget{OrgID: engine.org, BucketID: 0x3333333333333333}
+	tags := func(host string) models.Tags {
+		return models.NewTags(map[string]string{
+			models.FieldKeyTagKey:    "value",
+			models.MeasurementTagKey: "cpu",
+			"host":                   host,
+		})
+	}
+	var points []models.Point
+	for _, host := range []string{"a", "b"} {
+		for i := int64(1); i <= 3; i++ {
+			points = append(points, models.MustNewPoint(
+				tsdb.EncodeNameString(engine.org, engine.bucket),
+				tags(host),
+				map[string]interface{}{"value": float64(i)},
+				time.Unix(i, 0),
+			))
+		}
+	}
+	if err := engine.Engine.WritePoints(context.Background(), points); err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleted values are not copied.
+	if err := engine.DeleteBucketRange(context.Background(), engine.org, engine.bucket,
+		time.Unix(3, 0).UnixNano(), time.Unix(3, 0).UnixNano()); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := engine.CopyBucketRange(context.Background(),
+		influxdb.BucketCopyTarget{OrgID: engine.org, BucketID: engine.bucket}, dst,
+		time.Unix(2, 0).UnixNano(), math.MaxInt64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Series != 2 || stats.Blocks+stats.ReencodedBlocks != 2 || stats.Bytes == 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	// Check the copied series were indexed.
+	if got, exp := engine.SeriesCardinality(), int64(4); got != exp {
+		t.Fatalf("got %d series, exp %d series in index", got, exp)
+	}
+
+	// Check only the values within the range were copied.
+	ctx := context.Background()
+	itr, err := engine.CreateCursorIterator(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := tsdb.EncodeName(dst.OrgID, dst.BucketID)
+	cur, err := itr.Next(ctx, &tsdb.CursorRequest{
+		Name:      name[:],
+		Tags:      tags("a"),
+		Field:     "value",
+		EndTime:   math.MaxInt64,
+		Ascending: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if cur == nil {
+		t.Fatal("expected cursor to be present")
+	}
+	defer cur.Close()
+	a := cur.(tsdb.FloatArrayCursor).Next()
+	if got, exp := a.Timestamps, []int64{time.Unix(2, 0).UnixNano()}; len(got) != 1 || got[0] != exp[0] {
+		t.Fatalf("got timestamps %v, exp %v", got, exp)
+	}
+
+	// Copying a range without data is a no-op.
+	stats, err = engine.CopyBucketRange(context.Background(),
+		influxdb.BucketCopyTarget{OrgID: engine.org, BucketID: engine.bucket}, dst,
+		time.Unix(10, 0).UnixNano(), math.MaxInt64)
+	if err != nil {
+		t.Fatal(err)
+	} else if *stats != (influxdb.BucketCopyStats{}) {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
 func TestEngine_OpenClose(t *testing.T) {
 	engine := NewDefaultEngine()
 	engine.MustOpen()
@@ -404,6 +604,46 @@ func TestEngine_InitializeMetrics(t *testing.T) {
 	}
 }
 
+func TestEngine_BucketSeriesMetrics(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	other := influxdb.ID(0x3333)
+	for bucket, points := range map[influxdb.ID]string{
+		engine.bucket: "cpu,host=a value=1 1\ncpu,host=b value=1 1\nmem,host=a free=1 1",
+		other:         "cpu,host=a value=1 1",
+	} {
+		name := tsdb.EncodeName(engine.org, bucket)
+		pts, err := models.ParsePointsString(points, string(models.EscapeMeasurement(name[:])))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := engine.Engine.WritePoints(context.Background(), pts); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(engine.PrometheusCollectors()...)
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for bucket, exp := range map[influxdb.ID]float64{engine.bucket: 3, other: 1} {
+		m := promtest.MustFindMetric(t, mfs, "storage_bucket_series_total", prometheus.Labels{
+			"node_id":   fmt.Sprint(engine.nodeID),
+			"engine_id": fmt.Sprint(engine.engineID),
+			"org_id":    engine.org.String(),
+			"bucket_id": bucket.String(),
+		})
+		if got := m.GetGauge().GetValue(); got != exp {
+			t.Errorf("[%s] got %v, expected %v", m, got, exp)
+		}
+	}
+}
+
 // Ensures that when a shard is closed, it removes any series meta-data
 // from the index.
 func TestEngineClose_RemoveIndex(t *testing.T) {
@@ -490,6 +730,50 @@ func TestEngine_WriteConflictingBatch(t *testing.T) {
 	}
 }
 
+func TestEngine_WriteTrace(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	name := tsdb.EncodeNameString(engine.org, engine.bucket)
+	point := func(host string, ts int64) models.Point {
+		return models.MustNewPoint(
+			name,
+			models.NewTags(map[string]string{models.FieldKeyTagKey: "value", models.MeasurementTagKey: "cpu", "host": host}),
+			map[string]interface{}{"value": 1.0},
+			time.Unix(ts, 0),
+		)
+	}
+
+	var trace storage.WriteTrace
+	ctx := storage.WithWriteTrace(context.Background(), &trace)
+	if err := engine.Engine.WritePoints(ctx, []models.Point{point("a", 1), point("a", 2), point("b", 1)}); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := [3]int{trace.Points, trace.Series, trace.SeriesCreated}, [3]int{3, 2, 2}; got != exp {
+		t.Fatalf("got points, series and created series %v, expected %v", got, exp)
+	}
+
+	// The trace accumulates the writes, of which only the new series are created.
+	if err := engine.Engine.WritePoints(ctx, []models.Point{point("a", 3), point("c", 1)}); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := [3]int{trace.Points, trace.Series, trace.SeriesCreated}, [3]int{5, 4, 3}; got != exp {
+		t.Fatalf("got points, series and created series %v, expected %v", got, exp)
+	}
+
+	var n int
+	for _, series := range trace.IndexPartitions {
+		n += series
+	}
+	if n != trace.Series {
+		t.Fatalf("got %d series in index partitions, expected %d", n, trace.Series)
+	}
+	if len(trace.WALSegments) != 1 {
+		t.Fatalf("got WAL segments %v, expected a single segment", trace.WALSegments)
+	}
+}
+
 // BenchmarkWritePoints_100K demonstrates the impact that batch size has on
 // writing a fixed number of points into storage. In this case 100K points are
 // written according to varying batch sizes.
@@ -4,6 +4,7 @@ import (
 	"sort"
 	"sync"
 
+	"github.com/influxdata/influxdb/tsdb"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -31,6 +32,7 @@ func RetentionPrometheusCollectors() []prometheus.Collector {
 const namespace = "storage"
 
 const retentionSubsystem = "retention" // sub-system associated with metrics for writing points.
+const bucketSubsystem = "bucket"       // sub-system associated with metrics for buckets.
 
 // retentionMetrics is a set of metrics concerned with tracking data about retention policies.
 type retentionMetrics struct {
@@ -88,3 +90,44 @@ func (rm *retentionMetrics) PrometheusCollectors() []prometheus.Collector {
 		rm.CheckDuration,
 	}
 }
+
+// bucketSeriesCollector reports the number of series of each bucket of an
+// engine, read from its index when the metrics are gathered.
+type bucketSeriesCollector struct {
+	engine *Engine
+	series *prometheus.Desc
+}
+
+func newBucketSeriesCollector(e *Engine, labels prometheus.Labels) *bucketSeriesCollector {
+	return &bucketSeriesCollector{
+		engine: e,
+		series: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bucketSubsystem, "series_total"),
+			"Number of series in the bucket.",
+			[]string{"org_id", "bucket_id"}, labels),
+	}
+}
+
+// Describe satisfies the prometheus.Collector interface.
+func (c *bucketSeriesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.series
+}
+
+// Collect satisfies the prometheus.Collector interface.
+func (c *bucketSeriesCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.engine.MeasurementCardinalityStats()
+	if err == ErrEngineClosed {
+		return
+	} else if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.series, err)
+		return
+	}
+
+	for name, n := range stats {
+		if len(name) != 16 {
+			continue // not the name of a bucket
+		}
+		org, bucket := tsdb.DecodeNameSlice([]byte(name))
+		ch <- prometheus.MustNewConstMetric(c.series, prometheus.GaugeValue, float64(n), org.String(), bucket.String())
+	}
+}
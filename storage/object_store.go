@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/pkg/fs"
+	"github.com/influxdata/influxdb/pkg/objstore"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+	"go.uber.org/zap"
+)
+
+// objectStoreFiles keeps the TSM, statistics and tombstone files of the engine
+// in an object store, the local directory of the engine acting as a cache of
+// them. It observes the file store so that every file is stored before it is
+// made live, and removed once it is unlinked.
+type objectStoreFiles struct {
+	store  objstore.Store
+	dir    string
+	logger *zap.Logger
+}
+
+func newObjectStoreFiles(store objstore.Store, dir string) *objectStoreFiles {
+	return &objectStoreFiles{
+		store:  store,
+		dir:    dir,
+		logger: zap.NewNop(),
+	}
+}
+
+// objectKey returns the key of the object of a file of the engine directory,
+// which is its final name, or false if the file is not kept in the store.
+func objectKey(path string) (string, bool) {
+	name := strings.TrimSuffix(filepath.Base(path), "."+tsm1.CompactionTempExtension)
+	switch filepath.Ext(name) {
+	case "." + tsm1.TSMFileExtension, "." + tsm1.TSSFileExtension, ".tombstone":
+		return name, true
+	}
+	return "", false
+}
+
+// FileFinishing stores the file at path before it is renamed to its final name.
+func (o *objectStoreFiles) FileFinishing(path string) error {
+	key, ok := objectKey(path)
+	if !ok {
+		return nil
+	}
+	if err := o.upload(context.Background(), path, key); err != nil {
+		return fmt.Errorf("unable to store %s in the object store: %v", key, err)
+	}
+	return nil
+}
+
+// FileUnlinking removes the file at path from the store before it is unlinked.
+func (o *objectStoreFiles) FileUnlinking(path string) error {
+	key, ok := objectKey(path)
+	if !ok {
+		return nil
+	}
+	if err := o.store.Delete(context.Background(), key); err != nil {
+		return fmt.Errorf("unable to remove %s from the object store: %v", key, err)
+	}
+	return nil
+}
+
+// sync downloads the files of the store missing from the directory, and stores
+// the files of the directory missing from the store, such as those written
+// before the store was configured. It returns the number of downloaded files.
+func (o *objectStoreFiles) sync(ctx context.Context) (int, error) {
+	if err := os.MkdirAll(o.dir, 0777); err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	objects, err := o.store.List(ctx, "")
+	if err != nil {
+		return 0, fmt.Errorf("unable to list the files of the object store: %v", err)
+	}
+	stored := make(map[string]int64, len(objects))
+	for _, obj := range objects {
+		stored[obj.Key] = obj.Size
+	}
+
+	fis, err := ioutil.ReadDir(o.dir)
+	if err != nil {
+		return 0, err
+	}
+	local := make(map[string]bool, len(fis))
+	var uploaded int
+	for _, fi := range fis {
+		if !fi.Mode().IsRegular() || strings.HasSuffix(fi.Name(), "."+tsm1.CompactionTempExtension) {
+			continue
+		}
+		key, ok := objectKey(fi.Name())
+		if !ok {
+			continue
+		}
+		local[key] = true
+		if size, ok := stored[key]; ok && size == fi.Size() {
+			continue
+		}
+		if err := o.upload(ctx, filepath.Join(o.dir, fi.Name()), key); err != nil {
+			return 0, fmt.Errorf("unable to store %s in the object store: %v", key, err)
+		}
+		uploaded++
+	}
+
+	var downloaded int
+	for _, obj := range objects {
+		key, ok := objectKey(obj.Key)
+		if !ok || key != obj.Key || local[key] {
+			continue
+		}
+		if err := o.download(ctx, key); err != nil {
+			return downloaded, fmt.Errorf("unable to fetch %s from the object store: %v", key, err)
+		}
+		downloaded++
+	}
+
+	o.logger.Info("Synchronized TSM files with the object store",
+		zap.Int("downloaded", downloaded),
+		zap.Int("uploaded", uploaded),
+		zap.Duration("elapsed", time.Since(start)))
+	return downloaded, nil
+}
+
+// upload stores the file at path as the object key.
+func (o *objectStoreFiles) upload(ctx context.Context, path, key string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	if err := o.store.Put(ctx, key, f, fi.Size()); err != nil {
+		return err
+	}
+	o.logger.Debug("Stored file in the object store",
+		zap.String("path", path),
+		zap.Int64("size", fi.Size()),
+		zap.Duration("elapsed", time.Since(start)))
+	return nil
+}
+
+// download fetches the object key into the directory. The file is written
+// with a temporary extension, so that it is removed by the engine if the
+// download is interrupted.
+func (o *objectStoreFiles) download(ctx context.Context, key string) error {
+	r, err := o.store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	path := filepath.Join(o.dir, key)
+	tmp := path + ".download." + tsm1.CompactionTempExtension
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return fs.RenameFile(tmp, path)
+}
@@ -0,0 +1,146 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/objstore"
+	"github.com/influxdata/influxdb/storage"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+func TestEngine_ObjectStore(t *testing.T) {
+	store := newMemoryObjectStore()
+
+	dir, err := ioutil.TempDir("", "storage_object_store_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Files written by the engine are stored in the object store.
+	path := filepath.Join(dir, "a")
+	e := storage.NewEngine(path, storage.NewConfig(), storage.WithEngineID(0), storage.WithNodeID(0), storage.WithObjectStore(store))
+	if err := e.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	err = e.WritePoints(context.Background(), []models.Point{models.MustNewPoint(
+		tsdb.EncodeNameString(influxdb.ID(1), influxdb.ID(2)),
+		models.NewTags(map[string]string{models.FieldKeyTagKey: "value", models.MeasurementTagKey: "cpu", "host": "server"}),
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1, 2),
+	)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Creating a backup snapshots the cache to a TSM file.
+	if _, _, err := e.CreateBackup(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := store.keys()
+	var tsm []string
+	for _, key := range keys {
+		if strings.HasSuffix(key, "."+tsm1.TSMFileExtension) {
+			tsm = append(tsm, key)
+		}
+	}
+	if len(tsm) != 1 {
+		t.Fatalf("expected a TSM file in the object store, got %q", keys)
+	}
+
+	// The files of the object store are fetched by an engine without them.
+	path = filepath.Join(dir, "b")
+	e = storage.NewEngine(path, storage.NewConfig(), storage.WithEngineID(0), storage.WithNodeID(0), storage.WithObjectStore(store))
+	if err := e.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	for _, key := range keys {
+		b, err := ioutil.ReadFile(filepath.Join(storage.NewConfig().GetEnginePath(path), key))
+		if err != nil {
+			t.Fatalf("file of the object store not fetched: %v", err)
+		}
+		if !bytes.Equal(b, store.objects[key]) {
+			t.Fatalf("unexpected content of %s", key)
+		}
+	}
+}
+
+// memoryObjectStore is an object store keeping objects in memory.
+type memoryObjectStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newMemoryObjectStore() *memoryObjectStore {
+	return &memoryObjectStore{objects: make(map[string][]byte)}
+}
+
+func (s *memoryObjectStore) Put(ctx context.Context, key string, r io.ReadSeeker, size int64) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = b
+	return nil
+}
+
+func (s *memoryObjectStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.objects[key]
+	if !ok {
+		return nil, objstore.ErrNotFound
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (s *memoryObjectStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *memoryObjectStore) List(ctx context.Context, prefix string) ([]objstore.Object, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var objects []objstore.Object
+	for key, b := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, objstore.Object{Key: key, Size: int64(len(b))})
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (s *memoryObjectStore) keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []string
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -26,6 +26,35 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.GoGoProtoPackageIsVersion2 // please upgrade the proto package
 
+type ReadFilterRequest_SeriesOrder int32
+
+const (
+	// SeriesOrderNone returns the series in an unspecified order, which may
+	// change between requests.
+	SeriesOrderNone ReadFilterRequest_SeriesOrder = 0
+	// SeriesOrderKey returns the series sorted lexicographically by their
+	// series key, the measurement name followed by the tags sorted by key.
+	SeriesOrderKey ReadFilterRequest_SeriesOrder = 1
+)
+
+var ReadFilterRequest_SeriesOrder_name = map[int32]string{
+	0: "SERIES_ORDER_NONE",
+	1: "SERIES_ORDER_KEY",
+}
+
+var ReadFilterRequest_SeriesOrder_value = map[string]int32{
+	"SERIES_ORDER_NONE": 0,
+	"SERIES_ORDER_KEY":  1,
+}
+
+func (x ReadFilterRequest_SeriesOrder) String() string {
+	return proto.EnumName(ReadFilterRequest_SeriesOrder_name, int32(x))
+}
+
+func (ReadFilterRequest_SeriesOrder) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_715e4bf4cdf1f73d, []int{0, 0}
+}
+
 type ReadGroupRequest_Group int32
 
 const (
@@ -178,9 +207,16 @@ func (ReadResponse_DataType) EnumDescriptor() ([]byte, []int) {
 }
 
 type ReadFilterRequest struct {
-	ReadSource *types.Any     `protobuf:"bytes,1,opt,name=read_source,json=readSource,proto3" json:"read_source,omitempty"`
-	Range      TimestampRange `protobuf:"bytes,2,opt,name=range,proto3" json:"range"`
-	Predicate  *Predicate     `protobuf:"bytes,3,opt,name=predicate,proto3" json:"predicate,omitempty"`
+	ReadSource  *types.Any                    `protobuf:"bytes,1,opt,name=read_source,json=readSource,proto3" json:"read_source,omitempty"`
+	Range       TimestampRange                `protobuf:"bytes,2,opt,name=range,proto3" json:"range"`
+	Predicate   *Predicate                    `protobuf:"bytes,3,opt,name=predicate,proto3" json:"predicate,omitempty"`
+	SeriesOrder ReadFilterRequest_SeriesOrder `protobuf:"varint,4,opt,name=series_order,json=seriesOrder,proto3,enum=influxdata.platform.storage.ReadFilterRequest_SeriesOrder" json:"series_order,omitempty"`
+	// SeriesLimit is the maximum number of series to return, if greater than
+	// zero. It requires SeriesOrderKey.
+	SeriesLimit int64 `protobuf:"varint,5,opt,name=series_limit,json=seriesLimit,proto3" json:"series_limit,omitempty"`
+	// ContinuationToken resumes a read after the last series returned by a
+	// previous read with the same predicate and SeriesOrderKey.
+	ContinuationToken []byte `protobuf:"bytes,6,opt,name=continuation_token,json=continuationToken,proto3" json:"continuation_token,omitempty"`
 }
 
 func (m *ReadFilterRequest) Reset()         { *m = ReadFilterRequest{} }
@@ -339,6 +375,10 @@ var xxx_messageInfo_Tag proto.InternalMessageInfo
 // Response message for ReadFilter and ReadGroup
 type ReadResponse struct {
 	Frames []ReadResponse_Frame `protobuf:"bytes,1,rep,name=frames,proto3" json:"frames"`
+	// ContinuationToken is set in the last response of a ReadFilter request
+	// with a SeriesLimit when more series remain, and resumes the read when
+	// passed in the next request.
+	ContinuationToken []byte `protobuf:"bytes,2,opt,name=continuation_token,json=continuationToken,proto3" json:"continuation_token,omitempty"`
 }
 
 func (m *ReadResponse) Reset()         { *m = ReadResponse{} }
@@ -1146,6 +1186,7 @@ func (m *StringValuesResponse) XXX_DiscardUnknown() {
 var xxx_messageInfo_StringValuesResponse proto.InternalMessageInfo
 
 func init() {
+	proto.RegisterEnum("influxdata.platform.storage.ReadFilterRequest_SeriesOrder", ReadFilterRequest_SeriesOrder_name, ReadFilterRequest_SeriesOrder_value)
 	proto.RegisterEnum("influxdata.platform.storage.ReadGroupRequest_Group", ReadGroupRequest_Group_name, ReadGroupRequest_Group_value)
 	proto.RegisterEnum("influxdata.platform.storage.ReadGroupRequest_HintFlags", ReadGroupRequest_HintFlags_name, ReadGroupRequest_HintFlags_value)
 	proto.RegisterEnum("influxdata.platform.storage.Aggregate_AggregateType", Aggregate_AggregateType_name, Aggregate_AggregateType_value)
@@ -1175,101 +1216,108 @@ func init() {
 func init() { proto.RegisterFile("storage_common.proto", fileDescriptor_715e4bf4cdf1f73d) }
 
 var fileDescriptor_715e4bf4cdf1f73d = []byte{
-	// 1498 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xdc, 0x57, 0xcb, 0x6f, 0xdb, 0xc8,
-	0x19, 0x17, 0xf5, 0x34, 0x3f, 0xc9, 0x32, 0x3d, 0x51, 0x5d, 0x87, 0x69, 0x24, 0x56, 0x28, 0x52,
-	0x17, 0x49, 0xe4, 0xd4, 0x49, 0xd1, 0x20, 0x6d, 0x0f, 0x96, 0x23, 0x5b, 0x6a, 0x6c, 0xc9, 0xa0,
-	0xe4, 0x00, 0xe9, 0x45, 0x18, 0xdb, 0x63, 0x86, 0x88, 0x44, 0xb2, 0x24, 0x15, 0x58, 0x40, 0x2f,
-	0xbd, 0x05, 0x3a, 0xb5, 0x97, 0x1e, 0x0a, 0x08, 0x58, 0x60, 0x8f, 0x7b, 0xdf, 0xbf, 0x21, 0x87,
-	0x05, 0x36, 0xc7, 0x3d, 0x09, 0xbb, 0x0a, 0xb0, 0xc0, 0xfe, 0x0b, 0x7b, 0x5a, 0xcc, 0x0c, 0x47,
-	0xa2, 0x6c, 0xc3, 0x96, 0xf6, 0xb4, 0xc8, 0x6d, 0xe6, 0x7b, 0xfc, 0xbe, 0x07, 0xbf, 0xc7, 0x10,
-	0x72, 0x9e, 0x6f, 0xbb, 0xd8, 0x20, 0xed, 0x13, 0xbb, 0xdb, 0xb5, 0xad, 0x92, 0xe3, 0xda, 0xbe,
-	0x8d, 0xee, 0x98, 0xd6, 0x59, 0xa7, 0x77, 0x7e, 0x8a, 0x7d, 0x5c, 0x72, 0x3a, 0xd8, 0x3f, 0xb3,
-	0xdd, 0x6e, 0x29, 0x90, 0x54, 0x73, 0x86, 0x6d, 0xd8, 0x4c, 0x6e, 0x93, 0x9e, 0xb8, 0x8a, 0x7a,
-	0xc7, 0xb0, 0x6d, 0xa3, 0x43, 0x36, 0xd9, 0xed, 0xb8, 0x77, 0xb6, 0x49, 0xba, 0x8e, 0xdf, 0x0f,
-	0x98, 0xb7, 0x2f, 0x32, 0xb1, 0x25, 0x58, 0x2b, 0x8e, 0x4b, 0x4e, 0xcd, 0x13, 0xec, 0x13, 0x4e,
-	0x28, 0xfe, 0x20, 0xc1, 0xaa, 0x4e, 0xf0, 0xe9, 0xae, 0xd9, 0xf1, 0x89, 0xab, 0x93, 0x7f, 0xf6,
-	0x88, 0xe7, 0xa3, 0x0a, 0xa4, 0x5d, 0x82, 0x4f, 0xdb, 0x9e, 0xdd, 0x73, 0x4f, 0xc8, 0xba, 0xa4,
-	0x49, 0x1b, 0xe9, 0xad, 0x5c, 0x89, 0xe3, 0x96, 0x04, 0x6e, 0x69, 0xdb, 0xea, 0x97, 0xb3, 0xe3,
-	0x51, 0x01, 0x28, 0x42, 0x93, 0xc9, 0xea, 0xe0, 0x4e, 0xce, 0x68, 0x0f, 0x12, 0x2e, 0xb6, 0x0c,
-	0xb2, 0x1e, 0x65, 0x00, 0xf7, 0x4b, 0xd7, 0x04, 0x5a, 0x6a, 0x99, 0x5d, 0xe2, 0xf9, 0xb8, 0xeb,
-	0xe8, 0x54, 0xa5, 0x1c, 0x7f, 0x3f, 0x2a, 0x44, 0x74, 0xae, 0x8f, 0x9e, 0x83, 0x3c, 0x71, 0x7c,
-	0x3d, 0xc6, 0xc0, 0xee, 0x5d, 0x0b, 0x76, 0x28, 0xa4, 0xf5, 0xa9, 0x62, 0xf1, 0xab, 0x04, 0x28,
-	0xd4, 0xd3, 0x3d, 0xd7, 0xee, 0x39, 0x9f, 0x74, 0xa8, 0xe8, 0x01, 0x80, 0x41, 0xa3, 0x6c, 0xbf,
-	0x21, 0x7d, 0x6f, 0x3d, 0xae, 0xc5, 0x36, 0xe4, 0xf2, 0xf2, 0x78, 0x54, 0x90, 0x59, 0xec, 0x2f,
-	0x48, 0xdf, 0xd3, 0x65, 0x43, 0x1c, 0x51, 0x0d, 0x12, 0xec, 0xb2, 0x9e, 0xd0, 0xa4, 0x8d, 0xec,
-	0xd6, 0xe3, 0x6b, 0xed, 0x5d, 0xcc, 0x60, 0x89, 0x5f, 0x38, 0x02, 0x75, 0x1f, 0x1b, 0x86, 0x4b,
-	0x0c, 0xea, 0x7e, 0x72, 0x0e, 0xf7, 0xb7, 0x85, 0xb4, 0x3e, 0x55, 0x44, 0x0f, 0x20, 0xf1, 0xda,
-	0xb4, 0x7c, 0x6f, 0x3d, 0xa5, 0x49, 0x1b, 0xa9, 0xf2, 0xda, 0x78, 0x54, 0x48, 0x54, 0x29, 0xe1,
-	0xc7, 0x51, 0x41, 0xa6, 0x87, 0xdd, 0x0e, 0x36, 0x3c, 0x9d, 0x0b, 0x15, 0xf7, 0x20, 0xc1, 0x7c,
-	0x40, 0x77, 0x01, 0xf6, 0xf4, 0xc6, 0xd1, 0x61, 0xbb, 0xde, 0xa8, 0x57, 0x94, 0x88, 0xba, 0x3c,
-	0x18, 0x6a, 0x3c, 0xe2, 0xba, 0x6d, 0x11, 0x74, 0x1b, 0x96, 0x38, 0xbb, 0xfc, 0x4a, 0x89, 0xaa,
-	0xe9, 0xc1, 0x50, 0x4b, 0x31, 0x66, 0xb9, 0xaf, 0xc6, 0xdf, 0x7d, 0x9e, 0x8f, 0x14, 0xbf, 0x90,
-	0x60, 0x8a, 0x8e, 0xee, 0x80, 0x5c, 0xad, 0xd5, 0x5b, 0x02, 0x2c, 0x33, 0x18, 0x6a, 0x4b, 0x94,
-	0xcb, 0xb0, 0x7e, 0x07, 0xd9, 0x80, 0xd9, 0x3e, 0x6c, 0xd4, 0xea, 0xad, 0xa6, 0x22, 0xa9, 0xca,
-	0x60, 0xa8, 0x65, 0xb8, 0xc4, 0xa1, 0x4d, 0x3d, 0x0b, 0x4b, 0x35, 0x2b, 0x7a, 0xad, 0xd2, 0x54,
-	0xa2, 0x61, 0xa9, 0x26, 0x71, 0x4d, 0xe2, 0xa1, 0x4d, 0xc8, 0x31, 0xa9, 0xe6, 0x4e, 0xb5, 0x72,
-	0xb0, 0xdd, 0xde, 0xde, 0xdf, 0x6f, 0xb7, 0x6a, 0x07, 0x15, 0x25, 0xae, 0xfe, 0x6a, 0x30, 0xd4,
-	0x56, 0xa9, 0x6c, 0xf3, 0xe4, 0x35, 0xe9, 0xe2, 0xed, 0x4e, 0x87, 0x96, 0x4e, 0xe0, 0xed, 0xd7,
-	0x12, 0xc8, 0x93, 0xec, 0xa1, 0x2a, 0xc4, 0xfd, 0xbe, 0xc3, 0x0b, 0x38, 0xbb, 0xf5, 0x64, 0xbe,
-	0x9c, 0x4f, 0x4f, 0xad, 0xbe, 0x43, 0x74, 0x86, 0x50, 0x3c, 0x87, 0xe5, 0x19, 0x32, 0x2a, 0x40,
-	0x3c, 0xc8, 0x01, 0xf3, 0x67, 0x86, 0xc9, 0x92, 0x71, 0x17, 0x62, 0xcd, 0xa3, 0x03, 0x45, 0x52,
-	0x73, 0x83, 0xa1, 0xa6, 0xcc, 0xf0, 0x9b, 0xbd, 0x2e, 0xfa, 0x2d, 0x24, 0x76, 0x1a, 0x47, 0xf5,
-	0x96, 0x12, 0x55, 0xd7, 0x06, 0x43, 0x0d, 0xcd, 0x08, 0xec, 0xd8, 0x3d, 0xcb, 0x0f, 0x22, 0x7a,
-	0x08, 0xb1, 0x16, 0x36, 0x90, 0x02, 0xb1, 0x37, 0xa4, 0xcf, 0x22, 0xc9, 0xe8, 0xf4, 0x88, 0x72,
-	0x90, 0x78, 0x8b, 0x3b, 0x3d, 0xde, 0x5d, 0x19, 0x9d, 0x5f, 0x8a, 0xff, 0xcd, 0x42, 0x86, 0x56,
-	0xa3, 0x4e, 0x3c, 0xc7, 0xb6, 0x3c, 0x82, 0x0e, 0x20, 0x79, 0xe6, 0xe2, 0x2e, 0xf1, 0xd6, 0x25,
-	0x2d, 0xb6, 0x91, 0xde, 0xda, 0xbc, 0xb1, 0x90, 0x85, 0x6a, 0x69, 0x97, 0xea, 0x05, 0x9d, 0x18,
-	0x80, 0xa8, 0xef, 0x92, 0x90, 0x60, 0x74, 0xb4, 0x2f, 0x1a, 0x24, 0xc5, 0x2a, 0xfa, 0xc9, 0xfc,
-	0xb8, 0xac, 0xc0, 0x18, 0x48, 0x35, 0x22, 0x7a, 0xa4, 0x01, 0x49, 0x8f, 0x7d, 0xf9, 0x60, 0xda,
-	0xfc, 0x69, 0x7e, 0x38, 0x5e, 0x31, 0x02, 0x2f, 0x80, 0x41, 0x0e, 0x64, 0xce, 0x3a, 0x36, 0xf6,
-	0xdb, 0x0e, 0x2b, 0xbb, 0x60, 0x06, 0x3d, 0x5b, 0x20, 0x7a, 0xaa, 0xcd, 0x6b, 0x96, 0x27, 0x62,
-	0x65, 0x3c, 0x2a, 0xa4, 0x43, 0xd4, 0x6a, 0x44, 0x4f, 0x9f, 0x4d, 0xaf, 0xe8, 0x1c, 0xb2, 0xa6,
-	0xe5, 0x13, 0x83, 0xb8, 0xc2, 0x26, 0x1f, 0x55, 0x7f, 0x9d, 0xdf, 0x66, 0x8d, 0xeb, 0x87, 0xad,
-	0xae, 0x8e, 0x47, 0x85, 0xe5, 0x19, 0x7a, 0x35, 0xa2, 0x2f, 0x9b, 0x61, 0x02, 0xfa, 0x17, 0xac,
-	0xf4, 0x2c, 0xcf, 0x34, 0x2c, 0x72, 0x2a, 0x4c, 0xc7, 0x99, 0xe9, 0xbf, 0xcd, 0x6f, 0xfa, 0x28,
-	0x00, 0x08, 0xdb, 0x46, 0xe3, 0x51, 0x21, 0x3b, 0xcb, 0xa8, 0x46, 0xf4, 0x6c, 0x6f, 0x86, 0x42,
-	0xe3, 0x3e, 0xb6, 0xed, 0x0e, 0xc1, 0x96, 0x30, 0x9e, 0x58, 0x34, 0xee, 0x32, 0xd7, 0xbf, 0x14,
-	0xf7, 0x0c, 0x9d, 0xc6, 0x7d, 0x1c, 0x26, 0x20, 0x1f, 0x96, 0x3d, 0xdf, 0x35, 0x2d, 0x43, 0x18,
-	0xe6, 0xc3, 0xf5, 0x2f, 0x0b, 0xd4, 0x0e, 0x53, 0x0f, 0xdb, 0x55, 0xc6, 0xa3, 0x42, 0x26, 0x4c,
-	0xae, 0x46, 0xf4, 0x8c, 0x17, 0xba, 0x97, 0x93, 0x10, 0xa7, 0xc8, 0xea, 0x39, 0xc0, 0xb4, 0x92,
-	0xd1, 0x3d, 0x58, 0xf2, 0xb1, 0xc1, 0x77, 0x0b, 0xed, 0xb4, 0x4c, 0x39, 0x3d, 0x1e, 0x15, 0x52,
-	0x2d, 0x6c, 0xb0, 0xcd, 0x92, 0xf2, 0xf9, 0x01, 0x95, 0x01, 0x39, 0xd8, 0xf5, 0x4d, 0xdf, 0xb4,
-	0x2d, 0x2a, 0xdd, 0x7e, 0x8b, 0x3b, 0xb4, 0x3a, 0xa9, 0x46, 0x6e, 0x3c, 0x2a, 0x28, 0x87, 0x82,
-	0xfb, 0x82, 0xf4, 0x5f, 0xe2, 0x8e, 0xa7, 0x2b, 0xce, 0x05, 0x8a, 0xfa, 0x7f, 0x09, 0xd2, 0xa1,
-	0xaa, 0x47, 0xcf, 0x20, 0xee, 0x63, 0x43, 0x74, 0xb8, 0x76, 0xfd, 0x9e, 0xc5, 0x46, 0xd0, 0xd2,
-	0x4c, 0x07, 0x35, 0x40, 0xa6, 0x82, 0x6d, 0x36, 0x28, 0xa3, 0x6c, 0x50, 0x6e, 0xcd, 0x9f, 0xbf,
-	0xe7, 0xd8, 0xc7, 0x6c, 0x4c, 0x2e, 0x9d, 0x06, 0x27, 0xf5, 0xef, 0xa0, 0x5c, 0x6c, 0x1d, 0x94,
-	0x07, 0xf0, 0xc5, 0x7e, 0xe7, 0x6e, 0x2a, 0x7a, 0x88, 0x82, 0xd6, 0x20, 0xc9, 0xc6, 0x17, 0x4f,
-	0x84, 0xa4, 0x07, 0x37, 0x75, 0x1f, 0xd0, 0xe5, 0x96, 0x58, 0x10, 0x2d, 0x36, 0x41, 0x3b, 0x80,
-	0x5b, 0x57, 0x54, 0xf9, 0x82, 0x70, 0xf1, 0xb0, 0x73, 0x97, 0xeb, 0x76, 0x41, 0xb4, 0xa5, 0x09,
-	0xda, 0x0b, 0x58, 0xbd, 0x54, 0x8c, 0x0b, 0x82, 0xc9, 0x02, 0xac, 0xd8, 0x04, 0x99, 0x01, 0x04,
-	0xab, 0x2a, 0x19, 0x2c, 0xda, 0x88, 0x7a, 0x6b, 0x30, 0xd4, 0x56, 0x26, 0xac, 0x60, 0xd7, 0x16,
-	0x20, 0x39, 0xd9, 0xd7, 0xb3, 0x02, 0xdc, 0x97, 0x60, 0x13, 0x7d, 0x29, 0xc1, 0x92, 0xf8, 0xde,
-	0xe8, 0x37, 0x90, 0xd8, 0xdd, 0x6f, 0x6c, 0xb7, 0x94, 0x88, 0xba, 0x3a, 0x18, 0x6a, 0xcb, 0x82,
-	0xc1, 0x3e, 0x3d, 0xd2, 0x20, 0x55, 0xab, 0xb7, 0x2a, 0x7b, 0x15, 0x5d, 0x40, 0x0a, 0x7e, 0xf0,
-	0x39, 0x51, 0x11, 0x96, 0x8e, 0xea, 0xcd, 0xda, 0x5e, 0xbd, 0xf2, 0x5c, 0x89, 0xf2, 0x1d, 0x29,
-	0x44, 0xc4, 0x37, 0xa2, 0x28, 0xe5, 0x46, 0x63, 0xbf, 0xb2, 0x5d, 0x57, 0x62, 0xb3, 0x28, 0x41,
-	0xde, 0x51, 0x1e, 0x92, 0xcd, 0x96, 0x5e, 0xab, 0xef, 0x29, 0x71, 0x15, 0x0d, 0x86, 0x5a, 0x56,
-	0x08, 0xf0, 0x54, 0x06, 0x8e, 0x7f, 0x26, 0x41, 0x6e, 0x07, 0x3b, 0xf8, 0xd8, 0xec, 0x98, 0xbe,
-	0x49, 0xbc, 0xc9, 0x6e, 0x6c, 0x40, 0xfc, 0x04, 0x3b, 0xa2, 0x6f, 0xae, 0x1f, 0x1b, 0x57, 0x01,
-	0x50, 0xa2, 0x57, 0xb1, 0x7c, 0xb7, 0xaf, 0x33, 0x20, 0xf5, 0xcf, 0x20, 0x4f, 0x48, 0xe1, 0x95,
-	0x2d, 0x5f, 0xb1, 0xb2, 0xe5, 0x60, 0x65, 0x3f, 0x8b, 0x3e, 0x95, 0x8a, 0x4f, 0x21, 0x3b, 0xfb,
-	0x00, 0xa6, 0xb2, 0x9e, 0x8f, 0x5d, 0x9f, 0xe9, 0xc7, 0x74, 0x7e, 0xa1, 0x98, 0xc4, 0x3a, 0x65,
-	0xfa, 0x31, 0x9d, 0x1e, 0x8b, 0xdf, 0x4b, 0x90, 0x15, 0x43, 0x66, 0xfa, 0x7c, 0xa7, 0xad, 0x3d,
-	0xf7, 0xf3, 0xbd, 0x85, 0x0d, 0x4f, 0x3c, 0xdf, 0xfd, 0xc9, 0xf9, 0x97, 0xf6, 0xa7, 0xf2, 0xef,
-	0x28, 0x28, 0x2d, 0x6c, 0xbc, 0x64, 0x15, 0xfe, 0x49, 0x87, 0x8a, 0x7e, 0x0d, 0xa9, 0x60, 0x97,
-	0xb0, 0x3d, 0x2e, 0xeb, 0x49, 0xbe, 0x3d, 0x8a, 0x25, 0xc8, 0xf1, 0xca, 0x16, 0x59, 0x08, 0x0a,
-	0x79, 0x3a, 0x07, 0xd8, 0xea, 0x11, 0x73, 0x60, 0xeb, 0x7f, 0x71, 0x48, 0x35, 0xb9, 0x25, 0x64,
-	0x02, 0x4c, 0x7f, 0x6a, 0x51, 0xe9, 0xc6, 0x19, 0x3f, 0xf3, 0xf7, 0xab, 0xfe, 0x61, 0xee, 0x9d,
-	0xf0, 0x48, 0x42, 0x06, 0xc8, 0x93, 0x3f, 0x22, 0xf4, 0x70, 0xa1, 0x3f, 0xa7, 0xc5, 0x0c, 0xbd,
-	0x01, 0xb1, 0x60, 0xd1, 0xfd, 0x9b, 0xb6, 0x5e, 0xa8, 0x43, 0xd4, 0x3f, 0x5e, 0x2b, 0x7c, 0x55,
-	0x8a, 0x1f, 0x49, 0xc8, 0x06, 0x79, 0x52, 0x7f, 0x37, 0x44, 0x75, 0xb1, 0x4e, 0x7f, 0x9e, 0xc1,
-	0x57, 0x90, 0x09, 0x4f, 0x1d, 0xb4, 0x76, 0xa9, 0xae, 0x2b, 0x5d, 0xc7, 0xef, 0xdf, 0x00, 0x7e,
-	0xd5, 0xe0, 0x2a, 0xff, 0xfe, 0xfd, 0x77, 0xf9, 0xc8, 0xfb, 0x71, 0x5e, 0xfa, 0x30, 0xce, 0x4b,
-	0xdf, 0x8e, 0xf3, 0xd2, 0x7f, 0x3e, 0xe6, 0x23, 0x1f, 0x3e, 0xe6, 0x23, 0xdf, 0x7c, 0xcc, 0x47,
-	0xfe, 0xc1, 0x5e, 0x04, 0xf4, 0x41, 0xe0, 0x1d, 0x27, 0x99, 0xad, 0xc7, 0x3f, 0x05, 0x00, 0x00,
-	0xff, 0xff, 0x7f, 0x0e, 0xbb, 0xbb, 0xa6, 0x11, 0x00, 0x00,
+	// 1616 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xdc, 0x58, 0xcd, 0x6f, 0x23, 0x49,
+	0x15, 0x77, 0xfb, 0x33, 0xfd, 0xec, 0x78, 0x3a, 0xb5, 0x66, 0xf0, 0xf6, 0xb0, 0x76, 0xaf, 0x85,
+	0x16, 0xc3, 0xee, 0x38, 0x4b, 0x76, 0x11, 0xab, 0x01, 0x0e, 0xf1, 0xc4, 0x89, 0x4d, 0x12, 0x3b,
+	0x2a, 0x3b, 0x2b, 0x0d, 0x12, 0xb2, 0x2a, 0x71, 0xa5, 0xb7, 0x15, 0xbb, 0xdb, 0x74, 0xb7, 0x57,
+	0xb1, 0xc4, 0x85, 0x03, 0xd2, 0xca, 0x27, 0x4e, 0x1c, 0x90, 0x2c, 0x21, 0x71, 0xe4, 0xce, 0x99,
+	0xe3, 0x1c, 0x90, 0xd8, 0x23, 0x27, 0x0b, 0x3c, 0x12, 0x7f, 0x04, 0x27, 0x54, 0x55, 0x5d, 0x76,
+	0x3b, 0xc9, 0x26, 0x36, 0x27, 0x34, 0xb7, 0xaa, 0xf7, 0xf1, 0x7b, 0xf5, 0x5e, 0xff, 0xea, 0xbd,
+	0xb2, 0x21, 0xe7, 0xf9, 0x8e, 0x4b, 0x4c, 0xda, 0xbd, 0x74, 0x06, 0x03, 0xc7, 0xae, 0x0c, 0x5d,
+	0xc7, 0x77, 0xd0, 0x33, 0xcb, 0xbe, 0xea, 0x8f, 0x6e, 0x7a, 0xc4, 0x27, 0x95, 0x61, 0x9f, 0xf8,
+	0x57, 0x8e, 0x3b, 0xa8, 0x04, 0x96, 0x7a, 0xce, 0x74, 0x4c, 0x87, 0xdb, 0xed, 0xb2, 0x95, 0x70,
+	0xd1, 0x9f, 0x99, 0x8e, 0x63, 0xf6, 0xe9, 0x2e, 0xdf, 0x5d, 0x8c, 0xae, 0x76, 0xe9, 0x60, 0xe8,
+	0x8f, 0x03, 0xe5, 0xbb, 0xb7, 0x95, 0xc4, 0x96, 0xaa, 0x27, 0x43, 0x97, 0xf6, 0xac, 0x4b, 0xe2,
+	0x53, 0x21, 0x28, 0xfd, 0x36, 0x0e, 0x3b, 0x98, 0x92, 0xde, 0xa1, 0xd5, 0xf7, 0xa9, 0x8b, 0xe9,
+	0xaf, 0x46, 0xd4, 0xf3, 0x51, 0x0d, 0xd2, 0x2e, 0x25, 0xbd, 0xae, 0xe7, 0x8c, 0xdc, 0x4b, 0x9a,
+	0x57, 0x0c, 0xa5, 0x9c, 0xde, 0xcb, 0x55, 0x04, 0x6e, 0x45, 0xe2, 0x56, 0xf6, 0xed, 0x71, 0x35,
+	0x3b, 0x9f, 0x15, 0x81, 0x21, 0xb4, 0xb9, 0x2d, 0x06, 0x77, 0xb1, 0x46, 0x47, 0x90, 0x70, 0x89,
+	0x6d, 0xd2, 0x7c, 0x94, 0x03, 0x7c, 0x58, 0x79, 0x20, 0xd1, 0x4a, 0xc7, 0x1a, 0x50, 0xcf, 0x27,
+	0x83, 0x21, 0x66, 0x2e, 0xd5, 0xf8, 0xeb, 0x59, 0x31, 0x82, 0x85, 0x3f, 0x3a, 0x00, 0x75, 0x71,
+	0xf0, 0x7c, 0x8c, 0x83, 0x7d, 0xf0, 0x20, 0xd8, 0x99, 0xb4, 0xc6, 0x4b, 0x47, 0xf4, 0x4b, 0xc8,
+	0x78, 0xd4, 0xb5, 0xa8, 0xd7, 0x75, 0xdc, 0x1e, 0x75, 0xf3, 0x71, 0x43, 0x29, 0x67, 0xf7, 0x5e,
+	0x3c, 0x08, 0x74, 0xa7, 0x36, 0x95, 0x36, 0x87, 0x68, 0x31, 0x04, 0x9c, 0xf6, 0x96, 0x1b, 0xf4,
+	0xfe, 0x02, 0xbe, 0x6f, 0x0d, 0x2c, 0x3f, 0x9f, 0x30, 0x94, 0x72, 0x4c, 0x9a, 0x9c, 0x30, 0x11,
+	0x7a, 0x0e, 0xe8, 0xd2, 0xb1, 0x7d, 0xcb, 0x1e, 0x11, 0xdf, 0x72, 0xec, 0xae, 0xef, 0x5c, 0x53,
+	0x3b, 0x9f, 0x34, 0x94, 0x72, 0x06, 0xef, 0x84, 0x35, 0x1d, 0xa6, 0x28, 0x59, 0x90, 0x0e, 0x45,
+	0x43, 0x3f, 0x80, 0x9d, 0x76, 0x0d, 0x37, 0x6a, 0xed, 0x6e, 0x0b, 0x1f, 0xd4, 0x70, 0xb7, 0xd9,
+	0x6a, 0xd6, 0xb4, 0x88, 0xfe, 0xce, 0x64, 0x6a, 0x3c, 0x09, 0xd9, 0x35, 0x1d, 0x9b, 0xa2, 0x32,
+	0x68, 0x2b, 0xb6, 0xc7, 0xb5, 0x57, 0x9a, 0xa2, 0xa3, 0xc9, 0xd4, 0xc8, 0x86, 0x4c, 0x8f, 0xe9,
+	0x58, 0x8f, 0x7f, 0xf5, 0xa7, 0x42, 0xa4, 0xf4, 0xb7, 0x04, 0x68, 0x2c, 0xd7, 0x23, 0xd7, 0x19,
+	0x0d, 0xdf, 0x6e, 0x1a, 0x7c, 0x04, 0x60, 0xb2, 0x2c, 0xbb, 0xd7, 0x74, 0xec, 0xe5, 0xe3, 0x46,
+	0xac, 0xac, 0x56, 0xb7, 0xe7, 0xb3, 0xa2, 0xca, 0x73, 0x3f, 0xa6, 0x63, 0x0f, 0xab, 0xa6, 0x5c,
+	0xa2, 0x06, 0x24, 0xf8, 0x86, 0x7f, 0xce, 0xec, 0xde, 0x27, 0x8f, 0xb2, 0x25, 0x5c, 0xc1, 0x8a,
+	0xd8, 0x08, 0x04, 0x76, 0x7c, 0x62, 0x9a, 0x2e, 0x35, 0xd9, 0xf1, 0x93, 0x6b, 0x1c, 0x7f, 0x5f,
+	0x5a, 0xe3, 0xa5, 0x23, 0xfa, 0x08, 0x12, 0x5f, 0x58, 0xb6, 0xef, 0xe5, 0x53, 0x86, 0x52, 0x4e,
+	0x55, 0x9f, 0xce, 0x67, 0xc5, 0x44, 0x9d, 0x09, 0xfe, 0x33, 0x2b, 0xaa, 0x6c, 0x71, 0xd8, 0x27,
+	0xa6, 0x87, 0x85, 0x51, 0xe9, 0x08, 0x12, 0xfc, 0x0c, 0xe8, 0x3d, 0x80, 0x23, 0xdc, 0x3a, 0x3f,
+	0x93, 0xac, 0xd9, 0x9e, 0x4c, 0x0d, 0x91, 0x31, 0xe7, 0xcb, 0xbb, 0xb0, 0x25, 0xd4, 0xd5, 0x57,
+	0x5a, 0x54, 0x4f, 0x4f, 0xa6, 0x46, 0x8a, 0x2b, 0xab, 0x92, 0x20, 0x7f, 0x56, 0x60, 0x89, 0x8e,
+	0x9e, 0x81, 0x5a, 0x6f, 0x34, 0x3b, 0x12, 0x2c, 0x33, 0x99, 0x1a, 0x5b, 0x4c, 0xcb, 0xb1, 0xbe,
+	0x0b, 0xd9, 0x40, 0xd9, 0x3d, 0x6b, 0x35, 0x9a, 0x9d, 0xb6, 0xa6, 0xe8, 0xda, 0x64, 0x6a, 0x64,
+	0x84, 0xc5, 0x99, 0xc3, 0x4e, 0x16, 0xb6, 0x12, 0x4c, 0xd5, 0xa2, 0x61, 0x2b, 0xc1, 0x52, 0xb4,
+	0x0b, 0x39, 0x6e, 0xd5, 0x7e, 0x59, 0xaf, 0x9d, 0xee, 0x77, 0xf7, 0x4f, 0x4e, 0xba, 0x9d, 0xc6,
+	0x69, 0x4d, 0x8b, 0xeb, 0xdf, 0x9a, 0x4c, 0x8d, 0x1d, 0x66, 0xdb, 0xbe, 0xfc, 0x82, 0x0e, 0xc8,
+	0x7e, 0xbf, 0xcf, 0xa8, 0x13, 0x9c, 0xf6, 0xef, 0x0a, 0xa8, 0x8b, 0xea, 0xa1, 0x3a, 0xc4, 0xfd,
+	0xf1, 0x50, 0x10, 0x38, 0xbb, 0xf7, 0xe9, 0x7a, 0x35, 0x5f, 0xae, 0x3a, 0xe3, 0x21, 0xc5, 0x1c,
+	0xa1, 0x74, 0x03, 0xdb, 0x2b, 0x62, 0x54, 0x84, 0x78, 0x50, 0x03, 0x7e, 0x9e, 0x15, 0x25, 0x2f,
+	0xc6, 0x7b, 0x10, 0x6b, 0x9f, 0x9f, 0x6a, 0x8a, 0x9e, 0x9b, 0x4c, 0x0d, 0x6d, 0x45, 0xdf, 0x1e,
+	0x0d, 0xd0, 0xfb, 0x90, 0x78, 0xd9, 0x3a, 0x6f, 0x76, 0xb4, 0xa8, 0xfe, 0x74, 0x32, 0x35, 0xd0,
+	0x8a, 0xc1, 0x4b, 0x67, 0x64, 0xfb, 0x41, 0x46, 0xcf, 0x21, 0xd6, 0x21, 0x26, 0xd2, 0x20, 0x76,
+	0x4d, 0xc7, 0x3c, 0x93, 0x0c, 0x66, 0x4b, 0x94, 0x83, 0xc4, 0x97, 0xa4, 0x3f, 0x12, 0xb7, 0x2b,
+	0x83, 0xc5, 0xa6, 0xf4, 0xd7, 0x2c, 0x64, 0x18, 0x1b, 0x31, 0xf5, 0x86, 0x8e, 0xed, 0x51, 0x74,
+	0x0a, 0xc9, 0x2b, 0x97, 0x0c, 0xa8, 0x97, 0x57, 0x8c, 0x58, 0x39, 0xbd, 0xb7, 0xfb, 0x28, 0x91,
+	0xa5, 0x6b, 0xe5, 0x90, 0xf9, 0x05, 0x37, 0x31, 0x00, 0xf9, 0x86, 0x4e, 0x16, 0xfd, 0x86, 0x4e,
+	0xa6, 0x7f, 0x95, 0x84, 0x04, 0x87, 0x41, 0x27, 0xf2, 0x3e, 0xa5, 0xf8, 0x05, 0xf8, 0x74, 0xfd,
+	0x63, 0x70, 0x3e, 0x72, 0x90, 0x7a, 0x44, 0x5e, 0xa9, 0x16, 0x24, 0x45, 0x7f, 0x0d, 0x9a, 0xd3,
+	0x8f, 0xd6, 0x87, 0x13, 0x04, 0x93, 0x78, 0x01, 0x0c, 0x1a, 0x42, 0xe6, 0xaa, 0xef, 0x10, 0xbf,
+	0x3b, 0xe4, 0x2c, 0x0d, 0x5a, 0xd6, 0x8b, 0x0d, 0x8a, 0xc5, 0xbc, 0x05, 0xc5, 0x45, 0xdd, 0x9e,
+	0xcc, 0x67, 0xc5, 0x74, 0x48, 0x5a, 0x8f, 0xe0, 0xf4, 0xd5, 0x72, 0x8b, 0x6e, 0x20, 0x6b, 0xd9,
+	0x3e, 0x35, 0xa9, 0x2b, 0x63, 0x8a, 0xce, 0xf6, 0xd3, 0xf5, 0x63, 0x36, 0x84, 0x7f, 0x38, 0xea,
+	0xce, 0x7c, 0x56, 0xdc, 0x5e, 0x91, 0xd7, 0x23, 0x78, 0xdb, 0x0a, 0x0b, 0xd0, 0xaf, 0xe1, 0xc9,
+	0xc8, 0xf6, 0x2c, 0xd3, 0xa6, 0x3d, 0x19, 0x3a, 0xce, 0x43, 0xff, 0x6c, 0xfd, 0xd0, 0xe7, 0x01,
+	0x40, 0x38, 0x36, 0x9a, 0xcf, 0x8a, 0xd9, 0x55, 0x45, 0x3d, 0x82, 0xb3, 0xa3, 0x15, 0x09, 0xcb,
+	0xfb, 0xc2, 0x71, 0xfa, 0x94, 0xd8, 0x32, 0x78, 0x62, 0xd3, 0xbc, 0xab, 0xc2, 0xff, 0x4e, 0xde,
+	0x2b, 0x72, 0x96, 0xf7, 0x45, 0x58, 0x80, 0x7c, 0xd8, 0xf6, 0x7c, 0xd7, 0xb2, 0x4d, 0x19, 0x58,
+	0xf4, 0xe2, 0x9f, 0x6c, 0xc0, 0x1d, 0xee, 0x1e, 0x8e, 0xab, 0xcd, 0x67, 0xc5, 0x4c, 0x58, 0x5c,
+	0x8f, 0xe0, 0x8c, 0x17, 0xda, 0x57, 0x93, 0x10, 0x67, 0xc8, 0xfa, 0x0d, 0xc0, 0x92, 0xc9, 0xe8,
+	0x03, 0xd8, 0xf2, 0x89, 0x29, 0x46, 0x11, 0xbb, 0x98, 0x99, 0x6a, 0x7a, 0x3e, 0x2b, 0xa6, 0x3a,
+	0xc4, 0xe4, 0x83, 0x28, 0xe5, 0x8b, 0x05, 0xaa, 0x02, 0x1a, 0x12, 0xd7, 0xb7, 0xf8, 0x65, 0xbb,
+	0xa6, 0xe3, 0xee, 0x97, 0xa4, 0xcf, 0xd8, 0xc9, 0x3c, 0x72, 0xf3, 0x59, 0x51, 0x3b, 0x93, 0xda,
+	0x63, 0x3a, 0xfe, 0x9c, 0xf4, 0x3d, 0xac, 0x0d, 0x6f, 0x49, 0xf4, 0x3f, 0x28, 0xf2, 0x3d, 0x21,
+	0x62, 0xbf, 0x80, 0xb8, 0x4f, 0x4c, 0xd9, 0x10, 0x8c, 0x87, 0xc7, 0x32, 0x31, 0x83, 0x0e, 0xc0,
+	0x7d, 0x50, 0x0b, 0x54, 0x66, 0xd8, 0xe5, 0x7d, 0x35, 0xca, 0xfb, 0xea, 0xde, 0xfa, 0xf5, 0x3b,
+	0x20, 0x3e, 0xe1, 0x5d, 0x75, 0xab, 0x17, 0xac, 0xf4, 0x9f, 0x83, 0x76, 0xfb, 0xea, 0xa0, 0x02,
+	0x80, 0x2f, 0x9f, 0x03, 0xe2, 0x98, 0x1a, 0x0e, 0x49, 0xd0, 0x53, 0x48, 0xf2, 0x6e, 0x27, 0x0a,
+	0xa1, 0xe0, 0x60, 0xa7, 0x9f, 0x00, 0xba, 0x7b, 0x25, 0x36, 0x44, 0x8b, 0x2d, 0xd0, 0x4e, 0xe1,
+	0x9d, 0x7b, 0x58, 0xbe, 0x21, 0x5c, 0x3c, 0x7c, 0xb8, 0xbb, 0xbc, 0xdd, 0x10, 0x6d, 0x6b, 0x81,
+	0x76, 0x0c, 0x3b, 0x77, 0xc8, 0xb8, 0x21, 0x98, 0x2a, 0xc1, 0x4a, 0x6d, 0x50, 0x39, 0x40, 0x30,
+	0xd9, 0x92, 0xc1, 0x5c, 0x0e, 0x9e, 0x98, 0x0b, 0x55, 0x30, 0x9a, 0x8b, 0x90, 0x5c, 0x8c, 0xf7,
+	0x55, 0x03, 0x71, 0x96, 0x60, 0x70, 0xfd, 0x45, 0x81, 0x2d, 0xf9, 0xbd, 0xd1, 0x77, 0x20, 0x71,
+	0x78, 0xd2, 0xda, 0xef, 0x68, 0x11, 0x7d, 0x67, 0x32, 0x35, 0xb6, 0xa5, 0x82, 0x7f, 0x7a, 0x64,
+	0x40, 0xaa, 0xd1, 0xec, 0xd4, 0x8e, 0x6a, 0x58, 0x42, 0x4a, 0x7d, 0xf0, 0x39, 0x51, 0x09, 0xb6,
+	0xce, 0x9b, 0xed, 0xc6, 0x51, 0xb3, 0x76, 0xa0, 0x45, 0xc5, 0x48, 0x95, 0x26, 0xf2, 0x1b, 0x31,
+	0x94, 0x6a, 0xab, 0x75, 0x52, 0xdb, 0x6f, 0x6a, 0xb1, 0x55, 0x94, 0xa0, 0xee, 0xa8, 0x00, 0xc9,
+	0x76, 0x07, 0x37, 0x9a, 0x47, 0x5a, 0x5c, 0x3c, 0x89, 0xa5, 0x81, 0x28, 0x65, 0x70, 0xf0, 0x3f,
+	0x2a, 0x90, 0x7b, 0x49, 0x86, 0xe4, 0xc2, 0xea, 0x5b, 0xbe, 0x45, 0xbd, 0xc5, 0x28, 0x6d, 0x41,
+	0xfc, 0x92, 0x0c, 0xe5, 0xbd, 0x79, 0xb8, 0x6d, 0xdc, 0x07, 0xc0, 0x84, 0x5e, 0xcd, 0xf6, 0xdd,
+	0x31, 0xe6, 0x40, 0xfa, 0x8f, 0x41, 0x5d, 0x88, 0xc2, 0x13, 0x5e, 0xbd, 0x67, 0xc2, 0xab, 0xc1,
+	0x84, 0x7f, 0x11, 0xfd, 0x4c, 0x29, 0x7d, 0x06, 0xd9, 0xd5, 0xf7, 0x32, 0xb3, 0xf5, 0x7c, 0xe2,
+	0xfa, 0xdc, 0x3f, 0x86, 0xc5, 0x86, 0x61, 0x52, 0xbb, 0xc7, 0xfd, 0x63, 0x98, 0x2d, 0x4b, 0xff,
+	0x56, 0x20, 0x2b, 0x9b, 0xcc, 0xf2, 0xb5, 0xcf, 0xae, 0xf6, 0xda, 0xaf, 0xfd, 0x0e, 0x31, 0x3d,
+	0xf9, 0xda, 0xf7, 0x17, 0xeb, 0xff, 0xb3, 0xd7, 0x7e, 0xe9, 0x37, 0x51, 0xd0, 0x3a, 0xc4, 0xfc,
+	0x9c, 0x33, 0xfc, 0xad, 0x4e, 0x15, 0x7d, 0x1b, 0x52, 0xc1, 0x2c, 0xe1, 0x73, 0x5c, 0xc5, 0x49,
+	0x31, 0x3d, 0x4a, 0x15, 0xc8, 0x09, 0x66, 0xcb, 0x2a, 0x04, 0x44, 0x5e, 0xf6, 0x01, 0x3e, 0x7a,
+	0x64, 0x1f, 0xd8, 0xfb, 0x7d, 0x1c, 0x52, 0x6d, 0x11, 0x09, 0x59, 0x00, 0xcb, 0xdf, 0xc0, 0xa8,
+	0xb2, 0xd9, 0x8f, 0x65, 0xfd, 0xfb, 0x6b, 0xcf, 0x84, 0x8f, 0x15, 0x64, 0x82, 0xba, 0xf8, 0x01,
+	0x85, 0x9e, 0x6f, 0xf4, 0x43, 0x6b, 0xb3, 0x40, 0xd7, 0x20, 0x07, 0x2c, 0xfa, 0xf0, 0xb1, 0xa9,
+	0x17, 0xba, 0x21, 0xfa, 0x0f, 0x1f, 0x34, 0xbe, 0xaf, 0xc4, 0x1f, 0x2b, 0xc8, 0x01, 0x75, 0xc1,
+	0xbf, 0x47, 0xb2, 0xba, 0xcd, 0xd3, 0xff, 0x2d, 0xe0, 0x2b, 0xc8, 0x84, 0xbb, 0x0e, 0x7a, 0x7a,
+	0x87, 0xd7, 0x35, 0xf6, 0x67, 0xd1, 0x23, 0xe0, 0xf7, 0x35, 0xae, 0xea, 0xf7, 0x5e, 0xff, 0xab,
+	0x10, 0x79, 0x3d, 0x2f, 0x28, 0x5f, 0xcf, 0x0b, 0xca, 0x3f, 0xe7, 0x05, 0xe5, 0x77, 0x6f, 0x0a,
+	0x91, 0xaf, 0xdf, 0x14, 0x22, 0xff, 0x78, 0x53, 0x88, 0xfc, 0x82, 0xbf, 0x08, 0xd8, 0x83, 0xc0,
+	0xbb, 0x48, 0xf2, 0x58, 0x9f, 0xfc, 0x77, 0x00, 0xed, 0x72, 0x33, 0x22, 0xf1, 0x12, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1638,6 +1686,22 @@ func (m *ReadFilterRequest) MarshalTo(dAtA []byte) (int, error) {
 		}
 		i += n3
 	}
+	if m.SeriesOrder != 0 {
+		dAtA[i] = 0x20
+		i++
+		i = encodeVarintStorageCommon(dAtA, i, uint64(m.SeriesOrder))
+	}
+	if m.SeriesLimit != 0 {
+		dAtA[i] = 0x28
+		i++
+		i = encodeVarintStorageCommon(dAtA, i, uint64(m.SeriesLimit))
+	}
+	if len(m.ContinuationToken) > 0 {
+		dAtA[i] = 0x32
+		i++
+		i = encodeVarintStorageCommon(dAtA, i, uint64(len(m.ContinuationToken)))
+		i += copy(dAtA[i:], m.ContinuationToken)
+	}
 	return i, nil
 }
 
@@ -1803,6 +1867,12 @@ func (m *ReadResponse) MarshalTo(dAtA []byte) (int, error) {
 			i += n
 		}
 	}
+	if len(m.ContinuationToken) > 0 {
+		dAtA[i] = 0x12
+		i++
+		i = encodeVarintStorageCommon(dAtA, i, uint64(len(m.ContinuationToken)))
+		i += copy(dAtA[i:], m.ContinuationToken)
+	}
 	return i, nil
 }
 
@@ -2418,6 +2488,16 @@ func (m *ReadFilterRequest) Size() (n int) {
 		l = m.Predicate.Size()
 		n += 1 + l + sovStorageCommon(uint64(l))
 	}
+	if m.SeriesOrder != 0 {
+		n += 1 + sovStorageCommon(uint64(m.SeriesOrder))
+	}
+	if m.SeriesLimit != 0 {
+		n += 1 + sovStorageCommon(uint64(m.SeriesLimit))
+	}
+	l = len(m.ContinuationToken)
+	if l > 0 {
+		n += 1 + l + sovStorageCommon(uint64(l))
+	}
 	return n
 }
 
@@ -2497,6 +2577,10 @@ func (m *ReadResponse) Size() (n int) {
 			n += 1 + l + sovStorageCommon(uint64(l))
 		}
 	}
+	l = len(m.ContinuationToken)
+	if l > 0 {
+		n += 1 + l + sovStorageCommon(uint64(l))
+	}
 	return n
 }
 
@@ -2957,6 +3041,78 @@ func (m *ReadFilterRequest) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SeriesOrder", wireType)
+			}
+			m.SeriesOrder = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStorageCommon
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SeriesOrder |= ReadFilterRequest_SeriesOrder(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SeriesLimit", wireType)
+			}
+			m.SeriesLimit = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStorageCommon
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SeriesLimit |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ContinuationToken", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStorageCommon
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthStorageCommon
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthStorageCommon
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ContinuationToken = append(m.ContinuationToken[:0], dAtA[iNdEx:postIndex]...)
+			if m.ContinuationToken == nil {
+				m.ContinuationToken = []byte{}
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipStorageCommon(dAtA[iNdEx:])
@@ -3492,6 +3648,40 @@ func (m *ReadResponse) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ContinuationToken", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowStorageCommon
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthStorageCommon
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthStorageCommon
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ContinuationToken = append(m.ContinuationToken[:0], dAtA[iNdEx:postIndex]...)
+			if m.ContinuationToken == nil {
+				m.ContinuationToken = []byte{}
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipStorageCommon(dAtA[iNdEx:])
@@ -80,6 +80,14 @@ func (w *ResponseWriter) WriteResultSet(rs ResultSet) error {
 		}
 	}
 
+	// The token is sent with the last frames of the result set.
+	if rs, ok := rs.(interface{ ContinuationToken() []byte }); ok {
+		if token := rs.ContinuationToken(); len(token) > 0 {
+			w.res.ContinuationToken = token
+			w.sz += len(token)
+		}
+	}
+
 	stats := rs.Stats()
 	w.stream.SetTrailer(metadata.Pairs(
 		"scanned-bytes", fmt.Sprint(stats.ScannedBytes),
@@ -284,4 +292,5 @@ func (w *ResponseWriter) Flush() {
 		}
 	}
 	w.res.Frames = w.res.Frames[:0]
+	w.res.ContinuationToken = nil
 }
@@ -69,6 +69,15 @@ func (r *resultSet) Tags() models.Tags {
 	return r.row.Tags
 }
 
+// ContinuationToken returns the token resuming the read after the last
+// series, if the series cursor returns a page of series and more remain.
+func (r *resultSet) ContinuationToken() []byte {
+	if cur, ok := r.cur.(ContinuationTokenSeriesCursor); ok {
+		return cur.ContinuationToken()
+	}
+	return nil
+}
+
 // Stats returns the stats for the underlying cursors.
 // Available after resultset has been scanned.
 func (r *resultSet) Stats() cursors.CursorStats { return r.row.Query.Stats() }
@@ -0,0 +1,219 @@
+package reads
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/storage/reads/datatypes"
+	"github.com/influxdata/influxdb/tsdb/cursors"
+)
+
+// RetryPolicy bounds the retries of the reads failing with a transient error.
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of times a read is retried. Reads are
+	// not retried if it is zero.
+	MaxRetries int
+
+	// Delay is the delay before the first retry, doubled before every next one.
+	Delay time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy of the storage reads of influxd.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxRetries: 3, Delay: 10 * time.Millisecond}
+}
+
+// IsTransient returns true if err may not happen again when retrying the
+// read that failed with it, such as a TSM file removed by a compaction while
+// it was being read, an interrupted system call or an unavailable remote store.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, syscall.EINTR) || errors.Is(err, syscall.EAGAIN) || os.IsNotExist(err) {
+		return true
+	}
+	if influxdb.ErrorCode(err) == influxdb.EUnavailable {
+		return true
+	}
+	var t interface{ Temporary() bool }
+	return errors.As(err, &t) && t.Temporary()
+}
+
+// wait waits before the retry n, counted from zero. It returns false if
+// no retry is left or ctx is done first.
+func (p RetryPolicy) wait(ctx context.Context, n int) bool {
+	if n >= p.MaxRetries {
+		return false
+	}
+	timer := time.NewTimer(p.Delay << uint(n))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+type retryStore struct {
+	s      Store
+	policy RetryPolicy
+}
+
+// NewRetryStore returns a Store retrying the reads of s failing with a
+// transient error, according to policy.
+//
+// A read filter failing after some series were returned is resumed, skipping
+// the series already returned. Other reads are only retried if they fail
+// before returning anything.
+func NewRetryStore(s Store, policy RetryPolicy) Store {
+	return &retryStore{s: s, policy: policy}
+}
+
+func (s *retryStore) ReadFilter(ctx context.Context, req *datatypes.ReadFilterRequest) (ResultSet, error) {
+	var n int
+	rs, err := s.readFilter(ctx, req, &n)
+	if rs == nil || err != nil {
+		return nil, err
+	}
+	return &retryResultSet{
+		ctx:     ctx,
+		s:       s,
+		req:     req,
+		rs:      rs,
+		retries: n,
+		seen:    make(map[string]struct{}),
+	}, nil
+}
+
+// readFilter issues the read filter request, counting its retries in n.
+func (s *retryStore) readFilter(ctx context.Context, req *datatypes.ReadFilterRequest, n *int) (ResultSet, error) {
+	for ; ; *n++ {
+		rs, err := s.s.ReadFilter(ctx, req)
+		if err == nil || !IsTransient(err) || !s.policy.wait(ctx, *n) {
+			return rs, err
+		}
+	}
+}
+
+func (s *retryStore) ReadGroup(ctx context.Context, req *datatypes.ReadGroupRequest) (GroupResultSet, error) {
+	for n := 0; ; n++ {
+		rs, err := s.s.ReadGroup(ctx, req)
+		if err == nil || !IsTransient(err) || !s.policy.wait(ctx, n) {
+			return rs, err
+		}
+	}
+}
+
+func (s *retryStore) TagKeys(ctx context.Context, req *datatypes.TagKeysRequest) (cursors.StringIterator, error) {
+	for n := 0; ; n++ {
+		it, err := s.s.TagKeys(ctx, req)
+		if err == nil || !IsTransient(err) || !s.policy.wait(ctx, n) {
+			return it, err
+		}
+	}
+}
+
+func (s *retryStore) TagValues(ctx context.Context, req *datatypes.TagValuesRequest) (cursors.StringIterator, error) {
+	for n := 0; ; n++ {
+		it, err := s.s.TagValues(ctx, req)
+		if err == nil || !IsTransient(err) || !s.policy.wait(ctx, n) {
+			return it, err
+		}
+	}
+}
+
+// TimeRange returns the time range of the data of the bucket if s knows it,
+// or else a range bounding all time.
+func (s *retryStore) TimeRange(ctx context.Context, orgID, bucketID uint64) (min, max int64, ok bool, err error) {
+	trs, ok := s.s.(TimeRangeStore)
+	if !ok {
+		return math.MinInt64, math.MaxInt64, true, nil
+	}
+	return trs.TimeRange(ctx, orgID, bucketID)
+}
+
+func (s *retryStore) GetSource(orgID, bucketID uint64) proto.Message {
+	return s.s.GetSource(orgID, bucketID)
+}
+
+// retryResultSet reissues the read filter request when its result set fails
+// with a transient error, skipping the series already returned.
+type retryResultSet struct {
+	ctx     context.Context
+	s       *retryStore
+	req     *datatypes.ReadFilterRequest
+	rs      ResultSet
+	retries int
+	err     error
+
+	// seen holds the keys of the series returned, as the series of a
+	// result set are not necessarily ordered by key.
+	seen  map[string]struct{}
+	key   []byte
+	stats cursors.CursorStats // of the result sets closed
+}
+
+func (r *retryResultSet) Next() bool {
+	for r.rs != nil {
+		for r.rs.Next() {
+			r.key = models.AppendMakeKey(r.key[:0], nil, r.rs.Tags())
+			if _, ok := r.seen[string(r.key)]; ok {
+				continue
+			}
+			r.seen[string(r.key)] = struct{}{}
+			return true
+		}
+
+		err := r.rs.Err()
+		if err == nil || !IsTransient(err) || !r.s.policy.wait(r.ctx, r.retries) {
+			r.err = err
+			return false
+		}
+		r.retries++
+
+		r.closeResultSet()
+		r.rs, r.err = r.s.readFilter(r.ctx, r.req, &r.retries)
+	}
+	return false
+}
+
+func (r *retryResultSet) Cursor() cursors.Cursor { return r.rs.Cursor() }
+func (r *retryResultSet) Tags() models.Tags      { return r.rs.Tags() }
+
+// ContinuationToken returns the token of the underlying result set, if any.
+func (r *retryResultSet) ContinuationToken() []byte {
+	if rs, ok := r.rs.(interface{ ContinuationToken() []byte }); ok {
+		return rs.ContinuationToken()
+	}
+	return nil
+}
+
+func (r *retryResultSet) closeResultSet() {
+	if r.rs == nil {
+		return
+	}
+	r.stats.Add(r.rs.Stats())
+	r.rs.Close()
+	r.rs = nil
+}
+
+func (r *retryResultSet) Close() { r.closeResultSet() }
+
+func (r *retryResultSet) Err() error { return r.err }
+
+func (r *retryResultSet) Stats() cursors.CursorStats {
+	stats := r.stats
+	if r.rs != nil {
+		stats.Add(r.rs.Stats())
+	}
+	return stats
+}
@@ -0,0 +1,171 @@
+package reads_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/storage/reads"
+	"github.com/influxdata/influxdb/storage/reads/datatypes"
+	"github.com/influxdata/influxdb/tsdb/cursors"
+)
+
+func TestIsTransient(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want bool
+	}{
+		{err: nil, want: false},
+		{err: errors.New("corrupt block"), want: false},
+		{err: &os.PathError{Op: "open", Path: "000000001-000000001.tsm", Err: syscall.ENOENT}, want: true},
+		{err: &os.PathError{Op: "mmap", Path: "000000001-000000001.tsm", Err: syscall.EINTR}, want: true},
+		{err: &influxdb.Error{Code: influxdb.EUnavailable, Msg: "remote store unavailable"}, want: true},
+		{err: &influxdb.Error{Code: influxdb.EInternal, Msg: "internal"}, want: false},
+	} {
+		if got := reads.IsTransient(tt.err); got != tt.want {
+			t.Errorf("IsTransient(%v): got %t, want %t", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetryStore_ReadFilter(t *testing.T) {
+	transient := &os.PathError{Op: "read", Path: "000000001-000000001.tsm", Err: syscall.ENOENT}
+	policy := reads.RetryPolicy{MaxRetries: 2, Delay: time.Millisecond}
+
+	t.Run("resumes after returned series", func(t *testing.T) {
+		s := &failingStore{
+			series: []string{"m0,t=a", "m0,t=b", "m0,t=c"},
+			errs:   []failure{{at: 0, err: transient}, {at: 2, err: transient}},
+		}
+		rs, err := reads.NewRetryStore(s, policy).ReadFilter(context.Background(), &datatypes.ReadFilterRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rs.Close()
+
+		got := readSeries(rs)
+		if err := rs.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"a", "b", "c"}; !cmp.Equal(got, want) {
+			t.Fatalf("unexpected series: -got/+want\n%s", cmp.Diff(got, want))
+		}
+		if s.reads != 3 {
+			t.Fatalf("unexpected number of reads: got %d, want 3", s.reads)
+		}
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		s := &failingStore{
+			series: []string{"m0,t=a", "m0,t=b"},
+			errs:   []failure{{at: 1, err: transient}, {at: 1, err: transient}, {at: 1, err: transient}},
+		}
+		rs, err := reads.NewRetryStore(s, policy).ReadFilter(context.Background(), &datatypes.ReadFilterRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rs.Close()
+
+		if got, want := readSeries(rs), []string{"a"}; !cmp.Equal(got, want) {
+			t.Fatalf("unexpected series: -got/+want\n%s", cmp.Diff(got, want))
+		}
+		if err := rs.Err(); err != transient {
+			t.Fatalf("unexpected error: got %v, want %v", err, transient)
+		}
+	})
+
+	t.Run("does not retry other errors", func(t *testing.T) {
+		fatal := errors.New("corrupt block")
+		s := &failingStore{
+			series: []string{"m0,t=a"},
+			errs:   []failure{{at: 0, err: fatal}},
+		}
+		rs, err := reads.NewRetryStore(s, policy).ReadFilter(context.Background(), &datatypes.ReadFilterRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rs.Close()
+
+		if got := readSeries(rs); len(got) != 0 {
+			t.Fatalf("unexpected series: %v", got)
+		}
+		if err := rs.Err(); err != fatal {
+			t.Fatalf("unexpected error: got %v, want %v", err, fatal)
+		}
+		if s.reads != 1 {
+			t.Fatalf("unexpected number of reads: got %d, want 1", s.reads)
+		}
+	})
+}
+
+func readSeries(rs reads.ResultSet) []string {
+	var keys []string
+	for rs.Next() {
+		keys = append(keys, rs.Tags().GetString("t"))
+	}
+	return keys
+}
+
+// failure makes the read of a failingStore fail with err after returning
+// at series.
+type failure struct {
+	at  int
+	err error
+}
+
+// failingStore returns result sets of series, the result set of the read n
+// failing as described by errs[n].
+type failingStore struct {
+	reads.Store
+	series []string
+	errs   []failure
+	reads  int
+}
+
+func (s *failingStore) ReadFilter(ctx context.Context, req *datatypes.ReadFilterRequest) (reads.ResultSet, error) {
+	rs := &sliceResultSet{series: s.series, at: -1}
+	if s.reads < len(s.errs) {
+		rs.fail = s.errs[s.reads]
+		rs.series = rs.series[:rs.fail.at]
+	}
+	s.reads++
+	return rs, nil
+}
+
+func (s *failingStore) GetSource(orgID, bucketID uint64) proto.Message { return nil }
+
+type sliceResultSet struct {
+	series []string
+	at     int
+	fail   failure
+}
+
+func (r *sliceResultSet) Next() bool {
+	r.at++
+	return r.at < len(r.series)
+}
+
+func (r *sliceResultSet) Cursor() cursors.Cursor { return nil }
+
+func (r *sliceResultSet) Tags() models.Tags {
+	_, tags := models.ParseKeyBytes([]byte(r.series[r.at]))
+	return tags
+}
+
+func (r *sliceResultSet) Close() {}
+
+func (r *sliceResultSet) Err() error {
+	if r.at >= len(r.series) {
+		return r.fail.err
+	}
+	return nil
+}
+
+func (r *sliceResultSet) Stats() cursors.CursorStats { return cursors.CursorStats{} }
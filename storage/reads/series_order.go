@@ -0,0 +1,132 @@
+package reads
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// continuationTokenVersion is the first byte of the continuation tokens,
+// followed by the key of the last series returned.
+const continuationTokenVersion = 1
+
+var (
+	ErrInvalidContinuationToken = errors.New("invalid continuation token")
+	ErrSeriesLimitWithoutOrder  = errors.New("series limit requires series key order")
+)
+
+// ContinuationTokenSeriesCursor is a SeriesCursor returning a page of series.
+type ContinuationTokenSeriesCursor interface {
+	SeriesCursor
+
+	// ContinuationToken returns the token resuming the read after the last
+	// series returned, or nil if no series remain. It is only valid once
+	// Next has returned nil.
+	ContinuationToken() []byte
+}
+
+// keyOrderedSeriesCursor returns the rows of a SeriesCursor sorted by series key.
+type keyOrderedSeriesCursor struct {
+	cur   SeriesCursor
+	after []byte
+	limit int64
+
+	init  bool
+	rows  []SeriesRow
+	keys  [][]byte
+	i, n  int
+	token []byte
+	err   error
+}
+
+// NewKeyOrderedSeriesCursor returns a SeriesCursor returning the rows of cur
+// sorted lexicographically by series key, starting after the series of the
+// continuation token, if any, and returning at most limit rows if limit is
+// greater than zero. The rows of cur are all read on the first call to Next.
+func NewKeyOrderedSeriesCursor(cur SeriesCursor, token []byte, limit int64) (ContinuationTokenSeriesCursor, error) {
+	c := &keyOrderedSeriesCursor{cur: cur, limit: limit}
+	if len(token) > 0 {
+		if token[0] != continuationTokenVersion || len(token) == 1 {
+			return nil, ErrInvalidContinuationToken
+		}
+		c.after = token[1:]
+	}
+	return c, nil
+}
+
+func (c *keyOrderedSeriesCursor) Close() { c.cur.Close() }
+
+func (c *keyOrderedSeriesCursor) Err() error {
+	if c.err != nil {
+		return c.err
+	}
+	return c.cur.Err()
+}
+
+func (c *keyOrderedSeriesCursor) Next() *SeriesRow {
+	if !c.init {
+		c.init = true
+		c.readAll()
+	}
+
+	if c.i >= len(c.rows) {
+		return nil
+	}
+	if c.limit > 0 && int64(c.n) >= c.limit {
+		// The page is complete and more series remain.
+		c.token = append([]byte{continuationTokenVersion}, c.keys[c.i-1]...)
+		c.rows = c.rows[:c.i]
+		return nil
+	}
+
+	row := &c.rows[c.i]
+	c.i++
+	c.n++
+	return row
+}
+
+func (c *keyOrderedSeriesCursor) ContinuationToken() []byte { return c.token }
+
+// readAll reads, copies and sorts the rows of the underlying cursor, and
+// skips those up to the continuation token.
+func (c *keyOrderedSeriesCursor) readAll() {
+	for row := c.cur.Next(); row != nil; row = c.cur.Next() {
+		c.rows = append(c.rows, SeriesRow{
+			SortKey:    row.SortKey,
+			Name:       append([]byte(nil), row.Name...),
+			SeriesTags: row.SeriesTags.Clone(),
+			Tags:       row.Tags.Clone(),
+			Field:      row.Field,
+			Query:      row.Query,
+			ValueCond:  row.ValueCond,
+		})
+	}
+	if c.err = c.cur.Err(); c.err != nil {
+		c.rows = nil
+		return
+	}
+
+	c.keys = make([][]byte, len(c.rows))
+	for i := range c.rows {
+		c.keys[i] = models.MakeKey(c.rows[i].Name, c.rows[i].SeriesTags)
+	}
+	sort.Sort(byKey{rows: c.rows, keys: c.keys})
+
+	if c.after != nil {
+		c.i = sort.Search(len(c.keys), func(i int) bool { return bytes.Compare(c.keys[i], c.after) > 0 })
+	}
+}
+
+type byKey struct {
+	rows []SeriesRow
+	keys [][]byte
+}
+
+func (a byKey) Len() int           { return len(a.rows) }
+func (a byKey) Less(i, j int) bool { return bytes.Compare(a.keys[i], a.keys[j]) < 0 }
+func (a byKey) Swap(i, j int) {
+	a.rows[i], a.rows[j] = a.rows[j], a.rows[i]
+	a.keys[i], a.keys[j] = a.keys[j], a.keys[i]
+}
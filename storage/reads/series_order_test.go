@@ -0,0 +1,56 @@
+package reads_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/storage/reads"
+)
+
+func TestKeyOrderedSeriesCursor(t *testing.T) {
+	rows := newSeriesRows(
+		"mem,host=b",
+		"cpu,host=b",
+		"cpu,host=a,region=west",
+		"aaa,host=c",
+		"cpu,host=a",
+	)
+
+	// Read all the series, two at a time.
+	var (
+		pages [][]string
+		token []byte
+	)
+	for {
+		cur, err := reads.NewKeyOrderedSeriesCursor(&sliceSeriesCursor{rows: rows}, token, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var page []string
+		for row := cur.Next(); row != nil; row = cur.Next() {
+			page = append(page, string(models.MakeKey(row.Name, row.SeriesTags)))
+		}
+		pages = append(pages, page)
+
+		if token = cur.ContinuationToken(); token == nil {
+			break
+		}
+	}
+
+	exp := [][]string{
+		{"aaa,host=c", "cpu,host=a"},
+		{"cpu,host=a,region=west", "cpu,host=b"},
+		{"mem,host=b"},
+	}
+	if !reflect.DeepEqual(pages, exp) {
+		t.Fatalf("unexpected pages: got %q, exp %q", pages, exp)
+	}
+}
+
+func TestKeyOrderedSeriesCursor_InvalidToken(t *testing.T) {
+	if _, err := reads.NewKeyOrderedSeriesCursor(&sliceSeriesCursor{}, []byte("cpu"), 0); err != reads.ErrInvalidContinuationToken {
+		t.Fatalf("unexpected error: got %v, exp %v", err, reads.ErrInvalidContinuationToken)
+	}
+}
@@ -0,0 +1,279 @@
+package reads
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/influxdb/query/stdlib/influxdata/influxdb"
+	"github.com/influxdata/influxdb/tsdb/cursors"
+)
+
+// sharedReader deduplicates identical reads, such as the reads of the panels
+// of a dashboard querying the same data. The tables of a read are buffered
+// in memory and served to every identical read started while it runs or
+// within ttl of its completion.
+type sharedReader struct {
+	r   influxdb.Reader
+	ttl time.Duration
+	now func() time.Time
+
+	mu    sync.Mutex
+	reads map[string]*sharedRead
+}
+
+// NewSharedReader returns a Reader sharing the filter, group and window
+// aggregate reads of r which have identical specs, for ttl after they
+// complete. Tag key and tag value reads are not shared.
+//
+// Since the tables of shared reads are held in memory until they expire,
+// ttl should be short, such as the time to refresh a dashboard.
+func NewSharedReader(r influxdb.Reader, ttl time.Duration) influxdb.Reader {
+	return &sharedReader{
+		r:     r,
+		ttl:   ttl,
+		now:   time.Now,
+		reads: make(map[string]*sharedRead),
+	}
+}
+
+// sharedRead is the buffered result of a read.
+type sharedRead struct {
+	done   chan struct{}
+	tables []flux.BufferedTable
+	err    error
+
+	// The following fields are protected by the mutex of the reader.
+	expires time.Time // zero until the read completes
+	refs    int       // readers copying the tables
+	evicted bool      // removed from the reader, released once refs is zero
+}
+
+func (r *sharedReader) ReadFilter(ctx context.Context, spec influxdb.ReadFilterSpec, alloc *memory.Allocator) (influxdb.TableIterator, error) {
+	key, err := filterKey("filter", spec)
+	if err != nil {
+		return nil, err
+	}
+	return r.iterator(ctx, key, func() (influxdb.TableIterator, error) {
+		return r.r.ReadFilter(ctx, spec, alloc)
+	}), nil
+}
+
+func (r *sharedReader) ReadGroup(ctx context.Context, spec influxdb.ReadGroupSpec, alloc *memory.Allocator) (influxdb.TableIterator, error) {
+	key, err := filterKey("group", spec.ReadFilterSpec)
+	if err != nil {
+		return nil, err
+	}
+	key += fmt.Sprintf("|%d|%s|%s", spec.GroupMode, strings.Join(spec.GroupKeys, ","), spec.AggregateMethod)
+	return r.iterator(ctx, key, func() (influxdb.TableIterator, error) {
+		return r.r.ReadGroup(ctx, spec, alloc)
+	}), nil
+}
+
+func (r *sharedReader) ReadWindowAggregate(ctx context.Context, spec influxdb.ReadWindowAggregateSpec, alloc *memory.Allocator) (influxdb.TableIterator, error) {
+	key, err := filterKey("window", spec.ReadFilterSpec)
+	if err != nil {
+		return nil, err
+	}
+	key += fmt.Sprintf("|%d|%dmo|%s|%d", spec.WindowEvery, spec.WindowEveryMonths, spec.Aggregate, spec.Fill)
+	return r.iterator(ctx, key, func() (influxdb.TableIterator, error) {
+		return r.r.ReadWindowAggregate(ctx, spec, alloc)
+	}), nil
+}
+
+func (r *sharedReader) ReadTagKeys(ctx context.Context, spec influxdb.ReadTagKeysSpec, alloc *memory.Allocator) (influxdb.TableIterator, error) {
+	return r.r.ReadTagKeys(ctx, spec, alloc)
+}
+
+func (r *sharedReader) ReadTagValues(ctx context.Context, spec influxdb.ReadTagValuesSpec, alloc *memory.Allocator) (influxdb.TableIterator, error) {
+	return r.r.ReadTagValues(ctx, spec, alloc)
+}
+
+func (r *sharedReader) Close() {
+	r.mu.Lock()
+	for key, read := range r.reads {
+		r.evict(key, read)
+	}
+	r.mu.Unlock()
+	r.r.Close()
+}
+
+// filterKey returns the key identifying the reads of kind with spec.
+func filterKey(kind string, spec influxdb.ReadFilterSpec) (string, error) {
+	var predicate string
+	if spec.Predicate != nil {
+		p, err := toStoragePredicate(spec.Predicate)
+		if err != nil {
+			return "", err
+		}
+		predicate = p.String()
+	}
+	return fmt.Sprintf("%s|%s|%s|%d|%d|%s", kind, spec.OrganizationID, spec.BucketID,
+		spec.Bounds.Start, spec.Bounds.Stop, predicate), nil
+}
+
+func (r *sharedReader) iterator(ctx context.Context, key string, read func() (influxdb.TableIterator, error)) *sharedIterator {
+	return &sharedIterator{ctx: ctx, r: r, key: key, read: read}
+}
+
+// acquire returns the read of key, and true if it was created and must be
+// run by the caller.
+func (r *sharedReader) acquire(key string) (*sharedRead, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	for k, read := range r.reads {
+		if !read.expires.IsZero() && !now.Before(read.expires) {
+			r.evict(k, read)
+		}
+	}
+
+	read, ok := r.reads[key]
+	if !ok {
+		read = &sharedRead{done: make(chan struct{})}
+		r.reads[key] = read
+	}
+	read.refs++
+	return read, !ok
+}
+
+// complete records the result of the read of key, which expires after ttl.
+// A failed read is not shared any further.
+func (r *sharedReader) complete(key string, read *sharedRead, tables []flux.BufferedTable, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	read.tables, read.err = tables, err
+	read.expires = r.now().Add(r.ttl)
+	close(read.done)
+	if err != nil {
+		r.evict(key, read)
+		return
+	}
+	time.AfterFunc(r.ttl, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.reads[key] == read {
+			r.evict(key, read)
+		}
+	})
+}
+
+// release records that the caller of acquire is done copying the tables of
+// read.
+func (r *sharedReader) release(read *sharedRead) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	read.refs--
+	if read.evicted && read.refs == 0 {
+		read.free()
+	}
+}
+
+// evict removes the read of key, freeing its tables unless they are being
+// copied. The mutex must be held.
+func (r *sharedReader) evict(key string, read *sharedRead) {
+	if r.reads[key] == read {
+		delete(r.reads, key)
+	}
+	read.evicted = true
+	if read.refs == 0 {
+		read.free()
+	}
+}
+
+func (read *sharedRead) free() {
+	for _, t := range read.tables {
+		t.Done()
+	}
+	read.tables = nil
+}
+
+// sharedIterator serves the tables of a shared read, running it if no
+// identical read is running or buffered.
+type sharedIterator struct {
+	ctx   context.Context
+	r     *sharedReader
+	key   string
+	read  func() (influxdb.TableIterator, error)
+	stats cursors.CursorStats
+}
+
+// Statistics returns the statistics of the read if it was run by this
+// iterator. They are zero if the tables were shared by another read.
+func (si *sharedIterator) Statistics() cursors.CursorStats { return si.stats }
+
+func (si *sharedIterator) Do(f func(flux.Table) error) error {
+	read, run := si.r.acquire(si.key)
+	defer si.r.release(read)
+
+	if run {
+		tables, err := si.buffer()
+		si.r.complete(si.key, read, tables, err)
+		if err != nil {
+			return err
+		}
+	} else {
+		select {
+		case <-read.done:
+		case <-si.ctx.Done():
+			return si.ctx.Err()
+		}
+		if read.err != nil {
+			// The read failed for the iterator which ran it, possibly
+			// because its query was canceled, so it is run again.
+			return si.do(f)
+		}
+	}
+
+	for _, t := range read.tables {
+		if err := f(t.Copy()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buffer runs the read and returns its buffered tables.
+func (si *sharedIterator) buffer() ([]flux.BufferedTable, error) {
+	ti, err := si.read()
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []flux.BufferedTable
+	err = ti.Do(func(t flux.Table) error {
+		bt, err := execute.CopyTable(t)
+		if err != nil {
+			return err
+		}
+		tables = append(tables, bt)
+		return nil
+	})
+	si.stats = ti.Statistics()
+	if err != nil {
+		for _, t := range tables {
+			t.Done()
+		}
+		return nil, err
+	}
+	return tables, nil
+}
+
+// do runs the read without sharing it.
+func (si *sharedIterator) do(f func(flux.Table) error) error {
+	ti, err := si.read()
+	if err != nil {
+		return err
+	}
+	err = ti.Do(f)
+	si.stats = ti.Statistics()
+	return err
+}
@@ -0,0 +1,139 @@
+package reads_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/influxdb/query/stdlib/influxdata/influxdb"
+	"github.com/influxdata/influxdb/storage/reads"
+	"github.com/influxdata/influxdb/tsdb/cursors"
+)
+
+func TestSharedReader_ReadFilter(t *testing.T) {
+	r := &countingReader{}
+	shared := reads.NewSharedReader(r, 50*time.Millisecond)
+	defer shared.Close()
+
+	spec := influxdb.ReadFilterSpec{
+		OrganizationID: 1,
+		BucketID:       2,
+		Bounds:         execute.Bounds{Start: 0, Stop: 10},
+	}
+	want := []*executetest.Table{readerTable()}
+	executetest.NormalizeTables(want)
+
+	// Concurrent and subsequent identical reads share the first read.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got := readTables(t, shared, spec); !cmp.Equal(got, want) {
+				t.Errorf("unexpected tables: -got/+want\n%s", cmp.Diff(got, want))
+			}
+		}()
+	}
+	wg.Wait()
+	if got := readTables(t, shared, spec); !cmp.Equal(got, want) {
+		t.Fatalf("unexpected tables: -got/+want\n%s", cmp.Diff(got, want))
+	}
+	if got := r.count(); got != 1 {
+		t.Fatalf("unexpected number of reads: got %d, want 1", got)
+	}
+
+	// Reads of other specs are not shared.
+	other := spec
+	other.Bounds.Stop = 20
+	readTables(t, shared, other)
+	if got := r.count(); got != 2 {
+		t.Fatalf("unexpected number of reads: got %d, want 2", got)
+	}
+
+	// Reads are run again once the shared read expired.
+	time.Sleep(100 * time.Millisecond)
+	readTables(t, shared, spec)
+	if got := r.count(); got != 3 {
+		t.Fatalf("unexpected number of reads: got %d, want 3", got)
+	}
+}
+
+// readerTable is the table returned by every read of countingReader.
+func readerTable() *executetest.Table {
+	return &executetest.Table{
+		KeyCols: []string{"_measurement"},
+		ColMeta: []flux.ColMeta{
+			{Label: "_time", Type: flux.TTime},
+			{Label: "_measurement", Type: flux.TString},
+			{Label: "_value", Type: flux.TFloat},
+		},
+		Data: [][]interface{}{
+			{execute.Time(1), "cpu", 1.0},
+			{execute.Time(2), "cpu", 2.0},
+		},
+	}
+}
+
+func readTables(t *testing.T, r influxdb.Reader, spec influxdb.ReadFilterSpec) []*executetest.Table {
+	t.Helper()
+
+	ti, err := r.ReadFilter(context.Background(), spec, &memory.Allocator{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tables []*executetest.Table
+	if err := ti.Do(func(tbl flux.Table) error {
+		et, err := executetest.ConvertTable(tbl)
+		if err != nil {
+			return err
+		}
+		tables = append(tables, et)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	executetest.NormalizeTables(tables)
+	return tables
+}
+
+// countingReader counts the filter reads, which all return readerTable.
+type countingReader struct {
+	influxdb.Reader
+
+	mu    sync.Mutex
+	reads int
+}
+
+func (r *countingReader) ReadFilter(ctx context.Context, spec influxdb.ReadFilterSpec, alloc *memory.Allocator) (influxdb.TableIterator, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reads++
+	return tableIterator{readerTable()}, nil
+}
+
+func (r *countingReader) Close() {}
+
+func (r *countingReader) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reads
+}
+
+type tableIterator []flux.Table
+
+func (it tableIterator) Do(f func(flux.Table) error) error {
+	for _, t := range it {
+		if err := f(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (it tableIterator) Statistics() cursors.CursorStats { return cursors.CursorStats{} }
@@ -82,3 +82,12 @@ type Store interface {
 
 	GetSource(orgID, bucketID uint64) proto.Message
 }
+
+// TimeRangeStore is implemented by the stores knowing the time range of the
+// data of a bucket.
+type TimeRangeStore interface {
+	// TimeRange returns the minimum and maximum time of the data of the
+	// bucket, or false if it has no data. The range may be larger than that
+	// of the data, but never smaller.
+	TimeRange(ctx context.Context, orgID, bucketID uint64) (min, max int64, ok bool, err error)
+}
@@ -0,0 +1,537 @@
+package reads
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/stdlib/universe"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/query/stdlib/influxdata/influxdb"
+	"github.com/influxdata/influxdb/storage/reads/datatypes"
+	"github.com/influxdata/influxdb/tsdb/cursors"
+)
+
+func (r *storeReader) ReadWindowAggregate(ctx context.Context, spec influxdb.ReadWindowAggregateSpec, alloc *memory.Allocator) (influxdb.TableIterator, error) {
+	switch spec.Aggregate {
+	case universe.CountKind, universe.SumKind, universe.MeanKind:
+	default:
+		return nil, fmt.Errorf("unsupported window aggregate %q", spec.Aggregate)
+	}
+	if spec.WindowEveryMonths != 0 {
+		if spec.WindowEveryMonths < 0 || spec.WindowEvery != 0 {
+			return nil, fmt.Errorf("invalid window duration %dmo%dns", spec.WindowEveryMonths, spec.WindowEvery)
+		}
+	} else if spec.WindowEvery <= 0 {
+		return nil, fmt.Errorf("invalid window duration %d", spec.WindowEvery)
+	}
+
+	return &windowAggregateIterator{
+		ctx:   ctx,
+		s:     r.s,
+		spec:  spec,
+		alloc: alloc,
+	}, nil
+}
+
+// windowAggregateIterator aggregates the values of each series over windows
+// as they are read, producing a table of a row per window for each series.
+// Only the aggregate of each window is kept in memory, and empty windows are
+// filled at the same time, rather than by a separate pass over the tables.
+type windowAggregateIterator struct {
+	ctx   context.Context
+	s     Store
+	spec  influxdb.ReadWindowAggregateSpec
+	stats cursors.CursorStats
+	alloc *memory.Allocator
+}
+
+func (wi *windowAggregateIterator) Statistics() cursors.CursorStats { return wi.stats }
+
+func (wi *windowAggregateIterator) Do(f func(flux.Table) error) error {
+	src := wi.s.GetSource(
+		uint64(wi.spec.OrganizationID),
+		uint64(wi.spec.BucketID),
+	)
+
+	// Setup read request
+	any, err := types.MarshalAny(src)
+	if err != nil {
+		return err
+	}
+
+	var predicate *datatypes.Predicate
+	if wi.spec.Predicate != nil {
+		p, err := toStoragePredicate(wi.spec.Predicate)
+		if err != nil {
+			return err
+		}
+		predicate = p
+	}
+
+	start, stop, err := wi.readRange()
+	if err != nil {
+		return err
+	}
+	if stop <= start {
+		return nil
+	}
+
+	var req datatypes.ReadFilterRequest
+	req.ReadSource = any
+	req.Predicate = predicate
+	req.Range.Start = start
+	req.Range.End = stop
+
+	rs, err := wi.s.ReadFilter(wi.ctx, &req)
+	if err != nil {
+		return err
+	}
+
+	if rs == nil {
+		return nil
+	}
+	return wi.handleRead(f, rs, start, stop)
+}
+
+// readRange returns the range of the windows to read. When the store knows
+// the time range of the data of the bucket, the open ends of the bounds, a
+// start at or before the epoch or a stop in the future, are resolved to the
+// windows holding that data, so that a query over all time does not create
+// the empty windows before the first and after the last value. Without fill,
+// empty windows produce no row, so both ends are always resolved.
+func (wi *windowAggregateIterator) readRange() (start, stop int64, err error) {
+	start, stop = int64(wi.spec.Bounds.Start), int64(wi.spec.Bounds.Stop)
+	trs, ok := wi.s.(TimeRangeStore)
+	if !ok {
+		return start, stop, nil
+	}
+
+	every := wi.every()
+	fillNone := wi.spec.Fill == influxdb.FillNone
+	openStart := fillNone || start <= 0
+	openStop := fillNone || stop > time.Now().UnixNano()
+	if !openStart && !openStop {
+		return start, stop, nil
+	}
+
+	min, max, ok, err := trs.TimeRange(wi.ctx, uint64(wi.spec.OrganizationID), uint64(wi.spec.BucketID))
+	if err != nil {
+		return 0, 0, err
+	} else if !ok {
+		// The bucket has no data.
+		return start, start, nil
+	}
+
+	if ws := every.start(min); openStart && ws > start {
+		start = ws
+	}
+	if ws := every.next(every.start(max)); openStop && ws > max && ws < stop {
+		stop = ws
+	}
+	return start, stop, nil
+}
+
+// every returns the duration of the windows of the spec.
+func (wi *windowAggregateIterator) every() windowEvery {
+	return windowEvery{nsecs: wi.spec.WindowEvery, months: wi.spec.WindowEveryMonths}
+}
+
+// windowEvery is the duration of windows, either a fixed number of
+// nanoseconds aligned on the epoch, or a number of calendar months aligned
+// on the first month of year 0 in UTC, as with window().
+type windowEvery struct {
+	nsecs  int64
+	months int64
+}
+
+// start returns the start of the window of the time ts.
+func (e windowEvery) start(ts int64) int64 {
+	if e.months == 0 {
+		ws := ts - ts%e.nsecs
+		if ts%e.nsecs < 0 {
+			ws -= e.nsecs
+		}
+		return ws
+	}
+
+	year, month, _ := time.Unix(0, ts).UTC().Date()
+	total := int64(year)*12 + int64(month-1)
+	if rem := total % e.months; rem < 0 {
+		total -= rem + e.months
+	} else {
+		total -= rem
+	}
+	return time.Date(int(total/12), time.Month(total%12)+1, 1, 0, 0, 0, 0, time.UTC).UnixNano()
+}
+
+// next returns the start of the window following the window starting at ws,
+// or math.MaxInt64 if it is not representable.
+func (e windowEvery) next(ws int64) int64 {
+	if e.months == 0 {
+		if ws > math.MaxInt64-e.nsecs {
+			return math.MaxInt64
+		}
+		return ws + e.nsecs
+	}
+
+	t := time.Unix(0, ws).UTC().AddDate(0, int(e.months), 0)
+	if t.After(maxWindowTime) {
+		return math.MaxInt64
+	}
+	return t.UnixNano()
+}
+
+// maxWindowTime is the latest time whose nanoseconds since the epoch fit in
+// an int64.
+var maxWindowTime = time.Unix(0, math.MaxInt64).UTC()
+
+func (wi *windowAggregateIterator) handleRead(f func(flux.Table) error, rs ResultSet, start, stop int64) error {
+	defer rs.Close()
+
+	for rs.Next() {
+		cur := rs.Cursor()
+		if cur == nil {
+			// no data for series key + field combination
+			continue
+		}
+
+		w := newWindows(wi.spec.Bounds, start, stop, wi.every())
+		err := w.aggregate(cur, wi.spec.Aggregate)
+		stats := cur.Stats()
+		wi.stats.ScannedValues += stats.ScannedValues
+		wi.stats.ScannedBytes += stats.ScannedBytes
+		cur.Close()
+		if err != nil {
+			return err
+		}
+
+		// As with window(), a series without any value in the bounds
+		// produces no table, even when creating empty windows.
+		if w.points == 0 {
+			continue
+		}
+		w.fill(wi.spec.Fill)
+
+		tbl, err := w.table(rs.Tags(), wi.alloc)
+		if err != nil {
+			return err
+		}
+		if err := f(tbl); err != nil {
+			return err
+		}
+
+		select {
+		case <-wi.ctx.Done():
+			return wi.ctx.Err()
+		default:
+		}
+	}
+	return rs.Err()
+}
+
+// windows holds the aggregate of each window of a series over [start, stop),
+// in the column of the type of the aggregate.
+type windows struct {
+	bounds execute.Bounds // bounds of the query, of the columns of the table
+	start  int64
+	stop   int64
+	every  windowEvery
+	first  int64 // start of the first window, before clipping to start
+
+	typ    flux.ColType
+	stops  []int64 // clipped stop of each window, the time of its row
+	counts []int64
+	floats []float64
+	ints   []int64
+	uints  []uint64
+	valid  []bool
+	points int64
+}
+
+func newWindows(bounds execute.Bounds, start, stop int64, every windowEvery) *windows {
+	w := &windows{bounds: bounds, start: start, stop: stop, every: every}
+	if stop <= start {
+		return w
+	}
+
+	w.first = every.start(start)
+	for ws := w.first; ws < stop; ws = every.next(ws) {
+		ts := every.next(ws)
+		if ts > stop {
+			ts = stop
+		}
+		w.stops = append(w.stops, ts)
+		if ts == stop {
+			break
+		}
+	}
+	w.counts = make([]int64, len(w.stops))
+	return w
+}
+
+// index returns the index of the window of the time ts, or -1 if ts is out
+// of bounds.
+func (w *windows) index(ts int64) int {
+	if ts < w.start || ts >= w.stop {
+		return -1
+	}
+	if w.every.months == 0 {
+		return int((ts - w.first) / w.every.nsecs)
+	}
+	// Calendar months vary in length, so the window is the first one
+	// stopping after ts.
+	return sort.Search(len(w.stops), func(j int) bool { return w.stops[j] > ts })
+}
+
+// aggregate computes the aggregate of the values of the cursor for each window.
+func (w *windows) aggregate(cur cursors.Cursor, agg string) error {
+	n := len(w.stops)
+	switch agg {
+	case universe.CountKind:
+		w.typ = flux.TInt
+	case universe.MeanKind:
+		w.typ = flux.TFloat
+		w.floats = make([]float64, n)
+	}
+
+	switch cur := cur.(type) {
+	case cursors.FloatArrayCursor:
+		if agg == universe.SumKind {
+			w.typ = flux.TFloat
+			w.floats = make([]float64, n)
+		}
+		for a := cur.Next(); a.Len() > 0; a = cur.Next() {
+			for i, ts := range a.Timestamps {
+				if j := w.index(ts); j >= 0 {
+					w.counts[j]++
+					if w.floats != nil {
+						w.floats[j] += a.Values[i]
+					}
+				}
+			}
+		}
+	case cursors.IntegerArrayCursor:
+		if agg == universe.SumKind {
+			w.typ = flux.TInt
+			w.ints = make([]int64, n)
+		}
+		for a := cur.Next(); a.Len() > 0; a = cur.Next() {
+			for i, ts := range a.Timestamps {
+				if j := w.index(ts); j >= 0 {
+					w.counts[j]++
+					if w.ints != nil {
+						w.ints[j] += a.Values[i]
+					} else if w.floats != nil {
+						w.floats[j] += float64(a.Values[i])
+					}
+				}
+			}
+		}
+	case cursors.UnsignedArrayCursor:
+		if agg == universe.SumKind {
+			w.typ = flux.TUInt
+			w.uints = make([]uint64, n)
+		}
+		for a := cur.Next(); a.Len() > 0; a = cur.Next() {
+			for i, ts := range a.Timestamps {
+				if j := w.index(ts); j >= 0 {
+					w.counts[j]++
+					if w.uints != nil {
+						w.uints[j] += a.Values[i]
+					} else if w.floats != nil {
+						w.floats[j] += float64(a.Values[i])
+					}
+				}
+			}
+		}
+	case cursors.BooleanArrayCursor:
+		if agg != universe.CountKind {
+			return fmt.Errorf("unsupported aggregate column type %v", flux.TBool)
+		}
+		for a := cur.Next(); a.Len() > 0; a = cur.Next() {
+			for _, ts := range a.Timestamps {
+				if j := w.index(ts); j >= 0 {
+					w.counts[j]++
+				}
+			}
+		}
+	case cursors.StringArrayCursor:
+		if agg != universe.CountKind {
+			return fmt.Errorf("unsupported aggregate column type %v", flux.TString)
+		}
+		for a := cur.Next(); a.Len() > 0; a = cur.Next() {
+			for _, ts := range a.Timestamps {
+				if j := w.index(ts); j >= 0 {
+					w.counts[j]++
+				}
+			}
+		}
+	default:
+		panic(fmt.Sprintf("unreachable: %T", cur))
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+
+	w.valid = make([]bool, n)
+	for j, count := range w.counts {
+		w.points += count
+		if agg == universe.CountKind {
+			// The count of an empty window is 0, not null.
+			w.ints = w.counts
+			w.valid[j] = true
+			continue
+		}
+		w.valid[j] = count > 0
+		if agg == universe.MeanKind && count > 0 {
+			w.floats[j] /= float64(count)
+		}
+	}
+	return nil
+}
+
+// fill gives a value to the empty windows according to the policy.
+func (w *windows) fill(policy influxdb.FillPolicy) {
+	switch policy {
+	case influxdb.FillNone:
+		// Drop the windows without any value.
+		n := 0
+		for j := range w.stops {
+			if w.counts[j] == 0 {
+				continue
+			}
+			w.move(n, j)
+			n++
+		}
+		w.truncate(n)
+	case influxdb.FillPrevious:
+		for j := 1; j < len(w.stops); j++ {
+			if !w.valid[j] && w.valid[j-1] {
+				w.copy(j, j-1, j-1, 0)
+			}
+		}
+	case influxdb.FillLinear:
+		prev := -1
+		for j := range w.stops {
+			if !w.valid[j] {
+				continue
+			}
+			for k := prev + 1; prev >= 0 && k < j; k++ {
+				w.copy(k, prev, j, float64(k-prev)/float64(j-prev))
+			}
+			prev = j
+		}
+	}
+}
+
+// move moves the aggregate of window j to window i.
+func (w *windows) move(i, j int) {
+	w.stops[i] = w.stops[j]
+	w.counts[i] = w.counts[j]
+	w.valid[i] = w.valid[j]
+	if w.floats != nil {
+		w.floats[i] = w.floats[j]
+	}
+	if w.ints != nil {
+		w.ints[i] = w.ints[j]
+	}
+	if w.uints != nil {
+		w.uints[i] = w.uints[j]
+	}
+}
+
+// truncate keeps the first n windows.
+func (w *windows) truncate(n int) {
+	w.stops = w.stops[:n]
+	w.counts = w.counts[:n]
+	w.valid = w.valid[:n]
+	if w.floats != nil {
+		w.floats = w.floats[:n]
+	}
+	if w.ints != nil {
+		w.ints = w.ints[:n]
+	}
+	if w.uints != nil {
+		w.uints = w.uints[:n]
+	}
+}
+
+// copy sets the value of window i to the value at fraction f between the
+// values of windows a and b.
+func (w *windows) copy(i, a, b int, f float64) {
+	w.valid[i] = true
+	switch w.typ {
+	case flux.TFloat:
+		w.floats[i] = w.floats[a] + (w.floats[b]-w.floats[a])*f
+	case flux.TInt:
+		w.ints[i] = w.ints[a] + int64(math.Round(float64(w.ints[b]-w.ints[a])*f))
+	case flux.TUInt:
+		if w.uints[b] >= w.uints[a] {
+			w.uints[i] = w.uints[a] + uint64(math.Round(float64(w.uints[b]-w.uints[a])*f))
+		} else {
+			w.uints[i] = w.uints[a] - uint64(math.Round(float64(w.uints[a]-w.uints[b])*f))
+		}
+	}
+}
+
+// table returns the table of the windows of the series, with a row for each
+// window whose time is the stop time of the window, as with aggregateWindow().
+func (w *windows) table(tags models.Tags, alloc *memory.Allocator) (flux.Table, error) {
+	key := defaultGroupKeyForSeries(tags, w.bounds)
+	cols, _ := determineTableColsForSeries(tags, w.typ)
+	builder := execute.NewColListTableBuilder(key, alloc)
+	defer builder.ClearData()
+	for _, c := range cols {
+		if _, err := builder.AddCol(c); err != nil {
+			return nil, err
+		}
+	}
+
+	for j, stop := range w.stops {
+		if err := builder.AppendTime(startColIdx, w.bounds.Start); err != nil {
+			return nil, err
+		}
+		if err := builder.AppendTime(stopColIdx, w.bounds.Stop); err != nil {
+			return nil, err
+		}
+		if err := builder.AppendTime(timeColIdx, execute.Time(stop)); err != nil {
+			return nil, err
+		}
+
+		var err error
+		switch {
+		case !w.valid[j]:
+			err = builder.AppendNil(valueColIdx)
+		case w.typ == flux.TFloat:
+			err = builder.AppendFloat(valueColIdx, w.floats[j])
+		case w.typ == flux.TInt:
+			err = builder.AppendInt(valueColIdx, w.ints[j])
+		case w.typ == flux.TUInt:
+			err = builder.AppendUInt(valueColIdx, w.uints[j])
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for i, tag := range tags {
+			if err := builder.AppendString(4+i, string(tag.Value)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Construct the table and add to the reference count
+	// so we can free the table later.
+	tbl, err := builder.Table()
+	if err != nil {
+		return nil, err
+	}
+	return tbl, nil
+}
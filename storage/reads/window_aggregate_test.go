@@ -0,0 +1,458 @@
+package reads_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/execute"
+	"github.com/influxdata/flux/execute/executetest"
+	"github.com/influxdata/flux/memory"
+	"github.com/influxdata/flux/stdlib/universe"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/query/stdlib/influxdata/influxdb"
+	"github.com/influxdata/influxdb/storage/reads"
+	"github.com/influxdata/influxdb/storage/reads/datatypes"
+	"github.com/influxdata/influxdb/tsdb/cursors"
+)
+
+func TestStoreReader_ReadWindowAggregate(t *testing.T) {
+	// Windows of 10ns over [0, 60), the rows of a series being the values
+	// of its windows stopping at 10, 20, 30, 40, 50 and 60.
+	newStore := func() *windowStore {
+		return &windowStore{series: []windowSeries{
+			{key: "m,k=a", cur: &floatArrayCursor{a: &cursors.FloatArray{
+				Timestamps: []int64{10, 15, 45},
+				Values:     []float64{1, 3, 9},
+			}}},
+			{key: "m,k=b", cur: &integerArrayCursor{a: &cursors.IntegerArray{
+				Timestamps: []int64{5, 35, 60},
+				Values:     []int64{10, 40, 1000},
+			}}},
+		}}
+	}
+
+	for _, tt := range []struct {
+		name string
+		agg  string
+		fill influxdb.FillPolicy
+		want map[string][]interface{}
+	}{
+		{
+			name: "mean fill null",
+			agg:  universe.MeanKind,
+			fill: influxdb.FillNull,
+			want: map[string][]interface{}{
+				"a": {nil, 2.0, nil, nil, 9.0, nil},
+				"b": {10.0, nil, nil, 40.0, nil, nil},
+			},
+		},
+		{
+			name: "mean fill none",
+			agg:  universe.MeanKind,
+			fill: influxdb.FillNone,
+			want: map[string][]interface{}{
+				"a": {2.0, 9.0},
+				"b": {10.0, 40.0},
+			},
+		},
+		{
+			name: "sum fill previous",
+			agg:  universe.SumKind,
+			fill: influxdb.FillPrevious,
+			want: map[string][]interface{}{
+				"a": {nil, 4.0, 4.0, 4.0, 9.0, 9.0},
+				"b": {int64(10), int64(10), int64(10), int64(40), int64(40), int64(40)},
+			},
+		},
+		{
+			name: "sum fill linear",
+			agg:  universe.SumKind,
+			fill: influxdb.FillLinear,
+			want: map[string][]interface{}{
+				"a": {nil, 4.0, 5.666666666666666, 7.333333333333333, 9.0, nil},
+				"b": {int64(10), int64(20), int64(30), int64(40), nil, nil},
+			},
+		},
+		{
+			name: "count fill null",
+			agg:  universe.CountKind,
+			fill: influxdb.FillNull,
+			want: map[string][]interface{}{
+				"a": {int64(0), int64(2), int64(0), int64(0), int64(1), int64(0)},
+				"b": {int64(1), int64(0), int64(0), int64(1), int64(0), int64(0)},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ti, err := reads.NewReader(newStore()).ReadWindowAggregate(context.Background(), influxdb.ReadWindowAggregateSpec{
+				ReadFilterSpec: influxdb.ReadFilterSpec{
+					Bounds: execute.Bounds{Start: 0, Stop: 60},
+				},
+				WindowEvery: 10,
+				Aggregate:   tt.agg,
+				Fill:        tt.fill,
+			}, &memory.Allocator{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make(map[string][]interface{})
+			if err := ti.Do(func(tbl flux.Table) error {
+				table, err := executetest.ConvertTable(tbl)
+				if err != nil {
+					return err
+				}
+				k := table.Key().ValueString(execute.ColIdx("k", table.Key().Cols()))
+				timeIdx := execute.ColIdx(execute.DefaultTimeColLabel, table.Cols())
+				valueIdx := execute.ColIdx(execute.DefaultValueColLabel, table.Cols())
+				for _, row := range table.Data {
+					if stop := row[timeIdx].(execute.Time); stop%10 != 0 && tt.fill != influxdb.FillNone {
+						t.Errorf("unexpected window stop %d", stop)
+					}
+					got[k] = append(got[k], row[valueIdx])
+				}
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Fatalf("unexpected windows: -got/+want\n%s", cmp.Diff(got, tt.want))
+			}
+			if stats := ti.Statistics(); stats.ScannedValues != 6 {
+				t.Fatalf("unexpected scanned values: %d", stats.ScannedValues)
+			}
+		})
+	}
+
+	t.Run("unsupported aggregate", func(t *testing.T) {
+		_, err := reads.NewReader(newStore()).ReadWindowAggregate(context.Background(), influxdb.ReadWindowAggregateSpec{
+			WindowEvery: 10,
+			Aggregate:   universe.MaxKind,
+		}, &memory.Allocator{})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestStoreReader_ReadWindowAggregate_TimeRange(t *testing.T) {
+	future := execute.Time(time.Now().Add(time.Hour).UnixNano())
+	newStore := func(min, max int64, ok bool) *timeRangeWindowStore {
+		return &timeRangeWindowStore{
+			windowStore: windowStore{series: []windowSeries{
+				{key: "m,k=a", cur: &floatArrayCursor{a: &cursors.FloatArray{
+					Timestamps: []int64{12, 15, 45},
+					Values:     []float64{1, 3, 9},
+				}}},
+			}},
+			min: min, max: max, ok: ok,
+		}
+	}
+
+	for _, tt := range []struct {
+		name      string
+		bounds    execute.Bounds
+		fill      influxdb.FillPolicy
+		min, max  int64
+		ok        bool
+		wantRange datatypes.TimestampRange
+		wantTimes []execute.Time
+	}{
+		{
+			name:      "open bounds",
+			bounds:    execute.Bounds{Start: 0, Stop: future},
+			fill:      influxdb.FillNull,
+			min:       12,
+			max:       45,
+			ok:        true,
+			wantRange: datatypes.TimestampRange{Start: 10, End: 50},
+			wantTimes: []execute.Time{20, 30, 40, 50},
+		},
+		{
+			name:      "closed bounds",
+			bounds:    execute.Bounds{Start: 5, Stop: 60},
+			fill:      influxdb.FillNull,
+			min:       12,
+			max:       45,
+			ok:        true,
+			wantRange: datatypes.TimestampRange{Start: 5, End: 60},
+			wantTimes: []execute.Time{10, 20, 30, 40, 50, 60},
+		},
+		{
+			name:      "closed bounds fill none",
+			bounds:    execute.Bounds{Start: 5, Stop: 60},
+			fill:      influxdb.FillNone,
+			min:       12,
+			max:       45,
+			ok:        true,
+			wantRange: datatypes.TimestampRange{Start: 10, End: 50},
+			wantTimes: []execute.Time{20, 50},
+		},
+		{
+			name:   "empty bucket",
+			bounds: execute.Bounds{Start: 0, Stop: future},
+			fill:   influxdb.FillNull,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStore(tt.min, tt.max, tt.ok)
+			ti, err := reads.NewReader(s).ReadWindowAggregate(context.Background(), influxdb.ReadWindowAggregateSpec{
+				ReadFilterSpec: influxdb.ReadFilterSpec{Bounds: tt.bounds},
+				WindowEvery:    10,
+				Aggregate:      universe.CountKind,
+				Fill:           tt.fill,
+			}, &memory.Allocator{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var times []execute.Time
+			if err := ti.Do(func(tbl flux.Table) error {
+				table, err := executetest.ConvertTable(tbl)
+				if err != nil {
+					return err
+				}
+				if got := table.Key().ValueTime(execute.ColIdx(execute.DefaultStartColLabel, table.Key().Cols())); got != tt.bounds.Start {
+					t.Errorf("unexpected start %d", got)
+				}
+				if got := table.Key().ValueTime(execute.ColIdx(execute.DefaultStopColLabel, table.Key().Cols())); got != tt.bounds.Stop {
+					t.Errorf("unexpected stop %d", got)
+				}
+				timeIdx := execute.ColIdx(execute.DefaultTimeColLabel, table.Cols())
+				for _, row := range table.Data {
+					times = append(times, row[timeIdx].(execute.Time))
+				}
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			if !tt.ok {
+				if s.req != nil || len(times) > 0 {
+					t.Fatalf("unexpected read of an empty bucket: %v", times)
+				}
+				return
+			}
+			if s.req == nil || s.req.Range != tt.wantRange {
+				t.Fatalf("unexpected read range: %+v", s.req)
+			}
+			if !cmp.Equal(times, tt.wantTimes) {
+				t.Fatalf("unexpected window times: -got/+want\n%s", cmp.Diff(times, tt.wantTimes))
+			}
+		})
+	}
+}
+
+func TestStoreReader_ReadWindowAggregate_Months(t *testing.T) {
+	ts := func(s string) int64 {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			panic(err)
+		}
+		return t.UnixNano()
+	}
+	newStore := func() *windowStore {
+		return &windowStore{series: []windowSeries{
+			{key: "m,k=a", cur: &integerArrayCursor{a: &cursors.IntegerArray{
+				Timestamps: []int64{
+					ts("2020-01-15T00:00:00Z"),
+					ts("2020-01-31T23:59:59Z"),
+					ts("2020-02-29T12:00:00Z"),
+					ts("2020-03-01T00:00:00Z"),
+					ts("2020-07-04T00:00:00Z"),
+				},
+				Values: []int64{1, 2, 3, 4, 5},
+			}}},
+		}}
+	}
+
+	for _, tt := range []struct {
+		name      string
+		months    int64
+		bounds    execute.Bounds
+		wantTimes []int64
+		want      []interface{}
+	}{
+		{
+			name:   "months",
+			months: 1,
+			bounds: execute.Bounds{Start: execute.Time(ts("2020-01-10T00:00:00Z")), Stop: execute.Time(ts("2020-05-01T00:00:00Z"))},
+			wantTimes: []int64{
+				ts("2020-02-01T00:00:00Z"),
+				ts("2020-03-01T00:00:00Z"),
+				ts("2020-04-01T00:00:00Z"),
+				ts("2020-05-01T00:00:00Z"),
+			},
+			want: []interface{}{int64(3), int64(3), int64(4), nil},
+		},
+		{
+			name:   "quarters",
+			months: 3,
+			bounds: execute.Bounds{Start: execute.Time(ts("2020-01-01T00:00:00Z")), Stop: execute.Time(ts("2020-08-15T00:00:00Z"))},
+			wantTimes: []int64{
+				ts("2020-04-01T00:00:00Z"),
+				ts("2020-07-01T00:00:00Z"),
+				ts("2020-08-15T00:00:00Z"),
+			},
+			want: []interface{}{int64(10), nil, int64(5)},
+		},
+		{
+			name:   "years",
+			months: 12,
+			bounds: execute.Bounds{Start: execute.Time(ts("2019-06-01T00:00:00Z")), Stop: execute.Time(ts("2021-01-01T00:00:00Z"))},
+			wantTimes: []int64{
+				ts("2020-01-01T00:00:00Z"),
+				ts("2021-01-01T00:00:00Z"),
+			},
+			want: []interface{}{nil, int64(15)},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ti, err := reads.NewReader(newStore()).ReadWindowAggregate(context.Background(), influxdb.ReadWindowAggregateSpec{
+				ReadFilterSpec:    influxdb.ReadFilterSpec{Bounds: tt.bounds},
+				WindowEveryMonths: tt.months,
+				Aggregate:         universe.SumKind,
+				Fill:              influxdb.FillNull,
+			}, &memory.Allocator{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var times []int64
+			var got []interface{}
+			if err := ti.Do(func(tbl flux.Table) error {
+				table, err := executetest.ConvertTable(tbl)
+				if err != nil {
+					return err
+				}
+				timeIdx := execute.ColIdx(execute.DefaultTimeColLabel, table.Cols())
+				valueIdx := execute.ColIdx(execute.DefaultValueColLabel, table.Cols())
+				for _, row := range table.Data {
+					times = append(times, int64(row[timeIdx].(execute.Time)))
+					got = append(got, row[valueIdx])
+				}
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(times, tt.wantTimes) {
+				t.Fatalf("unexpected window times: -got/+want\n%s", cmp.Diff(times, tt.wantTimes))
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Fatalf("unexpected windows: -got/+want\n%s", cmp.Diff(got, tt.want))
+			}
+		})
+	}
+
+	t.Run("mixed duration", func(t *testing.T) {
+		_, err := reads.NewReader(newStore()).ReadWindowAggregate(context.Background(), influxdb.ReadWindowAggregateSpec{
+			WindowEvery:       10,
+			WindowEveryMonths: 1,
+			Aggregate:         universe.SumKind,
+		}, &memory.Allocator{})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+// windowStore returns the series of a single read filter.
+type windowStore struct {
+	reads.Store
+	series []windowSeries
+}
+
+// timeRangeWindowStore is a windowStore knowing the time range of the data,
+// recording the read filter request.
+type timeRangeWindowStore struct {
+	windowStore
+	min, max int64
+	ok       bool
+	req      *datatypes.ReadFilterRequest
+}
+
+func (s *timeRangeWindowStore) ReadFilter(ctx context.Context, req *datatypes.ReadFilterRequest) (reads.ResultSet, error) {
+	s.req = req
+	return s.windowStore.ReadFilter(ctx, req)
+}
+
+func (s *timeRangeWindowStore) TimeRange(ctx context.Context, orgID, bucketID uint64) (min, max int64, ok bool, err error) {
+	return s.min, s.max, s.ok, nil
+}
+
+type windowSeries struct {
+	key string
+	cur cursors.Cursor
+}
+
+func (s *windowStore) ReadFilter(ctx context.Context, req *datatypes.ReadFilterRequest) (reads.ResultSet, error) {
+	return &windowResultSet{series: s.series, at: -1}, nil
+}
+
+func (s *windowStore) GetSource(orgID, bucketID uint64) proto.Message { return &types.Empty{} }
+
+type windowResultSet struct {
+	series []windowSeries
+	at     int
+}
+
+func (r *windowResultSet) Next() bool {
+	r.at++
+	return r.at < len(r.series)
+}
+
+func (r *windowResultSet) Cursor() cursors.Cursor { return r.series[r.at].cur }
+
+func (r *windowResultSet) Tags() models.Tags {
+	_, tags := models.ParseKeyBytes([]byte(r.series[r.at].key))
+	return tags
+}
+
+func (r *windowResultSet) Close()                     {}
+func (r *windowResultSet) Err() error                 { return nil }
+func (r *windowResultSet) Stats() cursors.CursorStats { return cursors.CursorStats{} }
+
+// floatArrayCursor returns a single array.
+type floatArrayCursor struct {
+	a    *cursors.FloatArray
+	done bool
+}
+
+func (c *floatArrayCursor) Next() *cursors.FloatArray {
+	if c.done {
+		return &cursors.FloatArray{}
+	}
+	c.done = true
+	return c.a
+}
+
+func (c *floatArrayCursor) Close()     {}
+func (c *floatArrayCursor) Err() error { return nil }
+func (c *floatArrayCursor) Stats() cursors.CursorStats {
+	return cursors.CursorStats{ScannedValues: c.a.Len()}
+}
+
+// integerArrayCursor returns a single array.
+type integerArrayCursor struct {
+	a    *cursors.IntegerArray
+	done bool
+}
+
+func (c *integerArrayCursor) Next() *cursors.IntegerArray {
+	if c.done {
+		return &cursors.IntegerArray{}
+	}
+	c.done = true
+	return c.a
+}
+
+func (c *integerArrayCursor) Close()     {}
+func (c *integerArrayCursor) Err() error { return nil }
+func (c *integerArrayCursor) Stats() cursors.CursorStats {
+	return cursors.CursorStats{ScannedValues: c.a.Len()}
+}
@@ -3,6 +3,7 @@ package readservice
 import (
 	"context"
 	"errors"
+	"math"
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/gogo/protobuf/types"
@@ -25,6 +26,12 @@ type Viewer interface {
 	TagValues(ctx context.Context, orgID, bucketID influxdb.ID, tagKey string, start, end int64, predicate influxql.Expr) (cursors.StringIterator, error)
 }
 
+// TimeRangeViewer is implemented by the viewers knowing the time range of
+// the data of a bucket.
+type TimeRangeViewer interface {
+	TimeRange(orgID, bucketID influxdb.ID) (min, max int64, ok bool)
+}
+
 type store struct {
 	viewer Viewer
 }
@@ -47,6 +54,10 @@ func (s *store) ReadFilter(ctx context.Context, req *datatypes.ReadFilterRequest
 		return nil, tracing.LogError(span, err)
 	}
 
+	if req.SeriesOrder != datatypes.SeriesOrderKey && (req.SeriesLimit > 0 || len(req.ContinuationToken) > 0) {
+		return nil, tracing.LogError(span, reads.ErrSeriesLimitWithoutOrder)
+	}
+
 	var cur reads.SeriesCursor
 	if cur, err = newIndexSeriesCursor(ctx, &source, req.Predicate, s.viewer); err != nil {
 		return nil, tracing.LogError(span, err)
@@ -54,9 +65,26 @@ func (s *store) ReadFilter(ctx context.Context, req *datatypes.ReadFilterRequest
 		return nil, nil
 	}
 
+	if req.SeriesOrder == datatypes.SeriesOrderKey {
+		if cur, err = reads.NewKeyOrderedSeriesCursor(cur, req.ContinuationToken, req.SeriesLimit); err != nil {
+			return nil, tracing.LogError(span, err)
+		}
+	}
+
 	return reads.NewFilteredResultSet(ctx, req, cur), nil
 }
 
+// TimeRange returns the time range of the data of the bucket, or a range
+// bounding all time if the viewer does not know it.
+func (s *store) TimeRange(ctx context.Context, orgID, bucketID uint64) (min, max int64, ok bool, err error) {
+	v, ok := s.viewer.(TimeRangeViewer)
+	if !ok {
+		return math.MinInt64, math.MaxInt64, true, nil
+	}
+	min, max, ok = v.TimeRange(influxdb.ID(orgID), influxdb.ID(bucketID))
+	return min, max, ok, nil
+}
+
 func (s *store) ReadGroup(ctx context.Context, req *datatypes.ReadGroupRequest) (reads.GroupResultSet, error) {
 	span, ctx := tracing.StartSpanFromContext(ctx)
 	defer span.Finish()
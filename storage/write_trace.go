@@ -0,0 +1,54 @@
+package storage
+
+import "context"
+
+// WriteTrace records how the engine wrote the points of a write, to debug
+// unexpected series or file growth. The engine holds a single shard, so that
+// the points of a write are mapped to the segment of the WAL they are
+// appended to and to the partitions of the index of their series.
+//
+// A WriteTrace is filled by Engine.WritePoints when attached to the context
+// of the write with WithWriteTrace. It is not safe for concurrent writes.
+type WriteTrace struct {
+	// Points is the number of points written, a point holding the value of
+	// a single field, and Dropped the number of points rejected.
+	Points        int    `json:"points"`
+	Dropped       int    `json:"dropped"`
+	DroppedReason string `json:"droppedReason,omitempty"`
+
+	// Series is the number of distinct series written, of which
+	// SeriesCreated did not exist before the write.
+	Series        int `json:"series"`
+	SeriesCreated int `json:"seriesCreated"`
+
+	// WALSegments lists the IDs of the WAL segments the points were
+	// appended to, empty if the WAL is disabled.
+	WALSegments []int `json:"walSegments"`
+
+	// IndexPartitions is the number of series written to each partition of
+	// the index.
+	IndexPartitions map[int]int `json:"indexPartitions"`
+}
+
+type writeTraceKey struct{}
+
+// WithWriteTrace returns a context recording the writes of the engine to t.
+func WithWriteTrace(ctx context.Context, t *WriteTrace) context.Context {
+	return context.WithValue(ctx, writeTraceKey{}, t)
+}
+
+// WriteTraceFromContext returns the WriteTrace of the context, or nil.
+func WriteTraceFromContext(ctx context.Context) *WriteTrace {
+	t, _ := ctx.Value(writeTraceKey{}).(*WriteTrace)
+	return t
+}
+
+// addWALSegment records that points were appended to the WAL segment id.
+func (t *WriteTrace) addWALSegment(id int) {
+	for _, v := range t.WALSegments {
+		if v == id {
+			return
+		}
+	}
+	t.WALSegments = append(t.WALSegments, id)
+}
@@ -64,6 +64,11 @@ type Task struct {
 	CreatedAt       time.Time              `json:"createdAt,omitempty"`
 	UpdatedAt       time.Time              `json:"updatedAt,omitempty"`
 	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+
+	// DependsOn lists the tasks of the same organization whose successful
+	// runs trigger the task, instead of its every or cron option. The task
+	// runs once all of them completed a run for the same scheduled time.
+	DependsOn []ID `json:"dependsOn,omitempty"`
 }
 
 // EffectiveCron returns the effective cron string of the options.
@@ -154,6 +159,7 @@ type TaskCreate struct {
 	Organization   string                 `json:"org,omitempty"`
 	OwnerID        ID                     `json:"-"`
 	Metadata       map[string]interface{} `json:"-"` // not to be set through a web request but rather used by a http service using tasks backend.
+	DependsOn      []ID                   `json:"dependsOn,omitempty"`
 }
 
 func (t TaskCreate) Validate() error {
@@ -165,6 +171,22 @@ func (t TaskCreate) Validate() error {
 	case t.Status != "" && t.Status != TaskStatusActive && t.Status != TaskStatusInactive:
 		return fmt.Errorf("invalid task status: %q", t.Status)
 	}
+	return validateTaskDependencies(t.DependsOn)
+}
+
+// validateTaskDependencies checks that the dependencies of a task are valid
+// and listed once.
+func validateTaskDependencies(ids []ID) error {
+	seen := make(map[ID]bool, len(ids))
+	for _, id := range ids {
+		if !id.Valid() {
+			return errors.New("invalid task dependency id")
+		}
+		if seen[id] {
+			return fmt.Errorf("task dependency %s is listed more than once", id)
+		}
+		seen[id] = true
+	}
 	return nil
 }
 
@@ -181,6 +203,10 @@ type TaskUpdate struct {
 	LastRunError    *string                `json:"-"`
 	Metadata        map[string]interface{} `json:"-"` // not to be set through a web request but rather used by a http service using tasks backend.
 
+	// DependsOn replaces the dependencies of the task. An empty list removes
+	// them, so that the task is scheduled by its every or cron option again.
+	DependsOn *[]ID `json:"dependsOn,omitempty"`
+
 	// Options gets unmarshalled from json as if it was flat, with the same level as Flux and Status.
 	Options options.Options // when we unmarshal this gets unmarshalled from flat key-values
 }
@@ -207,6 +233,8 @@ func (t *TaskUpdate) UnmarshalJSON(data []byte) error {
 		Concurrency *int64 `json:"concurrency,omitempty"`
 
 		Retry *int64 `json:"retry,omitempty"`
+
+		DependsOn *[]ID `json:"dependsOn,omitempty"`
 	}{}
 
 	if err := json.Unmarshal(data, &jo); err != nil {
@@ -224,6 +252,7 @@ func (t *TaskUpdate) UnmarshalJSON(data []byte) error {
 	t.Options.Retry = jo.Retry
 	t.Flux = jo.Flux
 	t.Status = jo.Status
+	t.DependsOn = jo.DependsOn
 	return nil
 }
 
@@ -246,6 +275,8 @@ func (t *TaskUpdate) MarshalJSON() ([]byte, error) {
 		Concurrency *int64 `json:"concurrency,omitempty"`
 
 		Retry *int64 `json:"retry,omitempty"`
+
+		DependsOn *[]ID `json:"dependsOn,omitempty"`
 	}{}
 	jo.Name = t.Options.Name
 	jo.Cron = t.Options.Cron
@@ -259,6 +290,7 @@ func (t *TaskUpdate) MarshalJSON() ([]byte, error) {
 	jo.Retry = t.Options.Retry
 	jo.Flux = t.Flux
 	jo.Status = t.Status
+	jo.DependsOn = t.DependsOn
 	return json.Marshal(jo)
 }
 
@@ -274,11 +306,14 @@ func (t *TaskUpdate) Validate() error {
 		if _, err := time.ParseDuration(t.Options.Offset.String()); err != nil {
 			return fmt.Errorf("offset: %s, %s is invalid, the largest unit supported is h", t.Options.Offset.String(), err)
 		}
-	case t.Flux == nil && t.Status == nil && t.Options.IsZero():
+	case t.Flux == nil && t.Status == nil && t.DependsOn == nil && t.Options.IsZero():
 		return errors.New("cannot update task without content")
 	case t.Status != nil && *t.Status != TaskStatusActive && *t.Status != TaskStatusInactive:
 		return fmt.Errorf("invalid task status: %q", *t.Status)
 	}
+	if t.DependsOn != nil {
+		return validateTaskDependencies(*t.DependsOn)
+	}
 	return nil
 }
 
@@ -418,6 +453,8 @@ type TaskFilter struct {
 	User           *ID
 	Limit          int
 	Status         *string
+	// DependsOn restricts the tasks to those depending on this task.
+	DependsOn *ID
 }
 
 // QueryParams Converts TaskFilter fields to url query params.
@@ -443,9 +480,35 @@ func (f TaskFilter) QueryParams() map[string][]string {
 		qp["limit"] = []string{strconv.Itoa(f.Limit)}
 	}
 
+	if f.DependsOn != nil {
+		qp["dependsOn"] = []string{f.DependsOn.String()}
+	}
+
 	return qp
 }
 
+// TaskGraph is the dependency graph of the tasks of a pipeline, the tasks
+// connected to a task through their dependencies.
+type TaskGraph struct {
+	Nodes []TaskGraphNode `json:"nodes"`
+	// Edges go from a task to a task depending on it.
+	Edges []TaskGraphEdge `json:"edges"`
+}
+
+// TaskGraphNode is a task of a TaskGraph.
+type TaskGraphNode struct {
+	ID            ID     `json:"id"`
+	Name          string `json:"name"`
+	Status        string `json:"status"`
+	LastRunStatus string `json:"lastRunStatus,omitempty"`
+}
+
+// TaskGraphEdge is a dependency of a TaskGraph, From triggering To.
+type TaskGraphEdge struct {
+	From ID `json:"from"`
+	To   ID `json:"to"`
+}
+
 // RunFilter represents a set of filters that restrict the returned results
 type RunFilter struct {
 	// Task ID is required for listing runs.
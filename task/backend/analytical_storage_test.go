@@ -166,7 +166,7 @@ func newAnalyticalBackend(t *testing.T, orgSvc influxdb.OrganizationService, buc
 
 	// TODO(adam): do we need a proper secret service here?
 	reader := reads.NewReader(readservice.NewStore(engine))
-	deps, err := stdlib.NewDependencies(reader, engine, bucketSvc, orgSvc, nil, nil)
+	deps, err := stdlib.NewDependencies(reader, engine, nil, bucketSvc, orgSvc, nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -106,8 +106,14 @@ func NewCoordinator(log *zap.Logger, scheduler scheduler.Scheduler, executor Exe
 	return c
 }
 
-// TaskCreated asks the Scheduler to schedule the newly created task
+// TaskCreated asks the Scheduler to schedule the newly created task.
+// Tasks with dependencies are not scheduled, as they are triggered by the
+// runs of the tasks they depend on.
 func (c *Coordinator) TaskCreated(ctx context.Context, task *influxdb.Task) error {
+	if len(task.DependsOn) > 0 {
+		return nil
+	}
+
 	t, err := NewSchedulableTask(task)
 
 	if err != nil {
@@ -122,7 +128,8 @@ func (c *Coordinator) TaskCreated(ctx context.Context, task *influxdb.Task) erro
 	return nil
 }
 
-// TaskUpdated releases the task if it is being disabled, and schedules it otherwise
+// TaskUpdated releases the task if it is being disabled or now has
+// dependencies, and schedules it otherwise
 func (c *Coordinator) TaskUpdated(ctx context.Context, from, to *influxdb.Task) error {
 	sid := scheduler.ID(to.ID)
 	t, err := NewSchedulableTask(to)
@@ -130,8 +137,9 @@ func (c *Coordinator) TaskUpdated(ctx context.Context, from, to *influxdb.Task)
 		return err
 	}
 
-	// if disabling the task, release it before schedule update
-	if to.Status != from.Status && to.Status == string(backend.TaskInactive) {
+	// if disabling the task, or if it is now triggered by its dependencies,
+	// release it before schedule update
+	if (to.Status != from.Status && to.Status == string(backend.TaskInactive)) || len(to.DependsOn) > 0 {
 		if err := c.sch.Release(sid); err != nil && err != influxdb.ErrTaskNotClaimed {
 			return err
 		}
@@ -0,0 +1,81 @@
+package executor
+
+import (
+	"context"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/task/backend"
+	"go.uber.org/zap"
+)
+
+// triggerDependents starts a run of each active task depending on the task,
+// for the time of its successful run scheduledFor, once every task it depends
+// on has successfully completed a run for that time.
+func (e *Executor) triggerDependents(ctx context.Context, task *influxdb.Task, scheduledFor time.Time) {
+	// dependents sharing several dependencies must only be triggered once.
+	e.triggerMu.Lock()
+	defer e.triggerMu.Unlock()
+
+	active := influxdb.TaskStatusActive
+	dependents, _, err := e.ts.FindTasks(ctx, influxdb.TaskFilter{
+		OrganizationID: &task.OrganizationID,
+		DependsOn:      &task.ID,
+		Status:         &active,
+		Limit:          influxdb.TaskMaxPageSize,
+	})
+	if err != nil {
+		e.log.Error("Failed to find dependent tasks", zap.String("taskID", task.ID.String()), zap.Error(err))
+		return
+	}
+
+	for _, dep := range dependents {
+		if !dep.LatestScheduled.Before(scheduledFor) {
+			// already triggered for this time.
+			continue
+		}
+
+		ready, err := e.dependenciesCompleted(ctx, dep, scheduledFor)
+		if err != nil {
+			e.log.Error("Failed to check task dependencies", zap.String("taskID", dep.ID.String()), zap.Error(err))
+			continue
+		}
+		if !ready {
+			continue
+		}
+
+		if _, err := e.ts.UpdateTask(ctx, dep.ID, influxdb.TaskUpdate{LatestScheduled: &scheduledFor}); err != nil {
+			e.log.Error("Failed to update dependent task", zap.String("taskID", dep.ID.String()), zap.Error(err))
+			continue
+		}
+
+		run, err := e.tcs.CreateRun(ctx, dep.ID, scheduledFor, time.Now().UTC())
+		if err != nil {
+			e.log.Error("Failed to create run of dependent task", zap.String("taskID", dep.ID.String()), zap.Error(err))
+			continue
+		}
+		e.tcs.AddRunLog(ctx, dep.ID, run.ID, time.Now().UTC(), "Triggered by the completion of task "+task.ID.String())
+
+		if _, err := e.createPromise(ctx, run); err != nil {
+			e.log.Error("Failed to execute dependent task", zap.String("taskID", dep.ID.String()), zap.Error(err))
+			continue
+		}
+		e.metrics.dependentRunsCounter.WithLabelValues(dep.ID.String()).Inc()
+		e.startWorker()
+	}
+}
+
+// dependenciesCompleted returns true if every task the task depends on has
+// successfully completed a run for the time scheduledFor, or a later one.
+func (e *Executor) dependenciesCompleted(ctx context.Context, task *influxdb.Task, scheduledFor time.Time) (bool, error) {
+	for _, id := range task.DependsOn {
+		dep, err := e.ts.FindTaskByID(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		if dep.LatestCompleted.Before(scheduledFor) || dep.LastRunStatus != backend.RunSuccess.String() {
+			return false, nil
+		}
+	}
+	return true, nil
+}
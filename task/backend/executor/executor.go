@@ -88,6 +88,10 @@ type Executor struct {
 	// keep a pool of execution workers.
 	workerPool  sync.Pool
 	workerLimit chan struct{}
+
+	// triggerMu serializes the triggering of the tasks depending on
+	// completed tasks.
+	triggerMu sync.Mutex
 }
 
 // SetLimitFunc sets the limit func for this task executor
@@ -353,6 +357,12 @@ func (w *worker) finish(p *promise, rs backend.RunStatus, err error) {
 
 	if _, err := w.e.tcs.FinishRun(p.ctx, p.task.ID, p.run.ID); err != nil {
 		w.e.log.Error("Failed to finish run", zap.String("taskID", p.task.ID.String()), zap.String("runID", p.run.ID.String()), zap.Error(err))
+		return
+	}
+
+	if rs == backend.RunSuccess {
+		// queueing the runs of the dependent tasks may block until workers are available.
+		go w.e.triggerDependents(p.ctx, p.task, p.run.ScheduledFor)
 	}
 }
 
@@ -15,6 +15,7 @@ type ExecutorMetrics struct {
 	runDuration          *prometheus.SummaryVec
 	errorsCounter        *prometheus.CounterVec
 	manualRunsCounter    *prometheus.CounterVec
+	dependentRunsCounter *prometheus.CounterVec
 	resumeRunsCounter    *prometheus.CounterVec
 	unrecoverableCounter *prometheus.CounterVec
 	runLatency           *prometheus.HistogramVec
@@ -78,6 +79,13 @@ func NewExecutorMetrics(ex *Executor) *ExecutorMetrics {
 			Help:      "Total number of manual runs scheduled to run by task ID",
 		}, []string{"taskID"}),
 
+		dependentRunsCounter: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "dependent_runs_counter",
+			Help:      "Total number of runs triggered by the completion of the tasks they depend on by task ID",
+		}, []string{"taskID"}),
+
 		resumeRunsCounter: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Namespace: namespace,
 			Subsystem: subsystem,
@@ -128,6 +136,7 @@ func (em *ExecutorMetrics) PrometheusCollectors() []prometheus.Collector {
 		em.errorsCounter,
 		em.runDuration,
 		em.manualRunsCounter,
+		em.dependentRunsCounter,
 		em.resumeRunsCounter,
 		em.unrecoverableCounter,
 		em.runLatency,
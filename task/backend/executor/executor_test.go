@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/influxdata/flux"
 	"github.com/influxdata/influxdb"
 	icontext "github.com/influxdata/influxdb/context"
@@ -76,6 +77,79 @@ func TestTaskExecutor(t *testing.T) {
 	t.Run("Metrics", testMetrics)
 	t.Run("IteratorFailure", testIteratorFailure)
 	t.Run("ErrorHandling", testErrorHandling)
+	t.Run("Dependencies", testDependencies)
+}
+
+func testDependencies(t *testing.T) {
+	t.Parallel()
+
+	// the tasks are created before the time of the upstream run.
+	c := clock.NewMock()
+	c.Set(time.Unix(100, 0))
+
+	var (
+		aqs = newFakeQueryService()
+		qs  = query.QueryServiceBridge{AsyncQueryService: aqs}
+		i   = kv.NewService(zaptest.NewLogger(t), inmem.NewKVStore(), kv.ServiceConfig{Clock: c})
+		tcs = &taskControlService{TaskControlService: i}
+	)
+	ex, _ := NewExecutor(zaptest.NewLogger(t), qs, i, i, tcs)
+	tc := createCreds(t, i)
+
+	var (
+		ctx          = icontext.SetAuthorizer(context.Background(), tc.Auth)
+		scriptA      = fmt.Sprintf(fmtTestScript, t.Name()+"-a")
+		scriptB      = fmt.Sprintf(fmtTestScript, t.Name()+"-b")
+		scriptC      = fmt.Sprintf(fmtTestScript, t.Name()+"-c")
+		scheduledFor = time.Unix(123, 0).UTC()
+	)
+
+	a, err := i.CreateTask(ctx, influxdb.TaskCreate{OrganizationID: tc.OrgID, OwnerID: tc.Auth.GetUserID(), Flux: scriptA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := i.CreateTask(ctx, influxdb.TaskCreate{OrganizationID: tc.OrgID, OwnerID: tc.Auth.GetUserID(), Flux: scriptB, DependsOn: []influxdb.ID{a.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// c also depends on b, so it runs after it.
+	cTask, err := i.CreateTask(ctx, influxdb.TaskCreate{OrganizationID: tc.OrgID, OwnerID: tc.Auth.GetUserID(), Flux: scriptC, DependsOn: []influxdb.ID{a.ID, b.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	promise, err := ex.PromisedExecute(ctx, scheduler.ID(a.ID), scheduledFor, scheduledFor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aqs.WaitForQueryLive(t, scriptA)
+	aqs.SucceedQuery(scriptA)
+	<-promise.Done()
+	if err := promise.Error(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the completion of a triggers b, but not c which waits for b.
+	aqs.WaitForQueryLive(t, scriptB)
+	aqs.SucceedQuery(scriptB)
+	aqs.WaitForQueryLive(t, scriptC)
+	aqs.SucceedQuery(scriptC)
+
+	for _, id := range []influxdb.ID{b.ID, cTask.ID} {
+		var task *influxdb.Task
+		for attempt := 0; attempt < 50; attempt++ {
+			if task, err = i.FindTaskByID(ctx, id); err != nil {
+				t.Fatal(err)
+			}
+			if task.LatestCompleted.Equal(scheduledFor) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if !task.LatestCompleted.Equal(scheduledFor) || task.LastRunStatus != backend.RunSuccess.String() {
+			t.Fatalf("expected task %s to complete a run for %v, got %v (%s)", id, scheduledFor, task.LatestCompleted, task.LastRunStatus)
+		}
+	}
 }
 
 func testQuerySuccess(t *testing.T) {
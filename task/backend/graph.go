@@ -0,0 +1,78 @@
+package backend
+
+import (
+	"context"
+	"sort"
+
+	"github.com/influxdata/influxdb"
+)
+
+// FindTaskGraph returns the dependency graph of the pipeline of the task, the
+// tasks it depends on and the tasks depending on it, directly or through
+// other tasks.
+func FindTaskGraph(ctx context.Context, ts influxdb.TaskService, id influxdb.ID) (*influxdb.TaskGraph, error) {
+	task, err := ts.FindTaskByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		graph = &influxdb.TaskGraph{Nodes: []influxdb.TaskGraphNode{}, Edges: []influxdb.TaskGraphEdge{}}
+		tasks = map[influxdb.ID]*influxdb.Task{task.ID: task}
+		edges = make(map[influxdb.TaskGraphEdge]bool)
+		next  = []*influxdb.Task{task}
+	)
+	for len(next) > 0 {
+		t := next[len(next)-1]
+		next = next[:len(next)-1]
+		graph.Nodes = append(graph.Nodes, influxdb.TaskGraphNode{
+			ID:            t.ID,
+			Name:          t.Name,
+			Status:        t.Status,
+			LastRunStatus: t.LastRunStatus,
+		})
+
+		for _, upstream := range t.DependsOn {
+			edges[influxdb.TaskGraphEdge{From: upstream, To: t.ID}] = true
+			if _, ok := tasks[upstream]; ok {
+				continue
+			}
+			u, err := ts.FindTaskByID(ctx, upstream)
+			if err != nil {
+				return nil, err
+			}
+			tasks[u.ID] = u
+			next = append(next, u)
+		}
+
+		downstream, _, err := ts.FindTasks(ctx, influxdb.TaskFilter{
+			OrganizationID: &t.OrganizationID,
+			DependsOn:      &t.ID,
+			Limit:          influxdb.TaskMaxPageSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range downstream {
+			edges[influxdb.TaskGraphEdge{From: t.ID, To: d.ID}] = true
+			if _, ok := tasks[d.ID]; ok {
+				continue
+			}
+			tasks[d.ID] = d
+			next = append(next, d)
+		}
+	}
+
+	for e := range edges {
+		graph.Edges = append(graph.Edges, e)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool { return graph.Nodes[i].ID < graph.Nodes[j].ID })
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		a, b := graph.Edges[i], graph.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	return graph, nil
+}
@@ -0,0 +1,307 @@
+package backend
+
+import (
+	"strings"
+	"time"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/flux/ast"
+	"github.com/influxdata/flux/ast/edit"
+	"github.com/influxdata/influxdb"
+)
+
+// PreviewAST returns the AST of the task script with its writes removed and
+// the v.timeRangeStart and v.timeRangeStop options set to start and stop,
+// so that executing it returns the tables a run would have written.
+//
+// The statuses of checks and the notifications of notification rules are
+// returned rather than written to the monitoring bucket, and notifications
+// are not sent. A script with other side effects, such as an http.post()
+// call, can not be previewed and returns ErrTaskPreviewSideEffect.
+func PreviewAST(script string, start, stop time.Time) (*ast.Package, error) {
+	pkg, err := flux.Parse(script)
+	if err != nil {
+		return nil, influxdb.ErrFluxParseError(err)
+	}
+
+	for _, f := range pkg.Files {
+		removeWrites(f)
+		imports := fileImports(f)
+		removeNotifications(f, imports)
+		if err := checkSideEffects(f, imports); err != nil {
+			return nil, err
+		}
+		disableMonitorWrites(f, imports)
+	}
+
+	file := pkg.Files[len(pkg.Files)-1]
+	rng := map[string]ast.Expression{
+		"timeRangeStart": &ast.DateTimeLiteral{Value: start.UTC()},
+		"timeRangeStop":  &ast.DateTimeLiteral{Value: stop.UTC()},
+	}
+	found, err := edit.Option(file, "v", edit.OptionObjectFn(rng))
+	if err != nil {
+		return nil, influxdb.ErrFluxParseError(err)
+	}
+	if !found {
+		obj := &ast.ObjectExpression{}
+		for _, k := range []string{"timeRangeStart", "timeRangeStop"} {
+			obj.Properties = append(obj.Properties, &ast.Property{
+				Key:   &ast.Identifier{Name: k},
+				Value: rng[k],
+			})
+		}
+		opt := &ast.OptionStatement{
+			Assignment: &ast.VariableAssignment{
+				ID:   &ast.Identifier{Name: "v"},
+				Init: obj,
+			},
+		}
+		file.Body = append([]ast.Statement{opt}, file.Body...)
+	}
+	return pkg, nil
+}
+
+// removeWrites replaces every write call piped into by its input,
+// turning `data |> to(...)` into `data`.
+func removeWrites(node ast.Node) {
+	ast.Walk(ast.CreateVisitor(func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.ExpressionStatement:
+			n.Expression = unwrapWrites(n.Expression)
+		case *ast.ReturnStatement:
+			n.Argument = unwrapWrites(n.Argument)
+		case *ast.VariableAssignment:
+			n.Init = unwrapWrites(n.Init)
+		case *ast.MemberAssignment:
+			n.Init = unwrapWrites(n.Init)
+		case *ast.PipeExpression:
+			n.Argument = unwrapWrites(n.Argument)
+		case *ast.Property:
+			n.Value = unwrapWrites(n.Value)
+		case *ast.ArrayExpression:
+			for i, e := range n.Elements {
+				n.Elements[i] = unwrapWrites(e)
+			}
+		case *ast.ConditionalExpression:
+			n.Consequent = unwrapWrites(n.Consequent)
+			n.Alternate = unwrapWrites(n.Alternate)
+		case *ast.ParenExpression:
+			n.Expression = unwrapWrites(n.Expression)
+		case *ast.FunctionExpression:
+			if e, ok := n.Body.(ast.Expression); ok {
+				n.Body = unwrapWrites(e)
+			}
+		}
+	}), node)
+}
+
+// unwrapWrites returns the input of e while e pipes into a write call.
+func unwrapWrites(e ast.Expression) ast.Expression {
+	for {
+		p, ok := e.(*ast.PipeExpression)
+		if !ok || !isWriteCall(p.Call) {
+			return e
+		}
+		e = p.Argument
+	}
+}
+
+// isWriteCall reports whether call is a call to to() or a to() function
+// of an imported package, such as experimental.to().
+func isWriteCall(call *ast.CallExpression) bool {
+	if call == nil {
+		return false
+	}
+	switch callee := call.Callee.(type) {
+	case *ast.Identifier:
+		return callee.Name == "to"
+	case *ast.MemberExpression:
+		return callee.Property != nil && callee.Property.Key() == "to"
+	}
+	return false
+}
+
+const monitorPackage = "influxdata/influxdb/monitor"
+
+// sideEffects holds, by import path, the functions of the packages sending
+// data to external services. The endpoint functions return transformations
+// sending notifications, which are removed from the monitor.notify() calls.
+var sideEffects = map[string][]string{
+	"http":      {"post", "endpoint"},
+	"slack":     {"message", "endpoint"},
+	"pagerduty": {"sendEvent", "endpoint"},
+}
+
+// fileImports returns the import paths of f by package name.
+func fileImports(f *ast.File) map[string]string {
+	imports := make(map[string]string, len(f.Imports))
+	for _, imp := range f.Imports {
+		path := imp.Path.Value
+		name := path[strings.LastIndex(path, "/")+1:]
+		if imp.As != nil {
+			name = imp.As.Name
+		}
+		imports[name] = path
+	}
+	return imports
+}
+
+// packageMember returns the import path of the package and the name of the
+// member e refers to, if e is a member of an imported package.
+func packageMember(imports map[string]string, e ast.Expression) (path, name string, ok bool) {
+	m, ok := e.(*ast.MemberExpression)
+	if !ok || m.Property == nil {
+		return "", "", false
+	}
+	id, ok := m.Object.(*ast.Identifier)
+	if !ok {
+		return "", "", false
+	}
+	path, ok = imports[id.Name]
+	return path, m.Property.Key(), ok
+}
+
+// removeNotifications replaces the endpoint of every monitor.notify() call
+// by one marking the notifications as not sent, without sending them.
+func removeNotifications(f *ast.File, imports map[string]string) {
+	ast.Walk(ast.CreateVisitor(func(n ast.Node) {
+		call, ok := n.(*ast.CallExpression)
+		if !ok || len(call.Arguments) == 0 {
+			return
+		}
+		if path, name, ok := packageMember(imports, call.Callee); !ok || path != monitorPackage || name != "notify" {
+			return
+		}
+		args, ok := call.Arguments[0].(*ast.ObjectExpression)
+		if !ok {
+			return
+		}
+		for _, p := range args.Properties {
+			if p.Key != nil && p.Key.Key() == "endpoint" {
+				p.Value = unsentEndpoint()
+			}
+		}
+	}), f)
+}
+
+// unsentEndpoint returns the notification endpoint
+// `(tables=<-) => tables |> map(fn: (r) => ({r with _sent: "false"}))`.
+func unsentEndpoint() ast.Expression {
+	r := &ast.Identifier{Name: "r"}
+	return &ast.FunctionExpression{
+		Params: []*ast.Property{{Key: &ast.Identifier{Name: "tables"}, Value: &ast.PipeLiteral{}}},
+		Body: &ast.PipeExpression{
+			Argument: &ast.Identifier{Name: "tables"},
+			Call: &ast.CallExpression{
+				Callee: &ast.Identifier{Name: "map"},
+				Arguments: []ast.Expression{&ast.ObjectExpression{
+					Properties: []*ast.Property{{
+						Key: &ast.Identifier{Name: "fn"},
+						Value: &ast.FunctionExpression{
+							Params: []*ast.Property{{Key: r}},
+							Body: &ast.ParenExpression{Expression: &ast.ObjectExpression{
+								With: r,
+								Properties: []*ast.Property{{
+									Key:   &ast.Identifier{Name: "_sent"},
+									Value: &ast.StringLiteral{Value: "false"},
+								}},
+							}},
+						},
+					}},
+				}},
+			},
+		},
+	}
+}
+
+// checkSideEffects returns ErrTaskPreviewSideEffect if f refers to a function
+// with side effects, or calls a notification endpoint, once its notifications
+// are removed.
+func checkSideEffects(f *ast.File, imports map[string]string) error {
+	// endpoints holds the variables assigned a notification endpoint.
+	endpoints := make(map[string]string)
+	var err error
+	ast.Walk(ast.CreateVisitor(func(n ast.Node) {
+		if err != nil {
+			return
+		}
+		switch n := n.(type) {
+		case *ast.VariableAssignment:
+			if call, ok := n.Init.(*ast.CallExpression); ok {
+				if path, name, ok := packageMember(imports, call.Callee); ok && name == "endpoint" && isSideEffect(path, name) {
+					endpoints[n.ID.Name] = path + "." + name
+				}
+			}
+		case *ast.CallExpression:
+			if id, ok := n.Callee.(*ast.Identifier); ok {
+				if fn, ok := endpoints[id.Name]; ok {
+					err = influxdb.ErrTaskPreviewSideEffect(fn)
+				}
+			}
+			if call, ok := n.Callee.(*ast.CallExpression); ok {
+				if path, name, ok := packageMember(imports, call.Callee); ok && isSideEffect(path, name) {
+					err = influxdb.ErrTaskPreviewSideEffect(path + "." + name)
+				}
+			}
+		case *ast.MemberExpression:
+			if path, name, ok := packageMember(imports, n); ok && name != "endpoint" && isSideEffect(path, name) {
+				err = influxdb.ErrTaskPreviewSideEffect(path + "." + name)
+			}
+		}
+	}), f)
+	return err
+}
+
+func isSideEffect(path, name string) bool {
+	for _, fn := range sideEffects[path] {
+		if fn == name {
+			return true
+		}
+	}
+	return false
+}
+
+// disableMonitorWrites sets the write and log options of the monitor package,
+// if f imports it, to functions returning their input, so that monitor.check()
+// and monitor.notify() return the statuses and notifications they would have
+// written to the monitoring bucket.
+func disableMonitorWrites(f *ast.File, imports map[string]string) {
+	var monitor string
+	for name, path := range imports {
+		if path == monitorPackage {
+			monitor = name
+		}
+	}
+	if monitor == "" {
+		return
+	}
+
+	body := make([]ast.Statement, 0, len(f.Body)+2)
+	for _, opt := range []string{"write", "log"} {
+		body = append(body, &ast.OptionStatement{
+			Assignment: &ast.MemberAssignment{
+				Member: &ast.MemberExpression{
+					Object:   &ast.Identifier{Name: monitor},
+					Property: &ast.Identifier{Name: opt},
+				},
+				Init: &ast.FunctionExpression{
+					Params: []*ast.Property{{Key: &ast.Identifier{Name: "tables"}, Value: &ast.PipeLiteral{}}},
+					Body:   &ast.Identifier{Name: "tables"},
+				},
+			},
+		})
+	}
+	// Drop the options of the script setting them, as they would apply last.
+	for _, s := range f.Body {
+		if opt, ok := s.(*ast.OptionStatement); ok {
+			if a, ok := opt.Assignment.(*ast.MemberAssignment); ok {
+				if path, name, ok := packageMember(imports, a.Member); ok && path == monitorPackage && (name == "write" || name == "log") {
+					continue
+				}
+			}
+		}
+		body = append(body, s)
+	}
+	f.Body = body
+}
@@ -0,0 +1,174 @@
+package backend_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/flux/ast"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/task/backend"
+)
+
+func TestPreviewAST(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	stop := start.Add(time.Hour)
+
+	for _, tt := range []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{
+			name: "writes removed",
+			script: `option task = {name: "t", every: 1h}
+from(bucket: "b") |> range(start: -task.every) |> to(bucket: "out") |> yield()`,
+			want: `option v = {timeRangeStart: 2020-01-01T00:00:00Z, timeRangeStop: 2020-01-01T01:00:00Z}
+option task = {name: "t", every: 1h}
+
+from(bucket: "b")
+	|> range(start: -task.every)
+	|> yield()`,
+		},
+		{
+			name: "package writes and assignments",
+			script: `import "experimental"
+option task = {name: "t", every: 1h}
+data = from(bucket: "b") |> range(start: v.timeRangeStart, stop: v.timeRangeStop) |> experimental.to(bucket: "out")
+data |> to(bucket: "out")`,
+			want: `import "experimental"
+
+option v = {timeRangeStart: 2020-01-01T00:00:00Z, timeRangeStop: 2020-01-01T01:00:00Z}
+option task = {name: "t", every: 1h}
+
+data = from(bucket: "b")
+	|> range(start: v.timeRangeStart, stop: v.timeRangeStop)
+
+data`,
+		},
+		{
+			name: "check statuses returned",
+			script: `import "influxdata/influxdb/monitor"
+import "influxdata/influxdb/v1"
+option task = {name: "cpu", every: 1m}
+check = {_check_id: "000000000000000a", _check_name: "cpu", _type: "threshold", tags: {}}
+from(bucket: "telegraf") |> range(start: -task.every) |> v1.fieldsAsCols() |> monitor.check(data: check, messageFn: (r) => "cpu", crit: (r) => r.usage_idle < 5.0)`,
+			want: `import "influxdata/influxdb/monitor"
+import "influxdata/influxdb/v1"
+
+option v = {timeRangeStart: 2020-01-01T00:00:00Z, timeRangeStop: 2020-01-01T01:00:00Z}
+option monitor.write = (tables=<-) =>
+	(tables)
+option monitor.log = (tables=<-) =>
+	(tables)
+option task = {name: "cpu", every: 1m}
+
+check = {
+	_check_id: "000000000000000a",
+	_check_name: "cpu",
+	_type: "threshold",
+	tags: {},
+}
+
+from(bucket: "telegraf")
+	|> range(start: -task.every)
+	|> v1.fieldsAsCols()
+	|> monitor.check(data: check, messageFn: (r) =>
+		("cpu"), crit: (r) =>
+		(r.usage_idle < 5.0))`,
+		},
+		{
+			name: "notifications not sent",
+			script: `import m "influxdata/influxdb/monitor"
+import "slack"
+option task = {name: "rule", every: 1h}
+option m.log = (tables=<-) => tables |> to(bucket: "log")
+slack_endpoint = slack.endpoint(url: "http://localhost:7777")
+notification = {_notification_rule_id: "0000000000000001"}
+m.from(start: -2h) |> m.notify(data: notification, endpoint: slack_endpoint(mapFn: (r) => ({channel: "alerts", text: r._message, color: "danger"})))`,
+			want: `import m "influxdata/influxdb/monitor"
+import "slack"
+
+option v = {timeRangeStart: 2020-01-01T00:00:00Z, timeRangeStop: 2020-01-01T01:00:00Z}
+option m.write = (tables=<-) =>
+	(tables)
+option m.log = (tables=<-) =>
+	(tables)
+option task = {name: "rule", every: 1h}
+
+slack_endpoint = slack.endpoint(url: "http://localhost:7777")
+notification = {_notification_rule_id: "0000000000000001"}
+
+m.from(start: -2h)
+	|> m.notify(data: notification, endpoint: (tables=<-) =>
+		(tables
+			|> map(fn: (r) =>
+				({r with _sent: "false"}))))`,
+		},
+		{
+			name: "existing range option",
+			script: `option v = {timeRangeStart: -1d, timeRangeStop: now(), windowPeriod: 1m}
+option task = {name: "t", every: 1h}
+from(bucket: "b") |> range(start: v.timeRangeStart)`,
+			want: `option v = {timeRangeStart: 2020-01-01T00:00:00Z, timeRangeStop: 2020-01-01T01:00:00Z, windowPeriod: 1m}
+option task = {name: "t", every: 1h}
+
+from(bucket: "b")
+	|> range(start: v.timeRangeStart)`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg, err := backend.PreviewAST(tt.script, start, stop)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSpace(ast.Format(pkg.Files[0])); got != tt.want {
+				t.Fatalf("unexpected script:\ngot:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := backend.PreviewAST("from(", start, stop); err == nil {
+		t.Fatal("expected a parse error")
+	}
+}
+
+func TestPreviewAST_SideEffects(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	stop := start.Add(time.Hour)
+
+	for _, tt := range []struct {
+		name   string
+		script string
+	}{
+		{
+			name: "http post",
+			script: `import "http"
+from(bucket: "b") |> range(start: -1h) |> map(fn: (r) => ({r with status: http.post(url: "http://localhost", data: bytes(v: r._value))}))`,
+		},
+		{
+			name: "slack message",
+			script: `import s "slack"
+send = s.message
+from(bucket: "b") |> range(start: -1h) |> map(fn: (r) => ({r with status: send(channel: "alerts", text: "", color: "good")}))`,
+		},
+		{
+			name: "endpoint outside notify",
+			script: `import "pagerduty"
+e = pagerduty.endpoint()
+from(bucket: "b") |> range(start: -1h) |> e(mapFn: (r) => ({routingKey: "k"}))()`,
+		},
+		{
+			name: "endpoint called directly",
+			script: `import "http"
+from(bucket: "b") |> range(start: -1h) |> http.endpoint(url: "http://localhost")(mapFn: (r) => ({data: bytes(v: "")}))()`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := backend.PreviewAST(tt.script, start, stop)
+			if influxdb.ErrorCode(err) != influxdb.EInvalid {
+				t.Fatalf("expected an invalid error, got %v", err)
+			}
+		})
+	}
+}
@@ -67,6 +67,20 @@ var (
 		Code: ENotFound,
 	}
 
+	// ErrTaskDependencyCycle is returned when the dependencies of a task
+	// would make it depend on itself.
+	ErrTaskDependencyCycle = &Error{
+		Code: EInvalid,
+		Msg:  "task dependencies can not form a cycle",
+	}
+
+	// ErrTaskHasDependents is returned when deleting a task other tasks
+	// depend on.
+	ErrTaskHasDependents = &Error{
+		Code: EConflict,
+		Msg:  "task is a dependency of other tasks",
+	}
+
 	ErrTaskRunAlreadyQueued = &Error{
 		Msg:  "run already queued",
 		Code: EConflict,
@@ -95,6 +109,16 @@ func ErrFluxParseError(err error) *Error {
 	}
 }
 
+// ErrTaskPreviewSideEffect is returned when previewing a task calling fn, a
+// function with side effects a preview can not remove from its script.
+func ErrTaskPreviewSideEffect(fn string) *Error {
+	return &Error{
+		Code: EInvalid,
+		Msg:  fmt.Sprintf("task can not be previewed as it calls %s, which has side effects", fn),
+		Op:   "taskPreview",
+	}
+}
+
 // ErrQueryError is returned when an error is thrown by Query service in the task executor
 func ErrQueryError(err error) *Error {
 	return &Error{
@@ -153,6 +177,16 @@ func ErrTaskOptionParse(err error) *Error {
 	}
 }
 
+// ErrInvalidTaskDependency is returned when a task depends on a task that
+// does not exist or belongs to another organization.
+func ErrInvalidTaskDependency(id ID) *Error {
+	return &Error{
+		Code: EInvalid,
+		Msg:  fmt.Sprintf("task dependency %s not found in the organization of the task", id),
+		Op:   "taskDependencies",
+	}
+}
+
 func ErrJsonMarshalError(err error) *Error {
 	return &Error{
 		Code: EInvalid,
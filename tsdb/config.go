@@ -18,11 +18,16 @@ type Config struct {
 	// LargeSeriesWriteThreshold is the threshold before a write requires
 	// preallocation to improve throughput. Currently used in the series file.
 	LargeSeriesWriteThreshold int `toml:"large-series-write-threshold"`
+
+	// SeriesIDAllocation is the strategy used to create new series in the
+	// series file, either sequential or batched.
+	SeriesIDAllocation SeriesIDAllocation `toml:"series-id-allocation"`
 }
 
 // NewConfig return a new instance of config with default settings.
 func NewConfig() Config {
 	return Config{
 		LargeSeriesWriteThreshold: DefaultLargeSeriesWriteThreshold,
+		SeriesIDAllocation:        DefaultSeriesIDAllocation,
 	}
 }
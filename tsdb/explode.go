@@ -89,6 +89,12 @@ func ExplodePoints(org, bucket platform.ID, points []models.Point) ([]models.Poi
 				field[string(itr.FieldKey())] = itr.StringValue()
 			case models.Unsigned:
 				field[string(itr.FieldKey())], err = itr.UnsignedValue()
+			case models.Decimal:
+				// Decimals are stored as strings, which keep them exact.
+				var d models.FixedDecimal
+				if d, err = itr.DecimalValue(); err == nil {
+					field[string(itr.FieldKey())] = d.String()
+				}
 			}
 			if err != nil {
 				return nil, err
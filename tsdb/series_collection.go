@@ -55,7 +55,12 @@ func NewSeriesCollection(points []models.Point) *SeriesCollection {
 
 		fi := pt.FieldIterator()
 		fi.Next()
-		out.Types = append(out.Types, fi.Type())
+		typ := fi.Type()
+		if typ == models.Decimal {
+			// Decimals are stored as strings.
+			typ = models.String
+		}
+		out.Types = append(out.Types, typ)
 	}
 
 	return out
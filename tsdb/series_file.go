@@ -34,6 +34,28 @@ const (
 	SeriesFilePartitionN = 8
 )
 
+// SeriesIDAllocation is the strategy a series partition uses to assign IDs
+// to new series and persist them.
+type SeriesIDAllocation string
+
+const (
+	// SeriesIDAllocationSequential creates the new series of a batch one at a
+	// time under the write lock of their partition, looking each of them up
+	// again first.
+	SeriesIDAllocationSequential SeriesIDAllocation = "sequential"
+
+	// SeriesIDAllocationBatched encodes the entries of the new series of a
+	// batch before taking the write lock of their partition, under which
+	// their IDs are assigned in one go and the entries appended with a single
+	// flush. The series are only looked up again if other series were created
+	// in the partition meanwhile, which shortens the time the lock is held
+	// when many new series are created concurrently.
+	SeriesIDAllocationBatched SeriesIDAllocation = "batched"
+
+	// DefaultSeriesIDAllocation is the default strategy to create series.
+	DefaultSeriesIDAllocation = SeriesIDAllocationSequential
+)
+
 // SeriesFile represents the section of the index that holds series data.
 type SeriesFile struct {
 	mu  sync.Mutex // protects concurrent open and close
@@ -51,6 +73,9 @@ type SeriesFile struct {
 
 	LargeWriteThreshold int
 
+	// IDAllocation is the strategy used to create new series.
+	IDAllocation SeriesIDAllocation
+
 	Logger *zap.Logger
 }
 
@@ -62,6 +87,7 @@ func NewSeriesFile(path string) *SeriesFile {
 		Logger:         zap.NewNop(),
 
 		LargeWriteThreshold: DefaultLargeSeriesWriteThreshold,
+		IDAllocation:        DefaultSeriesIDAllocation,
 	}
 }
 
@@ -93,6 +119,12 @@ func (f *SeriesFile) Open(ctx context.Context) error {
 	if f.res.Opened() {
 		return errors.New("series file already opened")
 	}
+	switch f.IDAllocation {
+	case "", SeriesIDAllocationSequential, SeriesIDAllocationBatched:
+	default:
+		return fmt.Errorf("unknown series id allocation %q, must be %s or %s",
+			f.IDAllocation, SeriesIDAllocationSequential, SeriesIDAllocationBatched)
+	}
 
 	span, ctx := tracing.StartSpanFromContext(ctx)
 	defer span.Finish()
@@ -127,6 +159,7 @@ func (f *SeriesFile) Open(ctx context.Context) error {
 		// TODO(edd): These partition initialisation should be moved up to NewSeriesFile.
 		p := NewSeriesPartition(i, f.SeriesPartitionPath(i))
 		p.LargeWriteThreshold = f.LargeWriteThreshold
+		p.IDAllocation = f.IDAllocation
 		p.Logger = f.Logger.With(zap.Int("partition", p.ID()))
 
 		// For each series file index, rhh trackers are used to track the RHH Hashmap.
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"sync"
 	"testing"
 
 	"github.com/influxdata/influxdb/logger"
@@ -188,6 +189,99 @@ func TestSeriesFile_Type(t *testing.T) {
 	}
 }
 
+// Ensures that concurrent writers creating the same series get the same IDs,
+// with every allocation strategy.
+func TestSeriesFile_IDAllocation(t *testing.T) {
+	for _, allocation := range []tsdb.SeriesIDAllocation{tsdb.SeriesIDAllocationSequential, tsdb.SeriesIDAllocationBatched} {
+		t.Run(string(allocation), func(t *testing.T) {
+			sfile := NewSeriesFile()
+			sfile.IDAllocation = allocation
+			if err := sfile.Open(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			defer sfile.Close()
+
+			const writers, seriesN, batchSize = 8, 1000, 100
+			ids := make([][]tsdb.SeriesID, writers)
+			var wg sync.WaitGroup
+			for w := 0; w < writers; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					ids[w] = make([]tsdb.SeriesID, seriesN)
+					// Each writer creates the series in a different order.
+					for b := 0; b < seriesN/batchSize; b++ {
+						start := ((b + w) % (seriesN / batchSize)) * batchSize
+						collection := &tsdb.SeriesCollection{}
+						for i := start; i < start+batchSize; i++ {
+							collection.Names = append(collection.Names, []byte("cpu"))
+							collection.Tags = append(collection.Tags, models.NewTags(map[string]string{"host": fmt.Sprint(i)}))
+							collection.Types = append(collection.Types, models.Integer)
+						}
+						if err := sfile.CreateSeriesListIfNotExists(collection); err != nil {
+							t.Error(err)
+							return
+						}
+						copy(ids[w][start:], collection.SeriesIDs)
+					}
+				}(w)
+			}
+			wg.Wait()
+
+			seen := make(map[tsdb.SeriesID]int)
+			for i := 0; i < seriesN; i++ {
+				id := ids[0][i]
+				if id.IsZero() {
+					t.Fatalf("series %d not created", i)
+				}
+				for w := 1; w < writers; w++ {
+					if ids[w][i] != id {
+						t.Fatalf("series %d created with IDs %d and %d", i, id.RawID(), ids[w][i].RawID())
+					}
+				}
+				if j, ok := seen[id]; ok {
+					t.Fatalf("series %d and %d have the same ID %d", j, i, id.RawID())
+				}
+				seen[id] = i
+			}
+			if got, exp := sfile.SeriesCount(), uint64(seriesN); got != exp {
+				t.Fatalf("SeriesCount()=%d, expected %d", got, exp)
+			}
+
+			// A key repeated with another type in a batch is dropped.
+			collection := &tsdb.SeriesCollection{
+				Names: [][]byte{[]byte("mem"), []byte("mem"), []byte("disk")},
+				Tags:  []models.Tags{{}, {}, {}},
+				Types: []models.FieldType{models.Integer, models.Float, models.Integer},
+			}
+			if err := sfile.CreateSeriesListIfNotExists(collection); err != nil {
+				t.Fatal(err)
+			} else if err := collection.PartialWriteError(); err == nil {
+				t.Fatal("expected partial write error")
+			} else if collection.Length() != 2 || string(collection.Names[1]) != "disk" {
+				t.Fatalf("unexpected series remaining in collection: %q", collection.Names)
+			}
+
+			// New series do not reuse the IDs of existing series after a reopen.
+			if err := sfile.Reopen(); err != nil {
+				t.Fatal(err)
+			}
+			collection = &tsdb.SeriesCollection{
+				Names: [][]byte{[]byte("cpu"), []byte("new")},
+				Tags:  []models.Tags{models.NewTags(map[string]string{"host": "0"}), {}},
+				Types: []models.FieldType{models.Integer, models.Integer},
+			}
+			if err := sfile.CreateSeriesListIfNotExists(collection); err != nil {
+				t.Fatal(err)
+			} else if collection.SeriesIDs[0] != ids[0][0] {
+				t.Fatalf("unexpected ID %d after reopen, expected %d", collection.SeriesIDs[0].RawID(), ids[0][0].RawID())
+			} else if _, ok := seen[collection.SeriesIDs[1]]; ok {
+				t.Fatalf("ID %d reused", collection.SeriesIDs[1].RawID())
+			}
+		})
+	}
+}
+
 // Ensure series file deletions persist across compactions.
 func TestSeriesFile_DeleteSeriesID(t *testing.T) {
 	sfile := MustOpenSeriesFile()
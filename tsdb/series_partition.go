@@ -43,12 +43,14 @@ type SeriesPartition struct {
 	segments []*SeriesSegment
 	index    *SeriesIndex
 	seq      uint64 // series id sequence
+	inserts  uint64 // number of batches of series inserted
 
 	compacting          bool
 	compactionsDisabled int
 
 	CompactThreshold    int
 	LargeWriteThreshold int
+	IDAllocation        SeriesIDAllocation
 
 	tracker *seriesPartitionTracker
 	Logger  *zap.Logger
@@ -62,6 +64,7 @@ func NewSeriesPartition(id int, path string) *SeriesPartition {
 		closing:             make(chan struct{}),
 		CompactThreshold:    DefaultSeriesPartitionCompactThreshold,
 		LargeWriteThreshold: DefaultLargeSeriesWriteThreshold,
+		IDAllocation:        DefaultSeriesIDAllocation,
 		tracker:             newSeriesPartitionTracker(newSeriesFileMetrics(nil), nil),
 		Logger:              zap.NewNop(),
 		seq:                 uint64(id) + 1,
@@ -198,6 +201,9 @@ func (p *SeriesPartition) CreateSeriesListIfNotExists(collection *SeriesCollecti
 	defer span.Finish()
 
 	writeRequired := 0
+	var missing []int
+	batched := p.IDAllocation == SeriesIDAllocationBatched
+	inserts := p.inserts
 	for iter := collection.Iterator(); iter.Next(); {
 		index := iter.Index()
 		if keyPartitionIDs[index] != p.id {
@@ -206,6 +212,9 @@ func (p *SeriesPartition) CreateSeriesListIfNotExists(collection *SeriesCollecti
 		id := p.index.FindIDBySeriesKey(p.segments, iter.SeriesKey())
 		if id.IsZero() {
 			writeRequired++
+			if batched {
+				missing = append(missing, index)
+			}
 			continue
 		}
 		if id.HasType() && id.Type() != iter.Type() {
@@ -222,6 +231,9 @@ func (p *SeriesPartition) CreateSeriesListIfNotExists(collection *SeriesCollecti
 	if writeRequired == 0 {
 		return nil
 	}
+	if batched {
+		return p.createSeriesListBatched(ctx, collection, missing, inserts)
+	}
 
 	type keyRange struct {
 		key    []byte
@@ -305,7 +317,118 @@ func (p *SeriesPartition) CreateSeriesListIfNotExists(collection *SeriesCollecti
 	}
 	p.tracker.AddSeriesCreated(uint64(len(newKeyRanges))) // Track new series in metric.
 	p.tracker.AddSeries(uint64(len(newKeyRanges)))
+	p.inserts++
+
+	p.checkCompaction(ctx)
+	return nil
+}
+
+// createSeriesListBatched creates the series of the collection at the missing
+// indexes, which were not found in the partition when it was last read after
+// inserts batches of series had been inserted.
+//
+// The entries of the series are encoded before the write lock is taken. The
+// series are looked up again under the lock only if other series were
+// inserted meanwhile, and their IDs are then assigned in order, so that the
+// IDs of the entries of the segments keep increasing.
+func (p *SeriesPartition) createSeriesListBatched(ctx context.Context, collection *SeriesCollection, missing []int, inserts uint64) error {
+	type newSeries struct {
+		index      int // first index of the key in the collection
+		start, end int // position of the entry in buf
+		id         SeriesIDTyped
+		offset     int64
+		created    bool
+	}
+
+	// Encode the entries of the distinct keys, with the IDs left to set.
+	var (
+		buf    []byte
+		series = make([]newSeries, 0, len(missing))
+		first  = make(map[string]int, len(missing))
+	)
+	for _, index := range missing {
+		key := collection.SeriesKeys[index]
+		if _, ok := first[string(key)]; ok {
+			continue
+		}
+		first[string(key)] = len(series)
+		start := len(buf)
+		buf = AppendSeriesEntry(buf, SeriesEntryInsertFlag, SeriesIDTyped{}, key)
+		series = append(series, newSeries{index: index, start: start, end: len(buf)})
+	}
+
+	if len(series) >= p.LargeWriteThreshold {
+		p.mu.Lock()
+		p.index.GrowBy(len(series))
+		p.mu.Unlock()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return ErrSeriesPartitionClosed
+	}
+
+	// Other series may have been inserted since the keys were looked up.
+	recheck := p.inserts != inserts
+
+	var created int
+	for i := range series {
+		s := &series[i]
+		key, typ := collection.SeriesKeys[s.index], collection.Types[s.index]
+		if recheck {
+			if id := p.index.FindIDBySeriesKey(p.segments, key); !id.IsZero() {
+				s.id = id
+				continue
+			}
+		}
 
+		s.id = NewSeriesID(p.seq).WithType(typ)
+		binary.BigEndian.PutUint64(buf[s.start+1:], s.id.RawID())
+		offset, err := p.writeLogEntry(buf[s.start:s.end])
+		if err != nil {
+			return err
+		}
+		p.seq += SeriesFilePartitionN
+		s.offset, s.created = offset, true
+		created++
+	}
+
+	// Flush active segment writes so we can access data in mmap.
+	if segment := p.activeSegment(); segment != nil {
+		if err := segment.Flush(); err != nil {
+			return err
+		}
+	}
+
+	// Add keys to hash map(s), and set the IDs of the collection.
+	for _, s := range series {
+		if s.created {
+			p.index.Insert(collection.SeriesKeys[s.index], s.id, s.offset)
+		}
+	}
+	for _, index := range missing {
+		id := series[first[string(collection.SeriesKeys[index])]].id
+		if id.HasType() && id.Type() != collection.Types[index] {
+			collection.invalidIndex(index, fmt.Sprintf(
+				"series type mismatch: already %s but got %s",
+				id.Type(), collection.Types[index]))
+			continue
+		}
+		collection.SeriesIDs[index] = id.SeriesID()
+	}
+	p.tracker.AddSeriesCreated(uint64(created)) // Track new series in metric.
+	p.tracker.AddSeries(uint64(created))
+	p.inserts++
+
+	p.checkCompaction(ctx)
+	return nil
+}
+
+// checkCompaction starts a compaction of the partition in the background if
+// it has crossed the compaction threshold. The write lock must be held.
+func (p *SeriesPartition) checkCompaction(ctx context.Context) {
 	// Check if we've crossed the compaction threshold.
 	if p.compactionsEnabled() && !p.compacting && p.CompactThreshold != 0 && p.index.InMemCount() >= uint64(p.CompactThreshold) {
 		p.compacting = true
@@ -338,8 +461,6 @@ func (p *SeriesPartition) CreateSeriesListIfNotExists(collection *SeriesCollecti
 			p.tracker.SetDiskSize(p.DiskSize())
 		}()
 	}
-
-	return nil
 }
 
 // Compacting returns if the SeriesPartition is currently compacting.
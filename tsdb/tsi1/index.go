@@ -345,6 +345,21 @@ func (i *Index) Wait() {
 	}
 }
 
+// Flush writes the buffered data of the active log file of every partition to
+// disk, without syncing it, so that the series created so far survive the
+// process being killed even when fsyncs are disabled.
+func (i *Index) Flush() error {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	for _, p := range i.partitions {
+		if err := p.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Close closes the index.
 func (i *Index) Close() error {
 	// Lock index and close partitions.
@@ -382,6 +397,11 @@ func (i *Index) partitionIdx(key []byte) int {
 	return int(xxhash.Sum64(key) & (i.PartitionN - 1))
 }
 
+// PartitionIndex returns the index of the partition holding the series key.
+func (i *Index) PartitionIndex(key []byte) int {
+	return i.partitionIdx(key)
+}
+
 // availableThreads returns the minimum of GOMAXPROCS and the number of
 // partitions in the Index.
 func (i *Index) availableThreads() int {
@@ -1496,6 +1516,21 @@ func (i *Index) matchTagValueEqualEmptySeriesIDIterator(name, key []byte, value
 }
 
 func (i *Index) matchTagValueEqualNotEmptySeriesIDIterator(name, key []byte, value *regexp.Regexp) (tsdb.SeriesIDIterator, error) {
+	// Regexes matching a set of literals are looked up directly.
+	if literals, ok := regexLiterals(value); ok {
+		var itrs []tsdb.SeriesIDIterator
+		for _, v := range literals {
+			itr, err := i.tagValueSeriesIDIterator(name, key, v)
+			if err != nil {
+				tsdb.SeriesIDIterators(itrs).Close()
+				return nil, err
+			} else if itr != nil {
+				itrs = append(itrs, itr)
+			}
+		}
+		return tsdb.MergeSeriesIDIterators(itrs...), nil
+	}
+
 	vitr, err := i.TagValueIterator(name, key)
 	if err != nil {
 		return nil, err
@@ -1504,6 +1539,10 @@ func (i *Index) matchTagValueEqualNotEmptySeriesIDIterator(name, key []byte, val
 	}
 	defer vitr.Close()
 
+	// Values are iterated in order, so that only the range of the values with
+	// the literal prefix of the regex, if any, is matched.
+	prefix := regexPrefix(value)
+
 	var itrs []tsdb.SeriesIDIterator
 	for {
 		e, err := vitr.Next()
@@ -1514,6 +1553,13 @@ func (i *Index) matchTagValueEqualNotEmptySeriesIDIterator(name, key []byte, val
 			break
 		}
 
+		if prefix != nil && !bytes.HasPrefix(e, prefix) {
+			if bytes.Compare(e, prefix) > 0 {
+				break
+			}
+			continue
+		}
+
 		if value.Match(e) {
 			itr, err := i.tagValueSeriesIDIterator(name, key, e)
 			if err != nil {
@@ -1560,40 +1606,20 @@ func (i *Index) matchTagValueNotEqualEmptySeriesIDIterator(name, key []byte, val
 }
 
 func (i *Index) matchTagValueNotEqualNotEmptySeriesIDIterator(name, key []byte, value *regexp.Regexp) (tsdb.SeriesIDIterator, error) {
-	vitr, err := i.TagValueIterator(name, key)
+	// The series of the values matching the regex are excluded.
+	itr, err := i.matchTagValueEqualNotEmptySeriesIDIterator(name, key, value)
 	if err != nil {
 		return nil, err
-	} else if vitr == nil {
-		return i.measurementSeriesIDIterator(name)
-	}
-	defer vitr.Close()
-
-	var itrs []tsdb.SeriesIDIterator
-	for {
-		e, err := vitr.Next()
-		if err != nil {
-			tsdb.SeriesIDIterators(itrs).Close()
-			return nil, err
-		} else if e == nil {
-			break
-		}
-		if value.Match(e) {
-			itr, err := i.tagValueSeriesIDIterator(name, key, e)
-			if err != nil {
-				tsdb.SeriesIDIterators(itrs).Close()
-				return nil, err
-			} else if itr != nil {
-				itrs = append(itrs, itr)
-			}
-		}
 	}
 
 	mitr, err := i.measurementSeriesIDIterator(name)
 	if err != nil {
-		tsdb.SeriesIDIterators(itrs).Close()
+		if itr != nil {
+			itr.Close()
+		}
 		return nil, err
 	}
-	return tsdb.DifferenceSeriesIDIterators(mitr, tsdb.MergeSeriesIDIterators(itrs...)), nil
+	return tsdb.DifferenceSeriesIDIterators(mitr, itr), nil
 }
 
 // IsIndexDir returns true if directory contains at least one partition directory.
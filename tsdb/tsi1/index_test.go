@@ -166,6 +166,60 @@ func TestIndex_MeasurementNamesByRegex(t *testing.T) {
 	})
 }
 
+// Ensure index matches the series of tag values by regex, whether the regex
+// is looked up by literal values, by literal prefix or matched against values.
+func TestIndex_MatchTagValueSeriesIDIterator(t *testing.T) {
+	idx := MustOpenIndex(2, tsi1.NewConfig())
+	defer idx.Close()
+
+	var series []Series
+	for _, host := range []string{"a", "dev", "prod-1", "prod-2", "prodx", "stage-1", "zzz", ""} {
+		tags := models.NewTags(map[string]string{"region": "east"})
+		if host != "" {
+			tags.Set([]byte("host"), []byte(host))
+		}
+		series = append(series, Series{Name: []byte("cpu"), Tags: tags})
+	}
+	if err := idx.CreateSeriesSliceIfNotExists(series); err != nil {
+		t.Fatal(err)
+	}
+
+	idx.Run(t, func(t *testing.T) {
+		for _, expr := range []string{`^prod-`, `^prod`, `^(prod-1|dev)$`, `^prod-[12]$`, `^(none|zzz)$`, `^none`, `prod`, `^$|^dev`} {
+			re := regexp.MustCompile(expr)
+			for _, matches := range []bool{true, false} {
+				exp := make(map[tsdb.SeriesID]bool)
+				for _, s := range series {
+					if re.MatchString(s.Tags.GetString("host")) == matches {
+						exp[idx.SeriesFile.SeriesID(s.Name, s.Tags, nil)] = true
+					}
+				}
+
+				itr, err := idx.MatchTagValueSeriesIDIterator([]byte("cpu"), []byte("host"), re, matches)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := make(map[tsdb.SeriesID]bool)
+				for itr != nil {
+					e, err := itr.Next()
+					if err != nil {
+						t.Fatal(err)
+					} else if e.SeriesID.IsZero() {
+						break
+					}
+					got[e.SeriesID] = true
+				}
+				if itr != nil {
+					itr.Close()
+				}
+				if !reflect.DeepEqual(got, exp) {
+					t.Fatalf("%s (matches=%v): got series %v, expected %v", expr, matches, got, exp)
+				}
+			}
+		}
+	})
+}
+
 // Ensure index can delete a measurement and all related keys, values, & series.
 func TestIndex_DropMeasurement(t *testing.T) {
 	idx := MustOpenIndex(1, tsi1.NewConfig())
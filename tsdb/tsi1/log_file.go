@@ -225,6 +225,18 @@ func (f *LogFile) Close() error {
 	return nil
 }
 
+// Flush writes buffered data to the underlying file without syncing it, even
+// if the LogFile has disabled flushing and syncing.
+func (f *LogFile) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.w == nil {
+		return nil
+	}
+	return f.w.Flush()
+}
+
 // FlushAndSync flushes buffered data to disk and then fsyncs the underlying file.
 // If the LogFile has disabled flushing and syncing then FlushAndSync is a no-op.
 func (f *LogFile) FlushAndSync() error {
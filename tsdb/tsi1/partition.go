@@ -1137,6 +1137,18 @@ func (p *Partition) compactToLevel(files []*IndexFile, frefs lifecycle.Reference
 	}
 }
 
+// Flush writes the buffered data of the active log file to disk, without
+// syncing it.
+func (p *Partition) Flush() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.activeLogFile == nil {
+		return nil
+	}
+	return p.activeLogFile.Flush()
+}
+
 func (p *Partition) CheckLogFile() error {
 	// Check log file size under read lock.
 	p.mu.RLock()
@@ -0,0 +1,124 @@
+package tsi1
+
+import (
+	"regexp"
+	"regexp/syntax"
+)
+
+// regexLiterals returns the values matched by a regex of the form /^foo$/ or
+// /^(foo|bar)$/, which can be looked up in the index rather than matched
+// against every tag value. It returns false if the regex matches other values.
+func regexLiterals(re *regexp.Regexp) ([][]byte, bool) {
+	sre, ok := parseAnchoredRegex(re)
+	if !ok || len(sre.Sub) < 2 || sre.Sub[len(sre.Sub)-1].Op != syntax.OpEndText {
+		return nil, false
+	}
+
+	vals, ok := regexLiteralValues(&syntax.Regexp{Op: syntax.OpConcat, Sub: sre.Sub[1 : len(sre.Sub)-1]})
+	if !ok {
+		return nil, false
+	}
+	literals := make([][]byte, len(vals))
+	for i, v := range vals {
+		literals[i] = []byte(v)
+	}
+	return literals, true
+}
+
+// regexPrefix returns the literal prefix of every value matched by a regex
+// of the form /^foo/, so that the values without the prefix need not be
+// matched, or nil.
+func regexPrefix(re *regexp.Regexp) []byte {
+	sre, ok := parseAnchoredRegex(re)
+	if !ok || len(sre.Sub) < 2 {
+		return nil
+	}
+	if lit := sre.Sub[1]; lit.Op == syntax.OpLiteral && lit.Flags&syntax.FoldCase == 0 {
+		return []byte(string(lit.Rune))
+	}
+	return nil
+}
+
+// parseAnchoredRegex returns the simplified syntax tree of a regex anchored at
+// the beginning of the text, as a concatenation starting with the anchor.
+func parseAnchoredRegex(re *regexp.Regexp) (*syntax.Regexp, bool) {
+	sre, err := syntax.Parse(re.String(), syntax.Perl)
+	if err != nil {
+		return nil, false
+	}
+	sre = sre.Simplify()
+
+	if sre.Op != syntax.OpConcat || len(sre.Sub) == 0 || sre.Sub[0].Op != syntax.OpBeginText {
+		return nil, false
+	}
+	return sre, true
+}
+
+// regexLiteralValues returns the values matched by a regex made of literals,
+// alternations and concatenations, if possible.
+func regexLiteralValues(re *syntax.Regexp) ([]string, bool) {
+	// Case-insensitive literals match too many values.
+	if re.Flags&syntax.FoldCase != 0 {
+		return nil, false
+	}
+
+	switch re.Op {
+	case syntax.OpEmptyMatch:
+		return []string{""}, true
+	case syntax.OpLiteral:
+		return []string{string(re.Rune)}, true
+	case syntax.OpCapture:
+		return regexLiteralValues(re.Sub[0])
+	case syntax.OpConcat:
+		vals := []string{""}
+		for _, sub := range re.Sub {
+			subVals, ok := regexLiteralValues(sub)
+			if !ok {
+				return nil, false
+			}
+			next := make([]string, 0, len(vals)*len(subVals))
+			for _, v := range vals {
+				for _, s := range subVals {
+					next = append(next, v+s)
+				}
+			}
+			if len(next) > maxRegexLiterals {
+				return nil, false
+			}
+			vals = next
+		}
+		return vals, true
+	case syntax.OpAlternate:
+		var vals []string
+		for _, sub := range re.Sub {
+			subVals, ok := regexLiteralValues(sub)
+			if !ok {
+				return nil, false
+			}
+			vals = append(vals, subVals...)
+		}
+		if len(vals) > maxRegexLiterals {
+			return nil, false
+		}
+		return vals, true
+	case syntax.OpCharClass:
+		// A small class of characters, such as [ab], expands to its
+		// characters.
+		var vals []string
+		for i := 0; i < len(re.Rune); i += 2 {
+			lo, hi := re.Rune[i], re.Rune[i+1]
+			if len(vals)+int(hi-lo)+1 > maxRegexLiterals {
+				return nil, false
+			}
+			for r := lo; r <= hi; r++ {
+				vals = append(vals, string(r))
+			}
+		}
+		return vals, true
+	}
+	return nil, false
+}
+
+// maxRegexLiterals bounds the number of values a regex is expanded to, so
+// that regexes such as /^[a-z][a-z]$/ are matched against the tag values.
+const maxRegexLiterals = 64
@@ -0,0 +1,59 @@
+package tsi1
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestRegexLiterals(t *testing.T) {
+	for _, tt := range []struct {
+		re       string
+		literals []string
+	}{
+		{re: `^foo$`, literals: []string{"foo"}},
+		{re: `^(foo|bar)$`, literals: []string{"foo", "bar"}},
+		{re: `^(?:prod|stage)-[ab]$`, literals: []string{"prod-a", "prod-b", "stage-a", "stage-b"}},
+		{re: `^fo[ox]$`, literals: []string{"foo", "fox"}},
+		{re: `^foo`},
+		{re: `foo$`},
+		{re: `^(?i)foo$`},
+		{re: `^foo.*$`},
+		{re: `^[a-z][a-z]$`},
+	} {
+		t.Run(tt.re, func(t *testing.T) {
+			literals, ok := regexLiterals(regexp.MustCompile(tt.re))
+			if ok != (tt.literals != nil) {
+				t.Fatalf("got literals %q, %v", literals, ok)
+			}
+			var got []string
+			for _, l := range literals {
+				got = append(got, string(l))
+			}
+			if !reflect.DeepEqual(got, tt.literals) {
+				t.Fatalf("got literals %q, expected %q", got, tt.literals)
+			}
+		})
+	}
+}
+
+func TestRegexPrefix(t *testing.T) {
+	for _, tt := range []struct {
+		re     string
+		prefix string
+	}{
+		{re: `^prod-`, prefix: "prod-"},
+		{re: `^prod-.*-[0-9]+$`, prefix: "prod-"},
+		{re: `^prod|^stage`},
+		{re: `prod-`},
+		{re: `(?m)^prod-`},
+		{re: `^(?i)prod-`},
+		{re: `^.*prod`},
+	} {
+		t.Run(tt.re, func(t *testing.T) {
+			if got := string(regexPrefix(regexp.MustCompile(tt.re))); got != tt.prefix {
+				t.Fatalf("got prefix %q, expected %q", got, tt.prefix)
+			}
+		})
+	}
+}
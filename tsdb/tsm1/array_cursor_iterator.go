@@ -40,6 +40,9 @@ func (q *arrayCursorIterator) Next(ctx context.Context, r *tsdb.CursorRequest) (
 	}
 
 	q.e.readTracker.AddCursors(1)
+	if q.e.warmUp != nil {
+		q.e.warmUp.touch(r.Name)
+	}
 
 	if grp := metrics.GroupFromContext(ctx); grp != nil {
 		grp.GetCounter(numberOfRefCursorsCounter).Add(1)
@@ -0,0 +1,147 @@
+package tsm1
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressions of the blocks of the TSM files written by cache snapshots.
+//
+// Snapshot files are small and short lived, and are rewritten by the level
+// compactions, which store their blocks uncompressed again. Compressing their
+// blocks reduces the bytes written during ingest spikes at the cost of the CPU
+// compressing them, and of decompressing them when they are queried or
+// compacted.
+const (
+	SnapshotCompressionNone   = "none"
+	SnapshotCompressionSnappy = "snappy"
+	SnapshotCompressionZstd   = "zstd"
+)
+
+// Codecs of the compressed blocks.
+const (
+	blockCompressionNone byte = iota
+	blockCompressionSnappy
+	blockCompressionZstd
+)
+
+// A compressed block holds its block type, a zero byte and the codec of the
+// compression, followed by the compressed rest of the block: the length of
+// the timestamps, the timestamps and the values. An encoded block never has
+// a zero length of timestamps, which tells compressed blocks apart.
+const compressedBlockHeaderSize = 3
+
+// compressesBlocks returns true if the blocks read from iter may be
+// compressed, in which case they are written to a file of the
+// CompressedBlocksVersion.
+func compressesBlocks(iter KeyIterator) bool {
+	cki, ok := iter.(*cacheKeyIterator)
+	return ok && cki.compression != blockCompressionNone
+}
+
+// snapshotBlockCompression returns the codec of the snapshot compression
+// name, which is none if empty.
+func snapshotBlockCompression(name string) (byte, error) {
+	switch name {
+	case "", SnapshotCompressionNone:
+		return blockCompressionNone, nil
+	case SnapshotCompressionSnappy:
+		return blockCompressionSnappy, nil
+	case SnapshotCompressionZstd:
+		return blockCompressionZstd, nil
+	default:
+		return 0, fmt.Errorf("unknown snapshot compression %q", name)
+	}
+}
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+)
+
+// zstdCodecs returns the zstd encoder, at its fastest level, and decoder
+// shared by the blocks, which are safe for concurrent use.
+func zstdCodecs() (*zstd.Encoder, *zstd.Decoder) {
+	zstdOnce.Do(func() {
+		var err error
+		if zstdEncoder, err = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedFastest)); err != nil {
+			panic(err)
+		}
+		if zstdDecoder, err = zstd.NewReader(nil); err != nil {
+			panic(err)
+		}
+	})
+	return zstdEncoder, zstdDecoder
+}
+
+// compressBlock returns the encoded block compressed with codec, or the
+// block itself if the compression does not make it smaller.
+func compressBlock(block []byte, codec byte) []byte {
+	if codec == blockCompressionNone || len(block) <= compressedBlockHeaderSize {
+		return block
+	}
+
+	b := make([]byte, compressedBlockHeaderSize, len(block))
+	b[0], b[1], b[2] = block[0], 0, codec
+	switch codec {
+	case blockCompressionSnappy:
+		b = append(b, snappy.Encode(nil, block[1:])...)
+	case blockCompressionZstd:
+		enc, _ := zstdCodecs()
+		b = enc.EncodeAll(block[1:], b)
+	default:
+		return block
+	}
+
+	if len(b) >= len(block) {
+		return block
+	}
+	return b
+}
+
+// isCompressedBlock returns true if the block, without its block type, is
+// compressed.
+func isCompressedBlock(buf []byte) bool {
+	return len(buf) > 0 && buf[0] == 0
+}
+
+// decompressBlock returns the block, without its block type, decompressed.
+func decompressBlock(buf []byte) ([]byte, error) {
+	if len(buf) < compressedBlockHeaderSize-1 {
+		return nil, fmt.Errorf("compressed block too short: got %d bytes", len(buf))
+	}
+	switch buf[1] {
+	case blockCompressionSnappy:
+		b, err := snappy.Decode(nil, buf[2:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress snappy block: %v", err)
+		}
+		return b, nil
+	case blockCompressionZstd:
+		_, dec := zstdCodecs()
+		b, err := dec.DecodeAll(buf[2:], nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress zstd block: %v", err)
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("unknown block compression %d", buf[1])
+	}
+}
+
+// uncompressBlock returns the block decompressed if it is compressed, or
+// the block itself.
+func uncompressBlock(block []byte) ([]byte, error) {
+	if len(block) == 0 || !isCompressedBlock(block[1:]) {
+		return block, nil
+	}
+	b, err := decompressBlock(block[1:])
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{block[0]}, b...), nil
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/influxdata/influxdb/kit/tracing"
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/limiter"
 	"github.com/influxdata/influxdb/storage/wal"
 	"github.com/influxdata/influxdb/tsdb"
 	"github.com/influxdata/influxql"
@@ -40,12 +41,28 @@ func ErrCacheMemorySizeLimitExceeded(n, limit uint64) error {
 	return CacheMemorySizeLimitExceededError{Size: n, Limit: limit}
 }
 
+// CacheMemoryBudgetExceededError is the type of error returned from the cache when
+// a write would place the memory budget it shares with queries and compactions
+// over its limit.
+type CacheMemoryBudgetExceededError struct {
+	Size  uint64
+	Limit int64
+}
+
+func (c CacheMemoryBudgetExceededError) Error() string {
+	return fmt.Sprintf("memory budget exceeded: cache size %d, budget %d", c.Size, c.Limit)
+}
+
 // Cache maintains an in-memory store of Values for a set of keys.
 type Cache struct {
 	mu      sync.RWMutex
 	store   *ring
 	maxSize uint64
 
+	// budget accounts for the size of the cache in a memory budget shared
+	// with queries and compactions. It is nil if there is none.
+	budget *limiter.MemoryConsumer
+
 	// snapshots are the cache objects that are currently being written to tsm files
 	// they're kept in memory while flushing so they can be queried along with the cache.
 	// they are read only and should never be modified
@@ -82,6 +99,11 @@ func (c *Cache) Write(key []byte, values []Value) error {
 		c.tracker.AddWrittenBytesDrop(uint64(addedSize))
 		return ErrCacheMemorySizeLimitExceeded(n, limit)
 	}
+	if err := c.reserveBudget(n); err != nil {
+		c.tracker.IncWritesErr()
+		c.tracker.AddWrittenBytesDrop(uint64(addedSize))
+		return err
+	}
 
 	newKey, err := c.store.write(key, values)
 	if err != nil {
@@ -121,6 +143,11 @@ func (c *Cache) WriteMulti(values map[string][]Value) error {
 		c.tracker.AddWrittenBytesDrop(uint64(addedSize))
 		return ErrCacheMemorySizeLimitExceeded(n, limit)
 	}
+	if err := c.reserveBudget(n); err != nil {
+		c.tracker.IncWritesErr()
+		c.tracker.AddWrittenBytesDrop(uint64(addedSize))
+		return err
+	}
 
 	var werr error
 	c.mu.RLock()
@@ -253,6 +280,9 @@ func (c *Cache) ClearSnapshot(success bool) {
 		c.tracker.SetSnapshotSize(0)
 		c.tracker.SetDiskBytes(0)
 		c.tracker.SetSnapshotsActive(0)
+
+		// Give the memory of the snapshot back to the memory budget.
+		c.budget.Resize(int64(c.Size()))
 	}
 }
 
@@ -438,6 +468,29 @@ func (c *Cache) DeleteBucketRange(ctx context.Context, name string, min, max int
 
 	c.tracker.DecCacheSize(total)
 	c.tracker.SetMemBytes(uint64(c.Size()))
+	c.budget.Resize(int64(c.Size()))
+}
+
+// SetMemoryBudget sets the consumer of a memory budget the size of the cache is
+// accounted for in. Writes fail when they would place the budget over its limit.
+func (c *Cache) SetMemoryBudget(budget *limiter.MemoryConsumer) {
+	c.mu.Lock()
+	c.budget = budget
+	c.mu.Unlock()
+	budget.Resize(int64(c.Size()))
+}
+
+// reserveBudget grows the usage of the memory budget to n, the size of the
+// cache after a write.
+func (c *Cache) reserveBudget(n uint64) error {
+	c.mu.RLock()
+	budget := c.budget
+	c.mu.RUnlock()
+
+	if budget == nil || budget.TryResize(int64(n)) {
+		return nil
+	}
+	return CacheMemoryBudgetExceededError{Size: n, Limit: budget.Limit()}
 }
 
 // SetMaxSize updates the memory limit of the cache.
@@ -16,6 +16,7 @@ import (
 	"testing"
 
 	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/pkg/limiter"
 	"github.com/influxdata/influxdb/storage/wal"
 
 	"github.com/golang/snappy"
@@ -83,6 +84,42 @@ func TestCache_CacheWrite_TypeConflict(t *testing.T) {
 	}
 }
 
+func TestCache_CacheWrite_MemoryBudget(t *testing.T) {
+	v0 := NewValue(1, 1.0)
+	v1 := NewValue(2, 2.0)
+	values := Values{v0, v1}
+	valuesSize := uint64(v0.Size() + v1.Size())
+
+	budget := limiter.NewMemory(int64(3 * valuesSize))
+	query := budget.Consumer("query")
+	c := NewCache(0)
+	c.SetMemoryBudget(budget.Consumer("cache"))
+
+	if err := c.Write([]byte("foo"), values); err != nil {
+		t.Fatalf("failed to write key foo to cache: %s", err.Error())
+	}
+	if !query.TryReserve(int64(valuesSize)) {
+		t.Fatal("expected query reservation to succeed")
+	}
+	if err := c.Write([]byte("bar"), values); err == nil {
+		t.Fatal("expected memory budget to be exceeded")
+	} else if _, ok := err.(CacheMemoryBudgetExceededError); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Flushing the cache gives its memory back to the budget.
+	if _, err := c.Snapshot(); err != nil {
+		t.Fatal(err)
+	}
+	c.ClearSnapshot(true)
+	if exp, got := int64(valuesSize), budget.Used(); exp != got {
+		t.Fatalf("budget usage incorrect after snapshot, exp %d, got %d", exp, got)
+	}
+	if err := c.Write([]byte("bar"), values); err != nil {
+		t.Fatalf("failed to write key bar to cache: %s", err.Error())
+	}
+}
+
 func TestCache_CacheWriteMulti(t *testing.T) {
 	v0 := NewValue(1, 1.0)
 	v1 := NewValue(2, 2.0)
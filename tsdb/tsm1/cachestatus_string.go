@@ -14,11 +14,13 @@ func _() {
 	_ = x[CacheStatusColdNoWrites-3]
 	_ = x[CacheStatusRetention-4]
 	_ = x[CacheStatusFullCompaction-5]
+	_ = x[CacheStatusBackup-6]
+	_ = x[CacheStatusCopy-7]
 }
 
-const _CacheStatus_name = "CacheStatusOkayCacheStatusSizeExceededCacheStatusAgeExceededCacheStatusColdNoWritesCacheStatusRetentionCacheStatusFullCompaction"
+const _CacheStatus_name = "CacheStatusOkayCacheStatusSizeExceededCacheStatusAgeExceededCacheStatusColdNoWritesCacheStatusRetentionCacheStatusFullCompactionCacheStatusBackupCacheStatusCopy"
 
-var _CacheStatus_index = [...]uint8{0, 15, 38, 60, 83, 103, 128}
+var _CacheStatus_index = [...]uint8{0, 15, 38, 60, 83, 103, 128, 145, 160}
 
 func (i CacheStatus) String() string {
 	if i < 0 || i >= CacheStatus(len(_CacheStatus_index)-1) {
@@ -123,6 +123,17 @@ type DefaultPlanner struct {
 	// filesInUse is the set of files that have been returned as part of a plan and might
 	// be being compacted.  Two plans should not return the same file at any given time.
 	filesInUse map[string]struct{}
+
+	// maxFileSize is the size over which files holding full blocks are no longer compacted.
+	maxFileSize uint64
+
+	// maxLevel is the highest level planned by PlanLevel. Generations of higher levels
+	// are planned by the optimize and full planners.
+	maxLevel int
+
+	// levelGenerations is the minimum number of generations of a level, but level 1,
+	// compacted together.
+	levelGenerations int
 }
 
 type fileStore interface {
@@ -137,9 +148,20 @@ func NewDefaultPlanner(fs fileStore, writeColdDuration time.Duration) *DefaultPl
 		FileStore:                    fs,
 		compactFullWriteColdDuration: writeColdDuration,
 		filesInUse:                   make(map[string]struct{}),
+		maxFileSize:                  uint64(DefaultCompactMaxFileSize),
+		maxLevel:                     DefaultCompactMaxLevel,
+		levelGenerations:             DefaultCompactLevelGenerations,
 	}
 }
 
+// WithCompactionConfig sets the file size, maximum level and generations per
+// level used by the planner.
+func (c *DefaultPlanner) WithCompactionConfig(config CompactionConfig) {
+	c.maxFileSize = uint64(config.MaxFileSize)
+	c.maxLevel = config.MaxLevel
+	c.levelGenerations = config.LevelGenerations
+}
+
 // tsmGeneration represents the TSM files within a generation.
 // 000001-01.tsm, 000001-02.tsm would be in the same generation
 // 000001 each with different sequence numbers.
@@ -147,12 +169,14 @@ type tsmGeneration struct {
 	id            int
 	files         []FileStat
 	parseFileName ParseFileNameFunc
+	maxLevel      int
 }
 
-func newTsmGeneration(id int, parseFileNameFunc ParseFileNameFunc) *tsmGeneration {
+func newTsmGeneration(id int, maxLevel int, parseFileNameFunc ParseFileNameFunc) *tsmGeneration {
 	return &tsmGeneration{
 		id:            id,
 		parseFileName: parseFileNameFunc,
+		maxLevel:      maxLevel,
 	}
 }
 
@@ -170,9 +194,9 @@ func (t *tsmGeneration) level() int {
 	// Level 0 is always created from the result of a cache compaction.  It generates
 	// 1 file with a sequence num of 1.  Level 2 is generated by compacting multiple
 	// level 1 files.  Level 3 is generate by compacting multiple level 2 files.  Level
-	// 4 is for anything else.
+	// 4 is for anything else, including the levels above the maximum level.
 	_, seq, _ := t.parseFileName(t.files[0].Path)
-	if seq < 4 {
+	if seq <= t.maxLevel {
 		return seq
 	}
 
@@ -219,6 +243,10 @@ func (c *DefaultPlanner) ForceFull() {
 
 // PlanLevel returns a set of TSM files to rewrite for a specific level.
 func (c *DefaultPlanner) PlanLevel(level int) []CompactionGroup {
+	if level > c.maxLevel {
+		return nil
+	}
+
 	// If a full plan has been requested, don't plan any levels which will prevent
 	// the full plan from acquiring them.
 	c.mu.RLock()
@@ -277,9 +305,9 @@ func (c *DefaultPlanner) PlanLevel(level int) []CompactionGroup {
 		}
 	}
 
-	minGenerations := 4
+	minGenerations := c.levelGenerations
 	if level == 1 {
-		minGenerations = 8
+		minGenerations *= 2
 	}
 
 	var cGroups []CompactionGroup
@@ -343,7 +371,7 @@ func (c *DefaultPlanner) PlanOptimize() []CompactionGroup {
 		cur := generations[i]
 
 		// Skip the file if it's over the max size and contains a full block and it does not have any tombstones
-		if cur.count() > 2 && cur.size() > uint64(maxTSMFileSize) && c.FileStore.BlockCount(cur.files[0].Path, 1) == MaxPointsPerBlock && !cur.hasTombstones() {
+		if cur.count() > 2 && cur.size() > c.maxFileSize && c.FileStore.BlockCount(cur.files[0].Path, 1) == MaxPointsPerBlock && !cur.hasTombstones() {
 			continue
 		}
 
@@ -428,7 +456,7 @@ func (c *DefaultPlanner) Plan(lastWrite time.Time) []CompactionGroup {
 			var skip bool
 
 			// Skip the file if it's over the max size and contains a full block and it does not have any tombstones
-			if len(generations) > 2 && group.size() > uint64(maxTSMFileSize) && c.FileStore.BlockCount(group.files[0].Path, 1) == MaxPointsPerBlock && !group.hasTombstones() {
+			if len(generations) > 2 && group.size() > c.maxFileSize && c.FileStore.BlockCount(group.files[0].Path, 1) == MaxPointsPerBlock && !group.hasTombstones() {
 				skip = true
 			}
 
@@ -437,7 +465,7 @@ func (c *DefaultPlanner) Plan(lastWrite time.Time) []CompactionGroup {
 			// created files to get picked up by the full compaction planner and avoids having a few less optimally
 			// compressed files.
 			if i < len(generations)-1 {
-				if generations[i+1].level() <= 3 {
+				if generations[i+1].level() <= c.maxLevel {
 					skip = false
 				}
 			}
@@ -483,7 +511,7 @@ func (c *DefaultPlanner) Plan(lastWrite time.Time) []CompactionGroup {
 	end := 0
 	start := 0
 	for i, g := range generations {
-		if g.level() <= 3 {
+		if g.level() <= c.maxLevel {
 			break
 		}
 		end = i + 1
@@ -504,7 +532,7 @@ func (c *DefaultPlanner) Plan(lastWrite time.Time) []CompactionGroup {
 		// Skip the file if it's over the max size and contains a full block or the generation is split
 		// over multiple files.  In the latter case, that would mean the data in the file spilled over
 		// the 2GB limit.
-		if g.size() > uint64(maxTSMFileSize) && c.FileStore.BlockCount(g.files[0].Path, 1) == MaxPointsPerBlock {
+		if g.size() > c.maxFileSize && c.FileStore.BlockCount(g.files[0].Path, 1) == MaxPointsPerBlock {
 			start = i + 1
 		}
 
@@ -542,13 +570,13 @@ func (c *DefaultPlanner) Plan(lastWrite time.Time) []CompactionGroup {
 
 			// Skip compacting this group if there happens to be any lower level files in the
 			// middle.  These will get picked up by the level compactors.
-			if lvl <= 3 {
+			if lvl <= c.maxLevel {
 				skipGroup = true
 				break
 			}
 
 			// Skip the file if it's over the max size and it contains a full block
-			if gen.size() >= uint64(maxTSMFileSize) && c.FileStore.BlockCount(gen.files[0].Path, 1) == MaxPointsPerBlock && !gen.hasTombstones() {
+			if gen.size() >= c.maxFileSize && c.FileStore.BlockCount(gen.files[0].Path, 1) == MaxPointsPerBlock && !gen.hasTombstones() {
 				startIndex++
 				continue
 			}
@@ -629,7 +657,7 @@ func (c *DefaultPlanner) findGenerations(skipInUse bool) tsmGenerations {
 
 		group := generations[gen]
 		if group == nil {
-			group = newTsmGeneration(gen, c.ParseFileName)
+			group = newTsmGeneration(gen, c.maxLevel, c.ParseFileName)
 			generations[gen] = group
 		}
 		group.files = append(group.files, f)
@@ -698,6 +726,15 @@ type Compactor struct {
 	// RateLimit is the limit for disk writes for all concurrent compactions.
 	RateLimit limiter.Rate
 
+	// MaxFileSize is the size at which compactions roll over to a new file.
+	// It defaults to 2GB if zero.
+	MaxFileSize uint32
+
+	// SnapshotCompression is the compression of the blocks of the files
+	// written by snapshots, none if empty. Compactions always write
+	// uncompressed blocks.
+	SnapshotCompression string
+
 	formatFileName FormatFileNameFunc
 	parseFileName  ParseFileNameFunc
 
@@ -726,6 +763,14 @@ func NewCompactor() *Compactor {
 	}
 }
 
+// maxFileSize returns the size at which compactions roll over to a new file.
+func (c *Compactor) maxFileSize() uint32 {
+	if c.MaxFileSize == 0 {
+		return maxTSMFileSize
+	}
+	return c.MaxFileSize
+}
+
 func (c *Compactor) WithFormatFileNameFunc(formatFileNameFunc FormatFileNameFunc) {
 	c.formatFileName = formatFileNameFunc
 }
@@ -824,6 +869,11 @@ func (c *Compactor) WriteSnapshot(ctx context.Context, cache *Cache) ([]string,
 		return nil, errSnapshotsDisabled
 	}
 
+	compression, err := snapshotBlockCompression(c.SnapshotCompression)
+	if err != nil {
+		return nil, err
+	}
+
 	start := time.Now()
 	card := cache.Count()
 
@@ -852,14 +902,13 @@ func (c *Compactor) WriteSnapshot(ctx context.Context, cache *Cache) ([]string,
 	resC := make(chan res, concurrency)
 	for i := 0; i < concurrency; i++ {
 		go func(sp *Cache) {
-			iter := NewCacheKeyIterator(sp, MaxPointsPerBlock, intC)
+			iter := newCacheKeyIterator(sp, MaxPointsPerBlock, compression, intC)
 			files, err := c.writeNewFiles(c.FileStore.NextGeneration(), 0, nil, iter, throttle)
 			resC <- res{files: files, err: err}
 
 		}(splits[i])
 	}
 
-	var err error
 	files := make([]string, 0, concurrency)
 	for i := 0; i < concurrency; i++ {
 		result := <-resC
@@ -1122,6 +1171,12 @@ func (c *Compactor) write(path string, iter KeyIterator, throttle bool) (err err
 		}
 	}
 
+	// The files whose blocks may be compressed have their own version, which
+	// the versions unable to decompress the blocks reject.
+	if tw, ok := w.(*tsmWriter); ok && compressesBlocks(iter) {
+		tw.version = CompressedBlocksVersion
+	}
+
 	defer func() {
 		closeErr := w.Close()
 		if err == nil {
@@ -1173,7 +1228,7 @@ func (c *Compactor) write(path string, iter KeyIterator, throttle bool) (err err
 
 		// If we have a max file size configured and we're over it, close out the file
 		// and return the error.
-		if w.Size() > maxTSMFileSize {
+		if w.Size() > c.maxFileSize() {
 			if err := w.WriteIndex(); err != nil {
 				return err
 			}
@@ -1552,8 +1607,13 @@ func (k *tsmKeyIterator) Read() ([]byte, int64, int64, []byte, error) {
 		return nil, 0, 0, nil, k.err
 	}
 
+	// The blocks of snapshots copied as is are stored uncompressed.
 	block := k.merged[0]
-	return block.key, block.minTime, block.maxTime, block.b, k.err
+	b, err := uncompressBlock(block.b)
+	if err != nil {
+		return nil, 0, 0, nil, err
+	}
+	return block.key, block.minTime, block.maxTime, b, k.err
 }
 
 func (k *tsmKeyIterator) Close() error {
@@ -1840,8 +1900,13 @@ func (k *tsmBatchKeyIterator) Read() ([]byte, int64, int64, []byte, error) {
 		return nil, 0, 0, nil, k.err
 	}
 
+	// The blocks of snapshots copied as is are stored uncompressed.
 	block := k.merged[0]
-	return block.key, block.minTime, block.maxTime, block.b, k.err
+	b, err := uncompressBlock(block.b)
+	if err != nil {
+		return nil, 0, 0, nil, err
+	}
+	return block.key, block.minTime, block.maxTime, b, k.err
 }
 
 func (k *tsmBatchKeyIterator) Close() error {
@@ -1862,9 +1927,10 @@ func (k *tsmBatchKeyIterator) Err() error {
 }
 
 type cacheKeyIterator struct {
-	cache *Cache
-	size  int
-	order [][]byte
+	cache       *Cache
+	size        int
+	compression byte
+	order       [][]byte
 
 	i         int
 	blocks    [][]cacheBlock
@@ -1882,6 +1948,12 @@ type cacheBlock struct {
 
 // NewCacheKeyIterator returns a new KeyIterator from a Cache.
 func NewCacheKeyIterator(cache *Cache, size int, interrupt chan struct{}) KeyIterator {
+	return newCacheKeyIterator(cache, size, blockCompressionNone, interrupt)
+}
+
+// newCacheKeyIterator returns a new KeyIterator from a Cache, whose blocks
+// are compressed with compression.
+func newCacheKeyIterator(cache *Cache, size int, compression byte, interrupt chan struct{}) KeyIterator {
 	keys := cache.Keys()
 
 	chans := make([]chan struct{}, len(keys))
@@ -1890,13 +1962,14 @@ func NewCacheKeyIterator(cache *Cache, size int, interrupt chan struct{}) KeyIte
 	}
 
 	cki := &cacheKeyIterator{
-		i:         -1,
-		size:      size,
-		cache:     cache,
-		order:     keys,
-		ready:     chans,
-		blocks:    make([][]cacheBlock, len(keys)),
-		interrupt: interrupt,
+		i:           -1,
+		size:        size,
+		compression: compression,
+		cache:       cache,
+		order:       keys,
+		ready:       chans,
+		blocks:      make([][]cacheBlock, len(keys)),
+		interrupt:   interrupt,
 	}
 	go cki.encode()
 	return cki
@@ -1970,6 +2043,9 @@ func (c *cacheKeyIterator) encode() {
 					default:
 						b, err = Values(values[:end]).Encode(nil)
 					}
+					if err == nil {
+						b = compressBlock(b, c.compression)
+					}
 
 					values = values[end:]
 
@@ -3,7 +3,9 @@ package tsm1_test
 import (
 	"bufio"
 	"context"
+	"encoding/binary"
 	"fmt"
+	"io/ioutil"
 	"math"
 	"os"
 	"path/filepath"
@@ -147,6 +149,132 @@ func TestCompactor_CompactFullLastTimestamp(t *testing.T) {
 	}
 }
 
+func TestCompactor_SnapshotCompression(t *testing.T) {
+	for _, compression := range []string{tsm1.SnapshotCompressionSnappy, tsm1.SnapshotCompressionZstd} {
+		t.Run(compression, func(t *testing.T) {
+			dir := MustTempDir()
+			defer os.RemoveAll(dir)
+
+			// Large integers are stored uncompressed by their encoding.
+			key := []byte("cpu,host=A#!~#value")
+			values := make([]tsm1.Value, 100)
+			for i := range values {
+				values[i] = tsm1.NewValue(int64(i), int64(i%4)<<61)
+			}
+			c := tsm1.NewCache(0)
+			if err := c.Write(key, values); err != nil {
+				t.Fatal(err)
+			}
+
+			fs := &fakeFileStore{}
+			defer fs.Close()
+			compactor := tsm1.NewCompactor()
+			compactor.Dir = dir
+			compactor.FileStore = fs
+			compactor.SnapshotCompression = compression
+			compactor.Open()
+
+			// blockBytes returns the only block of the file and whether it
+			// is compressed.
+			blockBytes := func(file string) (*tsm1.TSMReader, []byte) {
+				r := MustOpenTSMReader(file)
+				entries, err := r.ReadEntries(key, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(entries) != 1 {
+					t.Fatalf("unexpected number of blocks: %d", len(entries))
+				}
+				_, b, err := r.ReadBytes(&entries[0], nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return r, b
+			}
+			checkValues := func(r *tsm1.TSMReader) {
+				got, err := r.ReadAll(key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(got) != len(values) {
+					t.Fatalf("values length mismatch: got %v, exp %v", len(got), len(values))
+				}
+				for i, v := range values {
+					if got[i].UnixNano() != v.UnixNano() || got[i].Value() != v.Value() {
+						t.Fatalf("value mismatch: got %v, exp %v", got[i], v)
+					}
+				}
+			}
+
+			files, err := compactor.WriteSnapshot(context.Background(), c)
+			if err != nil {
+				t.Fatalf("unexpected error writing snapshot: %v", err)
+			}
+			if got, exp := fileVersion(t, files[0]), tsm1.CompressedBlocksVersion; got != exp {
+				t.Fatalf("snapshot file version mismatch: got %v, exp %v", got, exp)
+			}
+			if err := verifyVersion1(files[0]); err == nil {
+				t.Fatalf("readers of version 1 should reject the snapshot file")
+			}
+			r, b := blockBytes(files[0])
+			if b[1] != 0 {
+				t.Fatalf("snapshot block should be compressed")
+			}
+			if got, exp := tsm1.BlockCount(b), len(values); got != exp {
+				t.Fatalf("block count mismatch: got %v, exp %v", got, exp)
+			}
+			checkValues(r)
+			r.Close()
+
+			files, err = compactor.CompactFast(files)
+			if err != nil {
+				t.Fatalf("unexpected error compacting: %v", err)
+			}
+			if got, exp := fileVersion(t, files[0]), tsm1.Version; got != exp {
+				t.Fatalf("compacted file version mismatch: got %v, exp %v", got, exp)
+			}
+			if err := verifyVersion1(files[0]); err != nil {
+				t.Fatalf("readers of version 1 should read the compacted file: %v", err)
+			}
+			r, b = blockBytes(files[0])
+			if b[1] == 0 {
+				t.Fatalf("compacted block should not be compressed")
+			}
+			checkValues(r)
+			r.Close()
+		})
+	}
+}
+
+// fileVersion returns the version in the header of the TSM file at path.
+func fileVersion(t *testing.T, path string) byte {
+	t.Helper()
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) < 5 {
+		t.Fatalf("TSM file too short: %d bytes", len(b))
+	}
+	return b[4]
+}
+
+// verifyVersion1 checks the header of the TSM file at path as the readers
+// released before compressed blocks do, which only read version 1.
+func verifyVersion1(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if len(b) < 5 || binary.BigEndian.Uint32(b[:4]) != tsm1.MagicNumber {
+		return fmt.Errorf("can only read from tsm file")
+	}
+	if b[4] != 1 {
+		return fmt.Errorf("init: file is version %b. expected %b", b[4], 1)
+	}
+	return nil
+}
+
 // Ensures that a compaction will properly merge multiple TSM files
 func TestCompactor_CompactFull(t *testing.T) {
 	dir := MustTempDir()
@@ -1767,6 +1895,46 @@ func TestDefaultPlanner_PlanLevel_SmallestCompactionStep(t *testing.T) {
 	}
 }
 
+func TestDefaultPlanner_PlanLevel_CompactionConfig(t *testing.T) {
+	data := []tsm1.FileStat{
+		{Path: "01-02.tsm1", Size: 1 * 1024 * 1024},
+		{Path: "02-02.tsm1", Size: 1 * 1024 * 1024},
+		{Path: "03-01.tsm1", Size: 1 * 1024 * 1024},
+		{Path: "04-01.tsm1", Size: 1 * 1024 * 1024},
+		{Path: "05-01.tsm1", Size: 1 * 1024 * 1024},
+		{Path: "06-01.tsm1", Size: 1 * 1024 * 1024},
+	}
+
+	cp := tsm1.NewDefaultPlanner(
+		&fakeFileStore{
+			PathsFn: func() []tsm1.FileStat {
+				return data
+			},
+		}, tsm1.DefaultCompactFullWriteColdDuration,
+	)
+
+	// By default, level 1 compacts 8 generations and level 2 compacts 4.
+	if tsm := cp.PlanLevel(1); len(tsm) != 0 {
+		t.Fatalf("unexpected level 1 plan: %v", tsm)
+	}
+
+	config := tsm1.NewConfig().Compaction
+	config.MaxLevel = 1
+	config.LevelGenerations = 2
+	cp.WithCompactionConfig(config)
+
+	tsm := cp.PlanLevel(1)
+	if exp := (tsm1.CompactionGroup{"03-01.tsm1", "04-01.tsm1", "05-01.tsm1", "06-01.tsm1"}); len(tsm) != 1 || !cmp.Equal(tsm[0], exp) {
+		t.Fatalf("unexpected level 1 plan: got %v, exp %v", tsm, exp)
+	}
+	cp.Release(tsm)
+
+	// Levels above the maximum level are left to the optimize planner.
+	if tsm := cp.PlanLevel(2); len(tsm) != 0 {
+		t.Fatalf("unexpected level 2 plan: %v", tsm)
+	}
+}
+
 func TestDefaultPlanner_PlanLevel_SplitFile(t *testing.T) {
 	data := []tsm1.FileStat{
 		{
@@ -1,6 +1,8 @@
 package tsm1
 
 import (
+	"errors"
+	"fmt"
 	"runtime"
 	"time"
 
@@ -35,6 +37,7 @@ type Config struct {
 
 	Compaction CompactionConfig `toml:"compaction"`
 	Cache      CacheConfig      `toml:"cache"`
+	WarmUp     WarmUpConfig     `toml:"warm-up"`
 }
 
 // NewConfig constructs a Config with the default values.
@@ -44,12 +47,18 @@ func NewConfig() Config {
 		MADVWillNeed:              DefaultMADVWillNeed,
 		LargeSeriesWriteThreshold: DefaultLargeSeriesWriteThreshold,
 
-		Cache: NewCacheConfig(),
+		Cache:  NewCacheConfig(),
+		WarmUp: NewWarmUpConfig(),
 		Compaction: CompactionConfig{
 			FullWriteColdDuration: toml.Duration(DefaultCompactFullWriteColdDuration),
 			Throughput:            toml.Size(DefaultCompactThroughput),
 			ThroughputBurst:       toml.Size(DefaultCompactThroughputBurst),
 			MaxConcurrent:         DefaultCompactMaxConcurrent,
+			MaxFileSize:           toml.Size(DefaultCompactMaxFileSize),
+			MaxLevel:              DefaultCompactMaxLevel,
+			LevelGenerations:      DefaultCompactLevelGenerations,
+			ReportWindow:          toml.Duration(DefaultCompactReportWindow),
+			SnapshotCompression:   DefaultCompactSnapshotCompression,
 		},
 	}
 }
@@ -59,6 +68,11 @@ const (
 	DefaultCompactThroughput            = 48 * 1024 * 1024
 	DefaultCompactThroughputBurst       = 48 * 1024 * 1024
 	DefaultCompactMaxConcurrent         = 0
+	DefaultCompactMaxFileSize           = maxTSMFileSize
+	DefaultCompactMaxLevel              = 3
+	DefaultCompactLevelGenerations      = 4
+	DefaultCompactReportWindow          = time.Duration(time.Hour)
+	DefaultCompactSnapshotCompression   = SnapshotCompressionNone
 )
 
 // CompactionConfing holds all of the configuration for compactions. Eventually we want
@@ -82,6 +96,54 @@ type CompactionConfig struct {
 	// MaxConcurrent is the maximum number of concurrent full and level compactions that can
 	// run at one time.  A value of 0 results in 50% of runtime.GOMAXPROCS(0) used at runtime.
 	MaxConcurrent int `toml:"max-concurrent"`
+
+	// MaxFileSize is the size at which compactions roll over to a new TSM file. Files
+	// over this size holding full blocks are no longer compacted, unless they have
+	// tombstones. It can not exceed the default of 2GB.
+	MaxFileSize toml.Size `toml:"max-file-size"`
+
+	// MaxLevel is the highest level, from 1 to 3, of the level compactions. The files
+	// of the higher levels are only rewritten by optimize and full compactions, so a
+	// lower value writes each point fewer times at the cost of more, smaller files.
+	MaxLevel int `toml:"max-level"`
+
+	// LevelGenerations is the number of generations of a level compacted together
+	// into the next level. Level 1 compacts twice as many generations since cache
+	// snapshots are small.
+	LevelGenerations int `toml:"level-generations"`
+
+	// ReportWindow is the period over which the bytes written by snapshots and
+	// compactions are reported to compute the write amplification.
+	ReportWindow toml.Duration `toml:"report-window"`
+
+	// SnapshotCompression is the compression, none, snappy or zstd, of the
+	// blocks of the TSM files written by cache snapshots. It reduces the bytes
+	// written during ingest spikes at the cost of CPU, and the level
+	// compactions store the blocks uncompressed again. The snapshot files
+	// written with a compression have the CompressedBlocksVersion of the TSM
+	// format, which the versions without the option refuse to open, so that
+	// downgrading requires the files to be compacted first.
+	SnapshotCompression string `toml:"snapshot-compression"`
+}
+
+// Validate returns an error if the compaction configuration is invalid.
+func (c CompactionConfig) Validate() error {
+	if c.MaxFileSize <= 0 || uint64(c.MaxFileSize) > uint64(maxTSMFileSize) {
+		return fmt.Errorf("compaction max-file-size must be between 1 and %d", maxTSMFileSize)
+	}
+	if c.MaxLevel < 1 || c.MaxLevel > 3 {
+		return errors.New("compaction max-level must be between 1 and 3")
+	}
+	if c.LevelGenerations < 2 {
+		return errors.New("compaction level-generations must be at least 2")
+	}
+	if c.ReportWindow <= 0 {
+		return errors.New("compaction report-window must be positive")
+	}
+	if _, err := snapshotBlockCompression(c.SnapshotCompression); err != nil {
+		return fmt.Errorf("compaction snapshot-compression: %v", err)
+	}
+	return nil
 }
 
 // Default Cache configuration values.
@@ -125,6 +187,39 @@ func NewCacheConfig() CacheConfig {
 	}
 }
 
+// Default warm-up configuration values.
+const (
+	DefaultWarmUpEnabled    = false
+	DefaultWarmUpMaxBuckets = 10
+	DefaultWarmUpBlocksAge  = time.Duration(0)
+)
+
+// WarmUpConfig holds the configuration of the warm-up phase that follows the
+// opening of the engine. The buckets most recently queried before the engine
+// was closed have their TSM index pages, and optionally their recent blocks,
+// read back into the page cache to avoid slow queries after a restart.
+type WarmUpConfig struct {
+	// Enabled controls whether recently queried buckets are recorded on close
+	// and warmed up on open.
+	Enabled bool `toml:"enabled"`
+
+	// MaxBuckets is the maximum number of recently queried buckets to record.
+	MaxBuckets int `toml:"max-buckets"`
+
+	// BlocksAge, when set, also warms up the blocks containing values
+	// more recent than this duration. Only index pages are read otherwise.
+	BlocksAge toml.Duration `toml:"blocks-age"`
+}
+
+// NewWarmUpConfig initialises a new WarmUpConfig with default values.
+func NewWarmUpConfig() WarmUpConfig {
+	return WarmUpConfig{
+		Enabled:    DefaultWarmUpEnabled,
+		MaxBuckets: DefaultWarmUpMaxBuckets,
+		BlocksAge:  toml.Duration(DefaultWarmUpBlocksAge),
+	}
+}
+
 // Default WAL configuration values.
 const (
 	DefaultWALEnabled    = true
@@ -184,6 +184,55 @@ func BlockCount(block []byte) int {
 	return CountTimestamps(tb)
 }
 
+// BlockEncodings returns the names of the encodings of the timestamps and of
+// the values of block.
+func BlockEncodings(block []byte) (timestamps, values string, err error) {
+	if len(block) <= encodedBlockHeaderSize {
+		return "", "", fmt.Errorf("encoded block too short: got %v, exp %v", len(block), encodedBlockHeaderSize)
+	}
+	typ, err := BlockType(block)
+	if err != nil {
+		return "", "", err
+	}
+	tb, vb, err := unpackBlock(block[1:])
+	if err != nil {
+		return "", "", err
+	}
+	if len(tb) == 0 || len(vb) == 0 {
+		return "", "", fmt.Errorf("empty timestamps or values")
+	}
+
+	switch tb[0] >> 4 {
+	case timeUncompressed:
+		timestamps = "uncompressed"
+	case timeCompressedPackedSimple:
+		timestamps = "simple8b"
+	case timeCompressedRLE:
+		timestamps = "rle"
+	default:
+		timestamps = fmt.Sprintf("unknown(%d)", tb[0]>>4)
+	}
+
+	enc := vb[0] >> 4
+	switch {
+	case typ == BlockFloat64 && enc == floatCompressedGorilla:
+		values = "gorilla"
+	case (typ == BlockInteger || typ == BlockUnsigned) && enc == intUncompressed:
+		values = "uncompressed"
+	case (typ == BlockInteger || typ == BlockUnsigned) && enc == intCompressedSimple:
+		values = "simple8b"
+	case (typ == BlockInteger || typ == BlockUnsigned) && enc == intCompressedRLE:
+		values = "rle"
+	case typ == BlockBoolean && enc == booleanCompressedBitPacked:
+		values = "bitpacked"
+	case typ == BlockString && enc == stringCompressedSnappy:
+		values = "snappy"
+	default:
+		values = fmt.Sprintf("unknown(%d)", enc)
+	}
+	return timestamps, values, nil
+}
+
 // DecodeBlock takes a byte slice and decodes it into values of the appropriate type
 // based on the block.
 func DecodeBlock(block []byte, vals []Value) ([]Value, error) {
@@ -769,6 +818,13 @@ func packBlock(buf []byte, typ byte, ts []byte, values []byte) []byte {
 }
 
 func unpackBlock(buf []byte) (ts, values []byte, err error) {
+	// Snapshot blocks may be compressed as a whole
+	if isCompressedBlock(buf) {
+		if buf, err = decompressBlock(buf); err != nil {
+			return
+		}
+	}
+
 	// Unpack the timestamp block length
 	tsLen, i := binary.Uvarint(buf)
 	if i <= 0 {
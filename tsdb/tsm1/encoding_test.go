@@ -293,6 +293,39 @@ func TestEncoding_BlockType(t *testing.T) {
 	}
 }
 
+func TestEncoding_BlockEncodings(t *testing.T) {
+	tests := []struct {
+		values     []tsm1.Value
+		timestamps string
+		encoding   string
+	}{
+		{values: []tsm1.Value{tsm1.NewValue(0, 1.5), tsm1.NewValue(10, 2.5)}, timestamps: "rle", encoding: "gorilla"},
+		{values: []tsm1.Value{tsm1.NewValue(0, int64(1)), tsm1.NewValue(10, int64(2)), tsm1.NewValue(20, int64(3))}, timestamps: "rle", encoding: "rle"},
+		{values: []tsm1.Value{tsm1.NewValue(0, uint64(1)), tsm1.NewValue(3, uint64(7)), tsm1.NewValue(10, uint64(2))}, timestamps: "simple8b", encoding: "simple8b"},
+		{values: []tsm1.Value{tsm1.NewValue(0, true)}, timestamps: "simple8b", encoding: "bitpacked"},
+		{values: []tsm1.Value{tsm1.NewValue(0, "string")}, timestamps: "simple8b", encoding: "snappy"},
+	}
+
+	for _, test := range tests {
+		b, err := tsm1.Values(test.values).Encode(nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		ts, enc, err := tsm1.BlockEncodings(b)
+		if err != nil {
+			t.Fatalf("unexpected error decoding block encodings: %v", err)
+		}
+		if ts != test.timestamps || enc != test.encoding {
+			t.Fatalf("encodings mismatch: got %s/%s, exp %s/%s", ts, enc, test.timestamps, test.encoding)
+		}
+	}
+
+	if _, _, err := tsm1.BlockEncodings([]byte{10}); err == nil {
+		t.Fatalf("expected error decoding block encodings, got nil")
+	}
+}
+
 func TestEncoding_Count(t *testing.T) {
 	tests := []struct {
 		value     interface{}
@@ -124,6 +124,16 @@ type Engine struct {
 	snapDone chan struct{}   // channel to signal snapshot compactions to stop
 	snapWG   *sync.WaitGroup // waitgroup for running snapshot compactions
 
+	// The following group of fields tracks level compactions paused by an operator via
+	// PauseCompactions. A paused engine holds one of the levelWorkers until it is resumed,
+	// either explicitly or by pauseTimer firing. pauseClosed is set while the engine is
+	// closed, so that a pauseTimer which has already fired does not restart compactions.
+	pauseMu     sync.Mutex
+	paused      bool
+	pauseClosed bool
+	pauseTimer  *time.Timer
+	pauseExpiry time.Time
+
 	path     string
 	sfile    *tsdb.SeriesFile
 	sfileref *lifecycle.Reference
@@ -155,12 +165,15 @@ type Engine struct {
 	// Controls whether to enabled compactions when the engine is open
 	enableCompactionsOnOpen bool
 
-	compactionTracker   *compactionTracker // Used to track state of compactions.
-	readTracker         *readTracker       // Used to track number of reads.
-	defaultMetricLabels prometheus.Labels  // N.B this must not be mutated after Open is called.
+	compactionTracker      *compactionTracker // Used to track state of compactions.
+	compactionReportWindow time.Duration      // Period over which bytes written by compactions are reported.
+	readTracker            *readTracker       // Used to track number of reads.
+	defaultMetricLabels    prometheus.Labels  // N.B this must not be mutated after Open is called.
 
 	// Limiter for concurrent compactions.
 	compactionLimiter limiter.Fixed
+	// Accounts for the working memory of compactions in a memory budget.
+	compactionMemory *limiter.MemoryConsumer
 	// A semaphore for limiting full compactions across multiple engines.
 	fullCompactionSemaphore influxdb.Semaphore
 	// Tracks how long the last full compaction took. Should be accessed atomically.
@@ -168,6 +181,14 @@ type Engine struct {
 
 	scheduler   *scheduler
 	snapshotter Snapshotter
+
+	// The following group of fields records the most recently queried buckets, which
+	// are warmed up in the background when the engine is next opened. warmUp is nil
+	// if warm-up is disabled.
+	warmUp       *warmUpTracker
+	warmUpConfig WarmUpConfig
+	warmUpCancel context.CancelFunc
+	warmUpWG     sync.WaitGroup
 }
 
 // NewEngine returns a new instance of Engine.
@@ -184,6 +205,11 @@ func NewEngine(path string, idx *tsi1.Index, config Config, options ...EngineOpt
 	c.RateLimit = limiter.NewRate(
 		int(config.Compaction.Throughput),
 		int(config.Compaction.ThroughputBurst))
+	c.MaxFileSize = uint32(config.Compaction.MaxFileSize)
+	c.SnapshotCompression = config.Compaction.SnapshotCompression
+
+	planner := NewDefaultPlanner(fs, time.Duration(config.Compaction.FullWriteColdDuration))
+	planner.WithCompactionConfig(config.Compaction)
 
 	// determine max concurrent compactions informed by the system
 	maxCompactions := config.Compaction.MaxConcurrent
@@ -214,10 +240,9 @@ func NewEngine(path string, idx *tsi1.Index, config Config, options ...EngineOpt
 
 		Cache: cache,
 
-		FileStore: fs,
-		Compactor: c,
-		CompactionPlan: NewDefaultPlanner(fs,
-			time.Duration(config.Compaction.FullWriteColdDuration)),
+		FileStore:      fs,
+		Compactor:      c,
+		CompactionPlan: planner,
 
 		CacheFlushMemorySizeThreshold:  uint64(config.Cache.SnapshotMemorySize),
 		CacheFlushWriteColdDuration:    time.Duration(config.Cache.SnapshotWriteColdDuration),
@@ -228,6 +253,12 @@ func NewEngine(path string, idx *tsi1.Index, config Config, options ...EngineOpt
 		fullCompactionSemaphore:        influxdb.NopSemaphore,
 		scheduler:                      newScheduler(maxCompactions),
 		snapshotter:                    new(noSnapshotter),
+		warmUpConfig:                   config.WarmUp,
+		compactionReportWindow:         time.Duration(config.Compaction.ReportWindow),
+	}
+
+	if config.WarmUp.Enabled {
+		e.warmUp = newWarmUpTracker()
 	}
 
 	for _, option := range options {
@@ -249,6 +280,15 @@ func (e *Engine) WithCompactionLimiter(limiter limiter.Fixed) {
 	e.compactionLimiter = limiter
 }
 
+// WithMemoryBudget accounts for the size of the cache and the working memory
+// of compactions in a memory budget shared with other consumers, such as
+// queries. Writes fail when the budget is exhausted, and compactions are
+// deferred until enough memory is released.
+func (e *Engine) WithMemoryBudget(budget *limiter.Memory) {
+	e.Cache.SetMemoryBudget(budget.Consumer("cache"))
+	e.compactionMemory = budget.Consumer("compaction")
+}
+
 func (e *Engine) WithFormatFileNameFunc(formatFileNameFunc FormatFileNameFunc) {
 	e.Compactor.WithFormatFileNameFunc(formatFileNameFunc)
 	e.formatFileName = formatFileNameFunc
@@ -463,6 +503,79 @@ func (e *Engine) ScheduleFullCompaction(ctx context.Context) error {
 	return nil
 }
 
+// PauseCompactions stops level compactions until ResumeCompactions is called. Running
+// level compactions are aborted. Snapshot compactions continue to run so that the
+// cache can still be flushed to disk.
+//
+// If d is positive, compactions are resumed automatically once d has elapsed. Pausing
+// an engine which is already paused replaces any previous timeout.
+func (e *Engine) PauseCompactions(d time.Duration) {
+	e.pauseMu.Lock()
+	defer e.pauseMu.Unlock()
+
+	if e.pauseTimer != nil {
+		e.pauseTimer.Stop()
+		e.pauseTimer = nil
+	}
+
+	if e.pauseClosed {
+		return
+	}
+
+	if !e.paused {
+		e.disableLevelCompactions(true)
+		e.paused = true
+	}
+
+	e.pauseExpiry = time.Time{}
+	if d > 0 {
+		e.pauseExpiry = time.Now().Add(d)
+		e.pauseTimer = time.AfterFunc(d, e.ResumeCompactions)
+	}
+
+	e.logger.Info("Level compactions paused", zap.Duration("timeout", d))
+}
+
+// ResumeCompactions restarts level compactions stopped by PauseCompactions.
+// It is a nop if compactions are not paused.
+func (e *Engine) ResumeCompactions() {
+	e.pauseMu.Lock()
+	defer e.pauseMu.Unlock()
+
+	if e.pauseTimer != nil {
+		e.pauseTimer.Stop()
+		e.pauseTimer = nil
+	}
+
+	if !e.paused || e.pauseClosed {
+		return
+	}
+
+	e.paused = false
+	e.pauseExpiry = time.Time{}
+	e.enableLevelCompactions(true)
+
+	e.logger.Info("Level compactions resumed")
+}
+
+// CompactionsPaused returns true if level compactions have been paused via
+// PauseCompactions, and the time at which they will automatically resume. The
+// returned time is zero if there is no timeout.
+func (e *Engine) CompactionsPaused() (bool, time.Time) {
+	e.pauseMu.Lock()
+	defer e.pauseMu.Unlock()
+	return e.paused, e.pauseExpiry
+}
+
+// WriteAmplification returns the bytes written by the snapshots and
+// compactions of the open engine over its compaction report window.
+func (e *Engine) WriteAmplification() WriteAmplification {
+	if e.compactionTracker == nil || e.compactionTracker.writes == nil {
+		return WriteAmplification{}
+	}
+	return e.compactionTracker.writes.report()
+}
+
 // Path returns the path the engine was opened with.
 func (e *Engine) Path() string { return e.path }
 
@@ -511,6 +624,7 @@ func (e *Engine) initTrackers() {
 
 	// Propagate prometheus metrics down into trackers.
 	e.compactionTracker = newCompactionTracker(bms.compactionMetrics, e.defaultMetricLabels)
+	e.compactionTracker.writes = newWriteTracker(e.compactionReportWindow)
 	e.FileStore.tracker = newFileTracker(bms.fileMetrics, e.defaultMetricLabels)
 	e.Cache.tracker = newCacheTracker(bms.cacheMetrics, e.defaultMetricLabels)
 	e.readTracker = newReadTracker(bms.readMetrics, e.defaultMetricLabels)
@@ -555,15 +669,39 @@ func (e *Engine) Open(ctx context.Context) (err error) {
 
 	e.Compactor.Open()
 
+	e.pauseMu.Lock()
+	e.pauseClosed = false
+	e.pauseMu.Unlock()
+
 	if e.enableCompactionsOnOpen {
 		e.SetCompactionsEnabled(true)
 	}
 
+	e.startWarmUp()
+
 	return nil
 }
 
 // Close closes the engine. Subsequent calls to Close are a nop.
 func (e *Engine) Close() error {
+	// Drop any pause, releasing the level worker it holds without restarting
+	// level compactions, so that they are enabled again when the engine is reopened.
+	e.pauseMu.Lock()
+	if e.pauseTimer != nil {
+		e.pauseTimer.Stop()
+		e.pauseTimer = nil
+	}
+	if e.paused {
+		e.mu.Lock()
+		e.levelWorkers--
+		e.mu.Unlock()
+		e.paused = false
+		e.pauseExpiry = time.Time{}
+	}
+	e.pauseClosed = true
+	e.pauseMu.Unlock()
+
+	e.stopWarmUp()
 	e.SetCompactionsEnabled(false)
 
 	// Lock now and close everything else down.
@@ -673,6 +811,8 @@ type compactionTracker struct {
 	// 4 	– Optimize compactions
 	// 5	– Full compactions
 
+	writes *writeTracker // Bytes written by snapshots and compactions, if tracked.
+
 	ok     [6]uint64 // Counter of TSM compactions (by level) that have successfully completed.
 	active [6]uint64 // Gauge of TSM compactions (by level) currently running.
 	errors [6]uint64 // Counter of TSM compcations (by level) that have failed due to error.
@@ -774,6 +914,14 @@ func (t *compactionTracker) Attempted(level compactionLevel, success bool, reaso
 	t.metrics.Compactions.With(labels).Inc()
 }
 
+// Written records the bytes written by a successful snapshot (level 0) or
+// compaction of the provided level.
+func (t *compactionTracker) Written(level compactionLevel, bytes uint64) {
+	if t.writes != nil {
+		t.writes.add(level, bytes)
+	}
+}
+
 // SnapshotAttempted updates the number of snapshots attempted.
 func (t *compactionTracker) SnapshotAttempted(success bool, reason CacheStatus, duration time.Duration) {
 	t.Attempted(0, success, reason.String(), duration)
@@ -871,6 +1019,7 @@ func (e *Engine) writeSnapshotAndCommit(ctx context.Context, log *zap.Logger, sn
 		return err
 	}
 
+	size := filesSize(newFiles)
 	return e.snapshotter.CommitSegments(ctx, segments, func() error {
 		e.mu.RLock()
 		defer e.mu.RUnlock()
@@ -880,6 +1029,7 @@ func (e *Engine) writeSnapshotAndCommit(ctx context.Context, log *zap.Logger, sn
 			log.Info("Error adding new TSM files from snapshot", zap.Error(err))
 			return err
 		}
+		e.compactionTracker.Written(0, size)
 
 		// clear the snapshot from the in-memory cache
 		e.Cache.ClearSnapshot(true)
@@ -934,6 +1084,7 @@ const (
 	CacheStatusRetention                         // The cache was snapshotted before running retention.
 	CacheStatusFullCompaction                    // The cache was snapshotted as part of a full compaction.
 	CacheStatusBackup                            // The cache was snapshotted before running backup.
+	CacheStatusCopy                              // The cache was snapshotted before copying a bucket.
 )
 
 // ShouldCompactCache returns a status indicating if the Cache should be
@@ -1064,14 +1215,14 @@ func (e *Engine) compactHiPriorityLevel(ctx context.Context, grp CompactionGroup
 	}
 
 	// Try hi priority limiter, otherwise steal a little from the low priority if we can.
-	if e.compactionLimiter.TryTake() {
+	if release := e.takeCompaction(grp); release != nil {
 		e.compactionTracker.IncActive(level)
 
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			defer e.compactionTracker.DecActive(level)
-			defer e.compactionLimiter.Release()
+			defer release()
 			s.Apply(ctx)
 			// Release the files in the compaction plan
 			e.CompactionPlan.Release([]CompactionGroup{s.group})
@@ -1092,13 +1243,13 @@ func (e *Engine) compactLoPriorityLevel(ctx context.Context, grp CompactionGroup
 	}
 
 	// Try the lo priority limiter, otherwise steal a little from the high priority if we can.
-	if e.compactionLimiter.TryTake() {
+	if release := e.takeCompaction(grp); release != nil {
 		e.compactionTracker.IncActive(level)
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			defer e.compactionTracker.DecActive(level)
-			defer e.compactionLimiter.Release()
+			defer release()
 			s.Apply(ctx)
 			// Release the files in the compaction plan
 			e.CompactionPlan.Release([]CompactionGroup{s.group})
@@ -1117,7 +1268,7 @@ func (e *Engine) compactFull(ctx context.Context, grp CompactionGroup, wg *sync.
 	}
 
 	// Try the lo priority limiter, otherwise steal a little from the high priority if we can.
-	if e.compactionLimiter.TryTake() {
+	if release := e.takeCompaction(grp); release != nil {
 		// Attempt to get ownership of the semaphore for this engine. If the
 		// default semaphore is in use then ownership will always be granted.
 		ttl := influxdb.DefaultLeaseTTL
@@ -1129,11 +1280,11 @@ func (e *Engine) compactFull(ctx context.Context, grp CompactionGroup, wg *sync.
 		lease, err := e.fullCompactionSemaphore.TryAcquire(ctx, ttl)
 		if err == influxdb.ErrNoAcquire {
 			e.logger.Info("Cannot acquire semaphore ownership to carry out full compaction", zap.Duration("semaphore_requested_ttl", ttl))
-			e.compactionLimiter.Release()
+			release()
 			return false
 		} else if err != nil {
 			e.logger.Warn("Failed to execute full compaction", zap.Error(err), zap.Duration("semaphore_requested_ttl", ttl))
-			e.compactionLimiter.Release()
+			release()
 			return false
 		} else if e.fullCompactionSemaphore != influxdb.NopSemaphore {
 			e.logger.Info("Acquired semaphore ownership for full compaction", zap.Duration("semaphore_requested_ttl", ttl))
@@ -1147,7 +1298,7 @@ func (e *Engine) compactFull(ctx context.Context, grp CompactionGroup, wg *sync.
 		go func() {
 			defer wg.Done()
 			defer e.compactionTracker.DecFullActive()
-			defer e.compactionLimiter.Release()
+			defer release()
 
 			now := time.Now() // Track how long compaction takes
 			s.Apply(ctx)
@@ -1162,6 +1313,33 @@ func (e *Engine) compactFull(ctx context.Context, grp CompactionGroup, wg *sync.
 	return false
 }
 
+// compactionMemoryPerFile is the estimated working memory of a compaction for
+// each TSM file it merges, holding the decoded blocks of the file and the
+// buffers of its block iterator.
+const compactionMemoryPerFile = 4 << 20
+
+// takeCompaction takes a token of the compaction limiter and reserves the
+// working memory of a compaction of grp in the memory budget. It returns a
+// function releasing both, or nil if either is not available.
+func (e *Engine) takeCompaction(grp CompactionGroup) (release func()) {
+	if !e.compactionLimiter.TryTake() {
+		return nil
+	}
+	mem := int64(len(grp)) * compactionMemoryPerFile
+	if limit := e.compactionMemory.Limit(); limit > 0 && mem > limit {
+		// Large groups would otherwise never be compacted.
+		mem = limit
+	}
+	if !e.compactionMemory.TryReserve(mem) {
+		e.compactionLimiter.Release()
+		return nil
+	}
+	return func() {
+		e.compactionMemory.Release(mem)
+		e.compactionLimiter.Release()
+	}
+}
+
 // keepLeaseAlive blocks, keeping a lease alive until the context is cancelled.
 func (e *Engine) keepLeaseAlive(ctx context.Context, lease influxdb.Lease) {
 	ttl, err := lease.TTL(ctx)
@@ -1257,6 +1435,7 @@ func (s *compactionStrategy) compactGroup(ctx context.Context) {
 		return
 	}
 
+	size := filesSize(files)
 	if err := s.fileStore.ReplaceWithCallback(group, files, nil); err != nil {
 		tracing.LogError(span, err)
 		log.Info("Error replacing new TSM files", zap.Error(err))
@@ -1279,6 +1458,7 @@ func (s *compactionStrategy) compactGroup(ctx context.Context) {
 	}
 	log.Info("Finished compacting files", zap.Int("tsm1_files_n", len(files)))
 	s.tracker.Attempted(s.level, true, "", time.Since(now))
+	s.tracker.Written(s.level, size)
 }
 
 // levelCompactionStrategy returns a compactionStrategy for the given level.
@@ -0,0 +1,200 @@
+package tsm1
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/influxdata/influxdb/kit/tracing"
+)
+
+// CopyStats summarizes the data copied by CopyPrefixRange.
+type CopyStats struct {
+	Keys      int   // number of distinct keys copied
+	Blocks    int   // number of blocks copied verbatim
+	Reencoded int   // number of blocks decoded to drop values outside of the range or deleted
+	Bytes     int64 // size of the blocks written
+}
+
+// CopyPrefixRange copies the values within [min, max] of the keys starting
+// with src to the same keys with src replaced by dst.
+//
+// Only TSM files are read, so the cache must be snapshotted beforehand for
+// recent writes to be copied. Blocks entirely within the range and without
+// deleted values are copied verbatim, while the others are decoded,
+// filtered and re-encoded. Each TSM file is copied to a new file, so that
+// the copied values of a key overwrite each other in the same order as the
+// original ones, and overwrite any value of the destination key with the
+// same timestamp, as a write would.
+//
+// Before the new files become visible, created is called with the copied
+// keys and their block types, so that the caller can index them.
+func (e *Engine) CopyPrefixRange(ctx context.Context, src, dst []byte, min, max int64, created func(keys [][]byte, types []byte) error) (stats CopyStats, err error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	span.LogKV("src_prefix", fmt.Sprintf("%x", src), "dst_prefix", fmt.Sprintf("%x", dst),
+		"min", time.Unix(0, min), "max", time.Unix(0, max))
+	defer span.Finish()
+
+	// Hold a reference to the files so that compactions do not remove them
+	// while they are being copied.
+	var files []TSMFile
+	e.FileStore.ForEachFile(func(f TSMFile) bool {
+		f.Ref()
+		files = append(files, f)
+		return true
+	})
+	defer func() {
+		for _, f := range files {
+			f.Unref()
+		}
+	}()
+
+	var newFiles []string
+	defer func() {
+		if err != nil {
+			for _, path := range newFiles {
+				os.Remove(path)
+				os.Remove(StatsFilename(path))
+			}
+		}
+	}()
+
+	keys := make(map[string]byte)
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		if !f.OverlapsTimeRange(min, max) {
+			continue
+		}
+
+		path := filepath.Join(e.path, e.formatFileName(e.FileStore.NextGeneration(), 1)+"."+TSMFileExtension+"."+TmpTSMFileExtension)
+		ok, err := e.copyFile(f, path, src, dst, min, max, keys, &stats)
+		if err != nil {
+			return stats, fmt.Errorf("unable to copy %s: %v", f.Path(), err)
+		} else if ok {
+			newFiles = append(newFiles, path)
+		}
+	}
+	if len(newFiles) == 0 {
+		return stats, nil
+	}
+
+	stats.Keys = len(keys)
+	if created != nil {
+		ks, types := make([][]byte, 0, len(keys)), make([]byte, 0, len(keys))
+		for k, typ := range keys {
+			ks, types = append(ks, []byte(k)), append(types, typ)
+		}
+		if err := created(ks, types); err != nil {
+			return stats, err
+		}
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return stats, e.FileStore.Replace(nil, newFiles)
+}
+
+// copyFile writes the copied blocks of the file f to a new TSM file at path.
+// It returns false, and removes the new file, if no block was copied.
+func (e *Engine) copyFile(f TSMFile, path string, src, dst []byte, min, max int64, keys map[string]byte, stats *CopyStats) (ok bool, err error) {
+	r, isReader := f.(*TSMReader)
+	if !isReader {
+		return false, fmt.Errorf("unsupported TSM file type %T", f)
+	}
+
+	fd, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_EXCL, 0666)
+	if err != nil {
+		return false, err
+	}
+	w, err := NewTSMWriter(fd)
+	if err != nil {
+		fd.Close()
+		return false, err
+	}
+	defer func() {
+		if err != nil || !ok {
+			w.Remove()
+		}
+	}()
+
+	var (
+		dstKey     []byte
+		values     []Value
+		tombstones []TimeRange
+	)
+	r.mu.RLock()
+	iter := r.index.Iterator(src)
+	r.mu.RUnlock()
+	for iter.Next() {
+		key := iter.Key()
+		if !bytes.HasPrefix(key, src) {
+			break
+		}
+		// The writer retains the key, so it can not be reused.
+		dstKey = append(append(make([]byte, 0, len(dst)+len(key)-len(src)), dst...), key[len(src):]...)
+		tombstones = r.TombstoneRange(key, tombstones[:0])
+
+		for _, entry := range iter.Entries() {
+			if entry.MaxTime < min || entry.MinTime > max {
+				continue
+			}
+
+			_, block, err := r.ReadBytes(&entry, nil)
+			if err != nil {
+				return false, err
+			}
+
+			if entry.MinTime < min || entry.MaxTime > max || overlapsTombstones(tombstones, entry.MinTime, entry.MaxTime) {
+				if values, err = DecodeBlock(block, values[:0]); err != nil {
+					return false, err
+				}
+				kept := Values(values).Include(min, max)
+				for _, tr := range tombstones {
+					kept = kept.Exclude(tr.Min, tr.Max)
+				}
+				if len(kept) == 0 {
+					continue
+				}
+				if block, err = kept.Encode(nil); err != nil {
+					return false, err
+				}
+				entry.MinTime, entry.MaxTime = kept.MinTime(), kept.MaxTime()
+				stats.Reencoded++
+			} else {
+				stats.Blocks++
+			}
+
+			if err := w.WriteBlock(dstKey, entry.MinTime, entry.MaxTime, block); err != nil {
+				return false, err
+			}
+			stats.Bytes += int64(len(block))
+			keys[string(dstKey)] = iter.Type()
+			ok = true
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, nil
+	}
+
+	if err := w.WriteIndex(); err != nil {
+		return false, err
+	}
+	return true, w.Close()
+}
+
+func overlapsTombstones(tombstones []TimeRange, min, max int64) bool {
+	for _, tr := range tombstones {
+		if tr.Min <= max && tr.Max >= min {
+			return true
+		}
+	}
+	return false
+}
@@ -19,8 +19,21 @@ import (
 // and series file data associated with the bucket. The provided time range ensures
 // that only bucket data for that range is removed.
 func (e *Engine) DeletePrefixRange(rootCtx context.Context, name []byte, min, max int64, pred Predicate) error {
+	return e.deletePrefixRange(rootCtx, name, nil, min, max, pred)
+}
+
+// DeleteMeasurementRange removes the TSM data of the series of a measurement
+// belonging to a bucket, and their index and series file data. Unlike
+// DeletePrefixRange with an equivalent predicate, only the keys of the
+// measurement are visited, and a single tombstone is written to each file.
+func (e *Engine) DeleteMeasurementRange(rootCtx context.Context, name, measurement []byte, min, max int64, pred Predicate) error {
+	return e.deletePrefixRange(rootCtx, name, measurement, min, max, pred)
+}
+
+func (e *Engine) deletePrefixRange(rootCtx context.Context, name, measurement []byte, min, max int64, pred Predicate) error {
 	span, ctx := tracing.StartSpanFromContext(rootCtx)
 	span.LogKV("name_prefix", fmt.Sprintf("%x", name),
+		"measurement", string(measurement),
 		"min", time.Unix(0, min), "max", time.Unix(0, max),
 		"has_pred", pred != nil,
 	)
@@ -91,20 +104,30 @@ func (e *Engine) DeletePrefixRange(rootCtx context.Context, name []byte, min, ma
 		span.LogKV("file_path", r.Path())
 		defer span.Finish()
 
-		return r.DeletePrefix(name, min, max, predClone, func(key []byte) {
+		dead := func(key []byte) {
 			possiblyDead.Lock()
 			possiblyDead.keys[string(key)] = struct{}{}
 			possiblyDead.Unlock()
-		})
+		}
+		if measurement != nil {
+			return r.DeleteMeasurement(name, measurement, min, max, predClone, dead)
+		}
+		return r.DeletePrefix(name, min, max, predClone, dead)
 	}); err != nil {
 		return err
 	}
 
+	// The cache and the files are scanned for the keys of the measurement only.
+	keyPrefix := name
+	if measurement != nil {
+		keyPrefix = MeasurementPrefix(name, measurement)
+	}
+
 	span, _ = tracing.StartSpanFromContextWithOperationName(rootCtx, "Cache find delete keys")
 	span.LogKV("cache_size", e.Cache.Size())
 	var keysChecked int // For tracing information.
 	// ApplySerialEntryFn cannot return an error in this invocation.
-	nameStr := string(name)
+	nameStr := string(keyPrefix)
 	_ = e.Cache.ApplyEntryFn(func(k string, _ *entry) error {
 		keysChecked++
 		if !strings.HasPrefix(k, nameStr) {
@@ -146,10 +169,10 @@ func (e *Engine) DeletePrefixRange(rootCtx context.Context, name []byte, min, ma
 		defer possiblyDead.RUnlock()
 
 		var keysChecked int
-		iter := r.Iterator(name)
+		iter := r.Iterator(keyPrefix)
 		for i := 0; iter.Next(); i++ {
 			key := iter.Key()
-			if !bytes.HasPrefix(key, name) {
+			if !bytes.HasPrefix(key, keyPrefix) {
 				break
 			}
 			if predClone != nil && !predClone.Matches(key) {
@@ -207,7 +230,7 @@ func (e *Engine) DeletePrefixRange(rootCtx context.Context, name []byte, min, ma
 		// the deletes of the data in the tsm files.
 
 		// In this case the entire measurement (bucket) can be removed from the index.
-		if min == math.MinInt64 && max == math.MaxInt64 && pred == nil {
+		if min == math.MinInt64 && max == math.MaxInt64 && pred == nil && measurement == nil {
 			// The TSI index and Series File do not store series data in escaped form.
 			name = models.UnescapeMeasurement(name)
 
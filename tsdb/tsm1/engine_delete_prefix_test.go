@@ -119,3 +119,58 @@ func TestEngine_DeletePrefix(t *testing.T) {
 		}
 	}
 }
+
+func TestEngine_DeleteMeasurementRange(t *testing.T) {
+	p1 := MustParsePointString("cpu,host=A value=1.1 1", "mm0")
+	p2 := MustParsePointString("cpu,host=B value=1.2 5", "mm0")
+	p3 := MustParsePointString("cpu2,host=A value=1.3 1", "mm0")
+	p4 := MustParsePointString("mem,host=A value=1.4 1", "mm0")
+	p5 := MustParsePointString("cpu,host=A value=1.5 1", "mm1")
+
+	e, err := NewEngine(tsm1.NewConfig(), t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	if err := e.writePoints(p1, p2, p3, p4, p5); err != nil {
+		t.Fatalf("failed to write points: %s", err.Error())
+	}
+	if err := e.WriteSnapshot(context.Background(), tsm1.CacheStatusColdNoWrites); err != nil {
+		t.Fatalf("failed to snapshot: %s", err.Error())
+	}
+
+	// Only the values of host=A are within the range, so host=B is kept.
+	if err := e.DeleteMeasurementRange(context.Background(), []byte("mm0"), []byte("cpu"), 0, 3, nil); err != nil {
+		t.Fatalf("failed to delete measurement: %v", err)
+	}
+
+	exp := map[string]byte{
+		"mm0,\x00=cpu,host=B,\xff=value#!~#value":  0,
+		"mm0,\x00=cpu2,host=A,\xff=value#!~#value": 0,
+		"mm0,\x00=mem,host=A,\xff=value#!~#value":  0,
+		"mm1,\x00=cpu,host=A,\xff=value#!~#value":  0,
+	}
+	if keys := e.FileStore.Keys(); !reflect.DeepEqual(keys, exp) {
+		t.Fatalf("unexpected series in file store: %v != %v", keys, exp)
+	}
+
+	// The measurement tombstone is applied when the files are reopened.
+	if err := e.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	if keys := e.FileStore.Keys(); !reflect.DeepEqual(keys, exp) {
+		t.Fatalf("unexpected series in file store after reopen: %v != %v", keys, exp)
+	}
+
+	if err := e.DeleteMeasurementRange(context.Background(), []byte("mm0"), []byte("cpu"), 0, 9, nil); err != nil {
+		t.Fatalf("failed to delete measurement: %v", err)
+	}
+	delete(exp, "mm0,\x00=cpu,host=B,\xff=value#!~#value")
+	if keys := e.FileStore.Keys(); !reflect.DeepEqual(keys, exp) {
+		t.Fatalf("unexpected series in file store: %v != %v", keys, exp)
+	}
+}
@@ -240,6 +240,74 @@ func TestEngine_DisableEnableCompactions_Concurrent(t *testing.T) {
 	}
 }
 
+func TestEngine_PauseResumeCompactions(t *testing.T) {
+	e := MustOpenEngine(t)
+	defer e.Close()
+
+	if paused, _ := e.CompactionsPaused(); paused {
+		t.Fatal("expected compactions to be running")
+	}
+
+	e.PauseCompactions(0)
+	if paused, expiry := e.CompactionsPaused(); !paused || !expiry.IsZero() {
+		t.Fatalf("unexpected pause state; got (%v, %v)", paused, expiry)
+	}
+
+	// Pausing again is idempotent and only replaces the timeout.
+	e.PauseCompactions(time.Hour)
+	if paused, expiry := e.CompactionsPaused(); !paused || expiry.IsZero() {
+		t.Fatalf("unexpected pause state; got (%v, %v)", paused, expiry)
+	}
+
+	e.ResumeCompactions()
+	if paused, _ := e.CompactionsPaused(); paused {
+		t.Fatal("expected compactions to be resumed")
+	}
+
+	// Resuming an engine which is not paused is a nop.
+	e.ResumeCompactions()
+}
+
+func TestEngine_PauseCompactions_Timeout(t *testing.T) {
+	e := MustOpenEngine(t)
+	defer e.Close()
+
+	e.PauseCompactions(10 * time.Millisecond)
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if paused, _ := e.CompactionsPaused(); !paused {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for compactions to resume")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestEngine_PauseCompactions_Close(t *testing.T) {
+	e := MustOpenEngine(t)
+	defer e.Close()
+
+	e.PauseCompactions(10 * time.Millisecond)
+	if err := e.Engine.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if paused, _ := e.CompactionsPaused(); paused {
+		t.Fatal("expected compactions not to be paused once closed")
+	}
+
+	// Neither the timeout nor an explicit resume restart level compactions
+	// of the closed engine.
+	time.Sleep(50 * time.Millisecond)
+	e.ResumeCompactions()
+	if _, err := e.Compactor.CompactFull(nil); err == nil || err.Error() != "compactions disabled" {
+		t.Fatalf("expected level compactions to be disabled, got %v", err)
+	}
+}
+
 func BenchmarkEngine_WritePoints(b *testing.B) {
 	batchSizes := []int{10, 100, 1000, 5000, 10000}
 	for _, sz := range batchSizes {
@@ -359,6 +427,72 @@ func BenchmarkEngine_DeletePrefixRange_Cache(b *testing.B) {
 	e.Close()
 }
 
+func TestEngine_WarmUp(t *testing.T) {
+	config := tsm1.NewConfig()
+	config.WarmUp.Enabled = true
+	config.WarmUp.BlocksAge = toml.Duration(time.Hour)
+
+	e, err := NewEngine(config, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	var (
+		org       = influxdb.ID(0x10)
+		queried   = influxdb.ID(0x20)
+		unqueried = influxdb.ID(0x30)
+		now       = time.Now().UnixNano()
+	)
+	e.MustWritePointsString(org, queried, fmt.Sprintf("cpu,host=a value=1 %d", now))
+	e.MustWritePointsString(org, unqueried, fmt.Sprintf("cpu,host=b value=2 %d", now))
+	e.MustWriteSnapshot()
+
+	// Query the first bucket only.
+	ctx := context.Background()
+	itr, err := e.CreateCursorIterator(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := MustParseExplodePoints(org, queried, "cpu,host=a value=1")[0]
+	cur, err := itr.Next(ctx, &tsdb.CursorRequest{Name: p.Name(), Tags: p.Tags(), Field: "value", EndTime: math.MaxInt64, Ascending: true})
+	if err != nil {
+		t.Fatal(err)
+	} else if cur == nil {
+		t.Fatal("expected cursor to be present")
+	}
+	cur.Close()
+
+	checkWarmUpFile := func() {
+		t.Helper()
+		data, err := ioutil.ReadFile(filepath.Join(e.root, "data", tsm1.WarmUpFileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), queried.String()) {
+			t.Fatalf("expected queried bucket to be recorded: %s", data)
+		}
+		if strings.Contains(string(data), unqueried.String()) {
+			t.Fatalf("unexpected bucket recorded: %s", data)
+		}
+	}
+
+	// The queried bucket is recorded on close and warmed up on open.
+	if err := e.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	checkWarmUpFile()
+
+	// Buckets loaded from the previous run are kept when not queried again.
+	if err := e.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	checkWarmUpFile()
+}
+
 // Engine is a test wrapper for tsm1.Engine.
 type Engine struct {
 	*tsm1.Engine
@@ -366,6 +500,7 @@ type Engine struct {
 	indexPath string
 	index     *tsi1.Index
 	sfile     *tsdb.SeriesFile
+	config    tsm1.Config
 }
 
 // NewEngine returns a new instance of Engine at a temporary location.
@@ -397,6 +532,7 @@ func NewEngine(config tsm1.Config, tb testing.TB) (*Engine, error) {
 		indexPath: idxPath,
 		index:     idx,
 		sfile:     sfile,
+		config:    config,
 	}, nil
 }
 
@@ -456,8 +592,7 @@ func (e *Engine) Reopen() error {
 	e.index = MustOpenIndex(e.indexPath, tsdb.NewSeriesIDSet(), e.sfile)
 
 	// Re-initialize engine.
-	config := tsm1.NewConfig()
-	e.Engine = tsm1.NewEngine(filepath.Join(e.root, "data"), e.index, config,
+	e.Engine = tsm1.NewEngine(filepath.Join(e.root, "data"), e.index, e.config,
 		tsm1.WithCompactionPlanner(newMockPlanner()))
 
 	// Reopen engine
@@ -0,0 +1,77 @@
+package tsm1
+
+import (
+	"bytes"
+	"math"
+	"strings"
+)
+
+// TimeRange returns the minimum and maximum time of the values of the keys
+// with the given prefix, or false if there is no such key. The range may be
+// larger than that of the values, as it spans the time range of every TSM
+// file holding such a key, including deleted values not yet compacted away.
+func (e *Engine) TimeRange(prefix []byte) (min, max int64, ok bool) {
+	min, max = math.MaxInt64, math.MinInt64
+
+	// The cache is read first, so that the values of a snapshot being
+	// written are found either in the snapshot or in the new TSM file.
+	if cmin, cmax, cok := e.Cache.timeRange(prefix); cok {
+		min, max, ok = cmin, cmax, true
+	}
+
+	e.FileStore.ForEachFile(func(f TSMFile) bool {
+		if !f.OverlapsKeyPrefixRange(prefix, prefix) {
+			return true
+		}
+		if iter := f.Iterator(prefix); !iter.Next() || !bytes.HasPrefix(iter.Key(), prefix) {
+			return true
+		}
+		fmin, fmax := f.TimeRange()
+		if fmin < min {
+			min = fmin
+		}
+		if fmax > max {
+			max = fmax
+		}
+		ok = true
+		return true
+	})
+	return min, max, ok
+}
+
+// timeRange returns the minimum and maximum time of the values of the keys
+// with the given prefix in the cache and its snapshot, or false if there is
+// no such key.
+func (c *Cache) timeRange(prefix []byte) (min, max int64, ok bool) {
+	min, max = math.MaxInt64, math.MinInt64
+
+	c.mu.RLock()
+	stores := []*ring{c.store}
+	if c.snapshot != nil {
+		stores = append(stores, c.snapshot.store)
+	}
+	c.mu.RUnlock()
+
+	prefixStr := string(prefix)
+	for _, store := range stores {
+		_ = store.applySerial(func(key string, e *entry) error {
+			if !strings.HasPrefix(key, prefixStr) {
+				return nil
+			}
+			e.mu.RLock()
+			for _, v := range e.values {
+				t := v.UnixNano()
+				if t < min {
+					min = t
+				}
+				if t > max {
+					max = t
+				}
+				ok = true
+			}
+			e.mu.RUnlock()
+			return nil
+		})
+	}
+	return min, max, ok
+}
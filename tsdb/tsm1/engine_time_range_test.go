@@ -0,0 +1,57 @@
+package tsm1_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+func TestEngine_TimeRange(t *testing.T) {
+	e, err := NewEngine(tsm1.NewConfig(), t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	check := func(prefix string, wantMin, wantMax int64, wantOK bool) {
+		t.Helper()
+		min, max, ok := e.TimeRange([]byte(prefix))
+		if ok != wantOK || (ok && (min != wantMin || max != wantMax)) {
+			t.Fatalf("unexpected time range of %q: got (%d, %d, %v), want (%d, %d, %v)",
+				prefix, min, max, ok, wantMin, wantMax, wantOK)
+		}
+	}
+
+	check("mm0", 0, 0, false)
+
+	// Values of the cache.
+	if err := e.writePoints(
+		MustParsePointString("cpu,host=A value=1 20", "mm0"),
+		MustParsePointString("cpu,host=B value=1 30", "mm0"),
+		MustParsePointString("cpu,host=A value=1 5", "mm1"),
+	); err != nil {
+		t.Fatal(err)
+	}
+	check("mm0", 20, 30, true)
+	check("mm1", 5, 5, true)
+	check("mm2", 0, 0, false)
+
+	// Values of a TSM file and of the cache. The range of a TSM file spans
+	// the values of every key of the file.
+	e.MustWriteSnapshot()
+	if err := e.writePoints(MustParsePointString("cpu,host=A value=1 50", "mm0")); err != nil {
+		t.Fatal(err)
+	}
+	check("mm0", 5, 50, true)
+	check("mm1", 5, 30, true)
+	check("mm2", 0, 0, false)
+
+	e.MustWriteSnapshot()
+	check("mm0", 5, 50, true)
+	check("mm1", 5, 30, true)
+	check("mm2", 0, 0, false)
+}
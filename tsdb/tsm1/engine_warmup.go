@@ -0,0 +1,267 @@
+package tsm1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"hash/crc32"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/fs"
+	"github.com/influxdata/influxdb/tsdb"
+	"go.uber.org/zap"
+)
+
+// WarmUpFileName is the name of the file, within the engine directory, that
+// records the buckets most recently queried before the engine was closed.
+const WarmUpFileName = "warmup.json"
+
+// warmUpBucket is a bucket recorded for warm-up.
+type warmUpBucket struct {
+	OrgID       influxdb.ID `json:"orgID"`
+	BucketID    influxdb.ID `json:"bucketID"`
+	LastQueried time.Time   `json:"lastQueried"`
+}
+
+// warmUpTracker records the last time each bucket was queried.
+type warmUpTracker struct {
+	mu      sync.RWMutex
+	buckets map[[influxdb.IDLength]byte]int64
+}
+
+func newWarmUpTracker() *warmUpTracker {
+	return &warmUpTracker{buckets: make(map[[influxdb.IDLength]byte]int64)}
+}
+
+// touch records that the bucket with the encoded org and bucket name was queried.
+// Consecutive queries within a second are only recorded once, so the tracker
+// is cheap to call for every cursor.
+func (t *warmUpTracker) touch(name []byte) {
+	var key [influxdb.IDLength]byte
+	if len(name) != len(key) {
+		return
+	}
+	copy(key[:], name)
+
+	now := time.Now().UnixNano()
+	t.mu.RLock()
+	last, ok := t.buckets[key]
+	t.mu.RUnlock()
+	if ok && now-last < int64(time.Second) {
+		return
+	}
+
+	t.mu.Lock()
+	if last := t.buckets[key]; now > last {
+		t.buckets[key] = now
+	}
+	t.mu.Unlock()
+}
+
+// add records buckets loaded from a previous run, keeping the most recent query time.
+func (t *warmUpTracker) add(buckets []warmUpBucket) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, b := range buckets {
+		key := tsdb.EncodeName(b.OrgID, b.BucketID)
+		if ts := b.LastQueried.UnixNano(); ts > t.buckets[key] {
+			t.buckets[key] = ts
+		}
+	}
+}
+
+// recent returns up to n buckets, most recently queried first.
+func (t *warmUpTracker) recent(n int) []warmUpBucket {
+	t.mu.RLock()
+	buckets := make([]warmUpBucket, 0, len(t.buckets))
+	for key, ts := range t.buckets {
+		orgID, bucketID := tsdb.DecodeName(key)
+		buckets = append(buckets, warmUpBucket{
+			OrgID:       orgID,
+			BucketID:    bucketID,
+			LastQueried: time.Unix(0, ts).UTC(),
+		})
+	}
+	t.mu.RUnlock()
+
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].LastQueried.After(buckets[j].LastQueried)
+	})
+	if n > 0 && len(buckets) > n {
+		buckets = buckets[:n]
+	}
+	return buckets
+}
+
+// readWarmUpFile returns the buckets recorded at path. A missing file records no bucket.
+func readWarmUpFile(path string) ([]warmUpBucket, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var buckets []warmUpBucket
+	if err := json.Unmarshal(data, &buckets); err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+// writeWarmUpFile atomically replaces the file at path with buckets.
+func writeWarmUpFile(path string, buckets []warmUpBucket) error {
+	data, err := json.Marshal(buckets)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + "." + TmpTSMFileExtension
+	if err := ioutil.WriteFile(tmp, data, 0666); err != nil {
+		return err
+	}
+	return fs.RenameFileWithReplacement(tmp, path)
+}
+
+// startWarmUp loads the buckets recorded by the previous run and warms them
+// up in the background. It is a nop unless warm-up is enabled.
+func (e *Engine) startWarmUp() {
+	if e.warmUp == nil {
+		return
+	}
+
+	path := filepath.Join(e.path, WarmUpFileName)
+	buckets, err := readWarmUpFile(path)
+	if err != nil {
+		e.logger.Warn("Unable to read warm-up file", zap.String("path", path), zap.Error(err))
+		return
+	}
+	if len(buckets) == 0 {
+		return
+	}
+	e.warmUp.add(buckets)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.warmUpCancel = cancel
+	e.warmUpWG.Add(1)
+	go func() {
+		defer e.warmUpWG.Done()
+		e.warmUpBuckets(ctx, buckets)
+	}()
+}
+
+// stopWarmUp interrupts any running warm-up and records the most recently
+// queried buckets for the next time the engine is opened.
+func (e *Engine) stopWarmUp() {
+	if e.warmUp == nil {
+		return
+	}
+
+	if e.warmUpCancel != nil {
+		e.warmUpCancel()
+		e.warmUpCancel = nil
+	}
+	e.warmUpWG.Wait()
+
+	buckets := e.warmUp.recent(e.warmUpConfig.MaxBuckets)
+	if len(buckets) == 0 {
+		return
+	}
+
+	path := filepath.Join(e.path, WarmUpFileName)
+	if err := writeWarmUpFile(path, buckets); err != nil {
+		e.logger.Warn("Unable to write warm-up file", zap.String("path", path), zap.Error(err))
+	}
+}
+
+// warmUpBuckets reads the TSM index entries of buckets, and of their recent
+// blocks if configured, so that they are in the page cache for the first queries.
+func (e *Engine) warmUpBuckets(ctx context.Context, buckets []warmUpBucket) {
+	start := time.Now()
+
+	minTime := int64(math.MaxInt64)
+	if age := time.Duration(e.warmUpConfig.BlocksAge); age > 0 {
+		minTime = start.Add(-age).UnixNano()
+	}
+
+	prefixes := make([][]byte, 0, len(buckets))
+	for _, b := range buckets {
+		name := tsdb.EncodeName(b.OrgID, b.BucketID)
+		prefixes = append(prefixes, models.EscapeMeasurement(name[:]))
+	}
+
+	keys, blocks, err := e.FileStore.warmUp(ctx, prefixes, minTime)
+	if err == context.Canceled {
+		e.logger.Info("Warm-up interrupted", zap.Int("keys", keys), zap.Int("blocks", blocks))
+		return
+	} else if err != nil {
+		e.logger.Warn("Warm-up failed", zap.Error(err))
+		return
+	}
+
+	e.logger.Info("Warm-up completed",
+		zap.Int("buckets", len(buckets)),
+		zap.Int("keys", keys),
+		zap.Int("blocks", blocks),
+		zap.Duration("duration", time.Since(start)))
+}
+
+// warmUp reads the index entries of the keys starting with any of prefixes in
+// every TSM file, as well as the blocks of those keys with values at or after
+// minTime. It returns the number of keys and blocks read.
+func (f *FileStore) warmUp(ctx context.Context, prefixes [][]byte, minTime int64) (keys, blocks int, err error) {
+	f.mu.RLock()
+	files := make(unrefs, 0, len(f.files))
+	for _, r := range f.files {
+		r.Ref()
+		files = append(files, r)
+	}
+	f.mu.RUnlock()
+	defer files.Unref()
+
+	type blockReader interface {
+		ReadBytes(e *IndexEntry, b []byte) (uint32, []byte, error)
+	}
+
+	for _, r := range files {
+		br, _ := r.(blockReader)
+		for _, prefix := range prefixes {
+			iter := r.Iterator(prefix)
+			for iter.Next() && bytes.HasPrefix(iter.Key(), prefix) {
+				if err := ctx.Err(); err != nil {
+					return keys, blocks, err
+				}
+				keys++
+
+				entries := iter.Entries()
+				if br == nil {
+					continue
+				}
+				for i := range entries {
+					if entries[i].MaxTime < minTime {
+						continue
+					}
+					_, block, err := br.ReadBytes(&entries[i], nil)
+					if err != nil {
+						return keys, blocks, err
+					}
+					// Blocks are mapped rather than copied, so read them to fault the pages in.
+					crc32.ChecksumIEEE(block)
+					blocks++
+				}
+			}
+			if err := iter.Err(); err != nil {
+				return keys, blocks, err
+			}
+		}
+	}
+	return keys, blocks, nil
+}
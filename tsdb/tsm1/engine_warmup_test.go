@@ -0,0 +1,110 @@
+package tsm1
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+func TestFileStore_WarmUp(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsm1-warmup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	prefix := func(bucketID influxdb.ID) []byte {
+		name := tsdb.EncodeName(0x10, bucketID)
+		return models.EscapeMeasurement(name[:])
+	}
+	key := func(bucketID influxdb.ID, host string) []byte {
+		k := append(prefix(bucketID), ",host="...)
+		return append(k, host+"#!~#value"...)
+	}
+
+	f, err := os.Create(filepath.Join(dir, DefaultFormatFileName(1, 1)+"."+TSMFileExtension))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewTSMWriter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kv := range []struct {
+		key    []byte
+		values []Value
+	}{
+		{key(0x21, "a"), []Value{NewValue(10, 1.0)}},
+		{key(0x21, "a"), []Value{NewValue(100, 1.0)}},
+		{key(0x21, "b"), []Value{NewValue(20, 1.0)}},
+		{key(0x31, "a"), []Value{NewValue(200, 1.0)}},
+	} {
+		if err := w.Write(kv.key, kv.values); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.WriteIndex(); err != nil {
+		t.Fatal(err)
+	} else if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := NewFileStore(dir)
+	if err := fs.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+
+	keys, blocks, err := fs.warmUp(context.Background(), [][]byte{prefix(0x21)}, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys != 2 || blocks != 1 {
+		t.Fatalf("unexpected warm-up: got %d keys and %d blocks, want 2 keys and 1 block", keys, blocks)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := fs.warmUp(ctx, [][]byte{prefix(0x21)}, 0); err != context.Canceled {
+		t.Fatalf("unexpected error: got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWarmUpTracker(t *testing.T) {
+	tr := newWarmUpTracker()
+	a := tsdb.EncodeName(1, 2)
+
+	tr.touch(a[:])
+	tr.touch([]byte("too short"))
+	tr.add([]warmUpBucket{{OrgID: 1, BucketID: 3, LastQueried: time.Now().Add(-time.Hour)}})
+
+	got := tr.recent(1)
+	if len(got) != 1 || got[0].BucketID != 2 {
+		t.Fatalf("unexpected recent buckets: %+v", got)
+	}
+
+	dir, err := ioutil.TempDir("", "tsm1-warmup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, WarmUpFileName)
+	if err := writeWarmUpFile(path, tr.recent(0)); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := readWarmUpFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 2 || loaded[0].BucketID != 2 || loaded[1].BucketID != 3 {
+		t.Fatalf("unexpected loaded buckets: %+v", loaded)
+	}
+}
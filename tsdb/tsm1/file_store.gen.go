@@ -12,6 +12,9 @@ LOOP:
 
 	// First block is the oldest block containing the points we're searching for.
 	first := c.current[0]
+	if err := c.waitRead(first.entry.Size); err != nil {
+		return nil, err
+	}
 	*buf = (*buf)[:0]
 	var values FloatValues
 	values, err := first.r.ReadFloatBlockAt(&first.entry, buf)
@@ -85,6 +88,9 @@ LOOP:
 				continue
 			}
 
+			if err := c.waitRead(cur.entry.Size); err != nil {
+				return nil, err
+			}
 			var a []FloatValue
 			var v FloatValues
 			v, err := cur.r.ReadFloatBlockAt(&cur.entry, &a)
@@ -148,6 +154,9 @@ LOOP:
 				continue
 			}
 
+			if err := c.waitRead(cur.entry.Size); err != nil {
+				return nil, err
+			}
 			var a []FloatValue
 			var v FloatValues
 			v, err := cur.r.ReadFloatBlockAt(&cur.entry, &a)
@@ -198,6 +207,9 @@ LOOP:
 
 	// First block is the oldest block containing the points we're searching for.
 	first := c.current[0]
+	if err := c.waitRead(first.entry.Size); err != nil {
+		return nil, err
+	}
 	*buf = (*buf)[:0]
 	var values IntegerValues
 	values, err := first.r.ReadIntegerBlockAt(&first.entry, buf)
@@ -271,6 +283,9 @@ LOOP:
 				continue
 			}
 
+			if err := c.waitRead(cur.entry.Size); err != nil {
+				return nil, err
+			}
 			var a []IntegerValue
 			var v IntegerValues
 			v, err := cur.r.ReadIntegerBlockAt(&cur.entry, &a)
@@ -334,6 +349,9 @@ LOOP:
 				continue
 			}
 
+			if err := c.waitRead(cur.entry.Size); err != nil {
+				return nil, err
+			}
 			var a []IntegerValue
 			var v IntegerValues
 			v, err := cur.r.ReadIntegerBlockAt(&cur.entry, &a)
@@ -384,6 +402,9 @@ LOOP:
 
 	// First block is the oldest block containing the points we're searching for.
 	first := c.current[0]
+	if err := c.waitRead(first.entry.Size); err != nil {
+		return nil, err
+	}
 	*buf = (*buf)[:0]
 	var values UnsignedValues
 	values, err := first.r.ReadUnsignedBlockAt(&first.entry, buf)
@@ -457,6 +478,9 @@ LOOP:
 				continue
 			}
 
+			if err := c.waitRead(cur.entry.Size); err != nil {
+				return nil, err
+			}
 			var a []UnsignedValue
 			var v UnsignedValues
 			v, err := cur.r.ReadUnsignedBlockAt(&cur.entry, &a)
@@ -520,6 +544,9 @@ LOOP:
 				continue
 			}
 
+			if err := c.waitRead(cur.entry.Size); err != nil {
+				return nil, err
+			}
 			var a []UnsignedValue
 			var v UnsignedValues
 			v, err := cur.r.ReadUnsignedBlockAt(&cur.entry, &a)
@@ -570,6 +597,9 @@ LOOP:
 
 	// First block is the oldest block containing the points we're searching for.
 	first := c.current[0]
+	if err := c.waitRead(first.entry.Size); err != nil {
+		return nil, err
+	}
 	*buf = (*buf)[:0]
 	var values StringValues
 	values, err := first.r.ReadStringBlockAt(&first.entry, buf)
@@ -643,6 +673,9 @@ LOOP:
 				continue
 			}
 
+			if err := c.waitRead(cur.entry.Size); err != nil {
+				return nil, err
+			}
 			var a []StringValue
 			var v StringValues
 			v, err := cur.r.ReadStringBlockAt(&cur.entry, &a)
@@ -706,6 +739,9 @@ LOOP:
 				continue
 			}
 
+			if err := c.waitRead(cur.entry.Size); err != nil {
+				return nil, err
+			}
 			var a []StringValue
 			var v StringValues
 			v, err := cur.r.ReadStringBlockAt(&cur.entry, &a)
@@ -756,6 +792,9 @@ LOOP:
 
 	// First block is the oldest block containing the points we're searching for.
 	first := c.current[0]
+	if err := c.waitRead(first.entry.Size); err != nil {
+		return nil, err
+	}
 	*buf = (*buf)[:0]
 	var values BooleanValues
 	values, err := first.r.ReadBooleanBlockAt(&first.entry, buf)
@@ -829,6 +868,9 @@ LOOP:
 				continue
 			}
 
+			if err := c.waitRead(cur.entry.Size); err != nil {
+				return nil, err
+			}
 			var a []BooleanValue
 			var v BooleanValues
 			v, err := cur.r.ReadBooleanBlockAt(&cur.entry, &a)
@@ -892,6 +934,9 @@ LOOP:
 				continue
 			}
 
+			if err := c.waitRead(cur.entry.Size); err != nil {
+				return nil, err
+			}
 			var a []BooleanValue
 			var v BooleanValues
 			v, err := cur.r.ReadBooleanBlockAt(&cur.entry, &a)
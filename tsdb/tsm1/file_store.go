@@ -119,6 +119,10 @@ type TSMFile interface {
 	// any keys that became dead as a result of this call.
 	DeletePrefix(prefix []byte, min, max int64, pred Predicate, dead func([]byte)) error
 
+	// DeleteMeasurement removes the values for the keys of the measurement beginning
+	// with prefix. It calls dead with any keys that became dead as a result of this call.
+	DeleteMeasurement(prefix, measurement []byte, min, max int64, pred Predicate, dead func([]byte)) error
+
 	// HasTombstones returns true if file contains values that have been deleted.
 	HasTombstones() bool
 
@@ -1333,8 +1337,9 @@ type KeyCursor struct {
 	current []*location
 	buf     []Value
 
-	ctx context.Context
-	col *metrics.Group
+	ctx  context.Context
+	col  *metrics.Group
+	rate limiter.Rate
 
 	// pos is the index within seeks.  Based on ascending, it will increment or
 	// decrement through the size of seeks slice.
@@ -1395,6 +1400,7 @@ func newKeyCursor(ctx context.Context, fs *FileStore, key []byte, t int64, ascen
 		seeks:     fs.locations(key, t, ascending),
 		ctx:       ctx,
 		col:       metrics.GroupFromContext(ctx),
+		rate:      limiter.RateFromContext(ctx),
 		ascending: ascending,
 	}
 
@@ -1425,6 +1431,25 @@ func (c *KeyCursor) Close() {
 	c.current = nil
 }
 
+// waitRead waits until the read rate limit of the cursor, if any, allows
+// reading a block of size bytes.
+func (c *KeyCursor) waitRead(size uint32) error {
+	if c.rate == nil {
+		return nil
+	}
+	for n := int(size); n > 0; {
+		wait := n
+		if b := c.rate.Burst(); wait > b {
+			wait = b
+		}
+		if err := c.rate.WaitN(c.ctx, wait); err != nil {
+			return err
+		}
+		n -= wait
+	}
+	return nil
+}
+
 // seek positions the cursor at the given time.
 func (c *KeyCursor) seek(t int64) {
 	if len(c.seeks) == 0 {
@@ -18,6 +18,9 @@ LOOP:
 
 	// First block is the oldest block containing the points we're searching for.
 	first := c.current[0]
+	if err := c.waitRead(first.entry.Size); err != nil {
+		return nil, err
+	}
 	err := first.r.ReadFloatArrayBlockAt(&first.entry, values)
 	if err != nil {
 		return nil, err
@@ -89,6 +92,9 @@ LOOP:
 				continue
 			}
 
+			if err := c.waitRead(cur.entry.Size); err != nil {
+				return nil, err
+			}
 			v := &tsdb.FloatArray{}
 			err := cur.r.ReadFloatArrayBlockAt(&cur.entry, v)
 			if err != nil {
@@ -151,6 +157,9 @@ LOOP:
 				continue
 			}
 
+			if err := c.waitRead(cur.entry.Size); err != nil {
+				return nil, err
+			}
 			v := &tsdb.FloatArray{}
 			err := cur.r.ReadFloatArrayBlockAt(&cur.entry, v)
 			if err != nil {
@@ -202,6 +211,9 @@ LOOP:
 
 	// First block is the oldest block containing the points we're searching for.
 	first := c.current[0]
+	if err := c.waitRead(first.entry.Size); err != nil {
+		return nil, err
+	}
 	err := first.r.ReadIntegerArrayBlockAt(&first.entry, values)
 	if err != nil {
 		return nil, err
@@ -273,6 +285,9 @@ LOOP:
 				continue
 			}
 
+			if err := c.waitRead(cur.entry.Size); err != nil {
+				return nil, err
+			}
 			v := &tsdb.IntegerArray{}
 			err := cur.r.ReadIntegerArrayBlockAt(&cur.entry, v)
 			if err != nil {
@@ -335,6 +350,9 @@ LOOP:
 				continue
 			}
 
+			if err := c.waitRead(cur.entry.Size); err != nil {
+				return nil, err
+			}
 			v := &tsdb.IntegerArray{}
 			err := cur.r.ReadIntegerArrayBlockAt(&cur.entry, v)
 			if err != nil {
@@ -386,6 +404,9 @@ LOOP:
 
 	// First block is the oldest block containing the points we're searching for.
 	first := c.current[0]
+	if err := c.waitRead(first.entry.Size); err != nil {
+		return nil, err
+	}
 	err := first.r.ReadUnsignedArrayBlockAt(&first.entry, values)
 	if err != nil {
 		return nil, err
@@ -457,6 +478,9 @@ LOOP:
 				continue
 			}
 
+			if err := c.waitRead(cur.entry.Size); err != nil {
+				return nil, err
+			}
 			v := &tsdb.UnsignedArray{}
 			err := cur.r.ReadUnsignedArrayBlockAt(&cur.entry, v)
 			if err != nil {
@@ -519,6 +543,9 @@ LOOP:
 				continue
 			}
 
+			if err := c.waitRead(cur.entry.Size); err != nil {
+				return nil, err
+			}
 			v := &tsdb.UnsignedArray{}
 			err := cur.r.ReadUnsignedArrayBlockAt(&cur.entry, v)
 			if err != nil {
@@ -570,6 +597,9 @@ LOOP:
 
 	// First block is the oldest block containing the points we're searching for.
 	first := c.current[0]
+	if err := c.waitRead(first.entry.Size); err != nil {
+		return nil, err
+	}
 	err := first.r.ReadStringArrayBlockAt(&first.entry, values)
 	if err != nil {
 		return nil, err
@@ -641,6 +671,9 @@ LOOP:
 				continue
 			}
 
+			if err := c.waitRead(cur.entry.Size); err != nil {
+				return nil, err
+			}
 			v := &tsdb.StringArray{}
 			err := cur.r.ReadStringArrayBlockAt(&cur.entry, v)
 			if err != nil {
@@ -703,6 +736,9 @@ LOOP:
 				continue
 			}
 
+			if err := c.waitRead(cur.entry.Size); err != nil {
+				return nil, err
+			}
 			v := &tsdb.StringArray{}
 			err := cur.r.ReadStringArrayBlockAt(&cur.entry, v)
 			if err != nil {
@@ -754,6 +790,9 @@ LOOP:
 
 	// First block is the oldest block containing the points we're searching for.
 	first := c.current[0]
+	if err := c.waitRead(first.entry.Size); err != nil {
+		return nil, err
+	}
 	err := first.r.ReadBooleanArrayBlockAt(&first.entry, values)
 	if err != nil {
 		return nil, err
@@ -825,6 +864,9 @@ LOOP:
 				continue
 			}
 
+			if err := c.waitRead(cur.entry.Size); err != nil {
+				return nil, err
+			}
 			v := &tsdb.BooleanArray{}
 			err := cur.r.ReadBooleanArrayBlockAt(&cur.entry, v)
 			if err != nil {
@@ -887,6 +929,9 @@ LOOP:
 				continue
 			}
 
+			if err := c.waitRead(cur.entry.Size); err != nil {
+				return nil, err
+			}
 			v := &tsdb.BooleanArray{}
 			err := cur.r.ReadBooleanArrayBlockAt(&cur.entry, v)
 			if err != nil {
@@ -15,6 +15,7 @@ import (
 
 	"github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/pkg/fs"
+	"github.com/influxdata/influxdb/pkg/limiter"
 	"github.com/influxdata/influxdb/tsdb/tsm1"
 )
 
@@ -94,6 +95,73 @@ func TestFileStore_SeekToAsc_FromStart(t *testing.T) {
 	}
 }
 
+// testRate records the bytes waited for by a read rate limit.
+type testRate struct {
+	burst int
+	waits []int
+	err   error
+}
+
+func (r *testRate) WaitN(ctx context.Context, n int) error {
+	r.waits = append(r.waits, n)
+	return r.err
+}
+
+func (r *testRate) Burst() int { return r.burst }
+
+func TestFileStore_KeyCursor_ReadRateLimit(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+	fs := tsm1.NewFileStore(dir)
+
+	data := []keyValues{
+		keyValues{"cpu", []tsm1.Value{tsm1.NewValue(0, 1.0)}},
+		keyValues{"cpu", []tsm1.Value{tsm1.NewValue(1, 2.0)}},
+	}
+
+	files, err := newFiles(dir, data...)
+	if err != nil {
+		t.Fatalf("unexpected error creating files: %v", err)
+	}
+
+	fs.Replace(nil, files)
+
+	// Blocks larger than the burst of the rate are waited for in several steps.
+	rate := &testRate{burst: 4}
+	ctx := limiter.NewContextWithRate(context.Background(), rate)
+	buf := make([]tsm1.FloatValue, 1000)
+	c := fs.KeyCursor(ctx, []byte("cpu"), 0, true)
+	for i := range data {
+		values, err := c.ReadFloatBlock(&buf)
+		if err != nil {
+			t.Fatalf("unexpected error reading values: %v", err)
+		}
+		if got, exp := len(values), 1; got != exp {
+			t.Fatalf("value length mismatch(%d): got %v, exp %v", i, got, exp)
+		}
+		c.Next()
+	}
+	c.Close()
+
+	if len(rate.waits) <= len(data) {
+		t.Fatalf("expected blocks to be waited for in several steps, got %v", rate.waits)
+	}
+	for _, n := range rate.waits {
+		if n <= 0 || n > rate.burst {
+			t.Fatalf("unexpected wait of %d bytes with a burst of %d", n, rate.burst)
+		}
+	}
+
+	// The error of the rate, such as that of a cancelled query, is returned.
+	rate = &testRate{burst: 1 << 20, err: context.Canceled}
+	ctx = limiter.NewContextWithRate(context.Background(), rate)
+	c = fs.KeyCursor(ctx, []byte("cpu"), 0, true)
+	defer c.Close()
+	if _, err := c.ReadFloatBlock(&buf); err != context.Canceled {
+		t.Fatalf("expected the error of the rate, got %v", err)
+	}
+}
+
 func TestFileStore_SeekToAsc_Duplicate(t *testing.T) {
 	dir := MustTempDir()
 	defer os.RemoveAll(dir)
@@ -186,6 +186,40 @@ func TestFloatEncoder_Roundtrip_NaN(t *testing.T) {
 	}
 }
 
+func TestFloatEncoder_Roundtrip_Inf(t *testing.T) {
+	values := []float64{1.0, math.Inf(1), math.Inf(-1), 2.0}
+
+	s := tsm1.NewFloatEncoder()
+	for _, v := range values {
+		s.Write(v)
+	}
+	s.Flush()
+
+	b, err := s.Bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var it tsm1.FloatDecoder
+	if err := it.SetBytes(b); err != nil {
+		t.Fatalf("unexpected error creating float decoder: %v", err)
+	}
+	for _, w := range values {
+		if !it.Next() {
+			t.Fatalf("Next()=false, want true")
+		}
+		if vv := it.Values(); vv != w {
+			t.Errorf("Values()=(%v), want (%v)\n", vv, w)
+		}
+	}
+	if it.Next() {
+		t.Fatalf("Next()=true, want false")
+	}
+	if err := it.Error(); err != nil {
+		t.Errorf("it.Error()=%v, want nil", err)
+	}
+}
+
 func TestFloatEncoder_Empty(t *testing.T) {
 	s := tsm1.NewFloatEncoder()
 	s.Flush()
@@ -6,6 +6,7 @@ import (
 	"regexp"
 
 	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/storage/reads/datatypes"
 )
 
@@ -175,6 +176,51 @@ func walkPredicateNodes(node *datatypes.Node, fn func(node *datatypes.Node)) {
 	}
 }
 
+// PredicateMeasurement returns the measurement that every key matched by pred
+// belongs to, if pred requires the measurement to be equal to a value, either
+// alone or as an operand of an AND.
+func PredicateMeasurement(pred Predicate) ([]byte, bool) {
+	p, ok := pred.(*predicateMatcher)
+	if !ok {
+		return nil, false
+	}
+	return predicateNodeMeasurement(p.pred.Root)
+}
+
+func predicateNodeMeasurement(node *datatypes.Node) ([]byte, bool) {
+	children := node.GetChildren()
+	if len(children) != 2 {
+		return nil, false
+	}
+
+	switch node.GetNodeType() {
+	case datatypes.NodeTypeComparisonExpression:
+		if node.GetComparison() != datatypes.ComparisonEqual {
+			return nil, false
+		}
+		ref, lit := children[0], children[1]
+		if ref.GetNodeType() != datatypes.NodeTypeTagRef {
+			ref, lit = lit, ref
+		}
+		if ref.GetNodeType() != datatypes.NodeTypeTagRef || ref.GetTagRefValue() != models.MeasurementTagKey {
+			return nil, false
+		}
+		if v, ok := lit.GetValue().(*datatypes.Node_StringValue); ok {
+			return []byte(v.StringValue), true
+		}
+
+	case datatypes.NodeTypeLogicalExpression:
+		if node.GetLogical() != datatypes.LogicalAnd {
+			return nil, false
+		}
+		if m, ok := predicateNodeMeasurement(children[0]); ok {
+			return m, true
+		}
+		return predicateNodeMeasurement(children[1])
+	}
+	return nil, false
+}
+
 // buildPredicateNode takes a protobuf node and converts it into a predicateNode. It is strict
 // in what it accepts.
 func buildPredicateNode(state *predicateState, node *datatypes.Node) (predicateNode, error) {
@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/storage/reads/datatypes"
 )
 
@@ -308,6 +309,78 @@ func TestPredicate_Unmarshal_Empty(t *testing.T) {
 	}
 }
 
+func TestPredicateMeasurement(t *testing.T) {
+	measurement := tagNode(models.MeasurementTagKey)
+	cases := []struct {
+		Name        string
+		Predicate   *datatypes.Predicate
+		Measurement string
+		OK          bool
+	}{
+		{
+			Name: "Measurement",
+			Predicate: predicate(
+				comparisonNode(datatypes.ComparisonEqual, measurement, stringNode("cpu"))),
+			Measurement: "cpu",
+			OK:          true,
+		},
+
+		{
+			Name: "Measurement And Tag",
+			Predicate: predicate(
+				andNode(
+					comparisonNode(datatypes.ComparisonEqual, tagNode("host"), stringNode("a")),
+					comparisonNode(datatypes.ComparisonEqual, measurement, stringNode("cpu")))),
+			Measurement: "cpu",
+			OK:          true,
+		},
+
+		{
+			Name: "Measurement Or Tag",
+			Predicate: predicate(
+				orNode(
+					comparisonNode(datatypes.ComparisonEqual, tagNode("host"), stringNode("a")),
+					comparisonNode(datatypes.ComparisonEqual, measurement, stringNode("cpu")))),
+		},
+
+		{
+			Name: "Measurement Not Equal",
+			Predicate: predicate(
+				comparisonNode(datatypes.ComparisonNotEqual, measurement, stringNode("cpu"))),
+		},
+
+		{
+			Name: "Measurement Regex",
+			Predicate: predicate(
+				comparisonNode(datatypes.ComparisonRegex, measurement, regexNode("^cpu"))),
+		},
+
+		{
+			Name: "Tag",
+			Predicate: predicate(
+				comparisonNode(datatypes.ComparisonEqual, tagNode("host"), stringNode("a"))),
+		},
+	}
+
+	for _, test := range cases {
+		t.Run(test.Name, func(t *testing.T) {
+			pred, err := NewProtobufPredicate(test.Predicate)
+			if err != nil {
+				t.Fatal("compile failure:", err)
+			}
+
+			m, ok := PredicateMeasurement(pred)
+			if ok != test.OK || string(m) != test.Measurement {
+				t.Fatalf("got (%q, %v), exp (%q, %v)", m, ok, test.Measurement, test.OK)
+			}
+		})
+	}
+
+	if _, ok := PredicateMeasurement(nil); ok {
+		t.Fatal("expected no measurement for a nil predicate")
+	}
+}
+
 func TestPredicate_Invalid_Protobuf(t *testing.T) {
 	cases := []struct {
 		Name      string
@@ -111,7 +111,7 @@ func (t *TSMReader) applyTombstones() error {
 			if err != nil {
 				return err
 			}
-			t.index.DeletePrefix(ts.Key, ts.Min, ts.Max, pred, nil)
+			t.index.DeletePrefix(ts.KeyPrefix(), ts.Min, ts.Max, pred, nil)
 			return nil
 		}
 
@@ -357,6 +357,34 @@ func (t *TSMReader) DeletePrefix(prefix []byte, minTime, maxTime int64,
 	return nil
 }
 
+// DeleteMeasurement removes the given points for the keys of the measurement
+// beginning with prefix. It calls dead with any keys that became dead as a
+// result of this call.
+func (t *TSMReader) DeleteMeasurement(prefix, measurement []byte, minTime, maxTime int64,
+	pred Predicate, dead func([]byte)) error {
+
+	// Marshal the predicate if passed for adding to the tombstone.
+	var predData []byte
+	if pred != nil {
+		var err error
+		predData, err = pred.Marshal()
+		if err != nil {
+			return err
+		}
+	}
+
+	if !t.index.DeletePrefix(MeasurementPrefix(prefix, measurement), minTime, maxTime, pred, dead) {
+		return nil
+	}
+	if err := t.tombstoner.AddMeasurementRange(prefix, measurement, minTime, maxTime, predData); err != nil {
+		return err
+	}
+	if err := t.tombstoner.Flush(); err != nil {
+		return err
+	}
+	return nil
+}
+
 // Iterator returns an iterator over the keys starting at the provided key. You must
 // call Next before calling any of the accessors.
 func (t *TSMReader) Iterator(key []byte) TSMIterator {
@@ -39,8 +39,18 @@ Tombstone file format:
 ║ └──────┘└───────────────┘└────────────┘└────────────────────────┘└───────────────┘└───────────────┘ ║
 ╚═════════════════════════════════════════════════════════════════════════════════════════════════════╝
 
-NOTE: v1, v2 and v3 tombstone supports have been dropped from 2.x. Only v4 is now
-supported.
+The Reserved bits hold the Predicate bit, set when the Max Time is followed by
+an 8 byte predicate length and the marshaled predicate, and, in v5 files only,
+the Measurement bit, set when the predicate is followed by a 4 byte measurement
+length and the measurement name. A prefix tombstone with a measurement only
+deletes the series of that measurement, so that its keys can be found without
+scanning every key of the prefix.
+
+A v4 file is upgraded to v5 by rewriting its header when the first tombstone
+with a measurement is added to it, as v5 entries are a superset of v4 entries.
+
+NOTE: v1, v2 and v3 tombstone supports have been dropped from 2.x. Only v4 and
+v5 are now supported.
 */
 
 import (
@@ -56,12 +66,14 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/pkg/fs"
 )
 
 const (
 	headerSize = 4
 	v4header   = 0x1504
+	v5header   = 0x1505
 )
 
 var errIncompatibleV4Version = errors.New("incompatible v4 version")
@@ -90,6 +102,7 @@ type Tombstoner struct {
 	gz                *gzip.Writer
 	bw                *bufio.Writer
 	pendingFile       *os.File
+	pendingHeader     uint32
 	tmp               [8]byte
 	lastAppliedOffset int64
 
@@ -120,6 +133,18 @@ type Tombstone struct {
 
 	// Predicate stores the marshaled form of some predicate for matching keys.
 	Predicate []byte
+
+	// Measurement optionally restricts a prefix tombstone to the keys of the
+	// measurement with this name.
+	Measurement []byte
+}
+
+// KeyPrefix returns the prefix of the keys a prefix tombstone applies to.
+func (t Tombstone) KeyPrefix() []byte {
+	if len(t.Measurement) == 0 {
+		return t.Key
+	}
+	return MeasurementPrefix(t.Key, t.Measurement)
 }
 
 func (t Tombstone) String() string {
@@ -127,9 +152,21 @@ func (t Tombstone) String() string {
 	if t.Prefix {
 		prefix = "Prefix"
 	}
+	if len(t.Measurement) > 0 {
+		return fmt.Sprintf("%s: %q, measurement: %q, [%d, %d] pred:%v", prefix, t.Key, t.Measurement, t.Min, t.Max, len(t.Predicate) > 0)
+	}
 	return fmt.Sprintf("%s: %q, [%d, %d] pred:%v", prefix, t.Key, t.Min, t.Max, len(t.Predicate) > 0)
 }
 
+// MeasurementPrefix returns the prefix of the keys of the series of the
+// measurement within the escaped prefix name, such as an org and bucket name.
+func MeasurementPrefix(name, measurement []byte) []byte {
+	tags := models.Tags{models.NewTag(models.MeasurementTagKeyBytes, measurement)}
+	key := tags.AppendHashKey(append(make([]byte, 0, len(name)+len(measurement)+4), name...))
+	// Every key has a field tag after the measurement tag.
+	return append(key, ',')
+}
+
 // WithObserver sets a FileStoreObserver for when the tombstone file is written.
 func (t *Tombstoner) WithObserver(obs FileStoreObserver) {
 	if obs == nil {
@@ -151,7 +188,7 @@ func (t *Tombstoner) AddPrefixRange(key []byte, min, max int64, predicate []byte
 
 	t.statsLoaded = false
 
-	if err := t.prepareLatest(); err != nil {
+	if err := t.prepareLatest(v4header); err != nil {
 		return err
 	}
 
@@ -164,6 +201,35 @@ func (t *Tombstoner) AddPrefixRange(key []byte, min, max int64, predicate []byte
 	})
 }
 
+// AddMeasurementRange adds a prefix-based tombstone key restricted to the
+// series of a measurement with an explicit range. It upgrades the tombstone
+// file to v5.
+func (t *Tombstoner) AddMeasurementRange(key, measurement []byte, min, max int64, predicate []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// If this TSMFile has not been written (mainly in tests), don't write a
+	// tombstone because the keys will not be written when it's actually saved.
+	if t.Path == "" {
+		return nil
+	}
+
+	t.statsLoaded = false
+
+	if err := t.prepareLatest(v5header); err != nil {
+		return err
+	}
+
+	return t.writeTombstoneV4(t.gz, Tombstone{
+		Key:         key,
+		Min:         min,
+		Max:         max,
+		Prefix:      true,
+		Predicate:   predicate,
+		Measurement: measurement,
+	})
+}
+
 // Add adds the all keys, across all timestamps, to the tombstone.
 func (t *Tombstoner) Add(keys [][]byte) error {
 	return t.AddRange(keys, math.MinInt64, math.MaxInt64)
@@ -190,7 +256,7 @@ func (t *Tombstoner) AddRange(keys [][]byte, min, max int64) error {
 
 	t.statsLoaded = false
 
-	if err := t.prepareLatest(); err != nil {
+	if err := t.prepareLatest(v4header); err != nil {
 		return err
 	}
 
@@ -242,6 +308,63 @@ func (t *Tombstoner) Delete() error {
 	return nil
 }
 
+// Rewrite replaces the tombstone file with the tombstones, in order, removing
+// it if there are none. It is meant to edit the tombstones of a file which is
+// not in use by the engine, and fails if tombstones are pending.
+func (t *Tombstoner) Rewrite(tombstones []Tombstone) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.pendingFile != nil {
+		return errors.New("tombstones are pending")
+	}
+	t.statsLoaded = false
+	t.lastAppliedOffset = 0
+
+	if len(tombstones) == 0 {
+		if err := os.Remove(t.tombstonePath()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	header := uint32(v4header)
+	for _, ts := range tombstones {
+		if len(ts.Measurement) > 0 {
+			header = v5header
+		}
+	}
+
+	tmpPath := fmt.Sprintf("%s.%s", t.tombstonePath(), CompactionTempExtension)
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_RDWR|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], header)
+	if _, err := tmp.Write(b[:]); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	bw := bufio.NewWriterSize(tmp, 64*1024)
+	t.pendingFile, t.pendingHeader = tmp, header
+	t.bw, t.gz = bw, gzip.NewWriter(bw)
+	for _, ts := range tombstones {
+		if err := t.writeTombstoneV4(t.gz, ts); err != nil {
+			_ = t.rollback()
+			return err
+		}
+	}
+	if err := t.commit(); err != nil {
+		_ = t.rollback()
+		return err
+	}
+	return nil
+}
+
 // HasTombstones return true if there are any tombstone entries recorded.
 func (t *Tombstoner) HasTombstones() bool {
 	files := t.TombstoneFiles()
@@ -309,15 +432,17 @@ func (t *Tombstoner) Walk(fn func(t Tombstone) error) error {
 	}
 
 	header := binary.BigEndian.Uint32(b[:])
-	if header == v4header {
-		return t.readTombstoneV4(f, fn)
+	if header == v4header || header == v5header {
+		return t.readTombstoneV4(f, header, fn)
 	}
 	return errors.New("invalid tombstone file")
 }
 
-func (t *Tombstoner) prepareLatest() error {
+// prepareLatest opens the pending tombstone file, with at least the version
+// of header so that the entries to write can be read back.
+func (t *Tombstoner) prepareLatest(header uint32) error {
 	if t.pendingFile != nil { // There is already a pending tombstone file open.
-		return nil
+		return t.upgradeHeader(header)
 	}
 
 	tmpPath := fmt.Sprintf("%s.%s", t.tombstonePath(), CompactionTempExtension)
@@ -342,13 +467,16 @@ func (t *Tombstoner) prepareLatest() error {
 		defer f.Close()
 		var b [4]byte
 		if n, err := f.Read(b[:]); n == 4 && err == nil {
-			header := binary.BigEndian.Uint32(b[:])
-			// There is an existing tombstone on disk and it's not a v4.
+			existing := binary.BigEndian.Uint32(b[:])
+			// There is an existing tombstone on disk and it's not a v4 or v5.
 			// We can't support it.
-			if header != v4header {
+			if existing != v4header && existing != v5header {
 				removeTmp()
 				return errIncompatibleV4Version
 			}
+			if existing > header {
+				header = existing
+			}
 
 			// Seek back to the beginning we copy the header
 			if _, err := f.Seek(0, io.SeekStart); err != nil {
@@ -362,6 +490,15 @@ func (t *Tombstoner) prepareLatest() error {
 				removeTmp()
 				return err
 			}
+
+			// Upgrade the copied header if needed.
+			if header != existing {
+				binary.BigEndian.PutUint32(b[:], header)
+				if _, err := tmp.WriteAt(b[:], 0); err != nil {
+					removeTmp()
+					return err
+				}
+			}
 		}
 	}
 
@@ -371,7 +508,7 @@ func (t *Tombstoner) prepareLatest() error {
 
 	// Write the header only if the file is new
 	if os.IsNotExist(err) {
-		binary.BigEndian.PutUint32(b[:4], v4header)
+		binary.BigEndian.PutUint32(b[:4], header)
 		if _, err := bw.Write(b[:4]); err != nil {
 			removeTmp()
 			return err
@@ -382,12 +519,33 @@ func (t *Tombstoner) prepareLatest() error {
 	gz := gzip.NewWriter(bw)
 
 	t.pendingFile = tmp
+	t.pendingHeader = header
 	t.gz = gz
 	t.bw = bw
 
 	return nil
 }
 
+// upgradeHeader rewrites the header of the pending tombstone file if it is
+// older than header.
+func (t *Tombstoner) upgradeHeader(header uint32) error {
+	if t.pendingHeader >= header {
+		return nil
+	}
+
+	// The header may still be buffered if the file is new.
+	if err := t.bw.Flush(); err != nil {
+		return err
+	}
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], header)
+	if _, err := t.pendingFile.WriteAt(b[:], 0); err != nil {
+		return err
+	}
+	t.pendingHeader = header
+	return nil
+}
+
 func (t *Tombstoner) commit() error {
 	// No pending writes
 	if t.pendingFile == nil {
@@ -423,6 +581,7 @@ func (t *Tombstoner) commit() error {
 	}
 
 	t.pendingFile = nil
+	t.pendingHeader = 0
 	t.bw = nil
 	t.gz = nil
 
@@ -439,12 +598,14 @@ func (t *Tombstoner) rollback() error {
 	t.gz = nil
 	t.bw = nil
 	t.pendingFile = nil
+	t.pendingHeader = 0
 	return os.Remove(tmpFilename)
 }
 
 // readTombstoneV4 reads the fourth version of tombstone files that are capable
-// of storing multiple v3 files appended together.
-func (t *Tombstoner) readTombstoneV4(f *os.File, fn func(t Tombstone) error) error {
+// of storing multiple v3 files appended together, and the fifth version that
+// adds measurement tombstones.
+func (t *Tombstoner) readTombstoneV4(f *os.File, header uint32, fn func(t Tombstone) error) error {
 	// Skip header, already checked earlier
 	if t.lastAppliedOffset != 0 {
 		if _, err := f.Seek(t.lastAppliedOffset, io.SeekStart); err != nil {
@@ -470,6 +631,7 @@ func (t *Tombstoner) readTombstoneV4(f *os.File, fn func(t Tombstone) error) err
 	var ( // save these buffers across loop iterations to avoid allocations
 		keyBuf  []byte
 		predBuf []byte
+		measBuf []byte
 	)
 
 	for {
@@ -487,6 +649,10 @@ func (t *Tombstoner) readTombstoneV4(f *os.File, fn func(t Tombstone) error) err
 				keyLen := int64(binary.BigEndian.Uint32(buf[:4]))
 				prefix := keyLen>>31&1 == 1 // Prefix is set according to whether the highest bit is set.
 				hasPred := keyLen>>30&1 == 1
+				hasMeas := keyLen>>29&1 == 1
+				if hasMeas && header < v5header {
+					return fmt.Errorf("measurement tombstone in v%d file", header&0xff)
+				}
 
 				// Remove 8 MSB to get correct length.
 				keyLen &^= kmask
@@ -529,12 +695,31 @@ func (t *Tombstoner) readTombstoneV4(f *os.File, fn func(t Tombstone) error) err
 					}
 				}
 
+				var measurement []byte
+				if hasMeas {
+					if _, err := io.ReadFull(gr, buf[:4]); err != nil {
+						return err
+					}
+					measLen := binary.BigEndian.Uint32(buf[:4])
+
+					if uint32(len(measBuf)) < measLen {
+						measBuf = make([]byte, measLen)
+					}
+					// cap slice protects against invalid usages of append in callback
+					measurement = measBuf[:measLen:measLen]
+
+					if _, err := io.ReadFull(gr, measurement); err != nil {
+						return err
+					}
+				}
+
 				if err := fn(Tombstone{
-					Key:       key,
-					Min:       min,
-					Max:       max,
-					Prefix:    prefix,
-					Predicate: predicate,
+					Key:         key,
+					Min:         min,
+					Max:         max,
+					Prefix:      prefix,
+					Predicate:   predicate,
+					Measurement: measurement,
 				}); err != nil {
 					return err
 				}
@@ -600,6 +785,10 @@ func (t *Tombstoner) writeTombstoneV4(dst io.Writer, ts Tombstone) error {
 		// A mask to set the predicate bit on a tombstone
 		l |= 1 << 30
 	}
+	if len(ts.Measurement) > 0 {
+		// A mask to set the measurement bit on a tombstone, only valid in v5 files.
+		l |= 1 << 29
+	}
 
 	binary.BigEndian.PutUint32(t.tmp[:4], l)
 	if _, err := dst.Write(t.tmp[:4]); err != nil {
@@ -630,5 +819,16 @@ func (t *Tombstoner) writeTombstoneV4(dst io.Writer, ts Tombstone) error {
 		}
 	}
 
+	if len(ts.Measurement) > 0 {
+		binary.BigEndian.PutUint32(t.tmp[:4], uint32(len(ts.Measurement)))
+		if _, err := dst.Write(t.tmp[:4]); err != nil {
+			return err
+		}
+
+		if _, err := dst.Write(ts.Measurement); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
@@ -2,6 +2,7 @@ package tsm1_test
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"io"
@@ -123,6 +124,56 @@ func TestTombstoner_AddPrefixRange(t *testing.T) {
 	}
 }
 
+func TestTombstoner_AddMeasurementRange(t *testing.T) {
+	for _, flush := range []bool{true, false} {
+		t.Run(fmt.Sprintf("flush=%v", flush), func(t *testing.T) {
+			dir := MustTempDir()
+			defer func() { os.RemoveAll(dir) }()
+
+			f := MustTempFile(dir)
+			ts := tsm1.NewTombstoner(f.Name(), nil)
+
+			// Only tombstones with a measurement require a v5 file.
+			if err := ts.AddPrefixRange([]byte("some-prefix"), 20, 30, nil); err != nil {
+				t.Fatal(err)
+			}
+			if flush {
+				if err := ts.Flush(); err != nil {
+					t.Fatalf("unexpected error flushing tombstone: %v", err)
+				}
+				if got, exp := mustReadHeader(t, ts), uint32(0x1504); got != exp {
+					t.Fatalf("unexpected header: got %#x, exp %#x", got, exp)
+				}
+			}
+
+			if err := ts.AddMeasurementRange([]byte("some-prefix"), []byte("cpu"), 10, 40, []byte("some-predicate")); err != nil {
+				t.Fatal(err)
+			}
+			if err := ts.Flush(); err != nil {
+				t.Fatalf("unexpected error flushing tombstone: %v", err)
+			}
+			if got, exp := mustReadHeader(t, ts), uint32(0x1505); got != exp {
+				t.Fatalf("unexpected header: got %#x, exp %#x", got, exp)
+			}
+
+			exp := []tsm1.Tombstone{
+				{Key: []byte("some-prefix"), Min: 20, Max: 30, Prefix: true},
+				{Key: []byte("some-prefix"), Min: 10, Max: 40, Prefix: true, Predicate: []byte("some-predicate"), Measurement: []byte("cpu")},
+			}
+			if got := mustReadAll(tsm1.NewTombstoner(f.Name(), nil)); !reflect.DeepEqual(got, exp) {
+				t.Fatalf("unexpected tombstone entries. Got %s, expected %s", got, exp)
+			}
+		})
+	}
+}
+
+func TestTombstone_KeyPrefix(t *testing.T) {
+	ts := tsm1.Tombstone{Key: []byte("prefix"), Prefix: true, Measurement: []byte("cpu load")}
+	if got, exp := string(ts.KeyPrefix()), "prefix,\x00=cpu\\ load,"; got != exp {
+		t.Fatalf("unexpected key prefix: got %q, exp %q", got, exp)
+	}
+}
+
 func TestTombstoner_Add_LargeKey(t *testing.T) {
 	dir := MustTempDir()
 	defer func() { os.RemoveAll(dir) }()
@@ -376,6 +427,43 @@ func TestTombstoner_Delete(t *testing.T) {
 	}
 }
 
+func TestTombstoner_Rewrite(t *testing.T) {
+	dir := MustTempDir()
+	defer func() { os.RemoveAll(dir) }()
+
+	f := MustTempFile(dir)
+	ts := tsm1.NewTombstoner(f.Name(), nil)
+
+	if err := ts.AddRange([][]byte{[]byte("foo"), []byte("bar")}, 10, 20); err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.AddMeasurementRange([]byte("some-prefix"), []byte("cpu"), 10, 40, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.Flush(); err != nil {
+		t.Fatalf("unexpected error flushing tombstone: %v", err)
+	}
+
+	// Dropping the measurement tombstone downgrades the file to v4.
+	exp := []tsm1.Tombstone{{Key: []byte("bar"), Min: 10, Max: 20}}
+	if err := ts.Rewrite(exp); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := mustReadHeader(t, ts), uint32(0x1504); got != exp {
+		t.Fatalf("unexpected header: got %#x, exp %#x", got, exp)
+	}
+	if got := mustReadAll(tsm1.NewTombstoner(f.Name(), nil)); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected tombstone entries. Got %s, expected %s", got, exp)
+	}
+
+	if err := ts.Rewrite(nil); err != nil {
+		t.Fatal(err)
+	}
+	if ts.HasTombstones() {
+		t.Fatal("expected the tombstone file to be removed")
+	}
+}
+
 func TestTombstoner_Existing(t *testing.T) {
 	dir := MustTempDir()
 	defer func() { os.RemoveAll(dir) }()
@@ -472,6 +560,26 @@ func TestTombstoner_Existing(t *testing.T) {
 	})
 }
 
+func mustReadHeader(t *testing.T, ts *tsm1.Tombstoner) uint32 {
+	t.Helper()
+
+	stats := ts.TombstoneFiles()
+	if len(stats) != 1 {
+		t.Fatalf("stat length mismatch: got %v, exp 1", len(stats))
+	}
+	f, err := os.Open(stats[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var b [4]byte
+	if _, err := io.ReadFull(f, b[:]); err != nil {
+		t.Fatal(err)
+	}
+	return binary.BigEndian.Uint32(b[:])
+}
+
 func mustReadAll(t *tsm1.Tombstoner) []tsm1.Tombstone {
 	var tombstones []tsm1.Tombstone
 	if err := t.Walk(func(t tsm1.Tombstone) error {
@@ -484,12 +592,19 @@ func mustReadAll(t *tsm1.Tombstoner) []tsm1.Tombstone {
 			copy(p, t.Predicate)
 		}
 
+		var m []byte
+		if t.Measurement != nil {
+			m = make([]byte, len(t.Measurement))
+			copy(m, t.Measurement)
+		}
+
 		tombstones = append(tombstones, tsm1.Tombstone{
-			Min:       t.Min,
-			Max:       t.Max,
-			Key:       b,
-			Prefix:    t.Prefix,
-			Predicate: p,
+			Min:         t.Min,
+			Max:         t.Max,
+			Key:         b,
+			Prefix:      t.Prefix,
+			Predicate:   p,
+			Measurement: m,
 		})
 		return nil
 	}); err != nil {
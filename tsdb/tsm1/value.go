@@ -93,6 +93,13 @@ func CollectionToValues(collection *tsdb.SeriesCollection) (map[string][]Value,
 					return nil, err
 				}
 				v = NewUnsignedValue(t, iv)
+			case models.Decimal:
+				// Decimals are stored as strings, which keep them exact.
+				dv, err := iter.DecimalValue()
+				if err != nil {
+					return nil, err
+				}
+				v = NewStringValue(t, dv.String())
 			case models.String:
 				v = NewStringValue(t, iter.StringValue())
 			case models.Boolean:
@@ -2,6 +2,7 @@ package tsm1
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"hash/crc32"
 	"io"
@@ -17,6 +18,27 @@ type VerifyTSM struct {
 	Paths    []string
 	OrgID    influxdb.ID
 	BucketID influxdb.ID
+
+	// Fix rewrites the files with corrupted blocks. Blocks whose checksum
+	// does not match or which can not be decoded are moved to a quarantine
+	// file next to the TSM file, and blocks whose index entry does not match
+	// their timestamps are kept with a corrected entry.
+	Fix bool
+}
+
+// QuarantineExtension is the extension of the file the corrupted blocks of a
+// TSM file are moved to by VerifyTSM.
+const QuarantineExtension = "quarantine"
+
+// QuarantinedBlock is a corrupted block moved to a quarantine file, which
+// holds one JSON encoded block per line.
+type QuarantinedBlock struct {
+	Key      []byte `json:"key"`
+	MinTime  int64  `json:"minTime"`
+	MaxTime  int64  `json:"maxTime"`
+	Checksum uint32 `json:"checksum"`
+	Data     []byte `json:"data,omitempty"`
+	Reason   string `json:"reason"`
 }
 
 func (v *VerifyTSM) Run() error {
@@ -99,5 +121,134 @@ func (v *VerifyTSM) processFile(path string) error {
 
 	fmt.Fprintf(v.Stdout, "Completed checking %d block(s)\n", count)
 
+	if !v.Fix || totalErrors == 0 {
+		return nil
+	}
+
+	kept, fixed, quarantined, err := v.fixFile(path, reader, start)
+	if err != nil {
+		return fmt.Errorf("failed to fix %q: %v", path, err)
+	}
+	fmt.Fprintf(v.Stdout, "Fixed %s: kept %d block(s), corrected the index entry of %d block(s), quarantined %d block(s) to %s\n",
+		path, kept, fixed, quarantined, path+"."+QuarantineExtension)
+
 	return nil
 }
+
+// fixFile rewrites the TSM file at path without the corrupted blocks of the
+// keys starting with start, which are appended to the quarantine file of
+// path. The blocks of the other keys are copied as they are.
+func (v *VerifyTSM) fixFile(path string, reader *TSMReader, start []byte) (kept, fixed, quarantined int, err error) {
+	// The stats file of the rewritten file replaces that of the file.
+	tmp := path + "." + TmpTSMFileExtension
+	if err := os.Remove(StatsFilename(path)); err != nil && !os.IsNotExist(err) {
+		return 0, 0, 0, err
+	}
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	w, err := NewTSMWriter(f)
+	if err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return 0, 0, 0, err
+	}
+
+	q, err := os.OpenFile(path+"."+QuarantineExtension, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0666)
+	if err != nil {
+		w.Remove()
+		return 0, 0, 0, err
+	}
+	defer q.Close()
+	enc := json.NewEncoder(q)
+
+	var (
+		ts      cursors.TimestampArray
+		entries []IndexEntry
+		buf     []byte
+	)
+	iter := reader.Iterator(nil)
+	for iter.Next() {
+		key := iter.Key()
+		verified := len(start) == 0 || bytes.HasPrefix(key, start)
+
+		if entries, err = reader.ReadEntries(key, entries[:0]); err != nil {
+			w.Remove()
+			return 0, 0, 0, err
+		}
+		for i := range entries {
+			entry := &entries[i]
+
+			var checksum uint32
+			var reason string
+			checksum, buf, err = reader.ReadBytes(entry, buf)
+			switch {
+			case !verified && err == nil:
+			case err != nil:
+				reason = err.Error()
+			case crc32.ChecksumIEEE(buf) != checksum:
+				reason = "checksum mismatch"
+			case DecodeTimestampArrayBlock(buf, &ts) != nil || ts.Len() == 0:
+				reason = "invalid timestamps"
+			}
+
+			if reason != "" {
+				qb := QuarantinedBlock{
+					Key:      key,
+					MinTime:  entry.MinTime,
+					MaxTime:  entry.MaxTime,
+					Checksum: checksum,
+					Reason:   reason,
+				}
+				if err == nil {
+					qb.Data = buf
+				}
+				if err := enc.Encode(qb); err != nil {
+					w.Remove()
+					return 0, 0, 0, err
+				}
+				quarantined++
+				continue
+			}
+
+			minTime, maxTime := entry.MinTime, entry.MaxTime
+			if verified && (minTime != ts.MinTime() || maxTime != ts.MaxTime()) {
+				minTime, maxTime = ts.MinTime(), ts.MaxTime()
+				fixed++
+			}
+			if err := w.WriteBlock(key, minTime, maxTime, buf); err != nil {
+				w.Remove()
+				return 0, 0, 0, err
+			}
+			kept++
+		}
+	}
+	if err := iter.Err(); err != nil {
+		w.Remove()
+		return 0, 0, 0, err
+	}
+	if err := q.Sync(); err != nil {
+		w.Remove()
+		return 0, 0, 0, err
+	}
+
+	// The file, its stats and tombstones are removed if no block is left.
+	// Otherwise, the tombstones apply to the rewritten file, which keeps the
+	// keys and timestamps of the blocks.
+	if kept == 0 {
+		w.Remove()
+		return kept, fixed, quarantined, reader.Remove()
+	}
+	if err := w.WriteIndex(); err != nil {
+		w.Remove()
+		return 0, 0, 0, err
+	} else if err := w.Close(); err != nil {
+		w.Remove()
+		return 0, 0, 0, err
+	}
+	if err := reader.Close(); err != nil {
+		return 0, 0, 0, err
+	}
+	return kept, fixed, quarantined, os.Rename(tmp, path)
+}
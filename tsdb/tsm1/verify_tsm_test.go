@@ -0,0 +1,93 @@
+package tsm1_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+func TestVerifyTSM_Fix(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	path := MustWriteTSM(dir, 1, map[string][]tsm1.Value{
+		"cpu": {tsm1.NewValue(1, 1.0), tsm1.NewValue(2, 2.0)},
+		"mem": {tsm1.NewValue(1, int64(10))},
+	})
+
+	// Corrupt the data of the first block, after the header and its checksum.
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[12] ^= 0xff
+	if err := ioutil.WriteFile(path, data, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	// Files are only checked without Fix.
+	var stdout bytes.Buffer
+	verify := tsm1.VerifyTSM{Stdout: &stdout, Paths: []string{path}}
+	if err := verify.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "unexpected checksum") {
+		t.Fatalf("unexpected output: %s", stdout.String())
+	}
+	if _, err := os.Stat(path + "." + tsm1.QuarantineExtension); !os.IsNotExist(err) {
+		t.Fatalf("unexpected quarantine file: %v", err)
+	}
+
+	stdout.Reset()
+	verify.Fix = true
+	if err := verify.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout.String(), "kept 1 block(s), corrected the index entry of 0 block(s), quarantined 1 block(s)") {
+		t.Fatalf("unexpected output: %s", stdout.String())
+	}
+
+	r := MustOpenTSMReader(path)
+	if got := r.KeyCount(); got != 1 {
+		t.Fatalf("unexpected key count: %d", got)
+	}
+	values, err := r.ReadAll([]byte("mem"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(values) != 1 || values[0].Value() != int64(10) {
+		t.Fatalf("unexpected values: %v", values)
+	}
+	r.Close()
+
+	f, err := os.Open(path + "." + tsm1.QuarantineExtension)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var blocks []tsm1.QuarantinedBlock
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		var b tsm1.QuarantinedBlock
+		if err := json.Unmarshal(scanner.Bytes(), &b); err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, b)
+	}
+	if len(blocks) != 1 || string(blocks[0].Key) != "cpu" || blocks[0].MinTime != 1 || blocks[0].MaxTime != 2 || len(blocks[0].Data) == 0 {
+		t.Fatalf("unexpected quarantined blocks: %+v", blocks)
+	}
+
+	// The fixed file verifies.
+	stdout.Reset()
+	if err := verify.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(stdout.String(), "unexpected") || strings.Contains(stdout.String(), "Fixed") {
+		t.Fatalf("unexpected output: %s", stdout.String())
+	}
+}
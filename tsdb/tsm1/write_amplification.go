@@ -0,0 +1,108 @@
+package tsm1
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// WriteAmplification summarizes the bytes written to TSM files by the
+// snapshots and compactions of an engine over a recent period.
+type WriteAmplification struct {
+	// Since is the start of the period.
+	Since time.Time
+
+	// Levels is indexed by compaction level: 0 for snapshots, 1 to 3 for
+	// level compactions, 4 for optimize and 5 for full compactions.
+	Levels [6]LevelWrites
+}
+
+// LevelWrites is the number of snapshots or compactions of a level, and the
+// bytes they wrote.
+type LevelWrites struct {
+	Compactions int
+	Bytes       uint64
+}
+
+// Ratio returns the number of bytes written by snapshots and compactions for
+// each byte written by snapshots, or zero if no snapshot was written.
+func (w WriteAmplification) Ratio() float64 {
+	snapshot := w.Levels[0].Bytes
+	if snapshot == 0 {
+		return 0
+	}
+
+	var total uint64
+	for _, l := range w.Levels {
+		total += l.Bytes
+	}
+	return float64(total) / float64(snapshot)
+}
+
+// writeTracker records the bytes written by snapshots and compactions over
+// a sliding window.
+type writeTracker struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu     sync.Mutex
+	writes []levelWrite
+}
+
+type levelWrite struct {
+	time  time.Time
+	level compactionLevel
+	bytes uint64
+}
+
+func newWriteTracker(window time.Duration) *writeTracker {
+	return &writeTracker{window: window, now: time.Now}
+}
+
+// add records the bytes written by a snapshot or compaction of level.
+func (t *writeTracker) add(level compactionLevel, bytes uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.prune(now)
+	t.writes = append(t.writes, levelWrite{time: now, level: level, bytes: bytes})
+}
+
+// report returns the bytes written within the window.
+func (t *writeTracker) report() WriteAmplification {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.prune(now)
+
+	w := WriteAmplification{Since: now.Add(-t.window)}
+	for _, lw := range t.writes {
+		w.Levels[lw.level].Compactions++
+		w.Levels[lw.level].Bytes += lw.bytes
+	}
+	return w
+}
+
+// prune drops the writes older than the window.
+func (t *writeTracker) prune(now time.Time) {
+	since := now.Add(-t.window)
+	i := 0
+	for i < len(t.writes) && t.writes[i].time.Before(since) {
+		i++
+	}
+	t.writes = append(t.writes[:0], t.writes[i:]...)
+}
+
+// filesSize returns the total size of the files at paths, ignoring the
+// files which can not be read.
+func filesSize(paths []string) uint64 {
+	var n uint64
+	for _, path := range paths {
+		if fi, err := os.Stat(path); err == nil {
+			n += uint64(fi.Size())
+		}
+	}
+	return n
+}
@@ -0,0 +1,44 @@
+package tsm1
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWriteTracker(t *testing.T) {
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr := newWriteTracker(time.Hour)
+	tr.now = func() time.Time { return now }
+
+	tr.add(0, 100)
+	now = now.Add(30 * time.Minute)
+	tr.add(0, 100)
+	tr.add(1, 200)
+	tr.add(5, 300)
+
+	w := tr.report()
+	if got, exp := w.Since, now.Add(-time.Hour); !got.Equal(exp) {
+		t.Fatalf("unexpected since: got %v, exp %v", got, exp)
+	}
+	if got, exp := w.Levels[0], (LevelWrites{Compactions: 2, Bytes: 200}); got != exp {
+		t.Fatalf("unexpected snapshot writes: got %v, exp %v", got, exp)
+	}
+	if got, exp := w.Ratio(), 3.5; got != exp {
+		t.Fatalf("unexpected write amplification: got %v, exp %v", got, exp)
+	}
+
+	// Writes older than the window are dropped.
+	now = now.Add(45 * time.Minute)
+	w = tr.report()
+	if got, exp := w.Levels[0], (LevelWrites{Compactions: 1, Bytes: 100}); got != exp {
+		t.Fatalf("unexpected snapshot writes: got %v, exp %v", got, exp)
+	}
+	if got, exp := w.Ratio(), 6.0; got != exp {
+		t.Fatalf("unexpected write amplification: got %v, exp %v", got, exp)
+	}
+
+	now = now.Add(time.Hour)
+	if got := tr.report().Ratio(); got != 0 {
+		t.Fatalf("unexpected write amplification without snapshots: %v", got)
+	}
+}
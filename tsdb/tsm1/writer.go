@@ -9,7 +9,8 @@ A TSM file is composed for four sections: header, blocks, index and the footer.
 └────────┴────────────────────────────────────┴─────────────┴──────────────┘
 
 Header is composed of a magic number to identify the file type and a version
-number.
+number. The version is 2 for the files written by snapshots whose blocks may
+be compressed, and 1 otherwise.
 
 ┌───────────────────┐
 │      Header       │
@@ -86,6 +87,11 @@ const (
 	// Version indicates the version of the TSM file format.
 	Version byte = 1
 
+	// CompressedBlocksVersion is the version of the TSM files whose blocks
+	// may be compressed, which the readers of Version reject instead of
+	// misdecoding their compressed blocks.
+	CompressedBlocksVersion byte = 2
+
 	// Size in bytes of an index entry
 	indexEntrySize = 28
 
@@ -588,6 +594,7 @@ type tsmWriter struct {
 	w       *bufio.Writer
 	index   IndexWriter
 	n       int64
+	version byte
 
 	// The bytes written count of when we last fsync'd
 	lastSync int64
@@ -602,6 +609,7 @@ func NewTSMWriter(w io.Writer) (TSMWriter, error) {
 		wrapped: w,
 		w:       bufio.NewWriterSize(w, 1024*1024),
 		index:   index,
+		version: Version,
 		stats:   NewMeasurementStats(),
 	}, nil
 }
@@ -626,6 +634,7 @@ func NewTSMWriterWithDiskBuffer(w io.Writer) (TSMWriter, error) {
 		wrapped: w,
 		w:       bufio.NewWriterSize(w, 1024*1024),
 		index:   index,
+		version: Version,
 		stats:   NewMeasurementStats(),
 	}, nil
 }
@@ -636,7 +645,7 @@ func (t *tsmWriter) MeasurementStats() MeasurementStats { return t.stats }
 func (t *tsmWriter) writeHeader() error {
 	var buf [5]byte
 	binary.BigEndian.PutUint32(buf[0:4], MagicNumber)
-	buf[4] = Version
+	buf[4] = t.version
 
 	n, err := t.w.Write(buf[:])
 	if err != nil {
@@ -893,7 +902,8 @@ func (t *tsmWriter) Size() uint32 {
 }
 
 // verifyVersion verifies that the reader's bytes are a TSM byte
-// stream of the correct version (1)
+// stream of the correct version (1), or of the version with compressed
+// blocks (2)
 func verifyVersion(r io.ReadSeeker) error {
 	_, err := r.Seek(0, 0)
 	if err != nil {
@@ -911,7 +921,7 @@ func verifyVersion(r io.ReadSeeker) error {
 	if err != nil {
 		return fmt.Errorf("init: error reading version: %v", err)
 	}
-	if b[0] != Version {
+	if b[0] != Version && b[0] != CompressedBlocksVersion {
 		return fmt.Errorf("init: file is version %b. expected %b", b[0], Version)
 	}
 